@@ -0,0 +1,104 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+const cacheCleanHelp = `
+This command evicts files from the repository cache, the files not refreshed
+for longer than --older-than, then the oldest files until the cache fits in
+--max-size:
+
+    $ helm cache clean --older-than 720h --max-size 500Mi
+
+The whole cache is removed with --all. Evicted indices are fetched again by
+'helm repo update'.
+`
+
+type cacheCleanOptions struct {
+	all       bool
+	olderThan time.Duration
+	maxSize   string
+	repoCache string
+}
+
+func newCacheCleanCmd(out io.Writer) *cobra.Command {
+	o := &cacheCleanOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "clean",
+		Short: "evict files from the repository cache",
+		Long:  cacheCleanHelp,
+		Args:  require.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			o.repoCache = settings.RepositoryCache
+			return o.run(out)
+		},
+	}
+
+	f := cmd.Flags()
+	f.DurationVar(&o.olderThan, "older-than", 0, "evict the files not refreshed for longer than this duration")
+	f.StringVar(&o.maxSize, "max-size", "", "evict the oldest files until the cache fits in this size, like 500Mi")
+	f.BoolVar(&o.all, "all", false, "evict every file of the cache")
+	cmd.MarkFlagsMutuallyExclusive("all", "older-than")
+	cmd.MarkFlagsMutuallyExclusive("all", "max-size")
+
+	return cmd
+}
+
+func (o *cacheCleanOptions) run(out io.Writer) error {
+	c := repo.NewCache(o.repoCache)
+	c.MaxAge = o.olderThan
+	if o.maxSize != "" {
+		q, err := resource.ParseQuantity(o.maxSize)
+		if err != nil || q.Sign() <= 0 {
+			return errors.Errorf("invalid cache size %q", o.maxSize)
+		}
+		c.MaxSize = q.Value()
+	}
+
+	var evicted []repo.CacheEntry
+	var err error
+	switch {
+	case o.all:
+		evicted, err = c.Clear()
+	case c.MaxAge == 0 && c.MaxSize == 0:
+		return errors.New("one of --older-than, --max-size or --all is required")
+	default:
+		evicted, err = c.Prune()
+	}
+	if err != nil {
+		return err
+	}
+	var size int64
+	for _, e := range evicted {
+		size += e.Size
+	}
+	fmt.Fprintf(out, "Evicted %d files, %s\n", len(evicted), resource.NewQuantity(size, resource.BinarySI))
+	return nil
+}
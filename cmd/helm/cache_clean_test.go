@@ -0,0 +1,76 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCacheClean(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "old-1.0.0.tgz")
+	current := filepath.Join(dir, "stable-index.yaml")
+	for _, f := range []string{old, current} {
+		if err := os.WriteFile(f, make([]byte, 1024), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	modTime := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(old, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	b := bytes.NewBuffer(nil)
+	o := &cacheCleanOptions{olderThan: 24 * time.Hour, repoCache: dir}
+	if err := o.run(b); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "Evicted 1 files, 1Ki\n" {
+		t.Errorf("unexpected output %q", b.String())
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be evicted", old)
+	}
+	if _, err := os.Stat(current); err != nil {
+		t.Errorf("expected %s to be kept, got %v", current, err)
+	}
+
+	o = &cacheCleanOptions{maxSize: "none", repoCache: dir}
+	if err := o.run(b); err == nil {
+		t.Error("expected an invalid size to fail")
+	}
+
+	// Without bounds, nothing is evicted unless --all is given
+	o = &cacheCleanOptions{repoCache: dir}
+	if err := o.run(b); err == nil {
+		t.Error("expected cleaning without bounds to fail")
+	}
+	if _, err := os.Stat(current); err != nil {
+		t.Errorf("expected %s to be kept, got %v", current, err)
+	}
+	o = &cacheCleanOptions{all: true, repoCache: dir}
+	if err := o.run(b); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(current); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be evicted", current)
+	}
+}
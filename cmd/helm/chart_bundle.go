@@ -0,0 +1,79 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"helm.sh/helm/v3/pkg/action"
+)
+
+const chartBundleHelp = `
+This command consists of multiple subcommands to move charts into disconnected
+environments with air-gap bundles.
+
+A bundle is a tarball in the OCI image layout holding a chart and all its OCI
+dependencies, exported from a registry with 'helm chart bundle export'. Once
+transferred, 'helm chart bundle import' pushes the charts into an internal
+registry with the digests of their manifests unchanged, so the digests pinned in
+Chart.lock files and the signatures of the charts stay valid.
+`
+
+func newChartBundleCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "bundle",
+		Short: "export and import air-gap bundles of charts",
+		Long:  chartBundleHelp,
+	}
+	cmd.AddCommand(
+		newChartBundleExportCmd(cfg, out),
+		newChartBundleImportCmd(cfg, out),
+	)
+	return cmd
+}
+
+// chartBundleRegistryOptions are the options of the registry client of the
+// bundle commands.
+type chartBundleRegistryOptions struct {
+	certFile              string
+	keyFile               string
+	caFile                string
+	insecureSkipTLSverify bool
+	plainHTTP             bool
+}
+
+func (o *chartBundleRegistryOptions) addFlags(f *pflag.FlagSet) {
+	f.StringVar(&o.certFile, "cert-file", "", "identify registry client using this SSL certificate file")
+	f.StringVar(&o.keyFile, "key-file", "", "identify registry client using this SSL key file")
+	f.StringVar(&o.caFile, "ca-file", "", "verify certificates of HTTPS-enabled servers using this CA bundle")
+	f.BoolVar(&o.insecureSkipTLSverify, "insecure-skip-tls-verify", false, "skip tls certificate checks for the registry")
+	f.BoolVar(&o.plainHTTP, "plain-http", false, "use insecure HTTP connections for the registry")
+}
+
+// setRegistryClient sets the registry client of cfg.
+func (o *chartBundleRegistryOptions) setRegistryClient(cfg *action.Configuration) error {
+	registryClient, err := newRegistryClient(o.certFile, o.keyFile, o.caFile, o.insecureSkipTLSverify, o.plainHTTP)
+	if err != nil {
+		return fmt.Errorf("missing registry client: %w", err)
+	}
+	cfg.RegistryClient = registryClient
+	return nil
+}
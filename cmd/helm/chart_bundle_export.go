@@ -0,0 +1,102 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+const chartBundleExportHelp = `
+This command exports a chart stored in an OCI registry, with all its OCI
+dependencies, to an air-gap bundle. Dependencies are resolved recursively, at
+the versions of the Chart.lock of each chart if it has one:
+
+    $ helm chart bundle export oci://example.com/charts/mychart --version 1.2.3 \
+        --file mychart-bundle.tar
+
+The bundle is written to standard output unless --file is set.
+
+With --images, the container images used by the charts are listed in the
+bundle, so they can be mirrored with container tooling. The images are found in
+the templates of each chart rendered with its default values; images selected
+by other values are not listed, and the content of the images is not exported.
+`
+
+func newChartBundleExportCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
+	client := action.NewBundleExport(cfg)
+	o := &chartBundleRegistryOptions{}
+	var file string
+
+	cmd := &cobra.Command{
+		Use:   "export CHART",
+		Short: "export a chart and its dependencies to an air-gap bundle",
+		Long:  chartBundleExportHelp,
+		Args:  require.ExactArgs(1),
+		ValidArgsFunction: func(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return noMoreArgsComp()
+			}
+			return []string{fmt.Sprintf("%s://", registry.OCIScheme)}, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			if err := o.setRegistryClient(cfg); err != nil {
+				return err
+			}
+			if file == "" || file == "-" {
+				_, err := client.Run(args[0], out)
+				return err
+			}
+
+			f, err := os.Create(file)
+			if err != nil {
+				return err
+			}
+			bundle, err := client.Run(args[0], f)
+			if err != nil {
+				f.Close()
+				os.Remove(file)
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+			for _, ch := range bundle.Charts {
+				fmt.Fprintf(out, "Exported: %s\nDigest: %s\n", ch.Ref, ch.Digest)
+			}
+			if len(bundle.Images) > 0 {
+				fmt.Fprintf(out, "Images: %d\n", len(bundle.Images))
+			}
+			return nil
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&client.Version, "version", "", "specify a version constraint for the chart version to use. If this is not specified, the latest version is used")
+	f.BoolVar(&client.Images, "images", false, "list the container images used by the charts in the bundle")
+	f.StringVar(&file, "file", "", "write the bundle to this file instead of the standard output")
+	o.addFlags(f)
+
+	return cmd
+}
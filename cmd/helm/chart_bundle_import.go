@@ -0,0 +1,89 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/pkg/action"
+)
+
+const chartBundleImportHelp = `
+This command pushes the charts of an air-gap bundle written by
+'helm chart bundle export' into a registry. Every chart keeps the path of its
+repository and its tag under the given registry namespace:
+
+    $ helm chart bundle import mychart-bundle.tar oci://registry.internal/mirror
+
+imports oci://example.com/charts/mychart:1.2.3 as
+oci://registry.internal/mirror/charts/mychart:1.2.3. Charts are pushed as they
+were exported, so the digests of their manifests do not change. The original
+references keep working when the registry is configured as a mirror of the
+exported registries in the registries config file, see 'helm registry --help'.
+
+Use '-' to read the bundle from standard input.
+`
+
+func newChartBundleImportCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
+	client := action.NewBundleImport(cfg)
+	o := &chartBundleRegistryOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "import FILE REMOTE",
+		Short: "push the charts of an air-gap bundle to a registry",
+		Long:  chartBundleImportHelp,
+		Args:  require.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.setRegistryClient(cfg); err != nil {
+				return err
+			}
+			in := cmd.InOrStdin()
+			if args[0] != "-" {
+				f, err := os.Open(args[0])
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				in = f
+			}
+
+			bundle, err := client.Run(in, args[1])
+			if err != nil {
+				return err
+			}
+			for _, ch := range bundle.Charts {
+				fmt.Fprintf(out, "Pushed: %s\nDigest: %s\n", ch.Ref, ch.Digest)
+			}
+			if len(bundle.Images) > 0 {
+				fmt.Fprintln(out, "Images used by the charts:")
+				for _, image := range bundle.Images {
+					fmt.Fprintf(out, "  %s\n", image)
+				}
+			}
+			return nil
+		},
+	}
+
+	o.addFlags(cmd.Flags())
+
+	return cmd
+}
@@ -0,0 +1,93 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/repo/repotest"
+)
+
+func TestChartBundleCmd(t *testing.T) {
+	srv, err := repotest.NewTempServerWithCleanup(t, "testdata/testcharts/*.tgz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	ociSrv, err := repotest.NewOCIServer(t, srv.Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := createTestingMetadataForOCI("oci-depending-chart", ociSrv.RegistryURL)
+	if _, err := chartutil.Save(c, ociSrv.Dir); err != nil {
+		t.Fatal(err)
+	}
+	ociSrv.Run(t, repotest.WithDependingChart(c))
+
+	flags := fmt.Sprintf("--plain-http --registry-config %s", filepath.Join(srv.Root(), "config.json"))
+	bundle := filepath.Join(srv.Root(), "bundle.tar")
+
+	_, out, err := executeActionCommand(fmt.Sprintf("chart bundle export oci://%s/u/ocitestuser/oci-depending-chart --version 1.2.3 --images --file %s %s",
+		ociSrv.RegistryURL, bundle, flags))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		fmt.Sprintf("Exported: %s/u/ocitestuser/oci-depending-chart:1.2.3", ociSrv.RegistryURL),
+		fmt.Sprintf("Exported: %s/u/ocitestuser/oci-dependent-chart:0.1.0", ociSrv.RegistryURL),
+		"Images: 2",
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected %q in the output of export, got:\n%s", s, out)
+		}
+	}
+	digest, err := ociSrv.Client.Resolve(fmt.Sprintf("%s/u/ocitestuser/oci-dependent-chart:0.1.0", ociSrv.RegistryURL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, out, err = executeActionCommand(fmt.Sprintf("chart bundle import %s oci://%s/mirror %s", bundle, ociSrv.RegistryURL, flags))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		fmt.Sprintf("Pushed: %s/mirror/u/ocitestuser/oci-depending-chart:1.2.3", ociSrv.RegistryURL),
+		fmt.Sprintf("Pushed: %s/mirror/u/ocitestuser/oci-dependent-chart:0.1.0\nDigest: %s", ociSrv.RegistryURL, digest),
+		"nginx:1.16.0",
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected %q in the output of import, got:\n%s", s, out)
+		}
+	}
+
+	imported, err := ociSrv.Client.Resolve(fmt.Sprintf("%s/mirror/u/ocitestuser/oci-dependent-chart:0.1.0", ociSrv.RegistryURL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported != digest {
+		t.Errorf("expected the imported chart to have the digest %s, got %s", digest, imported)
+	}
+
+	if _, _, err := executeActionCommand(fmt.Sprintf("chart bundle export %s/testcharts/oci-dependent-chart-0.1.0.tgz", srv.URL())); err == nil {
+		t.Error("expected an error exporting a chart which is not in a registry")
+	}
+}
@@ -19,7 +19,9 @@ package main
 import (
 	"fmt"
 	"io"
+	"log"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -48,10 +50,23 @@ something like this:
 do not exist, Helm will attempt to create them as it goes. If the given
 destination exists and there are files in that directory, conflicting files
 will be overwritten, but other files will be left alone.
+
+Use '--with' and '--without' to add or remove sets of templates, with their
+values, so that the chart starts closer to what it needs:
+
+    $ helm create --with statefulset,cronjob,networkpolicy --without ingress,serviceaccount foo
+
+The template sets are:
+
+%s
+The 'statefulset' set replaces the 'deployment' set. The sets depending on
+another set, like 'ingress' on 'service', cannot be kept without it.
 `
 
 type createOptions struct {
-	starter    string // --starter
+	starter    string   // --starter
+	with       []string // --with
+	without    []string // --without
 	name       string
 	starterDir string
 }
@@ -62,7 +77,7 @@ func newCreateCmd(out io.Writer) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "create NAME",
 		Short: "create a new chart with the given name",
-		Long:  createDesc,
+		Long:  fmt.Sprintf(createDesc, templateSetsHelp()),
 		Args:  require.ExactArgs(1),
 		ValidArgsFunction: func(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
 			if len(args) == 0 {
@@ -80,10 +95,42 @@ func newCreateCmd(out io.Writer) *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVarP(&o.starter, "starter", "p", "", "the name or absolute path to Helm starter scaffold")
+	f := cmd.Flags()
+	f.StringVarP(&o.starter, "starter", "p", "", "the name or absolute path to Helm starter scaffold")
+	f.StringSliceVar(&o.with, "with", []string{}, "add these template sets to the default ones, like statefulset,cronjob (can specify multiple)")
+	f.StringSliceVar(&o.without, "without", []string{}, "remove these template sets from the default ones, like ingress,serviceaccount (can specify multiple)")
+	cmd.MarkFlagsMutuallyExclusive("starter", "with")
+	cmd.MarkFlagsMutuallyExclusive("starter", "without")
+
+	for _, flag := range []string{"with", "without"} {
+		err := cmd.RegisterFlagCompletionFunc(flag, func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+			var sets []string
+			for _, s := range chartutil.TemplateSets() {
+				sets = append(sets, fmt.Sprintf("%s\t%s", s.Name, s.Description))
+			}
+			return sets, cobra.ShellCompDirectiveNoFileComp
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	return cmd
 }
 
+// templateSetsHelp returns the help of the template sets of 'helm create'.
+func templateSetsHelp() string {
+	var b strings.Builder
+	for _, s := range chartutil.TemplateSets() {
+		fmt.Fprintf(&b, "    %-15s %s", s.Name, s.Description)
+		if s.Default {
+			fmt.Fprint(&b, " (default)")
+		}
+		fmt.Fprintln(&b)
+	}
+	return b.String()
+}
+
 func (o *createOptions) run(out io.Writer) error {
 	fmt.Fprintf(out, "Creating %s\n", o.name)
 
@@ -108,6 +155,9 @@ func (o *createOptions) run(out io.Writer) error {
 	}
 
 	chartutil.Stderr = out
-	_, err := chartutil.Create(chartname, filepath.Dir(o.name))
+	_, err := chartutil.CreateWithOptions(chartname, filepath.Dir(o.name), chartutil.CreateOptions{
+		With:    o.with,
+		Without: o.without,
+	})
 	return err
 }
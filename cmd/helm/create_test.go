@@ -20,6 +20,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"testing"
 
 	"helm.sh/helm/v3/internal/test/ensure"
@@ -60,6 +62,45 @@ func TestCreateCmd(t *testing.T) {
 	}
 }
 
+func TestCreateCmdWithTemplateSets(t *testing.T) {
+	ensure.HelmHome(t)
+	cname := "testchart"
+	dir := t.TempDir()
+	defer testChdir(t, dir)()
+
+	if _, _, err := executeActionCommand("create --with statefulset,cronjob --without ingress " + cname); err != nil {
+		t.Fatalf("Failed to run create: %s", err)
+	}
+
+	c, err := loader.LoadDir(cname)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedTemplates := []string{
+		"templates/NOTES.txt",
+		"templates/_helpers.tpl",
+		"templates/cronjob.yaml",
+		"templates/hpa.yaml",
+		"templates/service.yaml",
+		"templates/serviceaccount.yaml",
+		"templates/statefulset.yaml",
+		"templates/tests/test-connection.yaml",
+	}
+	var templates []string
+	for _, tpl := range c.Templates {
+		templates = append(templates, tpl.Name)
+	}
+	sort.Strings(templates)
+	if !reflect.DeepEqual(templates, expectedTemplates) {
+		t.Errorf("Expected templates %v, got %v", expectedTemplates, templates)
+	}
+
+	if _, _, err := executeActionCommand("create --without service other"); err == nil || err.Error() != "the ingress template set requires the service template set" {
+		t.Errorf("Expected the missing service to be reported, got %v", err)
+	}
+}
+
 func TestCreateStarterCmd(t *testing.T) {
 	ensure.HelmHome(t)
 	cname := "testchart"
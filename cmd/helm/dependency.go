@@ -19,10 +19,12 @@ import (
 	"io"
 	"path/filepath"
 
+	"github.com/gosuri/uitable"
 	"github.com/spf13/cobra"
 
 	"helm.sh/helm/v3/cmd/helm/require"
 	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/cli/output"
 )
 
 const dependencyDesc = `
@@ -71,6 +73,20 @@ the dependency charts stored locally. The path should start with a prefix of
 If the dependency chart is retrieved locally, it is not required to have the
 repository added to helm by "helm add repo". Version matching is also supported
 for this case.
+
+The repository can also be the URL of a chart in a git repository, with a
+"git+" prefix, the directory of the chart after a double slash and the branch,
+tag or commit to check out. For example,
+
+    # Chart.yaml
+    dependencies:
+    - name: nginx
+      version: "1.2.3"
+      repository: "git+https://example.com/org/charts//nginx?ref=v1.2.3"
+
+The commit the ref points to is recorded in Chart.lock as the digest of the
+dependency, and 'helm dependency build' checks out that commit even if the ref
+is moved.
 `
 
 const dependencyListDesc = `
@@ -80,11 +96,15 @@ This can take chart archives and chart directories as input. It will not alter
 the contents of a chart.
 
 This will produce an error if the chart cannot be loaded.
+
+With '--output json' or '--output yaml', the dependencies are printed with
+their status, without the warnings about the charts of 'charts/' which are not
+declared in Chart.yaml.
 `
 
 func newDependencyCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:     "dependency update|build|list",
+		Use:     "dependency update|build|list|vendor|tree",
 		Aliases: []string{"dep", "dependencies"},
 		Short:   "manage a chart's dependencies",
 		Long:    dependencyDesc,
@@ -94,12 +114,15 @@ func newDependencyCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	cmd.AddCommand(newDependencyListCmd(out))
 	cmd.AddCommand(newDependencyUpdateCmd(cfg, out))
 	cmd.AddCommand(newDependencyBuildCmd(cfg, out))
+	cmd.AddCommand(newDependencyVendorCmd(cfg, out))
+	cmd.AddCommand(newDependencyTreeCmd(out))
 
 	return cmd
 }
 
 func newDependencyListCmd(out io.Writer) *cobra.Command {
 	client := action.NewDependency()
+	var outfmt output.Format
 	cmd := &cobra.Command{
 		Use:     "list CHART",
 		Aliases: []string{"ls"},
@@ -111,12 +134,43 @@ func newDependencyListCmd(out io.Writer) *cobra.Command {
 			if len(args) > 0 {
 				chartpath = filepath.Clean(args[0])
 			}
-			return client.List(chartpath, out)
+			if outfmt == output.Table {
+				return client.List(chartpath, out)
+			}
+			statuses, err := client.Statuses(chartpath)
+			if err != nil {
+				return err
+			}
+			return outfmt.Write(out, &dependencyListWriter{statuses, client.ColumnWidth})
 		},
 	}
 
 	f := cmd.Flags()
 
 	f.UintVar(&client.ColumnWidth, "max-col-width", 80, "maximum column width for output table")
+	bindOutputFlag(cmd, &outfmt)
 	return cmd
 }
+
+type dependencyListWriter struct {
+	statuses    []action.DependencyStatus
+	columnWidth uint
+}
+
+func (w *dependencyListWriter) WriteTable(out io.Writer) error {
+	table := uitable.New()
+	table.MaxColWidth = w.columnWidth
+	table.AddRow("NAME", "VERSION", "REPOSITORY", "STATUS")
+	for _, s := range w.statuses {
+		table.AddRow(s.Name, s.Version, s.Repository, s.Status)
+	}
+	return output.EncodeTable(out, table)
+}
+
+func (w *dependencyListWriter) WriteJSON(out io.Writer) error {
+	return output.EncodeJSON(out, w.statuses)
+}
+
+func (w *dependencyListWriter) WriteYAML(out io.Writer) error {
+	return output.EncodeYAML(out, w.statuses)
+}
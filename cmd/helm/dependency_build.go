@@ -21,11 +21,15 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/gosuri/uitable"
 	"github.com/spf13/cobra"
 	"k8s.io/client-go/util/homedir"
 
 	"helm.sh/helm/v3/cmd/helm/require"
 	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/cli/output"
 	"helm.sh/helm/v3/pkg/downloader"
 	"helm.sh/helm/v3/pkg/getter"
 )
@@ -37,12 +41,37 @@ Build is used to reconstruct a chart's dependencies to the state specified in
 the lock file. This will not re-negotiate dependencies, as 'helm dependency update'
 does.
 
+The lock file records the manifest digests of the dependencies stored in OCI
+registries. Build fails if the tag of such a dependency was moved to other
+content since the lock file was generated.
+
+The lock file also records the checksums of the archives of the dependencies
+downloaded from repositories and OCI registries. Build fails if a downloaded
+archive does not match its checksum, for instance if the archive of a version
+was replaced in its repository.
+
 If no lock file is found, 'helm dependency build' will mirror the behavior
 of 'helm dependency update'.
+
+With --vendored, the dependencies vendored by 'helm dependency vendor' are
+copied from the vendor/ directory instead of being downloaded, without network
+access. Build fails if a dependency of the lock file is not vendored, or if a
+vendored archive does not match its checksum.
+
+With --verify, the provenance file of every dependency downloaded from a
+repository is fetched and verified against the keyring, or the sigstore
+signature of every dependency is verified against the signature policy if
+there is one. Build fails listing all the dependencies which could not be
+verified.
+
+With '--output json' or '--output yaml', the dependencies of the lock file are
+printed once built, and the progress messages are written to the standard
+error.
 `
 
 func newDependencyBuildCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	client := action.NewDependency()
+	var outfmt output.Format
 
 	cmd := &cobra.Command{
 		Use:   "build CHART",
@@ -54,8 +83,12 @@ func newDependencyBuildCmd(cfg *action.Configuration, out io.Writer) *cobra.Comm
 			if len(args) > 0 {
 				chartpath = filepath.Clean(args[0])
 			}
+			progress := out
+			if outfmt != output.Table {
+				progress = os.Stderr
+			}
 			man := &downloader.Manager{
-				Out:              out,
+				Out:              progress,
 				ChartPath:        chartpath,
 				Keyring:          client.Keyring,
 				SkipUpdate:       client.SkipRefresh,
@@ -64,26 +97,75 @@ func newDependencyBuildCmd(cfg *action.Configuration, out io.Writer) *cobra.Comm
 				RepositoryConfig: settings.RepositoryConfig,
 				RepositoryCache:  settings.RepositoryCache,
 				Debug:            settings.Debug,
+				Concurrency:      client.Concurrency,
+				Vendored:         client.Vendored,
 			}
 			if client.Verify {
-				man.Verify = downloader.VerifyIfPossible
+				man.Verify = downloader.VerifyAlways
+				policy, err := action.LoadSignaturePolicy(settings)
+				if err != nil {
+					return err
+				}
+				man.SignaturePolicy = policy
 			}
 			err := man.Build()
 			if e, ok := err.(downloader.ErrRepoNotFound); ok {
 				return fmt.Errorf("%s. Please add the missing repos via 'helm repo add'", e.Error())
 			}
-			return err
+			if err != nil || outfmt == output.Table {
+				return err
+			}
+			return writeLockedDependencies(out, outfmt, chartpath)
 		},
 	}
 
 	f := cmd.Flags()
 	f.BoolVar(&client.Verify, "verify", false, "verify the packages against signatures")
-	f.StringVar(&client.Keyring, "keyring", defaultKeyring(), "keyring containing public keys")
+	f.StringVar(&client.Keyring, "keyring", defaultKeyring(), "keyring containing public keys, or a list of keyrings and directories of keyrings")
 	f.BoolVar(&client.SkipRefresh, "skip-refresh", false, "do not refresh the local repository cache")
+	f.IntVar(&client.Concurrency, "concurrency", downloader.DefaultConcurrency, "maximum number of dependencies downloaded concurrently")
+	f.BoolVar(&client.Vendored, "vendored", false, "build the dependencies from the vendor/ directory, without network access")
+	bindOutputFlag(cmd, &outfmt)
 
 	return cmd
 }
 
+// writeLockedDependencies writes the dependencies of the lock file of the
+// chart at chartpath in the given format.
+func writeLockedDependencies(out io.Writer, outfmt output.Format, chartpath string) error {
+	c, err := loader.Load(chartpath)
+	if err != nil {
+		return err
+	}
+	w := &dependencyLockWriter{deps: []*chart.Dependency{}}
+	if c.Lock != nil {
+		w.deps = append(w.deps, c.Lock.Dependencies...)
+	}
+	return outfmt.Write(out, w)
+}
+
+// dependencyLockWriter writes the locked dependencies of a chart.
+type dependencyLockWriter struct {
+	deps []*chart.Dependency
+}
+
+func (w *dependencyLockWriter) WriteTable(out io.Writer) error {
+	table := uitable.New()
+	table.AddRow("NAME", "VERSION", "REPOSITORY")
+	for _, d := range w.deps {
+		table.AddRow(d.Name, d.Version, d.Repository)
+	}
+	return output.EncodeTable(out, table)
+}
+
+func (w *dependencyLockWriter) WriteJSON(out io.Writer) error {
+	return output.EncodeJSON(out, w.deps)
+}
+
+func (w *dependencyLockWriter) WriteYAML(out io.Writer) error {
+	return output.EncodeYAML(out, w.deps)
+}
+
 // defaultKeyring returns the expanded path to the default keyring.
 func defaultKeyring() string {
 	if v, ok := os.LookupEnv("GNUPGHOME"); ok {
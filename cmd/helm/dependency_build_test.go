@@ -16,12 +16,14 @@ limitations under the License.
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/provenance"
 	"helm.sh/helm/v3/pkg/repo"
@@ -130,6 +132,20 @@ func TestDependencyBuildCmd(t *testing.T) {
 		t.Errorf("Repo did get updated\n%s", out)
 	}
 
+	// The locked dependencies are printed with a structured output.
+	_, out, err = executeActionCommand(skipRefreshCmd + " -o json")
+	if err != nil {
+		t.Logf("Output: %s", out)
+		t.Fatal(err)
+	}
+	var deps []chart.Dependency
+	if err := json.Unmarshal([]byte(out), &deps); err != nil {
+		t.Fatalf("expected JSON output, got %q: %v", out, err)
+	}
+	if len(deps) != 2 || deps[0].Name != "reqtest" || deps[0].Version != "0.1.0" || deps[0].Checksum == "" {
+		t.Errorf("expected the locked reqtest 0.1.0 dependency first, got %+v", deps)
+	}
+
 	// OCI dependencies
 	if err := chartutil.SaveDir(c, dir()); err != nil {
 		t.Fatal(err)
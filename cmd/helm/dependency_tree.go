@@ -0,0 +1,128 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/cli/output"
+)
+
+const dependencyTreeDesc = `
+Print the dependency tree of a chart, with the dependencies of its
+dependencies.
+
+Every dependency is printed with the version found in the 'charts/' directory of
+its parent, or as missing with its version constraint, the repository it comes
+from, the condition and the tags enabling it, and the path of its values in the
+values of the chart. For example, the values of the 'metrics' dependency of the
+'mysql' dependency of a chart are set under 'mysql.metrics'.
+
+The charts found in a 'charts/' directory without being declared in the
+'Chart.yaml' file of their parent are printed without repository.
+
+This can take chart archives and chart directories as input. It will not alter
+the contents of a chart.
+`
+
+func newDependencyTreeCmd(out io.Writer) *cobra.Command {
+	client := action.NewDependency()
+	var outfmt output.Format
+
+	cmd := &cobra.Command{
+		Use:   "tree CHART",
+		Short: "print the dependency tree of the given chart",
+		Long:  dependencyTreeDesc,
+		Args:  require.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			chartpath := "."
+			if len(args) > 0 {
+				chartpath = filepath.Clean(args[0])
+			}
+			tree, err := client.Tree(chartpath)
+			if err != nil {
+				return err
+			}
+			return outfmt.Write(out, &dependencyTreeWriter{tree})
+		},
+	}
+
+	bindOutputFlag(cmd, &outfmt)
+
+	return cmd
+}
+
+type dependencyTreeWriter struct {
+	tree *action.DependencyNode
+}
+
+func (w *dependencyTreeWriter) WriteTable(out io.Writer) error {
+	fmt.Fprintf(out, "%s %s\n", w.tree.Name, w.tree.Version)
+	writeDependencyNodes(out, w.tree.Dependencies, "")
+	return nil
+}
+
+func (w *dependencyTreeWriter) WriteJSON(out io.Writer) error {
+	return output.EncodeJSON(out, w.tree)
+}
+
+func (w *dependencyTreeWriter) WriteYAML(out io.Writer) error {
+	return output.EncodeYAML(out, w.tree)
+}
+
+// writeDependencyNodes writes the nodes, each one on a line starting with the
+// prefix, followed by their dependencies.
+func writeDependencyNodes(out io.Writer, nodes []*action.DependencyNode, prefix string) {
+	for i, node := range nodes {
+		branch, indent := "├── ", "│   "
+		if i == len(nodes)-1 {
+			branch, indent = "└── ", "    "
+		}
+
+		name := node.Name
+		if node.Alias != "" {
+			name = fmt.Sprintf("%s (alias of %s)", node.Alias, node.Name)
+		}
+		var details []string
+		if node.Missing {
+			name += " " + node.Constraint
+			details = append(details, "missing")
+		} else {
+			name += " " + node.Version
+		}
+		if node.Repository != "" {
+			details = append(details, "repository: "+node.Repository)
+		}
+		if node.Condition != "" {
+			details = append(details, "condition: "+node.Condition)
+		}
+		if len(node.Tags) > 0 {
+			details = append(details, "tags: "+strings.Join(node.Tags, ","))
+		}
+		details = append(details, "values: "+node.ValuesPath)
+
+		fmt.Fprintf(out, "%s%s%s (%s)\n", prefix, branch, strings.TrimSpace(name), strings.Join(details, ", "))
+		writeDependencyNodes(out, node.Dependencies, prefix+indent)
+	}
+}
@@ -0,0 +1,46 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+)
+
+func TestDependencyTreeCmd(t *testing.T) {
+	tests := []cmdTestCase{{
+		name:   "Dependencies with conditions and tags",
+		cmd:    "dependency tree testdata/testcharts/subchart",
+		golden: "output/dependency-tree.txt",
+	}, {
+		name:   "Dependencies with aliases",
+		cmd:    "dependency tree testdata/testcharts/chart-with-subcharts-lint",
+		golden: "output/dependency-tree-alias.txt",
+	}, {
+		name:   "Missing dependencies",
+		cmd:    "dependency tree testdata/testcharts/chart-missing-deps",
+		golden: "output/dependency-tree-missing.txt",
+	}, {
+		name:   "Dependencies in JSON",
+		cmd:    "dependency tree testdata/testcharts/chart-with-subcharts-lint -o json",
+		golden: "output/dependency-tree-json.txt",
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestDependencyTreeFileCompletion(t *testing.T) {
+	checkFileCompletion(t, "dependency tree", true)
+}
@@ -17,14 +17,17 @@ package main
 
 import (
 	"io"
+	"os"
 	"path/filepath"
 
 	"github.com/spf13/cobra"
 
 	"helm.sh/helm/v3/cmd/helm/require"
 	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/cli/output"
 	"helm.sh/helm/v3/pkg/downloader"
 	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/repo"
 )
 
 const dependencyUpDesc = `
@@ -40,11 +43,35 @@ rebuild the dependencies to an exact version.
 Dependencies are not required to be represented in 'Chart.yaml'. For that
 reason, an update command will not remove charts unless they are (a) present
 in the Chart.yaml file, but (b) at the wrong version.
+
+The repository of a dependency can be '@*', for the chart to be resolved from
+any of the configured repositories. The repositories are considered by
+descending priority, set by 'helm repo add --priority', and the repository
+serving the chart is chosen by the resolution policy:
+
+    - first-match: the first repository serving a matching version (default)
+    - highest-version: the repository serving the highest matching version
+    - error-on-ambiguity: fail if several repositories of the highest priority
+      serve a matching version
+
+The policy is set by --resolution-policy, or by 'resolutionPolicy' in the
+repositories file. The chosen repository is recorded in the lock file.
+
+With --verify, the provenance file of every dependency downloaded from a
+repository is fetched and verified against the keyring, or the sigstore
+signature of every dependency is verified against the signature policy if
+there is one. Update fails listing all the dependencies which could not be
+verified.
+
+With '--output json' or '--output yaml', the dependencies of the generated lock
+file are printed once updated, and the progress messages are written to the
+standard error.
 `
 
 // newDependencyUpdateCmd creates a new dependency update command.
 func newDependencyUpdateCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	client := action.NewDependency()
+	var outfmt output.Format
 
 	cmd := &cobra.Command{
 		Use:     "update CHART",
@@ -57,8 +84,12 @@ func newDependencyUpdateCmd(cfg *action.Configuration, out io.Writer) *cobra.Com
 			if len(args) > 0 {
 				chartpath = filepath.Clean(args[0])
 			}
+			progress := out
+			if outfmt != output.Table {
+				progress = os.Stderr
+			}
 			man := &downloader.Manager{
-				Out:              out,
+				Out:              progress,
 				ChartPath:        chartpath,
 				Keyring:          client.Keyring,
 				SkipUpdate:       client.SkipRefresh,
@@ -67,18 +98,34 @@ func newDependencyUpdateCmd(cfg *action.Configuration, out io.Writer) *cobra.Com
 				RepositoryConfig: settings.RepositoryConfig,
 				RepositoryCache:  settings.RepositoryCache,
 				Debug:            settings.Debug,
+				Concurrency:      client.Concurrency,
+				ResolutionPolicy: repo.ResolutionPolicy(client.ResolutionPolicy),
+			}
+			if err := man.ResolutionPolicy.Validate(); err != nil {
+				return err
 			}
 			if client.Verify {
 				man.Verify = downloader.VerifyAlways
+				policy, err := action.LoadSignaturePolicy(settings)
+				if err != nil {
+					return err
+				}
+				man.SignaturePolicy = policy
+			}
+			if err := man.Update(); err != nil || outfmt == output.Table {
+				return err
 			}
-			return man.Update()
+			return writeLockedDependencies(out, outfmt, chartpath)
 		},
 	}
 
 	f := cmd.Flags()
 	f.BoolVar(&client.Verify, "verify", false, "verify the packages against signatures")
-	f.StringVar(&client.Keyring, "keyring", defaultKeyring(), "keyring containing public keys")
+	f.StringVar(&client.Keyring, "keyring", defaultKeyring(), "keyring containing public keys, or a list of keyrings and directories of keyrings")
 	f.BoolVar(&client.SkipRefresh, "skip-refresh", false, "do not refresh the local repository cache")
+	f.IntVar(&client.Concurrency, "concurrency", downloader.DefaultConcurrency, "maximum number of dependencies downloaded concurrently")
+	f.StringVar(&client.ResolutionPolicy, "resolution-policy", "", "policy choosing among the repositories serving a chart: first-match, highest-version or error-on-ambiguity")
+	bindOutputFlag(cmd, &outfmt)
 
 	return cmd
 }
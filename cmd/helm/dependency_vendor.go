@@ -0,0 +1,101 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/downloader"
+	"helm.sh/helm/v3/pkg/getter"
+)
+
+const dependencyVendorDesc = `
+Vendor the dependencies of a chart into its vendor/ directory.
+
+Like 'helm dependency build', this builds the charts/ directory from the
+Chart.lock file. The archives of the dependencies downloaded from repositories,
+OCI registries and git repositories are then copied into the vendor/ directory,
+with a vendor.lock manifest of their versions and checksums. Vendored archives
+of dependencies no longer in the lock file are deleted.
+
+The vendor/ directory can be committed with the chart, so that its dependencies
+are built without network access by 'helm dependency build --vendored'. To
+keep the vendored archives out of the packages of the chart, add 'vendor/' to
+its .helmignore file.
+
+With --verify, the provenance file of every dependency downloaded from a
+repository is fetched and verified against the keyring, or the sigstore
+signature of every dependency is verified against the signature policy if
+there is one. Vendoring fails listing all the dependencies which could not be
+verified.
+`
+
+func newDependencyVendorCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
+	client := action.NewDependency()
+
+	cmd := &cobra.Command{
+		Use:   "vendor CHART",
+		Short: "copy the dependencies into the vendor/ directory for builds without network access",
+		Long:  dependencyVendorDesc,
+		Args:  require.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			chartpath := "."
+			if len(args) > 0 {
+				chartpath = filepath.Clean(args[0])
+			}
+			man := &downloader.Manager{
+				Out:              out,
+				ChartPath:        chartpath,
+				Keyring:          client.Keyring,
+				SkipUpdate:       client.SkipRefresh,
+				Getters:          getter.All(settings),
+				RegistryClient:   cfg.RegistryClient,
+				RepositoryConfig: settings.RepositoryConfig,
+				RepositoryCache:  settings.RepositoryCache,
+				Debug:            settings.Debug,
+				Concurrency:      client.Concurrency,
+			}
+			if client.Verify {
+				man.Verify = downloader.VerifyAlways
+				policy, err := action.LoadSignaturePolicy(settings)
+				if err != nil {
+					return err
+				}
+				man.SignaturePolicy = policy
+			}
+			err := man.Vendor()
+			if e, ok := err.(downloader.ErrRepoNotFound); ok {
+				return fmt.Errorf("%s. Please add the missing repos via 'helm repo add'", e.Error())
+			}
+			return err
+		},
+	}
+
+	f := cmd.Flags()
+	f.BoolVar(&client.Verify, "verify", false, "verify the packages against signatures")
+	f.StringVar(&client.Keyring, "keyring", defaultKeyring(), "keyring containing public keys, or a list of keyrings and directories of keyrings")
+	f.BoolVar(&client.SkipRefresh, "skip-refresh", false, "do not refresh the local repository cache")
+	f.IntVar(&client.Concurrency, "concurrency", downloader.DefaultConcurrency, "maximum number of dependencies downloaded concurrently")
+
+	return cmd
+}
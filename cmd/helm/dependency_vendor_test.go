@@ -0,0 +1,74 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/repo/repotest"
+)
+
+func TestDependencyVendorCmd(t *testing.T) {
+	srv, err := repotest.NewTempServerWithCleanup(t, "testdata/testcharts/*.tgz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+	if err := srv.LinkIndices(); err != nil {
+		t.Fatal(err)
+	}
+
+	rootDir := srv.Root()
+	chartname := "depvendor"
+	createTestingChart(t, rootDir, chartname, srv.URL())
+	chartpath := filepath.Join(rootDir, chartname)
+	repoFile := filepath.Join(rootDir, "repositories.yaml")
+
+	cmd := fmt.Sprintf("dependency vendor '%s' --repository-config %s --repository-cache %s", chartpath, repoFile, rootDir)
+	_, out, err := executeActionCommand(cmd)
+	if err != nil {
+		t.Logf("Output: %s", out)
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Vendored reqtest version 0.1.0") {
+		t.Errorf("unexpected output: %s", out)
+	}
+	for _, f := range []string{"reqtest-0.1.0.tgz", "vendor.lock"} {
+		if _, err := os.Stat(filepath.Join(chartpath, "vendor", f)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The vendored dependencies are built without the repository
+	srv.Stop()
+	if err := os.RemoveAll(filepath.Join(chartpath, "charts")); err != nil {
+		t.Fatal(err)
+	}
+	cmd = fmt.Sprintf("dependency build '%s' --vendored --repository-config %s --repository-cache %s", chartpath, repoFile, rootDir)
+	_, out, err = executeActionCommand(cmd)
+	if err != nil {
+		t.Logf("Output: %s", out)
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(chartpath, "charts", "reqtest-0.1.0.tgz")); err != nil {
+		t.Fatal(err)
+	}
+}
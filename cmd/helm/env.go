@@ -21,16 +21,27 @@ import (
 	"io"
 	"sort"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/cli/output"
 )
 
 var envHelp = `
 Env prints out all the environment information in use by Helm.
+
+With '--output json' or '--output yaml', the settings are printed as a
+structured document instead of environment variables, for the tools wrapping
+Helm. With '--validate', the settings are checked first: the command fails if
+a setting is invalid, like a kubeconfig context that does not exist.
 `
 
 func newEnvCmd(out io.Writer) *cobra.Command {
+	var outfmt output.Format
+	var validate bool
+
 	cmd := &cobra.Command{
 		Use:   "env",
 		Short: "helm client environment information",
@@ -44,25 +55,57 @@ func newEnvCmd(out io.Writer) *cobra.Command {
 
 			return noMoreArgsComp()
 		},
-		Run: func(_ *cobra.Command, args []string) {
-			envVars := settings.EnvVars()
-
-			if len(args) == 0 {
-				// Sort the variables by alphabetical order.
-				// This allows for a constant output across calls to 'helm env'.
-				keys := getSortedEnvVarKeys()
-
-				for _, k := range keys {
-					fmt.Fprintf(out, "%s=\"%s\"\n", k, envVars[k])
+		RunE: func(_ *cobra.Command, args []string) error {
+			if validate {
+				if err := settings.Validate(); err != nil {
+					return errors.Wrap(err, "invalid settings")
 				}
-			} else {
-				fmt.Fprintf(out, "%s\n", envVars[args[0]])
 			}
+			if len(args) == 0 {
+				return outfmt.Write(out, &envWriter{settings})
+			}
+			if outfmt != output.Table {
+				return errors.New("the output format cannot be set when printing a single variable")
+			}
+			fmt.Fprintf(out, "%s\n", settings.EnvVars()[args[0]])
+			return nil
 		},
 	}
+
+	cmd.Flags().BoolVar(&validate, "validate", false, "check that the settings are valid")
+	bindOutputFlag(cmd, &outfmt)
 	return cmd
 }
 
+type envWriter struct {
+	settings *cli.EnvSettings
+}
+
+func (w *envWriter) WriteTable(out io.Writer) error {
+	envVars := w.settings.EnvVars()
+
+	// Sort the variables by alphabetical order.
+	// This allows for a constant output across calls to 'helm env'.
+	keys := make([]string, 0, len(envVars))
+	for k := range envVars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		fmt.Fprintf(out, "%s=\"%s\"\n", k, envVars[k])
+	}
+	return nil
+}
+
+func (w *envWriter) WriteJSON(out io.Writer) error {
+	return output.EncodeJSON(out, w.settings)
+}
+
+func (w *envWriter) WriteYAML(out io.Writer) error {
+	return output.EncodeYAML(out, w.settings)
+}
+
 func getSortedEnvVarKeys() []string {
 	envVars := settings.EnvVars()
 
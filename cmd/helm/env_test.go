@@ -17,6 +17,8 @@ limitations under the License.
 package main
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 )
 
@@ -33,3 +35,37 @@ func TestEnvFileCompletion(t *testing.T) {
 	checkFileCompletion(t, "env", false)
 	checkFileCompletion(t, "env HELM_BIN", false)
 }
+
+func TestEnvOutput(t *testing.T) {
+	defer resetEnv()()
+
+	_, out, err := executeActionCommand("env --output json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var env map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &env); err != nil {
+		t.Fatalf("expected JSON, got %s: %s", out, err)
+	}
+	for _, key := range []string{"namespace", "repositoryConfig", "pluginsDirectory", "maxHistory"} {
+		if _, ok := env[key]; !ok {
+			t.Errorf("expected %q in the settings, got %s", key, out)
+		}
+	}
+
+	_, out, err = executeActionCommand("env --output yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "repositoryConfig: ") {
+		t.Errorf("expected the settings in YAML, got %s", out)
+	}
+
+	if _, _, err := executeActionCommand("env HELM_BIN --output json"); err == nil {
+		t.Error("expected an error setting the output format of a single variable")
+	}
+
+	if _, _, err := executeActionCommand("env --validate --kube-context helm-test-missing-context"); err == nil {
+		t.Error("expected an error validating a missing kubeconfig context")
+	}
+}
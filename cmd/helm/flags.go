@@ -17,33 +17,46 @@ limitations under the License.
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
+	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"k8s.io/klog/v2"
 
 	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/cli/output"
 	"helm.sh/helm/v3/pkg/cli/values"
 	"helm.sh/helm/v3/pkg/helmpath"
+	"helm.sh/helm/v3/pkg/plugin"
+	"helm.sh/helm/v3/pkg/plugin/sdk"
 	"helm.sh/helm/v3/pkg/postrender"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/renderhook"
 	"helm.sh/helm/v3/pkg/repo"
 )
 
 const (
-	outputFlag         = "output"
-	postRenderFlag     = "post-renderer"
-	postRenderArgsFlag = "post-renderer-args"
+	outputFlag           = "output"
+	postRenderFlag       = "post-renderer"
+	postRenderArgsFlag   = "post-renderer-args"
+	postRenderProtoFlag  = "post-renderer-protocol"
+	postRenderFilterFlag = "post-renderer-filter"
+	renderHookFlag       = "render-hook"
 )
 
 func addValueOptionsFlags(f *pflag.FlagSet, v *values.Options) {
-	f.StringSliceVarP(&v.ValueFiles, "values", "f", []string{}, "specify values in a YAML file or a URL (can specify multiple)")
+	f.StringSliceVarP(&v.ValueFiles, "values", "f", []string{}, "specify values in a YAML file, a URL or a key of a ConfigMap or a Secret like configmap://namespace/name/key (can specify multiple)")
 	f.StringArrayVar(&v.Values, "set", []string{}, "set values on the command line (can specify multiple or separate values with commas: key1=val1,key2=val2)")
 	f.StringArrayVar(&v.StringValues, "set-string", []string{}, "set STRING values on the command line (can specify multiple or separate values with commas: key1=val1,key2=val2)")
 	f.StringArrayVar(&v.FileValues, "set-file", []string{}, "set values from respective files specified via the command line (can specify multiple or separate values with commas: key1=path1,key2=path2)")
@@ -54,7 +67,7 @@ func addValueOptionsFlags(f *pflag.FlagSet, v *values.Options) {
 func addChartPathOptionsFlags(f *pflag.FlagSet, c *action.ChartPathOptions) {
 	f.StringVar(&c.Version, "version", "", "specify a version constraint for the chart version to use. This constraint can be a specific tag (e.g. 1.1.1) or it may reference a valid range (e.g. ^2.0.0). If this is not specified, the latest version is used")
 	f.BoolVar(&c.Verify, "verify", false, "verify the package before using it")
-	f.StringVar(&c.Keyring, "keyring", defaultKeyring(), "location of public keys used for verification")
+	f.StringVar(&c.Keyring, "keyring", defaultKeyring(), "location of public keys used for verification, or a list of keyrings and directories of keyrings")
 	f.StringVar(&c.RepoURL, "repo", "", "chart repository url where to locate the requested chart")
 	f.StringVar(&c.Username, "username", "", "chart repository username where to locate the requested chart")
 	f.StringVar(&c.Password, "password", "", "chart repository password where to locate the requested chart")
@@ -66,11 +79,20 @@ func addChartPathOptionsFlags(f *pflag.FlagSet, c *action.ChartPathOptions) {
 	f.BoolVar(&c.PassCredentialsAll, "pass-credentials", false, "pass credentials to all domains")
 }
 
+// addCosignSignFlags adds the flags configuring sigstore signatures, except
+// the passphrase file which package shares with PGP signatures.
+func addCosignSignFlags(f *pflag.FlagSet, o *action.CosignSignOptions) {
+	f.StringVar(&o.Key, "cosign-key", "", "path to the cosign private key signing the chart. The chart is signed keyless if it is not set")
+	f.StringVar(&o.IdentityToken, "identity-token", "", "OIDC identity token of keyless signatures. Defaults to $SIGSTORE_ID_TOKEN")
+	f.StringVar(&o.FulcioURL, "fulcio-url", registry.DefaultFulcioURL, "URL of the certificate authority issuing the certificates of keyless signatures")
+	f.StringVar(&o.RekorURL, "rekor-url", registry.DefaultRekorURL, "URL of the transparency log recording keyless signatures")
+}
+
 // bindOutputFlag will add the output flag to the given command and bind the
 // value to the given format pointer
 func bindOutputFlag(cmd *cobra.Command, varRef *output.Format) {
 	cmd.Flags().VarP(newOutputValue(output.Table, varRef), outputFlag, "o",
-		fmt.Sprintf("prints the output in the specified format. Allowed values: %s", strings.Join(output.Formats(), ", ")))
+		fmt.Sprintf("prints the output in the specified format. Allowed values: %s, go-template=<template> (executed on the JSON output)", strings.Join(output.Formats(), ", ")))
 
 	err := cmd.RegisterFlagCompletionFunc(outputFlag, func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
 		var formatNames []string
@@ -116,15 +138,94 @@ func (o *outputValue) Set(s string) error {
 }
 
 func bindPostRenderFlag(cmd *cobra.Command, varRef *postrender.PostRenderer) {
-	p := &postRendererOptions{varRef, "", []string{}}
-	cmd.Flags().Var(&postRendererString{p}, postRenderFlag, "the path to an executable to be used for post rendering. If it exists in $PATH, the binary will be used, otherwise it will try to look for the executable at the given path")
-	cmd.Flags().Var(&postRendererArgsSlice{p}, postRenderArgsFlag, "an argument to the post-renderer (can specify multiple)")
+	p := &postRendererOptions{renderer: varRef}
+	cmd.Flags().Var(&postRendererString{p}, postRenderFlag, "the path to an executable to be used for post rendering. If it exists in $PATH, the binary will be used, otherwise it will try to look for the executable at the given path. Use \"builtin-kustomize\" to apply a kustomization configured by the post-renderer arguments, like dir=./overlay. The post-renderers given several times are run in order, each one on the output of the previous one")
+	cmd.Flags().Var(&postRendererArgsSlice{p}, postRenderArgsFlag, "an argument to the last post-renderer given before it, or to the first one (can specify multiple)")
+	cmd.Flags().Var(&postRendererFilterSlice{p}, postRenderFilterFlag, "a filter of the manifests passed to the last post-renderer given before it, or to the first one, like kind=Deployment, name=web-* or label=app=web. The other manifests are passed through unchanged (can specify multiple)")
+	cmd.Flags().Var(&postRendererProtocol{p}, postRenderProtoFlag, "the version of the post-renderer protocol of the last post-renderer given before it, or of the first one: 1 for YAML, 2 for JSON manifests with the release metadata")
+}
+
+// postRenderRequest returns the request of the plugin API passed to the
+// post-renderers, of the newest version of the API.
+func postRenderRequest() *sdk.Request {
+	return plugin.NewRequest(settings, sdk.SupportedAPIVersions[0], sdk.KindPostRenderer, sdk.Plugin{})
 }
 
 type postRendererOptions struct {
-	renderer   *postrender.PostRenderer
-	binaryPath string
-	args       []string
+	renderer *postrender.PostRenderer
+	stages   []postRendererStage
+	// first are the options given before the first post-renderer, which
+	// are the options of the first post-renderer
+	first postRendererStage
+}
+
+// postRendererStage is a post-renderer of the chain, with the options given
+// after it.
+type postRendererStage struct {
+	binaryPath      string
+	args            []string
+	filters         []string
+	protocolVersion int
+}
+
+// last returns the last post-renderer.
+func (o *postRendererOptions) last() *postRendererStage {
+	if len(o.stages) == 0 {
+		return &o.first
+	}
+	return &o.stages[len(o.stages)-1]
+}
+
+// lastArgs returns the arguments of the last post-renderer.
+func (o *postRendererOptions) lastArgs() *[]string {
+	return &o.last().args
+}
+
+// build sets the renderer to the post-renderers of the options, chained if
+// there are several.
+func (o *postRendererOptions) build() error {
+	if len(o.stages) == 0 {
+		return nil
+	}
+	var chain postrender.Chain
+	for _, stage := range o.stages {
+		pr, err := newPostRenderer(stage)
+		if err != nil {
+			return err
+		}
+		chain = append(chain, pr)
+	}
+	if len(chain) == 1 {
+		*o.renderer = chain[0]
+	} else {
+		*o.renderer = chain
+	}
+	return nil
+}
+
+// newPostRenderer returns the registered post-renderer named like the binary
+// of the stage, like the built-in kustomize post-renderer, or the
+// post-renderer running the binary.
+func newPostRenderer(stage postRendererStage) (postrender.PostRenderer, error) {
+	var pr postrender.PostRenderer
+	var err error
+	if postrender.IsRegistered(stage.binaryPath) {
+		pr, err = postrender.New(stage.binaryPath, stage.args...)
+	} else {
+		protocolVersion := stage.protocolVersion
+		if protocolVersion == 0 {
+			protocolVersion = sdk.PostRenderProtocolV1
+		}
+		pr, err = postrender.NewExecWithProtocol(stage.binaryPath, protocolVersion, postRenderRequest, stage.args...)
+	}
+	if err != nil || len(stage.filters) == 0 {
+		return pr, err
+	}
+	selector, err := postrender.ParseSelector(stage.filters...)
+	if err != nil {
+		return nil, err
+	}
+	return postrender.NewFilter(pr, selector), nil
 }
 
 type postRendererString struct {
@@ -132,7 +233,11 @@ type postRendererString struct {
 }
 
 func (p *postRendererString) String() string {
-	return p.options.binaryPath
+	var paths []string
+	for _, stage := range p.options.stages {
+		paths = append(paths, stage.binaryPath)
+	}
+	return strings.Join(paths, ",")
 }
 
 func (p *postRendererString) Type() string {
@@ -143,13 +248,13 @@ func (p *postRendererString) Set(val string) error {
 	if val == "" {
 		return nil
 	}
-	p.options.binaryPath = val
-	pr, err := postrender.NewExec(p.options.binaryPath, p.options.args...)
-	if err != nil {
-		return err
+	var stage postRendererStage
+	if len(p.options.stages) == 0 {
+		stage, p.options.first = p.options.first, postRendererStage{}
 	}
-	*p.options.renderer = pr
-	return nil
+	stage.binaryPath = val
+	p.options.stages = append(p.options.stages, stage)
+	return p.options.build()
 }
 
 type postRendererArgsSlice struct {
@@ -157,7 +262,7 @@ type postRendererArgsSlice struct {
 }
 
 func (p *postRendererArgsSlice) String() string {
-	return "[" + strings.Join(p.options.args, ",") + "]"
+	return "[" + strings.Join(*p.options.lastArgs(), ",") + "]"
 }
 
 func (p *postRendererArgsSlice) Type() string {
@@ -167,32 +272,147 @@ func (p *postRendererArgsSlice) Type() string {
 func (p *postRendererArgsSlice) Set(val string) error {
 
 	// a post-renderer defined by a user may accept empty arguments
-	p.options.args = append(p.options.args, val)
+	args := p.options.lastArgs()
+	*args = append(*args, val)
 
-	if p.options.binaryPath == "" {
-		return nil
-	}
 	// overwrite if already create PostRenderer by `post-renderer` flags
-	pr, err := postrender.NewExec(p.options.binaryPath, p.options.args...)
-	if err != nil {
-		return err
-	}
-	*p.options.renderer = pr
-	return nil
+	return p.options.build()
 }
 
 func (p *postRendererArgsSlice) Append(val string) error {
-	p.options.args = append(p.options.args, val)
+	args := p.options.lastArgs()
+	*args = append(*args, val)
 	return nil
 }
 
 func (p *postRendererArgsSlice) Replace(val []string) error {
-	p.options.args = val
+	*p.options.lastArgs() = val
 	return nil
 }
 
 func (p *postRendererArgsSlice) GetSlice() []string {
-	return p.options.args
+	return *p.options.lastArgs()
+}
+
+type postRendererFilterSlice struct {
+	options *postRendererOptions
+}
+
+func (p *postRendererFilterSlice) String() string {
+	return "[" + strings.Join(p.options.last().filters, ",") + "]"
+}
+
+func (p *postRendererFilterSlice) Type() string {
+	return "stringArray"
+}
+
+func (p *postRendererFilterSlice) Set(val string) error {
+	stage := p.options.last()
+	stage.filters = append(stage.filters, val)
+	return p.options.build()
+}
+
+type postRendererProtocol struct {
+	options *postRendererOptions
+}
+
+func (p *postRendererProtocol) String() string {
+	if v := p.options.last().protocolVersion; v != 0 {
+		return strconv.Itoa(v)
+	}
+	return ""
+}
+
+func (p *postRendererProtocol) Type() string {
+	return "int"
+}
+
+func (p *postRendererProtocol) Set(val string) error {
+	v, err := strconv.Atoi(val)
+	if err != nil {
+		return err
+	}
+	p.options.last().protocolVersion = v
+	return p.options.build()
+}
+
+func bindRenderHookFlag(cmd *cobra.Command, varRef *[]renderhook.Hook) {
+	cmd.Flags().Var(&renderHookSlice{hooks: varRef}, renderHookFlag, "the name of an installed render hook plugin, run in process on the rendered manifests before the post-renderer (can specify multiple)")
+}
+
+// renderHookSlice is the flag of the render hook plugins, by name.
+type renderHookSlice struct {
+	names []string
+	hooks *[]renderhook.Hook
+}
+
+func (r *renderHookSlice) String() string {
+	return "[" + strings.Join(r.names, ",") + "]"
+}
+
+func (r *renderHookSlice) Type() string {
+	return "renderHookSlice"
+}
+
+func (r *renderHookSlice) Set(val string) error {
+	plugins, err := plugin.FindPlugins(settings.PluginsDirectory)
+	if err != nil {
+		return err
+	}
+	for _, p := range plugins {
+		if p.Metadata.Name != val {
+			continue
+		}
+		h, err := renderhook.NewPlugin(p, pluginHost{})
+		if err != nil {
+			return err
+		}
+		r.names = append(r.names, val)
+		*r.hooks = append(*r.hooks, h)
+		return nil
+	}
+	return fmt.Errorf("render hook plugin %q not found", val)
+}
+
+// addKindTimeoutsFlag adds the flag used to override the wait timeout for
+// resources of specific kinds.
+func addKindTimeoutsFlag(f *pflag.FlagSet, varRef *map[string]time.Duration) {
+	f.Var(&kindTimeoutsValue{varRef}, "kind-timeout", "override the --timeout used by --wait for resources of a kind (can specify multiple or separate values with commas: StatefulSet=30m,Job=10m). Resources annotated with helm.sh/wait-timeout use their own timeout")
+}
+
+type kindTimeoutsValue struct {
+	timeouts *map[string]time.Duration
+}
+
+func (k *kindTimeoutsValue) String() string {
+	var parts []string
+	for kind, d := range *k.timeouts {
+		parts = append(parts, kind+"="+d.String())
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+func (k *kindTimeoutsValue) Type() string {
+	return "kindTimeouts"
+}
+
+func (k *kindTimeoutsValue) Set(val string) error {
+	if *k.timeouts == nil {
+		*k.timeouts = map[string]time.Duration{}
+	}
+	for _, pair := range strings.Split(val, ",") {
+		kind, dur, ok := strings.Cut(pair, "=")
+		if !ok || kind == "" {
+			return fmt.Errorf("%q must be formatted as KIND=DURATION", pair)
+		}
+		d, err := time.ParseDuration(dur)
+		if err != nil {
+			return fmt.Errorf("invalid timeout for kind %q: %w", kind, err)
+		}
+		(*k.timeouts)[kind] = d
+	}
+	return nil
 }
 
 func compVersionFlag(chartRef string, _ string) ([]string, cobra.ShellCompDirective) {
@@ -227,9 +447,133 @@ func compVersionFlag(chartRef string, _ string) ([]string, cobra.ShellCompDirect
 		}
 	}
 
+	if registry.IsOCI(chartRef) {
+		if registryClient, err := newDefaultRegistryClient(false); err == nil {
+			if tags, err := registryClient.Tags(strings.TrimPrefix(chartRef, fmt.Sprintf("%s://", registry.OCIScheme))); err == nil {
+				versions = append(versions, tags...)
+			}
+		}
+	}
+
 	return versions, cobra.ShellCompDirectiveNoFileComp
 }
 
+// valuesFlags are the flags setting values on the command line.
+var valuesFlags = []string{"set", "set-string", "set-file", "set-json", "set-literal"}
+
+// registerValuesCompletion registers the completion of the values flags of
+// cmd with the value paths of the chart returned by chartRef, if any.
+func registerValuesCompletion(cmd *cobra.Command, chartRef func(args []string) (string, bool)) {
+	for _, name := range valuesFlags {
+		err := cmd.RegisterFlagCompletionFunc(name, func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			ref, ok := chartRef(args)
+			if !ok {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return compValuesFlag(ref, toComplete)
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// compValuesFlag completes the last key of a values flag, like "a=1,b.c",
+// with the dotted paths of the values and of the values schema of the chart
+// at chartRef. Only the local charts are completed.
+func compValuesFlag(chartRef string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	prefix, key := "", toComplete
+	if i := strings.LastIndex(toComplete, ","); i >= 0 {
+		prefix, key = toComplete[:i+1], toComplete[i+1:]
+	}
+	if strings.Contains(key, "=") {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	if _, err := os.Stat(chartRef); err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	chrt, err := loader.Load(chartRef)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	paths := make(map[string]string)
+	chartValuePaths(chrt, "", paths)
+	var completions []string
+	for p, desc := range paths {
+		if !strings.HasPrefix(p, key) {
+			continue
+		}
+		completion := prefix + p + "="
+		if desc != "" {
+			completion += "\t" + desc
+		}
+		completions = append(completions, completion)
+	}
+	sort.Strings(completions)
+	return completions, cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+}
+
+// chartValuePaths adds the dotted paths of the values and of the values
+// schema of the chart and its dependencies under prefix to paths, with their
+// descriptions.
+func chartValuePaths(chrt *chart.Chart, prefix string, paths map[string]string) {
+	valuePaths(chrt.Values, prefix, paths)
+	if len(chrt.Schema) > 0 {
+		var schema map[string]interface{}
+		if err := json.Unmarshal(chrt.Schema, &schema); err == nil {
+			schemaPaths(schema, prefix, paths)
+		}
+	}
+	for _, dep := range chrt.Dependencies() {
+		name := dep.Name()
+		if chrt.Metadata != nil {
+			for _, d := range chrt.Metadata.Dependencies {
+				if d.Name == name && d.Alias != "" {
+					name = d.Alias
+				}
+			}
+		}
+		chartValuePaths(dep, prefix+name+".", paths)
+	}
+}
+
+// valuePaths adds the dotted paths of the values under prefix to paths,
+// described by the default values of the scalars.
+func valuePaths(vals map[string]interface{}, prefix string, paths map[string]string) {
+	for k, v := range vals {
+		p := prefix + k
+		switch v := v.(type) {
+		case map[string]interface{}:
+			paths[p] = ""
+			valuePaths(v, p+".", paths)
+		case []interface{}:
+			paths[p] = "list"
+		default:
+			paths[p] = fmt.Sprintf("default: %v", v)
+		}
+	}
+}
+
+// schemaPaths adds the dotted paths of the properties of the JSON schema
+// under prefix to paths, described by their descriptions.
+func schemaPaths(schema map[string]interface{}, prefix string, paths map[string]string) {
+	properties, _ := schema["properties"].(map[string]interface{})
+	for k, v := range properties {
+		property, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		p := prefix + k
+		if desc, ok := property["description"].(string); ok && desc != "" {
+			paths[p] = desc
+		} else if _, ok := paths[p]; !ok {
+			paths[p] = ""
+		}
+		schemaPaths(property, p+".", paths)
+	}
+}
+
 // addKlogFlags adds flags from k8s.io/klog
 // marks the flags as hidden to avoid polluting the help text
 func addKlogFlags(fs *pflag.FlagSet) {
@@ -17,10 +17,16 @@ limitations under the License.
 package main
 
 import (
+	"bytes"
 	"fmt"
+	"runtime"
+	"strings"
 	"testing"
 
+	"github.com/spf13/cobra"
+
 	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/postrender"
 	"helm.sh/helm/v3/pkg/release"
 	helmtime "helm.sh/helm/v3/pkg/time"
 )
@@ -93,3 +99,111 @@ func outputFlagCompletionTest(t *testing.T, cmdName string) {
 	}}
 	runTestCmd(t, tests)
 }
+
+func TestPostRendererFlags(t *testing.T) {
+	var pr postrender.PostRenderer
+	cmd := &cobra.Command{}
+	bindPostRenderFlag(cmd, &pr)
+	if err := cmd.ParseFlags([]string{
+		"--post-renderer-args", "namespace=first",
+		"--post-renderer", postrender.BuiltinKustomize,
+		"--post-renderer", postrender.BuiltinKustomize,
+		"--post-renderer-args", "namespace=second",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	chain, ok := pr.(postrender.Chain)
+	if !ok || len(chain) != 2 {
+		t.Fatalf("expected a chain of 2 post-renderers, got %#v", pr)
+	}
+	out, err := chain.Run(bytes.NewBufferString("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "namespace: second") {
+		t.Errorf("expected the output of the last post-renderer, got:\n%s", out)
+	}
+
+	if err := cmd.ParseFlags([]string{"--post-renderer-args", "unknown=value"}); err == nil {
+		t.Error("expected the invalid argument of the last post-renderer to fail")
+	}
+}
+
+func TestPostRendererProtocolFlag(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the post-renderer is sh")
+	}
+	var pr postrender.PostRenderer
+	cmd := &cobra.Command{}
+	bindPostRenderFlag(cmd, &pr)
+	if err := cmd.ParseFlags([]string{"--post-renderer-protocol", "2", "--post-renderer", "sh"}); err != nil {
+		t.Fatal(err)
+	}
+	if pr == nil {
+		t.Fatal("expected a post-renderer")
+	}
+	if got := cmd.Flag("post-renderer-protocol").Value.String(); got != "2" {
+		t.Errorf("expected the protocol of the first post-renderer, got %q", got)
+	}
+	if err := cmd.ParseFlags([]string{"--post-renderer-protocol", "3"}); err == nil {
+		t.Error("expected an unsupported protocol to fail")
+	}
+}
+
+func TestPostRendererFilterFlag(t *testing.T) {
+	var pr postrender.PostRenderer
+	cmd := &cobra.Command{}
+	bindPostRenderFlag(cmd, &pr)
+	if err := cmd.ParseFlags([]string{
+		"--post-renderer", postrender.BuiltinKustomize,
+		"--post-renderer-args", "namespace=web",
+		"--post-renderer-filter", "kind=Deployment",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	manifests := "---\napiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n"
+	out, err := pr.Run(bytes.NewBufferString(manifests))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(out.String(), "namespace: web") != 1 || !strings.Contains(out.String(), "name: settings") {
+		t.Errorf("expected the deployment only to be post-rendered, got:\n%s", out)
+	}
+
+	if err := cmd.ParseFlags([]string{"--post-renderer-filter", "unknown=value"}); err == nil {
+		t.Error("expected an invalid filter to fail")
+	}
+}
+
+func TestValuesFlagCompletion(t *testing.T) {
+	chartPath := "testdata/testcharts/chart-with-schema"
+	for _, tt := range []struct {
+		cmd    string
+		expect []string
+	}{{
+		cmd:    fmt.Sprintf("__complete install myrelease %s --set employmentInfo.", chartPath),
+		expect: []string{"employmentInfo.salary=\tdefault: 100000", "employmentInfo.title=\tdefault: Software Developer"},
+	}, {
+		cmd:    fmt.Sprintf("__complete upgrade myrelease %s --set-string firstname=Jane,ag", chartPath),
+		expect: []string{"firstname=Jane,age=\tAge"},
+	}, {
+		cmd:    fmt.Sprintf("__complete template myrelease %s --set addr", chartPath),
+		expect: []string{"addresses=\tList of addresses"},
+	}, {
+		cmd: fmt.Sprintf("__complete install myrelease %s --set age=", chartPath),
+	}} {
+		_, out, err := executeActionCommand(tt.cmd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, line := range strings.Split(out, "\n") {
+			if line != "" && !strings.HasPrefix(line, ":") && !strings.HasPrefix(line, "Completion ended") {
+				got = append(got, line)
+			}
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.expect, "\n") {
+			t.Errorf("%s: expected %q, got %q", tt.cmd, tt.expect, got)
+		}
+	}
+}
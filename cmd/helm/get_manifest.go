@@ -25,6 +25,7 @@ import (
 
 	"helm.sh/helm/v3/cmd/helm/require"
 	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/cli/output"
 )
 
 var getManifestHelp = `
@@ -33,10 +34,17 @@ This command fetches the generated manifest for a given release.
 A manifest is a YAML-encoded representation of the Kubernetes resources that
 were generated from this release's chart(s). If a chart is dependent on other
 charts, those resources will also be included in the manifest.
+
+The resources can be filtered by kind with --kind and by name with --name,
+which accepts patterns like 'web-*'. With '-o json' or '-o yaml', the resources
+are written as a list with their kinds, names and namespaces.
+
+    $ helm get manifest myrelease --kind Deployment --name web
 `
 
 func newGetManifestCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
-	client := action.NewGet(cfg)
+	var outfmt output.Format
+	client := action.NewGetManifest(cfg)
 
 	cmd := &cobra.Command{
 		Use:   "manifest RELEASE_NAME",
@@ -50,16 +58,29 @@ func newGetManifestCmd(cfg *action.Configuration, out io.Writer) *cobra.Command
 			return compListReleases(toComplete, args, cfg)
 		},
 		RunE: func(_ *cobra.Command, args []string) error {
-			res, err := client.Run(args[0])
+			if outfmt == output.Table && len(client.Kinds) == 0 && len(client.Names) == 0 {
+				get := action.NewGet(cfg)
+				get.Version = client.Version
+				res, err := get.Run(args[0])
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(out, res.Manifest)
+				return nil
+			}
+			resources, err := client.Run(args[0])
 			if err != nil {
 				return err
 			}
-			fmt.Fprintln(out, res.Manifest)
-			return nil
+			return outfmt.Write(out, manifestWriter(resources))
 		},
 	}
 
-	cmd.Flags().IntVar(&client.Version, "revision", 0, "get the named release with revision")
+	f := cmd.Flags()
+	f.IntVar(&client.Version, "revision", 0, "get the named release with revision")
+	f.StringSliceVar(&client.Kinds, "kind", nil, "only get the resources of these kinds, like Deployment (can specify multiple)")
+	f.StringSliceVar(&client.Names, "name", nil, "only get the resources with names matching these patterns, like web-* (can specify multiple)")
+	bindOutputFlag(cmd, &outfmt)
 	err := cmd.RegisterFlagCompletionFunc("revision", func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		if len(args) == 1 {
 			return compListRevisions(toComplete, cfg, args[0])
@@ -73,3 +94,31 @@ func newGetManifestCmd(cfg *action.Configuration, out io.Writer) *cobra.Command
 
 	return cmd
 }
+
+// manifestWriter writes the resources of a manifest, as a YAML stream for the
+// table output.
+type manifestWriter []action.ManifestResource
+
+func (m manifestWriter) WriteTable(out io.Writer) error {
+	for _, r := range m {
+		fmt.Fprintf(out, "---\n%s", r.Manifest)
+	}
+	return nil
+}
+
+func (m manifestWriter) WriteJSON(out io.Writer) error {
+	return output.EncodeJSON(out, m.resources())
+}
+
+func (m manifestWriter) WriteYAML(out io.Writer) error {
+	return output.EncodeYAML(out, m.resources())
+}
+
+// resources returns the resources, empty rather than nil so that they are
+// encoded as a list.
+func (m manifestWriter) resources() []action.ManifestResource {
+	if m == nil {
+		return []action.ManifestResource{}
+	}
+	return m
+}
@@ -20,6 +20,7 @@ import (
 	"testing"
 
 	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
 )
 
 func TestGetManifest(t *testing.T) {
@@ -37,6 +38,67 @@ func TestGetManifest(t *testing.T) {
 	runTestCmd(t, tests)
 }
 
+func TestGetManifestFiltered(t *testing.T) {
+	rel := release.Mock(&release.MockReleaseOptions{Name: "juno"})
+	rel.Manifest = `---
+# Source: mychart/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: default
+---
+# Source: mychart/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+---
+# Source: mychart/templates/worker.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: worker
+`
+	tests := []cmdTestCase{{
+		name:   "get manifest filtered by kind",
+		cmd:    "get manifest juno --kind deployment",
+		golden: "output/get-manifest-kind.txt",
+		rels:   []*release.Release{rel},
+	}, {
+		name:   "get manifest filtered by kind and name",
+		cmd:    "get manifest juno --kind Deployment --name 'w*b'",
+		golden: "output/get-manifest-kind-name.txt",
+		rels:   []*release.Release{rel},
+	}, {
+		name:   "get manifest filtered as json",
+		cmd:    "get manifest juno --name web -o json",
+		golden: "output/get-manifest-name.json",
+		rels:   []*release.Release{rel},
+	}, {
+		name:   "get manifest without matching resources as yaml",
+		cmd:    "get manifest juno --kind Job -o yaml",
+		golden: "output/get-manifest-none.yaml",
+		rels:   []*release.Release{rel},
+	}}
+	runTestCmd(t, tests)
+
+	// The indexed releases give the same resources
+	indexed := *rel
+	indexed.ManifestIndex = releaseutil.IndexManifest(rel.Manifest)
+	runTestCmd(t, []cmdTestCase{{
+		name:   "get manifest of an indexed release filtered by kind",
+		cmd:    "get manifest juno --kind deployment",
+		golden: "output/get-manifest-kind.txt",
+		rels:   []*release.Release{&indexed},
+	}, {
+		name:   "get manifest of an indexed release filtered as json",
+		cmd:    "get manifest juno --name web -o json",
+		golden: "output/get-manifest-name.json",
+		rels:   []*release.Release{&indexed},
+	}})
+}
+
 func TestGetManifestCompletion(t *testing.T) {
 	checkReleaseCompletion(t, "get manifest", false)
 }
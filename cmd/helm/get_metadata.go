@@ -82,6 +82,16 @@ func (w metadataWriter) WriteTable(out io.Writer) error {
 	_, _ = fmt.Fprintf(out, "REVISION: %v\n", w.metadata.Revision)
 	_, _ = fmt.Fprintf(out, "STATUS: %v\n", w.metadata.Status)
 	_, _ = fmt.Fprintf(out, "DEPLOYED_AT: %v\n", w.metadata.DeployedAt)
+	if len(w.metadata.ValueSources) > 0 {
+		_, _ = fmt.Fprintln(out, "VALUE_SOURCES:")
+		for _, s := range w.metadata.ValueSources {
+			if s.Digest != "" {
+				_, _ = fmt.Fprintf(out, "  %v (%s)\n", s, s.Digest)
+			} else {
+				_, _ = fmt.Fprintf(out, "  %v\n", s)
+			}
+		}
+	}
 	return nil
 }
 
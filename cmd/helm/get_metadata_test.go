@@ -48,6 +48,28 @@ func TestGetMetadataCmd(t *testing.T) {
 	runTestCmd(t, tests)
 }
 
+func TestGetMetadataCmdWithValueSources(t *testing.T) {
+	rel := release.Mock(&release.MockReleaseOptions{Name: "thomas-guide", Version: 2})
+	rel.ValueSources = []release.ValueSource{
+		{Type: release.ValueSourceRevision, Revision: 1},
+		{Type: release.ValueSourceFile, Value: "prod.yaml", Digest: "sha256:8f434346648f6b96df89dda901c5176b10a6d83961dd3c1ac88b59b2dc327aa4"},
+		{Type: release.ValueSourceSet, Value: "image.tag=1.2.3"},
+	}
+
+	tests := []cmdTestCase{{
+		name:   "get metadata with value sources",
+		cmd:    "get metadata thomas-guide",
+		golden: "output/get-metadata-value-sources.txt",
+		rels:   []*release.Release{rel},
+	}, {
+		name:   "get metadata with value sources to json",
+		cmd:    "get metadata thomas-guide --output json",
+		golden: "output/get-metadata-value-sources.json",
+		rels:   []*release.Release{rel},
+	}}
+	runTestCmd(t, tests)
+}
+
 func TestGetMetadataCompletion(t *testing.T) {
 	checkReleaseCompletion(t, "get metadata", false)
 }
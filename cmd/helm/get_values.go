@@ -17,19 +17,34 @@ limitations under the License.
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"strconv"
+
+	"github.com/gosuri/uitable"
 
 	"github.com/spf13/cobra"
 
 	"helm.sh/helm/v3/cmd/helm/require"
 	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/cli/output"
 )
 
 var getValuesHelp = `
 This command downloads a values file for a given release.
+
+Use '--compare-to' to print the changes of the values of the release since
+another revision instead, like to audit what changed between two deploys:
+
+    $ helm get values myrelease --revision 3 --compare-to 2
+
+The changes of the values supplied by the users are printed apart from the
+changes of the computed values, which also include the changes of the default
+values of the chart between its versions. Use '--output json' or
+'--output yaml' for a structured diff of the values.
 `
 
 type valuesWriter struct {
@@ -53,6 +68,13 @@ func newGetValuesCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			return compListReleases(toComplete, args, cfg)
 		},
 		RunE: func(_ *cobra.Command, args []string) error {
+			if client.CompareTo != 0 {
+				diff, err := client.Diff(args[0])
+				if err != nil {
+					return err
+				}
+				return outfmt.Write(out, &valuesDiffWriter{diff})
+			}
 			vals, err := client.Run(args[0])
 			if err != nil {
 				return err
@@ -74,7 +96,19 @@ func newGetValuesCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 		log.Fatal(err)
 	}
 
+	f.IntVar(&client.CompareTo, "compare-to", 0, "print the changes of the values since this revision")
+	err = cmd.RegisterFlagCompletionFunc("compare-to", func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 1 {
+			return compListRevisions(toComplete, cfg, args[0])
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	f.BoolVarP(&client.AllValues, "all", "a", false, "dump all (computed) values")
+	cmd.MarkFlagsMutuallyExclusive("all", "compare-to")
 	bindOutputFlag(cmd, &outfmt)
 
 	return cmd
@@ -96,3 +130,54 @@ func (v valuesWriter) WriteJSON(out io.Writer) error {
 func (v valuesWriter) WriteYAML(out io.Writer) error {
 	return output.EncodeYAML(out, v.vals)
 }
+
+type valuesDiffWriter struct {
+	diff *action.ValuesDiff
+}
+
+func (v valuesDiffWriter) WriteTable(out io.Writer) error {
+	fmt.Fprintf(out, "USER-SUPPLIED VALUES CHANGED FROM REVISION %d TO %d:\n", v.diff.From, v.diff.To)
+	writeValueChanges(out, v.diff.UserSupplied)
+	fmt.Fprintf(out, "\nCOMPUTED VALUES CHANGED FROM REVISION %d TO %d:\n", v.diff.From, v.diff.To)
+	writeValueChanges(out, v.diff.Computed)
+	return nil
+}
+
+func (v valuesDiffWriter) WriteJSON(out io.Writer) error {
+	return output.EncodeJSON(out, v.diff)
+}
+
+func (v valuesDiffWriter) WriteYAML(out io.Writer) error {
+	return output.EncodeYAML(out, v.diff)
+}
+
+// writeValueChanges writes the changes of values as a table, with the values
+// encoded in JSON.
+func writeValueChanges(out io.Writer, changes []chartutil.ValueChange) {
+	if len(changes) == 0 {
+		fmt.Fprintln(out, "no changes")
+		return
+	}
+	table := uitable.New()
+	table.AddRow("PATH", "CHANGE", "FROM", "TO")
+	for _, c := range changes {
+		from, to := "", ""
+		if c.Type != chartutil.ValueAdded {
+			from = formatValue(c.From)
+		}
+		if c.Type != chartutil.ValueRemoved {
+			to = formatValue(c.To)
+		}
+		table.AddRow(c.Path, c.Type, from, to)
+	}
+	fmt.Fprintln(out, table)
+}
+
+// formatValue returns the value encoded in JSON.
+func formatValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return strconv.Quote(fmt.Sprint(v))
+	}
+	return string(data)
+}
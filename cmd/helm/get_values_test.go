@@ -53,6 +53,54 @@ func TestGetValuesCmd(t *testing.T) {
 	runTestCmd(t, tests)
 }
 
+func TestGetValuesCompareToCmd(t *testing.T) {
+	mk := func(vers int, status release.Status, defaults, config map[string]interface{}) *release.Release {
+		rel := release.Mock(&release.MockReleaseOptions{
+			Name:    "thomas-guide",
+			Version: vers,
+			Status:  status,
+		})
+		rel.Chart.Values = defaults
+		rel.Config = config
+		return rel
+	}
+	rels := []*release.Release{
+		mk(1, release.StatusSuperseded,
+			map[string]interface{}{"port": 80, "image": map[string]interface{}{"repository": "nginx", "tag": "1.25"}},
+			map[string]interface{}{"replicas": 1, "debug": true}),
+		mk(2, release.StatusSuperseded,
+			map[string]interface{}{"port": 80, "image": map[string]interface{}{"repository": "nginx", "tag": "1.25"}},
+			map[string]interface{}{"replicas": 1, "debug": true}),
+		mk(3, release.StatusDeployed,
+			map[string]interface{}{"port": 8080, "image": map[string]interface{}{"repository": "nginx", "tag": "1.25"}},
+			map[string]interface{}{"replicas": 3, "image": map[string]interface{}{"tag": "1.27"}}),
+	}
+
+	tests := []cmdTestCase{{
+		name:   "get values compared to a revision",
+		cmd:    "get values thomas-guide --compare-to 1",
+		golden: "output/get-values-compare-to.txt",
+		rels:   rels,
+	}, {
+		name:   "get values of a revision compared to a revision without changes",
+		cmd:    "get values thomas-guide --revision 2 --compare-to 1",
+		golden: "output/get-values-compare-to-none.txt",
+		rels:   rels,
+	}, {
+		name:   "get values compared to a revision to json",
+		cmd:    "get values thomas-guide --compare-to 1 --output json",
+		golden: "output/get-values-compare-to.json",
+		rels:   rels,
+	}, {
+		name:      "get values compared to a missing revision",
+		cmd:       "get values thomas-guide --compare-to 4",
+		golden:    "output/get-values-compare-to-missing.txt",
+		rels:      rels,
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
 func TestGetValuesCompletion(t *testing.T) {
 	checkReleaseCompletion(t, "get values", false)
 }
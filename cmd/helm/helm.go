@@ -21,8 +21,10 @@ import (
 	"io"
 	"log"
 	"os"
+	"strconv"
 	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"sigs.k8s.io/yaml"
 
@@ -35,6 +37,7 @@ import (
 	kubefake "helm.sh/helm/v3/pkg/kube/fake"
 	"helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/storage/driver"
+	"helm.sh/helm/v3/pkg/storage/encryption"
 )
 
 var settings = cli.New()
@@ -72,12 +75,28 @@ func main() {
 	// run when each command's execute method is called
 	cobra.OnInitialize(func() {
 		helmDriver := os.Getenv("HELM_DRIVER")
+		encryptor, err := releaseEncryptorFromEnv()
+		if err != nil {
+			log.Fatal(err)
+		}
+		actionConfig.ReleaseEncryptor = encryptor
+		compression, err := releaseCompressionFromEnv()
+		if err != nil {
+			log.Fatal(err)
+		}
+		actionConfig.ReleaseCompression = compression
 		if err := actionConfig.Init(settings.RESTClientGetter(), settings.Namespace(), helmDriver, debug); err != nil {
 			log.Fatal(err)
 		}
 		if helmDriver == "memory" {
 			loadReleasesInMemory(actionConfig)
 		}
+		actionConfig.RecordEvents, _ = strconv.ParseBool(os.Getenv("HELM_RECORD_EVENTS"))
+		lifecycleHooks, err := loadLifecycleHooks()
+		if err != nil {
+			debug("loading the lifecycle hooks of the plugins: %s", err)
+		}
+		actionConfig.LifecycleHooks = lifecycleHooks
 	})
 
 	if err := cmd.Execute(); err != nil {
@@ -91,6 +110,40 @@ func main() {
 	}
 }
 
+// releaseEncryptorFromEnv returns the encryptor of the stored releases
+// configured by the HELM_ENCRYPTION_* environment variables, or nil if
+// encryption is not enabled.
+func releaseEncryptorFromEnv() (driver.Encryptor, error) {
+	if path := os.Getenv("HELM_ENCRYPTION_KEY_FILE"); path != "" {
+		wrapper, err := encryption.LoadAESKeyWrapper(path)
+		if err != nil {
+			return nil, err
+		}
+		return encryption.NewEnvelope(wrapper), nil
+	}
+	wrap := strings.Fields(os.Getenv("HELM_ENCRYPTION_WRAP_COMMAND"))
+	unwrap := strings.Fields(os.Getenv("HELM_ENCRYPTION_UNWRAP_COMMAND"))
+	if len(wrap) > 0 || len(unwrap) > 0 {
+		return encryption.NewEnvelope(&encryption.ExecKeyWrapper{WrapCommand: wrap, UnwrapCommand: unwrap}), nil
+	}
+	return nil, nil
+}
+
+// releaseCompressionFromEnv returns the compression of the stored releases
+// configured by the HELM_DRIVER_COMPRESSION and HELM_DRIVER_COMPRESSION_LEVEL
+// environment variables.
+func releaseCompressionFromEnv() (driver.Compression, error) {
+	c := driver.Compression{Algorithm: os.Getenv("HELM_DRIVER_COMPRESSION")}
+	if v := os.Getenv("HELM_DRIVER_COMPRESSION_LEVEL"); v != "" {
+		level, err := strconv.Atoi(v)
+		if err != nil {
+			return c, errors.Wrap(err, "invalid HELM_DRIVER_COMPRESSION_LEVEL")
+		}
+		c.Level = level
+	}
+	return c, c.Validate()
+}
+
 // This function loads releases into the memory storage if the
 // environment variable is properly set.
 func loadReleasesInMemory(actionConfig *action.Configuration) {
@@ -23,6 +23,7 @@ import (
 	"time"
 
 	"github.com/gosuri/uitable"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"helm.sh/helm/v3/cmd/helm/require"
@@ -48,25 +49,59 @@ The historical release set is printed as a formatted table, e.g:
     2           Mon Oct 3 10:15:13 2016     superseded      alpine-0.1.0      1.0             Upgraded successfully
     3           Mon Oct 3 10:15:13 2016     superseded      alpine-0.1.0      1.0             Rolled back to 2
     4           Mon Oct 3 10:15:13 2016     deployed        alpine-0.1.0      1.0             Upgraded successfully
+
+The JSON and YAML outputs include the chart version of every revision and the
+digest of the values supplied to it, so that the revisions upgraded with
+different values can be told apart.
+
+With '--diff', the difference between two revisions is printed instead: the
+unified diffs of the values supplied to them and of their manifests.
+
+    $ helm history angry-bird --diff 2 4
 `
 
 func newHistoryCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	client := action.NewHistory(cfg)
 	var outfmt output.Format
+	var diff bool
 
 	cmd := &cobra.Command{
-		Use:     "history RELEASE_NAME",
+		Use:     "history RELEASE_NAME [--diff REVISION REVISION]",
 		Long:    historyHelp,
 		Short:   "fetch release history",
 		Aliases: []string{"hist"},
-		Args:    require.ExactArgs(1),
+		Args: func(cmd *cobra.Command, args []string) error {
+			if diff {
+				return require.ExactArgs(3)(cmd, args)
+			}
+			return require.ExactArgs(1)(cmd, args)
+		},
 		ValidArgsFunction: func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-			if len(args) != 0 {
-				return noMoreArgsComp()
+			if len(args) == 0 {
+				return compListReleases(toComplete, args, cfg)
+			}
+			if diff && len(args) < 3 {
+				return compListRevisions(toComplete, cfg, args[0])
 			}
-			return compListReleases(toComplete, args, cfg)
+			return noMoreArgsComp()
 		},
 		RunE: func(_ *cobra.Command, args []string) error {
+			if diff {
+				from, err := strconv.Atoi(args[1])
+				if err != nil {
+					return errors.Errorf("invalid revision %q", args[1])
+				}
+				to, err := strconv.Atoi(args[2])
+				if err != nil {
+					return errors.Errorf("invalid revision %q", args[2])
+				}
+				d, err := client.Diff(args[0], from, to)
+				if err != nil {
+					return err
+				}
+				return outfmt.Write(out, &revisionDiffWriter{d})
+			}
+
 			history, err := getHistory(client, args[0])
 			if err != nil {
 				return err
@@ -78,18 +113,21 @@ func newHistoryCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 
 	f := cmd.Flags()
 	f.IntVar(&client.Max, "max", 256, "maximum number of revision to include in history")
+	f.BoolVar(&diff, "diff", false, "print the difference between the two revisions given after the release name")
 	bindOutputFlag(cmd, &outfmt)
 
 	return cmd
 }
 
 type releaseInfo struct {
-	Revision    int           `json:"revision"`
-	Updated     helmtime.Time `json:"updated"`
-	Status      string        `json:"status"`
-	Chart       string        `json:"chart"`
-	AppVersion  string        `json:"app_version"`
-	Description string        `json:"description"`
+	Revision     int           `json:"revision"`
+	Updated      helmtime.Time `json:"updated"`
+	Status       string        `json:"status"`
+	Chart        string        `json:"chart"`
+	ChartVersion string        `json:"chart_version"`
+	AppVersion   string        `json:"app_version"`
+	ValuesDigest string        `json:"values_digest"`
+	Description  string        `json:"description"`
 }
 
 type releaseHistory []releaseInfo
@@ -143,11 +181,13 @@ func getReleaseHistory(rls []*release.Release) (history releaseHistory) {
 		a := formatAppVersion(r.Chart)
 
 		rInfo := releaseInfo{
-			Revision:    v,
-			Status:      s,
-			Chart:       c,
-			AppVersion:  a,
-			Description: d,
+			Revision:     v,
+			Status:       s,
+			Chart:        c,
+			ChartVersion: formatChartVersion(r.Chart),
+			AppVersion:   a,
+			ValuesDigest: action.ValuesDigest(r.Config),
+			Description:  d,
 		}
 		if !r.Info.LastDeployed.IsZero() {
 			rInfo.Updated = r.Info.LastDeployed
@@ -168,6 +208,13 @@ func formatChartname(c *chart.Chart) string {
 	return fmt.Sprintf("%s-%s", c.Name(), c.Metadata.Version)
 }
 
+func formatChartVersion(c *chart.Chart) string {
+	if c == nil || c.Metadata == nil {
+		return "MISSING"
+	}
+	return c.Metadata.Version
+}
+
 func formatAppVersion(c *chart.Chart) string {
 	if c == nil || c.Metadata == nil {
 		// This is an edge case that has happened in prod, though we don't
@@ -177,6 +224,37 @@ func formatAppVersion(c *chart.Chart) string {
 	return c.AppVersion()
 }
 
+type revisionDiffWriter struct {
+	diff *action.RevisionDiff
+}
+
+func (w *revisionDiffWriter) WriteTable(out io.Writer) error {
+	for _, info := range []action.RevisionInfo{w.diff.From, w.diff.To} {
+		fmt.Fprintf(out, "REVISION %d: chart %s-%s, app version %s, values %s\n",
+			info.Revision, info.Chart, info.ChartVersion, info.AppVersion, info.ValuesDigest)
+	}
+	for _, d := range []struct{ name, diff string }{
+		{"values", w.diff.Values},
+		{"manifest", w.diff.Manifest},
+	} {
+		fmt.Fprintln(out)
+		if d.diff == "" {
+			fmt.Fprintf(out, "No differences in the %s\n", d.name)
+			continue
+		}
+		fmt.Fprint(out, d.diff)
+	}
+	return nil
+}
+
+func (w *revisionDiffWriter) WriteJSON(out io.Writer) error {
+	return output.EncodeJSON(out, w.diff)
+}
+
+func (w *revisionDiffWriter) WriteYAML(out io.Writer) error {
+	return output.EncodeYAML(out, w.diff)
+}
+
 func min(x, y int) int {
 	if x < y {
 		return x
@@ -66,6 +66,77 @@ func TestHistoryCmd(t *testing.T) {
 			mk("angry-bird", 3, release.StatusSuperseded),
 		},
 		golden: "output/history.json",
+	}, {
+		name: "get history with go-template output format",
+		cmd:  `history angry-bird --output 'go-template={{range .}}{{.revision}} {{.status}}{{"\n"}}{{end}}'`,
+		rels: []*release.Release{
+			mk("angry-bird", 4, release.StatusDeployed),
+			mk("angry-bird", 3, release.StatusSuperseded),
+		},
+		golden: "output/history-template.txt",
+	}, {
+		name:      "get history with an invalid go-template",
+		cmd:       "history angry-bird --output 'go-template={{.revision'",
+		golden:    "output/history-invalid-template.txt",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestHistoryDiffCmd(t *testing.T) {
+	mk := func(vers int, status release.Status, chartVersion string, config map[string]interface{}, manifest string) *release.Release {
+		rel := release.Mock(&release.MockReleaseOptions{
+			Name:    "angry-bird",
+			Version: vers,
+			Status:  status,
+		})
+		rel.Chart.Metadata.Version = chartVersion
+		rel.Config = config
+		rel.Manifest = manifest
+		return rel
+	}
+	rels := []*release.Release{
+		mk(1, release.StatusSuperseded, "0.1.0", map[string]interface{}{"replicas": 1, "image": "nginx:1.25"},
+			"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: angry-bird\ndata:\n  replicas: \"1\"\n"),
+		mk(2, release.StatusSuperseded, "0.1.0", map[string]interface{}{"replicas": 1, "image": "nginx:1.25"},
+			"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: angry-bird\ndata:\n  replicas: \"1\"\n"),
+		mk(3, release.StatusDeployed, "0.2.0", map[string]interface{}{"replicas": 3, "image": "nginx:1.25"},
+			"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: angry-bird\ndata:\n  replicas: \"3\"\n"),
+	}
+
+	tests := []cmdTestCase{{
+		name:   "diff two revisions",
+		cmd:    "history angry-bird --diff 1 3",
+		rels:   rels,
+		golden: "output/history-diff.txt",
+	}, {
+		name:   "diff identical revisions",
+		cmd:    "history angry-bird --diff 1 2",
+		rels:   rels,
+		golden: "output/history-diff-none.txt",
+	}, {
+		name:   "diff two revisions with json output format",
+		cmd:    "history angry-bird --diff 2 3 -o json",
+		rels:   rels,
+		golden: "output/history-diff.json",
+	}, {
+		name:      "diff a missing revision",
+		cmd:       "history angry-bird --diff 1 4",
+		rels:      rels,
+		golden:    "output/history-diff-missing.txt",
+		wantError: true,
+	}, {
+		name:      "diff an invalid revision",
+		cmd:       "history angry-bird --diff 1 latest",
+		rels:      rels,
+		golden:    "output/history-diff-invalid.txt",
+		wantError: true,
+	}, {
+		name:      "diff requires two revisions",
+		cmd:       "history angry-bird --diff 1",
+		rels:      rels,
+		golden:    "output/history-diff-args.txt",
+		wantError: true,
 	}}
 	runTestCmd(t, tests)
 }
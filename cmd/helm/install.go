@@ -79,6 +79,13 @@ contained a key called 'Test', the value set in override.yaml would take precede
 
     $ helm install -f myvalues.yaml -f override.yaml  myredis ./redis
 
+The values may be read from a key of a ConfigMap or a Secret of the cluster,
+with URLs like configmap://namespace/name/key and secret://namespace/name/key.
+The namespace of the kubeconfig is used if it is omitted, like in
+configmap:///name/key:
+
+    $ helm install -f secret://prod/redis-values/values.yaml myredis ./redis
+
 You can specify the '--set' flag multiple times. The priority will be given to the
 last (right-most) set specified. For example, if both 'bar' and 'newbar' values are
 set for a key called 'foo', the 'newbar' value would take precedence:
@@ -101,9 +108,12 @@ which can contain sensitive values. To hide Kubernetes Secrets use the
 --hide-secret flag. Please carefully consider how and when these flags are used.
 
 If --verify is set, the chart MUST have a provenance file, and the provenance
-file MUST pass all verification steps.
+file MUST pass all verification steps. If a signature policy exists at
+$HELM_SIGNATURE_POLICY, the chart MUST instead have a sigstore signature
+trusted by the policy: attached to the chart in a registry, or in a cosign
+bundle alongside a chart archive.
 
-There are six different ways you can express the chart you want to install:
+There are seven different ways you can express the chart you want to install:
 
 1. By chart reference: helm install mymaria example/mariadb
 2. By path to a packaged chart: helm install mynginx ./nginx-1.2.3.tgz
@@ -111,6 +121,7 @@ There are six different ways you can express the chart you want to install:
 4. By absolute URL: helm install mynginx https://example.com/charts/nginx-1.2.3.tgz
 5. By chart reference and repo url: helm install --repo https://example.com/charts/ mynginx nginx
 6. By OCI registries: helm install mynginx --version 1.2.3 oci://example.com/charts/nginx
+7. By git repositories: helm install mynginx 'git+https://example.com/org/charts//nginx?ref=v1.2.3'
 
 CHART REFERENCES
 
@@ -124,6 +135,15 @@ supply a version number with the '--version' flag.
 
 To see the list of chart repositories, use 'helm repo list'. To search for
 charts in a repository, use 'helm search'.
+
+The '--version' flag also accepts semantic version constraints, such as
+'>=1.2.0 <2.0.0'. For OCI registries, the tags of the repository are listed and
+the highest version matching the constraint is installed.
+
+Charts in git repositories are referenced by the URL of the repository with a
+'git+' prefix, followed by the directory of the chart after a double slash and
+the branch, tag or commit to check out in the 'ref' parameter. The repository is
+cloned with git, so private repositories are accessed with its credentials.
 `
 
 func newInstallCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
@@ -169,6 +189,7 @@ func newInstallCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.BoolVar(&client.HideSecret, "hide-secret", false, "hide Kubernetes Secrets when also using the --dry-run flag")
 	bindOutputFlag(cmd, &outfmt)
 	bindPostRenderFlag(cmd, &client.PostRenderer)
+	bindRenderHookFlag(cmd, &client.RenderHooks)
 
 	return cmd
 }
@@ -188,17 +209,22 @@ func addInstallFlags(cmd *cobra.Command, f *pflag.FlagSet, client *action.Instal
 	f.DurationVar(&client.Timeout, "timeout", 300*time.Second, "time to wait for any individual Kubernetes operation (like Jobs for hooks)")
 	f.BoolVar(&client.Wait, "wait", false, "if set, will wait until all Pods, PVCs, Services, and minimum number of Pods of a Deployment, StatefulSet, or ReplicaSet are in a ready state before marking the release as successful. It will wait for as long as --timeout")
 	f.BoolVar(&client.WaitForJobs, "wait-for-jobs", false, "if set and --wait enabled, will wait until all Jobs have been completed before marking the release as successful. It will wait for as long as --timeout")
+	addKindTimeoutsFlag(f, &client.KindTimeouts)
+	f.IntVar(&client.BatchSize, "batch-size", 0, "if greater than 0, create resources in batches of this size. With --wait, each batch must be ready before the next one is created")
+	f.IntVar(&client.BatchRetries, "batch-retries", 0, "number of times a batch is retried after a transient API server error. Only used with --batch-size")
 	f.BoolVarP(&client.GenerateName, "generate-name", "g", false, "generate the name (and omit the NAME parameter)")
 	f.StringVar(&client.NameTemplate, "name-template", "", "specify template used to name the release")
 	f.StringVar(&client.Description, "description", "", "add a custom description")
 	f.BoolVar(&client.Devel, "devel", false, "use development versions, too. Equivalent to version '>0.0.0-0'. If --version is set, this is ignored")
 	f.BoolVar(&client.DependencyUpdate, "dependency-update", false, "update dependencies if they are missing before installing the chart")
 	f.BoolVar(&client.DisableOpenAPIValidation, "disable-openapi-validation", false, "if set, the installation process will not validate rendered templates against the Kubernetes OpenAPI Schema")
+	f.BoolVar(&client.ValidateManifests, "validate-manifests", false, "if set, validate all rendered resources and hooks against the cluster OpenAPI schema (or the Kubernetes API types compiled into Helm if it is unavailable) before creating anything, listing every error")
 	f.BoolVar(&client.Atomic, "atomic", false, "if set, the installation process deletes the installation on failure. The --wait flag will be set automatically if --atomic is used")
 	f.BoolVar(&client.SkipCRDs, "skip-crds", false, "if set, no CRDs will be installed. By default, CRDs are installed if not already present")
 	f.BoolVar(&client.SubNotes, "render-subchart-notes", false, "if set, render subchart notes along with the parent")
 	f.BoolVar(&client.SkipSchemaValidation, "skip-schema-validation", false, "if set, disables JSON schema validation")
 	f.StringToStringVarP(&client.Labels, "labels", "l", nil, "Labels that would be added to release metadata. Should be divided by comma.")
+	f.StringToStringVar(&client.Annotations, "annotations", nil, "Annotations that would be added to the release, like team=web,ticket=OPS-123. Should be divided by comma.")
 	f.BoolVar(&client.EnableDNS, "enable-dns", false, "enable DNS lookups when rendering templates")
 	f.BoolVar(&client.HideNotes, "hide-notes", false, "if set, do not show notes in install output. Does not affect presence in chart metadata")
 	addValueOptionsFlags(f, valueOpts)
@@ -218,6 +244,16 @@ func addInstallFlags(cmd *cobra.Command, f *pflag.FlagSet, client *action.Instal
 	if err != nil {
 		log.Fatal(err)
 	}
+	registerValuesCompletion(cmd, func(args []string) (string, bool) {
+		requiredArgs := 2
+		if client.GenerateName {
+			requiredArgs = 1
+		}
+		if len(args) != requiredArgs {
+			return "", false
+		}
+		return args[requiredArgs-1], true
+	})
 }
 
 func runInstall(args []string, client *action.Install, valueOpts *values.Options, out io.Writer) (*release.Release, error) {
@@ -241,10 +277,11 @@ func runInstall(args []string, client *action.Install, valueOpts *values.Options
 	debug("CHART PATH: %s\n", cp)
 
 	p := getter.All(settings)
-	vals, err := valueOpts.MergeValues(p)
+	vals, sources, err := valueOpts.MergeValuesWithSources(p)
 	if err != nil {
 		return nil, err
 	}
+	client.ValueSources = sources
 
 	// Check chart dependencies to make sure all are present in /charts
 	chartRequested, err := loader.Load(cp)
@@ -278,6 +315,12 @@ func runInstall(args []string, client *action.Install, valueOpts *values.Options
 					Debug:            settings.Debug,
 					RegistryClient:   client.GetRegistryClient(),
 				}
+				if client.Verify {
+					man.Verify = downloader.VerifyAlways
+					if man.SignaturePolicy, err = action.LoadSignaturePolicy(settings); err != nil {
+						return nil, err
+					}
+				}
 				if err := man.Update(); err != nil {
 					return nil, err
 				}
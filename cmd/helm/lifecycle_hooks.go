@@ -0,0 +1,96 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/plugin"
+	"helm.sh/helm/v3/pkg/plugin/sdk"
+)
+
+// loadLifecycleHooks returns the lifecycle hooks of the installed plugins.
+func loadLifecycleHooks() ([]action.LifecycleHook, error) {
+	plugins, err := plugin.FindPlugins(settings.PluginsDirectory)
+	if err != nil {
+		return nil, err
+	}
+	var hooks []action.LifecycleHook
+	for _, p := range plugins {
+		for _, event := range plugin.LifecycleEvents {
+			if p.Metadata.Hooks[event] != "" {
+				hooks = append(hooks, &pluginLifecycleHook{plugin: p})
+				break
+			}
+		}
+	}
+	return hooks, nil
+}
+
+// pluginLifecycleHook runs the lifecycle hooks of a plugin, writing the event
+// on their standard input. Their output is written to stderr, so that it
+// does not mix with the output of the commands.
+type pluginLifecycleHook struct {
+	plugin *plugin.Plugin
+}
+
+func (h *pluginLifecycleHook) Name() string {
+	return h.plugin.Metadata.Name
+}
+
+func (h *pluginLifecycleHook) Run(e action.LifecycleEvent) error {
+	if h.plugin.Metadata.Hooks[e.Event] == "" {
+		return nil
+	}
+	data, err := json.Marshal(newLifecycleEvent(e))
+	if err != nil {
+		return err
+	}
+	return runHookWithInput(h.plugin, e.Event, bytes.NewReader(data), os.Stderr)
+}
+
+// newLifecycleEvent returns the event of the plugin API of e.
+func newLifecycleEvent(e action.LifecycleEvent) *sdk.LifecycleEvent {
+	rel := e.Release
+	event := &sdk.LifecycleEvent{
+		Event: e.Event,
+		Release: sdk.Release{
+			Name:      rel.Name,
+			Namespace: rel.Namespace,
+			Revision:  rel.Version,
+		},
+		Values: rel.Config,
+	}
+	if rel.Info != nil {
+		event.Release.Status = rel.Info.Status.String()
+		event.Release.Description = rel.Info.Description
+	}
+	if rel.Chart != nil && rel.Chart.Metadata != nil {
+		event.Chart = sdk.Chart{
+			Name:       rel.Chart.Metadata.Name,
+			Version:    rel.Chart.Metadata.Version,
+			AppVersion: rel.Chart.Metadata.AppVersion,
+		}
+	}
+	if e.Err != nil {
+		event.Error = e.Err.Error()
+	}
+	return event
+}
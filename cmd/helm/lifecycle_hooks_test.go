@@ -0,0 +1,103 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/plugin/sdk"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+const lifecyclePluginYAML = `name: audit
+version: 0.1.0
+hooks:
+  install: "echo installed"
+  pre-install: "cat > $HELM_PLUGIN_DIR/event.json"
+  pre-upgrade: "exit 1"
+`
+
+func TestLifecycleHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the lifecycle hooks are run with sh")
+	}
+	dir := t.TempDir()
+	pluginDir := filepath.Join(dir, "audit")
+	if err := os.Mkdir(pluginDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(pluginDir, "plugin.yaml"), []byte(lifecyclePluginYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "echo"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "echo", "plugin.yaml"), []byte("name: echo\nhooks:\n  install: \"echo installed\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	oldDir := settings.PluginsDirectory
+	settings.PluginsDirectory = dir
+	defer func() { settings.PluginsDirectory = oldDir }()
+
+	hooks, err := loadLifecycleHooks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hooks) != 1 || hooks[0].Name() != "audit" {
+		t.Fatalf("expected the lifecycle hooks of the audit plugin only, got %v", hooks)
+	}
+
+	rel := &release.Release{
+		Name:      "myrelease",
+		Namespace: "default",
+		Version:   1,
+		Info:      &release.Info{Status: release.StatusPendingInstall},
+		Chart:     &chart.Chart{Metadata: &chart.Metadata{Name: "mychart", Version: "1.2.3"}},
+		Config:    map[string]interface{}{"replicas": float64(2)},
+	}
+	if err := hooks[0].Run(action.LifecycleEvent{Event: action.LifecyclePreInstall, Release: rel}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(filepath.Join(pluginDir, "event.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	event, err := sdk.ReadLifecycleEvent(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Event != "pre-install" || event.Release.Name != "myrelease" || event.Release.Status != "pending-install" ||
+		event.Chart.Version != "1.2.3" || event.Values["replicas"] != float64(2) {
+		t.Errorf("unexpected event %+v", event)
+	}
+
+	if err := hooks[0].Run(action.LifecycleEvent{Event: action.LifecyclePreUpgrade, Release: rel}); err == nil {
+		t.Error("expected the failing hook to fail")
+	}
+	// The events without a hook are ignored
+	if err := hooks[0].Run(action.LifecycleEvent{Event: action.LifecyclePostUpgrade, Release: rel, Err: errors.New("failed")}); err != nil {
+		t.Error(err)
+	}
+}
@@ -19,17 +19,22 @@ package main
 import (
 	"fmt"
 	"io"
+	"log"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/gosuri/uitable"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/cli/output"
 	"helm.sh/helm/v3/pkg/cli/values"
 	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/helmpath"
+	"helm.sh/helm/v3/pkg/lint"
 	"helm.sh/helm/v3/pkg/lint/support"
 )
 
@@ -40,29 +45,170 @@ the chart is well-formed.
 If the linter encounters things that will cause the chart to fail installation,
 it will emit [ERROR] messages. If it encounters issues that break with convention
 or recommendation, it will emit [WARNING] messages.
+
+Besides the rules of Helm, the linter runs the lint rules of the installed
+plugins, declared in the 'lintRules' of their plugin.yaml. Their IDs are
+prefixed by the names of their plugins. Use '--list-rules' to list the rules,
+and '--disable-rule' to skip some of them:
+
+    $ helm lint --disable-rule dependencies --disable-rule myplugin/labels mychart
+
+Some rules are optional, and only run when enabled with '--enable-rule', like
+the best-practice rules of the workloads: 'workload-resources' for the resource
+requests and limits of the containers, 'workload-image-tag' for the images
+without a tag or with the latest tag, 'workload-probes' for the liveness and
+readiness probes, and 'workload-security-context' for the security contexts.
+The optional security rules are a baseline of policy scanners:
+'security-privileged' for the privileged containers, 'security-host-access' for
+the hostPath volumes and the host namespaces, 'security-rbac-wildcards' for the
+wildcards of the RBAC rules, and 'security-configmap-secrets' for the secrets
+in ConfigMaps.
+Use '--rule-severity' to change the severity of the failures of a rule, like
+to fail on the warnings of a rule without '--strict':
+
+    $ helm lint --enable-rule workload-resources --rule-severity workload-resources=error mychart
+
+Use '--values-only' to only check the values files and the values set on the
+command line against the values.schema.json files of the chart and of its
+subcharts, without rendering the templates, like to validate the values files
+of an environment in isolation:
+
+    $ helm lint --values-only -f values-prod.yaml mychart
+
+Use '--with-scenarios' to lint the charts once per scenario, a test values
+file of their ci directory named like 'ci/<scenario>-values.yaml', to exercise
+the conditional branches of their templates. The values of a scenario are
+merged over the supplied values, and the messages are reported per scenario.
+The charts without scenarios are linted once.
+
+Use '--kube-version' with comma-separated versions to lint the charts once per
+Kubernetes version, with the capabilities and the deprecated APIs of each
+version, like for a chart supporting a range of versions. The messages are
+reported per version, followed by the number of failed charts per version:
+
+    $ helm lint --kube-version 1.27,1.28,1.29 mychart
+
+Use '--recursive' to lint the subcharts of the charts, bundled in or resolved to
+their charts directories, with the values passed by their parent charts: their
+own values coalesced with the values of their parents and the global values.
+The disabled subcharts are not linted. The messages are reported per chart,
+the subcharts under the charts directories of their parents, by name or alias.
+Use '--subchart' to restrict the linted subcharts:
+
+    $ helm lint --recursive --subchart postgresql --subchart 'common*' mychart
+
+The rules of a chart can be configured in its .helmlintrc file, or in the file
+of '--config' for all the charts. The flags take precedence over the file. The
+ignores of the file skip the failures of a rule in a file of the chart, or at
+a line of the file, and must be justified by a reason:
+
+    rules:
+      enable: [workload-probes]
+      disable: [dependencies]
+      severities:
+        workload-probes: error
+    ignores:
+    - rule: workload-image-tag
+      path: templates/debug-*.yaml
+      reason: the debug pods always run the latest tools
+
+With '--enable-rule manifest-schemas', the rendered Kubernetes resources are
+validated against their schemas, to catch unknown fields and fields of the
+wrong types before they are applied, like 'helm template --validate-offline'
+does. The built-in resources are validated against the Kubernetes API types
+compiled into Helm, whatever '--kube-version' is. The custom resources are
+validated against the JSON schemas of the '--kube-schema-dir' directories, laid
+out like the CRDs catalog of kubeconform: <group>/<kind>_<version>.json, with
+the lower-cased kind. The custom resources without a schema are not validated.
+
+Use '--fix' to apply safe mechanical fixes to the chart directories before
+linting them, printing the diffs of the fixed files: the missing apiVersion and
+name of Chart.yaml are added, its apiVersion is lower-cased, its numeric
+version and appVersion are quoted, and the https scheme is added to its icon
+URL without scheme. The numbers of values.yaml losing digits when parsed, like
+the version 1.10 parsed as 1.1 or 0755 parsed as an octal number, are quoted.
+The chart archives are not fixed.
+
+Use '--incremental' to only re-run the rules whose inputs changed since the
+previous incremental run on the chart directories, reusing the cached messages
+of the other rules, like the rules of Chart.yaml when only a template changed.
+The changed files are the files whose modification times or sizes changed, or
+the files of '--changed', like the files of a commit:
+
+    $ helm lint --changed mychart/values.yaml mychart
+
+The rules are all run again when the flags or the values change. The chart
+archives are linted fully.
+
+Use '--output json' or '--output sarif' to print the messages with their rule
+IDs, severities, file paths and lines, to gate on them in scripts or to upload
+them to code scanning tools supporting SARIF 2.1.0.
 `
 
 func newLintCmd(out io.Writer) *cobra.Command {
 	client := action.NewLint()
 	valueOpts := &values.Options{}
 	var kubeVersion string
+	var listRules bool
+	var ruleSeverities []string
+	var outputFormat string
 
 	cmd := &cobra.Command{
 		Use:   "lint PATH",
 		Short: "examine a chart for possible issues",
 		Long:  longLintHelp,
 		RunE: func(_ *cobra.Command, args []string) error {
+			pluginRules, err := lint.PluginRules(settings)
+			if err != nil {
+				warning("cannot load the lint rules of the plugins: %s", err)
+			}
+			client.Rules = pluginRules
+
+			switch outputFormat {
+			case lintOutputText, lintOutputJSON, lintOutputSARIF:
+			default:
+				return errors.Errorf("invalid output format %q. Allowed values: %s", outputFormat, strings.Join(lintOutputFormats, ", "))
+			}
+
+			if listRules {
+				table := uitable.New()
+				table.AddRow("ID", "SEVERITY", "DEFAULT", "DESCRIPTION")
+				for _, r := range append(lint.Rules(), pluginRules...) {
+					md := r.Metadata()
+					enabled := "enabled"
+					if md.Optional {
+						enabled = "disabled"
+					}
+					table.AddRow(md.ID, support.SeverityName(md.Severity), enabled, md.Description)
+				}
+				fmt.Fprintln(out, table)
+				return nil
+			}
+
+			client.RuleSeverities, err = parseRuleSeverities(ruleSeverities)
+			if err != nil {
+				return err
+			}
+
 			paths := []string{"."}
 			if len(args) > 0 {
 				paths = args
 			}
 
 			if kubeVersion != "" {
-				parsedKubeVersion, err := chartutil.ParseKubeVersion(kubeVersion)
-				if err != nil {
-					return fmt.Errorf("invalid kube version '%s': %s", kubeVersion, err)
+				var kubeVersions []*chartutil.KubeVersion
+				for _, v := range strings.Split(kubeVersion, ",") {
+					parsedKubeVersion, err := chartutil.ParseKubeVersion(strings.TrimSpace(v))
+					if err != nil {
+						return fmt.Errorf("invalid kube version '%s': %s", v, err)
+					}
+					kubeVersions = append(kubeVersions, parsedKubeVersion)
+				}
+				if len(kubeVersions) == 1 {
+					client.KubeVersion = kubeVersions[0]
+				} else {
+					client.KubeVersions = kubeVersions
 				}
-				client.KubeVersion = parsedKubeVersion
 			}
 
 			if client.WithSubcharts {
@@ -81,29 +227,71 @@ func newLintCmd(out io.Writer) *cobra.Command {
 			}
 
 			client.Namespace = settings.Namespace()
+			client.CacheDir = helmpath.CachePath("lint")
+			if client.ChangedFiles != nil {
+				client.Incremental = true
+			}
 			vals, err := valueOpts.MergeValues(getter.All(settings))
 			if err != nil {
 				return err
 			}
 
 			var message strings.Builder
+			var reports []lintReport
 			failed := 0
 			errorsOrWarnings := 0
+			// The charts linted and failed per Kubernetes version, in order
+			var versions []string
+			versionLinted := make(map[string]int)
+			versionFailed := make(map[string]int)
 
+			var results []*action.LintResult
 			for _, path := range paths {
-				result := client.Run([]string{path}, vals)
+				results = append(results, client.Run([]string{path}, vals).Charts...)
+			}
 
+			for _, result := range results {
 				// If there is no errors/warnings and quiet flag is set
 				// go to the next chart
 				hasWarningsOrErrors := action.HasWarningsOrErrors(result)
 				if hasWarningsOrErrors {
 					errorsOrWarnings++
 				}
+				if len(result.Errors) != 0 {
+					failed++
+				}
+				if result.KubeVersion != "" {
+					if versionLinted[result.KubeVersion] == 0 {
+						versions = append(versions, result.KubeVersion)
+					}
+					versionLinted[result.KubeVersion]++
+					if len(result.Errors) != 0 {
+						versionFailed[result.KubeVersion]++
+					}
+				}
+				if outputFormat != lintOutputText {
+					reports = append(reports, newLintReport(result.Path, result, client.Quiet))
+					continue
+				}
 				if client.Quiet && !hasWarningsOrErrors {
 					continue
 				}
 
-				fmt.Fprintf(&message, "==> Linting %s\n", path)
+				fmt.Fprintf(&message, "==> Linting %s", result.Path)
+				if result.Scenario != "" {
+					fmt.Fprintf(&message, " with %s", result.Scenario)
+				}
+				if result.KubeVersion != "" {
+					fmt.Fprintf(&message, " for Kubernetes %s", result.KubeVersion)
+				}
+				fmt.Fprint(&message, "\n")
+
+				for _, f := range result.Fixes {
+					for _, change := range f.Changes {
+						fmt.Fprintf(&message, "[FIXED] %s: %s\n", f.Path, change)
+					}
+					fmt.Fprint(&message, f.Diff())
+				}
 
 				// All the Errors that are generated by a chart
 				// that failed a lint will be included in the
@@ -120,9 +308,8 @@ func newLintCmd(out io.Writer) *cobra.Command {
 						fmt.Fprintf(&message, "%s\n", msg)
 					}
 				}
-
-				if len(result.Errors) != 0 {
-					failed++
+				if len(result.Ignored) > 0 && !client.Quiet {
+					fmt.Fprintf(&message, "%d message(s) ignored by the lint configuration\n", len(result.Ignored))
 				}
 
 				// Adding extra new line here to break up the
@@ -131,15 +318,27 @@ func newLintCmd(out io.Writer) *cobra.Command {
 				fmt.Fprint(&message, "\n")
 			}
 
-			fmt.Fprint(out, message.String())
-
-			summary := fmt.Sprintf("%d chart(s) linted, %d chart(s) failed", len(paths), failed)
+			summary := fmt.Sprintf("%d chart(s) linted, %d chart(s) failed", len(results), failed)
+			switch outputFormat {
+			case lintOutputJSON:
+				err = output.EncodeJSON(out, &lintJSONReport{Charts: reports, Linted: len(results), Failed: failed})
+			case lintOutputSARIF:
+				err = output.EncodeJSON(out, newSARIFLog(reports, append(lint.Rules(), pluginRules...)))
+			default:
+				fmt.Fprint(out, message.String())
+				for _, v := range versions {
+					fmt.Fprintf(out, "Kubernetes %s: %d chart(s) linted, %d chart(s) failed\n", v, versionLinted[v], versionFailed[v])
+				}
+				if failed == 0 && (!client.Quiet || errorsOrWarnings > 0) {
+					fmt.Fprintln(out, summary)
+				}
+			}
+			if err != nil {
+				return err
+			}
 			if failed > 0 {
 				return errors.New(summary)
 			}
-			if !client.Quiet || errorsOrWarnings > 0 {
-				fmt.Fprintln(out, summary)
-			}
 			return nil
 		},
 	}
@@ -149,8 +348,268 @@ func newLintCmd(out io.Writer) *cobra.Command {
 	f.BoolVar(&client.WithSubcharts, "with-subcharts", false, "lint dependent charts")
 	f.BoolVar(&client.Quiet, "quiet", false, "print only warnings and errors")
 	f.BoolVar(&client.SkipSchemaValidation, "skip-schema-validation", false, "if set, disables JSON schema validation")
-	f.StringVar(&kubeVersion, "kube-version", "", "Kubernetes version used for capabilities and deprecation checks")
+	f.StringVar(&kubeVersion, "kube-version", "", "Kubernetes version used for capabilities and deprecation checks, or comma-separated versions the charts are linted for in turn, like 1.27,1.28,1.29")
+	f.StringArrayVar(&client.DisabledRules, "disable-rule", []string{}, "ID of a lint rule not to run (can specify multiple)")
+	f.StringArrayVar(&client.EnabledRules, "enable-rule", []string{}, "ID of an optional lint rule to run (can specify multiple)")
+	f.StringArrayVar(&ruleSeverities, "rule-severity", []string{}, "severity of the failures of a lint rule, like workload-probes=error (can specify multiple)")
+	f.BoolVar(&listRules, "list-rules", false, "list the lint rules and exit")
+	f.BoolVar(&client.Recursive, "recursive", false, "lint the subcharts recursively with the values passed by their parent charts")
+	f.StringArrayVar(&client.Subcharts, "subchart", []string{}, "name or alias of a subchart linted with --recursive, or a pattern like 'common*' (can specify multiple). All the subcharts are linted by default")
+	cmd.MarkFlagsMutuallyExclusive("recursive", "with-subcharts")
+	f.BoolVar(&client.ValuesOnly, "values-only", false, "only check the values against the schemas of the chart and its subcharts, without rendering the templates")
+	cmd.MarkFlagsMutuallyExclusive("values-only", "skip-schema-validation")
+	f.BoolVar(&client.Fix, "fix", false, "apply the safe mechanical fixes to the chart directories before linting them, printing their diffs")
+	f.BoolVar(&client.WithScenarios, "with-scenarios", false, "lint the charts once per test values file of their ci directories, like ci/ingress-values.yaml")
+	f.StringVar(&client.ConfigFile, "config", "", "lint configuration file used instead of the .helmlintrc files of the charts")
+	f.BoolVar(&client.Incremental, "incremental", false, "only re-run the lint rules whose inputs changed since the previous incremental run, reusing the cached messages of the other rules")
+	f.StringArrayVar(&client.ChangedFiles, "changed", nil, "file changed since the previous incremental run, implying --incremental (can specify multiple). The changed files are detected from their modification times by default")
+	f.StringArrayVar(&client.KubeSchemaDirs, "kube-schema-dir", []string{}, "directory of the JSON schemas of custom resources, like <group>/<kind>_<version>.json (can specify multiple)")
+	f.StringVarP(&outputFormat, "output", "o", lintOutputText, fmt.Sprintf("prints the output in the specified format. Allowed values: %s", strings.Join(lintOutputFormats, ", ")))
 	addValueOptionsFlags(f, valueOpts)
 
+	err := cmd.RegisterFlagCompletionFunc("output", func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return lintOutputFormats, cobra.ShellCompDirectiveNoFileComp
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	return cmd
 }
+
+// The output formats of 'helm lint'.
+const (
+	lintOutputText  = "text"
+	lintOutputJSON  = "json"
+	lintOutputSARIF = "sarif"
+)
+
+var lintOutputFormats = []string{lintOutputText, lintOutputJSON, lintOutputSARIF}
+
+// lintJSONReport is the JSON output of 'helm lint'.
+type lintJSONReport struct {
+	Charts []lintReport `json:"charts"`
+	Linted int          `json:"linted"`
+	Failed int          `json:"failed"`
+}
+
+// lintReport is the result of the linting of a chart.
+type lintReport struct {
+	Path     string `json:"path"`
+	Scenario string `json:"scenario,omitempty"`
+	// KubeVersion is the Kubernetes version of the linting, with several
+	// versions of --kube-version.
+	KubeVersion string        `json:"kubeVersion,omitempty"`
+	Failed      bool          `json:"failed"`
+	Messages    []lintMessage `json:"messages"`
+	// Errors are the errors of the charts which could not be linted.
+	Errors []string `json:"errors,omitempty"`
+	// Ignored is the number of messages ignored by the lint configuration.
+	Ignored int `json:"ignored,omitempty"`
+	// Fixed are the paths to the files fixed by --fix.
+	Fixed []string `json:"fixed,omitempty"`
+}
+
+// lintMessage is a message of the linting of a chart.
+type lintMessage struct {
+	Rule     string `json:"rule,omitempty"`
+	Severity string `json:"severity"`
+	Path     string `json:"path"`
+	Line     int    `json:"line,omitempty"`
+	Message  string `json:"message"`
+}
+
+func newLintReport(path string, result *action.LintResult, quiet bool) lintReport {
+	report := lintReport{
+		Path:        path,
+		Scenario:    result.Scenario,
+		KubeVersion: result.KubeVersion,
+		Failed:      len(result.Errors) != 0,
+		Messages:    []lintMessage{},
+		Ignored:     len(result.Ignored),
+	}
+	for _, f := range result.Fixes {
+		report.Fixed = append(report.Fixed, f.Path)
+	}
+	// Like the text output, the errors are part of the messages of the
+	// charts which could be linted
+	if len(result.Messages) == 0 {
+		for _, err := range result.Errors {
+			report.Errors = append(report.Errors, err.Error())
+		}
+	}
+	for _, msg := range result.Messages {
+		if quiet && msg.Severity <= support.InfoSev {
+			continue
+		}
+		report.Messages = append(report.Messages, lintMessage{
+			Rule:     msg.Rule,
+			Severity: support.SeverityName(msg.Severity),
+			Path:     msg.Path,
+			Line:     msg.Line,
+			Message:  msg.Err.Error(),
+		})
+	}
+	return report
+}
+
+// parseRuleSeverities parses the severities of the rules, like
+// "workload-probes=error", by ID.
+func parseRuleSeverities(args []string) (map[string]int, error) {
+	severities := make(map[string]int, len(args))
+	for _, arg := range args {
+		id, name, ok := strings.Cut(arg, "=")
+		if !ok || id == "" {
+			return nil, errors.Errorf("invalid rule severity %q, expected ID=SEVERITY", arg)
+		}
+		severity, err := support.ParseSeverity(name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid rule severity %q", arg)
+		}
+		severities[id] = severity
+	}
+	return severities, nil
+}
+
+// sarifLog is a SARIF 2.1.0 log of the messages of 'helm lint', for code
+// scanning tools.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	ShortDescription     sarifText          `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifText struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId,omitempty"`
+	Level     string          `json:"level"`
+	Message   sarifText       `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// newSARIFLog returns the SARIF log of the reports, describing the rules of
+// their messages.
+func newSARIFLog(reports []lintReport, rules []lint.Rule) *sarifLog {
+	metadata := make(map[string]lint.RuleMetadata, len(rules))
+	for _, r := range rules {
+		md := r.Metadata()
+		metadata[md.ID] = md
+	}
+
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "helm lint",
+			InformationURI: "https://helm.sh/docs/helm/helm_lint/",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+	described := make(map[string]bool)
+	for _, report := range reports {
+		for _, err := range report.Errors {
+			run.Results = append(run.Results, sarifResult{
+				Level:     "error",
+				Message:   sarifText{Text: err},
+				Locations: []sarifLocation{newSARIFLocation(report.Path, "", 0)},
+			})
+		}
+		for _, msg := range report.Messages {
+			if md, ok := metadata[msg.Rule]; ok && !described[msg.Rule] {
+				run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+					ID:                   md.ID,
+					ShortDescription:     sarifText{Text: md.Description},
+					DefaultConfiguration: sarifConfiguration{Level: sarifLevel(md.Severity)},
+				})
+				described[msg.Rule] = true
+			}
+			severity, _ := support.ParseSeverity(msg.Severity)
+			run.Results = append(run.Results, sarifResult{
+				RuleID:    msg.Rule,
+				Level:     sarifLevel(severity),
+				Message:   sarifText{Text: msg.Message},
+				Locations: []sarifLocation{newSARIFLocation(report.Path, msg.Path, msg.Line)},
+			})
+		}
+	}
+	return &sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}
+}
+
+// newSARIFLocation returns the location of a message of the chart at
+// chartPath, in the file path of the chart.
+func newSARIFLocation(chartPath, path string, line int) sarifLocation {
+	// The absolute paths are the directories of the charts, and the files
+	// of the archives are not addressable
+	uri := chartPath
+	if path != "" && !filepath.IsAbs(path) && !strings.HasSuffix(chartPath, ".tgz") && !strings.HasSuffix(chartPath, ".tar.gz") {
+		uri = filepath.Join(chartPath, path)
+	}
+	loc := sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+		ArtifactLocation: sarifArtifactLocation{URI: filepath.ToSlash(uri)},
+	}}
+	if line > 0 {
+		loc.PhysicalLocation.Region = &sarifRegion{StartLine: line}
+	}
+	return loc
+}
+
+// sarifLevel returns the SARIF level of a severity.
+func sarifLevel(severity int) string {
+	switch severity {
+	case support.ErrorSev:
+		return "error"
+	case support.WarningSev:
+		return "warning"
+	case support.InfoSev:
+		return "note"
+	}
+	return "none"
+}
@@ -18,6 +18,9 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -63,6 +66,158 @@ func TestLintCmdWithQuietFlag(t *testing.T) {
 
 }
 
+func TestLintCmdRules(t *testing.T) {
+	testChart := "testdata/testcharts/chart-with-bad-subcharts"
+	tests := []cmdTestCase{{
+		name:   "list the lint rules",
+		cmd:    "lint --list-rules",
+		golden: "output/lint-list-rules.txt",
+	}, {
+		name:   "lint chart with bad dependencies without the dependencies rule",
+		cmd:    fmt.Sprintf("lint --disable-rule dependencies --disable-rule templates %s", testChart),
+		golden: "output/lint-disable-rule.txt",
+	}, {
+		name:      "lint chart disabling an unknown rule",
+		cmd:       fmt.Sprintf("lint --disable-rule unknown %s", testChart),
+		golden:    "output/lint-disable-unknown-rule.txt",
+		wantError: true,
+	}, {
+		name:   "lint chart with optional rules",
+		cmd:    "lint --enable-rule workload-resources --enable-rule workload-image-tag --rule-severity workload-resources=info testdata/testcharts/alpine",
+		golden: "output/lint-enable-rule.txt",
+	}, {
+		name:      "lint chart with an invalid rule severity",
+		cmd:       "lint --rule-severity workload-probes=fatal testdata/testcharts/alpine",
+		golden:    "output/lint-invalid-rule-severity.txt",
+		wantError: true,
+	}, {
+		name:   "lint chart with a lint configuration",
+		cmd:    "lint testdata/testcharts/chart-with-lint-config",
+		golden: "output/lint-config.txt",
+	}, {
+		name:   "lint chart with a lint configuration and overriding flags",
+		cmd:    "lint --set image=nginx --rule-severity workload-image-tag=info --config testdata/testcharts/chart-with-lint-config/.helmlintrc testdata/testcharts/chart-with-lint-config",
+		golden: "output/lint-config-flags.txt",
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdRecursive(t *testing.T) {
+	testChart := "testdata/testcharts/chart-with-subcharts-lint"
+	tests := []cmdTestCase{{
+		name:      "lint chart with subcharts recursively",
+		cmd:       fmt.Sprintf("lint --recursive %s", testChart),
+		golden:    "output/lint-recursive.txt",
+		wantError: true,
+	}, {
+		name:      "lint chart with included subcharts recursively in JSON",
+		cmd:       fmt.Sprintf("lint --recursive --subchart child -o json %s", testChart),
+		golden:    "output/lint-recursive-subchart.json",
+		wantError: true,
+	}, {
+		name:      "lint chart recursively with subcharts",
+		cmd:       fmt.Sprintf("lint --recursive --with-subcharts %s", testChart),
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdValuesOnly(t *testing.T) {
+	testChart := "testdata/testcharts/chart-with-subcharts-lint"
+	tests := []cmdTestCase{{
+		name:      "lint values against the schemas of the subcharts",
+		cmd:       fmt.Sprintf("lint --values-only %s", testChart),
+		golden:    "output/lint-values-only.txt",
+		wantError: true,
+	}, {
+		name:   "lint values files against the schemas of the subcharts",
+		cmd:    fmt.Sprintf("lint --values-only -f testdata/testcharts/chart-with-subcharts-lint-values.yaml %s", testChart),
+		golden: "output/lint-values-only-files.txt",
+	}, {
+		name:      "lint values without schema validation",
+		cmd:       fmt.Sprintf("lint --values-only --skip-schema-validation %s", testChart),
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdWithScenarios(t *testing.T) {
+	testChart := "testdata/testcharts/chart-with-scenarios"
+	tests := []cmdTestCase{{
+		name:      "lint chart with scenarios",
+		cmd:       fmt.Sprintf("lint --with-scenarios --enable-rule manifest-schemas %s", testChart),
+		golden:    "output/lint-with-scenarios.txt",
+		wantError: true,
+	}, {
+		name:      "lint chart with scenarios in JSON",
+		cmd:       fmt.Sprintf("lint --with-scenarios --enable-rule manifest-schemas -o json %s", testChart),
+		golden:    "output/lint-with-scenarios.json",
+		wantError: true,
+	}, {
+		name:   "lint chart without scenarios",
+		cmd:    fmt.Sprintf("lint %s", testChart),
+		golden: "output/lint-without-scenarios.txt",
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintCmdFix(t *testing.T) {
+	chartDir := filepath.Join(t.TempDir(), "fixme")
+	if err := os.MkdirAll(filepath.Join(chartDir, "templates"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte("apiVersion: v2\nname: fixme\nversion: 0.1.0\nappVersion: 1.10\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, out, err := executeActionCommand(fmt.Sprintf("lint --fix %s", chartDir))
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+	for _, expected := range []string{
+		"[FIXED] Chart.yaml: quote the appVersion 1.10\n",
+		"--- a/Chart.yaml\n+++ b/Chart.yaml\n",
+		"-appVersion: 1.10\n+appVersion: \"1.10\"\n",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected %q in the output, got:\n%s", expected, out)
+		}
+	}
+}
+
+func TestLintCmdOutput(t *testing.T) {
+	testChart := "testdata/testcharts/chart-with-bad-subcharts"
+	tests := []cmdTestCase{{
+		name:      "lint chart with bad subcharts in JSON",
+		cmd:       fmt.Sprintf("lint -o json %s", testChart),
+		golden:    "output/lint-chart-with-bad-subcharts.json",
+		wantError: true,
+	}, {
+		name:      "lint chart with bad subcharts in SARIF",
+		cmd:       fmt.Sprintf("lint -o sarif %s", testChart),
+		golden:    "output/lint-chart-with-bad-subcharts.sarif",
+		wantError: true,
+	}, {
+		name:   "lint good chart in JSON using --quiet flag",
+		cmd:    "lint -o json --quiet testdata/testcharts/alpine",
+		golden: "output/lint-quiet.json",
+	}, {
+		name:      "lint non-existent chart in JSON",
+		cmd:       "lint -o json thischartdoesntexist/",
+		golden:    "output/lint-non-existent-chart.json",
+		wantError: true,
+	}, {
+		name:      "lint with an invalid output format",
+		cmd:       fmt.Sprintf("lint -o yaml %s", testChart),
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestLintOutputCompletion(t *testing.T) {
+	checkFileCompletion(t, "lint --output", false)
+}
+
 func TestLintCmdWithKubeVersionFlag(t *testing.T) {
 	testChart := "testdata/testcharts/chart-with-deprecated-api"
 	tests := []cmdTestCase{{
@@ -87,6 +242,20 @@ func TestLintCmdWithKubeVersionFlag(t *testing.T) {
 		cmd:       fmt.Sprintf("lint --kube-version 1.21.0 --strict %s", testChart),
 		golden:    "output/lint-chart-with-deprecated-api-old-k8s.txt",
 		wantError: false,
+	}, {
+		name:      "lint chart with deprecated api version for several kube versions",
+		cmd:       fmt.Sprintf("lint --kube-version 1.21,1.22,1.25 --strict %s", testChart),
+		golden:    "output/lint-chart-with-deprecated-api-kube-versions.txt",
+		wantError: true,
+	}, {
+		name:   "lint chart with deprecated api version for several kube versions in JSON",
+		cmd:    fmt.Sprintf("lint --kube-version 1.21,1.25 -o json %s", testChart),
+		golden: "output/lint-chart-with-deprecated-api-kube-versions.json",
+	}, {
+		name:      "lint chart with an invalid kube version among several",
+		cmd:       fmt.Sprintf("lint --kube-version 1.21,latest %s", testChart),
+		golden:    "output/lint-invalid-kube-versions.txt",
+		wantError: true,
 	}}
 	runTestCmd(t, tests)
 }
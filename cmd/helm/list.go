@@ -19,6 +19,7 @@ package main
 import (
 	"fmt"
 	"io"
+	"log"
 	"os"
 	"strconv"
 
@@ -29,6 +30,7 @@ import (
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/cli/output"
 	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
 )
 
 var listHelp = `
@@ -56,11 +58,27 @@ By default, up to 256 items may be returned. To limit this, use the '--max' flag
 Setting '--max' to 0 will not return all results. Rather, it will return the
 server's default, which may be much higher than 256. Pairing the '--max'
 flag with the '--offset' flag allows you to page through results.
+
+The '--all-contexts' flag lists the releases in every context of the
+kubeconfig, and the '--contexts' flag in the named contexts only, with the
+context of each release in a CONTEXT column. The flags like '--max' apply to
+each context, and the contexts that cannot be listed are reported as warnings.
+
+    $ helm list --contexts prod-eu,prod-us -A
+
+The labels and the annotations set on the releases with '--labels' and
+'--annotations' on install and upgrade are printed with '--output json' and
+'--output yaml'. Use '--selector' to list the releases with labels, and
+'--annotations' to list the releases with annotations:
+
+    $ helm list --selector team=web --annotations ticket=OPS-123
 `
 
 func newListCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	client := action.NewList(cfg)
 	var outfmt output.Format
+	var allContexts bool
+	var contexts []string
 
 	cmd := &cobra.Command{
 		Use:               "list",
@@ -77,9 +95,21 @@ func newListCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			}
 			client.SetStateMask()
 
-			results, err := client.Run()
-			if err != nil {
-				return err
+			var results []*release.Release
+			var releaseContexts []string
+			if allContexts || len(contexts) > 0 {
+				if allContexts {
+					var err error
+					if contexts, err = settings.KubeContexts(); err != nil {
+						return err
+					}
+				}
+				results, releaseContexts = listContexts(cfg, client, contexts)
+			} else {
+				var err error
+				if results, err = client.Run(); err != nil {
+					return err
+				}
 			}
 
 			if client.Short {
@@ -105,7 +135,7 @@ func newListCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 				}
 			}
 
-			return outfmt.Write(out, newReleaseListWriter(results, client.TimeFormat, client.NoHeaders))
+			return outfmt.Write(out, newReleaseListWriter(results, releaseContexts, client.TimeFormat, client.NoHeaders))
 		},
 	}
 
@@ -123,16 +153,71 @@ func newListCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.BoolVar(&client.Failed, "failed", false, "show failed releases")
 	f.BoolVar(&client.Pending, "pending", false, "show pending releases")
 	f.BoolVarP(&client.AllNamespaces, "all-namespaces", "A", false, "list releases across all namespaces")
+	f.BoolVar(&allContexts, "all-contexts", false, "list releases across all the contexts of the kubeconfig")
+	f.StringSliceVar(&contexts, "contexts", nil, "list releases across the given contexts of the kubeconfig (can specify multiple or separate values with commas: ctx1,ctx2)")
 	f.IntVarP(&client.Limit, "max", "m", 256, "maximum number of releases to fetch")
 	f.IntVar(&client.Offset, "offset", 0, "next release index in the list, used to offset from start value")
 	f.StringVarP(&client.Filter, "filter", "f", "", "a regular expression (Perl compatible). Any releases that match the expression will be included in the results")
+	f.Int64Var(&client.PageSize, "page-size", 0, "number of release records to request from the storage backend at a time. All records are requested at once if it is 0")
 	f.StringVarP(&client.Selector, "selector", "l", "", "Selector (label query) to filter on, supports '=', '==', and '!='.(e.g. -l key1=value1,key2=value2). Works only for secret(default) and configmap storage backends.")
+	f.StringToStringVar(&client.Annotations, "annotations", nil, "only list the releases with these annotations, like team=web,ticket=OPS-123")
 	bindOutputFlag(cmd, &outfmt)
+	cmd.MarkFlagsMutuallyExclusive("all-contexts", "contexts")
+
+	err := cmd.RegisterFlagCompletionFunc("contexts", func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+		contexts, err := settings.KubeContexts()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		return contexts, cobra.ShellCompDirectiveNoFileComp
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	return cmd
 }
 
+// listContexts lists the releases in each one of the kubeconfig contexts,
+// returning the releases with their contexts. The contexts that cannot be
+// listed are reported as warnings.
+func listContexts(cfg *action.Configuration, client *action.List, contexts []string) ([]*release.Release, []string) {
+	results := client.RunContexts(contexts, func(kubeContext string) (*action.Configuration, error) {
+		getter := settings.RESTClientGetterForContext(kubeContext)
+		namespace := ""
+		if !client.AllNamespaces {
+			var err error
+			if namespace, _, err = getter.ToRawKubeConfigLoader().Namespace(); err != nil {
+				return nil, err
+			}
+		}
+		contextCfg := &action.Configuration{
+			ReleaseEncryptor:   cfg.ReleaseEncryptor,
+			ReleaseCompression: cfg.ReleaseCompression,
+		}
+		if err := contextCfg.Init(getter, namespace, os.Getenv("HELM_DRIVER"), debug); err != nil {
+			return nil, err
+		}
+		return contextCfg, nil
+	})
+
+	var releases []*release.Release
+	releaseContexts := []string{}
+	for _, res := range results {
+		if res.Err != nil {
+			warning("unable to list the releases in context %q: %s", res.Context, res.Err)
+			continue
+		}
+		for _, rel := range res.Releases {
+			releases = append(releases, rel)
+			releaseContexts = append(releaseContexts, res.Context)
+		}
+	}
+	return releases, releaseContexts
+}
+
 type releaseElement struct {
+	Context    string `json:"context,omitempty"`
 	Name       string `json:"name"`
 	Namespace  string `json:"namespace"`
 	Revision   string `json:"revision"`
@@ -140,24 +225,32 @@ type releaseElement struct {
 	Status     string `json:"status"`
 	Chart      string `json:"chart"`
 	AppVersion string `json:"app_version"`
+	// Labels are the labels of the release set by the users.
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
 }
 
 type releaseListWriter struct {
-	releases  []releaseElement
-	noHeaders bool
+	releases     []releaseElement
+	withContexts bool
+	noHeaders    bool
 }
 
-func newReleaseListWriter(releases []*release.Release, timeFormat string, noHeaders bool) *releaseListWriter {
+// newReleaseListWriter returns the writer of the releases, with the context of
+// each release if contexts is not nil.
+func newReleaseListWriter(releases []*release.Release, contexts []string, timeFormat string, noHeaders bool) *releaseListWriter {
 	// Initialize the array so no results returns an empty array instead of null
 	elements := make([]releaseElement, 0, len(releases))
-	for _, r := range releases {
+	for i, r := range releases {
 		element := releaseElement{
-			Name:       r.Name,
-			Namespace:  r.Namespace,
-			Revision:   strconv.Itoa(r.Version),
-			Status:     r.Info.Status.String(),
-			Chart:      formatChartname(r.Chart),
-			AppVersion: formatAppVersion(r.Chart),
+			Name:        r.Name,
+			Namespace:   r.Namespace,
+			Revision:    strconv.Itoa(r.Version),
+			Status:      r.Info.Status.String(),
+			Chart:       formatChartname(r.Chart),
+			AppVersion:  formatAppVersion(r.Chart),
+			Labels:      driver.FilterSystemLabels(r.Labels),
+			Annotations: r.Annotations,
 		}
 
 		t := "-"
@@ -169,19 +262,30 @@ func newReleaseListWriter(releases []*release.Release, timeFormat string, noHead
 			}
 		}
 		element.Updated = t
+		if contexts != nil {
+			element.Context = contexts[i]
+		}
 
 		elements = append(elements, element)
 	}
-	return &releaseListWriter{elements, noHeaders}
+	return &releaseListWriter{elements, contexts != nil, noHeaders}
 }
 
 func (r *releaseListWriter) WriteTable(out io.Writer) error {
 	table := uitable.New()
 	if !r.noHeaders {
-		table.AddRow("NAME", "NAMESPACE", "REVISION", "UPDATED", "STATUS", "CHART", "APP VERSION")
+		if r.withContexts {
+			table.AddRow("CONTEXT", "NAME", "NAMESPACE", "REVISION", "UPDATED", "STATUS", "CHART", "APP VERSION")
+		} else {
+			table.AddRow("NAME", "NAMESPACE", "REVISION", "UPDATED", "STATUS", "CHART", "APP VERSION")
+		}
 	}
-	for _, r := range r.releases {
-		table.AddRow(r.Name, r.Namespace, r.Revision, r.Updated, r.Status, r.Chart, r.AppVersion)
+	for _, rel := range r.releases {
+		if r.withContexts {
+			table.AddRow(rel.Context, rel.Name, rel.Namespace, rel.Revision, rel.Updated, rel.Status, rel.Chart, rel.AppVersion)
+		} else {
+			table.AddRow(rel.Name, rel.Namespace, rel.Revision, rel.Updated, rel.Status, rel.Chart, rel.AppVersion)
+		}
 	}
 	return output.EncodeTable(out, table)
 }
@@ -17,6 +17,8 @@ limitations under the License.
 package main
 
 import (
+	"bytes"
+	"strings"
 	"testing"
 
 	"helm.sh/helm/v3/pkg/chart"
@@ -237,6 +239,72 @@ func TestListCmd(t *testing.T) {
 	runTestCmd(t, tests)
 }
 
+func TestReleaseListWriterContexts(t *testing.T) {
+	rels := []*release.Release{
+		release.Mock(&release.MockReleaseOptions{Name: "web", Namespace: "default"}),
+		release.Mock(&release.MockReleaseOptions{Name: "web", Namespace: "default"}),
+	}
+	writer := newReleaseListWriter(rels, []string{"prod", "staging"}, "", false)
+
+	var out bytes.Buffer
+	if err := writer.WriteTable(&out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "CONTEXT") || !strings.HasPrefix(lines[1], "prod") || !strings.HasPrefix(lines[2], "staging") {
+		t.Errorf("expected the releases with their contexts, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := writer.WriteJSON(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `"context":"staging"`) {
+		t.Errorf("expected the contexts in the JSON output, got %s", out.String())
+	}
+
+	// The contexts are omitted when listing a single context
+	out.Reset()
+	if err := newReleaseListWriter(rels, nil, "", false).WriteJSON(&out); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), `"context"`) {
+		t.Errorf("expected no contexts in the JSON output, got %s", out.String())
+	}
+}
+
+func TestListLabelsAndAnnotations(t *testing.T) {
+	rels := []*release.Release{
+		release.Mock(&release.MockReleaseOptions{Name: "starlord"}),
+		release.Mock(&release.MockReleaseOptions{Name: "groot"}),
+	}
+	rels[0].Labels = map[string]string{"team": "guardians", "owner": "helm"}
+	rels[0].Annotations = map[string]string{"example.com/ticket": "GOTG-3"}
+	for _, rel := range rels {
+		rel.Info.LastDeployed = time.Unix(1452902400, 0).UTC()
+	}
+
+	tests := []cmdTestCase{{
+		name:   "list releases with labels and annotations in json",
+		cmd:    "list -o json",
+		golden: "output/list-labels-annotations.json",
+		rels:   rels,
+	}, {
+		name:   "list releases filtered by annotations",
+		cmd:    "list --annotations example.com/ticket=GOTG-3",
+		golden: "output/list-annotations.txt",
+		rels:   rels,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestListContextsFlags(t *testing.T) {
+	_, _, err := executeActionCommand("list --all-contexts --contexts prod")
+	if err == nil {
+		t.Error("expected --all-contexts and --contexts to be mutually exclusive")
+	}
+}
+
 func TestListOutputCompletion(t *testing.T) {
 	outputFlagCompletionTest(t, "list")
 }
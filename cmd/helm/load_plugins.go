@@ -32,6 +32,7 @@ import (
 	"sigs.k8s.io/yaml"
 
 	"helm.sh/helm/v3/pkg/plugin"
+	"helm.sh/helm/v3/pkg/plugin/sdk"
 )
 
 const (
@@ -80,6 +81,10 @@ func loadPlugins(baseCmd *cobra.Command, out io.Writer) {
 					return err
 				}
 
+				if plug.IsWasm() {
+					return callPluginWasm(plug, u, out)
+				}
+
 				// Call setupEnv before PrepareCommand because
 				// PrepareCommand uses os.ExpandEnv and expects the
 				// setupEnv vars.
@@ -90,7 +95,14 @@ func loadPlugins(baseCmd *cobra.Command, out io.Writer) {
 					return errors.Errorf("plugin %q exited with error", md.Name)
 				}
 
-				return callPluginExecutable(md.Name, main, argv, out)
+				req := plug.NewRequest(settings, sdk.KindCommand)
+				if req != nil {
+					req.Command = &sdk.CommandRequest{Args: u}
+					if md.IgnoreFlags {
+						req.Command.Args = nil
+					}
+				}
+				return callPluginExecutable(md.Name, main, argv, req, md.Capabilities, out)
 			},
 			// This passes all the flags to the subcommand.
 			DisableFlagParsing: true,
@@ -121,16 +133,27 @@ func processParent(cmd *cobra.Command, args []string) ([]string, error) {
 }
 
 // This function is used to setup the environment for the plugin and then
-// call the executable specified by the parameter 'main'
-func callPluginExecutable(pluginName string, main string, argv []string, out io.Writer) error {
+// call the executable specified by the parameter 'main', passing it the
+// request of the plugin API, if any, in the sandbox of the capabilities of
+// the plugin, if it declares them.
+func callPluginExecutable(pluginName string, main string, argv []string, req *sdk.Request, capabilities *plugin.Capabilities, out io.Writer) error {
 	env := os.Environ()
 	for k, v := range settings.EnvVars() {
 		env = append(env, fmt.Sprintf("%s=%s", k, v))
 	}
+	env, err := plugin.RequestEnv(env, req)
+	if err != nil {
+		return err
+	}
 
 	mainCmdExp := os.ExpandEnv(main)
 	prog := exec.Command(mainCmdExp, argv...)
 	prog.Env = env
+	cleanup, err := plugin.Sandbox(prog, pluginName, capabilities)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
 	prog.Stdin = os.Stdin
 	prog.Stdout = out
 	prog.Stderr = os.Stderr
@@ -346,7 +369,7 @@ func pluginDynamicComp(plug *plugin.Plugin, cmd *cobra.Command, args []string, t
 
 	cobra.CompDebugln(fmt.Sprintf("calling %s with args %v", main, argv), settings.Debug)
 	buf := new(bytes.Buffer)
-	if err := callPluginExecutable(md.Name, main, argv, buf); err != nil {
+	if err := callPluginExecutable(md.Name, main, argv, nil, md.Capabilities, buf); err != nil {
 		// The dynamic completion file is optional for a plugin, so this error is ok.
 		cobra.CompDebugln(fmt.Sprintf("Unable to call %s: %v", main, err.Error()), settings.Debug)
 		return nil, cobra.ShellCompDirectiveDefault
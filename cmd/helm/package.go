@@ -26,6 +26,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/cli/output"
 	"helm.sh/helm/v3/pkg/cli/values"
 	"helm.sh/helm/v3/pkg/downloader"
 	"helm.sh/helm/v3/pkg/getter"
@@ -45,11 +46,23 @@ provide '--keyring path/to/secret/keys' and '--key keyname'.
 
 If '--keyring' is not specified, Helm usually defaults to the public keyring
 unless your environment is otherwise configured.
+
+To sign a chart with a sigstore signature, use the '--sign-cosign' flag. The
+signature is written to a cosign bundle alongside the chart archive, which
+'cosign verify-blob --bundle' verifies. The chart is signed with the private
+key given by '--cosign-key', or keyless with a certificate issued to the OIDC
+identity token given by '--identity-token' or $SIGSTORE_ID_TOKEN.
+
+  $ helm package --sign-cosign ./mychart --cosign-key cosign.key
+
+With '--output json' or '--output yaml', the paths of the chart archives are
+printed once packaged.
 `
 
 func newPackageCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	client := action.NewPackage()
 	valueOpts := &values.Options{}
+	var outfmt output.Format
 
 	cmd := &cobra.Command{
 		Use:   "package [CHART_PATH] [...]",
@@ -75,6 +88,7 @@ func newPackageCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 				return err
 			}
 
+			w := &packageWriter{paths: []string{}}
 			for i := 0; i < len(args); i++ {
 				path, err := filepath.Abs(args[i])
 				if err != nil {
@@ -104,14 +118,21 @@ func newPackageCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 				if err != nil {
 					return err
 				}
-				fmt.Fprintf(out, "Successfully packaged chart and saved it to: %s\n", p)
+				w.paths = append(w.paths, p)
+				if outfmt == output.Table {
+					fmt.Fprintf(out, "Successfully packaged chart and saved it to: %s\n", p)
+				}
 			}
-			return nil
+			if outfmt == output.Table {
+				return nil
+			}
+			return outfmt.Write(out, w)
 		},
 	}
 
 	f := cmd.Flags()
 	f.BoolVar(&client.Sign, "sign", false, "use a PGP private key to sign this package")
+	f.BoolVar(&client.SignCosign, "sign-cosign", false, "sign this package with a sigstore signature, written to a cosign bundle")
 	f.StringVar(&client.Key, "key", "", "name of the key to use when signing. Used if --sign is true")
 	f.StringVar(&client.Keyring, "keyring", defaultKeyring(), "location of a public keyring")
 	f.StringVar(&client.PassphraseFile, "passphrase-file", "", `location of a file which contains the passphrase for the signing key. Use "-" in order to read from stdin.`)
@@ -119,6 +140,28 @@ func newPackageCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.StringVar(&client.AppVersion, "app-version", "", "set the appVersion on the chart to this version")
 	f.StringVarP(&client.Destination, "destination", "d", ".", "location to write the chart.")
 	f.BoolVarP(&client.DependencyUpdate, "dependency-update", "u", false, `update dependencies from "Chart.yaml" to dir "charts/" before packaging`)
+	addCosignSignFlags(f, &client.Cosign)
+	bindOutputFlag(cmd, &outfmt)
 
 	return cmd
 }
+
+// packageWriter writes the paths of the packaged chart archives.
+type packageWriter struct {
+	paths []string
+}
+
+func (w *packageWriter) WriteTable(out io.Writer) error {
+	for _, p := range w.paths {
+		fmt.Fprintf(out, "Successfully packaged chart and saved it to: %s\n", p)
+	}
+	return nil
+}
+
+func (w *packageWriter) WriteJSON(out io.Writer) error {
+	return output.EncodeJSON(out, w.paths)
+}
+
+func (w *packageWriter) WriteYAML(out io.Writer) error {
+	return output.EncodeYAML(out, w.paths)
+}
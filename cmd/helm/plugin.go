@@ -24,6 +24,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"helm.sh/helm/v3/pkg/plugin"
+	"helm.sh/helm/v3/pkg/plugin/sdk"
 )
 
 const pluginHelp = `
@@ -47,6 +48,12 @@ func newPluginCmd(out io.Writer) *cobra.Command {
 
 // runHook will execute a plugin hook.
 func runHook(p *plugin.Plugin, event string) error {
+	return runHookWithInput(p, event, nil, os.Stdout)
+}
+
+// runHookWithInput executes a plugin hook, with stdin as its standard input
+// and stdout as its standard output.
+func runHookWithInput(p *plugin.Plugin, event string, stdin io.Reader, stdout io.Writer) error {
 	hook := p.Metadata.Hooks[event]
 	if hook == "" {
 		return nil
@@ -60,7 +67,22 @@ func runHook(p *plugin.Plugin, event string) error {
 	debug("running %s hook: %s", event, prog)
 
 	plugin.SetupPluginEnv(settings, p.Metadata.Name, p.Dir)
-	prog.Stdout, prog.Stderr = os.Stdout, os.Stderr
+	req := p.NewRequest(settings, sdk.KindHook)
+	if req != nil {
+		req.Hook = &sdk.HookRequest{Event: event}
+	}
+	env, err := plugin.RequestEnv(os.Environ(), req)
+	if err != nil {
+		return err
+	}
+	prog.Env = env
+	cleanup, err := plugin.Sandbox(prog, p.Metadata.Name, p.Metadata.Capabilities)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	prog.Stdin = stdin
+	prog.Stdout, prog.Stderr = stdout, os.Stderr
 	if err := prog.Run(); err != nil {
 		if eerr, ok := err.(*exec.ExitError); ok {
 			os.Stderr.Write(eerr.Stderr)
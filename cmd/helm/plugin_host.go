@@ -0,0 +1,81 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/plugin"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// pluginHost is the host API of the WebAssembly plugins, backed by the
+// settings.
+type pluginHost struct{}
+
+func (pluginHost) Settings() map[string]string {
+	return settings.EnvVars()
+}
+
+func (pluginHost) Repositories() ([]plugin.Repository, error) {
+	f, err := repo.LoadFile(settings.RepositoryConfig)
+	if os.IsNotExist(errors.Cause(err)) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	repos := make([]plugin.Repository, 0, len(f.Repositories))
+	for _, r := range f.Repositories {
+		repos = append(repos, plugin.Repository{Name: r.Name, URL: r.URL})
+	}
+	return repos, nil
+}
+
+func (pluginHost) RegistryCredentials(registry string) (plugin.Credentials, error) {
+	client, err := newDefaultRegistryClient(false)
+	if err != nil {
+		return plugin.Credentials{}, err
+	}
+	username, password, err := client.Credential(registry)
+	return plugin.Credentials{Username: username, Password: password}, err
+}
+
+// callPluginWasm runs the WebAssembly module of the plugin with the
+// arguments.
+func callPluginWasm(plug *plugin.Plugin, argv []string, out io.Writer) error {
+	code, err := plug.RunWasm(context.Background(), plugin.WasmConfig{
+		Args:   argv,
+		Stdin:  os.Stdin,
+		Stdout: out,
+		Stderr: os.Stderr,
+		Host:   pluginHost{},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "plugin %q failed", plug.Metadata.Name)
+	}
+	if code != 0 {
+		return pluginError{
+			error: errors.Errorf("plugin %q exited with error", plug.Metadata.Name),
+			code:  code,
+		}
+	}
+	return nil
+}
@@ -18,22 +18,49 @@ package main
 import (
 	"fmt"
 	"io"
+	"os"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/plugin"
 	"helm.sh/helm/v3/pkg/plugin/installer"
 )
 
 type pluginInstallOptions struct {
-	source  string
-	version string
+	source      string
+	version     string
+	unsandboxed bool
 }
 
 const pluginInstallDesc = `
-This command allows you to install a plugin from a url to a VCS repo or a local path.
+This command allows you to install a plugin from a url to a VCS repo, an OCI
+registry or a local path.
+
+The plugins of OCI registries, like 'oci://registry.example.com/plugins/myplugin',
+are installed from the tag or the digest of the reference, or else from the
+highest tag matching --version. The archive of the platform of Helm is selected
+from the index of the plugin, if it has one. A reference with a digest, like
+'oci://registry.example.com/plugins/myplugin@sha256:...', pins the plugin: it is
+not changed by 'helm plugin update'. The other plugins are updated when their
+reference resolves to another digest.
+
+The plugin archives downloaded from a url are verified with their signatures,
+published alongside them: the ASCII armored detached GPG signature
+'<archive>.asc', checked against the keyring of $HELM_PLUGIN_KEYRING, and the
+cosign bundle '<archive>.cosign.bundle', checked against the signature policy of
+$HELM_SIGNATURE_POLICY. The archives with an invalid signature, or without one
+of the signatures declared in the 'signatures' of their plugin.yaml, are
+rejected. The plugins of OCI registries are verified with the cosign signature
+of their manifest. The plugins installed without a trusted signature, including
+the plugins of VCS repositories, are installed with a warning, or rejected if
+$HELM_PLUGIN_VERIFY_POLICY is 'deny'. The local plugins are trusted.
+
+The plugins are run in a sandbox restricting them to the capabilities declared
+in their plugin.yaml. The plugins declaring no capabilities run with all the
+access of Helm: they are rejected unless --unsandboxed is set.
 `
 
 func newPluginInstallCmd(out io.Writer) *cobra.Command {
@@ -60,6 +87,7 @@ func newPluginInstallCmd(out io.Writer) *cobra.Command {
 		},
 	}
 	cmd.Flags().StringVar(&o.version, "version", "", "specify a version constraint. If this is not specified, the latest version is installed")
+	cmd.Flags().BoolVar(&o.unsandboxed, "unsandboxed", false, "install a plugin declaring no capabilities, run with all the access of Helm")
 	return cmd
 }
 
@@ -75,6 +103,23 @@ func (o *pluginInstallOptions) run(out io.Writer) error {
 	if err != nil {
 		return err
 	}
+	verify, err := pluginVerifyOptions()
+	if err != nil {
+		return err
+	}
+	switch i := i.(type) {
+	case *installer.HTTPInstaller:
+		i.Verify = verify
+	case *installer.OCIInstaller:
+		i.Verify = verify
+		if i.RegistryClient, err = newDefaultRegistryClient(false); err != nil {
+			return err
+		}
+	case *installer.VCSInstaller:
+		if err := verify.Unsigned(o.source, errors.New("the plugins of VCS repositories are not signed")); err != nil {
+			return err
+		}
+	}
 	if err := installer.Install(i); err != nil {
 		return err
 	}
@@ -84,11 +129,55 @@ func (o *pluginInstallOptions) run(out io.Writer) error {
 	if err != nil {
 		return errors.Wrap(err, "plugin is installed but unusable")
 	}
+	if unsandboxed(p) {
+		if !o.unsandboxed {
+			if err := os.RemoveAll(p.Dir); err != nil {
+				return err
+			}
+			return errors.Errorf("plugin %q declares no capabilities and would run with all the access of Helm: install it with --unsandboxed to allow it", p.Metadata.Name)
+		}
+		warning("plugin %q declares no capabilities, it runs with all the access of Helm", p.Metadata.Name)
+	}
 
 	if err := runHook(p, plugin.Install); err != nil {
 		return err
 	}
 
 	fmt.Fprintf(out, "Installed plugin: %s\n", p.Metadata.Name)
+	if p.Metadata.Capabilities != nil {
+		fmt.Fprintf(out, "Capabilities: %s\n", p.Metadata.Capabilities)
+	}
 	return nil
 }
+
+// unsandboxed returns whether the plugin is run outside of the sandbox, its
+// commands declaring no capabilities. The WebAssembly modules are always
+// sandboxed.
+func unsandboxed(p *plugin.Plugin) bool {
+	return p.Metadata.Capabilities == nil && p.Metadata.Runtime != plugin.RuntimeWasm
+}
+
+// pluginVerifyOptions returns the options verifying the plugin archives, from
+// the settings.
+func pluginVerifyOptions() (*installer.VerifyOptions, error) {
+	o := &installer.VerifyOptions{
+		Policy:  settings.PluginVerifyPolicy,
+		Keyring: settings.PluginKeyring,
+		Warn:    warning,
+	}
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	if o.Keyring == "" {
+		o.Keyring = defaultKeyring()
+	}
+	if _, err := os.Stat(o.Keyring); err != nil {
+		o.Keyring = ""
+	}
+	policy, err := action.LoadSignaturePolicy(settings)
+	if err != nil {
+		return nil, err
+	}
+	o.SignaturePolicy = policy
+	return o, nil
+}
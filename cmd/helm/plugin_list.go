@@ -22,10 +22,12 @@ import (
 	"github.com/gosuri/uitable"
 	"github.com/spf13/cobra"
 
+	"helm.sh/helm/v3/pkg/cli/output"
 	"helm.sh/helm/v3/pkg/plugin"
 )
 
 func newPluginListCmd(out io.Writer) *cobra.Command {
+	var outfmt output.Format
 	cmd := &cobra.Command{
 		Use:               "list",
 		Aliases:           []string{"ls"},
@@ -38,18 +40,53 @@ func newPluginListCmd(out io.Writer) *cobra.Command {
 				return err
 			}
 
-			table := uitable.New()
-			table.AddRow("NAME", "VERSION", "DESCRIPTION")
-			for _, p := range plugins {
-				table.AddRow(p.Metadata.Name, p.Metadata.Version, p.Metadata.Description)
-			}
-			fmt.Fprintln(out, table)
-			return nil
+			return outfmt.Write(out, newPluginListWriter(plugins))
 		},
 	}
+	bindOutputFlag(cmd, &outfmt)
 	return cmd
 }
 
+// pluginListElement is an installed plugin, as listed by 'helm plugin list'.
+type pluginListElement struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+}
+
+type pluginListWriter struct {
+	plugins []pluginListElement
+}
+
+func newPluginListWriter(plugins []*plugin.Plugin) *pluginListWriter {
+	w := &pluginListWriter{plugins: make([]pluginListElement, 0, len(plugins))}
+	for _, p := range plugins {
+		w.plugins = append(w.plugins, pluginListElement{
+			Name:        p.Metadata.Name,
+			Version:     p.Metadata.Version,
+			Description: p.Metadata.Description,
+		})
+	}
+	return w
+}
+
+func (w *pluginListWriter) WriteTable(out io.Writer) error {
+	table := uitable.New()
+	table.AddRow("NAME", "VERSION", "DESCRIPTION")
+	for _, p := range w.plugins {
+		table.AddRow(p.Name, p.Version, p.Description)
+	}
+	return output.EncodeTable(out, table)
+}
+
+func (w *pluginListWriter) WriteJSON(out io.Writer) error {
+	return output.EncodeJSON(out, w.plugins)
+}
+
+func (w *pluginListWriter) WriteYAML(out io.Writer) error {
+	return output.EncodeYAML(out, w.plugins)
+}
+
 // Returns all plugins from plugins, except those with names matching ignoredPluginNames
 func filterPlugins(plugins []*plugin.Plugin, ignoredPluginNames []string) []*plugin.Plugin {
 	// if ignoredPluginNames is nil, just return plugins
@@ -18,6 +18,7 @@ package main
 import (
 	"bytes"
 	"os"
+	"path/filepath"
 	"runtime"
 	"sort"
 	"strings"
@@ -438,6 +439,27 @@ func TestPluginInstallFileCompletion(t *testing.T) {
 	checkFileCompletion(t, "plugin install mypath", false)
 }
 
+func TestPluginInstallUnsandboxed(t *testing.T) {
+	plugins := t.TempDir()
+	t.Setenv("HELM_PLUGINS", plugins)
+
+	_, _, err := executeActionCommand("plugin install testdata/testplugin")
+	if err == nil || !strings.Contains(err.Error(), `plugin "testplugin" declares no capabilities`) {
+		t.Fatalf("expected the plugin without capabilities to be rejected, got %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(plugins, "testplugin")); !os.IsNotExist(err) {
+		t.Errorf("expected the rejected plugin to be removed, got %v", err)
+	}
+
+	_, out, err := executeActionCommand("plugin install --unsandboxed testdata/testplugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Installed plugin: testplugin") {
+		t.Errorf("expected the plugin to be installed, got %q", out)
+	}
+}
+
 func TestPluginListFileCompletion(t *testing.T) {
 	checkFileCompletion(t, "plugin list", false)
 }
@@ -100,6 +100,14 @@ func updatePlugin(p *plugin.Plugin) error {
 	if err != nil {
 		return err
 	}
+	if i, ok := i.(*installer.OCIInstaller); ok {
+		if i.Verify, err = pluginVerifyOptions(); err != nil {
+			return err
+		}
+		if i.RegistryClient, err = newDefaultRegistryClient(false); err != nil {
+			return err
+		}
+	}
 	if err := installer.Update(i); err != nil {
 		return err
 	}
@@ -109,6 +117,9 @@ func updatePlugin(p *plugin.Plugin) error {
 	if err != nil {
 		return err
 	}
+	if unsandboxed(updatedPlugin) {
+		warning("plugin %q declares no capabilities, it runs with all the access of Helm", updatedPlugin.Metadata.Name)
+	}
 
 	return runHook(updatedPlugin, plugin.Update)
 }
@@ -0,0 +1,109 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+const (
+	// progressBarThreshold is the size from which the transfers of content
+	// to and from registries are shown with a progress bar.
+	progressBarThreshold = 1 << 20
+	progressBarWidth     = 30
+	progressBarInterval  = 100 * time.Millisecond
+)
+
+// progressBar draws the progress of the transfers of large blobs to and
+// from registries, one line per blob.
+type progressBar struct {
+	out io.Writer
+
+	mu      sync.Mutex
+	drawn   map[digest.Digest]time.Time
+	settled map[digest.Digest]bool
+}
+
+// newProgressBar returns a registry.ProgressFunc drawing a progress bar on
+// out, which should be a terminal.
+func newProgressBar(out io.Writer) registry.ProgressFunc {
+	b := &progressBar{
+		out:     out,
+		drawn:   map[digest.Digest]time.Time{},
+		settled: map[digest.Digest]bool{},
+	}
+	return b.update
+}
+
+func (b *progressBar) update(desc ocispec.Descriptor, transferred int64) {
+	if desc.Size < progressBarThreshold {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.settled[desc.Digest] {
+		return
+	}
+	done := transferred >= desc.Size
+	if last, ok := b.drawn[desc.Digest]; ok && !done && time.Since(last) < progressBarInterval {
+		return
+	}
+	b.drawn[desc.Digest] = time.Now()
+
+	filled := int(int64(progressBarWidth) * transferred / desc.Size)
+	fmt.Fprintf(b.out, "\r%s [%s%s] %3d%% %s/%s",
+		shortDigest(desc.Digest),
+		strings.Repeat("=", filled), strings.Repeat(" ", progressBarWidth-filled),
+		100*transferred/desc.Size,
+		formatBytes(transferred), formatBytes(desc.Size))
+	if done {
+		fmt.Fprintln(b.out)
+		b.settled[desc.Digest] = true
+	}
+}
+
+func shortDigest(d digest.Digest) string {
+	encoded := d.Encoded()
+	if len(encoded) > 12 {
+		encoded = encoded[:12]
+	}
+	return fmt.Sprintf("%s:%s", d.Algorithm(), encoded)
+}
+
+// formatBytes formats n bytes with a binary unit.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,69 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func TestProgressBar(t *testing.T) {
+	var out bytes.Buffer
+	progress := newProgressBar(&out)
+
+	small := ocispec.Descriptor{Digest: digest.FromString("small"), Size: 1024}
+	progress(small, 1024)
+	if out.Len() != 0 {
+		t.Errorf("Expected no progress bar for small content, got %q", out.String())
+	}
+
+	large := ocispec.Descriptor{Digest: digest.FromString("large"), Size: 4 << 20}
+	progress(large, 1<<20)
+	progress(large, 2<<20) // throttled
+	progress(large, 4<<20)
+	progress(large, 4<<20) // already complete
+
+	lines := strings.Split(strings.TrimPrefix(out.String(), "\r"), "\r")
+	if len(lines) != 2 {
+		t.Fatalf("Expected the first and the last updates to be drawn, got %q", out.String())
+	}
+	if !strings.HasPrefix(lines[0], shortDigest(large.Digest)) || !strings.Contains(lines[0], " 25% 1.0MiB/4.0MiB") {
+		t.Errorf("Unexpected progress %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "[==============================] 100% 4.0MiB/4.0MiB\n") {
+		t.Errorf("Unexpected completed progress %q", lines[1])
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for n, want := range map[int64]string{
+		0:             "0B",
+		1023:          "1023B",
+		1024:          "1.0KiB",
+		1536:          "1.5KiB",
+		300 << 20:     "300.0MiB",
+		(5 << 30) / 2: "2.5GiB",
+	} {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d): expected %q, got %q", n, want, got)
+		}
+	}
+}
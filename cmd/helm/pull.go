@@ -21,10 +21,12 @@ import (
 	"io"
 	"log"
 
+	"github.com/gosuri/uitable"
 	"github.com/spf13/cobra"
 
 	"helm.sh/helm/v3/cmd/helm/require"
 	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/cli/output"
 )
 
 const pullDesc = `
@@ -34,16 +36,44 @@ This is useful for fetching packages to inspect, modify, or repackage. It can
 also be used to perform cryptographic verification of a chart without installing
 the chart.
 
+Charts in git repositories are packaged from a checkout of the repository:
+
+    $ helm pull 'git+https://example.com/org/charts//nginx?ref=v1.2.3'
+
 There are options for unpacking the chart after download. This will create a
 directory for the chart and uncompress into that directory.
 
 If the --verify flag is specified, the requested chart MUST have a provenance
 file, and MUST pass the verification process. Failure in any part of this will
 result in an error, and the chart will not be saved locally.
+
+If a signature policy exists at $HELM_SIGNATURE_POLICY, the chart is verified
+with its sigstore signature instead: the signature attached to it in a
+registry, or the cosign bundle alongside a chart archive. The policy sets the
+trusted public keys and keyless identities, the annotations signatures must
+have, and whether they must be recorded in a transparency log:
+
+    publicKeys:
+    - cosign.pub
+    keyless:
+      roots: fulcio.pem
+      transparencyLogKey: rekor.pub
+      identities:
+      - subject: release@example.com
+        issuer: https://accounts.google.com
+    annotations:
+      org.example/approved: "true"
+    transparencyLog:
+      required: true
+
+With '--output json' or '--output yaml', the path of every pulled chart is
+printed instead of the verification messages.
 `
 
 func newPullCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
-	client := action.NewPullWithOpts(action.WithConfig(cfg))
+	var outfmt output.Format
+	result := &action.PullResult{}
+	client := action.NewPullWithOpts(action.WithConfig(cfg), action.WithPullResult(result))
 
 	cmd := &cobra.Command{
 		Use:     "pull [chart URL | repo/chartname] [...]",
@@ -71,14 +101,22 @@ func newPullCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			}
 			client.SetRegistryClient(registryClient)
 
+			w := &pullWriter{results: []action.PullResult{}}
 			for i := 0; i < len(args); i++ {
-				output, err := client.Run(args[i])
+				msg, err := client.Run(args[i])
 				if err != nil {
 					return err
 				}
-				fmt.Fprint(out, output)
+				if outfmt == output.Table {
+					fmt.Fprint(out, msg)
+					continue
+				}
+				w.results = append(w.results, *result)
 			}
-			return nil
+			if outfmt == output.Table {
+				return nil
+			}
+			return outfmt.Write(out, w)
 		},
 	}
 
@@ -89,6 +127,7 @@ func newPullCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.StringVar(&client.UntarDir, "untardir", ".", "if untar is specified, this flag specifies the name of the directory into which the chart is expanded")
 	f.StringVarP(&client.DestDir, "destination", "d", ".", "location to write the chart. If this and untardir are specified, untardir is appended to this")
 	addChartPathOptionsFlags(f, &client.ChartPathOptions)
+	bindOutputFlag(cmd, &outfmt)
 
 	err := cmd.RegisterFlagCompletionFunc("version", func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		if len(args) != 1 {
@@ -103,3 +142,25 @@ func newPullCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 
 	return cmd
 }
+
+// pullWriter writes the results of the pulls of the charts.
+type pullWriter struct {
+	results []action.PullResult
+}
+
+func (w *pullWriter) WriteTable(out io.Writer) error {
+	table := uitable.New()
+	table.AddRow("CHART", "PATH")
+	for _, r := range w.results {
+		table.AddRow(r.Ref, r.Path)
+	}
+	return output.EncodeTable(out, table)
+}
+
+func (w *pullWriter) WriteJSON(out io.Writer) error {
+	return output.EncodeJSON(out, w.results)
+}
+
+func (w *pullWriter) WriteYAML(out io.Writer) error {
+	return output.EncodeYAML(out, w.results)
+}
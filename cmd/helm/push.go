@@ -24,7 +24,9 @@ import (
 
 	"helm.sh/helm/v3/cmd/helm/require"
 	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/cli/output"
 	"helm.sh/helm/v3/pkg/pusher"
+	"helm.sh/helm/v3/pkg/registry"
 )
 
 const pushDesc = `
@@ -32,6 +34,14 @@ Upload a chart to a registry.
 
 If the chart has an associated provenance file,
 it will also be uploaded.
+
+To sign the chart with a sigstore signature attached to it in the registry,
+use the '--sign' flag. The chart is signed with the private key given by
+'--cosign-key', or keyless with a certificate issued to the OIDC identity
+token given by '--identity-token' or $SIGSTORE_ID_TOKEN.
+
+With '--output json' or '--output yaml', the reference and the digests of the
+chart pushed to a registry are printed.
 `
 
 type registryPushOptions struct {
@@ -40,6 +50,9 @@ type registryPushOptions struct {
 	caFile                string
 	insecureSkipTLSverify bool
 	plainHTTP             bool
+	sign                  bool
+	cosign                action.CosignSignOptions
+	outfmt                output.Format
 }
 
 func newPushCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
@@ -75,18 +88,25 @@ func newPushCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			cfg.RegistryClient = registryClient
 			chartRef := args[0]
 			remote := args[1]
-			client := action.NewPushWithOpts(action.WithPushConfig(cfg),
+			opts := []action.PushOpt{action.WithPushConfig(cfg),
 				action.WithTLSClientConfig(o.certFile, o.keyFile, o.caFile),
 				action.WithInsecureSkipTLSVerify(o.insecureSkipTLSverify),
 				action.WithPlainHTTP(o.plainHTTP),
-				action.WithPushOptWriter(out))
+				action.WithPushOptWriter(out)}
+			if o.sign {
+				opts = append(opts, action.WithCosignSign(&o.cosign))
+			}
+			result := &registry.PushResult{}
+			if o.outfmt != output.Table {
+				opts = append(opts, action.WithPushResult(result))
+			}
+			client := action.NewPushWithOpts(opts...)
 			client.Settings = settings
-			output, err := client.Run(chartRef, remote)
+			msg, err := client.Run(chartRef, remote)
 			if err != nil {
 				return err
 			}
-			fmt.Fprint(out, output)
-			return nil
+			return o.outfmt.Write(out, &pushWriter{msg, result})
 		},
 	}
 
@@ -96,6 +116,28 @@ func newPushCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.StringVar(&o.caFile, "ca-file", "", "verify certificates of HTTPS-enabled servers using this CA bundle")
 	f.BoolVar(&o.insecureSkipTLSverify, "insecure-skip-tls-verify", false, "skip tls certificate checks for the chart upload")
 	f.BoolVar(&o.plainHTTP, "plain-http", false, "use insecure HTTP connections for the chart upload")
+	f.BoolVar(&o.sign, "sign", false, "sign the chart with a sigstore signature attached to it in the registry")
+	f.StringVar(&o.cosign.PassphraseFile, "passphrase-file", "", `location of a file which contains the password of the cosign key. Use "-" in order to read from stdin.`)
+	addCosignSignFlags(f, &o.cosign)
+	bindOutputFlag(cmd, &o.outfmt)
 
 	return cmd
 }
+
+type pushWriter struct {
+	msg    string
+	result *registry.PushResult
+}
+
+func (w *pushWriter) WriteTable(out io.Writer) error {
+	_, err := fmt.Fprint(out, w.msg)
+	return err
+}
+
+func (w *pushWriter) WriteJSON(out io.Writer) error {
+	return output.EncodeJSON(out, w.result)
+}
+
+func (w *pushWriter) WriteYAML(out io.Writer) error {
+	return output.EncodeYAML(out, w.result)
+}
@@ -25,6 +25,20 @@ import (
 
 const registryHelp = `
 This command consists of multiple subcommands to interact with registries.
+
+Charts can be pulled from mirrors of registries, such as pull-through caches,
+without changing their references. The mirrors are configured in the file set
+by '--registries-config' ($HELM_REGISTRIES_CONFIG), tried in order before the
+registry itself:
+
+    registries:
+      ghcr.io:
+        mirrors:
+        - endpoint: https://mirror.example.com
+          caFile: mirror-ca.pem
+        - endpoint: https://harbor.example.com/v2/ghcr-proxy
+          username: robot
+          password: secret
 `
 
 func newRegistryCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
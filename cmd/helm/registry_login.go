@@ -29,10 +29,34 @@ import (
 
 	"helm.sh/helm/v3/cmd/helm/require"
 	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/registry"
 )
 
 const registryLoginDesc = `
 Authenticate to a remote registry.
+
+A login is not needed for the registries whose credentials are provided by a
+Docker credential helper, such as the helpers of ECR, GCR or ACR. Helpers are
+configured in the "credHelpers" or "credsStore" of the Helm registry config
+file or of the Docker config file. The keys of "credHelpers" may be patterns
+like "*.dkr.ecr.us-east-1.amazonaws.com":
+
+	{"credHelpers": {"*.dkr.ecr.us-east-1.amazonaws.com": "ecr-login"}}
+
+Credentials may also be scoped to the repositories under a path of a registry,
+with keys like "registry.example.com/team-a" in "auths" or "credHelpers". The
+credentials of the longest path prefixing a repository are used for it. If a
+registry rejects the credentials of a pull, the pull is retried anonymously so
+public charts are pulled anyway.
+
+With --oidc, an OIDC identity token is exchanged for a short-lived registry
+token, which is refreshed when it expires. The identity token is either read
+from the workload identity token file given by --oidc-token-file, such as a
+projected Kubernetes service account token, or obtained from the OIDC provider
+given by --oidc-issuer with a device code shown to the user:
+
+	$ helm registry login registry.example.com --oidc \
+		--oidc-issuer https://accounts.example.com --oidc-client-id helm
 `
 
 type registryLoginOptions struct {
@@ -43,6 +67,8 @@ type registryLoginOptions struct {
 	keyFile              string
 	caFile               string
 	insecure             bool
+	oidc                 bool
+	oidcLogin            registry.OIDCLogin
 }
 
 func newRegistryLoginCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
@@ -57,16 +83,23 @@ func newRegistryLoginCmd(cfg *action.Configuration, out io.Writer) *cobra.Comman
 		RunE: func(_ *cobra.Command, args []string) error {
 			hostname := args[0]
 
+			opts := []action.RegistryLoginOpt{
+				action.WithCertFile(o.certFile),
+				action.WithKeyFile(o.keyFile),
+				action.WithCAFile(o.caFile),
+				action.WithInsecure(o.insecure),
+			}
+			if o.oidc {
+				opts = append(opts, action.WithOIDC(&o.oidcLogin))
+				return action.NewRegistryLogin(cfg).Run(out, hostname, "", "", opts...)
+			}
+
 			username, password, err := getUsernamePassword(o.username, o.password, o.passwordFromStdinOpt)
 			if err != nil {
 				return err
 			}
 
-			return action.NewRegistryLogin(cfg).Run(out, hostname, username, password,
-				action.WithCertFile(o.certFile),
-				action.WithKeyFile(o.keyFile),
-				action.WithCAFile(o.caFile),
-				action.WithInsecure(o.insecure))
+			return action.NewRegistryLogin(cfg).Run(out, hostname, username, password, opts...)
 		},
 	}
 
@@ -78,6 +111,11 @@ func newRegistryLoginCmd(cfg *action.Configuration, out io.Writer) *cobra.Comman
 	f.StringVar(&o.certFile, "cert-file", "", "identify registry client using this SSL certificate file")
 	f.StringVar(&o.keyFile, "key-file", "", "identify registry client using this SSL key file")
 	f.StringVar(&o.caFile, "ca-file", "", "verify certificates of HTTPS-enabled servers using this CA bundle")
+	f.BoolVar(&o.oidc, "oidc", false, "log in by exchanging an OIDC identity token for a registry token")
+	f.StringVar(&o.oidcLogin.Issuer, "oidc-issuer", "", "URL of the OIDC provider issuing the identity token with a device code")
+	f.StringVar(&o.oidcLogin.ClientID, "oidc-client-id", "", "client ID of Helm at the OIDC provider")
+	f.StringVar(&o.oidcLogin.TokenFile, "oidc-token-file", "", "path of a workload identity token to exchange, read again when the registry token expires")
+	f.StringVar(&o.oidcLogin.ExchangeURL, "oidc-exchange-url", "", "token exchange endpoint of the registry. Defaults to the token endpoint of the registry")
 
 	return cmd
 }
@@ -0,0 +1,77 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"helm.sh/helm/v3/internal/test"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+func TestReleaseExportImport(t *testing.T) {
+	defer resetEnv()()
+
+	file := filepath.Join(t.TempDir(), "angry-bird.tgz")
+	src := storageFixture()
+	for _, rel := range []*release.Release{
+		release.Mock(&release.MockReleaseOptions{Name: "angry-bird", Version: 1}),
+		release.Mock(&release.MockReleaseOptions{Name: "angry-bird", Version: 2}),
+	} {
+		if err := src.Create(rel); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, out, err := executeActionCommandC(src, fmt.Sprintf("release export angry-bird --file %s", file)); err != nil {
+		t.Fatalf("expected no error, got: '%v'\n%s", err, out)
+	}
+
+	dst := storageFixture()
+	_, out, err := executeActionCommandC(dst, fmt.Sprintf("release import %s", file))
+	if err != nil {
+		t.Fatalf("expected no error, got: '%v'", err)
+	}
+	test.AssertGoldenString(t, out, "output/release-import.txt")
+
+	if _, _, err := executeActionCommandC(dst, fmt.Sprintf("release import %s", file)); err == nil {
+		t.Error("expected importing an existing release to fail")
+	}
+}
+
+func TestReleaseExport(t *testing.T) {
+	tests := []cmdTestCase{
+		{
+			name:      "export a missing release",
+			cmd:       "release export angry-bird",
+			golden:    "output/release-export-not-found.txt",
+			wantError: true,
+		},
+		{
+			name:      "export without a release",
+			cmd:       "release export",
+			golden:    "output/release-export-no-args.txt",
+			wantError: true,
+		},
+	}
+	runTestCmd(t, tests)
+}
+
+func TestReleaseExportCompletion(t *testing.T) {
+	checkReleaseCompletion(t, "release export", false)
+}
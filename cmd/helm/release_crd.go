@@ -0,0 +1,53 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+const releaseCRDHelp = `
+This command prints the CustomResourceDefinition of the HelmReleaseRecord
+resource, which stores the releases with HELM_DRIVER=crd.
+
+The first use of the crd driver installs the definition if it is missing. Users
+who are not allowed to create CustomResourceDefinitions need an administrator
+to install it beforehand:
+
+    $ helm release crd | kubectl apply -f -
+`
+
+func newReleaseCRDCmd(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "crd",
+		Short:             "print the CustomResourceDefinition of the release records of the crd driver",
+		Long:              releaseCRDHelp,
+		Args:              require.NoArgs,
+		ValidArgsFunction: noMoreArgsCompFunc,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			_, err := fmt.Fprint(out, driver.ReleaseRecordCRD)
+			return err
+		},
+	}
+	return cmd
+}
@@ -0,0 +1,79 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/pkg/action"
+)
+
+const releaseExportHelp = `
+This command exports every revision of a release to a release archive, a
+gzipped tarball that 'helm release import' restores into another cluster,
+namespace or storage driver. It backs up the release records, and migrates
+them between storage drivers:
+
+    $ helm release export my-release --file my-release.tgz
+    $ HELM_DRIVER=sql helm release import my-release.tgz
+
+The archive is written to standard output unless --file is set. The stored
+records are decrypted, so the archive holds the values of the release in the
+clear.
+`
+
+func newReleaseExportCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
+	client := action.NewExport(cfg)
+	var file string
+
+	cmd := &cobra.Command{
+		Use:   "export RELEASE_NAME",
+		Short: "export the history of a release to an archive",
+		Long:  releaseExportHelp,
+		Args:  require.ExactArgs(1),
+		ValidArgsFunction: func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return noMoreArgsComp()
+			}
+			return compListReleases(toComplete, args, cfg)
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			if file == "" || file == "-" {
+				return client.Run(args[0], out)
+			}
+
+			f, err := os.Create(file)
+			if err != nil {
+				return err
+			}
+			if err := client.Run(args[0], f); err != nil {
+				f.Close()
+				os.Remove(file)
+				return err
+			}
+			return f.Close()
+		},
+	}
+
+	cmd.Flags().StringVar(&file, "file", "", "write the archive to this file instead of the standard output")
+
+	return cmd
+}
@@ -0,0 +1,70 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/pkg/action"
+)
+
+const releaseImportHelp = `
+This command restores a release from an archive written by
+'helm release export'. Every revision of the release is stored with the
+configured storage driver, in the current namespace. The command fails if
+the release already exists.
+
+Only the release records are restored; the resources of the release are not
+created or changed. Use '-' to read the archive from standard input.
+`
+
+func newReleaseImportCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
+	client := action.NewImport(cfg)
+
+	cmd := &cobra.Command{
+		Use:   "import FILE",
+		Short: "restore a release from an archive",
+		Long:  releaseImportHelp,
+		Args:  require.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			in := cmd.InOrStdin()
+			if args[0] != "-" {
+				f, err := os.Open(args[0])
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				in = f
+			}
+
+			client.Namespace = settings.Namespace()
+			rels, err := client.Run(in)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Imported %d revisions of release %q\n", len(rels), rels[0].Name)
+			return nil
+		},
+	}
+
+	return cmd
+}
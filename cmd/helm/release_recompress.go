@@ -0,0 +1,70 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/pkg/action"
+)
+
+const releaseRecompressHelp = `
+This command rewrites the stored records of a release, or of all releases in
+the namespace if no release is given.
+
+The records are written with the compression set by $HELM_DRIVER_COMPRESSION
+and $HELM_DRIVER_COMPRESSION_LEVEL, and with the encryption set by the
+$HELM_ENCRYPTION_* variables. Records are read regardless of how they were
+stored, so this command migrates existing records after these settings change:
+
+    $ HELM_DRIVER_COMPRESSION=zstd helm release recompress
+`
+
+func newReleaseRecompressCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
+	client := action.NewRecompress(cfg)
+
+	cmd := &cobra.Command{
+		Use:   "recompress [RELEASE_NAME]",
+		Short: "rewrite stored release records with the current compression",
+		Long:  releaseRecompressHelp,
+		Args:  require.MaximumNArgs(1),
+		ValidArgsFunction: func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return noMoreArgsComp()
+			}
+			return compListReleases(toComplete, args, cfg)
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			name := ""
+			if len(args) > 0 {
+				name = args[0]
+			}
+			rels, err := client.Run(name)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Rewrote %d release records\n", len(rels))
+			return nil
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,78 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"testing"
+
+	"helm.sh/helm/v3/pkg/release"
+)
+
+func TestReleaseRecompress(t *testing.T) {
+	rels := []*release.Release{
+		release.Mock(&release.MockReleaseOptions{Name: "angry-bird", Version: 1}),
+		release.Mock(&release.MockReleaseOptions{Name: "angry-bird", Version: 2}),
+		release.Mock(&release.MockReleaseOptions{Name: "smug-pigeon", Version: 1}),
+	}
+	tests := []cmdTestCase{
+		{
+			name:   "recompress a release",
+			cmd:    "release recompress angry-bird",
+			golden: "output/release-recompress.txt",
+			rels:   rels,
+		},
+		{
+			name:   "recompress all releases",
+			cmd:    "release recompress",
+			golden: "output/release-recompress-all.txt",
+			rels:   rels,
+		},
+		{
+			name:      "recompress with too many arguments",
+			cmd:       "release recompress angry-bird smug-pigeon",
+			golden:    "output/release-recompress-too-many-args.txt",
+			wantError: true,
+		},
+	}
+	runTestCmd(t, tests)
+}
+
+func TestReleaseRecompressCompletion(t *testing.T) {
+	checkReleaseCompletion(t, "release recompress", false)
+}
+
+func TestReleaseCompressionFromEnv(t *testing.T) {
+	t.Setenv("HELM_DRIVER_COMPRESSION", "zstd")
+	t.Setenv("HELM_DRIVER_COMPRESSION_LEVEL", "3")
+	c, err := releaseCompressionFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Algorithm != "zstd" || c.Level != 3 {
+		t.Errorf("Expected zstd level 3, got %v", c)
+	}
+
+	t.Setenv("HELM_DRIVER_COMPRESSION_LEVEL", "fast")
+	if _, err := releaseCompressionFromEnv(); err == nil {
+		t.Error("Expected an invalid level to fail")
+	}
+	t.Setenv("HELM_DRIVER_COMPRESSION", "lz4")
+	t.Setenv("HELM_DRIVER_COMPRESSION_LEVEL", "")
+	if _, err := releaseCompressionFromEnv(); err == nil {
+		t.Error("Expected an unsupported compression to fail")
+	}
+}
@@ -19,15 +19,19 @@ package main
 import (
 	"fmt"
 	"io"
+	"log"
+	"os"
 	"regexp"
 	"strings"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"helm.sh/helm/v3/cmd/helm/require"
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/cli/output"
+	"helm.sh/helm/v3/pkg/release"
 )
 
 const releaseTestHelp = `
@@ -35,6 +39,16 @@ The test command runs the tests for a release.
 
 The argument this command takes is the name of a deployed release.
 The tests to be run are defined in the chart that was installed.
+
+The tests can be selected with '--filter', by name or by the labels of their
+resources, and excluded with a filter starting with '!'. For example, to run
+the smoke tests except the slow ones:
+
+    $ helm test myrelease --filter label=suite=smoke,!label=speed=slow
+
+With '--report', a report of the results and the durations of the tests is
+written to a file, or to stdout for '-', in the JUnit XML format or in JSON
+with '--report-format json', to be collected by test dashboards.
 `
 
 func newReleaseTestCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
@@ -42,6 +56,8 @@ func newReleaseTestCmd(cfg *action.Configuration, out io.Writer) *cobra.Command
 	var outfmt = output.Table
 	var outputLogs bool
 	var filter []string
+	var reportFile string
+	var reportFormat string
 
 	cmd := &cobra.Command{
 		Use:   "test [RELEASE]",
@@ -55,13 +71,21 @@ func newReleaseTestCmd(cfg *action.Configuration, out io.Writer) *cobra.Command
 			return compListReleases(toComplete, args, cfg)
 		},
 		RunE: func(_ *cobra.Command, args []string) error {
+			if reportFormat != "junit" && reportFormat != "json" {
+				return errors.Errorf("invalid report format %q: expected junit or json", reportFormat)
+			}
 			client.Namespace = settings.Namespace()
 			notName := regexp.MustCompile(`^!\s?name=`)
+			notLabel := regexp.MustCompile(`^!\s?label=`)
 			for _, f := range filter {
 				if strings.HasPrefix(f, "name=") {
 					client.Filters[action.IncludeNameFilter] = append(client.Filters[action.IncludeNameFilter], strings.TrimPrefix(f, "name="))
 				} else if notName.MatchString(f) {
 					client.Filters[action.ExcludeNameFilter] = append(client.Filters[action.ExcludeNameFilter], notName.ReplaceAllLiteralString(f, ""))
+				} else if strings.HasPrefix(f, "label=") {
+					client.Filters[action.IncludeLabelFilter] = append(client.Filters[action.IncludeLabelFilter], strings.TrimPrefix(f, "label="))
+				} else if notLabel.MatchString(f) {
+					client.Filters[action.ExcludeLabelFilter] = append(client.Filters[action.ExcludeLabelFilter], notLabel.ReplaceAllLiteralString(f, ""))
 				}
 			}
 			rel, runErr := client.Run(args[0])
@@ -84,6 +108,12 @@ func newReleaseTestCmd(cfg *action.Configuration, out io.Writer) *cobra.Command
 				}
 			}
 
+			if reportFile != "" {
+				if err := writeTestReport(client, rel, reportFile, reportFormat, out); err != nil {
+					return err
+				}
+			}
+
 			return runErr
 		},
 	}
@@ -91,8 +121,42 @@ func newReleaseTestCmd(cfg *action.Configuration, out io.Writer) *cobra.Command
 	f := cmd.Flags()
 	f.DurationVar(&client.Timeout, "timeout", 300*time.Second, "time to wait for any individual Kubernetes operation (like Jobs for hooks)")
 	f.BoolVar(&outputLogs, "logs", false, "dump the logs from test pods (this runs after all tests are complete, but before any cleanup)")
-	f.StringSliceVar(&filter, "filter", []string{}, "specify tests by attribute (currently \"name\") using attribute=value syntax or '!attribute=value' to exclude a test (can specify multiple or separate values with commas: name=test1,name=test2)")
+	f.StringSliceVar(&filter, "filter", []string{}, "specify tests by attribute (\"name\" or \"label\", whose value is a label selector like label=suite=smoke) using attribute=value syntax or '!attribute=value' to exclude a test (can specify multiple or separate values with commas: name=test1,name=test2)")
+	f.StringVar(&reportFile, "report", "", "write a report of the results and the durations of the tests to the file, or to stdout for '-'")
+	f.StringVar(&reportFormat, "report-format", "junit", "format of the report: junit or json")
 	f.BoolVar(&client.HideNotes, "hide-notes", false, "if set, do not show notes in test output. Does not affect presence in chart metadata")
+	bindOutputFlag(cmd, &outfmt)
+
+	err := cmd.RegisterFlagCompletionFunc("report-format", func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return []string{"junit", "json"}, cobra.ShellCompDirectiveNoFileComp
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	return cmd
 }
+
+// writeTestReport writes the report of the tests of the release to the file,
+// or to out for "-".
+func writeTestReport(client *action.ReleaseTesting, rel *release.Release, file, format string, out io.Writer) error {
+	report, err := client.Report(rel)
+	if err != nil {
+		return err
+	}
+	w := out
+	if file != "-" {
+		f, err := os.Create(file)
+		if err != nil {
+			return errors.Wrap(err, "unable to write the test report")
+		}
+		defer f.Close()
+		w = f
+	} else {
+		fmt.Fprintln(out)
+	}
+	if format == "json" {
+		return output.EncodeJSON(w, report)
+	}
+	return report.WriteJUnit(w)
+}
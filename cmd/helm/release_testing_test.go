@@ -17,9 +17,67 @@ limitations under the License.
 package main
 
 import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
 )
 
+func TestReleaseTestingReport(t *testing.T) {
+	store := storageFixture()
+	rel := release.Mock(&release.MockReleaseOptions{Name: "tested", Namespace: "default"})
+	rel.Hooks = nil
+	for _, test := range []struct{ name, suite string }{{"smoke", "smoke"}, {"load", "load"}} {
+		rel.Hooks = append(rel.Hooks, &release.Hook{
+			Name:     test.name,
+			Kind:     "Pod",
+			Path:     "templates/tests/" + test.name + ".yaml",
+			Events:   []release.HookEvent{release.HookTest},
+			Manifest: "apiVersion: v1\nkind: Pod\nmetadata:\n  name: " + test.name + "\n  labels:\n    suite: " + test.suite + "\n",
+		})
+	}
+	if err := store.Create(rel); err != nil {
+		t.Fatal(err)
+	}
+
+	report := filepath.Join(t.TempDir(), "report.json")
+	_, out, err := executeActionCommandC(store, "test tested --filter label=suite=smoke --report "+report+" --report-format json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "TEST SUITE:     smoke") || strings.Contains(out, "TEST SUITE:     load") {
+		t.Errorf("Expected only the smoke test to run, got %q", out)
+	}
+	data, err := os.ReadFile(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r action.TestReport
+	if err := json.Unmarshal(data, &r); err != nil {
+		t.Fatal(err)
+	}
+	// The tests are reported in the order they are run, by weight and name
+	if len(r.Tests) != 2 || !r.Tests[0].Skipped || r.Tests[1].Name != "smoke" || r.Tests[1].Phase != release.HookPhaseSucceeded {
+		t.Errorf("Unexpected report %s", data)
+	}
+
+	_, out, err = executeActionCommandC(store, "test tested --report -")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, `<testsuite name="default/tested" tests="2" failures="0" skipped="0"`) {
+		t.Errorf("Expected a JUnit report, got %q", out)
+	}
+
+	if _, _, err := executeActionCommandC(store, "test tested --report - --report-format html"); err == nil {
+		t.Error("Expected an invalid report format to fail")
+	}
+}
+
 func TestReleaseTestingCompletion(t *testing.T) {
 	checkReleaseCompletion(t, "test", false)
 }
@@ -24,18 +24,26 @@ import (
 	"github.com/spf13/cobra"
 
 	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/pkg/action"
 )
 
 var repoHelm = `
 This command consists of multiple subcommands to interact with chart repositories.
 
-It can be used to add, remove, list, and index chart repositories.
+It can be used to add, remove, list, index, and mirror chart repositories.
+
+Besides HTTP servers, repositories can be hosted as static files in Amazon S3,
+Google Cloud Storage and Azure Blob Storage buckets, at URLs like
+s3://bucket/charts, gs://bucket/charts and azblob://container/charts. The
+requests are authorized with the ambient credentials of the cloud provider, such
+as the AWS_* environment variables, the application default credentials of
+Google Cloud, or AZURE_STORAGE_ACCOUNT with a managed identity.
 `
 
-func newRepoCmd(out io.Writer) *cobra.Command {
+func newRepoCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "repo add|remove|list|index|update [ARGS]",
-		Short: "add, list, remove, update, and index chart repositories",
+		Use:   "repo add|remove|list|index|update|mirror [ARGS]",
+		Short: "add, list, remove, update, index, and mirror chart repositories",
 		Long:  repoHelm,
 		Args:  require.NoArgs,
 	}
@@ -45,6 +53,7 @@ func newRepoCmd(out io.Writer) *cobra.Command {
 	cmd.AddCommand(newRepoRemoveCmd(out))
 	cmd.AddCommand(newRepoIndexCmd(out))
 	cmd.AddCommand(newRepoUpdateCmd(out))
+	cmd.AddCommand(newRepoMirrorCmd(cfg, out))
 
 	return cmd
 }
@@ -20,8 +20,10 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"time"
 
@@ -32,6 +34,7 @@ import (
 	"sigs.k8s.io/yaml"
 
 	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/pkg/cli/output"
 	"helm.sh/helm/v3/pkg/getter"
 	"helm.sh/helm/v3/pkg/repo"
 )
@@ -57,9 +60,17 @@ type repoAddOptions struct {
 	caFile                string
 	insecureSkipTLSverify bool
 
+	proxy   string
+	noProxy string
+	headers []string
+
+	priority int
+
 	repoFile  string
 	repoCache string
 
+	outfmt output.Format
+
 	// Deprecated, but cannot be removed until Helm 4
 	deprecatedNoUpdate bool
 }
@@ -99,6 +110,11 @@ func newRepoAddCmd(out io.Writer) *cobra.Command {
 	f.BoolVar(&o.insecureSkipTLSverify, "insecure-skip-tls-verify", false, "skip tls certificate checks for the repository")
 	f.BoolVar(&o.allowDeprecatedRepos, "allow-deprecated-repos", false, "by default, this command will not allow adding official repos that have been permanently deleted. This disables that behavior")
 	f.BoolVar(&o.passCredentialsAll, "pass-credentials", false, "pass credentials to all domains")
+	f.StringVar(&o.proxy, "proxy", "", "URL of the HTTP(S) proxy of the repository, overriding the HTTP_PROXY and HTTPS_PROXY environment variables")
+	f.StringVar(&o.noProxy, "no-proxy", "", "comma-separated list of hosts not reached through the proxy of the repository")
+	f.StringArrayVar(&o.headers, "header", nil, "static header sent to the repository, like an API token (can specify multiple): 'Name: value'")
+	f.IntVar(&o.priority, "priority", 0, "priority of the repository among the repositories serving the same chart, the higher the preferred")
+	bindOutputFlag(cmd, &o.outfmt)
 
 	return cmd
 }
@@ -169,6 +185,11 @@ func (o *repoAddOptions) run(out io.Writer) error {
 		}
 	}
 
+	headers, err := parseHeaders(o.headers)
+	if err != nil {
+		return err
+	}
+
 	c := repo.Entry{
 		Name:                  o.name,
 		URL:                   o.url,
@@ -179,6 +200,10 @@ func (o *repoAddOptions) run(out io.Writer) error {
 		KeyFile:               o.keyFile,
 		CAFile:                o.caFile,
 		InsecureSkipTLSverify: o.insecureSkipTLSverify,
+		Proxy:                 o.proxy,
+		NoProxy:               o.noProxy,
+		Headers:               headers,
+		Priority:              o.priority,
 	}
 
 	// Check if the repo name is legal
@@ -191,7 +216,7 @@ func (o *repoAddOptions) run(out io.Writer) error {
 	// 2. When the config is different require --force-update
 	if !o.forceUpdate && f.Has(o.name) {
 		existing := f.Get(o.name)
-		if c != *existing {
+		if !reflect.DeepEqual(c, *existing) {
 
 			// The input coming in for the name is different from what is already
 			// configured. Return an error.
@@ -199,8 +224,8 @@ func (o *repoAddOptions) run(out io.Writer) error {
 		}
 
 		// The add is idempotent so do nothing
-		fmt.Fprintf(out, "%q already exists with the same configuration, skipping\n", o.name)
-		return nil
+		msg := fmt.Sprintf("%q already exists with the same configuration, skipping\n", o.name)
+		return o.write(out, &repoAddWriter{msg, repoAddResult{Name: o.name, URL: o.url}})
 	}
 
 	r, err := repo.NewChartRepository(&c, getter.All(settings))
@@ -220,6 +245,57 @@ func (o *repoAddOptions) run(out io.Writer) error {
 	if err := f.WriteFile(o.repoFile, 0600); err != nil {
 		return err
 	}
-	fmt.Fprintf(out, "%q has been added to your repositories\n", o.name)
-	return nil
+	msg := fmt.Sprintf("%q has been added to your repositories\n", o.name)
+	return o.write(out, &repoAddWriter{msg, repoAddResult{Name: o.name, URL: o.url, Added: true}})
+}
+
+func (o *repoAddOptions) write(out io.Writer, w *repoAddWriter) error {
+	if o.outfmt == "" {
+		return w.WriteTable(out)
+	}
+	return o.outfmt.Write(out, w)
+}
+
+// repoAddResult is the result of the addition of a repository.
+type repoAddResult struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// Added is false if the repository already existed with the same
+	// configuration.
+	Added bool `json:"added"`
+}
+
+type repoAddWriter struct {
+	msg    string
+	result repoAddResult
+}
+
+func (w *repoAddWriter) WriteTable(out io.Writer) error {
+	_, err := fmt.Fprint(out, w.msg)
+	return err
+}
+
+func (w *repoAddWriter) WriteJSON(out io.Writer) error {
+	return output.EncodeJSON(out, w.result)
+}
+
+func (w *repoAddWriter) WriteYAML(out io.Writer) error {
+	return output.EncodeYAML(out, w.result)
+}
+
+// parseHeaders parses headers of the form "Name: value".
+func parseHeaders(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	headers := make(map[string]string, len(values))
+	for _, v := range values {
+		name, value, ok := strings.Cut(v, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, errors.Errorf("invalid header %q, expected 'Name: value'", v)
+		}
+		headers[http.CanonicalHeaderKey(name)] = strings.TrimSpace(value)
+	}
+	return headers, nil
 }
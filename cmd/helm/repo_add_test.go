@@ -21,6 +21,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"sync"
 	"testing"
@@ -134,6 +135,56 @@ func TestRepoAdd(t *testing.T) {
 	}
 }
 
+func TestRepoAddWithHeadersAndProxy(t *testing.T) {
+	ts, err := repotest.NewTempServerWithCleanup(t, "testdata/testserver/*.*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ts.Stop()
+
+	rootDir := t.TempDir()
+	repoFile := filepath.Join(rootDir, "repositories.yaml")
+	os.Setenv(xdg.CacheHomeEnvVar, rootDir)
+
+	o := &repoAddOptions{
+		name:     "test-name",
+		url:      ts.URL(),
+		noProxy:  "127.0.0.1",
+		proxy:    "http://proxy.example.invalid:3128",
+		headers:  []string{"x-api-token: secret", "X-Tenant:helm"},
+		repoFile: repoFile,
+	}
+	if err := o.run(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	f, err := repo.LoadFile(repoFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := f.Get("test-name")
+	if e.Proxy != o.proxy || e.NoProxy != o.noProxy {
+		t.Errorf("unexpected proxy %q, %q", e.Proxy, e.NoProxy)
+	}
+	if !reflect.DeepEqual(e.Headers, map[string]string{"X-Api-Token": "secret", "X-Tenant": "helm"}) {
+		t.Errorf("unexpected headers %v", e.Headers)
+	}
+
+	// Adding the same configuration is idempotent
+	if err := o.run(io.Discard); err != nil {
+		t.Error(err)
+	}
+	o.headers = []string{"X-Tenant: other"}
+	if err := o.run(io.Discard); err == nil {
+		t.Error("expected an error for a different configuration")
+	}
+
+	o.forceUpdate = true
+	o.headers = []string{"invalid"}
+	if err := o.run(io.Discard); err == nil || !strings.Contains(err.Error(), "invalid header") {
+		t.Errorf("expected an invalid header error, got %v", err)
+	}
+}
+
 func TestRepoAddCheckLegalName(t *testing.T) {
 	ts, err := repotest.NewTempServerWithCleanup(t, "testdata/testserver/*.*")
 	if err != nil {
@@ -17,9 +17,11 @@ limitations under the License.
 package main
 
 import (
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -37,13 +39,37 @@ set an absolute URL to the charts, use '--url' flag.
 To merge the generated index with an existing index file, use the '--merge'
 flag. In this case, the charts found in the current directory will be merged
 into the existing index, with local charts taking priority over existing charts.
+The charts whose archives are already in the existing index, with the same
+digest, are not loaded again, so merging a few new charts into a large index
+only reads the new archives.
+
+Only the metadata of the charts is loaded from their archives, and the index
+is written one chart at a time, so large repositories are indexed with little
+memory.
+
+Use the '--format' flag to also, or instead, generate the index in JSON, which
+is faster to parse, and compressed with gzip or zstd. The formats are written
+to 'index.yaml', 'index.json', 'index.yaml.gz', 'index.json.gz',
+'index.yaml.zst' and 'index.json.zst' and Helm requests the index in the best
+format the repository serves, so servers negotiating the format with the Accept
+and Accept-Encoding headers can serve compressed JSON to the clients supporting
+it and 'index.yaml' to the others.
+
+	$ helm repo index --format yaml,json.zst,json.gz .
+
+Use the '--shards' flag to also write the index sharded by chart in the
+'index.d' directory: a file with the versions of each chart and a
+'manifest.json' listing them with their digests. 'helm repo update' then only
+downloads the charts that changed since the cached index of the repository.
 `
 
 type repoIndexOptions struct {
-	dir   string
-	url   string
-	merge string
-	json  bool
+	dir     string
+	url     string
+	merge   string
+	json    bool
+	formats []string
+	shards  bool
 }
 
 func newRepoIndexCmd(out io.Writer) *cobra.Command {
@@ -72,6 +98,8 @@ func newRepoIndexCmd(out io.Writer) *cobra.Command {
 	f.StringVar(&o.url, "url", "", "url of chart repository")
 	f.StringVar(&o.merge, "merge", "", "merge the generated index into the given index")
 	f.BoolVar(&o.json, "json", false, "output in JSON format")
+	f.BoolVar(&o.shards, "shards", false, "also write the index sharded by chart in the index.d directory, for incremental updates")
+	f.StringSliceVar(&o.formats, "format", []string{string(repo.IndexFormatYAML)}, fmt.Sprintf("formats of the generated index files, among %s", formatNames()))
 
 	return cmd
 }
@@ -82,16 +110,31 @@ func (i *repoIndexOptions) run(_ io.Writer) error {
 		return err
 	}
 
-	return index(path, i.url, i.merge, i.json)
-}
+	formats := make([]repo.IndexFormat, 0, len(i.formats))
+	for _, name := range i.formats {
+		f, err := repo.ParseIndexFormat(name)
+		if err != nil {
+			return err
+		}
+		if i.json && f != repo.IndexFormatYAML {
+			return errors.New("the --json flag cannot be combined with formats other than yaml")
+		}
+		formats = append(formats, f)
+	}
+	if len(formats) == 0 {
+		return errors.New("at least one index format is required")
+	}
 
-func index(dir, url, mergeTo string, json bool) error {
-	out := filepath.Join(dir, "index.yaml")
+	return index(path, i.url, i.merge, i.json, i.shards, formats...)
+}
 
-	i, err := repo.IndexDirectory(dir, url)
-	if err != nil {
-		return err
+func index(dir, url, mergeTo string, json, shards bool, formats ...repo.IndexFormat) error {
+	if len(formats) == 0 {
+		formats = []repo.IndexFormat{repo.IndexFormatYAML}
 	}
+
+	var i *repo.IndexFile
+	var err error
 	if mergeTo != "" {
 		// if index.yaml is missing then create an empty one to merge into
 		var i2 *repo.IndexFile
@@ -104,10 +147,45 @@ func index(dir, url, mergeTo string, json bool) error {
 				return errors.Wrap(err, "merge failed")
 			}
 		}
-		i.Merge(i2)
+		// The charts already in the merged index are not loaded again
+		i, err = repo.MergeDirectory(dir, url, i2)
+	} else {
+		i, err = repo.IndexDirectory(dir, url)
+	}
+	if err != nil {
+		return err
 	}
 	i.SortEntries()
-	return writeIndexFile(i, out, json)
+	if shards {
+		if i.Annotations == nil {
+			i.Annotations = map[string]string{}
+		}
+		i.Annotations[repo.IndexShardsAnnotation] = "true"
+	}
+	for _, f := range formats {
+		out := filepath.Join(dir, f.FileName())
+		if f == repo.IndexFormatYAML {
+			if err := writeIndexFile(i, out, json); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := i.WriteFormatFile(out, f, 0644); err != nil {
+			return err
+		}
+	}
+	if shards {
+		return i.WriteShards(filepath.Join(dir, repo.IndexShardsDir), 0644)
+	}
+	return nil
+}
+
+func formatNames() string {
+	names := make([]string, len(repo.IndexFormats))
+	for i, f := range repo.IndexFormats {
+		names[i] = string(f)
+	}
+	return strings.Join(names, ", ")
 }
 
 func writeIndexFile(i *repo.IndexFile, out string, json bool) error {
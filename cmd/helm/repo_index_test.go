@@ -22,6 +22,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"helm.sh/helm/v3/pkg/repo"
@@ -162,6 +163,62 @@ func TestRepoIndexCmd(t *testing.T) {
 	}
 }
 
+func TestRepoIndexCmdFormats(t *testing.T) {
+	dir := t.TempDir()
+	if err := linkOrCopy("testdata/testcharts/compressedchart-0.1.0.tgz", filepath.Join(dir, "compressedchart-0.1.0.tgz")); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newRepoIndexCmd(io.Discard)
+	c.ParseFlags([]string{"--format", "json,yaml.gz,json.zst"})
+	if err := c.RunE(c, []string{dir}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "index.yaml")); !os.IsNotExist(err) {
+		t.Errorf("expected no index.yaml, got %v", err)
+	}
+	for _, name := range []string{"index.json", "index.yaml.gz", "index.json.zst"} {
+		index, err := repo.LoadIndexFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(index.Entries["compressedchart"]) != 1 {
+			t.Errorf("%s: unexpected entries %#v", name, index.Entries)
+		}
+	}
+
+	// Sharded index
+	c = newRepoIndexCmd(io.Discard)
+	c.ParseFlags([]string{"--shards"})
+	if err := c.RunE(c, []string{dir}); err != nil {
+		t.Fatal(err)
+	}
+	index, err := repo.LoadIndexFile(filepath.Join(dir, "index.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.Annotations[repo.IndexShardsAnnotation] == "" {
+		t.Error("expected the index to advertise its shards")
+	}
+	for _, name := range []string{"manifest.json", "compressedchart.json"} {
+		if _, err := os.Stat(filepath.Join(dir, repo.IndexShardsDir, name)); err != nil {
+			t.Error(err)
+		}
+	}
+
+	c = newRepoIndexCmd(io.Discard)
+	c.ParseFlags([]string{"--format", "xml"})
+	if err := c.RunE(c, []string{dir}); err == nil || !strings.Contains(err.Error(), "unsupported index format") {
+		t.Errorf("expected an unsupported format error, got %v", err)
+	}
+
+	c = newRepoIndexCmd(io.Discard)
+	c.ParseFlags([]string{"--json", "--format", "json.gz"})
+	if err := c.RunE(c, []string{dir}); err == nil {
+		t.Error("expected an error combining --json with other formats")
+	}
+}
+
 func linkOrCopy(old, new string) error {
 	if err := os.Link(old, new); err != nil {
 		return copyFile(old, new)
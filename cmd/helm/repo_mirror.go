@@ -0,0 +1,173 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+const repoMirrorDesc = `
+Mirror the charts of a repository for disconnected environments.
+
+The repository is the name of a configured repository, or the URL of a
+repository. The versions of the charts selected by '--chart' and '--version'
+are copied with their provenance files into the destination, along with an
+index of the mirrored charts preserving their digests:
+
+    $ helm repo mirror bitnami ./mirror --chart 'nginx*' --version '>=15.0.0'
+
+The destination can be a local directory, to be served as a repository, or an
+OCI registry, the charts being pushed with their provenance files:
+
+    $ helm repo mirror bitnami oci://registry.example.com/charts --chart nginx
+
+Mirroring into a directory again only downloads the chart versions not already
+mirrored, and keeps the ones previously mirrored in the index.
+`
+
+type repoMirrorOptions struct {
+	source  string
+	dest    string
+	charts  []string
+	version string
+
+	insecureSkipTLSverify bool
+	plainHTTP             bool
+
+	repoFile  string
+	repoCache string
+}
+
+func newRepoMirrorCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
+	o := &repoMirrorOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "mirror [REPO] [DEST]",
+		Short: "mirror the charts of a repository into a directory or a registry",
+		Long:  repoMirrorDesc,
+		Args:  require.ExactArgs(2),
+		ValidArgsFunction: func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) == 0 {
+				return compListRepos(toComplete, args), cobra.ShellCompDirectiveNoFileComp
+			}
+			if len(args) == 1 {
+				return nil, cobra.ShellCompDirectiveDefault
+			}
+			return noMoreArgsComp()
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			o.source = args[0]
+			o.dest = args[1]
+			o.repoFile = settings.RepositoryConfig
+			o.repoCache = settings.RepositoryCache
+			return o.run(cfg, out)
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringArrayVar(&o.charts, "chart", nil, "pattern of the names of the mirrored charts, like 'nginx*' (can specify multiple). All the charts are mirrored by default")
+	f.StringVar(&o.version, "version", "", "version constraint of the mirrored chart versions. All the versions are mirrored by default")
+	f.BoolVar(&o.insecureSkipTLSverify, "insecure-skip-tls-verify", false, "skip tls certificate checks for the destination registry")
+	f.BoolVar(&o.plainHTTP, "plain-http", false, "use insecure HTTP connections for the destination registry")
+
+	return cmd
+}
+
+func (o *repoMirrorOptions) run(cfg *action.Configuration, out io.Writer) error {
+	r, err := o.sourceRepository()
+	if err != nil {
+		return err
+	}
+	filter := repo.MirrorFilter{Charts: o.charts, Version: o.version}
+
+	if !registry.IsOCI(o.dest) {
+		mirrored, err := r.Mirror(o.dest, filter, out)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Mirrored %d chart versions of %q to %s\n", len(mirrored), o.source, o.dest)
+		return nil
+	}
+
+	// The charts are mirrored into a temporary directory, then pushed
+	dir, err := os.MkdirTemp("", "helm-mirror-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	mirrored, err := r.Mirror(dir, filter, out)
+	if err != nil {
+		return err
+	}
+
+	registryClient, err := newRegistryClient("", "", "", o.insecureSkipTLSverify, o.plainHTTP)
+	if err != nil {
+		return fmt.Errorf("missing registry client: %w", err)
+	}
+	cfg.RegistryClient = registryClient
+	client := action.NewPushWithOpts(action.WithPushConfig(cfg),
+		action.WithInsecureSkipTLSVerify(o.insecureSkipTLSverify),
+		action.WithPlainHTTP(o.plainHTTP),
+		action.WithPushOptWriter(out))
+	client.Settings = settings
+	for _, cv := range mirrored {
+		output, err := client.Run(filepath.Join(dir, cv.URLs[0]), o.dest)
+		if err != nil {
+			return errors.Wrapf(err, "failed to push %s version %s", cv.Name, cv.Version)
+		}
+		fmt.Fprint(out, output)
+	}
+	fmt.Fprintf(out, "Mirrored %d chart versions of %q to %s\n", len(mirrored), o.source, o.dest)
+	return nil
+}
+
+// sourceRepository returns the mirrored repository, a configured repository
+// or the repository at a URL.
+func (o *repoMirrorOptions) sourceRepository() (*repo.ChartRepository, error) {
+	var entry *repo.Entry
+	if f, err := repo.LoadFile(o.repoFile); err == nil && f.Has(o.source) {
+		entry = f.Get(o.source)
+	} else if u, err := url.Parse(o.source); err == nil && u.IsAbs() {
+		// The index of the repository is cached under a name of its URL
+		entry = &repo.Entry{Name: fmt.Sprintf("mirror-%x", sha256.Sum256([]byte(o.source))), URL: o.source}
+	} else {
+		return nil, errors.Errorf("no repo named %q found", o.source)
+	}
+
+	r, err := repo.NewChartRepository(entry, getter.All(settings))
+	if err != nil {
+		return nil, err
+	}
+	if o.repoCache != "" {
+		r.CachePath = o.repoCache
+	}
+	return r, nil
+}
@@ -0,0 +1,64 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/repo"
+	"helm.sh/helm/v3/pkg/repo/repotest"
+)
+
+func TestRepoMirrorCmd(t *testing.T) {
+	srv, err := repotest.NewTempServerWithCleanup(t, "testdata/testcharts/signtest-0.1.0.tgz*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	dest := filepath.Join(t.TempDir(), "mirror")
+	cache := t.TempDir()
+	cmd := fmt.Sprintf("repo mirror %s %s --chart signtest --repository-config %s --repository-cache %s", srv.URL(), dest, filepath.Join(cache, "repositories.yaml"), cache)
+	_, out, err := executeActionCommand(cmd)
+	if err != nil {
+		t.Logf("Output: %s", out)
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Mirrored 1 chart versions") {
+		t.Errorf("unexpected output: %s", out)
+	}
+	for _, name := range []string{"signtest-0.1.0.tgz", "signtest-0.1.0.tgz.prov"} {
+		if _, err := os.Stat(filepath.Join(dest, name)); err != nil {
+			t.Errorf("expected %s to be mirrored: %s", name, err)
+		}
+	}
+	i, err := repo.LoadIndexFile(filepath.Join(dest, "index.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !i.Has("signtest", "0.1.0") {
+		t.Error("expected the index of the mirror to have signtest")
+	}
+
+	if _, _, err := executeActionCommand(fmt.Sprintf("repo mirror missing %s --repository-config %s", dest, filepath.Join(cache, "repositories.yaml"))); err == nil {
+		t.Error("expected an unknown repository to fail")
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/pkg/cli/output"
 	"helm.sh/helm/v3/pkg/helmpath"
 	"helm.sh/helm/v3/pkg/repo"
 )
@@ -34,6 +35,7 @@ type repoRemoveOptions struct {
 	names     []string
 	repoFile  string
 	repoCache string
+	outfmt    output.Format
 }
 
 func newRepoRemoveCmd(out io.Writer) *cobra.Command {
@@ -54,6 +56,7 @@ func newRepoRemoveCmd(out io.Writer) *cobra.Command {
 			return o.run(out)
 		},
 	}
+	bindOutputFlag(cmd, &o.outfmt)
 	return cmd
 }
 
@@ -74,19 +77,46 @@ func (o *repoRemoveOptions) run(out io.Writer) error {
 		if err := removeRepoCache(o.repoCache, name); err != nil {
 			return err
 		}
-		fmt.Fprintf(out, "%q has been removed from your repositories\n", name)
+		if o.outfmt == "" || o.outfmt == output.Table {
+			fmt.Fprintf(out, "%q has been removed from your repositories\n", name)
+		}
+	}
+
+	if o.outfmt == "" || o.outfmt == output.Table {
+		return nil
 	}
+	return o.outfmt.Write(out, &repoRemoveWriter{o.names})
+}
+
+// repoRemoveWriter writes the names of the removed repositories.
+type repoRemoveWriter struct {
+	names []string
+}
 
+func (w *repoRemoveWriter) WriteTable(out io.Writer) error {
+	for _, name := range w.names {
+		fmt.Fprintf(out, "%q has been removed from your repositories\n", name)
+	}
 	return nil
 }
 
+func (w *repoRemoveWriter) WriteJSON(out io.Writer) error {
+	return output.EncodeJSON(out, w.names)
+}
+
+func (w *repoRemoveWriter) WriteYAML(out io.Writer) error {
+	return output.EncodeYAML(out, w.names)
+}
+
 func removeRepoCache(root, name string) error {
-	idx := filepath.Join(root, helmpath.CacheChartsFile(name))
-	if _, err := os.Stat(idx); err == nil {
-		os.Remove(idx)
+	for _, f := range []string{helmpath.CacheChartsFile(name), helmpath.CacheIndexManifestFile(name), helmpath.CacheIndexValidatorsFile(name)} {
+		idx := filepath.Join(root, f)
+		if _, err := os.Stat(idx); err == nil {
+			os.Remove(idx)
+		}
 	}
 
-	idx = filepath.Join(root, helmpath.CacheIndexFile(name))
+	idx := filepath.Join(root, helmpath.CacheIndexFile(name))
 	if _, err := os.Stat(idx); os.IsNotExist(err) {
 		return nil
 	} else if err != nil {
@@ -21,10 +21,12 @@ import (
 	"io"
 	"sync"
 
+	"github.com/gosuri/uitable"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/pkg/cli/output"
 	"helm.sh/helm/v3/pkg/getter"
 	"helm.sh/helm/v3/pkg/repo"
 )
@@ -32,10 +34,15 @@ import (
 const updateDesc = `
 Update gets the latest information about charts from the respective chart repositories.
 Information is cached locally, where it is used by commands like 'helm search'.
+The cached indices of repositories serving a sharded index (see 'helm repo
+index --shards') are updated by only downloading the charts that changed.
 
 You can optionally specify a list of repositories you want to update.
 	$ helm repo update <repo_name> ...
 To update all the repositories, use 'helm repo update'.
+
+With '--output json' or '--output yaml', the name, URL and status of the
+update of each repository are printed instead of the progress messages.
 `
 
 var errNoRepositories = errors.New("no repositories found. You must add one before updating")
@@ -46,6 +53,7 @@ type repoUpdateOptions struct {
 	repoCache            string
 	names                []string
 	failOnRepoUpdateFail bool
+	outfmt               output.Format
 }
 
 func newRepoUpdateCmd(out io.Writer) *cobra.Command {
@@ -73,6 +81,7 @@ func newRepoUpdateCmd(out io.Writer) *cobra.Command {
 	// Adding this flag for Helm 3 as stop gap functionality for https://github.com/helm/helm/issues/10016.
 	// This should be deprecated in Helm 4 by update to the behaviour of `helm repo update` command.
 	f.BoolVar(&o.failOnRepoUpdateFail, "fail-on-repo-update-fail", false, "update fails if any of the repository updates fail")
+	bindOutputFlag(cmd, &o.outfmt)
 
 	return cmd
 }
@@ -111,36 +120,102 @@ func (o *repoUpdateOptions) run(out io.Writer) error {
 		}
 	}
 
-	return o.update(repos, out, o.failOnRepoUpdateFail)
+	if o.outfmt == "" || o.outfmt == output.Table {
+		return o.update(repos, out, o.failOnRepoUpdateFail)
+	}
+	results := updateRepos(repos)
+	if err := o.outfmt.Write(out, &repoUpdateWriter{results}); err != nil {
+		return err
+	}
+	return checkRepoUpdates(results, o.failOnRepoUpdateFail)
 }
 
-func updateCharts(repos []*repo.ChartRepository, out io.Writer, failOnRepoUpdateFail bool) error {
-	fmt.Fprintln(out, "Hang tight while we grab the latest from your chart repositories...")
+// repoUpdateResult is the result of the update of a repository.
+type repoUpdateResult struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+const (
+	repoUpdated      = "updated"
+	repoUpdateFailed = "failed"
+)
+
+// updateRepos downloads the indexes of the repositories concurrently, and
+// returns the results in the order of the repositories.
+func updateRepos(repos []*repo.ChartRepository) []repoUpdateResult {
+	results := make([]repoUpdateResult, len(repos))
 	var wg sync.WaitGroup
-	var repoFailList []string
-	for _, re := range repos {
+	for i, re := range repos {
 		wg.Add(1)
-		go func(re *repo.ChartRepository) {
+		go func(i int, re *repo.ChartRepository) {
 			defer wg.Done()
+			results[i] = repoUpdateResult{Name: re.Config.Name, URL: re.Config.URL, Status: repoUpdated}
 			if _, err := re.DownloadIndexFile(); err != nil {
-				fmt.Fprintf(out, "...Unable to get an update from the %q chart repository (%s):\n\t%s\n", re.Config.Name, re.Config.URL, err)
-				repoFailList = append(repoFailList, re.Config.URL)
-			} else {
-				fmt.Fprintf(out, "...Successfully got an update from the %q chart repository\n", re.Config.Name)
+				results[i].Status, results[i].Error = repoUpdateFailed, err.Error()
 			}
-		}(re)
+		}(i, re)
 	}
 	wg.Wait()
+	return results
+}
 
+func checkRepoUpdates(results []repoUpdateResult, failOnRepoUpdateFail bool) error {
+	var repoFailList []string
+	for _, r := range results {
+		if r.Status == repoUpdateFailed {
+			repoFailList = append(repoFailList, r.URL)
+		}
+	}
 	if len(repoFailList) > 0 && failOnRepoUpdateFail {
 		return fmt.Errorf("Failed to update the following repositories: %s",
 			repoFailList)
 	}
+	return nil
+}
+
+func updateCharts(repos []*repo.ChartRepository, out io.Writer, failOnRepoUpdateFail bool) error {
+	fmt.Fprintln(out, "Hang tight while we grab the latest from your chart repositories...")
+	results := updateRepos(repos)
+	for _, r := range results {
+		if r.Status == repoUpdateFailed {
+			fmt.Fprintf(out, "...Unable to get an update from the %q chart repository (%s):\n\t%s\n", r.Name, r.URL, r.Error)
+		} else {
+			fmt.Fprintf(out, "...Successfully got an update from the %q chart repository\n", r.Name)
+		}
+	}
+
+	if err := checkRepoUpdates(results, failOnRepoUpdateFail); err != nil {
+		return err
+	}
 
 	fmt.Fprintln(out, "Update Complete. ⎈Happy Helming!⎈")
 	return nil
 }
 
+type repoUpdateWriter struct {
+	results []repoUpdateResult
+}
+
+func (w *repoUpdateWriter) WriteTable(out io.Writer) error {
+	table := uitable.New()
+	table.AddRow("NAME", "URL", "STATUS")
+	for _, r := range w.results {
+		table.AddRow(r.Name, r.URL, r.Status)
+	}
+	return output.EncodeTable(out, table)
+}
+
+func (w *repoUpdateWriter) WriteJSON(out io.Writer) error {
+	return output.EncodeJSON(out, w.results)
+}
+
+func (w *repoUpdateWriter) WriteYAML(out io.Writer) error {
+	return output.EncodeYAML(out, w.results)
+}
+
 func checkRequestedRepos(requestedRepos []string, validRepos []*repo.Entry) error {
 	for _, requestedRepo := range requestedRepos {
 		found := false
@@ -17,6 +17,7 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -25,6 +26,7 @@ import (
 	"testing"
 
 	"helm.sh/helm/v3/internal/test/ensure"
+	"helm.sh/helm/v3/pkg/cli/output"
 	"helm.sh/helm/v3/pkg/getter"
 	"helm.sh/helm/v3/pkg/repo"
 	"helm.sh/helm/v3/pkg/repo/repotest"
@@ -156,6 +158,57 @@ func TestUpdateCharts(t *testing.T) {
 	}
 }
 
+func TestUpdateChartsOutput(t *testing.T) {
+	defer resetEnv()()
+	ensure.HelmHome(t)
+
+	ts, err := repotest.NewTempServerWithCleanup(t, "testdata/testserver/*.*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ts.Stop()
+
+	repoFile := filepath.Join(t.TempDir(), "repositories.yaml")
+	f := repo.NewFile()
+	f.Add(&repo.Entry{Name: "charts", URL: ts.URL()}, &repo.Entry{Name: "broken", URL: ts.URL() + "55"})
+	if err := f.WriteFile(repoFile, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	o := &repoUpdateOptions{
+		update:    updateCharts,
+		repoFile:  repoFile,
+		repoCache: t.TempDir(),
+		outfmt:    output.JSON,
+	}
+	var b bytes.Buffer
+	if err := o.run(&b); err != nil {
+		t.Fatal(err)
+	}
+	var results []repoUpdateResult
+	if err := json.Unmarshal(b.Bytes(), &results); err != nil {
+		t.Fatalf("expected JSON output, got %q: %v", b.String(), err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %v", results)
+	}
+	if r := results[0]; r.Name != "charts" || r.Status != repoUpdated || r.Error != "" {
+		t.Errorf("expected charts to be updated, got %+v", r)
+	}
+	if r := results[1]; r.Name != "broken" || r.Status != repoUpdateFailed || r.Error == "" {
+		t.Errorf("expected broken to fail, got %+v", r)
+	}
+
+	o.failOnRepoUpdateFail = true
+	b.Reset()
+	if err := o.run(&b); err == nil {
+		t.Error("expected an error with fail-on-repo-update-fail")
+	}
+	if !json.Valid(b.Bytes()) {
+		t.Errorf("expected the results to be written before the error, got %q", b.String())
+	}
+}
+
 func TestRepoUpdateFileCompletion(t *testing.T) {
 	checkFileCompletion(t, "repo update", false)
 	checkFileCompletion(t, "repo update repo1", false)
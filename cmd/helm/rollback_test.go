@@ -17,6 +17,7 @@ limitations under the License.
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"testing"
@@ -164,3 +165,36 @@ func TestRollbackWithLabels(t *testing.T) {
 		t.Errorf("Expected {%v}, got {%v}", labels1, updatedRel.Labels)
 	}
 }
+
+func TestRollbackOutputJSON(t *testing.T) {
+	storage := storageFixture()
+	for _, rel := range []*release.Release{
+		{
+			Name:    "funny-honey",
+			Info:    &release.Info{Status: release.StatusSuperseded},
+			Chart:   &chart.Chart{},
+			Version: 1,
+		},
+		{
+			Name:    "funny-honey",
+			Info:    &release.Info{Status: release.StatusDeployed},
+			Chart:   &chart.Chart{},
+			Version: 2,
+		},
+	} {
+		if err := storage.Create(rel); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, out, err := executeActionCommandC(storage, "rollback funny-honey 1 -o json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rel release.Release
+	if err := json.Unmarshal([]byte(out), &rel); err != nil {
+		t.Fatalf("output is not a release: %s\n%s", err, out)
+	}
+	if rel.Name != "funny-honey" || rel.Version != 3 || rel.Info.Status != release.StatusDeployed {
+		t.Errorf("expected the deployed revision 3 of funny-honey, got revision %d of %q in status %q", rel.Version, rel.Name, rel.Info.Status)
+	}
+}
@@ -25,11 +25,13 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/clientcmd"
 
 	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/plugin/credentials"
 	"helm.sh/helm/v3/pkg/registry"
 	"helm.sh/helm/v3/pkg/repo"
 )
@@ -51,15 +53,36 @@ Environment variables:
 | $HELM_CONFIG_HOME                  | set an alternative location for storing Helm configuration.                                                |
 | $HELM_DATA_HOME                    | set an alternative location for storing Helm data.                                                         |
 | $HELM_DEBUG                        | indicate whether or not Helm is running in Debug mode                                                      |
-| $HELM_DRIVER                       | set the backend storage driver. Values are: configmap, secret, memory, sql.                                |
+| $HELM_DRIVER                       | set the backend storage driver. Values are: configmap, secret, memory, sql, sqlite, crd, objectstore.      |
+| $HELM_DRIVER_COMPRESSION           | set the compression of stored releases. Values are: gzip (default), zstd.                                  |
+| $HELM_DRIVER_COMPRESSION_LEVEL     | set the compression level of stored releases (gzip: 1-9, zstd: 1-22).                                      |
+| $HELM_DRIVER_OBJECTSTORE_URL       | set the bucket the object storage driver should use, e.g. s3://bucket/prefix or file:///var/lib/helm.     |
 | $HELM_DRIVER_SQL_CONNECTION_STRING | set the connection string the SQL storage driver should use.                                               |
+| $HELM_DRIVER_SQL_DIALECT           | set the dialect of the SQL storage driver database. Values are: postgres, mysql, sqlite.                   |
+| $HELM_DRIVER_SQL_PASSWORD_FILE     | set a file holding the SQL database password, read for every connection (e.g. IAM tokens).                 |
+| $HELM_DRIVER_SQL_TLS_CA_FILE       | verify the certificate of the SQL database using this CA bundle.                                           |
+| $HELM_DRIVER_SQL_TLS_CERT_FILE     | identify to the SQL database using this client certificate file.                                           |
+| $HELM_DRIVER_SQL_TLS_KEY_FILE      | identify to the SQL database using this client key file.                                                   |
+| $HELM_DRIVER_SQL_MAX_OPEN_CONNS    | set the maximum number of open connections to the SQL database.                                            |
+| $HELM_DRIVER_SQL_MAX_IDLE_CONNS    | set the maximum number of idle connections to the SQL database.                                            |
+| $HELM_DRIVER_SQL_CONN_MAX_LIFETIME | set the maximum amount of time a connection to the SQL database may be reused.                             |
+| $HELM_DRIVER_SQL_SKIP_MIGRATIONS   | disable the automatic creation and migration of the SQL database schema.                                   |
+| $HELM_DRIVER_SQLITE_PATH           | set the database file of the SQLite storage driver (default $HELM_DATA_HOME/releases.db).                  |
+| $HELM_ENCRYPTION_KEY_FILE          | encrypt stored releases with data keys wrapped by the 32 byte key in this file.                            |
+| $HELM_ENCRYPTION_WRAP_COMMAND      | encrypt stored releases with data keys wrapped by this command (e.g. age, a KMS client).                   |
+| $HELM_ENCRYPTION_UNWRAP_COMMAND    | set the command unwrapping the data keys of encrypted releases.                                            |
 | $HELM_MAX_HISTORY                  | set the maximum number of helm release history.                                                            |
 | $HELM_NAMESPACE                    | set the namespace used for the helm operations.                                                            |
 | $HELM_NO_PLUGINS                   | disable plugins. Set HELM_NO_PLUGINS=1 to disable plugins.                                                 |
 | $HELM_PLUGINS                      | set the path to the plugins directory                                                                      |
+| $HELM_PLUGIN_VERIFY_POLICY         | set the policy of the plugins installed without a trusted signature: warn (default) or deny.               |
+| $HELM_PLUGIN_KEYRING               | set the keyring of the GPG keys trusted to sign plugins (default the GnuPG public keyring).                |
+| $HELM_RECORD_EVENTS                | record Kubernetes Events on the release record when operations start, succeed and fail.                    |
 | $HELM_REGISTRY_CONFIG              | set the path to the registry config file.                                                                  |
+| $HELM_REGISTRIES_CONFIG            | set the path to the file configuring the mirrors of registries.                                            |
 | $HELM_REPOSITORY_CACHE             | set the path to the repository cache directory                                                             |
 | $HELM_REPOSITORY_CONFIG            | set the path to the repositories file.                                                                     |
+| $HELM_SIGNATURE_POLICY             | set the path to the policy verifying the sigstore signatures of charts                                     |
 | $KUBECONFIG                        | set an alternative Kubernetes configuration file (default "~/.kube/config")                                |
 | $HELM_KUBEAPISERVER                | set the Kubernetes API Server Endpoint for authentication                                                  |
 | $HELM_KUBECAFILE                   | set the Kubernetes certificate authority file.                                                             |
@@ -72,6 +95,11 @@ Environment variables:
 | $HELM_BURST_LIMIT                  | set the default burst limit in the case the server contains many CRDs (default 100, -1 to disable)         |
 | $HELM_QPS                          | set the Queries Per Second in cases where a high number of calls exceed the option for higher burst values |
 
+With HELM_DRIVER=crd, the releases are stored as HelmReleaseRecord custom
+resources. Their CustomResourceDefinition is installed on first use if it is
+missing; it is printed by 'helm release crd' for an administrator to install it
+when the users are not allowed to.
+
 Helm stores cache, configuration, and data based on the following configuration order:
 
 - If a HELM_*_HOME environment variable is set, it will be used
@@ -162,13 +190,14 @@ func newRootCmd(actionConfig *action.Configuration, out io.Writer, args []string
 	// Add subcommands
 	cmd.AddCommand(
 		// chart commands
+		newChartCmd(actionConfig, out),
 		newCreateCmd(out),
 		newDependencyCmd(actionConfig, out),
 		newPullCmd(actionConfig, out),
 		newShowCmd(actionConfig, out),
 		newLintCmd(out),
 		newPackageCmd(actionConfig, out),
-		newRepoCmd(out),
+		newRepoCmd(actionConfig, out),
 		newSearchCmd(out),
 		newVerifyCmd(out),
 
@@ -177,6 +206,7 @@ func newRootCmd(actionConfig *action.Configuration, out io.Writer, args []string
 		newHistoryCmd(actionConfig, out),
 		newInstallCmd(actionConfig, out),
 		newListCmd(actionConfig, out),
+		newReleaseCmd(actionConfig, out),
 		newReleaseTestCmd(actionConfig, out),
 		newRollbackCmd(actionConfig, out),
 		newStatusCmd(actionConfig, out),
@@ -184,6 +214,7 @@ func newRootCmd(actionConfig *action.Configuration, out io.Writer, args []string
 		newUninstallCmd(actionConfig, out),
 		newUpgradeCmd(actionConfig, out),
 
+		newCacheCmd(out),
 		newCompletionCmd(out),
 		newEnvCmd(out),
 		newPluginCmd(out),
@@ -279,10 +310,13 @@ func newDefaultRegistryClient(plainHTTP bool) (*registry.Client, error) {
 		registry.ClientOptEnableCache(true),
 		registry.ClientOptWriter(os.Stderr),
 		registry.ClientOptCredentialsFile(settings.RegistryConfig),
+		registry.ClientOptRegistriesConfig(settings.RegistriesConfig),
 	}
 	if plainHTTP {
 		opts = append(opts, registry.ClientOptPlainHTTP())
 	}
+	opts = append(opts, registryProgressOptions()...)
+	opts = append(opts, registryCredentialOptions()...)
 
 	// Create a new registry client
 	registryClient, err := registry.NewClient(opts...)
@@ -296,9 +330,34 @@ func newRegistryClientWithTLS(certFile, keyFile, caFile string, insecureSkipTLSv
 	// Create a new registry client
 	registryClient, err := registry.NewRegistryClientWithTLS(os.Stderr, certFile, keyFile, caFile, insecureSkipTLSverify,
 		settings.RegistryConfig, settings.Debug,
+		append(append(registryProgressOptions(), registryCredentialOptions()...), registry.ClientOptRegistriesConfig(settings.RegistriesConfig))...,
 	)
 	if err != nil {
 		return nil, err
 	}
 	return registryClient, nil
 }
+
+// registryCredentialOptions returns the options getting the credentials of
+// the registries without stored credentials from the credential providers of
+// the plugins, if any.
+func registryCredentialOptions() []registry.ClientOption {
+	providers, err := credentials.Load(settings)
+	if err != nil {
+		debug("loading the credential providers: %s", err)
+		return nil
+	}
+	if providers == nil {
+		return nil
+	}
+	return []registry.ClientOption{registry.ClientOptCredentialProvider(providers)}
+}
+
+// registryProgressOptions returns the options showing a progress bar of the
+// transfers of the registry clients, if stderr is a terminal.
+func registryProgressOptions() []registry.ClientOption {
+	if !term.IsTerminal(int(os.Stderr.Fd())) {
+		return nil
+	}
+	return []registry.ClientOption{registry.ClientOptProgress(newProgressBar(os.Stderr))}
+}
@@ -20,21 +20,45 @@ import (
 	"io"
 
 	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v3/pkg/registry"
 )
 
 const searchDesc = `
 Search provides the ability to search for Helm charts in the various places
 they can be stored including the Artifact Hub and repositories you have added.
 Use search subcommands to search different locations for charts.
+
+OCI registries are searched when the first argument is an oci:// reference to
+a registry or to a namespace of a registry. The repositories under the
+namespace are listed with the catalog API of the registry, and the charts they
+store are searched like the charts of a repository index, with the optional
+keyword. Registries which do not implement the catalog API cannot be searched.
+
+Examples:
+
+    # List the charts stored under the "charts" namespace of a registry
+    $ helm search oci://registry.example.com/charts
+
+    # Search every version of the charts of a registry for the keyword "nginx"
+    $ helm search oci://registry.example.com nginx --versions
 `
 
 func newSearchCmd(out io.Writer) *cobra.Command {
+	o := &searchOCIOptions{}
 
 	cmd := &cobra.Command{
-		Use:   "search [keyword]",
+		Use:   "search [oci://registry/namespace] [keyword]",
 		Short: "search for a keyword in charts",
 		Long:  searchDesc,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 || !registry.IsOCI(args[0]) {
+				return cmd.Help()
+			}
+			return o.run(out, args[0], args[1:])
+		},
 	}
+	addSearchOCIFlags(cmd, o)
 
 	cmd.AddCommand(newSearchHubCmd(out))
 	cmd.AddCommand(newSearchRepoCmd(out))
@@ -28,6 +28,7 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/Masterminds/semver/v3"
 
@@ -48,6 +49,7 @@ type Result struct {
 type Index struct {
 	lines  map[string]string
 	charts map[string]*repo.ChartVersion
+	filter Filter
 }
 
 const sep = "\v"
@@ -57,6 +59,39 @@ func NewIndex() *Index {
 	return &Index{lines: map[string]string{}, charts: map[string]*repo.ChartVersion{}}
 }
 
+// NewFilteredIndex creates a new Index of the charts selected by the filter.
+// The charts are filtered as they are added, so that the charts which are
+// not selected are never searched.
+func NewFilteredIndex(filter Filter) *Index {
+	i := NewIndex()
+	i.filter = filter
+	return i
+}
+
+// Filter selects the charts of an index. Its zero value selects every chart.
+type Filter struct {
+	// Name is a regular expression the full names of the charts must match,
+	// like "bitnami/nginx".
+	Name *regexp.Regexp
+	// CreatedAfter selects the versions created after it, if it is set. The
+	// versions without a creation date are not selected.
+	CreatedAfter time.Time
+	// NotDeprecated excludes the deprecated charts.
+	NotDeprecated bool
+}
+
+// Matches returns whether the filter selects the version of a chart named
+// name.
+func (f Filter) Matches(name string, created time.Time, deprecated bool) bool {
+	if f.Name != nil && !f.Name.MatchString(name) {
+		return false
+	}
+	if !f.CreatedAfter.IsZero() && !created.After(f.CreatedAfter) {
+		return false
+	}
+	return !f.NotDeprecated || !deprecated
+}
+
 // verSep is a separator for version fields in map keys.
 const verSep = "$$"
 
@@ -74,6 +109,14 @@ func (i *Index) AddRepo(rname string, ind *repo.IndexFile, all bool) {
 		// Note: Do not use filePath.Join since on Windows it will return \
 		//       which results in a repo name that cannot be understood.
 		fname := path.Join(rname, name)
+		// A chart is deprecated by its newest version.
+		if i.filter.NotDeprecated && ref[0].Deprecated {
+			continue
+		}
+		ref = i.filter.versions(fname, ref)
+		if len(ref) == 0 {
+			continue
+		}
 		if !all {
 			i.lines[fname] = indstr(rname, ref[0])
 			i.charts[fname] = ref[0]
@@ -90,6 +133,20 @@ func (i *Index) AddRepo(rname string, ind *repo.IndexFile, all bool) {
 	}
 }
 
+// versions returns the versions of the chart named name the filter selects.
+func (f Filter) versions(name string, ref repo.ChartVersions) repo.ChartVersions {
+	if f == (Filter{}) {
+		return ref
+	}
+	var selected repo.ChartVersions
+	for _, rr := range ref {
+		if f.Matches(name, rr.Created, rr.Deprecated) {
+			selected = append(selected, rr)
+		}
+	}
+	return selected
+}
+
 // All returns all charts in the index as if they were search results.
 //
 // Each will be given a score of 0.
@@ -184,6 +241,41 @@ func SortScore(r []*Result) {
 	sort.Sort(scoreSorter(r))
 }
 
+// SortName does an in-place sort of the results by name, the newest versions
+// of a chart first, regardless of their scores.
+func SortName(r []*Result) {
+	sort.SliceStable(r, func(a, b int) bool {
+		if r[a].Name != r[b].Name {
+			return r[a].Name < r[b].Name
+		}
+		return newerVersion(r[a].Chart, r[b].Chart)
+	})
+}
+
+// SortCreated does an in-place sort of the results by creation date, the
+// newest first. Results created at the same time are subsorted by name.
+func SortCreated(r []*Result) {
+	sort.SliceStable(r, func(a, b int) bool {
+		if !r[a].Chart.Created.Equal(r[b].Chart.Created) {
+			return r[a].Chart.Created.After(r[b].Chart.Created)
+		}
+		return r[a].Name < r[b].Name
+	})
+}
+
+// newerVersion returns whether the version of a is newer than the one of b.
+func newerVersion(a, b *repo.ChartVersion) bool {
+	v1, err := semver.NewVersion(a.Version)
+	if err != nil {
+		return false
+	}
+	v2, err := semver.NewVersion(b.Version)
+	if err != nil {
+		return true
+	}
+	return v1.GreaterThan(v2)
+}
+
 // scoreSorter sorts results by score, and subsorts by alpha Name.
 type scoreSorter []*Result
 
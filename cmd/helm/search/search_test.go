@@ -17,8 +17,12 @@ limitations under the License.
 package search
 
 import (
+	"reflect"
+	"regexp"
+	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/repo"
@@ -309,3 +313,77 @@ func TestCalcScore(t *testing.T) {
 		t.Errorf("Expected 3, got %d", r)
 	}
 }
+
+func TestFilteredIndex(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	entries := map[string]repo.ChartVersions{
+		"web": {
+			{Metadata: &chart.Metadata{Name: "web", Version: "1.1.0"}, Created: day(20)},
+			{Metadata: &chart.Metadata{Name: "web", Version: "1.0.0"}, Created: day(10)},
+		},
+		"legacy": {
+			{Metadata: &chart.Metadata{Name: "legacy", Version: "2.0.0", Deprecated: true}, Created: day(25)},
+			{Metadata: &chart.Metadata{Name: "legacy", Version: "1.0.0"}, Created: day(5)},
+		},
+		"worker": {
+			{Metadata: &chart.Metadata{Name: "worker", Version: "0.1.0"}, Created: day(1)},
+		},
+	}
+	names := func(res []*Result) []string {
+		var names []string
+		for _, r := range res {
+			names = append(names, r.Name+"-"+r.Chart.Version)
+		}
+		return names
+	}
+
+	for _, tt := range []struct {
+		name   string
+		filter Filter
+		all    bool
+		expect []string
+	}{{
+		name:   "no filter",
+		expect: []string{"testing/legacy-2.0.0", "testing/web-1.1.0", "testing/worker-0.1.0"},
+	}, {
+		name:   "name",
+		filter: Filter{Name: regexp.MustCompile("^testing/w")},
+		expect: []string{"testing/web-1.1.0", "testing/worker-0.1.0"},
+	}, {
+		name:   "created after",
+		filter: Filter{CreatedAfter: day(15)},
+		all:    true,
+		expect: []string{"testing/legacy-2.0.0", "testing/web-1.1.0"},
+	}, {
+		name:   "not deprecated",
+		filter: Filter{NotDeprecated: true},
+		all:    true,
+		expect: []string{"testing/web-1.0.0", "testing/web-1.1.0", "testing/worker-0.1.0"},
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			i := NewFilteredIndex(tt.filter)
+			i.AddRepo("testing", &repo.IndexFile{Entries: entries}, tt.all)
+			res := i.All()
+			SortName(res)
+			got := names(res)
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.expect) {
+				t.Errorf("expected %v, got %v", tt.expect, got)
+			}
+		})
+	}
+
+	i := NewIndex()
+	i.AddRepo("testing", &repo.IndexFile{Entries: entries}, true)
+	res := i.All()
+	SortName(res)
+	expect := []string{"testing/legacy-2.0.0", "testing/legacy-1.0.0", "testing/web-1.1.0", "testing/web-1.0.0", "testing/worker-0.1.0"}
+	if got := names(res); !reflect.DeepEqual(got, expect) {
+		t.Errorf("expected the results sorted by name, got %v", got)
+	}
+	SortCreated(res)
+	expect = []string{"testing/legacy-2.0.0", "testing/web-1.1.0", "testing/web-1.0.0", "testing/legacy-1.0.0", "testing/worker-0.1.0"}
+	if got := names(res); !reflect.DeepEqual(got, expect) {
+		t.Errorf("expected the results sorted by creation date, got %v", got)
+	}
+}
@@ -19,12 +19,15 @@ package main
 import (
 	"fmt"
 	"io"
+	"path"
+	"sort"
 	"strings"
 
 	"github.com/gosuri/uitable"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
+	"helm.sh/helm/v3/cmd/helm/search"
 	"helm.sh/helm/v3/internal/monocular"
 	"helm.sh/helm/v3/pkg/cli/output"
 )
@@ -47,9 +50,14 @@ Monocular search API. Similarly, when setting the 'endpoint' flag, the specified
 endpoint must also be implement a Monocular compatible search API endpoint.
 Note that when specifying a Monocular instance as the 'endpoint', rich queries
 are not supported. For API details, see https://github.com/helm/monocular
+
+The '--name-regexp', '--created-after' and '--not-deprecated' flags filter the
+results returned by the hub, by the full names of the charts (like
+'bitnami/nginx') and the creation dates of their latest versions.
 `
 
 type searchHubOptions struct {
+	searchFilterOptions
 	searchEndpoint string
 	maxColWidth    uint
 	outputFormat   output.Format
@@ -74,6 +82,7 @@ func newSearchHubCmd(out io.Writer) *cobra.Command {
 	f.UintVar(&o.maxColWidth, "max-col-width", 50, "maximum column width for output table")
 	f.BoolVar(&o.listRepoURL, "list-repo-url", false, "print charts repository URL")
 	f.BoolVar(&o.failOnNoResult, "fail-on-no-result", false, "search fails if no results are found")
+	addSearchFilterFlags(cmd, &o.searchFilterOptions)
 
 	bindOutputFlag(cmd, &o.outputFormat)
 
@@ -81,6 +90,10 @@ func newSearchHubCmd(out io.Writer) *cobra.Command {
 }
 
 func (o *searchHubOptions) run(out io.Writer, args []string) error {
+	filter, err := o.filter()
+	if err != nil {
+		return err
+	}
 	c, err := monocular.New(o.searchEndpoint)
 	if err != nil {
 		return errors.Wrap(err, fmt.Sprintf("unable to create connection to %q", o.searchEndpoint))
@@ -92,10 +105,37 @@ func (o *searchHubOptions) run(out io.Writer, args []string) error {
 		debug("%s", err)
 		return fmt.Errorf("unable to perform search against %q", o.searchEndpoint)
 	}
+	results = o.filterResults(results, filter)
 
 	return o.outputFormat.Write(out, newHubSearchWriter(results, o.searchEndpoint, o.maxColWidth, o.listRepoURL, o.failOnNoResult))
 }
 
+// filterResults returns the results selected by the filter, in the sort order
+// of the options. The results are named after their repositories, like
+// "bitnami/nginx", and are in the order of the hub if they are sorted by score.
+func (o *searchHubOptions) filterResults(results []monocular.SearchResult, filter search.Filter) []monocular.SearchResult {
+	var selected []monocular.SearchResult
+	for _, r := range results {
+		name := path.Join(r.Attributes.Repo.Name, r.Attributes.Name)
+		if filter.Matches(name, r.Relationships.LatestChartVersion.Data.Created, r.Attributes.Deprecated) {
+			selected = append(selected, r)
+		}
+	}
+
+	switch o.sortBy {
+	case searchSortName:
+		sort.SliceStable(selected, func(i, j int) bool {
+			return path.Join(selected[i].Attributes.Repo.Name, selected[i].Attributes.Name) <
+				path.Join(selected[j].Attributes.Repo.Name, selected[j].Attributes.Name)
+		})
+	case searchSortCreated:
+		sort.SliceStable(selected, func(i, j int) bool {
+			return selected[i].Relationships.LatestChartVersion.Data.Created.After(selected[j].Relationships.LatestChartVersion.Data.Created)
+		})
+	}
+	return selected
+}
+
 type hubChartRepo struct {
 	URL  string `json:"url"`
 	Name string `json:"name"`
@@ -17,9 +17,12 @@ limitations under the License.
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -83,6 +86,46 @@ func TestSearchHubListRepoCmd(t *testing.T) {
 	}
 }
 
+func TestSearchHubFilters(t *testing.T) {
+	var searchResult = `{"data":[
+{"id":"stable/web","attributes":{"name":"web","repo":{"name":"stable"},"description":"Web"},"relationships":{"latestChartVersion":{"data":{"version":"1.0.0","created":"2024-01-10T00:00:00Z"}}}},
+{"id":"bitnami/web","attributes":{"name":"web","repo":{"name":"bitnami"},"description":"Web"},"relationships":{"latestChartVersion":{"data":{"version":"2.0.0","created":"2024-02-10T00:00:00Z"}}}},
+{"id":"bitnami/legacy","attributes":{"name":"legacy","repo":{"name":"bitnami"},"description":"Legacy","deprecated":true},"relationships":{"latestChartVersion":{"data":{"version":"3.0.0","created":"2024-03-10T00:00:00Z"}}}}
+]}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintln(w, searchResult)
+	}))
+	defer ts.Close()
+
+	for _, tt := range []struct {
+		args   string
+		expect []string
+	}{
+		{"", []string{"stable/web", "bitnami/web", "bitnami/legacy"}},
+		{"--not-deprecated", []string{"stable/web", "bitnami/web"}},
+		{"--name-regexp ^bitnami/", []string{"bitnami/web", "bitnami/legacy"}},
+		{"--created-after 2024-02-01 --sort-by name", []string{"bitnami/legacy", "bitnami/web"}},
+		{"--sort-by created", []string{"bitnami/legacy", "bitnami/web", "stable/web"}},
+	} {
+		_, out, err := executeActionCommand("search hub --endpoint " + ts.URL + " web --output json " + tt.args)
+		if err != nil {
+			t.Errorf("%q: unexpected error, %s", tt.args, err)
+			continue
+		}
+		var elements []hubChartElement
+		if err := json.Unmarshal([]byte(out), &elements); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, e := range elements {
+			got = append(got, strings.TrimPrefix(e.URL, ts.URL+"/charts/"))
+		}
+		if !reflect.DeepEqual(got, tt.expect) {
+			t.Errorf("%q: expected %v, got %v", tt.args, tt.expect, got)
+		}
+	}
+}
+
 func TestSearchHubOutputCompletion(t *testing.T) {
 	outputFlagCompletionTest(t, "search hub")
 }
@@ -0,0 +1,123 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v3/cmd/helm/search"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+type searchOCIOptions struct {
+	searchRepoOptions
+	certFile              string
+	keyFile               string
+	caFile                string
+	insecureSkipTLSverify bool
+	plainHTTP             bool
+}
+
+func addSearchOCIFlags(cmd *cobra.Command, o *searchOCIOptions) {
+	f := cmd.Flags()
+	f.BoolVarP(&o.regexp, "regexp", "r", false, "use regular expressions for searching the registry")
+	f.BoolVarP(&o.versions, "versions", "l", false, "show the long listing, with each version of each chart on its own line, for the registry")
+	f.BoolVar(&o.devel, "devel", false, "use development versions (alpha, beta, and release candidate releases), too. Equivalent to version '>0.0.0-0'. If --version is set, this is ignored")
+	f.StringVar(&o.version, "version", "", "search using semantic versioning constraints on the registry")
+	f.UintVar(&o.maxColWidth, "max-col-width", 50, "maximum column width for output table")
+	f.BoolVar(&o.failOnNoResult, "fail-on-no-result", false, "search fails if no results are found")
+	f.StringVar(&o.certFile, "cert-file", "", "identify registry client using this SSL certificate file")
+	f.StringVar(&o.keyFile, "key-file", "", "identify registry client using this SSL key file")
+	f.StringVar(&o.caFile, "ca-file", "", "verify certificates of HTTPS-enabled servers using this CA bundle")
+	f.BoolVar(&o.insecureSkipTLSverify, "insecure-skip-tls-verify", false, "skip tls certificate checks for the registry")
+	f.BoolVar(&o.plainHTTP, "plain-http", false, "use insecure HTTP connections for the registry")
+	addSearchFilterFlags(cmd, &o.searchFilterOptions)
+
+	bindOutputFlag(cmd, &o.outputFormat)
+}
+
+func (o *searchOCIOptions) run(out io.Writer, namespace string, args []string) error {
+	o.setupSearchedVersion()
+
+	filter, err := o.filter()
+	if err != nil {
+		return err
+	}
+	registryClient, err := newRegistryClient(o.certFile, o.keyFile, o.caFile, o.insecureSkipTLSverify, o.plainHTTP)
+	if err != nil {
+		return fmt.Errorf("missing registry client: %w", err)
+	}
+	index, err := o.buildIndex(registryClient, namespace, filter)
+	if err != nil {
+		return err
+	}
+
+	var res []*search.Result
+	if len(args) == 0 {
+		res = index.All()
+	} else {
+		q := strings.Join(args, " ")
+		res, err = index.Search(q, searchMaxScore, o.regexp)
+		if err != nil {
+			return err
+		}
+	}
+
+	search.SortScore(res)
+	data, err := o.applyConstraint(res)
+	if err != nil {
+		return err
+	}
+	o.sort(data)
+	for _, r := range data {
+		r.Name = fmt.Sprintf("%s://%s", registry.OCIScheme, r.Name)
+	}
+
+	return o.outputFormat.Write(out, &repoSearchWriter{data, o.maxColWidth, o.failOnNoResult})
+}
+
+// buildIndex lists the charts under namespace as the index of a repository
+// named after the registry, with the paths of the repositories as chart
+// names. Every version is listed, so the constraints can select any of them.
+func (o *searchOCIOptions) buildIndex(registryClient *registry.Client, namespace string, filter search.Filter) (*search.Index, error) {
+	entries, err := registryClient.Catalog(namespace, registry.CatalogOptAllVersions(true))
+	if err != nil {
+		return nil, err
+	}
+
+	ind := repo.NewIndexFile()
+	host := ""
+	for _, entry := range entries {
+		var name string
+		host, name, _ = strings.Cut(entry.Repository, "/")
+		for _, v := range entry.Versions {
+			ind.Entries[name] = append(ind.Entries[name], &repo.ChartVersion{
+				Metadata: v.Metadata,
+				URLs:     []string{fmt.Sprintf("%s://%s:%s", registry.OCIScheme, entry.Repository, v.Tag)},
+			})
+		}
+	}
+
+	i := search.NewFilteredIndex(filter)
+	i.AddRepo(host, ind, true)
+	return i, nil
+}
@@ -0,0 +1,89 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/repo/repotest"
+)
+
+func TestSearchOCICmd(t *testing.T) {
+	srv, err := repotest.NewTempServerWithCleanup(t, "testdata/testcharts/*.tgz*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	ociSrv, err := repotest.NewOCIServer(t, srv.Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ociSrv.Run(t)
+
+	tests := []struct {
+		name      string
+		args      string
+		expect    []string
+		wantError bool
+	}{{
+		name:   "list the charts of a namespace",
+		args:   fmt.Sprintf("oci://%s/u/ocitestuser", ociSrv.RegistryURL),
+		expect: []string{fmt.Sprintf("oci://%s/u/ocitestuser/oci-dependent-chart", ociSrv.RegistryURL), "0.1.0"},
+	}, {
+		name:   "search the charts of a registry for a keyword",
+		args:   fmt.Sprintf("oci://%s oci-dependent", ociSrv.RegistryURL),
+		expect: []string{fmt.Sprintf("oci://%s/u/ocitestuser/oci-dependent-chart", ociSrv.RegistryURL)},
+	}, {
+		name:   "search a namespace without charts",
+		args:   fmt.Sprintf("oci://%s/u/nobody", ociSrv.RegistryURL),
+		expect: []string{"No results found"},
+	}, {
+		name:   "search for a version constraint no chart matches",
+		args:   fmt.Sprintf("oci://%s/u/ocitestuser --version '>= 1.0.0'", ociSrv.RegistryURL),
+		expect: []string{"No results found"},
+	}, {
+		name:      "fail on no result",
+		args:      fmt.Sprintf("oci://%s/u/nobody --fail-on-no-result", ociSrv.RegistryURL),
+		wantError: true,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := fmt.Sprintf("search %s --max-col-width 120 --plain-http --registry-config %s",
+				tt.args, filepath.Join(srv.Root(), "config.json"))
+			_, out, err := executeActionCommand(cmd)
+			if tt.wantError {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range tt.expect {
+				if !strings.Contains(out, s) {
+					t.Errorf("expected %q in the output, got:\n%s", s, out)
+				}
+			}
+		})
+	}
+}
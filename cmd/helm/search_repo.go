@@ -21,9 +21,12 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/gosuri/uitable"
@@ -56,6 +59,10 @@ Examples:
     # Search for the latest stable release for nginx-ingress with a major version of 1
     $ helm search repo nginx-ingress --version ^1.0.0
 
+    # List the charts of the bitnami repository created in 2024 which are not
+    # deprecated, the newest first
+    $ helm search repo --name-regexp '^bitnami/' --created-after 2024-01-01 --not-deprecated --sort-by created
+
 Repositories are managed with 'helm repo' commands.
 `
 
@@ -63,6 +70,7 @@ Repositories are managed with 'helm repo' commands.
 const searchMaxScore = 25
 
 type searchRepoOptions struct {
+	searchFilterOptions
 	versions       bool
 	regexp         bool
 	devel          bool
@@ -95,16 +103,91 @@ func newSearchRepoCmd(out io.Writer) *cobra.Command {
 	f.StringVar(&o.version, "version", "", "search using semantic versioning constraints on repositories you have added")
 	f.UintVar(&o.maxColWidth, "max-col-width", 50, "maximum column width for output table")
 	f.BoolVar(&o.failOnNoResult, "fail-on-no-result", false, "search fails if no results are found")
+	addSearchFilterFlags(cmd, &o.searchFilterOptions)
 
 	bindOutputFlag(cmd, &o.outputFormat)
 
 	return cmd
 }
 
+// Sort orders of the search results
+const (
+	searchSortScore   = "score"
+	searchSortName    = "name"
+	searchSortCreated = "created"
+)
+
+// searchFilterOptions are the options selecting and sorting the charts found by
+// the search commands.
+type searchFilterOptions struct {
+	nameRegexp    string
+	createdAfter  string
+	notDeprecated bool
+	sortBy        string
+}
+
+func addSearchFilterFlags(cmd *cobra.Command, o *searchFilterOptions) {
+	f := cmd.Flags()
+	f.StringVar(&o.nameRegexp, "name-regexp", "", "only show the charts whose full name (like 'bitnami/nginx') matches the regular expression")
+	f.StringVar(&o.createdAfter, "created-after", "", "only show the chart versions created after the date, like 2024-01-31 or 2024-01-31T12:00:00Z")
+	f.BoolVar(&o.notDeprecated, "not-deprecated", false, "do not show the deprecated charts")
+	f.StringVar(&o.sortBy, "sort-by", searchSortScore, fmt.Sprintf("sort the charts by %s (relevance), %s or %s (newest first)", searchSortScore, searchSortName, searchSortCreated))
+
+	err := cmd.RegisterFlagCompletionFunc("sort-by", func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return []string{searchSortScore, searchSortName, searchSortCreated}, cobra.ShellCompDirectiveNoFileComp
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// filter returns the filter of the charts selected by the options, after
+// checking the sort order.
+func (o *searchFilterOptions) filter() (search.Filter, error) {
+	var filter search.Filter
+	switch o.sortBy {
+	case "", searchSortScore, searchSortName, searchSortCreated:
+	default:
+		return filter, errors.Errorf("invalid --sort-by %q: must be %s, %s or %s", o.sortBy, searchSortScore, searchSortName, searchSortCreated)
+	}
+	if o.nameRegexp != "" {
+		re, err := regexp.Compile(o.nameRegexp)
+		if err != nil {
+			return filter, errors.Wrap(err, "invalid --name-regexp")
+		}
+		filter.Name = re
+	}
+	if o.createdAfter != "" {
+		t, err := time.Parse(time.RFC3339, o.createdAfter)
+		if err != nil {
+			if t, err = time.Parse(time.DateOnly, o.createdAfter); err != nil {
+				return filter, errors.Errorf("invalid --created-after %q: expected a date like 2024-01-31 or 2024-01-31T12:00:00Z", o.createdAfter)
+			}
+		}
+		filter.CreatedAfter = t
+	}
+	filter.NotDeprecated = o.notDeprecated
+	return filter, nil
+}
+
+// sort sorts the results, which are sorted by score already.
+func (o *searchFilterOptions) sort(res []*search.Result) {
+	switch o.sortBy {
+	case searchSortName:
+		search.SortName(res)
+	case searchSortCreated:
+		search.SortCreated(res)
+	}
+}
+
 func (o *searchRepoOptions) run(out io.Writer, args []string) error {
 	o.setupSearchedVersion()
 
-	index, err := o.buildIndex()
+	filter, err := o.filter()
+	if err != nil {
+		return err
+	}
+	index, err := o.buildIndex(filter)
 	if err != nil {
 		return err
 	}
@@ -125,6 +208,7 @@ func (o *searchRepoOptions) run(out io.Writer, args []string) error {
 	if err != nil {
 		return err
 	}
+	o.sort(data)
 
 	return o.outputFormat.Write(out, &repoSearchWriter{data, o.maxColWidth, o.failOnNoResult})
 }
@@ -176,14 +260,14 @@ func (o *searchRepoOptions) applyConstraint(res []*search.Result) ([]*search.Res
 	return data, nil
 }
 
-func (o *searchRepoOptions) buildIndex() (*search.Index, error) {
+func (o *searchRepoOptions) buildIndex(filter search.Filter) (*search.Index, error) {
 	// Load the repositories.yaml
 	rf, err := repo.LoadFile(o.repoFile)
 	if isNotExist(err) || len(rf.Repositories) == 0 {
 		return nil, errors.New("no repositories configured")
 	}
 
-	i := search.NewIndex()
+	i := search.NewFilteredIndex(filter)
 	for _, re := range rf.Repositories {
 		n := re.Name
 		f := filepath.Join(o.repoCacheDir, helmpath.CacheIndexFile(n))
@@ -86,6 +86,30 @@ func TestSearchRepositoriesCmd(t *testing.T) {
 		name:   "search for 'alpine', expect valid yaml output",
 		cmd:    "search repo alpine --output yaml",
 		golden: "output/search-output-yaml.txt",
+	}, {
+		name:   "search the charts named like 'testing/m', expect one match",
+		cmd:    "search repo --name-regexp '^testing/m'",
+		golden: "output/search-name-regexp.txt",
+	}, {
+		name:   "search for 'alpine' with versions created after a date, expect two matches",
+		cmd:    "search repo alpine --versions --devel --created-after 2018-07-01",
+		golden: "output/search-created-after.txt",
+	}, {
+		name:   "search for 'alpine' with versions not deprecated, expect the deprecated version to be excluded",
+		cmd:    "search repo alpine --versions --not-deprecated",
+		golden: "output/search-not-deprecated.txt",
+	}, {
+		name:   "search every chart sorted by creation date",
+		cmd:    "search repo --sort-by created",
+		golden: "output/search-sort-created.txt",
+	}, {
+		name:      "search with an invalid sort order, expect failure",
+		cmd:       "search repo alpine --sort-by size",
+		wantError: true,
+	}, {
+		name:      "search with an invalid date, expect failure",
+		cmd:       "search repo alpine --created-after yesterday",
+		wantError: true,
 	}}
 
 	settings.Debug = true
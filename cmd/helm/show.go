@@ -185,6 +185,8 @@ func newShowCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 		addShowFlags(subCmd, client)
 		showCommand.AddCommand(subCmd)
 	}
+	showCommand.AddCommand(newShowArtifactsCmd(cfg, out))
+	showCommand.AddCommand(newShowImagesCmd(cfg, out))
 
 	return showCommand
 }
@@ -0,0 +1,168 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/gosuri/uitable"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/cli/output"
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+const showArtifactsDesc = `
+This command lists the artifacts attached to a chart in an OCI registry, such
+as software bills of materials (SBOMs), provenance attestations or test reports.
+The artifacts are found with the OCI referrers API of the registry.
+
+	$ helm show artifacts oci://example.com/charts/mychart --version 1.2.3
+
+With --digest, the content of an artifact is fetched and printed, or written to
+the directory given by --destination:
+
+	$ helm show artifacts oci://example.com/charts/mychart --version 1.2.3 \
+		--digest sha256:... --destination ./artifacts
+`
+
+type showArtifactsOptions struct {
+	certFile              string
+	keyFile               string
+	caFile                string
+	insecureSkipTLSverify bool
+	plainHTTP             bool
+	digest                string
+	destination           string
+	outfmt                output.Format
+}
+
+func newShowArtifactsCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
+	client := action.NewShowArtifacts(cfg)
+	o := &showArtifactsOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "artifacts [CHART]",
+		Short: "show the artifacts attached to a chart in a registry",
+		Long:  showArtifactsDesc,
+		Args:  require.ExactArgs(1),
+		ValidArgsFunction: func(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return noMoreArgsComp()
+			}
+			return []string{fmt.Sprintf("%s://", registry.OCIScheme)}, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			registryClient, err := newRegistryClient(o.certFile, o.keyFile, o.caFile, o.insecureSkipTLSverify, o.plainHTTP)
+			if err != nil {
+				return fmt.Errorf("missing registry client: %w", err)
+			}
+			cfg.RegistryClient = registryClient
+
+			if o.digest != "" {
+				_, files, err := client.Fetch(args[0], o.digest)
+				if err != nil {
+					return err
+				}
+				return writeArtifactFiles(out, o.destination, o.digest, files)
+			}
+			artifacts, err := client.Run(args[0])
+			if err != nil {
+				return err
+			}
+			return o.outfmt.Write(out, &artifactListWriter{artifacts})
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&client.Version, "version", "", "specify a version constraint for the chart version to use. If this is not specified, the latest version is used")
+	f.StringVar(&client.ArtifactType, "artifact-type", "", "only list the artifacts of this type, such as application/spdx+json")
+	f.StringVar(&o.digest, "digest", "", "fetch the content of the artifact with this manifest digest")
+	f.StringVarP(&o.destination, "destination", "d", "", "location to write the content of the fetched artifact. It is printed if this is not set")
+	f.StringVar(&o.certFile, "cert-file", "", "identify registry client using this SSL certificate file")
+	f.StringVar(&o.keyFile, "key-file", "", "identify registry client using this SSL key file")
+	f.StringVar(&o.caFile, "ca-file", "", "verify certificates of HTTPS-enabled servers using this CA bundle")
+	f.BoolVar(&o.insecureSkipTLSverify, "insecure-skip-tls-verify", false, "skip tls certificate checks for the registry")
+	f.BoolVar(&o.plainHTTP, "plain-http", false, "use insecure HTTP connections for the registry")
+	bindOutputFlag(cmd, &o.outfmt)
+
+	return cmd
+}
+
+// writeArtifactFiles prints the content of a fetched artifact to out, or
+// writes its files to the destination directory if it is set.
+func writeArtifactFiles(out io.Writer, destination, dgst string, files []registry.ArtifactFile) error {
+	if destination == "" {
+		if len(files) != 1 {
+			return errors.Errorf("artifact %s has %d files, use --destination to write them", dgst, len(files))
+		}
+		_, err := out.Write(files[0].Data)
+		return err
+	}
+
+	if err := os.MkdirAll(destination, 0755); err != nil {
+		return err
+	}
+	for i, file := range files {
+		name := filepath.Base(filepath.Clean("/" + file.Name))
+		if file.Name == "" || name == string(filepath.Separator) {
+			name = fmt.Sprintf("layer-%d", i)
+		}
+		p := filepath.Join(destination, name)
+		if err := os.WriteFile(p, file.Data, 0644); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Wrote: %s\n", p)
+	}
+	return nil
+}
+
+type artifactListWriter struct {
+	artifacts []*registry.Artifact
+}
+
+func (w *artifactListWriter) WriteTable(out io.Writer) error {
+	table := uitable.New()
+	table.AddRow("DIGEST", "ARTIFACT TYPE", "TITLE", "CREATED")
+	for _, a := range w.artifacts {
+		table.AddRow(a.Digest, a.ArtifactType, a.Title(), a.Created())
+	}
+	return output.EncodeTable(out, table)
+}
+
+func (w *artifactListWriter) WriteJSON(out io.Writer) error {
+	return output.EncodeJSON(out, w.list())
+}
+
+func (w *artifactListWriter) WriteYAML(out io.Writer) error {
+	return output.EncodeYAML(out, w.list())
+}
+
+// list returns the artifacts, with an empty list instead of null if there
+// are none.
+func (w *artifactListWriter) list() []*registry.Artifact {
+	if w.artifacts == nil {
+		return []*registry.Artifact{}
+	}
+	return w.artifacts
+}
@@ -0,0 +1,149 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/gosuri/uitable"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/cli/output"
+	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/getter"
+)
+
+const showImagesDesc = `
+This command renders a chart (directory, file, or URL) with the given values
+and lists the container images it uses, such as the images to mirror into a
+registry for an air-gapped installation.
+
+The images are the images of the containers, the init containers and the
+ephemeral containers of every manifest, like the pod templates of Deployments
+and CronJobs, and the strings found in the manifests at JSONPath expressions
+for the images of custom resources. The expressions default to '{.spec.image}'
+and are replaced by the '--image-path' flags:
+
+    $ helm show images ./mychart -f values.yaml --image-path '{.spec.image}' \
+        --image-path '{.spec.thanos.image}'
+
+The digests of the images referenced by digest are listed. With
+'--resolve-digests', the digests of the images referenced by tag are resolved
+from their registries, and left empty if they cannot be resolved.
+`
+
+func newShowImagesCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
+	client := action.NewShowImages(cfg)
+	valueOpts := &values.Options{}
+	var outfmt output.Format
+
+	cmd := &cobra.Command{
+		Use:   "images [CHART]",
+		Short: "show the container images used by a chart",
+		Long:  showImagesDesc,
+		Args:  require.ExactArgs(1),
+		ValidArgsFunction: func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return noMoreArgsComp()
+			}
+			return compListCharts(toComplete, true)
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			registryClient, err := newRegistryClient(client.CertFile, client.KeyFile, client.CaFile,
+				client.InsecureSkipTLSverify, client.PlainHTTP)
+			if err != nil {
+				return fmt.Errorf("missing registry client: %w", err)
+			}
+			client.SetRegistryClient(registryClient)
+
+			if client.Version == "" && client.Devel {
+				debug("setting version to >0.0.0-0")
+				client.Version = ">0.0.0-0"
+			}
+			cp, err := client.ChartPathOptions.LocateChart(args[0], settings)
+			if err != nil {
+				return err
+			}
+			vals, err := valueOpts.MergeValues(getter.All(settings))
+			if err != nil {
+				return err
+			}
+			chrt, err := loader.Load(cp)
+			if err != nil {
+				return err
+			}
+			if req := chrt.Metadata.Dependencies; req != nil {
+				if err := action.CheckDependencies(chrt, req); err != nil {
+					return errors.Wrap(err, "An error occurred while checking for chart dependencies. You may need to run `helm dependency build` to fetch missing dependencies")
+				}
+			}
+
+			images, err := client.Run(chrt, vals)
+			if err != nil {
+				return err
+			}
+			return outfmt.Write(out, &chartImagesWriter{images})
+		},
+	}
+
+	f := cmd.Flags()
+	f.BoolVar(&client.Devel, "devel", false, "use development versions, too. Equivalent to version '>0.0.0-0'. If --version is set, this is ignored")
+	f.StringArrayVar(&client.ImagePaths, "image-path", nil, "JSONPath expression of the images of custom resources, replacing the default '{.spec.image}' (can specify multiple)")
+	f.BoolVar(&client.ResolveDigests, "resolve-digests", false, "resolve the digests of the images referenced by tag from their registries")
+	addValueOptionsFlags(f, valueOpts)
+	addChartPathOptionsFlags(f, &client.ChartPathOptions)
+	bindOutputFlag(cmd, &outfmt)
+
+	err := cmd.RegisterFlagCompletionFunc("version", func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) != 1 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return compVersionFlag(args[0], toComplete)
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return cmd
+}
+
+type chartImagesWriter struct {
+	images []action.ChartImage
+}
+
+func (w *chartImagesWriter) WriteTable(out io.Writer) error {
+	table := uitable.New()
+	table.AddRow("IMAGE", "DIGEST")
+	for _, image := range w.images {
+		table.AddRow(image.Image, image.Digest)
+	}
+	return output.EncodeTable(out, table)
+}
+
+func (w *chartImagesWriter) WriteJSON(out io.Writer) error {
+	return output.EncodeJSON(out, w.images)
+}
+
+func (w *chartImagesWriter) WriteYAML(out io.Writer) error {
+	return output.EncodeYAML(out, w.images)
+}
@@ -153,3 +153,33 @@ func TestShowValuesFileCompletion(t *testing.T) {
 func TestShowCRDsFileCompletion(t *testing.T) {
 	checkFileCompletion(t, "show crds", true)
 }
+
+func TestShowArtifactsFileCompletion(t *testing.T) {
+	checkFileCompletion(t, "show artifacts", false)
+}
+
+func TestShowArtifactsCmd(t *testing.T) {
+	tests := []cmdTestCase{{
+		name:      "show artifacts of a local chart",
+		cmd:       "show artifacts testdata/testcharts/alpine",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
+
+func TestShowImagesCmd(t *testing.T) {
+	tests := []cmdTestCase{{
+		name:   "show the images of a chart",
+		cmd:    "show images testdata/testcharts/alpine",
+		golden: "output/show-images.txt",
+	}, {
+		name:   "show the images of a chart in JSON",
+		cmd:    "show images testdata/testcharts/alpine --output json",
+		golden: "output/show-images-json.txt",
+	}, {
+		name:      "show the images of a chart with an invalid image path",
+		cmd:       "show images testdata/testcharts/alpine --image-path '{.spec'",
+		wantError: true,
+	}}
+	runTestCmd(t, tests)
+}
@@ -18,9 +18,11 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"sort"
 	"strings"
 	"time"
 
@@ -32,7 +34,9 @@ import (
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/cli/output"
+	"helm.sh/helm/v3/pkg/kube"
 	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
 )
 
 // NOTE: Keep the list of statuses up-to-date with pkg/release/status.go.
@@ -45,13 +49,16 @@ The status consists of:
 - revision of the release
 - description of the release (can be completion message or error message, need to enable --show-desc)
 - list of resources that this release consists of (need to enable --show-resources)
+- health of the resources in the cluster, in the JSON and YAML output (need to enable --show-resources)
 - details on last test suite run, if applicable
 - additional notes provided by the chart
+- resources that drifted from the release manifest (need to enable --detect-drift)
 `
 
 func newStatusCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	client := action.NewStatus(cfg)
 	var outfmt output.Format
+	var detectDrift bool
 
 	cmd := &cobra.Command{
 		Use:   "status RELEASE_NAME",
@@ -71,6 +78,10 @@ func newStatusCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			// returned. This mirrors the handling in kubectl.
 			if outfmt == output.Table {
 				client.ShowResourcesTable = true
+			} else {
+				// The structured output gives the health of the resources
+				// along with them.
+				client.ShowHealth = client.ShowResources
 			}
 			rel, err := client.Run(args[0])
 			if err != nil {
@@ -80,7 +91,18 @@ func newStatusCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			// strip chart metadata from the output
 			rel.Chart = nil
 
-			return outfmt.Write(out, &statusPrinter{rel, false, client.ShowDescription, client.ShowResources, false, false})
+			printer := statusPrinter{rel, false, client.ShowDescription, client.ShowResources, false, false}
+			if !detectDrift {
+				return outfmt.Write(out, &printer)
+			}
+
+			drift := action.NewDrift(cfg)
+			drift.Version = client.Version
+			report, err := drift.Run(args[0])
+			if err != nil {
+				return err
+			}
+			return outfmt.Write(out, &driftStatusPrinter{printer, report})
 		},
 	}
 
@@ -104,6 +126,8 @@ func newStatusCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 
 	f.BoolVar(&client.ShowResources, "show-resources", false, "if set, display the resources of the named release")
 
+	f.BoolVar(&detectDrift, "detect-drift", false, "if set, compare the live resources against the release manifest and display the resources that drifted")
+
 	return cmd
 }
 
@@ -116,12 +140,33 @@ type statusPrinter struct {
 	hideNotes       bool
 }
 
+// releaseWithLabels is a release encoded with its labels, which are stored
+// apart from the release by the storage drivers.
+type releaseWithLabels struct {
+	*release.Release
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+func newReleaseWithLabels(rel *release.Release) releaseWithLabels {
+	r := releaseWithLabels{Release: rel}
+	if rel != nil {
+		r.Labels = driver.FilterSystemLabels(rel.Labels)
+	}
+	return r
+}
+
 func (s statusPrinter) WriteJSON(out io.Writer) error {
-	return output.EncodeJSON(out, s.release)
+	if s.release == nil {
+		return output.EncodeJSON(out, s.release)
+	}
+	return output.EncodeJSON(out, newReleaseWithLabels(s.release))
 }
 
 func (s statusPrinter) WriteYAML(out io.Writer) error {
-	return output.EncodeYAML(out, s.release)
+	if s.release == nil {
+		return output.EncodeYAML(out, s.release)
+	}
+	return output.EncodeYAML(out, newReleaseWithLabels(s.release))
 }
 
 func (s statusPrinter) WriteTable(out io.Writer) error {
@@ -135,6 +180,12 @@ func (s statusPrinter) WriteTable(out io.Writer) error {
 	_, _ = fmt.Fprintf(out, "NAMESPACE: %s\n", s.release.Namespace)
 	_, _ = fmt.Fprintf(out, "STATUS: %s\n", s.release.Info.Status.String())
 	_, _ = fmt.Fprintf(out, "REVISION: %d\n", s.release.Version)
+	if labels := driver.FilterSystemLabels(s.release.Labels); len(labels) > 0 {
+		_, _ = fmt.Fprintf(out, "LABELS: %s\n", formatStringMap(labels))
+	}
+	if len(s.release.Annotations) > 0 {
+		_, _ = fmt.Fprintf(out, "ANNOTATIONS: %s\n", formatStringMap(s.release.Annotations))
+	}
 	if s.showMetadata {
 		_, _ = fmt.Fprintf(out, "CHART: %s\n", s.release.Chart.Metadata.Name)
 		_, _ = fmt.Fprintf(out, "VERSION: %s\n", s.release.Chart.Metadata.Version)
@@ -227,6 +278,77 @@ func (s statusPrinter) WriteTable(out io.Writer) error {
 	return nil
 }
 
+// driftStatusPrinter prints the status of a release followed by its drift
+// report.
+type driftStatusPrinter struct {
+	statusPrinter
+	drift *action.DriftReport
+}
+
+type releaseWithDrift struct {
+	releaseWithLabels
+	Drift *action.DriftReport `json:"drift"`
+}
+
+func (s driftStatusPrinter) WriteJSON(out io.Writer) error {
+	return output.EncodeJSON(out, releaseWithDrift{newReleaseWithLabels(s.release), s.drift})
+}
+
+func (s driftStatusPrinter) WriteYAML(out io.Writer) error {
+	return output.EncodeYAML(out, releaseWithDrift{newReleaseWithLabels(s.release), s.drift})
+}
+
+func (s driftStatusPrinter) WriteTable(out io.Writer) error {
+	if err := s.statusPrinter.WriteTable(out); err != nil {
+		return err
+	}
+	if !s.drift.Drifted() {
+		_, _ = fmt.Fprintln(out, "DRIFT: None")
+		return nil
+	}
+	_, _ = fmt.Fprintf(out, "DRIFT: %d resource(s) differ from revision %d\n", len(s.drift.Resources), s.drift.Revision)
+	for _, r := range s.drift.Resources {
+		name := r.Name
+		if r.Namespace != "" {
+			name = r.Namespace + "/" + r.Name
+		}
+		if r.Action == kube.DiffActionCreate {
+			_, _ = fmt.Fprintf(out, "==> %s %s (missing)\n", r.Kind, name)
+			continue
+		}
+		_, _ = fmt.Fprintf(out, "==> %s %s (modified)\n", r.Kind, name)
+		for _, f := range r.Fields {
+			_, _ = fmt.Fprintf(out, "    %s: %s (live) != %s (release)\n", f.Path, driftValue(f.Live), driftValue(f.Desired))
+		}
+	}
+	return nil
+}
+
+func driftValue(v interface{}) string {
+	if v == nil {
+		return "<unset>"
+	}
+	if v == kube.RedactedValue {
+		return kube.RedactedValue
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// formatStringMap returns the keys and values of the map like "k1=v1,k2=v2",
+// sorted by key.
+func formatStringMap(m map[string]string) string {
+	pairs := make([]string, 0, len(m))
+	for k, v := range m {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
 func executionsByHookEvent(rel *release.Release) map[release.HookEvent][]*release.Hook {
 	result := make(map[release.HookEvent][]*release.Hook)
 	for _, h := range rel.Hooks {
@@ -17,10 +17,14 @@ limitations under the License.
 package main
 
 import (
+	"bytes"
+	"strings"
 	"testing"
 	"time"
 
+	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/kube"
 	"helm.sh/helm/v3/pkg/release"
 	helmtime "helm.sh/helm/v3/pkg/time"
 )
@@ -68,6 +72,13 @@ func TestStatusCmd(t *testing.T) {
 			Status: release.StatusDeployed,
 			Notes:  "release notes",
 		}),
+	}, {
+		name:   "get status of a deployed release with drift detection",
+		cmd:    "status --detect-drift flummoxed-chickadee",
+		golden: "output/status-with-drift.txt",
+		rels: releasesMockWithStatus(&release.Info{
+			Status: release.StatusDeployed,
+		}),
 	}, {
 		name:   "get status of a deployed release with resources",
 		cmd:    "status --show-resources flummoxed-chickadee",
@@ -130,6 +141,21 @@ func TestStatusCmd(t *testing.T) {
 	runTestCmd(t, tests)
 }
 
+func TestStatusCmdLabelsAndAnnotations(t *testing.T) {
+	rel := release.Mock(&release.MockReleaseOptions{Name: "flummoxed-chickadee"})
+	rel.Info.LastDeployed = helmtime.Unix(1452902400, 0).UTC()
+	rel.Labels = map[string]string{"team": "birds", "owner": "helm"}
+	rel.Annotations = map[string]string{"example.com/ticket": "BIRD-1"}
+
+	tests := []cmdTestCase{{
+		name:   "get status of a release with labels and annotations",
+		cmd:    "status flummoxed-chickadee",
+		golden: "output/status-labels-annotations.txt",
+		rels:   []*release.Release{rel},
+	}}
+	runTestCmd(t, tests)
+}
+
 func mustParseTime(t string) helmtime.Time {
 	res, _ := helmtime.Parse(time.RFC3339, t)
 	return res
@@ -218,3 +244,38 @@ func TestStatusFileCompletion(t *testing.T) {
 	checkFileCompletion(t, "status", false)
 	checkFileCompletion(t, "status myrelease", false)
 }
+
+func TestDriftStatusPrinter(t *testing.T) {
+	rel := &release.Release{Name: "flummoxed-chickadee", Namespace: "default", Version: 2, Info: &release.Info{Status: release.StatusDeployed}}
+	report := &action.DriftReport{
+		Release:  rel.Name,
+		Revision: rel.Version,
+		Resources: []kube.ResourceDiff{
+			{Kind: "ConfigMap", Namespace: "default", Name: "settings", Action: kube.DiffActionCreate},
+			{
+				Kind:      "Deployment",
+				Namespace: "default",
+				Name:      "web",
+				Action:    kube.DiffActionUpdate,
+				Fields: []kube.FieldDiff{
+					{Path: "spec.replicas", Live: int64(5), Desired: int64(3)},
+					{Path: `metadata.labels["team"]`, Live: "ops"},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := (driftStatusPrinter{statusPrinter{release: rel}, report}).WriteTable(&buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := `DRIFT: 2 resource(s) differ from revision 2
+==> ConfigMap default/settings (missing)
+==> Deployment default/web (modified)
+    spec.replicas: 5 (live) != 3 (release)
+    metadata.labels["team"]: "ops" (live) != <unset> (release)
+`
+	if got := buf.String(); !strings.HasSuffix(got, expected) {
+		t.Errorf("expected output to end with\n%s\ngot\n%s", expected, got)
+	}
+}
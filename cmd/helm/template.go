@@ -20,18 +20,23 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 
 	"helm.sh/helm/v3/pkg/release"
 
 	"github.com/spf13/cobra"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/yaml"
 
 	"helm.sh/helm/v3/cmd/helm/require"
+	"helm.sh/helm/v3/internal/deprecations"
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/cli/values"
@@ -44,12 +49,33 @@ Render chart templates locally and display the output.
 Any values that would normally be looked up or retrieved in-cluster will be
 faked locally. Additionally, none of the server-side testing of chart validity
 (e.g. whether an API is supported) is done.
+
+With --kube-version, the resources using Kubernetes APIs deprecated or removed
+in this version are reported as warnings, with the API version to use instead.
+
+With --validate-offline, the rendered resources are validated without cluster
+access, which needs no kubeconfig, against the Kubernetes API types compiled
+into Helm: the unknown fields and the values of the wrong type are reported,
+and so are the resources using APIs no longer served by the Kubernetes version
+given with --kube-version. The fields are always validated against the API
+types of the Kubernetes version Helm was built with, whatever --kube-version
+is. The kinds unknown to Helm, such as custom resources, are not validated. The
+manifests are printed before the violations are reported.
+
+    $ helm template ./mychart --validate-offline --kube-version 1.29
+
+With --output-dir and --output-kustomization, the files of several resources
+are split into a file per resource, and a kustomization.yaml listing all the
+files of output-dir is written, so that output-dir can be applied with
+'kubectl apply -k'.
 `
 
 func newTemplateCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	var validate bool
+	var validateOffline bool
 	var includeCrds bool
 	var skipTests bool
+	var outputKustomization bool
 	client := action.NewInstall(cfg)
 	valueOpts := &values.Options{}
 	var kubeVersion string
@@ -65,6 +91,9 @@ func newTemplateCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			return compInstall(args, toComplete, client)
 		},
 		RunE: func(_ *cobra.Command, args []string) error {
+			if outputKustomization && client.OutputDir == "" {
+				return fmt.Errorf("--output-kustomization requires --output-dir")
+			}
 			if kubeVersion != "" {
 				parsedKubeVersion, err := chartutil.ParseKubeVersion(kubeVersion)
 				if err != nil {
@@ -104,6 +133,12 @@ func newTemplateCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			// We ignore a potential error here because, when the --debug flag was specified,
 			// we always want to print the YAML, even if it is not valid. The error is still returned afterwards.
 			if rel != nil {
+				if client.KubeVersion != nil {
+					for _, w := range deprecatedAPIWarnings(rel, client.KubeVersion) {
+						warning("%s", w)
+					}
+				}
+
 				var manifests bytes.Buffer
 				fmt.Fprintln(&manifests, strings.TrimSpace(rel.Manifest))
 				if !client.DisableHooks {
@@ -133,6 +168,12 @@ func newTemplateCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 					}
 				}
 
+				if outputKustomization {
+					if err := writeKustomization(client.OutputDir, out); err != nil {
+						return err
+					}
+				}
+
 				// if we have a list of files to render, then check that each of the
 				// provided files exists in the chart.
 				if len(showFiles) > 0 {
@@ -183,6 +224,14 @@ func newTemplateCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 				} else {
 					fmt.Fprintf(out, "%s", manifests.String())
 				}
+
+				if validateOffline && err == nil {
+					kubeVersion := &chartutil.DefaultCapabilities.KubeVersion
+					if client.KubeVersion != nil {
+						kubeVersion = client.KubeVersion
+					}
+					err = action.ValidateOffline(rel, kubeVersion)
+				}
 			}
 
 			return err
@@ -193,7 +242,9 @@ func newTemplateCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	addInstallFlags(cmd, f, client, valueOpts)
 	f.StringArrayVarP(&showFiles, "show-only", "s", []string{}, "only show manifests rendered from the given templates")
 	f.StringVar(&client.OutputDir, "output-dir", "", "writes the executed templates to files in output-dir instead of stdout")
+	f.BoolVar(&outputKustomization, "output-kustomization", false, "split the files of output-dir into a file per resource, and write a kustomization.yaml listing them")
 	f.BoolVar(&validate, "validate", false, "validate your manifests against the Kubernetes cluster you are currently pointing at. This is the same validation performed on an install")
+	f.BoolVar(&validateOffline, "validate-offline", false, "validate your manifests without cluster access, against the Kubernetes API types compiled into Helm (not those of --kube-version) and the APIs served by --kube-version")
 	f.BoolVar(&includeCrds, "include-crds", false, "include CRDs in the templated output")
 	f.BoolVar(&skipTests, "skip-tests", false, "skip tests from templated output")
 	f.BoolVar(&client.IsUpgrade, "is-upgrade", false, "set .Release.IsUpgrade instead of .Release.IsInstall")
@@ -201,10 +252,140 @@ func newTemplateCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.StringSliceVarP(&extraAPIs, "api-versions", "a", []string{}, "Kubernetes api versions used for Capabilities.APIVersions")
 	f.BoolVar(&client.UseReleaseName, "release-name", false, "use release name in the output-dir path.")
 	bindPostRenderFlag(cmd, &client.PostRenderer)
+	bindRenderHookFlag(cmd, &client.RenderHooks)
+	cmd.MarkFlagsMutuallyExclusive("validate", "validate-offline")
 
 	return cmd
 }
 
+// deprecatedAPIWarnings returns the warnings about the resources of the
+// release using APIs deprecated or removed in the Kubernetes version.
+func deprecatedAPIWarnings(rel *release.Release, kubeVersion *chartutil.KubeVersion) []string {
+	major, _ := strconv.Atoi(kubeVersion.Major)
+	minor, _ := strconv.Atoi(kubeVersion.Minor)
+
+	var warnings []string
+	for _, m := range releaseutil.ReleaseManifests(rel) {
+		var head releaseutil.SimpleHead
+		if err := yaml.Unmarshal([]byte(m.Content), &head); err != nil {
+			continue
+		}
+		if d := deprecations.Check(head.Version, head.Kind, major, minor); d != nil {
+			warnings = append(warnings, fmt.Sprintf("%s: %s", m.Name, d))
+		}
+	}
+	return warnings
+}
+
+// writeKustomization splits the files of several resources in dir into a
+// file per resource, and writes a kustomization.yaml listing all the files.
+func writeKustomization(dir string, out io.Writer) error {
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if ext := filepath.Ext(p); (ext != ".yaml" && ext != ".yml") || p == filepath.Join(dir, "kustomization.yaml") {
+			return nil
+		}
+		split, err := splitResources(p, out)
+		if err != nil {
+			return err
+		}
+		for _, f := range split {
+			rel, err := filepath.Rel(dir, f)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	kustomization := types.Kustomization{
+		TypeMeta: types.TypeMeta{
+			APIVersion: types.KustomizationVersion,
+			Kind:       types.KustomizationKind,
+		},
+		Resources: files,
+	}
+	data, err := yaml.Marshal(kustomization)
+	if err != nil {
+		return err
+	}
+	file := filepath.Join(dir, "kustomization.yaml")
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "wrote %s\n", file)
+	return nil
+}
+
+// splitResources splits the file, if it has several resources, into a file
+// per resource named after the file and the resource, and returns the files
+// of the resources.
+func splitResources(file string, out io.Writer) ([]string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	split := releaseutil.SplitManifests(string(data))
+	keys := make([]string, 0, len(split))
+	for k := range split {
+		keys = append(keys, k)
+	}
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+
+	var source string
+	if submatch := regexp.MustCompile("# Source: (.+)").FindStringSubmatch(string(data)); submatch != nil {
+		source = submatch[1]
+	}
+	type resource struct {
+		name    string
+		content string
+	}
+	var resources []resource
+	for _, k := range keys {
+		var head releaseutil.SimpleHead
+		if err := yaml.Unmarshal([]byte(split[k]), &head); err != nil || head.Kind == "" {
+			continue
+		}
+		name := strings.ToLower(head.Kind)
+		if head.Metadata != nil && head.Metadata.Name != "" {
+			name += "-" + head.Metadata.Name
+		}
+		resources = append(resources, resource{name, split[k]})
+	}
+	if len(resources) < 2 {
+		return []string{file}, nil
+	}
+
+	stem := strings.TrimSuffix(file, filepath.Ext(file))
+	written := make(map[string]bool)
+	var files []string
+	for _, r := range resources {
+		name := stem + "-" + r.name + ".yaml"
+		for i := 2; written[name]; i++ {
+			name = fmt.Sprintf("%s-%s-%d.yaml", stem, r.name, i)
+		}
+		written[name] = true
+		content := r.content
+		if source != "" && !strings.Contains(content, "# Source: ") {
+			content = "# Source: " + source + "\n" + content
+		}
+		if err := os.WriteFile(name, []byte("---\n"+content+"\n"), 0644); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(out, "wrote %s\n", name)
+		files = append(files, name)
+	}
+	return files, os.Remove(file)
+}
+
 func isTestHook(h *release.Hook) bool {
 	for _, e := range h.Events {
 		if e == release.HookTest {
@@ -18,8 +18,14 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/release"
 )
 
 var chartPath = "testdata/testcharts/subchart"
@@ -161,10 +167,126 @@ func TestTemplateCmd(t *testing.T) {
 			cmd:    fmt.Sprintf("template '%s' -f %s/extra_values.yaml", chartPath, chartPath),
 			golden: "output/template-subchart-cm-set-file.txt",
 		},
+		{
+			name:   "template with offline validation",
+			cmd:    fmt.Sprintf("template '%s' --validate-offline", chartPath),
+			golden: "output/template.txt",
+		},
+		{
+			name:   "template with offline validation of an API served by the kube version",
+			cmd:    "template testdata/testcharts/chart-with-deprecated-api --validate-offline --kube-version 1.24",
+			golden: "output/template-validate-offline.txt",
+		},
+		{
+			name:      "template with offline validation of an API removed from the kube version",
+			cmd:       "template testdata/testcharts/chart-with-deprecated-api --validate-offline --kube-version 1.29",
+			golden:    "output/template-validate-offline-removed.txt",
+			wantError: true,
+		},
+		{
+			name:      "template with online and offline validation",
+			cmd:       fmt.Sprintf("template '%s' --validate --validate-offline", chartPath),
+			golden:    "output/template-validate-offline-exclusive.txt",
+			wantError: true,
+		},
 	}
 	runTestCmd(t, tests)
 }
 
+func TestTemplateOutputKustomization(t *testing.T) {
+	dir := t.TempDir()
+	_, _, err := executeActionCommand(fmt.Sprintf("template testdata/testcharts/object-order --output-dir '%s' --output-kustomization", dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "kustomization.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- object-order/templates/01-a-deployment-fourth.yaml
+- object-order/templates/01-a-networkpolicy-first.yaml
+- object-order/templates/01-a-networkpolicy-second.yaml
+- object-order/templates/01-a-networkpolicy-third.yaml
+`
+	if !strings.HasPrefix(string(data), expect) {
+		t.Errorf("expected the kustomization to start with:\n%s\ngot:\n%s", expect, data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "object-order", "templates", "01-a.yml")); !os.IsNotExist(err) {
+		t.Errorf("expected the file of several resources to be split, got %v", err)
+	}
+	data, err = os.ReadFile(filepath.Join(dir, "object-order", "templates", "01-a-networkpolicy-second.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "---\n# Source: object-order/templates/01-a.yml\n") || strings.Count(string(data), "kind: NetworkPolicy") != 1 {
+		t.Errorf("unexpected resource file:\n%s", data)
+	}
+
+	if _, _, err := executeActionCommand("template testdata/testcharts/object-order --output-kustomization"); err == nil {
+		t.Error("expected --output-kustomization without --output-dir to fail")
+	}
+}
+
+func TestDeprecatedAPIWarnings(t *testing.T) {
+	rel := &release.Release{
+		Manifest: `---
+# Source: mychart/templates/hpa.yaml
+apiVersion: autoscaling/v2beta1
+kind: HorizontalPodAutoscaler
+metadata:
+  name: hpa
+---
+# Source: mychart/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: deployment
+---
+# Source: mychart/templates/cronjob.yaml
+apiVersion: batch/v1beta1
+kind: CronJob
+metadata:
+  name: cronjob
+`,
+		Hooks: []*release.Hook{{
+			Path:     "templates/psp.yaml",
+			Manifest: "apiVersion: policy/v1beta1\nkind: PodSecurityPolicy\nmetadata:\n  name: psp\n",
+		}},
+	}
+
+	for _, tt := range []struct {
+		kubeVersion string
+		expect      []string
+	}{{
+		kubeVersion: "1.20.0",
+	}, {
+		kubeVersion: "1.22.0",
+		expect: []string{
+			"templates/hpa.yaml: autoscaling/v2beta1 HorizontalPodAutoscaler is deprecated in v1.22+, unavailable in v1.25+; use autoscaling/v2 HorizontalPodAutoscaler",
+			"templates/cronjob.yaml: batch/v1beta1 CronJob is deprecated in v1.21+, unavailable in v1.25+; use batch/v1 CronJob",
+			"templates/psp.yaml: policy/v1beta1 PodSecurityPolicy is deprecated in v1.21+, unavailable in v1.25+",
+		},
+	}, {
+		kubeVersion: "1.25.0",
+		expect: []string{
+			"templates/hpa.yaml: autoscaling/v2beta1 HorizontalPodAutoscaler is unavailable in v1.25+; use autoscaling/v2 HorizontalPodAutoscaler",
+			"templates/cronjob.yaml: batch/v1beta1 CronJob is unavailable in v1.25+; use batch/v1 CronJob",
+			"templates/psp.yaml: policy/v1beta1 PodSecurityPolicy is unavailable in v1.25+",
+		},
+	}} {
+		kubeVersion, err := chartutil.ParseKubeVersion(tt.kubeVersion)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := deprecatedAPIWarnings(rel, kubeVersion); !reflect.DeepEqual(got, tt.expect) {
+			t.Errorf("expected the warnings of v%s to be %q, got %q", tt.kubeVersion, tt.expect, got)
+		}
+	}
+}
+
 func TestTemplateVersionCompletion(t *testing.T) {
 	repoFile := "testdata/helmhome/helm/repositories.yaml"
 	repoCache := "testdata/helmhome/helm/repository"
@@ -25,6 +25,7 @@ import (
 
 	"helm.sh/helm/v3/cmd/helm/require"
 	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/cli/output"
 )
 
 const uninstallDesc = `
@@ -35,10 +36,14 @@ as well as the release history, freeing it up for future use.
 
 Use the '--dry-run' flag to see which releases will be uninstalled without actually
 uninstalling them.
+
+With '--output json' or '--output yaml', the names of the uninstalled releases
+are printed with the messages of their uninstallation.
 `
 
 func newUninstallCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	client := action.NewUninstall(cfg)
+	var outfmt output.Format
 
 	cmd := &cobra.Command{
 		Use:        "uninstall RELEASE_NAME [...]",
@@ -55,19 +60,31 @@ func newUninstallCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			if validationErr != nil {
 				return validationErr
 			}
+			w := &uninstallWriter{results: []uninstallResult{}}
 			for i := 0; i < len(args); i++ {
 
 				res, err := client.Run(args[i])
 				if err != nil {
 					return err
 				}
+				if outfmt != output.Table {
+					r := uninstallResult{Name: args[i]}
+					if res != nil {
+						r.Info = res.Info
+					}
+					w.results = append(w.results, r)
+					continue
+				}
 				if res != nil && res.Info != "" {
 					fmt.Fprintln(out, res.Info)
 				}
 
 				fmt.Fprintf(out, "release \"%s\" uninstalled\n", args[i])
 			}
-			return nil
+			if outfmt == output.Table {
+				return nil
+			}
+			return outfmt.Write(out, w)
 		},
 	}
 
@@ -80,6 +97,7 @@ func newUninstallCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.StringVar(&client.DeletionPropagation, "cascade", "background", "Must be \"background\", \"orphan\", or \"foreground\". Selects the deletion cascading strategy for the dependents. Defaults to background.")
 	f.DurationVar(&client.Timeout, "timeout", 300*time.Second, "time to wait for any individual Kubernetes operation (like Jobs for hooks)")
 	f.StringVar(&client.Description, "description", "", "add a custom description")
+	bindOutputFlag(cmd, &outfmt)
 
 	return cmd
 }
@@ -90,3 +108,31 @@ func validateCascadeFlag(client *action.Uninstall) error {
 	}
 	return nil
 }
+
+// uninstallResult is an uninstalled release.
+type uninstallResult struct {
+	Name string `json:"name"`
+	Info string `json:"info,omitempty"`
+}
+
+type uninstallWriter struct {
+	results []uninstallResult
+}
+
+func (w *uninstallWriter) WriteTable(out io.Writer) error {
+	for _, r := range w.results {
+		if r.Info != "" {
+			fmt.Fprintln(out, r.Info)
+		}
+		fmt.Fprintf(out, "release \"%s\" uninstalled\n", r.Name)
+	}
+	return nil
+}
+
+func (w *uninstallWriter) WriteJSON(out io.Writer) error {
+	return output.EncodeJSON(out, w.results)
+}
+
+func (w *uninstallWriter) WriteYAML(out io.Writer) error {
+	return output.EncodeYAML(out, w.results)
+}
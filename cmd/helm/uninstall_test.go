@@ -63,6 +63,15 @@ func TestUninstall(t *testing.T) {
 			golden: "output/uninstall-wait.txt",
 			rels:   []*release.Release{release.Mock(&release.MockReleaseOptions{Name: "aeneas"})},
 		},
+		{
+			name:   "uninstall with json output",
+			cmd:    "uninstall aeneas aeneas2 -o json",
+			golden: "output/uninstall-json.txt",
+			rels: []*release.Release{
+				release.Mock(&release.MockReleaseOptions{Name: "aeneas"}),
+				release.Mock(&release.MockReleaseOptions{Name: "aeneas2"}),
+			},
+		},
 		{
 			name:      "uninstall without release",
 			cmd:       "uninstall",
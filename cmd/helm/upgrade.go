@@ -17,12 +17,14 @@ limitations under the License.
 package main
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -36,6 +38,7 @@ import (
 	"helm.sh/helm/v3/pkg/cli/values"
 	"helm.sh/helm/v3/pkg/downloader"
 	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/kube"
 	"helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/storage/driver"
 )
@@ -77,6 +80,16 @@ or '--set' flags. Priority is given to new values.
 The --dry-run flag will output all generated chart manifests, including Secrets
 which can contain sensitive values. To hide Kubernetes Secrets use the
 --hide-secret flag. Please carefully consider how and when these flags are used.
+
+The --confirm flag shows the changes the upgrade would make to the resources of
+the release in the cluster, and asks for a confirmation before applying them.
+The upgrade is cancelled unless 'yes' is answered. With --auto-approve as well,
+the changes are shown without asking, for automation. The values of the
+Secrets are redacted from the changes shown. With --install, the upgrade fails
+if the release does not exist, as the changes of an install cannot be
+confirmed.
+
+    $ helm upgrade --confirm redis ./redis
 `
 
 func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
@@ -84,6 +97,7 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	valueOpts := &values.Options{}
 	var outfmt output.Format
 	var createNamespace bool
+	var confirm, autoApprove bool
 
 	cmd := &cobra.Command{
 		Use:   "upgrade [RELEASE] [CHART]",
@@ -99,7 +113,10 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			}
 			return noMoreArgsComp()
 		},
-		RunE: func(_ *cobra.Command, args []string) error {
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if autoApprove && !confirm {
+				return fmt.Errorf("--auto-approve requires --confirm")
+			}
 			client.Namespace = settings.Namespace()
 
 			registryClient, err := newRegistryClient(client.CertFile, client.KeyFile, client.CaFile,
@@ -123,6 +140,10 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 				histClient.Max = 1
 				versions, err := histClient.Run(args[0])
 				if err == driver.ErrReleaseNotFound || isReleaseUninstalled(versions) {
+					// The changes of an install cannot be confirmed
+					if confirm {
+						return fmt.Errorf("release %q does not exist: --confirm cannot be used to install it", args[0])
+					}
 					// Only print this to stdout for table output
 					if outfmt == output.Table {
 						fmt.Fprintf(out, "Release %q does not exist. Installing it now.\n", args[0])
@@ -136,19 +157,25 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 					instClient.DisableHooks = client.DisableHooks
 					instClient.SkipCRDs = client.SkipCRDs
 					instClient.Timeout = client.Timeout
+					instClient.KindTimeouts = client.KindTimeouts
+					instClient.BatchSize = client.BatchSize
+					instClient.BatchRetries = client.BatchRetries
 					instClient.Wait = client.Wait
 					instClient.WaitForJobs = client.WaitForJobs
 					instClient.Devel = client.Devel
 					instClient.Namespace = client.Namespace
 					instClient.Atomic = client.Atomic
 					instClient.PostRenderer = client.PostRenderer
+					instClient.RenderHooks = client.RenderHooks
 					instClient.DisableOpenAPIValidation = client.DisableOpenAPIValidation
+					instClient.ValidateManifests = client.ValidateManifests
 					instClient.SubNotes = client.SubNotes
 					instClient.HideNotes = client.HideNotes
 					instClient.SkipSchemaValidation = client.SkipSchemaValidation
 					instClient.Description = client.Description
 					instClient.DependencyUpdate = client.DependencyUpdate
 					instClient.Labels = client.Labels
+					instClient.Annotations = client.Annotations
 					instClient.EnableDNS = client.EnableDNS
 					instClient.HideSecret = client.HideSecret
 
@@ -181,10 +208,11 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 			}
 
 			p := getter.All(settings)
-			vals, err := valueOpts.MergeValues(p)
+			vals, sources, err := valueOpts.MergeValuesWithSources(p)
 			if err != nil {
 				return err
 			}
+			client.ValueSources = sources
 
 			// Check chart dependencies to make sure all are present in /charts
 			ch, err := loader.Load(chartPath)
@@ -205,6 +233,12 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 							RepositoryCache:  settings.RepositoryCache,
 							Debug:            settings.Debug,
 						}
+						if client.Verify {
+							man.Verify = downloader.VerifyAlways
+							if man.SignaturePolicy, err = action.LoadSignaturePolicy(settings); err != nil {
+								return err
+							}
+						}
 						if err := man.Update(); err != nil {
 							return err
 						}
@@ -237,6 +271,15 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 				cancel()
 			}()
 
+			if confirm {
+				// Keep the structured output parsable
+				planOut := out
+				if outfmt != output.Table {
+					planOut = cmd.ErrOrStderr()
+				}
+				client.Confirm = confirmUpgrade(cmd.InOrStdin(), planOut, autoApprove)
+			}
+
 			rel, err := client.RunWithContext(ctx, args[0], ch, vals)
 
 			if err != nil {
@@ -263,6 +306,7 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.BoolVar(&client.Force, "force", false, "force resource updates through a replacement strategy")
 	f.BoolVar(&client.DisableHooks, "no-hooks", false, "disable pre/post upgrade hooks")
 	f.BoolVar(&client.DisableOpenAPIValidation, "disable-openapi-validation", false, "if set, the upgrade process will not validate rendered templates against the Kubernetes OpenAPI Schema")
+	f.BoolVar(&client.ValidateManifests, "validate-manifests", false, "if set, validate all rendered resources and hooks against the cluster OpenAPI schema (or the Kubernetes API types compiled into Helm if it is unavailable) before applying anything, listing every error")
 	f.BoolVar(&client.SkipCRDs, "skip-crds", false, "if set, no CRDs will be installed when an upgrade is performed with install flag enabled. By default, CRDs are installed if not already present, when an upgrade is performed with install flag enabled")
 	f.DurationVar(&client.Timeout, "timeout", 300*time.Second, "time to wait for any individual Kubernetes operation (like Jobs for hooks)")
 	f.BoolVar(&client.ResetValues, "reset-values", false, "when upgrading, reset the values to the ones built into the chart")
@@ -270,6 +314,9 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.BoolVar(&client.ResetThenReuseValues, "reset-then-reuse-values", false, "when upgrading, reset the values to the ones built into the chart, apply the last release's values and merge in any overrides from the command line via --set and -f. If '--reset-values' or '--reuse-values' is specified, this is ignored")
 	f.BoolVar(&client.Wait, "wait", false, "if set, will wait until all Pods, PVCs, Services, and minimum number of Pods of a Deployment, StatefulSet, or ReplicaSet are in a ready state before marking the release as successful. It will wait for as long as --timeout")
 	f.BoolVar(&client.WaitForJobs, "wait-for-jobs", false, "if set and --wait enabled, will wait until all Jobs have been completed before marking the release as successful. It will wait for as long as --timeout")
+	addKindTimeoutsFlag(f, &client.KindTimeouts)
+	f.IntVar(&client.BatchSize, "batch-size", 0, "if greater than 0, apply resources in batches of this size. With --wait, each batch must be ready before the next one is applied")
+	f.IntVar(&client.BatchRetries, "batch-retries", 0, "number of times a batch is retried after a transient API server error. Only used with --batch-size")
 	f.BoolVar(&client.Atomic, "atomic", false, "if set, upgrade process rolls back changes made in case of failed upgrade. The --wait flag will be set automatically if --atomic is used")
 	f.IntVar(&client.MaxHistory, "history-max", settings.MaxHistory, "limit the maximum number of revisions saved per release. Use 0 for no limit")
 	f.BoolVar(&client.CleanupOnFail, "cleanup-on-fail", false, "allow deletion of new resources created in this upgrade when upgrade fails")
@@ -277,13 +324,17 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	f.BoolVar(&client.HideNotes, "hide-notes", false, "if set, do not show notes in upgrade output. Does not affect presence in chart metadata")
 	f.BoolVar(&client.SkipSchemaValidation, "skip-schema-validation", false, "if set, disables JSON schema validation")
 	f.StringToStringVarP(&client.Labels, "labels", "l", nil, "Labels that would be added to release metadata. Should be separated by comma. Original release labels will be merged with upgrade labels. You can unset label using null.")
+	f.StringToStringVar(&client.Annotations, "annotations", nil, "Annotations that would be added to the release, like team=web,ticket=OPS-123. Should be separated by comma. Original release annotations will be merged with upgrade annotations. You can unset annotation using null.")
 	f.StringVar(&client.Description, "description", "", "add a custom description")
 	f.BoolVar(&client.DependencyUpdate, "dependency-update", false, "update dependencies if they are missing before installing the chart")
 	f.BoolVar(&client.EnableDNS, "enable-dns", false, "enable DNS lookups when rendering templates")
+	f.BoolVar(&confirm, "confirm", false, "show the changes to the resources of the release and ask for a confirmation before applying them")
+	f.BoolVar(&autoApprove, "auto-approve", false, "with --confirm, show the changes and apply them without asking for a confirmation")
 	addChartPathOptionsFlags(f, &client.ChartPathOptions)
 	addValueOptionsFlags(f, valueOpts)
 	bindOutputFlag(cmd, &outfmt)
 	bindPostRenderFlag(cmd, &client.PostRenderer)
+	bindRenderHookFlag(cmd, &client.RenderHooks)
 
 	err := cmd.RegisterFlagCompletionFunc("version", func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		if len(args) != 2 {
@@ -295,10 +346,60 @@ func newUpgradeCmd(cfg *action.Configuration, out io.Writer) *cobra.Command {
 	if err != nil {
 		log.Fatal(err)
 	}
+	registerValuesCompletion(cmd, func(args []string) (string, bool) {
+		if len(args) != 2 {
+			return "", false
+		}
+		return args[1], true
+	})
 
 	return cmd
 }
 
+// confirmUpgrade returns a function writing the plan of an upgrade, and
+// reading its confirmation unless it is auto-approved.
+func confirmUpgrade(in io.Reader, out io.Writer, autoApprove bool) func(*action.UpgradePlan) (bool, error) {
+	return func(plan *action.UpgradePlan) (bool, error) {
+		writeUpgradePlan(out, plan)
+		if autoApprove {
+			fmt.Fprintln(out, "Auto-approved.")
+			return true, nil
+		}
+		fmt.Fprint(out, "Do you want to perform this upgrade? Only 'yes' will be accepted: ")
+		answer, err := bufio.NewReader(in).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return false, err
+		}
+		fmt.Fprintln(out)
+		return strings.TrimSpace(answer) == "yes", nil
+	}
+}
+
+func writeUpgradePlan(out io.Writer, plan *action.UpgradePlan) {
+	if !plan.Changed() {
+		fmt.Fprintf(out, "Release %q will be upgraded to revision %d without changing its resources.\n", plan.Release, plan.Revision)
+		return
+	}
+	fmt.Fprintf(out, "Release %q will be upgraded to revision %d, changing %d resource(s):\n", plan.Release, plan.Revision, len(plan.Resources))
+	for _, r := range plan.Resources {
+		name := r.Name
+		if r.Namespace != "" {
+			name = r.Namespace + "/" + r.Name
+		}
+		switch r.Action {
+		case kube.DiffActionCreate:
+			fmt.Fprintf(out, "  + %s %s (create)\n", r.Kind, name)
+		case kube.DiffActionDelete:
+			fmt.Fprintf(out, "  - %s %s (delete)\n", r.Kind, name)
+		default:
+			fmt.Fprintf(out, "  ~ %s %s (update)\n", r.Kind, name)
+			for _, f := range r.Fields {
+				fmt.Fprintf(out, "      %s: %s -> %s\n", f.Path, driftValue(f.Live), driftValue(f.Desired))
+			}
+		}
+	}
+}
+
 func isReleaseUninstalled(versions []*release.Release) bool {
 	return len(versions) > 0 && versions[len(versions)-1].Info.Status == release.StatusUninstalled
 }
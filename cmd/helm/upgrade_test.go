@@ -24,9 +24,11 @@ import (
 	"strings"
 	"testing"
 
+	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/kube"
 	"helm.sh/helm/v3/pkg/release"
 )
 
@@ -565,3 +567,102 @@ func TestUpgradeWithDryRun(t *testing.T) {
 		t.Error("expected error when --hide-secret used without --dry-run")
 	}
 }
+
+func TestUpgradeConfirm(t *testing.T) {
+	releaseName := "funny-bunny-confirm"
+	relMock, ch, chartPath := prepareMockRelease(releaseName, t)
+
+	defer resetEnv()()
+
+	store := storageFixture()
+	store.Create(relMock(releaseName, 3, ch))
+
+	answer := func(s string) *os.File {
+		f := filepath.Join(t.TempDir(), "answer")
+		if err := os.WriteFile(f, []byte(s), 0644); err != nil {
+			t.Fatal(err)
+		}
+		in, err := os.Open(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { in.Close() })
+		return in
+	}
+
+	cmd := fmt.Sprintf("upgrade %s --confirm '%s'", releaseName, chartPath)
+	_, out, err := executeActionCommandStdinC(store, answer("no\n"), cmd)
+	if err == nil || !strings.Contains(err.Error(), "upgrade not confirmed") {
+		t.Errorf("expected the upgrade not to be confirmed, got %v", err)
+	}
+	if !strings.Contains(out, `Release "funny-bunny-confirm" will be upgraded to revision 4`) {
+		t.Errorf("expected the plan of the upgrade, got:\n%s", out)
+	}
+	if _, err := store.Get(releaseName, 4); err == nil {
+		t.Error("expected no revision to be created")
+	}
+
+	_, out, err = executeActionCommandStdinC(store, answer("yes\n"), cmd)
+	if err != nil {
+		t.Fatalf("unexpected error, got '%v'", err)
+	}
+	if !strings.Contains(out, "has been upgraded") {
+		t.Errorf("expected the release to be upgraded, got:\n%s", out)
+	}
+
+	_, out, err = executeActionCommandStdinC(store, answer(""), cmd+" --auto-approve")
+	if err != nil {
+		t.Fatalf("unexpected error, got '%v'", err)
+	}
+	if !strings.Contains(out, "Auto-approved.") || strings.Contains(out, "Do you want") {
+		t.Errorf("expected the upgrade to be approved without asking, got:\n%s", out)
+	}
+	if _, err := store.Get(releaseName, 5); err != nil {
+		t.Errorf("expected revision 5, got '%v'", err)
+	}
+
+	cmd = fmt.Sprintf("upgrade %s --auto-approve '%s'", releaseName, chartPath)
+	_, _, err = executeActionCommandStdinC(store, answer(""), cmd)
+	if err == nil || !strings.Contains(err.Error(), "--auto-approve requires --confirm") {
+		t.Errorf("expected --auto-approve to be rejected without --confirm, got %v", err)
+	}
+	if _, err := store.Get(releaseName, 6); err == nil {
+		t.Error("expected no revision to be created")
+	}
+
+	cmd = fmt.Sprintf("upgrade funny-bunny-new --install --confirm '%s'", chartPath)
+	_, _, err = executeActionCommandStdinC(store, answer("yes\n"), cmd)
+	if err == nil || !strings.Contains(err.Error(), "--confirm cannot be used to install it") {
+		t.Errorf("expected the install not to be confirmed, got %v", err)
+	}
+	if _, err := store.Get("funny-bunny-new", 1); err == nil {
+		t.Error("expected the release not to be installed")
+	}
+}
+
+func TestWriteUpgradePlan(t *testing.T) {
+	var b strings.Builder
+	writeUpgradePlan(&b, &action.UpgradePlan{
+		Release:   "web",
+		Namespace: "default",
+		Revision:  2,
+		Resources: []kube.ResourceDiff{
+			{Kind: "ConfigMap", Namespace: "default", Name: "settings", Action: kube.DiffActionCreate},
+			{Kind: "Deployment", Namespace: "default", Name: "web", Action: kube.DiffActionUpdate, Fields: []kube.FieldDiff{
+				{Path: "spec.replicas", Live: int64(1), Desired: int64(3)},
+				{Path: "spec.paused", Live: true},
+			}},
+			{Kind: "Service", Namespace: "default", Name: "legacy", Action: kube.DiffActionDelete},
+		},
+	})
+	expect := `Release "web" will be upgraded to revision 2, changing 3 resource(s):
+  + ConfigMap default/settings (create)
+  ~ Deployment default/web (update)
+      spec.replicas: 1 -> 3
+      spec.paused: true -> <unset>
+  - Service default/legacy (delete)
+`
+	if b.String() != expect {
+		t.Errorf("expected:\n%s\ngot:\n%s", expect, b.String())
+	}
+}
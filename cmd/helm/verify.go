@@ -31,6 +31,16 @@ Verify that the given chart has a valid provenance file.
 Provenance files provide cryptographic verification that a chart has not been
 tampered with, and was packaged by a trusted provider.
 
+The public keys are read from the keyring given with '--keyring'. Several
+keyrings may be given, separated by ':' (';' on Windows), and each one may be a
+directory of keyring files, such as the current and the previous keys of a
+rotation. The output reports the identity and the keyring of the key that
+signed the chart.
+
+If the signature policy given with '--signature-policy' exists, and the chart
+has a cosign bundle whose name is the name of the chart archive plus the
+".cosign.bundle" extension, its sigstore signature is verified too.
+
 This command can be used to verify a local chart. Several other commands provide
 '--verify' flags that run the same validation. To generate a signed package, use
 the 'helm package --sign' command.
@@ -53,7 +63,13 @@ func newVerifyCmd(out io.Writer) *cobra.Command {
 			return noMoreArgsComp()
 		},
 		RunE: func(_ *cobra.Command, args []string) error {
-			err := client.Run(args[0])
+			policy, err := action.LoadSignaturePolicy(settings)
+			if err != nil {
+				return err
+			}
+			client.SignaturePolicy = policy
+
+			err = client.Run(args[0])
 			if err != nil {
 				return err
 			}
@@ -64,7 +80,7 @@ func newVerifyCmd(out io.Writer) *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&client.Keyring, "keyring", defaultKeyring(), "keyring containing public keys")
+	cmd.Flags().StringVar(&client.Keyring, "keyring", defaultKeyring(), "keyring containing public keys, or a list of keyrings and directories of keyrings")
 
 	return cmd
 }
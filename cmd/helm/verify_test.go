@@ -16,9 +16,20 @@ limitations under the License.
 package main
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
+
+	"helm.sh/helm/v3/pkg/registry"
 )
 
 func TestVerifyCmd(t *testing.T) {
@@ -65,7 +76,7 @@ func TestVerifyCmd(t *testing.T) {
 		{
 			name:      "verify validates a properly signed chart",
 			cmd:       "verify testdata/testcharts/signtest-0.1.0.tgz --keyring testdata/helm-test-key.pub",
-			expect:    "Signed by: Helm Testing (This key should only be used for testing. DO NOT TRUST.) <helm-testing@helm.sh>\nUsing Key With Fingerprint: 5E615389B53CA37F0EE60BD3843BBF981FC18762\nChart Hash Verified: sha256:e5ef611620fb97704d8751c16bab17fedb68883bfb0edc76f78a70e9173f9b55\n",
+			expect:    "Signed by: Helm Testing (This key should only be used for testing. DO NOT TRUST.) <helm-testing@helm.sh>\nUsing Key With Fingerprint: 5E615389B53CA37F0EE60BD3843BBF981FC18762\nFound In Keyring: testdata/helm-test-key.pub\nChart Hash Verified: sha256:e5ef611620fb97704d8751c16bab17fedb68883bfb0edc76f78a70e9173f9b55\n",
 			wantError: false,
 		},
 	}
@@ -91,6 +102,105 @@ func TestVerifyCmd(t *testing.T) {
 	}
 }
 
+func TestVerifyCmdKeyrings(t *testing.T) {
+	dir := t.TempDir()
+	data, err := os.ReadFile("testdata/helm-test-key.pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "2024.gpg"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("rotated keys"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	empty := t.TempDir()
+
+	keyring := strings.Join([]string{filepath.Join(empty, "none.gpg"), dir}, string(filepath.ListSeparator))
+	if _, _, err := executeActionCommand("verify testdata/testcharts/signtest-0.1.0.tgz --keyring " + keyring); err == nil {
+		t.Error("Expected a missing keyring to fail")
+	}
+
+	keyring = strings.Join([]string{empty, dir}, string(filepath.ListSeparator))
+	_, out, err := executeActionCommand("verify testdata/testcharts/signtest-0.1.0.tgz --keyring " + keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := "Found In Keyring: " + filepath.Join(dir, "2024.gpg") + "\n"; !strings.Contains(out, expect) {
+		t.Errorf("Expected %q in %q", expect, out)
+	}
+
+	if _, _, err := executeActionCommand("verify testdata/testcharts/signtest-0.1.0.tgz --keyring " + empty); err == nil || !strings.Contains(err.Error(), "no public keys found") {
+		t.Errorf("Expected a keyring without keys to fail, got %v", err)
+	}
+}
+
+func TestVerifyCmdSignatureBundle(t *testing.T) {
+	defer resetEnv()()
+
+	dir := t.TempDir()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cosign.key"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if der, err = x509.MarshalPKIXPublicKey(&key.PublicKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cosign.pub"), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	policy := filepath.Join(dir, "signature-policy.yaml")
+	if err := os.WriteFile(policy, []byte("publicKeys:\n- cosign.pub\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	chart := filepath.Join(dir, "compressedchart-0.1.0.tgz")
+	data, err := os.ReadFile("testdata/testcharts/compressedchart-0.1.0.tgz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(chart, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	signer, err := registry.NewKeySigner(filepath.Join(dir, "cosign.key"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle, err := signer.SignBlob(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := json.Marshal(bundle)
+	if err := os.WriteFile(chart+registry.SignatureBundleExt, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, out, err := executeActionCommand(fmt.Sprintf("verify %s --signature-policy %s", chart, policy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := "Signed by: " + filepath.Join(dir, "cosign.pub") + "\n"; !strings.HasPrefix(out, expect) {
+		t.Errorf("Expected %q to start with %q", out, expect)
+	}
+	if strings.Contains(out, "Fingerprint") {
+		t.Errorf("Expected the chart to be verified with its cosign bundle only, got %q", out)
+	}
+
+	if err := os.WriteFile(chart, append(data, 0), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := executeActionCommand(fmt.Sprintf("verify %s --signature-policy %s", chart, policy)); err == nil {
+		t.Error("Expected a tampered chart to fail")
+	}
+}
+
 func TestVerifyFileCompletion(t *testing.T) {
 	checkFileCompletion(t, "verify", true)
 	checkFileCompletion(t, "verify mypath", false)
@@ -0,0 +1,159 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package deprecations is a database of the deprecated and removed Kubernetes
+// APIs.
+//
+// Unlike the deprecation markers of the Kubernetes client libraries, the
+// database knows the APIs removed from the libraries, like the
+// PodSecurityPolicy APIs.
+package deprecations // import "helm.sh/helm/v3/internal/deprecations"
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is the latest Kubernetes version the database is current to.
+const Version = "v1.32"
+
+// API is a deprecated Kubernetes API.
+type API struct {
+	APIVersion string
+	Kind       string
+	// DeprecatedIn is the Kubernetes version deprecating the API, like "1.22".
+	DeprecatedIn string
+	// RemovedIn is the Kubernetes version no longer serving the API.
+	RemovedIn string
+	// Replacement is the API version to use instead, if any.
+	Replacement string
+}
+
+// apis are the deprecated Kubernetes APIs, by Kubernetes version removing
+// them.
+var apis = []API{
+	// Removed in 1.16
+	{"extensions/v1beta1", "DaemonSet", "1.8", "1.16", "apps/v1"},
+	{"extensions/v1beta1", "Deployment", "1.8", "1.16", "apps/v1"},
+	{"extensions/v1beta1", "ReplicaSet", "1.8", "1.16", "apps/v1"},
+	{"extensions/v1beta1", "NetworkPolicy", "1.9", "1.16", "networking.k8s.io/v1"},
+	{"extensions/v1beta1", "PodSecurityPolicy", "1.10", "1.16", "policy/v1beta1"},
+	{"apps/v1beta1", "Deployment", "1.9", "1.16", "apps/v1"},
+	{"apps/v1beta1", "StatefulSet", "1.9", "1.16", "apps/v1"},
+	{"apps/v1beta1", "ControllerRevision", "1.9", "1.16", "apps/v1"},
+	{"apps/v1beta2", "DaemonSet", "1.9", "1.16", "apps/v1"},
+	{"apps/v1beta2", "Deployment", "1.9", "1.16", "apps/v1"},
+	{"apps/v1beta2", "ReplicaSet", "1.9", "1.16", "apps/v1"},
+	{"apps/v1beta2", "StatefulSet", "1.9", "1.16", "apps/v1"},
+	{"apps/v1beta2", "ControllerRevision", "1.9", "1.16", "apps/v1"},
+
+	// Removed in 1.22
+	{"extensions/v1beta1", "Ingress", "1.14", "1.22", "networking.k8s.io/v1"},
+	{"networking.k8s.io/v1beta1", "Ingress", "1.19", "1.22", "networking.k8s.io/v1"},
+	{"networking.k8s.io/v1beta1", "IngressClass", "1.19", "1.22", "networking.k8s.io/v1"},
+	{"apiextensions.k8s.io/v1beta1", "CustomResourceDefinition", "1.16", "1.22", "apiextensions.k8s.io/v1"},
+	{"apiregistration.k8s.io/v1beta1", "APIService", "1.19", "1.22", "apiregistration.k8s.io/v1"},
+	{"admissionregistration.k8s.io/v1beta1", "MutatingWebhookConfiguration", "1.16", "1.22", "admissionregistration.k8s.io/v1"},
+	{"admissionregistration.k8s.io/v1beta1", "ValidatingWebhookConfiguration", "1.16", "1.22", "admissionregistration.k8s.io/v1"},
+	{"rbac.authorization.k8s.io/v1beta1", "ClusterRole", "1.17", "1.22", "rbac.authorization.k8s.io/v1"},
+	{"rbac.authorization.k8s.io/v1beta1", "ClusterRoleBinding", "1.17", "1.22", "rbac.authorization.k8s.io/v1"},
+	{"rbac.authorization.k8s.io/v1beta1", "Role", "1.17", "1.22", "rbac.authorization.k8s.io/v1"},
+	{"rbac.authorization.k8s.io/v1beta1", "RoleBinding", "1.17", "1.22", "rbac.authorization.k8s.io/v1"},
+	{"scheduling.k8s.io/v1beta1", "PriorityClass", "1.14", "1.22", "scheduling.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", "CSIDriver", "1.19", "1.22", "storage.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", "CSINode", "1.17", "1.22", "storage.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", "StorageClass", "1.19", "1.22", "storage.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", "VolumeAttachment", "1.19", "1.22", "storage.k8s.io/v1"},
+	{"certificates.k8s.io/v1beta1", "CertificateSigningRequest", "1.19", "1.22", "certificates.k8s.io/v1"},
+	{"coordination.k8s.io/v1beta1", "Lease", "1.19", "1.22", "coordination.k8s.io/v1"},
+
+	// Removed in 1.25
+	{"batch/v1beta1", "CronJob", "1.21", "1.25", "batch/v1"},
+	{"discovery.k8s.io/v1beta1", "EndpointSlice", "1.21", "1.25", "discovery.k8s.io/v1"},
+	{"events.k8s.io/v1beta1", "Event", "1.19", "1.25", "events.k8s.io/v1"},
+	{"autoscaling/v2beta1", "HorizontalPodAutoscaler", "1.22", "1.25", "autoscaling/v2"},
+	{"policy/v1beta1", "PodDisruptionBudget", "1.21", "1.25", "policy/v1"},
+	{"policy/v1beta1", "PodSecurityPolicy", "1.21", "1.25", ""},
+	{"node.k8s.io/v1beta1", "RuntimeClass", "1.20", "1.25", "node.k8s.io/v1"},
+
+	// Removed in 1.26
+	{"autoscaling/v2beta2", "HorizontalPodAutoscaler", "1.23", "1.26", "autoscaling/v2"},
+	{"flowcontrol.apiserver.k8s.io/v1beta1", "FlowSchema", "1.23", "1.26", "flowcontrol.apiserver.k8s.io/v1beta3"},
+	{"flowcontrol.apiserver.k8s.io/v1beta1", "PriorityLevelConfiguration", "1.23", "1.26", "flowcontrol.apiserver.k8s.io/v1beta3"},
+
+	// Removed in 1.27
+	{"storage.k8s.io/v1beta1", "CSIStorageCapacity", "1.24", "1.27", "storage.k8s.io/v1"},
+
+	// Removed in 1.29
+	{"flowcontrol.apiserver.k8s.io/v1beta2", "FlowSchema", "1.26", "1.29", "flowcontrol.apiserver.k8s.io/v1"},
+	{"flowcontrol.apiserver.k8s.io/v1beta2", "PriorityLevelConfiguration", "1.26", "1.29", "flowcontrol.apiserver.k8s.io/v1"},
+
+	// Removed in 1.32
+	{"flowcontrol.apiserver.k8s.io/v1beta3", "FlowSchema", "1.29", "1.32", "flowcontrol.apiserver.k8s.io/v1"},
+	{"flowcontrol.apiserver.k8s.io/v1beta3", "PriorityLevelConfiguration", "1.29", "1.32", "flowcontrol.apiserver.k8s.io/v1"},
+}
+
+// Deprecation is the use of a deprecated API in a Kubernetes version.
+type Deprecation struct {
+	API
+	// Removed is whether the Kubernetes version no longer serves the API.
+	Removed bool
+}
+
+// String returns the message of the deprecation, suggesting the replacement
+// of the API.
+func (d Deprecation) String() string {
+	var msg string
+	if d.Removed {
+		msg = fmt.Sprintf("%s %s is unavailable in v%s+", d.APIVersion, d.Kind, d.RemovedIn)
+	} else {
+		msg = fmt.Sprintf("%s %s is deprecated in v%s+, unavailable in v%s+", d.APIVersion, d.Kind, d.DeprecatedIn, d.RemovedIn)
+	}
+	if d.Replacement != "" {
+		msg += fmt.Sprintf("; use %s %s", d.Replacement, d.Kind)
+	}
+	return msg
+}
+
+// Lookup returns the deprecated API of the API version and kind, if any.
+func Lookup(apiVersion, kind string) (API, bool) {
+	for _, api := range apis {
+		if api.APIVersion == apiVersion && api.Kind == kind {
+			return api, true
+		}
+	}
+	return API{}, false
+}
+
+// Check returns the deprecation of the API version and kind in the Kubernetes
+// version major.minor, or nil if the API is not deprecated in this version.
+func Check(apiVersion, kind string, major, minor int) *Deprecation {
+	api, ok := Lookup(apiVersion, kind)
+	if !ok || !atLeast(major, minor, api.DeprecatedIn) {
+		return nil
+	}
+	return &Deprecation{API: api, Removed: atLeast(major, minor, api.RemovedIn)}
+}
+
+// atLeast returns whether the Kubernetes version major.minor is at least the
+// version v, like "1.22".
+func atLeast(major, minor int, v string) bool {
+	vmajor, vminor, _ := strings.Cut(v, ".")
+	maj, _ := strconv.Atoi(vmajor)
+	min, _ := strconv.Atoi(vminor)
+	return major > maj || (major == maj && minor >= min)
+}
@@ -0,0 +1,64 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deprecations
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name, apiVersion, kind string
+		major, minor           int
+		expect                 string
+	}{
+		{name: "supported API", apiVersion: "apps/v1", kind: "Deployment", major: 1, minor: 30},
+		{name: "not yet deprecated", apiVersion: "autoscaling/v2beta1", kind: "HorizontalPodAutoscaler", major: 1, minor: 21},
+		{name: "deprecated", apiVersion: "autoscaling/v2beta1", kind: "HorizontalPodAutoscaler", major: 1, minor: 22, expect: "autoscaling/v2beta1 HorizontalPodAutoscaler is deprecated in v1.22+, unavailable in v1.25+; use autoscaling/v2 HorizontalPodAutoscaler"},
+		{name: "removed", apiVersion: "extensions/v1beta1", kind: "Ingress", major: 1, minor: 22, expect: "extensions/v1beta1 Ingress is unavailable in v1.22+; use networking.k8s.io/v1 Ingress"},
+		{name: "removed without replacement", apiVersion: "policy/v1beta1", kind: "PodSecurityPolicy", major: 1, minor: 25, expect: "policy/v1beta1 PodSecurityPolicy is unavailable in v1.25+"},
+		{name: "next major version", apiVersion: "batch/v1beta1", kind: "CronJob", major: 2, minor: 0, expect: "batch/v1beta1 CronJob is unavailable in v1.25+; use batch/v1 CronJob"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := Check(tt.apiVersion, tt.kind, tt.major, tt.minor)
+			switch {
+			case d == nil && tt.expect != "":
+				t.Errorf("expected %q, got no deprecation", tt.expect)
+			case d != nil && d.String() != tt.expect:
+				t.Errorf("expected %q, got %q", tt.expect, d.String())
+			}
+		})
+	}
+}
+
+func TestAPIVersions(t *testing.T) {
+	for _, api := range apis {
+		removed, _ := Lookup(api.APIVersion, api.Kind)
+		if removed != api {
+			t.Errorf("%s %s is in the database twice", api.APIVersion, api.Kind)
+		}
+		major, minor := 1, 0
+		if _, err := fmt.Sscanf(api.RemovedIn, "%d.%d", &major, &minor); err != nil {
+			t.Fatalf("%s %s: invalid version %q", api.APIVersion, api.Kind, api.RemovedIn)
+		}
+		if !atLeast(major, minor-1, api.DeprecatedIn) {
+			t.Errorf("%s %s is removed before it is deprecated", api.APIVersion, api.Kind)
+		}
+	}
+}
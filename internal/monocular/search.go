@@ -62,6 +62,7 @@ type Chart struct {
 	Maintainers []chart.Maintainer `json:"maintainers"`
 	Sources     []string           `json:"sources"`
 	Icon        string             `json:"icon"`
+	Deprecated  bool               `json:"deprecated"`
 }
 
 // Repo contains the name in monocular the url for the repository
@@ -0,0 +1,378 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+
+	"helm.sh/helm/v3/internal/urlutil"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/helmpath"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+// maxGraphDepth bounds the depth of the dependency graphs, the dependencies of
+// charts depending on each other being infinite.
+const maxGraphDepth = 10
+
+// Graph is the dependency graph of a chart: its dependencies, the
+// dependencies of its dependencies, and so on.
+type Graph struct {
+	// Root is the node of the chart.
+	Root *Node
+}
+
+// Node is a chart of a dependency graph.
+type Node struct {
+	// Name is the name of the chart.
+	Name string
+	// Alias is the alias of the dependency, if any.
+	Alias string
+	// Constraint is the version constraint of the dependency.
+	Constraint string
+	// Repository is the repository of the dependency.
+	Repository string
+	// Version is the version of the chart, or an empty string if it could not
+	// be resolved. The transitive dependencies of the charts of repositories
+	// are resolved to the newest version of the repository satisfying their
+	// constraint, as they would be by updating the dependencies of their chart.
+	Version string
+	// Parent is the chart depending on the chart, nil for the root.
+	Parent *Node
+	// Dependencies are the dependencies of the chart.
+	Dependencies []*Node
+}
+
+// Path returns the names of the charts from the root to the node, like
+// "mychart > mariadb > common".
+func (n *Node) Path() string {
+	var names []string
+	for p := n; p != nil; p = p.Parent {
+		names = append([]string{p.Name}, names...)
+	}
+	return strings.Join(names, " > ")
+}
+
+// Shared is a chart required by several charts of a dependency graph. Each
+// chart packages its own copy of its dependencies, so the requirements of a
+// shared chart conflict when no version satisfies all of them, and the chart
+// is installed in different versions.
+type Shared struct {
+	// Name is the name of the chart.
+	Name string
+	// Nodes are the nodes of the chart in the graph.
+	Nodes []*Node
+}
+
+// Conflicting returns true if none of the resolved versions of the chart
+// satisfies the constraints of all its nodes.
+func (s Shared) Conflicting() bool {
+	return len(s.versions()) > 0 && s.satisfying() == ""
+}
+
+// satisfying returns a resolved version of the chart satisfying the
+// constraints of all its nodes, or an empty string.
+func (s Shared) satisfying() string {
+	for _, version := range s.versions() {
+		v, err := semver.NewVersion(version)
+		if err != nil {
+			continue
+		}
+		ok := true
+		for _, n := range s.Nodes {
+			if c, err := semver.NewConstraint(n.Constraint); err == nil && !c.Check(v) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return version
+		}
+	}
+	return ""
+}
+
+// versions returns the distinct resolved versions of the chart.
+func (s Shared) versions() []string {
+	var versions []string
+	seen := make(map[string]bool)
+	for _, n := range s.Nodes {
+		if n.Version != "" && !seen[n.Version] {
+			seen[n.Version] = true
+			versions = append(versions, n.Version)
+		}
+	}
+	return versions
+}
+
+// String explains the requirements of the chart.
+func (s Shared) String() string {
+	requirements := make([]string, len(s.Nodes))
+	for i, n := range s.Nodes {
+		requirements[i] = fmt.Sprintf("%s by %s (%s)", orUnresolved(n.Version), n.Parent.Path(), n.Constraint)
+	}
+	if s.Conflicting() {
+		return fmt.Sprintf("chart %q is required in conflicting versions: %s", s.Name, strings.Join(requirements, ", "))
+	}
+	explanation := fmt.Sprintf("chart %q is required several times: %s", s.Name, strings.Join(requirements, ", "))
+	if v := s.satisfying(); v != "" {
+		explanation += fmt.Sprintf(", all satisfied by %s", v)
+	}
+	return explanation
+}
+
+// Shared returns the charts required by several charts of the graph, sorted by
+// name.
+func (g *Graph) Shared() []Shared {
+	nodes := make(map[string][]*Node)
+	var walk func(n *Node)
+	walk = func(n *Node) {
+		for _, d := range n.Dependencies {
+			nodes[d.Name] = append(nodes[d.Name], d)
+			walk(d)
+		}
+	}
+	walk(g.Root)
+
+	var shared []Shared
+	for name, ns := range nodes {
+		if len(ns) > 1 {
+			shared = append(shared, Shared{Name: name, Nodes: ns})
+		}
+	}
+	sort.Slice(shared, func(i, j int) bool { return shared[i].Name < shared[j].Name })
+	return shared
+}
+
+// Conflicts returns the shared charts of the graph required in conflicting
+// versions.
+func (g *Graph) Conflicts() []Shared {
+	var conflicts []Shared
+	for _, s := range g.Shared() {
+		if s.Conflicting() {
+			conflicts = append(conflicts, s)
+		}
+	}
+	return conflicts
+}
+
+// String renders the graph as an indented tree, a chart by line with its
+// version, constraint and repository.
+func (g *Graph) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", g.Root.Name, g.Root.Version)
+	var walk func(n *Node, indent string)
+	walk = func(n *Node, indent string) {
+		for _, d := range n.Dependencies {
+			name := d.Name
+			if d.Alias != "" {
+				name += " as " + d.Alias
+			}
+			fmt.Fprintf(&b, "%s%s %s (%s", indent, name, orUnresolved(d.Version), d.Constraint)
+			if d.Repository != "" {
+				fmt.Fprintf(&b, " from %s", d.Repository)
+			}
+			b.WriteString(")\n")
+			walk(d, indent+"  ")
+		}
+	}
+	walk(g.Root, "  ")
+	return b.String()
+}
+
+func orUnresolved(version string) string {
+	if version == "" {
+		return "unresolved"
+	}
+	return version
+}
+
+// graphChart is a chart of a dependency graph whose dependencies are
+// resolved: its metadata, and when the chart is loaded, the chart and its
+// subcharts, and the directory relative to which its file:// dependencies are.
+type graphChart struct {
+	md  *chart.Metadata
+	ch  *chart.Chart
+	dir string
+}
+
+// graphBuilder builds the dependency graph of a chart.
+type graphBuilder struct {
+	r *Resolver
+	// repos are the URLs of the repositories by name.
+	repos   map[string]string
+	indices map[string]*repo.IndexFile
+}
+
+// Graph returns the dependency graph of the chart whose dependencies are
+// resolved in locked, the dependencies of the lock file returned by Resolve.
+// The transitive dependencies are resolved from the metadata of the charts:
+// the subcharts of the charts of local directories and git repositories, and
+// the indices of the repositories, whose URLs by name are given by repos.
+// Dependencies that cannot be resolved, like those of OCI registries, are left
+// unresolved.
+func (r *Resolver) Graph(md *chart.Metadata, locked []*chart.Dependency, repos map[string]string) *Graph {
+	b := &graphBuilder{r: r, repos: repos, indices: make(map[string]*repo.IndexFile)}
+	root := &Node{Name: md.Name, Version: md.Version}
+	for i, d := range md.Dependencies {
+		n := &Node{Name: d.Name, Alias: d.Alias, Constraint: d.Version, Repository: d.Repository, Parent: root}
+		if i < len(locked) && locked[i] != nil {
+			n.Version = locked[i].Version
+		}
+		root.Dependencies = append(root.Dependencies, n)
+
+		var gc *graphChart
+		switch {
+		case d.Repository == "":
+			gc = b.loadDir(filepath.Join(r.chartpath, "charts", d.Name))
+		case strings.HasPrefix(d.Repository, "file://"):
+			if p, err := GetLocalPath(d.Repository, r.chartpath); err == nil {
+				gc = b.loadDir(p)
+			}
+		case getter.IsGitURL(d.Repository):
+			if ch, ok := r.gitCharts[d.Repository]; ok {
+				gc = &graphChart{md: ch.Metadata, ch: ch}
+			}
+		default:
+			gc = b.fromIndex(d, n.Version)
+		}
+		if gc == nil {
+			continue
+		}
+		n.Version = gc.md.Version
+		b.expand(n, gc, 1)
+	}
+	return &Graph{Root: root}
+}
+
+// expand adds the dependencies of the chart gc of the node n to the graph.
+func (b *graphBuilder) expand(n *Node, gc *graphChart, depth int) {
+	if depth >= maxGraphDepth {
+		return
+	}
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Name == n.Name && p.Version == n.Version {
+			// The charts depend on each other
+			return
+		}
+	}
+	for _, d := range gc.md.Dependencies {
+		dn := &Node{Name: d.Name, Alias: d.Alias, Constraint: d.Version, Repository: d.Repository, Parent: n}
+		n.Dependencies = append(n.Dependencies, dn)
+		if dc := b.resolve(d, gc); dc != nil {
+			dn.Version = dc.md.Version
+			b.expand(dn, dc, depth+1)
+		}
+	}
+}
+
+// resolve returns the chart of the dependency d of the chart gc, or nil if it
+// cannot be resolved. The subcharts packaged with a chart are the ones it is
+// installed with, so they are preferred.
+func (b *graphBuilder) resolve(d *chart.Dependency, gc *graphChart) *graphChart {
+	if gc.ch != nil {
+		for _, sub := range gc.ch.Dependencies() {
+			if sub.Name() == d.Name {
+				return &graphChart{md: sub.Metadata, ch: sub}
+			}
+		}
+	}
+	switch {
+	case d.Repository == "":
+		return nil
+	case strings.HasPrefix(d.Repository, "file://"):
+		if gc.dir == "" {
+			return nil
+		}
+		p, err := GetLocalPath(d.Repository, gc.dir)
+		if err != nil {
+			return nil
+		}
+		return b.loadDir(p)
+	case getter.IsGitURL(d.Repository), registry.IsOCI(d.Repository):
+		return nil
+	}
+	return b.fromIndex(d, d.Version)
+}
+
+// loadDir returns the chart of the directory dir, or nil if it cannot be
+// loaded.
+func (b *graphBuilder) loadDir(dir string) *graphChart {
+	ch, err := loader.LoadDir(dir)
+	if err != nil {
+		return nil
+	}
+	return &graphChart{md: ch.Metadata, ch: ch, dir: dir}
+}
+
+// fromIndex returns the newest chart of the dependency d satisfying version in
+// the cached index of its repository, or nil if the repository is not known.
+func (b *graphBuilder) fromIndex(d *chart.Dependency, version string) *graphChart {
+	if registry.IsOCI(d.Repository) {
+		return nil
+	}
+	name := b.repoName(d.Repository)
+	if name == "" {
+		return nil
+	}
+	index, ok := b.indices[name]
+	if !ok {
+		index, _ = repo.LoadIndexFile(filepath.Join(b.r.cachepath, helmpath.CacheIndexFile(name)))
+		b.indices[name] = index
+	}
+	if index == nil {
+		return nil
+	}
+	cv, err := index.Get(d.Name, version)
+	if err != nil || cv.Metadata == nil {
+		return nil
+	}
+	return &graphChart{md: cv.Metadata}
+}
+
+// repoName returns the name of the repository of a dependency, given by name
+// with "@" or "alias:", or by URL.
+func (b *graphBuilder) repoName(repository string) string {
+	for _, prefix := range []string{"@", "alias:"} {
+		if strings.HasPrefix(repository, prefix) {
+			name := strings.TrimPrefix(repository, prefix)
+			if _, ok := b.repos[name]; ok {
+				return name
+			}
+			return ""
+		}
+	}
+	// Iterate in order of names, for repositories of the same URL
+	names := make([]string, 0, len(b.repos))
+	for name := range b.repos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if urlutil.Equal(b.repos[name], repository) {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,84 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+func TestGraph(t *testing.T) {
+	md := &chart.Metadata{
+		Name:    "mychart",
+		Version: "0.1.0",
+		Dependencies: []*chart.Dependency{
+			{Name: "app", Repository: "https://charts.example.com/", Version: "^1.0.0"},
+			{Name: "common", Repository: "@graph", Version: ">=1.0.0"},
+			{Name: "localdependency", Version: "0.1.0"},
+			{Name: "ouroboros", Repository: "https://charts.example.com", Version: "1.0.0"},
+			{Name: "redis", Repository: "oci://example.com/charts", Version: "^1.0.0"},
+		},
+	}
+	locked := []*chart.Dependency{
+		{Name: "app", Repository: "https://charts.example.com/", Version: "1.0.0"},
+		{Name: "common", Repository: "@graph", Version: "2.0.1"},
+		{Name: "localdependency", Version: "0.1.0"},
+		{Name: "ouroboros", Repository: "https://charts.example.com", Version: "1.0.0"},
+		{Name: "redis", Repository: "oci://example.com/charts", Version: "1.0.2"},
+	}
+	r := New("testdata/chartpath", "testdata/repository", nil)
+	g := r.Graph(md, locked, map[string]string{"graph": "https://charts.example.com"})
+
+	expect := `mychart 0.1.0
+  app 1.0.0 (^1.0.0 from https://charts.example.com/)
+    common 1.2.0 (^1.0.0 from https://charts.example.com)
+    mariadb 0.3.0 (0.3.0 from @graph)
+      common 2.0.1 (~2.0.0 from https://charts.example.com)
+      util 1.1.0 (^1.1.0 from https://charts.example.com)
+    util 1.1.0 (>=1.0.0 from https://charts.example.com)
+  common 2.0.1 (>=1.0.0 from @graph)
+  localdependency 0.1.0 (0.1.0)
+  ouroboros 1.0.0 (1.0.0 from https://charts.example.com)
+    ouroboros 1.0.0 (1.0.0 from https://charts.example.com)
+  redis 1.0.2 (^1.0.0 from oci://example.com/charts)
+`
+	if got := g.String(); got != expect {
+		t.Errorf("unexpected graph:\n%s\nexpected:\n%s", got, expect)
+	}
+
+	shared := g.Shared()
+	var names []string
+	for _, s := range shared {
+		names = append(names, s.Name)
+	}
+	if got := strings.Join(names, ","); got != "common,ouroboros,util" {
+		t.Fatalf("unexpected shared charts %s", got)
+	}
+	if !shared[0].Conflicting() || shared[1].Conflicting() || shared[2].Conflicting() {
+		t.Errorf("expected only common to conflict")
+	}
+	if got := shared[0].String(); got != `chart "common" is required in conflicting versions: 1.2.0 by mychart > app (^1.0.0), 2.0.1 by mychart > app > mariadb (~2.0.0), 2.0.1 by mychart (>=1.0.0)` {
+		t.Errorf("unexpected explanation %q", got)
+	}
+	if got := shared[2].String(); !strings.HasSuffix(got, "all satisfied by 1.1.0") {
+		t.Errorf("unexpected explanation %q", got)
+	}
+	if conflicts := g.Conflicts(); len(conflicts) != 1 || conflicts[0].Name != "common" {
+		t.Errorf("unexpected conflicts %v", conflicts)
+	}
+}
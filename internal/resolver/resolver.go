@@ -29,6 +29,7 @@ import (
 
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/getter"
 	"helm.sh/helm/v3/pkg/helmpath"
 	"helm.sh/helm/v3/pkg/provenance"
 	"helm.sh/helm/v3/pkg/registry"
@@ -40,6 +41,9 @@ type Resolver struct {
 	chartpath      string
 	cachepath      string
 	registryClient *registry.Client
+	// gitCharts are the charts of the git dependencies loaded by Resolve, by
+	// repository.
+	gitCharts map[string]*chart.Chart
 }
 
 // New creates a new resolver for a given chart, helm home and registry client.
@@ -48,6 +52,7 @@ func New(chartpath, cachepath string, registryClient *registry.Client) *Resolver
 		chartpath:      chartpath,
 		cachepath:      cachepath,
 		registryClient: registryClient,
+		gitCharts:      make(map[string]*chart.Chart),
 	}
 }
 
@@ -108,6 +113,37 @@ func (r *Resolver) Resolve(reqs []*chart.Dependency, repoNames map[string]string
 			continue
 		}
 
+		if getter.IsGitURL(d.Repository) {
+			// Pin the commit of the ref, so the dependency is built from the
+			// same commit if the ref is moved
+			commit, err := getter.ResolveGitCommit(d.Repository)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "could not resolve the commit of %s", d.Repository)
+			}
+			ch, err := loadGitChart(d.Repository, commit)
+			if err != nil {
+				return nil, nil, err
+			}
+			if ch.Name() != d.Name {
+				return nil, nil, errors.Errorf("dependency %q does not match the chart %q of %s", d.Name, ch.Name(), d.Repository)
+			}
+			r.gitCharts[d.Repository] = ch
+
+			v, err := semver.NewVersion(ch.Metadata.Version)
+			if err != nil || !constraint.Check(v) {
+				missing = append(missing, fmt.Sprintf("%q (repository %q, version %q)", d.Name, d.Repository, d.Version))
+				continue
+			}
+
+			locked[i] = &chart.Dependency{
+				Name:       d.Name,
+				Repository: d.Repository,
+				Version:    ch.Metadata.Version,
+				Digest:     commit,
+			}
+			continue
+		}
+
 		repoName := repoNames[d.Name]
 		// if the repository was not defined, but the dependency defines a repository url, bypass the cache
 		if repoName == "" && d.Repository != "" {
@@ -201,6 +237,15 @@ func (r *Resolver) Resolve(reqs []*chart.Dependency, repoNames map[string]string
 					urls[d.Repository+ver.Name+ver.Version] = ver.URLs[0]
 				}
 				locked[i].Version = v.Original()
+				if registry.IsOCI(d.Repository) && r.registryClient != nil {
+					// Pin the manifest of the tag, so builds fail if it is moved
+					ref := fmt.Sprintf("%s/%s:%s", strings.TrimPrefix(d.Repository, fmt.Sprintf("%s://", registry.OCIScheme)), d.Name, locked[i].Version)
+					digest, err := r.registryClient.Resolve(ref)
+					if err != nil {
+						return nil, nil, errors.Wrapf(err, "could not resolve the digest of %s", ref)
+					}
+					locked[i].Digest = digest
+				}
 				break
 			}
 		}
@@ -225,6 +270,19 @@ func (r *Resolver) Resolve(reqs []*chart.Dependency, repoNames map[string]string
 	}, urls, nil
 }
 
+// loadGitChart loads the chart of the git chart URL href at commit.
+func loadGitChart(href, commit string) (*chart.Chart, error) {
+	g, err := getter.NewGitGetter()
+	if err != nil {
+		return nil, err
+	}
+	data, err := g.Get(href, getter.WithDigest(commit))
+	if err != nil {
+		return nil, err
+	}
+	return loader.LoadArchive(data)
+}
+
 // HashReq generates a hash of the dependencies.
 //
 // This should be used only to compare against another hash generated by this
@@ -19,13 +19,13 @@ package action
 import (
 	"bytes"
 	"fmt"
-	"os"
 	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
 
 	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/discovery"
@@ -40,6 +40,7 @@ import (
 	"helm.sh/helm/v3/pkg/registry"
 	"helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/releaseutil"
+	"helm.sh/helm/v3/pkg/renderhook"
 	"helm.sh/helm/v3/pkg/storage"
 	"helm.sh/helm/v3/pkg/storage/driver"
 	"helm.sh/helm/v3/pkg/time"
@@ -94,16 +95,59 @@ type Configuration struct {
 	// Capabilities describes the capabilities of the Kubernetes cluster.
 	Capabilities *chartutil.Capabilities
 
+	// RecordEvents enables recording Kubernetes Events when release operations
+	// start, succeed and fail.
+	RecordEvents bool
+
+	// EventTarget is the object Events are recorded on. If it is nil, Events
+	// are recorded on the release's storage object when the Secret or ConfigMap
+	// driver is used.
+	EventTarget *v1.ObjectReference
+
+	// ReleaseEncryptor encrypts the releases written by the storage driver
+	// created by Init. Releases stored without encryption remain readable.
+	ReleaseEncryptor driver.Encryptor
+
+	// ReleaseCompression configures the compression of the releases written
+	// by the storage driver created by Init.
+	ReleaseCompression driver.Compression
+
+	// StorageInstrumentation configures the metrics and the tracing of the
+	// operations of the storage driver created by Init. The driver is not
+	// instrumented if neither a tracer provider nor a registerer is set.
+	StorageInstrumentation driver.InstrumentOptions
+
+	// LifecycleHooks are run on the events of the lifecycle of the releases
+	// installed, upgraded, rolled back and uninstalled with the
+	// configuration. They are not run by dry runs.
+	LifecycleHooks []LifecycleHook
+
 	Log func(string, ...interface{})
 }
 
+// postRenderer returns the post-renderer pr followed by the registered
+// post-renderer named name, if any.
+func postRenderer(pr postrender.PostRenderer, name string, args []string) (postrender.PostRenderer, error) {
+	if name == "" {
+		return pr, nil
+	}
+	named, err := postrender.New(name, args...)
+	if err != nil {
+		return nil, err
+	}
+	if pr == nil {
+		return named, nil
+	}
+	return postrender.Chain{pr, named}, nil
+}
+
 // renderResources renders the templates in a chart
 //
 // TODO: This function is badly in need of a refactor.
 // TODO: As part of the refactor the duplicate code in cmd/helm/template.go should be removed
 //
 //	This code has to do with writing files to disk.
-func (cfg *Configuration) renderResources(ch *chart.Chart, values chartutil.Values, releaseName, outputDir string, subNotes, useReleaseName, includeCrds bool, pr postrender.PostRenderer, interactWithRemote, enableDNS, hideSecret bool) ([]*release.Hook, *bytes.Buffer, string, error) {
+func (cfg *Configuration) renderResources(ch *chart.Chart, values chartutil.Values, releaseName, outputDir string, subNotes, useReleaseName, includeCrds bool, pr postrender.PostRenderer, rh []renderhook.Hook, rc renderhook.Context, interactWithRemote, enableDNS, hideSecret bool) ([]*release.Hook, *bytes.Buffer, string, error) {
 	hs := []*release.Hook{}
 	b := bytes.NewBuffer(nil)
 
@@ -181,6 +225,10 @@ func (cfg *Configuration) renderResources(ch *chart.Chart, values chartutil.Valu
 		return hs, b, "", err
 	}
 
+	if err := runRenderHooks(rh, rc, hs, manifests); err != nil {
+		return hs, b, "", err
+	}
+
 	// Aggregate all valid manifests into one big doc.
 	fileWritten := make(map[string]bool)
 
@@ -223,7 +271,13 @@ func (cfg *Configuration) renderResources(ch *chart.Chart, values chartutil.Valu
 	}
 
 	if pr != nil {
-		b, err = pr.Run(b)
+		b, err = postrender.Run(pr, b, postrender.Metadata{
+			ReleaseName:  rc.ReleaseName,
+			Namespace:    rc.Namespace,
+			Revision:     rc.Revision,
+			Chart:        rc.Chart,
+			ChartVersion: rc.ChartVersion,
+		})
 		if err != nil {
 			return hs, b, notes, errors.Wrap(err, "error while running post render on files")
 		}
@@ -384,15 +438,38 @@ func (cfg *Configuration) Init(getter genericclioptions.RESTClientGetter, namesp
 	case "secret", "secrets", "":
 		d := driver.NewSecrets(newSecretClient(lazyClient))
 		d.Log = log
+		d.Compression = cfg.ReleaseCompression
+		d.Encryptor = cfg.ReleaseEncryptor
 		store = storage.Init(d)
 	case "configmap", "configmaps":
 		d := driver.NewConfigMaps(newConfigMapClient(lazyClient))
 		d.Log = log
+		d.Compression = cfg.ReleaseCompression
+		d.Encryptor = cfg.ReleaseEncryptor
+		store = storage.Init(d)
+	case "crd", "crds":
+		d := driver.NewReleaseRecords(newReleaseRecordClient(kc.Factory.DynamicClient, namespace))
+		d.Log = log
+		d.Compression = cfg.ReleaseCompression
+		d.Encryptor = cfg.ReleaseEncryptor
+		store = storage.Init(d)
+	case "objectstore":
+		d, err := objectStorageFromEnv(namespace)
+		if err != nil {
+			return errors.Wrap(err, "unable to instantiate object storage driver")
+		}
+		d.Log = log
+		d.Compression = cfg.ReleaseCompression
+		d.Encryptor = cfg.ReleaseEncryptor
 		store = storage.Init(d)
 	case "memory":
 		var d *driver.Memory
 		if cfg.Releases != nil {
-			if mem, ok := cfg.Releases.Driver.(*driver.Memory); ok {
+			current := cfg.Releases.Driver
+			if i, ok := current.(*driver.Instrumented); ok {
+				current = i.Unwrap()
+			}
+			if mem, ok := current.(*driver.Memory); ok {
 				// This function can be called more than once (e.g., helm list --all-namespaces).
 				// If a memory driver was already initialized, re-use it but set the possibly new namespace.
 				// We re-use it in case some releases where already created in the existing memory driver.
@@ -405,19 +482,37 @@ func (cfg *Configuration) Init(getter genericclioptions.RESTClientGetter, namesp
 		d.SetNamespace(namespace)
 		store = storage.Init(d)
 	case "sql":
-		d, err := driver.NewSQL(
-			os.Getenv("HELM_DRIVER_SQL_CONNECTION_STRING"),
-			log,
-			namespace,
-		)
+		opts, err := sqlOptionsFromEnv()
+		if err != nil {
+			return errors.Wrap(err, "unable to instantiate SQL driver")
+		}
+		d, err := driver.NewSQLWithOptions(opts, log, namespace)
 		if err != nil {
 			return errors.Wrap(err, "unable to instantiate SQL driver")
 		}
+		d.Compression = cfg.ReleaseCompression
+		d.Encryptor = cfg.ReleaseEncryptor
+		store = storage.Init(d)
+	case "sqlite":
+		d, err := driver.NewSQLite(sqlitePathFromEnv(), log, namespace)
+		if err != nil {
+			return errors.Wrap(err, "unable to instantiate SQLite driver")
+		}
+		d.Compression = cfg.ReleaseCompression
+		d.Encryptor = cfg.ReleaseEncryptor
 		store = storage.Init(d)
 	default:
 		return errors.Errorf("unknown driver %q", helmDriver)
 	}
 
+	if opts := cfg.StorageInstrumentation; opts.TracerProvider != nil || opts.Registerer != nil {
+		d, err := driver.NewInstrumented(store.Driver, opts)
+		if err != nil {
+			return err
+		}
+		store.Driver = d
+	}
+
 	cfg.RESTClientGetter = getter
 	cfg.KubeClient = kc
 	cfg.Releases = store
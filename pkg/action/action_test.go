@@ -19,8 +19,10 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"path/filepath"
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	fakeclientset "k8s.io/client-go/kubernetes/fake"
 
@@ -343,6 +345,29 @@ func TestConfiguration_Init(t *testing.T) {
 	}
 }
 
+func TestConfiguration_InitInstrumentation(t *testing.T) {
+	cfg := &Configuration{
+		StorageInstrumentation: driver.InstrumentOptions{Registerer: prometheus.NewRegistry()},
+	}
+	assert.NoError(t, cfg.Init(nil, "default", "memory", nil))
+	assert.IsType(t, &driver.Instrumented{}, cfg.Releases.Driver)
+
+	// the memory driver is reused when the configuration is initialized again
+	mem := cfg.Releases.Driver.(*driver.Instrumented).Unwrap()
+	assert.NoError(t, cfg.Init(nil, "other", "memory", nil))
+	assert.Same(t, mem, cfg.Releases.Driver.(*driver.Instrumented).Unwrap())
+}
+
+func TestConfiguration_InitSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "releases.db")
+	t.Setenv("HELM_DRIVER_SQLITE_PATH", path)
+
+	cfg := &Configuration{}
+	assert.NoError(t, cfg.Init(nil, "default", "sqlite", func(_ string, _ ...interface{}) {}))
+	assert.IsType(t, &driver.SQL{}, cfg.Releases.Driver)
+	assert.FileExists(t, path)
+}
+
 func TestGetVersionSet(t *testing.T) {
 	client := fakeclientset.NewSimpleClientset()
 
@@ -0,0 +1,253 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/util/jsonpath"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/releaseutil"
+)
+
+// BundleExport is the action for exporting a chart and its OCI dependencies
+// to an air-gap bundle.
+//
+// It provides the implementation of 'helm chart bundle export'. The bundle
+// holds the charts as they are stored in the registry, and BundleImport
+// pushes them into another registry with the same digests.
+type BundleExport struct {
+	cfg *Configuration
+
+	// Version is the version, or the version constraint, of the chart. The
+	// latest version is used if it is empty.
+	Version string
+	// Images lists the container images used by the charts in the bundle.
+	// The images are found in the templates of every chart rendered with
+	// its default values; their content is not exported.
+	Images bool
+}
+
+// NewBundleExport creates a new BundleExport object with the given configuration.
+func NewBundleExport(cfg *Configuration) *BundleExport {
+	return &BundleExport{
+		cfg: cfg,
+	}
+}
+
+// Run writes the bundle of the chart at the oci:// reference chartRef and of
+// its OCI dependencies, recursively, to out.
+func (b *BundleExport) Run(chartRef string, out io.Writer) (*registry.Bundle, error) {
+	if !registry.IsOCI(chartRef) {
+		return nil, errors.Errorf("only oci:// charts can be bundled: %s", chartRef)
+	}
+	if b.cfg.RegistryClient == nil {
+		return nil, errors.New("missing registry client")
+	}
+	ref, err := tagReference(b.cfg.RegistryClient, chartRef, b.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []string
+	var charts []*chart.Chart
+	seen := map[string]bool{ref: true}
+	for queue := []string{ref}; len(queue) > 0; queue = queue[1:] {
+		ref := queue[0]
+		b.cfg.Log("bundling %s", ref)
+		ch, err := b.pullChart(ref)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+		charts = append(charts, ch)
+
+		deps, err := b.dependencies(ch)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to resolve the dependencies of %s", ref)
+		}
+		for _, dep := range deps {
+			if !seen[dep] {
+				seen[dep] = true
+				queue = append(queue, dep)
+			}
+		}
+	}
+
+	var opts []registry.BundleOption
+	if b.Images {
+		images, err := chartImages(charts)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, registry.BundleOptImages(images))
+	}
+	return b.cfg.RegistryClient.ExportBundle(out, refs, opts...)
+}
+
+func (b *BundleExport) pullChart(ref string) (*chart.Chart, error) {
+	result, err := b.cfg.RegistryClient.Pull(ref, registry.PullOptWithChart(true))
+	if err != nil {
+		return nil, err
+	}
+	return loader.LoadArchive(bytes.NewReader(result.Chart.Data))
+}
+
+// dependencies returns the references of the OCI dependencies of ch, at the
+// versions of its Chart.lock if it has one.
+func (b *BundleExport) dependencies(ch *chart.Chart) ([]string, error) {
+	deps := ch.Metadata.Dependencies
+	if ch.Lock != nil {
+		deps = ch.Lock.Dependencies
+	}
+
+	var refs []string
+	for _, dep := range deps {
+		if !registry.IsOCI(dep.Repository) {
+			continue
+		}
+		ref, err := tagReference(b.cfg.RegistryClient, fmt.Sprintf("%s/%s", strings.TrimSuffix(dep.Repository, "/"), dep.Name), dep.Version)
+		if err != nil {
+			return nil, errors.Wrapf(err, "dependency %s", dep.Name)
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
+// chartImages returns the container images of the templates of the charts,
+// each rendered with its default values.
+func chartImages(charts []*chart.Chart) ([]string, error) {
+	found := map[string]bool{}
+	for _, ch := range charts {
+		vals, err := chartutil.ToRenderValues(ch, map[string]interface{}{}, chartutil.ReleaseOptions{
+			Name:      ch.Name(),
+			Namespace: "default",
+			IsInstall: true,
+		}, chartutil.DefaultCapabilities)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to render chart %s", ch.Name())
+		}
+		files, err := engine.Render(ch, vals)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to render chart %s", ch.Name())
+		}
+		collectManifestImages(files, nil, found)
+	}
+
+	images := make([]string, 0, len(found))
+	for image := range found {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+	return images, nil
+}
+
+// collectManifestImages adds the images of the containers found in the
+// rendered manifests to found, with the strings found at the paths.
+func collectManifestImages(files map[string]string, paths []*jsonpath.JSONPath, found map[string]bool) {
+	for name, content := range files {
+		if path.Ext(name) != ".yaml" && path.Ext(name) != ".yml" {
+			continue
+		}
+		for _, manifest := range releaseutil.SplitManifests(content) {
+			var obj interface{}
+			if err := yaml.Unmarshal([]byte(manifest), &obj); err != nil {
+				continue
+			}
+			collectImages(obj, found)
+			for _, p := range paths {
+				results, err := p.FindResults(obj)
+				if err != nil {
+					continue
+				}
+				for _, values := range results {
+					for _, v := range values {
+						if image, ok := v.Interface().(string); ok && image != "" {
+							found[image] = true
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// collectImages adds the images of the containers found in obj to found.
+func collectImages(obj interface{}, found map[string]bool) {
+	switch v := obj.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			switch key {
+			case "containers", "initContainers", "ephemeralContainers":
+				if containers, ok := value.([]interface{}); ok {
+					for _, c := range containers {
+						if c, ok := c.(map[string]interface{}); ok {
+							if image, ok := c["image"].(string); ok && image != "" {
+								found[image] = true
+							}
+						}
+					}
+				}
+			}
+			collectImages(value, found)
+		}
+	case []interface{}:
+		for _, value := range v {
+			collectImages(value, found)
+		}
+	}
+}
+
+// BundleImport is the action for importing an air-gap bundle into a
+// registry.
+//
+// It provides the implementation of 'helm chart bundle import'.
+type BundleImport struct {
+	cfg *Configuration
+}
+
+// NewBundleImport creates a new BundleImport object with the given configuration.
+func NewBundleImport(cfg *Configuration) *BundleImport {
+	return &BundleImport{
+		cfg: cfg,
+	}
+}
+
+// Run pushes the charts of the bundle read from in under the oci:// registry
+// namespace remote, and returns the pushed charts.
+func (b *BundleImport) Run(in io.Reader, remote string) (*registry.Bundle, error) {
+	if !registry.IsOCI(remote) {
+		return nil, errors.Errorf("bundles can only be imported into oci:// registries: %s", remote)
+	}
+	if b.cfg.RegistryClient == nil {
+		return nil, errors.New("missing registry client")
+	}
+	return b.cfg.RegistryClient.ImportBundle(in, remote)
+}
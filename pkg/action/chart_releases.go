@@ -0,0 +1,140 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"sort"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+// chartReleaseStatuses are the statuses of the revisions that use their
+// chart: superseded and uninstalled revisions do not.
+var chartReleaseStatuses = []release.Status{
+	release.StatusDeployed,
+	release.StatusFailed,
+	release.StatusPendingInstall,
+	release.StatusPendingUpgrade,
+	release.StatusPendingRollback,
+	release.StatusUninstalling,
+	release.StatusUnknown,
+}
+
+// ChartReleases is the action for finding the releases of a chart.
+//
+// It answers questions such as "which releases use chart X before version Y",
+// in every namespace if the configuration is initialized for all namespaces.
+// The revisions are selected by the name of their chart in the storage
+// backend, and only their chart versions are compared by Helm.
+type ChartReleases struct {
+	cfg *Configuration
+
+	// Chart is the name of the chart.
+	Chart string
+	// Version is a semantic version constraint the version of the chart must
+	// satisfy, e.g. "< 1.2.3". Every version matches if it is empty.
+	Version string
+	// PageSize is the number of release records requested from the storage
+	// backend at a time. All records are requested at once if it is 0.
+	PageSize int64
+}
+
+// NewChartReleases creates a new ChartReleases object with the given
+// configuration.
+func NewChartReleases(cfg *Configuration) *ChartReleases {
+	return &ChartReleases{
+		cfg: cfg,
+	}
+}
+
+// Run returns the revisions of releases using the chart that are neither
+// superseded nor uninstalled, sorted by namespace, name and revision.
+func (c *ChartReleases) Run() ([]*release.Release, error) {
+	if err := c.cfg.KubeClient.IsReachable(); err != nil {
+		return nil, err
+	}
+	if c.Chart == "" {
+		return nil, errors.New("no chart name given")
+	}
+
+	var constraint *semver.Constraints
+	if c.Version != "" {
+		var err error
+		if constraint, err = semver.NewConstraint(c.Version); err != nil {
+			return nil, errors.Wrapf(err, "invalid version constraint %q", c.Version)
+		}
+	}
+
+	match := func(rel *release.Release) bool {
+		if rel.Chart == nil || rel.Chart.Metadata == nil || rel.Chart.Metadata.Name != c.Chart {
+			return false
+		}
+		if constraint == nil {
+			return true
+		}
+		v, err := semver.NewVersion(rel.Chart.Metadata.Version)
+		if err != nil {
+			c.cfg.Log("skipping release %s: invalid chart version %q", rel.Name, rel.Chart.Metadata.Version)
+			return false
+		}
+		return constraint.Check(v)
+	}
+
+	// The chart name label is not set on the revisions stored by older
+	// versions of Helm, nor if the chart name is not a valid label value, so
+	// these revisions are selected as well and matched by Helm.
+	var selectors []labels.Selector
+	if req, err := labels.NewRequirement(driver.ChartNameLabel, selection.Equals, []string{c.Chart}); err == nil {
+		selectors = append(selectors, labels.NewSelector().Add(*req))
+	}
+	unlabeled, err := labels.NewRequirement(driver.ChartNameLabel, selection.DoesNotExist, nil)
+	if err != nil {
+		return nil, err
+	}
+	selectors = append(selectors, labels.NewSelector().Add(*unlabeled))
+
+	var results []*release.Release
+	for _, selector := range selectors {
+		rels, err := listPages(c.cfg.Releases, driver.ListOptions{
+			Selector: selector,
+			Statuses: chartReleaseStatuses,
+			Limit:    c.PageSize,
+		}, match)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, rels...)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Version < b.Version
+	})
+	return results, nil
+}
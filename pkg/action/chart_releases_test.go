@@ -0,0 +1,109 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+func chartReleaseMock(namespace, name string, version int, status release.Status, chart, chartVersion string) *release.Release {
+	rel := release.Mock(&release.MockReleaseOptions{Name: name, Namespace: namespace, Version: version, Status: status})
+	rel.Chart.Metadata.Name = chart
+	rel.Chart.Metadata.Version = chartVersion
+	return rel
+}
+
+func chartReleaseNames(rels []*release.Release) []string {
+	var names []string
+	for _, rel := range rels {
+		names = append(names, fmt.Sprintf("%s/%s.v%d", rel.Namespace, rel.Name, rel.Version))
+	}
+	return names
+}
+
+func TestChartReleases(t *testing.T) {
+	is := assert.New(t)
+
+	config := actionConfigFixture(t)
+	mem := driver.NewMemory()
+	config.Releases = storage.Init(mem)
+	for _, rel := range []*release.Release{
+		chartReleaseMock("default", "ingress", 1, release.StatusSuperseded, "nginx", "1.0.0"),
+		chartReleaseMock("default", "ingress", 2, release.StatusDeployed, "nginx", "1.1.0"),
+		chartReleaseMock("web", "frontend", 1, release.StatusFailed, "nginx", "2.0.0"),
+		chartReleaseMock("web", "proxy", 1, release.StatusDeployed, "nginx", "1.0.0"),
+		chartReleaseMock("web", "gone", 1, release.StatusUninstalled, "nginx", "1.0.0"),
+		chartReleaseMock("web", "db", 1, release.StatusDeployed, "postgres", "1.0.0"),
+	} {
+		is.NoError(config.Releases.Create(rel))
+	}
+	// query all namespaces
+	mem.SetNamespace("")
+
+	query := NewChartReleases(config)
+	query.Chart = "nginx"
+	rels, err := query.Run()
+	is.NoError(err)
+	is.Equal([]string{"default/ingress.v2", "web/frontend.v1", "web/proxy.v1"}, chartReleaseNames(rels))
+
+	query.Version = "< 2.0.0"
+	query.PageSize = 1
+	rels, err = query.Run()
+	is.NoError(err)
+	is.Equal([]string{"default/ingress.v2", "web/proxy.v1"}, chartReleaseNames(rels))
+
+	query.Version = "invalid"
+	_, err = query.Run()
+	is.Error(err)
+
+	query.Chart = ""
+	_, err = query.Run()
+	is.Error(err)
+}
+
+func TestChartReleasesUnlabeled(t *testing.T) {
+	is := assert.New(t)
+
+	secrets := fake.NewSimpleClientset().CoreV1().Secrets("default")
+	config := actionConfigFixture(t)
+	config.Releases = storage.Init(driver.NewSecrets(secrets))
+	is.NoError(config.Releases.Create(chartReleaseMock("default", "labeled", 1, release.StatusDeployed, "nginx", "1.0.0")))
+	is.NoError(config.Releases.Create(chartReleaseMock("default", "unlabeled", 1, release.StatusDeployed, "nginx", "1.0.0")))
+
+	// releases stored by older versions of Helm have no chart name label
+	obj, err := secrets.Get(context.Background(), "sh.helm.release.v1.unlabeled.v1", metav1.GetOptions{})
+	is.NoError(err)
+	delete(obj.Labels, driver.ChartNameLabel)
+	_, err = secrets.Update(context.Background(), obj, metav1.UpdateOptions{})
+	is.NoError(err)
+
+	query := NewChartReleases(config)
+	query.Chart = "nginx"
+	rels, err := query.Run()
+	is.NoError(err)
+	is.Equal([]string{"default/labeled.v1", "default/unlabeled.v1"}, chartReleaseNames(rels))
+}
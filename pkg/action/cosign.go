@@ -0,0 +1,75 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"os"
+
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+// CosignBundleExt is the extension of the cosign bundles holding the
+// sigstore signatures of chart archives.
+const CosignBundleExt = registry.SignatureBundleExt
+
+// CosignSignOptions configures the sigstore signatures made by 'helm package'
+// and 'helm push'.
+type CosignSignOptions struct {
+	// Key is the path of the private key signing charts. Charts are signed
+	// keyless if it is empty.
+	Key string
+	// PassphraseFile is the path of the file holding the password of the key,
+	// or "-" for stdin. The password is read from $COSIGN_PASSWORD otherwise.
+	PassphraseFile string
+	// IdentityToken is the OIDC identity token of keyless signatures. It is
+	// read from $SIGSTORE_ID_TOKEN otherwise.
+	IdentityToken string
+	// FulcioURL is the URL of the certificate authority of keyless signatures.
+	FulcioURL string
+	// RekorURL is the URL of the transparency log of keyless signatures.
+	RekorURL string
+}
+
+// Signer returns the signer making the signatures.
+func (o *CosignSignOptions) Signer() (*registry.SignatureSigner, error) {
+	if o.Key == "" {
+		var opts []registry.SignerOption
+		if o.FulcioURL != "" {
+			opts = append(opts, registry.SignerOptFulcioURL(o.FulcioURL))
+		}
+		if o.RekorURL != "" {
+			opts = append(opts, registry.SignerOptRekorURL(o.RekorURL))
+		}
+		token := o.IdentityToken
+		if token == "" {
+			token = os.Getenv("SIGSTORE_ID_TOKEN")
+		}
+		return registry.NewKeylessSigner(token, opts...)
+	}
+
+	password := []byte(os.Getenv("COSIGN_PASSWORD"))
+	if o.PassphraseFile != "" {
+		fetcher, err := passphraseFileFetcher(o.PassphraseFile, os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		if password, err = fetcher(o.Key); err != nil {
+			return nil, err
+		}
+	}
+	return registry.NewKeySigner(o.Key, password)
+}
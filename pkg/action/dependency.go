@@ -38,6 +38,14 @@ type Dependency struct {
 	Keyring     string
 	SkipRefresh bool
 	ColumnWidth uint
+	// Concurrency is the maximum number of dependencies downloaded
+	// concurrently.
+	Concurrency int
+	// Vendored builds the dependencies from the vendor directory of the chart.
+	Vendored bool
+	// ResolutionPolicy chooses among the repositories serving the charts of
+	// the dependencies served by any repository.
+	ResolutionPolicy string
 }
 
 // NewDependency creates a new Dependency object with the given configuration.
@@ -65,6 +73,36 @@ func (d *Dependency) List(chartpath string, out io.Writer) error {
 	return nil
 }
 
+// DependencyStatus is a dependency of a chart, as listed by 'helm dependency list'.
+type DependencyStatus struct {
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	Repository string `json:"repository"`
+	// Status describes the state of the dependency in the charts/ directory,
+	// like "ok" or "missing".
+	Status string `json:"status"`
+}
+
+// Statuses returns the dependencies of the chart at chartpath with their
+// status, in the order of Chart.yaml.
+func (d *Dependency) Statuses(chartpath string) ([]DependencyStatus, error) {
+	c, err := loader.Load(chartpath)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []DependencyStatus{}
+	for _, row := range c.Metadata.Dependencies {
+		statuses = append(statuses, DependencyStatus{
+			Name:       row.Name,
+			Version:    row.Version,
+			Repository: row.Repository,
+			Status:     d.dependencyStatus(chartpath, row, c),
+		})
+	}
+	return statuses, nil
+}
+
 // dependencyStatus returns a string describing the status of a dependency viz a viz the parent chart.
 func (d *Dependency) dependencyStatus(chartpath string, dep *chart.Dependency, parent *chart.Chart) string {
 	filename := fmt.Sprintf("%s-%s.tgz", dep.Name, "*")
@@ -150,3 +150,82 @@ func TestStatArchiveForStatus(t *testing.T) {
 	}
 	is.Equal("ok", statArchiveForStatus(where, dep))
 }
+
+func TestDependencyTree(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("Chart.yaml", `apiVersion: v2
+name: app
+version: 1.0.0
+dependencies:
+- name: db
+  alias: database
+  version: ~2.0.0
+  repository: oci://registry.example.com/charts
+  condition: database.enabled
+  tags: [backend]
+- name: cache
+  version: 3.0.0
+  repository: https://example.com/charts
+`)
+	writeFile("charts/db/Chart.yaml", `apiVersion: v2
+name: db
+version: 2.0.1
+dependencies:
+- name: metrics
+  version: 0.1.0
+  repository: file://../metrics
+`)
+	writeFile("charts/db/charts/metrics/Chart.yaml", "apiVersion: v2\nname: metrics\nversion: 0.1.0\n")
+	writeFile("charts/extra/Chart.yaml", "apiVersion: v2\nname: extra\nversion: 0.3.0\n")
+
+	tree, err := NewDependency().Tree(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := &DependencyNode{
+		Name:    "app",
+		Version: "1.0.0",
+		Dependencies: []*DependencyNode{{
+			Name:       "db",
+			Version:    "2.0.1",
+			Constraint: "~2.0.0",
+			Alias:      "database",
+			Repository: "oci://registry.example.com/charts",
+			Condition:  "database.enabled",
+			Tags:       []string{"backend"},
+			ValuesPath: "database",
+			Dependencies: []*DependencyNode{{
+				Name:       "metrics",
+				Version:    "0.1.0",
+				Constraint: "0.1.0",
+				Repository: "file://../metrics",
+				ValuesPath: "database.metrics",
+			}},
+		}, {
+			Name:       "cache",
+			Constraint: "3.0.0",
+			Repository: "https://example.com/charts",
+			ValuesPath: "cache",
+			Missing:    true,
+		}, {
+			Name:       "extra",
+			Version:    "0.3.0",
+			ValuesPath: "extra",
+		}},
+	}
+	assert.Equal(t, expect, tree)
+
+	if _, err := NewDependency().Tree(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected a missing chart to fail")
+	}
+}
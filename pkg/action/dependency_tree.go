@@ -0,0 +1,128 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+)
+
+// DependencyNode is a chart of the dependency tree of a chart.
+type DependencyNode struct {
+	// Name is the name of the chart.
+	Name string `json:"name"`
+	// Version is the version of the chart, or empty if it is missing from
+	// the charts directory of its parent.
+	Version string `json:"version,omitempty"`
+	// Constraint is the version constraint of the dependency in the parent
+	// chart.
+	Constraint string `json:"constraint,omitempty"`
+	// Alias is the alias of the dependency in the parent chart.
+	Alias string `json:"alias,omitempty"`
+	// Repository is the repository, OCI reference or path the dependency is
+	// fetched from. It is empty for the charts found in the charts directory
+	// of the parent chart without being declared in it.
+	Repository string `json:"repository,omitempty"`
+	// Condition is the condition enabling the dependency.
+	Condition string `json:"condition,omitempty"`
+	// Tags are the tags enabling the dependency.
+	Tags []string `json:"tags,omitempty"`
+	// ValuesPath is the path of the values of the chart in the values of
+	// the root chart, like "mysql.metrics". It is empty for the root chart.
+	ValuesPath string `json:"valuesPath,omitempty"`
+	// Missing is true if the dependency is not found in the charts directory
+	// of the parent chart, so that its own dependencies are unknown.
+	Missing bool `json:"missing,omitempty"`
+	// Dependencies are the dependencies of the chart.
+	Dependencies []*DependencyNode `json:"dependencies,omitempty"`
+}
+
+// Tree executes 'helm dependency tree'.
+//
+// It returns the dependency tree of the chart at chartpath, made of the
+// dependencies declared in the charts in order followed by the charts found
+// in their charts directories without being declared.
+func (d *Dependency) Tree(chartpath string) (*DependencyNode, error) {
+	c, err := loader.Load(chartpath)
+	if err != nil {
+		return nil, err
+	}
+	root := &DependencyNode{
+		Name:    c.Name(),
+		Version: c.Metadata.Version,
+	}
+	root.Dependencies = dependencyNodes(c, "")
+	return root, nil
+}
+
+// dependencyNodes returns the nodes of the dependencies of the chart, whose
+// values are at valuesPath.
+func dependencyNodes(c *chart.Chart, valuesPath string) []*DependencyNode {
+	var nodes []*DependencyNode
+	declared := map[string]bool{}
+	for _, dep := range c.Metadata.Dependencies {
+		declared[dep.Name] = true
+		node := &DependencyNode{
+			Name:       dep.Name,
+			Constraint: dep.Version,
+			Alias:      dep.Alias,
+			Repository: dep.Repository,
+			Condition:  dep.Condition,
+			Tags:       dep.Tags,
+		}
+		key := dep.Name
+		if dep.Alias != "" {
+			key = dep.Alias
+		}
+		node.ValuesPath = joinValuesPath(valuesPath, key)
+
+		var sub *chart.Chart
+		for _, item := range c.Dependencies() {
+			if item.Name() == dep.Name {
+				sub = item
+			}
+		}
+		if sub == nil {
+			node.Missing = true
+		} else {
+			node.Version = sub.Metadata.Version
+			node.Dependencies = dependencyNodes(sub, node.ValuesPath)
+		}
+		nodes = append(nodes, node)
+	}
+
+	for _, sub := range c.Dependencies() {
+		if declared[sub.Name()] {
+			continue
+		}
+		node := &DependencyNode{
+			Name:       sub.Name(),
+			Version:    sub.Metadata.Version,
+			ValuesPath: joinValuesPath(valuesPath, sub.Name()),
+		}
+		node.Dependencies = dependencyNodes(sub, node.ValuesPath)
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+func joinValuesPath(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
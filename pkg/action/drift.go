@@ -0,0 +1,101 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"bytes"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/kube"
+)
+
+// DriftReport lists the resources of a release whose live state no longer
+// matches the release manifest.
+type DriftReport struct {
+	Release   string `json:"release"`
+	Namespace string `json:"namespace"`
+	Revision  int    `json:"revision"`
+	// Resources holds the drifted resources. A resource with the
+	// kube.DiffActionCreate action is missing from the cluster. The values
+	// of the data of the Secrets are redacted.
+	Resources []kube.ResourceDiff `json:"resources"`
+}
+
+// Drifted reports whether any resource has drifted.
+func (r *DriftReport) Drifted() bool {
+	return len(r.Resources) > 0
+}
+
+// Drift is the action for detecting drift between a release and the cluster.
+//
+// It compares every resource of the stored release manifest against the live
+// object, normalizing server-side defaults with a dry-run apply, so that
+// controllers can poll it to find out-of-band changes.
+type Drift struct {
+	cfg *Configuration
+
+	// Version is the revision to compare against. Defaults to the latest.
+	Version int
+}
+
+// NewDrift creates a new Drift object with the given configuration.
+func NewDrift(cfg *Configuration) *Drift {
+	return &Drift{
+		cfg: cfg,
+	}
+}
+
+// Run detects the drift of the named release.
+func (d *Drift) Run(name string) (*DriftReport, error) {
+	if err := d.cfg.KubeClient.IsReachable(); err != nil {
+		return nil, err
+	}
+
+	kubeClient, ok := d.cfg.KubeClient.(kube.InterfaceDiff)
+	if !ok {
+		return nil, errors.New("unable to get kubeClient with interface InterfaceDiff")
+	}
+
+	rel, err := d.cfg.releaseContent(name, d.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	resources, err := d.cfg.KubeClient.Build(bytes.NewBufferString(rel.Manifest), false)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to build kubernetes objects from release manifest")
+	}
+
+	diffs, err := kubeClient.GetDiff(resources)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &DriftReport{
+		Release:   rel.Name,
+		Namespace: rel.Namespace,
+		Revision:  rel.Version,
+		Resources: []kube.ResourceDiff{},
+	}
+	for _, diff := range diffs {
+		if diff.Changed() {
+			report.Resources = append(report.Resources, diff.RedactSecrets())
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,80 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"helm.sh/helm/v3/pkg/kube"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+)
+
+func TestDrift(t *testing.T) {
+	cfg := actionConfigFixture(t)
+	rel := releaseStub()
+	require.NoError(t, cfg.Releases.Create(rel))
+
+	failer := cfg.KubeClient.(*kubefake.FailingKubeClient)
+	failer.Diffs = []kube.ResourceDiff{
+		{Kind: "ConfigMap", Name: "unchanged", Action: kube.DiffActionNone},
+		{Kind: "ConfigMap", Name: "deleted", Action: kube.DiffActionCreate},
+		{
+			Kind:   "Deployment",
+			Name:   "scaled",
+			Action: kube.DiffActionUpdate,
+			Fields: []kube.FieldDiff{{Path: "spec.replicas", Live: int64(5), Desired: int64(3)}},
+		},
+	}
+
+	report, err := NewDrift(cfg).Run(rel.Name)
+	require.NoError(t, err)
+	assert.True(t, report.Drifted())
+	assert.Equal(t, rel.Name, report.Release)
+	assert.Equal(t, rel.Version, report.Revision)
+	require.Len(t, report.Resources, 2)
+	assert.Equal(t, "deleted", report.Resources[0].Name)
+	assert.Equal(t, "scaled", report.Resources[1].Name)
+
+	// The data of the Secrets are redacted
+	failer.Diffs = []kube.ResourceDiff{{
+		APIVersion: "v1",
+		Kind:       "Secret",
+		Name:       "credentials",
+		Action:     kube.DiffActionUpdate,
+		Fields:     []kube.FieldDiff{{Path: "data.password", Live: "b2xk", Desired: "bmV3"}},
+	}}
+	report, err = NewDrift(cfg).Run(rel.Name)
+	require.NoError(t, err)
+	require.Len(t, report.Resources, 1)
+	assert.Equal(t, []kube.FieldDiff{{Path: "data.password", Live: kube.RedactedValue, Desired: kube.RedactedValue}}, report.Resources[0].Fields)
+
+	failer.Diffs = []kube.ResourceDiff{{Kind: "ConfigMap", Name: "unchanged", Action: kube.DiffActionNone}}
+	report, err = NewDrift(cfg).Run(rel.Name)
+	require.NoError(t, err)
+	assert.False(t, report.Drifted())
+
+	failer.GetDiffError = errors.New("dry-run rejected")
+	_, err = NewDrift(cfg).Run(rel.Name)
+	assert.EqualError(t, err, "dry-run rejected")
+
+	_, err = NewDrift(cfg).Run("missing")
+	assert.Error(t, err)
+}
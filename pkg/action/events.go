@@ -0,0 +1,97 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+
+	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+// Reasons of the Events recorded when Configuration.RecordEvents is set.
+const (
+	EventReasonInstallStarted     = "InstallStarted"
+	EventReasonInstallSucceeded   = "InstallSucceeded"
+	EventReasonInstallFailed      = "InstallFailed"
+	EventReasonUpgradeStarted     = "UpgradeStarted"
+	EventReasonUpgradeSucceeded   = "UpgradeSucceeded"
+	EventReasonUpgradeFailed      = "UpgradeFailed"
+	EventReasonRollbackStarted    = "RollbackStarted"
+	EventReasonRollbackSucceeded  = "RollbackSucceeded"
+	EventReasonRollbackFailed     = "RollbackFailed"
+	EventReasonUninstallStarted   = "UninstallStarted"
+	EventReasonUninstallSucceeded = "UninstallSucceeded"
+	EventReasonUninstallFailed    = "UninstallFailed"
+)
+
+// recordEvent records a lifecycle Event for rel. A non-nil err produces a
+// Warning Event that includes the error. Failing to record an Event never
+// fails the operation itself.
+func (cfg *Configuration) recordEvent(rel *release.Release, reason, message string, err error) {
+	if !cfg.RecordEvents {
+		return
+	}
+	ec, ok := cfg.KubeClient.(kube.InterfaceEvents)
+	if !ok {
+		return
+	}
+	ref := cfg.eventTarget(rel)
+	if ref == nil {
+		cfg.Log("not recording event %s for release %s: no event target for the %s storage driver", reason, rel.Name, cfg.Releases.Name())
+		return
+	}
+
+	eventType := v1.EventTypeNormal
+	if err != nil {
+		eventType = v1.EventTypeWarning
+		message = fmt.Sprintf("%s: %s", message, err)
+	}
+	if err := ec.RecordEvent(ref, eventType, reason, message); err != nil {
+		cfg.Log("warning: %s", err)
+	}
+}
+
+// eventTarget returns the object Events about rel are recorded on.
+func (cfg *Configuration) eventTarget(rel *release.Release) *v1.ObjectReference {
+	if cfg.EventTarget != nil {
+		ref := cfg.EventTarget.DeepCopy()
+		if ref.Namespace == "" {
+			ref.Namespace = rel.Namespace
+		}
+		return ref
+	}
+
+	ref := &v1.ObjectReference{
+		APIVersion: "v1",
+		Namespace:  rel.Namespace,
+		Name:       fmt.Sprintf("%s.%s.v%d", storage.HelmStorageType, rel.Name, rel.Version),
+	}
+	switch cfg.Releases.Name() {
+	case driver.SecretsDriverName:
+		ref.Kind = "Secret"
+	case driver.ConfigMapsDriverName:
+		ref.Kind = "ConfigMap"
+	default:
+		return nil
+	}
+	return ref
+}
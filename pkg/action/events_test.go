@@ -0,0 +1,100 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+type eventRecordingKubeClient struct {
+	kubefake.FailingKubeClient
+	events []string
+}
+
+func (c *eventRecordingKubeClient) RecordEvent(ref *v1.ObjectReference, eventType, reason, message string) error {
+	c.events = append(c.events, fmt.Sprintf("%s %s/%s %s: %s", eventType, ref.Kind, ref.Name, reason, message))
+	return nil
+}
+
+func TestInstallRecordsEvents(t *testing.T) {
+	instAction := installAction(t)
+	client := &eventRecordingKubeClient{FailingKubeClient: kubefake.FailingKubeClient{PrintingKubeClient: kubefake.PrintingKubeClient{Out: io.Discard}}}
+	instAction.cfg.KubeClient = client
+	instAction.cfg.RecordEvents = true
+	instAction.cfg.EventTarget = &v1.ObjectReference{Kind: "ConfigMap", Name: "helm-events"}
+
+	_, err := instAction.Run(buildChart(), map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"Normal ConfigMap/helm-events InstallStarted: Installing release test-install-release revision 1",
+		"Normal ConfigMap/helm-events InstallSucceeded: Installed release test-install-release revision 1",
+	}, client.events)
+
+	client.events = nil
+	client.WaitError = fmt.Errorf("timed out")
+	instAction.Wait = true
+	instAction.ReleaseName = "failing-release"
+	_, err = instAction.Run(buildChart(), map[string]interface{}{})
+	require.Error(t, err)
+	assert.Equal(t, []string{
+		"Normal ConfigMap/helm-events InstallStarted: Installing release failing-release revision 1",
+		"Warning ConfigMap/helm-events InstallFailed: Failed to install release failing-release revision 1: timed out",
+	}, client.events)
+}
+
+func TestRecordEventsDisabled(t *testing.T) {
+	instAction := installAction(t)
+	client := &eventRecordingKubeClient{FailingKubeClient: kubefake.FailingKubeClient{PrintingKubeClient: kubefake.PrintingKubeClient{Out: io.Discard}}}
+	instAction.cfg.KubeClient = client
+	instAction.cfg.EventTarget = &v1.ObjectReference{Kind: "ConfigMap", Name: "helm-events"}
+
+	_, err := instAction.Run(buildChart(), map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Empty(t, client.events)
+}
+
+func TestEventTarget(t *testing.T) {
+	cfg := actionConfigFixture(t)
+	rel := &release.Release{Name: "foo", Namespace: "ns", Version: 3}
+
+	assert.Nil(t, cfg.eventTarget(rel), "the memory driver has no storage object")
+
+	cfg.Releases = storage.Init(driver.NewSecrets(nil))
+	assert.Equal(t, &v1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "Secret",
+		Namespace:  "ns",
+		Name:       "sh.helm.release.v1.foo.v3",
+	}, cfg.eventTarget(rel))
+
+	cfg.Releases = storage.Init(driver.NewConfigMaps(nil))
+	assert.Equal(t, "ConfigMap", cfg.eventTarget(rel).Kind)
+
+	cfg.EventTarget = &v1.ObjectReference{Kind: "Deployment", Name: "deployer", Namespace: "ops"}
+	assert.Equal(t, &v1.ObjectReference{Kind: "Deployment", Name: "deployer", Namespace: "ops"}, cfg.eventTarget(rel))
+}
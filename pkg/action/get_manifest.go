@@ -0,0 +1,143 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"path"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
+)
+
+// GetManifest is the action for getting the resources of the manifest of a
+// release.
+//
+// It provides the implementation of 'helm get manifest' with filters.
+type GetManifest struct {
+	cfg *Configuration
+
+	Version int
+	// Kinds are the kinds of the resources, like "Deployment", matched case
+	// insensitively. All the kinds are returned if empty.
+	Kinds []string
+	// Names are the patterns of the names of the resources, like "web-*".
+	// All the names are returned if empty.
+	Names []string
+}
+
+// ManifestResource is a resource of the manifest of a release.
+type ManifestResource struct {
+	// Source is the path to the template of the resource, like
+	// "mychart/templates/deployment.yaml".
+	Source     string `json:"source,omitempty"`
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace,omitempty"`
+	// Manifest is the YAML manifest of the resource.
+	Manifest string `json:"manifest"`
+}
+
+// NewGetManifest creates a new GetManifest object with the given configuration.
+func NewGetManifest(cfg *Configuration) *GetManifest {
+	return &GetManifest{
+		cfg: cfg,
+	}
+}
+
+// Run returns the resources of the manifest of the release matching the
+// kinds and the names, in the order of the manifest.
+func (g *GetManifest) Run(name string) ([]ManifestResource, error) {
+	if err := g.cfg.KubeClient.IsReachable(); err != nil {
+		return nil, err
+	}
+
+	rel, err := g.cfg.releaseContent(name, g.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []ManifestResource
+	for _, r := range releaseResources(rel) {
+		if g.matches(r) {
+			resources = append(resources, r)
+		}
+	}
+	return resources, nil
+}
+
+func (g *GetManifest) matches(r ManifestResource) bool {
+	if len(g.Kinds) > 0 {
+		found := false
+		for _, kind := range g.Kinds {
+			found = found || strings.EqualFold(kind, r.Kind)
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(g.Names) > 0 {
+		found := false
+		for _, pattern := range g.Names {
+			ok, _ := path.Match(pattern, r.Name)
+			found = found || ok
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// ManifestResources splits a release manifest into its resources, in order.
+// The documents which are not resources are skipped.
+func ManifestResources(manifest string) []ManifestResource {
+	return indexedResources(&release.Release{Manifest: manifest}, releaseutil.IndexManifest(manifest))
+}
+
+// releaseResources returns the resources of the manifest of the release, in
+// order, using its index if it has a valid one rather than splitting the
+// manifest.
+func releaseResources(rel *release.Release) []ManifestResource {
+	if rel.ManifestIndex == nil {
+		// The release was stored before the manifests were indexed
+		return ManifestResources(rel.Manifest)
+	}
+	for _, i := range rel.ManifestIndex {
+		if _, ok := rel.ResourceManifest(i); !ok {
+			return ManifestResources(rel.Manifest)
+		}
+	}
+	return indexedResources(rel, rel.ManifestIndex)
+}
+
+func indexedResources(rel *release.Release, index []release.ResourceIndex) []ManifestResource {
+	resources := make([]ManifestResource, 0, len(index))
+	for _, i := range index {
+		manifest, _ := rel.ResourceManifest(i)
+		resources = append(resources, ManifestResource{
+			Source:     i.Source,
+			APIVersion: i.APIVersion,
+			Kind:       i.Kind,
+			Name:       i.Name,
+			Namespace:  i.Namespace,
+			Manifest:   manifest + "\n",
+		})
+	}
+	return resources
+}
@@ -16,7 +16,11 @@ limitations under the License.
 
 package action
 
-import "time"
+import (
+	"time"
+
+	"helm.sh/helm/v3/pkg/release"
+)
 
 // GetMetadata is the action for checking a given release's metadata.
 //
@@ -36,6 +40,9 @@ type Metadata struct {
 	Revision   int    `json:"revision" yaml:"revision"`
 	Status     string `json:"status" yaml:"status"`
 	DeployedAt string `json:"deployedAt" yaml:"deployedAt"`
+	// ValueSources are the sources of the values of the revision, in the
+	// order they were merged.
+	ValueSources []release.ValueSource `json:"valueSources,omitempty" yaml:"valueSources,omitempty"`
 }
 
 // NewGetMetadata creates a new GetMetadata object with the given configuration.
@@ -57,13 +64,14 @@ func (g *GetMetadata) Run(name string) (*Metadata, error) {
 	}
 
 	return &Metadata{
-		Name:       rel.Name,
-		Chart:      rel.Chart.Metadata.Name,
-		Version:    rel.Chart.Metadata.Version,
-		AppVersion: rel.Chart.Metadata.AppVersion,
-		Namespace:  rel.Namespace,
-		Revision:   rel.Version,
-		Status:     rel.Info.Status.String(),
-		DeployedAt: rel.Info.LastDeployed.Format(time.RFC3339),
+		Name:         rel.Name,
+		Chart:        rel.Chart.Metadata.Name,
+		Version:      rel.Chart.Metadata.Version,
+		AppVersion:   rel.Chart.Metadata.AppVersion,
+		Namespace:    rel.Namespace,
+		Revision:     rel.Version,
+		Status:       rel.Info.Status.String(),
+		DeployedAt:   rel.Info.LastDeployed.Format(time.RFC3339),
+		ValueSources: rel.ValueSources,
 	}, nil
 }
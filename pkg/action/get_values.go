@@ -28,6 +28,9 @@ type GetValues struct {
 
 	Version   int
 	AllValues bool
+	// CompareTo is the revision the values of the revision Version are
+	// compared to by Diff.
+	CompareTo int
 }
 
 // NewGetValues creates a new GetValues object with the given configuration.
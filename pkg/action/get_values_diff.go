@@ -0,0 +1,86 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// ValuesDiff is the difference between the values of two revisions of a
+// release.
+type ValuesDiff struct {
+	Release string `json:"release"`
+	// From is the revision compared to.
+	From int `json:"from"`
+	// To is the compared revision.
+	To int `json:"to"`
+	// UserSupplied are the changes of the values supplied by the users.
+	UserSupplied []chartutil.ValueChange `json:"userSupplied"`
+	// Computed are the changes of the computed values: the values supplied
+	// by the users coalesced with the values of the charts. The changes of the
+	// values of the charts, between their versions, are only found there.
+	Computed []chartutil.ValueChange `json:"computed"`
+}
+
+// Diff returns the difference between the values of the revision CompareTo of
+// the release and the values of its revision Version, or of its last
+// revision if Version is 0.
+func (g *GetValues) Diff(name string) (*ValuesDiff, error) {
+	if err := g.cfg.KubeClient.IsReachable(); err != nil {
+		return nil, err
+	}
+
+	if g.CompareTo <= 0 {
+		return nil, errors.Errorf("invalid revision %d", g.CompareTo)
+	}
+	from, err := g.cfg.releaseContent(name, g.CompareTo)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to get revision %d of release %s", g.CompareTo, name)
+	}
+	to, err := g.cfg.releaseContent(name, g.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	fromComputed, err := chartutil.CoalesceValues(from.Chart, from.Config)
+	if err != nil {
+		return nil, err
+	}
+	toComputed, err := chartutil.CoalesceValues(to.Chart, to.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ValuesDiff{
+		Release:      name,
+		From:         from.Version,
+		To:           to.Version,
+		UserSupplied: nonNilChanges(chartutil.DiffValues(from.Config, to.Config)),
+		Computed:     nonNilChanges(chartutil.DiffValues(fromComputed, toComputed)),
+	}, nil
+}
+
+// nonNilChanges returns the changes, or an empty list if there are none, so
+// that they are encoded as an empty list rather than null.
+func nonNilChanges(changes []chartutil.ValueChange) []chartutil.ValueChange {
+	if changes == nil {
+		return []chartutil.ValueChange{}
+	}
+	return changes
+}
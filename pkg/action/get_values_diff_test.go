@@ -0,0 +1,94 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"reflect"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+func TestGetValuesDiff(t *testing.T) {
+	config := actionConfigFixture(t)
+	for i, vals := range []map[string]interface{}{
+		{"replicas": 1},
+		{"replicas": 2, "debug": true},
+	} {
+		rel := releaseStub()
+		rel.Name = "values-diff"
+		rel.Version = i + 1
+		rel.Chart.Values = map[string]interface{}{"port": 80 + i}
+		rel.Config = vals
+		if err := config.Releases.Create(rel); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	client := NewGetValues(config)
+	client.CompareTo = 1
+	diff, err := client.Diff("values-diff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.From != 1 || diff.To != 2 {
+		t.Errorf("expected the revision 2 compared to the revision 1, got %d compared to %d", diff.To, diff.From)
+	}
+
+	userSupplied := []chartutil.ValueChange{
+		{Path: "debug", Type: chartutil.ValueAdded, To: true},
+		{Path: "replicas", Type: chartutil.ValueChanged, From: 1, To: 2},
+	}
+	if !reflect.DeepEqual(diff.UserSupplied, userSupplied) {
+		t.Errorf("expected the user-supplied changes %v, got %v", userSupplied, diff.UserSupplied)
+	}
+	// The default values of the chart changed too
+	computed := []chartutil.ValueChange{
+		{Path: "debug", Type: chartutil.ValueAdded, To: true},
+		{Path: "port", Type: chartutil.ValueChanged, From: 80, To: 81},
+		{Path: "replicas", Type: chartutil.ValueChanged, From: 1, To: 2},
+	}
+	if !reflect.DeepEqual(diff.Computed, computed) {
+		t.Errorf("expected the computed changes %v, got %v", computed, diff.Computed)
+	}
+
+	client.Version = 1
+	if diff, err := client.Diff("values-diff"); err != nil || len(diff.UserSupplied) != 0 || len(diff.Computed) != 0 {
+		t.Errorf("expected no changes between the same revisions, got %v, %v", diff, err)
+	}
+
+	client.CompareTo = 0
+	if _, err := client.Diff("values-diff"); err == nil {
+		t.Error("expected an error comparing to an invalid revision")
+	}
+}
+
+func TestGetValuesDiffMissingRevision(t *testing.T) {
+	config := actionConfigFixture(t)
+	rel := releaseStub()
+	rel.Info.Status = release.StatusDeployed
+	if err := config.Releases.Create(rel); err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewGetValues(config)
+	client.CompareTo = 2
+	if _, err := client.Diff(rel.Name); err == nil {
+		t.Error("expected an error comparing to a missing revision")
+	}
+}
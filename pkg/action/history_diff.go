@@ -0,0 +1,134 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// RevisionInfo describes a revision of a release compared by History.Diff.
+type RevisionInfo struct {
+	Revision     int    `json:"revision"`
+	Chart        string `json:"chart"`
+	ChartVersion string `json:"chart_version"`
+	AppVersion   string `json:"app_version"`
+	// ValuesDigest is the digest of the values supplied to the revision, as
+	// computed by ValuesDigest.
+	ValuesDigest string `json:"values_digest"`
+}
+
+// RevisionDiff is the difference between two revisions of a release.
+type RevisionDiff struct {
+	From RevisionInfo `json:"from"`
+	To   RevisionInfo `json:"to"`
+	// Values is the unified diff of the values supplied to the revisions, or
+	// empty if they are the same.
+	Values string `json:"values,omitempty"`
+	// Manifest is the unified diff of the manifests of the revisions, or
+	// empty if they are the same.
+	Manifest string `json:"manifest,omitempty"`
+}
+
+// Diff returns the difference between the revisions from and to of the
+// release: the difference between the values supplied to them and between
+// their manifests.
+func (h *History) Diff(name string, from, to int) (*RevisionDiff, error) {
+	if err := h.cfg.KubeClient.IsReachable(); err != nil {
+		return nil, err
+	}
+
+	if err := chartutil.ValidateReleaseName(name); err != nil {
+		return nil, errors.Errorf("release name is invalid: %s", name)
+	}
+
+	h.cfg.Log("comparing revisions %d and %d of release %s", from, to, name)
+	var rels [2]*release.Release
+	for i, version := range []int{from, to} {
+		if version <= 0 {
+			return nil, errors.Errorf("invalid revision %d", version)
+		}
+		rel, err := h.cfg.Releases.Get(name, version)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to get revision %d of release %s", version, name)
+		}
+		rels[i] = rel
+	}
+
+	d := &RevisionDiff{
+		From: revisionInfo(rels[0]),
+		To:   revisionInfo(rels[1]),
+	}
+	fromValues, err := yaml.Marshal(rels[0].Config)
+	if err != nil {
+		return nil, err
+	}
+	toValues, err := yaml.Marshal(rels[1].Config)
+	if err != nil {
+		return nil, err
+	}
+	d.Values = unifiedDiff(string(fromValues), string(toValues), from, to, "values.yaml")
+	d.Manifest = unifiedDiff(rels[0].Manifest, rels[1].Manifest, from, to, "manifest.yaml")
+	return d, nil
+}
+
+// revisionInfo returns the description of the revision of the release.
+func revisionInfo(rel *release.Release) RevisionInfo {
+	info := RevisionInfo{
+		Revision:     rel.Version,
+		ValuesDigest: ValuesDigest(rel.Config),
+	}
+	if rel.Chart != nil && rel.Chart.Metadata != nil {
+		info.Chart = rel.Chart.Name()
+		info.ChartVersion = rel.Chart.Metadata.Version
+		info.AppVersion = rel.Chart.AppVersion()
+	}
+	return info
+}
+
+// ValuesDigest returns the SHA-256 digest of the values supplied to a
+// release, like "sha256:...", so that the revisions of a release installed or
+// upgraded with the same values have the same digest.
+func ValuesDigest(values map[string]interface{}) string {
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+	// The keys of the maps are sorted by encoding/json
+	data, _ := json.Marshal(values)
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+}
+
+// unifiedDiff returns the unified diff of the file name between the revisions
+// from and to.
+func unifiedDiff(a, b string, from, to int, name string) string {
+	diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(a),
+		B:        difflib.SplitLines(b),
+		FromFile: fmt.Sprintf("revision-%d/%s", from, name),
+		ToFile:   fmt.Sprintf("revision-%d/%s", to, name),
+		Context:  3,
+	})
+	return diff
+}
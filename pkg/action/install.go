@@ -50,6 +50,7 @@ import (
 	"helm.sh/helm/v3/pkg/registry"
 	"helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/releaseutil"
+	"helm.sh/helm/v3/pkg/renderhook"
 	"helm.sh/helm/v3/pkg/repo"
 	"helm.sh/helm/v3/pkg/storage"
 	"helm.sh/helm/v3/pkg/storage/driver"
@@ -84,6 +85,9 @@ type Install struct {
 	Devel                    bool
 	DependencyUpdate         bool
 	Timeout                  time.Duration
+	KindTimeouts             map[string]time.Duration
+	BatchSize                int
+	BatchRetries             int
 	Namespace                string
 	ReleaseName              string
 	GenerateName             bool
@@ -96,8 +100,13 @@ type Install struct {
 	HideNotes                bool
 	SkipSchemaValidation     bool
 	DisableOpenAPIValidation bool
+	ValidateManifests        bool
 	IncludeCRDs              bool
 	Labels                   map[string]string
+	Annotations              map[string]string
+	// ValueSources are the sources of the values of the release, recorded
+	// with it.
+	ValueSources []release.ValueSource
 	// KubeVersion allows specifying a custom kubernetes version to use and
 	// APIVersions allows a manual set of supported API Versions to be passed
 	// (for things like templating). These are ignored if ClientOnly is false
@@ -113,6 +122,14 @@ type Install struct {
 	// TakeOwnership will ignore the check for helm annotations and take ownership of the resources.
 	TakeOwnership bool
 	PostRenderer  postrender.PostRenderer
+	// PostRendererName is the name of a post-renderer registered with
+	// postrender.Register, run in process after PostRenderer, configured by
+	// PostRendererArgs.
+	PostRendererName string
+	PostRendererArgs []string
+	// RenderHooks are run in process on the rendered manifests and hooks,
+	// before the post-renderer. They can mutate the manifests or veto them.
+	RenderHooks []renderhook.Hook
 	// Lock to control raceconditions when the process receives a SIGTERM
 	Lock sync.Mutex
 }
@@ -310,13 +327,19 @@ func (i *Install) RunWithContext(ctx context.Context, chrt *chart.Chart, vals ma
 		return nil, fmt.Errorf("user suplied labels contains system reserved label name. System labels: %+v", driver.GetSystemLabels())
 	}
 
-	rel := i.createRelease(chrt, vals, i.Labels)
+	pr, err := postRenderer(i.PostRenderer, i.PostRendererName, i.PostRendererArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	rel := i.createRelease(chrt, vals, i.Labels, i.Annotations)
 
 	var manifestDoc *bytes.Buffer
-	rel.Hooks, manifestDoc, rel.Info.Notes, err = i.cfg.renderResources(chrt, valuesToRender, i.ReleaseName, i.OutputDir, i.SubNotes, i.UseReleaseName, i.IncludeCRDs, i.PostRenderer, interactWithRemote, i.EnableDNS, i.HideSecret)
+	rel.Hooks, manifestDoc, rel.Info.Notes, err = i.cfg.renderResources(chrt, valuesToRender, i.ReleaseName, i.OutputDir, i.SubNotes, i.UseReleaseName, i.IncludeCRDs, pr, i.RenderHooks, i.renderHookContext(chrt), interactWithRemote, i.EnableDNS, i.HideSecret)
 	// Even for errors, attach this if available
 	if manifestDoc != nil {
 		rel.Manifest = manifestDoc.String()
+		rel.ManifestIndex = releaseutil.IndexManifest(rel.Manifest)
 	}
 	// Check error from render
 	if err != nil {
@@ -334,6 +357,12 @@ func (i *Install) RunWithContext(ctx context.Context, chrt *chart.Chart, vals ma
 		return nil, errors.Wrap(err, "unable to build kubernetes objects from release manifest")
 	}
 
+	if i.ValidateManifests {
+		if err := validateSchemas(i.cfg.KubeClient, resources, rel.Hooks); err != nil {
+			return nil, errors.Wrap(err, "manifest validation failed")
+		}
+	}
+
 	// It is safe to use "force" here because these are resources currently rendered by the chart.
 	err = resources.Visit(setMetadataVisitor(rel.Name, rel.Namespace, true))
 	if err != nil {
@@ -363,6 +392,10 @@ func (i *Install) RunWithContext(ctx context.Context, chrt *chart.Chart, vals ma
 		return rel, nil
 	}
 
+	if err := i.cfg.runLifecycleHooks(LifecyclePreInstall, rel); err != nil {
+		return nil, err
+	}
+
 	if i.CreateNamespace {
 		ns := &v1.Namespace{
 			TypeMeta: metav1.TypeMeta{
@@ -404,6 +437,7 @@ func (i *Install) RunWithContext(ctx context.Context, chrt *chart.Chart, vals ma
 		// not working.
 		return rel, err
 	}
+	i.cfg.recordEvent(rel, EventReasonInstallStarted, fmt.Sprintf("Installing release %s revision %d", rel.Name, rel.Version), nil)
 
 	rel, err = i.performInstallCtx(ctx, rel, toBeAdopted, resources)
 	if err != nil {
@@ -452,7 +486,9 @@ func (i *Install) performInstall(rel *release.Release, toBeAdopted kube.Resource
 	// At this point, we can do the install. Note that before we were detecting whether to
 	// do an update, but it's not clear whether we WANT to do an update if the re-use is set
 	// to true, since that is basically an upgrade operation.
-	if len(toBeAdopted) == 0 && len(resources) > 0 {
+	if i.BatchSize > 0 && len(resources) > 0 {
+		_, err = kube.ApplyInBatches(i.cfg.KubeClient, toBeAdopted, resources, i.Force, i.batchOptions())
+	} else if len(toBeAdopted) == 0 && len(resources) > 0 {
 		_, err = i.cfg.KubeClient.Create(resources)
 	} else if len(resources) > 0 {
 		_, err = i.cfg.KubeClient.Update(toBeAdopted, resources, i.Force)
@@ -462,11 +498,11 @@ func (i *Install) performInstall(rel *release.Release, toBeAdopted kube.Resource
 	}
 
 	if i.Wait {
-		if i.WaitForJobs {
-			err = i.cfg.KubeClient.WaitWithJobs(resources, i.Timeout)
-		} else {
-			err = i.cfg.KubeClient.Wait(resources, i.Timeout)
-		}
+		err = kube.WaitWithOptions(i.cfg.KubeClient, resources, kube.WaitOptions{
+			Timeout:      i.Timeout,
+			KindTimeouts: i.KindTimeouts,
+			WaitForJobs:  i.WaitForJobs,
+		})
 		if err != nil {
 			return rel, err
 		}
@@ -483,6 +519,7 @@ func (i *Install) performInstall(rel *release.Release, toBeAdopted kube.Resource
 	} else {
 		rel.SetStatus(release.StatusDeployed, "Install complete")
 	}
+	i.cfg.recordClusterState(rel, nil)
 
 	// This is a tricky case. The release has been created, but the result
 	// cannot be recorded. The truest thing to tell the user is that the
@@ -494,12 +531,30 @@ func (i *Install) performInstall(rel *release.Release, toBeAdopted kube.Resource
 	if err := i.recordRelease(rel); err != nil {
 		i.cfg.Log("failed to record the release: %s", err)
 	}
+	i.cfg.recordEvent(rel, EventReasonInstallSucceeded, fmt.Sprintf("Installed release %s revision %d", rel.Name, rel.Version), nil)
+	i.cfg.runPostLifecycleHooks(LifecyclePostInstall, rel, nil)
 
 	return rel, nil
 }
 
+func (i *Install) batchOptions() kube.BatchOptions {
+	return kube.BatchOptions{
+		Size:    i.BatchSize,
+		Wait:    i.Wait,
+		Retries: i.BatchRetries,
+		WaitOptions: kube.WaitOptions{
+			Timeout:      i.Timeout,
+			KindTimeouts: i.KindTimeouts,
+			WaitForJobs:  i.WaitForJobs,
+		},
+	}
+}
+
 func (i *Install) failRelease(rel *release.Release, err error) (*release.Release, error) {
 	rel.SetStatus(release.StatusFailed, fmt.Sprintf("Release %q failed: %s", i.ReleaseName, err.Error()))
+	i.cfg.recordClusterState(rel, err)
+	i.cfg.recordEvent(rel, EventReasonInstallFailed, fmt.Sprintf("Failed to install release %s revision %d", rel.Name, rel.Version), err)
+	i.cfg.runPostLifecycleHooks(LifecyclePostInstall, rel, err)
 	if i.Atomic {
 		i.cfg.Log("Install failed and atomic is set, uninstalling release")
 		uninstall := NewUninstall(i.cfg)
@@ -547,8 +602,25 @@ func (i *Install) availableName() error {
 	return errors.New("cannot re-use a name that is still in use")
 }
 
+// renderHookContext returns the context of the render hooks of the chart,
+// rendered by a template if ClientOnly.
+func (i *Install) renderHookContext(chrt *chart.Chart) renderhook.Context {
+	op := renderhook.OperationInstall
+	if i.ClientOnly {
+		op = renderhook.OperationTemplate
+	}
+	return renderhook.Context{
+		Operation:    op,
+		ReleaseName:  i.ReleaseName,
+		Namespace:    i.Namespace,
+		Revision:     1,
+		Chart:        chrt.Name(),
+		ChartVersion: chrt.Metadata.Version,
+	}
+}
+
 // createRelease creates a new release object
-func (i *Install) createRelease(chrt *chart.Chart, rawVals map[string]interface{}, labels map[string]string) *release.Release {
+func (i *Install) createRelease(chrt *chart.Chart, rawVals map[string]interface{}, labels, annotations map[string]string) *release.Release {
 	ts := i.cfg.Now()
 	return &release.Release{
 		Name:      i.ReleaseName,
@@ -560,8 +632,10 @@ func (i *Install) createRelease(chrt *chart.Chart, rawVals map[string]interface{
 			LastDeployed:  ts,
 			Status:        release.StatusUnknown,
 		},
-		Version: 1,
-		Labels:  labels,
+		Version:      1,
+		Labels:       labels,
+		Annotations:  annotations,
+		ValueSources: i.ValueSources,
 	}
 }
 
@@ -751,7 +825,16 @@ func (c *ChartPathOptions) LocateChart(name string, settings *cli.EnvSettings) (
 			return abs, err
 		}
 		if c.Verify {
-			if _, err := downloader.VerifyChart(abs, c.Keyring); err != nil {
+			policy, err := LoadSignaturePolicy(settings)
+			if err != nil {
+				return "", err
+			}
+			if policy != nil {
+				_, err = downloader.VerifyChartBundle(abs, policy)
+			} else {
+				_, err = downloader.VerifyChart(abs, c.Keyring)
+			}
+			if err != nil {
 				return "", err
 			}
 		}
@@ -782,6 +865,11 @@ func (c *ChartPathOptions) LocateChart(name string, settings *cli.EnvSettings) (
 
 	if c.Verify {
 		dl.Verify = downloader.VerifyAlways
+		policy, err := LoadSignaturePolicy(settings)
+		if err != nil {
+			return "", err
+		}
+		dl.SignaturePolicy = policy
 	}
 	if c.RepoURL != "" {
 		chartURL, err := repo.FindChartInAuthAndTLSAndPassRepoURL(c.RepoURL, c.Username, c.Password, name, version,
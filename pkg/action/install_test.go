@@ -17,7 +17,9 @@ limitations under the License.
 package action
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -34,8 +36,12 @@ import (
 	"helm.sh/helm/v3/internal/test"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/kube"
 	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/postrender"
 	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"helm.sh/helm/v3/pkg/renderhook"
 	"helm.sh/helm/v3/pkg/storage/driver"
 	helmtime "helm.sh/helm/v3/pkg/time"
 )
@@ -255,6 +261,44 @@ func TestInstallRelease_DryRun(t *testing.T) {
 	is.Equal(res.Info.Description, "Dry run complete")
 }
 
+type replacePostRenderer struct {
+	old, new string
+}
+
+func (r *replacePostRenderer) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	return bytes.NewBufferString(strings.ReplaceAll(renderedManifests.String(), r.old, r.new)), nil
+}
+
+func TestInstallRelease_RegisteredPostRenderer(t *testing.T) {
+	is := assert.New(t)
+	err := postrender.Register("test-install-replace", func(args ...string) (postrender.PostRenderer, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("expected 2 arguments, got %d", len(args))
+		}
+		return &replacePostRenderer{args[0], args[1]}, nil
+	})
+	is.NoError(err)
+
+	instAction := installAction(t)
+	instAction.DryRun = true
+	instAction.PostRenderer = &replacePostRenderer{"hello: world", "hello: mars"}
+	instAction.PostRendererName = "test-install-replace"
+	instAction.PostRendererArgs = []string{"mars", "venus"}
+	res, err := instAction.Run(buildChart(), map[string]interface{}{})
+	is.NoError(err)
+	is.Contains(res.Manifest, "hello: venus")
+
+	instAction = installAction(t)
+	instAction.PostRendererName = "test-install-replace"
+	_, err = instAction.Run(buildChart(), map[string]interface{}{})
+	is.ErrorContains(err, "expected 2 arguments")
+
+	instAction = installAction(t)
+	instAction.PostRendererName = "unregistered"
+	_, err = instAction.Run(buildChart(), map[string]interface{}{})
+	is.ErrorContains(err, `post-renderer "unregistered" is not registered`)
+}
+
 func TestInstallRelease_DryRunHiddenSecret(t *testing.T) {
 	is := assert.New(t)
 	instAction := installAction(t)
@@ -769,6 +813,42 @@ func TestNameAndChartGenerateName(t *testing.T) {
 	}
 }
 
+func TestInstallRelease_RenderHooks(t *testing.T) {
+	is := assert.New(t)
+	instAction := installAction(t)
+	var hookCtx renderhook.Context
+	instAction.RenderHooks = []renderhook.Hook{
+		renderhook.HookFunc("label", func(ctx renderhook.Context, manifests []*renderhook.Manifest) error {
+			hookCtx = ctx
+			for _, m := range manifests {
+				if !m.Hook {
+					m.Object["team"] = "platform"
+				}
+			}
+			return nil
+		}),
+	}
+	res, err := instAction.Run(buildChart(), map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Failed install: %s", err)
+	}
+	is.Equal(renderhook.Context{Operation: renderhook.OperationInstall, ReleaseName: "test-install-release", Namespace: "spaced", Revision: 1, Chart: "hello", ChartVersion: "0.1.0"}, hookCtx)
+	is.Contains(res.Manifest, "# Source: hello/templates/hello\nhello: world\nteam: platform\n")
+	is.Equal(manifestWithHook, res.Hooks[0].Manifest, "Expected the unchanged hook to keep its manifest")
+
+	instAction = installAction(t)
+	instAction.RenderHooks = []renderhook.Hook{
+		renderhook.HookFunc("policy", func(renderhook.Context, []*renderhook.Manifest) error {
+			return fmt.Errorf("the team label is required")
+		}),
+	}
+	_, err = instAction.Run(buildChart(), map[string]interface{}{})
+	var veto *renderhook.VetoError
+	if !errors.As(err, &veto) || veto.Hook != "policy" {
+		t.Fatalf("expected a veto of the policy render hook, got %v", err)
+	}
+}
+
 func TestInstallWithLabels(t *testing.T) {
 	is := assert.New(t)
 	instAction := installAction(t)
@@ -784,6 +864,59 @@ func TestInstallWithLabels(t *testing.T) {
 	is.Equal(instAction.Labels, res.Labels)
 }
 
+func TestInstallWithAnnotations(t *testing.T) {
+	is := assert.New(t)
+	instAction := installAction(t)
+	instAction.Annotations = map[string]string{
+		"team":   "web",
+		"ticket": "OPS-123",
+	}
+	res, err := instAction.Run(buildChart(), nil)
+	if err != nil {
+		t.Fatalf("Failed install: %s", err)
+	}
+
+	stored, err := instAction.cfg.Releases.Get(res.Name, res.Version)
+	is.NoError(err)
+	is.Equal(instAction.Annotations, stored.Annotations)
+}
+
+func TestInstallRelease_ManifestIndex(t *testing.T) {
+	is := assert.New(t)
+	instAction := installAction(t)
+	res, err := instAction.Run(buildChart(withSampleSecret()), nil)
+	if err != nil {
+		t.Fatalf("Failed install: %s", err)
+	}
+
+	stored, err := instAction.cfg.Releases.Get(res.Name, res.Version)
+	is.NoError(err)
+	is.NotEmpty(stored.ManifestIndex)
+	is.Equal(releaseutil.IndexManifest(stored.Manifest), stored.ManifestIndex)
+	for _, r := range stored.ManifestIndex {
+		manifest, ok := stored.ResourceManifest(r)
+		is.True(ok)
+		is.Contains(manifest, "kind: "+r.Kind)
+	}
+}
+
+func TestInstallWithValueSources(t *testing.T) {
+	is := assert.New(t)
+	instAction := installAction(t)
+	instAction.ValueSources = []release.ValueSource{
+		{Type: release.ValueSourceFile, Value: "values.yaml", Digest: "sha256:0123"},
+		{Type: release.ValueSourceSet, Value: "name=value"},
+	}
+	res, err := instAction.Run(buildChart(), map[string]interface{}{"name": "value"})
+	if err != nil {
+		t.Fatalf("Failed install: %s", err)
+	}
+
+	stored, err := instAction.cfg.Releases.Get(res.Name, res.Version)
+	is.NoError(err)
+	is.Equal(instAction.ValueSources, stored.ValueSources)
+}
+
 func TestInstallWithSystemLabels(t *testing.T) {
 	is := assert.New(t)
 	instAction := installAction(t)
@@ -798,3 +931,26 @@ func TestInstallWithSystemLabels(t *testing.T) {
 
 	is.Equal(fmt.Errorf("user suplied labels contains system reserved label name. System labels: %+v", driver.GetSystemLabels()), err)
 }
+
+func TestInstallRelease_TargetClusters(t *testing.T) {
+	is := assert.New(t)
+	instAction := installAction(t)
+	instAction.cfg.KubeClient = &kube.MultiClusterClient{
+		Default: instAction.cfg.KubeClient,
+		Clusters: map[string]kube.Interface{
+			"east": &kubefake.PrintingKubeClient{Out: io.Discard},
+		},
+	}
+	agent := "kind: ConfigMap\nmetadata:\n  name: agent\n  annotations:\n    helm.sh/target-cluster: east\n"
+	chrt := buildChart()
+	chrt.Templates = append(chrt.Templates, &chart.File{Name: "templates/agent", Data: []byte(agent)})
+
+	res, err := instAction.Run(chrt, map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("Failed install: %s", err)
+	}
+	is.Len(res.Info.Clusters, 2)
+	is.Equal(release.StatusDeployed, res.Info.Clusters["east"].Status)
+	is.Equal(1, res.Info.Clusters["east"].Resources)
+	is.Equal(1, res.Info.Clusters[""].Resources)
+}
@@ -22,11 +22,15 @@ import (
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
 	applycorev1 "k8s.io/client-go/applyconfigurations/core/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"helm.sh/helm/v3/pkg/storage/driver"
 )
 
 // lazyClient is a workaround to deal with Kubernetes having an unstable client API.
@@ -195,3 +199,111 @@ func (c *configMapClient) Apply(ctx context.Context, configMap *applycorev1.Conf
 	}
 	return c.client.CoreV1().ConfigMaps(c.namespace).Apply(ctx, configMap, opts)
 }
+
+// releaseRecordClient implements a dynamic.ResourceInterface for the
+// HelmReleaseRecord resource, creating the dynamic client and installing the
+// HelmReleaseRecord CRD if needed on first use.
+type releaseRecordClient struct {
+	initClient sync.Once
+	client     dynamic.Interface
+	clientErr  error
+
+	// clientFn loads a dynamic client
+	clientFn func() (dynamic.Interface, error)
+
+	// namespace passed to each client request
+	namespace string
+}
+
+var _ dynamic.ResourceInterface = (*releaseRecordClient)(nil)
+
+func newReleaseRecordClient(clientFn func() (dynamic.Interface, error), namespace string) *releaseRecordClient {
+	return &releaseRecordClient{clientFn: clientFn, namespace: namespace}
+}
+
+func (r *releaseRecordClient) init() error {
+	r.initClient.Do(func() {
+		r.client, r.clientErr = r.clientFn()
+		if r.clientErr == nil {
+			r.clientErr = driver.EnsureReleaseRecordCRD(r.client)
+		}
+	})
+	return r.clientErr
+}
+
+func (r *releaseRecordClient) Create(ctx context.Context, obj *unstructured.Unstructured, opts metav1.CreateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	if err := r.init(); err != nil {
+		return nil, err
+	}
+	return r.client.Resource(driver.ReleaseRecordGVR).Namespace(r.namespace).Create(ctx, obj, opts, subresources...)
+}
+
+func (r *releaseRecordClient) Update(ctx context.Context, obj *unstructured.Unstructured, opts metav1.UpdateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	if err := r.init(); err != nil {
+		return nil, err
+	}
+	return r.client.Resource(driver.ReleaseRecordGVR).Namespace(r.namespace).Update(ctx, obj, opts, subresources...)
+}
+
+func (r *releaseRecordClient) UpdateStatus(ctx context.Context, obj *unstructured.Unstructured, opts metav1.UpdateOptions) (*unstructured.Unstructured, error) {
+	if err := r.init(); err != nil {
+		return nil, err
+	}
+	return r.client.Resource(driver.ReleaseRecordGVR).Namespace(r.namespace).UpdateStatus(ctx, obj, opts)
+}
+
+func (r *releaseRecordClient) Delete(ctx context.Context, name string, opts metav1.DeleteOptions, subresources ...string) error {
+	if err := r.init(); err != nil {
+		return err
+	}
+	return r.client.Resource(driver.ReleaseRecordGVR).Namespace(r.namespace).Delete(ctx, name, opts, subresources...)
+}
+
+func (r *releaseRecordClient) DeleteCollection(ctx context.Context, opts metav1.DeleteOptions, listOpts metav1.ListOptions) error {
+	if err := r.init(); err != nil {
+		return err
+	}
+	return r.client.Resource(driver.ReleaseRecordGVR).Namespace(r.namespace).DeleteCollection(ctx, opts, listOpts)
+}
+
+func (r *releaseRecordClient) Get(ctx context.Context, name string, opts metav1.GetOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	if err := r.init(); err != nil {
+		return nil, err
+	}
+	return r.client.Resource(driver.ReleaseRecordGVR).Namespace(r.namespace).Get(ctx, name, opts, subresources...)
+}
+
+func (r *releaseRecordClient) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	if err := r.init(); err != nil {
+		return nil, err
+	}
+	return r.client.Resource(driver.ReleaseRecordGVR).Namespace(r.namespace).List(ctx, opts)
+}
+
+func (r *releaseRecordClient) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	if err := r.init(); err != nil {
+		return nil, err
+	}
+	return r.client.Resource(driver.ReleaseRecordGVR).Namespace(r.namespace).Watch(ctx, opts)
+}
+
+func (r *releaseRecordClient) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	if err := r.init(); err != nil {
+		return nil, err
+	}
+	return r.client.Resource(driver.ReleaseRecordGVR).Namespace(r.namespace).Patch(ctx, name, pt, data, opts, subresources...)
+}
+
+func (r *releaseRecordClient) Apply(ctx context.Context, name string, obj *unstructured.Unstructured, opts metav1.ApplyOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	if err := r.init(); err != nil {
+		return nil, err
+	}
+	return r.client.Resource(driver.ReleaseRecordGVR).Namespace(r.namespace).Apply(ctx, name, obj, opts, subresources...)
+}
+
+func (r *releaseRecordClient) ApplyStatus(ctx context.Context, name string, obj *unstructured.Unstructured, opts metav1.ApplyOptions) (*unstructured.Unstructured, error) {
+	if err := r.init(); err != nil {
+		return nil, err
+	}
+	return r.client.Resource(driver.ReleaseRecordGVR).Namespace(r.namespace).ApplyStatus(ctx, name, obj, opts)
+}
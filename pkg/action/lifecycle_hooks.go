@@ -0,0 +1,82 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// The events of the lifecycle hooks.
+const (
+	LifecyclePreInstall    = "pre-install"
+	LifecyclePostInstall   = "post-install"
+	LifecyclePreUpgrade    = "pre-upgrade"
+	LifecyclePostUpgrade   = "post-upgrade"
+	LifecyclePreRollback   = "pre-rollback"
+	LifecyclePostRollback  = "post-rollback"
+	LifecyclePreUninstall  = "pre-uninstall"
+	LifecyclePostUninstall = "post-uninstall"
+)
+
+// LifecycleEvent is an event of the lifecycle of a release.
+type LifecycleEvent struct {
+	// Event is the event, like LifecyclePreInstall.
+	Event string
+	// Release is the release of the operation: the release to install,
+	// upgrade to, roll back to or uninstall, with its chart and its values.
+	// It must not be modified.
+	Release *release.Release
+	// Err is the error of the failed operation of a post event.
+	Err error
+}
+
+// LifecycleHook is run on the events of the lifecycle of the releases, like
+// an install, at the level of the operations of Helm. Unlike the hooks of
+// the charts, it is not a resource of the release: it is run by Helm, like
+// the hooks of plugins logging the operations or opening tickets.
+type LifecycleHook interface {
+	// Name identifies the lifecycle hook.
+	Name() string
+	// Run runs the hook on the event. An error of a pre event aborts the
+	// operation, before the release is stored; an error of a post event is
+	// logged.
+	Run(event LifecycleEvent) error
+}
+
+// runLifecycleHooks runs the lifecycle hooks of the configuration on the pre
+// event of rel, returning the error of the first hook failing.
+func (cfg *Configuration) runLifecycleHooks(event string, rel *release.Release) error {
+	for _, h := range cfg.LifecycleHooks {
+		if err := h.Run(LifecycleEvent{Event: event, Release: rel}); err != nil {
+			return errors.Wrapf(err, "lifecycle hook %q failed on %s", h.Name(), event)
+		}
+	}
+	return nil
+}
+
+// runPostLifecycleHooks runs the lifecycle hooks of the configuration on the
+// post event of rel, with the error of the operation. The failures of the
+// hooks are logged.
+func (cfg *Configuration) runPostLifecycleHooks(event string, rel *release.Release, err error) {
+	for _, h := range cfg.LifecycleHooks {
+		if hookErr := h.Run(LifecycleEvent{Event: event, Release: rel, Err: err}); hookErr != nil {
+			cfg.Log("warning: lifecycle hook %q failed on %s: %s", h.Name(), event, hookErr)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+)
+
+// recordingLifecycleHook records the events it runs on, failing on the
+// event fail.
+type recordingLifecycleHook struct {
+	events []string
+	fail   string
+}
+
+func (h *recordingLifecycleHook) Name() string { return "recorder" }
+
+func (h *recordingLifecycleHook) Run(e LifecycleEvent) error {
+	event := fmt.Sprintf("%s %s v%d", e.Event, e.Release.Name, e.Release.Version)
+	if e.Err != nil {
+		event += ": " + e.Err.Error()
+	}
+	h.events = append(h.events, event)
+	if e.Event == h.fail {
+		return fmt.Errorf("ticket not approved")
+	}
+	return nil
+}
+
+func TestInstallRunsLifecycleHooks(t *testing.T) {
+	hook := &recordingLifecycleHook{}
+	instAction := installAction(t)
+	instAction.cfg.LifecycleHooks = []LifecycleHook{hook}
+
+	_, err := instAction.Run(buildChart(), map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"pre-install test-install-release v1",
+		"post-install test-install-release v1",
+	}, hook.events)
+
+	hook.events = nil
+	failer := instAction.cfg.KubeClient.(*kubefake.FailingKubeClient)
+	failer.WaitError = fmt.Errorf("timed out")
+	instAction.Wait = true
+	instAction.ReleaseName = "failing-release"
+	_, err = instAction.Run(buildChart(), map[string]interface{}{})
+	require.Error(t, err)
+	assert.Equal(t, []string{
+		"pre-install failing-release v1",
+		"post-install failing-release v1: timed out",
+	}, hook.events)
+
+	// The dry runs do not run the lifecycle hooks
+	hook.events = nil
+	instAction = installAction(t)
+	instAction.cfg.LifecycleHooks = []LifecycleHook{hook}
+	instAction.DryRun = true
+	_, err = instAction.Run(buildChart(), map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Empty(t, hook.events)
+}
+
+func TestInstallLifecycleHookVeto(t *testing.T) {
+	hook := &recordingLifecycleHook{fail: LifecyclePreInstall}
+	instAction := installAction(t)
+	instAction.cfg.LifecycleHooks = []LifecycleHook{hook}
+
+	_, err := instAction.Run(buildChart(), map[string]interface{}{})
+	require.EqualError(t, err, `lifecycle hook "recorder" failed on pre-install: ticket not approved`)
+	assert.Equal(t, []string{"pre-install test-install-release v1"}, hook.events)
+	_, err = instAction.cfg.Releases.Last(instAction.ReleaseName)
+	assert.Error(t, err, "expected the vetoed release not to be stored")
+}
+
+func TestUpgradeAndUninstallRunLifecycleHooks(t *testing.T) {
+	hook := &recordingLifecycleHook{fail: LifecyclePostUpgrade}
+	upAction := upgradeAction(t)
+	upAction.cfg.LifecycleHooks = []LifecycleHook{hook}
+	rel := releaseStub()
+	rel.Name = "lifecycle"
+	require.NoError(t, upAction.cfg.Releases.Create(rel))
+
+	// The failures of the post events do not fail the operations
+	_, err := upAction.Run(rel.Name, buildChart(), map[string]interface{}{})
+	require.NoError(t, err)
+
+	unAction := NewUninstall(upAction.cfg)
+	unAction.DisableHooks = true
+	_, err = unAction.Run(rel.Name)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"pre-upgrade lifecycle v2",
+		"post-upgrade lifecycle v2",
+		"pre-uninstall lifecycle v2",
+		"post-uninstall lifecycle v2",
+	}, hook.events)
+}
@@ -19,10 +19,12 @@ package action
 import (
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/pkg/errors"
 
+	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/lint"
 	"helm.sh/helm/v3/pkg/lint/support"
@@ -38,13 +40,84 @@ type Lint struct {
 	Quiet                bool
 	SkipSchemaValidation bool
 	KubeVersion          *chartutil.KubeVersion
+	// KubeVersions are the Kubernetes versions the charts are linted for,
+	// once per version, instead of KubeVersion, so that the charts supporting
+	// a range of versions are checked against the capabilities and the
+	// deprecations of each version.
+	KubeVersions []*chartutil.KubeVersion
+	// Rules are lint rules run after the built-in and the registered rules,
+	// like the rules of plugins.
+	Rules []lint.Rule
+	// DisabledRules are the IDs of the rules not run.
+	DisabledRules []string
+	// EnabledRules are the IDs of the optional rules run.
+	EnabledRules []string
+	// RuleSeverities are the severities of the failures of the rules, by ID,
+	// overriding the severities of the rules.
+	RuleSeverities map[string]int
+	// KubeSchemaDirs are the directories of the JSON schemas the custom
+	// resources of the charts are validated against.
+	KubeSchemaDirs []string
+	// ConfigFile is the lint configuration used instead of the configurations
+	// of the charts, in their .helmlintrc files.
+	ConfigFile string
+	// Recursive lints the subcharts of the charts, bundled or resolved in
+	// their charts directories, with the values their parent charts pass them.
+	Recursive bool
+	// Subcharts are the names of the subcharts linted recursively, or patterns
+	// like "common*", matching the names or aliases of the dependencies. All
+	// the subcharts are linted if empty.
+	Subcharts []string
+	// ValuesOnly only checks the values against the schemas of the charts and
+	// of their subcharts, without rendering the templates.
+	ValuesOnly bool
+	// WithScenarios lints the charts once per test values file of their ci
+	// directories, like ci/ingress-values.yaml, merged over the supplied
+	// values. The charts without scenarios are linted once.
+	WithScenarios bool
+	// Fix applies the safe mechanical fixes of the chart directories before
+	// linting them. The archives are not fixed.
+	Fix bool
+	// Incremental only re-runs the rules whose inputs changed since the
+	// previous run on the chart directories, reusing the messages of the
+	// other rules cached in CacheDir. The archives are linted fully.
+	Incremental bool
+	// CacheDir is the directory of the caches of the incremental runs.
+	CacheDir string
+	// ChangedFiles are the files changed since the previous incremental run.
+	// The changed files are the files whose modification times or sizes
+	// changed if nil.
+	ChangedFiles []string
 }
 
+// scenariosGlob matches the test values files of a chart.
+const scenariosGlob = "ci/*-values.yaml"
+
+// valuesSchemasRule is the ID of the rule run with ValuesOnly.
+const valuesSchemasRule = "values-schemas"
+
 // LintResult is the result of Lint
 type LintResult struct {
 	TotalChartsLinted int
 	Messages          []support.Message
 	Errors            []error
+	// Ignored are the messages ignored by the lint configurations.
+	Ignored []support.Message
+	// Path is the path to the linted chart, in the results of Charts. The
+	// subcharts are under the charts directories of their parents, by name or
+	// alias, like "mychart/charts/mysubchart".
+	Path string
+	// Scenario is the test values file the chart is linted with, like
+	// "ci/ingress-values.yaml", in the results of Charts.
+	Scenario string
+	// KubeVersion is the Kubernetes version the chart is linted for, like
+	// "v1.29.0", in the results of Charts linted for KubeVersions.
+	KubeVersion string
+	// Fixes are the fixes applied to the chart, in the results of Charts.
+	Fixes []lint.Fix
+	// Charts are the results of the linted charts, each subchart following
+	// its parent chart.
+	Charts []*LintResult
 }
 
 // NewLint creates a new Lint object with the given configuration.
@@ -58,25 +131,344 @@ func (l *Lint) Run(paths []string, vals map[string]interface{}) *LintResult {
 	if l.Strict {
 		lowestTolerance = support.WarningSev
 	}
+	var config *lint.Config
+	var configErr error
+	if l.ConfigFile != "" {
+		config, configErr = lint.LoadConfigFile(l.ConfigFile)
+	}
 	result := &LintResult{}
 	for _, path := range paths {
-		linter, err := lintChart(path, vals, l.Namespace, l.KubeVersion, l.SkipSchemaValidation)
-		if err != nil {
-			result.Errors = append(result.Errors, err)
+		if configErr != nil {
+			result.Charts = append(result.Charts, &LintResult{Path: path, Errors: []error{configErr}})
 			continue
 		}
+		result.Charts = append(result.Charts, l.lint(path, path, vals, config, true)...)
+	}
 
-		result.Messages = append(result.Messages, linter.Messages...)
-		result.TotalChartsLinted++
-		for _, msg := range linter.Messages {
+	for _, chartResult := range result.Charts {
+		for _, msg := range chartResult.Messages {
 			if msg.Severity >= lowestTolerance {
-				result.Errors = append(result.Errors, msg.Err)
+				chartResult.Errors = append(chartResult.Errors, msg.Err)
 			}
 		}
+		result.TotalChartsLinted += chartResult.TotalChartsLinted
+		result.Messages = append(result.Messages, chartResult.Messages...)
+		result.Errors = append(result.Errors, chartResult.Errors...)
+		result.Ignored = append(result.Ignored, chartResult.Ignored...)
 	}
 	return result
 }
 
+// lint lints the chart of the path, a directory or an archive, with the
+// values, once per Kubernetes version of KubeVersions, once per scenario if
+// scenarios and WithScenarios, and its subcharts if Recursive. The chart directory is fixed first if Fix. It returns the
+// results of the chart and of its subcharts, without the errors of the
+// failures.
+func (l *Lint) lint(path, name string, vals map[string]interface{}, config *lint.Config, scenarios bool) []*LintResult {
+	chartPath, cleanup, err := expandChart(path)
+	if err != nil {
+		return []*LintResult{{Path: name, Errors: []error{err}}}
+	}
+	defer cleanup()
+
+	var fixes []lint.Fix
+	if l.Fix && chartPath == path {
+		if fixes, err = applyFixes(chartPath); err != nil {
+			return []*LintResult{{Path: name, Errors: []error{err}}}
+		}
+	}
+
+	var cacheKey string
+	if l.Incremental && chartPath == path {
+		cacheKey, _ = filepath.Abs(chartPath)
+	}
+
+	results := l.lintVersions(chartPath, name, vals, config, scenarios, cacheKey)
+	results[0].Fixes = fixes
+	return results
+}
+
+// lintVersions lints the chart of the directory with the values once per
+// Kubernetes version of KubeVersions, like lint, or once for KubeVersion if
+// there are none.
+func (l *Lint) lintVersions(chartPath, name string, vals map[string]interface{}, config *lint.Config, scenarios bool, cacheKey string) []*LintResult {
+	if len(l.KubeVersions) == 0 {
+		return l.lintScenarios(chartPath, name, vals, config, scenarios, cacheKey)
+	}
+
+	var results []*LintResult
+	for _, kubeVersion := range l.KubeVersions {
+		// The subcharts linted recursively are linted for the version only
+		versionLint := *l
+		versionLint.KubeVersion = kubeVersion
+		versionLint.KubeVersions = nil
+		versionKey := cacheKey
+		if cacheKey != "" {
+			versionKey += "@" + kubeVersion.Version
+		}
+		for _, result := range versionLint.lintScenarios(chartPath, name, vals, config, scenarios, versionKey) {
+			result.KubeVersion = kubeVersion.Version
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// lintScenarios lints the chart of the directory with the values, once per
+// scenario if scenarios and WithScenarios, like lint. The chart is linted
+// incrementally with the cache of the key, if any.
+func (l *Lint) lintScenarios(chartPath, name string, vals map[string]interface{}, config *lint.Config, scenarios bool, cacheKey string) []*LintResult {
+	var files []string
+	if scenarios && l.WithScenarios {
+		files, _ = filepath.Glob(filepath.Join(chartPath, scenariosGlob))
+	}
+	if len(files) == 0 {
+		return l.lintDir(chartPath, name, vals, config, cacheKey)
+	}
+
+	var results []*LintResult
+	for _, file := range files {
+		scenario := filepath.ToSlash(filepath.Join(filepath.Dir(scenariosGlob), filepath.Base(file)))
+		scenarioVals, err := chartutil.ReadValuesFile(file)
+		if err != nil {
+			results = append(results, &LintResult{Path: name, Scenario: scenario, Errors: []error{errors.Wrapf(err, "unable to read scenario %s", scenario)}})
+			continue
+		}
+		// The values of the scenario take precedence over the supplied values
+		scenarioKey := cacheKey
+		if cacheKey != "" {
+			scenarioKey += "#" + scenario
+		}
+		for _, result := range l.lintDir(chartPath, name, chartutil.CoalesceTables(scenarioVals, vals), config, scenarioKey) {
+			result.Scenario = scenario
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// applyFixes applies the fixes of the chart in the directory, returning them.
+func applyFixes(chartPath string) ([]lint.Fix, error) {
+	fixes, err := lint.Fixes(chartPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to fix the chart")
+	}
+	for _, f := range fixes {
+		if err := f.Apply(chartPath); err != nil {
+			return nil, errors.Wrapf(err, "unable to fix %s", f.Path)
+		}
+	}
+	return fixes, nil
+}
+
+// lintDir lints the chart of the directory with the values like lint,
+// incrementally with the cache of the key, if any.
+func (l *Lint) lintDir(chartPath, name string, vals map[string]interface{}, config *lint.Config, cacheKey string) []*LintResult {
+	result := &LintResult{Path: name}
+	results := []*LintResult{result}
+
+	run := func(chartPath string, opts lint.Options, rules []lint.Rule) (support.Linter, error) {
+		return lint.Run(chartPath, opts, rules...), nil
+	}
+	if cacheKey != "" {
+		run = func(chartPath string, opts lint.Options, rules []lint.Rule) (support.Linter, error) {
+			return lint.RunIncremental(chartPath, opts, lint.CacheFile(l.CacheDir, cacheKey), l.changedFiles(chartPath), rules...)
+		}
+	}
+	linter, ignored, err := lintChartDir(chartPath, l.options(vals), config, l.rules, run)
+	if err != nil {
+		result.Errors = append(result.Errors, err)
+		return results
+	}
+	result.TotalChartsLinted = 1
+	result.Messages = linter.Messages
+	result.Ignored = ignored
+	if !l.Recursive {
+		return results
+	}
+
+	subcharts, err := l.subcharts(chartPath, vals)
+	if err != nil {
+		result.Errors = append(result.Errors, errors.Wrap(err, "unable to resolve the subcharts"))
+		return results
+	}
+	for _, sub := range subcharts {
+		results = append(results, l.lint(sub.path, filepath.Join(name, "charts", sub.name), sub.values, config, false)...)
+	}
+	return results
+}
+
+// changedFiles returns the ChangedFiles in the chart directory, relative to
+// the directory, or nil if the changed files are not known.
+func (l *Lint) changedFiles(chartPath string) []string {
+	if l.ChangedFiles == nil {
+		return nil
+	}
+	chartDir, err := filepath.Abs(chartPath)
+	if err != nil {
+		return nil
+	}
+	changed := []string{}
+	for _, name := range l.ChangedFiles {
+		abs, err := filepath.Abs(name)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(chartDir, abs)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		changed = append(changed, rel)
+	}
+	return changed
+}
+
+// options returns the options of the linting of a chart with the values.
+func (l *Lint) options(vals map[string]interface{}) lint.Options {
+	return lint.Options{
+		Values:               vals,
+		Namespace:            l.Namespace,
+		KubeVersion:          l.KubeVersion,
+		SkipSchemaValidation: l.SkipSchemaValidation,
+		KubeSchemaDirs:       l.KubeSchemaDirs,
+	}
+}
+
+// subchart is a subchart in the charts directory of a chart.
+type subchart struct {
+	// name is the name or alias of the subchart.
+	name string
+	// path is the path to the subchart, a directory or an archive.
+	path string
+	// values are the values the parent chart passes the subchart.
+	values map[string]interface{}
+}
+
+// subcharts returns the enabled subcharts of the chart in the chart directory
+// matching the Subcharts, with the values the chart passes them when rendered
+// with vals. An aliased subchart is returned once per alias.
+func (l *Lint) subcharts(chartPath string, vals map[string]interface{}) ([]subchart, error) {
+	chrt, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, err
+	}
+	// The names of the aliased subcharts, renamed by the processing
+	aliases := make(map[string]string)
+	for _, req := range chrt.Metadata.Dependencies {
+		if req.Alias != "" {
+			aliases[req.Alias] = req.Name
+		}
+	}
+	if err := chartutil.ProcessDependenciesWithMerge(chrt, vals); err != nil {
+		return nil, err
+	}
+	cvals, err := chartutil.CoalesceValues(chrt, vals)
+	if err != nil {
+		return nil, err
+	}
+
+	// The subcharts of the charts directory, by name and version
+	paths := make(map[string]string)
+	entries, err := os.ReadDir(filepath.Join(chartPath, "charts"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		path := filepath.Join(chartPath, "charts", entry.Name())
+		if !entry.IsDir() && !strings.HasSuffix(path, ".tgz") && !strings.HasSuffix(path, ".tar.gz") {
+			continue
+		}
+		sub, err := loader.Load(path)
+		if err != nil {
+			// The invalid subcharts are reported by the Dependencies rule
+			continue
+		}
+		paths[sub.Name()+"-"+sub.Metadata.Version] = path
+	}
+
+	var result []subchart
+	for _, dep := range chrt.Dependencies() {
+		if !l.subchartIncluded(dep.Name()) {
+			continue
+		}
+		name := dep.Name()
+		if original, ok := aliases[name]; ok {
+			name = original
+		}
+		path, ok := paths[name+"-"+dep.Metadata.Version]
+		if !ok {
+			continue
+		}
+		values, err := cvals.Table(dep.Name())
+		if err != nil {
+			values = chartutil.Values{}
+		}
+		result = append(result, subchart{name: dep.Name(), path: path, values: values})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].name < result[j].name })
+	return result, nil
+}
+
+// subchartIncluded returns whether the subchart of the name or alias is linted
+// recursively.
+func (l *Lint) subchartIncluded(name string) bool {
+	if len(l.Subcharts) == 0 {
+		return true
+	}
+	for _, pattern := range l.Subcharts {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// rules returns the rules run with the lint configuration: the rules not
+// optional and the enabled rules, without the disabled rules, with their
+// severities overridden. The rules configured by the Lint take precedence over
+// the configuration. With ValuesOnly, only the values-schemas rule is run. It
+// returns an error if a configured rule is unknown.
+func (l *Lint) rules(config *lint.Config) ([]lint.Rule, error) {
+	unknown := make(map[string]bool)
+	disabled := make(map[string]bool)
+	for _, id := range append(config.Rules.Disable, l.DisabledRules...) {
+		disabled[id] = true
+		unknown[id] = true
+	}
+	enabled := make(map[string]bool)
+	for _, id := range append(config.Rules.Enable, l.EnabledRules...) {
+		enabled[id] = true
+		unknown[id] = true
+	}
+	severities := config.RuleSeverities()
+	for id, severity := range l.RuleSeverities {
+		severities[id] = severity
+	}
+	for id := range severities {
+		unknown[id] = true
+	}
+	var rules []lint.Rule
+	for _, r := range append(lint.Rules(), l.Rules...) {
+		md := r.Metadata()
+		delete(unknown, md.ID)
+		if l.ValuesOnly {
+			if md.ID != valuesSchemasRule {
+				continue
+			}
+		} else if disabled[md.ID] || (md.Optional && !enabled[md.ID]) {
+			continue
+		}
+		if severity, ok := severities[md.ID]; ok {
+			r = lint.WithSeverity(r, severity)
+		}
+		rules = append(rules, r)
+	}
+	for id := range unknown {
+		return nil, errors.Errorf("unknown lint rule %q", id)
+	}
+	return rules, nil
+}
+
 // HasWarningsOrErrors checks is LintResult has any warnings or errors
 func HasWarningsOrErrors(result *LintResult) bool {
 	for _, msg := range result.Messages {
@@ -87,33 +479,47 @@ func HasWarningsOrErrors(result *LintResult) bool {
 	return len(result.Errors) > 0
 }
 
-func lintChart(path string, vals map[string]interface{}, namespace string, kubeVersion *chartutil.KubeVersion, skipSchemaValidation bool) (support.Linter, error) {
-	var chartPath string
-	linter := support.Linter{}
+// lintChart lints the chart of the path, a directory or an archive, with the
+// rules of the lint configuration, the configuration of the chart if config is
+// nil. It returns the linter and the messages ignored by the configuration.
+func lintChart(path string, opts lint.Options, config *lint.Config, rules func(*lint.Config) ([]lint.Rule, error)) (support.Linter, []support.Message, error) {
+	chartPath, cleanup, err := expandChart(path)
+	if err != nil {
+		return support.Linter{}, nil, err
+	}
+	defer cleanup()
+	return lintChartDir(chartPath, opts, config, rules, func(chartPath string, opts lint.Options, rules []lint.Rule) (support.Linter, error) {
+		return lint.Run(chartPath, opts, rules...), nil
+	})
+}
 
+// expandChart returns the directory of the chart of the path, expanding the
+// archives in a temporary directory removed by cleanup.
+func expandChart(path string) (chartPath string, cleanup func(), err error) {
+	cleanup = func() {}
 	if strings.HasSuffix(path, ".tgz") || strings.HasSuffix(path, ".tar.gz") {
 		tempDir, err := os.MkdirTemp("", "helm-lint")
 		if err != nil {
-			return linter, errors.Wrap(err, "unable to create temp dir to extract tarball")
+			return "", cleanup, errors.Wrap(err, "unable to create temp dir to extract tarball")
 		}
-		defer os.RemoveAll(tempDir)
+		cleanup = func() { os.RemoveAll(tempDir) }
 
 		file, err := os.Open(path)
 		if err != nil {
-			return linter, errors.Wrap(err, "unable to open tarball")
+			return "", cleanup, errors.Wrap(err, "unable to open tarball")
 		}
 		defer file.Close()
 
 		if err = chartutil.Expand(tempDir, file); err != nil {
-			return linter, errors.Wrap(err, "unable to extract tarball")
+			return "", cleanup, errors.Wrap(err, "unable to extract tarball")
 		}
 
 		files, err := os.ReadDir(tempDir)
 		if err != nil {
-			return linter, errors.Wrapf(err, "unable to read temporary output directory %s", tempDir)
+			return "", cleanup, errors.Wrapf(err, "unable to read temporary output directory %s", tempDir)
 		}
 		if !files[0].IsDir() {
-			return linter, errors.Errorf("unexpected file %s in temporary output directory %s", files[0].Name(), tempDir)
+			return "", cleanup, errors.Errorf("unexpected file %s in temporary output directory %s", files[0].Name(), tempDir)
 		}
 
 		chartPath = filepath.Join(tempDir, files[0].Name())
@@ -123,8 +529,37 @@ func lintChart(path string, vals map[string]interface{}, namespace string, kubeV
 
 	// Guard: Error out if this is not a chart.
 	if _, err := os.Stat(filepath.Join(chartPath, "Chart.yaml")); err != nil {
-		return linter, errors.Wrap(err, "unable to check Chart.yaml file in chart")
+		return "", cleanup, errors.Wrap(err, "unable to check Chart.yaml file in chart")
+	}
+	return chartPath, cleanup, nil
+}
+
+// lintChartDir lints the chart of the directory like lintChart, running the
+// rules with run.
+func lintChartDir(chartPath string, opts lint.Options, config *lint.Config, rules func(*lint.Config) ([]lint.Rule, error), run func(string, lint.Options, []lint.Rule) (support.Linter, error)) (support.Linter, []support.Message, error) {
+	var linter support.Linter
+	if config == nil {
+		var err error
+		if config, err = lint.LoadConfig(chartPath); err != nil {
+			return linter, nil, err
+		}
+	}
+	chartRules, err := rules(config)
+	if err != nil {
+		return linter, nil, err
 	}
 
-	return lint.AllWithKubeVersionAndSchemaValidation(chartPath, vals, namespace, kubeVersion, skipSchemaValidation), nil
+	if linter, err = run(chartPath, opts, chartRules); err != nil {
+		return linter, nil, err
+	}
+	var messages, ignored []support.Message
+	for _, msg := range linter.Messages {
+		if config.Ignored(msg) {
+			ignored = append(ignored, msg)
+		} else {
+			messages = append(messages, msg)
+		}
+	}
+	linter.Messages = messages
+	return linter, ignored, nil
 }
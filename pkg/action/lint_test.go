@@ -17,7 +17,16 @@ limitations under the License.
 package action
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/lint"
+	"helm.sh/helm/v3/pkg/lint/support"
 )
 
 var (
@@ -27,6 +36,10 @@ var (
 	chart2MultipleChartLint = "testdata/charts/multiplecharts-lint-chart-2"
 	corruptedTgzChart       = "testdata/charts/corrupted-compressed-chart.tgz"
 	chartWithNoTemplatesDir = "testdata/charts/chart-with-no-templates-dir"
+	chartWithLintConfig     = "testdata/charts/chart-with-lint-config"
+	chartWithSubchartsLint  = "testdata/charts/chart-with-subcharts-lint"
+	chartWithScenarios      = "testdata/charts/chart-with-scenarios"
+	chartWithDeprecatedAPI  = "testdata/charts/chart-with-deprecated-api"
 )
 
 func TestLintChart(t *testing.T) {
@@ -83,7 +96,11 @@ func TestLintChart(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := lintChart(tt.chartPath, map[string]interface{}{}, namespace, nil, tt.skipSchemaValidation)
+			_, _, err := lintChart(tt.chartPath, lint.Options{
+				Values:               map[string]interface{}{},
+				Namespace:            namespace,
+				SkipSchemaValidation: tt.skipSchemaValidation,
+			}, nil, NewLint().rules)
 			switch {
 			case err != nil && !tt.err:
 				t.Errorf("%s", err)
@@ -163,3 +180,333 @@ func TestLint_ChartWithWarnings(t *testing.T) {
 		}
 	})
 }
+
+func TestLint_Rules(t *testing.T) {
+	houseRule := lint.RuleFunc(lint.RuleMetadata{ID: "house/labels", Severity: support.ErrorSev}, func(linter *support.Linter, _ lint.Options) {
+		linter.RunLinterRule(support.ErrorSev, "templates/", errors.New("missing team label"))
+	})
+
+	t.Run("should run the extra rules", func(t *testing.T) {
+		testLint := NewLint()
+		testLint.Rules = []lint.Rule{houseRule}
+		result := testLint.Run([]string{chart1MultipleChartLint}, values)
+		if len(result.Errors) != 1 {
+			t.Fatalf("expected 1 error, got %v", result.Errors)
+		}
+		if msg := result.Messages[len(result.Messages)-1]; msg.Rule != "house/labels" {
+			t.Errorf("expected a message of rule house/labels, got %q", msg.Rule)
+		}
+	})
+
+	t.Run("should not run the disabled rules", func(t *testing.T) {
+		testLint := NewLint()
+		testLint.Rules = []lint.Rule{houseRule}
+		testLint.DisabledRules = []string{"house/labels"}
+		if result := testLint.Run([]string{chart1MultipleChartLint}, values); len(result.Errors) != 0 {
+			t.Errorf("expected no errors, got %v", result.Errors)
+		}
+	})
+
+	t.Run("should run the enabled optional rules", func(t *testing.T) {
+		optionalRule := lint.RuleFunc(lint.RuleMetadata{ID: "house/optional", Severity: support.ErrorSev, Optional: true}, func(linter *support.Linter, _ lint.Options) {
+			linter.RunLinterRule(support.ErrorSev, "templates/", errors.New("missing annotation"))
+		})
+		testLint := NewLint()
+		testLint.Rules = []lint.Rule{optionalRule}
+		if result := testLint.Run([]string{chart1MultipleChartLint}, values); len(result.Errors) != 0 {
+			t.Errorf("expected no errors, got %v", result.Errors)
+		}
+		testLint.EnabledRules = []string{"house/optional"}
+		if result := testLint.Run([]string{chart1MultipleChartLint}, values); len(result.Errors) != 1 {
+			t.Errorf("expected 1 error, got %v", result.Errors)
+		}
+	})
+
+	t.Run("should override the severities of the rules", func(t *testing.T) {
+		testLint := NewLint()
+		testLint.Rules = []lint.Rule{houseRule}
+		testLint.RuleSeverities = map[string]int{"house/labels": support.WarningSev}
+		result := testLint.Run([]string{chart1MultipleChartLint}, values)
+		if len(result.Errors) != 0 {
+			t.Errorf("expected no errors, got %v", result.Errors)
+		}
+		msg := result.Messages[len(result.Messages)-1]
+		if msg.Severity != support.WarningSev || msg.Rule != "house/labels" {
+			t.Errorf("expected a warning of rule house/labels, got %v", msg)
+		}
+	})
+
+	t.Run("should fail on unknown disabled rules", func(t *testing.T) {
+		testLint := NewLint()
+		testLint.DisabledRules = []string{"unknown"}
+		if result := testLint.Run([]string{chart1MultipleChartLint}, values); len(result.Errors) != 1 {
+			t.Errorf("expected 1 error, got %v", result.Errors)
+		}
+	})
+
+	t.Run("should fail on unknown rule severities", func(t *testing.T) {
+		testLint := NewLint()
+		testLint.RuleSeverities = map[string]int{"unknown": support.InfoSev}
+		if result := testLint.Run([]string{chart1MultipleChartLint}, values); len(result.Errors) != 1 {
+			t.Errorf("expected 1 error, got %v", result.Errors)
+		}
+	})
+}
+
+func TestLint_Config(t *testing.T) {
+	t.Run("should apply the configuration of the chart", func(t *testing.T) {
+		testLint := NewLint()
+		result := testLint.Run([]string{chartWithLintConfig}, values)
+		if len(result.Errors) != 0 {
+			t.Errorf("expected no errors, got %v", result.Errors)
+		}
+		if len(result.Ignored) != 1 || result.Ignored[0].Path != "templates/debug-pod.yaml" {
+			t.Errorf("expected the failure of the debug pod to be ignored, got %v", result.Ignored)
+		}
+
+		result = testLint.Run([]string{chartWithLintConfig}, map[string]interface{}{"image": "nginx:latest"})
+		if len(result.Errors) != 1 {
+			t.Fatalf("expected 1 error, got %v", result.Errors)
+		}
+		if msg := result.Messages[len(result.Messages)-1]; msg.Rule != "workload-image-tag" || msg.Severity != support.ErrorSev {
+			t.Errorf("expected an error of rule workload-image-tag, got %v", msg)
+		}
+	})
+
+	t.Run("should let the flags override the configuration", func(t *testing.T) {
+		testLint := NewLint()
+		testLint.RuleSeverities = map[string]int{"workload-image-tag": support.WarningSev}
+		result := testLint.Run([]string{chartWithLintConfig}, map[string]interface{}{"image": "nginx:latest"})
+		if len(result.Errors) != 0 {
+			t.Errorf("expected no errors, got %v", result.Errors)
+		}
+	})
+
+	t.Run("should use the configuration file instead", func(t *testing.T) {
+		configFile := filepath.Join(t.TempDir(), "helmlintrc")
+		if err := os.WriteFile(configFile, []byte("rules:\n  enable: [workload-image-tag]\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		testLint := NewLint()
+		testLint.Strict = true
+		testLint.ConfigFile = configFile
+		result := testLint.Run([]string{chartWithLintConfig}, values)
+		if len(result.Errors) != 1 || len(result.Ignored) != 0 {
+			t.Errorf("expected 1 error and no ignored messages, got %v and %v", result.Errors, result.Ignored)
+		}
+	})
+
+	t.Run("should fail on invalid configuration files", func(t *testing.T) {
+		configFile := filepath.Join(t.TempDir(), "helmlintrc")
+		if err := os.WriteFile(configFile, []byte("ignores:\n- rule: templates\n  path: templates/pod.yaml\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		testLint := NewLint()
+		testLint.ConfigFile = configFile
+		if result := testLint.Run([]string{chartWithLintConfig}, values); len(result.Errors) != 1 || result.TotalChartsLinted != 0 {
+			t.Errorf("expected 1 error, got %v", result.Errors)
+		}
+	})
+}
+
+func TestLint_Recursive(t *testing.T) {
+	chartPaths := func(result *LintResult) []string {
+		var paths []string
+		for _, c := range result.Charts {
+			paths = append(paths, c.Path)
+		}
+		return paths
+	}
+
+	t.Run("should lint the subcharts with the values of their parents", func(t *testing.T) {
+		testLint := NewLint()
+		testLint.Recursive = true
+		result := testLint.Run([]string{chartWithSubchartsLint}, values)
+		expected := []string{
+			chartWithSubchartsLint,
+			filepath.Join(chartWithSubchartsLint, "charts", "child"),
+			filepath.Join(chartWithSubchartsLint, "charts", "other-child"),
+		}
+		if paths := chartPaths(result); !reflect.DeepEqual(paths, expected) {
+			t.Fatalf("expected the charts %v, got %v", expected, paths)
+		}
+		if result.TotalChartsLinted != 3 {
+			t.Errorf("expected 3 charts linted, got %d", result.TotalChartsLinted)
+		}
+		if len(result.Charts[1].Errors) != 0 {
+			t.Errorf("expected no errors in child, got %v", result.Charts[1].Errors)
+		}
+		if len(result.Charts[2].Errors) == 0 || !strings.Contains(result.Charts[2].Errors[0].Error(), "image: String length must be greater than or equal to 1") {
+			t.Errorf("expected the image to be required in other-child, got %v", result.Charts[2].Errors)
+		}
+	})
+
+	t.Run("should only lint the included subcharts", func(t *testing.T) {
+		testLint := NewLint()
+		testLint.Recursive = true
+		testLint.Subcharts = []string{"child"}
+		result := testLint.Run([]string{chartWithSubchartsLint}, values)
+		expected := []string{chartWithSubchartsLint, filepath.Join(chartWithSubchartsLint, "charts", "child")}
+		if paths := chartPaths(result); !reflect.DeepEqual(paths, expected) {
+			t.Errorf("expected the charts %v, got %v", expected, paths)
+		}
+		if len(result.Charts[1].Errors) != 0 {
+			t.Errorf("expected no errors in child, got %v", result.Charts[1].Errors)
+		}
+	})
+
+	t.Run("should not lint the subcharts by default", func(t *testing.T) {
+		result := NewLint().Run([]string{chartWithSubchartsLint}, values)
+		if paths := chartPaths(result); len(paths) != 1 {
+			t.Errorf("expected only the chart, got %v", paths)
+		}
+	})
+}
+
+func TestLint_ValuesOnly(t *testing.T) {
+	testLint := NewLint()
+	testLint.ValuesOnly = true
+	result := testLint.Run([]string{chartWithSubchartsLint}, values)
+	if len(result.Errors) != 1 || !strings.Contains(result.Errors[0].Error(), "other-child:\n- image: String length must be greater than or equal to 1") {
+		t.Errorf("expected the image of other-child to be required, got %v", result.Errors)
+	}
+	for _, msg := range result.Messages {
+		if msg.Rule != "values-schemas" {
+			t.Errorf("expected only messages of rule values-schemas, got %v of rule %s", msg, msg.Rule)
+		}
+	}
+
+	result = testLint.Run([]string{chartWithSubchartsLint}, map[string]interface{}{
+		"other-child": map[string]interface{}{"image": "nginx:1.25"},
+	})
+	if len(result.Errors) != 0 {
+		t.Errorf("expected no errors, got %v", result.Errors)
+	}
+}
+
+func TestLint_WithScenarios(t *testing.T) {
+	testLint := NewLint()
+	testLint.WithScenarios = true
+	testLint.EnabledRules = []string{"manifest-schemas"}
+	result := testLint.Run([]string{chartWithScenarios}, values)
+	if len(result.Charts) != 2 || result.TotalChartsLinted != 2 {
+		t.Fatalf("expected the chart linted twice, got %d results", len(result.Charts))
+	}
+	for i, scenario := range []string{"ci/default-values.yaml", "ci/ingress-values.yaml"} {
+		if c := result.Charts[i]; c.Path != chartWithScenarios || c.Scenario != scenario {
+			t.Errorf("expected the chart linted with %s, got %s with %s", scenario, c.Path, c.Scenario)
+		}
+	}
+	if len(result.Charts[0].Errors) != 0 {
+		t.Errorf("expected no errors with the default scenario, got %v", result.Charts[0].Errors)
+	}
+	// The port of the backend of the ingress is an object
+	if errs := result.Charts[1].Errors; len(errs) != 1 || !strings.Contains(errs[0].Error(), `networking.k8s.io/v1 Ingress "test-release" does not match its schema`) {
+		t.Errorf("expected the ingress to be invalid with the ingress scenario, got %v", errs)
+	}
+
+	testLint.WithScenarios = false
+	if result := testLint.Run([]string{chartWithScenarios}, values); len(result.Charts) != 1 || len(result.Errors) != 0 {
+		t.Errorf("expected the chart linted once without errors, got %d results and %v", len(result.Charts), result.Errors)
+	}
+}
+
+func TestLint_KubeVersions(t *testing.T) {
+	testLint := NewLint()
+	testLint.Strict = true
+	for _, v := range []string{"1.21.0", "1.25.0"} {
+		kubeVersion, err := chartutil.ParseKubeVersion(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testLint.KubeVersions = append(testLint.KubeVersions, kubeVersion)
+	}
+	result := testLint.Run([]string{chartWithDeprecatedAPI}, values)
+	if len(result.Charts) != 2 || result.TotalChartsLinted != 2 {
+		t.Fatalf("expected the chart linted twice, got %d results", len(result.Charts))
+	}
+	for i, kubeVersion := range []string{"v1.21.0", "v1.25.0"} {
+		if c := result.Charts[i]; c.KubeVersion != kubeVersion {
+			t.Errorf("expected the chart linted for %s, got %s", kubeVersion, c.KubeVersion)
+		}
+	}
+	if errs := result.Charts[0].Errors; len(errs) != 0 {
+		t.Errorf("expected no errors for v1.21.0, got %v", errs)
+	}
+	// The autoscaling/v2beta1 API is removed in v1.25
+	if errs := result.Charts[1].Errors; len(errs) != 1 || !strings.Contains(errs[0].Error(), "unavailable in v1.25+") {
+		t.Errorf("expected the removed API reported for v1.25.0, got %v", errs)
+	}
+}
+
+func TestLint_Fix(t *testing.T) {
+	chartDir := filepath.Join(t.TempDir(), "fixme")
+	if err := os.MkdirAll(filepath.Join(chartDir, "templates"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte("name: fixme\nversion: 0.1.0\nicon: example.com/icon.png\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testLint := NewLint()
+	if result := testLint.Run([]string{chartDir}, values); len(result.Errors) == 0 {
+		t.Fatal("expected errors before the fixes")
+	}
+
+	testLint.Fix = true
+	result := testLint.Run([]string{chartDir}, values)
+	if len(result.Errors) != 0 {
+		t.Errorf("expected no errors after the fixes, got %v", result.Errors)
+	}
+	if fixes := result.Charts[0].Fixes; len(fixes) != 1 || fixes[0].Path != "Chart.yaml" {
+		t.Fatalf("expected a fix of Chart.yaml, got %v", fixes)
+	}
+	data, err := os.ReadFile(filepath.Join(chartDir, "Chart.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "apiVersion: v1\nname: fixme\nversion: 0.1.0\nicon: https://example.com/icon.png\n"; string(data) != expected {
+		t.Errorf("expected Chart.yaml fixed as:\n%s\ngot:\n%s", expected, data)
+	}
+}
+
+func TestLint_Incremental(t *testing.T) {
+	chartDir := filepath.Join(t.TempDir(), "incremental")
+	if err := os.MkdirAll(filepath.Join(chartDir, "templates"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	chartfile := filepath.Join(chartDir, "Chart.yaml")
+	if err := os.WriteFile(chartfile, []byte("apiVersion: v2\nname: incremental\nversion: 0.1.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testLint := NewLint()
+	testLint.Incremental = true
+	testLint.CacheDir = t.TempDir()
+	if result := testLint.Run([]string{chartDir}, values); len(result.Errors) != 0 {
+		t.Fatalf("expected no errors, got %v", result.Errors)
+	}
+
+	if err := os.WriteFile(chartfile, []byte("apiVersion: v2\nname: incremental\nversion: bad\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// The changed files outside the chart are skipped, the messages are cached
+	testLint.ChangedFiles = []string{"elsewhere/Chart.yaml"}
+	if result := testLint.Run([]string{chartDir}, values); len(result.Errors) != 0 {
+		t.Errorf("expected the cached messages without errors, got %v", result.Errors)
+	}
+
+	testLint.ChangedFiles = []string{chartfile}
+	if result := testLint.Run([]string{chartDir}, values); len(result.Errors) == 0 {
+		t.Errorf("expected the invalid version reported, got %v", result.Errors)
+	}
+
+	// The modification times detect the changes without changed files
+	testLint.ChangedFiles = nil
+	if err := os.WriteFile(chartfile, []byte("apiVersion: v2\nname: incremental\nversion: 0.2.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if result := testLint.Run([]string{chartDir}, values); len(result.Errors) != 0 {
+		t.Errorf("expected no errors, got %v", result.Errors)
+	}
+}
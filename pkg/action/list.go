@@ -21,9 +21,12 @@ import (
 	"regexp"
 
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
 
 	"helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/releaseutil"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
 )
 
 // ListStates represents zero or more status codes that a list item may have set
@@ -80,6 +83,33 @@ func (s ListStates) FromName(str string) ListStates {
 	return ListUnknown
 }
 
+// statuses returns the release statuses selected by the mask. It returns
+// false if the mask selects unknown statuses, which cannot be enumerated.
+func (s ListStates) statuses() ([]release.Status, bool) {
+	if s&ListUnknown != 0 {
+		return nil, false
+	}
+	var statuses []release.Status
+	for _, m := range []struct {
+		state  ListStates
+		status release.Status
+	}{
+		{ListDeployed, release.StatusDeployed},
+		{ListUninstalled, release.StatusUninstalled},
+		{ListUninstalling, release.StatusUninstalling},
+		{ListPendingInstall, release.StatusPendingInstall},
+		{ListPendingUpgrade, release.StatusPendingUpgrade},
+		{ListPendingRollback, release.StatusPendingRollback},
+		{ListSuperseded, release.StatusSuperseded},
+		{ListFailed, release.StatusFailed},
+	} {
+		if s&m.state != 0 {
+			statuses = append(statuses, m.status)
+		}
+	}
+	return statuses, true
+}
+
 // ListAll is a convenience for enabling all list filters
 const ListAll = ListDeployed | ListUninstalled | ListUninstalling | ListPendingInstall | ListPendingRollback | ListPendingUpgrade | ListSuperseded | ListFailed
 
@@ -134,6 +164,13 @@ type List struct {
 	Failed       bool
 	Pending      bool
 	Selector     string
+	// Annotations are the annotations the listed releases have, with the
+	// same values.
+	Annotations map[string]string
+	// PageSize is the number of release records requested from the storage
+	// backend at a time, if the driver supports pagination. All records are
+	// requested at once if it is zero.
+	PageSize int64
 }
 
 // NewList constructs a new *List
@@ -159,15 +196,12 @@ func (l *List) Run() ([]*release.Release, error) {
 		}
 	}
 
-	results, err := l.cfg.Releases.List(func(rel *release.Release) bool {
-		// Skip anything that doesn't match the filter.
-		if filter != nil && !filter.MatchString(rel.Name) {
-			return false
-		}
-
-		return true
-	})
+	selectorObj, err := labels.Parse(l.Selector)
+	if err != nil {
+		return nil, err
+	}
 
+	results, err := l.listReleases(filter, selectorObj)
 	if err != nil {
 		return nil, err
 	}
@@ -188,11 +222,8 @@ func (l *List) Run() ([]*release.Release, error) {
 	results = l.filterStateMask(results)
 
 	// Skip anything that doesn't match the selector
-	selectorObj, err := labels.Parse(l.Selector)
-	if err != nil {
-		return nil, err
-	}
 	results = l.filterSelector(results, selectorObj)
+	results = l.filterAnnotations(results)
 
 	// Unfortunately, we have to sort before truncating, which can incur substantial overhead
 	l.sort(results)
@@ -216,6 +247,106 @@ func (l *List) Run() ([]*release.Release, error) {
 	return results, err
 }
 
+// listNamesPerRequest is the number of releases whose revisions are
+// requested from the storage backend at a time.
+const listNamesPerRequest = 100
+
+// listReleases returns the release revisions matching the name filter that
+// are needed to compute the results of the List action.
+//
+// If the driver filters releases in its storage backend, only the revisions
+// of the releases that have a revision matching the selector and the state
+// mask are listed: a release can only be in the results if one of its
+// revisions matches, and all its revisions are needed to find the latest.
+func (l *List) listReleases(filter *regexp.Regexp, selector labels.Selector) ([]*release.Release, error) {
+	matchName := func(rel *release.Release) bool {
+		// Skip anything that doesn't match the filter.
+		return filter == nil || filter.MatchString(rel.Name)
+	}
+
+	d := l.cfg.Releases.Driver
+	if i, ok := d.(*driver.Instrumented); ok {
+		d = i.Unwrap()
+	}
+	if _, ok := d.(driver.Pager); !ok {
+		return l.cfg.Releases.List(matchName)
+	}
+
+	opts := driver.ListOptions{Limit: l.PageSize}
+	statuses, ok := l.StateMask.statuses()
+	if ok && l.StateMask&ListAll != ListAll {
+		opts.Statuses = statuses
+	}
+	if !selector.Empty() {
+		opts.Selector = selector
+	}
+	if opts.Statuses == nil && opts.Selector == nil {
+		return listPages(l.cfg.Releases, opts, matchName)
+	}
+
+	candidates, err := listPages(l.cfg.Releases, opts, matchName)
+	if err != nil {
+		return nil, err
+	}
+	// superseded revisions are listed as is, without looking for the latest
+	// revisions, see Run
+	if l.StateMask == ListSuperseded {
+		return candidates, nil
+	}
+
+	seen := map[string]bool{}
+	var names []string
+	for _, rel := range candidates {
+		if !seen[rel.Name] {
+			seen[rel.Name] = true
+			names = append(names, rel.Name)
+		}
+	}
+
+	var results []*release.Release
+	for len(names) > 0 {
+		n := len(names)
+		if n > listNamesPerRequest {
+			n = listNamesPerRequest
+		}
+		req, err := labels.NewRequirement("name", selection.In, names[:n])
+		if err != nil {
+			return nil, err
+		}
+		names = names[n:]
+
+		rels, err := listPages(l.cfg.Releases, driver.ListOptions{
+			Selector: labels.NewSelector().Add(*req),
+			Limit:    l.PageSize,
+		}, matchName)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, rels...)
+	}
+	return results, nil
+}
+
+// listPages lists all the pages of the releases selected by opts.
+func listPages(store *storage.Storage, opts driver.ListOptions, filter func(*release.Release) bool) ([]*release.Release, error) {
+	var results []*release.Release
+	for {
+		rels, next, err := store.ListPage(opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, rel := range rels {
+			if filter(rel) {
+				results = append(results, rel)
+			}
+		}
+		if next == "" {
+			return results, nil
+		}
+		opts.Continue = next
+	}
+}
+
 // sort is an in-place sort where order is based on the value of a.Sort
 func (l *List) sort(rels []*release.Release) {
 	if l.SortReverse {
@@ -288,6 +419,28 @@ func (l *List) filterSelector(releases []*release.Release, selector labels.Selec
 	return desiredStateReleases
 }
 
+func (l *List) filterAnnotations(releases []*release.Release) []*release.Release {
+	if len(l.Annotations) == 0 {
+		return releases
+	}
+
+	desiredStateReleases := make([]*release.Release, 0)
+	for _, rls := range releases {
+		matches := true
+		for k, v := range l.Annotations {
+			if value, ok := rls.Annotations[k]; !ok || value != v {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			desiredStateReleases = append(desiredStateReleases, rls)
+		}
+	}
+
+	return desiredStateReleases
+}
+
 // SetStateMask calculates the state mask based on parameters.
 func (l *List) SetStateMask() {
 	if l.All {
@@ -0,0 +1,66 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// ContextConfigurer returns the configuration of the actions run against a
+// kubeconfig context.
+type ContextConfigurer func(kubeContext string) (*Configuration, error)
+
+// ContextReleases are the releases listed in a kubeconfig context.
+type ContextReleases struct {
+	// Context is the name of the kubeconfig context.
+	Context string
+	// Releases are the releases listed in the context.
+	Releases []*release.Release
+	// Err is the error of the context, if it could not be listed.
+	Err error
+}
+
+// RunContexts runs the list in each one of the kubeconfig contexts
+// concurrently, with the configuration returned by configure for the context
+// instead of the one of the list. The results are in the order of the
+// contexts, and the error of a context does not stop the others from being
+// listed.
+func (l *List) RunContexts(contexts []string, configure ContextConfigurer) []ContextReleases {
+	results := make([]ContextReleases, len(contexts))
+	var wg sync.WaitGroup
+	for i, kubeContext := range contexts {
+		wg.Add(1)
+		go func(i int, kubeContext string) {
+			defer wg.Done()
+			results[i] = ContextReleases{Context: kubeContext}
+			cfg, err := configure(kubeContext)
+			if err != nil {
+				results[i].Err = errors.Wrapf(err, "unable to configure context %q", kubeContext)
+				return
+			}
+			list := *l
+			list.cfg = cfg
+			results[i].Releases, results[i].Err = list.Run()
+		}(i, kubeContext)
+	}
+	wg.Wait()
+	return results
+}
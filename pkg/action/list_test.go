@@ -17,12 +17,15 @@ limitations under the License.
 package action
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
 
 	"helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
 )
 
 func TestListStates(t *testing.T) {
@@ -366,3 +369,119 @@ func TestSelectorList(t *testing.T) {
 		assert.ElementsMatch(t, expectedFilteredList, res)
 	})
 }
+
+func TestAnnotationsList(t *testing.T) {
+	r1 := releaseStub()
+	r1.Name = "r1"
+	r1.Annotations = map[string]string{"team": "web", "ticket": "OPS-1"}
+	r2 := releaseStub()
+	r2.Name = "r2"
+	r2.Annotations = map[string]string{"team": "web"}
+	r3 := releaseStub()
+	r3.Name = "r3"
+
+	lister := newListFixture(t)
+	for _, rel := range []*release.Release{r1, r2, r3} {
+		if err := lister.cfg.Releases.Create(rel); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tt := range []struct {
+		annotations map[string]string
+		expected    []*release.Release
+	}{
+		{nil, []*release.Release{r1, r2, r3}},
+		{map[string]string{"team": "web"}, []*release.Release{r1, r2}},
+		{map[string]string{"team": "web", "ticket": "OPS-1"}, []*release.Release{r1}},
+		{map[string]string{"team": "api"}, []*release.Release{}},
+	} {
+		lister.Annotations = tt.annotations
+		res, err := lister.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.ElementsMatch(t, tt.expected, res, "annotations %v", tt.annotations)
+	}
+}
+
+func TestListPushdown(t *testing.T) {
+	is := assert.New(t)
+
+	lister := newListFixture(t)
+	lister.cfg.Releases = storage.Init(driver.NewSecrets(fake.NewSimpleClientset().CoreV1().Secrets("default")))
+	lister.PageSize = 1
+
+	mock := func(name string, version int, status release.Status, team string) *release.Release {
+		rel := release.Mock(&release.MockReleaseOptions{Name: name, Version: version, Status: status})
+		rel.Labels = map[string]string{"team": team}
+		return rel
+	}
+	for _, rel := range []*release.Release{
+		// the latest revision of each release is the last one
+		mock("angry-bird", 1, release.StatusSuperseded, "a"),
+		mock("angry-bird", 2, release.StatusDeployed, "a"),
+		mock("smug-pigeon", 1, release.StatusDeployed, "a"),
+		mock("smug-pigeon", 2, release.StatusFailed, "b"),
+		mock("happy-panda", 1, release.StatusDeployed, "b"),
+	} {
+		is.NoError(lister.cfg.Releases.Create(rel))
+	}
+
+	names := func(rels []*release.Release) []string {
+		var names []string
+		for _, rel := range rels {
+			names = append(names, fmt.Sprintf("%s.v%d", rel.Name, rel.Version))
+		}
+		return names
+	}
+
+	// an older revision that matches does not hide the latest revision
+	lister.StateMask = ListDeployed
+	rels, err := lister.Run()
+	is.NoError(err)
+	is.Equal([]string{"angry-bird.v2", "happy-panda.v1"}, names(rels))
+
+	lister.StateMask = ListAll
+	lister.Selector = "team=a"
+	rels, err = lister.Run()
+	is.NoError(err)
+	is.Equal([]string{"angry-bird.v2"}, names(rels))
+
+	lister.StateMask = ListSuperseded
+	lister.Selector = ""
+	rels, err = lister.Run()
+	is.NoError(err)
+	is.Equal([]string{"angry-bird.v1"}, names(rels))
+}
+
+func TestList_RunContexts(t *testing.T) {
+	is := assert.New(t)
+	prod := actionConfigFixture(t)
+	makeMeSomeReleases(prod.Releases, t)
+	staging := actionConfigFixture(t)
+	rel := namedReleaseStub("canary", release.StatusDeployed)
+	is.NoError(staging.Releases.Create(rel))
+
+	configs := map[string]*Configuration{"prod": prod, "staging": staging}
+	lister := NewList(nil)
+	results := lister.RunContexts([]string{"staging", "prod", "missing"}, func(kubeContext string) (*Configuration, error) {
+		cfg, ok := configs[kubeContext]
+		if !ok {
+			return nil, fmt.Errorf("context %q does not exist", kubeContext)
+		}
+		return cfg, nil
+	})
+
+	is.Len(results, 3)
+	is.Equal("staging", results[0].Context)
+	is.NoError(results[0].Err)
+	is.Len(results[0].Releases, 1)
+	is.Equal("canary", results[0].Releases[0].Name)
+	is.Equal("prod", results[1].Context)
+	is.NoError(results[1].Err)
+	is.Len(results[1].Releases, 3)
+	is.Equal("missing", results[2].Context)
+	is.Error(results[2].Err)
+	is.Nil(lister.cfg)
+}
@@ -18,6 +18,7 @@ package action
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"os"
 	"syscall"
@@ -36,6 +37,8 @@ import (
 // It provides the implementation of 'helm package'.
 type Package struct {
 	Sign             bool
+	SignCosign       bool
+	Cosign           CosignSignOptions
 	Key              string
 	Keyring          string
 	PassphraseFile   string
@@ -97,7 +100,12 @@ func (p *Package) Run(path string, _ map[string]interface{}) (string, error) {
 	}
 
 	if p.Sign {
-		err = p.Clearsign(name)
+		if err := p.Clearsign(name); err != nil {
+			return name, err
+		}
+	}
+	if p.SignCosign {
+		err = p.SignBundle(name)
 	}
 
 	return name, err
@@ -139,6 +147,32 @@ func (p *Package) Clearsign(filename string) error {
 	return os.WriteFile(filename+".prov", []byte(sig), 0644)
 }
 
+// SignBundle signs a chart with a sigstore signature, written to a cosign
+// bundle alongside the chart archive.
+func (p *Package) SignBundle(filename string) error {
+	opts := p.Cosign
+	if opts.PassphraseFile == "" {
+		opts.PassphraseFile = p.PassphraseFile
+	}
+	signer, err := opts.Signer()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	bundle, err := signer.SignBlob(data)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(bundle)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename+CosignBundleExt, b, 0644)
+}
+
 // promptUser implements provenance.PassphraseFetcher
 func promptUser(name string) ([]byte, error) {
 	fmt.Printf("Password for key %q >  ", name)
@@ -17,13 +17,23 @@ limitations under the License.
 package action
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
 	"os"
 	"path"
+	"path/filepath"
 	"testing"
 
 	"github.com/Masterminds/semver/v3"
 
 	"helm.sh/helm/v3/internal/test/ensure"
+	"helm.sh/helm/v3/pkg/registry"
 )
 
 func TestPassphraseFileFetcher(t *testing.T) {
@@ -119,3 +129,49 @@ func TestValidateVersion(t *testing.T) {
 		})
 	}
 }
+
+func TestPackage_SignCosign(t *testing.T) {
+	dir := t.TempDir()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(dir, "cosign.key")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewPackage()
+	client.Destination = dir
+	client.SignCosign = true
+	client.Cosign.Key = keyFile
+	name, err := client.Run("testdata/charts/chart-with-schema", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(name + CosignBundleExt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bundle registry.SignatureBundle
+	if err := json.Unmarshal(b, &bundle); err != nil {
+		t.Fatal(err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(bundle.Base64Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+	if !ecdsa.VerifyASN1(&key.PublicKey, sum[:], sig) {
+		t.Error("Expected the bundle to hold a signature of the chart archive")
+	}
+}
@@ -18,6 +18,7 @@ package action
 
 import (
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -46,10 +47,30 @@ type Pull struct {
 	UntarDir    string
 	DestDir     string
 	cfg         *Configuration
+	result      *PullResult
+}
+
+// PullResult is the result of the pull of a chart.
+type PullResult struct {
+	// Ref is the reference of the pulled chart.
+	Ref string `json:"ref"`
+	// Path is the path of the downloaded chart archive, or the directory the
+	// chart was expanded into when it is untarred.
+	Path string `json:"path"`
+	// Hash is the hash of the chart archive, set when it is verified.
+	Hash string `json:"hash,omitempty"`
 }
 
 type PullOpt func(*Pull)
 
+// WithPullResult sets the result of the pull of a chart, set once the chart
+// is pulled.
+func WithPullResult(result *PullResult) PullOpt {
+	return func(p *Pull) {
+		p.result = result
+	}
+}
+
 func WithConfig(cfg *Configuration) PullOpt {
 	return func(p *Pull) {
 		p.cfg = cfg
@@ -105,6 +126,11 @@ func (p *Pull) Run(chartRef string) (string, error) {
 
 	if p.Verify {
 		c.Verify = downloader.VerifyAlways
+		policy, err := LoadSignaturePolicy(p.Settings)
+		if err != nil {
+			return out.String(), err
+		}
+		c.SignaturePolicy = policy
 	} else if p.VerifyLater {
 		c.Verify = downloader.VerifyLater
 	}
@@ -121,6 +147,7 @@ func (p *Pull) Run(chartRef string) (string, error) {
 		defer os.RemoveAll(dest)
 	}
 
+	ref := chartRef
 	if p.RepoURL != "" {
 		chartURL, err := repo.FindChartInAuthAndTLSAndPassRepoURL(p.RepoURL, p.Username, p.Password, chartRef, p.Version, p.CertFile, p.KeyFile, p.CaFile, p.InsecureSkipTLSverify, p.PassCredentialsAll, getter.All(p.Settings))
 		if err != nil {
@@ -135,10 +162,14 @@ func (p *Pull) Run(chartRef string) (string, error) {
 	}
 
 	if p.Verify {
-		for name := range v.SignedBy.Identities {
-			fmt.Fprintf(&out, "Signed by: %v\n", name)
+		if v.SignedBy != nil {
+			for name := range v.SignedBy.Identities {
+				fmt.Fprintf(&out, "Signed by: %v\n", name)
+			}
+			fmt.Fprintf(&out, "Using Key With Fingerprint: %X\n", v.SignedBy.PrimaryKey.Fingerprint)
+		} else {
+			fmt.Fprintf(&out, "Signed by: %s\n", v.Signer)
 		}
-		fmt.Fprintf(&out, "Using Key With Fingerprint: %X\n", v.SignedBy.PrimaryKey.Fingerprint)
 		fmt.Fprintf(&out, "Chart Hash Verified: %s\n", v.FileHash)
 	}
 
@@ -166,7 +197,29 @@ func (p *Pull) Run(chartRef string) (string, error) {
 			return out.String(), errors.Errorf("failed to untar: a file or directory with the name %s already exists", udCheck)
 		}
 
-		return out.String(), chartutil.ExpandFile(ud, saved)
+		if err := chartutil.ExpandFile(ud, saved); err != nil {
+			return out.String(), err
+		}
+		saved = ud
+	}
+	if p.result != nil {
+		*p.result = PullResult{Ref: ref, Path: saved}
+		if p.Verify {
+			p.result.Hash = v.FileHash
+		}
 	}
 	return out.String(), nil
 }
+
+// LoadSignaturePolicy loads the policy verifying the signatures of charts, or
+// returns nil if there is no policy file.
+func LoadSignaturePolicy(settings *cli.EnvSettings) (*registry.SignaturePolicy, error) {
+	if settings == nil || settings.SignaturePolicy == "" {
+		return nil, nil
+	}
+	policy, err := registry.LoadSignaturePolicy(settings.SignaturePolicy)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return policy, err
+}
@@ -20,6 +20,8 @@ import (
 	"io"
 	"strings"
 
+	"github.com/pkg/errors"
+
 	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/pusher"
 	"helm.sh/helm/v3/pkg/registry"
@@ -37,6 +39,8 @@ type Push struct {
 	caFile                string
 	insecureSkipTLSverify bool
 	plainHTTP             bool
+	cosign                *CosignSignOptions
+	result                *registry.PushResult
 	out                   io.Writer
 }
 
@@ -73,6 +77,21 @@ func WithPlainHTTP(plainHTTP bool) PushOpt {
 	}
 }
 
+// WithCosignSign signs the pushed chart with a sigstore signature.
+func WithCosignSign(opts *CosignSignOptions) PushOpt {
+	return func(p *Push) {
+		p.cosign = opts
+	}
+}
+
+// WithPushResult sets the result of the push of a chart to a registry, set
+// once the chart is pushed.
+func WithPushResult(result *registry.PushResult) PushOpt {
+	return func(p *Push) {
+		p.result = result
+	}
+}
+
 // WithOptWriter sets the registryOut field on the push configuration object.
 func WithPushOptWriter(out io.Writer) PushOpt {
 	return func(p *Push) {
@@ -106,6 +125,20 @@ func (p *Push) Run(chartRef string, remote string) (string, error) {
 	if registry.IsOCI(remote) {
 		// Don't use the default registry client if tls options are set.
 		c.Options = append(c.Options, pusher.WithRegistryClient(p.cfg.RegistryClient))
+		if p.result != nil {
+			c.Options = append(c.Options, pusher.WithPushResult(p.result))
+		}
+	}
+
+	if p.cosign != nil {
+		if !registry.IsOCI(remote) {
+			return out.String(), errors.New("only charts pushed to registries can be signed")
+		}
+		signer, err := p.cosign.Signer()
+		if err != nil {
+			return out.String(), err
+		}
+		c.Options = append(c.Options, pusher.WithSigner(signer))
 	}
 
 	return out.String(), c.UploadTo(chartRef, remote)
@@ -0,0 +1,70 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// Recompress is the action for rewriting stored release records.
+//
+// It provides the implementation of 'helm release recompress'. Every revision
+// is written back through the storage driver, so the records are stored with
+// the current compression and encryption settings of the configuration.
+type Recompress struct {
+	cfg *Configuration
+}
+
+// NewRecompress creates a new Recompress object with the given configuration.
+func NewRecompress(cfg *Configuration) *Recompress {
+	return &Recompress{
+		cfg: cfg,
+	}
+}
+
+// Run rewrites all the revisions of the named release, or of all releases of
+// the namespace if name is empty. It returns the rewritten revisions.
+func (r *Recompress) Run(name string) ([]*release.Release, error) {
+	if err := r.cfg.KubeClient.IsReachable(); err != nil {
+		return nil, err
+	}
+
+	var rels []*release.Release
+	var err error
+	if name == "" {
+		rels, err = r.cfg.Releases.ListReleases()
+	} else {
+		if err := chartutil.ValidateReleaseName(name); err != nil {
+			return nil, errors.Errorf("release name is invalid: %s", name)
+		}
+		rels, err = r.cfg.Releases.History(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i, rel := range rels {
+		r.cfg.Log("recompressing release %s revision %d", rel.Name, rel.Version)
+		if err := r.cfg.Releases.Update(rel); err != nil {
+			return rels[:i], errors.Wrapf(err, "failed to rewrite release %s revision %d", rel.Name, rel.Version)
+		}
+	}
+	return rels, nil
+}
@@ -0,0 +1,96 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+func TestRecompress(t *testing.T) {
+	secrets := fake.NewSimpleClientset().CoreV1().Secrets("default")
+	d := driver.NewSecrets(secrets)
+
+	config := actionConfigFixture(t)
+	config.Releases = storage.Init(d)
+	for _, rel := range []*release.Release{
+		release.Mock(&release.MockReleaseOptions{Name: "angry-bird", Version: 1}),
+		release.Mock(&release.MockReleaseOptions{Name: "angry-bird", Version: 2}),
+		release.Mock(&release.MockReleaseOptions{Name: "smug-pigeon", Version: 1}),
+	} {
+		if err := config.Releases.Create(rel); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	isZstd := func(name string) bool {
+		t.Helper()
+		obj, err := secrets.Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := base64.StdEncoding.DecodeString(string(obj.Data["release"]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return bytes.HasPrefix(b, []byte{0x28, 0xb5, 0x2f, 0xfd})
+	}
+
+	d.Compression = driver.Compression{Algorithm: driver.CompressionZstd}
+	rels, err := NewRecompress(config).Run("angry-bird")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rels) != 2 {
+		t.Errorf("Expected 2 revisions to be rewritten, got %d", len(rels))
+	}
+	if !isZstd("sh.helm.release.v1.angry-bird.v1") || !isZstd("sh.helm.release.v1.angry-bird.v2") {
+		t.Error("Expected the revisions of angry-bird to be compressed with zstd")
+	}
+	if isZstd("sh.helm.release.v1.smug-pigeon.v1") {
+		t.Error("Expected smug-pigeon not to be rewritten")
+	}
+
+	rels, err = NewRecompress(config).Run("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rels) != 3 {
+		t.Errorf("Expected 3 revisions to be rewritten, got %d", len(rels))
+	}
+	if !isZstd("sh.helm.release.v1.smug-pigeon.v1") {
+		t.Error("Expected smug-pigeon to be compressed with zstd")
+	}
+
+	// the rewritten records are read back
+	rel, err := config.Releases.Get("angry-bird", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rel.Version != 2 {
+		t.Errorf("Expected revision 2, got %d", rel.Version)
+	}
+}
@@ -29,6 +29,7 @@ type RegistryLogin struct {
 	keyFile  string
 	caFile   string
 	insecure bool
+	oidc     *registry.OIDCLogin
 }
 
 type RegistryLoginOpt func(*RegistryLogin) error
@@ -65,6 +66,15 @@ func WithCAFile(caFile string) RegistryLoginOpt {
 	}
 }
 
+// WithOIDC specifies that the login exchanges an OIDC identity token for the
+// registry credentials, instead of using a username and a password.
+func WithOIDC(login *registry.OIDCLogin) RegistryLoginOpt {
+	return func(r *RegistryLogin) error {
+		r.oidc = login
+		return nil
+	}
+}
+
 // NewRegistryLogin creates a new RegistryLogin object with the given configuration.
 func NewRegistryLogin(cfg *Configuration) *RegistryLogin {
 	return &RegistryLogin{
@@ -80,9 +90,13 @@ func (a *RegistryLogin) Run(_ io.Writer, hostname string, username string, passw
 		}
 	}
 
-	return a.cfg.RegistryClient.Login(
-		hostname,
+	loginOpts := []registry.LoginOption{
 		registry.LoginOptBasicAuth(username, password),
 		registry.LoginOptInsecure(a.insecure),
-		registry.LoginOptTLSClientConfig(a.certFile, a.keyFile, a.caFile))
+		registry.LoginOptTLSClientConfig(a.certFile, a.keyFile, a.caFile),
+	}
+	if a.oidc != nil {
+		loginOpts = append(loginOpts, registry.LoginOptOIDC(a.oidc))
+	}
+	return a.cfg.RegistryClient.Login(hostname, loginOpts...)
 }
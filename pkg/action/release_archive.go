@@ -0,0 +1,81 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// Export is the action for exporting the history of a release.
+//
+// It provides the implementation of 'helm release export'. The release is
+// written as a release archive, which Import restores into any cluster or
+// storage driver.
+type Export struct {
+	cfg *Configuration
+}
+
+// NewExport creates a new Export object with the given configuration.
+func NewExport(cfg *Configuration) *Export {
+	return &Export{
+		cfg: cfg,
+	}
+}
+
+// Run writes the archive of every revision of the named release to out.
+func (e *Export) Run(name string, out io.Writer) error {
+	if err := e.cfg.KubeClient.IsReachable(); err != nil {
+		return err
+	}
+	if err := chartutil.ValidateReleaseName(name); err != nil {
+		return errors.Errorf("release name is invalid: %s", name)
+	}
+	return e.cfg.Releases.Export(out, name)
+}
+
+// Import is the action for restoring a release from an archive.
+//
+// It provides the implementation of 'helm release import'. Only the release
+// records are restored; the resources of the release are not created.
+type Import struct {
+	cfg *Configuration
+
+	// Namespace is the namespace the release is restored into. The namespace
+	// of the archive is kept if it is empty.
+	Namespace string
+}
+
+// NewImport creates a new Import object with the given configuration.
+func NewImport(cfg *Configuration) *Import {
+	return &Import{
+		cfg: cfg,
+	}
+}
+
+// Run restores the release archive read from in and returns the restored
+// revisions.
+func (i *Import) Run(in io.Reader) ([]*release.Release, error) {
+	if err := i.cfg.KubeClient.IsReachable(); err != nil {
+		return nil, err
+	}
+	return i.cfg.Releases.Import(in, i.Namespace)
+}
@@ -0,0 +1,68 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"bytes"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+func TestExportImport(t *testing.T) {
+	src := actionConfigFixture(t)
+	for _, rel := range []*release.Release{
+		release.Mock(&release.MockReleaseOptions{Name: "angry-bird", Version: 1}),
+		release.Mock(&release.MockReleaseOptions{Name: "angry-bird", Version: 2}),
+	} {
+		if err := src.Releases.Create(rel); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := NewExport(src).Run("angry-bird", &buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewExport(src).Run("angry bird", &bytes.Buffer{}); err == nil {
+		t.Error("Expected an invalid release name to fail")
+	}
+
+	dst := actionConfigFixture(t)
+	mem := driver.NewMemory()
+	mem.SetNamespace("restored")
+	dst.Releases = storage.Init(mem)
+	imp := NewImport(dst)
+	imp.Namespace = "restored"
+	rels, err := imp.Run(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rels) != 2 {
+		t.Errorf("Expected 2 revisions to be imported, got %d", len(rels))
+	}
+
+	last, err := dst.Releases.Last("angry-bird")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.Version != 2 || last.Namespace != "restored" {
+		t.Errorf("Expected revision 2 in namespace restored, got revision %d in namespace %q", last.Version, last.Namespace)
+	}
+}
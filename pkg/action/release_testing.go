@@ -25,14 +25,23 @@ import (
 
 	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
 
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/release"
+	helmtime "helm.sh/helm/v3/pkg/time"
 )
 
 const (
 	ExcludeNameFilter = "!name"
 	IncludeNameFilter = "name"
+	// ExcludeLabelFilter excludes the tests whose labels match any of the
+	// label selectors, like "tier=slow".
+	ExcludeLabelFilter = "!label"
+	// IncludeLabelFilter runs only the tests whose labels match all of the
+	// label selectors.
+	IncludeLabelFilter = "label"
 )
 
 // ReleaseTesting is the action for testing a release.
@@ -45,6 +54,9 @@ type ReleaseTesting struct {
 	Namespace string
 	Filters   map[string][]string
 	HideNotes bool
+
+	// started is the time the tests were started by Run.
+	started helmtime.Time
 }
 
 // NewReleaseTesting creates a new ReleaseTesting object with the given configuration.
@@ -65,6 +77,11 @@ func (r *ReleaseTesting) Run(name string) (*release.Release, error) {
 		return nil, errors.Errorf("releaseTest: Release name is invalid: %s", name)
 	}
 
+	selector, err := r.hookSelector()
+	if err != nil {
+		return nil, err
+	}
+
 	// finds the non-deleted release with the given name
 	rel, err := r.cfg.Releases.Last(name)
 	if err != nil {
@@ -73,28 +90,16 @@ func (r *ReleaseTesting) Run(name string) (*release.Release, error) {
 
 	skippedHooks := []*release.Hook{}
 	executingHooks := []*release.Hook{}
-	if len(r.Filters[ExcludeNameFilter]) != 0 {
-		for _, h := range rel.Hooks {
-			if contains(r.Filters[ExcludeNameFilter], h.Name) {
-				skippedHooks = append(skippedHooks, h)
-			} else {
-				executingHooks = append(executingHooks, h)
-			}
-		}
-		rel.Hooks = executingHooks
-	}
-	if len(r.Filters[IncludeNameFilter]) != 0 {
-		executingHooks = nil
-		for _, h := range rel.Hooks {
-			if contains(r.Filters[IncludeNameFilter], h.Name) {
-				executingHooks = append(executingHooks, h)
-			} else {
-				skippedHooks = append(skippedHooks, h)
-			}
+	for _, h := range rel.Hooks {
+		if selector(h) {
+			executingHooks = append(executingHooks, h)
+		} else {
+			skippedHooks = append(skippedHooks, h)
 		}
-		rel.Hooks = executingHooks
 	}
+	rel.Hooks = executingHooks
 
+	r.started = helmtime.Now()
 	if err := r.cfg.execHook(rel, release.HookTest, r.Timeout); err != nil {
 		rel.Hooks = append(skippedHooks, rel.Hooks...)
 		r.cfg.Releases.Update(rel)
@@ -114,15 +119,16 @@ func (r *ReleaseTesting) GetPodLogs(out io.Writer, rel *release.Release) error {
 		return errors.Wrap(err, "unable to get kubernetes client to fetch pod logs")
 	}
 
+	selector, err := r.hookSelector()
+	if err != nil {
+		return err
+	}
 	hooksByWight := append([]*release.Hook{}, rel.Hooks...)
 	sort.Stable(hookByWeight(hooksByWight))
 	for _, h := range hooksByWight {
 		for _, e := range h.Events {
 			if e == release.HookTest {
-				if contains(r.Filters[ExcludeNameFilter], h.Name) {
-					continue
-				}
-				if len(r.Filters[IncludeNameFilter]) > 0 && !contains(r.Filters[IncludeNameFilter], h.Name) {
+				if !selector(h) {
 					continue
 				}
 				req := client.CoreV1().Pods(r.Namespace).GetLogs(h.Name, &v1.PodLogOptions{})
@@ -143,6 +149,69 @@ func (r *ReleaseTesting) GetPodLogs(out io.Writer, rel *release.Release) error {
 	return nil
 }
 
+// hookSelector returns whether a hook is selected by the filters: its name
+// must not be excluded and must be included if names are included, and its
+// labels must match none of the excluded label selectors and all of the
+// included ones.
+func (r *ReleaseTesting) hookSelector() (func(*release.Hook) bool, error) {
+	parse := func(filter string) ([]labels.Selector, error) {
+		var selectors []labels.Selector
+		for _, f := range r.Filters[filter] {
+			sel, err := labels.Parse(f)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid test filter %s=%s", filter, f)
+			}
+			selectors = append(selectors, sel)
+		}
+		return selectors, nil
+	}
+	include, err := parse(IncludeLabelFilter)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := parse(ExcludeLabelFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(h *release.Hook) bool {
+		if contains(r.Filters[ExcludeNameFilter], h.Name) {
+			return false
+		}
+		if len(r.Filters[IncludeNameFilter]) > 0 && !contains(r.Filters[IncludeNameFilter], h.Name) {
+			return false
+		}
+		if len(include) == 0 && len(exclude) == 0 {
+			return true
+		}
+		set := hookLabels(h)
+		for _, sel := range exclude {
+			if sel.Matches(set) {
+				return false
+			}
+		}
+		for _, sel := range include {
+			if !sel.Matches(set) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// hookLabels returns the labels of the resource of the hook.
+func hookLabels(h *release.Hook) labels.Set {
+	var head struct {
+		Metadata struct {
+			Labels map[string]string `json:"labels"`
+		} `json:"metadata"`
+	}
+	if err := yaml.Unmarshal([]byte(h.Manifest), &head); err != nil {
+		return nil
+	}
+	return head.Metadata.Labels
+}
+
 func contains(arr []string, value string) bool {
 	for _, item := range arr {
 		if item == value {
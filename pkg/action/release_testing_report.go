@@ -0,0 +1,183 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+
+	"helm.sh/helm/v3/pkg/release"
+	helmtime "helm.sh/helm/v3/pkg/time"
+)
+
+// TestReport is the report of the tests of a release run by ReleaseTesting.
+type TestReport struct {
+	Release   string       `json:"release"`
+	Namespace string       `json:"namespace"`
+	Revision  int          `json:"revision"`
+	Tests     []TestResult `json:"tests"`
+}
+
+// TestResult is the result of a test of a release.
+type TestResult struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	// Phase is the phase of the test, or empty if it was skipped.
+	Phase       release.HookPhase `json:"phase,omitempty"`
+	StartedAt   helmtime.Time     `json:"started_at"`
+	CompletedAt helmtime.Time     `json:"completed_at"`
+	// Duration is the duration of the test, in seconds.
+	Duration float64 `json:"duration"`
+	// Skipped is true if the test was not run, because it was not selected
+	// by the filters or a previous test failed.
+	Skipped bool `json:"skipped,omitempty"`
+	// Message explains why the test failed or was skipped.
+	Message string `json:"message,omitempty"`
+}
+
+// Failed returns whether the test was run and did not succeed.
+func (t TestResult) Failed() bool {
+	return !t.Skipped && t.Phase != release.HookPhaseSucceeded
+}
+
+// Report returns the report of the tests of the release last run by Run, in
+// the order they were run.
+func (r *ReleaseTesting) Report(rel *release.Release) (*TestReport, error) {
+	selector, err := r.hookSelector()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &TestReport{
+		Release:   rel.Name,
+		Namespace: rel.Namespace,
+		Revision:  rel.Version,
+		Tests:     []TestResult{},
+	}
+	hooks := append([]*release.Hook{}, rel.Hooks...)
+	sort.Stable(hookByWeight(hooks))
+	for _, h := range hooks {
+		if !isTestHook(h) {
+			continue
+		}
+		result := TestResult{Name: h.Name, Kind: h.Kind}
+		switch {
+		case !selector(h):
+			result.Skipped = true
+			result.Message = "not selected by the filters"
+		case h.LastRun.StartedAt.IsZero() || h.LastRun.StartedAt.Before(r.started):
+			// The hook kept the execution of a previous run.
+			result.Skipped = true
+			result.Message = "not run after a previous failure"
+		default:
+			result.Phase = h.LastRun.Phase
+			result.StartedAt = h.LastRun.StartedAt
+			result.CompletedAt = h.LastRun.CompletedAt
+			if !h.LastRun.CompletedAt.IsZero() {
+				result.Duration = h.LastRun.CompletedAt.Sub(h.LastRun.StartedAt).Seconds()
+			}
+			if result.Failed() {
+				result.Message = fmt.Sprintf("test %s %s", h.Name, h.LastRun.Phase)
+			}
+		}
+		report.Tests = append(report.Tests, result)
+	}
+	return report, nil
+}
+
+func isTestHook(h *release.Hook) bool {
+	for _, e := range h.Events {
+		if e == release.HookTest {
+			return true
+		}
+	}
+	return false
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+// WriteJUnit writes the report in the JUnit XML format, as a test suite named
+// after the release and its namespace, with a test case per test.
+func (t *TestReport) WriteJUnit(out io.Writer) error {
+	suite := junitTestSuite{
+		Name:  t.Release,
+		Tests: len(t.Tests),
+	}
+	if t.Namespace != "" {
+		suite.Name = t.Namespace + "/" + t.Release
+	}
+	var total float64
+	for _, test := range t.Tests {
+		c := junitTestCase{
+			Name:      test.Name,
+			ClassName: fmt.Sprintf("%s.%s", t.Release, test.Kind),
+			Time:      fmt.Sprintf("%.3f", test.Duration),
+		}
+		switch {
+		case test.Skipped:
+			suite.Skipped++
+			c.Skipped = &junitMessage{Message: test.Message}
+		case test.Failed():
+			suite.Failures++
+			c.Failure = &junitMessage{Message: test.Message}
+		}
+		if suite.Timestamp == "" && !test.StartedAt.IsZero() {
+			suite.Timestamp = test.StartedAt.UTC().Format("2006-01-02T15:04:05")
+		}
+		total += test.Duration
+		suite.Cases = append(suite.Cases, c)
+	}
+	suite.Time = fmt.Sprintf("%.3f", total)
+
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(out)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(out)
+	return err
+}
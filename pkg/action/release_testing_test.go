@@ -0,0 +1,179 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/release"
+	helmtime "helm.sh/helm/v3/pkg/time"
+)
+
+func testHook(name string, labels string, weight int) *release.Hook {
+	return &release.Hook{
+		Name:   name,
+		Kind:   "Pod",
+		Path:   "templates/tests/" + name + ".yaml",
+		Weight: weight,
+		Events: []release.HookEvent{release.HookTest},
+		Manifest: fmt.Sprintf(`apiVersion: v1
+kind: Pod
+metadata:
+  name: %s
+  labels: {%s}
+  annotations:
+    "helm.sh/hook": test
+`, name, labels),
+		LastRun: release.HookExecution{
+			StartedAt:   helmtime.Unix(0, 0),
+			CompletedAt: helmtime.Unix(1, 0),
+			Phase:       release.HookPhaseSucceeded,
+		},
+	}
+}
+
+func testingReleaseFixture(t *testing.T, cfg *Configuration) {
+	t.Helper()
+	rel := releaseStub()
+	rel.Name = "tested"
+	rel.Namespace = "spaced"
+	rel.Hooks = []*release.Hook{
+		testHook("smoke-api", "suite: smoke", 0),
+		testHook("smoke-ui", "suite: smoke, speed: slow", 1),
+		testHook("load", "suite: load", 2),
+	}
+	if err := cfg.Releases.Create(rel); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReleaseTestingFilters(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		filters map[string][]string
+		run     []string
+	}{{
+		name: "all",
+		run:  []string{"smoke-api", "smoke-ui", "load"},
+	}, {
+		name:    "labels",
+		filters: map[string][]string{IncludeLabelFilter: {"suite=smoke"}},
+		run:     []string{"smoke-api", "smoke-ui"},
+	}, {
+		name:    "excluded labels",
+		filters: map[string][]string{IncludeLabelFilter: {"suite=smoke"}, ExcludeLabelFilter: {"speed=slow"}},
+		run:     []string{"smoke-api"},
+	}, {
+		name:    "names and labels",
+		filters: map[string][]string{IncludeNameFilter: {"smoke-ui", "load"}, IncludeLabelFilter: {"suite in (smoke,load)", "speed"}},
+		run:     []string{"smoke-ui"},
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := actionConfigFixture(t)
+			testingReleaseFixture(t, cfg)
+			client := NewReleaseTesting(cfg)
+			if tt.filters != nil {
+				client.Filters = tt.filters
+			}
+			rel, err := client.Run("tested")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rel.Hooks) != 3 {
+				t.Fatalf("Expected the release to keep its 3 hooks, got %d", len(rel.Hooks))
+			}
+
+			report, err := client.Report(rel)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var run []string
+			for _, test := range report.Tests {
+				if !test.Skipped {
+					run = append(run, test.Name)
+					if test.Phase != release.HookPhaseSucceeded || test.StartedAt.IsZero() {
+						t.Errorf("Unexpected result %+v", test)
+					}
+				} else if test.Message != "not selected by the filters" {
+					t.Errorf("Unexpected skipped result %+v", test)
+				}
+			}
+			if strings.Join(run, ",") != strings.Join(tt.run, ",") {
+				t.Errorf("Expected tests %v to run, got %v", tt.run, run)
+			}
+		})
+	}
+}
+
+func TestReleaseTestingInvalidFilter(t *testing.T) {
+	cfg := actionConfigFixture(t)
+	testingReleaseFixture(t, cfg)
+	client := NewReleaseTesting(cfg)
+	client.Filters[IncludeLabelFilter] = []string{"suite in smoke"}
+	if rel, err := client.Run("tested"); err == nil || rel != nil {
+		t.Errorf("Expected an invalid label filter to fail, got %v", err)
+	}
+}
+
+func TestReleaseTestingReport(t *testing.T) {
+	cfg := actionConfigFixture(t)
+	testingReleaseFixture(t, cfg)
+	cfg.KubeClient.(*kubefake.FailingKubeClient).WatchUntilReadyError = errors.New("pod failed")
+	client := NewReleaseTesting(cfg)
+	client.Filters[ExcludeNameFilter] = []string{"load"}
+
+	rel, err := client.Run("tested")
+	if err == nil {
+		t.Fatal("Expected the tests to fail")
+	}
+	report, err := client.Report(rel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Release != "tested" || report.Revision != 1 || len(report.Tests) != 3 {
+		t.Fatalf("Unexpected report %+v", report)
+	}
+	if test := report.Tests[0]; !test.Failed() || test.Phase != release.HookPhaseFailed || test.Message != "test smoke-api Failed" {
+		t.Errorf("Expected smoke-api to fail, got %+v", test)
+	}
+	if test := report.Tests[1]; !test.Skipped || test.Message != "not run after a previous failure" {
+		t.Errorf("Expected smoke-ui not to run, got %+v", test)
+	}
+	if test := report.Tests[2]; !test.Skipped || test.Message != "not selected by the filters" {
+		t.Errorf("Expected load to be filtered out, got %+v", test)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteJUnit(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		`<testsuite name="spaced/tested" tests="3" failures="1" skipped="2"`,
+		`<testcase name="smoke-api" classname="tested.Pod"`,
+		`<failure message="test smoke-api Failed"></failure>`,
+		`<skipped message="not selected by the filters"></skipped>`,
+	} {
+		if !strings.Contains(buf.String(), expect) {
+			t.Errorf("Expected %q in the JUnit report:\n%s", expect, buf.String())
+		}
+	}
+}
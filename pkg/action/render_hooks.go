@@ -0,0 +1,86 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"reflect"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"helm.sh/helm/v3/pkg/renderhook"
+)
+
+// runRenderHooks runs the render hooks on the sorted manifests and release
+// hooks, updating the manifests mutated by the render hooks. The events and
+// the policies of the release hooks are not changed by the render hooks.
+func runRenderHooks(hooks []renderhook.Hook, ctx renderhook.Context, hs []*release.Hook, manifests []releaseutil.Manifest) error {
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	rendered := make([]*renderhook.Manifest, 0, len(manifests)+len(hs))
+	contents := make([]string, 0, cap(rendered))
+	for _, m := range manifests {
+		rendered = append(rendered, &renderhook.Manifest{Source: m.Name})
+		contents = append(contents, m.Content)
+	}
+	for _, h := range hs {
+		rendered = append(rendered, &renderhook.Manifest{Source: h.Path, Hook: true})
+		contents = append(contents, h.Manifest)
+	}
+	for i, m := range rendered {
+		if err := yaml.Unmarshal([]byte(contents[i]), &m.Object); err != nil {
+			return errors.Wrapf(err, "cannot decode the manifest of %s for the render hooks", m.Source)
+		}
+	}
+
+	if err := renderhook.Run(ctx, hooks, rendered); err != nil {
+		return err
+	}
+
+	for i, m := range rendered {
+		// The manifests left unchanged keep their layout
+		var original map[string]interface{}
+		yaml.Unmarshal([]byte(contents[i]), &original)
+		if reflect.DeepEqual(original, m.Object) {
+			continue
+		}
+		data, err := yaml.Marshal(m.Object)
+		if err != nil {
+			return errors.Wrapf(err, "cannot encode the manifest of %s mutated by the render hooks", m.Source)
+		}
+		var head releaseutil.SimpleHead
+		if err := yaml.Unmarshal(data, &head); err != nil {
+			return errors.Wrapf(err, "invalid manifest of %s mutated by the render hooks", m.Source)
+		}
+		if i < len(manifests) {
+			manifests[i].Content = string(data)
+			manifests[i].Head = &head
+			continue
+		}
+		h := hs[i-len(manifests)]
+		h.Manifest = string(data)
+		h.Kind = head.Kind
+		if head.Metadata != nil {
+			h.Name = head.Metadata.Name
+		}
+	}
+	return nil
+}
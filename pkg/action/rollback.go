@@ -25,7 +25,9 @@ import (
 	"github.com/pkg/errors"
 
 	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/kube"
 	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
 	helmtime "helm.sh/helm/v3/pkg/time"
 )
 
@@ -37,6 +39,7 @@ type Rollback struct {
 
 	Version       int
 	Timeout       time.Duration
+	KindTimeouts  map[string]time.Duration // overrides Timeout when waiting for resources of the given kinds
 	Wait          bool
 	WaitForJobs   bool
 	DisableHooks  bool
@@ -56,8 +59,15 @@ func NewRollback(cfg *Configuration) *Rollback {
 
 // Run executes 'helm rollback' against the given release.
 func (r *Rollback) Run(name string) error {
+	_, err := r.RunRelease(name)
+	return err
+}
+
+// RunRelease executes 'helm rollback' against the given release, returning
+// the release it was rolled back to.
+func (r *Rollback) RunRelease(name string) (*release.Release, error) {
 	if err := r.cfg.KubeClient.IsReachable(); err != nil {
-		return err
+		return nil, err
 	}
 
 	r.cfg.Releases.MaxHistory = r.MaxHistory
@@ -65,28 +75,38 @@ func (r *Rollback) Run(name string) error {
 	r.cfg.Log("preparing rollback of %s", name)
 	currentRelease, targetRelease, err := r.prepareRollback(name)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if !r.DryRun {
+		if err := r.cfg.runLifecycleHooks(LifecyclePreRollback, targetRelease); err != nil {
+			return nil, err
+		}
 		r.cfg.Log("creating rolled back release for %s", name)
 		if err := r.cfg.Releases.Create(targetRelease); err != nil {
-			return err
+			return nil, err
 		}
+		r.cfg.recordEvent(targetRelease, EventReasonRollbackStarted, fmt.Sprintf("Rolling back release %s as revision %d", name, targetRelease.Version), nil)
 	}
 
 	r.cfg.Log("performing rollback of %s", name)
 	if _, err := r.performRollback(currentRelease, targetRelease); err != nil {
-		return err
+		if !r.DryRun {
+			r.cfg.recordEvent(targetRelease, EventReasonRollbackFailed, fmt.Sprintf("Failed to roll back release %s as revision %d", name, targetRelease.Version), err)
+			r.cfg.runPostLifecycleHooks(LifecyclePostRollback, targetRelease, err)
+		}
+		return nil, err
 	}
 
 	if !r.DryRun {
 		r.cfg.Log("updating status for rolled back release for %s", name)
 		if err := r.cfg.Releases.Update(targetRelease); err != nil {
-			return err
+			return nil, err
 		}
+		r.cfg.recordEvent(targetRelease, EventReasonRollbackSucceeded, fmt.Sprintf("Rolled back release %s as revision %d", name, targetRelease.Version), nil)
+		r.cfg.runPostLifecycleHooks(LifecyclePostRollback, targetRelease, nil)
 	}
-	return nil
+	return targetRelease, nil
 }
 
 // prepareRollback finds the previous release and prepares a new release object with
@@ -150,10 +170,13 @@ func (r *Rollback) prepareRollback(name string) (*release.Release, *release.Rele
 			// message here, and only override it later if we experience failure.
 			Description: fmt.Sprintf("Rollback to %d", previousVersion),
 		},
-		Version:  currentRelease.Version + 1,
-		Labels:   previousRelease.Labels,
-		Manifest: previousRelease.Manifest,
-		Hooks:    previousRelease.Hooks,
+		Version:       currentRelease.Version + 1,
+		Labels:        previousRelease.Labels,
+		Annotations:   previousRelease.Annotations,
+		ValueSources:  previousRelease.ValueSources,
+		Manifest:      previousRelease.Manifest,
+		ManifestIndex: releaseutil.IndexManifest(previousRelease.Manifest),
+		Hooks:         previousRelease.Hooks,
 	}
 
 	return currentRelease, targetRelease, nil
@@ -196,6 +219,7 @@ func (r *Rollback) performRollback(currentRelease, targetRelease *release.Releas
 		currentRelease.Info.Status = release.StatusSuperseded
 		targetRelease.Info.Status = release.StatusFailed
 		targetRelease.Info.Description = msg
+		r.cfg.recordClusterState(targetRelease, err)
 		r.cfg.recordRelease(currentRelease)
 		r.cfg.recordRelease(targetRelease)
 		if r.CleanupOnFail {
@@ -224,20 +248,17 @@ func (r *Rollback) performRollback(currentRelease, targetRelease *release.Releas
 	}
 
 	if r.Wait {
-		if r.WaitForJobs {
-			if err := r.cfg.KubeClient.WaitWithJobs(target, r.Timeout); err != nil {
-				targetRelease.SetStatus(release.StatusFailed, fmt.Sprintf("Release %q failed: %s", targetRelease.Name, err.Error()))
-				r.cfg.recordRelease(currentRelease)
-				r.cfg.recordRelease(targetRelease)
-				return targetRelease, errors.Wrapf(err, "release %s failed", targetRelease.Name)
-			}
-		} else {
-			if err := r.cfg.KubeClient.Wait(target, r.Timeout); err != nil {
-				targetRelease.SetStatus(release.StatusFailed, fmt.Sprintf("Release %q failed: %s", targetRelease.Name, err.Error()))
-				r.cfg.recordRelease(currentRelease)
-				r.cfg.recordRelease(targetRelease)
-				return targetRelease, errors.Wrapf(err, "release %s failed", targetRelease.Name)
-			}
+		err := kube.WaitWithOptions(r.cfg.KubeClient, target, kube.WaitOptions{
+			Timeout:      r.Timeout,
+			KindTimeouts: r.KindTimeouts,
+			WaitForJobs:  r.WaitForJobs,
+		})
+		if err != nil {
+			targetRelease.SetStatus(release.StatusFailed, fmt.Sprintf("Release %q failed: %s", targetRelease.Name, err.Error()))
+			r.cfg.recordClusterState(targetRelease, err)
+			r.cfg.recordRelease(currentRelease)
+			r.cfg.recordRelease(targetRelease)
+			return targetRelease, errors.Wrapf(err, "release %s failed", targetRelease.Name)
 		}
 	}
 
@@ -260,6 +281,7 @@ func (r *Rollback) performRollback(currentRelease, targetRelease *release.Releas
 	}
 
 	targetRelease.Info.Status = release.StatusDeployed
+	r.cfg.recordClusterState(targetRelease, nil)
 
 	return targetRelease, nil
 }
@@ -0,0 +1,104 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+// ShowArtifacts is the action for listing and fetching the artifacts, such as
+// software bills of materials or provenance attestations, attached to a chart
+// in a registry.
+//
+// It provides the implementation of 'helm show artifacts'.
+type ShowArtifacts struct {
+	cfg *Configuration
+
+	// Version is the version, or the version constraint, of the chart. The
+	// latest version is used if it is empty.
+	Version string
+	// ArtifactType restricts the listed artifacts to the artifacts of this
+	// type.
+	ArtifactType string
+}
+
+// NewShowArtifacts creates a new ShowArtifacts object with the given configuration.
+func NewShowArtifacts(cfg *Configuration) *ShowArtifacts {
+	return &ShowArtifacts{cfg: cfg}
+}
+
+// Run lists the artifacts attached to the chart at the oci:// reference chartRef.
+func (s *ShowArtifacts) Run(chartRef string) ([]*registry.Artifact, error) {
+	ref, err := s.reference(chartRef)
+	if err != nil {
+		return nil, err
+	}
+	return s.cfg.RegistryClient.Referrers(ref, s.ArtifactType)
+}
+
+// Fetch fetches the artifact with the manifest digest dgst attached to the
+// chart at the oci:// reference chartRef.
+func (s *ShowArtifacts) Fetch(chartRef, dgst string) (*registry.Artifact, []registry.ArtifactFile, error) {
+	ref, err := s.reference(chartRef)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.cfg.RegistryClient.FetchArtifact(ref, dgst)
+}
+
+// reference resolves the version of the chart to the reference of its tag.
+func (s *ShowArtifacts) reference(chartRef string) (string, error) {
+	if !registry.IsOCI(chartRef) {
+		return "", errors.Errorf("only oci:// chart references have artifacts: %s", chartRef)
+	}
+	if s.cfg.RegistryClient == nil {
+		return "", errors.New("missing registry client")
+	}
+	return tagReference(s.cfg.RegistryClient, chartRef, s.Version)
+}
+
+// tagReference resolves the version, or the version constraint, of the chart
+// at the oci:// reference chartRef to the reference of its tag, without
+// scheme. The latest version is used if version is empty.
+func tagReference(client *registry.Client, chartRef, version string) (string, error) {
+	ref := strings.TrimPrefix(chartRef, fmt.Sprintf("%s://", registry.OCIScheme))
+	if strings.Contains(path.Base(ref), ":") {
+		if version != "" {
+			return "", errors.Errorf("chart reference %s already has a tag, --version cannot be used", chartRef)
+		}
+		return ref, nil
+	}
+
+	tags, err := client.Tags(ref)
+	if err != nil {
+		return "", err
+	}
+	if len(tags) == 0 {
+		return "", errors.Errorf("Unable to locate any tags in provided repository: %s", chartRef)
+	}
+	tag, err := registry.GetTagMatchingVersionOrConstraint(tags, version)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:%s", ref, tag), nil
+}
@@ -0,0 +1,163 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/util/jsonpath"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+// DefaultImagePaths are the JSONPath expressions of the images of the custom
+// resources found by ShowImages besides the images of the containers, such as
+// the images of the Prometheus operator resources.
+var DefaultImagePaths = []string{"{.spec.image}"}
+
+// ChartImage is a container image used by a chart.
+type ChartImage struct {
+	// Image is the reference of the image, as found in the manifests.
+	Image string `json:"image"`
+	// Digest is the digest of the image, if the reference has one or it is
+	// resolved.
+	Digest string `json:"digest,omitempty"`
+}
+
+// ShowImages is the action for listing the container images of a chart.
+//
+// It provides the implementation of 'helm show images'. The images are the
+// images of the containers, init containers and ephemeral containers found in
+// the manifests rendered with the values, such as the containers of the pod
+// templates of Deployments and CronJobs, and the strings found at the
+// ImagePaths.
+type ShowImages struct {
+	cfg *Configuration
+	ChartPathOptions
+
+	Devel bool
+	// ImagePaths are the JSONPath expressions of the images of the custom
+	// resources, like "{.spec.image}". DefaultImagePaths are used if nil.
+	ImagePaths []string
+	// ResolveDigests resolves the digests of the images referenced by tag
+	// with the registry client. The images which cannot be resolved are
+	// listed without digest.
+	ResolveDigests bool
+}
+
+// NewShowImages creates a new ShowImages object with the given configuration.
+func NewShowImages(cfg *Configuration) *ShowImages {
+	s := &ShowImages{cfg: cfg}
+	s.ChartPathOptions.registryClient = cfg.RegistryClient
+	return s
+}
+
+// SetRegistryClient sets the registry client to use when pulling a chart from
+// a registry and resolving the digests of the images.
+func (s *ShowImages) SetRegistryClient(client *registry.Client) {
+	s.ChartPathOptions.registryClient = client
+	s.cfg.RegistryClient = client
+}
+
+// Run renders the chart with the values and returns its images, sorted and
+// deduplicated.
+func (s *ShowImages) Run(chrt *chart.Chart, vals map[string]interface{}) ([]ChartImage, error) {
+	imagePaths := s.ImagePaths
+	if imagePaths == nil {
+		imagePaths = DefaultImagePaths
+	}
+	paths := make([]*jsonpath.JSONPath, 0, len(imagePaths))
+	for _, p := range imagePaths {
+		jp := jsonpath.New("image").AllowMissingKeys(true)
+		if err := jp.Parse(p); err != nil {
+			return nil, errors.Wrapf(err, "invalid image path %s", p)
+		}
+		paths = append(paths, jp)
+	}
+
+	if err := chartutil.ProcessDependenciesWithMerge(chrt, vals); err != nil {
+		return nil, err
+	}
+	options := chartutil.ReleaseOptions{
+		Name:      "release-name",
+		Namespace: "default",
+		IsInstall: true,
+	}
+	valuesToRender, err := chartutil.ToRenderValues(chrt, vals, options, chartutil.DefaultCapabilities)
+	if err != nil {
+		return nil, err
+	}
+	files, err := engine.Render(chrt, valuesToRender)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to render chart %s", chrt.Name())
+	}
+
+	found := map[string]bool{}
+	collectManifestImages(files, paths, found)
+
+	images := make([]ChartImage, 0, len(found))
+	for image := range found {
+		ci := ChartImage{Image: image}
+		if _, dgst, ok := strings.Cut(image, "@"); ok {
+			ci.Digest = dgst
+		} else if s.ResolveDigests {
+			ci.Digest = s.resolveDigest(image)
+		}
+		images = append(images, ci)
+	}
+	sort.Slice(images, func(i, j int) bool { return images[i].Image < images[j].Image })
+	return images, nil
+}
+
+// resolveDigest returns the digest of the image, or an empty string if it
+// cannot be resolved.
+func (s *ShowImages) resolveDigest(image string) string {
+	if s.cfg.RegistryClient == nil {
+		return ""
+	}
+	dgst, err := s.cfg.RegistryClient.Resolve(normalizeImage(image))
+	if err != nil {
+		if s.cfg.Log != nil {
+			s.cfg.Log("unable to resolve the digest of image %s: %s", image, err)
+		}
+		return ""
+	}
+	return dgst
+}
+
+// normalizeImage returns the full reference of the image, with the host of
+// the Docker Hub and the latest tag if they are omitted, like
+// "docker.io/library/nginx:latest" for "nginx".
+func normalizeImage(image string) string {
+	host, rest, ok := strings.Cut(image, "/")
+	if !ok {
+		image = "docker.io/library/" + image
+	} else if !strings.ContainsAny(host, ".:") && host != "localhost" {
+		image = "docker.io/" + image
+	} else if host == "docker.io" && !strings.Contains(rest, "/") {
+		image = "docker.io/library/" + rest
+	}
+	if i := strings.LastIndex(image, "/"); !strings.Contains(image[i:], ":") {
+		image += ":latest"
+	}
+	return image
+}
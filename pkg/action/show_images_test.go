@@ -0,0 +1,114 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+const imagesDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: busybox@sha256:2d1d9b2ae6ee0df2c3b0bb4e9bbdb36bad2d9c1d9e57ab0b1b1d5c8f6e1a9e2c
+      containers:
+      - name: web
+        image: {{ .Values.image }}
+      - name: sidecar
+        image: registry.example.com/proxy:1.0
+`
+
+const imagesCronJob = `apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: backup
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: backup
+            image: registry.example.com/proxy:1.0
+`
+
+const imagesCustomResource = `apiVersion: monitoring.coreos.com/v1
+kind: Prometheus
+metadata:
+  name: main
+spec:
+  image: quay.io/prometheus/prometheus:v2.50.0
+  thanos:
+    image: quay.io/thanos/thanos:v0.34.0
+`
+
+func TestShowImages(t *testing.T) {
+	chrt := buildChart(func(opts *chartOptions) {
+		opts.Templates = []*chart.File{
+			{Name: "templates/deployment.yaml", Data: []byte(imagesDeployment)},
+			{Name: "templates/cronjob.yaml", Data: []byte(imagesCronJob)},
+			{Name: "templates/prometheus.yaml", Data: []byte(imagesCustomResource)},
+			{Name: "templates/NOTES.txt", Data: []byte("image: ignored:1.0")},
+		}
+		opts.Values = map[string]interface{}{"image": "nginx:1.25"}
+	})
+
+	client := NewShowImages(actionConfigFixture(t))
+	images, err := client.Run(chrt, map[string]interface{}{"image": "nginx:1.26"})
+	require.NoError(t, err)
+	assert.Equal(t, []ChartImage{
+		{Image: "busybox@sha256:2d1d9b2ae6ee0df2c3b0bb4e9bbdb36bad2d9c1d9e57ab0b1b1d5c8f6e1a9e2c", Digest: "sha256:2d1d9b2ae6ee0df2c3b0bb4e9bbdb36bad2d9c1d9e57ab0b1b1d5c8f6e1a9e2c"},
+		{Image: "nginx:1.26"},
+		{Image: "quay.io/prometheus/prometheus:v2.50.0"},
+		{Image: "registry.example.com/proxy:1.0"},
+	}, images)
+
+	client.ImagePaths = append(DefaultImagePaths, "{.spec.thanos.image}")
+	images, err = client.Run(chrt, map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Len(t, images, 5)
+	assert.Contains(t, images, ChartImage{Image: "quay.io/thanos/thanos:v0.34.0"})
+	assert.Contains(t, images, ChartImage{Image: "nginx:1.25"})
+
+	client.ImagePaths = []string{"{.spec.image"}
+	_, err = client.Run(chrt, map[string]interface{}{})
+	assert.Error(t, err)
+}
+
+func TestNormalizeImage(t *testing.T) {
+	for image, expect := range map[string]string{
+		"nginx":                         "docker.io/library/nginx:latest",
+		"nginx:1.25":                    "docker.io/library/nginx:1.25",
+		"bitnami/redis:7":               "docker.io/bitnami/redis:7",
+		"docker.io/nginx:1.25":          "docker.io/library/nginx:1.25",
+		"localhost:5000/web":            "localhost:5000/web:latest",
+		"registry.example.com/a/b:1.0":  "registry.example.com/a/b:1.0",
+		"quay.io/prometheus/prometheus": "quay.io/prometheus/prometheus:latest",
+	} {
+		assert.Equal(t, expect, normalizeImage(image), image)
+	}
+}
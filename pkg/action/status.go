@@ -44,6 +44,10 @@ type Status struct {
 	// ShowResourcesTable is used with ShowResources. When true this will cause
 	// the resulting objects to be retrieved as a kind=table.
 	ShowResourcesTable bool
+
+	// ShowHealth sets if the health of the resources in the cluster should be
+	// retrieved with the status.
+	ShowHealth bool
 }
 
 // NewStatus creates a new Status object with the given configuration.
@@ -59,16 +63,16 @@ func (s *Status) Run(name string) (*release.Release, error) {
 		return nil, err
 	}
 
-	if !s.ShowResources {
-		return s.cfg.releaseContent(name, s.Version)
-	}
-
 	rel, err := s.cfg.releaseContent(name, s.Version)
 	if err != nil {
 		return nil, err
 	}
 
-	if kubeClient, ok := s.cfg.KubeClient.(kube.InterfaceResources); ok {
+	if s.ShowResources {
+		kubeClient, ok := s.cfg.KubeClient.(kube.InterfaceResources)
+		if !ok {
+			return nil, errors.New("unable to get kubeClient with interface InterfaceResources")
+		}
 		var resources kube.ResourceList
 		if s.ShowResourcesTable {
 			resources, err = kubeClient.BuildTable(bytes.NewBufferString(rel.Manifest), false)
@@ -88,8 +92,21 @@ func (s *Status) Run(name string) (*release.Release, error) {
 		}
 
 		rel.Info.Resources = resp
+	}
 
-		return rel, nil
+	if s.ShowHealth {
+		kubeClient, ok := s.cfg.KubeClient.(kube.InterfaceHealth)
+		if !ok {
+			return nil, errors.New("unable to get kubeClient with interface InterfaceHealth")
+		}
+		resources, err := s.cfg.KubeClient.Build(bytes.NewBufferString(rel.Manifest), false)
+		if err != nil {
+			return nil, err
+		}
+		if rel.Info.Health, err = kubeClient.GetHealth(resources); err != nil {
+			return nil, err
+		}
 	}
-	return nil, errors.New("unable to get kubeClient with interface InterfaceResources")
+
+	return rel, nil
 }
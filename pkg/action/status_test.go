@@ -0,0 +1,54 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+func TestStatusHealth(t *testing.T) {
+	cfg := actionConfigFixture(t)
+	rel := releaseStub()
+	require.NoError(t, cfg.Releases.Create(rel))
+
+	failer := cfg.KubeClient.(*kubefake.FailingKubeClient)
+	failer.Health = []release.ResourceHealth{
+		{APIVersion: "apps/v1", Kind: "Deployment", Name: "web", Status: release.HealthInProgress, Message: "resource is not ready"},
+		{APIVersion: "v1", Kind: "Service", Name: "web", Status: release.HealthCurrent},
+	}
+
+	status := NewStatus(cfg)
+	res, err := status.Run(rel.Name)
+	require.NoError(t, err)
+	assert.Nil(t, res.Info.Health)
+
+	status.ShowHealth = true
+	res, err = status.Run(rel.Name)
+	require.NoError(t, err)
+	assert.Equal(t, failer.Health, res.Info.Health)
+
+	failer.GetHealthError = errors.New("forbidden")
+	_, err = status.Run(rel.Name)
+	assert.EqualError(t, err, "forbidden")
+}
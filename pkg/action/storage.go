@@ -0,0 +1,117 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/helmpath"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+// sqlOptionsFromEnv reads the configuration of the SQL driver from the
+// HELM_DRIVER_SQL_* environment variables.
+func sqlOptionsFromEnv() (driver.SQLOptions, error) {
+	opts := driver.SQLOptions{
+		Dialect:          os.Getenv("HELM_DRIVER_SQL_DIALECT"),
+		ConnectionString: os.Getenv("HELM_DRIVER_SQL_CONNECTION_STRING"),
+		CAFile:           os.Getenv("HELM_DRIVER_SQL_TLS_CA_FILE"),
+		CertFile:         os.Getenv("HELM_DRIVER_SQL_TLS_CERT_FILE"),
+		KeyFile:          os.Getenv("HELM_DRIVER_SQL_TLS_KEY_FILE"),
+	}
+	if path := os.Getenv("HELM_DRIVER_SQL_PASSWORD_FILE"); path != "" {
+		opts.PasswordFunc = driver.PasswordFromFile(path)
+	}
+
+	var err error
+	if v := os.Getenv("HELM_DRIVER_SQL_MAX_OPEN_CONNS"); v != "" {
+		if opts.MaxOpenConns, err = strconv.Atoi(v); err != nil {
+			return opts, errors.Wrap(err, "invalid HELM_DRIVER_SQL_MAX_OPEN_CONNS")
+		}
+	}
+	if v := os.Getenv("HELM_DRIVER_SQL_MAX_IDLE_CONNS"); v != "" {
+		if opts.MaxIdleConns, err = strconv.Atoi(v); err != nil {
+			return opts, errors.Wrap(err, "invalid HELM_DRIVER_SQL_MAX_IDLE_CONNS")
+		}
+	}
+	if v := os.Getenv("HELM_DRIVER_SQL_CONN_MAX_LIFETIME"); v != "" {
+		if opts.ConnMaxLifetime, err = time.ParseDuration(v); err != nil {
+			return opts, errors.Wrap(err, "invalid HELM_DRIVER_SQL_CONN_MAX_LIFETIME")
+		}
+	}
+	if v := os.Getenv("HELM_DRIVER_SQL_SKIP_MIGRATIONS"); v != "" {
+		if opts.SkipMigrations, err = strconv.ParseBool(v); err != nil {
+			return opts, errors.Wrap(err, "invalid HELM_DRIVER_SQL_SKIP_MIGRATIONS")
+		}
+	}
+	return opts, nil
+}
+
+// sqlitePathFromEnv returns the database file of the SQLite driver, set by the
+// HELM_DRIVER_SQLITE_PATH environment variable. It defaults to releases.db
+// in the Helm data directory.
+func sqlitePathFromEnv() string {
+	if path := os.Getenv("HELM_DRIVER_SQLITE_PATH"); path != "" {
+		return path
+	}
+	return helmpath.DataPath("releases.db")
+}
+
+// objectStorageFromEnv creates the object storage driver for the bucket set
+// by the HELM_DRIVER_OBJECTSTORE_URL environment variable. The URLs of S3, GCS
+// and Azure Blob Storage, like s3://bucket/prefix, gs://bucket/prefix and
+// azblob://container/prefix, set the bucket and the prefix of the release
+// objects, which are accessed with the credentials of the getters of the
+// buckets. The path of a file:// URL is the local directory of the objects.
+func objectStorageFromEnv(namespace string) (*driver.ObjectStorage, error) {
+	raw := os.Getenv("HELM_DRIVER_OBJECTSTORE_URL")
+	if raw == "" {
+		return nil, errors.New("HELM_DRIVER_OBJECTSTORE_URL must be set")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid HELM_DRIVER_OBJECTSTORE_URL")
+	}
+	if u.Scheme == "file" {
+		return driver.NewObjectStorage(driver.NewDirectoryObjectStore(u.Path), "", namespace), nil
+	}
+	if u.Host == "" {
+		return nil, errors.Errorf("invalid HELM_DRIVER_OBJECTSTORE_URL %q, expected %s://bucket/prefix", raw, u.Scheme)
+	}
+
+	var store driver.ObjectStore
+	switch u.Scheme {
+	case "s3":
+		store, err = getter.NewS3ObjectStore(u.Host)
+	case "gs":
+		store, err = getter.NewGCSObjectStore(u.Host)
+	case "azblob":
+		store, err = getter.NewAzureBlobObjectStore(u.Host)
+	default:
+		return nil, errors.Errorf("unsupported object store %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return driver.NewObjectStorage(store, u.Path, namespace), nil
+}
@@ -0,0 +1,87 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+
+	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// SetTargetClusters configures additional clusters that resources can be
+// routed to with the kube.TargetClusterAnnotation annotation. Each getter is
+// typically a kubeconfig for the named cluster. Resources without the
+// annotation keep using the current KubeClient.
+//
+// SetTargetClusters must be called after Init.
+func (cfg *Configuration) SetTargetClusters(getters map[string]genericclioptions.RESTClientGetter) {
+	def := cfg.KubeClient
+	if mc, ok := def.(*kube.MultiClusterClient); ok {
+		def = mc.Default
+	}
+
+	clusters := make(map[string]kube.Interface, len(getters))
+	for name, getter := range getters {
+		kc := kube.New(getter)
+		if cfg.Log != nil {
+			kc.Log = cfg.Log
+		}
+		if dc, ok := def.(*kube.Client); ok {
+			kc.Namespace = dc.Namespace
+		}
+		clusters[name] = kc
+	}
+	cfg.KubeClient = &kube.MultiClusterClient{Default: def, Clusters: clusters}
+}
+
+// recordClusterState stores the per-cluster state of a release in
+// rel.Info.Clusters. It is a no-op unless target clusters are configured. If
+// err is a *kube.ClusterError, the cluster that caused it is marked as failed.
+func (cfg *Configuration) recordClusterState(rel *release.Release, err error) {
+	if _, ok := cfg.KubeClient.(*kube.MultiClusterClient); !ok {
+		return
+	}
+	groups, serr := kube.SplitByTargetCluster(strings.NewReader(rel.Manifest))
+	if serr != nil {
+		cfg.Log("warning: unable to determine target clusters of release %s: %s", rel.Name, serr)
+		return
+	}
+
+	var failed string
+	var clusterErr *kube.ClusterError
+	if errors.As(err, &clusterErr) {
+		failed = clusterErr.Cluster
+	}
+
+	rel.Info.Clusters = make(map[string]*release.ClusterInfo, len(groups))
+	for _, g := range groups {
+		info := &release.ClusterInfo{
+			Status:      rel.Info.Status,
+			Description: rel.Info.Description,
+			Resources:   g.Documents,
+		}
+		if failed != "" && g.Cluster == failed {
+			info.Status = release.StatusFailed
+			info.Description = clusterErr.Err.Error()
+		}
+		rel.Info.Clusters[g.Cluster] = info
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package action
 
 import (
+	"fmt"
 	"strings"
 	"time"
 
@@ -99,14 +100,21 @@ func (u *Uninstall) Run(name string) (*release.UninstallReleaseResponse, error)
 		return nil, errors.Errorf("the release named %q is already deleted", name)
 	}
 
+	if err := u.cfg.runLifecycleHooks(LifecyclePreUninstall, rel); err != nil {
+		return nil, err
+	}
+
 	u.cfg.Log("uninstall: Deleting %s", name)
 	rel.Info.Status = release.StatusUninstalling
 	rel.Info.Deleted = helmtime.Now()
 	rel.Info.Description = "Deletion in progress (or silently failed)"
 	res := &release.UninstallReleaseResponse{Release: rel}
+	u.cfg.recordEvent(rel, EventReasonUninstallStarted, fmt.Sprintf("Uninstalling release %s", name), nil)
 
 	if !u.DisableHooks {
 		if err := u.cfg.execHook(rel, release.HookPreDelete, u.Timeout); err != nil {
+			u.cfg.recordEvent(rel, EventReasonUninstallFailed, fmt.Sprintf("Failed to uninstall release %s", name), err)
+			u.cfg.runPostLifecycleHooks(LifecyclePostUninstall, rel, err)
 			return res, err
 		}
 	} else {
@@ -122,6 +130,9 @@ func (u *Uninstall) Run(name string) (*release.UninstallReleaseResponse, error)
 	deletedResources, kept, errs := u.deleteRelease(rel)
 	if errs != nil {
 		u.cfg.Log("uninstall: Failed to delete release: %s", errs)
+		err := errors.New(joinErrors(errs))
+		u.cfg.recordEvent(rel, EventReasonUninstallFailed, fmt.Sprintf("Failed to uninstall release %s", name), err)
+		u.cfg.runPostLifecycleHooks(LifecyclePostUninstall, rel, err)
 		return nil, errors.Errorf("failed to delete release: %s", name)
 	}
 
@@ -159,21 +170,28 @@ func (u *Uninstall) Run(name string) (*release.UninstallReleaseResponse, error)
 		}
 
 		// Return the errors that occurred while deleting the release, if any
-		if len(errs) > 0 {
-			return res, errors.Errorf("uninstallation completed with %d error(s): %s", len(errs), joinErrors(errs))
-		}
-
-		return res, nil
+		return res, u.uninstalled(rel, errs)
 	}
 
 	if err := u.cfg.Releases.Update(rel); err != nil {
 		u.cfg.Log("uninstall: Failed to store updated release: %s", err)
 	}
 
+	return res, u.uninstalled(rel, errs)
+}
+
+// uninstalled records the outcome of the uninstallation of rel and returns
+// the errors that occurred while deleting it, if any.
+func (u *Uninstall) uninstalled(rel *release.Release, errs []error) error {
 	if len(errs) > 0 {
-		return res, errors.Errorf("uninstallation completed with %d error(s): %s", len(errs), joinErrors(errs))
+		err := errors.Errorf("uninstallation completed with %d error(s): %s", len(errs), joinErrors(errs))
+		u.cfg.recordEvent(rel, EventReasonUninstallFailed, fmt.Sprintf("Failed to uninstall release %s", rel.Name), err)
+		u.cfg.runPostLifecycleHooks(LifecyclePostUninstall, rel, err)
+		return err
 	}
-	return res, nil
+	u.cfg.recordEvent(rel, EventReasonUninstallSucceeded, fmt.Sprintf("Uninstalled release %s", rel.Name), nil)
+	u.cfg.runPostLifecycleHooks(LifecyclePostUninstall, rel, nil)
+	return nil
 }
 
 func (u *Uninstall) purgeReleases(rels ...*release.Release) error {
@@ -197,7 +215,7 @@ func joinErrors(errs []error) string {
 func (u *Uninstall) deleteRelease(rel *release.Release) (kube.ResourceList, string, []error) {
 	var errs []error
 
-	manifests := releaseutil.SplitManifests(rel.Manifest)
+	manifests := releaseManifests(rel)
 	_, files, err := releaseutil.SortManifests(manifests, nil, releaseutil.UninstallOrder)
 	if err != nil {
 		// We could instead just delete everything in no particular order.
@@ -232,6 +250,20 @@ func (u *Uninstall) deleteRelease(rel *release.Release) (kube.ResourceList, stri
 	return resources, kept, errs
 }
 
+// releaseManifests returns the manifests of the resources of the release,
+// keyed like SplitManifests does, using its index if it has one.
+func releaseManifests(rel *release.Release) map[string]string {
+	if rel.ManifestIndex == nil {
+		return releaseutil.SplitManifests(rel.Manifest)
+	}
+	resources := releaseResources(rel)
+	manifests := make(map[string]string, len(resources))
+	for i, r := range resources {
+		manifests[fmt.Sprintf("manifest-%d", i)] = strings.TrimSpace(r.Manifest)
+	}
+	return manifests
+}
+
 func parseCascadingFlag(cfg *Configuration, cascadingFlag string) v1.DeletionPropagation {
 	switch cascadingFlag {
 	case "orphan":
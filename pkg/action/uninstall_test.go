@@ -24,6 +24,7 @@ import (
 
 	kubefake "helm.sh/helm/v3/pkg/kube/fake"
 	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
 )
 
 func uninstallAction(t *testing.T) *Uninstall {
@@ -76,6 +77,39 @@ func TestUninstallRelease_deleteRelease(t *testing.T) {
 	is.Contains(res.Info, expected)
 }
 
+func TestUninstallRelease_ManifestIndex(t *testing.T) {
+	is := assert.New(t)
+
+	unAction := uninstallAction(t)
+	unAction.DisableHooks = true
+	unAction.KeepHistory = true
+
+	rel := releaseStub()
+	rel.Name = "indexed"
+	rel.Manifest = `---
+# Source: hello/templates/secret.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: kept
+  annotations:
+    helm.sh/resource-policy: keep
+---
+# Source: hello/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: deleted
+`
+	rel.ManifestIndex = releaseutil.IndexManifest(rel.Manifest)
+	is.Equal(releaseutil.SplitManifests(rel.Manifest), releaseManifests(rel))
+
+	is.NoError(unAction.cfg.Releases.Create(rel))
+	res, err := unAction.Run(rel.Name)
+	is.NoError(err)
+	is.Contains(res.Info, "[Secret] kept\n")
+}
+
 func TestUninstallRelease_Wait(t *testing.T) {
 	is := assert.New(t)
 
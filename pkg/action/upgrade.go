@@ -25,6 +25,7 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/cli-runtime/pkg/resource"
 
@@ -35,6 +36,7 @@ import (
 	"helm.sh/helm/v3/pkg/registry"
 	"helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/releaseutil"
+	"helm.sh/helm/v3/pkg/renderhook"
 	"helm.sh/helm/v3/pkg/storage/driver"
 )
 
@@ -64,6 +66,14 @@ type Upgrade struct {
 	SkipCRDs bool
 	// Timeout is the timeout for this operation
 	Timeout time.Duration
+	// KindTimeouts overrides Timeout when waiting for resources of the given kinds.
+	KindTimeouts map[string]time.Duration
+	// BatchSize, if greater than zero, applies resources in batches of at most
+	// this many resources. With Wait, each batch must be ready before the next
+	// one is applied.
+	BatchSize int
+	// BatchRetries is the number of times a batch is retried after a transient failure.
+	BatchRetries int
 	// Wait determines whether the wait operation should be performed after the upgrade is requested.
 	Wait bool
 	// WaitForJobs determines whether the wait operation for the Jobs should be performed after the upgrade is requested.
@@ -104,13 +114,30 @@ type Upgrade struct {
 	// Description is the description of this operation
 	Description string
 	Labels      map[string]string
+	// Annotations are merged with the annotations of the last release, the
+	// annotations set to "null" being removed.
+	Annotations map[string]string
+	// ValueSources are the sources of the values of the release, recorded
+	// with it after the values of the previous revision, if reused.
+	ValueSources []release.ValueSource
 	// PostRender is an optional post-renderer
 	//
 	// If this is non-nil, then after templates are rendered, they will be sent to the
 	// post renderer before sending to the Kubernetes API server.
 	PostRenderer postrender.PostRenderer
+	// PostRendererName is the name of a post-renderer registered with
+	// postrender.Register, run in process after PostRenderer, configured by
+	// PostRendererArgs.
+	PostRendererName string
+	PostRendererArgs []string
+	// RenderHooks are run in process on the rendered manifests and hooks,
+	// before the post-renderer. They can mutate the manifests or veto them.
+	RenderHooks []renderhook.Hook
 	// DisableOpenAPIValidation controls whether OpenAPI validation is enforced.
 	DisableOpenAPIValidation bool
+	// ValidateManifests validates all rendered resources and hooks against the
+	// cluster schema before applying anything.
+	ValidateManifests bool
 	// Get missing dependencies
 	DependencyUpdate bool
 	// Lock to control raceconditions when the process receives a SIGTERM
@@ -119,6 +146,30 @@ type Upgrade struct {
 	EnableDNS bool
 	// TakeOwnership will skip the check for helm annotations and adopt all existing resources.
 	TakeOwnership bool
+	// Confirm, if set, is called with the changes the upgrade would make to
+	// the cluster before anything is applied. The upgrade is cancelled with
+	// ErrUpgradeNotConfirmed unless it returns true.
+	Confirm func(plan *UpgradePlan) (bool, error)
+}
+
+// ErrUpgradeNotConfirmed is returned when the changes of an upgrade are not
+// confirmed.
+var ErrUpgradeNotConfirmed = errors.New("upgrade not confirmed")
+
+// UpgradePlan lists the changes an upgrade would make to the resources of a
+// release.
+type UpgradePlan struct {
+	Release   string `json:"release"`
+	Namespace string `json:"namespace"`
+	Revision  int    `json:"revision"`
+	// Resources holds the resources which would be created, updated or
+	// deleted, with the values of the Secrets redacted.
+	Resources []kube.ResourceDiff `json:"resources"`
+}
+
+// Changed reports whether the upgrade would change any resource.
+func (p *UpgradePlan) Changed() bool {
+	return len(p.Resources) > 0
 }
 
 type resultMessage struct {
@@ -238,10 +289,14 @@ func (u *Upgrade) prepareUpgrade(name string, chart *chart.Chart, vals map[strin
 	}
 
 	// determine if values will be reused
-	vals, err = u.reuseValues(chart, currentRelease, vals)
+	vals, reused, err := u.reuseValues(chart, currentRelease, vals)
 	if err != nil {
 		return nil, nil, err
 	}
+	valueSources := u.ValueSources
+	if reused {
+		valueSources = append([]release.ValueSource{{Type: release.ValueSourceRevision, Revision: currentRelease.Version}}, valueSources...)
+	}
 
 	if err := chartutil.ProcessDependenciesWithMerge(chart, vals); err != nil {
 		return nil, nil, err
@@ -273,7 +328,19 @@ func (u *Upgrade) prepareUpgrade(name string, chart *chart.Chart, vals map[strin
 		interactWithRemote = true
 	}
 
-	hooks, manifestDoc, notesTxt, err := u.cfg.renderResources(chart, valuesToRender, "", "", u.SubNotes, false, false, u.PostRenderer, interactWithRemote, u.EnableDNS, u.HideSecret)
+	pr, err := postRenderer(u.PostRenderer, u.PostRendererName, u.PostRendererArgs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hooks, manifestDoc, notesTxt, err := u.cfg.renderResources(chart, valuesToRender, "", "", u.SubNotes, false, false, pr, u.RenderHooks, renderhook.Context{
+		Operation:    renderhook.OperationUpgrade,
+		ReleaseName:  name,
+		Namespace:    u.Namespace,
+		Revision:     revision,
+		Chart:        chart.Name(),
+		ChartVersion: chart.Metadata.Version,
+	}, interactWithRemote, u.EnableDNS, u.HideSecret)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -294,10 +361,13 @@ func (u *Upgrade) prepareUpgrade(name string, chart *chart.Chart, vals map[strin
 			Status:        release.StatusPendingUpgrade,
 			Description:   "Preparing upgrade", // This should be overwritten later.
 		},
-		Version:  revision,
-		Manifest: manifestDoc.String(),
-		Hooks:    hooks,
-		Labels:   mergeCustomLabels(lastRelease.Labels, u.Labels),
+		Version:       revision,
+		Manifest:      manifestDoc.String(),
+		ManifestIndex: releaseutil.IndexManifest(manifestDoc.String()),
+		Hooks:         hooks,
+		Labels:        mergeCustomLabels(lastRelease.Labels, u.Labels),
+		Annotations:   mergeCustomLabels(lastRelease.Annotations, u.Annotations),
+		ValueSources:  valueSources,
 	}
 
 	if len(notesTxt) > 0 {
@@ -324,6 +394,12 @@ func (u *Upgrade) performUpgrade(ctx context.Context, originalRelease, upgradedR
 		return upgradedRelease, errors.Wrap(err, "unable to build kubernetes objects from new release manifest")
 	}
 
+	if u.ValidateManifests {
+		if err := validateSchemas(u.cfg.KubeClient, target, upgradedRelease.Hooks); err != nil {
+			return upgradedRelease, errors.Wrap(err, "manifest validation failed")
+		}
+	}
+
 	// It is safe to use force only on target because these are resources currently rendered by the chart.
 	err = target.Visit(setMetadataVisitor(upgradedRelease.Name, upgradedRelease.Namespace, true))
 	if err != nil {
@@ -372,10 +448,29 @@ func (u *Upgrade) performUpgrade(ctx context.Context, originalRelease, upgradedR
 		return upgradedRelease, nil
 	}
 
+	if u.Confirm != nil {
+		plan, err := u.plan(upgradedRelease, current, target)
+		if err != nil {
+			return nil, err
+		}
+		confirmed, err := u.Confirm(plan)
+		if err != nil {
+			return nil, err
+		}
+		if !confirmed {
+			return nil, ErrUpgradeNotConfirmed
+		}
+	}
+
+	if err := u.cfg.runLifecycleHooks(LifecyclePreUpgrade, upgradedRelease); err != nil {
+		return nil, err
+	}
+
 	u.cfg.Log("creating upgraded release for %s", upgradedRelease.Name)
 	if err := u.cfg.Releases.Create(upgradedRelease); err != nil {
 		return nil, err
 	}
+	u.cfg.recordEvent(upgradedRelease, EventReasonUpgradeStarted, fmt.Sprintf("Upgrading release %s to revision %d", upgradedRelease.Name, upgradedRelease.Version), nil)
 	rChan := make(chan resultMessage)
 	ctxChan := make(chan resultMessage)
 	doneChan := make(chan interface{})
@@ -390,6 +485,46 @@ func (u *Upgrade) performUpgrade(ctx context.Context, originalRelease, upgradedR
 	}
 }
 
+// plan diffs the target resources against the cluster, and lists the current
+// resources the upgrade would delete.
+func (u *Upgrade) plan(upgradedRelease *release.Release, current, target kube.ResourceList) (*UpgradePlan, error) {
+	kubeClient, ok := u.cfg.KubeClient.(kube.InterfaceDiff)
+	if !ok {
+		return nil, errors.New("unable to get kubeClient with interface InterfaceDiff")
+	}
+	diffs, err := kubeClient.GetDiff(target)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to diff the upgraded resources")
+	}
+
+	plan := &UpgradePlan{
+		Release:   upgradedRelease.Name,
+		Namespace: upgradedRelease.Namespace,
+		Revision:  upgradedRelease.Version,
+		Resources: []kube.ResourceDiff{},
+	}
+	for _, diff := range diffs {
+		if diff.Changed() {
+			plan.Resources = append(plan.Resources, diff.RedactSecrets())
+		}
+	}
+	for _, info := range current.Difference(target) {
+		// The resources kept by their policy are not deleted by the upgrade
+		if accessor, err := meta.Accessor(info.Object); err == nil && accessor.GetAnnotations()[kube.ResourcePolicyAnno] == kube.KeepPolicy {
+			continue
+		}
+		gvk := info.Mapping.GroupVersionKind
+		plan.Resources = append(plan.Resources, kube.ResourceDiff{
+			APIVersion: gvk.GroupVersion().String(),
+			Kind:       gvk.Kind,
+			Namespace:  info.Namespace,
+			Name:       info.Name,
+			Action:     kube.DiffActionDelete,
+		})
+	}
+	return plan, nil
+}
+
 // Function used to lock the Mutex, this is important for the case when the atomic flag is set.
 // In that case the upgrade will finish before the rollback is finished so it is necessary to wait for the rollback to finish.
 // The rollback will be trigger by the function failRelease
@@ -426,7 +561,22 @@ func (u *Upgrade) releasingUpgrade(c chan<- resultMessage, upgradedRelease *rele
 		u.cfg.Log("upgrade hooks disabled for %s", upgradedRelease.Name)
 	}
 
-	results, err := u.cfg.KubeClient.Update(current, target, u.Force)
+	var results *kube.Result
+	var err error
+	if u.BatchSize > 0 {
+		results, err = kube.ApplyInBatches(u.cfg.KubeClient, current, target, u.Force, kube.BatchOptions{
+			Size:    u.BatchSize,
+			Wait:    u.Wait,
+			Retries: u.BatchRetries,
+			WaitOptions: kube.WaitOptions{
+				Timeout:      u.Timeout,
+				KindTimeouts: u.KindTimeouts,
+				WaitForJobs:  u.WaitForJobs,
+			},
+		})
+	} else {
+		results, err = u.cfg.KubeClient.Update(current, target, u.Force)
+	}
 	if err != nil {
 		u.cfg.recordRelease(originalRelease)
 		u.reportToPerformUpgrade(c, upgradedRelease, results.Created, err)
@@ -447,18 +597,15 @@ func (u *Upgrade) releasingUpgrade(c chan<- resultMessage, upgradedRelease *rele
 		u.cfg.Log(
 			"waiting for release %s resources (created: %d updated: %d  deleted: %d)",
 			upgradedRelease.Name, len(results.Created), len(results.Updated), len(results.Deleted))
-		if u.WaitForJobs {
-			if err := u.cfg.KubeClient.WaitWithJobs(target, u.Timeout); err != nil {
-				u.cfg.recordRelease(originalRelease)
-				u.reportToPerformUpgrade(c, upgradedRelease, results.Created, err)
-				return
-			}
-		} else {
-			if err := u.cfg.KubeClient.Wait(target, u.Timeout); err != nil {
-				u.cfg.recordRelease(originalRelease)
-				u.reportToPerformUpgrade(c, upgradedRelease, results.Created, err)
-				return
-			}
+		err := kube.WaitWithOptions(u.cfg.KubeClient, target, kube.WaitOptions{
+			Timeout:      u.Timeout,
+			KindTimeouts: u.KindTimeouts,
+			WaitForJobs:  u.WaitForJobs,
+		})
+		if err != nil {
+			u.cfg.recordRelease(originalRelease)
+			u.reportToPerformUpgrade(c, upgradedRelease, results.Created, err)
+			return
 		}
 	}
 
@@ -479,6 +626,9 @@ func (u *Upgrade) releasingUpgrade(c chan<- resultMessage, upgradedRelease *rele
 	} else {
 		upgradedRelease.Info.Description = "Upgrade complete"
 	}
+	u.cfg.recordClusterState(upgradedRelease, nil)
+	u.cfg.recordEvent(upgradedRelease, EventReasonUpgradeSucceeded, fmt.Sprintf("Upgraded release %s to revision %d", upgradedRelease.Name, upgradedRelease.Version), nil)
+	u.cfg.runPostLifecycleHooks(LifecyclePostUpgrade, upgradedRelease, nil)
 	u.reportToPerformUpgrade(c, upgradedRelease, nil, nil)
 }
 
@@ -488,6 +638,9 @@ func (u *Upgrade) failRelease(rel *release.Release, created kube.ResourceList, e
 
 	rel.Info.Status = release.StatusFailed
 	rel.Info.Description = msg
+	u.cfg.recordClusterState(rel, err)
+	u.cfg.recordEvent(rel, EventReasonUpgradeFailed, fmt.Sprintf("Failed to upgrade release %s to revision %d", rel.Name, rel.Version), err)
+	u.cfg.runPostLifecycleHooks(LifecyclePostUpgrade, rel, err)
 	u.cfg.recordRelease(rel)
 	if u.CleanupOnFail && len(created) > 0 {
 		u.cfg.Log("Cleanup on fail set, cleaning up %d resources", len(created))
@@ -549,11 +702,13 @@ func (u *Upgrade) failRelease(rel *release.Release, created kube.ResourceList, e
 //
 // This is skipped if the u.ResetValues flag is set, in which case the
 // request values are not altered.
-func (u *Upgrade) reuseValues(chart *chart.Chart, current *release.Release, newVals map[string]interface{}) (map[string]interface{}, error) {
+//
+// It also returns whether the values of the current release were reused.
+func (u *Upgrade) reuseValues(chart *chart.Chart, current *release.Release, newVals map[string]interface{}) (map[string]interface{}, bool, error) {
 	if u.ResetValues {
 		// If ResetValues is set, we completely ignore current.Config.
 		u.cfg.Log("resetting values to the chart's original version")
-		return newVals, nil
+		return newVals, false, nil
 	}
 
 	// If the ReuseValues flag is set, we always copy the old values over the new config's values.
@@ -563,14 +718,14 @@ func (u *Upgrade) reuseValues(chart *chart.Chart, current *release.Release, newV
 		// We have to regenerate the old coalesced values:
 		oldVals, err := chartutil.CoalesceValues(current.Chart, current.Config)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to rebuild old values")
+			return nil, false, errors.Wrap(err, "failed to rebuild old values")
 		}
 
 		newVals = chartutil.CoalesceTables(newVals, current.Config)
 
 		chart.Values = oldVals
 
-		return newVals, nil
+		return newVals, true, nil
 	}
 
 	// If the ResetThenReuseValues flag is set, we use the new chart's values, but we copy the old config's values over the new config's values.
@@ -579,14 +734,14 @@ func (u *Upgrade) reuseValues(chart *chart.Chart, current *release.Release, newV
 
 		newVals = chartutil.CoalesceTables(newVals, current.Config)
 
-		return newVals, nil
+		return newVals, true, nil
 	}
 
 	if len(newVals) == 0 && len(current.Config) > 0 {
 		u.cfg.Log("copying values from %s (v%d) to new release.", current.Name, current.Version)
-		newVals = current.Config
+		return current.Config, true, nil
 	}
-	return newVals, nil
+	return newVals, false, nil
 }
 
 func validateManifest(c kube.Interface, manifest []byte, openAPIValidation bool) error {
@@ -29,6 +29,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"helm.sh/helm/v3/pkg/kube"
 	kubefake "helm.sh/helm/v3/pkg/kube/fake"
 	"helm.sh/helm/v3/pkg/release"
 	helmtime "helm.sh/helm/v3/pkg/time"
@@ -506,6 +507,75 @@ func TestUpgradeRelease_Labels(t *testing.T) {
 	is.Equal(initialRes.Labels, rel.Labels)
 }
 
+func TestUpgradeRelease_Annotations(t *testing.T) {
+	is := assert.New(t)
+	upAction := upgradeAction(t)
+
+	rel := releaseStub()
+	rel.Name = "annotations"
+	rel.Annotations = map[string]string{
+		"team":   "web",
+		"ticket": "OPS-1",
+	}
+	rel.Info.Status = release.StatusDeployed
+	is.NoError(upAction.cfg.Releases.Create(rel))
+
+	upAction.Annotations = map[string]string{
+		"team":   "null",
+		"ticket": "OPS-2",
+	}
+	res, err := upAction.Run(rel.Name, buildChart(), nil)
+	is.NoError(err)
+
+	updatedRes, err := upAction.cfg.Releases.Get(res.Name, 2)
+	is.NoError(err)
+	is.Equal(map[string]string{"ticket": "OPS-2"}, updatedRes.Annotations)
+
+	// The superseded release keeps its annotations
+	initialRes, err := upAction.cfg.Releases.Get(res.Name, 1)
+	is.NoError(err)
+	is.Equal(map[string]string{"team": "web", "ticket": "OPS-1"}, initialRes.Annotations)
+}
+
+func TestUpgradeRelease_ValueSources(t *testing.T) {
+	is := assert.New(t)
+	sources := []release.ValueSource{{Type: release.ValueSourceSet, Value: "name=value"}}
+
+	t.Run("without reused values", func(t *testing.T) {
+		upAction := upgradeAction(t)
+		rel := releaseStub()
+		rel.Info.Status = release.StatusDeployed
+		is.NoError(upAction.cfg.Releases.Create(rel))
+
+		upAction.ResetValues = true
+		upAction.ValueSources = sources
+		res, err := upAction.Run(rel.Name, buildChart(), map[string]interface{}{"name": "value"})
+		is.NoError(err)
+		is.Equal(sources, res.ValueSources)
+	})
+
+	t.Run("with reused values", func(t *testing.T) {
+		upAction := upgradeAction(t)
+		rel := releaseStub()
+		rel.Info.Status = release.StatusDeployed
+		rel.Config = map[string]interface{}{"reused": "value"}
+		is.NoError(upAction.cfg.Releases.Create(rel))
+
+		upAction.ReuseValues = true
+		upAction.ValueSources = sources
+		res, err := upAction.Run(rel.Name, buildChart(), map[string]interface{}{"name": "value"})
+		is.NoError(err)
+		is.Equal([]release.ValueSource{
+			{Type: release.ValueSourceRevision, Revision: rel.Version},
+			sources[0],
+		}, res.ValueSources)
+
+		stored, err := upAction.cfg.Releases.Get(rel.Name, res.Version)
+		is.NoError(err)
+		is.Equal(res.ValueSources, stored.ValueSources)
+	})
+}
+
 func TestUpgradeRelease_SystemLabels(t *testing.T) {
 	is := assert.New(t)
 	upAction := upgradeAction(t)
@@ -586,3 +656,54 @@ func TestUpgradeRelease_DryRun(t *testing.T) {
 	done()
 	req.Error(err)
 }
+
+func TestUpgradeRelease_Confirm(t *testing.T) {
+	is := assert.New(t)
+	req := require.New(t)
+
+	upAction := upgradeAction(t)
+	rel := releaseStub()
+	rel.Name = "confirmed"
+	rel.Info.Status = release.StatusDeployed
+	req.NoError(upAction.cfg.Releases.Create(rel))
+
+	failer := upAction.cfg.KubeClient.(*kubefake.FailingKubeClient)
+	failer.Diffs = []kube.ResourceDiff{
+		{Kind: "ConfigMap", Name: "unchanged", Action: kube.DiffActionNone},
+		{Kind: "Deployment", Name: "web", Action: kube.DiffActionUpdate},
+		{APIVersion: "v1", Kind: "Secret", Name: "credentials", Action: kube.DiffActionUpdate, Fields: []kube.FieldDiff{
+			{Path: "data.password", Live: "b2xk", Desired: "bmV3"},
+		}},
+	}
+
+	var plan *UpgradePlan
+	upAction.Confirm = func(p *UpgradePlan) (bool, error) {
+		plan = p
+		return false, nil
+	}
+	_, err := upAction.Run(rel.Name, buildChart(), map[string]interface{}{})
+	req.ErrorIs(err, ErrUpgradeNotConfirmed)
+	req.NotNil(plan)
+	is.Equal(rel.Name, plan.Release)
+	is.Equal(rel.Version+1, plan.Revision)
+	is.True(plan.Changed())
+	req.Len(plan.Resources, 2)
+	is.Equal("web", plan.Resources[0].Name)
+	// The values of the Secrets are not disclosed by the plan
+	is.Equal([]kube.FieldDiff{{Path: "data.password", Live: kube.RedactedValue, Desired: kube.RedactedValue}}, plan.Resources[1].Fields)
+
+	// Nothing is applied nor recorded without a confirmation
+	lastRelease, err := upAction.cfg.Releases.Last(rel.Name)
+	req.NoError(err)
+	is.Equal(rel.Version, lastRelease.Version)
+
+	upAction.Confirm = func(_ *UpgradePlan) (bool, error) { return true, nil }
+	res, err := upAction.Run(rel.Name, buildChart(), map[string]interface{}{})
+	req.NoError(err)
+	is.Equal(release.StatusDeployed, res.Info.Status)
+	is.Equal(rel.Version+1, res.Version)
+
+	failer.GetDiffError = fmt.Errorf("dry-run rejected")
+	_, err = upAction.Run(rel.Name, buildChart(), map[string]interface{}{})
+	req.ErrorContains(err, "dry-run rejected")
+}
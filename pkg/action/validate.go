@@ -17,6 +17,7 @@ limitations under the License.
 package action
 
 import (
+	"bytes"
 	"fmt"
 
 	"github.com/pkg/errors"
@@ -26,6 +27,7 @@ import (
 	"k8s.io/cli-runtime/pkg/resource"
 
 	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/release"
 )
 
 var accessor = meta.NewAccessor()
@@ -37,6 +39,25 @@ const (
 	helmReleaseNamespaceAnnotation = "meta.helm.sh/release-namespace"
 )
 
+// validateSchemas checks the release resources and hooks against the cluster
+// schema before anything is applied. It is a no-op for clients that do not
+// implement kube.InterfaceValidate.
+func validateSchemas(kubeClient kube.Interface, resources kube.ResourceList, hooks []*release.Hook) error {
+	v, ok := kubeClient.(kube.InterfaceValidate)
+	if !ok {
+		return nil
+	}
+	all := append(kube.ResourceList{}, resources...)
+	for _, h := range hooks {
+		hookResources, err := kubeClient.Build(bytes.NewBufferString(h.Manifest), false)
+		if err != nil {
+			return errors.Wrapf(err, "unable to build kubernetes object for hook %s", h.Path)
+		}
+		all = append(all, hookResources...)
+	}
+	return v.Validate(all)
+}
+
 // requireAdoption returns the subset of resources that already exist in the cluster.
 func requireAdoption(resources kube.ResourceList) (kube.ResourceList, error) {
 	var requireUpdate kube.ResourceList
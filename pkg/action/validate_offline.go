@@ -0,0 +1,83 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/pkg/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/internal/deprecations"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
+)
+
+// ValidateOffline validates the manifests of a release and of its hooks
+// without cluster access, as 'helm template --validate-offline' does.
+//
+// The resources are validated with kube.BuiltinSchema, against the
+// Kubernetes API types compiled into Helm whatever kubeVersion is, reporting
+// the unknown fields and the values of the wrong type. Only the resources
+// using APIs no longer served by kubeVersion depend on it, and are rejected. The kinds unknown to Helm, such as custom resources, are not
+// validated. All the violations are returned together as a
+// *kube.ValidationError.
+func ValidateOffline(rel *release.Release, kubeVersion *chartutil.KubeVersion) error {
+	major, _ := strconv.Atoi(kubeVersion.Major)
+	minor, _ := strconv.Atoi(kubeVersion.Minor)
+
+	schema := kube.BuiltinSchema()
+	var errs []error
+	for _, m := range releaseutil.ReleaseManifests(rel) {
+		data, err := yaml.YAMLToJSON([]byte(m.Content))
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "%s: unable to parse YAML", m.Name))
+			continue
+		}
+		var head struct {
+			APIVersion string `json:"apiVersion"`
+			Kind       string `json:"kind"`
+			Metadata   struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+		}
+		if string(data) == "null" || json.Unmarshal(data, &head) != nil {
+			continue
+		}
+
+		if d := deprecations.Check(head.APIVersion, head.Kind, major, minor); d != nil && d.Removed {
+			errs = append(errs, errors.Errorf("%s: %s %q: %s", m.Name, head.Kind, head.Metadata.Name, d))
+		}
+		if err := schema.ValidateBytes(data); err != nil {
+			var agg utilerrors.Aggregate
+			if !errors.As(err, &agg) {
+				agg = utilerrors.NewAggregate([]error{err})
+			}
+			for _, e := range agg.Errors() {
+				errs = append(errs, errors.Wrapf(e, "%s: %s %q", m.Name, head.Kind, head.Metadata.Name))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return &kube.ValidationError{Errors: errs}
+	}
+	return nil
+}
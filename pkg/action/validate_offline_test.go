@@ -0,0 +1,109 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package action
+
+import (
+	"errors"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+func TestValidateOffline(t *testing.T) {
+	rel := &release.Release{
+		Manifest: `---
+# Source: app/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: "two"
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: nginx
+        imagePullPolicy: Always
+        port: 80
+---
+# Source: app/templates/cronjob.yaml
+apiVersion: batch/v1beta1
+kind: CronJob
+metadata:
+  name: backup
+spec:
+  schedule: "@daily"
+---
+# Source: app/templates/monitor.yaml
+apiVersion: monitoring.coreos.com/v1
+kind: ServiceMonitor
+metadata:
+  name: web
+spec:
+  anything: goes
+---
+# Source: app/templates/empty.yaml
+# nothing rendered
+`,
+		Hooks: []*release.Hook{{
+			Path: "app/templates/job.yaml",
+			Manifest: `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+spec:
+  backoffLimit: true
+`,
+		}},
+	}
+
+	kubeVersion := &chartutil.KubeVersion{Version: "v1.29.0", Major: "1", Minor: "29"}
+	err := ValidateOffline(rel, kubeVersion)
+	var verr *kube.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected a validation error, got %v", err)
+	}
+	expect := []string{
+		`templates/deployment.yaml: Deployment "web": spec.replicas: expected integer, got string`,
+		`templates/deployment.yaml: Deployment "web": spec.template.spec.containers[0]: unknown field "port"`,
+		`templates/cronjob.yaml: CronJob "backup": batch/v1beta1 CronJob is unavailable in v1.25+; use batch/v1 CronJob`,
+		`app/templates/job.yaml: Job "migrate": spec.backoffLimit: expected integer, got boolean`,
+	}
+	if len(verr.Errors) != len(expect) {
+		t.Fatalf("Expected %d errors, got %v", len(expect), verr.Errors)
+	}
+	for i, e := range verr.Errors {
+		if e.Error() != expect[i] {
+			t.Errorf("Expected error %q, got %q", expect[i], e)
+		}
+	}
+
+	// The CronJob API is still served by Kubernetes 1.24.
+	kubeVersion = &chartutil.KubeVersion{Version: "v1.24.0", Major: "1", Minor: "24"}
+	if err := ValidateOffline(rel, kubeVersion); !errors.As(err, &verr) || len(verr.Errors) != 3 {
+		t.Errorf("Expected 3 errors, got %v", err)
+	}
+}
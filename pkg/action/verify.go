@@ -18,17 +18,25 @@ package action
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
 	"helm.sh/helm/v3/pkg/downloader"
+	"helm.sh/helm/v3/pkg/registry"
 )
 
 // Verify is the action for building a given chart's Verify tree.
 //
 // It provides the implementation of 'helm verify'.
 type Verify struct {
+	// Keyring is the keyring verifying the provenance file of the chart, or
+	// a list of keyring files and directories as accepted by
+	// downloader.VerifyChart.
 	Keyring string
-	Out     string
+	// SignaturePolicy verifies the cosign bundle of the chart, if it has one.
+	// The chart is verified with the provenance file only if it is nil.
+	SignaturePolicy *registry.SignaturePolicy
+	Out             string
 }
 
 // NewVerify creates a new Verify object with the given configuration.
@@ -37,18 +45,40 @@ func NewVerify() *Verify {
 }
 
 // Run executes 'helm verify'.
+//
+// The chart is verified with its cosign bundle if there is a signature policy
+// and the chart has one, and with its provenance file if it has one or has no
+// cosign bundle. Both must be valid if the chart has both.
 func (v *Verify) Run(chartfile string) error {
 	var out strings.Builder
-	p, err := downloader.VerifyChart(chartfile, v.Keyring)
-	if err != nil {
-		return err
+	verified := false
+	if v.SignaturePolicy != nil {
+		if _, err := os.Stat(chartfile + registry.SignatureBundleExt); err == nil {
+			p, err := downloader.VerifyChartBundle(chartfile, v.SignaturePolicy)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(&out, "Signed by: %s\n", p.Signer)
+			fmt.Fprintf(&out, "Chart Hash Verified: %s\n", p.FileHash)
+			verified = true
+		}
 	}
 
-	for name := range p.SignedBy.Identities {
-		fmt.Fprintf(&out, "Signed by: %v\n", name)
+	if _, err := os.Stat(chartfile + ".prov"); err == nil || !verified {
+		p, err := downloader.VerifyChart(chartfile, v.Keyring)
+		if err != nil {
+			return err
+		}
+
+		for name := range p.SignedBy.Identities {
+			fmt.Fprintf(&out, "Signed by: %v\n", name)
+		}
+		fmt.Fprintf(&out, "Using Key With Fingerprint: %X\n", p.SignedBy.PrimaryKey.Fingerprint)
+		if p.Keyring != "" {
+			fmt.Fprintf(&out, "Found In Keyring: %s\n", p.Keyring)
+		}
+		fmt.Fprintf(&out, "Chart Hash Verified: %s\n", p.FileHash)
 	}
-	fmt.Fprintf(&out, "Using Key With Fingerprint: %X\n", p.SignedBy.PrimaryKey.Fingerprint)
-	fmt.Fprintf(&out, "Chart Hash Verified: %s\n", p.FileHash)
 
 	// TODO(mattfarina): The output is set as a property rather than returned
 	// to maintain the Go API. In Helm v4 this function should return the out
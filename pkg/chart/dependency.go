@@ -47,6 +47,19 @@ type Dependency struct {
 	ImportValues []interface{} `json:"import-values,omitempty"`
 	// Alias usable alias to be used for the chart
 	Alias string `json:"alias,omitempty"`
+	// Digest is the digest of the manifest of a dependency stored in an OCI
+	// registry, or the commit of a dependency stored in a git repository.
+	//
+	// A lock file records it, so the dependency fails to build if its tag
+	// is moved to other content in a registry, and is built from the same
+	// commit if its ref is moved in a git repository.
+	Digest string `json:"digest,omitempty"`
+	// Checksum is the SHA256 digest of the archive of a dependency downloaded
+	// from a repository or an OCI registry, like sha256:<hex>.
+	//
+	// A lock file records it, so the dependency fails to build if the archive
+	// of its version is replaced in the repository.
+	Checksum string `json:"checksum,omitempty"`
 }
 
 // Validate checks for common problems with the dependency datastructure in
@@ -29,6 +29,7 @@ import (
 	"strings"
 
 	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
 
 	"helm.sh/helm/v3/pkg/chart"
 )
@@ -129,55 +130,14 @@ func LoadArchiveFiles(in io.Reader) ([]*BufferedFile, error) {
 			return nil, err
 		}
 
-		if hd.FileInfo().IsDir() {
-			// Use this instead of hd.Typeflag because we don't have to do any
-			// inference chasing.
-			continue
+		n, skip, err := archiveEntryName(hd)
+		if err != nil {
+			return nil, err
 		}
-
-		switch hd.Typeflag {
-		// We don't want to process these extension header files.
-		case tar.TypeXGlobalHeader, tar.TypeXHeader:
+		if skip {
 			continue
 		}
 
-		// Archive could contain \ if generated on Windows
-		delimiter := "/"
-		if strings.ContainsRune(hd.Name, '\\') {
-			delimiter = "\\"
-		}
-
-		parts := strings.Split(hd.Name, delimiter)
-		n := strings.Join(parts[1:], delimiter)
-
-		// Normalize the path to the / delimiter
-		n = strings.ReplaceAll(n, delimiter, "/")
-
-		if path.IsAbs(n) {
-			return nil, errors.New("chart illegally contains absolute paths")
-		}
-
-		n = path.Clean(n)
-		if n == "." {
-			// In this case, the original path was relative when it should have been absolute.
-			return nil, errors.Errorf("chart illegally contains content outside the base directory: %q", hd.Name)
-		}
-		if strings.HasPrefix(n, "..") {
-			return nil, errors.New("chart illegally references parent directory")
-		}
-
-		// In some particularly arcane acts of path creativity, it is possible to intermix
-		// UNIX and Windows style paths in such a way that you produce a result of the form
-		// c:/foo even after all the built-in absolute path checks. So we explicitly check
-		// for this condition.
-		if drivePathPattern.MatchString(n) {
-			return nil, errors.New("chart contains illegally named files")
-		}
-
-		if parts[0] == "Chart.yaml" {
-			return nil, errors.New("chart yaml not in base directory")
-		}
-
 		if _, err := io.Copy(b, tr); err != nil {
 			return nil, err
 		}
@@ -194,6 +154,127 @@ func LoadArchiveFiles(in io.Reader) ([]*BufferedFile, error) {
 	return files, nil
 }
 
+// archiveEntryName returns the path of the file of the header relative to the
+// base directory of the chart archive, performing the path security checks,
+// or whether the entry is not a file of the chart.
+func archiveEntryName(hd *tar.Header) (string, bool, error) {
+	if hd.FileInfo().IsDir() {
+		// Use this instead of hd.Typeflag because we don't have to do any
+		// inference chasing.
+		return "", true, nil
+	}
+
+	switch hd.Typeflag {
+	// We don't want to process these extension header files.
+	case tar.TypeXGlobalHeader, tar.TypeXHeader:
+		return "", true, nil
+	}
+
+	// Archive could contain \ if generated on Windows
+	delimiter := "/"
+	if strings.ContainsRune(hd.Name, '\\') {
+		delimiter = "\\"
+	}
+
+	parts := strings.Split(hd.Name, delimiter)
+	n := strings.Join(parts[1:], delimiter)
+
+	// Normalize the path to the / delimiter
+	n = strings.ReplaceAll(n, delimiter, "/")
+
+	if path.IsAbs(n) {
+		return "", false, errors.New("chart illegally contains absolute paths")
+	}
+
+	n = path.Clean(n)
+	if n == "." {
+		// In this case, the original path was relative when it should have been absolute.
+		return "", false, errors.Errorf("chart illegally contains content outside the base directory: %q", hd.Name)
+	}
+	if strings.HasPrefix(n, "..") {
+		return "", false, errors.New("chart illegally references parent directory")
+	}
+
+	// In some particularly arcane acts of path creativity, it is possible to intermix
+	// UNIX and Windows style paths in such a way that you produce a result of the form
+	// c:/foo even after all the built-in absolute path checks. So we explicitly check
+	// for this condition.
+	if drivePathPattern.MatchString(n) {
+		return "", false, errors.New("chart contains illegally named files")
+	}
+
+	if parts[0] == "Chart.yaml" {
+		return "", false, errors.New("chart yaml not in base directory")
+	}
+	return n, false, nil
+}
+
+// LoadArchiveMetadata loads the metadata of a chart from a reader containing
+// a compressed tar archive, without loading the other files of the chart in
+// memory. The dependencies of the requirements.yaml file of apiVersion v1
+// charts are loaded like by LoadArchive.
+func LoadArchiveMetadata(in io.Reader) (*chart.Metadata, error) {
+	unzipped, err := gzip.NewReader(in)
+	if err != nil {
+		return nil, err
+	}
+	defer unzipped.Close()
+
+	var chartfile, requirements []byte
+	found := false
+	tr := tar.NewReader(unzipped)
+	for {
+		hd, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		n, skip, err := archiveEntryName(hd)
+		if err != nil {
+			return nil, err
+		}
+		if skip {
+			continue
+		}
+		found = true
+		if n != "Chart.yaml" && n != "requirements.yaml" {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		data = bytes.TrimPrefix(data, utf8bom)
+		if n == "Chart.yaml" {
+			chartfile = data
+		} else {
+			requirements = data
+		}
+	}
+	if !found {
+		return nil, errors.New("no files in chart archive")
+	}
+	if chartfile == nil {
+		return nil, errors.New("Chart.yaml file is missing")
+	}
+
+	md := new(chart.Metadata)
+	if err := yaml.Unmarshal(chartfile, md); err != nil {
+		return nil, errors.Wrap(err, "cannot load Chart.yaml")
+	}
+	if md.APIVersion == "" {
+		md.APIVersion = chart.APIVersionV1
+	}
+	if requirements != nil {
+		if err := yaml.Unmarshal(requirements, md); err != nil {
+			return nil, errors.Wrap(err, "cannot load requirements.yaml")
+		}
+	}
+	return md, md.Validate()
+}
+
 // LoadArchive loads from a reader containing a compressed tar archive.
 func LoadArchive(in io.Reader) (*chart.Chart, error) {
 	files, err := LoadArchiveFiles(in)
@@ -20,6 +20,8 @@ import (
 	"archive/tar"
 	"bytes"
 	"compress/gzip"
+	"os"
+	"reflect"
 	"testing"
 )
 
@@ -88,3 +90,25 @@ func TestLoadArchiveFiles(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadArchiveMetadata(t *testing.T) {
+	for _, archive := range []string{"testdata/frobnitz-1.2.3.tgz", "testdata/frobnitz.v1.tgz"} {
+		f, err := os.Open(archive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		md, err := LoadArchiveMetadata(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("failed to load the metadata of %s: %s", archive, err)
+		}
+
+		c, err := LoadFile(archive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(md, c.Metadata) {
+			t.Errorf("expected the metadata of %s to be %#v, got %#v", archive, c.Metadata, md)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loader
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// VendorDir is the directory of a chart holding the archives of its vendored
+// dependencies.
+const VendorDir = "vendor"
+
+// LoadDirVendored loads a chart from a directory like LoadDir, preferring the
+// archives of its vendor directory to the subcharts of the same names in its
+// charts directory. The vendor directory is not loaded as files of the chart.
+func LoadDirVendored(dir string) (*chart.Chart, error) {
+	c, err := LoadDir(dir)
+	if err != nil {
+		return c, err
+	}
+	c.Raw = withoutVendored(c.Raw)
+	c.Files = withoutVendored(c.Files)
+
+	archives, err := filepath.Glob(filepath.Join(dir, VendorDir, "*.tgz"))
+	if err != nil {
+		return c, err
+	}
+	vendored := make(map[string]bool)
+	var deps []*chart.Chart
+	for _, archive := range archives {
+		sc, err := LoadFile(archive)
+		if err != nil {
+			return c, errors.Wrapf(err, "error loading vendored chart %s in %s", filepath.Base(archive), c.Name())
+		}
+		vendored[sc.Name()] = true
+		deps = append(deps, sc)
+	}
+	for _, sc := range c.Dependencies() {
+		if !vendored[sc.Name()] {
+			deps = append(deps, sc)
+		}
+	}
+	c.SetDependencies(deps...)
+	return c, nil
+}
+
+func withoutVendored(files []*chart.File) []*chart.File {
+	var kept []*chart.File
+	for _, f := range files {
+		if !strings.HasPrefix(f.Name, VendorDir+"/") {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,89 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loader
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadDirVendored(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"Chart.yaml":              "apiVersion: v2\nname: parent\nversion: 0.1.0\n",
+		"charts/dep/Chart.yaml":   "apiVersion: v2\nname: dep\nversion: 0.1.0\n",
+		"charts/other/Chart.yaml": "apiVersion: v2\nname: other\nversion: 0.1.0\n",
+		"vendor/vendor.lock":      "dependencies: []\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := os.Create(filepath.Join(dir, VendorDir, "dep-0.2.0.tgz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zipper := gzip.NewWriter(f)
+	tw := tar.NewWriter(zipper)
+	body := []byte("apiVersion: v2\nname: dep\nversion: 0.2.0\n")
+	if err := tw.WriteHeader(&tar.Header{Name: "dep/Chart.yaml", Mode: 0644, Size: int64(len(body))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(body); err != nil {
+		t.Fatal(err)
+	}
+	tw.Close()
+	zipper.Close()
+	f.Close()
+
+	c, err := LoadDirVendored(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions := make(map[string]string)
+	for _, sc := range c.Dependencies() {
+		versions[sc.Name()] = sc.Metadata.Version
+	}
+	if len(versions) != 2 || versions["dep"] != "0.2.0" || versions["other"] != "0.1.0" {
+		t.Errorf("expected the vendored dependency to be preferred, got %v", versions)
+	}
+	for _, f := range c.Files {
+		if filepath.Dir(f.Name) == VendorDir {
+			t.Errorf("expected the vendor directory not to be loaded, got %s", f.Name)
+		}
+	}
+
+	// LoadDir ignores the vendor directory
+	c, err = LoadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sc := range c.Dependencies() {
+		if sc.Metadata.Version != "0.1.0" {
+			t.Errorf("expected the dependencies of the charts directory, got %s %s", sc.Name(), sc.Metadata.Version)
+		}
+	}
+}
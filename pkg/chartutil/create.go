@@ -68,6 +68,12 @@ const (
 	HelpersName = TemplatesDir + sep + "_helpers.tpl"
 	// TestConnectionName is the name of the example test file.
 	TestConnectionName = TemplatesTestsDir + sep + "test-connection.yaml"
+	// StatefulSetName is the name of the example statefulset file.
+	StatefulSetName = TemplatesDir + sep + "statefulset.yaml"
+	// CronJobName is the name of the example cronjob file.
+	CronJobName = TemplatesDir + sep + "cronjob.yaml"
+	// NetworkPolicyName is the name of the example networkpolicy file.
+	NetworkPolicyName = TemplatesDir + sep + "networkpolicy.yaml"
 )
 
 // maxChartNameLength is lower than the limits we know of with certain file systems,
@@ -105,9 +111,11 @@ appVersion: "1.16.0"
 const defaultValues = `# Default values for %s.
 # This is a YAML-formatted file.
 # Declare variables to be passed into your templates.
+[[- if or .deployment .statefulset ]]
 
 # This will set the replicaset count more information can be found here: https://kubernetes.io/docs/concepts/workloads/controllers/replicaset/
 replicaCount: 1
+[[- end ]]
 
 # This sets the container image more information can be found here: https://kubernetes.io/docs/concepts/containers/images/
 image:
@@ -122,6 +130,7 @@ imagePullSecrets: []
 # This is to override the chart name.
 nameOverride: ""
 fullnameOverride: ""
+[[- if .serviceaccount ]]
 
 #This section builds out the service account more information can be found here: https://kubernetes.io/docs/concepts/security/service-accounts/
 serviceAccount:
@@ -134,6 +143,7 @@ serviceAccount:
   # The name of the service account to use.
   # If not set and create is true, a name is generated using the fullname template
   name: ""
+[[- end ]]
 
 # This is for setting Kubernetes Annotations to a Pod.
 # For more information checkout: https://kubernetes.io/docs/concepts/overview/working-with-objects/annotations/ 
@@ -152,6 +162,7 @@ securityContext: {}
   # readOnlyRootFilesystem: true
   # runAsNonRoot: true
   # runAsUser: 1000
+[[- if .service ]]
 
 # This is for setting up a service more information can be found here: https://kubernetes.io/docs/concepts/services-networking/service/
 service:
@@ -159,6 +170,8 @@ service:
   type: ClusterIP
   # This sets the ports more information can be found here: https://kubernetes.io/docs/concepts/services-networking/service/#field-spec-ports
   port: 80
+[[- end ]]
+[[- if .ingress ]]
 
 # This block is for setting up the ingress for more information can be found here: https://kubernetes.io/docs/concepts/services-networking/ingress/
 ingress:
@@ -176,6 +189,7 @@ ingress:
   #  - secretName: chart-example-tls
   #    hosts:
   #      - chart-example.local
+[[- end ]]
 
 resources: {}
   # We usually recommend not to specify default resources and to leave this as a conscious
@@ -188,6 +202,7 @@ resources: {}
   # requests:
   #   cpu: 100m
   #   memory: 128Mi
+[[- if .service ]]
 
 # This is to setup the liveness and readiness probes more information can be found here: https://kubernetes.io/docs/tasks/configure-pod-container/configure-liveness-readiness-startup-probes/
 livenessProbe:
@@ -198,6 +213,8 @@ readinessProbe:
   httpGet:
     path: /
     port: http
+[[- end ]]
+[[- if .hpa ]]
 
 #This section is for setting up autoscaling more information can be found here: https://kubernetes.io/docs/concepts/workloads/autoscaling/
 autoscaling:
@@ -206,6 +223,7 @@ autoscaling:
   maxReplicas: 100
   targetCPUUtilizationPercentage: 80
   # targetMemoryUtilizationPercentage: 80
+[[- end ]]
 
 # Additional volumes on the output Deployment definition.
 volumes: []
@@ -219,6 +237,44 @@ volumeMounts: []
 # - name: foo
 #   mountPath: "/etc/foo"
 #   readOnly: true
+[[- if .statefulset ]]
+
+# Additional volumeClaimTemplates on the output StatefulSet definition, more information can be found here: https://kubernetes.io/docs/concepts/workloads/controllers/statefulset/#volume-claim-templates
+volumeClaimTemplates: []
+# - metadata:
+#     name: data
+#   spec:
+#     accessModes: ["ReadWriteOnce"]
+#     resources:
+#       requests:
+#         storage: 1Gi
+[[- end ]]
+[[- if .cronjob ]]
+
+# This sets up a CronJob running the image on a schedule, more information can be found here: https://kubernetes.io/docs/concepts/workloads/controllers/cron-jobs/
+cronJob:
+  schedule: "0 * * * *"
+  # The command run by the jobs instead of the entrypoint of the image.
+  command: []
+  # - /bin/sh
+  # - -c
+  # - date
+  concurrencyPolicy: Forbid
+  restartPolicy: OnFailure
+  successfulJobsHistoryLimit: 3
+  failedJobsHistoryLimit: 1
+[[- end ]]
+[[- if .networkpolicy ]]
+
+# This sets up a NetworkPolicy only allowing the traffic to the port of the service, more information can be found here: https://kubernetes.io/docs/concepts/services-networking/network-policies/
+networkPolicy:
+  enabled: true
+  # The sources allowed to reach the pods, all the sources if empty.
+  from: []
+  # - podSelector:
+  #     matchLabels:
+  #       role: frontend
+[[- end ]]
 
 nodeSelector: {}
 
@@ -297,16 +353,25 @@ spec:
 {{- end }}
 `
 
+// defaultDeployment is the scaffold of the Deployment, or of the StatefulSet
+// of the statefulset template set.
 const defaultDeployment = `apiVersion: apps/v1
-kind: Deployment
+kind: [[ if .statefulset ]]StatefulSet[[ else ]]Deployment[[ end ]]
 metadata:
   name: {{ include "<CHARTNAME>.fullname" . }}
   labels:
     {{- include "<CHARTNAME>.labels" . | nindent 4 }}
 spec:
+  [[- if .hpa ]]
   {{- if not .Values.autoscaling.enabled }}
+  [[- end ]]
   replicas: {{ .Values.replicaCount }}
+  [[- if .hpa ]]
   {{- end }}
+  [[- end ]]
+  [[- if .statefulset ]]
+  serviceName: {{ include "<CHARTNAME>.fullname" . }}
+  [[- end ]]
   selector:
     matchLabels:
       {{- include "<CHARTNAME>.selectorLabels" . | nindent 6 }}
@@ -326,7 +391,9 @@ spec:
       imagePullSecrets:
         {{- toYaml . | nindent 8 }}
       {{- end }}
+      [[- if .serviceaccount ]]
       serviceAccountName: {{ include "<CHARTNAME>.serviceAccountName" . }}
+      [[- end ]]
       securityContext:
         {{- toYaml .Values.podSecurityContext | nindent 8 }}
       containers:
@@ -335,6 +402,7 @@ spec:
             {{- toYaml .Values.securityContext | nindent 12 }}
           image: "{{ .Values.image.repository }}:{{ .Values.image.tag | default .Chart.AppVersion }}"
           imagePullPolicy: {{ .Values.image.pullPolicy }}
+          [[- if .service ]]
           ports:
             - name: http
               containerPort: {{ .Values.service.port }}
@@ -343,6 +411,7 @@ spec:
             {{- toYaml .Values.livenessProbe | nindent 12 }}
           readinessProbe:
             {{- toYaml .Values.readinessProbe | nindent 12 }}
+          [[- end ]]
           resources:
             {{- toYaml .Values.resources | nindent 12 }}
           {{- with .Values.volumeMounts }}
@@ -365,6 +434,12 @@ spec:
       tolerations:
         {{- toYaml . | nindent 8 }}
       {{- end }}
+  [[- if .statefulset ]]
+  {{- with .Values.volumeClaimTemplates }}
+  volumeClaimTemplates:
+    {{- toYaml . | nindent 4 }}
+  {{- end }}
+  [[- end ]]
 `
 
 const defaultService = `apiVersion: v1
@@ -409,7 +484,7 @@ metadata:
 spec:
   scaleTargetRef:
     apiVersion: apps/v1
-    kind: Deployment
+    kind: [[ if .statefulset ]]StatefulSet[[ else ]]Deployment[[ end ]]
     name: {{ include "<CHARTNAME>.fullname" . }}
   minReplicas: {{ .Values.autoscaling.minReplicas }}
   maxReplicas: {{ .Values.autoscaling.maxReplicas }}
@@ -433,7 +508,9 @@ spec:
 {{- end }}
 `
 
-const defaultNotes = `1. Get the application URL by running these commands:
+const defaultNotes = `[[ if .service -]]
+1. Get the application URL by running these commands:
+[[- if .ingress ]]
 {{- if .Values.ingress.enabled }}
 {{- range $host := .Values.ingress.hosts }}
   {{- range .paths }}
@@ -441,6 +518,9 @@ const defaultNotes = `1. Get the application URL by running these commands:
   {{- end }}
 {{- end }}
 {{- else if contains "NodePort" .Values.service.type }}
+[[- else ]]
+{{- if contains "NodePort" .Values.service.type }}
+[[- end ]]
   export NODE_PORT=$(kubectl get --namespace {{ .Release.Namespace }} -o jsonpath="{.spec.ports[0].nodePort}" services {{ include "<CHARTNAME>.fullname" . }})
   export NODE_IP=$(kubectl get nodes --namespace {{ .Release.Namespace }} -o jsonpath="{.items[0].status.addresses[0].address}")
   echo http://$NODE_IP:$NODE_PORT
@@ -455,6 +535,10 @@ const defaultNotes = `1. Get the application URL by running these commands:
   echo "Visit http://127.0.0.1:8080 to use your application"
   kubectl --namespace {{ .Release.Namespace }} port-forward $POD_NAME 8080:$CONTAINER_PORT
 {{- end }}
+[[- else -]]
+1. Get the status of the release by running these commands:
+  helm status {{ .Release.Name }} --namespace {{ .Release.Namespace }}
+[[- end ]]
 `
 
 const defaultHelpers = `{{/*
@@ -508,6 +592,7 @@ Selector labels
 app.kubernetes.io/name: {{ include "<CHARTNAME>.name" . }}
 app.kubernetes.io/instance: {{ .Release.Name }}
 {{- end }}
+[[- if .serviceaccount ]]
 
 {{/*
 Create the name of the service account to use
@@ -519,6 +604,7 @@ Create the name of the service account to use
 {{- default "default" .Values.serviceAccount.name }}
 {{- end }}
 {{- end }}
+[[- end ]]
 `
 
 const defaultTestConnection = `apiVersion: v1
@@ -538,6 +624,100 @@ spec:
   restartPolicy: Never
 `
 
+const defaultCronJob = `apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: {{ include "<CHARTNAME>.fullname" . }}
+  labels:
+    {{- include "<CHARTNAME>.labels" . | nindent 4 }}
+spec:
+  schedule: {{ .Values.cronJob.schedule | quote }}
+  concurrencyPolicy: {{ .Values.cronJob.concurrencyPolicy }}
+  successfulJobsHistoryLimit: {{ .Values.cronJob.successfulJobsHistoryLimit }}
+  failedJobsHistoryLimit: {{ .Values.cronJob.failedJobsHistoryLimit }}
+  jobTemplate:
+    spec:
+      template:
+        metadata:
+          {{- with .Values.podAnnotations }}
+          annotations:
+            {{- toYaml . | nindent 12 }}
+          {{- end }}
+          labels:
+            {{- include "<CHARTNAME>.labels" . | nindent 12 }}
+            app.kubernetes.io/component: cronjob
+            {{- with .Values.podLabels }}
+            {{- toYaml . | nindent 12 }}
+            {{- end }}
+        spec:
+          {{- with .Values.imagePullSecrets }}
+          imagePullSecrets:
+            {{- toYaml . | nindent 12 }}
+          {{- end }}
+          [[- if .serviceaccount ]]
+          serviceAccountName: {{ include "<CHARTNAME>.serviceAccountName" . }}
+          [[- end ]]
+          restartPolicy: {{ .Values.cronJob.restartPolicy }}
+          securityContext:
+            {{- toYaml .Values.podSecurityContext | nindent 12 }}
+          containers:
+            - name: {{ .Chart.Name }}
+              securityContext:
+                {{- toYaml .Values.securityContext | nindent 16 }}
+              image: "{{ .Values.image.repository }}:{{ .Values.image.tag | default .Chart.AppVersion }}"
+              imagePullPolicy: {{ .Values.image.pullPolicy }}
+              {{- with .Values.cronJob.command }}
+              command:
+                {{- toYaml . | nindent 16 }}
+              {{- end }}
+              resources:
+                {{- toYaml .Values.resources | nindent 16 }}
+              {{- with .Values.volumeMounts }}
+              volumeMounts:
+                {{- toYaml . | nindent 16 }}
+              {{- end }}
+          {{- with .Values.volumes }}
+          volumes:
+            {{- toYaml . | nindent 12 }}
+          {{- end }}
+          {{- with .Values.nodeSelector }}
+          nodeSelector:
+            {{- toYaml . | nindent 12 }}
+          {{- end }}
+          {{- with .Values.affinity }}
+          affinity:
+            {{- toYaml . | nindent 12 }}
+          {{- end }}
+          {{- with .Values.tolerations }}
+          tolerations:
+            {{- toYaml . | nindent 12 }}
+          {{- end }}
+`
+
+const defaultNetworkPolicy = `{{- if .Values.networkPolicy.enabled }}
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: {{ include "<CHARTNAME>.fullname" . }}
+  labels:
+    {{- include "<CHARTNAME>.labels" . | nindent 4 }}
+spec:
+  podSelector:
+    matchLabels:
+      {{- include "<CHARTNAME>.selectorLabels" . | nindent 6 }}
+  policyTypes:
+    - Ingress
+  ingress:
+    - ports:
+        - port: http
+          protocol: TCP
+      {{- with .Values.networkPolicy.from }}
+      from:
+        {{- toYaml . | nindent 8 }}
+      {{- end }}
+{{- end }}
+`
+
 // Stderr is an io.Writer to which error messages can be written
 //
 // In Helm 4, this will be replaced. It is needed in Helm 3 to preserve API backward
@@ -598,12 +778,23 @@ func CreateFrom(chartfile *chart.Metadata, dest, src string) error {
 // error. In such a case, this will attempt to clean up by removing the
 // new chart directory.
 func Create(name, dir string) (string, error) {
+	return CreateWithOptions(name, dir, CreateOptions{})
+}
+
+// CreateWithOptions creates a new chart in a directory like Create, with the
+// templates of the template sets selected by the options, and their values.
+func CreateWithOptions(name, dir string, opts CreateOptions) (string, error) {
 
 	// Sanity-check the name of a chart so user doesn't create one that causes problems.
 	if err := validateChartName(name); err != nil {
 		return "", err
 	}
 
+	sets, err := opts.templateSets()
+	if err != nil {
+		return "", err
+	}
+
 	path, err := filepath.Abs(dir)
 	if err != nil {
 		return path, err
@@ -620,73 +811,39 @@ func Create(name, dir string) (string, error) {
 		return cdir, errors.Errorf("file %s already exists and is not a directory", cdir)
 	}
 
-	files := []struct {
-		path    string
-		content []byte
-	}{
-		{
-			// Chart.yaml
-			path:    filepath.Join(cdir, ChartfileName),
-			content: []byte(fmt.Sprintf(defaultChartfile, name)),
-		},
-		{
-			// values.yaml
-			path:    filepath.Join(cdir, ValuesfileName),
-			content: []byte(fmt.Sprintf(defaultValues, name)),
-		},
-		{
-			// .helmignore
-			path:    filepath.Join(cdir, IgnorefileName),
-			content: []byte(defaultIgnore),
-		},
-		{
-			// ingress.yaml
-			path:    filepath.Join(cdir, IngressFileName),
-			content: transform(defaultIngress, name),
-		},
-		{
-			// deployment.yaml
-			path:    filepath.Join(cdir, DeploymentName),
-			content: transform(defaultDeployment, name),
-		},
-		{
-			// service.yaml
-			path:    filepath.Join(cdir, ServiceName),
-			content: transform(defaultService, name),
-		},
-		{
-			// serviceaccount.yaml
-			path:    filepath.Join(cdir, ServiceAccountName),
-			content: transform(defaultServiceAccount, name),
-		},
-		{
-			// hpa.yaml
-			path:    filepath.Join(cdir, HorizontalPodAutoscalerName),
-			content: transform(defaultHorizontalPodAutoscaler, name),
-		},
-		{
-			// NOTES.txt
-			path:    filepath.Join(cdir, NotesName),
-			content: transform(defaultNotes, name),
-		},
-		{
-			// _helpers.tpl
-			path:    filepath.Join(cdir, HelpersName),
-			content: transform(defaultHelpers, name),
-		},
-		{
-			// test-connection.yaml
-			path:    filepath.Join(cdir, TestConnectionName),
-			content: transform(defaultTestConnection, name),
-		},
+	scaffolds := []scaffoldFile{
+		{ChartfileName, defaultChartfile},
+		{ValuesfileName, defaultValues},
+		{IgnorefileName, defaultIgnore},
 	}
+	for _, s := range templateSets {
+		if sets[s.Name] {
+			scaffolds = append(scaffolds, s.files...)
+		}
+	}
+	scaffolds = append(scaffolds, scaffoldFile{NotesName, defaultNotes}, scaffoldFile{HelpersName, defaultHelpers})
+
+	for _, f := range scaffolds {
+		scaffold, err := renderScaffold(f.path, f.scaffold, sets)
+		if err != nil {
+			return cdir, err
+		}
+		var content []byte
+		switch f.path {
+		case ChartfileName, ValuesfileName:
+			content = []byte(fmt.Sprintf(scaffold, name))
+		case IgnorefileName:
+			content = []byte(scaffold)
+		default:
+			content = transform(scaffold, name)
+		}
 
-	for _, file := range files {
-		if _, err := os.Stat(file.path); err == nil {
+		path := filepath.Join(cdir, f.path)
+		if _, err := os.Stat(path); err == nil {
 			// There is no handle to a preferred output stream here.
-			fmt.Fprintf(Stderr, "WARNING: File %q already exists. Overwriting.\n", file.path)
+			fmt.Fprintf(Stderr, "WARNING: File %q already exists. Overwriting.\n", path)
 		}
-		if err := writeFile(file.path, file.content); err != nil {
+		if err := writeFile(path, content); err != nil {
 			return cdir, err
 		}
 	}
@@ -0,0 +1,184 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartutil
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// TemplateSet is a set of templates scaffolded by CreateWithOptions, like the
+// Deployment of a chart, with its values.
+type TemplateSet struct {
+	// Name is the name of the template set, like "deployment".
+	Name string
+	// Description describes the templates of the set.
+	Description string
+	// Default is true for the template sets scaffolded by default.
+	Default bool
+
+	// files are the files of the set, with their scaffolds
+	files []scaffoldFile
+	// requires are the template sets one of which the set requires
+	requires []string
+}
+
+// scaffoldFile is a file of a template set.
+type scaffoldFile struct {
+	path     string
+	scaffold string
+}
+
+var templateSets = []TemplateSet{
+	{
+		Name:        "deployment",
+		Description: "a Deployment running the image of the chart",
+		Default:     true,
+		files:       []scaffoldFile{{DeploymentName, defaultDeployment}},
+	},
+	{
+		Name:        "statefulset",
+		Description: "a StatefulSet running the image of the chart, replacing the Deployment",
+		files:       []scaffoldFile{{StatefulSetName, defaultDeployment}},
+	},
+	{
+		Name:        "service",
+		Description: "a Service exposing the pods",
+		Default:     true,
+		files:       []scaffoldFile{{ServiceName, defaultService}},
+	},
+	{
+		Name:        "serviceaccount",
+		Description: "a ServiceAccount the pods run as",
+		Default:     true,
+		files:       []scaffoldFile{{ServiceAccountName, defaultServiceAccount}},
+	},
+	{
+		Name:        "ingress",
+		Description: "an Ingress routing to the Service",
+		Default:     true,
+		files:       []scaffoldFile{{IngressFileName, defaultIngress}},
+		requires:    []string{"service"},
+	},
+	{
+		Name:        "hpa",
+		Description: "a HorizontalPodAutoscaler scaling the Deployment or the StatefulSet",
+		Default:     true,
+		files:       []scaffoldFile{{HorizontalPodAutoscalerName, defaultHorizontalPodAutoscaler}},
+		requires:    []string{"deployment", "statefulset"},
+	},
+	{
+		Name:        "tests",
+		Description: "a test connecting to the Service",
+		Default:     true,
+		files:       []scaffoldFile{{TestConnectionName, defaultTestConnection}},
+		requires:    []string{"service"},
+	},
+	{
+		Name:        "cronjob",
+		Description: "a CronJob running the image of the chart on a schedule",
+		files:       []scaffoldFile{{CronJobName, defaultCronJob}},
+	},
+	{
+		Name:        "networkpolicy",
+		Description: "a NetworkPolicy only allowing the traffic to the port of the Service",
+		files:       []scaffoldFile{{NetworkPolicyName, defaultNetworkPolicy}},
+		requires:    []string{"service"},
+	},
+}
+
+// TemplateSets returns the template sets of CreateWithOptions.
+func TemplateSets() []TemplateSet {
+	return append([]TemplateSet{}, templateSets...)
+}
+
+// CreateOptions are the options of CreateWithOptions.
+type CreateOptions struct {
+	// With are the names of the template sets scaffolded in addition to the
+	// default ones. The statefulset template set replaces the deployment one,
+	// unless both are given.
+	With []string
+	// Without are the names of the default template sets not scaffolded.
+	Without []string
+}
+
+// templateSets returns the template sets selected by the options, by name.
+func (o CreateOptions) templateSets() (map[string]bool, error) {
+	known := make(map[string]bool, len(templateSets))
+	selected := make(map[string]bool)
+	for _, s := range templateSets {
+		known[s.Name] = true
+		selected[s.Name] = s.Default
+	}
+
+	with := make(map[string]bool, len(o.With))
+	for _, name := range o.With {
+		if !known[name] {
+			return nil, errors.Errorf("unknown template set %q", name)
+		}
+		with[name] = true
+		selected[name] = true
+	}
+	if with["statefulset"] {
+		if with["deployment"] {
+			return nil, errors.New("the deployment and statefulset template sets cannot be combined")
+		}
+		selected["deployment"] = false
+	}
+	for _, name := range o.Without {
+		if !known[name] {
+			return nil, errors.Errorf("unknown template set %q", name)
+		}
+		if with[name] {
+			return nil, errors.Errorf("the template set %q is both added and removed", name)
+		}
+		selected[name] = false
+	}
+
+	for _, s := range templateSets {
+		if !selected[s.Name] || len(s.requires) == 0 {
+			continue
+		}
+		found := false
+		for _, name := range s.requires {
+			found = found || selected[name]
+		}
+		if !found {
+			return nil, errors.Errorf("the %s template set requires the %s template set", s.Name, strings.Join(s.requires, " or "))
+		}
+	}
+	return selected, nil
+}
+
+// renderScaffold renders the scaffold of a file for the selected template
+// sets. The scaffolds are Go templates delimited by "[[" and "]]", so as not
+// to conflict with the templates of the charts, whose data are the selected
+// template sets by name, like "[[ if .ingress ]]".
+func renderScaffold(name, scaffold string, sets map[string]bool) (string, error) {
+	t, err := template.New(name).Delims("[[", "]]").Option("missingkey=zero").Parse(scaffold)
+	if err != nil {
+		return "", errors.Wrapf(err, "parsing the scaffold of %s", name)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, sets); err != nil {
+		return "", errors.Wrapf(err, "rendering the scaffold of %s", name)
+	}
+	return buf.String(), nil
+}
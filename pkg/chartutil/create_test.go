@@ -64,6 +64,104 @@ func TestCreate(t *testing.T) {
 	}
 }
 
+func TestCreateWithOptions(t *testing.T) {
+	tdir := t.TempDir()
+
+	opts := CreateOptions{
+		With:    []string{"statefulset", "cronjob", "networkpolicy"},
+		Without: []string{"ingress", "serviceaccount"},
+	}
+	c, err := CreateWithOptions("foo", tdir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mychart, err := loader.LoadDir(c)
+	if err != nil {
+		t.Fatalf("Failed to load newly created chart %q: %s", c, err)
+	}
+
+	for _, f := range []string{
+		StatefulSetName,
+		CronJobName,
+		NetworkPolicyName,
+		ServiceName,
+		HorizontalPodAutoscalerName,
+		TestConnectionName,
+	} {
+		if _, err := os.Stat(filepath.Join(c, f)); err != nil {
+			t.Errorf("Expected %s file: %s", f, err)
+		}
+	}
+	for _, f := range []string{DeploymentName, IngressFileName, ServiceAccountName} {
+		if _, err := os.Stat(filepath.Join(c, f)); !os.IsNotExist(err) {
+			t.Errorf("Expected no %s file, got %v", f, err)
+		}
+	}
+
+	for _, key := range []string{"volumeClaimTemplates", "cronJob", "networkPolicy", "service", "autoscaling"} {
+		if _, ok := mychart.Values[key]; !ok {
+			t.Errorf("Expected the %s values", key)
+		}
+	}
+	for _, key := range []string{"ingress", "serviceAccount"} {
+		if _, ok := mychart.Values[key]; ok {
+			t.Errorf("Expected no %s values", key)
+		}
+	}
+
+	hpa, err := os.ReadFile(filepath.Join(c, HorizontalPodAutoscalerName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(hpa, []byte("kind: StatefulSet")) {
+		t.Errorf("Expected the HorizontalPodAutoscaler to scale the StatefulSet, got:\n%s", hpa)
+	}
+}
+
+func TestCreateWithOptions_Errors(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		opts CreateOptions
+		err  string
+	}{{
+		name: "unknown template set",
+		opts: CreateOptions{With: []string{"daemonset"}},
+		err:  `unknown template set "daemonset"`,
+	}, {
+		name: "unknown removed template set",
+		opts: CreateOptions{Without: []string{"daemonset"}},
+		err:  `unknown template set "daemonset"`,
+	}, {
+		name: "both workloads",
+		opts: CreateOptions{With: []string{"deployment", "statefulset"}},
+		err:  "the deployment and statefulset template sets cannot be combined",
+	}, {
+		name: "added and removed",
+		opts: CreateOptions{With: []string{"cronjob"}, Without: []string{"cronjob"}},
+		err:  `the template set "cronjob" is both added and removed`,
+	}, {
+		name: "missing requirement",
+		opts: CreateOptions{Without: []string{"service"}},
+		err:  "the ingress template set requires the service template set",
+	}, {
+		name: "missing workload",
+		opts: CreateOptions{Without: []string{"deployment"}},
+		err:  "the hpa template set requires the deployment or statefulset template set",
+	}} {
+		t.Run(tt.name, func(t *testing.T) {
+			tdir := t.TempDir()
+			_, err := CreateWithOptions("foo", tdir, tt.opts)
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("Expected error %q, got %v", tt.err, err)
+			}
+			if _, err := os.Stat(filepath.Join(tdir, "foo")); !os.IsNotExist(err) {
+				t.Errorf("Expected no chart directory, got %v", err)
+			}
+		})
+	}
+}
+
 func TestCreateFrom(t *testing.T) {
 	tdir := t.TempDir()
 
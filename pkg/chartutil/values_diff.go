@@ -0,0 +1,114 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartutil
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ValueChangeType is the type of the change of a value.
+type ValueChangeType string
+
+const (
+	// ValueAdded is the type of the values set in the new values only.
+	ValueAdded ValueChangeType = "added"
+	// ValueRemoved is the type of the values set in the old values only.
+	ValueRemoved ValueChangeType = "removed"
+	// ValueChanged is the type of the values set in both values, differently.
+	ValueChanged ValueChangeType = "changed"
+)
+
+// ValueChange is a change of a value between two sets of values.
+type ValueChange struct {
+	// Path is the path of the value, like "image.tag". The keys containing
+	// dots or brackets are quoted, like `podAnnotations["example.com/team"]`.
+	Path string          `json:"path"`
+	Type ValueChangeType `json:"type"`
+	// From is the old value, nil if it was added.
+	From interface{} `json:"from,omitempty"`
+	// To is the new value, nil if it was removed.
+	To interface{} `json:"to,omitempty"`
+}
+
+// DiffValues returns the changes of the values from the values from to the
+// values to, sorted by path.
+//
+// The tables are compared key by key, and the other values as a whole. In
+// particular, the lists are compared as a whole, as Helm replaces them
+// rather than merging them.
+func DiffValues(from, to map[string]interface{}) []ValueChange {
+	return diffTables("", from, to)
+}
+
+func diffTables(path string, from, to map[string]interface{}) []ValueChange {
+	keys := make([]string, 0, len(from)+len(to))
+	for k := range from {
+		keys = append(keys, k)
+	}
+	for k := range to {
+		if _, ok := from[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var changes []ValueChange
+	for _, k := range keys {
+		p := joinValuePath(path, k)
+		fromValue, inFrom := from[k]
+		toValue, inTo := to[k]
+		switch {
+		case !inFrom:
+			changes = append(changes, ValueChange{Path: p, Type: ValueAdded, To: toValue})
+		case !inTo:
+			changes = append(changes, ValueChange{Path: p, Type: ValueRemoved, From: fromValue})
+		default:
+			fromTable, fromIsTable := asTable(fromValue)
+			toTable, toIsTable := asTable(toValue)
+			if fromIsTable && toIsTable {
+				changes = append(changes, diffTables(p, fromTable, toTable)...)
+			} else if !reflect.DeepEqual(fromValue, toValue) {
+				changes = append(changes, ValueChange{Path: p, Type: ValueChanged, From: fromValue, To: toValue})
+			}
+		}
+	}
+	return changes
+}
+
+// asTable returns the value as a table, if it is one.
+func asTable(v interface{}) (map[string]interface{}, bool) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		return t, true
+	case Values:
+		return t, true
+	}
+	return nil, false
+}
+
+func joinValuePath(path, key string) string {
+	if strings.ContainsAny(key, ".[]") {
+		return fmt.Sprintf("%s[%q]", path, key)
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
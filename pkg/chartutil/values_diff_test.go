@@ -0,0 +1,68 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package chartutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffValues(t *testing.T) {
+	from := map[string]interface{}{
+		"replicas": 1,
+		"debug":    true,
+		"image": map[string]interface{}{
+			"repository": "nginx",
+			"tag":        "1.25",
+		},
+		"podAnnotations": map[string]interface{}{
+			"example.com/team": "web",
+		},
+		"hosts":   []interface{}{"a.example.com"},
+		"service": nil,
+	}
+	to := map[string]interface{}{
+		"replicas": 3,
+		"image": Values{
+			"repository": "nginx",
+			"tag":        "1.27",
+		},
+		"podAnnotations": map[string]interface{}{
+			"example.com/team": "api",
+		},
+		"hosts":   []interface{}{"a.example.com", "b.example.com"},
+		"service": map[string]interface{}{"port": 80},
+		"extra":   "value",
+	}
+
+	expected := []ValueChange{
+		{Path: "debug", Type: ValueRemoved, From: true},
+		{Path: "extra", Type: ValueAdded, To: "value"},
+		{Path: "hosts", Type: ValueChanged, From: []interface{}{"a.example.com"}, To: []interface{}{"a.example.com", "b.example.com"}},
+		{Path: "image.tag", Type: ValueChanged, From: "1.25", To: "1.27"},
+		{Path: `podAnnotations["example.com/team"]`, Type: ValueChanged, From: "web", To: "api"},
+		{Path: "replicas", Type: ValueChanged, From: 1, To: 3},
+		{Path: "service", Type: ValueChanged, From: nil, To: map[string]interface{}{"port": 80}},
+	}
+	if changes := DiffValues(from, to); !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Expected changes\n%#v\ngot\n%#v", expected, changes)
+	}
+
+	if changes := DiffValues(from, from); len(changes) != 0 {
+		t.Errorf("Expected no changes between the same values, got %#v", changes)
+	}
+}
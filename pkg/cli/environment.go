@@ -24,13 +24,17 @@ These dependencies are expressed as interfaces so that alternate implementations
 package cli
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/rest"
 
@@ -48,46 +52,62 @@ const defaultBurstLimit = 100
 const defaultQPS = float32(0)
 
 // EnvSettings describes all of the environment settings.
+//
+// EnvSettings is serialized to JSON and YAML with the namespace, so that the
+// tools wrapping Helm may read the settings in use without parsing the output
+// of 'helm env'.
 type EnvSettings struct {
 	namespace string
 	config    *genericclioptions.ConfigFlags
 
 	// KubeConfig is the path to the kubeconfig file
-	KubeConfig string
+	KubeConfig string `json:"kubeConfig,omitempty"`
 	// KubeContext is the name of the kubeconfig context.
-	KubeContext string
+	KubeContext string `json:"kubeContext,omitempty"`
 	// Bearer KubeToken used for authentication
-	KubeToken string
+	KubeToken string `json:"kubeToken,omitempty"`
 	// Username to impersonate for the operation
-	KubeAsUser string
+	KubeAsUser string `json:"kubeAsUser,omitempty"`
 	// Groups to impersonate for the operation, multiple groups parsed from a comma delimited list
-	KubeAsGroups []string
+	KubeAsGroups []string `json:"kubeAsGroups,omitempty"`
 	// Kubernetes API Server Endpoint for authentication
-	KubeAPIServer string
+	KubeAPIServer string `json:"kubeAPIServer,omitempty"`
 	// Custom certificate authority file.
-	KubeCaFile string
+	KubeCaFile string `json:"kubeCaFile,omitempty"`
 	// KubeInsecureSkipTLSVerify indicates if server's certificate will not be checked for validity.
 	// This makes the HTTPS connections insecure
-	KubeInsecureSkipTLSVerify bool
+	KubeInsecureSkipTLSVerify bool `json:"kubeInsecureSkipTLSVerify"`
 	// KubeTLSServerName overrides the name to use for server certificate validation.
 	// If it is not provided, the hostname used to contact the server is used
-	KubeTLSServerName string
+	KubeTLSServerName string `json:"kubeTLSServerName,omitempty"`
 	// Debug indicates whether or not Helm is running in Debug mode.
-	Debug bool
+	Debug bool `json:"debug"`
 	// RegistryConfig is the path to the registry config file.
-	RegistryConfig string
+	RegistryConfig string `json:"registryConfig"`
+	// RegistriesConfig is the path to the file configuring the mirrors of
+	// registries.
+	RegistriesConfig string `json:"registriesConfig"`
 	// RepositoryConfig is the path to the repositories file.
-	RepositoryConfig string
+	RepositoryConfig string `json:"repositoryConfig"`
 	// RepositoryCache is the path to the repository cache directory.
-	RepositoryCache string
+	RepositoryCache string `json:"repositoryCache"`
+	// SignaturePolicy is the path to the policy for verifying the sigstore
+	// signatures of charts.
+	SignaturePolicy string `json:"signaturePolicy"`
 	// PluginsDirectory is the path to the plugins directory.
-	PluginsDirectory string
+	PluginsDirectory string `json:"pluginsDirectory"`
+	// PluginVerifyPolicy is the policy of the plugins installed without a
+	// trusted signature: "warn" or "deny".
+	PluginVerifyPolicy string `json:"pluginVerifyPolicy"`
+	// PluginKeyring is the path to the keyring of the GPG keys trusted to sign
+	// plugins.
+	PluginKeyring string `json:"pluginKeyring,omitempty"`
 	// MaxHistory is the max release history maintained.
-	MaxHistory int
+	MaxHistory int `json:"maxHistory"`
 	// BurstLimit is the default client-side throttling limit.
-	BurstLimit int
+	BurstLimit int `json:"burstLimit"`
 	// QPS is queries per second which may be used to avoid throttling.
-	QPS float32
+	QPS float32 `json:"qps"`
 }
 
 func New() *EnvSettings {
@@ -103,29 +123,41 @@ func New() *EnvSettings {
 		KubeTLSServerName:         os.Getenv("HELM_KUBETLS_SERVER_NAME"),
 		KubeInsecureSkipTLSVerify: envBoolOr("HELM_KUBEINSECURE_SKIP_TLS_VERIFY", false),
 		PluginsDirectory:          envOr("HELM_PLUGINS", helmpath.DataPath("plugins")),
+		PluginVerifyPolicy:        envOr("HELM_PLUGIN_VERIFY_POLICY", "warn"),
+		PluginKeyring:             os.Getenv("HELM_PLUGIN_KEYRING"),
 		RegistryConfig:            envOr("HELM_REGISTRY_CONFIG", helmpath.ConfigPath("registry/config.json")),
+		RegistriesConfig:          envOr("HELM_REGISTRIES_CONFIG", helmpath.ConfigPath("registry/registries.yaml")),
 		RepositoryConfig:          envOr("HELM_REPOSITORY_CONFIG", helmpath.ConfigPath("repositories.yaml")),
 		RepositoryCache:           envOr("HELM_REPOSITORY_CACHE", helmpath.CachePath("repository")),
+		SignaturePolicy:           envOr("HELM_SIGNATURE_POLICY", helmpath.ConfigPath("signature-policy.yaml")),
 		BurstLimit:                envIntOr("HELM_BURST_LIMIT", defaultBurstLimit),
 		QPS:                       envFloat32Or("HELM_QPS", defaultQPS),
 	}
 	env.Debug, _ = strconv.ParseBool(os.Getenv("HELM_DEBUG"))
 
 	// bind to kubernetes config flags
+	env.config = env.newConfigFlags(&env.KubeContext)
+
+	return env
+}
+
+// newConfigFlags returns the kubernetes config flags bound to the settings,
+// using the given kubeconfig context.
+func (s *EnvSettings) newConfigFlags(kubeContext *string) *genericclioptions.ConfigFlags {
 	config := &genericclioptions.ConfigFlags{
-		Namespace:        &env.namespace,
-		Context:          &env.KubeContext,
-		BearerToken:      &env.KubeToken,
-		APIServer:        &env.KubeAPIServer,
-		CAFile:           &env.KubeCaFile,
-		KubeConfig:       &env.KubeConfig,
-		Impersonate:      &env.KubeAsUser,
-		Insecure:         &env.KubeInsecureSkipTLSVerify,
-		TLSServerName:    &env.KubeTLSServerName,
-		ImpersonateGroup: &env.KubeAsGroups,
+		Namespace:        &s.namespace,
+		Context:          kubeContext,
+		BearerToken:      &s.KubeToken,
+		APIServer:        &s.KubeAPIServer,
+		CAFile:           &s.KubeCaFile,
+		KubeConfig:       &s.KubeConfig,
+		Impersonate:      &s.KubeAsUser,
+		Insecure:         &s.KubeInsecureSkipTLSVerify,
+		TLSServerName:    &s.KubeTLSServerName,
+		ImpersonateGroup: &s.KubeAsGroups,
 		WrapConfigFn: func(config *rest.Config) *rest.Config {
-			config.Burst = env.BurstLimit
-			config.QPS = env.QPS
+			config.Burst = s.BurstLimit
+			config.QPS = s.QPS
 			config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
 				return &retryingRoundTripper{wrapped: rt}
 			})
@@ -133,12 +165,10 @@ func New() *EnvSettings {
 			return config
 		},
 	}
-	if env.BurstLimit != defaultBurstLimit {
-		config = config.WithDiscoveryBurst(env.BurstLimit)
+	if s.BurstLimit != defaultBurstLimit {
+		config = config.WithDiscoveryBurst(s.BurstLimit)
 	}
-	env.config = config
-
-	return env
+	return config
 }
 
 // AddFlags binds flags to the given flagset.
@@ -155,8 +185,10 @@ func (s *EnvSettings) AddFlags(fs *pflag.FlagSet) {
 	fs.BoolVar(&s.KubeInsecureSkipTLSVerify, "kube-insecure-skip-tls-verify", s.KubeInsecureSkipTLSVerify, "if true, the Kubernetes API server's certificate will not be checked for validity. This will make your HTTPS connections insecure")
 	fs.BoolVar(&s.Debug, "debug", s.Debug, "enable verbose output")
 	fs.StringVar(&s.RegistryConfig, "registry-config", s.RegistryConfig, "path to the registry config file")
+	fs.StringVar(&s.RegistriesConfig, "registries-config", s.RegistriesConfig, "path to the file configuring the mirrors of registries")
 	fs.StringVar(&s.RepositoryConfig, "repository-config", s.RepositoryConfig, "path to the file containing repository names and URLs")
 	fs.StringVar(&s.RepositoryCache, "repository-cache", s.RepositoryCache, "path to the directory containing cached repository indexes")
+	fs.StringVar(&s.SignaturePolicy, "signature-policy", s.SignaturePolicy, "path to the policy for verifying the signatures of charts")
 	fs.IntVar(&s.BurstLimit, "burst-limit", s.BurstLimit, "client-side default throttling limit")
 	fs.Float32Var(&s.QPS, "qps", s.QPS, "queries per second used when communicating with the Kubernetes API, not including bursting")
 }
@@ -214,19 +246,23 @@ func envCSV(name string) (ls []string) {
 
 func (s *EnvSettings) EnvVars() map[string]string {
 	envvars := map[string]string{
-		"HELM_BIN":               os.Args[0],
-		"HELM_CACHE_HOME":        helmpath.CachePath(""),
-		"HELM_CONFIG_HOME":       helmpath.ConfigPath(""),
-		"HELM_DATA_HOME":         helmpath.DataPath(""),
-		"HELM_DEBUG":             fmt.Sprint(s.Debug),
-		"HELM_PLUGINS":           s.PluginsDirectory,
-		"HELM_REGISTRY_CONFIG":   s.RegistryConfig,
-		"HELM_REPOSITORY_CACHE":  s.RepositoryCache,
-		"HELM_REPOSITORY_CONFIG": s.RepositoryConfig,
-		"HELM_NAMESPACE":         s.Namespace(),
-		"HELM_MAX_HISTORY":       strconv.Itoa(s.MaxHistory),
-		"HELM_BURST_LIMIT":       strconv.Itoa(s.BurstLimit),
-		"HELM_QPS":               strconv.FormatFloat(float64(s.QPS), 'f', 2, 32),
+		"HELM_BIN":                  os.Args[0],
+		"HELM_CACHE_HOME":           helmpath.CachePath(""),
+		"HELM_CONFIG_HOME":          helmpath.ConfigPath(""),
+		"HELM_DATA_HOME":            helmpath.DataPath(""),
+		"HELM_DEBUG":                fmt.Sprint(s.Debug),
+		"HELM_PLUGINS":              s.PluginsDirectory,
+		"HELM_PLUGIN_VERIFY_POLICY": s.PluginVerifyPolicy,
+		"HELM_PLUGIN_KEYRING":       s.PluginKeyring,
+		"HELM_REGISTRY_CONFIG":      s.RegistryConfig,
+		"HELM_REGISTRIES_CONFIG":    s.RegistriesConfig,
+		"HELM_REPOSITORY_CACHE":     s.RepositoryCache,
+		"HELM_REPOSITORY_CONFIG":    s.RepositoryConfig,
+		"HELM_SIGNATURE_POLICY":     s.SignaturePolicy,
+		"HELM_NAMESPACE":            s.Namespace(),
+		"HELM_MAX_HISTORY":          strconv.Itoa(s.MaxHistory),
+		"HELM_BURST_LIMIT":          strconv.Itoa(s.BurstLimit),
+		"HELM_QPS":                  strconv.FormatFloat(float64(s.QPS), 'f', 2, 32),
 
 		// broken, these are populated from helm flags and not kubeconfig.
 		"HELM_KUBECONTEXT":                  s.KubeContext,
@@ -260,7 +296,95 @@ func (s *EnvSettings) SetNamespace(namespace string) {
 	s.namespace = namespace
 }
 
+// envSettings is EnvSettings without its methods, so that it is serialized
+// with the default encoding.
+type envSettings EnvSettings
+
+// MarshalJSON encodes the settings with their namespace.
+func (s *EnvSettings) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Namespace string `json:"namespace"`
+		envSettings
+	}{s.Namespace(), envSettings(*s)})
+}
+
+// UnmarshalJSON decodes the settings, keeping the settings which are not
+// given.
+func (s *EnvSettings) UnmarshalJSON(data []byte) error {
+	settings := struct {
+		Namespace *string `json:"namespace"`
+		envSettings
+	}{envSettings: envSettings(*s)}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return err
+	}
+	*s = EnvSettings(settings.envSettings)
+	if settings.Namespace != nil {
+		s.namespace = *settings.Namespace
+	}
+	return nil
+}
+
+// Validate checks that the settings are valid: the numbers are in range, the
+// policies are known and the files and the kubeconfig context given exist.
+func (s *EnvSettings) Validate() error {
+	var errs []error
+	if s.MaxHistory < 0 {
+		errs = append(errs, errors.Errorf("invalid max history %d: must not be negative", s.MaxHistory))
+	}
+	if s.BurstLimit < 0 {
+		errs = append(errs, errors.Errorf("invalid burst limit %d: must not be negative", s.BurstLimit))
+	}
+	if s.QPS < 0 {
+		errs = append(errs, errors.Errorf("invalid QPS %v: must not be negative", s.QPS))
+	}
+	switch s.PluginVerifyPolicy {
+	case "", "warn", "deny":
+	default:
+		errs = append(errs, errors.Errorf("invalid plugin verification policy %q: must be warn or deny", s.PluginVerifyPolicy))
+	}
+	if s.KubeCaFile != "" {
+		if _, err := os.Stat(s.KubeCaFile); err != nil {
+			errs = append(errs, errors.Wrap(err, "invalid certificate authority file"))
+		}
+	}
+	if s.KubeConfig != "" {
+		if _, err := os.Stat(s.KubeConfig); err != nil {
+			return utilerrors.NewAggregate(append(errs, errors.Wrap(err, "invalid kubeconfig file")))
+		}
+	}
+	if s.KubeContext != "" {
+		config, err := s.config.ToRawKubeConfigLoader().RawConfig()
+		if err != nil {
+			errs = append(errs, errors.Wrap(err, "unable to load the kubeconfig"))
+		} else if _, ok := config.Contexts[s.KubeContext]; !ok {
+			errs = append(errs, errors.Errorf("kubeconfig context %q does not exist", s.KubeContext))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
 // RESTClientGetter gets the kubeconfig from EnvSettings
 func (s *EnvSettings) RESTClientGetter() genericclioptions.RESTClientGetter {
 	return s.config
 }
+
+// RESTClientGetterForContext gets the kubeconfig from EnvSettings, using the
+// given kubeconfig context instead of KubeContext.
+func (s *EnvSettings) RESTClientGetterForContext(kubeContext string) genericclioptions.RESTClientGetter {
+	return s.newConfigFlags(&kubeContext)
+}
+
+// KubeContexts returns the sorted names of the contexts of the kubeconfig.
+func (s *EnvSettings) KubeContexts() ([]string, error) {
+	config, err := s.config.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return nil, err
+	}
+	contexts := make([]string, 0, len(config.Contexts))
+	for name := range config.Contexts {
+		contexts = append(contexts, name)
+	}
+	sort.Strings(contexts)
+	return contexts, nil
+}
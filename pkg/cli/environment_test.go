@@ -17,7 +17,9 @@ limitations under the License.
 package cli
 
 import (
+	"encoding/json"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -261,6 +263,137 @@ func TestUserAgentHeaderInK8sRESTClientConfig(t *testing.T) {
 	}
 }
 
+func TestKubeContexts(t *testing.T) {
+	defer resetEnv()()
+
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	data := `apiVersion: v1
+kind: Config
+clusters:
+- name: prod
+  cluster:
+    server: https://prod.example.com
+- name: staging
+  cluster:
+    server: https://staging.example.com
+contexts:
+- name: staging
+  context:
+    cluster: staging
+- name: prod
+  context:
+    cluster: prod
+    namespace: web
+current-context: staging
+`
+	if err := os.WriteFile(kubeconfig, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KUBECONFIG", kubeconfig)
+
+	settings := New()
+	contexts, err := settings.KubeContexts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(contexts, []string{"prod", "staging"}) {
+		t.Errorf("expected contexts [prod staging], got %v", contexts)
+	}
+
+	getter := settings.RESTClientGetterForContext("prod")
+	restConfig, err := getter.ToRESTConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restConfig.Host != "https://prod.example.com" {
+		t.Errorf("expected the host of the prod context, got %q", restConfig.Host)
+	}
+	if ns, _, _ := getter.ToRawKubeConfigLoader().Namespace(); ns != "web" {
+		t.Errorf("expected the namespace of the prod context, got %q", ns)
+	}
+	if settings.KubeContext != "" {
+		t.Errorf("expected the kube context to be unchanged, got %q", settings.KubeContext)
+	}
+}
+
+func TestEnvSettingsJSON(t *testing.T) {
+	defer resetEnv()()
+
+	settings := New()
+	settings.SetNamespace("web")
+	settings.KubeAsGroups = []string{"admins", "developers"}
+	settings.MaxHistory = 5
+	data, err := json.Marshal(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{`"namespace":"web"`, `"kubeAsGroups":["admins","developers"]`, `"maxHistory":5`} {
+		if !strings.Contains(string(data), expect) {
+			t.Errorf("expected %s in %s", expect, data)
+		}
+	}
+
+	decoded := New()
+	if err := json.Unmarshal(data, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Namespace() != "web" || decoded.MaxHistory != 5 || !reflect.DeepEqual(decoded.KubeAsGroups, settings.KubeAsGroups) {
+		t.Errorf("expected the decoded settings to match, got %+v", decoded)
+	}
+
+	// The settings which are not given are kept
+	if err := json.Unmarshal([]byte(`{"debug":true}`), decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Debug || decoded.MaxHistory != 5 || decoded.Namespace() != "web" {
+		t.Errorf("expected the other settings to be kept, got %+v", decoded)
+	}
+}
+
+func TestEnvSettingsValidate(t *testing.T) {
+	defer resetEnv()()
+
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	data := `apiVersion: v1
+kind: Config
+clusters:
+- name: prod
+  cluster:
+    server: https://prod.example.com
+contexts:
+- name: prod
+  context:
+    cluster: prod
+`
+	if err := os.WriteFile(kubeconfig, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KUBECONFIG", kubeconfig)
+
+	settings := New()
+	if err := settings.Validate(); err != nil {
+		t.Errorf("expected the default settings to be valid, got %s", err)
+	}
+	settings.KubeContext = "prod"
+	if err := settings.Validate(); err != nil {
+		t.Errorf("expected the context to exist, got %s", err)
+	}
+
+	settings.KubeContext = "staging"
+	settings.MaxHistory = -1
+	settings.PluginVerifyPolicy = "allow"
+	settings.KubeCaFile = filepath.Join(t.TempDir(), "missing.crt")
+	err := settings.Validate()
+	if err == nil {
+		t.Fatal("expected the settings to be invalid")
+	}
+	for _, expect := range []string{"max history", "plugin verification policy", "certificate authority", `context "staging"`} {
+		if !strings.Contains(err.Error(), expect) {
+			t.Errorf("expected %q in the error, got %s", expect, err)
+		}
+	}
+}
+
 func resetEnv() func() {
 	origEnv := os.Environ()
 
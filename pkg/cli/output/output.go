@@ -17,9 +17,12 @@ limitations under the License.
 package output
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
+	"strings"
+	"text/template"
 
 	"github.com/gosuri/uitable"
 	"github.com/pkg/errors"
@@ -35,6 +38,10 @@ const (
 	YAML  Format = "yaml"
 )
 
+// goTemplatePrefix is the prefix of the formats executing a Go template on
+// the JSON output, like "go-template={{range .}}{{.name}}{{end}}".
+const goTemplatePrefix = "go-template="
+
 // Formats returns a list of the string representation of the supported formats
 func Formats() []string {
 	return []string{Table.String(), JSON.String(), YAML.String()}
@@ -44,9 +51,10 @@ func Formats() []string {
 // including a description
 func FormatsWithDesc() map[string]string {
 	return map[string]string{
-		Table.String(): "Output result in human-readable format",
-		JSON.String():  "Output result in JSON format",
-		YAML.String():  "Output result in YAML format",
+		Table.String():   "Output result in human-readable format",
+		JSON.String():    "Output result in JSON format",
+		YAML.String():    "Output result in YAML format",
+		goTemplatePrefix: "Output result by executing a Go template on the JSON output",
 	}
 }
 
@@ -58,9 +66,18 @@ func (o Format) String() string {
 	return string(o)
 }
 
+// Template returns the Go template of a go-template format, and whether the
+// format is one.
+func (o Format) Template() (string, bool) {
+	return strings.CutPrefix(string(o), goTemplatePrefix)
+}
+
 // Write the output in the given format to the io.Writer. Unsupported formats
 // will return an error
 func (o Format) Write(out io.Writer, w Writer) error {
+	if text, ok := o.Template(); ok {
+		return writeTemplate(out, text, w)
+	}
 	switch o {
 	case Table:
 		return w.WriteTable(out)
@@ -83,6 +100,12 @@ func ParseFormat(s string) (out Format, err error) {
 	case YAML.String():
 		out, err = YAML, nil
 	default:
+		if text, ok := Format(s).Template(); ok {
+			if _, err := template.New("output").Parse(text); err != nil {
+				return "", errors.Wrap(err, "invalid go-template")
+			}
+			return Format(s), nil
+		}
 		out, err = "", ErrInvalidFormatType
 	}
 	return
@@ -101,6 +124,30 @@ type Writer interface {
 	WriteYAML(out io.Writer) error
 }
 
+// writeTemplate executes the Go template on the JSON output of the writer, so
+// that the template refers to the fields by their JSON names.
+func writeTemplate(out io.Writer, text string, w Writer) error {
+	tmpl, err := template.New("output").Parse(text)
+	if err != nil {
+		return errors.Wrap(err, "invalid go-template")
+	}
+	var buf bytes.Buffer
+	if err := w.WriteJSON(&buf); err != nil {
+		return err
+	}
+	var data interface{}
+	dec := json.NewDecoder(&buf)
+	// Decode the numbers as written, instead of as floats in exponent notation
+	dec.UseNumber()
+	if err := dec.Decode(&data); err != nil {
+		return errors.Wrap(err, "unable to write go-template output")
+	}
+	if err := tmpl.Execute(out, data); err != nil {
+		return errors.Wrap(err, "unable to write go-template output")
+	}
+	return nil
+}
+
 // EncodeJSON is a helper function to decorate any error message with a bit more
 // context and avoid writing the same code over and over for printers.
 func EncodeJSON(out io.Writer, obj interface{}) error {
@@ -0,0 +1,79 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package output
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+type testWriter struct {
+	Name    string `json:"name"`
+	Version int64  `json:"version"`
+}
+
+func (w testWriter) WriteTable(out io.Writer) error {
+	_, err := io.WriteString(out, w.Name+"\n")
+	return err
+}
+
+func (w testWriter) WriteJSON(out io.Writer) error {
+	return EncodeJSON(out, w)
+}
+
+func (w testWriter) WriteYAML(out io.Writer) error {
+	return EncodeYAML(out, w)
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, s := range []string{"table", "json", "yaml", "go-template={{.name}}"} {
+		format, err := ParseFormat(s)
+		if err != nil {
+			t.Errorf("expected %q to be valid, got %v", s, err)
+		}
+		if format.String() != s {
+			t.Errorf("expected %q, got %q", s, format)
+		}
+	}
+	for _, s := range []string{"", "xml", "go-template", "go-template={{.name"} {
+		if _, err := ParseFormat(s); err == nil {
+			t.Errorf("expected %q to be invalid", s)
+		}
+	}
+}
+
+func TestWriteTemplate(t *testing.T) {
+	format, err := ParseFormat(`go-template={{.name}} {{.version}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, ok := format.Template(); !ok || text != "{{.name}} {{.version}}" {
+		t.Errorf("expected the template of the format, got %q", text)
+	}
+	var out bytes.Buffer
+	if err := format.Write(&out, testWriter{Name: "nginx", Version: 10000000}); err != nil {
+		t.Fatal(err)
+	}
+	if expect := "nginx 10000000"; out.String() != expect {
+		t.Errorf("expected %q, got %q", expect, out.String())
+	}
+
+	if _, ok := JSON.Template(); ok {
+		t.Error("expected json not to be a template")
+	}
+}
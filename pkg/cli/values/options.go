@@ -17,6 +17,8 @@ limitations under the License.
 package values
 
 import (
+	"crypto/sha256"
+	"fmt"
 	"io"
 	"net/url"
 	"os"
@@ -26,6 +28,7 @@ import (
 	"sigs.k8s.io/yaml"
 
 	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/strvals"
 )
 
@@ -42,7 +45,15 @@ type Options struct {
 // MergeValues merges values from files specified via -f/--values and directly
 // via --set-json, --set, --set-string, or --set-file, marshaling them to YAML
 func (opts *Options) MergeValues(p getter.Providers) (map[string]interface{}, error) {
+	base, _, err := opts.MergeValuesWithSources(p)
+	return base, err
+}
+
+// MergeValuesWithSources merges values like MergeValues, and returns their
+// sources in the order they were merged, with the digests of the files read.
+func (opts *Options) MergeValuesWithSources(p getter.Providers) (map[string]interface{}, []release.ValueSource, error) {
 	base := map[string]interface{}{}
+	var sources []release.ValueSource
 
 	// User specified a values files via -f/--values
 	for _, filePath := range opts.ValueFiles {
@@ -50,59 +61,73 @@ func (opts *Options) MergeValues(p getter.Providers) (map[string]interface{}, er
 
 		bytes, err := readFile(filePath, p)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		if err := yaml.Unmarshal(bytes, &currentMap); err != nil {
-			return nil, errors.Wrapf(err, "failed to parse %s", filePath)
+			return nil, nil, errors.Wrapf(err, "failed to parse %s", filePath)
 		}
 		// Merge with the previous map
 		base = mergeMaps(base, currentMap)
+		sources = append(sources, release.ValueSource{Type: release.ValueSourceFile, Value: filePath, Digest: digest(bytes)})
 	}
 
 	// User specified a value via --set-json
 	for _, value := range opts.JSONValues {
 		if err := strvals.ParseJSON(value, base); err != nil {
-			return nil, errors.Errorf("failed parsing --set-json data %s", value)
+			return nil, nil, errors.Errorf("failed parsing --set-json data %s", value)
 		}
+		sources = append(sources, release.ValueSource{Type: release.ValueSourceSetJSON, Value: value})
 	}
 
 	// User specified a value via --set
 	for _, value := range opts.Values {
 		if err := strvals.ParseInto(value, base); err != nil {
-			return nil, errors.Wrap(err, "failed parsing --set data")
+			return nil, nil, errors.Wrap(err, "failed parsing --set data")
 		}
+		sources = append(sources, release.ValueSource{Type: release.ValueSourceSet, Value: value})
 	}
 
 	// User specified a value via --set-string
 	for _, value := range opts.StringValues {
 		if err := strvals.ParseIntoString(value, base); err != nil {
-			return nil, errors.Wrap(err, "failed parsing --set-string data")
+			return nil, nil, errors.Wrap(err, "failed parsing --set-string data")
 		}
+		sources = append(sources, release.ValueSource{Type: release.ValueSourceSetString, Value: value})
 	}
 
 	// User specified a value via --set-file
 	for _, value := range opts.FileValues {
+		var digests []string
 		reader := func(rs []rune) (interface{}, error) {
 			bytes, err := readFile(string(rs), p)
 			if err != nil {
 				return nil, err
 			}
+			digests = append(digests, digest(bytes))
 			return string(bytes), err
 		}
 		if err := strvals.ParseIntoFile(value, base, reader); err != nil {
-			return nil, errors.Wrap(err, "failed parsing --set-file data")
+			return nil, nil, errors.Wrap(err, "failed parsing --set-file data")
 		}
+		sources = append(sources, release.ValueSource{Type: release.ValueSourceSetFile, Value: value, Digest: strings.Join(digests, ",")})
 	}
 
 	// User specified a value via --set-literal
 	for _, value := range opts.LiteralValues {
 		if err := strvals.ParseLiteralInto(value, base); err != nil {
-			return nil, errors.Wrap(err, "failed parsing --set-literal data")
+			return nil, nil, errors.Wrap(err, "failed parsing --set-literal data")
 		}
+		sources = append(sources, release.ValueSource{Type: release.ValueSourceSetLiteral, Value: value})
 	}
 
-	return base, nil
+	return base, sources, nil
+}
+
+// digest returns the SHA256 digest of the content of a file, like
+// "sha256:...".
+func digest(data []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(data))
 }
 
 func mergeMaps(a, b map[string]interface{}) map[string]interface{} {
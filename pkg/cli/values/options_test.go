@@ -17,10 +17,15 @@ limitations under the License.
 package values
 
 import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
 	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/release"
 )
 
 func TestMergeValues(t *testing.T) {
@@ -78,6 +83,51 @@ func TestMergeValues(t *testing.T) {
 	}
 }
 
+func TestMergeValuesWithSources(t *testing.T) {
+	dir := t.TempDir()
+	valuesFile := filepath.Join(dir, "values.yaml")
+	if err := os.WriteFile(valuesFile, []byte("name: file\nreplicas: 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	notesFile := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(notesFile, []byte("notes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &Options{
+		ValueFiles:   []string{valuesFile},
+		Values:       []string{"name=set"},
+		StringValues: []string{"replicas=2"},
+		FileValues:   []string{"notes=" + notesFile},
+	}
+	vals, sources, err := opts.MergeValuesWithSources(getter.Providers{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedVals := map[string]interface{}{
+		"name":     "set",
+		"replicas": "2",
+		"notes":    "notes",
+	}
+	if !reflect.DeepEqual(vals, expectedVals) {
+		t.Errorf("Expected values %v, got %v", expectedVals, vals)
+	}
+	expectedSources := []release.ValueSource{
+		{Type: release.ValueSourceFile, Value: valuesFile, Digest: "sha256:" + sha256Hex("name: file\nreplicas: 1\n")},
+		{Type: release.ValueSourceSet, Value: "name=set"},
+		{Type: release.ValueSourceSetString, Value: "replicas=2"},
+		{Type: release.ValueSourceSetFile, Value: "notes=" + notesFile, Digest: "sha256:" + sha256Hex("notes")},
+	}
+	if !reflect.DeepEqual(sources, expectedSources) {
+		t.Errorf("Expected sources %v, got %v", expectedSources, sources)
+	}
+}
+
+func sha256Hex(s string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(s)))
+}
+
 func TestReadFile(t *testing.T) {
 	var p getter.Providers
 	filePath := "%a.txt"
@@ -16,6 +16,8 @@ limitations under the License.
 package downloader
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/url"
@@ -28,6 +30,7 @@ import (
 
 	"helm.sh/helm/v3/internal/fileutil"
 	"helm.sh/helm/v3/internal/urlutil"
+	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/getter"
 	"helm.sh/helm/v3/pkg/helmpath"
 	"helm.sh/helm/v3/pkg/provenance"
@@ -56,6 +59,22 @@ const (
 // ErrNoOwnerRepo indicates that a given chart URL can't be found in any repos.
 var ErrNoOwnerRepo = errors.New("could not find a repo containing the given URL")
 
+// ErrVerification indicates that a chart was downloaded, but could not be
+// verified against its provenance file or its signature.
+type ErrVerification struct {
+	Err error
+}
+
+// Error implements the error interface.
+func (e ErrVerification) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the cause of the failed verification.
+func (e ErrVerification) Unwrap() error {
+	return e.Err
+}
+
 // ChartDownloader handles downloading a chart.
 //
 // It is capable of performing verifications on charts as well.
@@ -64,7 +83,8 @@ type ChartDownloader struct {
 	Out io.Writer
 	// Verify indicates what verification strategy to use.
 	Verify VerificationStrategy
-	// Keyring is the keyring file used for verification.
+	// Keyring is the keyring file used for verification, or a list of
+	// keyring files and directories as accepted by VerifyChart.
 	Keyring string
 	// Getter collection for the operation
 	Getters getter.Providers
@@ -73,6 +93,10 @@ type ChartDownloader struct {
 	RegistryClient   *registry.Client
 	RepositoryConfig string
 	RepositoryCache  string
+	// SignaturePolicy verifies the sigstore signatures of charts, attached
+	// to charts in registries or in the cosign bundles alongside chart
+	// archives, instead of their provenance files, if it is set.
+	SignaturePolicy *registry.SignaturePolicy
 }
 
 // DownloadTo retrieves a chart. Depending on the settings, it may also download a provenance file.
@@ -106,47 +130,100 @@ func (c *ChartDownloader) DownloadTo(ref, version, dest string) (string, *proven
 	if u.Scheme == registry.OCIScheme {
 		idx := strings.LastIndexByte(name, ':')
 		name = fmt.Sprintf("%s-%s.tgz", name[:idx], name[idx+1:])
+	} else if getter.IsGitURL(u.String()) {
+		// Charts in git repositories are packaged by the getter, the URL
+		// does not name the archive.
+		ch, err := loader.LoadArchive(bytes.NewReader(data.Bytes()))
+		if err != nil {
+			return "", nil, err
+		}
+		name = fmt.Sprintf("%s-%s.tgz", ch.Name(), ch.Metadata.Version)
 	}
 
 	destfile := filepath.Join(dest, name)
+
+	// Charts in registries are verified with their signatures before they
+	// are saved if there is a signature policy.
+	if c.Verify > VerifyNever && c.Verify != VerifyLater && u.Scheme == registry.OCIScheme && c.SignaturePolicy != nil {
+		ver, err := c.verifySignature(u, data.Bytes())
+		if err != nil {
+			return destfile, nil, ErrVerification{Err: err}
+		}
+		return destfile, ver, fileutil.AtomicWriteFile(destfile, data, 0644)
+	}
+
 	if err := fileutil.AtomicWriteFile(destfile, data, 0644); err != nil {
 		return destfile, nil, err
 	}
 
-	// If provenance is requested, verify it.
+	// If provenance is requested, verify it. Chart archives are verified with
+	// their cosign bundles instead if there is a signature policy.
 	ver := &provenance.Verification{}
+	if c.Verify > VerifyNever && getter.IsGitURL(u.String()) {
+		// Charts packaged from git repositories have no provenance.
+		if c.Verify == VerifyAlways {
+			return destfile, ver, ErrVerification{Err: errors.Errorf("charts in git repositories cannot be verified: %s", ref)}
+		}
+		fmt.Fprintf(c.Out, "WARNING: Verification not found for %s: charts in git repositories are not signed\n", ref)
+		return destfile, ver, nil
+	}
 	if c.Verify > VerifyNever {
-		body, err := g.Get(u.String() + ".prov")
+		ext := ".prov"
+		if c.SignaturePolicy != nil {
+			ext = registry.SignatureBundleExt
+		}
+		// The provenance is fetched with the TLS configuration and the
+		// credentials of the chart
+		body, err := g.Get(u.String()+ext, c.Options...)
 		if err != nil {
 			if c.Verify == VerifyAlways {
-				return destfile, ver, errors.Errorf("failed to fetch provenance %q", u.String()+".prov")
+				return destfile, ver, ErrVerification{Err: errors.Errorf("failed to fetch provenance %q", u.String()+ext)}
 			}
 			fmt.Fprintf(c.Out, "WARNING: Verification not found for %s: %s\n", ref, err)
 			return destfile, ver, nil
 		}
-		provfile := destfile + ".prov"
+		provfile := destfile + ext
 		if err := fileutil.AtomicWriteFile(provfile, body, 0644); err != nil {
 			return destfile, nil, err
 		}
 
 		if c.Verify != VerifyLater {
-			ver, err = VerifyChart(destfile, c.Keyring)
+			if c.SignaturePolicy != nil {
+				ver, err = VerifyChartBundle(destfile, c.SignaturePolicy)
+			} else {
+				ver, err = VerifyChart(destfile, c.Keyring)
+			}
 			if err != nil {
 				// Fail always in this case, since it means the verification step
 				// failed.
-				return destfile, ver, err
+				return destfile, ver, ErrVerification{Err: err}
 			}
 		}
 	}
 	return destfile, ver, nil
 }
 
+// verifySignature verifies the sigstore signature of the chart at the OCI
+// reference u, with the chart layer data.
+func (c *ChartDownloader) verifySignature(u *url.URL, data []byte) (*provenance.Verification, error) {
+	if c.RegistryClient == nil {
+		return nil, errors.New("unable to verify the chart signature, missing registry client")
+	}
+	ref := strings.TrimPrefix(u.String(), fmt.Sprintf("%s://", registry.OCIScheme))
+	sv, err := c.RegistryClient.VerifySignature(ref, data, c.SignaturePolicy)
+	if err != nil {
+		return nil, err
+	}
+	return signatureVerification(sv, filepath.Base(u.Path)), nil
+}
+
 func (c *ChartDownloader) getOciURI(ref, version string, u *url.URL) (*url.URL, error) {
 	var tag string
 	var err error
 
-	// Evaluate whether an explicit version has been provided. Otherwise, determine version to use
-	_, errSemVer := semver.NewVersion(version)
+	// Evaluate whether an explicit version has been provided. Otherwise, determine version to use.
+	// Partial versions such as "1.2" are constraints, as for classic repositories.
+	_, errSemVer := semver.StrictNewVersion(version)
 	if errSemVer == nil {
 		tag = version
 	} else {
@@ -199,6 +276,12 @@ func (c *ChartDownloader) ResolveChartVersion(ref, version string) (*url.URL, er
 		return c.getOciURI(ref, version, u)
 	}
 
+	// Charts in git repositories are checked out at the ref of the URL.
+	if getter.IsGitURL(ref) {
+		c.Options = append(c.Options, getter.WithURL(ref))
+		return u, nil
+	}
+
 	rf, err := loadRepoConfig(c.RepositoryConfig)
 	if err != nil {
 		return u, err
@@ -229,16 +312,7 @@ func (c *ChartDownloader) ResolveChartVersion(ref, version string) (*url.URL, er
 			c.Options,
 			getter.WithURL(rc.URL),
 		)
-		if rc.CertFile != "" || rc.KeyFile != "" || rc.CAFile != "" {
-			c.Options = append(c.Options, getter.WithTLSClientConfig(rc.CertFile, rc.KeyFile, rc.CAFile))
-		}
-		if rc.Username != "" && rc.Password != "" {
-			c.Options = append(
-				c.Options,
-				getter.WithBasicAuth(rc.Username, rc.Password),
-				getter.WithPassCredentialsAll(rc.PassCredentialsAll),
-			)
-		}
+		c.Options = append(c.Options, repoEntryOptions(rc)...)
 		return u, nil
 	}
 
@@ -266,15 +340,7 @@ func (c *ChartDownloader) ResolveChartVersion(ref, version string) (*url.URL, er
 	}
 
 	if r != nil && r.Config != nil {
-		if r.Config.CertFile != "" || r.Config.KeyFile != "" || r.Config.CAFile != "" {
-			c.Options = append(c.Options, getter.WithTLSClientConfig(r.Config.CertFile, r.Config.KeyFile, r.Config.CAFile))
-		}
-		if r.Config.Username != "" && r.Config.Password != "" {
-			c.Options = append(c.Options,
-				getter.WithBasicAuth(r.Config.Username, r.Config.Password),
-				getter.WithPassCredentialsAll(r.Config.PassCredentialsAll),
-			)
-		}
+		c.Options = append(c.Options, repoEntryOptions(r.Config)...)
 	}
 
 	// Next, we need to load the index, and actually look up the chart.
@@ -305,6 +371,10 @@ func (c *ChartDownloader) ResolveChartVersion(ref, version string) (*url.URL, er
 
 // VerifyChart takes a path to a chart archive and a keyring, and verifies the chart.
 //
+// The keyring may be a list of keyring files and directories of keyring files,
+// separated by the OS-specific list separator like $PATH, so that the charts
+// signed with any of their keys are verified.
+//
 // It assumes that a chart archive file is accompanied by a provenance file whose
 // name is the archive file name plus the ".prov" extension.
 func VerifyChart(path, keyring string) (*provenance.Verification, error) {
@@ -323,13 +393,62 @@ func VerifyChart(path, keyring string) (*provenance.Verification, error) {
 		return nil, errors.Wrapf(err, "could not load provenance file %s", provfile)
 	}
 
-	sig, err := provenance.NewFromKeyring(keyring, "")
+	sig, err := provenance.NewFromKeyrings(filepath.SplitList(keyring)...)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to load keyring")
 	}
 	return sig.Verify(path, provfile)
 }
 
+// VerifyChartBundle takes a path to a chart archive and a signature policy,
+// and verifies the sigstore signature of the chart.
+//
+// It assumes that a chart archive file is accompanied by a cosign bundle whose
+// name is the archive file name plus the ".cosign.bundle" extension.
+func VerifyChartBundle(path string, policy *registry.SignaturePolicy) (*provenance.Verification, error) {
+	switch fi, err := os.Stat(path); {
+	case err != nil:
+		return nil, err
+	case fi.IsDir():
+		return nil, errors.New("unpacked charts cannot be verified")
+	case !isTar(path):
+		return nil, errors.New("chart must be a tgz file")
+	}
+
+	bundlefile := path + registry.SignatureBundleExt
+	b, err := os.ReadFile(bundlefile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not load cosign bundle %s", bundlefile)
+	}
+	bundle := &registry.SignatureBundle{}
+	if err := json.Unmarshal(b, bundle); err != nil {
+		return nil, errors.Wrapf(err, "invalid cosign bundle %s", bundlefile)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sv, err := policy.VerifyBundle(data, bundle)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to verify %s", filepath.Base(path))
+	}
+	return signatureVerification(sv, filepath.Base(path)), nil
+}
+
+// signatureVerification returns the verification of the sigstore signature
+// of the chart archive name.
+func signatureVerification(sv *registry.SignatureVerification, name string) *provenance.Verification {
+	ver := &provenance.Verification{
+		Signer:   sv.Signer,
+		FileHash: sv.ChartDigest,
+		FileName: name,
+	}
+	if sv.Issuer != "" {
+		ver.Signer = fmt.Sprintf("%s (%s)", sv.Signer, sv.Issuer)
+	}
+	return ver
+}
+
 // isTar tests whether the given file is a tar file.
 //
 // Currently, this simply checks extension, since a subsequent function will
@@ -338,6 +457,40 @@ func isTar(filename string) bool {
 	return strings.EqualFold(filepath.Ext(filename), ".tgz")
 }
 
+// repoEntryOptions returns the options of the getters of the repository
+// entry rc: its TLS configuration, its credentials and its network options.
+// The TLS files are read by the getters on each download, so the rotated
+// certificates are used.
+func repoEntryOptions(rc *repo.Entry) []getter.Option {
+	var opts []getter.Option
+	if rc.CertFile != "" || rc.KeyFile != "" || rc.CAFile != "" {
+		opts = append(opts, getter.WithTLSClientConfig(rc.CertFile, rc.KeyFile, rc.CAFile))
+	}
+	if rc.InsecureSkipTLSverify {
+		opts = append(opts, getter.WithInsecureSkipVerifyTLS(true))
+	}
+	if rc.Username != "" && rc.Password != "" {
+		opts = append(opts,
+			getter.WithBasicAuth(rc.Username, rc.Password),
+			getter.WithPassCredentialsAll(rc.PassCredentialsAll),
+		)
+	}
+	return append(opts, repoNetworkOptions(rc)...)
+}
+
+// repoNetworkOptions returns the options of the getters for the proxy and the
+// static headers of the repository rc.
+func repoNetworkOptions(rc *repo.Entry) []getter.Option {
+	var opts []getter.Option
+	if rc.Proxy != "" {
+		opts = append(opts, getter.WithProxy(rc.Proxy, rc.NoProxy))
+	}
+	if len(rc.Headers) > 0 {
+		opts = append(opts, getter.WithHeaders(rc.Headers))
+	}
+	return opts
+}
+
 func pickChartRepositoryConfigByName(name string, cfgs []*repo.Entry) (*repo.Entry, error) {
 	for _, rc := range cfgs {
 		if rc.Name == name {
@@ -16,13 +16,22 @@ limitations under the License.
 package downloader
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"helm.sh/helm/v3/internal/test/ensure"
 	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/registry"
 	"helm.sh/helm/v3/pkg/repo"
 	"helm.sh/helm/v3/pkg/repo/repotest"
 )
@@ -135,7 +144,7 @@ func TestResolveChartOpts(t *testing.T) {
 			continue
 		}
 
-		if *(got.(*getter.HTTPGetter)) != *(expect.(*getter.HTTPGetter)) {
+		if !reflect.DeepEqual(got, expect) {
 			t.Errorf("%s: expected %s, got %s", tt.name, expect, got)
 		}
 	}
@@ -216,6 +225,109 @@ func TestDownloadTo(t *testing.T) {
 	}
 }
 
+// writeCosignKey writes the private and public keys of a new key pair to
+// name.key and name.pub.
+func writeCosignKey(t *testing.T, name string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name+".key", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if der, err = x509.MarshalPKIXPublicKey(&key.PublicKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name+".pub", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// signChart writes the cosign bundle of the chart archive, signed with the
+// private key.
+func signChart(t *testing.T, chart, key string) {
+	t.Helper()
+	signer, err := registry.NewKeySigner(key, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(chart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle, err := signer.SignBlob(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := json.Marshal(bundle)
+	if err := os.WriteFile(chart+registry.SignatureBundleExt, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDownloadTo_SignaturePolicy(t *testing.T) {
+	dir := t.TempDir()
+	writeCosignKey(t, filepath.Join(dir, "cosign"))
+	writeCosignKey(t, filepath.Join(dir, "other"))
+
+	charts := filepath.Join(dir, "charts")
+	if err := os.Mkdir(charts, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"signtest-0.1.0.tgz", "local-subchart-0.1.0.tgz"} {
+		data, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(charts, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	signChart(t, filepath.Join(charts, "signtest-0.1.0.tgz"), filepath.Join(dir, "cosign.key"))
+	signChart(t, filepath.Join(charts, "local-subchart-0.1.0.tgz"), filepath.Join(dir, "other.key"))
+
+	srv, err := repotest.NewTempServerWithCleanup(t, filepath.Join(charts, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	c := ChartDownloader{
+		Out:              os.Stderr,
+		Verify:           VerifyAlways,
+		RepositoryConfig: repoConfig,
+		RepositoryCache:  repoCache,
+		Getters: getter.All(&cli.EnvSettings{
+			RepositoryConfig: repoConfig,
+			RepositoryCache:  repoCache,
+		}),
+		SignaturePolicy: &registry.SignaturePolicy{PublicKeys: []string{filepath.Join(dir, "cosign.pub")}},
+	}
+	dest := t.TempDir()
+	where, v, err := c.DownloadTo(srv.URL()+"/signtest-0.1.0.tgz", "", dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Signer != filepath.Join(dir, "cosign.pub") || v.FileHash == "" {
+		t.Errorf("Unexpected verification %+v", v)
+	}
+	if _, err := os.Stat(where + registry.SignatureBundleExt); err != nil {
+		t.Error(err)
+	}
+	if _, err := VerifyChartBundle(where, c.SignaturePolicy); err != nil {
+		t.Errorf("Expected the downloaded chart to verify, got %v", err)
+	}
+
+	if _, _, err := c.DownloadTo(srv.URL()+"/local-subchart-0.1.0.tgz", "", dest); err == nil || !strings.Contains(err.Error(), "trusted key") {
+		t.Errorf("Expected a chart signed with an untrusted key to fail, got %v", err)
+	}
+}
+
 func TestDownloadTo_TLS(t *testing.T) {
 	// Set up mock server w/ tls enabled
 	srv, err := repotest.NewTempServerWithCleanup(t, "testdata/*.tgz*")
@@ -268,6 +380,51 @@ func TestDownloadTo_TLS(t *testing.T) {
 	}
 }
 
+func TestDownloadTo_TLSChartURL(t *testing.T) {
+	// Set up mock server w/ tls enabled
+	srv, err := repotest.NewTempServerWithCleanup(t, "testdata/*.tgz*")
+	srv.Stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.StartTLS()
+	defer srv.Stop()
+	if err := srv.CreateIndex(); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.LinkIndices(); err != nil {
+		t.Fatal(err)
+	}
+
+	repoConfig := filepath.Join(srv.Root(), "repositories.yaml")
+	repoCache := srv.Root()
+
+	// The chart and its provenance are downloaded by URL with the CA file of
+	// the repository serving the chart
+	c := ChartDownloader{
+		Out:              os.Stderr,
+		Verify:           VerifyAlways,
+		Keyring:          "testdata/helm-test-key.pub",
+		RepositoryConfig: repoConfig,
+		RepositoryCache:  repoCache,
+		Getters: getter.All(&cli.EnvSettings{
+			RepositoryConfig: repoConfig,
+			RepositoryCache:  repoCache,
+		}),
+	}
+	dest := t.TempDir()
+	where, v, err := c.DownloadTo(srv.URL()+"/signtest-0.1.0.tgz", "", dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := filepath.Join(dest, "signtest-0.1.0.tgz"); where != expect {
+		t.Errorf("Expected download to %s, got %s", expect, where)
+	}
+	if v.FileHash == "" {
+		t.Error("File hash was empty, but verification is required.")
+	}
+}
+
 func TestDownloadTo_VerifyLater(t *testing.T) {
 	ensure.HelmHome(t)
 
@@ -30,6 +30,7 @@ import (
 	"sync"
 
 	"github.com/Masterminds/semver/v3"
+	multierror "github.com/hashicorp/go-multierror"
 	"github.com/pkg/errors"
 	"sigs.k8s.io/yaml"
 
@@ -41,6 +42,7 @@ import (
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/getter"
 	"helm.sh/helm/v3/pkg/helmpath"
+	"helm.sh/helm/v3/pkg/provenance"
 	"helm.sh/helm/v3/pkg/registry"
 	"helm.sh/helm/v3/pkg/repo"
 )
@@ -56,6 +58,24 @@ func (e ErrRepoNotFound) Error() string {
 	return fmt.Sprintf("no repository definition for %s", strings.Join(e.Repos, ", "))
 }
 
+// ErrUnverifiedDependencies indicates that dependencies could not be verified
+// against their provenance files or their signatures. The value of Errs is
+// why each of the Dependencies could not be verified.
+type ErrUnverifiedDependencies struct {
+	Dependencies []*chart.Dependency
+	Errs         []error
+}
+
+// Error implements the error interface.
+func (e ErrUnverifiedDependencies) Error() string {
+	var b strings.Builder
+	b.WriteString("the following dependencies could not be verified:")
+	for i, dep := range e.Dependencies {
+		fmt.Fprintf(&b, "\n\t%s (%s): %s", dep.Name, dep.Version, e.Errs[i])
+	}
+	return b.String()
+}
+
 // Manager handles the lifecycle of fetching, resolving, and storing dependencies.
 type Manager struct {
 	// Out is used to print warnings and notifications.
@@ -68,6 +88,9 @@ type Manager struct {
 	Debug bool
 	// Keyring is the key ring file.
 	Keyring string
+	// SignaturePolicy verifies the sigstore signatures of the dependencies
+	// instead of their provenance files, if it is set.
+	SignaturePolicy *registry.SignaturePolicy
 	// SkipUpdate indicates that the repository should not be updated first.
 	SkipUpdate bool
 	// Getter collection for the operation
@@ -75,8 +98,23 @@ type Manager struct {
 	RegistryClient   *registry.Client
 	RepositoryConfig string
 	RepositoryCache  string
+	// Concurrency is the maximum number of dependencies downloaded at the
+	// same time, DefaultConcurrency if it is not positive.
+	Concurrency int
+	// Vendored makes Build copy the dependencies vendored by Vendor from the
+	// vendor directory of the chart instead of downloading them, without
+	// network access.
+	Vendored bool
+	// ResolutionPolicy chooses among the repositories serving the charts of
+	// the dependencies of the repo.AnyRepository repository, overriding the
+	// policy of the repositories file.
+	ResolutionPolicy repo.ResolutionPolicy
 }
 
+// DefaultConcurrency is the default number of dependencies a Manager
+// downloads concurrently.
+const DefaultConcurrency = 4
+
 // Build rebuilds a local charts directory from a lockfile.
 //
 // If the lockfile is not present, this will run a Manager.Update()
@@ -92,6 +130,9 @@ func (m *Manager) Build() error {
 	// an update.
 	lock := c.Lock
 	if lock == nil {
+		if m.Vendored {
+			return errors.New("the chart has no lock file (Chart.lock) to build the vendored dependencies from")
+		}
 		return m.Update()
 	}
 
@@ -128,6 +169,17 @@ func (m *Manager) Build() error {
 		}
 	}
 
+	if m.Vendored {
+		manifest, err := m.loadVendorManifest()
+		if err != nil {
+			return err
+		}
+		if manifest.Digest != lock.Digest {
+			return errors.New("the vendored dependencies are out of sync with the lock file (Chart.lock). Please vendor the dependencies")
+		}
+		return m.downloadAll(lock.Dependencies, nil)
+	}
+
 	// Check that all of the repos we're dependent on actually exist.
 	if err := m.hasAllRepos(lock.Dependencies); err != nil {
 		return err
@@ -191,7 +243,7 @@ func (m *Manager) Update() error {
 
 	// Now we need to find out which version of a chart best satisfies the
 	// dependencies in the Chart.yaml
-	lock, urls, err := m.resolve(req, repoNames)
+	lock, urls, err := m.resolve(c.Metadata, repoNames)
 	if err != nil {
 		return err
 	}
@@ -227,12 +279,84 @@ func (m *Manager) loadChartDir() (*chart.Chart, error) {
 	return loader.LoadDir(m.ChartPath)
 }
 
-// resolve takes a list of dependencies and translates them into an exact version to download.
+// resolve takes the dependencies of a chart and translates them into an exact version to download.
 //
 // This returns a lock file, which has all of the dependencies normalized to a specific version.
-func (m *Manager) resolve(req []*chart.Dependency, repoNames map[string]string) (*chart.Lock, map[string]string, error) {
+// The transitive dependencies are resolved too, to warn of the charts required in conflicting
+// versions, and explain the resolution in debug mode.
+func (m *Manager) resolve(md *chart.Metadata, repoNames map[string]string) (*chart.Lock, map[string]string, error) {
+	deps, err := m.chooseRepositories(md.Dependencies, repoNames)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	res := resolver.New(m.ChartPath, m.RepositoryCache, m.RegistryClient)
-	return res.Resolve(req, repoNames)
+	lock, urls, err := res.Resolve(deps, repoNames)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	graph := res.Graph(md, lock.Dependencies, m.repoURLs(md.Dependencies, repoNames))
+	if m.Debug {
+		fmt.Fprintf(m.Out, "Resolved dependencies:\n%s", graph)
+	}
+	for _, s := range graph.Shared() {
+		if s.Conflicting() {
+			fmt.Fprintf(m.Out, "WARNING: %s\n", s)
+		} else if m.Debug {
+			fmt.Fprintf(m.Out, "Note: %s\n", s)
+		}
+	}
+	return lock, urls, nil
+}
+
+// chooseRepositories returns the dependencies with the repositories of the
+// dependencies of the repo.AnyRepository repository chosen by the resolution
+// policy, so the lock file records the chosen repositories. The names of the
+// chosen repositories are set in repoNames.
+func (m *Manager) chooseRepositories(deps []*chart.Dependency, repoNames map[string]string) ([]*chart.Dependency, error) {
+	var rf *repo.File
+	chosen := make([]*chart.Dependency, len(deps))
+	for i, dd := range deps {
+		chosen[i] = dd
+		if dd.Repository != repo.AnyRepository {
+			continue
+		}
+		if rf == nil {
+			var err error
+			if rf, err = loadRepoConfig(m.RepositoryConfig); err != nil {
+				return nil, err
+			}
+		}
+		src, err := rf.ResolveChart(dd.Name, dd.Version, m.RepositoryCache, m.ResolutionPolicy)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not choose the repository of dependency %q", dd.Name)
+		}
+		fmt.Fprintf(m.Out, "Dependency %s is resolved from the %q repository\n", dd.Name, src.Repository.Name)
+
+		d := *dd
+		d.Repository = src.Repository.URL
+		chosen[i] = &d
+		repoNames[dd.Name] = src.Repository.Name
+	}
+	return chosen, nil
+}
+
+// repoURLs returns the URLs of the repositories Helm is configured to know
+// about and of the repositories added for the dependencies, by name.
+func (m *Manager) repoURLs(deps []*chart.Dependency, repoNames map[string]string) map[string]string {
+	urls := make(map[string]string)
+	if rf, err := loadRepoConfig(m.RepositoryConfig); err == nil {
+		for _, r := range rf.Repositories {
+			urls[r.Name] = r.URL
+		}
+	}
+	for _, dd := range deps {
+		if name, ok := repoNames[dd.Name]; ok && strings.HasPrefix(name, managerKeyPrefix) {
+			urls[name] = dd.Repository
+		}
+	}
+	return urls
 }
 
 // downloadAll takes a list of dependencies and downloads them into charts/
@@ -240,7 +364,15 @@ func (m *Manager) resolve(req []*chart.Dependency, repoNames map[string]string)
 // It will delete versions of the chart that exist on disk and might cause
 // a conflict.
 func (m *Manager) downloadAll(deps []*chart.Dependency, urls map[string]string) error {
-	repos, err := m.loadChartRepositories()
+	// The vendored dependencies are copied without the repositories
+	var repos map[string]*repo.ChartRepository
+	var vendored *chart.Lock
+	var err error
+	if m.Vendored {
+		vendored, err = m.loadVendorManifest()
+	} else {
+		repos, err = m.loadChartRepositories()
+	}
 	if err != nil {
 		return err
 	}
@@ -267,9 +399,13 @@ func (m *Manager) downloadAll(deps []*chart.Dependency, urls map[string]string)
 	}
 	defer os.RemoveAll(tmpPath)
 
+	// The messages of the concurrent downloads are written to out
+	out := &syncWriter{w: m.Out}
+
 	fmt.Fprintf(m.Out, "Saving %d charts\n", len(deps))
 	var saveError error
-	churls := make(map[string]struct{})
+	var downloads []dependencyDownload
+	churls := make(map[string]int)
 	for _, dep := range deps {
 		// No repository means the chart is in charts directory
 		if dep.Repository == "" {
@@ -310,6 +446,14 @@ func (m *Manager) downloadAll(deps []*chart.Dependency, urls map[string]string)
 			continue
 		}
 
+		if vendored != nil {
+			if err := m.copyVendored(dep, vendored, tmpPath); err != nil {
+				saveError = err
+				break
+			}
+			continue
+		}
+
 		// Any failure to resolve/download a chart should fail:
 		// https://github.com/helm/helm/issues/1439
 		churl, username, password, insecureskiptlsverify, passcredentialsall, caFile, certFile, keyFile, err := m.findChartURL(dep.Name, dep.Version, dep.Repository, repos, urls)
@@ -318,17 +462,18 @@ func (m *Manager) downloadAll(deps []*chart.Dependency, urls map[string]string)
 			break
 		}
 
-		if _, ok := churls[churl]; ok {
+		if i, ok := churls[churl]; ok {
 			fmt.Fprintf(m.Out, "Already downloaded %s from repo %s\n", dep.Name, dep.Repository)
+			downloads[i].duplicates = append(downloads[i].duplicates, dep)
 			continue
 		}
-
-		fmt.Fprintf(m.Out, "Downloading %s from repo %s\n", dep.Name, dep.Repository)
+		churls[churl] = len(downloads)
 
 		dl := ChartDownloader{
-			Out:              m.Out,
+			Out:              out,
 			Verify:           m.Verify,
 			Keyring:          m.Keyring,
+			SignaturePolicy:  m.SignaturePolicy,
 			RepositoryConfig: m.RepositoryConfig,
 			RepositoryCache:  m.RepositoryCache,
 			RegistryClient:   m.RegistryClient,
@@ -340,6 +485,12 @@ func (m *Manager) downloadAll(deps []*chart.Dependency, urls map[string]string)
 				getter.WithTLSClientConfig(certFile, keyFile, caFile),
 			},
 		}
+		for _, cr := range repos {
+			if urlutil.Equal(dep.Repository, cr.Config.URL) {
+				dl.Options = append(dl.Options, repoNetworkOptions(cr.Config)...)
+				break
+			}
+		}
 
 		version := ""
 		if registry.IsOCI(churl) {
@@ -350,14 +501,18 @@ func (m *Manager) downloadAll(deps []*chart.Dependency, urls map[string]string)
 			dl.Options = append(dl.Options,
 				getter.WithRegistryClient(m.RegistryClient),
 				getter.WithTagName(version))
+			if dep.Digest != "" {
+				dl.Options = append(dl.Options, getter.WithDigest(dep.Digest))
+			}
+		} else if getter.IsGitURL(churl) && dep.Digest != "" {
+			// Check out the commit the dependency is locked to
+			dl.Options = append(dl.Options, getter.WithDigest(dep.Digest))
 		}
 
-		if _, _, err = dl.DownloadTo(churl, version, tmpPath); err != nil {
-			saveError = errors.Wrapf(err, "could not download %s", churl)
-			break
-		}
-
-		churls[churl] = struct{}{}
+		downloads = append(downloads, dependencyDownload{dep: dep, url: churl, version: version, downloader: dl})
+	}
+	if saveError == nil {
+		saveError = m.downloadConcurrently(downloads, out, tmpPath)
 	}
 
 	// TODO: this should probably be refactored to be a []error, so we can capture and provide more information rather than "last error wins".
@@ -373,6 +528,110 @@ func (m *Manager) downloadAll(deps []*chart.Dependency, urls map[string]string)
 	return nil
 }
 
+// dependencyDownload is the download of a dependency from a repository.
+type dependencyDownload struct {
+	dep *chart.Dependency
+	// duplicates are the other dependencies of the same chart archive.
+	duplicates []*chart.Dependency
+	url        string
+	version    string
+	downloader ChartDownloader
+}
+
+// downloadConcurrently downloads the dependencies to dest, at most
+// m.Concurrency at the same time. All the dependencies are downloaded even if
+// some fail, and the errors of all the failed downloads are returned.
+//
+// The archives are verified against the checksums of the dependencies locked
+// with one, and the checksums of the others are set. The dependencies failing
+// the verification of their provenance files or signatures are all returned
+// in an ErrUnverifiedDependencies.
+func (m *Manager) downloadConcurrently(downloads []dependencyDownload, out io.Writer, dest string) error {
+	concurrency := m.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, concurrency)
+	errs := make([]error, len(downloads))
+	for i, d := range downloads {
+		wg.Add(1)
+		limit <- struct{}{}
+		go func(i int, d dependencyDownload) {
+			defer func() {
+				<-limit
+				wg.Done()
+			}()
+			fmt.Fprintf(out, "Downloading %s from repo %s\n", d.dep.Name, d.dep.Repository)
+			saved, _, err := d.downloader.DownloadTo(d.url, d.version, dest)
+			if errors.As(err, &ErrVerification{}) {
+				errs[i] = err
+				return
+			}
+			if err != nil {
+				errs[i] = errors.Wrapf(err, "could not download %s", d.url)
+				return
+			}
+			if getter.IsGitURL(d.url) {
+				// Git archives are not reproducible, the commit of the
+				// dependency pins them
+				return
+			}
+			errs[i] = verifyChecksum(saved, append([]*chart.Dependency{d.dep}, d.duplicates...))
+		}(i, d)
+	}
+	wg.Wait()
+
+	var result *multierror.Error
+	var unverified ErrUnverifiedDependencies
+	for i, err := range errs {
+		if errors.As(err, &ErrVerification{}) {
+			unverified.Dependencies = append(unverified.Dependencies, downloads[i].dep)
+			unverified.Errs = append(unverified.Errs, err)
+		} else if err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
+	if len(unverified.Dependencies) > 0 {
+		result = multierror.Append(result, unverified)
+	}
+	if result != nil && len(result.Errors) == 1 {
+		return result.Errors[0]
+	}
+	return result.ErrorOrNil()
+}
+
+// verifyChecksum verifies the archive of the dependencies deps against their
+// checksums, and sets the checksums of the dependencies without one.
+func verifyChecksum(archive string, deps []*chart.Dependency) error {
+	sum, err := provenance.DigestFile(archive)
+	if err != nil {
+		return err
+	}
+	checksum := "sha256:" + sum
+	for _, dep := range deps {
+		if dep.Checksum == "" {
+			dep.Checksum = checksum
+		} else if dep.Checksum != checksum {
+			return errors.Errorf("the archive of dependency %q version %s has checksum %s, but the lock file requires %s. The archive may have been tampered with, or replaced in the repository", dep.Name, dep.Version, checksum, dep.Checksum)
+		}
+	}
+	return nil
+}
+
+// syncWriter serializes the writes of concurrent downloads.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
 func parseOCIRef(chartRef string) (string, string, error) {
 	refTagRegexp := regexp.MustCompile(`^(oci://[^:]+(:[0-9]{1,5})?[^:]+):(.*)$`)
 	caps := refTagRegexp.FindStringSubmatch(chartRef)
@@ -471,8 +730,8 @@ func (m *Manager) hasAllRepos(deps []*chart.Dependency) error {
 	missing := []string{}
 Loop:
 	for _, dd := range deps {
-		// If repo is from local path or OCI, continue
-		if strings.HasPrefix(dd.Repository, "file://") || registry.IsOCI(dd.Repository) {
+		// If repo is from local path, OCI or git, continue
+		if strings.HasPrefix(dd.Repository, "file://") || registry.IsOCI(dd.Repository) || getter.IsGitURL(dd.Repository) {
 			continue
 		}
 
@@ -506,7 +765,7 @@ Outer:
 
 		// If the chart is in the local charts directory no repository needs
 		// to be specified.
-		if dd.Repository == "" {
+		if dd.Repository == "" || dd.Repository == repo.AnyRepository {
 			continue
 		}
 
@@ -598,7 +857,13 @@ func (m *Manager) resolveRepoNames(deps []*chart.Dependency) (map[string]string,
 			continue
 		}
 
-		if registry.IsOCI(dd.Repository) {
+		if registry.IsOCI(dd.Repository) || getter.IsGitURL(dd.Repository) {
+			reposMap[dd.Name] = dd.Repository
+			continue
+		}
+
+		// The repository is chosen when the dependency is resolved
+		if dd.Repository == repo.AnyRepository {
 			reposMap[dd.Name] = dd.Repository
 			continue
 		}
@@ -716,6 +981,10 @@ func (m *Manager) findChartURL(name, version, repoURL string, repos map[string]*
 	if registry.IsOCI(repoURL) {
 		return fmt.Sprintf("%s/%s:%s", repoURL, name, version), "", "", false, false, "", "", "", nil
 	}
+	// The URL of a git dependency is the URL of its chart
+	if getter.IsGitURL(repoURL) {
+		return repoURL, "", "", false, false, "", "", "", nil
+	}
 
 	for _, cr := range repos {
 
@@ -17,15 +17,26 @@ package downloader
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/provenance"
+	"helm.sh/helm/v3/pkg/repo"
 	"helm.sh/helm/v3/pkg/repo/repotest"
 )
 
@@ -290,6 +301,80 @@ version: 0.1.0`
 	}
 }
 
+func TestDownloadConcurrently(t *testing.T) {
+	archive, err := os.ReadFile(filepath.Join("testdata", "local-subchart-0.1.0.tgz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	active, maxActive := 0, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		active--
+		mu.Unlock()
+
+		if strings.HasPrefix(r.URL.Path, "/missing") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(archive)
+	}))
+	defer srv.Close()
+
+	dest := t.TempDir()
+	out := &syncWriter{w: new(bytes.Buffer)}
+	m := &Manager{Out: out, Concurrency: 2}
+	download := func(path string) dependencyDownload {
+		return dependencyDownload{
+			dep: &chart.Dependency{Name: "local-subchart", Repository: srv.URL},
+			url: srv.URL + path,
+			downloader: ChartDownloader{
+				Out:              out,
+				Getters:          getter.All(&cli.EnvSettings{}),
+				RepositoryConfig: repoConfig,
+				RepositoryCache:  repoCache,
+			},
+		}
+	}
+
+	var downloads []dependencyDownload
+	for i := 0; i < 6; i++ {
+		downloads = append(downloads, download(fmt.Sprintf("/charts/local-subchart-%d.tgz", i)))
+	}
+	if err := m.downloadConcurrently(downloads, out, dest); err != nil {
+		t.Fatal(err)
+	}
+	if maxActive != 2 {
+		t.Errorf("expected 2 concurrent downloads, got %d", maxActive)
+	}
+	files, err := os.ReadDir(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 6 {
+		t.Errorf("expected 6 downloaded charts, got %d", len(files))
+	}
+
+	// The errors of all the failed downloads are reported
+	downloads = append(downloads, download("/missing/a-0.1.0.tgz"), download("/missing/b-0.1.0.tgz"))
+	err = m.downloadConcurrently(downloads, out, dest)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, name := range []string{"/missing/a-0.1.0.tgz", "/missing/b-0.1.0.tgz"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("expected the error to report %s, got %s", name, err)
+		}
+	}
+}
+
 func TestUpdateBeforeBuild(t *testing.T) {
 	// Set up a fake repo
 	srv, err := repotest.NewTempServerWithCleanup(t, "testdata/*.tgz*")
@@ -358,6 +443,76 @@ func TestUpdateBeforeBuild(t *testing.T) {
 	}
 }
 
+func TestBuildVerifiesChecksums(t *testing.T) {
+	// Set up a fake repo
+	srv, err := repotest.NewTempServerWithCleanup(t, "testdata/*.tgz*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+	if err := srv.LinkIndices(); err != nil {
+		t.Fatal(err)
+	}
+	dir := func(p ...string) string {
+		return filepath.Join(append([]string{srv.Root()}, p...)...)
+	}
+
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{
+			Name:       "with-checksum",
+			Version:    "0.1.0",
+			APIVersion: "v2",
+			Dependencies: []*chart.Dependency{{
+				Name:       "local-subchart",
+				Version:    "0.1.0",
+				Repository: srv.URL(),
+			}},
+		},
+	}
+	if err := chartutil.SaveDir(c, dir()); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &Manager{
+		ChartPath:        dir(c.Metadata.Name),
+		Out:              new(bytes.Buffer),
+		Getters:          getter.All(&cli.EnvSettings{}),
+		RepositoryConfig: dir("repositories.yaml"),
+		RepositoryCache:  dir(),
+	}
+	if err := m.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The lock file records the checksum of the archive
+	archive, err := os.ReadFile(dir("local-subchart-0.1.0.tgz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := provenance.Digest(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch, err := loader.LoadDir(m.ChartPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ch.Lock.Dependencies[0].Checksum; got != "sha256:"+sum {
+		t.Fatalf("expected the checksum sha256:%s to be locked, got %q", sum, got)
+	}
+	if err := m.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Build fails if the archive is replaced in the repository
+	if err := os.WriteFile(dir("local-subchart-0.1.0.tgz"), append(archive, 0), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Build(); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("expected a checksum mismatch, got %v", err)
+	}
+}
+
 // TestUpdateWithNoRepo is for the case of a dependency that has no repo listed.
 // This happens when the dependency is in the charts directory and does not need
 // to be fetched.
@@ -598,3 +753,204 @@ func TestKey(t *testing.T) {
 		}
 	}
 }
+
+func TestUpdateWithGitDependency(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	repoDir := filepath.Join(dir, "git-dep")
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=Helm", "-c", "user.email=helm@example.com"}, args...)...)
+		cmd.Dir = repoDir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %s: %s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	// Save the dependency in a git repository, tagged v0.2.0
+	d := &chart.Chart{
+		Metadata: &chart.Metadata{
+			Name:       "git-dep",
+			Version:    "0.2.0",
+			APIVersion: "v2",
+		},
+	}
+	if err := chartutil.SaveDir(d, dir); err != nil {
+		t.Fatal(err)
+	}
+	git("init", "-q")
+	git("add", ".")
+	git("commit", "-q", "-m", "git-dep 0.2.0")
+	git("tag", "-a", "v0.2.0", "-m", "v0.2.0")
+	commit := git("rev-parse", "HEAD")
+
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{
+			Name:       "with-git-dependency",
+			Version:    "0.1.0",
+			APIVersion: "v2",
+			Dependencies: []*chart.Dependency{{
+				Name:       d.Metadata.Name,
+				Version:    "^0.2.0",
+				Repository: "git+file://" + filepath.ToSlash(repoDir) + "?ref=v0.2.0",
+			}},
+		},
+	}
+	if err := chartutil.SaveDir(c, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &Manager{
+		ChartPath: filepath.Join(dir, c.Metadata.Name),
+		Out:       bytes.NewBuffer(nil),
+		Getters: getter.Providers{getter.Provider{
+			Schemes: []string{"git+file"},
+			New:     getter.NewGitGetter,
+		}},
+		RepositoryConfig: filepath.Join(dir, "repositories.yaml"),
+		RepositoryCache:  dir,
+	}
+	if err := m.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	lock, err := loader.LoadDir(m.ChartPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	locked := lock.Lock.Dependencies[0]
+	if locked.Version != "0.2.0" || locked.Digest != commit {
+		t.Fatalf("expected git-dep 0.2.0 locked to %s, got %s at %s", commit, locked.Version, locked.Digest)
+	}
+
+	// Move the tag, the build checks out the locked commit
+	if err := os.WriteFile(filepath.Join(repoDir, "values.yaml"), []byte("moved: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git("add", ".")
+	git("commit", "-q", "-m", "move v0.2.0")
+	git("tag", "-f", "-a", "v0.2.0", "-m", "v0.2.0")
+
+	if err := m.Build(); err != nil {
+		t.Fatal(err)
+	}
+	ch, err := loader.Load(filepath.Join(m.ChartPath, "charts", "git-dep-0.2.0.tgz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ch.Values["moved"] != nil {
+		t.Error("expected the dependency to be built from the locked commit")
+	}
+}
+
+func TestUpdateWithAnyRepository(t *testing.T) {
+	// Set up a fake repo
+	srv, err := repotest.NewTempServerWithCleanup(t, "testdata/*.tgz*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+	if err := srv.LinkIndices(); err != nil {
+		t.Fatal(err)
+	}
+	dir := func(p ...string) string {
+		return filepath.Join(append([]string{srv.Root()}, p...)...)
+	}
+
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{
+			Name:       "with-any-repository",
+			Version:    "0.1.0",
+			APIVersion: "v2",
+			Dependencies: []*chart.Dependency{{
+				Name:       "local-subchart",
+				Version:    "0.1.0",
+				Repository: repo.AnyRepository,
+			}},
+		},
+	}
+	if err := chartutil.SaveDir(c, dir()); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &Manager{
+		ChartPath:        dir(c.Metadata.Name),
+		Out:              new(bytes.Buffer),
+		Getters:          getter.All(&cli.EnvSettings{}),
+		RepositoryConfig: dir("repositories.yaml"),
+		RepositoryCache:  dir(),
+		ResolutionPolicy: repo.ErrorOnAmbiguity,
+	}
+	if err := m.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The lock file records the chosen repository
+	ch, err := loader.LoadDir(m.ChartPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ch.Lock.Dependencies[0].Repository; got != srv.URL() {
+		t.Errorf("expected the repository %s to be locked, got %q", srv.URL(), got)
+	}
+	if err := m.Build(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDownloadConcurrentlyVerifies(t *testing.T) {
+	srv := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer srv.Close()
+
+	dest := t.TempDir()
+	out := &syncWriter{w: new(bytes.Buffer)}
+	m := &Manager{Out: out}
+	download := func(name, version string) dependencyDownload {
+		return dependencyDownload{
+			dep: &chart.Dependency{Name: name, Version: version, Repository: srv.URL},
+			url: fmt.Sprintf("%s/%s-%s.tgz", srv.URL, name, version),
+			downloader: ChartDownloader{
+				Out:              out,
+				Verify:           VerifyAlways,
+				Keyring:          "testdata/helm-test-key.pub",
+				Getters:          getter.All(&cli.EnvSettings{}),
+				RepositoryConfig: repoConfig,
+				RepositoryCache:  repoCache,
+			},
+		}
+	}
+
+	if err := m.downloadConcurrently([]dependencyDownload{download("signtest", "0.1.0")}, out, dest); err != nil {
+		t.Fatal(err)
+	}
+
+	// All the dependencies without provenance files are reported
+	downloads := []dependencyDownload{
+		download("signtest", "0.1.0"),
+		download("local-subchart", "0.1.0"),
+	}
+	missing := download("local-subchart", "0.1.0")
+	missing.dep = &chart.Dependency{Name: "other-subchart", Version: "0.1.0", Repository: srv.URL}
+	downloads = append(downloads, missing)
+
+	err := m.downloadConcurrently(downloads, out, dest)
+	var unverified ErrUnverifiedDependencies
+	if !errors.As(err, &unverified) {
+		t.Fatalf("expected an ErrUnverifiedDependencies, got %v", err)
+	}
+	if len(unverified.Dependencies) != 2 {
+		t.Fatalf("expected 2 unverified dependencies, got %d", len(unverified.Dependencies))
+	}
+	for _, name := range []string{"local-subchart (0.1.0)", "other-subchart (0.1.0)"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("expected the error to report %s, got %s", name, err)
+		}
+	}
+	if strings.Contains(err.Error(), "signtest") {
+		t.Errorf("expected the verified dependency not to be reported, got %s", err)
+	}
+}
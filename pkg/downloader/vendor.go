@@ -0,0 +1,198 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package downloader
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/internal/fileutil"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/provenance"
+)
+
+// VendorManifestFile is the manifest of the vendored dependencies of a
+// chart, in its vendor directory. It has the format of a lock file, the digest
+// being the digest of the lock file the dependencies were vendored from, and
+// every dependency having the checksum of its vendored archive.
+const VendorManifestFile = "vendor.lock"
+
+// Vendor builds the dependencies of the chart, then copies the archives of
+// the dependencies downloaded from repositories, OCI registries and git
+// repositories into the vendor directory of the chart, with a manifest of
+// their versions and checksums.
+//
+// The vendored dependencies are built without network access by a Manager
+// with Vendored set.
+func (m *Manager) Vendor() error {
+	if err := m.Build(); err != nil {
+		return err
+	}
+	c, err := m.loadChartDir()
+	if err != nil {
+		return err
+	}
+	if c.Lock == nil {
+		return nil
+	}
+
+	archives, err := m.dependencyArchives()
+	if err != nil {
+		return err
+	}
+
+	vendorPath := filepath.Join(m.ChartPath, loader.VendorDir)
+	if err := os.MkdirAll(vendorPath, 0755); err != nil {
+		return err
+	}
+	manifest := &chart.Lock{Generated: time.Now(), Digest: c.Lock.Digest}
+	vendored := make(map[string]bool)
+	for _, dep := range c.Lock.Dependencies {
+		if !isVendorable(dep) {
+			continue
+		}
+		src, ok := archives[dep.Name+"-"+dep.Version]
+		if !ok {
+			return errors.Errorf("no archive of dependency %q version %s found in the charts directory", dep.Name, dep.Version)
+		}
+		name := vendorArchive(dep)
+		if !vendored[name] {
+			f, err := os.Open(src)
+			if err != nil {
+				return err
+			}
+			err = fileutil.AtomicWriteFile(filepath.Join(vendorPath, name), f, 0644)
+			f.Close()
+			if err != nil {
+				return err
+			}
+			vendored[name] = true
+		}
+		sum, err := provenance.DigestFile(src)
+		if err != nil {
+			return err
+		}
+		d := *dep
+		d.Checksum = "sha256:" + sum
+		manifest.Dependencies = append(manifest.Dependencies, &d)
+		fmt.Fprintf(m.Out, "Vendored %s version %s\n", dep.Name, dep.Version)
+	}
+
+	// Delete the archives of the dependencies which are no longer vendored
+	files, err := os.ReadDir(vendorPath)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if !f.IsDir() && filepath.Ext(f.Name()) == ".tgz" && !vendored[f.Name()] {
+			if err := os.Remove(filepath.Join(vendorPath, f.Name())); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := yaml.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(vendorPath, VendorManifestFile), data, 0644)
+}
+
+// dependencyArchives returns the paths to the archives of the charts
+// directory, by chart name and version.
+func (m *Manager) dependencyArchives() (map[string]string, error) {
+	paths, err := filepath.Glob(filepath.Join(m.ChartPath, "charts", "*.tgz"))
+	if err != nil {
+		return nil, err
+	}
+	archives := make(map[string]string)
+	for _, path := range paths {
+		ch, err := loader.LoadFile(path)
+		if err != nil {
+			fmt.Fprintf(m.Out, "Could not load %s for vendoring: %s (Skipping)\n", path, err)
+			continue
+		}
+		archives[ch.Name()+"-"+ch.Metadata.Version] = path
+	}
+	return archives, nil
+}
+
+// loadVendorManifest loads the manifest of the vendored dependencies of the
+// chart.
+func (m *Manager) loadVendorManifest() (*chart.Lock, error) {
+	data, err := os.ReadFile(filepath.Join(m.ChartPath, loader.VendorDir, VendorManifestFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.New("the chart has no vendored dependencies. Please vendor them with 'helm dependency vendor'")
+		}
+		return nil, err
+	}
+	manifest := new(chart.Lock)
+	if err := yaml.Unmarshal(data, manifest); err != nil {
+		return nil, errors.Wrapf(err, "cannot load %s", VendorManifestFile)
+	}
+	return manifest, nil
+}
+
+// copyVendored copies the vendored archive of dep to dest, verifying it
+// against the checksums of the manifest and of the lock file.
+func (m *Manager) copyVendored(dep *chart.Dependency, manifest *chart.Lock, dest string) error {
+	var vendored *chart.Dependency
+	for _, d := range manifest.Dependencies {
+		if d.Name == dep.Name && versionEquals(d.Version, dep.Version) {
+			vendored = d
+			break
+		}
+	}
+	if vendored == nil {
+		return errors.Errorf("dependency %q version %s is not vendored. Please vendor it with 'helm dependency vendor'", dep.Name, dep.Version)
+	}
+
+	name := vendorArchive(vendored)
+	src := filepath.Join(m.ChartPath, loader.VendorDir, name)
+	if err := verifyChecksum(src, []*chart.Dependency{vendored, dep}); err != nil {
+		return err
+	}
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if m.Debug {
+		fmt.Fprintf(m.Out, "Copying %s from the vendor directory\n", dep.Name)
+	}
+	return fileutil.AtomicWriteFile(filepath.Join(dest, name), f, 0644)
+}
+
+// isVendorable returns whether dep is fetched from outside of the chart,
+// the dependencies in the charts directory and in local directories being
+// built without network access already.
+func isVendorable(dep *chart.Dependency) bool {
+	return dep.Repository != "" && !strings.HasPrefix(dep.Repository, "file://")
+}
+
+// vendorArchive returns the name of the vendored archive of dep.
+func vendorArchive(dep *chart.Dependency) string {
+	return fmt.Sprintf("%s-%s.tgz", dep.Name, dep.Version)
+}
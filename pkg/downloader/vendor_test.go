@@ -0,0 +1,107 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package downloader
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/repo/repotest"
+)
+
+func TestVendor(t *testing.T) {
+	// Set up a fake repo
+	srv, err := repotest.NewTempServerWithCleanup(t, "testdata/*.tgz*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+	if err := srv.LinkIndices(); err != nil {
+		t.Fatal(err)
+	}
+	dir := func(p ...string) string {
+		return filepath.Join(append([]string{srv.Root()}, p...)...)
+	}
+
+	c := &chart.Chart{
+		Metadata: &chart.Metadata{
+			Name:       "with-vendored",
+			Version:    "0.1.0",
+			APIVersion: "v2",
+			Dependencies: []*chart.Dependency{{
+				Name:       "local-subchart",
+				Version:    "0.1.0",
+				Repository: srv.URL(),
+			}},
+		},
+	}
+	if err := chartutil.SaveDir(c, dir()); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &Manager{
+		ChartPath:        dir(c.Metadata.Name),
+		Out:              new(bytes.Buffer),
+		Getters:          getter.All(&cli.EnvSettings{}),
+		RepositoryConfig: dir("repositories.yaml"),
+		RepositoryCache:  dir(),
+	}
+	if err := m.Vendor(); err != nil {
+		t.Fatal(err)
+	}
+	vendored := filepath.Join(m.ChartPath, loader.VendorDir, "local-subchart-0.1.0.tgz")
+	if _, err := os.Stat(vendored); err != nil {
+		t.Fatalf("expected the dependency to be vendored: %s", err)
+	}
+	manifest, err := m.loadVendorManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Dependencies) != 1 || !strings.HasPrefix(manifest.Dependencies[0].Checksum, "sha256:") {
+		t.Errorf("unexpected vendor manifest %+v", manifest.Dependencies)
+	}
+
+	// The vendored dependencies are built without the repository
+	srv.Stop()
+	if err := os.RemoveAll(filepath.Join(m.ChartPath, "charts")); err != nil {
+		t.Fatal(err)
+	}
+	m.Vendored = true
+	m.RepositoryConfig = dir("missing.yaml")
+	if err := m.Build(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(m.ChartPath, "charts", "local-subchart-0.1.0.tgz")); err != nil {
+		t.Errorf("expected the vendored dependency to be built: %s", err)
+	}
+
+	// Build fails if a vendored archive is tampered with
+	if err := os.WriteFile(vendored, []byte("tampered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Build(); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("expected a checksum mismatch, got %v", err)
+	}
+}
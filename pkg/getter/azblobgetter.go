@@ -0,0 +1,341 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// azureStorageVersion is the version of the Blob service REST API used.
+const azureStorageVersion = "2021-08-06"
+
+// azureStorageResource is the resource of the tokens of Azure Storage.
+const azureStorageResource = "https://storage.azure.com/"
+
+// azureAccount is a storage account and the credentials of its requests.
+type azureAccount struct {
+	name     string
+	endpoint string
+	// key is the decoded shared key of the account.
+	key []byte
+	// sas is a shared access signature, appended to the query of requests.
+	sas string
+}
+
+// AzureBlobGetter is the backend handler of the charts and the indices of
+// repositories stored in Azure Blob Storage containers, at URLs like
+// azblob://container/charts/index.yaml. The storage account is set by
+// AZURE_STORAGE_ACCOUNT, or by AZURE_STORAGE_CONNECTION_STRING.
+//
+// Requests are authorized with the ambient Azure credentials: the shared
+// access signature of AZURE_STORAGE_SAS_TOKEN or of the connection string, the
+// account key of AZURE_STORAGE_KEY or of the connection string, the client
+// secret or the workload identity of the AZURE_CLIENT_ID application of the
+// AZURE_TENANT_ID tenant, or the managed identity, in this order. Blobs of
+// public containers are fetched anonymously if there are none.
+type AzureBlobGetter struct {
+	opts options
+	auth azureAuth
+}
+
+// Get performs a Get from repo.Getter and returns the body.
+func (g *AzureBlobGetter) Get(href string, options ...Option) (*bytes.Buffer, error) {
+	for _, opt := range options {
+		opt(&g.opts)
+	}
+	return g.get(href)
+}
+
+func (g *AzureBlobGetter) get(href string) (*bytes.Buffer, error) {
+	container, blob, err := splitObjectURL(href)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.auth.init(); err != nil {
+		return nil, err
+	}
+	client, err := objectHTTPClient(g.opts)
+	if err != nil {
+		return nil, err
+	}
+	return getObject(client, g.auth.url(container, escapeObjectKey(blob), ""), g.auth.sign)
+}
+
+// NewAzureBlobGetter constructs a Getter of the blobs of Azure Blob Storage
+// containers.
+func NewAzureBlobGetter(options ...Option) (Getter, error) {
+	var client AzureBlobGetter
+
+	for _, opt := range options {
+		opt(&client.opts)
+	}
+
+	return &client, nil
+}
+
+// azureAuth authorizes the requests of Azure Blob Storage with the ambient
+// credentials of the storage account, looked up by the first request.
+type azureAuth struct {
+	once    sync.Once
+	account *azureAccount
+	// token is the bearer token found by the first request, if the account
+	// has no shared key nor signature.
+	token string
+	err   error
+}
+
+// init looks up the storage account and its credentials, once.
+func (a *azureAuth) init() error {
+	a.once.Do(func() {
+		a.account, a.err = lookupAzureAccount()
+		if a.err == nil && a.account.key == nil && a.account.sas == "" {
+			a.token, a.err = lookupAzureToken()
+		}
+	})
+	return a.err
+}
+
+// url returns the URL of the escaped path of the container of the account,
+// with the query and the shared access signature of the account.
+func (a *azureAuth) url(container, path, query string) string {
+	u := a.account.endpoint + "/" + url.PathEscape(container)
+	if path != "" {
+		u += "/" + path
+	}
+	if a.account.sas != "" {
+		if query != "" {
+			query += "&"
+		}
+		query += strings.TrimPrefix(a.account.sas, "?")
+	}
+	if query != "" {
+		u += "?" + query
+	}
+	return u
+}
+
+func (a *azureAuth) sign(req *http.Request) error {
+	req.Header.Set("X-Ms-Version", azureStorageVersion)
+	req.Header.Set("X-Ms-Date", time.Now().UTC().Format(http.TimeFormat))
+	switch {
+	case a.account.sas != "":
+	case a.account.key != nil:
+		signAzureRequest(req, a.account)
+	case a.token != "":
+		req.Header.Set("Authorization", "Bearer "+a.token)
+	}
+	return nil
+}
+
+// lookupAzureAccount returns the storage account of the environment.
+func lookupAzureAccount() (*azureAccount, error) {
+	account := &azureAccount{
+		name: os.Getenv("AZURE_STORAGE_ACCOUNT"),
+		sas:  os.Getenv("AZURE_STORAGE_SAS_TOKEN"),
+	}
+	key := os.Getenv("AZURE_STORAGE_KEY")
+
+	if cs := os.Getenv("AZURE_STORAGE_CONNECTION_STRING"); cs != "" {
+		protocol, suffix := "https", "core.windows.net"
+		for _, part := range strings.Split(cs, ";") {
+			k, v, ok := strings.Cut(part, "=")
+			if !ok {
+				continue
+			}
+			switch strings.TrimSpace(k) {
+			case "AccountName":
+				account.name = v
+			case "AccountKey":
+				key = v
+			case "SharedAccessSignature":
+				account.sas = v
+			case "BlobEndpoint":
+				account.endpoint = strings.TrimSuffix(v, "/")
+			case "DefaultEndpointsProtocol":
+				protocol = v
+			case "EndpointSuffix":
+				suffix = v
+			}
+		}
+		if account.endpoint == "" && account.name != "" {
+			account.endpoint = fmt.Sprintf("%s://%s.blob.%s", protocol, account.name, suffix)
+		}
+	}
+
+	if account.name == "" {
+		return nil, errors.New("the storage account of Azure Blob Storage is not set, set AZURE_STORAGE_ACCOUNT or AZURE_STORAGE_CONNECTION_STRING")
+	}
+	if account.endpoint == "" {
+		account.endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", account.name)
+	}
+	if key != "" && account.sas == "" {
+		decoded, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid key of the storage account")
+		}
+		account.key = decoded
+	}
+	return account, nil
+}
+
+// lookupAzureToken returns a token of Azure Storage of the ambient
+// credentials, or an empty token if there are none.
+func lookupAzureToken() (string, error) {
+	tenant, clientID := os.Getenv("AZURE_TENANT_ID"), os.Getenv("AZURE_CLIENT_ID")
+	secret, tokenFile := os.Getenv("AZURE_CLIENT_SECRET"), os.Getenv("AZURE_FEDERATED_TOKEN_FILE")
+	if tenant != "" && clientID != "" && (secret != "" || tokenFile != "") {
+		token, err := azureClientToken(tenant, clientID, secret, tokenFile)
+		return token, errors.Wrapf(err, "failed to get a token of the application %s", clientID)
+	}
+
+	// The requests are anonymous without a managed identity.
+	token, _ := azureManagedIdentityToken(clientID)
+	return token, nil
+}
+
+// azureClientToken returns a token of the application clientID, holding the
+// client secret or the federated token of tokenFile.
+func azureClientToken(tenant, clientID, secret, tokenFile string) (string, error) {
+	authority := os.Getenv("AZURE_AUTHORITY_HOST")
+	if authority == "" {
+		authority = "https://login.microsoftonline.com/"
+	}
+	form := url.Values{
+		"grant_type": {"client_credentials"},
+		"client_id":  {clientID},
+		"scope":      {azureStorageResource + ".default"},
+	}
+	if secret != "" {
+		form.Set("client_secret", secret)
+	} else {
+		assertion, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return "", err
+		}
+		form.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+		form.Set("client_assertion", strings.TrimSpace(string(assertion)))
+	}
+
+	req, err := postForm(strings.TrimSuffix(authority, "/")+"/"+url.PathEscape(tenant)+"/oauth2/v2.0/token", form)
+	if err != nil {
+		return "", err
+	}
+	var token oauthToken
+	if err := fetchCredentialJSON(credentialClient, req, &token); err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
+
+// azureManagedIdentityToken returns a token of the managed identity, of the
+// App Service or of the virtual machine. clientID selects a user assigned
+// identity if it is set.
+func azureManagedIdentityToken(clientID string) (string, error) {
+	query := url.Values{"resource": {azureStorageResource}}
+	if clientID != "" {
+		query.Set("client_id", clientID)
+	}
+
+	var req *http.Request
+	var err error
+	if endpoint := os.Getenv("IDENTITY_ENDPOINT"); endpoint != "" {
+		query.Set("api-version", "2019-08-01")
+		if req, err = http.NewRequest(http.MethodGet, endpoint+"?"+query.Encode(), nil); err != nil {
+			return "", err
+		}
+		req.Header.Set("X-Identity-Header", os.Getenv("IDENTITY_HEADER"))
+	} else {
+		query.Set("api-version", "2018-02-01")
+		if req, err = http.NewRequest(http.MethodGet, "http://169.254.169.254/metadata/identity/oauth2/token?"+query.Encode(), nil); err != nil {
+			return "", err
+		}
+		req.Header.Set("Metadata", "true")
+	}
+
+	var token oauthToken
+	if err := fetchCredentialJSON(metadataClient(), req, &token); err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
+
+// signAzureRequest signs req with the shared key of account.
+func signAzureRequest(req *http.Request, account *azureAccount) {
+	var canonicalHeaders []string
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "x-ms-") {
+			canonicalHeaders = append(canonicalHeaders, name+":"+strings.TrimSpace(strings.Join(values, ",")))
+		}
+	}
+	sort.Strings(canonicalHeaders)
+
+	// The resource is the path of the blob in the account, followed by the
+	// parameters of the query.
+	resource := "/" + account.name + req.URL.EscapedPath()
+	query := req.URL.Query()
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		vs := append([]string{}, query[k]...)
+		sort.Strings(vs)
+		resource += "\n" + strings.ToLower(k) + ":" + strings.Join(vs, ",")
+	}
+
+	// The length of the empty bodies is empty.
+	contentLength := ""
+	if req.ContentLength > 0 {
+		contentLength = strconv.FormatInt(req.ContentLength, 10)
+	}
+	stringToSign := strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		contentLength,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		"", // Date, the x-ms-date header is set
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+		strings.Join(canonicalHeaders, "\n"),
+		resource,
+	}, "\n")
+
+	h := hmac.New(sha256.New, account.key)
+	h.Write([]byte(stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("SharedKey %s:%s", account.name, base64.StdEncoding.EncodeToString(h.Sum(nil))))
+}
@@ -0,0 +1,121 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// clearAzureEnv isolates the tests from the Azure credentials of the
+// environment.
+func clearAzureEnv(t *testing.T) {
+	for _, env := range []string{
+		"AZURE_STORAGE_ACCOUNT", "AZURE_STORAGE_KEY", "AZURE_STORAGE_SAS_TOKEN", "AZURE_STORAGE_CONNECTION_STRING",
+		"AZURE_TENANT_ID", "AZURE_CLIENT_ID", "AZURE_CLIENT_SECRET", "AZURE_FEDERATED_TOKEN_FILE", "AZURE_AUTHORITY_HOST",
+		"IDENTITY_HEADER",
+	} {
+		t.Setenv(env, "")
+	}
+	// The managed identity endpoint cannot be reached
+	t.Setenv("IDENTITY_ENDPOINT", "http://127.0.0.1:1/token")
+}
+
+func TestAzureBlobGetter(t *testing.T) {
+	clearAzureEnv(t)
+	srv := newObjectServer(t, map[string]string{"/devstoreaccount1/charts/stable/index.yaml": "apiVersion: v1\n"})
+	key := base64.StdEncoding.EncodeToString([]byte("account-key"))
+
+	get := func(href string) (string, error) {
+		g, err := NewAzureBlobGetter()
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf, err := g.Get(href)
+		if err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+
+	if _, err := get("azblob://charts/stable/index.yaml"); err == nil || !strings.Contains(err.Error(), "AZURE_STORAGE_ACCOUNT") {
+		t.Errorf("expected an error without a storage account, got %v", err)
+	}
+
+	// Shared key of a connection string
+	t.Setenv("AZURE_STORAGE_CONNECTION_STRING", "DefaultEndpointsProtocol=http;AccountName=devstoreaccount1;AccountKey="+key+";BlobEndpoint="+srv.URL+"/devstoreaccount1;")
+	data, err := get("azblob://charts/stable/index.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data != "apiVersion: v1\n" {
+		t.Errorf("unexpected blob %q", data)
+	}
+	req := srv.lastRequest()
+	if auth := req.Header.Get("Authorization"); !strings.HasPrefix(auth, "SharedKey devstoreaccount1:") {
+		t.Errorf("unexpected authorization %q", auth)
+	}
+	if req.Header.Get("X-Ms-Version") != azureStorageVersion || req.Header.Get("X-Ms-Date") == "" {
+		t.Errorf("unexpected headers %v", req.Header)
+	}
+
+	// Shared access signature
+	t.Setenv("AZURE_STORAGE_SAS_TOKEN", "?sv=2021-08-06&sig=signature")
+	if _, err := get("azblob://charts/stable/index.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	req = srv.lastRequest()
+	if req.Header.Get("Authorization") != "" || req.URL.Query().Get("sig") != "signature" {
+		t.Errorf("expected a request with the shared access signature, got %s %v", req.URL, req.Header)
+	}
+
+	if _, err := get("azblob://charts/stable/missing.tgz"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+func TestAzureBlobGetterManagedIdentity(t *testing.T) {
+	clearAzureEnv(t)
+	identity := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Identity-Header") != "secret" || r.URL.Query().Get("resource") != azureStorageResource {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "managed-identity"})
+	}))
+	defer identity.Close()
+	srv := newObjectServer(t, map[string]string{"/helm/charts/mychart-0.1.0.tgz": "chart"})
+
+	t.Setenv("AZURE_STORAGE_CONNECTION_STRING", "AccountName=helm;BlobEndpoint="+srv.URL+"/helm")
+	t.Setenv("IDENTITY_ENDPOINT", identity.URL)
+	t.Setenv("IDENTITY_HEADER", "secret")
+
+	g, err := NewAzureBlobGetter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Get("azblob://charts/mychart-0.1.0.tgz"); err != nil {
+		t.Fatal(err)
+	}
+	if auth := srv.lastRequest().Header.Get("Authorization"); auth != "Bearer managed-identity" {
+		t.Errorf("unexpected authorization %q", auth)
+	}
+}
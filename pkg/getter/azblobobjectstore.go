@@ -0,0 +1,121 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+// AzureBlobObjectStore is the object store of the object storage driver of
+// releases keeping the releases in an Azure Blob Storage container. Its
+// requests are authorized with the storage account and the credentials of
+// AzureBlobGetter.
+//
+// The writes are conditional on the ETags of the blobs, with the If-Match and
+// If-None-Match headers.
+type AzureBlobObjectStore struct {
+	container string
+	client    *http.Client
+	auth      azureAuth
+}
+
+// NewAzureBlobObjectStore returns an object store keeping the objects in
+// container.
+func NewAzureBlobObjectStore(container string, opts ...Option) (*AzureBlobObjectStore, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	client, err := objectHTTPClient(o)
+	if err != nil {
+		return nil, err
+	}
+	return &AzureBlobObjectStore{container: container, client: client}, nil
+}
+
+// Get returns the content and the ETag of the named blob.
+func (s *AzureBlobObjectStore) Get(ctx context.Context, name string) ([]byte, string, error) {
+	header, content, err := s.request(ctx, http.MethodGet, escapeObjectKey(name), "", nil, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	return content, header.Get("ETag"), nil
+}
+
+// Put writes the named blob if its ETag is ifMatch, or if it does not exist
+// when ifMatch is empty.
+func (s *AzureBlobObjectStore) Put(ctx context.Context, name string, data []byte, ifMatch string) (string, error) {
+	header := conditionHeader(ifMatch)
+	header.Set("X-Ms-Blob-Type", "BlockBlob")
+	header, _, err := s.request(ctx, http.MethodPut, escapeObjectKey(name), "", header, data)
+	if err != nil {
+		return "", err
+	}
+	return header.Get("ETag"), nil
+}
+
+// Delete deletes the named blob if its ETag is ifMatch.
+func (s *AzureBlobObjectStore) Delete(ctx context.Context, name, ifMatch string) error {
+	_, _, err := s.request(ctx, http.MethodDelete, escapeObjectKey(name), "", conditionHeader(ifMatch), nil)
+	return err
+}
+
+// List returns the names of the blobs starting with prefix.
+func (s *AzureBlobObjectStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var names []string
+	marker := ""
+	for {
+		query := url.Values{"restype": {"container"}, "comp": {"list"}, "prefix": {prefix}}
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+		_, content, err := s.request(ctx, http.MethodGet, "", query.Encode(), nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Blobs []struct {
+				Name string `xml:"Name"`
+			} `xml:"Blobs>Blob"`
+			NextMarker string `xml:"NextMarker"`
+		}
+		if err := xml.Unmarshal(content, &result); err != nil {
+			return nil, errors.Wrap(err, "invalid list of blobs")
+		}
+		for _, b := range result.Blobs {
+			names = append(names, b.Name)
+		}
+		if result.NextMarker == "" {
+			return names, nil
+		}
+		marker = result.NextMarker
+	}
+}
+
+// request sends a request of the escaped path of the container, with the
+// query.
+func (s *AzureBlobObjectStore) request(ctx context.Context, method, path, query string, header http.Header, data []byte) (http.Header, []byte, error) {
+	if err := s.auth.init(); err != nil {
+		return nil, nil, err
+	}
+	return objectRequest(ctx, s.client, method, s.auth.url(s.container, path, query), header, data, s.auth.sign)
+}
@@ -0,0 +1,294 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The OAuth scopes of the tokens reading, and writing, GCS objects.
+const (
+	gcsReadOnlyScope  = "https://www.googleapis.com/auth/devstorage.read_only"
+	gcsReadWriteScope = "https://www.googleapis.com/auth/devstorage.read_write"
+)
+
+// googleCredentials is a file of Google application default credentials.
+type googleCredentials struct {
+	Type string `json:"type"`
+	// service_account
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+	// authorized_user
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// GCSGetter is the backend handler of the charts and the indices of
+// repositories stored in Google Cloud Storage buckets, at URLs like
+// gs://bucket/charts/index.yaml.
+//
+// Requests are authorized with the ambient Google credentials: the access
+// token of CLOUDSDK_AUTH_ACCESS_TOKEN, the application default credentials of
+// GOOGLE_APPLICATION_CREDENTIALS or of 'gcloud auth application-default
+// login', or the service account of the metadata server of Google Cloud, in
+// this order. Objects of public buckets are fetched anonymously if there are
+// none. STORAGE_EMULATOR_HOST sets the host of a storage emulator.
+type GCSGetter struct {
+	opts options
+	auth googleAuth
+}
+
+// Get performs a Get from repo.Getter and returns the body.
+func (g *GCSGetter) Get(href string, options ...Option) (*bytes.Buffer, error) {
+	for _, opt := range options {
+		opt(&g.opts)
+	}
+	return g.get(href)
+}
+
+func (g *GCSGetter) get(href string) (*bytes.Buffer, error) {
+	bucket, object, err := splitObjectURL(href)
+	if err != nil {
+		return nil, err
+	}
+	client, err := objectHTTPClient(g.opts)
+	if err != nil {
+		return nil, err
+	}
+	return getObject(client, gcsObjectURL(bucket, object), g.auth.sign)
+}
+
+// NewGCSGetter constructs a Getter of the objects of GCS buckets.
+func NewGCSGetter(options ...Option) (Getter, error) {
+	client := GCSGetter{auth: googleAuth{scope: gcsReadOnlyScope}}
+
+	for _, opt := range options {
+		opt(&client.opts)
+	}
+
+	return &client, nil
+}
+
+// googleAuth authorizes the requests of GCS with an access token of the
+// ambient credentials, looked up by the first request.
+type googleAuth struct {
+	// scope is the OAuth scope of the tokens of the service accounts.
+	scope string
+	once  sync.Once
+	// token is the access token found by the first request, empty if the
+	// requests are anonymous.
+	token string
+	err   error
+}
+
+func (a *googleAuth) sign(req *http.Request) error {
+	a.once.Do(func() {
+		a.token, a.err = lookupGoogleToken(a.scope)
+	})
+	if a.err != nil {
+		return a.err
+	}
+	if a.token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.token)
+	}
+	return nil
+}
+
+// gcsBucketURL returns the URL of bucket, with the XML API.
+func gcsBucketURL(bucket string) string {
+	endpoint := "https://storage.googleapis.com"
+	if host := os.Getenv("STORAGE_EMULATOR_HOST"); host != "" {
+		endpoint = host
+		if !strings.Contains(host, "://") {
+			endpoint = "http://" + host
+		}
+	}
+	return fmt.Sprintf("%s/%s", strings.TrimSuffix(endpoint, "/"), url.PathEscape(bucket))
+}
+
+// gcsObjectURL returns the URL of object in bucket, with the XML API.
+func gcsObjectURL(bucket, object string) string {
+	return gcsBucketURL(bucket) + "/" + escapeObjectKey(object)
+}
+
+// lookupGoogleToken returns an access token of the ambient credentials, of
+// scope for the service accounts, or an empty token if there are none.
+func lookupGoogleToken(scope string) (string, error) {
+	for _, env := range []string{"CLOUDSDK_AUTH_ACCESS_TOKEN", "GOOGLE_OAUTH_ACCESS_TOKEN"} {
+		if token := os.Getenv(env); token != "" {
+			return token, nil
+		}
+	}
+
+	path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if path == "" {
+		path = googleWellKnownCredentials()
+		if _, err := os.Stat(path); err != nil {
+			path = ""
+		}
+	}
+	if path != "" {
+		token, err := googleCredentialsToken(path, scope)
+		return token, errors.Wrapf(err, "failed to get an access token of the credentials of %s", path)
+	}
+
+	// The requests are anonymous outside of Google Cloud.
+	token, _ := googleMetadataToken()
+	return token, nil
+}
+
+// googleWellKnownCredentials returns the path of the application default
+// credentials written by gcloud.
+func googleWellKnownCredentials() string {
+	if dir := os.Getenv("CLOUDSDK_CONFIG"); dir != "" {
+		return filepath.Join(dir, "application_default_credentials.json")
+	}
+	if appData := os.Getenv("APPDATA"); appData != "" {
+		return filepath.Join(appData, "gcloud", "application_default_credentials.json")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "gcloud", "application_default_credentials.json")
+}
+
+// googleCredentialsToken exchanges the credentials of the file at path for
+// an access token, of scope if they are the credentials of a service account.
+func googleCredentialsToken(path, scope string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var creds googleCredentials
+	if err := json.Unmarshal(b, &creds); err != nil {
+		return "", err
+	}
+
+	var form url.Values
+	endpoint := "https://oauth2.googleapis.com/token"
+	switch creds.Type {
+	case "service_account":
+		if creds.TokenURI != "" {
+			endpoint = creds.TokenURI
+		}
+		assertion, err := googleJWTAssertion(&creds, endpoint, scope, time.Now())
+		if err != nil {
+			return "", err
+		}
+		form = url.Values{
+			"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+			"assertion":  {assertion},
+		}
+	case "authorized_user":
+		form = url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {creds.ClientID},
+			"client_secret": {creds.ClientSecret},
+			"refresh_token": {creds.RefreshToken},
+		}
+	default:
+		return "", errors.Errorf("unsupported credentials type %q", creds.Type)
+	}
+
+	req, err := postForm(endpoint, form)
+	if err != nil {
+		return "", err
+	}
+	var token oauthToken
+	if err := fetchCredentialJSON(credentialClient, req, &token); err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
+
+// googleJWTAssertion returns the JWT a service account is authenticated with
+// at the token endpoint aud, for a token of scope.
+func googleJWTAssertion(creds *googleCredentials, aud, scope string, now time.Time) (string, error) {
+	block, _ := pem.Decode([]byte(creds.PrivateKey))
+	if block == nil {
+		return "", errors.New("invalid private key of the service account")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		if parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return "", errors.Wrap(err, "invalid private key of the service account")
+		}
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", errors.New("the private key of the service account is not an RSA key")
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   creds.ClientEmail,
+		"scope": scope,
+		"aud":   aud,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// googleMetadataToken returns an access token of the service account of the
+// metadata server.
+func googleMetadataToken() (string, error) {
+	host := os.Getenv("GCE_METADATA_HOST")
+	if host == "" {
+		host = "metadata.google.internal"
+	}
+	req, err := http.NewRequest(http.MethodGet, "http://"+host+"/computeMetadata/v1/instance/service-accounts/default/token", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	var token oauthToken
+	if err := fetchCredentialJSON(metadataClient(), req, &token); err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
@@ -0,0 +1,163 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// clearGoogleEnv isolates the tests from the Google credentials of the
+// environment.
+func clearGoogleEnv(t *testing.T) {
+	t.Setenv("CLOUDSDK_AUTH_ACCESS_TOKEN", "")
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "")
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", "")
+	t.Setenv("CLOUDSDK_CONFIG", t.TempDir())
+	// The metadata server cannot be reached
+	t.Setenv("GCE_METADATA_HOST", "127.0.0.1:1")
+}
+
+func TestGCSGetter(t *testing.T) {
+	clearGoogleEnv(t)
+	srv := newObjectServer(t, map[string]string{"/charts/stable/index.yaml": "apiVersion: v1\n"})
+	t.Setenv("STORAGE_EMULATOR_HOST", strings.TrimPrefix(srv.URL, "http://"))
+
+	get := func(href string) (string, error) {
+		g, err := NewGCSGetter()
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf, err := g.Get(href)
+		if err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+
+	data, err := get("gs://charts/stable/index.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data != "apiVersion: v1\n" {
+		t.Errorf("unexpected object %q", data)
+	}
+	if auth := srv.lastRequest().Header.Get("Authorization"); auth != "" {
+		t.Errorf("expected an anonymous request, got %q", auth)
+	}
+
+	t.Setenv("CLOUDSDK_AUTH_ACCESS_TOKEN", "ya29.token")
+	if _, err := get("gs://charts/stable/index.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if auth := srv.lastRequest().Header.Get("Authorization"); auth != "Bearer ya29.token" {
+		t.Errorf("unexpected authorization %q", auth)
+	}
+
+	if _, err := get("gs://charts/stable/missing.tgz"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+func TestGCSGetterServiceAccount(t *testing.T) {
+	clearGoogleEnv(t)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		parts := strings.Split(r.Form.Get("assertion"), ".")
+		if r.Form.Get("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" || len(parts) != 3 {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hash[:], sig); err != nil {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		claims, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		var c map[string]interface{}
+		if err := json.Unmarshal(claims, &c); err != nil || c["iss"] != "helm@project.iam.gserviceaccount.com" || c["scope"] != gcsReadOnlyScope {
+			http.Error(w, "invalid claims", http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "ya29.service-account"})
+	}))
+	defer tokens.Close()
+	srv := newObjectServer(t, map[string]string{"/charts/mychart-0.1.0.tgz": "chart"})
+	t.Setenv("STORAGE_EMULATOR_HOST", srv.URL)
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds, err := json.Marshal(googleCredentials{
+		Type:        "service_account",
+		ClientEmail: "helm@project.iam.gserviceaccount.com",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		TokenURI:    tokens.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "credentials.json")
+	if err := os.WriteFile(file, creds, 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", file)
+
+	g, err := NewGCSGetter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Get("gs://charts/mychart-0.1.0.tgz"); err != nil {
+		t.Fatal(err)
+	}
+	if auth := srv.lastRequest().Header.Get("Authorization"); auth != "Bearer ya29.service-account" {
+		t.Errorf("unexpected authorization %q", auth)
+	}
+
+	// Invalid credentials are not ignored
+	if err := os.WriteFile(file, []byte(`{"type": "external_account"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	g, err = NewGCSGetter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Get("gs://charts/mychart-0.1.0.tgz"); err == nil || !strings.Contains(err.Error(), "unsupported credentials type") {
+		t.Errorf("expected an unsupported credentials error, got %v", err)
+	}
+}
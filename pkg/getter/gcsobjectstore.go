@@ -0,0 +1,88 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"context"
+	"net/http"
+)
+
+// GCSObjectStore is the object store of the object storage driver of releases
+// keeping the releases in a Google Cloud Storage bucket. Its requests are
+// authorized with the credentials of GCSGetter, with the read and write scope
+// for the service accounts.
+//
+// The ETags of the objects are their generations: the writes are conditional
+// with the x-goog-if-generation-match header, the preconditions of GCS
+// equivalent to If-Match and If-None-Match.
+type GCSObjectStore struct {
+	bucket string
+	client *http.Client
+	auth   googleAuth
+}
+
+// NewGCSObjectStore returns an object store keeping the objects in bucket.
+func NewGCSObjectStore(bucket string, opts ...Option) (*GCSObjectStore, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	client, err := objectHTTPClient(o)
+	if err != nil {
+		return nil, err
+	}
+	return &GCSObjectStore{bucket: bucket, client: client, auth: googleAuth{scope: gcsReadWriteScope}}, nil
+}
+
+// Get returns the content and the generation of the named object.
+func (s *GCSObjectStore) Get(ctx context.Context, name string) ([]byte, string, error) {
+	header, content, err := objectRequest(ctx, s.client, http.MethodGet, gcsObjectURL(s.bucket, name), nil, nil, s.auth.sign)
+	if err != nil {
+		return nil, "", err
+	}
+	return content, header.Get("X-Goog-Generation"), nil
+}
+
+// Put writes the named object if its generation is ifMatch, or if it does not
+// exist when ifMatch is empty.
+func (s *GCSObjectStore) Put(ctx context.Context, name string, data []byte, ifMatch string) (string, error) {
+	// The generation 0 is the generation of the objects that do not exist.
+	if ifMatch == "" {
+		ifMatch = "0"
+	}
+	header := http.Header{"X-Goog-If-Generation-Match": {ifMatch}}
+	header, _, err := objectRequest(ctx, s.client, http.MethodPut, gcsObjectURL(s.bucket, name), header, data, s.auth.sign)
+	if err != nil {
+		return "", err
+	}
+	return header.Get("X-Goog-Generation"), nil
+}
+
+// Delete deletes the named object if its generation is ifMatch.
+func (s *GCSObjectStore) Delete(ctx context.Context, name, ifMatch string) error {
+	header := http.Header{"X-Goog-If-Generation-Match": {ifMatch}}
+	_, _, err := objectRequest(ctx, s.client, http.MethodDelete, gcsObjectURL(s.bucket, name), header, nil, s.auth.sign)
+	return err
+}
+
+// List returns the names of the objects starting with prefix.
+func (s *GCSObjectStore) List(ctx context.Context, prefix string) ([]string, error) {
+	return listBucket(prefix, func(query string) ([]byte, error) {
+		_, content, err := objectRequest(ctx, s.client, http.MethodGet, gcsBucketURL(s.bucket)+"?"+query, nil, nil, s.auth.sign)
+		return content, err
+	})
+}
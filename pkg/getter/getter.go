@@ -18,12 +18,17 @@ package getter
 
 import (
 	"bytes"
+	"fmt"
 	"net/http"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
 
 	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/plugin/credentials"
 	"helm.sh/helm/v3/pkg/registry"
 )
 
@@ -42,10 +47,35 @@ type options struct {
 	password              string
 	passCredentialsAll    bool
 	userAgent             string
+	headers               map[string]string
+	proxy                 string
+	noProxy               string
+	accept                string
+	acceptEncoding        string
+	validators            *CacheValidators
 	version               string
+	digest                string
 	registryClient        *registry.Client
+	credentialProvider    registry.CredentialProvider
 	timeout               time.Duration
+	retries               int
+	retryBackoff          time.Duration
 	transport             *http.Transport
+	restClientGetter      genericclioptions.RESTClientGetter
+}
+
+// tlsKey identifies the TLS configuration of the options, with the
+// modification times and sizes of its files, so the getters renew their
+// connections when the certificates are rotated.
+func (o *options) tlsKey() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s|%s|%s|%t|%s", o.certFile, o.keyFile, o.caFile, o.insecureSkipVerifyTLS, o.url)
+	for _, f := range []string{o.certFile, o.keyFile, o.caFile} {
+		if fi, err := os.Stat(f); f != "" && err == nil {
+			fmt.Fprintf(&b, "|%d|%d", fi.ModTime().UnixNano(), fi.Size())
+		}
+	}
+	return b.String()
 }
 
 // Option allows specifying various settings configurable by the user for overriding the defaults
@@ -81,6 +111,57 @@ func WithUserAgent(userAgent string) Option {
 	}
 }
 
+// WithHeaders sets static headers of the requests, like the API tokens of
+// private repositories. Like basic auth credentials, they are only sent to the
+// host of the URL of WithURL, unless WithPassCredentialsAll is set.
+func WithHeaders(headers map[string]string) Option {
+	return func(opts *options) {
+		opts.headers = headers
+	}
+}
+
+// WithProxy sets the proxy of the requests, overriding the HTTP_PROXY and
+// HTTPS_PROXY environment variables. noProxy is a comma-separated list of
+// hosts the requests of which do not go through the proxy, like NO_PROXY. The
+// environment variables are used if proxy is empty.
+func WithProxy(proxy, noProxy string) Option {
+	return func(opts *options) {
+		opts.proxy = proxy
+		opts.noProxy = noProxy
+	}
+}
+
+// WithAcceptHeaders sets the request's Accept and Accept-Encoding headers,
+// negotiating the format of the content with the server. The getter returns
+// the content as it is sent, it is not decoded.
+func WithAcceptHeaders(accept, acceptEncoding string) Option {
+	return func(opts *options) {
+		opts.accept = accept
+		opts.acceptEncoding = acceptEncoding
+	}
+}
+
+// ErrNotModified is returned by the getters when the content is unchanged
+// since it was fetched with the validators of WithCacheValidators.
+var ErrNotModified = errors.New("not modified")
+
+// CacheValidators are the validators of a cached copy of the content of a URL,
+// the ETag and Last-Modified headers of the response that fetched it.
+type CacheValidators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// WithCacheValidators makes the request conditional on the content being
+// modified since it was fetched with the validators v, the getter returning
+// ErrNotModified otherwise. v is updated with the validators of the fetched
+// content. Conditional requests are not made if v is nil.
+func WithCacheValidators(v *CacheValidators) Option {
+	return func(opts *options) {
+		opts.validators = v
+	}
+}
+
 // WithInsecureSkipVerifyTLS determines if a TLS Certificate will be checked
 func WithInsecureSkipVerifyTLS(insecureSkipVerifyTLS bool) Option {
 	return func(opts *options) {
@@ -110,12 +191,30 @@ func WithTimeout(timeout time.Duration) Option {
 	}
 }
 
+// WithRetries sets the number of times the HTTP getter retries a download
+// failing with a network error or a transient server error, waiting backoff
+// before the first retry and doubling the wait for every retry. Downloads are
+// resumed from the received content if the server supports ranges.
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(opts *options) {
+		opts.retries = retries
+		opts.retryBackoff = backoff
+	}
+}
+
 func WithTagName(tagname string) Option {
 	return func(opts *options) {
 		opts.version = tagname
 	}
 }
 
+// WithDigest sets the digest the manifest of an OCI chart must have
+func WithDigest(digest string) Option {
+	return func(opts *options) {
+		opts.digest = digest
+	}
+}
+
 func WithRegistryClient(client *registry.Client) Option {
 	return func(opts *options) {
 		opts.registryClient = client
@@ -128,6 +227,14 @@ func WithUntar() Option {
 	}
 }
 
+// WithCredentialProvider sets the provider of the credentials of the hosts
+// the HTTPGetter gets from without basic auth credentials.
+func WithCredentialProvider(provider registry.CredentialProvider) Option {
+	return func(opts *options) {
+		opts.credentialProvider = provider
+	}
+}
+
 // WithTransport sets the http.Transport to allow overwriting the HTTPGetter default.
 func WithTransport(transport *http.Transport) Option {
 	return func(opts *options) {
@@ -135,6 +242,14 @@ func WithTransport(transport *http.Transport) Option {
 	}
 }
 
+// WithRESTClientGetter sets the kubeconfig the KubeGetter gets the objects of
+// the cluster with.
+func WithRESTClientGetter(getter genericclioptions.RESTClientGetter) Option {
+	return func(opts *options) {
+		opts.restClientGetter = getter
+	}
+}
+
 // Getter is an interface to support GET to the specified URL.
 type Getter interface {
 	// Get file content by url string
@@ -184,9 +299,18 @@ const (
 	// https://github.com/curl/curl/blob/master/lib/connect.h#L40C21-L40C21
 	// The helm commands are usually executed manually. Considering the acceptable waiting time, we reduced the entire request time to 120s.
 	DefaultHTTPTimeout = 120
+
+	// DefaultRetries is the number of times the downloads of the getters of
+	// All are retried.
+	DefaultRetries = 3
+	// DefaultRetryBackoff is the wait before the first retry of a download.
+	DefaultRetryBackoff = 500 * time.Millisecond
 )
 
-var defaultOptions = []Option{WithTimeout(time.Second * DefaultHTTPTimeout)}
+var defaultOptions = []Option{
+	WithTimeout(time.Second * DefaultHTTPTimeout),
+	WithRetries(DefaultRetries, DefaultRetryBackoff),
+}
 
 var httpProvider = Provider{
 	Schemes: []string{"http", "https"},
@@ -201,12 +325,67 @@ var ociProvider = Provider{
 	New:     NewOCIGetter,
 }
 
+var s3Provider = Provider{
+	Schemes: []string{"s3"},
+	New: func(options ...Option) (Getter, error) {
+		options = append(options, defaultOptions...)
+		return NewS3Getter(options...)
+	},
+}
+
+var gcsProvider = Provider{
+	Schemes: []string{"gs"},
+	New: func(options ...Option) (Getter, error) {
+		options = append(options, defaultOptions...)
+		return NewGCSGetter(options...)
+	},
+}
+
+var azblobProvider = Provider{
+	Schemes: []string{"azblob"},
+	New: func(options ...Option) (Getter, error) {
+		options = append(options, defaultOptions...)
+		return NewAzureBlobGetter(options...)
+	},
+}
+
+var gitProvider = Provider{
+	Schemes: gitSchemes,
+	New:     NewGitGetter,
+}
+
+// kubeProvider returns the provider of the getters of the objects of the
+// cluster of the settings.
+func kubeProvider(settings *cli.EnvSettings) Provider {
+	return Provider{
+		Schemes: []string{ConfigMapScheme, SecretScheme},
+		New: func(options ...Option) (Getter, error) {
+			return NewKubeGetter(append([]Option{WithRESTClientGetter(settings.RESTClientGetter())}, options...)...)
+		},
+	}
+}
+
 // All finds all of the registered getters as a list of Provider instances.
 // Currently, the built-in getters and the discovered plugins with downloader
 // notations are collected.
 func All(settings *cli.EnvSettings) Providers {
-	result := Providers{httpProvider, ociProvider}
+	httpGetters := httpProvider
+	if providers, err := credentials.Load(settings); err == nil && providers != nil {
+		httpGetters = withCredentialProvider(httpProvider, providers)
+	}
+	result := Providers{httpGetters, ociProvider, gitProvider, s3Provider, gcsProvider, azblobProvider, kubeProvider(settings)}
 	pluginDownloaders, _ := collectPlugins(settings)
 	result = append(result, pluginDownloaders...)
 	return result
 }
+
+// withCredentialProvider returns p, its getters getting the credentials of
+// the hosts without basic auth credentials from provider.
+func withCredentialProvider(p Provider, provider registry.CredentialProvider) Provider {
+	return Provider{
+		Schemes: p.Schemes,
+		New: func(options ...Option) (Getter, error) {
+			return p.New(append([]Option{WithCredentialProvider(provider)}, options...)...)
+		},
+	}
+}
@@ -57,8 +57,10 @@ func TestAll(t *testing.T) {
 	env.PluginsDirectory = pluginDir
 
 	all := All(env)
-	if len(all) != 4 {
-		t.Errorf("expected 4 providers (default plus three plugins), got %d", len(all))
+	// http(s), oci, git, s3, gs, azblob and the objects of the cluster, plus
+	// the three plugins of the test data
+	if len(all) != 10 {
+		t.Errorf("expected 10 providers (seven built-in plus three plugins), got %d", len(all))
 	}
 
 	if _, err := all.ByScheme("test2"); err != nil {
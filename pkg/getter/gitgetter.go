@@ -0,0 +1,218 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"bufio"
+	"bytes"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/vcs"
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// gitSchemePrefix is the prefix of the schemes of the charts in git
+// repositories, followed by the scheme of the remote.
+const gitSchemePrefix = "git+"
+
+var gitSchemes = []string{"git+https", "git+http", "git+ssh", "git+file"}
+
+var commitRegexp = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// IsGitURL returns true if href is the URL of a chart in a git repository.
+func IsGitURL(href string) bool {
+	for _, scheme := range gitSchemes {
+		if strings.HasPrefix(href, scheme+"://") {
+			return true
+		}
+	}
+	return false
+}
+
+// GitReference is a chart in a git repository, referenced by a URL like
+// git+https://github.com/org/repo//charts/mychart?ref=v1.2.3.
+type GitReference struct {
+	// Remote is the URL of the git repository, without the "git+" prefix.
+	Remote string
+	// Path is the directory of the chart in the repository, after the
+	// double slash. The chart is at the root of the repository if it is
+	// empty.
+	Path string
+	// Ref is the branch, tag or commit to check out. The default branch of
+	// the repository is checked out if it is empty.
+	Ref string
+}
+
+// ParseGitReference parses the URL of a chart in a git repository.
+func ParseGitReference(href string) (*GitReference, error) {
+	if !IsGitURL(href) {
+		return nil, errors.Errorf("invalid git chart URL %q", href)
+	}
+	u, err := url.Parse(strings.TrimPrefix(href, gitSchemePrefix))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid git chart URL %q", href)
+	}
+
+	ref := &GitReference{Ref: u.Query().Get("ref")}
+	u.RawQuery = ""
+	if i := strings.Index(u.Path, "//"); i >= 0 {
+		ref.Path = strings.Trim(path.Clean(u.Path[i+2:]), "/")
+		if ref.Path == "." {
+			ref.Path = ""
+		}
+		u.Path = u.Path[:i]
+		u.RawPath = ""
+	}
+	if ref.Path == ".." || strings.HasPrefix(ref.Path, "../") {
+		return nil, errors.Errorf("invalid git chart URL %q: the chart path must be inside the repository", href)
+	}
+	ref.Remote = u.String()
+	return ref, nil
+}
+
+// ResolveGitCommit returns the commit the ref of the git chart URL href points
+// to, so the chart can be pinned to it.
+func ResolveGitCommit(href string) (string, error) {
+	ref, err := ParseGitReference(href)
+	if err != nil {
+		return "", err
+	}
+	if commitRegexp.MatchString(ref.Ref) {
+		return ref.Ref, nil
+	}
+
+	pattern := ref.Ref
+	if pattern == "" {
+		pattern = "HEAD"
+	}
+	out, err := exec.Command("git", "ls-remote", ref.Remote, pattern, pattern+"^{}").Output()
+	if err != nil {
+		return "", errors.Wrapf(gitError(err), "failed to list the references of %s", ref.Remote)
+	}
+
+	// Annotated tags are resolved to the commit they point to, which is
+	// listed with the "^{}" suffix.
+	refs := map[string]string{}
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 2 {
+			refs[fields[1]] = fields[0]
+		}
+	}
+	candidates := []string{"HEAD"}
+	if ref.Ref != "" {
+		candidates = []string{"refs/tags/" + pattern + "^{}", "refs/tags/" + pattern, "refs/heads/" + pattern}
+	}
+	for _, name := range candidates {
+		if commit, ok := refs[name]; ok {
+			return commit, nil
+		}
+	}
+	return "", errors.Errorf("reference %q not found in %s", ref.Ref, ref.Remote)
+}
+
+// GitGetter is the backend handler of the charts in git repositories. It
+// clones the repository with the git command, checks out the ref of the URL,
+// or the commit set with WithDigest, and packages the chart found in the path
+// of the URL.
+//
+// Credentials are not passed to git; private repositories are accessed with
+// the credential helpers and SSH keys git is configured with.
+type GitGetter struct {
+	opts options
+}
+
+// Get clones the git repository of href and returns the chart archive.
+func (g *GitGetter) Get(href string, options ...Option) (*bytes.Buffer, error) {
+	for _, opt := range options {
+		opt(&g.opts)
+	}
+	return g.get(href)
+}
+
+func (g *GitGetter) get(href string) (*bytes.Buffer, error) {
+	ref, err := ParseGitReference(href)
+	if err != nil {
+		return nil, err
+	}
+	version := ref.Ref
+	if g.opts.digest != "" {
+		version = g.opts.digest
+	}
+
+	tmp, err := os.MkdirTemp("", "helm-git-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	repo, err := vcs.NewGitRepo(ref.Remote, filepath.Join(tmp, "repo"))
+	if err != nil {
+		return nil, err
+	}
+	if err := repo.Get(); err != nil {
+		return nil, errors.Wrapf(err, "failed to clone %s", ref.Remote)
+	}
+	if version != "" {
+		if err := repo.UpdateVersion(version); err != nil {
+			return nil, errors.Wrapf(err, "failed to check out %q of %s", version, ref.Remote)
+		}
+	}
+
+	ch, err := loader.LoadDir(filepath.Join(repo.LocalPath(), filepath.FromSlash(ref.Path)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load the chart of %s", href)
+	}
+	name, err := chartutil.Save(ch, tmp)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to package the chart of %s", href)
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewBuffer(data), nil
+}
+
+// NewGitGetter constructs a Getter of the charts in git repositories.
+func NewGitGetter(ops ...Option) (Getter, error) {
+	var client GitGetter
+
+	for _, opt := range ops {
+		opt(&client.opts)
+	}
+
+	return &client, nil
+}
+
+// gitError adds the output of git to the error, if it exited with an error.
+func gitError(err error) error {
+	if ee, ok := err.(*exec.ExitError); ok && len(ee.Stderr) > 0 {
+		return errors.New(strings.TrimSpace(string(ee.Stderr)))
+	}
+	return err
+}
@@ -0,0 +1,184 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+)
+
+func TestParseGitReference(t *testing.T) {
+	tests := []struct {
+		href    string
+		want    GitReference
+		wantErr bool
+	}{
+		{
+			href: "git+https://github.com/org/repo//charts/mychart?ref=v1.2.3",
+			want: GitReference{Remote: "https://github.com/org/repo", Path: "charts/mychart", Ref: "v1.2.3"},
+		},
+		{
+			href: "git+ssh://git@github.com/org/repo.git",
+			want: GitReference{Remote: "ssh://git@github.com/org/repo.git"},
+		},
+		{
+			href: "git+file:///srv/git/repo//mychart/",
+			want: GitReference{Remote: "file:///srv/git/repo", Path: "mychart"},
+		},
+		{href: "git+https://github.com/org/repo//../mychart", wantErr: true},
+		{href: "https://github.com/org/repo", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseGitReference(tt.href)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.href)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", tt.href, err)
+			continue
+		}
+		if *got != tt.want {
+			t.Errorf("%s: expected %+v, got %+v", tt.href, tt.want, *got)
+		}
+	}
+}
+
+// newGitRepo creates a git repository with the chart mychart at
+// charts/mychart, at version 1.2.3 in the annotated tag v1.2.3 and at version
+// 1.3.0 in the branch main. It returns the directory of the repository and the
+// commits of the two versions.
+func newGitRepo(t *testing.T) (string, string, string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=Helm", "-c", "user.email=helm@example.com"}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %s: %s", strings.Join(args, " "), err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	writeChart := func(version string) {
+		t.Helper()
+		chartDir := filepath.Join(dir, "charts", "mychart")
+		if err := os.MkdirAll(chartDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		metadata := fmt.Sprintf("apiVersion: v2\nname: mychart\nversion: %s\n", version)
+		if err := os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte(metadata), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "-q", "-b", "main")
+	writeChart("1.2.3")
+	git("add", ".")
+	git("commit", "-q", "-m", "mychart 1.2.3")
+	git("tag", "-a", "v1.2.3", "-m", "v1.2.3")
+	first := git("rev-parse", "HEAD")
+	writeChart("1.3.0")
+	git("commit", "-q", "-am", "mychart 1.3.0")
+	second := git("rev-parse", "HEAD")
+	return dir, first, second
+}
+
+func TestResolveGitCommit(t *testing.T) {
+	dir, first, second := newGitRepo(t)
+	base := "git+file://" + filepath.ToSlash(dir) + "//charts/mychart"
+
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"", second},
+		{"?ref=v1.2.3", first},
+		{"?ref=main", second},
+		{"?ref=" + first, first},
+	}
+	for _, tt := range tests {
+		got, err := ResolveGitCommit(base + tt.ref)
+		if err != nil {
+			t.Errorf("%q: %s", tt.ref, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q: expected commit %s, got %s", tt.ref, tt.want, got)
+		}
+	}
+
+	if _, err := ResolveGitCommit(base + "?ref=v9.9.9"); err == nil {
+		t.Error("expected an error resolving a missing ref")
+	}
+}
+
+func TestGitGetter(t *testing.T) {
+	dir, first, _ := newGitRepo(t)
+	base := "git+file://" + filepath.ToSlash(dir) + "//charts/mychart"
+
+	tests := []struct {
+		name    string
+		href    string
+		options []Option
+		want    string
+	}{
+		{"default branch", base, nil, "1.3.0"},
+		{"tag", base + "?ref=v1.2.3", nil, "1.2.3"},
+		{"pinned commit", base + "?ref=main", []Option{WithDigest(first)}, "1.2.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := NewGitGetter()
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := g.Get(tt.href, tt.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ch, err := loader.LoadArchive(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ch.Name() != "mychart" || ch.Metadata.Version != tt.want {
+				t.Errorf("expected mychart %s, got %s %s", tt.want, ch.Name(), ch.Metadata.Version)
+			}
+		})
+	}
+
+	g, err := NewGitGetter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Get("git+file://" + filepath.ToSlash(dir) + "//missing"); err == nil {
+		t.Error("expected an error getting a missing chart")
+	}
+}
@@ -17,13 +17,16 @@ package getter
 
 import (
 	"bytes"
-	"crypto/tls"
+	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
+	"golang.org/x/net/http/httpproxy"
 
 	"helm.sh/helm/v3/internal/tlsutil"
 	"helm.sh/helm/v3/internal/urlutil"
@@ -34,7 +37,9 @@ import (
 type HTTPGetter struct {
 	opts      options
 	transport *http.Transport
-	once      sync.Once
+	// tlsKey is the TLS configuration the transport was created with.
+	tlsKey string
+	mu     sync.Mutex
 }
 
 // Get performs a Get from repo.Getter and returns the body.
@@ -46,6 +51,97 @@ func (g *HTTPGetter) Get(href string, options ...Option) (*bytes.Buffer, error)
 }
 
 func (g *HTTPGetter) get(href string) (*bytes.Buffer, error) {
+	client, err := g.httpClient()
+	if err != nil {
+		return nil, err
+	}
+
+	// A download failing with a transient error is retried, resuming from
+	// the received content when the server supports ranges.
+	buf := bytes.NewBuffer(nil)
+	ifRange := ""
+	for retries := 0; ; retries++ {
+		retry, err := g.fetch(client, href, buf, &ifRange)
+		if err == nil {
+			return buf, nil
+		}
+		if !retry || retries >= g.opts.retries {
+			return nil, err
+		}
+		time.Sleep(g.opts.retryBackoff << retries)
+	}
+}
+
+// fetch appends the content of href to buf, requesting the range following
+// the content already in buf if ifRange holds a validator of the content.
+// ifRange is set to the validator of the content of a complete response. It
+// returns whether the request can be retried if it fails.
+func (g *HTTPGetter) fetch(client *http.Client, href string, buf *bytes.Buffer, ifRange *string) (bool, error) {
+	req, err := g.newRequest(href)
+	if err != nil {
+		return false, err
+	}
+	offset := buf.Len()
+	if offset > 0 {
+		if *ifRange == "" {
+			buf.Reset()
+			offset = 0
+		} else {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+			req.Header.Set("If-Range", *ifRange)
+			req.Header.Del("If-None-Match")
+			req.Header.Del("If-Modified-Since")
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && g.opts.validators != nil && offset == 0:
+		return false, ErrNotModified
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		if !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset)) {
+			buf.Reset()
+			return true, errors.Errorf("failed to fetch %s : unexpected Content-Range %q", href, resp.Header.Get("Content-Range"))
+		}
+	case resp.StatusCode == http.StatusOK:
+		buf.Reset()
+		if v := g.opts.validators; v != nil {
+			v.ETag, v.LastModified = resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+		}
+		*ifRange = rangeValidator(resp.Header)
+	default:
+		temporary := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+		return temporary, errors.Errorf("failed to fetch %s : %s", href, resp.Status)
+	}
+
+	if _, err := io.Copy(buf, resp.Body); err != nil {
+		return true, errors.Wrapf(err, "failed to fetch %s", href)
+	}
+	return false, nil
+}
+
+// rangeValidator returns the validator of the content of a response to make
+// the requests of its ranges conditional on, its strong ETag or its
+// modification time, or an empty string if ranges cannot be requested.
+func rangeValidator(header http.Header) string {
+	if header.Get("Accept-Ranges") == "none" {
+		return ""
+	}
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return header.Get("Last-Modified")
+}
+
+// newRequest returns the request of href, with the headers and the
+// credentials of the options, else the credentials of the credential
+// provider for the host of href.
+func (g *HTTPGetter) newRequest(href string) (*http.Request, error) {
 	// Set a helm specific user agent so that a repo server and metrics can
 	// separate helm calls from other tools interacting with repos.
 	req, err := http.NewRequest(http.MethodGet, href, nil)
@@ -57,6 +153,20 @@ func (g *HTTPGetter) get(href string) (*bytes.Buffer, error) {
 	if g.opts.userAgent != "" {
 		req.Header.Set("User-Agent", g.opts.userAgent)
 	}
+	if g.opts.accept != "" {
+		req.Header.Set("Accept", g.opts.accept)
+	}
+	if g.opts.acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", g.opts.acceptEncoding)
+	}
+	if v := g.opts.validators; v != nil {
+		if v.ETag != "" {
+			req.Header.Set("If-None-Match", v.ETag)
+		}
+		if v.LastModified != "" {
+			req.Header.Set("If-Modified-Since", v.LastModified)
+		}
+	}
 
 	// Before setting the basic auth credentials, make sure the URL associated
 	// with the basic auth is the one being fetched.
@@ -76,25 +186,23 @@ func (g *HTTPGetter) get(href string) (*bytes.Buffer, error) {
 		if g.opts.username != "" && g.opts.password != "" {
 			req.SetBasicAuth(g.opts.username, g.opts.password)
 		}
+		for k, v := range g.opts.headers {
+			req.Header.Set(k, v)
+		}
 	}
-
-	client, err := g.httpClient()
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return nil, errors.Errorf("failed to fetch %s : %s", href, resp.Status)
+	if req.Header.Get("Authorization") == "" && g.opts.credentialProvider != nil {
+		username, password, err := g.opts.credentialProvider.Credential(u2.Host)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case username != "":
+			req.SetBasicAuth(username, password)
+		case password != "":
+			req.Header.Set("Authorization", "Bearer "+password)
+		}
 	}
-
-	buf := bytes.NewBuffer(nil)
-	_, err = io.Copy(buf, resp.Body)
-	return buf, err
+	return req, nil
 }
 
 // NewHTTPGetter constructs a valid http/https client as a Getter
@@ -116,21 +224,37 @@ func (g *HTTPGetter) httpClient() (*http.Client, error) {
 		}, nil
 	}
 
-	g.once.Do(func() {
-		g.transport = &http.Transport{
-			DisableCompression: true,
-			Proxy:              http.ProxyFromEnvironment,
-		}
-	})
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// The transport is created again when the TLS configuration changes, so
+	// the connections use the rotated certificates
+	key := g.opts.tlsKey()
+	if g.transport != nil && key == g.tlsKey {
+		return &http.Client{
+			Transport: g.transport,
+			Timeout:   g.opts.timeout,
+		}, nil
+	}
+	if g.transport != nil {
+		g.transport.CloseIdleConnections()
+	}
+	g.transport = &http.Transport{
+		DisableCompression: true,
+		Proxy:              g.proxy,
+	}
+	g.tlsKey = key
 
 	if (g.opts.certFile != "" && g.opts.keyFile != "") || g.opts.caFile != "" || g.opts.insecureSkipVerifyTLS {
 		tlsConf, err := tlsutil.NewClientTLS(g.opts.certFile, g.opts.keyFile, g.opts.caFile, g.opts.insecureSkipVerifyTLS)
 		if err != nil {
+			g.transport = nil
 			return nil, errors.Wrap(err, "can't create TLS config for client")
 		}
 
 		sni, err := urlutil.ExtractHostname(g.opts.url)
 		if err != nil {
+			g.transport = nil
 			return nil, err
 		}
 		tlsConf.ServerName = sni
@@ -138,16 +262,6 @@ func (g *HTTPGetter) httpClient() (*http.Client, error) {
 		g.transport.TLSClientConfig = tlsConf
 	}
 
-	if g.opts.insecureSkipVerifyTLS {
-		if g.transport.TLSClientConfig == nil {
-			g.transport.TLSClientConfig = &tls.Config{
-				InsecureSkipVerify: true,
-			}
-		} else {
-			g.transport.TLSClientConfig.InsecureSkipVerify = true
-		}
-	}
-
 	client := &http.Client{
 		Transport: g.transport,
 		Timeout:   g.opts.timeout,
@@ -155,3 +269,17 @@ func (g *HTTPGetter) httpClient() (*http.Client, error) {
 
 	return client, nil
 }
+
+// proxy returns the proxy of the request, set by WithProxy or by the
+// environment variables.
+func (g *HTTPGetter) proxy(req *http.Request) (*url.URL, error) {
+	if g.opts.proxy == "" {
+		return http.ProxyFromEnvironment(req)
+	}
+	conf := &httpproxy.Config{
+		HTTPProxy:  g.opts.proxy,
+		HTTPSProxy: g.opts.proxy,
+		NoProxy:    g.opts.noProxy,
+	}
+	return conf.ProxyFunc()(req.URL)
+}
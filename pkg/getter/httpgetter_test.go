@@ -16,6 +16,7 @@ limitations under the License.
 package getter
 
 import (
+	"encoding/base64"
 	"fmt"
 	"io"
 	"net/http"
@@ -399,6 +400,214 @@ func TestHTTPGetterTarDownload(t *testing.T) {
 	}
 }
 
+func TestHTTPGetterCacheValidators(t *testing.T) {
+	modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	var accept string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept = r.Header.Get("Accept") + "|" + r.Header.Get("Accept-Encoding")
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "index.yaml", modified, strings.NewReader("apiVersion: v1\n"))
+	}))
+	defer srv.Close()
+
+	g, err := NewHTTPGetter(WithURL(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := &CacheValidators{}
+	if _, err := g.Get(srv.URL, WithCacheValidators(v), WithAcceptHeaders("application/json", "gzip")); err != nil {
+		t.Fatal(err)
+	}
+	if v.ETag != `"v1"` || v.LastModified != modified.Format(http.TimeFormat) {
+		t.Errorf("unexpected validators %+v", v)
+	}
+	if accept != "application/json|gzip" {
+		t.Errorf("unexpected accept headers %s", accept)
+	}
+
+	if _, err := g.Get(srv.URL, WithCacheValidators(v)); err != ErrNotModified {
+		t.Errorf("expected %v, got %v", ErrNotModified, err)
+	}
+	if _, err := g.Get(srv.URL, WithCacheValidators(&CacheValidators{ETag: `"v0"`})); err != nil {
+		t.Errorf("expected the modified content, got %v", err)
+	}
+
+	// Requests are not conditional without validators
+	buf, err := g.Get(srv.URL, WithCacheValidators(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "apiVersion: v1\n" {
+		t.Errorf("unexpected content %q", buf.String())
+	}
+}
+
+func TestHTTPGetterHeadersAndProxy(t *testing.T) {
+	var token string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token = r.Header.Get("X-Api-Token")
+	}))
+	defer srv.Close()
+
+	headers := map[string]string{"X-Api-Token": "secret"}
+	g, err := NewHTTPGetter(WithURL(srv.URL), WithHeaders(headers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Get(srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if token != "secret" {
+		t.Errorf("expected the static header to be sent, got %q", token)
+	}
+
+	// Like credentials, the headers are not sent to other hosts
+	g, err = NewHTTPGetter(WithURL("https://charts.example.com"), WithHeaders(headers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Get(srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		t.Errorf("expected the static header not to be sent, got %q", token)
+	}
+
+	// The requests go through the proxy of the repository
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+	g, err = NewHTTPGetter(WithProxy(proxy.URL, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Get("http://charts.example.invalid/index.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if proxied != "http://charts.example.invalid/index.yaml" {
+		t.Errorf("expected the request to be proxied, got %q", proxied)
+	}
+
+	// Except the requests of the hosts of noProxy
+	proxied = ""
+	g, err = NewHTTPGetter(WithProxy(proxy.URL, "charts.example.invalid"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Get("http://charts.example.invalid/index.yaml"); err == nil || proxied != "" {
+		t.Errorf("expected a direct request, got %q, %v", proxied, err)
+	}
+}
+
+// credentialProviderFunc is a registry.CredentialProvider calling the
+// function.
+type credentialProviderFunc func(host string) (string, string, error)
+
+func (f credentialProviderFunc) Credential(host string) (string, string, error) {
+	return f(host)
+}
+
+func TestHTTPGetterCredentialProvider(t *testing.T) {
+	var authorization string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+
+	var username string
+	provider := credentialProviderFunc(func(host string) (string, string, error) {
+		if host != u.Host {
+			t.Errorf("expected the credentials of %s, got %s", u.Host, host)
+		}
+		return username, "token", nil
+	})
+	g, err := NewHTTPGetter(WithURL(srv.URL), WithCredentialProvider(provider))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Get(srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if authorization != "Bearer token" {
+		t.Errorf("expected the token of the provider, got %q", authorization)
+	}
+
+	username = "user"
+	if _, err := g.Get(srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if authorization != "Basic "+base64.StdEncoding.EncodeToString([]byte("user:token")) {
+		t.Errorf("expected the credentials of the provider, got %q", authorization)
+	}
+
+	// The credentials of the options take precedence
+	g, err = NewHTTPGetter(WithURL(srv.URL), WithBasicAuth("helm", "pass"), WithCredentialProvider(provider))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Get(srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if authorization != "Basic "+base64.StdEncoding.EncodeToString([]byte("helm:pass")) {
+		t.Errorf("expected the credentials of the options, got %q", authorization)
+	}
+}
+
+func TestHTTPGetterRetries(t *testing.T) {
+	content := strings.Repeat("chart", 1024)
+	modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		switch len(ranges) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			// The connection is closed in the middle of the content
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.Write([]byte(content[:1000]))
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		default:
+			w.Header().Set("ETag", `"v1"`)
+			http.ServeContent(w, r, "chart.tgz", modified, strings.NewReader(content))
+		}
+	}))
+	defer srv.Close()
+
+	g, err := NewHTTPGetter(WithURL(srv.URL), WithRetries(2, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := g.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != content {
+		t.Errorf("unexpected content of %d bytes", buf.Len())
+	}
+	if len(ranges) != 3 || ranges[2] != "bytes=1000-" {
+		t.Errorf("expected the download to be resumed, got ranges %q", ranges)
+	}
+
+	// The download fails once the retries are exhausted
+	ranges = nil
+	g, err = NewHTTPGetter(WithURL(srv.URL), WithRetries(0, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Get(srv.URL); err == nil {
+		t.Error("expected the download to fail")
+	}
+	if len(ranges) != 1 {
+		t.Errorf("expected 1 request, got %d", len(ranges))
+	}
+}
+
 func TestHttpClientInsecureSkipVerify(t *testing.T) {
 	g := HTTPGetter{}
 	g.opts.url = "https://localhost"
@@ -482,6 +691,52 @@ func TestDefaultHTTPTransportReuse(t *testing.T) {
 	}
 }
 
+func TestHTTPTransportTLSRotation(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	for src, dest := range map[string]string{"testdata/client.crt": certFile, "testdata/client.key": keyFile} {
+		b, err := os.ReadFile(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(dest, b, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g := HTTPGetter{}
+	g.opts.url = "https://localhost"
+	g.opts.certFile = certFile
+	g.opts.keyFile = keyFile
+	client1, err := g.httpClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client2, err := g.httpClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client1.Transport != client2.Transport {
+		t.Fatal("Expected the transport to be reused while the certificates are unchanged")
+	}
+
+	// The certificates are loaded again when they are rotated
+	rotated := time.Now().Add(time.Hour)
+	if err := os.Chtimes(certFile, rotated, rotated); err != nil {
+		t.Fatal(err)
+	}
+	client3, err := g.httpClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client3.Transport == client2.Transport {
+		t.Fatal("Expected a new transport for the rotated certificates")
+	}
+	if len(client3.Transport.(*http.Transport).TLSClientConfig.Certificates) == 0 {
+		t.Fatal("Expected the rotated certificates to be loaded")
+	}
+}
+
 func TestHTTPTransportOption(t *testing.T) {
 	transport := &http.Transport{}
 
@@ -0,0 +1,144 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// ConfigMapScheme is the scheme of the URLs of the keys of ConfigMaps.
+	ConfigMapScheme = "configmap"
+	// SecretScheme is the scheme of the URLs of the keys of Secrets.
+	SecretScheme = "secret"
+)
+
+// KubeGetter is the backend handler of the keys of the ConfigMaps and the
+// Secrets of the cluster, at URLs like configmap://namespace/name/key and
+// secret://namespace/name/key, such as the values of the releases stored in
+// the cluster. The objects are read with the kubeconfig of
+// WithRESTClientGetter, in its namespace if the URL has none, like
+// configmap:///name/key.
+type KubeGetter struct {
+	opts options
+	// client is the client of the cluster, created from the kubeconfig if
+	// nil.
+	client kubernetes.Interface
+}
+
+// Get performs a Get from repo.Getter and returns the body.
+func (g *KubeGetter) Get(href string, options ...Option) (*bytes.Buffer, error) {
+	for _, opt := range options {
+		opt(&g.opts)
+	}
+	return g.get(href)
+}
+
+func (g *KubeGetter) get(href string) (*bytes.Buffer, error) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return nil, err
+	}
+	name, key, ok := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/")
+	if !ok || name == "" || key == "" {
+		return nil, errors.Errorf("invalid URL %q: expected %s://namespace/name/key", href, u.Scheme)
+	}
+	client, namespace, err := g.kubeClient()
+	if err != nil {
+		return nil, err
+	}
+	if u.Host != "" {
+		namespace = u.Host
+	}
+
+	ctx := context.Background()
+	if g.opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.opts.timeout)
+		defer cancel()
+	}
+
+	switch u.Scheme {
+	case ConfigMapScheme:
+		cm, err := client.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to get the ConfigMap %s/%s", namespace, name)
+		}
+		if data, ok := cm.Data[key]; ok {
+			return bytes.NewBufferString(data), nil
+		}
+		if data, ok := cm.BinaryData[key]; ok {
+			return bytes.NewBuffer(data), nil
+		}
+		return nil, errors.Errorf("key %q not found in the ConfigMap %s/%s", key, namespace, name)
+	case SecretScheme:
+		secret, err := client.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to get the Secret %s/%s", namespace, name)
+		}
+		if data, ok := secret.Data[key]; ok {
+			return bytes.NewBuffer(data), nil
+		}
+		return nil, errors.Errorf("key %q not found in the Secret %s/%s", key, namespace, name)
+	default:
+		return nil, errors.Errorf("scheme %q not supported", u.Scheme)
+	}
+}
+
+// kubeClient returns the client of the cluster and the namespace of the
+// kubeconfig.
+func (g *KubeGetter) kubeClient() (kubernetes.Interface, string, error) {
+	namespace := "default"
+	if g.opts.restClientGetter != nil {
+		if ns, _, err := g.opts.restClientGetter.ToRawKubeConfigLoader().Namespace(); err == nil && ns != "" {
+			namespace = ns
+		}
+	}
+	if g.client != nil {
+		return g.client, namespace, nil
+	}
+	if g.opts.restClientGetter == nil {
+		return nil, "", errors.New("no kubeconfig to get the objects of the cluster with")
+	}
+	config, err := g.opts.restClientGetter.ToRESTConfig()
+	if err != nil {
+		return nil, "", err
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, "", err
+	}
+	return client, namespace, nil
+}
+
+// NewKubeGetter constructs a Getter of the keys of the ConfigMaps and the
+// Secrets of a cluster.
+func NewKubeGetter(options ...Option) (Getter, error) {
+	var client KubeGetter
+
+	for _, opt := range options {
+		opt(&client.opts)
+	}
+
+	return &client, nil
+}
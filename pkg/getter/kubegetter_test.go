@@ -0,0 +1,65 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestKubeGetter(t *testing.T) {
+	g := &KubeGetter{client: fake.NewSimpleClientset(
+		&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "web"},
+			Data:       map[string]string{"values.yaml": "replicas: 3\n"},
+			BinaryData: map[string][]byte{"extra.yaml": []byte("debug: true\n")},
+		},
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "credentials", Namespace: "default"},
+			Data:       map[string][]byte{"values.yaml": []byte("password: secret\n")},
+		},
+	)}
+
+	for href, expect := range map[string]string{
+		"configmap://web/settings/values.yaml":     "replicas: 3\n",
+		"configmap://web/settings/extra.yaml":      "debug: true\n",
+		"secret:///credentials/values.yaml":        "password: secret\n",
+		"secret://default/credentials/values.yaml": "password: secret\n",
+	} {
+		data, err := g.Get(href)
+		if err != nil {
+			t.Errorf("%s: %s", href, err)
+			continue
+		}
+		if data.String() != expect {
+			t.Errorf("%s: expected %q, got %q", href, expect, data.String())
+		}
+	}
+
+	for _, href := range []string{
+		"configmap://web/settings",
+		"configmap://web/settings/missing.yaml",
+		"configmap://default/settings/values.yaml",
+		"secret://web/credentials/values.yaml",
+	} {
+		if _, err := g.Get(href); err == nil {
+			t.Errorf("%s: expected an error", href)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/internal/tlsutil"
+	"helm.sh/helm/v3/internal/version"
+)
+
+// objectPartSize is the size of the ranges objects of cloud object stores are
+// downloaded in. A range failing with a transient error is requested again
+// from the offset it stopped at, so large charts and indices do not have to be
+// downloaded again from the start.
+var objectPartSize int64 = 8 << 20
+
+// objectRetries is the number of times a range is requested again.
+const objectRetries = 3
+
+// objectRetryDelay is the delay before the first retry of a range, doubled
+// for every retry.
+var objectRetryDelay = 200 * time.Millisecond
+
+// credentialTimeout bounds the requests to the metadata services of the
+// cloud providers, so credentials are looked up quickly outside of them.
+var credentialTimeout = time.Second
+
+// objectSigner authorizes a request of an object store. It returns an error
+// if the credentials cannot be found.
+type objectSigner func(req *http.Request) error
+
+// objectStatusError is the error of a request of an object store failing
+// with an unexpected status.
+type objectStatusError struct {
+	// method is the method of the request, GET if it is empty.
+	method     string
+	url        string
+	statusCode int
+	header     http.Header
+	message    string
+}
+
+func (e *objectStatusError) Error() string {
+	verb := "fetch"
+	switch e.method {
+	case http.MethodPut:
+		verb = "write"
+	case http.MethodDelete:
+		verb = "delete"
+	}
+	msg := fmt.Sprintf("failed to %s %s : %d %s", verb, e.url, e.statusCode, http.StatusText(e.statusCode))
+	if e.message != "" {
+		msg += ": " + e.message
+	}
+	return msg
+}
+
+// temporary returns true if the request can be retried.
+func (e *objectStatusError) temporary() bool {
+	return e.statusCode == http.StatusTooManyRequests || e.statusCode >= http.StatusInternalServerError
+}
+
+// getObject downloads the object at objectURL in ranges of objectPartSize,
+// authorizing every request with sign. The ranges after the first are
+// conditional on the ETag of the object, so a download fails instead of
+// mixing two versions of an object written in the meantime.
+func getObject(client *http.Client, objectURL string, sign objectSigner) (*bytes.Buffer, error) {
+	buf := bytes.NewBuffer(nil)
+	size := int64(-1)
+	etag := ""
+	retries := 0
+	for size < 0 || int64(buf.Len()) < size {
+		total, retry, err := getObjectRange(client, objectURL, sign, buf, &etag)
+		if err == nil {
+			size = total
+			retries = 0
+			continue
+		}
+		if !retry || retries >= objectRetries {
+			return nil, err
+		}
+		time.Sleep(objectRetryDelay << retries)
+		retries++
+	}
+	return buf, nil
+}
+
+// getObjectRange appends the range of the object starting at the length of
+// buf to buf, and returns the size of the object. It returns whether the
+// range can be requested again if it fails.
+func getObjectRange(client *http.Client, objectURL string, sign objectSigner, buf *bytes.Buffer, etag *string) (int64, bool, error) {
+	req, err := http.NewRequest(http.MethodGet, objectURL, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	offset := int64(buf.Len())
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+objectPartSize-1))
+	req.Header.Set("User-Agent", version.GetUserAgent())
+	if *etag != "" {
+		req.Header.Set("If-Match", *etag)
+	}
+	if err := sign(req); err != nil {
+		return 0, false, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, true, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// The store ignored the range and sent the whole object.
+		buf.Truncate(0)
+		_, err := io.Copy(buf, resp.Body)
+		return int64(buf.Len()), true, err
+	case http.StatusPartialContent:
+		total, err := contentRangeSize(resp.Header.Get("Content-Range"))
+		if err != nil {
+			return 0, false, errors.Wrapf(err, "failed to fetch %s", objectURL)
+		}
+		if *etag == "" {
+			*etag = resp.Header.Get("ETag")
+		}
+		_, err = io.Copy(buf, resp.Body)
+		return total, true, err
+	case http.StatusRequestedRangeNotSatisfiable:
+		if offset == 0 {
+			// The object is empty.
+			return 0, false, nil
+		}
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	se := &objectStatusError{
+		url:        objectURL,
+		statusCode: resp.StatusCode,
+		header:     resp.Header,
+		message:    strings.TrimSpace(string(body)),
+	}
+	return 0, se.temporary(), se
+}
+
+// contentRangeSize returns the complete length of a Content-Range header,
+// such as "bytes 0-1023/4096".
+func contentRangeSize(contentRange string) (int64, error) {
+	i := strings.LastIndexByte(contentRange, '/')
+	if !strings.HasPrefix(contentRange, "bytes ") || i < 0 {
+		return 0, errors.Errorf("invalid Content-Range %q", contentRange)
+	}
+	size, err := strconv.ParseInt(contentRange[i+1:], 10, 64)
+	if err != nil {
+		return 0, errors.Errorf("invalid Content-Range %q", contentRange)
+	}
+	return size, nil
+}
+
+// objectHTTPClient returns the client of the requests of an object store,
+// with the transport and the TLS configuration of opts.
+func objectHTTPClient(opts options) (*http.Client, error) {
+	if opts.transport != nil {
+		return &http.Client{Transport: opts.transport, Timeout: opts.timeout}, nil
+	}
+	transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
+	if (opts.certFile != "" && opts.keyFile != "") || opts.caFile != "" || opts.insecureSkipVerifyTLS {
+		tlsConf, err := tlsutil.NewClientTLS(opts.certFile, opts.keyFile, opts.caFile, opts.insecureSkipVerifyTLS)
+		if err != nil {
+			return nil, errors.Wrap(err, "can't create TLS config for client")
+		}
+		transport.TLSClientConfig = tlsConf
+	}
+	return &http.Client{Transport: transport, Timeout: opts.timeout}, nil
+}
+
+// credentialClient is the client of the requests of credentials, to token
+// endpoints and to the metadata services of the cloud providers.
+var credentialClient = &http.Client{Timeout: 10 * time.Second}
+
+// oauthToken is the response of the token endpoints of the cloud providers.
+type oauthToken struct {
+	AccessToken string `json:"access_token"`
+}
+
+// fetchCredentialJSON sends req and decodes the JSON response into v.
+func fetchCredentialJSON(client *http.Client, req *http.Request, v interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("%s %s: %s: %s", req.Method, req.URL.Redacted(), resp.Status, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// metadataClient returns the client of the requests to a metadata service,
+// timing out quickly if the service cannot be reached.
+func metadataClient() *http.Client {
+	return &http.Client{Timeout: credentialTimeout}
+}
+
+// postForm returns a request posting the form values to endpoint.
+func postForm(endpoint string, values url.Values) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req, nil
+}
+
+// splitObjectURL returns the bucket and the key of an object URL such as
+// s3://bucket/path/to/key.
+func splitObjectURL(href string) (string, string, error) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "invalid object URL %q", href)
+	}
+	key := strings.TrimPrefix(u.Path, "/")
+	if u.Host == "" || key == "" {
+		return "", "", errors.Errorf("invalid object URL %q, expected %s://bucket/key", href, u.Scheme)
+	}
+	return u.Host, key, nil
+}
+
+// escapeObjectKey escapes the segments of the path of an object key.
+func escapeObjectKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,168 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// objectServer serves objects by path with range requests, recording the
+// requests.
+type objectServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	objects  map[string]string
+	requests []*http.Request
+	// fail is the number of requests failing with 503 before the objects
+	// are served.
+	fail  int
+	fails int
+}
+
+func newObjectServer(t *testing.T, objects map[string]string) *objectServer {
+	s := &objectServer{objects: objects}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r)
+		fail := s.fails < s.fail
+		if fail {
+			s.fails++
+		}
+		content, ok := s.objects[r.URL.Path]
+		s.mu.Unlock()
+
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("ETag", fmt.Sprintf("%q", fmt.Sprint(len(content))))
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(content))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *objectServer) lastRequest() *http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[len(s.requests)-1]
+}
+
+func setObjectPartSize(t *testing.T, size int64) {
+	partSize, delay := objectPartSize, objectRetryDelay
+	objectPartSize, objectRetryDelay = size, time.Millisecond
+	t.Cleanup(func() { objectPartSize, objectRetryDelay = partSize, delay })
+}
+
+func TestGetObject(t *testing.T) {
+	setObjectPartSize(t, 4)
+	content := "apiVersion: v1\nentries: {}\n"
+	srv := newObjectServer(t, map[string]string{
+		"/index.yaml": content,
+		"/empty":      "",
+	})
+	noSign := func(*http.Request) error { return nil }
+
+	buf, err := getObject(srv.Client(), srv.URL+"/index.yaml", noSign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != content {
+		t.Errorf("expected %q, got %q", content, buf.String())
+	}
+	want := (len(content) + 3) / 4
+	if len(srv.requests) != want {
+		t.Errorf("expected %d range requests, got %d", want, len(srv.requests))
+	}
+	last := srv.lastRequest()
+	if last.Header.Get("Range") != fmt.Sprintf("bytes=%d-%d", (want-1)*4, want*4-1) || last.Header.Get("If-Match") == "" {
+		t.Errorf("unexpected headers of the last range: %v", last.Header)
+	}
+
+	buf, err = getObject(srv.Client(), srv.URL+"/empty", noSign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected an empty object, got %q", buf.String())
+	}
+
+	if _, err := getObject(srv.Client(), srv.URL+"/missing", noSign); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+
+	// Transient failures are retried
+	srv.fail = objectRetries
+	buf, err = getObject(srv.Client(), srv.URL+"/index.yaml", noSign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != content {
+		t.Errorf("expected %q, got %q", content, buf.String())
+	}
+	srv.fail, srv.fails = objectRetries+1, 0
+	if _, err := getObject(srv.Client(), srv.URL+"/index.yaml", noSign); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("expected the retries to be exhausted, got %v", err)
+	}
+}
+
+func TestGetObjectChanged(t *testing.T) {
+	setObjectPartSize(t, 4)
+	srv := newObjectServer(t, map[string]string{"/chart.tgz": "01234567"})
+
+	// The object is written after the first range
+	sign := func(req *http.Request) error {
+		if req.Header.Get("If-Match") != "" {
+			srv.mu.Lock()
+			srv.objects["/chart.tgz"] = "0123456789"
+			srv.mu.Unlock()
+		}
+		return nil
+	}
+	_, err := getObject(srv.Client(), srv.URL+"/chart.tgz", sign)
+	if err == nil || !strings.Contains(err.Error(), "412") {
+		t.Errorf("expected a precondition failure, got %v", err)
+	}
+}
+
+func TestSplitObjectURL(t *testing.T) {
+	bucket, key, err := splitObjectURL("s3://bucket/charts/index.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bucket != "bucket" || key != "charts/index.yaml" {
+		t.Errorf("unexpected bucket %q and key %q", bucket, key)
+	}
+	for _, href := range []string{"s3://bucket", "s3:///key", "gs://bucket/"} {
+		if _, _, err := splitObjectURL(href); err == nil {
+			t.Errorf("%s: expected an error", href)
+		}
+	}
+	if got := escapeObjectKey("charts/my chart+1.tgz"); got != "charts/my%20chart+1.tgz" {
+		t.Errorf("unexpected escaped key %q", got)
+	}
+}
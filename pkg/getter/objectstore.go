@@ -0,0 +1,139 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/internal/version"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+// The object stores of the buckets the getters fetch from are the object
+// stores of the object storage driver of releases, with the credentials of
+// the getters.
+var (
+	_ driver.ObjectStore = (*S3ObjectStore)(nil)
+	_ driver.ObjectStore = (*GCSObjectStore)(nil)
+	_ driver.ObjectStore = (*AzureBlobObjectStore)(nil)
+)
+
+// objectRequest sends a request of an object store, with the header and the
+// body data if it is not nil, authorized with sign. It returns the header and
+// the body of the response, driver.ErrObjectNotFound if the object does not
+// exist, and driver.ErrObjectPreconditionFailed if the condition of the
+// request is not met.
+func objectRequest(ctx context.Context, client *http.Client, method, objectURL string, header http.Header, data []byte, sign objectSigner) (http.Header, []byte, error) {
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, objectURL, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("User-Agent", version.GetUserAgent())
+	if err := sign(req); err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return resp.Header, content, nil
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, nil, driver.ErrObjectNotFound
+	case resp.StatusCode == http.StatusPreconditionFailed, resp.StatusCode == http.StatusConflict && method != http.MethodGet:
+		// S3 returns a conflict when concurrent conditional writes of an
+		// object conflict, and Azure when the blob created exists.
+		return nil, nil, driver.ErrObjectPreconditionFailed
+	}
+	return nil, nil, &objectStatusError{
+		method:     method,
+		url:        objectURL,
+		statusCode: resp.StatusCode,
+		header:     resp.Header,
+		message:    strings.TrimSpace(string(content)),
+	}
+}
+
+// conditionHeader returns the header of a write conditional on the ETag of
+// the object, or on its absence if ifMatch is empty.
+func conditionHeader(ifMatch string) http.Header {
+	if ifMatch == "" {
+		return http.Header{"If-None-Match": {"*"}}
+	}
+	return http.Header{"If-Match": {ifMatch}}
+}
+
+// listBucketResult is a page of the results of the ListObjectsV2 requests of
+// S3, also supported by the XML API of GCS.
+type listBucketResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// listBucket returns the keys starting with prefix of a bucket, listed with
+// ListObjectsV2 requests of the query by list.
+func listBucket(prefix string, list func(query string) ([]byte, error)) ([]string, error) {
+	var keys []string
+	token := ""
+	for {
+		// The query is encoded as AWS signatures expect.
+		query := "list-type=2&prefix=" + awsURIEncode(prefix, true)
+		if token != "" {
+			query = "continuation-token=" + awsURIEncode(token, true) + "&" + query
+		}
+		content, err := list(query)
+		if err != nil {
+			return nil, err
+		}
+		var result listBucketResult
+		if err := xml.Unmarshal(content, &result); err != nil {
+			return nil, errors.Wrap(err, "invalid list of objects")
+		}
+		for _, c := range result.Contents {
+			keys = append(keys, c.Key)
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return keys, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
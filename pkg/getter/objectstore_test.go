@@ -0,0 +1,280 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+// bucketServer is a bucket of S3, GCS or Azure Blob Storage, at root, listing
+// one object by page of the results.
+type bucketServer struct {
+	*httptest.Server
+	provider string
+	root     string
+
+	mu         sync.Mutex
+	objects    map[string]string
+	versions   map[string]int
+	generation int
+	requests   []*http.Request
+}
+
+func newBucketServer(t *testing.T, provider, root string) *bucketServer {
+	s := &bucketServer{provider: provider, root: root, objects: map[string]string{}, versions: map[string]int{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *bucketServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r)
+	data, _ := io.ReadAll(r.Body)
+
+	if r.URL.Path == s.root || r.URL.Path == s.root+"/" {
+		s.list(w, r)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, s.root+"/")
+	version, exists := s.versions[key]
+	if r.Method == http.MethodGet || r.Method == http.MethodDelete {
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+	}
+	if r.Method != http.MethodGet {
+		if status := s.checkCondition(r, version, exists); status != 0 {
+			w.WriteHeader(status)
+			return
+		}
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.writeVersion(w, version)
+		fmt.Fprint(w, s.objects[key])
+	case http.MethodPut:
+		s.generation++
+		s.objects[key], s.versions[key] = string(data), s.generation
+		s.writeVersion(w, s.generation)
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodDelete:
+		delete(s.objects, key)
+		delete(s.versions, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// checkCondition returns the status of a write whose condition is not met,
+// or 0.
+func (s *bucketServer) checkCondition(r *http.Request, version int, exists bool) int {
+	if s.provider == "gcs" {
+		match := r.Header.Get("X-Goog-If-Generation-Match")
+		if match == "" || (match == "0") == exists || (exists && match != strconv.Itoa(version)) {
+			return http.StatusPreconditionFailed
+		}
+		return 0
+	}
+	if r.Header.Get("If-None-Match") == "*" {
+		if exists {
+			if s.provider == "azblob" {
+				return http.StatusConflict
+			}
+			return http.StatusPreconditionFailed
+		}
+		return 0
+	}
+	if !exists || r.Header.Get("If-Match") != strconv.Quote(strconv.Itoa(version)) {
+		return http.StatusPreconditionFailed
+	}
+	return 0
+}
+
+func (s *bucketServer) writeVersion(w http.ResponseWriter, version int) {
+	if s.provider == "gcs" {
+		w.Header().Set("X-Goog-Generation", strconv.Itoa(version))
+		return
+	}
+	w.Header().Set("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+func (s *bucketServer) list(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	after := query.Get("continuation-token")
+	if s.provider == "azblob" {
+		after = query.Get("marker")
+	}
+	var keys []string
+	for key := range s.objects {
+		if strings.HasPrefix(key, query.Get("prefix")) && key > after {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	next := ""
+	if len(keys) > 1 {
+		keys, next = keys[:1], keys[0]
+	}
+
+	if s.provider == "azblob" {
+		fmt.Fprint(w, "<EnumerationResults><Blobs>")
+		for _, key := range keys {
+			fmt.Fprintf(w, "<Blob><Name>%s</Name></Blob>", key)
+		}
+		fmt.Fprintf(w, "</Blobs><NextMarker>%s</NextMarker></EnumerationResults>", next)
+		return
+	}
+	if query.Get("list-type") != "2" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	fmt.Fprint(w, "<ListBucketResult>")
+	for _, key := range keys {
+		fmt.Fprintf(w, "<Contents><Key>%s</Key></Contents>", key)
+	}
+	fmt.Fprintf(w, "<IsTruncated>%t</IsTruncated><NextContinuationToken>%s</NextContinuationToken></ListBucketResult>", next != "", next)
+}
+
+func (s *bucketServer) lastRequest() *http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[len(s.requests)-1]
+}
+
+// testObjectStore tests the conditional writes and the list of store.
+func testObjectStore(t *testing.T, store driver.ObjectStore) {
+	t.Helper()
+	ctx := context.Background()
+
+	if _, _, err := store.Get(ctx, "default/sh.helm.release.v1.a.v1"); !errors.Is(err, driver.ErrObjectNotFound) {
+		t.Fatalf("expected the object not to be found, got %v", err)
+	}
+	etag, err := store.Put(ctx, "default/sh.helm.release.v1.a.v1", []byte("v1"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Put(ctx, "default/sh.helm.release.v1.a.v1", []byte("v1"), ""); !errors.Is(err, driver.ErrObjectPreconditionFailed) {
+		t.Errorf("expected the object not to be created again, got %v", err)
+	}
+	data, got, err := store.Get(ctx, "default/sh.helm.release.v1.a.v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "v1" || got != etag {
+		t.Errorf("expected v1 of ETag %s, got %s of ETag %s", etag, data, got)
+	}
+
+	updated, err := store.Put(ctx, "default/sh.helm.release.v1.a.v1", []byte("v1 updated"), etag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated == etag {
+		t.Errorf("expected the ETag %s to change", etag)
+	}
+	if _, err := store.Put(ctx, "default/sh.helm.release.v1.a.v1", []byte("stale"), etag); !errors.Is(err, driver.ErrObjectPreconditionFailed) {
+		t.Errorf("expected the stale write to fail, got %v", err)
+	}
+
+	for _, name := range []string{"default/sh.helm.release.v1.b.v1", "kube-system/sh.helm.release.v1.c.v1"} {
+		if _, err := store.Put(ctx, name, []byte("v1"), ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	names, err := store.List(ctx, "default/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(names, ",") != "default/sh.helm.release.v1.a.v1,default/sh.helm.release.v1.b.v1" {
+		t.Errorf("unexpected objects %v", names)
+	}
+
+	if err := store.Delete(ctx, "default/sh.helm.release.v1.a.v1", etag); !errors.Is(err, driver.ErrObjectPreconditionFailed) {
+		t.Errorf("expected the stale delete to fail, got %v", err)
+	}
+	if err := store.Delete(ctx, "default/sh.helm.release.v1.a.v1", updated); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(ctx, "default/sh.helm.release.v1.a.v1", updated); !errors.Is(err, driver.ErrObjectNotFound) {
+		t.Errorf("expected the object not to be found, got %v", err)
+	}
+}
+
+func TestS3ObjectStore(t *testing.T) {
+	clearAWSEnv(t)
+	srv := newBucketServer(t, "s3", "/releases")
+	t.Setenv("AWS_ENDPOINT_URL_S3", srv.URL)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDENV")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	store, err := NewS3ObjectStore("releases")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testObjectStore(t, store)
+	if auth := srv.lastRequest().Header.Get("Authorization"); !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDENV/") {
+		t.Errorf("unexpected authorization %q", auth)
+	}
+}
+
+func TestGCSObjectStore(t *testing.T) {
+	clearGoogleEnv(t)
+	srv := newBucketServer(t, "gcs", "/releases")
+	t.Setenv("STORAGE_EMULATOR_HOST", strings.TrimPrefix(srv.URL, "http://"))
+	t.Setenv("CLOUDSDK_AUTH_ACCESS_TOKEN", "token")
+
+	store, err := NewGCSObjectStore("releases")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testObjectStore(t, store)
+	if auth := srv.lastRequest().Header.Get("Authorization"); auth != "Bearer token" {
+		t.Errorf("unexpected authorization %q", auth)
+	}
+}
+
+func TestAzureBlobObjectStore(t *testing.T) {
+	clearAzureEnv(t)
+	srv := newBucketServer(t, "azblob", "/devstoreaccount1/releases")
+	key := base64.StdEncoding.EncodeToString([]byte("account-key"))
+	t.Setenv("AZURE_STORAGE_CONNECTION_STRING", "DefaultEndpointsProtocol=http;AccountName=devstoreaccount1;AccountKey="+key+";BlobEndpoint="+srv.URL+"/devstoreaccount1;")
+
+	store, err := NewAzureBlobObjectStore("releases")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testObjectStore(t, store)
+	if auth := srv.lastRequest().Header.Get("Authorization"); !strings.HasPrefix(auth, "SharedKey devstoreaccount1:") {
+		t.Errorf("unexpected authorization %q", auth)
+	}
+}
@@ -33,7 +33,9 @@ import (
 type OCIGetter struct {
 	opts      options
 	transport *http.Transport
-	once      sync.Once
+	// tlsKey is the TLS configuration the transport was created with.
+	tlsKey string
+	mu     sync.Mutex
 }
 
 // Get performs a Get from repo.Getter and returns the body.
@@ -67,6 +69,10 @@ func (g *OCIGetter) get(href string) (*bytes.Buffer, error) {
 			registry.PullOptWithProv(true))
 	}
 
+	if g.opts.digest != "" {
+		pullOpts = append(pullOpts, registry.PullOptDigest(g.opts.digest))
+	}
+
 	result, err := client.Pull(ref, pullOpts...)
 	if err != nil {
 		return nil, err
@@ -103,7 +109,17 @@ func (g *OCIGetter) newRegistryClient() (*registry.Client, error) {
 		return client, nil
 	}
 
-	g.once.Do(func() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// The transport is created again when the TLS configuration changes, so
+	// the connections use the rotated certificates
+	key := g.opts.tlsKey()
+	if g.transport == nil || key != g.tlsKey {
+		if g.transport != nil {
+			g.transport.CloseIdleConnections()
+		}
+		g.tlsKey = key
 		g.transport = &http.Transport{
 			// From https://github.com/google/go-containerregistry/blob/31786c6cbb82d6ec4fb8eb79cd9387905130534e/pkg/v1/remote/options.go#L87
 			DisableCompression: true,
@@ -121,21 +137,23 @@ func (g *OCIGetter) newRegistryClient() (*registry.Client, error) {
 			ExpectContinueTimeout: 1 * time.Second,
 			Proxy:                 http.ProxyFromEnvironment,
 		}
-	})
-
-	if (g.opts.certFile != "" && g.opts.keyFile != "") || g.opts.caFile != "" || g.opts.insecureSkipVerifyTLS {
-		tlsConf, err := tlsutil.NewClientTLS(g.opts.certFile, g.opts.keyFile, g.opts.caFile, g.opts.insecureSkipVerifyTLS)
-		if err != nil {
-			return nil, fmt.Errorf("can't create TLS config for client: %w", err)
-		}
 
-		sni, err := urlutil.ExtractHostname(g.opts.url)
-		if err != nil {
-			return nil, err
+		if (g.opts.certFile != "" && g.opts.keyFile != "") || g.opts.caFile != "" || g.opts.insecureSkipVerifyTLS {
+			tlsConf, err := tlsutil.NewClientTLS(g.opts.certFile, g.opts.keyFile, g.opts.caFile, g.opts.insecureSkipVerifyTLS)
+			if err != nil {
+				g.transport = nil
+				return nil, fmt.Errorf("can't create TLS config for client: %w", err)
+			}
+
+			sni, err := urlutil.ExtractHostname(g.opts.url)
+			if err != nil {
+				g.transport = nil
+				return nil, err
+			}
+			tlsConf.ServerName = sni
+
+			g.transport.TLSClientConfig = tlsConf
 		}
-		tlsConf.ServerName = sni
-
-		g.transport.TLSClientConfig = tlsConf
 	}
 
 	opts := []registry.ClientOption{registry.ClientOptHTTPClient(&http.Client{
@@ -27,6 +27,7 @@ import (
 
 	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/plugin"
+	"helm.sh/helm/v3/pkg/plugin/sdk"
 )
 
 // collectPlugins scans for getter plugins.
@@ -37,15 +38,22 @@ func collectPlugins(settings *cli.EnvSettings) (Providers, error) {
 		return nil, err
 	}
 	var result Providers
-	for _, plugin := range plugins {
-		for _, downloader := range plugin.Metadata.Downloaders {
+	for _, plug := range plugins {
+		for _, downloader := range plug.Metadata.Downloaders {
+			protocolVersion := plugin.DownloaderProtocolV1
+			if downloader.ProtocolVersion == plugin.DownloaderProtocolV2 {
+				protocolVersion = plugin.DownloaderProtocolV2
+			}
 			result = append(result, Provider{
 				Schemes: downloader.Protocols,
-				New: NewPluginGetter(
+				New: newPluginGetter(
 					downloader.Command,
 					settings,
-					plugin.Metadata.Name,
-					plugin.Dir,
+					plug.Metadata.Name,
+					plug.Dir,
+					protocolVersion,
+					plug.NewRequest(settings, sdk.KindDownloader),
+					plug.Metadata.Capabilities,
 				),
 			})
 		}
@@ -56,11 +64,18 @@ func collectPlugins(settings *cli.EnvSettings) (Providers, error) {
 // pluginGetter is a generic type to invoke custom downloaders,
 // implemented in plugins.
 type pluginGetter struct {
-	command  string
-	settings *cli.EnvSettings
-	name     string
-	base     string
-	opts     options
+	command         string
+	settings        *cli.EnvSettings
+	name            string
+	base            string
+	protocolVersion int
+	// request is the request of the plugin API passed to the command, nil
+	// if the plugin declares no version of the API.
+	request *sdk.Request
+	// capabilities are the capabilities of the plugin, nil if it declares
+	// none.
+	capabilities *plugin.Capabilities
+	opts         options
 }
 
 func (p *pluginGetter) setupOptionsEnv(env []string) []string {
@@ -75,11 +90,23 @@ func (p *pluginGetter) Get(href string, options ...Option) (*bytes.Buffer, error
 	for _, opt := range options {
 		opt(&p.opts)
 	}
+	if p.protocolVersion == plugin.DownloaderProtocolV2 {
+		return p.getV2(href)
+	}
 	commands := strings.Split(p.command, " ")
 	argv := append(commands[1:], p.opts.certFile, p.opts.keyFile, p.opts.caFile, href)
 	prog := exec.Command(filepath.Join(p.base, commands[0]), argv...)
 	plugin.SetupPluginEnv(p.settings, p.name, p.base)
-	prog.Env = p.setupOptionsEnv(os.Environ())
+	env, err := plugin.RequestEnv(p.setupOptionsEnv(os.Environ()), p.downloadRequest(href))
+	if err != nil {
+		return nil, err
+	}
+	prog.Env = env
+	cleanup, err := plugin.Sandbox(prog, p.name, p.capabilities)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
 	buf := bytes.NewBuffer(nil)
 	prog.Stdout = buf
 	prog.Stderr = os.Stderr
@@ -95,12 +122,25 @@ func (p *pluginGetter) Get(href string, options ...Option) (*bytes.Buffer, error
 
 // NewPluginGetter constructs a valid plugin getter
 func NewPluginGetter(command string, settings *cli.EnvSettings, name, base string) Constructor {
+	return newPluginGetter(command, settings, name, base, plugin.DownloaderProtocolV1, nil, nil)
+}
+
+// NewPluginGetterV2 constructs a plugin getter talking to the command with the
+// version 2 of the downloader protocol.
+func NewPluginGetterV2(command string, settings *cli.EnvSettings, name, base string) Constructor {
+	return newPluginGetter(command, settings, name, base, plugin.DownloaderProtocolV2, nil, nil)
+}
+
+func newPluginGetter(command string, settings *cli.EnvSettings, name, base string, protocolVersion int, request *sdk.Request, capabilities *plugin.Capabilities) Constructor {
 	return func(options ...Option) (Getter, error) {
 		result := &pluginGetter{
-			command:  command,
-			settings: settings,
-			name:     name,
-			base:     base,
+			command:         command,
+			settings:        settings,
+			name:            name,
+			base:            base,
+			protocolVersion: protocolVersion,
+			request:         request,
+			capabilities:    capabilities,
 		}
 		for _, opt := range options {
 			opt(&result.opts)
@@ -108,3 +148,25 @@ func NewPluginGetter(command string, settings *cli.EnvSettings, name, base strin
 		return result, nil
 	}
 }
+
+// downloadRequest returns the request of the plugin API of the download of
+// href, or nil if the plugin declares no version of the API.
+func (p *pluginGetter) downloadRequest(href string) *sdk.Request {
+	if p.request == nil {
+		return nil
+	}
+	req := *p.request
+	req.Download = &sdk.DownloadRequest{
+		ProtocolVersion:    p.protocolVersion,
+		URL:                href,
+		CertFile:           p.opts.certFile,
+		KeyFile:            p.opts.keyFile,
+		CAFile:             p.opts.caFile,
+		PassCredentialsAll: p.opts.passCredentialsAll,
+	}
+	if p.protocolVersion == plugin.DownloaderProtocolV1 {
+		req.Download.Username = p.opts.username
+		req.Download.Password = p.opts.password
+	}
+	return &req
+}
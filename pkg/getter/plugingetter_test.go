@@ -16,11 +16,15 @@ limitations under the License.
 package getter
 
 import (
+	"path/filepath"
+	"reflect"
 	"runtime"
 	"strings"
 	"testing"
 
 	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/plugin"
+	"helm.sh/helm/v3/pkg/plugin/sdk"
 )
 
 func TestCollectPlugins(t *testing.T) {
@@ -32,8 +36,8 @@ func TestCollectPlugins(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if len(p) != 2 {
-		t.Errorf("Expected 2 plugins, got %d: %v", len(p), p)
+	if len(p) != 3 {
+		t.Errorf("Expected 3 plugins, got %d: %v", len(p), p)
 	}
 
 	if _, err := p.ByScheme("test2"); err != nil {
@@ -99,3 +103,84 @@ func TestPluginSubCommands(t *testing.T) {
 		t.Errorf("Expected %q, got %q", expect, got)
 	}
 }
+
+func TestPluginGetterDownloadRequest(t *testing.T) {
+	env := cli.New()
+	request := &sdk.Request{APIVersion: sdk.APIVersionV1, Kind: sdk.KindDownloader}
+	opts := []Option{WithBasicAuth("user", "pass"), WithTLSClientConfig("cert", "key", "ca")}
+
+	for _, protocolVersion := range []int{plugin.DownloaderProtocolV1, plugin.DownloaderProtocolV2} {
+		g, err := newPluginGetter("get.sh", env, "test", ".", protocolVersion, request, nil)(opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := g.(*pluginGetter).downloadRequest("test://foo/bar")
+		expect := &sdk.DownloadRequest{
+			ProtocolVersion: protocolVersion,
+			URL:             "test://foo/bar",
+			CertFile:        "cert",
+			KeyFile:         "key",
+			CAFile:          "ca",
+		}
+		// The downloaders of the version 2 request the credentials
+		if protocolVersion == plugin.DownloaderProtocolV1 {
+			expect.Username, expect.Password = "user", "pass"
+		}
+		if !reflect.DeepEqual(req.Download, expect) {
+			t.Errorf("protocol %d: expected %+v, got %+v", protocolVersion, expect, req.Download)
+		}
+		if request.Download != nil {
+			t.Fatal("expected the request of the plugin unchanged")
+		}
+	}
+
+	g, err := NewPluginGetter("echo", env, "test", ".")()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req := g.(*pluginGetter).downloadRequest("test://foo/bar"); req != nil {
+		t.Errorf("expected no request without a plugin API version, got %+v", req)
+	}
+}
+
+func TestPluginGetterV2(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("TODO: refactor this test to work on windows")
+	}
+
+	env := cli.New()
+	env.PluginsDirectory = pluginDir
+	pg := NewPluginGetterV2("get.sh", env, "testgetter3", filepath.Join(pluginDir, "testgetter3"))
+	g, err := pg(WithBasicAuth("user", "pass"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The body is streamed in chunks, with the credentials of the same host
+	data, err := g.Get("test3://data/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `{"type":"credentials","username":"user","password":"pass"}chunk`
+	if got := data.String(); got != expect {
+		t.Errorf("Expected %q, got %q", expect, got)
+	}
+
+	// The credentials are not passed to other hosts the download is
+	// redirected to
+	data, err = g.Get("test3://redirect/foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect = `{"type":"credentials"}chunk`
+	if got := data.String(); got != expect {
+		t.Errorf("Expected %q, got %q", expect, got)
+	}
+
+	if _, err := g.Get("test3://error/foo"); err == nil || !strings.Contains(err.Error(), "no such chart") {
+		t.Errorf("Expected the error of the plugin, got %v", err)
+	}
+	if _, err := g.Get("test3://data/foo", WithDigest("sha256:0000")); err == nil || !strings.Contains(err.Error(), "digest") {
+		t.Errorf("Expected a digest mismatch, got %v", err)
+	}
+}
@@ -0,0 +1,224 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/plugin"
+	"helm.sh/helm/v3/pkg/plugin/sdk"
+)
+
+// The downloader plugins of the version 2 of the protocol exchange the
+// sdk.DownloaderMessage messages with Helm, one per line. Helm writes a
+// sdk.DownloaderGet message on the standard input of the command, then reads
+// the messages of the plugin on its standard output, until the download ends.
+//
+// The standard error of the command is the standard error of Helm.
+
+// maxPluginRedirects is the number of redirects a download through plugins
+// follows.
+const maxPluginRedirects = 10
+
+// getV2 runs the downloader plugin command with the version 2 of the
+// protocol, following its redirects.
+func (p *pluginGetter) getV2(href string) (*bytes.Buffer, error) {
+	origin := href
+	for i := 0; ; i++ {
+		buf, redirect, err := p.run(origin, href)
+		if err != nil || redirect == "" {
+			return buf, err
+		}
+		if i == maxPluginRedirects {
+			return nil, errors.Errorf("plugin %q stopped after %d redirects", p.command, maxPluginRedirects)
+		}
+		u, err := url.Parse(redirect)
+		if err != nil {
+			return nil, errors.Wrapf(err, "plugin %q redirected to an invalid URL", p.command)
+		}
+		g, err := All(p.settings).ByScheme(u.Scheme)
+		if err != nil {
+			return nil, errors.Wrapf(err, "plugin %q redirected to %s", p.command, redirect)
+		}
+		pg, ok := g.(*pluginGetter)
+		if !ok || pg.protocolVersion != plugin.DownloaderProtocolV2 {
+			return g.Get(redirect, p.redirectOptions()...)
+		}
+		// Redirects between plugins are followed here, so they are bounded
+		pg.opts = p.opts
+		p, href = pg, redirect
+	}
+}
+
+// run runs the downloader plugin command for a download of href, redirected
+// from origin. It returns the body of the download, or the URL the plugin
+// redirected to.
+func (p *pluginGetter) run(origin, href string) (*bytes.Buffer, string, error) {
+	commands := strings.Split(p.command, " ")
+	prog := exec.Command(filepath.Join(p.base, commands[0]), commands[1:]...)
+	plugin.SetupPluginEnv(p.settings, p.name, p.base)
+	env, err := plugin.RequestEnv(os.Environ(), p.downloadRequest(href))
+	if err != nil {
+		return nil, "", err
+	}
+	prog.Env = env
+	cleanup, err := plugin.Sandbox(prog, p.name, p.capabilities)
+	if err != nil {
+		return nil, "", err
+	}
+	defer cleanup()
+	prog.Stderr = os.Stderr
+	stdin, err := prog.StdinPipe()
+	if err != nil {
+		return nil, "", err
+	}
+	stdout, err := prog.StdoutPipe()
+	if err != nil {
+		return nil, "", err
+	}
+	if err := prog.Start(); err != nil {
+		return nil, "", err
+	}
+
+	buf, redirect, err := p.exchange(origin, href, stdin, bufio.NewReader(stdout))
+	stdin.Close()
+	if err != nil {
+		// The plugin may still be writing, it is not waited for
+		prog.Process.Kill()
+		prog.Wait()
+		return nil, "", err
+	}
+	if err := prog.Wait(); err != nil {
+		return nil, "", errors.Errorf("plugin %q exited with error", p.command)
+	}
+	return buf, redirect, nil
+}
+
+// exchange sends the "get" message of href to the plugin and handles its
+// messages until the download ends.
+func (p *pluginGetter) exchange(origin, href string, in io.Writer, out *bufio.Reader) (*bytes.Buffer, string, error) {
+	enc := json.NewEncoder(in)
+	err := enc.Encode(&sdk.DownloaderMessage{
+		Type:                  sdk.DownloaderGet,
+		URL:                   href,
+		CertFile:              p.opts.certFile,
+		KeyFile:               p.opts.keyFile,
+		CAFile:                p.opts.caFile,
+		InsecureSkipTLSVerify: p.opts.insecureSkipVerifyTLS,
+		Headers:               p.opts.headers,
+		Version:               p.opts.version,
+		Digest:                p.opts.digest,
+	})
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "failed to send the download to plugin %q", p.command)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	hash := sha256.New()
+	for {
+		line, err := out.ReadBytes('\n')
+		if err != nil {
+			return nil, "", errors.Errorf("plugin %q ended the download unexpectedly", p.command)
+		}
+		var msg sdk.DownloaderMessage
+		if err := json.Unmarshal(line, &msg); err != nil {
+			return nil, "", errors.Wrapf(err, "plugin %q sent an invalid message", p.command)
+		}
+
+		switch msg.Type {
+		case sdk.DownloaderCredentials:
+			answer := &sdk.DownloaderMessage{Type: sdk.DownloaderCredentials}
+			if p.passCredentials(origin, msg.URL) {
+				answer.Username = p.opts.username
+				answer.Password = p.opts.password
+			}
+			if err := enc.Encode(answer); err != nil {
+				return nil, "", errors.Wrapf(err, "failed to send the credentials to plugin %q", p.command)
+			}
+		case sdk.DownloaderRedirect:
+			if msg.URL == "" {
+				return nil, "", errors.Errorf("plugin %q redirected to no URL", p.command)
+			}
+			return nil, msg.URL, nil
+		case sdk.DownloaderData:
+			if _, err := io.CopyN(io.MultiWriter(buf, hash), out, msg.Size); err != nil {
+				return nil, "", errors.Wrapf(err, "failed to read the body of the download from plugin %q", p.command)
+			}
+		case sdk.DownloaderDone:
+			digest := "sha256:" + hex.EncodeToString(hash.Sum(nil))
+			for _, want := range []string{msg.Digest, p.opts.digest} {
+				if strings.HasPrefix(want, "sha256:") && want != digest {
+					return nil, "", errors.Errorf("the body of %s downloaded by plugin %q has digest %s, but %s is required", href, p.command, digest, want)
+				}
+			}
+			return buf, "", nil
+		case sdk.DownloaderError:
+			return nil, "", errors.Errorf("plugin %q failed to download %s: %s", p.command, href, msg.Message)
+		default:
+			return nil, "", errors.Errorf("plugin %q sent an unknown message %q", p.command, msg.Type)
+		}
+	}
+}
+
+// passCredentials returns whether the credentials of a download of origin
+// are passed to target, like the credentials of HTTP downloads: only to the
+// same scheme and host as the URL of the options, or origin without one,
+// unless they are passed to all the domains.
+func (p *pluginGetter) passCredentials(origin, target string) bool {
+	if p.opts.passCredentialsAll {
+		return true
+	}
+	base := p.opts.url
+	if base == "" {
+		base = origin
+	}
+	u1, err := url.Parse(base)
+	if err != nil {
+		return false
+	}
+	u2, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+	return u1.Scheme == u2.Scheme && u1.Host == u2.Host
+}
+
+// redirectOptions returns the options of the download for the getter of a
+// redirect.
+func (p *pluginGetter) redirectOptions() []Option {
+	return []Option{
+		WithURL(p.opts.url),
+		WithBasicAuth(p.opts.username, p.opts.password),
+		WithPassCredentialsAll(p.opts.passCredentialsAll),
+		WithTLSClientConfig(p.opts.certFile, p.opts.keyFile, p.opts.caFile),
+		WithInsecureSkipVerifyTLS(p.opts.insecureSkipVerifyTLS),
+		WithHeaders(p.opts.headers),
+		WithTagName(p.opts.version),
+		WithDigest(p.opts.digest),
+	}
+}
@@ -0,0 +1,495 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// awsCredentials are the credentials requests to S3 are signed with.
+type awsCredentials struct {
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	SessionToken    string `json:"Token"`
+}
+
+// S3Getter is the backend handler of the charts and the indices of
+// repositories stored in Amazon S3 buckets, and in the object stores
+// compatible with it, at URLs like s3://bucket/charts/index.yaml.
+//
+// Requests are signed with the ambient AWS credentials: the AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment variables, the
+// profile of the shared credentials file, the web identity token of
+// AWS_WEB_IDENTITY_TOKEN_FILE, the container credentials of ECS, or the
+// instance profile of EC2, in this order. Objects of public buckets are
+// fetched anonymously if there are none. The region is set by AWS_REGION, and
+// AWS_ENDPOINT_URL_S3 sets the endpoint of other object stores.
+type S3Getter struct {
+	opts options
+	auth awsAuth
+}
+
+// Get performs a Get from repo.Getter and returns the body.
+func (g *S3Getter) Get(href string, options ...Option) (*bytes.Buffer, error) {
+	for _, opt := range options {
+		opt(&g.opts)
+	}
+	return g.get(href)
+}
+
+func (g *S3Getter) get(href string) (*bytes.Buffer, error) {
+	bucket, key, err := splitObjectURL(href)
+	if err != nil {
+		return nil, err
+	}
+	client, err := objectHTTPClient(g.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	region := awsRegion()
+	buf, err := getObject(client, s3ObjectURL(bucket, key, region), g.auth.signer(region))
+	// Buckets are read from the region they are in, which S3 returns if it
+	// is not the configured one.
+	if bucketRegion := s3BucketRegion(err); bucketRegion != "" && bucketRegion != region {
+		return getObject(client, s3ObjectURL(bucket, key, bucketRegion), g.auth.signer(bucketRegion))
+	}
+	return buf, err
+}
+
+// NewS3Getter constructs a Getter of the objects of S3 buckets.
+func NewS3Getter(options ...Option) (Getter, error) {
+	var client S3Getter
+
+	for _, opt := range options {
+		opt(&client.opts)
+	}
+
+	return &client, nil
+}
+
+// awsAuth signs the requests of S3 with the ambient credentials, looked up by
+// the first request.
+type awsAuth struct {
+	once sync.Once
+	// creds are the credentials found by the first request, nil if the
+	// requests are anonymous.
+	creds *awsCredentials
+	err   error
+}
+
+// signer returns the signer of the requests to region.
+func (a *awsAuth) signer(region string) objectSigner {
+	return func(req *http.Request) error {
+		a.once.Do(func() {
+			a.creds, a.err = lookupAWSCredentials(region)
+		})
+		if a.err != nil {
+			return a.err
+		}
+		if a.creds != nil {
+			signAWSRequest(req, a.creds, region, "s3", time.Now())
+		}
+		return nil
+	}
+}
+
+// s3BucketRegion returns the region of the bucket S3 returns with the error
+// of a request sent to another region, or an empty region.
+func s3BucketRegion(err error) string {
+	if se, ok := err.(*objectStatusError); ok {
+		return se.header.Get("X-Amz-Bucket-Region")
+	}
+	return ""
+}
+
+// awsRegion returns the region of the requests, us-east-1 by default.
+func awsRegion() string {
+	for _, env := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if region := os.Getenv(env); region != "" {
+			return region
+		}
+	}
+	return "us-east-1"
+}
+
+// s3ObjectURL returns the URL of the key of bucket in region. Buckets are
+// addressed in the path of custom endpoints and of the bucket names with dots,
+// which are not valid hostnames for TLS.
+func s3ObjectURL(bucket, key, region string) string {
+	path := "/" + awsURIEncode(key, false)
+	for _, env := range []string{"AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"} {
+		if endpoint := os.Getenv(env); endpoint != "" {
+			return strings.TrimSuffix(endpoint, "/") + "/" + bucket + path
+		}
+	}
+	if strings.Contains(bucket, ".") {
+		return fmt.Sprintf("https://s3.%s.amazonaws.com/%s%s", region, bucket, path)
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com%s", bucket, region, path)
+}
+
+// lookupAWSCredentials returns the ambient credentials, or nil if there are
+// none.
+func lookupAWSCredentials(region string) (*awsCredentials, error) {
+	if id := os.Getenv("AWS_ACCESS_KEY_ID"); id != "" {
+		return &awsCredentials{
+			AccessKeyID:     id,
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}, nil
+	}
+
+	creds, err := awsSharedCredentials()
+	if creds != nil || err != nil {
+		return creds, err
+	}
+
+	if tokenFile := os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"); tokenFile != "" {
+		creds, err := awsWebIdentityCredentials(tokenFile, os.Getenv("AWS_ROLE_ARN"), region)
+		return creds, errors.Wrap(err, "failed to assume the role of the web identity")
+	}
+
+	if os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI") != "" || os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI") != "" {
+		creds, err := awsContainerCredentials()
+		return creds, errors.Wrap(err, "failed to get the container credentials")
+	}
+
+	if strings.EqualFold(os.Getenv("AWS_EC2_METADATA_DISABLED"), "true") {
+		return nil, nil
+	}
+	// The requests are anonymous outside of EC2.
+	creds, _ = awsInstanceCredentials()
+	return creds, nil
+}
+
+// awsSharedCredentials returns the credentials of the AWS_PROFILE profile of
+// the shared credentials file, or nil if there are none.
+func awsSharedCredentials() (*awsCredentials, error) {
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, nil
+		}
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+	profile := os.Getenv("AWS_PROFILE")
+	if profile == "" {
+		profile = "default"
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	values := map[string]string{}
+	section := ""
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			section = strings.TrimSpace(line[1 : len(line)-1])
+		case section == profile:
+			if k, v, ok := strings.Cut(line, "="); ok {
+				values[strings.TrimSpace(k)] = strings.TrimSpace(v)
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", path)
+	}
+	if values["aws_access_key_id"] == "" {
+		return nil, nil
+	}
+	return &awsCredentials{
+		AccessKeyID:     values["aws_access_key_id"],
+		SecretAccessKey: values["aws_secret_access_key"],
+		SessionToken:    values["aws_session_token"],
+	}, nil
+}
+
+// awsWebIdentityCredentials assumes role with the web identity token of
+// tokenFile, as pods of EKS do.
+func awsWebIdentityCredentials(tokenFile, role, region string) (*awsCredentials, error) {
+	token, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := os.Getenv("AWS_ENDPOINT_URL_STS")
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://sts.%s.amazonaws.com", region)
+	}
+	session := os.Getenv("AWS_ROLE_SESSION_NAME")
+	if session == "" {
+		session = "helm"
+	}
+	req, err := postForm(endpoint, url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"Version":          {"2011-06-15"},
+		"RoleArn":          {role},
+		"RoleSessionName":  {session},
+		"WebIdentityToken": {strings.TrimSpace(string(token))},
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := credentialClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var result struct {
+		Credentials struct {
+			AccessKeyID     string `xml:"AccessKeyId"`
+			SecretAccessKey string `xml:"SecretAccessKey"`
+			SessionToken    string `xml:"SessionToken"`
+		} `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+	}
+	if err := xml.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+	return &awsCredentials{
+		AccessKeyID:     result.Credentials.AccessKeyID,
+		SecretAccessKey: result.Credentials.SecretAccessKey,
+		SessionToken:    result.Credentials.SessionToken,
+	}, nil
+}
+
+// awsContainerCredentials returns the credentials of the task role of an
+// ECS container.
+func awsContainerCredentials() (*awsCredentials, error) {
+	endpoint := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+	if endpoint == "" {
+		endpoint = "http://169.254.170.2" + os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI")
+	}
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN")
+	if tokenFile := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE"); tokenFile != "" {
+		b, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return nil, err
+		}
+		token = strings.TrimSpace(string(b))
+	}
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	creds := &awsCredentials{}
+	return creds, fetchCredentialJSON(credentialClient, req, creds)
+}
+
+// awsInstanceCredentials returns the credentials of the instance profile of
+// an EC2 instance, with the version 2 of the instance metadata service.
+func awsInstanceCredentials() (*awsCredentials, error) {
+	endpoint := os.Getenv("AWS_EC2_METADATA_SERVICE_ENDPOINT")
+	if endpoint == "" {
+		endpoint = "http://169.254.169.254"
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	client := metadataClient()
+
+	req, err := http.NewRequest(http.MethodPut, endpoint+"/latest/api/token", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Aws-Ec2-Metadata-Token-Ttl-Seconds", "21600")
+	token, err := fetchText(client, req)
+	if err != nil {
+		return nil, err
+	}
+
+	get := func(path string) (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodGet, endpoint+"/latest/meta-data/iam/security-credentials/"+path, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("X-Aws-Ec2-Metadata-Token", token)
+		return req, nil
+	}
+	req, err = get("")
+	if err != nil {
+		return nil, err
+	}
+	roles, err := fetchText(client, req)
+	if err != nil {
+		return nil, err
+	}
+	role, _, _ := strings.Cut(roles, "\n")
+	if role == "" {
+		return nil, errors.New("the instance has no instance profile")
+	}
+	if req, err = get(role); err != nil {
+		return nil, err
+	}
+	creds := &awsCredentials{}
+	return creds, fetchCredentialJSON(client, req, creds)
+}
+
+// fetchText sends req and returns the trimmed text of the response.
+func fetchText(client *http.Client, req *http.Request) (string, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("%s %s: %s", req.Method, req.URL.Redacted(), resp.Status)
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+// signAWSRequest signs req with the signature version 4 of AWS. The payload
+// is not signed unless its hash is set in the X-Amz-Content-Sha256 header.
+func signAWSRequest(req *http.Request, creds *awsCredentials, region, service string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	payloadHash := req.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash == "" {
+		payloadHash = "UNSIGNED-PAYLOAD"
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	// The host, the range and the x-amz-* headers are signed.
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "range" || strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", name, headers[name])
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	query := req.URL.Query()
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var canonicalQuery []string
+	for _, k := range keys {
+		vs := append([]string{}, query[k]...)
+		sort.Strings(vs)
+		for _, v := range vs {
+			canonicalQuery = append(canonicalQuery, awsURIEncode(k, true)+"="+awsURIEncode(v, true))
+		}
+	}
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		strings.Join(canonicalQuery, "&"),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{date, region, service, "aws4_request"}, "/")
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(hash[:])}, "\n")
+
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, s := range []string{date, region, service, "aws4_request"} {
+		key = hmacSHA256(key, s)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// awsURIEncode encodes s as AWS signatures expect, escaping every byte but the
+// unreserved characters, and the slashes unless encodeSlash is set.
+func awsURIEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,115 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sync"
+)
+
+// S3ObjectStore is the object store of the object storage driver of releases
+// keeping the releases in an Amazon S3 bucket, or in a bucket of an object
+// store compatible with it. Its requests are signed with the credentials, and
+// sent to the region and the endpoint, of S3Getter.
+//
+// The writes are conditional on the ETags of the objects, with the If-Match
+// and If-None-Match headers.
+type S3ObjectStore struct {
+	bucket string
+	client *http.Client
+	auth   awsAuth
+
+	mu sync.Mutex
+	// region is the region of the bucket, once S3 returned it.
+	region string
+}
+
+// NewS3ObjectStore returns an object store keeping the objects in bucket.
+func NewS3ObjectStore(bucket string, opts ...Option) (*S3ObjectStore, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	client, err := objectHTTPClient(o)
+	if err != nil {
+		return nil, err
+	}
+	return &S3ObjectStore{bucket: bucket, client: client, region: awsRegion()}, nil
+}
+
+// Get returns the content and the ETag of the named object.
+func (s *S3ObjectStore) Get(ctx context.Context, name string) ([]byte, string, error) {
+	header, content, err := s.request(ctx, http.MethodGet, name, "", nil, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	return content, header.Get("ETag"), nil
+}
+
+// Put writes the named object if its ETag is ifMatch, or if it does not exist
+// when ifMatch is empty.
+func (s *S3ObjectStore) Put(ctx context.Context, name string, data []byte, ifMatch string) (string, error) {
+	header := conditionHeader(ifMatch)
+	sum := sha256.Sum256(data)
+	header.Set("X-Amz-Content-Sha256", hex.EncodeToString(sum[:]))
+	header, _, err := s.request(ctx, http.MethodPut, name, "", header, data)
+	if err != nil {
+		return "", err
+	}
+	return header.Get("ETag"), nil
+}
+
+// Delete deletes the named object if its ETag is ifMatch.
+func (s *S3ObjectStore) Delete(ctx context.Context, name, ifMatch string) error {
+	_, _, err := s.request(ctx, http.MethodDelete, name, "", conditionHeader(ifMatch), nil)
+	return err
+}
+
+// List returns the names of the objects starting with prefix.
+func (s *S3ObjectStore) List(ctx context.Context, prefix string) ([]string, error) {
+	return listBucket(prefix, func(query string) ([]byte, error) {
+		_, content, err := s.request(ctx, http.MethodGet, "", query, nil, nil)
+		return content, err
+	})
+}
+
+// request sends a request of the key of the bucket, with the query, to the
+// region of the bucket, which S3 returns if it is not the configured one.
+func (s *S3ObjectStore) request(ctx context.Context, method, key, query string, header http.Header, data []byte) (http.Header, []byte, error) {
+	send := func(region string) (http.Header, []byte, error) {
+		objectURL := s3ObjectURL(s.bucket, key, region)
+		if query != "" {
+			objectURL += "?" + query
+		}
+		return objectRequest(ctx, s.client, method, objectURL, header, data, s.auth.signer(region))
+	}
+
+	s.mu.Lock()
+	region := s.region
+	s.mu.Unlock()
+	respHeader, content, err := send(region)
+	if bucketRegion := s3BucketRegion(err); bucketRegion != "" && bucketRegion != region {
+		s.mu.Lock()
+		s.region = bucketRegion
+		s.mu.Unlock()
+		return send(bucketRegion)
+	}
+	return respHeader, content, err
+}
@@ -0,0 +1,19 @@
+#!/bin/sh
+
+read -r request
+case "$request" in
+*'"url":"test3://redirect/'*)
+  echo '{"type":"redirect","url":"test3://data/redirected"}'
+  ;;
+*'"url":"test3://error/'*)
+  echo '{"type":"error","message":"no such chart"}'
+  ;;
+*)
+  # Stream the credentials answered by Helm, then a second chunk
+  echo '{"type":"credentials","url":"test3://data/"}'
+  read -r credentials
+  printf '{"type":"data","size":%d}\n%s' ${#credentials} "$credentials"
+  printf '{"type":"data","size":5}\nchunk'
+  echo '{"type":"done"}'
+  ;;
+esac
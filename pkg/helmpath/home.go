@@ -34,6 +34,24 @@ func CacheIndexFile(name string) string {
 	return name + "index.yaml"
 }
 
+// CacheIndexManifestFile returns the path to the manifest of the sharded
+// index for the given named repository.
+func CacheIndexManifestFile(name string) string {
+	if name != "" {
+		name += "-"
+	}
+	return name + "index-manifest.json"
+}
+
+// CacheIndexValidatorsFile returns the path to the HTTP validators of the
+// index for the given named repository.
+func CacheIndexValidatorsFile(name string) string {
+	if name != "" {
+		name += "-"
+	}
+	return name + "index-validators.json"
+}
+
 // CacheChartsFile returns the path to a text file listing all the charts
 // within the given named repository.
 func CacheChartsFile(name string) string {
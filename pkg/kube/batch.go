@@ -0,0 +1,152 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v3/pkg/kube"
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+)
+
+// defaultBatchRetryInterval is the initial delay before a failed batch is
+// retried. It doubles after each attempt.
+const defaultBatchRetryInterval = time.Second
+
+// BatchOptions configures ApplyInBatches.
+type BatchOptions struct {
+	// Size is the maximum number of resources applied per batch. If it is zero
+	// or less, all resources are applied in a single batch.
+	Size int
+	// Wait waits for the resources of each batch to be ready before the next
+	// batch is applied.
+	Wait bool
+	// WaitOptions configures the readiness check between batches.
+	WaitOptions WaitOptions
+	// Retries is the number of times a batch is retried after a transient
+	// failure, such as throttling or an unavailable API server.
+	Retries int
+	// RetryInterval is the initial delay between retries. It doubles after
+	// each attempt. Defaults to one second.
+	RetryInterval time.Duration
+}
+
+// ApplyInBatches applies target in batches of at most opts.Size resources,
+// keeping their order. Resources of original that are missing from target are
+// deleted once every batch has been applied. If original is empty, the
+// resources are created as Create would.
+//
+// A batch that fails with a transient error is retried from where it stopped:
+// batches that were applied are not applied again, and resources of the failed
+// batch that were already created are updated instead.
+func ApplyInBatches(c Interface, original, target ResourceList, force bool, opts BatchOptions) (*Result, error) {
+	batches := splitBatches(target, opts.Size)
+	res := &Result{}
+	for i, batch := range batches {
+		r, err := applyBatch(c, original, batch, force, opts)
+		mergeResults(res, r)
+		if err != nil {
+			return res, errors.Wrapf(err, "batch %d of %d failed", i+1, len(batches))
+		}
+		if opts.Wait && i < len(batches)-1 {
+			if err := WaitWithOptions(c, batch, opts.WaitOptions); err != nil {
+				return res, errors.Wrapf(err, "batch %d of %d did not become ready", i+1, len(batches))
+			}
+		}
+	}
+
+	if obsolete := original.Difference(target); len(obsolete) > 0 {
+		r, err := c.Update(obsolete, ResourceList{}, force)
+		mergeResults(res, r)
+		if err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+func applyBatch(c Interface, original, batch ResourceList, force bool, opts BatchOptions) (*Result, error) {
+	interval := opts.RetryInterval
+	if interval <= 0 {
+		interval = defaultBatchRetryInterval
+	}
+
+	res := &Result{}
+	var err error
+	for attempt := 0; ; attempt++ {
+		var r *Result
+		switch {
+		case attempt == 0 && len(original) == 0:
+			r, err = c.Create(batch)
+		case attempt == 0:
+			r, err = c.Update(original.Intersect(batch), batch, force)
+		default:
+			// Part of the batch may have been applied by the failed attempt,
+			// so every resource is treated as existing.
+			r, err = c.Update(retryOriginal(original, batch), batch, force)
+		}
+		mergeResults(res, r)
+		if err == nil || attempt >= opts.Retries || !isTransientError(err) {
+			return res, err
+		}
+		time.Sleep(interval)
+		interval *= 2
+	}
+}
+
+// retryOriginal returns the original state of each resource of batch, using
+// the desired state for resources that did not exist before.
+func retryOriginal(original, batch ResourceList) ResourceList {
+	out := make(ResourceList, 0, len(batch))
+	for _, info := range batch {
+		if o := original.Get(info); o != nil {
+			out = append(out, o)
+		} else {
+			out = append(out, info)
+		}
+	}
+	return out
+}
+
+func splitBatches(resources ResourceList, size int) []ResourceList {
+	if size <= 0 || len(resources) <= size {
+		return []ResourceList{resources}
+	}
+	var batches []ResourceList
+	for start := 0; start < len(resources); start += size {
+		end := start + size
+		if end > len(resources) {
+			end = len(resources)
+		}
+		batches = append(batches, resources[start:end])
+	}
+	return batches
+}
+
+// isTransientError reports whether err is likely to go away if the request is
+// repeated.
+func isTransientError(err error) bool {
+	return apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) ||
+		apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsInternalError(err) ||
+		utilnet.IsConnectionReset(err) ||
+		utilnet.IsConnectionRefused(err) ||
+		utilnet.IsProbableEOF(err)
+}
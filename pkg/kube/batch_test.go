@@ -0,0 +1,194 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+// batchClient records the calls made by ApplyInBatches. The first failures
+// calls to Create or Update that include failOn fail with err.
+type batchClient struct {
+	Interface
+	calls    []string
+	failOn   string
+	failures int
+	err      error
+}
+
+func (b *batchClient) fail(resources ResourceList) error {
+	if b.failures > 0 && resources.Contains(newBatchInfo(b.failOn)) {
+		b.failures--
+		return b.err
+	}
+	return nil
+}
+
+func (b *batchClient) Create(resources ResourceList) (*Result, error) {
+	b.calls = append(b.calls, "create "+batchNames(resources))
+	if err := b.fail(resources); err != nil {
+		return nil, err
+	}
+	return &Result{Created: resources}, nil
+}
+
+func (b *batchClient) Update(original, target ResourceList, _ bool) (*Result, error) {
+	b.calls = append(b.calls, fmt.Sprintf("update %s -> %s", batchNames(original), batchNames(target)))
+	if err := b.fail(target); err != nil {
+		return &Result{}, err
+	}
+	return &Result{Updated: target.Intersect(original), Deleted: original.Difference(target)}, nil
+}
+
+func (b *batchClient) Wait(resources ResourceList, _ time.Duration) error {
+	b.calls = append(b.calls, "wait "+batchNames(resources))
+	return nil
+}
+
+func newBatchInfo(name string) *resource.Info {
+	return &resource.Info{
+		Name:      name,
+		Namespace: "default",
+		Mapping:   &meta.RESTMapping{GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}},
+	}
+}
+
+func newBatchList(names ...string) ResourceList {
+	var rl ResourceList
+	for _, name := range names {
+		rl = append(rl, newBatchInfo(name))
+	}
+	return rl
+}
+
+func batchNames(rl ResourceList) string {
+	names := make([]string, len(rl))
+	for i, info := range rl {
+		names[i] = info.Name
+	}
+	return "[" + strings.Join(names, ",") + "]"
+}
+
+func TestApplyInBatches(t *testing.T) {
+	unavailable := apierrors.NewServiceUnavailable("overloaded")
+	tests := []struct {
+		name     string
+		original ResourceList
+		target   ResourceList
+		opts     BatchOptions
+		client   *batchClient
+		expected []string
+		wantErr  string
+	}{
+		{
+			name:   "create in batches with waits",
+			target: newBatchList("a", "b", "c", "d", "e"),
+			opts:   BatchOptions{Size: 2, Wait: true},
+			client: &batchClient{},
+			expected: []string{
+				"create [a,b]", "wait [a,b]",
+				"create [c,d]", "wait [c,d]",
+				"create [e]",
+			},
+		},
+		{
+			name:     "update in batches deletes obsolete resources last",
+			original: newBatchList("a", "b", "old"),
+			target:   newBatchList("a", "b", "c"),
+			opts:     BatchOptions{Size: 2},
+			client:   &batchClient{},
+			expected: []string{
+				"update [a,b] -> [a,b]",
+				"update [] -> [c]",
+				"update [old] -> []",
+			},
+		},
+		{
+			name:   "transient failure resumes the failed batch",
+			target: newBatchList("a", "b", "c", "d"),
+			opts:   BatchOptions{Size: 2, Retries: 2, RetryInterval: time.Millisecond},
+			client: &batchClient{failOn: "c", failures: 2, err: unavailable},
+			expected: []string{
+				"create [a,b]",
+				"create [c,d]",
+				"update [c,d] -> [c,d]",
+				"update [c,d] -> [c,d]",
+			},
+		},
+		{
+			name:   "retries are exhausted",
+			target: newBatchList("a", "b", "c"),
+			opts:   BatchOptions{Size: 2, Retries: 1, RetryInterval: time.Millisecond},
+			client: &batchClient{failOn: "c", failures: 5, err: unavailable},
+			expected: []string{
+				"create [a,b]",
+				"create [c]",
+				"update [c] -> [c]",
+			},
+			wantErr: "batch 2 of 2 failed: overloaded",
+		},
+		{
+			name:   "permanent failures are not retried",
+			target: newBatchList("a", "b"),
+			opts:   BatchOptions{Size: 1, Retries: 3, RetryInterval: time.Millisecond},
+			client: &batchClient{failOn: "a", failures: 1, err: apierrors.NewBadRequest("invalid")},
+			expected: []string{
+				"create [a]",
+			},
+			wantErr: "batch 1 of 2 failed: invalid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ApplyInBatches(tt.client, tt.original, tt.target, false, tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("expected error %q, got %v", tt.wantErr, err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tt.client.calls, tt.expected) {
+				t.Errorf("expected calls\n%v\ngot\n%v", tt.expected, tt.client.calls)
+			}
+		})
+	}
+}
+
+func TestApplyInBatchesResult(t *testing.T) {
+	client := &batchClient{}
+	res, err := ApplyInBatches(client, newBatchList("a", "old"), newBatchList("a", "b", "c"), false, BatchOptions{Size: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := batchNames(res.Updated); got != "[a]" {
+		t.Errorf("expected a to be updated, got %s", got)
+	}
+	if got := batchNames(res.Deleted); got != "[old]" {
+		t.Errorf("expected old to be deleted, got %s", got)
+	}
+}
@@ -283,30 +283,28 @@ func getResource(info *resource.Info) (runtime.Object, error) {
 
 // Wait waits up to the given timeout for the specified resources to be ready.
 func (c *Client) Wait(resources ResourceList, timeout time.Duration) error {
-	cs, err := c.getKubeClient()
-	if err != nil {
-		return err
-	}
-	checker := NewReadyChecker(cs, c.Log, PausedAsReady(true))
-	w := waiter{
-		c:       checker,
-		log:     c.Log,
-		timeout: timeout,
-	}
-	return w.waitForResources(resources)
+	return c.WaitWithOptions(resources, WaitOptions{Timeout: timeout})
 }
 
 // WaitWithJobs wait up to the given timeout for the specified resources to be ready, including jobs.
 func (c *Client) WaitWithJobs(resources ResourceList, timeout time.Duration) error {
+	return c.WaitWithOptions(resources, WaitOptions{Timeout: timeout, WaitForJobs: true})
+}
+
+// WaitWithOptions waits for the specified resources to be ready. Resources
+// annotated with WaitTimeoutAnnotation, or whose kind is listed in
+// opts.KindTimeouts, are given their own timeout instead of opts.Timeout.
+func (c *Client) WaitWithOptions(resources ResourceList, opts WaitOptions) error {
 	cs, err := c.getKubeClient()
 	if err != nil {
 		return err
 	}
-	checker := NewReadyChecker(cs, c.Log, PausedAsReady(true), CheckJobs(true))
+	checker := NewReadyChecker(cs, c.Log, PausedAsReady(true), CheckJobs(opts.WaitForJobs))
 	w := waiter{
-		c:       checker,
-		log:     c.Log,
-		timeout: timeout,
+		c:            checker,
+		log:          c.Log,
+		timeout:      opts.Timeout,
+		kindTimeouts: opts.KindTimeouts,
 	}
 	return w.waitForResources(resources)
 }
@@ -397,7 +395,11 @@ func (c *Client) Update(original, target ResourceList, force bool) (*Result, err
 		}
 
 		helper := resource.NewHelper(info.Client, info.Mapping).WithFieldManager(getManagedFieldsManager())
-		if _, err := helper.Get(info.Namespace, info.Name); err != nil {
+		err = retryOnUnauthorized(func() error {
+			_, err := helper.Get(info.Namespace, info.Name)
+			return err
+		})
+		if err != nil {
 			if !apierrors.IsNotFound(err) {
 				return errors.Wrap(err, "could not get information about the resource")
 			}
@@ -421,7 +423,10 @@ func (c *Client) Update(original, target ResourceList, force bool) (*Result, err
 			return errors.Errorf("no %s with the name %q found", kind, info.Name)
 		}
 
-		if err := updateResource(c, info, originalInfo.Object, force); err != nil {
+		err = retryOnUnauthorized(func() error {
+			return updateResource(c, info, originalInfo.Object, force)
+		})
+		if err != nil {
 			c.Log("error updating the resource %q:\n\t %v", info.Name, err)
 			updateErrors = append(updateErrors, err.Error())
 		}
@@ -596,7 +601,11 @@ func batchPerform(infos ResourceList, fn func(*resource.Info) error, errs chan<-
 }
 
 func createResource(info *resource.Info) error {
-	obj, err := resource.NewHelper(info.Client, info.Mapping).WithFieldManager(getManagedFieldsManager()).Create(info.Namespace, true, info.Object)
+	var obj runtime.Object
+	err := retryOnUnauthorized(func() (err error) {
+		obj, err = resource.NewHelper(info.Client, info.Mapping).WithFieldManager(getManagedFieldsManager()).Create(info.Namespace, true, info.Object)
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -605,8 +614,10 @@ func createResource(info *resource.Info) error {
 
 func deleteResource(info *resource.Info, policy metav1.DeletionPropagation) error {
 	opts := &metav1.DeleteOptions{PropagationPolicy: &policy}
-	_, err := resource.NewHelper(info.Client, info.Mapping).WithFieldManager(getManagedFieldsManager()).DeleteWithOptions(info.Namespace, info.Name, opts)
-	return err
+	return retryOnUnauthorized(func() error {
+		_, err := resource.NewHelper(info.Client, info.Mapping).WithFieldManager(getManagedFieldsManager()).DeleteWithOptions(info.Namespace, info.Name, opts)
+		return err
+	})
 }
 
 func createPatch(target *resource.Info, current runtime.Object) ([]byte, types.PatchType, error) {
@@ -825,9 +836,13 @@ func (c *Client) WaitAndGetCompletedPodPhase(name string, timeout time.Duration)
 		return v1.PodUnknown, err
 	}
 	to := int64(timeout)
-	watcher, err := client.CoreV1().Pods(c.namespace()).Watch(context.Background(), metav1.ListOptions{
-		FieldSelector:  fmt.Sprintf("metadata.name=%s", name),
-		TimeoutSeconds: &to,
+	var watcher watch.Interface
+	err = retryOnUnauthorized(func() (err error) {
+		watcher, err = client.CoreV1().Pods(c.namespace()).Watch(context.Background(), metav1.ListOptions{
+			FieldSelector:  fmt.Sprintf("metadata.name=%s", name),
+			TimeoutSeconds: &to,
+		})
+		return err
 	})
 	if err != nil {
 		return v1.PodUnknown, err
@@ -0,0 +1,41 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v3/pkg/kube"
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// unauthorizedRetries is the number of times a request rejected with 401
+// Unauthorized is repeated.
+//
+// Credentials issued by exec plugins (and other refreshable auth providers)
+// can expire in the middle of a long operation such as --wait. When a request
+// is rejected, client-go runs the plugin again before returning the response,
+// so repeating the request uses the refreshed credentials.
+const unauthorizedRetries = 1
+
+// retryOnUnauthorized calls fn and repeats it if it fails because the
+// credentials were rejected. A rejected request was not processed by the API
+// server, so repeating it is always safe.
+func retryOnUnauthorized(fn func() error) error {
+	err := fn()
+	for i := 0; i < unauthorizedRetries && apierrors.IsUnauthorized(err); i++ {
+		err = fn()
+	}
+	return err
+}
@@ -0,0 +1,215 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/rest/fake"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+)
+
+// expiringCredentials simulates credentials that expire once during an
+// operation: the first request is rejected with 401 Unauthorized, after which
+// the credentials are considered refreshed.
+type expiringCredentials struct {
+	mu       sync.Mutex
+	rejected int
+	expired  bool
+}
+
+func (e *expiringCredentials) reject() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.expired {
+		e.expired = false
+		e.rejected++
+		return true
+	}
+	return false
+}
+
+func unauthorizedResponse() (*http.Response, error) {
+	status := apierrors.NewUnauthorized("token has expired").ErrStatus
+	return newResponse(http.StatusUnauthorized, &status)
+}
+
+func TestRetryOnUnauthorized(t *testing.T) {
+	calls := 0
+	err := retryOnUnauthorized(func() error {
+		calls++
+		if calls == 1 {
+			return apierrors.NewUnauthorized("token has expired")
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Errorf("expected a successful retry, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = retryOnUnauthorized(func() error {
+		calls++
+		return apierrors.NewUnauthorized("invalid token")
+	})
+	if !apierrors.IsUnauthorized(err) || calls != unauthorizedRetries+1 {
+		t.Errorf("expected unauthorized error after %d calls, got %v after %d calls", unauthorizedRetries+1, err, calls)
+	}
+
+	calls = 0
+	err = retryOnUnauthorized(func() error {
+		calls++
+		return errors.New("boom")
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("expected other errors not to be retried, got %v after %d calls", err, calls)
+	}
+}
+
+func TestCreateWithExpiredCredentials(t *testing.T) {
+	creds := &expiringCredentials{expired: true}
+	pods := newPodList("starfish")
+
+	c := newTestClient(t)
+	c.Factory.(*cmdtesting.TestFactory).UnstructuredClient = &fake.RESTClient{
+		NegotiatedSerializer: unstructuredSerializer,
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			if creds.reject() {
+				return unauthorizedResponse()
+			}
+			if req.Method == "POST" && req.URL.Path == "/namespaces/default/pods" {
+				return newResponse(201, &pods.Items[0])
+			}
+			t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+			return nil, nil
+		}),
+	}
+
+	resources, err := c.Build(objBody(&pods), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Create(resources); err != nil {
+		t.Fatalf("expected create to succeed with refreshed credentials, got %s", err)
+	}
+	if creds.rejected != 1 {
+		t.Errorf("expected one rejected request, got %d", creds.rejected)
+	}
+}
+
+func TestUpdateWithExpiredCredentials(t *testing.T) {
+	creds := &expiringCredentials{}
+	current := newPodList("starfish")
+	target := newPodList("starfish")
+	target.Items[0].Spec.Containers[0].Image = "abc/app:v5"
+
+	c := newTestClient(t)
+	c.Factory.(*cmdtesting.TestFactory).UnstructuredClient = &fake.RESTClient{
+		NegotiatedSerializer: unstructuredSerializer,
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			if creds.reject() {
+				return unauthorizedResponse()
+			}
+			switch {
+			case req.Method == "GET" && req.URL.Path == "/namespaces/default/pods/starfish":
+				return newResponse(200, &current.Items[0])
+			case req.Method == "PATCH" && req.URL.Path == "/namespaces/default/pods/starfish":
+				return newResponse(200, &target.Items[0])
+			}
+			t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+			return nil, nil
+		}),
+	}
+
+	original, err := c.Build(objBody(&current), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	desired, err := c.Build(objBody(&target), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Expire the credentials for the first request of the update.
+	creds.expired = true
+	result, err := c.Update(original, desired, false)
+	if err != nil {
+		t.Fatalf("expected update to succeed with refreshed credentials, got %s", err)
+	}
+	if len(result.Updated) != 1 {
+		t.Errorf("expected 1 resource updated, got %d", len(result.Updated))
+	}
+	if creds.rejected != 1 {
+		t.Errorf("expected one rejected request, got %d", creds.rejected)
+	}
+}
+
+func TestWaitWithExpiredCredentials(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping test that polls the API server")
+	}
+
+	creds := &expiringCredentials{}
+	pods := newPodList("starfish")
+	ready := newPodWithStatus("starfish", v1.PodStatus{
+		Phase:      v1.PodRunning,
+		Conditions: []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}},
+	}, "")
+
+	c := newTestClient(t)
+	tf := c.Factory.(*cmdtesting.TestFactory)
+	tf.UnstructuredClient = &fake.RESTClient{
+		NegotiatedSerializer: unstructuredSerializer,
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+			return nil, nil
+		}),
+	}
+	tf.Client = &fake.RESTClient{
+		NegotiatedSerializer: unstructuredSerializer,
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			if creds.reject() {
+				return unauthorizedResponse()
+			}
+			if req.Method == "GET" && req.URL.Path == "/api/v1/namespaces/default/pods/starfish" {
+				return newResponse(200, &ready)
+			}
+			t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+			return nil, nil
+		}),
+	}
+
+	resources, err := c.Build(objBody(&pods), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The credentials expire in the middle of the wait.
+	creds.expired = true
+	if err := c.Wait(resources, 10*time.Second); err != nil {
+		t.Fatalf("expected wait to succeed with refreshed credentials, got %s", err)
+	}
+	if creds.rejected != 1 {
+		t.Errorf("expected one rejected request, got %d", creds.rejected)
+	}
+}
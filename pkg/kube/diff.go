@@ -0,0 +1,281 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v3/pkg/kube"
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+// DiffAction describes what would happen to a resource if its desired state
+// were applied to the cluster.
+type DiffAction string
+
+const (
+	// DiffActionCreate indicates the resource does not exist in the cluster.
+	DiffActionCreate DiffAction = "create"
+	// DiffActionUpdate indicates the live resource differs from the desired state.
+	DiffActionUpdate DiffAction = "update"
+	// DiffActionNone indicates the live resource matches the desired state.
+	DiffActionNone DiffAction = "none"
+	// DiffActionDelete indicates the resource would be removed from the cluster.
+	DiffActionDelete DiffAction = "delete"
+)
+
+// FieldDiff is a single field-level difference between a live object and its
+// desired state.
+type FieldDiff struct {
+	// Path is the location of the field, e.g. "spec.template.spec.containers[0].image".
+	Path string `json:"path"`
+	// Live is the value of the field in the cluster. It is nil if the field is not set.
+	Live interface{} `json:"live,omitempty"`
+	// Desired is the value the field would have after applying. It is nil if the field would be removed.
+	Desired interface{} `json:"desired,omitempty"`
+}
+
+// ResourceDiff is the structured difference between a live resource and the
+// desired state for it.
+type ResourceDiff struct {
+	APIVersion string      `json:"apiVersion"`
+	Kind       string      `json:"kind"`
+	Namespace  string      `json:"namespace,omitempty"`
+	Name       string      `json:"name"`
+	Action     DiffAction  `json:"action"`
+	Fields     []FieldDiff `json:"fields,omitempty"`
+
+	// Live is the object as currently stored in the cluster, or nil if it does not exist.
+	Live *unstructured.Unstructured `json:"-"`
+	// Desired is the object as the API server would store it after applying.
+	Desired *unstructured.Unstructured `json:"-"`
+}
+
+// Changed reports whether applying the desired state would modify the cluster.
+func (d ResourceDiff) Changed() bool {
+	return d.Action != DiffActionNone
+}
+
+// RedactedValue replaces the values of the data of the Secrets in the diffs
+// redacted by RedactSecrets.
+const RedactedValue = "<redacted>"
+
+// secretDataFields are the fields of a Secret holding its data.
+var secretDataFields = []string{"data", "stringData"}
+
+// RedactSecrets returns a copy of the diff in which the values of the data of
+// a Secret are replaced with RedactedValue, so that the diff can be shown
+// without disclosing them. The changed fields are still listed, and the
+// objects of the Secret are dropped.
+func (d ResourceDiff) RedactSecrets() ResourceDiff {
+	if d.Kind != "Secret" || d.APIVersion != "" && d.APIVersion != "v1" {
+		return d
+	}
+	d.Live, d.Desired = nil, nil
+	fields := make([]FieldDiff, len(d.Fields))
+	for i, f := range d.Fields {
+		if isSecretDataField(f.Path) {
+			if f.Live != nil {
+				f.Live = RedactedValue
+			}
+			if f.Desired != nil {
+				f.Desired = RedactedValue
+			}
+		}
+		fields[i] = f
+	}
+	d.Fields = fields
+	return d
+}
+
+func isSecretDataField(path string) bool {
+	for _, field := range secretDataFields {
+		if path == field || strings.HasPrefix(path, field+".") || strings.HasPrefix(path, field+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoredDiffFields are server-populated fields that never represent a
+// difference in desired state.
+var ignoredDiffFields = [][]string{
+	{"status"},
+	{"metadata", "managedFields"},
+	{"metadata", "resourceVersion"},
+	{"metadata", "uid"},
+	{"metadata", "generation"},
+	{"metadata", "creationTimestamp"},
+	{"metadata", "selfLink"},
+	{"metadata", "annotations", "kubectl.kubernetes.io/last-applied-configuration"},
+	{"metadata", "annotations", "deployment.kubernetes.io/revision"},
+}
+
+// GetDiff compares each of the given resources against the live object in the
+// cluster. The desired state is normalized with a server-side apply dry-run so
+// API server defaults and mutating admission do not show up as differences.
+func (c *Client) GetDiff(resources ResourceList) ([]ResourceDiff, error) {
+	diffs := make([]ResourceDiff, 0, len(resources))
+	err := resources.Visit(func(info *resource.Info, err error) error {
+		if err != nil {
+			return err
+		}
+		d, err := c.diffResource(info)
+		if err != nil {
+			return errors.Wrapf(err, "unable to diff %s %q", info.Mapping.GroupVersionKind.Kind, info.Name)
+		}
+		diffs = append(diffs, d)
+		return nil
+	})
+	return diffs, err
+}
+
+func (c *Client) diffResource(info *resource.Info) (ResourceDiff, error) {
+	gvk := info.Mapping.GroupVersionKind
+	d := ResourceDiff{
+		APIVersion: gvk.GroupVersion().String(),
+		Kind:       gvk.Kind,
+		Namespace:  info.Namespace,
+		Name:       info.Name,
+	}
+
+	helper := resource.NewHelper(info.Client, info.Mapping).WithFieldManager(getManagedFieldsManager())
+	liveObj, err := helper.Get(info.Namespace, info.Name)
+	notFound := apierrors.IsNotFound(err)
+	if err != nil && !notFound {
+		return d, errors.Wrap(err, "could not get information about the resource")
+	}
+
+	data, err := runtime.Encode(unstructured.UnstructuredJSONScheme, info.Object)
+	if err != nil {
+		return d, errors.Wrap(err, "serializing target configuration")
+	}
+	force := true
+	desiredObj, err := helper.DryRun(true).Patch(info.Namespace, info.Name, types.ApplyPatchType, data, &metav1.PatchOptions{Force: &force})
+	if err != nil {
+		return d, errors.Wrap(err, "server-side dry-run failed")
+	}
+	desired, err := toUnstructured(desiredObj)
+	if err != nil {
+		return d, err
+	}
+	d.Desired = desired
+
+	if notFound {
+		d.Action = DiffActionCreate
+		d.Fields = diffValues("", nil, normalizeForDiff(desired.Object))
+		return d, nil
+	}
+	live, err := toUnstructured(liveObj)
+	if err != nil {
+		return d, err
+	}
+	d.Live = live
+
+	d.Fields = diffValues("", normalizeForDiff(live.Object), normalizeForDiff(desired.Object))
+	if len(d.Fields) == 0 {
+		d.Action = DiffActionNone
+	} else {
+		d.Action = DiffActionUpdate
+	}
+	return d, nil
+}
+
+func toUnstructured(obj runtime.Object) (*unstructured.Unstructured, error) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		return u, nil
+	}
+	m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to convert object to unstructured")
+	}
+	return &unstructured.Unstructured{Object: m}, nil
+}
+
+// normalizeForDiff returns a copy of obj with the ignored fields removed.
+func normalizeForDiff(obj map[string]interface{}) map[string]interface{} {
+	out := runtime.DeepCopyJSON(obj)
+	for _, path := range ignoredDiffFields {
+		unstructured.RemoveNestedField(out, path...)
+	}
+	for _, field := range []string{"annotations", "labels"} {
+		if m, ok, _ := unstructured.NestedMap(out, "metadata", field); ok && len(m) == 0 {
+			unstructured.RemoveNestedField(out, "metadata", field)
+		}
+	}
+	return out
+}
+
+// diffValues recursively compares two decoded JSON values and returns the
+// differing leaves, sorted by path.
+func diffValues(path string, live, desired interface{}) []FieldDiff {
+	liveMap, liveIsMap := live.(map[string]interface{})
+	desiredMap, desiredIsMap := desired.(map[string]interface{})
+	if liveIsMap && desiredIsMap || live == nil && desiredIsMap || liveIsMap && desired == nil {
+		keys := map[string]struct{}{}
+		for k := range liveMap {
+			keys[k] = struct{}{}
+		}
+		for k := range desiredMap {
+			keys[k] = struct{}{}
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+
+		var out []FieldDiff
+		for _, k := range sorted {
+			out = append(out, diffValues(joinFieldPath(path, k), liveMap[k], desiredMap[k])...)
+		}
+		return out
+	}
+
+	liveList, liveIsList := live.([]interface{})
+	desiredList, desiredIsList := desired.([]interface{})
+	if liveIsList && desiredIsList && len(liveList) == len(desiredList) {
+		var out []FieldDiff
+		for i := range liveList {
+			out = append(out, diffValues(fmt.Sprintf("%s[%d]", path, i), liveList[i], desiredList[i])...)
+		}
+		return out
+	}
+
+	if reflect.DeepEqual(live, desired) {
+		return nil
+	}
+	return []FieldDiff{{Path: path, Live: live, Desired: desired}}
+}
+
+func joinFieldPath(path, key string) string {
+	if strings.ContainsAny(key, ".[]") {
+		return fmt.Sprintf("%s[%q]", path, key)
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
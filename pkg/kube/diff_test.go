@@ -0,0 +1,180 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest/fake"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+)
+
+func TestGetDiff(t *testing.T) {
+	desired := newPodList("starfish", "otter", "dolphin")
+	desired.Items[0].Spec.Containers[0].Image = "abc/app:v5"
+
+	live := newPodList("starfish", "otter")
+	for i := range live.Items {
+		live.Items[i].ResourceVersion = "42"
+		live.Items[i].UID = "some-uid"
+		live.Items[i].Status.Phase = v1.PodRunning
+	}
+
+	c := newTestClient(t)
+	c.Factory.(*cmdtesting.TestFactory).UnstructuredClient = &fake.RESTClient{
+		NegotiatedSerializer: unstructuredSerializer,
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			p, m := req.URL.Path, req.Method
+			if m == "PATCH" {
+				if got := req.Header.Get("Content-Type"); got != string(types.ApplyPatchType) {
+					t.Errorf("expected apply patch, got %q", got)
+				}
+				if got := req.URL.Query().Get("dryRun"); got != "All" {
+					t.Errorf("expected dry-run request, got dryRun=%q", got)
+				}
+			}
+			switch {
+			case p == "/namespaces/default/pods/starfish" && m == "GET":
+				return newResponse(200, &live.Items[0])
+			case p == "/namespaces/default/pods/otter" && m == "GET":
+				return newResponse(200, &live.Items[1])
+			case p == "/namespaces/default/pods/dolphin" && m == "GET":
+				return newResponse(404, notFoundBody())
+			case p == "/namespaces/default/pods/starfish" && m == "PATCH":
+				return newResponse(200, &desired.Items[0])
+			case p == "/namespaces/default/pods/otter" && m == "PATCH":
+				return newResponse(200, &desired.Items[1])
+			case p == "/namespaces/default/pods/dolphin" && m == "PATCH":
+				return newResponse(200, &desired.Items[2])
+			default:
+				t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+				return nil, nil
+			}
+		}),
+	}
+
+	resources, err := c.Build(objBody(&desired), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffs, err := c.GetDiff(resources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 3 {
+		t.Fatalf("expected 3 diffs, got %d", len(diffs))
+	}
+
+	if diffs[0].Action != DiffActionUpdate {
+		t.Errorf("expected starfish to be updated, got %q", diffs[0].Action)
+	}
+	expected := []FieldDiff{{
+		Path:    "spec.containers[0].image",
+		Live:    "abc/app:v4",
+		Desired: "abc/app:v5",
+	}}
+	if !reflect.DeepEqual(diffs[0].Fields, expected) {
+		t.Errorf("expected fields %v, got %v", expected, diffs[0].Fields)
+	}
+
+	if diffs[1].Action != DiffActionNone || diffs[1].Changed() {
+		t.Errorf("expected otter to be unchanged, got %q with %v", diffs[1].Action, diffs[1].Fields)
+	}
+	if diffs[2].Action != DiffActionCreate || diffs[2].Live != nil {
+		t.Errorf("expected dolphin to be created, got %q", diffs[2].Action)
+	}
+}
+
+func TestDiffValues(t *testing.T) {
+	live := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{"app.kubernetes.io/name": "a"},
+		},
+		"spec": map[string]interface{}{
+			"replicas": int64(1),
+			"ports":    []interface{}{int64(80)},
+		},
+	}
+	desired := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]interface{}{"app.kubernetes.io/name": "b"},
+		},
+		"spec": map[string]interface{}{
+			"ports":  []interface{}{int64(80), int64(443)},
+			"paused": true,
+		},
+	}
+
+	expected := []FieldDiff{
+		{Path: `metadata.labels["app.kubernetes.io/name"]`, Live: "a", Desired: "b"},
+		{Path: "spec.paused", Desired: true},
+		{Path: "spec.ports", Live: []interface{}{int64(80)}, Desired: []interface{}{int64(80), int64(443)}},
+		{Path: "spec.replicas", Live: int64(1)},
+	}
+	got := diffValues("", live, desired)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected\n%v\ngot\n%v", expected, got)
+	}
+
+	if got := diffValues("", live, live); len(got) != 0 {
+		t.Errorf("expected no differences, got %v", got)
+	}
+}
+
+func TestResourceDiffRedactSecrets(t *testing.T) {
+	d := ResourceDiff{
+		APIVersion: "v1",
+		Kind:       "Secret",
+		Name:       "credentials",
+		Action:     DiffActionUpdate,
+		Fields: []FieldDiff{
+			{Path: "data.password", Live: "b2xk", Desired: "bmV3"},
+			{Path: `data["tls.key"]`, Desired: "a2V5"},
+			{Path: "stringData.token", Live: "old"},
+			{Path: "metadata.labels.app", Live: "a", Desired: "b"},
+		},
+		Live:    &unstructured.Unstructured{},
+		Desired: &unstructured.Unstructured{},
+	}
+
+	expected := []FieldDiff{
+		{Path: "data.password", Live: RedactedValue, Desired: RedactedValue},
+		{Path: `data["tls.key"]`, Desired: RedactedValue},
+		{Path: "stringData.token", Live: RedactedValue},
+		{Path: "metadata.labels.app", Live: "a", Desired: "b"},
+	}
+	got := d.RedactSecrets()
+	if !reflect.DeepEqual(got.Fields, expected) {
+		t.Errorf("expected\n%v\ngot\n%v", expected, got.Fields)
+	}
+	if got.Live != nil || got.Desired != nil {
+		t.Error("expected the objects of the Secret to be dropped")
+	}
+	if d.Fields[0].Live != "b2xk" {
+		t.Error("expected the diff not to be modified")
+	}
+
+	configMap := ResourceDiff{APIVersion: "v1", Kind: "ConfigMap", Fields: []FieldDiff{{Path: "data.key", Live: "a", Desired: "b"}}}
+	if got := configMap.RedactSecrets(); !reflect.DeepEqual(got, configMap) {
+		t.Errorf("expected a ConfigMap not to be redacted, got %v", got)
+	}
+}
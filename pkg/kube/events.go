@@ -0,0 +1,70 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v3/pkg/kube"
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EventSource is the component reported on Events recorded by Helm.
+const EventSource = "helm"
+
+// RecordEvent creates an Event of the given type (v1.EventTypeNormal or
+// v1.EventTypeWarning) about the referenced object. The object does not need
+// to exist; the Event is created in the object's namespace.
+func (c *Client) RecordEvent(ref *v1.ObjectReference, eventType, reason, message string) error {
+	client, err := c.getKubeClient()
+	if err != nil {
+		return err
+	}
+	event := newEvent(ref, eventType, reason, message)
+	if _, err := client.CoreV1().Events(event.Namespace).Create(context.Background(), event, metav1.CreateOptions{}); err != nil {
+		return errors.Wrapf(err, "unable to record event %s for %s %q", reason, ref.Kind, ref.Name)
+	}
+	return nil
+}
+
+func newEvent(ref *v1.ObjectReference, eventType, reason, message string) *v1.Event {
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+	host, _ := os.Hostname()
+	now := metav1.Now()
+	return &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("%s.", ref.Name),
+			Namespace:    namespace,
+		},
+		InvolvedObject:      *ref,
+		Reason:              reason,
+		Message:             message,
+		Type:                eventType,
+		Source:              v1.EventSource{Component: EventSource, Host: host},
+		FirstTimestamp:      now,
+		LastTimestamp:       now,
+		Count:               1,
+		ReportingController: "helm.sh/" + EventSource,
+		ReportingInstance:   host,
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest/fake"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+)
+
+func TestRecordEvent(t *testing.T) {
+	var created v1.Event
+	c := newTestClient(t)
+	c.Factory.(*cmdtesting.TestFactory).Client = &fake.RESTClient{
+		NegotiatedSerializer: unstructuredSerializer,
+		Client: fake.CreateHTTPClient(func(req *http.Request) (*http.Response, error) {
+			if req.Method != "POST" || req.URL.Path != "/api/v1/namespaces/ns/events" {
+				t.Fatalf("unexpected request: %s %s", req.Method, req.URL.Path)
+			}
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal(body, &created); err != nil {
+				t.Fatal(err)
+			}
+			return newResponse(201, &created)
+		}),
+	}
+
+	ref := &v1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "Secret",
+		Namespace:  "ns",
+		Name:       "sh.helm.release.v1.foo.v1",
+	}
+	if err := c.RecordEvent(ref, v1.EventTypeWarning, "InstallFailed", "it broke"); err != nil {
+		t.Fatal(err)
+	}
+
+	if created.InvolvedObject != *ref {
+		t.Errorf("expected event about %v, got %v", *ref, created.InvolvedObject)
+	}
+	if created.GenerateName != "sh.helm.release.v1.foo.v1." {
+		t.Errorf("unexpected generateName %q", created.GenerateName)
+	}
+	if created.Type != v1.EventTypeWarning || created.Reason != "InstallFailed" || created.Message != "it broke" {
+		t.Errorf("unexpected event %s %s: %s", created.Type, created.Reason, created.Message)
+	}
+	if created.Source.Component != EventSource || created.Count != 1 {
+		t.Errorf("unexpected source %v with count %d", created.Source, created.Count)
+	}
+}
@@ -27,6 +27,7 @@ import (
 	"k8s.io/cli-runtime/pkg/resource"
 
 	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/release"
 )
 
 // FailingKubeClient implements KubeClient for testing purposes. It also has
@@ -46,6 +47,11 @@ type FailingKubeClient struct {
 	BuildDummy                       bool
 	BuildUnstructuredError           error
 	WaitAndGetCompletedPodPhaseError error
+	GetDiffError                     error
+	GetHealthError                   error
+	RecordEventError                 error
+	Diffs                            []kube.ResourceDiff
+	Health                           []release.ResourceHealth
 	WaitDuration                     time.Duration
 }
 
@@ -149,6 +155,36 @@ func (f *FailingKubeClient) DeleteWithPropagationPolicy(resources kube.ResourceL
 	return f.PrintingKubeClient.DeleteWithPropagationPolicy(resources, policy)
 }
 
+// GetDiff returns the configured error or diffs if set or prints
+func (f *FailingKubeClient) GetDiff(resources kube.ResourceList) ([]kube.ResourceDiff, error) {
+	if f.GetDiffError != nil {
+		return nil, f.GetDiffError
+	}
+	if f.Diffs != nil {
+		return f.Diffs, nil
+	}
+	return f.PrintingKubeClient.GetDiff(resources)
+}
+
+// GetHealth returns the configured error or health if set or prints
+func (f *FailingKubeClient) GetHealth(resources kube.ResourceList) ([]release.ResourceHealth, error) {
+	if f.GetHealthError != nil {
+		return nil, f.GetHealthError
+	}
+	if f.Health != nil {
+		return f.Health, nil
+	}
+	return f.PrintingKubeClient.GetHealth(resources)
+}
+
+// RecordEvent returns the configured error if set or delegates to PrintingKubeClient
+func (f *FailingKubeClient) RecordEvent(ref *v1.ObjectReference, eventType, reason, message string) error {
+	if f.RecordEventError != nil {
+		return f.RecordEventError
+	}
+	return f.PrintingKubeClient.RecordEvent(ref, eventType, reason, message)
+}
+
 func createDummyResourceList() kube.ResourceList {
 	var resInfo resource.Info
 	resInfo.Name = "dummyName"
@@ -27,6 +27,7 @@ import (
 	"k8s.io/cli-runtime/pkg/resource"
 
 	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/release"
 )
 
 // PrintingKubeClient implements KubeClient, but simply prints the reader to
@@ -127,6 +128,46 @@ func (p *PrintingKubeClient) DeleteWithPropagationPolicy(resources kube.Resource
 	return &kube.Result{Deleted: resources}, nil
 }
 
+// GetDiff implements KubeClient GetDiff.
+//
+// It reports every resource as unchanged.
+func (p *PrintingKubeClient) GetDiff(resources kube.ResourceList) ([]kube.ResourceDiff, error) {
+	_, err := io.Copy(p.Out, bufferize(resources))
+	if err != nil {
+		return nil, err
+	}
+	diffs := make([]kube.ResourceDiff, 0, len(resources))
+	for _, info := range resources {
+		d := kube.ResourceDiff{Namespace: info.Namespace, Name: info.Name, Action: kube.DiffActionNone}
+		if info.Mapping != nil {
+			d.APIVersion = info.Mapping.GroupVersionKind.GroupVersion().String()
+			d.Kind = info.Mapping.GroupVersionKind.Kind
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs, nil
+}
+
+// GetHealth implements KubeClient GetHealth.
+//
+// It reports every resource as current.
+func (p *PrintingKubeClient) GetHealth(resources kube.ResourceList) ([]release.ResourceHealth, error) {
+	_, err := io.Copy(p.Out, bufferize(resources))
+	if err != nil {
+		return nil, err
+	}
+	health := make([]release.ResourceHealth, 0, len(resources))
+	for _, info := range resources {
+		h := release.ResourceHealth{Namespace: info.Namespace, Name: info.Name, Status: release.HealthCurrent}
+		if info.Mapping != nil {
+			h.APIVersion = info.Mapping.GroupVersionKind.GroupVersion().String()
+			h.Kind = info.Mapping.GroupVersionKind.Kind
+		}
+		health = append(health, h)
+	}
+	return health, nil
+}
+
 func bufferize(resources kube.ResourceList) io.Reader {
 	var builder strings.Builder
 	for _, info := range resources {
@@ -134,3 +175,8 @@ func bufferize(resources kube.ResourceList) io.Reader {
 	}
 	return strings.NewReader(builder.String())
 }
+
+// RecordEvent implements KubeClient RecordEvent.
+func (p *PrintingKubeClient) RecordEvent(_ *v1.ObjectReference, _, _, _ string) error {
+	return nil
+}
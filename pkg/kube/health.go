@@ -0,0 +1,122 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v3/pkg/kube"
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/cli-runtime/pkg/resource"
+
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// GetHealth returns the health of each of the given resources in the cluster.
+// A resource is in progress while its controller has not observed its latest
+// generation or while its conditions report it is reconciling, and failed if
+// its conditions report it is stalled or failed. The other resources are
+// current if they are ready.
+func (c *Client) GetHealth(resources ResourceList) ([]release.ResourceHealth, error) {
+	cs, err := c.getKubeClient()
+	if err != nil {
+		return nil, err
+	}
+	checker := NewReadyChecker(cs, c.Log, PausedAsReady(true), CheckJobs(true))
+	health := make([]release.ResourceHealth, 0, len(resources))
+	err = resources.Visit(func(info *resource.Info, err error) error {
+		if err != nil {
+			return err
+		}
+		health = append(health, resourceHealth(context.Background(), &checker, info))
+		return nil
+	})
+	return health, err
+}
+
+func resourceHealth(ctx context.Context, checker *ReadyChecker, info *resource.Info) release.ResourceHealth {
+	gvk := info.Mapping.GroupVersionKind
+	h := release.ResourceHealth{
+		APIVersion: gvk.GroupVersion().String(),
+		Kind:       gvk.Kind,
+		Namespace:  info.Namespace,
+		Name:       info.Name,
+	}
+
+	live, err := getResource(info)
+	if apierrors.IsNotFound(err) {
+		h.Status, h.Message = release.HealthNotFound, "resource not found"
+		return h
+	}
+	if err != nil {
+		h.Status, h.Message = release.HealthUnknown, err.Error()
+		return h
+	}
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(live)
+	if err != nil {
+		h.Status, h.Message = release.HealthUnknown, err.Error()
+		return h
+	}
+	if status, message, ok := objectHealth(obj); ok {
+		h.Status, h.Message = status, message
+		return h
+	}
+
+	ready, err := checker.IsReady(ctx, info)
+	switch {
+	case err != nil:
+		h.Status, h.Message = release.HealthUnknown, err.Error()
+	case !ready:
+		h.Status, h.Message = release.HealthInProgress, "resource is not ready"
+	default:
+		h.Status = release.HealthCurrent
+	}
+	return h
+}
+
+// objectHealth returns the health of a live object given by its metadata and
+// status conditions, and whether it could be determined from them.
+func objectHealth(obj map[string]interface{}) (release.HealthStatus, string, bool) {
+	if ts, _, _ := unstructured.NestedString(obj, "metadata", "deletionTimestamp"); ts != "" {
+		return release.HealthInProgress, "resource is being deleted", true
+	}
+	generation, _, _ := unstructured.NestedInt64(obj, "metadata", "generation")
+	observed, found, _ := unstructured.NestedInt64(obj, "status", "observedGeneration")
+	if found && observed < generation {
+		return release.HealthInProgress, fmt.Sprintf("generation %d is not observed yet", generation), true
+	}
+
+	conditions, _, _ := unstructured.NestedSlice(obj, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		kind, _, _ := unstructured.NestedString(condition, "type")
+		status, _, _ := unstructured.NestedString(condition, "status")
+		message, _, _ := unstructured.NestedString(condition, "message")
+		switch {
+		case (kind == "Stalled" || kind == "Failed") && status == "True":
+			return release.HealthFailed, message, true
+		case kind == "Reconciling" && status == "True":
+			return release.HealthInProgress, message, true
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,103 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"testing"
+
+	"helm.sh/helm/v3/pkg/release"
+)
+
+func TestObjectHealth(t *testing.T) {
+	tests := []struct {
+		name    string
+		obj     map[string]interface{}
+		status  release.HealthStatus
+		message string
+		found   bool
+	}{{
+		name: "without status",
+		obj:  map[string]interface{}{"metadata": map[string]interface{}{"generation": int64(1)}},
+	}, {
+		name: "being deleted",
+		obj: map[string]interface{}{"metadata": map[string]interface{}{
+			"deletionTimestamp": "2024-01-01T00:00:00Z",
+		}},
+		status:  release.HealthInProgress,
+		message: "resource is being deleted",
+		found:   true,
+	}, {
+		name: "generation not observed",
+		obj: map[string]interface{}{
+			"metadata": map[string]interface{}{"generation": int64(3)},
+			"status":   map[string]interface{}{"observedGeneration": int64(2)},
+		},
+		status:  release.HealthInProgress,
+		message: "generation 3 is not observed yet",
+		found:   true,
+	}, {
+		name: "stalled",
+		obj: map[string]interface{}{
+			"metadata": map[string]interface{}{"generation": int64(2)},
+			"status": map[string]interface{}{
+				"observedGeneration": int64(2),
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Ready", "status": "False"},
+					map[string]interface{}{"type": "Stalled", "status": "True", "message": "invalid spec"},
+				},
+			},
+		},
+		status:  release.HealthFailed,
+		message: "invalid spec",
+		found:   true,
+	}, {
+		name: "failed job",
+		obj: map[string]interface{}{"status": map[string]interface{}{
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Failed", "status": "True", "message": "Job has reached the specified backoff limit"},
+			},
+		}},
+		status:  release.HealthFailed,
+		message: "Job has reached the specified backoff limit",
+		found:   true,
+	}, {
+		name: "reconciling",
+		obj: map[string]interface{}{"status": map[string]interface{}{
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Reconciling", "status": "True", "message": "scaling up"},
+			},
+		}},
+		status:  release.HealthInProgress,
+		message: "scaling up",
+		found:   true,
+	}, {
+		name: "reconciled",
+		obj: map[string]interface{}{"status": map[string]interface{}{
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Reconciling", "status": "False"},
+			},
+		}},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, message, found := objectHealth(tt.obj)
+			if status != tt.status || message != tt.message || found != tt.found {
+				t.Errorf("expected (%q, %q, %v), got (%q, %q, %v)", tt.status, tt.message, tt.found, status, message, found)
+			}
+		})
+	}
+}
@@ -23,6 +23,8 @@ import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+
+	"helm.sh/helm/v3/pkg/release"
 )
 
 // Interface represents a client capable of communicating with the Kubernetes API.
@@ -110,7 +112,56 @@ type InterfaceResources interface {
 	BuildTable(reader io.Reader, validate bool) (ResourceList, error)
 }
 
+// InterfaceDiff is introduced to avoid breaking backwards compatibility for Interface implementers.
+//
+// TODO Helm 4: Remove InterfaceDiff and integrate its method(s) into the Interface.
+type InterfaceDiff interface {
+	// GetDiff returns the per-resource differences between the given resources
+	// and the matching live objects in the cluster.
+	GetDiff(resources ResourceList) ([]ResourceDiff, error)
+}
+
+// InterfaceWaitOptions is introduced to avoid breaking backwards compatibility for Interface implementers.
+//
+// TODO Helm 4: Remove InterfaceWaitOptions and integrate its method(s) into the Interface.
+type InterfaceWaitOptions interface {
+	// WaitWithOptions waits for the specified resources to be ready, honoring
+	// per-kind and per-resource timeouts.
+	WaitWithOptions(resources ResourceList, opts WaitOptions) error
+}
+
+// InterfaceValidate is introduced to avoid breaking backwards compatibility for Interface implementers.
+//
+// TODO Helm 4: Remove InterfaceValidate and integrate its method(s) into the Interface.
+type InterfaceValidate interface {
+	// Validate checks the resources against the schema of the cluster and
+	// returns all violations at once.
+	Validate(resources ResourceList) error
+}
+
+// InterfaceEvents is introduced to avoid breaking backwards compatibility for Interface implementers.
+//
+// TODO Helm 4: Remove InterfaceEvents and integrate its method(s) into the Interface.
+type InterfaceEvents interface {
+	// RecordEvent creates a Kubernetes Event about the referenced object.
+	RecordEvent(ref *v1.ObjectReference, eventType, reason, message string) error
+}
+
+// InterfaceHealth is introduced to avoid breaking backwards compatibility for Interface implementers.
+//
+// TODO Helm 4: Remove InterfaceHealth and integrate its method(s) into the Interface.
+type InterfaceHealth interface {
+	// GetHealth returns the health of each of the given resources in the
+	// cluster.
+	GetHealth(resources ResourceList) ([]release.ResourceHealth, error)
+}
+
 var _ Interface = (*Client)(nil)
 var _ InterfaceExt = (*Client)(nil)
 var _ InterfaceDeletionPropagation = (*Client)(nil)
 var _ InterfaceResources = (*Client)(nil)
+var _ InterfaceDiff = (*Client)(nil)
+var _ InterfaceWaitOptions = (*Client)(nil)
+var _ InterfaceValidate = (*Client)(nil)
+var _ InterfaceEvents = (*Client)(nil)
+var _ InterfaceHealth = (*Client)(nil)
@@ -0,0 +1,476 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v3/pkg/kube"
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/cli-runtime/pkg/resource"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// TargetClusterAnnotation names the cluster a resource should be deployed to.
+// Resources without the annotation are deployed to the default cluster.
+const TargetClusterAnnotation = "helm.sh/target-cluster"
+
+// ClusterError is returned by MultiClusterClient when an operation against a
+// named target cluster fails.
+type ClusterError struct {
+	Cluster string
+	Err     error
+}
+
+func (e *ClusterError) Error() string {
+	return fmt.Sprintf("cluster %q: %s", e.Cluster, e.Err)
+}
+
+func (e *ClusterError) Unwrap() error {
+	return e.Err
+}
+
+// ClusterManifest is the part of a manifest that targets a single cluster.
+type ClusterManifest struct {
+	// Cluster is the target cluster name, or empty for the default cluster.
+	Cluster string
+	// Manifest is the YAML stream of the documents targeting Cluster.
+	Manifest []byte
+	// Documents is the number of documents in Manifest.
+	Documents int
+}
+
+// SplitByTargetCluster splits a YAML stream according to the
+// TargetClusterAnnotation of each document. Groups are returned in the order
+// in which their cluster first appears in the stream.
+func SplitByTargetCluster(reader io.Reader) ([]ClusterManifest, error) {
+	var groups []ClusterManifest
+	index := map[string]int{}
+
+	yr := utilyaml.NewYAMLReader(bufio.NewReader(reader))
+	for {
+		doc, err := yr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to read manifest")
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+
+		var head struct {
+			Metadata struct {
+				Annotations map[string]string `json:"annotations"`
+			} `json:"metadata"`
+		}
+		if err := yaml.Unmarshal(doc, &head); err != nil {
+			return nil, errors.Wrap(err, "unable to parse manifest")
+		}
+		cluster := head.Metadata.Annotations[TargetClusterAnnotation]
+
+		i, ok := index[cluster]
+		if !ok {
+			i = len(groups)
+			index[cluster] = i
+			groups = append(groups, ClusterManifest{Cluster: cluster})
+		}
+		g := &groups[i]
+		if g.Documents > 0 {
+			g.Manifest = append(g.Manifest, []byte("---\n")...)
+		}
+		g.Manifest = append(g.Manifest, doc...)
+		if !bytes.HasSuffix(doc, []byte("\n")) {
+			g.Manifest = append(g.Manifest, '\n')
+		}
+		g.Documents++
+	}
+	return groups, nil
+}
+
+// MultiClusterClient routes resources to different clusters based on their
+// TargetClusterAnnotation. Resources without the annotation are handled by
+// Default.
+type MultiClusterClient struct {
+	// Default handles resources that do not name a target cluster.
+	Default Interface
+	// Clusters maps target cluster names to the client for that cluster.
+	Clusters map[string]Interface
+}
+
+var _ Interface = (*MultiClusterClient)(nil)
+var _ InterfaceExt = (*MultiClusterClient)(nil)
+var _ InterfaceDeletionPropagation = (*MultiClusterClient)(nil)
+var _ InterfaceResources = (*MultiClusterClient)(nil)
+var _ InterfaceDiff = (*MultiClusterClient)(nil)
+var _ InterfaceWaitOptions = (*MultiClusterClient)(nil)
+var _ InterfaceValidate = (*MultiClusterClient)(nil)
+var _ InterfaceEvents = (*MultiClusterClient)(nil)
+var _ InterfaceHealth = (*MultiClusterClient)(nil)
+
+// ClusterNames returns the sorted names of the configured target clusters.
+func (m *MultiClusterClient) ClusterNames() []string {
+	names := make([]string, 0, len(m.Clusters))
+	for name := range m.Clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (m *MultiClusterClient) client(cluster string) (Interface, error) {
+	if cluster == "" {
+		return m.Default, nil
+	}
+	c, ok := m.Clusters[cluster]
+	if !ok {
+		return nil, &ClusterError{Cluster: cluster, Err: errors.New("no client configured for target cluster")}
+	}
+	return c, nil
+}
+
+func wrapClusterError(cluster string, err error) error {
+	if err == nil || cluster == "" {
+		return err
+	}
+	return &ClusterError{Cluster: cluster, Err: err}
+}
+
+// ResourceCluster returns the target cluster of a resource, or an empty
+// string if it targets the default cluster.
+func ResourceCluster(info *resource.Info) string {
+	if info.Object == nil {
+		return ""
+	}
+	annotations, err := metadataAccessor.Annotations(info.Object)
+	if err != nil {
+		return ""
+	}
+	return annotations[TargetClusterAnnotation]
+}
+
+type clusterResources struct {
+	cluster   string
+	resources ResourceList
+}
+
+// groupByCluster splits a ResourceList by target cluster, preserving the
+// relative order of resources within each cluster.
+func groupByCluster(lists ...ResourceList) []clusterResources {
+	var groups []clusterResources
+	index := map[string]int{}
+	for n, list := range lists {
+		for _, info := range list {
+			cluster := ResourceCluster(info)
+			i, ok := index[cluster]
+			if !ok {
+				i = len(groups)
+				index[cluster] = i
+				groups = append(groups, clusterResources{cluster: cluster})
+			}
+			if n == 0 {
+				groups[i].resources.Append(info)
+			}
+		}
+	}
+	return groups
+}
+
+func (m *MultiClusterClient) forEach(resources ResourceList, fn func(Interface, ResourceList) error) error {
+	for _, g := range groupByCluster(resources) {
+		c, err := m.client(g.cluster)
+		if err != nil {
+			return err
+		}
+		if err := fn(c, g.resources); err != nil {
+			return wrapClusterError(g.cluster, err)
+		}
+	}
+	return nil
+}
+
+func mergeResults(into, from *Result) {
+	if from == nil {
+		return
+	}
+	into.Created = append(into.Created, from.Created...)
+	into.Updated = append(into.Updated, from.Updated...)
+	into.Deleted = append(into.Deleted, from.Deleted...)
+}
+
+// IsReachable checks that every configured cluster can be reached.
+func (m *MultiClusterClient) IsReachable() error {
+	if err := m.Default.IsReachable(); err != nil {
+		return err
+	}
+	for _, name := range m.ClusterNames() {
+		if err := m.Clusters[name].IsReachable(); err != nil {
+			return wrapClusterError(name, err)
+		}
+	}
+	return nil
+}
+
+// Create creates each resource in its target cluster.
+func (m *MultiClusterClient) Create(resources ResourceList) (*Result, error) {
+	res := &Result{}
+	err := m.forEach(resources, func(c Interface, rl ResourceList) error {
+		r, err := c.Create(rl)
+		mergeResults(res, r)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Wait waits for each resource in its target cluster.
+func (m *MultiClusterClient) Wait(resources ResourceList, timeout time.Duration) error {
+	return m.forEach(resources, func(c Interface, rl ResourceList) error {
+		return c.Wait(rl, timeout)
+	})
+}
+
+// WaitWithJobs waits for each resource, including jobs, in its target cluster.
+func (m *MultiClusterClient) WaitWithJobs(resources ResourceList, timeout time.Duration) error {
+	return m.forEach(resources, func(c Interface, rl ResourceList) error {
+		return c.WaitWithJobs(rl, timeout)
+	})
+}
+
+// WaitWithOptions waits for each resource in its target cluster.
+func (m *MultiClusterClient) WaitWithOptions(resources ResourceList, opts WaitOptions) error {
+	return m.forEach(resources, func(c Interface, rl ResourceList) error {
+		return WaitWithOptions(c, rl, opts)
+	})
+}
+
+// WaitForDelete waits for each resource to be deleted from its target cluster.
+func (m *MultiClusterClient) WaitForDelete(resources ResourceList, timeout time.Duration) error {
+	return m.forEach(resources, func(c Interface, rl ResourceList) error {
+		if ext, ok := c.(InterfaceExt); ok {
+			return ext.WaitForDelete(rl, timeout)
+		}
+		return nil
+	})
+}
+
+// Delete deletes each resource from its target cluster.
+func (m *MultiClusterClient) Delete(resources ResourceList) (*Result, []error) {
+	return m.delete(resources, func(c Interface, rl ResourceList) (*Result, []error) {
+		return c.Delete(rl)
+	})
+}
+
+// DeleteWithPropagationPolicy deletes each resource from its target cluster
+// with the given propagation policy.
+func (m *MultiClusterClient) DeleteWithPropagationPolicy(resources ResourceList, policy metav1.DeletionPropagation) (*Result, []error) {
+	return m.delete(resources, func(c Interface, rl ResourceList) (*Result, []error) {
+		if dp, ok := c.(InterfaceDeletionPropagation); ok {
+			return dp.DeleteWithPropagationPolicy(rl, policy)
+		}
+		return c.Delete(rl)
+	})
+}
+
+func (m *MultiClusterClient) delete(resources ResourceList, fn func(Interface, ResourceList) (*Result, []error)) (*Result, []error) {
+	res := &Result{}
+	var errs []error
+	for _, g := range groupByCluster(resources) {
+		c, err := m.client(g.cluster)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		r, cerrs := fn(c, g.resources)
+		mergeResults(res, r)
+		for _, err := range cerrs {
+			errs = append(errs, wrapClusterError(g.cluster, err))
+		}
+	}
+	if errs != nil {
+		return nil, errs
+	}
+	return res, nil
+}
+
+// WatchUntilReady watches each resource in its target cluster.
+func (m *MultiClusterClient) WatchUntilReady(resources ResourceList, timeout time.Duration) error {
+	return m.forEach(resources, func(c Interface, rl ResourceList) error {
+		return c.WatchUntilReady(rl, timeout)
+	})
+}
+
+// Update updates the resources of each target cluster. Clusters that only
+// appear in original have all of their resources deleted.
+func (m *MultiClusterClient) Update(original, target ResourceList, force bool) (*Result, error) {
+	res := &Result{}
+	for _, g := range groupByCluster(target, original) {
+		c, err := m.client(g.cluster)
+		if err != nil {
+			return res, err
+		}
+		orig := original.Filter(func(info *resource.Info) bool { return ResourceCluster(info) == g.cluster })
+		r, err := c.Update(orig, g.resources, force)
+		mergeResults(res, r)
+		if err != nil {
+			return res, wrapClusterError(g.cluster, err)
+		}
+	}
+	return res, nil
+}
+
+// Build builds the resources of each document with the client of its target
+// cluster, so the resulting infos talk to the right API server.
+func (m *MultiClusterClient) Build(reader io.Reader, validate bool) (ResourceList, error) {
+	return m.build(reader, func(c Interface, r io.Reader) (ResourceList, error) {
+		return c.Build(r, validate)
+	})
+}
+
+// BuildTable builds the resources of each document with the client of its
+// target cluster, returning table kinds.
+func (m *MultiClusterClient) BuildTable(reader io.Reader, validate bool) (ResourceList, error) {
+	return m.build(reader, func(c Interface, r io.Reader) (ResourceList, error) {
+		if ir, ok := c.(InterfaceResources); ok {
+			return ir.BuildTable(r, validate)
+		}
+		return c.Build(r, validate)
+	})
+}
+
+func (m *MultiClusterClient) build(reader io.Reader, fn func(Interface, io.Reader) (ResourceList, error)) (ResourceList, error) {
+	groups, err := SplitByTargetCluster(reader)
+	if err != nil {
+		return nil, err
+	}
+	var result ResourceList
+	for _, g := range groups {
+		c, err := m.client(g.Cluster)
+		if err != nil {
+			return nil, err
+		}
+		rl, err := fn(c, bytes.NewReader(g.Manifest))
+		if err != nil {
+			return nil, wrapClusterError(g.Cluster, err)
+		}
+		result = append(result, rl...)
+	}
+	return result, nil
+}
+
+// Get retrieves each resource from its target cluster.
+func (m *MultiClusterClient) Get(resources ResourceList, related bool) (map[string][]runtime.Object, error) {
+	objs := make(map[string][]runtime.Object)
+	err := m.forEach(resources, func(c Interface, rl ResourceList) error {
+		ir, ok := c.(InterfaceResources)
+		if !ok {
+			return errors.New("client does not support getting resources")
+		}
+		got, err := ir.Get(rl, related)
+		for k, v := range got {
+			objs[k] = append(objs[k], v...)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return objs, nil
+}
+
+// GetDiff diffs each resource against its target cluster.
+func (m *MultiClusterClient) GetDiff(resources ResourceList) ([]ResourceDiff, error) {
+	var diffs []ResourceDiff
+	err := m.forEach(resources, func(c Interface, rl ResourceList) error {
+		dc, ok := c.(InterfaceDiff)
+		if !ok {
+			return errors.New("client does not support diffs")
+		}
+		d, err := dc.GetDiff(rl)
+		diffs = append(diffs, d...)
+		return err
+	})
+	return diffs, err
+}
+
+// GetHealth returns the health of each resource in its target cluster.
+func (m *MultiClusterClient) GetHealth(resources ResourceList) ([]release.ResourceHealth, error) {
+	var health []release.ResourceHealth
+	err := m.forEach(resources, func(c Interface, rl ResourceList) error {
+		hc, ok := c.(InterfaceHealth)
+		if !ok {
+			return errors.New("client does not support health checks")
+		}
+		h, err := hc.GetHealth(rl)
+		health = append(health, h...)
+		return err
+	})
+	return health, err
+}
+
+// Validate validates each resource against the schema of its target cluster.
+// Violations from all clusters are returned together.
+func (m *MultiClusterClient) Validate(resources ResourceList) error {
+	var errs []error
+	err := m.forEach(resources, func(c Interface, rl ResourceList) error {
+		v, ok := c.(InterfaceValidate)
+		if !ok {
+			return nil
+		}
+		err := v.Validate(rl)
+		var verr *ValidationError
+		if errors.As(err, &verr) {
+			errs = append(errs, verr.Errors...)
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// WaitAndGetCompletedPodPhase waits for a pod in the default cluster.
+func (m *MultiClusterClient) WaitAndGetCompletedPodPhase(name string, timeout time.Duration) (v1.PodPhase, error) {
+	return m.Default.WaitAndGetCompletedPodPhase(name, timeout)
+}
+
+// RecordEvent records the Event with the default cluster, which holds the
+// release records.
+func (m *MultiClusterClient) RecordEvent(ref *v1.ObjectReference, eventType, reason, message string) error {
+	if ec, ok := m.Default.(InterfaceEvents); ok {
+		return ec.RecordEvent(ref, eventType, reason, message)
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+	"sigs.k8s.io/yaml"
+)
+
+const multiClusterManifest = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: local
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: east-agent
+  annotations:
+    helm.sh/target-cluster: east
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: west-agent
+  annotations:
+    helm.sh/target-cluster: west
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: east-config
+  annotations:
+    helm.sh/target-cluster: east
+`
+
+// recordingClient builds unstructured infos from YAML and records the names
+// of the resources passed to it.
+type recordingClient struct {
+	Interface
+	created  []string
+	updated  []string
+	failWith error
+}
+
+func (r *recordingClient) Build(reader io.Reader, _ bool) (ResourceList, error) {
+	groups, err := SplitByTargetCluster(reader)
+	if err != nil {
+		return nil, err
+	}
+	var rl ResourceList
+	for _, g := range groups {
+		for _, doc := range strings.Split(string(g.Manifest), "---\n") {
+			obj := &unstructured.Unstructured{}
+			if err := yaml.Unmarshal([]byte(doc), &obj.Object); err != nil {
+				return nil, err
+			}
+			rl.Append(&resource.Info{
+				Name:   obj.GetName(),
+				Object: obj,
+				Mapping: &meta.RESTMapping{
+					GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: obj.GetKind()},
+				},
+			})
+		}
+	}
+	return rl, nil
+}
+
+func (r *recordingClient) Create(resources ResourceList) (*Result, error) {
+	if r.failWith != nil {
+		return nil, r.failWith
+	}
+	for _, info := range resources {
+		r.created = append(r.created, info.Name)
+	}
+	return &Result{Created: resources}, nil
+}
+
+func (r *recordingClient) Update(_, target ResourceList, _ bool) (*Result, error) {
+	for _, info := range target {
+		r.updated = append(r.updated, info.Name)
+	}
+	return &Result{Updated: target}, nil
+}
+
+func (r *recordingClient) WaitAndGetCompletedPodPhase(_ string, _ time.Duration) (v1.PodPhase, error) {
+	return v1.PodSucceeded, nil
+}
+
+func TestSplitByTargetCluster(t *testing.T) {
+	groups, err := SplitByTargetCluster(strings.NewReader(multiClusterManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 3 {
+		t.Fatalf("expected 3 groups, got %d", len(groups))
+	}
+	expected := []struct {
+		cluster   string
+		documents int
+	}{{"", 1}, {"east", 2}, {"west", 1}}
+	for i, e := range expected {
+		if groups[i].Cluster != e.cluster || groups[i].Documents != e.documents {
+			t.Errorf("expected group %d to be %q with %d documents, got %q with %d", i, e.cluster, e.documents, groups[i].Cluster, groups[i].Documents)
+		}
+	}
+	if !strings.Contains(string(groups[1].Manifest), "name: east-agent") || !strings.Contains(string(groups[1].Manifest), "name: east-config") {
+		t.Errorf("unexpected manifest for east: %s", groups[1].Manifest)
+	}
+}
+
+func TestMultiClusterClient(t *testing.T) {
+	def, east, west := &recordingClient{}, &recordingClient{}, &recordingClient{}
+	mc := &MultiClusterClient{
+		Default:  def,
+		Clusters: map[string]Interface{"east": east, "west": west},
+	}
+
+	resources, err := mc.Build(bytes.NewBufferString(multiClusterManifest), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resources) != 4 {
+		t.Fatalf("expected 4 resources, got %d", len(resources))
+	}
+
+	res, err := mc.Create(resources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Created) != 4 {
+		t.Errorf("expected 4 created resources, got %d", len(res.Created))
+	}
+	if strings.Join(def.created, ",") != "local" {
+		t.Errorf("unexpected resources created in default cluster: %v", def.created)
+	}
+	if strings.Join(east.created, ",") != "east-agent,east-config" {
+		t.Errorf("unexpected resources created in east: %v", east.created)
+	}
+	if strings.Join(west.created, ",") != "west-agent" {
+		t.Errorf("unexpected resources created in west: %v", west.created)
+	}
+
+	// Dropping west from the target still updates west, so its resources get deleted.
+	target := resources.Filter(func(info *resource.Info) bool { return ResourceCluster(info) != "west" })
+	if _, err := mc.Update(resources, target, false); err != nil {
+		t.Fatal(err)
+	}
+	if len(west.updated) != 0 {
+		t.Errorf("expected no resources updated in west, got %v", west.updated)
+	}
+
+	west.failWith = errors.New("boom")
+	_, err = mc.Create(resources)
+	var clusterErr *ClusterError
+	if !errors.As(err, &clusterErr) || clusterErr.Cluster != "west" {
+		t.Errorf("expected cluster error for west, got %v", err)
+	}
+
+	delete(mc.Clusters, "east")
+	if _, err := mc.Build(bytes.NewBufferString(multiClusterManifest), false); err == nil {
+		t.Error("expected error for unknown target cluster")
+	}
+}
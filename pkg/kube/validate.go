@@ -0,0 +1,377 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube // import "helm.sh/helm/v3/pkg/kube"
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/xeipuuv/gojsonschema"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/kubectl/pkg/util/openapi"
+	"k8s.io/kubectl/pkg/validation"
+)
+
+// ValidationError lists every schema violation found in a set of resources.
+type ValidationError struct {
+	Errors []error
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = "  - " + err.Error()
+	}
+	return fmt.Sprintf("%d validation error(s) found:\n%s", len(e.Errors), strings.Join(msgs, "\n"))
+}
+
+// Validate checks every resource against the OpenAPI schema published by the
+// cluster before anything is applied. If the cluster schema cannot be
+// retrieved, BuiltinSchema is used instead. All violations are returned
+// together as a *ValidationError.
+func (c *Client) Validate(resources ResourceList) error {
+	s := c.validationSchema()
+
+	var errs []error
+	err := resources.Visit(func(info *resource.Info, err error) error {
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(info.Object)
+		if err != nil {
+			return errors.Wrapf(err, "unable to serialize %s", info.ObjectName())
+		}
+		if err := s.ValidateBytes(data); err != nil {
+			for _, e := range flattenErrors(err) {
+				errs = append(errs, errors.Wrapf(e, "%s %q", resourceKind(info), info.Name))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// validationSchema returns the cluster schema if it is reachable and
+// BuiltinSchema otherwise.
+func (c *Client) validationSchema() validation.Schema {
+	getter, ok := c.Factory.(openapi.OpenAPIResourcesGetter)
+	if !ok {
+		return BuiltinSchema()
+	}
+	if _, err := getter.OpenAPISchema(); err != nil {
+		c.Log("unable to fetch the cluster OpenAPI schema, validating with the built-in schemas: %s", err)
+		return BuiltinSchema()
+	}
+	return validation.ConjunctiveSchema{
+		validation.NewSchemaValidation(getter),
+		validation.NoDoubleKeySchema{},
+	}
+}
+
+func flattenErrors(err error) []error {
+	var agg utilerrors.Aggregate
+	if errors.As(err, &agg) {
+		var out []error
+		for _, e := range agg.Errors() {
+			out = append(out, flattenErrors(e)...)
+		}
+		return out
+	}
+	return []error{err}
+}
+
+// BuiltinSchema returns a schema that validates objects against the Go types
+// of the Kubernetes APIs compiled into Helm. It needs no cluster access. It
+// reports unknown fields and values of the wrong type. The types are those of
+// the Kubernetes version Helm is built with, not versioned schemas.
+//
+// The kinds unknown to Helm, such as custom resources, are validated against
+// the JSON schemas found in schemaDirs, laid out like the CRDs catalog of
+// kubeconform: <group>/<kind>_<version>.json, with the lower-cased kind. The
+// kinds without a schema are not validated.
+func BuiltinSchema(schemaDirs ...string) validation.Schema {
+	return &builtinSchema{
+		scheme:     scheme.Scheme,
+		schemaDirs: schemaDirs,
+		schemas:    map[string]*gojsonschema.Schema{},
+	}
+}
+
+type builtinSchema struct {
+	scheme     *runtime.Scheme
+	schemaDirs []string
+
+	mu      sync.Mutex
+	schemas map[string]*gojsonschema.Schema
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+func (b *builtinSchema) ValidateBytes(data []byte) error {
+	var obj map[string]interface{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return errors.Wrap(err, "unable to parse object")
+	}
+	if list, ok := obj["items"].([]interface{}); ok && strings.HasSuffix(fmt.Sprint(obj["kind"]), "List") {
+		var errs []error
+		for _, item := range list {
+			if m, ok := item.(map[string]interface{}); ok {
+				errs = append(errs, b.validateObject(m)...)
+			}
+		}
+		return utilerrors.NewAggregate(errs)
+	}
+	return utilerrors.NewAggregate(b.validateObject(obj))
+}
+
+func (b *builtinSchema) validateObject(obj map[string]interface{}) []error {
+	apiVersion, _ := obj["apiVersion"].(string)
+	kind, _ := obj["kind"].(string)
+	if apiVersion == "" || kind == "" {
+		return []error{errors.New("apiVersion and kind must be set")}
+	}
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return []error{errors.Wrapf(err, "invalid apiVersion %q", apiVersion)}
+	}
+	gvk := gv.WithKind(kind)
+	typed, err := b.scheme.New(gvk)
+	if err != nil {
+		return b.validateCustomObject(gvk, obj)
+	}
+	return validateValue("", obj, reflect.TypeOf(typed))
+}
+
+// validateCustomObject validates an object of a kind unknown to the scheme
+// against its JSON schema in the schema directories, if there is one.
+func (b *builtinSchema) validateCustomObject(gvk schema.GroupVersionKind, obj map[string]interface{}) []error {
+	s, err := b.customSchema(gvk)
+	if err != nil {
+		return []error{err}
+	}
+	if s == nil {
+		return nil
+	}
+	result, err := s.Validate(gojsonschema.NewGoLoader(obj))
+	if err != nil {
+		return []error{err}
+	}
+	var errs []error
+	for _, desc := range result.Errors() {
+		errs = append(errs, errors.New(desc.String()))
+	}
+	return errs
+}
+
+// customSchema returns the JSON schema of gvk in the schema directories, or
+// nil if there is none.
+func (b *builtinSchema) customSchema(gvk schema.GroupVersionKind) (*gojsonschema.Schema, error) {
+	name := filepath.Join(gvk.Group, fmt.Sprintf("%s_%s.json", strings.ToLower(gvk.Kind), gvk.Version))
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if s, ok := b.schemas[name]; ok {
+		return s, nil
+	}
+	var s *gojsonschema.Schema
+	for _, dir := range b.schemaDirs {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if s, err = gojsonschema.NewSchema(gojsonschema.NewBytesLoader(data)); err != nil {
+			return nil, errors.Wrapf(err, "invalid schema %s", filepath.Join(dir, name))
+		}
+		break
+	}
+	b.schemas[name] = s
+	return s, nil
+}
+
+// validateValue checks a decoded JSON value against a Go type using the same
+// rules encoding/json would apply, reporting every mismatch.
+func validateValue(path string, value interface{}, t reflect.Type) []error {
+	if value == nil {
+		return nil
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	// Types with custom decoding (Quantity, IntOrString, Time, RawExtension, ...)
+	// accept more than one JSON representation.
+	if t.Implements(jsonUnmarshalerType) || reflect.PtrTo(t).Implements(jsonUnmarshalerType) {
+		return nil
+	}
+
+	mismatch := func(expected string) []error {
+		return []error{errors.Errorf("%s: expected %s, got %s", displayPath(path), expected, jsonTypeName(value))}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return mismatch("object")
+		}
+		fields := jsonFields(t)
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var errs []error
+		for _, k := range keys {
+			ft, ok := fields[k]
+			if !ok {
+				errs = append(errs, errors.Errorf("%s: unknown field %q", displayPath(path), k))
+				continue
+			}
+			errs = append(errs, validateValue(joinFieldPath(path, k), m[k], ft)...)
+		}
+		return errs
+	case reflect.Map:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return mismatch("object")
+		}
+		var errs []error
+		for k, v := range m {
+			errs = append(errs, validateValue(joinFieldPath(path, k), v, t.Elem())...)
+		}
+		return errs
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			if _, ok := value.(string); !ok {
+				return mismatch("string")
+			}
+			return nil
+		}
+		l, ok := value.([]interface{})
+		if !ok {
+			return mismatch("array")
+		}
+		var errs []error
+		for i, v := range l {
+			errs = append(errs, validateValue(fmt.Sprintf("%s[%d]", path, i), v, t.Elem())...)
+		}
+		return errs
+	case reflect.String:
+		if _, ok := value.(string); !ok {
+			return mismatch("string")
+		}
+	case reflect.Bool:
+		if _, ok := value.(bool); !ok {
+			return mismatch("boolean")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		f, ok := value.(float64)
+		if !ok {
+			return mismatch("integer")
+		}
+		if f != float64(int64(f)) {
+			return mismatch("integer")
+		}
+	case reflect.Float32, reflect.Float64:
+		if _, ok := value.(float64); !ok {
+			return mismatch("number")
+		}
+	}
+	return nil
+}
+
+// jsonFields returns the JSON field names of a struct, including the fields
+// of inlined embedded structs.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			for k, v := range jsonFields(ft) {
+				fields[k] = v
+			}
+			continue
+		}
+		if opts == "inline" && ft.Kind() == reflect.Struct {
+			for k, v := range jsonFields(ft) {
+				fields[k] = v
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "<root>"
+	}
+	return path
+}
+
+func jsonTypeName(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
@@ -0,0 +1,185 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	cmdtesting "k8s.io/kubectl/pkg/cmd/testing"
+	"k8s.io/kubectl/pkg/util/openapi"
+)
+
+func TestBuiltinSchema(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		errors   []string
+	}{
+		{
+			name: "valid deployment",
+			manifest: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    app: web
+spec:
+  replicas: 2
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: nginx
+        ports:
+        - containerPort: 80
+        resources:
+          limits:
+            cpu: 100m
+            memory: 1
+`,
+		},
+		{
+			name: "invalid deployment",
+			manifest: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: "two"
+  template:
+    spec:
+      containers:
+      - name: web
+        imagePullPolicy: Always
+        imgae: nginx
+`,
+			errors: []string{
+				`spec.replicas: expected integer, got string`,
+				`spec.template.spec.containers[0]: unknown field "imgae"`,
+			},
+		},
+		{
+			name:     "unknown kind",
+			manifest: "apiVersion: example.com/v1\nkind: Widget\nspec:\n  anything: goes\n",
+		},
+		{
+			name:     "missing kind",
+			manifest: "apiVersion: v1\nmetadata:\n  name: x\n",
+			errors:   []string{"apiVersion and kind must be set"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := yaml.ToJSON([]byte(tt.manifest))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = BuiltinSchema().ValidateBytes(data)
+			if len(tt.errors) == 0 {
+				if err != nil {
+					t.Fatalf("expected no error, got %s", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected validation errors")
+			}
+			got := flattenErrors(err)
+			if len(got) != len(tt.errors) {
+				t.Fatalf("expected %d errors, got %d: %v", len(tt.errors), len(got), got)
+			}
+			for i, e := range tt.errors {
+				if got[i].Error() != e {
+					t.Errorf("expected error %q, got %q", e, got[i].Error())
+				}
+			}
+		})
+	}
+}
+
+func TestBuiltinSchemaCustomResources(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "example.com"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	schema := `{"type": "object", "properties": {"spec": {"type": "object", "properties": {"size": {"type": "integer"}}}}}`
+	if err := os.WriteFile(filepath.Join(dir, "example.com", "widget_v1.json"), []byte(schema), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := BuiltinSchema(dir)
+
+	err := s.ValidateBytes([]byte(`{"apiVersion": "example.com/v1", "kind": "Widget", "spec": {"size": "big"}}`))
+	if got := flattenErrors(err); len(got) != 1 || got[0].Error() != "spec.size: Invalid type. Expected: integer, given: string" {
+		t.Errorf("expected the widget not to match its schema, got %v", got)
+	}
+	if err := s.ValidateBytes([]byte(`{"apiVersion": "example.com/v1", "kind": "Widget", "spec": {"size": 3}}`)); err != nil {
+		t.Errorf("expected no error, got %s", err)
+	}
+	// The kinds without a schema are not validated
+	if err := s.ValidateBytes([]byte(`{"apiVersion": "example.com/v1", "kind": "Gadget", "spec": {"size": "big"}}`)); err != nil {
+		t.Errorf("expected no error, got %s", err)
+	}
+}
+
+func TestValidateFallsBackToBuiltinSchema(t *testing.T) {
+	c := newTestClient(t)
+	c.Factory.(*cmdtesting.TestFactory).OpenAPISchemaFunc = func() (openapi.Resources, error) {
+		return nil, errors.New("cluster unreachable")
+	}
+
+	pods := newPodList("starfish", "otter")
+	resources, err := c.Build(objBody(&pods), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Validate(resources); err != nil {
+		t.Fatalf("expected valid pods, got %s", err)
+	}
+
+	unstructured.SetNestedField(resources[0].Object.(*unstructured.Unstructured).Object, true, "spec", "hostnetwork")
+	unstructured.SetNestedField(resources[1].Object.(*unstructured.Unstructured).Object, "high", "spec", "priority")
+
+	err = c.Validate(resources)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	if len(verr.Errors) != 2 {
+		t.Fatalf("expected errors for both pods, got %v", verr.Errors)
+	}
+	if !strings.Contains(verr.Error(), `Pod "starfish": spec: unknown field "hostnetwork"`) {
+		t.Errorf("unexpected error: %s", verr)
+	}
+	if !strings.Contains(verr.Error(), `Pod "otter": spec.priority: expected integer, got string`) {
+		t.Errorf("unexpected error: %s", verr)
+	}
+}
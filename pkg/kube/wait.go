@@ -38,18 +38,88 @@ import (
 	"k8s.io/apimachinery/pkg/util/wait"
 )
 
+// WaitTimeoutAnnotation overrides the wait timeout for a single resource. The
+// value is a duration such as "90s" or "30m".
+const WaitTimeoutAnnotation = "helm.sh/wait-timeout"
+
+// WaitOptions configures how long to wait for resources to become ready.
+type WaitOptions struct {
+	// Timeout is the wait budget for resources without a more specific timeout.
+	Timeout time.Duration
+	// KindTimeouts overrides Timeout for all resources of a Kind, e.g. "StatefulSet".
+	KindTimeouts map[string]time.Duration
+	// WaitForJobs also waits for Jobs to complete.
+	WaitForJobs bool
+}
+
+// WaitWithOptions waits for resources using c. If c does not implement
+// InterfaceWaitOptions, per-kind timeouts are ignored and it falls back to
+// Wait or WaitWithJobs.
+func WaitWithOptions(c Interface, resources ResourceList, opts WaitOptions) error {
+	if wc, ok := c.(InterfaceWaitOptions); ok {
+		return wc.WaitWithOptions(resources, opts)
+	}
+	if opts.WaitForJobs {
+		return c.WaitWithJobs(resources, opts.Timeout)
+	}
+	return c.Wait(resources, opts.Timeout)
+}
+
 type waiter struct {
-	c       ReadyChecker
-	timeout time.Duration
-	log     func(string, ...interface{})
+	c            ReadyChecker
+	timeout      time.Duration
+	kindTimeouts map[string]time.Duration
+	log          func(string, ...interface{})
+}
+
+// resourceTimeout returns the wait budget of a resource. The
+// WaitTimeoutAnnotation takes precedence over the per-kind timeout, which
+// takes precedence over the default timeout.
+func (w *waiter) resourceTimeout(info *resource.Info) time.Duration {
+	if info.Object != nil {
+		if annotations, err := metadataAccessor.Annotations(info.Object); err == nil {
+			if v, ok := annotations[WaitTimeoutAnnotation]; ok {
+				d, err := time.ParseDuration(v)
+				if err == nil {
+					return d
+				}
+				w.log("Ignoring invalid %s annotation %q on %s: %s", WaitTimeoutAnnotation, v, info.Name, err)
+			}
+		}
+	}
+	if d, ok := w.kindTimeouts[resourceKind(info)]; ok {
+		return d
+	}
+	return w.timeout
+}
+
+func resourceKind(info *resource.Info) string {
+	if info.Mapping != nil {
+		return info.Mapping.GroupVersionKind.Kind
+	}
+	if info.Object != nil {
+		return info.Object.GetObjectKind().GroupVersionKind().Kind
+	}
+	return ""
 }
 
 // waitForResources polls to get the current status of all pods, PVCs, Services and
 // Jobs(optional) until all are ready or a timeout is reached
 func (w *waiter) waitForResources(created ResourceList) error {
-	w.log("beginning wait for %d resources with timeout of %v", len(created), w.timeout)
+	// Each resource has its own budget; the wait as a whole lasts as long as
+	// the largest one.
+	timeouts := make([]time.Duration, len(created))
+	budget := w.timeout
+	for i, v := range created {
+		timeouts[i] = w.resourceTimeout(v)
+		if timeouts[i] > budget {
+			budget = timeouts[i]
+		}
+	}
+	w.log("beginning wait for %d resources with timeout of %v", len(created), budget)
 
-	ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), budget)
 	defer cancel()
 
 	numberOfErrors := make([]int, len(created))
@@ -73,6 +143,9 @@ func (w *waiter) waitForResources(created ResourceList) error {
 			}
 			numberOfErrors[i] = 0
 			if !ready {
+				if err == nil && time.Since(start) > timeouts[i] {
+					err = errors.Errorf("timed out waiting for %s %q to be ready after %v", resourceKind(v), v.Name, timeouts[i])
+				}
 				return false, err
 			}
 		}
@@ -96,7 +169,10 @@ func (w *waiter) isRetryableError(err error, resource *resource.Info) bool {
 }
 
 func (w *waiter) isRetryableHTTPStatusCode(httpStatusCode int32) bool {
-	return httpStatusCode == 0 || httpStatusCode == http.StatusTooManyRequests || (httpStatusCode >= 500 && httpStatusCode != http.StatusNotImplemented)
+	// Unauthorized is retried because refreshable credentials, such as those
+	// from exec plugins, may expire during a long wait and are renewed by the
+	// time the next poll is made.
+	return httpStatusCode == 0 || httpStatusCode == http.StatusTooManyRequests || httpStatusCode == http.StatusUnauthorized || (httpStatusCode >= 500 && httpStatusCode != http.StatusNotImplemented)
 }
 
 // waitForDeletedResources polls to check if all the resources are deleted or a timeout is reached
@@ -109,6 +185,10 @@ func (w *waiter) waitForDeletedResources(deleted ResourceList) error {
 	return wait.PollUntilContextCancel(ctx, 2*time.Second, true, func(_ context.Context) (bool, error) {
 		for _, v := range deleted {
 			err := v.Get()
+			if apierrors.IsUnauthorized(err) {
+				w.log("credentials rejected while waiting for %s to be deleted, retrying", v.Name)
+				return false, nil
+			}
 			if err == nil || !apierrors.IsNotFound(err) {
 				return false, err
 			}
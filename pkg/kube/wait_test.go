@@ -0,0 +1,66 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+func newWaitInfo(kind, name string, annotations map[string]string) *resource.Info {
+	obj := &unstructured.Unstructured{}
+	obj.SetKind(kind)
+	obj.SetName(name)
+	obj.SetAnnotations(annotations)
+	return &resource.Info{
+		Name:    name,
+		Object:  obj,
+		Mapping: &meta.RESTMapping{GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: kind}},
+	}
+}
+
+func TestWaiterResourceTimeout(t *testing.T) {
+	w := waiter{
+		timeout:      5 * time.Minute,
+		kindTimeouts: map[string]time.Duration{"StatefulSet": 30 * time.Minute},
+		log:          nopLogger,
+	}
+
+	tests := []struct {
+		name     string
+		info     *resource.Info
+		expected time.Duration
+	}{
+		{"default", newWaitInfo("ConfigMap", "cm", nil), 5 * time.Minute},
+		{"kind", newWaitInfo("StatefulSet", "db", nil), 30 * time.Minute},
+		{"annotation", newWaitInfo("StatefulSet", "db", map[string]string{WaitTimeoutAnnotation: "45m"}), 45 * time.Minute},
+		{"annotation on other kind", newWaitInfo("Deployment", "web", map[string]string{WaitTimeoutAnnotation: "90s"}), 90 * time.Second},
+		{"invalid annotation", newWaitInfo("Deployment", "web", map[string]string{WaitTimeoutAnnotation: "soon"}), 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := w.resourceTimeout(tt.info); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+// ConfigFileName is the name of the lint configuration file of a chart, in
+// its root directory.
+const ConfigFileName = ".helmlintrc"
+
+// Config is the lint configuration of a chart, like:
+//
+//	rules:
+//	  enable: [workload-probes]
+//	  disable: [dependencies]
+//	  severities:
+//	    workload-probes: error
+//	ignores:
+//	- rule: workload-image-tag
+//	  path: templates/debug-pod.yaml
+//	  reason: the debug pod always runs the latest tools
+type Config struct {
+	Rules RulesConfig `json:"rules,omitempty"`
+	// Ignores are the failures not reported.
+	Ignores []Ignore `json:"ignores,omitempty"`
+}
+
+// RulesConfig configures the rules run on a chart.
+type RulesConfig struct {
+	// Enable are the IDs of the optional rules run.
+	Enable []string `json:"enable,omitempty"`
+	// Disable are the IDs of the rules not run.
+	Disable []string `json:"disable,omitempty"`
+	// Severities are the severities of the failures of the rules, by ID, like
+	// "warning".
+	Severities map[string]string `json:"severities,omitempty"`
+}
+
+// Ignore ignores the failures of a rule in a file of a chart.
+type Ignore struct {
+	// Rule is the ID of the rule.
+	Rule string `json:"rule"`
+	// Path is the path to the file in the chart, or a pattern like
+	// "templates/*.yaml".
+	Path string `json:"path"`
+	// Line is the line of the failures in the file. All the failures in the
+	// file are ignored if 0.
+	Line int `json:"line,omitempty"`
+	// Reason is the justification of the ignore, for the reviewers.
+	Reason string `json:"reason"`
+}
+
+// LoadConfig loads the lint configuration of the chart in the chart
+// directory. It returns an empty configuration if the chart has none.
+func LoadConfig(chartDir string) (*Config, error) {
+	c, err := LoadConfigFile(filepath.Join(chartDir, ConfigFileName))
+	if os.IsNotExist(errors.Cause(err)) {
+		return &Config{}, nil
+	}
+	return c, err
+}
+
+// LoadConfigFile loads the lint configuration file.
+func LoadConfigFile(filename string) (*Config, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	if err := yaml.UnmarshalStrict(data, c); err != nil {
+		return nil, errors.Wrapf(err, "cannot load lint configuration %s", filename)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, errors.Wrapf(err, "invalid lint configuration %s", filename)
+	}
+	return c, nil
+}
+
+// Validate checks the severities and the ignores of the configuration. The
+// ignores must be justified by a reason.
+func (c *Config) Validate() error {
+	for id, name := range c.Rules.Severities {
+		if _, err := support.ParseSeverity(name); err != nil {
+			return errors.Wrapf(err, "rule %q", id)
+		}
+	}
+	for i, ig := range c.Ignores {
+		if ig.Rule == "" || ig.Path == "" {
+			return errors.Errorf("ignore %d has no rule or path", i+1)
+		}
+		if _, err := filepath.Match(ig.Path, ""); err != nil {
+			return errors.Wrapf(err, "ignore %d has an invalid path %q", i+1, ig.Path)
+		}
+		if ig.Reason == "" {
+			return errors.Errorf("ignore %d of rule %q in %s has no reason", i+1, ig.Rule, ig.Path)
+		}
+	}
+	return nil
+}
+
+// RuleSeverities returns the severities of the rules, by ID.
+func (c *Config) RuleSeverities() map[string]int {
+	severities := make(map[string]int, len(c.Rules.Severities))
+	for id, name := range c.Rules.Severities {
+		// The severities are checked by Validate
+		severities[id], _ = support.ParseSeverity(name)
+	}
+	return severities
+}
+
+// Ignored returns whether the failure of the message is ignored.
+func (c *Config) Ignored(msg support.Message) bool {
+	for _, ig := range c.Ignores {
+		if ig.Rule != msg.Rule || (ig.Line != 0 && ig.Line != msg.Line) {
+			continue
+		}
+		if ok, _ := filepath.Match(ig.Path, msg.Path); ok {
+			return true
+		}
+	}
+	return false
+}
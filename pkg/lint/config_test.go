@@ -0,0 +1,74 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+func TestLoadConfig(t *testing.T) {
+	c, err := LoadConfig(goodChartDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Rules.Enable) != 0 || len(c.Ignores) != 0 {
+		t.Errorf("expected an empty configuration, got %#v", c)
+	}
+
+	for name, data := range map[string]string{
+		"unknown field":    "rule:\n  enable: [workload-probes]\n",
+		"unknown severity": "rules:\n  severities:\n    templates: fatal\n",
+		"no reason":        "ignores:\n- rule: templates\n  path: templates/pod.yaml\n",
+		"no path":          "ignores:\n- rule: templates\n  reason: legacy\n",
+		"invalid path":     "ignores:\n- rule: templates\n  path: templates/[\n  reason: legacy\n",
+	} {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, ConfigFileName), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadConfig(dir); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestConfigIgnored(t *testing.T) {
+	c := &Config{Ignores: []Ignore{
+		{Rule: "workload-image-tag", Path: "templates/debug-*.yaml", Reason: "debugging tools"},
+		{Rule: "templates", Path: "templates/legacy.yaml", Line: 12, Reason: "legacy"},
+	}}
+	err := errors.New("failure")
+	for _, tt := range []struct {
+		msg     support.Message
+		ignored bool
+	}{
+		{support.Message{Rule: "workload-image-tag", Path: "templates/debug-pod.yaml", Err: err}, true},
+		{support.Message{Rule: "workload-image-tag", Path: "templates/pod.yaml", Err: err}, false},
+		{support.Message{Rule: "workload-probes", Path: "templates/debug-pod.yaml", Err: err}, false},
+		{support.Message{Rule: "templates", Path: "templates/legacy.yaml", Line: 12, Err: err}, true},
+		{support.Message{Rule: "templates", Path: "templates/legacy.yaml", Line: 13, Err: err}, false},
+	} {
+		if got := c.Ignored(tt.msg); got != tt.ignored {
+			t.Errorf("expected %s of rule %s ignored: %t, got %t", tt.msg, tt.msg.Rule, tt.ignored, got)
+		}
+	}
+}
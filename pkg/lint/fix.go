@@ -0,0 +1,270 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/pmezard/go-difflib/difflib"
+	"gopkg.in/yaml.v3"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// Fix is a safe mechanical fix of a file of a chart, keeping the comments and
+// the layout of the file.
+type Fix struct {
+	// Path is the path to the file in the chart, like "Chart.yaml".
+	Path string
+	// Changes describe the changes of the fix.
+	Changes []string
+	// Original is the content of the file before the fix.
+	Original []byte
+	// Fixed is the content of the file after the fix.
+	Fixed []byte
+}
+
+// Diff returns the unified diff of the fix.
+func (f *Fix) Diff() string {
+	diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(f.Original)),
+		B:        difflib.SplitLines(string(f.Fixed)),
+		FromFile: "a/" + f.Path,
+		ToFile:   "b/" + f.Path,
+		Context:  3,
+	})
+	return diff
+}
+
+// Apply writes the fixed file in the chart directory.
+func (f *Fix) Apply(chartDir string) error {
+	filename := filepath.Join(chartDir, filepath.FromSlash(f.Path))
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, f.Fixed, fi.Mode())
+}
+
+// Fixes returns the fixes of the chart in the chart directory, without
+// applying them:
+//
+//   - the missing apiVersion and name of Chart.yaml are added, v1 like the
+//     charts without apiVersion and the name of the directory
+//   - the apiVersion of Chart.yaml is lower-cased
+//   - the version and appVersion of Chart.yaml are quoted if they are numbers
+//   - the icon URL of Chart.yaml without a scheme gets the https scheme
+//   - the numbers of values.yaml losing digits when parsed, like the version
+//     1.10 or the octal 0755, are quoted
+func Fixes(chartDir string) ([]Fix, error) {
+	var fixes []Fix
+	for _, f := range []struct {
+		path string
+		fix  func(chartDir string, lines []string, doc *yaml.Node) ([]string, []textEdit)
+	}{
+		{chartutil.ChartfileName, fixChartfile},
+		{chartutil.ValuesfileName, fixValues},
+	} {
+		original, err := os.ReadFile(filepath.Join(chartDir, f.path))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var doc yaml.Node
+		if err := yaml.Unmarshal(original, &doc); err != nil {
+			// The invalid files are reported by the rules
+			continue
+		}
+		lines := strings.Split(string(original), "\n")
+		changes, edits := f.fix(chartDir, lines, &doc)
+		if len(edits) == 0 {
+			continue
+		}
+		fixes = append(fixes, Fix{
+			Path:     f.path,
+			Changes:  changes,
+			Original: original,
+			Fixed:    []byte(strings.Join(applyEdits(lines, edits), "\n")),
+		})
+	}
+	return fixes, nil
+}
+
+// textEdit replaces the text old at the line and the column of a file, both
+// starting at 1, by new. The edits of line 0 insert new as a line at the top
+// of the file.
+type textEdit struct {
+	line, column int
+	old, new     string
+}
+
+// applyEdits returns the lines with the edits.
+func applyEdits(lines []string, edits []textEdit) []string {
+	sort.SliceStable(edits, func(i, j int) bool {
+		if edits[i].line != edits[j].line {
+			return edits[i].line < edits[j].line
+		}
+		return edits[i].column > edits[j].column
+	})
+	result := append([]string(nil), lines...)
+	var inserted []string
+	for _, e := range edits {
+		if e.line == 0 {
+			inserted = append(inserted, e.new)
+			continue
+		}
+		line := result[e.line-1]
+		result[e.line-1] = line[:e.column-1] + e.new + line[e.column-1+len(e.old):]
+	}
+	return append(inserted, result...)
+}
+
+// scalarText returns the text of the scalar node in the lines, with its
+// quotes, or false if the node spans several lines or has escapes.
+func scalarText(lines []string, node *yaml.Node) (string, bool) {
+	if node.Kind != yaml.ScalarNode || node.Line < 1 || node.Line > len(lines) || node.Column < 1 {
+		return "", false
+	}
+	text := node.Value
+	switch node.Style {
+	case 0:
+	case yaml.DoubleQuotedStyle:
+		text = `"` + text + `"`
+	case yaml.SingleQuotedStyle:
+		text = `'` + text + `'`
+	default:
+		return "", false
+	}
+	line := lines[node.Line-1]
+	if node.Column-1 > len(line) || !strings.HasPrefix(line[node.Column-1:], text) {
+		return "", false
+	}
+	return text, true
+}
+
+// replaceScalar returns the edit replacing the text of the scalar node by
+// text, or false if the node cannot be edited.
+func replaceScalar(lines []string, node *yaml.Node, text string) (textEdit, bool) {
+	old, ok := scalarText(lines, node)
+	if !ok {
+		return textEdit{}, false
+	}
+	return textEdit{line: node.Line, column: node.Column, old: old, new: text}, true
+}
+
+// mappingValue returns the value of the key in the mapping node, or nil.
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func fixChartfile(chartDir string, lines []string, doc *yaml.Node) ([]string, []textEdit) {
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	root := doc.Content[0]
+	var changes []string
+	var edits []textEdit
+
+	if v := mappingValue(root, "apiVersion"); v == nil {
+		changes = append(changes, fmt.Sprintf("add the missing apiVersion %s", chart.APIVersionV1))
+		edits = append(edits, textEdit{new: "apiVersion: " + chart.APIVersionV1})
+	} else if normalized := strings.ToLower(strings.TrimSpace(v.Value)); normalized != v.Value && (normalized == chart.APIVersionV1 || normalized == chart.APIVersionV2) {
+		if e, ok := replaceScalar(lines, v, normalized); ok {
+			changes = append(changes, fmt.Sprintf("normalize the apiVersion %q to %s", v.Value, normalized))
+			edits = append(edits, e)
+		}
+	}
+
+	if mappingValue(root, "name") == nil {
+		name := filepath.Base(chartDir)
+		if govalidator.Matches(name, `^[a-zA-Z0-9._-]+$`) {
+			changes = append(changes, fmt.Sprintf("add the missing name %s, the name of the chart directory", name))
+			edits = append(edits, textEdit{new: "name: " + name})
+		}
+	}
+
+	for _, key := range []string{"version", "appVersion"} {
+		if v := mappingValue(root, key); v != nil && v.Kind == yaml.ScalarNode && v.Style == 0 && v.Tag != "!!str" && v.Tag != "!!null" {
+			if e, ok := replaceScalar(lines, v, `"`+v.Value+`"`); ok {
+				changes = append(changes, fmt.Sprintf("quote the %s %s", key, v.Value))
+				edits = append(edits, e)
+			}
+		}
+	}
+
+	if v := mappingValue(root, "icon"); v != nil && v.Kind == yaml.ScalarNode && v.Value != "" && !govalidator.IsRequestURL(v.Value) {
+		icon := "https://" + strings.TrimPrefix(v.Value, "//")
+		if govalidator.IsRequestURL(icon) && !strings.Contains(v.Value, "://") {
+			if e, ok := replaceScalar(lines, v, icon); ok {
+				changes = append(changes, fmt.Sprintf("add the https scheme to the icon URL %s", v.Value))
+				edits = append(edits, e)
+			}
+		}
+	}
+	return changes, edits
+}
+
+// ambiguousNumber matches the numbers losing digits when parsed, like the
+// versions 1.10 and 2.0, and the numbers with leading zeros parsed as octal
+// numbers, like 0755.
+var ambiguousNumber = regexp.MustCompile(`^[-+]?([0-9]+\.[0-9]*0|0[0-9]+)$`)
+
+func fixValues(_ string, lines []string, doc *yaml.Node) ([]string, []textEdit) {
+	var changes []string
+	var edits []textEdit
+	var walk func(node *yaml.Node, path string)
+	walk = func(node *yaml.Node, path string) {
+		switch node.Kind {
+		case yaml.DocumentNode:
+			for _, n := range node.Content {
+				walk(n, path)
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				walk(node.Content[i+1], strings.TrimPrefix(path+"."+node.Content[i].Value, "."))
+			}
+		case yaml.SequenceNode:
+			for i, n := range node.Content {
+				walk(n, fmt.Sprintf("%s[%d]", path, i))
+			}
+		case yaml.ScalarNode:
+			if node.Style != 0 || !ambiguousNumber.MatchString(node.Value) {
+				return
+			}
+			if e, ok := replaceScalar(lines, node, `"`+node.Value+`"`); ok {
+				changes = append(changes, fmt.Sprintf("quote the number %s of %s", node.Value, path))
+				edits = append(edits, e)
+			}
+		}
+	}
+	walk(doc, "")
+	return changes, edits
+}
@@ -0,0 +1,127 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const unfixedChartfile = `# The chart of the fixes
+version: 0.1.0
+appVersion: 1.10 # the version of the app
+icon: example.com/icon.png
+`
+
+const fixedChartfile = `apiVersion: v1
+name: fixme
+# The chart of the fixes
+version: 0.1.0
+appVersion: "1.10" # the version of the app
+icon: https://example.com/icon.png
+`
+
+const unfixedValues = `# The image of the app
+image:
+  tag: 2.0
+  pullPolicy: IfNotPresent
+replicas: 1
+ratio: 0.5
+modes: [0755, 0644]
+quoted: "1.10"
+`
+
+const fixedValues = `# The image of the app
+image:
+  tag: "2.0"
+  pullPolicy: IfNotPresent
+replicas: 1
+ratio: 0.5
+modes: ["0755", "0644"]
+quoted: "1.10"
+`
+
+func writeChart(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "fixme")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestFixes(t *testing.T) {
+	dir := writeChart(t, map[string]string{"Chart.yaml": unfixedChartfile, "values.yaml": unfixedValues})
+	fixes, err := Fixes(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixes) != 2 {
+		t.Fatalf("expected fixes of Chart.yaml and values.yaml, got %d fixes", len(fixes))
+	}
+
+	for i, expected := range []struct {
+		path    string
+		fixed   string
+		changes int
+	}{
+		{"Chart.yaml", fixedChartfile, 4},
+		{"values.yaml", fixedValues, 3},
+	} {
+		f := fixes[i]
+		if f.Path != expected.path || string(f.Fixed) != expected.fixed {
+			t.Errorf("expected %s fixed as:\n%s\ngot %s fixed as:\n%s", expected.path, expected.fixed, f.Path, f.Fixed)
+		}
+		if len(f.Changes) != expected.changes {
+			t.Errorf("expected %d changes of %s, got %q", expected.changes, f.Path, f.Changes)
+		}
+		if err := f.Apply(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if diff := fixes[0].Diff(); !strings.Contains(diff, "--- a/Chart.yaml\n+++ b/Chart.yaml\n") || !strings.Contains(diff, "-appVersion: 1.10 # the version of the app\n") || !strings.Contains(diff, "+appVersion: \"1.10\" # the version of the app\n") {
+		t.Errorf("unexpected diff:\n%s", diff)
+	}
+	if fixes, err := Fixes(dir); err != nil || len(fixes) != 0 {
+		t.Errorf("expected no fixes of the fixed chart, got %v and %v", fixes, err)
+	}
+}
+
+func TestFixesAPIVersion(t *testing.T) {
+	dir := writeChart(t, map[string]string{"Chart.yaml": "apiVersion: 'V2'\nname: fixme\nversion: 0.1.0\n"})
+	fixes, err := Fixes(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixes) != 1 || string(fixes[0].Fixed) != "apiVersion: v2\nname: fixme\nversion: 0.1.0\n" {
+		t.Errorf("expected the apiVersion to be normalized, got %v", fixes)
+	}
+}
+
+func TestFixesGoodChart(t *testing.T) {
+	if fixes, err := Fixes(goodChartDir); err != nil || len(fixes) != 0 {
+		t.Errorf("expected no fixes of a good chart, got %v and %v", fixes, err)
+	}
+}
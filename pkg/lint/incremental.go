@@ -0,0 +1,226 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+// cacheVersion is the version of the format of the incremental cache files.
+const cacheVersion = 1
+
+// incrementalCache is the result of a run of the rules on a chart, cached for
+// the next incremental run.
+type incrementalCache struct {
+	Version int `json:"version"`
+	// Options is the digest of the options and the rules of the run.
+	Options string `json:"options"`
+	// Files are the modification times and the sizes of the files of the
+	// chart, by path.
+	Files map[string]fileStamp `json:"files"`
+	// Messages are the messages of the rules, by rule ID.
+	Messages map[string][]cachedMessage `json:"messages"`
+}
+
+type fileStamp struct {
+	ModTime int64 `json:"modTime"`
+	Size    int64 `json:"size"`
+}
+
+type cachedMessage struct {
+	Severity int    `json:"severity"`
+	Path     string `json:"path"`
+	Line     int    `json:"line,omitempty"`
+	Message  string `json:"message"`
+}
+
+// CacheFile returns the incremental cache file of a chart in the cache
+// directory. The key identifies the chart, like the absolute path to its
+// directory.
+func CacheFile(cacheDir, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(cacheDir, hex.EncodeToString(sum[:8])+".json")
+}
+
+// RunIncremental runs the rules on the chart like Run, reusing the messages
+// of the rules cached in the cache file by the previous run whose inputs did
+// not change. The changed files are the paths relative to the chart
+// directory, or the files whose modification times or sizes changed since the
+// previous run if nil. All the rules are run if the options or the rules, like
+// their severities, changed.
+//
+// The messages of the run are cached in the cache file for the next run.
+func RunIncremental(basedir string, opts Options, cacheFile string, changed []string, rules ...Rule) (support.Linter, error) {
+	chartDir, _ := filepath.Abs(basedir)
+	linter := support.Linter{ChartDir: chartDir}
+
+	digest, err := optionsDigest(opts, rules)
+	if err != nil {
+		return linter, err
+	}
+	files, err := stampFiles(chartDir)
+	if err != nil {
+		return linter, err
+	}
+
+	var cache incrementalCache
+	if data, err := os.ReadFile(cacheFile); err == nil {
+		// An invalid cache is ignored, like a missing cache
+		json.Unmarshal(data, &cache)
+	}
+	valid := cache.Version == cacheVersion && cache.Options == digest
+	if valid && changed == nil {
+		changed = changedFiles(cache.Files, files)
+	}
+
+	opts = opts.withRenderCache()
+	next := incrementalCache{
+		Version:  cacheVersion,
+		Options:  digest,
+		Files:    files,
+		Messages: make(map[string][]cachedMessage, len(rules)),
+	}
+	for _, r := range rules {
+		md := r.Metadata()
+		cached, ok := cache.Messages[md.ID]
+		if valid && ok && !inputsChanged(md.Inputs, changed) {
+			for _, m := range cached {
+				linter.Messages = append(linter.Messages, support.Message{
+					Severity: m.Severity,
+					Path:     m.Path,
+					Line:     m.Line,
+					Err:      errors.New(m.Message),
+					Rule:     md.ID,
+				})
+			}
+			next.Messages[md.ID] = cached
+			continue
+		}
+
+		first := len(linter.Messages)
+		linter.Rule = md.ID
+		r.Run(&linter, opts)
+		linter.Rule = ""
+		msgs := []cachedMessage{}
+		for _, m := range linter.Messages[first:] {
+			msgs = append(msgs, cachedMessage{Severity: m.Severity, Path: m.Path, Line: m.Line, Message: m.Err.Error()})
+		}
+		next.Messages[md.ID] = msgs
+	}
+
+	data, err := json.Marshal(&next)
+	if err != nil {
+		return linter, err
+	}
+	if err := os.MkdirAll(filepath.Dir(cacheFile), 0755); err != nil {
+		return linter, err
+	}
+	return linter, os.WriteFile(cacheFile, data, 0644)
+}
+
+// optionsDigest returns the digest of the options and the rules of a run.
+func optionsDigest(opts Options, rules []Rule) (string, error) {
+	run := struct {
+		Options Options
+		Rules   []RuleMetadata
+	}{Options: opts}
+	for _, r := range rules {
+		run.Rules = append(run.Rules, r.Metadata())
+	}
+	data, err := json.Marshal(&run)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to digest the lint options")
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// stampFiles returns the modification times and the sizes of the files of
+// the chart directory, by slash-separated path.
+func stampFiles(chartDir string) (map[string]fileStamp, error) {
+	files := make(map[string]fileStamp)
+	err := filepath.WalkDir(chartDir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(chartDir, name)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = fileStamp{ModTime: fi.ModTime().UnixNano(), Size: fi.Size()}
+		return nil
+	})
+	return files, err
+}
+
+// changedFiles returns the files added, removed or modified between the
+// stamps.
+func changedFiles(before, after map[string]fileStamp) []string {
+	changed := []string{}
+	for name, stamp := range after {
+		if before[name] != stamp {
+			changed = append(changed, name)
+		}
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+// inputsChanged returns whether one of the changed files is an input of a
+// rule. All the files are inputs of the rules without inputs.
+func inputsChanged(inputs, changed []string) bool {
+	if len(changed) == 0 {
+		return false
+	}
+	if len(inputs) == 0 {
+		return true
+	}
+	for _, name := range changed {
+		name = filepath.ToSlash(name)
+		for _, input := range inputs {
+			if ok, _ := path.Match(input, name); ok || strings.HasPrefix(name, input+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+// countingRules returns a rule of Chart.yaml and a rule of all the files,
+// counting their runs.
+func countingRules(runs map[string]int) []Rule {
+	return []Rule{
+		RuleFunc(RuleMetadata{ID: "house/chartfile", Severity: support.WarningSev, Inputs: []string{"Chart.yaml"}}, func(linter *support.Linter, _ Options) {
+			runs["house/chartfile"]++
+			linter.RunLinterRule(support.WarningSev, "Chart.yaml", errors.New("the chart has no team"))
+		}),
+		RuleFunc(RuleMetadata{ID: "house/templates", Severity: support.InfoSev}, func(linter *support.Linter, _ Options) {
+			runs["house/templates"]++
+		}),
+	}
+}
+
+func TestRunIncremental(t *testing.T) {
+	dir := writeChart(t, map[string]string{"Chart.yaml": "name: fixme\n", "values.yaml": "replicas: 1\n"})
+	cacheFile := CacheFile(t.TempDir(), dir)
+	runs := map[string]int{}
+	rules := countingRules(runs)
+
+	run := func(opts Options, changed []string) []support.Message {
+		t.Helper()
+		linter, err := RunIncremental(dir, opts, cacheFile, changed, rules...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(linter.Messages) != 1 || linter.Messages[0].Rule != "house/chartfile" || linter.Messages[0].Err.Error() != "the chart has no team" {
+			t.Fatalf("unexpected messages %#v", linter.Messages)
+		}
+		return linter.Messages
+	}
+	expectRuns := func(chartfile, templates int) {
+		t.Helper()
+		if runs["house/chartfile"] != chartfile || runs["house/templates"] != templates {
+			t.Errorf("expected %d and %d runs, got %v", chartfile, templates, runs)
+		}
+	}
+
+	run(Options{}, nil)
+	expectRuns(1, 1)
+
+	// Nothing changed, the messages are cached
+	run(Options{}, nil)
+	expectRuns(1, 1)
+
+	// values.yaml is not an input of the rule of Chart.yaml
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "values.yaml"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	run(Options{}, nil)
+	expectRuns(1, 2)
+
+	if err := os.Chtimes(filepath.Join(dir, "Chart.yaml"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	run(Options{}, nil)
+	expectRuns(2, 3)
+
+	// The changed files replace the modification times
+	run(Options{}, []string{})
+	expectRuns(2, 3)
+	run(Options{}, []string{"Chart.yaml"})
+	expectRuns(3, 4)
+
+	// The options invalidate the cache
+	run(Options{Namespace: "other"}, []string{})
+	expectRuns(4, 5)
+}
+
+func TestInputsChanged(t *testing.T) {
+	tests := []struct {
+		inputs, changed []string
+		expect          bool
+	}{
+		{nil, nil, false},
+		{nil, []string{"templates/service.yaml"}, true},
+		{[]string{"Chart.yaml"}, []string{"templates/service.yaml"}, false},
+		{[]string{"Chart.yaml"}, []string{"Chart.yaml"}, true},
+		{[]string{"charts"}, []string{"charts/sub/values.yaml"}, true},
+		{[]string{"ci/*-values.yaml"}, []string{"ci/ingress-values.yaml"}, true},
+	}
+	for _, tt := range tests {
+		if got := inputsChanged(tt.inputs, tt.changed); got != tt.expect {
+			t.Errorf("inputs %v with changed %v: expected %t, got %t", tt.inputs, tt.changed, tt.expect, got)
+		}
+	}
+}
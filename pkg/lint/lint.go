@@ -17,10 +17,7 @@ limitations under the License.
 package lint // import "helm.sh/helm/v3/pkg/lint"
 
 import (
-	"path/filepath"
-
 	"helm.sh/helm/v3/pkg/chartutil"
-	"helm.sh/helm/v3/pkg/lint/rules"
 	"helm.sh/helm/v3/pkg/lint/support"
 )
 
@@ -35,14 +32,14 @@ func AllWithKubeVersion(basedir string, values map[string]interface{}, namespace
 }
 
 // AllWithKubeVersionAndSchemaValidation runs all the available linters on the given base directory, allowing to specify the kubernetes version and if schema validation is enabled or not.
+//
+// The available linters are the built-in rules and the rules registered with
+// Register, except the optional rules.
 func AllWithKubeVersionAndSchemaValidation(basedir string, values map[string]interface{}, namespace string, kubeVersion *chartutil.KubeVersion, skipSchemaValidation bool) support.Linter {
-	// Using abs path to get directory context
-	chartDir, _ := filepath.Abs(basedir)
-
-	linter := support.Linter{ChartDir: chartDir}
-	rules.Chartfile(&linter)
-	rules.ValuesWithOverrides(&linter, values)
-	rules.TemplatesWithSkipSchemaValidation(&linter, values, namespace, kubeVersion, skipSchemaValidation)
-	rules.Dependencies(&linter)
-	return linter
+	return Run(basedir, Options{
+		Values:               values,
+		Namespace:            namespace,
+		KubeVersion:          kubeVersion,
+		SkipSchemaValidation: skipSchemaValidation,
+	}, DefaultRules()...)
 }
@@ -137,6 +137,38 @@ func TestHelmCreateChart(t *testing.T) {
 	}
 }
 
+// TestHelmCreateChartWithTemplateSets tests that the charts created with
+// template sets pass a `helm lint` test.
+func TestHelmCreateChartWithTemplateSets(t *testing.T) {
+	for name, opts := range map[string]chartutil.CreateOptions{
+		"extrasets": {
+			With:    []string{"statefulset", "cronjob", "networkpolicy"},
+			Without: []string{"ingress", "serviceaccount"},
+		},
+		"cronjobonly": {
+			With:    []string{"cronjob"},
+			Without: []string{"deployment", "service", "serviceaccount", "ingress", "hpa", "tests"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			createdChart, err := chartutil.CreateWithOptions(name, t.TempDir(), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			m := All(createdChart, values, namespace, true).Messages
+			if ll := len(m); ll != 1 {
+				t.Errorf("All should have had exactly 1 error. Got %d", ll)
+				for i, msg := range m {
+					t.Logf("Message %d: %s", i, msg.Error())
+				}
+			} else if msg := m[0].Err.Error(); !strings.Contains(msg, "icon is recommended") {
+				t.Errorf("Unexpected lint error: %s", msg)
+			}
+		})
+	}
+}
+
 // lint ignores import-values
 // See https://github.com/helm/helm/issues/9658
 func TestSubChartValuesChart(t *testing.T) {
@@ -0,0 +1,134 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/lint/support"
+	"helm.sh/helm/v3/pkg/plugin"
+)
+
+// PluginRules returns the lint rules of the installed plugins. Their IDs are
+// prefixed by the names of their plugins, like "myplugin/labels".
+func PluginRules(settings *cli.EnvSettings) ([]Rule, error) {
+	plugins, err := plugin.FindPlugins(settings.PluginsDirectory)
+	if err != nil {
+		return nil, err
+	}
+	var result []Rule
+	for _, plug := range plugins {
+		for _, r := range plug.Metadata.LintRules {
+			severity := support.ErrorSev
+			if r.Severity != "" {
+				if severity, err = support.ParseSeverity(r.Severity); err != nil {
+					return nil, errors.Wrapf(err, "lint rule %q of plugin %q", r.ID, plug.Metadata.Name)
+				}
+			}
+			result = append(result, &pluginRule{
+				md: RuleMetadata{
+					ID:          plug.Metadata.Name + "/" + r.ID,
+					Description: r.Description,
+					Severity:    severity,
+				},
+				command:      r.Command,
+				settings:     settings,
+				name:         plug.Metadata.Name,
+				base:         plug.Dir,
+				capabilities: plug.Metadata.Capabilities,
+			})
+		}
+	}
+	return result, nil
+}
+
+// pluginRule is a lint rule running the command of a plugin.
+type pluginRule struct {
+	md           RuleMetadata
+	command      string
+	settings     *cli.EnvSettings
+	name         string
+	base         string
+	capabilities *plugin.Capabilities
+}
+
+// pluginFailure is a failure written by the command of a plugin rule.
+type pluginFailure struct {
+	Severity string `json:"severity"`
+	Path     string `json:"path"`
+	Line     int    `json:"line"`
+	Message  string `json:"message"`
+}
+
+func (r *pluginRule) Metadata() RuleMetadata { return r.md }
+
+// Run runs the command of the rule on the chart directory, recording the
+// failures it writes.
+func (r *pluginRule) Run(linter *support.Linter, _ Options) {
+	plugin.SetupPluginEnv(r.settings, r.name, r.base)
+	commands := strings.Split(os.ExpandEnv(r.command), " ")
+	prog := exec.Command(commands[0], append(commands[1:], linter.ChartDir)...)
+	prog.Env = os.Environ()
+	cleanup, err := plugin.Sandbox(prog, r.name, r.capabilities)
+	if err != nil {
+		linter.RunLinterRule(support.ErrorSev, linter.ChartDir, errors.Wrapf(err, "lint rule %q failed", r.md.ID))
+		return
+	}
+	defer cleanup()
+	buf := bytes.NewBuffer(nil)
+	prog.Stdout = buf
+	prog.Stderr = os.Stderr
+	if err := prog.Run(); err != nil {
+		linter.RunLinterRule(support.ErrorSev, linter.ChartDir, errors.Wrapf(err, "lint rule %q failed", r.md.ID))
+		return
+	}
+
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var f pluginFailure
+		if err := json.Unmarshal([]byte(line), &f); err != nil {
+			linter.RunLinterRule(support.ErrorSev, linter.ChartDir, errors.Wrapf(err, "lint rule %q wrote an invalid failure", r.md.ID))
+			continue
+		}
+		severity := r.md.Severity
+		if f.Severity != "" {
+			if s, err := support.ParseSeverity(f.Severity); err == nil {
+				severity = s
+			}
+		}
+		path := f.Path
+		if path == "" {
+			path = linter.ChartDir
+		}
+		linter.RunLinterRule(severity, path, errors.New(f.Message))
+		if f.Line > 0 {
+			linter.Messages[len(linter.Messages)-1].Line = f.Line
+		}
+	}
+}
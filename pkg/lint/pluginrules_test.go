@@ -0,0 +1,65 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"runtime"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+func TestPluginRules(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("TODO: refactor this test to work on windows")
+	}
+
+	env := cli.New()
+	env.PluginsDirectory = "testdata/plugins"
+	rules, err := PluginRules(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 {
+		t.Fatalf("expected 2 rules, got %d", len(rules))
+	}
+	if md := rules[0].Metadata(); md.ID != "linter/icon" || md.Severity != support.WarningSev {
+		t.Errorf("unexpected metadata %#v", md)
+	}
+	if md := rules[1].Metadata(); md.ID != "linter/broken" || md.Severity != support.ErrorSev {
+		t.Errorf("unexpected metadata %#v", md)
+	}
+
+	linter := Run(goodChartDir, Options{}, rules...)
+	m := linter.Messages
+	if len(m) != 3 {
+		t.Fatalf("expected 3 messages, got %#v", m)
+	}
+	if m[0].Severity != support.WarningSev || m[0].Path != "Chart.yaml" || m[0].Line != 2 || m[0].Err.Error() != "the chart has no icon" {
+		t.Errorf("unexpected message %#v", m[0])
+	}
+	if m[1].Severity != support.InfoSev || m[1].Rule != "linter/icon" {
+		t.Errorf("unexpected message %#v", m[1])
+	}
+	if m[2].Severity != support.ErrorSev || m[2].Rule != "linter/broken" {
+		t.Errorf("unexpected message %#v", m[2])
+	}
+	if linter.HighestSeverity != support.ErrorSev {
+		t.Errorf("expected the highest severity to be an error, got %d", linter.HighestSeverity)
+	}
+}
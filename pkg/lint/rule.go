@@ -0,0 +1,323 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/lint/rules"
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+// Rule is a lint rule checking charts.
+//
+// Rules registered with Register are run by All and 'helm lint' after the
+// built-in rules.
+type Rule interface {
+	// Metadata describes the rule.
+	Metadata() RuleMetadata
+	// Run checks the chart of the linter, recording its failures with the
+	// linter.
+	Run(linter *support.Linter, opts Options)
+}
+
+// RuleMetadata describes a lint rule.
+type RuleMetadata struct {
+	// ID identifies the rule, like "templates" or "example.com/labels".
+	ID string
+	// Description describes what the rule checks.
+	Description string
+	// Severity is the highest severity of the failures of the rule, one of
+	// the support.*Sev constants.
+	Severity int
+	// Optional rules are only run when enabled, like the best-practice
+	// rules of the workloads.
+	Optional bool
+	// Inputs are the files of the chart the rule checks, patterns like
+	// "templates/*.yaml" or directories like "charts", for the incremental
+	// runs. The rules without inputs check all the files of the chart, like
+	// the rules rendering the templates.
+	Inputs []string
+}
+
+// Options are the parameters of a linting run.
+type Options struct {
+	// Values override the values of the chart.
+	Values map[string]interface{}
+	// Namespace is the namespace the chart is rendered in.
+	Namespace string
+	// KubeVersion is the Kubernetes version the chart is rendered for.
+	KubeVersion *chartutil.KubeVersion
+	// SkipSchemaValidation disables the validation of the values against
+	// the JSON schema of the chart.
+	SkipSchemaValidation bool
+	// KubeSchemaDirs are the directories of the JSON schemas of the custom
+	// resources, like <group>/<kind>_<version>.json.
+	KubeSchemaDirs []string
+
+	// rendered holds the resources rendered for the rules of a run, so the
+	// chart is rendered once per run.
+	rendered *renderedResources
+}
+
+type renderedResources struct {
+	once      sync.Once
+	resources []rules.Resource
+}
+
+// withRenderCache returns the options sharing the rendered resources between
+// the rules of a run.
+func (o Options) withRenderCache() Options {
+	o.rendered = &renderedResources{}
+	return o
+}
+
+// Resources returns the Kubernetes resources rendered by the templates of the
+// chart of the linter, for the rules checking the rendered resources. The
+// chart is rendered once per run of the rules, the resources being shared by
+// its rules, which must not modify them.
+func (o Options) Resources(linter *support.Linter) []rules.Resource {
+	render := func() []rules.Resource {
+		return rules.RenderResources(linter.ChartDir, o.Values, o.Namespace, o.KubeVersion)
+	}
+	if o.rendered == nil {
+		return render()
+	}
+	o.rendered.once.Do(func() { o.rendered.resources = render() })
+	return o.rendered.resources
+}
+
+type ruleFunc struct {
+	md RuleMetadata
+	fn func(*support.Linter, Options)
+}
+
+func (r ruleFunc) Metadata() RuleMetadata                   { return r.md }
+func (r ruleFunc) Run(linter *support.Linter, opts Options) { r.fn(linter, opts) }
+
+// RuleFunc returns the rule described by md, running fn.
+func RuleFunc(md RuleMetadata, fn func(linter *support.Linter, opts Options)) Rule {
+	return ruleFunc{md: md, fn: fn}
+}
+
+type severityRule struct {
+	Rule
+	severity int
+}
+
+func (r severityRule) Metadata() RuleMetadata {
+	md := r.Rule.Metadata()
+	md.Severity = r.severity
+	return md
+}
+
+func (r severityRule) Run(linter *support.Linter, opts Options) {
+	rl := support.Linter{ChartDir: linter.ChartDir, Rule: linter.Rule}
+	r.Rule.Run(&rl, opts)
+	for _, msg := range rl.Messages {
+		linter.RunLinterRule(r.severity, msg.Path, msg.Err)
+		linter.Messages[len(linter.Messages)-1].Line = msg.Line
+	}
+}
+
+// WithSeverity returns the rule r recording its failures with the severity,
+// one of the support.*Sev constants.
+func WithSeverity(r Rule, severity int) Rule {
+	return severityRule{Rule: r, severity: severity}
+}
+
+// builtinRules are the rules of Helm, run first.
+var builtinRules = []Rule{
+	RuleFunc(RuleMetadata{
+		ID:          "chartfile",
+		Description: "Chart.yaml is well-formed, with a valid name, version and maintainers",
+		Severity:    support.ErrorSev,
+		Inputs:      []string{"Chart.yaml"},
+	}, func(linter *support.Linter, _ Options) {
+		rules.Chartfile(linter)
+	}),
+	RuleFunc(RuleMetadata{
+		ID:          "values",
+		Description: "values.yaml is well-formed and matches the schema of the chart",
+		Severity:    support.ErrorSev,
+		Inputs:      []string{"values.yaml", "values.schema.json"},
+	}, func(linter *support.Linter, opts Options) {
+		rules.ValuesWithOverrides(linter, opts.Values)
+	}),
+	RuleFunc(RuleMetadata{
+		ID:          "templates",
+		Description: "the templates render to valid Kubernetes manifests without deprecated APIs",
+		Severity:    support.ErrorSev,
+	}, func(linter *support.Linter, opts Options) {
+		rules.TemplatesWithSkipSchemaValidation(linter, opts.Values, opts.Namespace, opts.KubeVersion, opts.SkipSchemaValidation)
+	}),
+	RuleFunc(RuleMetadata{
+		ID:          "manifest-schemas",
+		Description: "the rendered Kubernetes resources match their schemas, without unknown fields or fields of the wrong types",
+		Severity:    support.ErrorSev,
+		Optional:    true,
+	}, func(linter *support.Linter, opts Options) {
+		rules.ManifestSchemas(linter, opts.Resources(linter), opts.KubeSchemaDirs)
+	}),
+	RuleFunc(RuleMetadata{
+		ID:          "dependencies",
+		Description: "the dependencies of Chart.yaml are in the charts directory and unique",
+		Severity:    support.ErrorSev,
+		Inputs:      []string{"Chart.yaml", "Chart.lock", "requirements.yaml", "requirements.lock", "charts"},
+	}, func(linter *support.Linter, _ Options) {
+		rules.Dependencies(linter)
+	}),
+	RuleFunc(RuleMetadata{
+		ID:          "values-schemas",
+		Description: "the values match the schemas of the chart and its subcharts, without rendering the templates",
+		Severity:    support.ErrorSev,
+		Optional:    true,
+		Inputs:      []string{"Chart.yaml", "values.yaml", "values.schema.json", "charts"},
+	}, func(linter *support.Linter, opts Options) {
+		rules.ValuesSchemas(linter, opts.Values)
+	}),
+	RuleFunc(RuleMetadata{
+		ID:          "workload-resources",
+		Description: "the containers of the workloads have resource requests and limits",
+		Severity:    support.WarningSev,
+		Optional:    true,
+	}, func(linter *support.Linter, opts Options) {
+		rules.WorkloadResources(linter, opts.Resources(linter))
+	}),
+	RuleFunc(RuleMetadata{
+		ID:          "workload-image-tag",
+		Description: "the images of the workloads have a tag other than latest, or a digest",
+		Severity:    support.WarningSev,
+		Optional:    true,
+	}, func(linter *support.Linter, opts Options) {
+		rules.WorkloadImageTags(linter, opts.Resources(linter))
+	}),
+	RuleFunc(RuleMetadata{
+		ID:          "workload-probes",
+		Description: "the containers of the long-running workloads have liveness and readiness probes",
+		Severity:    support.WarningSev,
+		Optional:    true,
+	}, func(linter *support.Linter, opts Options) {
+		rules.WorkloadProbes(linter, opts.Resources(linter))
+	}),
+	RuleFunc(RuleMetadata{
+		ID:          "workload-security-context",
+		Description: "the containers of the workloads have a securityContext",
+		Severity:    support.WarningSev,
+		Optional:    true,
+	}, func(linter *support.Linter, opts Options) {
+		rules.WorkloadSecurityContexts(linter, opts.Resources(linter))
+	}),
+	RuleFunc(RuleMetadata{
+		ID:          "security-privileged",
+		Description: "the containers of the workloads are not privileged",
+		Severity:    support.WarningSev,
+		Optional:    true,
+	}, func(linter *support.Linter, opts Options) {
+		rules.SecurityPrivileged(linter, opts.Resources(linter))
+	}),
+	RuleFunc(RuleMetadata{
+		ID:          "security-host-access",
+		Description: "the workloads use neither hostPath volumes nor the network, PID or IPC namespaces of their hosts",
+		Severity:    support.WarningSev,
+		Optional:    true,
+	}, func(linter *support.Linter, opts Options) {
+		rules.SecurityHostAccess(linter, opts.Resources(linter))
+	}),
+	RuleFunc(RuleMetadata{
+		ID:          "security-rbac-wildcards",
+		Description: "the rules of the Roles and ClusterRoles grant no wildcard verbs, resources or API groups",
+		Severity:    support.WarningSev,
+		Optional:    true,
+	}, func(linter *support.Linter, opts Options) {
+		rules.SecurityRBACWildcards(linter, opts.Resources(linter))
+	}),
+	RuleFunc(RuleMetadata{
+		ID:          "security-configmap-secrets",
+		Description: "the ConfigMaps hold no passwords, tokens or private keys",
+		Severity:    support.WarningSev,
+		Optional:    true,
+	}, func(linter *support.Linter, opts Options) {
+		rules.SecurityConfigMapSecrets(linter, opts.Resources(linter))
+	}),
+}
+
+var registry struct {
+	mu    sync.Mutex
+	rules []Rule
+}
+
+// Register registers a rule run by All and 'helm lint' after the built-in
+// rules and the rules registered before. It returns an error if the rule has
+// no ID, or the ID of a registered rule.
+func Register(r Rule) error {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	id := r.Metadata().ID
+	if id == "" {
+		return fmt.Errorf("lint rule has no ID")
+	}
+	for _, rule := range append(builtinRules, registry.rules...) {
+		if rule.Metadata().ID == id {
+			return fmt.Errorf("lint rule %q is already registered", id)
+		}
+	}
+	registry.rules = append(registry.rules, r)
+	return nil
+}
+
+// Rules returns the built-in rules, then the registered rules, including the
+// optional rules.
+func Rules() []Rule {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	all := make([]Rule, 0, len(builtinRules)+len(registry.rules))
+	all = append(all, builtinRules...)
+	return append(all, registry.rules...)
+}
+
+// DefaultRules returns the rules which are not optional.
+func DefaultRules() []Rule {
+	var result []Rule
+	for _, r := range Rules() {
+		if !r.Metadata().Optional {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// Run runs the rules on the chart in the base directory. The messages are
+// recorded with the IDs of their rules.
+func Run(basedir string, opts Options, rules ...Rule) support.Linter {
+	// Using abs path to get directory context
+	chartDir, _ := filepath.Abs(basedir)
+
+	linter := support.Linter{ChartDir: chartDir}
+	opts = opts.withRenderCache()
+	for _, r := range rules {
+		linter.Rule = r.Metadata().ID
+		r.Run(&linter, opts)
+	}
+	linter.Rule = ""
+	return linter
+}
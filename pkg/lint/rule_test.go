@@ -0,0 +1,113 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"errors"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/lint/rules"
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+func TestRegister(t *testing.T) {
+	defer func() { registry.rules = nil }()
+
+	rule := RuleFunc(RuleMetadata{ID: "house/maintainers", Severity: support.WarningSev}, func(linter *support.Linter, _ Options) {
+		linter.RunLinterRule(support.WarningSev, "Chart.yaml", errors.New("the team is not a maintainer"))
+	})
+	if err := Register(rule); err != nil {
+		t.Fatal(err)
+	}
+	if err := Register(rule); err == nil {
+		t.Error("expected an error registering a rule twice")
+	}
+	if err := Register(RuleFunc(RuleMetadata{ID: "templates"}, nil)); err == nil {
+		t.Error("expected an error registering a rule with the ID of a built-in rule")
+	}
+	if err := Register(RuleFunc(RuleMetadata{}, nil)); err == nil {
+		t.Error("expected an error registering a rule without an ID")
+	}
+
+	rules := Rules()
+	if len(rules) != len(builtinRules)+1 || rules[len(rules)-1].Metadata().ID != "house/maintainers" {
+		t.Fatalf("expected the registered rule after the built-in rules, got %d rules", len(rules))
+	}
+
+	m := All(goodChartDir, values, namespace, strict).Messages
+	if len(m) != 1 {
+		t.Fatalf("expected 1 message, got %#v", m)
+	}
+	if m[0].Rule != "house/maintainers" || m[0].Severity != support.WarningSev {
+		t.Errorf("unexpected message %#v", m[0])
+	}
+}
+
+func TestRunRecordsRules(t *testing.T) {
+	m := Run(badChartDir, Options{Namespace: namespace}, Rules()...).Messages
+	if len(m) == 0 {
+		t.Fatal("expected messages")
+	}
+	for _, msg := range m {
+		if msg.Rule == "" {
+			t.Errorf("message %q has no rule", msg.Err)
+		}
+	}
+}
+
+func TestWithSeverity(t *testing.T) {
+	rule := RuleFunc(RuleMetadata{ID: "house/icon", Severity: support.ErrorSev}, func(linter *support.Linter, _ Options) {
+		linter.RunLinterRule(support.ErrorSev, "Chart.yaml", errors.New("the icon is not the team icon"))
+	})
+	rule = WithSeverity(rule, support.InfoSev)
+	if md := rule.Metadata(); md.ID != "house/icon" || md.Severity != support.InfoSev {
+		t.Errorf("unexpected metadata %#v", md)
+	}
+
+	m := Run(goodChartDir, Options{Namespace: namespace}, rule).Messages
+	if len(m) != 1 {
+		t.Fatalf("expected 1 message, got %#v", m)
+	}
+	if m[0].Rule != "house/icon" || m[0].Severity != support.InfoSev {
+		t.Errorf("unexpected message %#v", m[0])
+	}
+}
+
+func TestDefaultRules(t *testing.T) {
+	for _, r := range DefaultRules() {
+		if r.Metadata().Optional {
+			t.Errorf("expected no optional rules, got %q", r.Metadata().ID)
+		}
+	}
+}
+
+func TestResourcesRenderedOnce(t *testing.T) {
+	var rendered [][]rules.Resource
+	rule := func(id string) Rule {
+		return RuleFunc(RuleMetadata{ID: id}, func(linter *support.Linter, opts Options) {
+			rendered = append(rendered, opts.Resources(linter))
+		})
+	}
+
+	Run(goodChartDir, Options{Values: values, Namespace: namespace}, rule("first"), rule("second"))
+	if len(rendered) != 2 || len(rendered[0]) == 0 {
+		t.Fatalf("expected the rules to get the rendered resources, got %v", rendered)
+	}
+	if rendered[0][0].Object != rendered[1][0].Object {
+		t.Error("expected the rules of a run to share the rendered resources")
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"k8s.io/apiserver/pkg/endpoints/deprecation"
 	kscheme "k8s.io/client-go/kubernetes/scheme"
 
+	"helm.sh/helm/v3/internal/deprecations"
 	"helm.sh/helm/v3/pkg/chartutil"
 )
 
@@ -64,28 +65,41 @@ func validateNoDeprecations(resource *K8sYamlStruct, kubeVersion *chartutil.Kube
 		minorVersion = kubeVersion.Minor
 	}
 
-	runtimeObject, err := resourceToRuntimeObject(resource)
+	maj, err := strconv.Atoi(majorVersion)
 	if err != nil {
-		// do not error for non-kubernetes resources
-		if runtime.IsNotRegisteredError(err) {
-			return nil
-		}
 		return err
 	}
-
-	maj, err := strconv.Atoi(majorVersion)
+	min, err := strconv.Atoi(minorVersion)
 	if err != nil {
 		return err
 	}
-	min, err := strconv.Atoi(minorVersion)
+
+	gvk := fmt.Sprintf("%s %s", resource.APIVersion, resource.Kind)
+
+	// The database knows the APIs removed from the Kubernetes libraries
+	if _, ok := deprecations.Lookup(resource.APIVersion, resource.Kind); ok {
+		d := deprecations.Check(resource.APIVersion, resource.Kind, maj, min)
+		if d == nil {
+			return nil
+		}
+		return deprecatedAPIError{
+			Deprecated: gvk,
+			Message:    d.String(),
+		}
+	}
+
+	runtimeObject, err := resourceToRuntimeObject(resource)
 	if err != nil {
+		// do not error for non-kubernetes resources
+		if runtime.IsNotRegisteredError(err) {
+			return nil
+		}
 		return err
 	}
 
 	if !deprecation.IsDeprecated(runtimeObject, maj, min) {
 		return nil
 	}
-	gvk := fmt.Sprintf("%s %s", resource.APIVersion, resource.Kind)
 	return deprecatedAPIError{
 		Deprecated: gvk,
 		Message:    deprecation.WarningMessage(runtimeObject),
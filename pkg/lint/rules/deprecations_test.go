@@ -16,7 +16,11 @@ limitations under the License.
 
 package rules // import "helm.sh/helm/v3/pkg/lint/rules"
 
-import "testing"
+import (
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+)
 
 func TestValidateNoDeprecations(t *testing.T) {
 	deprecated := &K8sYamlStruct{
@@ -39,3 +43,26 @@ func TestValidateNoDeprecations(t *testing.T) {
 		t.Errorf("Expected a v1 Pod to not be deprecated")
 	}
 }
+
+func TestValidateNoDeprecationsRemovedAPIs(t *testing.T) {
+	// PodSecurityPolicy is no longer in the Kubernetes libraries, only in
+	// the deprecation database
+	psp := &K8sYamlStruct{
+		APIVersion: "policy/v1beta1",
+		Kind:       "PodSecurityPolicy",
+	}
+	kubeVersion, _ := chartutil.ParseKubeVersion("v1.25.0")
+	err := validateNoDeprecations(psp, kubeVersion)
+	if err == nil {
+		t.Fatal("Expected removed PodSecurityPolicy to be flagged")
+	}
+	expect := "policy/v1beta1 PodSecurityPolicy is unavailable in v1.25+"
+	if err.Error() != expect {
+		t.Errorf("Expected %q, got %q", expect, err)
+	}
+
+	kubeVersion, _ = chartutil.ParseKubeVersion("v1.20.0")
+	if err := validateNoDeprecations(psp, kubeVersion); err != nil {
+		t.Errorf("Expected PodSecurityPolicy to not be deprecated in v1.20: %s", err)
+	}
+}
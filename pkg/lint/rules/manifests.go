@@ -0,0 +1,147 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/kubectl/pkg/validation"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+// ManifestSchemas lints the rendered resources of the chart in the Linter
+// against their schemas with kube.BuiltinSchema, reporting unknown fields and
+// fields of the wrong types.
+//
+// The built-in resources are validated against the Kubernetes API types
+// compiled into Helm. The custom resources are validated against the JSON
+// schemas found in schemaDirs, laid out like the CRDs catalog of kubeconform:
+// <group>/<kind>_<version>.json, with the lower-cased kind. The resources
+// without a schema are not validated.
+func ManifestSchemas(linter *support.Linter, resources []Resource, schemaDirs []string) {
+	s := kube.BuiltinSchema(schemaDirs...)
+	for _, r := range resources {
+		linter.RunLinterRule(support.ErrorSev, r.Path, validateResource(s, r))
+	}
+}
+
+// validateResource validates the resource against its schema.
+func validateResource(s validation.Schema, r Resource) error {
+	if r.Object == nil || r.Object.GetAPIVersion() == "" || r.Object.GetKind() == "" {
+		// The resources without a type are reported by the Templates rule
+		return nil
+	}
+	err := s.ValidateBytes(r.Data)
+	if err == nil {
+		return nil
+	}
+	errs := []error{err}
+	var agg utilerrors.Aggregate
+	if errors.As(err, &agg) {
+		errs = agg.Errors()
+	}
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Error()
+	}
+	return errors.Errorf("%s %s %q does not match its schema: %s", r.Object.GetAPIVersion(), r.Object.GetKind(), r.Object.GetName(), strings.Join(msgs, "; "))
+}
+
+// Resource is a Kubernetes resource rendered by a template of a chart.
+type Resource struct {
+	// Path is the path to the template in the chart.
+	Path string
+	// Data is the resource, in JSON.
+	Data []byte
+	// Object is the parsed resource, nil if it is not a JSON object.
+	Object *unstructured.Unstructured
+}
+
+// RenderResources renders the templates of the chart in chartDir, and
+// returns the resources of the YAML templates, for the rules checking the
+// rendered resources. It returns no resources if the chart cannot be
+// rendered, the errors of rendering being reported by the Templates rule.
+func RenderResources(chartDir string, values map[string]interface{}, namespace string, kubeVersion *chartutil.KubeVersion) []Resource {
+	chart, err := loader.Load(chartDir)
+	if err != nil {
+		return nil
+	}
+
+	options := chartutil.ReleaseOptions{
+		Name:      "test-release",
+		Namespace: namespace,
+	}
+	caps := chartutil.DefaultCapabilities.Copy()
+	if kubeVersion != nil {
+		caps.KubeVersion = *kubeVersion
+	}
+	if err := chartutil.ProcessDependenciesWithMerge(chart, values); err != nil {
+		return nil
+	}
+	cvals, err := chartutil.CoalesceValues(chart, values)
+	if err != nil {
+		return nil
+	}
+	valuesToRender, err := chartutil.ToRenderValues(chart, cvals, options, caps)
+	if err != nil {
+		return nil
+	}
+	var e engine.Engine
+	e.LintMode = true
+	rendered, err := e.Render(chart, valuesToRender)
+	if err != nil {
+		return nil
+	}
+
+	var resources []Resource
+	for _, template := range chart.Templates {
+		if filepath.Ext(template.Name) != ".yaml" {
+			continue
+		}
+		content := rendered[path.Join(chart.Name(), template.Name)]
+		decoder := yaml.NewYAMLOrJSONDecoder(strings.NewReader(content), 4096)
+		for {
+			var raw runtime.RawExtension
+			if err := decoder.Decode(&raw); err != nil {
+				// The YAML errors are reported by the Templates rule
+				break
+			}
+			if len(raw.Raw) == 0 || string(raw.Raw) == "null" {
+				continue
+			}
+			r := Resource{Path: template.Name, Data: raw.Raw}
+			obj := &unstructured.Unstructured{}
+			if err := obj.UnmarshalJSON(raw.Raw); err == nil {
+				r.Object = obj
+			}
+			resources = append(resources, r)
+		}
+	}
+	return resources
+}
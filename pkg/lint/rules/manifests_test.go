@@ -0,0 +1,86 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+func TestManifestSchemas(t *testing.T) {
+	mychart := chart.Chart{
+		Metadata: &chart.Metadata{
+			APIVersion: "v2",
+			Name:       "schemas",
+			Version:    "0.1.0",
+		},
+		Templates: []*chart.File{
+			{
+				Name: "templates/deployment.yaml",
+				Data: []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: typo\nspec:\n  replica: 2\n  selector: {matchLabels: {app: typo}}\n"),
+			},
+			{
+				Name: "templates/pod.yaml",
+				Data: []byte("apiVersion: v1\nkind: Pod\nmetadata:\n  name: wrongtype\n  labels:\n    version: 1.2\nspec:\n  containers:\n  - name: app\n    image: app\n"),
+			},
+			{
+				Name: "templates/goodsecret.yaml",
+				Data: []byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: goodsecret\nstringData:\n  key: value\n"),
+			},
+			{
+				Name: "templates/widgets.yaml",
+				Data: []byte("apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: good\nspec:\n  size: 3\n---\napiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: bad\nspec:\n  size: large\n"),
+			},
+			{
+				Name: "templates/gadget.yaml",
+				Data: []byte("apiVersion: example.com/v1\nkind: Gadget\nmetadata:\n  name: noschema\nspec:\n  anything: goes\n"),
+			},
+		},
+	}
+	tmpdir := t.TempDir()
+	if err := chartutil.SaveDir(&mychart, tmpdir); err != nil {
+		t.Fatal(err)
+	}
+
+	linter := support.Linter{ChartDir: filepath.Join(tmpdir, mychart.Name())}
+	ManifestSchemas(&linter, RenderResources(linter.ChartDir, values, namespace, nil), []string{"testdata/kube-schemas"})
+
+	expected := map[string]string{
+		"templates/deployment.yaml": `apps/v1 Deployment "typo" does not match its schema: spec: unknown field "replica"`,
+		"templates/pod.yaml":        `v1 Pod "wrongtype" does not match its schema: metadata.labels.version: expected string, got number`,
+		"templates/widgets.yaml":    `example.com/v1 Widget "bad" does not match its schema: spec.size: Invalid type. Expected: integer, given: string`,
+	}
+	if len(linter.Messages) != len(expected) {
+		for i, msg := range linter.Messages {
+			t.Logf("Message %d: %s", i, msg)
+		}
+		t.Fatalf("Expected %d lint errors, got %d", len(expected), len(linter.Messages))
+	}
+	for _, msg := range linter.Messages {
+		if msg.Severity != support.ErrorSev {
+			t.Errorf("Expected an error, got %s", msg)
+		}
+		if want, ok := expected[msg.Path]; !ok || !strings.Contains(msg.Err.Error(), want) {
+			t.Errorf("Expected %q in %s, got %q", want, msg.Path, msg.Err)
+		}
+	}
+}
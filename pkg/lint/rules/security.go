@@ -0,0 +1,154 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+var (
+	// secretKey matches the keys of the ConfigMaps holding secrets, like
+	// "db-password" or "apiKey".
+	secretKey = regexp.MustCompile(`(?i)(password|passwd|secret|token|api[_-]?key|private[_-]?key|credentials?)`)
+	// privateKey matches the PEM private keys.
+	privateKey = regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)
+)
+
+// SecurityPrivileged lints the containers of the rendered workloads of the
+// chart in the Linter, reporting the privileged containers.
+func SecurityPrivileged(linter *support.Linter, resources []Resource) {
+	for _, w := range workloads(resources) {
+		for _, c := range allContainers(&w.podSpec) {
+			if sc := c.SecurityContext; sc != nil && sc.Privileged != nil && *sc.Privileged {
+				linter.RunLinterRule(support.WarningSev, w.path, errors.Errorf("%s is privileged", w.container(c)))
+			}
+		}
+	}
+}
+
+// SecurityHostAccess lints the rendered workloads of the chart in the Linter,
+// reporting the workloads sharing the namespaces of their hosts or mounting
+// their filesystems.
+func SecurityHostAccess(linter *support.Linter, resources []Resource) {
+	for _, w := range workloads(resources) {
+		for _, field := range []struct {
+			name    string
+			enabled bool
+		}{
+			{"hostNetwork", w.podSpec.HostNetwork},
+			{"hostPID", w.podSpec.HostPID},
+			{"hostIPC", w.podSpec.HostIPC},
+		} {
+			if field.enabled {
+				linter.RunLinterRule(support.WarningSev, w.path, errors.Errorf("%s %q uses %s", w.kind, w.name, field.name))
+			}
+		}
+		for _, v := range w.podSpec.Volumes {
+			if v.HostPath != nil {
+				linter.RunLinterRule(support.WarningSev, w.path, errors.Errorf("%s %q mounts the hostPath %q in volume %q", w.kind, w.name, v.HostPath.Path, v.Name))
+			}
+		}
+	}
+}
+
+// SecurityRBACWildcards lints the rendered Roles and ClusterRoles of the chart
+// in the Linter, reporting the rules granting all the verbs, resources or API
+// groups with a wildcard.
+func SecurityRBACWildcards(linter *support.Linter, resources []Resource) {
+	for _, r := range resources {
+		obj := r.Object
+		if obj == nil {
+			continue
+		}
+		gvk := obj.GroupVersionKind()
+		if gvk.Group != rbacv1.GroupName || (gvk.Kind != "Role" && gvk.Kind != "ClusterRole") {
+			continue
+		}
+		var role rbacv1.ClusterRole
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &role); err != nil {
+			continue
+		}
+		for i, rule := range role.Rules {
+			for _, field := range []struct {
+				name  string
+				items []string
+			}{
+				{"verbs", rule.Verbs},
+				{"resources", rule.Resources},
+				{"apiGroups", rule.APIGroups},
+				{"nonResourceURLs", rule.NonResourceURLs},
+			} {
+				if hasWildcard(field.items) {
+					linter.RunLinterRule(support.WarningSev, r.Path, errors.Errorf("%s %q grants all the %s with a wildcard in rule %d", gvk.Kind, obj.GetName(), field.name, i+1))
+				}
+			}
+		}
+	}
+}
+
+// SecurityConfigMapSecrets lints the rendered ConfigMaps of the chart in the
+// Linter, reporting the keys looking like secrets, like passwords and private
+// keys, which belong in Secrets.
+func SecurityConfigMapSecrets(linter *support.Linter, resources []Resource) {
+	for _, r := range resources {
+		obj := r.Object
+		if obj == nil {
+			continue
+		}
+		gvk := obj.GroupVersionKind()
+		if gvk.Group != "" || gvk.Kind != "ConfigMap" {
+			continue
+		}
+		var cm corev1.ConfigMap
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &cm); err != nil {
+			continue
+		}
+		keys := make([]string, 0, len(cm.Data))
+		for key := range cm.Data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			value := strings.TrimSpace(cm.Data[key])
+			switch {
+			case privateKey.MatchString(value):
+				linter.RunLinterRule(support.WarningSev, r.Path, errors.Errorf("ConfigMap %q holds a private key in %q, use a Secret", cm.Name, key))
+			case value != "" && secretKey.MatchString(key):
+				linter.RunLinterRule(support.WarningSev, r.Path, errors.Errorf("ConfigMap %q holds a secret in %q, use a Secret", cm.Name, key))
+			}
+		}
+	}
+}
+
+// hasWildcard returns whether the items of an RBAC rule have a wildcard.
+func hasWildcard(items []string) bool {
+	for _, item := range items {
+		if item == rbacv1.ResourceAll {
+			return true
+		}
+	}
+	return false
+}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/pkg/errors"
 
+	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/chartutil"
 	"helm.sh/helm/v3/pkg/lint/support"
 )
@@ -51,6 +52,31 @@ func ValuesWithOverrides(linter *support.Linter, values map[string]interface{})
 	linter.RunLinterRule(support.ErrorSev, file, validateValuesFile(vf, values))
 }
 
+// ValuesSchemas tests the values of the chart, coalesced with the supplied
+// values, against the schemas of the chart and of its enabled subcharts,
+// without rendering the templates.
+func ValuesSchemas(linter *support.Linter, values map[string]interface{}) {
+	linter.RunLinterRule(support.ErrorSev, "values.yaml", validateValuesSchemas(linter.ChartDir, values))
+}
+
+func validateValuesSchemas(chartDir string, values map[string]interface{}) error {
+	chart, err := loader.Load(chartDir)
+	if err != nil {
+		return errors.Wrap(err, "unable to load chart")
+	}
+	if err := chartutil.ProcessDependenciesWithMerge(chart, values); err != nil {
+		return err
+	}
+	cvals, err := chartutil.CoalesceValues(chart, values)
+	if err != nil {
+		return err
+	}
+	if err := chartutil.ValidateAgainstSchema(chart, cvals); err != nil {
+		return errors.Errorf("values don't meet the specifications of the schema(s) in the following chart(s):\n%s", err)
+	}
+	return nil
+}
+
 func validateValuesFileExistence(valuesPath string) error {
 	_, err := os.Stat(valuesPath)
 	if err != nil {
@@ -24,6 +24,8 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"helm.sh/helm/v3/internal/test/ensure"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
 )
 
 var nonExistingValuesFilePath = filepath.Join("/fake/dir", "values.yaml")
@@ -159,6 +161,64 @@ func TestValidateValuesFile(t *testing.T) {
 	}
 }
 
+func TestValidateValuesSchemas(t *testing.T) {
+	subchart := &chart.Chart{
+		Metadata: &chart.Metadata{APIVersion: "v2", Name: "credentials", Version: "0.1.0"},
+		Schema:   []byte(`{"type": "object", "required": ["password"], "properties": {"password": {"type": "string"}}}`),
+	}
+	mychart := &chart.Chart{
+		Metadata: &chart.Metadata{
+			APIVersion: "v2",
+			Name:       "app",
+			Version:    "0.1.0",
+			Dependencies: []*chart.Dependency{
+				{Name: "credentials", Version: "0.1.0", Condition: "credentials.enabled"},
+			},
+		},
+		Values: map[string]interface{}{"credentials": map[string]interface{}{"enabled": true}},
+	}
+	mychart.AddDependency(subchart)
+	tmpdir := t.TempDir()
+	if err := chartutil.SaveDir(mychart, tmpdir); err != nil {
+		t.Fatal(err)
+	}
+	chartDir := filepath.Join(tmpdir, mychart.Name())
+
+	tests := []struct {
+		name         string
+		values       map[string]interface{}
+		errorMessage string
+	}{
+		{
+			name:         "subchart value missing",
+			values:       map[string]interface{}{},
+			errorMessage: "credentials:\n- (root): password is required",
+		},
+		{
+			name:   "subchart value supplied",
+			values: map[string]interface{}{"credentials": map[string]interface{}{"password": "swordfish"}},
+		},
+		{
+			name:   "subchart disabled",
+			values: map[string]interface{}{"credentials": map[string]interface{}{"enabled": false}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateValuesSchemas(chartDir, tt.values)
+			switch {
+			case err != nil && tt.errorMessage == "":
+				t.Errorf("Failed validation with %s", err)
+			case err == nil && tt.errorMessage != "":
+				t.Error("expected values to fail validation")
+			case err != nil && tt.errorMessage != "":
+				assert.Contains(t, err.Error(), tt.errorMessage, "Failed with unexpected error")
+			}
+		})
+	}
+}
+
 func createTestingSchema(t *testing.T, dir string) string {
 	t.Helper()
 	schemafile := filepath.Join(dir, "values.schema.json")
@@ -0,0 +1,187 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+// podSpecPaths are the paths to the pod specs of the workloads, by kind.
+var podSpecPaths = map[string][]string{
+	"Pod":                   {"spec"},
+	"Deployment":            {"spec", "template", "spec"},
+	"StatefulSet":           {"spec", "template", "spec"},
+	"DaemonSet":             {"spec", "template", "spec"},
+	"ReplicaSet":            {"spec", "template", "spec"},
+	"ReplicationController": {"spec", "template", "spec"},
+	"Job":                   {"spec", "template", "spec"},
+	"CronJob":               {"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// workloadGroups are the API groups of the workloads.
+var workloadGroups = map[string]bool{"": true, "apps": true, "batch": true, "extensions": true}
+
+// workload is a Kubernetes resource running pods.
+type workload struct {
+	path    string
+	kind    string
+	name    string
+	podSpec corev1.PodSpec
+}
+
+// container returns the description of the container c of the workload.
+func (w *workload) container(c *corev1.Container) string {
+	return fmt.Sprintf("container %q of %s %q", c.Name, w.kind, w.name)
+}
+
+// WorkloadResources lints the containers of the rendered workloads of the
+// chart in the Linter, reporting the containers without resource requests or
+// limits.
+func WorkloadResources(linter *support.Linter, resources []Resource) {
+	for _, w := range workloads(resources) {
+		for _, c := range allContainers(&w.podSpec) {
+			var missing []string
+			if len(c.Resources.Requests) == 0 {
+				missing = append(missing, "requests")
+			}
+			if len(c.Resources.Limits) == 0 {
+				missing = append(missing, "limits")
+			}
+			if len(missing) > 0 {
+				linter.RunLinterRule(support.WarningSev, w.path, errors.Errorf("%s has no resource %s", w.container(c), strings.Join(missing, " and ")))
+			}
+		}
+	}
+}
+
+// WorkloadImageTags lints the containers of the rendered workloads of the
+// chart in the Linter, reporting the images without a tag or digest, or with
+// the latest tag.
+func WorkloadImageTags(linter *support.Linter, resources []Resource) {
+	for _, w := range workloads(resources) {
+		for _, c := range allContainers(&w.podSpec) {
+			linter.RunLinterRule(support.WarningSev, w.path, validateImageTag(w, c))
+		}
+	}
+}
+
+// WorkloadProbes lints the containers of the rendered workloads of the chart
+// in the Linter, reporting the containers without liveness or readiness
+// probes. The containers of the jobs, which run to completion, are not
+// reported.
+func WorkloadProbes(linter *support.Linter, resources []Resource) {
+	for _, w := range workloads(resources) {
+		if w.kind == "Job" || w.kind == "CronJob" {
+			continue
+		}
+		for i := range w.podSpec.Containers {
+			c := &w.podSpec.Containers[i]
+			var missing []string
+			if c.LivenessProbe == nil {
+				missing = append(missing, "liveness")
+			}
+			if c.ReadinessProbe == nil {
+				missing = append(missing, "readiness")
+			}
+			if len(missing) > 0 {
+				linter.RunLinterRule(support.WarningSev, w.path, errors.Errorf("%s has no %s probe", w.container(c), strings.Join(missing, " and ")))
+			}
+		}
+	}
+}
+
+// WorkloadSecurityContexts lints the containers of the rendered workloads of
+// the chart in the Linter, reporting the containers without a security
+// context, neither their own nor the security context of their pod.
+func WorkloadSecurityContexts(linter *support.Linter, resources []Resource) {
+	for _, w := range workloads(resources) {
+		if w.podSpec.SecurityContext != nil {
+			continue
+		}
+		for _, c := range allContainers(&w.podSpec) {
+			if c.SecurityContext == nil {
+				linter.RunLinterRule(support.WarningSev, w.path, errors.Errorf("%s has no securityContext", w.container(c)))
+			}
+		}
+	}
+}
+
+// validateImageTag checks the image of the container has a tag other than
+// latest, or a digest.
+func validateImageTag(w *workload, c *corev1.Container) error {
+	image := c.Image
+	if strings.Contains(image, "@") {
+		return nil
+	}
+	// The tag follows the last colon after the registry, which may have a port
+	name := image[strings.LastIndex(image, "/")+1:]
+	i := strings.LastIndex(name, ":")
+	if i < 0 {
+		return errors.Errorf("%s uses the untagged image %q", w.container(c), image)
+	}
+	if name[i+1:] == "latest" {
+		return errors.Errorf("%s uses the image %q with the latest tag", w.container(c), image)
+	}
+	return nil
+}
+
+// allContainers returns the init containers and the containers of the pod
+// spec.
+func allContainers(spec *corev1.PodSpec) []*corev1.Container {
+	containers := make([]*corev1.Container, 0, len(spec.InitContainers)+len(spec.Containers))
+	for i := range spec.InitContainers {
+		containers = append(containers, &spec.InitContainers[i])
+	}
+	for i := range spec.Containers {
+		containers = append(containers, &spec.Containers[i])
+	}
+	return containers
+}
+
+// workloads returns the workloads of the rendered resources. The workloads
+// with invalid pod specs are left to the ManifestSchemas rule.
+func workloads(resources []Resource) []*workload {
+	var result []*workload
+	for _, r := range resources {
+		if r.Object == nil {
+			continue
+		}
+		gvk := r.Object.GroupVersionKind()
+		specPath, ok := podSpecPaths[gvk.Kind]
+		if !ok || !workloadGroups[gvk.Group] {
+			continue
+		}
+		spec, found, err := unstructured.NestedMap(r.Object.Object, specPath...)
+		if !found || err != nil {
+			continue
+		}
+		w := &workload{path: r.Path, kind: gvk.Kind, name: r.Object.GetName()}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(spec, &w.podSpec); err != nil {
+			continue
+		}
+		result = append(result, w)
+	}
+	return result
+}
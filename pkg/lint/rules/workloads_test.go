@@ -0,0 +1,187 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rules
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/lint/support"
+)
+
+const hardenedDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: hardened
+spec:
+  selector: {matchLabels: {app: hardened}}
+  template:
+    metadata:
+      labels: {app: hardened}
+    spec:
+      securityContext: {runAsNonRoot: true}
+      containers:
+      - name: app
+        image: registry.example.com:5000/app:1.2.3
+        resources:
+          requests: {cpu: 100m}
+          limits: {memory: 128Mi}
+        livenessProbe: {tcpSocket: {port: 80}}
+        readinessProbe: {tcpSocket: {port: 80}}
+`
+
+const sloppyCronJob = `apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: sloppy
+spec:
+  schedule: "@daily"
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          restartPolicy: Never
+          initContainers:
+          - name: init
+            image: busybox
+          containers:
+          - name: job
+            image: app:latest
+`
+
+const sloppyPod = `apiVersion: v1
+kind: Pod
+metadata:
+  name: sloppy
+spec:
+  containers:
+  - name: app
+    image: app@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef
+    securityContext: {readOnlyRootFilesystem: true}
+`
+
+func lintWorkloads(t *testing.T, rule func(*support.Linter, []Resource)) map[string][]string {
+	t.Helper()
+	mychart := chart.Chart{
+		Metadata: &chart.Metadata{
+			APIVersion: "v2",
+			Name:       "workloads",
+			Version:    "0.1.0",
+		},
+		Templates: []*chart.File{
+			{Name: "templates/deployment.yaml", Data: []byte(hardenedDeployment)},
+			{Name: "templates/cronjob.yaml", Data: []byte(sloppyCronJob)},
+			{Name: "templates/pod.yaml", Data: []byte(sloppyPod)},
+		},
+	}
+	tmpdir := t.TempDir()
+	if err := chartutil.SaveDir(&mychart, tmpdir); err != nil {
+		t.Fatal(err)
+	}
+
+	linter := support.Linter{ChartDir: filepath.Join(tmpdir, mychart.Name())}
+	rule(&linter, RenderResources(linter.ChartDir, values, namespace, nil))
+	msgs := make(map[string][]string)
+	for _, msg := range linter.Messages {
+		if msg.Severity != support.WarningSev {
+			t.Errorf("Expected a warning, got %s", msg)
+		}
+		msgs[msg.Path] = append(msgs[msg.Path], msg.Err.Error())
+	}
+	return msgs
+}
+
+func expectWorkloadMessages(t *testing.T, msgs map[string][]string, expected map[string][]string) {
+	t.Helper()
+	if len(msgs) != len(expected) {
+		t.Errorf("Expected messages for %d templates, got %v", len(expected), msgs)
+	}
+	for path, want := range expected {
+		got := msgs[path]
+		if len(got) != len(want) {
+			t.Errorf("Expected %d messages for %s, got %v", len(want), path, got)
+			continue
+		}
+		for i := range want {
+			if !strings.Contains(got[i], want[i]) {
+				t.Errorf("Expected %q in %s, got %q", want[i], path, got[i])
+			}
+		}
+	}
+}
+
+func TestWorkloadResources(t *testing.T) {
+	expectWorkloadMessages(t, lintWorkloads(t, WorkloadResources), map[string][]string{
+		"templates/cronjob.yaml": {
+			`container "init" of CronJob "sloppy" has no resource requests and limits`,
+			`container "job" of CronJob "sloppy" has no resource requests and limits`,
+		},
+		"templates/pod.yaml": {
+			`container "app" of Pod "sloppy" has no resource requests and limits`,
+		},
+	})
+}
+
+func TestWorkloadImageTags(t *testing.T) {
+	expectWorkloadMessages(t, lintWorkloads(t, WorkloadImageTags), map[string][]string{
+		"templates/cronjob.yaml": {
+			`container "init" of CronJob "sloppy" uses the untagged image "busybox"`,
+			`container "job" of CronJob "sloppy" uses the image "app:latest" with the latest tag`,
+		},
+	})
+}
+
+func TestWorkloadProbes(t *testing.T) {
+	expectWorkloadMessages(t, lintWorkloads(t, WorkloadProbes), map[string][]string{
+		"templates/pod.yaml": {
+			`container "app" of Pod "sloppy" has no liveness and readiness probe`,
+		},
+	})
+}
+
+func TestWorkloadSecurityContexts(t *testing.T) {
+	expectWorkloadMessages(t, lintWorkloads(t, WorkloadSecurityContexts), map[string][]string{
+		"templates/cronjob.yaml": {
+			`container "init" of CronJob "sloppy" has no securityContext`,
+			`container "job" of CronJob "sloppy" has no securityContext`,
+		},
+	})
+}
+
+func TestValidateImageTag(t *testing.T) {
+	w := &workload{kind: "Pod", name: "test"}
+	for image, valid := range map[string]bool{
+		"nginx":                         false,
+		"nginx:latest":                  false,
+		"nginx:1.25":                    true,
+		"localhost:5000/nginx":          false,
+		"localhost:5000/nginx:1.25":     true,
+		"nginx@sha256:0123456789abcdef": true,
+	} {
+		err := validateImageTag(w, &corev1.Container{Name: "c", Image: image})
+		if valid && err != nil {
+			t.Errorf("Expected image %q to be valid, got %s", image, err)
+		} else if !valid && err == nil {
+			t.Errorf("Expected image %q to be invalid", image)
+		}
+	}
+}
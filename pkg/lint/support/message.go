@@ -16,7 +16,12 @@ limitations under the License.
 
 package support
 
-import "fmt"
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
 
 // Severity indicates the severity of a Message.
 const (
@@ -33,12 +38,32 @@ const (
 // sev matches the *Sev states.
 var sev = []string{"UNKNOWN", "INFO", "WARNING", "ERROR"}
 
+// SeverityName returns the name of a severity, like "WARNING".
+func SeverityName(severity int) string {
+	if severity < 0 || severity >= len(sev) {
+		return sev[UnknownSev]
+	}
+	return sev[severity]
+}
+
+// ParseSeverity parses the name of a severity, like "warning".
+func ParseSeverity(name string) (int, error) {
+	for i, s := range sev {
+		if strings.EqualFold(name, s) {
+			return i, nil
+		}
+	}
+	return UnknownSev, fmt.Errorf("unknown severity %q", name)
+}
+
 // Linter encapsulates a linting run of a particular chart.
 type Linter struct {
 	Messages []Message
 	// The highest severity of all the failing lint rules
 	HighestSeverity int
 	ChartDir        string
+	// Rule is the ID of the running lint rule, recorded in its messages.
+	Rule string
 }
 
 // Message describes an error encountered while linting.
@@ -47,6 +72,10 @@ type Message struct {
 	Severity int
 	Path     string
 	Err      error
+	// Rule is the ID of the lint rule of the message, if any.
+	Rule string
+	// Line is the line of the error in the file of Path, 0 if unknown.
+	Line int
 }
 
 func (m Message) Error() string {
@@ -55,7 +84,39 @@ func (m Message) Error() string {
 
 // NewMessage creates a new Message struct
 func NewMessage(severity int, path string, err error) Message {
-	return Message{Severity: severity, Path: path, Err: err}
+	return Message{Severity: severity, Path: path, Err: err, Line: errorLine(path, err)}
+}
+
+var (
+	// yamlLine matches the line of the YAML parsing errors.
+	yamlLine = regexp.MustCompile(`yaml: line (\d+):`)
+	// templateLine matches the position of the template errors, like
+	// "mychart/templates/pod.yaml:12:4".
+	templateLine = regexp.MustCompile(`([^\s():]+):(\d+)(?::\d+)?`)
+)
+
+// errorLine returns the line of err in the file path, 0 if unknown.
+//
+// The YAML parsing errors of the templates are not used, their lines being
+// the lines of the rendered templates.
+func errorLine(path string, err error) int {
+	if err == nil || path == "" || strings.HasSuffix(path, "/") {
+		return 0
+	}
+	if !strings.HasPrefix(path, "templates/") {
+		if m := yamlLine.FindStringSubmatch(err.Error()); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			return line
+		}
+		return 0
+	}
+	for _, m := range templateLine.FindAllStringSubmatch(err.Error(), -1) {
+		if m[1] == path || strings.HasSuffix(m[1], "/"+path) {
+			line, _ := strconv.Atoi(m[2])
+			return line
+		}
+	}
+	return 0
 }
 
 // RunLinterRule returns true if the validation passed
@@ -66,7 +127,9 @@ func (l *Linter) RunLinterRule(severity int, path string, err error) bool {
 	}
 
 	if err != nil {
-		l.Messages = append(l.Messages, NewMessage(severity, path, err))
+		msg := NewMessage(severity, path, err)
+		msg.Rule = l.Rule
+		l.Messages = append(l.Messages, msg)
 
 		if severity > l.HighestSeverity {
 			l.HighestSeverity = severity
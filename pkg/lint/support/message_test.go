@@ -63,18 +63,52 @@ func TestRunLinterRule(t *testing.T) {
 }
 
 func TestMessage(t *testing.T) {
-	m := Message{ErrorSev, "Chart.yaml", errors.New("Foo")}
+	m := Message{Severity: ErrorSev, Path: "Chart.yaml", Err: errors.New("Foo")}
 	if m.Error() != "[ERROR] Chart.yaml: Foo" {
 		t.Errorf("Unexpected output: %s", m.Error())
 	}
 
-	m = Message{WarningSev, "templates/", errors.New("Bar")}
+	m = Message{Severity: WarningSev, Path: "templates/", Err: errors.New("Bar")}
 	if m.Error() != "[WARNING] templates/: Bar" {
 		t.Errorf("Unexpected output: %s", m.Error())
 	}
 
-	m = Message{InfoSev, "templates/rc.yaml", errors.New("FooBar")}
+	m = Message{Severity: InfoSev, Path: "templates/rc.yaml", Err: errors.New("FooBar")}
 	if m.Error() != "[INFO] templates/rc.yaml: FooBar" {
 		t.Errorf("Unexpected output: %s", m.Error())
 	}
 }
+
+func TestParseSeverity(t *testing.T) {
+	for name, want := range map[string]int{"info": InfoSev, "WARNING": WarningSev, "Error": ErrorSev} {
+		got, err := ParseSeverity(name)
+		if err != nil {
+			t.Fatalf("ParseSeverity(%q): %s", name, err)
+		}
+		if got != want {
+			t.Errorf("ParseSeverity(%q) = %d, want %d", name, got, want)
+		}
+	}
+	if _, err := ParseSeverity("fatal"); err == nil {
+		t.Error("expected an error for an unknown severity")
+	}
+}
+
+func TestMessageLine(t *testing.T) {
+	for _, tt := range []struct {
+		path string
+		err  string
+		line int
+	}{
+		{"Chart.yaml", "error converting YAML to JSON: yaml: line 6: did not find expected '-' indicator", 6},
+		{"templates/pod.yaml", `template: mychart/templates/pod.yaml:12:4: executing "mychart/templates/pod.yaml" at <.Values.foo>: nil pointer`, 12},
+		{"templates/pod.yaml", `parse error at (mychart/templates/_helpers.tpl:3): unexpected "}"`, 0},
+		{"templates/pod.yaml", "unable to parse YAML: error converting YAML to JSON: yaml: line 3: mapping values are not allowed", 0},
+		{"templates/", "template: mychart/templates/pod.yaml:12:4: error", 0},
+		{"values.yaml", "unable to parse values", 0},
+	} {
+		if m := NewMessage(ErrorSev, tt.path, errors.New(tt.err)); m.Line != tt.line {
+			t.Errorf("the line of %q in %s should be %d, we got %d", tt.err, tt.path, tt.line, m.Line)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+#!/bin/sh
+
+case "$1" in
+  icon)
+    test -f "$2/Chart.yaml" || exit 1
+    echo '{"path": "Chart.yaml", "line": 2, "message": "the chart has no icon"}'
+    echo '{"severity": "info", "path": "Chart.yaml", "message": "the chart was checked"}'
+    ;;
+  *)
+    exit 1
+    ;;
+esac
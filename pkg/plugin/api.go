@@ -0,0 +1,85 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin // import "helm.sh/helm/v3/pkg/plugin"
+
+import (
+	"os"
+
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/helmpath"
+	"helm.sh/helm/v3/pkg/plugin/sdk"
+)
+
+// APIVersion returns the version of the plugin API negotiated with the plugin,
+// or an empty string if the plugin declares none.
+func (p *Plugin) APIVersion() string {
+	v, err := sdk.Negotiate(p.Metadata.APIVersions)
+	if err != nil {
+		return ""
+	}
+	return v
+}
+
+// NewRequest returns the request of the plugin API of a command of the plugin
+// of the kind, or nil if the plugin declares no version of the API.
+func (p *Plugin) NewRequest(settings *cli.EnvSettings, kind string) *sdk.Request {
+	v := p.APIVersion()
+	if v == "" {
+		return nil
+	}
+	return NewRequest(settings, v, kind, sdk.Plugin{
+		Name:    p.Metadata.Name,
+		Version: p.Metadata.Version,
+		Dir:     p.Dir,
+	})
+}
+
+// NewRequest returns the request of the version of the plugin API of a
+// command of the kind, with the settings.
+func NewRequest(settings *cli.EnvSettings, apiVersion, kind string, plugin sdk.Plugin) *sdk.Request {
+	return &sdk.Request{
+		APIVersion: apiVersion,
+		Kind:       kind,
+		Plugin:     plugin,
+		Settings: sdk.Settings{
+			Bin:              os.Args[0],
+			Debug:            settings.Debug,
+			Namespace:        settings.Namespace(),
+			KubeConfig:       settings.KubeConfig,
+			KubeContext:      settings.KubeContext,
+			CacheHome:        helmpath.CachePath(""),
+			ConfigHome:       helmpath.ConfigPath(""),
+			DataHome:         helmpath.DataPath(""),
+			PluginsDirectory: settings.PluginsDirectory,
+			RegistryConfig:   settings.RegistryConfig,
+			RepositoryConfig: settings.RepositoryConfig,
+			RepositoryCache:  settings.RepositoryCache,
+		},
+	}
+}
+
+// RequestEnv appends the environment variables passing the request, if any,
+// to env.
+func RequestEnv(env []string, req *sdk.Request) ([]string, error) {
+	if req == nil {
+		return env, nil
+	}
+	vars, err := req.Env()
+	if err != nil {
+		return nil, err
+	}
+	return append(env, vars...), nil
+}
@@ -0,0 +1,236 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package credentials provides the credentials of repositories and registries
+// with the credential providers of the plugins.
+package credentials // import "helm.sh/helm/v3/pkg/plugin/credentials"
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/helmpath"
+	"helm.sh/helm/v3/pkg/plugin"
+	"helm.sh/helm/v3/pkg/plugin/sdk"
+)
+
+// ExpiryMargin is how long before they expire the cached credentials are
+// renewed.
+const ExpiryMargin = time.Minute
+
+// provider is the credential provider of a plugin.
+type provider struct {
+	plugin *plugin.Plugin
+	plugin.CredentialProvider
+}
+
+// matches returns whether the provider has credentials for host, matching
+// the hostname of host too if its hosts have no port.
+func (p *provider) matches(host string) bool {
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	for _, pattern := range p.Hosts {
+		if ok, _ := path.Match(pattern, host); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, hostname); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Providers provides the credentials of hosts with the credential providers
+// of plugins. The first provider matching a host provides its credentials.
+//
+// The credentials are cached for the life of the Providers, and the
+// credentials with an expiry on disk too, until ExpiryMargin before they
+// expire, so that the providers do not run for every request.
+type Providers struct {
+	// CacheDir is the directory of the credentials cached on disk.
+	CacheDir string
+
+	settings  *cli.EnvSettings
+	providers []provider
+	now       func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]*sdk.CredentialsResponse
+}
+
+// Load returns the credential providers of the plugins of settings, or nil
+// if no plugin has one.
+func Load(settings *cli.EnvSettings) (*Providers, error) {
+	plugins, err := plugin.FindPlugins(settings.PluginsDirectory)
+	if err != nil {
+		return nil, err
+	}
+	return New(settings, plugins), nil
+}
+
+// New returns the credential providers of plugins, or nil if no plugin has
+// one.
+func New(settings *cli.EnvSettings, plugins []*plugin.Plugin) *Providers {
+	var providers []provider
+	for _, plug := range plugins {
+		for _, c := range plug.Metadata.CredentialProviders {
+			providers = append(providers, provider{plugin: plug, CredentialProvider: c})
+		}
+	}
+	if len(providers) == 0 {
+		return nil
+	}
+	return &Providers{
+		CacheDir:  helmpath.CachePath("credentials"),
+		settings:  settings,
+		providers: providers,
+		now:       time.Now,
+		cache:     make(map[string]*sdk.CredentialsResponse),
+	}
+}
+
+// Credential returns the username and the password of host, both empty if
+// no provider has credentials for it. An empty username with a password is a
+// token.
+func (p *Providers) Credential(host string) (string, string, error) {
+	for i := range p.providers {
+		if !p.providers[i].matches(host) {
+			continue
+		}
+		resp, err := p.credential(&p.providers[i], host)
+		if err != nil {
+			return "", "", err
+		}
+		return resp.Username, resp.Password, nil
+	}
+	return "", "", nil
+}
+
+// credential returns the cached credentials of host provided by pr, running
+// pr if they are missing or about to expire.
+func (p *Providers) credential(pr *provider, host string) (*sdk.CredentialsResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := pr.plugin.Metadata.Name + "\x00" + host
+	if resp, ok := p.cache[key]; ok && p.valid(resp) {
+		return resp, nil
+	}
+	file := filepath.Join(p.CacheDir, cacheKey(key)+".json")
+	if resp, err := readCache(file); err == nil && resp.ExpiresAt != nil && p.valid(resp) {
+		p.cache[key] = resp
+		return resp, nil
+	}
+
+	resp, err := p.run(pr, host)
+	if err != nil {
+		return nil, errors.Wrapf(err, "credential provider of plugin %q for %s", pr.plugin.Metadata.Name, host)
+	}
+	p.cache[key] = resp
+	if resp.ExpiresAt != nil {
+		if err := writeCache(file, resp); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// valid returns whether the cached credentials resp are not about to
+// expire.
+func (p *Providers) valid(resp *sdk.CredentialsResponse) bool {
+	return resp.ExpiresAt == nil || p.now().Add(ExpiryMargin).Before(*resp.ExpiresAt)
+}
+
+// run runs the command of pr with the request of the credentials of host,
+// and reads the response it writes.
+func (p *Providers) run(pr *provider, host string) (*sdk.CredentialsResponse, error) {
+	req := pr.plugin.NewRequest(p.settings, sdk.KindCredentialProvider)
+	if req == nil {
+		return nil, errors.New("the plugin declares no supported version of the plugin API")
+	}
+	req.Credentials = &sdk.CredentialsRequest{Host: host}
+
+	plugin.SetupPluginEnv(p.settings, pr.plugin.Metadata.Name, pr.plugin.Dir)
+	env, err := plugin.RequestEnv(os.Environ(), req)
+	if err != nil {
+		return nil, err
+	}
+	commands := strings.Split(os.ExpandEnv(pr.Command), " ")
+	prog := exec.Command(commands[0], commands[1:]...)
+	prog.Env = env
+	cleanup, err := plugin.Sandbox(prog, pr.plugin.Metadata.Name, pr.plugin.Metadata.Capabilities)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	buf := bytes.NewBuffer(nil)
+	prog.Stdout = buf
+	prog.Stderr = os.Stderr
+	if err := prog.Run(); err != nil {
+		return nil, err
+	}
+
+	resp := &sdk.CredentialsResponse{}
+	if err := json.Unmarshal(buf.Bytes(), resp); err != nil {
+		return nil, errors.Wrap(err, "invalid response")
+	}
+	return resp, nil
+}
+
+// cacheKey returns the name of the cache file of key, which is file system
+// safe and does not disclose the host.
+func cacheKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func readCache(file string) (*sdk.CredentialsResponse, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	resp := &sdk.CredentialsResponse{}
+	if err := json.Unmarshal(data, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// writeCache writes the credentials resp to file, readable by the user only.
+func writeCache(file string, resp *sdk.CredentialsResponse) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0600)
+}
@@ -0,0 +1,156 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentials // import "helm.sh/helm/v3/pkg/plugin/credentials"
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/plugin"
+	"helm.sh/helm/v3/pkg/plugin/sdk"
+)
+
+// testProvider writes the credentials of its request, counting its runs in
+// the file "runs" of the plugin directory.
+const testProvider = `#!/bin/sh
+echo run >> "$HELM_PLUGIN_DIR/runs"
+case "$HELM_PLUGIN_REQUEST" in
+*'"host":"registry.example.com:5000"'*) echo '{"password": "token", "expiresAt": "'$EXPIRES_AT'"}' ;;
+*'"host":"charts.example.com"'*) echo '{"username": "helm", "password": "pass"}' ;;
+*) exit 1 ;;
+esac
+`
+
+func newTestProviders(t *testing.T) (*Providers, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the test credential provider is a shell script")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "provider.sh"), []byte(testProvider), 0755); err != nil {
+		t.Fatal(err)
+	}
+	plug := &plugin.Plugin{
+		Dir: dir,
+		Metadata: &plugin.Metadata{
+			Name:        "cloud",
+			APIVersions: []string{sdk.APIVersionV1},
+			CredentialProviders: []plugin.CredentialProvider{{
+				Hosts:   []string{"*.example.com", "failing.example.org"},
+				Command: "$HELM_PLUGIN_DIR/provider.sh",
+			}},
+		},
+	}
+	p := New(cli.New(), []*plugin.Plugin{plug})
+	p.CacheDir = filepath.Join(dir, "cache")
+	return p, dir
+}
+
+func runs(t *testing.T, dir string) int {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, "runs"))
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	return strings.Count(string(data), "run")
+}
+
+func TestNew(t *testing.T) {
+	plug := &plugin.Plugin{Metadata: &plugin.Metadata{Name: "echo"}}
+	if p := New(cli.New(), []*plugin.Plugin{plug}); p != nil {
+		t.Errorf("expected no providers, got %+v", p)
+	}
+}
+
+func TestCredential(t *testing.T) {
+	p, dir := newTestProviders(t)
+	now := time.Now()
+	p.now = func() time.Time { return now }
+	t.Setenv("EXPIRES_AT", now.Add(time.Hour).UTC().Format(time.RFC3339))
+
+	for _, tt := range []struct {
+		host               string
+		username, password string
+		wantErr            bool
+	}{
+		{host: "registry.example.com:5000", password: "token"},
+		{host: "charts.example.com", username: "helm", password: "pass"},
+		{host: "charts.example.org"},
+		{host: "failing.example.org", wantErr: true},
+	} {
+		username, password, err := p.Credential(tt.host)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.host)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if username != tt.username || password != tt.password {
+			t.Errorf("%s: expected %q %q, got %q %q", tt.host, tt.username, tt.password, username, password)
+		}
+	}
+	if n := runs(t, dir); n != 3 {
+		t.Fatalf("expected 3 runs, got %d", n)
+	}
+
+	// The credentials are cached
+	for _, host := range []string{"registry.example.com:5000", "charts.example.com"} {
+		if _, _, err := p.Credential(host); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := runs(t, dir); n != 3 {
+		t.Errorf("expected the cached credentials, got %d runs", n)
+	}
+
+	// The credentials with an expiry are cached on disk
+	other := New(cli.New(), []*plugin.Plugin{p.providers[0].plugin})
+	other.CacheDir = p.CacheDir
+	other.now = p.now
+	if _, password, err := other.Credential("registry.example.com:5000"); err != nil || password != "token" {
+		t.Fatalf("expected the token, got %q, %v", password, err)
+	}
+	if n := runs(t, dir); n != 3 {
+		t.Errorf("expected the credentials cached on disk, got %d runs", n)
+	}
+	files, err := os.ReadDir(p.CacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected one cached credential, got %d", len(files))
+	}
+	if fi, err := files[0].Info(); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("expected the cached credentials to be private, got %v, %v", fi.Mode(), err)
+	}
+
+	// The credentials are renewed before they expire
+	now = now.Add(time.Hour - ExpiryMargin/2)
+	if _, _, err := p.Credential("registry.example.com:5000"); err != nil {
+		t.Fatal(err)
+	}
+	if n := runs(t, dir); n != 4 {
+		t.Errorf("expected the credentials to be renewed, got %d runs", n)
+	}
+}
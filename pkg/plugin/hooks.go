@@ -25,5 +25,24 @@ const (
 	Update = "update"
 )
 
+// Types of lifecycle hooks, run on the events of the lifecycle of the
+// releases, around the operations of Helm. Unlike the hooks of the charts,
+// they are run by Helm on the machine running Helm. They read the
+// sdk.LifecycleEvent of the release on their standard input, and a
+// lifecycle hook failing on a pre event aborts the operation.
+const (
+	PreInstall    = "pre-install"
+	PostInstall   = "post-install"
+	PreUpgrade    = "pre-upgrade"
+	PostUpgrade   = "post-upgrade"
+	PreRollback   = "pre-rollback"
+	PostRollback  = "post-rollback"
+	PreUninstall  = "pre-uninstall"
+	PostUninstall = "post-uninstall"
+)
+
+// LifecycleEvents are the types of lifecycle hooks.
+var LifecycleEvents = []string{PreInstall, PostInstall, PreUpgrade, PostUpgrade, PreRollback, PostRollback, PreUninstall, PostUninstall}
+
 // Hooks is a map of events to commands.
 type Hooks map[string]string
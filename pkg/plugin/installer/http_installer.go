@@ -33,13 +33,21 @@ import (
 	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/getter"
 	"helm.sh/helm/v3/pkg/helmpath"
+	"helm.sh/helm/v3/pkg/plugin"
 	"helm.sh/helm/v3/pkg/plugin/cache"
+	"helm.sh/helm/v3/pkg/registry"
 )
 
 // HTTPInstaller installs plugins from an archive served by a web server.
 type HTTPInstaller struct {
 	CacheDir   string
 	PluginName string
+	// Verify configures the verification of the signatures of the archive.
+	// The archive is not verified if it is nil.
+	Verify *VerifyOptions
+	// Verification is the verification of the installed archive, or nil if
+	// it had no trusted signature.
+	Verification *Verification
 	base
 	extractor Extractor
 	getter    getter.Getter
@@ -130,6 +138,7 @@ func (i *HTTPInstaller) Install() error {
 	if err != nil {
 		return err
 	}
+	data := append([]byte(nil), pluginData.Bytes()...)
 
 	if err := i.extractor.Extract(pluginData, i.CacheDir); err != nil {
 		return errors.Wrap(err, "extracting files from archive")
@@ -139,6 +148,12 @@ func (i *HTTPInstaller) Install() error {
 		return ErrMissingMetadata
 	}
 
+	if i.Verify != nil {
+		if err := i.verify(data); err != nil {
+			return err
+		}
+	}
+
 	src, err := filepath.Abs(i.CacheDir)
 	if err != nil {
 		return err
@@ -148,6 +163,41 @@ func (i *HTTPInstaller) Install() error {
 	return fs.CopyDir(src, i.Path())
 }
 
+// verify verifies the signatures of the archive data, extracted in the cache
+// directory, published alongside the archive.
+func (i *HTTPInstaller) verify(data []byte) error {
+	signatures := make(map[string][]byte)
+	for kind, ext := range map[string]string{
+		plugin.SignatureGPG:      GPGSignatureExt,
+		plugin.SignatureSigstore: registry.SignatureBundleExt,
+	} {
+		sig, err := i.getter.Get(i.Source + ext)
+		if err != nil {
+			debug("no %s signature at %s: %s", kind, i.Source+ext, err)
+			continue
+		}
+		signatures[kind] = sig.Bytes()
+	}
+
+	p, err := plugin.LoadDir(i.CacheDir)
+	if err != nil {
+		return err
+	}
+	if err := checkDeclared(p.Metadata, signatures); err != nil {
+		return err
+	}
+	v, err := i.Verify.verifyArchive(data, signatures)
+	if err != nil {
+		return errors.Wrapf(err, "failed to verify %s", i.Source)
+	}
+	if v == nil {
+		return i.Verify.Unsigned(i.Source, nil)
+	}
+	debug("verified the %s signature of %s by %s", v.Signature, i.Source, v.Signer)
+	i.Verification = v
+	return nil
+}
+
 // Update updates a local repository
 // Not implemented for now since tarball most likely will be packaged by version
 func (i *HTTPInstaller) Update() error {
@@ -26,6 +26,7 @@ import (
 	"github.com/pkg/errors"
 
 	"helm.sh/helm/v3/pkg/plugin"
+	"helm.sh/helm/v3/pkg/registry"
 )
 
 // ErrMissingMetadata indicates that plugin.yaml is missing.
@@ -68,6 +69,8 @@ func NewForSource(source, version string) (Installer, error) {
 	// Check if source is a local directory
 	if isLocalReference(source) {
 		return NewLocalInstaller(source)
+	} else if registry.IsOCI(source) {
+		return NewOCIInstaller(source, version)
 	} else if isRemoteHTTPArchive(source) {
 		return NewHTTPInstaller(source)
 	}
@@ -76,6 +79,11 @@ func NewForSource(source, version string) (Installer, error) {
 
 // FindSource determines the correct Installer for the given source.
 func FindSource(location string) (Installer, error) {
+	if i, err := existingOCIPlugin(location); err != nil {
+		return nil, err
+	} else if i != nil {
+		return i, nil
+	}
 	installer, err := existingVCSRepo(location)
 	if err != nil && err.Error() == "Cannot detect VCS" {
 		return installer, errors.New("cannot get information about plugin source")
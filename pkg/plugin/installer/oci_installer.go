@@ -0,0 +1,332 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package installer // import "helm.sh/helm/v3/pkg/plugin/installer"
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/internal/third_party/dep/fs"
+	"helm.sh/helm/v3/pkg/helmpath"
+	"helm.sh/helm/v3/pkg/plugin"
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+// OCISourceFile is the file recording the source of a plugin installed from
+// an OCI registry, in the directory of the plugin.
+const OCISourceFile = ".helm-oci-source.json"
+
+// ociSource is the source of a plugin installed from an OCI registry.
+type ociSource struct {
+	// Repository is the repository of the plugin, like
+	// "registry.example.com/plugins/myplugin".
+	Repository string `json:"repository"`
+	// Reference is the tag or the digest of the installed plugin, as
+	// requested.
+	Reference string `json:"reference,omitempty"`
+	// Version is the version constraint of the tags of the plugin.
+	Version string `json:"version,omitempty"`
+	// Digest is the digest the reference resolved to.
+	Digest string `json:"digest"`
+}
+
+// pinned returns whether the source is pinned to a digest.
+func (s *ociSource) pinned() bool {
+	return strings.Contains(s.Reference, ":")
+}
+
+// OCIInstaller installs plugins from an OCI registry, like
+// "oci://registry.example.com/plugins/myplugin:1.2.3".
+//
+// The plugin is the archive of the manifest at the reference, or of the
+// manifest of the platform of Helm in the index at the reference. A reference
+// with a digest pins the plugin. A reference without a tag installs the
+// highest version of the tags matching the version constraint.
+type OCIInstaller struct {
+	CacheDir   string
+	PluginName string
+	// Version is the version constraint of the tags of the plugin, if the
+	// source has no tag or digest.
+	Version string
+	// RegistryClient pulls the plugin. A client with the default registry
+	// config is used if it is nil.
+	RegistryClient *registry.Client
+	// Verify configures the verification of the cosign signatures of the
+	// plugin. The plugin is not verified if it is nil.
+	Verify *VerifyOptions
+	// Verification is the verification of the installed plugin, or nil if it
+	// had no trusted signature.
+	Verification *Verification
+	base
+	source   ociSource
+	platform ocispec.Platform
+	dir      string
+}
+
+// NewOCIInstaller creates a new OCIInstaller.
+func NewOCIInstaller(source, version string) (*OCIInstaller, error) {
+	ref := strings.TrimPrefix(source, registry.OCIScheme+"://")
+	repository, reference := ref, ""
+	if i := strings.Index(ref, "@"); i != -1 {
+		repository, reference = ref[:i], ref[i+1:]
+	}
+	if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		if reference == "" {
+			reference = repository[i+1:]
+		}
+		repository = repository[:i]
+	}
+	if repository == "" || !strings.Contains(repository, "/") {
+		return nil, errors.Errorf("invalid plugin reference %q", source)
+	}
+	if reference != "" && version != "" {
+		return nil, errors.Errorf("plugin reference %q has a tag or a digest, and a version %q", source, version)
+	}
+
+	name := path.Base(repository)
+	return &OCIInstaller{
+		CacheDir:   helmpath.CachePath("plugins", "oci", strings.ReplaceAll(repository, "/", "-")),
+		PluginName: name,
+		Version:    version,
+		base:       newBase(source),
+		source:     ociSource{Repository: repository, Reference: reference, Version: version},
+		platform:   ocispec.Platform{OS: runtime.GOOS, Architecture: runtime.GOARCH},
+	}, nil
+}
+
+// existingOCIPlugin returns the installer of the plugin installed from an OCI
+// registry at location, or nil if it was not.
+func existingOCIPlugin(location string) (*OCIInstaller, error) {
+	data, err := os.ReadFile(filepath.Join(location, OCISourceFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	source := ociSource{}
+	if err := json.Unmarshal(data, &source); err != nil {
+		return nil, errors.Wrapf(err, "invalid OCI source of the plugin at %s", location)
+	}
+	ref := source.Repository
+	switch {
+	case source.pinned():
+		ref += "@" + source.Reference
+	case source.Reference != "":
+		ref += ":" + source.Reference
+	}
+	i, err := NewOCIInstaller(registry.OCIScheme+"://"+ref, source.Version)
+	if err != nil {
+		return nil, err
+	}
+	i.source.Digest = source.Digest
+	i.dir = location
+	return i, nil
+}
+
+// Digest returns the digest of the installed plugin.
+func (i *OCIInstaller) Digest() string {
+	return i.source.Digest
+}
+
+// Install pulls the plugin and installs it into the plugin directory.
+//
+// Implements Installer.
+func (i *OCIInstaller) Install() error {
+	ref, err := i.resolve()
+	if err != nil {
+		return err
+	}
+	if err := i.pull(ref); err != nil {
+		return err
+	}
+	return i.copy()
+}
+
+// Update installs the newest plugin matching the source of the installed
+// one, if its digest changed. The plugins pinned to a digest are not
+// updated.
+//
+// Implements Installer.
+func (i *OCIInstaller) Update() error {
+	if i.source.pinned() {
+		debug("%s is pinned to %s", i.source.Repository, i.source.Reference)
+		return nil
+	}
+	ok, ref, err := i.UpdateAvailable()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		debug("%s is up to date", ref)
+		return nil
+	}
+	if err := i.pull(ref); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(i.Path()); err != nil {
+		return err
+	}
+	return i.copy()
+}
+
+// UpdateAvailable returns whether the source of the installed plugin resolves
+// to another digest, with the reference it resolves.
+func (i *OCIInstaller) UpdateAvailable() (bool, string, error) {
+	ref, err := i.resolve()
+	if err != nil {
+		return false, "", err
+	}
+	client, err := i.client()
+	if err != nil {
+		return false, "", err
+	}
+	digest, err := client.Resolve(ref)
+	if err != nil {
+		return false, "", err
+	}
+	return digest != i.source.Digest, ref, nil
+}
+
+// Path is overridden because we want to join on the plugin name, not the
+// reference.
+func (i *OCIInstaller) Path() string {
+	if i.dir != "" {
+		return i.dir
+	}
+	if i.base.Source == "" {
+		return ""
+	}
+	return helmpath.DataPath("plugins", i.PluginName)
+}
+
+// resolve returns the reference of the plugin to pull, with the highest tag
+// matching the version constraint if the source has no tag or digest.
+func (i *OCIInstaller) resolve() (string, error) {
+	switch {
+	case i.source.pinned():
+		return i.source.Repository + "@" + i.source.Reference, nil
+	case i.source.Reference != "":
+		return i.source.Repository + ":" + i.source.Reference, nil
+	}
+	client, err := i.client()
+	if err != nil {
+		return "", err
+	}
+	tags, err := client.Tags(i.source.Repository)
+	if err != nil {
+		return "", err
+	}
+	tag, err := registry.GetTagMatchingVersionOrConstraint(tags, i.Version)
+	if err != nil {
+		return "", errors.Wrapf(err, "plugin %s", i.source.Repository)
+	}
+	return i.source.Repository + ":" + tag, nil
+}
+
+// pull pulls the plugin at ref, verifies it and extracts it into the cache
+// directory.
+func (i *OCIInstaller) pull(ref string) error {
+	client, err := i.client()
+	if err != nil {
+		return err
+	}
+	result, err := client.PullPlugin(ref, i.platform)
+	if err != nil {
+		return err
+	}
+	debug("pulled %s with digest %s", ref, result.Digest)
+
+	if err := os.RemoveAll(i.CacheDir); err != nil {
+		return err
+	}
+	if err := new(TarGzExtractor).Extract(bytes.NewBuffer(result.Archive.Data), i.CacheDir); err != nil {
+		return errors.Wrap(err, "extracting files from archive")
+	}
+	if !isPlugin(i.CacheDir) {
+		return ErrMissingMetadata
+	}
+	if i.Verify != nil {
+		manifestRef := i.source.Repository + "@" + result.Manifest
+		if err := i.verify(client, manifestRef, result.Archive.Data); err != nil {
+			return err
+		}
+	}
+	i.source.Digest = result.Digest
+	return nil
+}
+
+// verify verifies the cosign signature of the manifest of the plugin archive
+// data, extracted in the cache directory.
+func (i *OCIInstaller) verify(client *registry.Client, ref string, data []byte) error {
+	p, err := plugin.LoadDir(i.CacheDir)
+	if err != nil {
+		return err
+	}
+	var reason error
+	if i.Verify.SignaturePolicy != nil {
+		sv, err := client.VerifySignature(ref, data, i.Verify.SignaturePolicy)
+		if err == nil {
+			debug("verified the %s signature of %s by %s", plugin.SignatureSigstore, ref, sv.Signer)
+			i.Verification = &Verification{Signature: plugin.SignatureSigstore, Signer: sv.Signer}
+			return nil
+		}
+		reason = err
+	}
+	if len(p.Metadata.Signatures) > 0 {
+		return errors.Errorf("plugin %q declares signatures, but %s has no trusted signature: %v", p.Metadata.Name, ref, reason)
+	}
+	return i.Verify.Unsigned(i.Source, reason)
+}
+
+// copy copies the plugin extracted in the cache directory into the plugin
+// directory, with its source.
+func (i *OCIInstaller) copy() error {
+	src, err := filepath.Abs(i.CacheDir)
+	if err != nil {
+		return err
+	}
+	debug("copying %s to %s", src, i.Path())
+	if err := fs.CopyDir(src, i.Path()); err != nil {
+		return err
+	}
+	data, err := json.Marshal(&i.source)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(i.Path(), OCISourceFile), data, 0644)
+}
+
+func (i *OCIInstaller) client() (*registry.Client, error) {
+	if i.RegistryClient == nil {
+		client, err := registry.NewClient(
+			registry.ClientOptCredentialsFile(helmpath.ConfigPath(registry.CredentialsFileBasename)),
+		)
+		if err != nil {
+			return nil, err
+		}
+		i.RegistryClient = client
+	}
+	return i.RegistryClient, nil
+}
@@ -0,0 +1,214 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package installer // import "helm.sh/helm/v3/pkg/plugin/installer"
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"helm.sh/helm/v3/internal/test/ensure"
+	"helm.sh/helm/v3/pkg/helmpath"
+	"helm.sh/helm/v3/pkg/plugin"
+	"helm.sh/helm/v3/pkg/registry"
+	"helm.sh/helm/v3/pkg/repo/repotest"
+)
+
+// ociRegistry starts a registry, returning its host and a client logged in.
+func ociRegistry(t *testing.T) (string, *registry.Client) {
+	t.Helper()
+	srv, err := repotest.NewOCIServer(t, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.ListenAndServe()
+
+	client, err := registry.NewClient(registry.ClientOptPlainHTTP(), registry.ClientOptCredentialsFile(filepath.Join(t.TempDir(), "config.json")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Login(srv.RegistryURL, registry.LoginOptBasicAuth(srv.TestUsername, srv.TestPassword)); err != nil {
+		t.Fatal(err)
+	}
+	return srv.RegistryURL, client
+}
+
+// fakePlugin returns the archive of the fake plugin running the command.
+func fakePlugin(t *testing.T, command string) []byte {
+	return pluginArchive(t, fmt.Sprintf("name: fake-plugin\nversion: 0.0.1\ncommand: %s\n", command))
+}
+
+func loadCommand(t *testing.T, dir string) string {
+	t.Helper()
+	p, err := plugin.LoadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.Metadata.Command
+}
+
+func TestOCIInstaller(t *testing.T) {
+	ensure.HelmHome(t)
+	if err := os.MkdirAll(helmpath.DataPath("plugins"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	host, client := ociRegistry(t)
+	repository := host + "/plugins/fake-plugin"
+
+	pinned, err := client.PushPlugin(repository+":0.1.0", registry.PluginArchive{Data: fakePlugin(t, "echo 0.1.0")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.PushPlugin(repository+":0.2.0",
+		registry.PluginArchive{Platform: &ocispec.Platform{OS: "linux", Architecture: "arm64"}, Data: fakePlugin(t, "echo linux-arm64")},
+		registry.PluginArchive{Platform: &ocispec.Platform{OS: "darwin", Architecture: "arm64"}, Data: fakePlugin(t, "echo darwin-arm64")},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The highest tag is installed, with the archive of the platform
+	i, err := NewOCIInstaller("oci://"+repository, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.RegistryClient = client
+	i.platform = ocispec.Platform{OS: "linux", Architecture: "arm64"}
+	if err := Install(i); err != nil {
+		t.Fatal(err)
+	}
+	if i.Path() != helmpath.DataPath("plugins", "fake-plugin") {
+		t.Errorf("expected the plugin installed in %s, got %s", helmpath.DataPath("plugins", "fake-plugin"), i.Path())
+	}
+	if got := loadCommand(t, i.Path()); got != "echo linux-arm64" {
+		t.Errorf("expected the plugin of linux/arm64, got the command %q", got)
+	}
+	if err := os.RemoveAll(i.Path()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The plugins without an archive of the platform are not installed
+	i, err = NewOCIInstaller("oci://"+repository+":0.2.0", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.RegistryClient = client
+	i.platform = ocispec.Platform{OS: "windows", Architecture: "amd64"}
+	if err := Install(i); err == nil {
+		t.Error("expected an error installing a plugin without an archive of the platform")
+	}
+
+	// A plugin pinned to a digest is not updated
+	i, err = NewOCIInstaller("oci://"+repository+"@"+pinned, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.RegistryClient = client
+	if err := Install(i); err != nil {
+		t.Fatal(err)
+	}
+	if i.Digest() != pinned {
+		t.Errorf("expected the digest %s, got %s", pinned, i.Digest())
+	}
+	if _, err := client.PushPlugin(repository+":0.1.1", registry.PluginArchive{Data: fakePlugin(t, "echo 0.1.1")}); err != nil {
+		t.Fatal(err)
+	}
+	found, err := FindSource(i.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	found.(*OCIInstaller).RegistryClient = client
+	if err := Update(found); err != nil {
+		t.Fatal(err)
+	}
+	if got := loadCommand(t, i.Path()); got != "echo 0.1.0" {
+		t.Errorf("expected the pinned plugin unchanged, got the command %q", got)
+	}
+	if err := os.RemoveAll(i.Path()); err != nil {
+		t.Fatal(err)
+	}
+
+	// A plugin installed with a version constraint is updated to the highest
+	// matching tag
+	i, err = NewOCIInstaller("oci://"+repository, "~0.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.RegistryClient = client
+	if err := Install(i); err != nil {
+		t.Fatal(err)
+	}
+	if got := loadCommand(t, i.Path()); got != "echo 0.1.1" {
+		t.Errorf("expected the plugin 0.1.1, got the command %q", got)
+	}
+	if _, err := client.PushPlugin(repository+":0.1.2", registry.PluginArchive{Data: fakePlugin(t, "echo 0.1.2")}); err != nil {
+		t.Fatal(err)
+	}
+	found, err = FindSource(i.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	oi := found.(*OCIInstaller)
+	oi.RegistryClient = client
+	if ok, ref, err := oi.UpdateAvailable(); err != nil || !ok || ref != repository+":0.1.2" {
+		t.Errorf("expected an update to %s:0.1.2, got %t, %s, %v", repository, ok, ref, err)
+	}
+	if err := Update(oi); err != nil {
+		t.Fatal(err)
+	}
+	if got := loadCommand(t, i.Path()); got != "echo 0.1.2" {
+		t.Errorf("expected the plugin updated to 0.1.2, got the command %q", got)
+	}
+	if ok, _, err := oi.UpdateAvailable(); err != nil || ok {
+		t.Errorf("expected no update after updating, got %t, %v", ok, err)
+	}
+}
+
+func TestNewOCIInstaller(t *testing.T) {
+	for _, tt := range []struct {
+		source, version string
+		repository      string
+		reference       string
+		err             bool
+	}{
+		{source: "oci://example.com/plugins/myplugin", repository: "example.com/plugins/myplugin"},
+		{source: "oci://example.com:5000/plugins/myplugin:1.2.3", repository: "example.com:5000/plugins/myplugin", reference: "1.2.3"},
+		{source: "oci://example.com/myplugin:1.2.3@sha256:abc", repository: "example.com/myplugin", reference: "sha256:abc"},
+		{source: "oci://example.com/myplugin:1.2.3", version: "~1.2", err: true},
+		{source: "oci://myplugin", err: true},
+	} {
+		i, err := NewOCIInstaller(tt.source, tt.version)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", tt.source)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", tt.source, err)
+			continue
+		}
+		if i.source.Repository != tt.repository || i.source.Reference != tt.reference {
+			t.Errorf("%s: expected %s and %q, got %s and %q", tt.source, tt.repository, tt.reference, i.source.Repository, i.source.Reference)
+		}
+		if i.PluginName != "myplugin" {
+			t.Errorf("%s: expected the plugin name myplugin, got %s", tt.source, i.PluginName)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package installer // import "helm.sh/helm/v3/pkg/plugin/installer"
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/openpgp" //nolint
+
+	"helm.sh/helm/v3/pkg/plugin"
+	"helm.sh/helm/v3/pkg/provenance"
+	"helm.sh/helm/v3/pkg/registry"
+)
+
+// The policies of the plugins installed without a trusted signature.
+const (
+	// VerifyPolicyWarn installs the unsigned plugins with a warning.
+	VerifyPolicyWarn = "warn"
+	// VerifyPolicyDeny rejects the unsigned plugins.
+	VerifyPolicyDeny = "deny"
+)
+
+// GPGSignatureExt is the extension of the ASCII armored detached GPG
+// signatures of the plugin archives.
+const GPGSignatureExt = ".asc"
+
+// ErrUnsigned indicates that a plugin has no trusted signature.
+var ErrUnsigned = errors.New("plugin is not signed")
+
+// VerifyOptions configures the verification of the signatures of the plugin
+// archives. The archives with an invalid signature, or without one of the
+// signatures declared in their plugin.yaml, are always rejected.
+type VerifyOptions struct {
+	// Policy is the policy of the unsigned plugins: VerifyPolicyWarn, the
+	// default, or VerifyPolicyDeny.
+	Policy string
+	// Keyring is the path to the keyring of the GPG keys trusted to sign the
+	// plugins. The GPG signatures are not verified if it is empty.
+	Keyring string
+	// SignaturePolicy is the policy of the sigstore signatures trusted to sign
+	// the plugins. The sigstore signatures are not verified if it is nil.
+	SignaturePolicy *registry.SignaturePolicy
+	// Warn prints the warnings of the plugins installed without a trusted
+	// signature.
+	Warn func(format string, v ...interface{})
+}
+
+// Validate checks the policy of the options.
+func (o *VerifyOptions) Validate() error {
+	switch o.Policy {
+	case "", VerifyPolicyWarn, VerifyPolicyDeny:
+		return nil
+	}
+	return errors.Errorf("invalid plugin verification policy %q: must be %s or %s", o.Policy, VerifyPolicyWarn, VerifyPolicyDeny)
+}
+
+// Verification is the result of the verification of a plugin archive.
+type Verification struct {
+	// Signature is the kind of the trusted signature, like
+	// plugin.SignatureGPG.
+	Signature string
+	// Signer is the identity of the GPG key, the public key file or the
+	// subject of the certificate of the signature.
+	Signer string
+}
+
+// Unsigned applies the policy to a plugin installed from the source without
+// a trusted signature.
+func (o *VerifyOptions) Unsigned(source string, reason error) error {
+	if reason == nil {
+		reason = ErrUnsigned
+	}
+	if o.Policy == VerifyPolicyDeny {
+		return errors.Wrapf(reason, "refusing to install the plugin from %s", source)
+	}
+	if o.Warn != nil {
+		o.Warn("installing the plugin from %s without verifying it: %s", source, reason)
+	}
+	return nil
+}
+
+// verifyArchive verifies the archive of a plugin with its signatures, by
+// kind. The signatures which cannot be verified with the options are
+// ignored.
+func (o *VerifyOptions) verifyArchive(data []byte, signatures map[string][]byte) (*Verification, error) {
+	var problems []string
+	if sig := signatures[plugin.SignatureGPG]; len(sig) > 0 && o.Keyring != "" {
+		v, err := verifyGPG(o.Keyring, data, sig)
+		if err == nil {
+			return v, nil
+		}
+		problems = append(problems, err.Error())
+	}
+	if sig := signatures[plugin.SignatureSigstore]; len(sig) > 0 && o.SignaturePolicy != nil {
+		v, err := verifySigstore(o.SignaturePolicy, data, sig)
+		if err == nil {
+			return v, nil
+		}
+		problems = append(problems, err.Error())
+	}
+	if len(problems) > 0 {
+		return nil, errors.Errorf("invalid plugin signature: %s", strings.Join(problems, "; "))
+	}
+	return nil, nil
+}
+
+// checkDeclared checks the archive of the plugin has all the signatures
+// declared in its metadata.
+func checkDeclared(md *plugin.Metadata, signatures map[string][]byte) error {
+	for _, kind := range md.Signatures {
+		if len(signatures[kind]) == 0 {
+			return errors.Errorf("plugin %q declares a %s signature, but its archive has none", md.Name, kind)
+		}
+	}
+	return nil
+}
+
+func verifyGPG(keyring string, data, sig []byte) (*Verification, error) {
+	s, err := provenance.NewFromKeyring(keyring, "")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load the GPG keyring")
+	}
+	signer, err := openpgp.CheckArmoredDetachedSignature(s.KeyRing, bytes.NewReader(data), bytes.NewReader(sig))
+	if err != nil {
+		return nil, errors.Wrap(err, "GPG signature")
+	}
+	v := &Verification{Signature: plugin.SignatureGPG, Signer: fmt.Sprintf("%X", signer.PrimaryKey.Fingerprint)}
+	names := make([]string, 0, len(signer.Identities))
+	for name := range signer.Identities {
+		names = append(names, name)
+	}
+	if len(names) > 0 {
+		sort.Strings(names)
+		v.Signer = names[0]
+	}
+	return v, nil
+}
+
+func verifySigstore(policy *registry.SignaturePolicy, data, sig []byte) (*Verification, error) {
+	bundle := &registry.SignatureBundle{}
+	if err := json.Unmarshal(sig, bundle); err != nil {
+		return nil, errors.Wrap(err, "invalid cosign bundle")
+	}
+	sv, err := policy.VerifyBundle(data, bundle)
+	if err != nil {
+		return nil, errors.Wrap(err, "sigstore signature")
+	}
+	return &Verification{Signature: plugin.SignatureSigstore, Signer: sv.Signer}, nil
+}
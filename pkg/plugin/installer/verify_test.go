@@ -0,0 +1,194 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package installer // import "helm.sh/helm/v3/pkg/plugin/installer"
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/openpgp" //nolint
+
+	"helm.sh/helm/v3/internal/test/ensure"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/helmpath"
+)
+
+const testKeyring = "testdata/helm-test-key.pub"
+
+// mapGetter serves the files of a map, by URL.
+type mapGetter map[string][]byte
+
+func (g mapGetter) Get(url string, _ ...getter.Option) (*bytes.Buffer, error) {
+	data, ok := g[url]
+	if !ok {
+		return nil, errors.Errorf("failed to fetch %s : 404 Not Found", url)
+	}
+	return bytes.NewBuffer(data), nil
+}
+
+// pluginArchive returns the archive of the plugin with the metadata.
+func pluginArchive(t *testing.T, metadata string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: "plugin.yaml", Mode: 0644, Size: int64(len(metadata)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write([]byte(metadata)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// gpgSign returns the ASCII armored detached signature of the data by the
+// test key.
+func gpgSign(t *testing.T, data []byte) []byte {
+	t.Helper()
+	f, err := os.Open("testdata/helm-test-key.secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	ring, err := openpgp.ReadKeyRing(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sig bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&sig, ring[0], bytes.NewReader(data), nil); err != nil {
+		t.Fatal(err)
+	}
+	return sig.Bytes()
+}
+
+func TestHTTPInstallerVerify(t *testing.T) {
+	unsigned := pluginArchive(t, "name: fake-plugin\nversion: 0.0.1\ncommand: echo\n")
+	declared := pluginArchive(t, "name: fake-plugin\nversion: 0.0.1\ncommand: echo\nsignatures: [gpg]\n")
+	tampered := pluginArchive(t, "name: fake-plugin\nversion: 0.0.1\ncommand: rm\n")
+
+	for _, tt := range []struct {
+		name   string
+		policy string
+		files  map[string][]byte
+		signer string
+		warned bool
+		err    string
+	}{
+		{
+			name:   "signed",
+			policy: VerifyPolicyDeny,
+			files:  map[string][]byte{"": unsigned, ".asc": gpgSign(t, unsigned)},
+			signer: "helm-test",
+		},
+		{
+			name:   "tampered",
+			policy: VerifyPolicyWarn,
+			files:  map[string][]byte{"": tampered, ".asc": gpgSign(t, unsigned)},
+			err:    "invalid plugin signature",
+		},
+		{
+			name:   "unsigned with the warn policy",
+			policy: VerifyPolicyWarn,
+			files:  map[string][]byte{"": unsigned},
+			warned: true,
+		},
+		{
+			name:   "unsigned with the deny policy",
+			policy: VerifyPolicyDeny,
+			files:  map[string][]byte{"": unsigned},
+			err:    "plugin is not signed",
+		},
+		{
+			name:   "declared signature missing",
+			policy: VerifyPolicyWarn,
+			files:  map[string][]byte{"": declared},
+			err:    `plugin "fake-plugin" declares a gpg signature, but its archive has none`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ensure.HelmHome(t)
+			if err := os.MkdirAll(helmpath.DataPath("plugins"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			source := "https://example.com/plugins/fake-plugin-0.0.1.tar.gz"
+			i, err := NewHTTPInstaller(source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files := mapGetter{}
+			for ext, data := range tt.files {
+				files[source+ext] = data
+			}
+			i.getter = files
+			var warnings []string
+			i.Verify = &VerifyOptions{
+				Policy:  tt.policy,
+				Keyring: testKeyring,
+				Warn: func(format string, v ...interface{}) {
+					warnings = append(warnings, fmt.Sprintf(format, v...))
+				},
+			}
+
+			err = Install(i)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected an error containing %q, got %v", tt.err, err)
+				}
+				if _, err := os.Stat(i.Path()); !os.IsNotExist(err) {
+					t.Error("expected the plugin not installed")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(filepath.Join(i.Path(), "plugin.yaml")); err != nil {
+				t.Errorf("expected the plugin installed: %s", err)
+			}
+			if tt.warned != (len(warnings) > 0) {
+				t.Errorf("unexpected warnings %v", warnings)
+			}
+			if tt.signer == "" {
+				if i.Verification != nil {
+					t.Errorf("expected no verification, got %+v", i.Verification)
+				}
+			} else if i.Verification == nil || !strings.Contains(i.Verification.Signer, tt.signer) {
+				t.Errorf("expected a verification by %s, got %+v", tt.signer, i.Verification)
+			}
+		})
+	}
+}
+
+func TestVerifyOptionsValidate(t *testing.T) {
+	for policy, valid := range map[string]bool{"": true, VerifyPolicyWarn: true, VerifyPolicyDeny: true, "allow": false} {
+		if err := (&VerifyOptions{Policy: policy}).Validate(); (err == nil) != valid {
+			t.Errorf("policy %q: expected valid %t, got %v", policy, valid, err)
+		}
+	}
+}
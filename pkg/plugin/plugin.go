@@ -18,6 +18,7 @@ package plugin // import "helm.sh/helm/v3/pkg/plugin"
 import (
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"runtime"
@@ -28,10 +29,17 @@ import (
 	"sigs.k8s.io/yaml"
 
 	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/plugin/sdk"
 )
 
 const PluginFileName = "plugin.yaml"
 
+// The kinds of the signatures of the plugin archives.
+const (
+	SignatureGPG      = "gpg"
+	SignatureSigstore = "sigstore"
+)
+
 // Downloaders represents the plugins capability if it can retrieve
 // charts from special sources
 type Downloaders struct {
@@ -40,6 +48,52 @@ type Downloaders struct {
 	// Command is the executable path with which the plugin performs
 	// the actual download for the corresponding Protocols
 	Command string `json:"command"`
+	// ProtocolVersion is the version of the protocol Helm talks to the
+	// Command with, DownloaderProtocolV1 if it is not set.
+	ProtocolVersion int `json:"protocolVersion,omitempty"`
+}
+
+const (
+	// DownloaderProtocolV1 passes the URL and the TLS files of a download as
+	// arguments of the command, the credentials in the environment, and reads
+	// the body of the download on its standard output.
+	DownloaderProtocolV1 = 1
+	// DownloaderProtocolV2 exchanges JSON messages with the command on its
+	// standard input and output, supporting streamed bodies, credential
+	// requests, redirects and digests.
+	DownloaderProtocolV2 = 2
+)
+
+// LintRule is a lint rule of 'helm lint' implemented by a plugin.
+//
+// The command is run with the chart directory as its argument, after
+// environment expansion. It writes the failures of the rule on its standard
+// output as JSON objects, one per line, with the "message" of the failure and
+// optionally its "path" in the chart, its "line" and its "severity". A
+// command exiting with an error fails the rule.
+type LintRule struct {
+	// ID identifies the rule. It is prefixed by the name of the plugin.
+	ID string `json:"id"`
+	// Description describes what the rule checks.
+	Description string `json:"description"`
+	// Severity is the severity of the failures without one: "info",
+	// "warning" or "error", the default.
+	Severity string `json:"severity,omitempty"`
+	// Command is the command checking the chart.
+	Command string `json:"command"`
+}
+
+// CredentialProvider is a command of a plugin providing the credentials of
+// the repositories and the registries of some hosts, like short-lived tokens
+// exchanged for a cloud identity. The plugin must declare a version of the
+// plugin API: the command reads the host in its request, and writes the
+// credentials on its standard output.
+type CredentialProvider struct {
+	// Hosts are the hosts the provider has credentials for. They may be
+	// patterns like "*.dkr.ecr.*.amazonaws.com".
+	Hosts []string `json:"hosts"`
+	// Command is the command providing the credentials.
+	Command string `json:"command"`
 }
 
 // PlatformCommand represents a command for a particular operating system and architecture
@@ -83,6 +137,37 @@ type Metadata struct {
 	PlatformCommand []PlatformCommand `json:"platformCommand"`
 	Command         string            `json:"command"`
 
+	// Runtime is the runtime of the plugin: RuntimeSubprocess, the default,
+	// runs its command, and RuntimeWasm runs its WebAssembly module.
+	Runtime string `json:"runtime,omitempty"`
+
+	// Module is the path to the WebAssembly module of the plugins of
+	// RuntimeWasm, relative to the plugin directory, like "plugin.wasm".
+	//
+	// The module is a WASI command, run with the name of the plugin and the
+	// arguments of the plugin command. It only accesses Helm through the host
+	// functions of HostModule, and has no command, hooks, downloaders or lint
+	// rules.
+	Module string `json:"module,omitempty"`
+
+	// RenderHook is whether the WebAssembly module of the plugin is a render
+	// hook, run in process on the rendered manifests of the installs, the
+	// upgrades and the templates with '--render-hook'.
+	RenderHook bool `json:"renderHook,omitempty"`
+
+	// APIVersions are the versions of the plugin API supported by the
+	// commands of the plugin, like "v1". Helm passes the request of the
+	// newest version it supports too to each run of the commands, which read
+	// it with the sdk package. The plugins declaring none get no request.
+	APIVersions []string `json:"apiVersions,omitempty"`
+
+	// Signatures are the kinds of the signatures of the archives of the
+	// plugin, published alongside them: SignatureGPG for the ASCII armored
+	// detached signature "<archive>.asc", and SignatureSigstore for the cosign
+	// bundle "<archive>.cosign.bundle". The archives installed without one of
+	// their declared signatures are rejected.
+	Signatures []string `json:"signatures,omitempty"`
+
 	// IgnoreFlags ignores any flags passed in from Helm
 	//
 	// For example, if the plugin is invoked as `helm --debug myplugin`, if this
@@ -97,6 +182,18 @@ type Metadata struct {
 	// for special protocols.
 	Downloaders []Downloaders `json:"downloaders"`
 
+	// LintRules are the rules the plugin adds to 'helm lint'.
+	LintRules []LintRule `json:"lintRules,omitempty"`
+
+	// CredentialProviders provide the credentials of repositories and
+	// registries.
+	CredentialProviders []CredentialProvider `json:"credentialProviders,omitempty"`
+
+	// Capabilities declare what the commands of the plugin access. The
+	// commands of a plugin declaring its capabilities are sandboxed, a plugin
+	// declaring none is only installed with --unsandboxed.
+	Capabilities *Capabilities `json:"capabilities,omitempty"`
+
 	// UseTunnelDeprecated indicates that this command needs a tunnel.
 	// Setting this will cause a number of side effects, such as the
 	// automatic setting of HELM_HOST.
@@ -183,11 +280,88 @@ func validatePluginData(plug *Plugin, filepath string) error {
 		return fmt.Errorf("invalid plugin name at %q", filepath)
 	}
 	plug.Metadata.Usage = sanitizeString(plug.Metadata.Usage)
+	if err := validateRuntime(plug.Metadata, filepath); err != nil {
+		return err
+	}
+	if len(plug.Metadata.APIVersions) > 0 {
+		if _, err := sdk.Negotiate(plug.Metadata.APIVersions); err != nil {
+			return fmt.Errorf("%s at %q", err, filepath)
+		}
+	}
+	for _, sig := range plug.Metadata.Signatures {
+		if sig != SignatureGPG && sig != SignatureSigstore {
+			return fmt.Errorf("unsupported signature %q at %q", sig, filepath)
+		}
+	}
+	for _, d := range plug.Metadata.Downloaders {
+		if d.ProtocolVersion < 0 || d.ProtocolVersion > DownloaderProtocolV2 {
+			return fmt.Errorf("unsupported downloader protocol version %d at %q", d.ProtocolVersion, filepath)
+		}
+	}
+	for _, c := range plug.Metadata.CredentialProviders {
+		if len(c.Hosts) == 0 || c.Command == "" {
+			return fmt.Errorf("credential provider without hosts or a command at %q", filepath)
+		}
+		for _, h := range c.Hosts {
+			if _, err := path.Match(h, ""); err != nil {
+				return fmt.Errorf("invalid credential provider host %q at %q", h, filepath)
+			}
+		}
+		if len(plug.Metadata.APIVersions) == 0 {
+			return fmt.Errorf("credential provider of a plugin without an API version at %q", filepath)
+		}
+	}
+	if c := plug.Metadata.Capabilities; c != nil {
+		for _, p := range c.Filesystem {
+			if strings.TrimSpace(p) == "" {
+				return fmt.Errorf("empty filesystem capability at %q", filepath)
+			}
+		}
+		for _, e := range c.Env {
+			if _, err := path.Match(e, ""); err != nil || e == "" || strings.Contains(e, "=") {
+				return fmt.Errorf("invalid env capability %q at %q", e, filepath)
+			}
+		}
+	}
+	for _, r := range plug.Metadata.LintRules {
+		if r.ID == "" || r.Command == "" {
+			return fmt.Errorf("lint rule without an ID or a command at %q", filepath)
+		}
+		switch strings.ToLower(r.Severity) {
+		case "", "info", "warning", "error":
+		default:
+			return fmt.Errorf("invalid severity %q of lint rule %q at %q", r.Severity, r.ID, filepath)
+		}
+	}
 
 	// We could also validate SemVer, executable, and other fields should we so choose.
 	return nil
 }
 
+// validateRuntime validates the runtime of a plugin, and the module of the
+// WebAssembly plugins.
+func validateRuntime(md *Metadata, filepath string) error {
+	switch md.Runtime {
+	case "", RuntimeSubprocess:
+		if md.Module != "" || md.RenderHook {
+			return fmt.Errorf("module or render hook of a plugin without the %s runtime at %q", RuntimeWasm, filepath)
+		}
+	case RuntimeWasm:
+		if md.Module == "" || path.IsAbs(md.Module) || strings.HasPrefix(path.Clean(md.Module), "..") {
+			return fmt.Errorf("invalid module %q of a %s plugin at %q", md.Module, RuntimeWasm, filepath)
+		}
+		if md.Command != "" || len(md.PlatformCommand) > 0 || len(md.Hooks) > 0 || len(md.Downloaders) > 0 || len(md.LintRules) > 0 || len(md.CredentialProviders) > 0 {
+			return fmt.Errorf("%s plugin with commands at %q", RuntimeWasm, filepath)
+		}
+		if md.Capabilities != nil {
+			return fmt.Errorf("%s plugin with capabilities at %q: the modules are sandboxed without any", RuntimeWasm, filepath)
+		}
+	default:
+		return fmt.Errorf("unsupported plugin runtime %q at %q", md.Runtime, filepath)
+	}
+	return nil
+}
+
 // sanitizeString normalize spaces and removes non-printable characters.
 func sanitizeString(str string) string {
 	return strings.Map(func(r rune) rune {
@@ -231,7 +405,15 @@ func LoadDir(dirname string) (*Plugin, error) {
 	if err := yaml.UnmarshalStrict(data, &plug.Metadata); err != nil {
 		return nil, errors.Wrapf(err, "failed to load plugin at %q", pluginfile)
 	}
-	return plug, validatePluginData(plug, pluginfile)
+	if err := validatePluginData(plug, pluginfile); err != nil {
+		return plug, err
+	}
+	if plug.IsWasm() {
+		if _, err := os.Stat(plug.ModulePath()); err != nil {
+			return plug, errors.Wrapf(err, "failed to find the module of plugin at %q", pluginfile)
+		}
+	}
+	return plug, nil
 }
 
 // LoadAll loads all plugins found beneath the base directory.
@@ -24,6 +24,7 @@ import (
 	"testing"
 
 	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/plugin/sdk"
 )
 
 func checkCommand(p *Plugin, extraArgs []string, osStrCmp string, t *testing.T) {
@@ -208,6 +209,23 @@ func TestLoadDir(t *testing.T) {
 	}
 }
 
+func TestLoadDirWasm(t *testing.T) {
+	plug, err := LoadDir("testdata/plugdir/wasm/hello-wasm")
+	if err != nil {
+		t.Fatalf("error loading the hello-wasm plugin: %s", err)
+	}
+	if !plug.IsWasm() {
+		t.Error("expected a WebAssembly plugin")
+	}
+	if expect := filepath.Join("testdata/plugdir/wasm/hello-wasm", "plugin.wasm"); plug.ModulePath() != expect {
+		t.Errorf("expected the module %q, got %q", expect, plug.ModulePath())
+	}
+
+	if _, err := LoadDir("testdata/plugdir/bad/wasm-missing-module"); err == nil {
+		t.Error("expected an error loading a plugin without its module")
+	}
+}
+
 func TestLoadDirDuplicateEntries(t *testing.T) {
 	dirname := "testdata/plugdir/bad/duplicate-entries"
 	if _, err := LoadDir(dirname); err == nil {
@@ -352,11 +370,101 @@ func TestSetupEnvWithSpace(t *testing.T) {
 	}
 }
 
+func TestNewRequest(t *testing.T) {
+	s := cli.New()
+	s.PluginsDirectory = "testdata/helmhome/helm/plugins"
+
+	p := mockPlugin("api")
+	if req := p.NewRequest(s, sdk.KindHook); req != nil {
+		t.Errorf("expected no request without a plugin API version, got %+v", req)
+	}
+
+	p.Metadata.APIVersions = []string{"v99", sdk.APIVersionV1}
+	req := p.NewRequest(s, sdk.KindHook)
+	if req == nil {
+		t.Fatal("expected a request")
+	}
+	if req.APIVersion != sdk.APIVersionV1 || req.Kind != sdk.KindHook {
+		t.Errorf("unexpected request %+v", req)
+	}
+	if req.Plugin != (sdk.Plugin{Name: "api", Version: "v0.1.2", Dir: "no-such-dir"}) {
+		t.Errorf("unexpected plugin %+v", req.Plugin)
+	}
+	if req.Settings.PluginsDirectory != s.PluginsDirectory || req.Settings.Namespace != "default" {
+		t.Errorf("unexpected settings %+v", req.Settings)
+	}
+
+	env, err := RequestEnv([]string{"FOO=bar"}, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(env) != 3 || env[1] != sdk.EnvAPIVersion+"="+sdk.APIVersionV1 {
+		t.Errorf("unexpected environment %v", env)
+	}
+}
+
 func TestValidatePluginData(t *testing.T) {
 	// A mock plugin missing any metadata.
 	mockMissingMeta := &Plugin{
 		Dir: "no-such-dir",
 	}
+	// Mock plugins with downloaders of supported and unsupported protocols.
+	mockDownloaderV2 := mockPlugin("downloader")
+	mockDownloaderV2.Metadata.Downloaders = []Downloaders{{Command: "get", Protocols: []string{"test"}, ProtocolVersion: DownloaderProtocolV2}}
+	mockDownloaderV3 := mockPlugin("downloader")
+	mockDownloaderV3.Metadata.Downloaders = []Downloaders{{Command: "get", Protocols: []string{"test"}, ProtocolVersion: 3}}
+	// Mock plugins with valid and invalid lint rules.
+	mockLintRule := mockPlugin("linter")
+	mockLintRule.Metadata.LintRules = []LintRule{{ID: "labels", Command: "lint", Severity: "Warning"}}
+	mockLintRuleSeverity := mockPlugin("linter")
+	mockLintRuleSeverity.Metadata.LintRules = []LintRule{{ID: "labels", Command: "lint", Severity: "fatal"}}
+	mockLintRuleCommand := mockPlugin("linter")
+	mockLintRuleCommand.Metadata.LintRules = []LintRule{{ID: "labels"}}
+	// Mock plugins with valid and invalid runtimes.
+	mockWasm := mockPlugin("wasm")
+	mockWasm.Metadata.Command = ""
+	mockWasm.Metadata.Runtime = RuntimeWasm
+	mockWasm.Metadata.Module = "plugin.wasm"
+	mockWasmCommand := mockPlugin("wasm")
+	mockWasmCommand.Metadata.Runtime = RuntimeWasm
+	mockWasmCommand.Metadata.Module = "plugin.wasm"
+	mockWasmModule := mockPlugin("wasm")
+	mockWasmModule.Metadata.Command = ""
+	mockWasmModule.Metadata.Runtime = RuntimeWasm
+	mockWasmModule.Metadata.Module = "../plugin.wasm"
+	mockSubprocessModule := mockPlugin("subprocess")
+	mockSubprocessModule.Metadata.Module = "plugin.wasm"
+	mockSubprocessRenderHook := mockPlugin("subprocess")
+	mockSubprocessRenderHook.Metadata.RenderHook = true
+	mockSignatures := mockPlugin("signed")
+	mockSignatures.Metadata.Signatures = []string{SignatureGPG, SignatureSigstore}
+	mockSignaturesKind := mockPlugin("signed")
+	mockSignaturesKind.Metadata.Signatures = []string{"x509"}
+	mockRuntime := mockPlugin("runtime")
+	mockRuntime.Metadata.Runtime = "jvm"
+	mockAPIVersions := mockPlugin("api")
+	mockAPIVersions.Metadata.APIVersions = []string{"v99", "v1"}
+	mockAPIVersionsUnsupported := mockPlugin("api")
+	mockAPIVersionsUnsupported.Metadata.APIVersions = []string{"v99"}
+	// Mock plugins with valid and invalid credential providers.
+	mockCredentials := mockPlugin("credentials")
+	mockCredentials.Metadata.APIVersions = []string{"v1"}
+	mockCredentials.Metadata.CredentialProviders = []CredentialProvider{{Hosts: []string{"*.example.com"}, Command: "token"}}
+	mockCredentialsHosts := mockPlugin("credentials")
+	mockCredentialsHosts.Metadata.APIVersions = []string{"v1"}
+	mockCredentialsHosts.Metadata.CredentialProviders = []CredentialProvider{{Command: "token"}}
+	mockCredentialsAPI := mockPlugin("credentials")
+	mockCredentialsAPI.Metadata.CredentialProviders = []CredentialProvider{{Hosts: []string{"example.com"}, Command: "token"}}
+	// Mock plugins with valid and invalid capabilities.
+	mockCapabilities := mockPlugin("sandboxed")
+	mockCapabilities.Metadata.Capabilities = &Capabilities{Network: true, Filesystem: []string{"~/.aws"}, Env: []string{"AWS_*"}}
+	mockCapabilitiesEnv := mockPlugin("sandboxed")
+	mockCapabilitiesEnv.Metadata.Capabilities = &Capabilities{Env: []string{"AWS_REGION=us-east-1"}}
+	mockWasmCapabilities := mockPlugin("wasm")
+	mockWasmCapabilities.Metadata.Command = ""
+	mockWasmCapabilities.Metadata.Runtime = RuntimeWasm
+	mockWasmCapabilities.Metadata.Module = "plugin.wasm"
+	mockWasmCapabilities.Metadata.Capabilities = &Capabilities{Network: true}
 
 	for i, item := range []struct {
 		pass bool
@@ -369,6 +477,27 @@ func TestValidatePluginData(t *testing.T) {
 		{false, mockPlugin("foo -bar ")}, // Test trailing chars
 		{false, mockPlugin("foo\nbar")},  // Test newline
 		{false, mockMissingMeta},         // Test if the metadata section missing
+		{true, mockDownloaderV2},
+		{false, mockDownloaderV3}, // Test unsupported downloader protocols
+		{true, mockLintRule},
+		{false, mockLintRuleSeverity}, // Test invalid lint rule severities
+		{false, mockLintRuleCommand},  // Test lint rules without a command
+		{true, mockWasm},
+		{false, mockWasmCommand},          // Test WebAssembly plugins with a command
+		{false, mockWasmModule},           // Test modules outside the plugin directory
+		{false, mockSubprocessModule},     // Test modules of subprocess plugins
+		{false, mockSubprocessRenderHook}, // Test render hooks of subprocess plugins
+		{false, mockRuntime},              // Test unsupported runtimes
+		{true, mockSignatures},
+		{false, mockSignaturesKind}, // Test unsupported signatures
+		{true, mockAPIVersions},
+		{false, mockAPIVersionsUnsupported}, // Test unsupported plugin API versions
+		{true, mockCredentials},
+		{false, mockCredentialsHosts}, // Test credential providers without hosts
+		{false, mockCredentialsAPI},   // Test credential providers without an API version
+		{true, mockCapabilities},
+		{false, mockCapabilitiesEnv},  // Test invalid env capabilities
+		{false, mockWasmCapabilities}, // Test capabilities of WebAssembly plugins
 	} {
 		err := validatePluginData(item.plug, fmt.Sprintf("test-%d", i))
 		if item.pass && err != nil {
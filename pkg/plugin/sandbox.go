@@ -0,0 +1,200 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin // import "helm.sh/helm/v3/pkg/plugin"
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Capabilities declare what the commands of a plugin access. A plugin
+// declaring its capabilities is run in a sandbox restricting it to them; a
+// plugin without capabilities has all the access of Helm, and is only
+// installed by 'helm plugin install --unsandboxed'.
+//
+// The sandbox limits what a plugin accesses by accident, it is not a security
+// boundary: the commands are given a private home and temporary directory,
+// and only the environment variables of Helm and of the capabilities. On
+// Linux, if the user namespaces are available, the commands are run in a
+// mount namespace where the home directory of the user is replaced by the
+// private home, and in a network namespace without any interface but the
+// loopback unless the plugin has the network capability. A warning is logged
+// when the filesystem or the network cannot be isolated, the rest of the
+// filesystem being then accessible outside of the home directory.
+type Capabilities struct {
+	// Network is whether the plugin accesses the network.
+	Network bool `json:"network,omitempty"`
+	// Filesystem are the paths under the home directory the plugin accesses,
+	// like "~/.aws" or "$XDG_CONFIG_HOME/gcloud". They are mounted into the
+	// private home of the plugin, or linked into it where the filesystem is
+	// not isolated. The paths outside of the home directory are accessible
+	// without being declared.
+	Filesystem []string `json:"filesystem,omitempty"`
+	// Env are the names of the environment variables the plugin reads,
+	// besides the variables of Helm. They may be patterns like "AWS_*".
+	Env []string `json:"env,omitempty"`
+}
+
+// String describes the capabilities, like
+// "network, filesystem [~/.aws], env [AWS_*]".
+func (c *Capabilities) String() string {
+	var parts []string
+	if c.Network {
+		parts = append(parts, "network")
+	}
+	if len(c.Filesystem) > 0 {
+		parts = append(parts, fmt.Sprintf("filesystem [%s]", strings.Join(c.Filesystem, ", ")))
+	}
+	if len(c.Env) > 0 {
+		parts = append(parts, fmt.Sprintf("env [%s]", strings.Join(c.Env, ", ")))
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// sandboxEnv are the environment variables passed to all the sandboxed
+// commands, besides the variables of Helm.
+var sandboxEnv = []string{"PATH", "LANG", "LC_*", "TERM", "TZ", "SYSTEMROOT", "COMSPEC", "PATHEXT"}
+
+// networkEnv are the environment variables passed to the sandboxed commands
+// with the network capability.
+var networkEnv = []string{"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy", "SSL_CERT_FILE", "SSL_CERT_DIR"}
+
+// Sandbox restricts cmd, a command of a plugin with the capabilities, to
+// them, as far as the sandbox allows. It must be called once the environment
+// of cmd is set, before cmd is started. The returned function removes the
+// private directories of cmd once it exited. It does nothing if the plugin
+// declares no capabilities.
+func Sandbox(cmd *exec.Cmd, name string, capabilities *Capabilities) (func(), error) {
+	if capabilities == nil {
+		return func() {}, nil
+	}
+	dir, err := os.MkdirTemp("", "helm-plugin-"+name+"-")
+	if err != nil {
+		return nil, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+	home, tmp := filepath.Join(dir, "home"), filepath.Join(dir, "tmp")
+	for _, d := range []string{home, tmp} {
+		if err := os.Mkdir(d, 0700); err != nil {
+			cleanup()
+			return nil, err
+		}
+	}
+
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	allowed := append(append([]string{"HELM_*"}, sandboxEnv...), capabilities.Env...)
+	if capabilities.Network {
+		allowed = append(allowed, networkEnv...)
+	}
+	var sandboxed []string
+	for _, kv := range env {
+		k, _, _ := strings.Cut(kv, "=")
+		if matchEnv(allowed, k) {
+			sandboxed = append(sandboxed, kv)
+		}
+	}
+	cmd.Env = append(sandboxed, "HOME="+home, "USERPROFILE="+home, "TMPDIR="+tmp, "TMP="+tmp, "TEMP="+tmp)
+
+	if !capabilities.Network {
+		if err := isolateNetwork(cmd); err != nil {
+			log.Printf("Warning: the network of plugin %q is not isolated: %s", name, err)
+		}
+	}
+	userHome, paths := homePaths(capabilities.Filesystem)
+	if userHome == "" {
+		return cleanup, nil
+	}
+	if err := isolateFilesystem(cmd, dir, userHome, paths); err != nil {
+		log.Printf("Warning: the filesystem of plugin %q is not isolated: %s", name, err)
+		if err := linkHomePaths(home, userHome, paths); err != nil {
+			cleanup()
+			return nil, errors.Wrapf(err, "plugin %q", name)
+		}
+	}
+	return cleanup, nil
+}
+
+// matchEnv returns whether the environment variable key matches one of the
+// patterns.
+func matchEnv(patterns []string, key string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// homePaths returns the home directory of the user, and the paths under it,
+// relative to it. It returns an empty home if it is unknown.
+func homePaths(paths []string) (string, []string) {
+	userHome, err := os.UserHomeDir()
+	if err != nil {
+		return "", nil
+	}
+	var rels []string
+	for _, p := range paths {
+		p = os.ExpandEnv(p)
+		if p == "~" || strings.HasPrefix(p, "~/") {
+			p = filepath.Join(userHome, p[1:])
+		}
+		if rel, ok := underHome(userHome, p); ok {
+			rels = append(rels, rel)
+		}
+	}
+	return userHome, rels
+}
+
+// underHome returns the path p relative to the home directory, and whether it
+// is under it.
+func underHome(userHome, p string) (string, bool) {
+	if !filepath.IsAbs(p) {
+		return "", false
+	}
+	rel, err := filepath.Rel(userHome, filepath.Clean(p))
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// linkHomePaths links the paths under the home directory into the private
+// home of a sandboxed command.
+func linkHomePaths(home, userHome string, paths []string) error {
+	for _, rel := range paths {
+		link := filepath.Join(home, rel)
+		if err := os.MkdirAll(filepath.Dir(link), 0700); err != nil {
+			return err
+		}
+		if err := os.Symlink(filepath.Join(userHome, rel), link); err != nil && !os.IsExist(err) {
+			return errors.Wrapf(err, "linking %s into the sandbox", filepath.Join(userHome, rel))
+		}
+	}
+	return nil
+}
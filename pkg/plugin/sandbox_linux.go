@@ -0,0 +1,268 @@
+//go:build linux
+
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin // import "helm.sh/helm/v3/pkg/plugin"
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+// The capabilities and the prctl options of Linux the sandbox uses.
+const (
+	capSetpcap              = 8
+	capSysAdmin             = 21
+	prCapbsetDrop           = 24
+	linuxCapabilityVersion3 = 0x20080522
+)
+
+// sandboxMountsEnv is the environment variable passing its mounts to Helm,
+// run in the namespaces of a sandboxed command to mount them before
+// executing the command.
+const sandboxMountsEnv = "_HELM_PLUGIN_SANDBOX_MOUNTS"
+
+var (
+	userNamespacesOnce      sync.Once
+	userNamespacesAvailable bool
+)
+
+func init() {
+	if mounts, ok := os.LookupEnv(sandboxMountsEnv); ok {
+		execSandboxed(mounts)
+	}
+}
+
+// namespaceAttr returns the attributes of the processes run in new user
+// namespaces, and in the other namespaces of the flags, the user and the group
+// of Helm mapped to themselves.
+func namespaceAttr(flags uintptr) *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{
+		Cloneflags:                 syscall.CLONE_NEWUSER | flags,
+		UidMappings:                []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}},
+		GidMappings:                []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}},
+		GidMappingsEnableSetgroups: false,
+	}
+}
+
+// unshare runs cmd in new user namespaces, and in the other namespaces of the
+// flags. It returns an error if the user namespaces are not available to Helm.
+// They are probed once, by running the shell in the namespaces.
+func unshare(cmd *exec.Cmd, flags uintptr) error {
+	userNamespacesOnce.Do(func() {
+		probe := exec.Command("/bin/sh", "-c", "exit 0")
+		probe.SysProcAttr = namespaceAttr(syscall.CLONE_NEWNET | syscall.CLONE_NEWNS)
+		userNamespacesAvailable = probe.Run() == nil
+	})
+	if !userNamespacesAvailable {
+		return errors.New("the user namespaces are not available")
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = namespaceAttr(flags)
+		return nil
+	}
+	attr := namespaceAttr(flags)
+	cmd.SysProcAttr.Cloneflags |= attr.Cloneflags
+	cmd.SysProcAttr.UidMappings = attr.UidMappings
+	cmd.SysProcAttr.GidMappings = attr.GidMappings
+	return nil
+}
+
+// isolateNetwork runs cmd in a new network namespace, without access to the
+// network.
+func isolateNetwork(cmd *exec.Cmd) error {
+	return unshare(cmd, syscall.CLONE_NEWNET)
+}
+
+// sandboxMounts are the mounts of a sandboxed command.
+type sandboxMounts struct {
+	// Path is the executable of the command.
+	Path string `json:"path"`
+	// Home is the home directory of the user, replaced by Private, the
+	// private home of the command.
+	Home    string `json:"home"`
+	Private string `json:"private"`
+	// Paths are the paths under the home directory, relative to it, mounted
+	// into the private home.
+	Paths []string `json:"paths"`
+}
+
+// isolateFilesystem runs cmd in a new mount namespace, where the home
+// directory of the user is replaced by the private home of the sandbox
+// directory dir. The paths under the home directory are mounted into the
+// private home, with the working directory and the executable of cmd and the
+// paths of the environment variables of Helm. The mounts are made by Helm,
+// executed in the namespaces of cmd with the mounts in its environment, which
+// then executes cmd.
+func isolateFilesystem(cmd *exec.Cmd, dir, userHome string, paths []string) error {
+	if _, ok := underHome(userHome, dir); ok || userHome == "/" {
+		return errors.Errorf("the temporary directory %s is under the home directory", dir)
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	wd := cmd.Dir
+	if wd == "" {
+		if wd, err = os.Getwd(); err != nil {
+			return err
+		}
+	}
+	mounts := sandboxMounts{Path: cmd.Path, Home: userHome, Private: filepath.Join(dir, "home"), Paths: paths}
+	others := []string{wd, cmd.Path}
+	for _, kv := range cmd.Env {
+		if k, v, _ := strings.Cut(kv, "="); strings.HasPrefix(k, "HELM_") {
+			others = append(others, v)
+		}
+	}
+	for _, p := range others {
+		if rel, ok := underHome(userHome, p); ok {
+			mounts.Paths = append(mounts.Paths, rel)
+		}
+	}
+	data, err := json.Marshal(mounts)
+	if err != nil {
+		return err
+	}
+	if err := unshare(cmd, syscall.CLONE_NEWNS); err != nil {
+		return err
+	}
+	// Helm mounts the paths with the capabilities of the namespaces, and
+	// drops them before executing cmd
+	cmd.SysProcAttr.AmbientCaps = []uintptr{capSetpcap, capSysAdmin}
+	cmd.Path = self
+	cmd.Env = append(cmd.Env, sandboxMountsEnv+"="+string(data))
+	return nil
+}
+
+// execSandboxed mounts the mounts of a sandboxed command, Helm being run in
+// its namespaces, then drops the capabilities of the namespaces and executes
+// the command. It exits Helm if the command cannot be executed.
+func execSandboxed(data string) {
+	// The capabilities are those of the thread executing the command
+	runtime.LockOSThread()
+	err := func() error {
+		var mounts sandboxMounts
+		if err := json.Unmarshal([]byte(data), &mounts); err != nil {
+			return err
+		}
+		if err := mounts.mount(); err != nil {
+			return err
+		}
+		if err := dropCapabilities(); err != nil {
+			return errors.Wrap(err, "dropping the capabilities")
+		}
+		var env []string
+		for _, kv := range os.Environ() {
+			if !strings.HasPrefix(kv, sandboxMountsEnv+"=") {
+				env = append(env, kv)
+			}
+		}
+		return syscall.Exec(mounts.Path, os.Args, env)
+	}()
+	fmt.Fprintf(os.Stderr, "Error: sandboxing the plugin: %s\n", err)
+	os.Exit(1)
+}
+
+// dropCapabilities drops the capability to mount from the bounding set of the
+// thread, and clears its inheritable and ambient capabilities, for the
+// executed command not to undo the mounts, even as root.
+func dropCapabilities() error {
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prCapbsetDrop, capSysAdmin, 0); errno != 0 {
+		return errno
+	}
+	header := struct {
+		version uint32
+		pid     int32
+	}{version: linuxCapabilityVersion3}
+	var data [2]struct{ effective, permitted, inheritable uint32 }
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_CAPGET, uintptr(unsafe.Pointer(&header)), uintptr(unsafe.Pointer(&data[0])), 0); errno != 0 {
+		return errno
+	}
+	// Clearing the inheritable capabilities clears the ambient ones
+	data[0].inheritable, data[1].inheritable = 0, 0
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_CAPSET, uintptr(unsafe.Pointer(&header)), uintptr(unsafe.Pointer(&data[0])), 0); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// mount mounts the paths into the private home, and the private home over
+// the home directory. The paths under another path are mounted with it.
+func (m *sandboxMounts) mount() error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return errors.Wrap(err, "making the mounts private")
+	}
+	sort.Strings(m.Paths)
+	var mounted []string
+	for _, rel := range m.Paths {
+		if len(mounted) > 0 {
+			if last := mounted[len(mounted)-1]; rel == last || strings.HasPrefix(rel, last+string(filepath.Separator)) {
+				continue
+			}
+		}
+		source, target := filepath.Join(m.Home, rel), filepath.Join(m.Private, rel)
+		fi, err := os.Stat(source)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if err := mountPoint(target, fi.IsDir()); err != nil {
+			return err
+		}
+		if err := syscall.Mount(source, target, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+			return errors.Wrapf(err, "mounting %s", source)
+		}
+		mounted = append(mounted, rel)
+	}
+	if err := syscall.Mount(m.Private, m.Home, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return errors.Wrapf(err, "mounting the private home over %s", m.Home)
+	}
+	// The working directory is resolved again, in the private home if it is
+	// under the home directory
+	return os.Chdir(wd)
+}
+
+// mountPoint creates the directory, or the empty file, target is mounted on.
+func mountPoint(target string, dir bool) error {
+	if dir {
+		return os.MkdirAll(target, 0700)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
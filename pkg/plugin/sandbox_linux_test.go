@@ -0,0 +1,105 @@
+//go:build linux
+
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin // import "helm.sh/helm/v3/pkg/plugin"
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSandboxNetwork(t *testing.T) {
+	interfaces := func(capabilities *Capabilities) []string {
+		prog := exec.Command("sh", "-c", "tail -n +3 /proc/net/dev | cut -d: -f1")
+		cleanup, err := Sandbox(prog, "network", capabilities)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer cleanup()
+		out, err := prog.Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Fields(string(out))
+	}
+
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+	isolated := interfaces(&Capabilities{})
+	if !userNamespacesAvailable {
+		if !strings.Contains(logged.String(), `Warning: the network of plugin "network" is not isolated`) {
+			t.Errorf("expected a warning, got %q", logged.String())
+		}
+		t.Skip("the user namespaces are not available")
+	}
+	if len(isolated) != 1 || isolated[0] != "lo" {
+		t.Errorf("expected the loopback interface only, got %v", isolated)
+	}
+	if connected := interfaces(&Capabilities{Network: true}); len(connected) < 1 {
+		t.Errorf("expected the interfaces of the host, got %v", connected)
+	}
+}
+
+func TestSandboxFilesystem(t *testing.T) {
+	home := t.TempDir()
+	for _, f := range []string{".aws/config", ".ssh/id_rsa", "plugins/sandboxed/plugin.yaml"} {
+		if err := os.MkdirAll(filepath.Join(home, filepath.Dir(f)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(home, f), []byte(f), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("HOME", home)
+	t.Setenv("HELM_PLUGIN_DIR", filepath.Join(home, "plugins", "sandboxed"))
+
+	read := func(f string) (string, error) {
+		prog := exec.Command("cat", filepath.Join(home, f))
+		cleanup, err := Sandbox(prog, "filesystem", &Capabilities{Filesystem: []string{"~/.aws"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer cleanup()
+		out, err := prog.Output()
+		return string(out), err
+	}
+
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+	for _, f := range []string{".aws/config", "plugins/sandboxed/plugin.yaml"} {
+		if out, err := read(f); err != nil || out != f {
+			t.Errorf("expected %s to be readable, got %q, %v", f, out, err)
+		}
+	}
+	_, err := read(".ssh/id_rsa")
+	if !userNamespacesAvailable {
+		if !strings.Contains(logged.String(), `Warning: the filesystem of plugin "filesystem" is not isolated`) {
+			t.Errorf("expected a warning, got %q", logged.String())
+		}
+		t.Skip("the user namespaces are not available")
+	}
+	if err == nil {
+		t.Error("expected the undeclared paths of the home directory to be hidden")
+	}
+}
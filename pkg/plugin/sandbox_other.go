@@ -0,0 +1,36 @@
+//go:build !linux
+
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin // import "helm.sh/helm/v3/pkg/plugin"
+
+import (
+	"os/exec"
+
+	"github.com/pkg/errors"
+)
+
+// isolateNetwork returns an error: the network of the commands is only
+// isolated on Linux.
+func isolateNetwork(_ *exec.Cmd) error {
+	return errors.New("the network is only isolated on Linux")
+}
+
+// isolateFilesystem returns an error: the filesystem of the commands is only
+// isolated on Linux.
+func isolateFilesystem(_ *exec.Cmd, _, _ string, _ []string) error {
+	return errors.New("the filesystem is only isolated on Linux")
+}
@@ -0,0 +1,99 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin // import "helm.sh/helm/v3/pkg/plugin"
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSandbox(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the sandboxed command is run with sh")
+	}
+	home := t.TempDir()
+	if err := os.Mkdir(filepath.Join(home, ".aws"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".aws", "config"), []byte("[default]"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", home)
+	t.Setenv("HELM_NAMESPACE", "default")
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("SECRET_TOKEN", "secret")
+
+	prog := exec.Command("sh", "-c", `env; test -f "$HOME/.aws/config" && echo found`)
+	cleanup, err := Sandbox(prog, "sandboxed", &Capabilities{Filesystem: []string{"~/.aws"}, Env: []string{"AWS_*"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := prog.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := string(out)
+	for _, expect := range []string{"HELM_NAMESPACE=default", "AWS_REGION=us-east-1", "found"} {
+		if !strings.Contains(env, expect) {
+			t.Errorf("expected %q in the sandbox, got:\n%s", expect, env)
+		}
+	}
+	if strings.Contains(env, "SECRET_TOKEN") {
+		t.Errorf("expected the undeclared variables to be removed, got:\n%s", env)
+	}
+	if strings.Contains(env, "HOME="+home+"\n") {
+		t.Errorf("expected a private home, got:\n%s", env)
+	}
+
+	var dir string
+	for _, kv := range prog.Env {
+		if strings.HasPrefix(kv, "TMPDIR=") {
+			dir = filepath.Dir(strings.TrimPrefix(kv, "TMPDIR="))
+		}
+	}
+	cleanup()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("expected the sandbox %q to be removed, got %v", dir, err)
+	}
+
+	// The plugins without capabilities are not sandboxed
+	prog = exec.Command("true")
+	if _, err := Sandbox(prog, "unsandboxed", nil); err != nil {
+		t.Fatal(err)
+	}
+	if prog.Env != nil || prog.SysProcAttr != nil {
+		t.Errorf("expected the command to be unchanged, got %v", prog.Env)
+	}
+}
+
+func TestCapabilitiesString(t *testing.T) {
+	for _, tt := range []struct {
+		capabilities Capabilities
+		expect       string
+	}{
+		{Capabilities{}, "none"},
+		{Capabilities{Network: true}, "network"},
+		{Capabilities{Network: true, Filesystem: []string{"~/.aws", "/etc/ssl"}, Env: []string{"AWS_*"}}, "network, filesystem [~/.aws, /etc/ssl], env [AWS_*]"},
+	} {
+		if got := tt.capabilities.String(); got != tt.expect {
+			t.Errorf("expected %q, got %q", tt.expect, got)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk // import "helm.sh/helm/v3/pkg/plugin/sdk"
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// CredentialsResponse is the response of a credential provider, written as a
+// JSON object on its standard output. A response without a username and a
+// password tells that the provider has no credentials for the host.
+type CredentialsResponse struct {
+	// Username is the username of the credentials. An empty username with a
+	// password is a token.
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// ExpiresAt is when the credentials expire. Helm caches the credentials
+	// until shortly before then, or for the run of Helm only if it is not
+	// set.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// WriteCredentials writes the response of a credential provider to w.
+func WriteCredentials(w io.Writer, resp *CredentialsResponse) error {
+	return json.NewEncoder(w).Encode(resp)
+}
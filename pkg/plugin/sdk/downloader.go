@@ -0,0 +1,65 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk // import "helm.sh/helm/v3/pkg/plugin/sdk"
+
+// The types of the messages of the version 2 of the downloader protocol,
+// exchanged as JSON objects, one per line, on the standard input and output
+// of the downloader.
+const (
+	// DownloaderGet is the download written by Helm.
+	DownloaderGet = "get"
+	// DownloaderCredentials requests the credentials for the URL of the
+	// message. Helm answers with a DownloaderCredentials message, empty if
+	// the credentials of the download must not be passed to the URL.
+	DownloaderCredentials = "credentials"
+	// DownloaderRedirect ends the download, Helm getting the URL of the
+	// message instead, with the getter of its scheme.
+	DownloaderRedirect = "redirect"
+	// DownloaderData is followed by the number of raw bytes of the message
+	// size, which are the next part of the body of the download.
+	DownloaderData = "data"
+	// DownloaderDone ends the download. Helm verifies the body against the
+	// digest of the message and the digest of the DownloaderGet message, if
+	// any.
+	DownloaderDone = "done"
+	// DownloaderError ends the download with the error of the message.
+	DownloaderError = "error"
+)
+
+// DownloaderMessage is a message of the version 2 of the downloader protocol.
+type DownloaderMessage struct {
+	Type string `json:"type"`
+	URL  string `json:"url,omitempty"`
+
+	// Fields of the DownloaderGet message.
+	CertFile              string            `json:"certFile,omitempty"`
+	KeyFile               string            `json:"keyFile,omitempty"`
+	CAFile                string            `json:"caFile,omitempty"`
+	InsecureSkipTLSVerify bool              `json:"insecureSkipTLSVerify,omitempty"`
+	Headers               map[string]string `json:"headers,omitempty"`
+	Version               string            `json:"version,omitempty"`
+
+	// Fields of the DownloaderCredentials message answered by Helm.
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+
+	// Size is the number of bytes following a DownloaderData message.
+	Size int64 `json:"size,omitempty"`
+	// Digest is the digest of the body, like "sha256:<hex>".
+	Digest string `json:"digest,omitempty"`
+	// Message is the error of a DownloaderError message.
+	Message string `json:"message,omitempty"`
+}
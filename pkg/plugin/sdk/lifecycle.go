@@ -0,0 +1,63 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk // import "helm.sh/helm/v3/pkg/plugin/sdk"
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// LifecycleEvent is an event of the lifecycle of a release, like
+// "pre-install", read by the lifecycle hooks of a plugin on their standard
+// input. A lifecycle hook failing on a pre event aborts the operation.
+type LifecycleEvent struct {
+	// Event is the event, like "pre-install" or "post-uninstall".
+	Event string `json:"event"`
+	// Release is the release of the operation.
+	Release Release `json:"release"`
+	// Chart is the chart of the release.
+	Chart Chart `json:"chart"`
+	// Values are the values of the release supplied by the user.
+	Values map[string]interface{} `json:"values,omitempty"`
+	// Error is the error of the failed operation of a post event.
+	Error string `json:"error,omitempty"`
+}
+
+// Release describes a release.
+type Release struct {
+	Name        string `json:"name"`
+	Namespace   string `json:"namespace"`
+	Revision    int    `json:"revision"`
+	Status      string `json:"status"`
+	Description string `json:"description,omitempty"`
+}
+
+// Chart describes the chart of a release.
+type Chart struct {
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	AppVersion string `json:"appVersion,omitempty"`
+}
+
+// ReadLifecycleEvent reads the event of a lifecycle hook from r, usually the
+// standard input.
+func ReadLifecycleEvent(r io.Reader) (*LifecycleEvent, error) {
+	e := &LifecycleEvent{}
+	if err := json.NewDecoder(r).Decode(e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
@@ -0,0 +1,78 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk // import "helm.sh/helm/v3/pkg/plugin/sdk"
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// The versions of the post-renderer protocol.
+const (
+	// PostRenderProtocolV1 is the version 1 of the post-renderer protocol:
+	// the post-renderer reads the rendered manifests as a YAML stream on its
+	// standard input, and writes the post-rendered ones as a YAML stream on
+	// its standard output.
+	PostRenderProtocolV1 = 1
+	// PostRenderProtocolV2 is the version 2 of the post-renderer protocol:
+	// the post-renderer reads the rendered manifests as PostRenderManifest
+	// JSON objects, one per line, on its standard input, and writes the
+	// post-rendered ones the same way on its standard output. It may reject
+	// a manifest by writing it with an Error.
+	PostRenderProtocolV2 = 2
+)
+
+// PostRenderManifest is a manifest of the version 2 of the post-renderer
+// protocol.
+type PostRenderManifest struct {
+	// Source is the path to the template of the manifest, like
+	// "mychart/templates/deployment.yaml". It is empty for the manifests
+	// added by the post-renderer.
+	Source string `json:"source,omitempty"`
+	// Object is the decoded manifest.
+	Object map[string]interface{} `json:"object,omitempty"`
+	// Error is the error of the manifest rejected by the post-renderer,
+	// which fails the operation.
+	Error string `json:"error,omitempty"`
+}
+
+// ReadPostRenderManifests reads the manifests of the version 2 of the
+// post-renderer protocol from r, usually the standard input.
+func ReadPostRenderManifests(r io.Reader) ([]PostRenderManifest, error) {
+	var manifests []PostRenderManifest
+	dec := json.NewDecoder(r)
+	for {
+		var m PostRenderManifest
+		if err := dec.Decode(&m); err == io.EOF {
+			return manifests, nil
+		} else if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, m)
+	}
+}
+
+// WritePostRenderManifests writes the manifests of the version 2 of the
+// post-renderer protocol to w, usually the standard output.
+func WritePostRenderManifests(w io.Writer, manifests []PostRenderManifest) error {
+	enc := json.NewEncoder(w)
+	for _, m := range manifests {
+		if err := enc.Encode(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk // import "helm.sh/helm/v3/pkg/plugin/sdk"
+
+// The kinds of the commands run by Helm.
+const (
+	// KindCommand is the command of a plugin, run as 'helm <plugin>'.
+	KindCommand = "command"
+	// KindHook is a hook of a plugin, run on an event like an install.
+	KindHook = "hook"
+	// KindDownloader is the command of a downloader of a plugin.
+	KindDownloader = "downloader"
+	// KindPostRenderer is a post-renderer of the rendered manifests.
+	KindPostRenderer = "postrenderer"
+	// KindCredentialProvider is the command of a credential provider of a
+	// plugin.
+	KindCredentialProvider = "credentialprovider"
+)
+
+// Request is the request of a run of a command by Helm.
+type Request struct {
+	// APIVersion is the version of the plugin API of the request.
+	APIVersion string `json:"apiVersion"`
+	// Kind is the kind of the command, like KindHook. Only the field of the
+	// kind is set, like Hook.
+	Kind string `json:"kind"`
+	// Plugin is the plugin of the command. It is empty for the
+	// post-renderers.
+	Plugin Plugin `json:"plugin"`
+	// Settings are the settings of Helm.
+	Settings Settings `json:"settings"`
+
+	Command     *CommandRequest     `json:"command,omitempty"`
+	Hook        *HookRequest        `json:"hook,omitempty"`
+	Download    *DownloadRequest    `json:"download,omitempty"`
+	PostRender  *PostRenderRequest  `json:"postRender,omitempty"`
+	Credentials *CredentialsRequest `json:"credentials,omitempty"`
+}
+
+// Plugin describes the plugin of a command.
+type Plugin struct {
+	// Name is the name of the plugin.
+	Name string `json:"name"`
+	// Version is the version of the plugin.
+	Version string `json:"version"`
+	// Dir is the directory of the plugin.
+	Dir string `json:"dir"`
+}
+
+// Settings are the settings of Helm.
+type Settings struct {
+	// Bin is the path to the Helm binary.
+	Bin string `json:"bin"`
+	// Debug is whether the debug output is enabled.
+	Debug bool `json:"debug"`
+	// Namespace is the namespace of the releases.
+	Namespace string `json:"namespace"`
+	// KubeConfig is the path to the kubeconfig file, if set.
+	KubeConfig string `json:"kubeConfig,omitempty"`
+	// KubeContext is the name of the kubeconfig context, if set.
+	KubeContext string `json:"kubeContext,omitempty"`
+	// CacheHome, ConfigHome and DataHome are the base directories of the
+	// cached files, the configuration and the data of Helm.
+	CacheHome  string `json:"cacheHome"`
+	ConfigHome string `json:"configHome"`
+	DataHome   string `json:"dataHome"`
+	// PluginsDirectory is the list of the directories of the plugins.
+	PluginsDirectory string `json:"pluginsDirectory"`
+	// RegistryConfig is the path to the registry config file.
+	RegistryConfig string `json:"registryConfig"`
+	// RepositoryConfig is the path to the repositories file.
+	RepositoryConfig string `json:"repositoryConfig"`
+	// RepositoryCache is the path to the repository cache directory.
+	RepositoryCache string `json:"repositoryCache"`
+}
+
+// CommandRequest is the request of KindCommand.
+type CommandRequest struct {
+	// Args are the arguments of the command, after the plugin name.
+	Args []string `json:"args"`
+}
+
+// HookRequest is the request of KindHook. The lifecycle hooks, run on the
+// events of the releases like "pre-install", read a LifecycleEvent on their
+// standard input.
+type HookRequest struct {
+	// Event is the event of the hook, like "install".
+	Event string `json:"event"`
+}
+
+// DownloadRequest is the request of KindDownloader.
+type DownloadRequest struct {
+	// ProtocolVersion is the version of the downloader protocol.
+	ProtocolVersion int `json:"protocolVersion"`
+	// URL is the URL to download.
+	URL string `json:"url"`
+	// CertFile, KeyFile and CAFile are the TLS files of the download.
+	CertFile string `json:"certFile,omitempty"`
+	KeyFile  string `json:"keyFile,omitempty"`
+	CAFile   string `json:"caFile,omitempty"`
+	// Username and Password are the credentials of the download. They are
+	// only set for the version 1 of the downloader protocol: the downloaders
+	// of the version 2 request them with a DownloaderCredentials message.
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// PassCredentialsAll is whether the credentials are passed to all the
+	// domains.
+	PassCredentialsAll bool `json:"passCredentialsAll,omitempty"`
+}
+
+// PostRenderRequest is the request of KindPostRenderer. The post-renderer
+// reads the rendered manifests on its standard input, and writes the
+// post-rendered ones on its standard output, in the format of the version of
+// the post-renderer protocol.
+type PostRenderRequest struct {
+	// Args are the arguments of the post-renderer.
+	Args []string `json:"args"`
+	// ProtocolVersion is the version of the post-renderer protocol, like
+	// PostRenderProtocolV2. It is unset by the versions of Helm supporting
+	// the version 1 only.
+	ProtocolVersion int `json:"protocolVersion,omitempty"`
+	// Release and Chart describe the release of the rendered manifests, if
+	// they are rendered for a release.
+	Release *Release `json:"release,omitempty"`
+	Chart   *Chart   `json:"chart,omitempty"`
+}
+
+// CredentialsRequest is the request of KindCredentialProvider. The provider
+// writes a CredentialsResponse on its standard output.
+type CredentialsRequest struct {
+	// Host is the host of the repository or the registry, with its port if
+	// it has one, like "registry.example.com:5000".
+	Host string `json:"host"`
+}
@@ -0,0 +1,125 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package sdk is the Go SDK of the Helm plugins.
+
+It defines the versioned plugin API between Helm and the commands of the
+plugins: the commands themselves, their hooks, their downloaders, and the
+post-renderers. The types of a version of the API are stable: fields may be
+added, but they are never removed or changed.
+
+A plugin declares the versions of the API it supports in its plugin.yaml:
+
+	apiVersions: ["v1"]
+
+Helm negotiates the newest version it supports too, and passes the request of
+each run of a command in its environment. The command reads it with
+ReadRequest, instead of parsing the HELM_* environment variables:
+
+	req, err := sdk.ReadRequest()
+	if err != nil {
+		// Run by a Helm without the plugin API, or an unsupported version
+	}
+	fmt.Println(req.Settings.Namespace)
+
+The post-renderers have no plugin.yaml: Helm passes them the request of the
+newest version of the API it supports. The user selects the version of the
+post-renderer protocol of a post-renderer with --post-renderer-protocol; the
+version 2 exchanges PostRenderManifest objects instead of YAML, and the
+request describes the release of the manifests.
+*/
+package sdk // import "helm.sh/helm/v3/pkg/plugin/sdk"
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// APIVersionV1 is the version 1 of the plugin API.
+const APIVersionV1 = "v1"
+
+// SupportedAPIVersions are the versions of the plugin API supported by this
+// SDK, the newest first.
+var SupportedAPIVersions = []string{APIVersionV1}
+
+// The environment variables of the plugin API.
+const (
+	// EnvAPIVersion is the negotiated version of the plugin API.
+	EnvAPIVersion = "HELM_PLUGIN_API_VERSION"
+	// EnvRequest is the request, encoded in JSON.
+	EnvRequest = "HELM_PLUGIN_REQUEST"
+)
+
+// ErrNoRequest indicates that a command was run without a request, by a
+// version of Helm without the plugin API, or for a plugin declaring no version
+// of the API.
+var ErrNoRequest = errors.New("no plugin API request in the environment")
+
+// IsSupported returns whether the version of the plugin API is supported.
+func IsSupported(version string) bool {
+	for _, v := range SupportedAPIVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// Negotiate returns the newest version of the plugin API supported both by
+// this SDK and by a plugin declaring the versions.
+func Negotiate(versions []string) (string, error) {
+	for _, v := range SupportedAPIVersions {
+		for _, pv := range versions {
+			if v == pv {
+				return v, nil
+			}
+		}
+	}
+	return "", errors.Errorf("none of the plugin API versions %v is supported, only %v", versions, SupportedAPIVersions)
+}
+
+// Env returns the environment variables passing the request to a command, as
+// "key=value" strings.
+func (r *Request) Env() ([]string, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode the plugin API request")
+	}
+	return []string{EnvAPIVersion + "=" + r.APIVersion, EnvRequest + "=" + string(data)}, nil
+}
+
+// ParseRequest decodes a request, checking its version is supported.
+func ParseRequest(data []byte) (*Request, error) {
+	req := &Request{}
+	if err := json.Unmarshal(data, req); err != nil {
+		return nil, errors.Wrap(err, "invalid plugin API request")
+	}
+	if !IsSupported(req.APIVersion) {
+		return nil, errors.Errorf("unsupported plugin API version %q", req.APIVersion)
+	}
+	return req, nil
+}
+
+// ReadRequest reads the request of the command from its environment.
+func ReadRequest() (*Request, error) {
+	data, ok := os.LookupEnv(EnvRequest)
+	if !ok || data == "" {
+		return nil, ErrNoRequest
+	}
+	return ParseRequest([]byte(data))
+}
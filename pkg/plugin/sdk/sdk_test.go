@@ -0,0 +1,105 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk // import "helm.sh/helm/v3/pkg/plugin/sdk"
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	for _, tt := range []struct {
+		versions []string
+		expect   string
+	}{
+		{[]string{"v1"}, APIVersionV1},
+		{[]string{"v99", "v1"}, APIVersionV1},
+		{[]string{"v99"}, ""},
+		{nil, ""},
+	} {
+		v, err := Negotiate(tt.versions)
+		if v != tt.expect {
+			t.Errorf("%v: expected version %q, got %q", tt.versions, tt.expect, v)
+		}
+		if (err == nil) != (tt.expect != "") {
+			t.Errorf("%v: unexpected error %v", tt.versions, err)
+		}
+	}
+}
+
+func TestReadRequest(t *testing.T) {
+	t.Setenv(EnvRequest, "")
+	if _, err := ReadRequest(); err != ErrNoRequest {
+		t.Fatalf("expected ErrNoRequest, got %v", err)
+	}
+
+	req := &Request{
+		APIVersion: APIVersionV1,
+		Kind:       KindHook,
+		Plugin:     Plugin{Name: "myplugin", Version: "1.0.0", Dir: "/plugins/myplugin"},
+		Settings:   Settings{Namespace: "test"},
+		Hook:       &HookRequest{Event: "install"},
+	}
+	env, err := req.Env()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(env) != 2 || env[0] != EnvAPIVersion+"="+APIVersionV1 {
+		t.Fatalf("unexpected environment %v", env)
+	}
+	t.Setenv(EnvRequest, strings.TrimPrefix(env[1], EnvRequest+"="))
+	got, err := ReadRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, req) {
+		t.Errorf("expected %+v, got %+v", req, got)
+	}
+}
+
+func TestParseRequestUnsupported(t *testing.T) {
+	if _, err := ParseRequest([]byte(`{"apiVersion": "v99", "kind": "command"}`)); err == nil {
+		t.Error("expected an unsupported version to fail")
+	}
+	if _, err := ParseRequest([]byte(`{`)); err == nil {
+		t.Error("expected an invalid request to fail")
+	}
+}
+
+func TestPostRenderManifests(t *testing.T) {
+	manifests := []PostRenderManifest{
+		{Source: "mychart/templates/cm.yaml", Object: map[string]interface{}{"kind": "ConfigMap"}},
+		{Object: map[string]interface{}{"kind": "Secret"}, Error: "secrets are not allowed"},
+	}
+	var buf strings.Builder
+	if err := WritePostRenderManifests(&buf, manifests); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(buf.String(), "\n") != 2 {
+		t.Errorf("expected a manifest per line, got:\n%s", buf.String())
+	}
+	got, err := ReadPostRenderManifests(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, manifests) {
+		t.Errorf("expected %+v, got %+v", manifests, got)
+	}
+	if _, err := ReadPostRenderManifests(strings.NewReader("{")); err == nil {
+		t.Error("expected invalid manifests to fail")
+	}
+}
@@ -0,0 +1,15 @@
+;; The source of plugin.wasm: a WASI command printing the settings of the
+;; host, then exiting with the code 3.
+(module
+  (import "wasi_snapshot_preview1" "fd_write" (func $fd_write (param i32 i32 i32 i32) (result i32)))
+  (import "wasi_snapshot_preview1" "proc_exit" (func $proc_exit (param i32)))
+  (import "helm" "settings" (func $settings (param i32 i32 i32 i32) (result i32)))
+  (memory (export "memory") 1)
+  (func (export "_start") (local $n i32)
+    ;; the response is written at 1024, in a buffer of 4096 bytes
+    (local.set $n (call $settings (i32.const 0) (i32.const 0) (i32.const 1024) (i32.const 4096)))
+    ;; the iovec of the response is at 16
+    (i32.store (i32.const 16) (i32.const 1024))
+    (i32.store (i32.const 20) (local.get $n))
+    (drop (call $fd_write (i32.const 1) (i32.const 16) (i32.const 1) (i32.const 32)))
+    (call $proc_exit (i32.const 3))))
@@ -0,0 +1,188 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin // import "helm.sh/helm/v3/pkg/plugin"
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// RuntimeSubprocess runs the command of a plugin as a subprocess, the
+	// default runtime.
+	RuntimeSubprocess = "subprocess"
+	// RuntimeWasm runs the WebAssembly module of a plugin, portable across
+	// the operating systems and architectures, in a sandbox.
+	RuntimeWasm = "wasm"
+)
+
+// HostModule is the name of the module of the host functions imported by the
+// WebAssembly modules of the plugins.
+//
+// The host functions take a JSON request and return a JSON response, both in
+// the memory of the module. They are dispatched by CallHost.
+//
+// Their parameters are the offset and the length of the request in the memory
+// of the module, then the offset and the capacity of the buffer of the
+// response, all i32. They return the length of the response, an i32, which is
+// written in the buffer if it fits; the module calls them again with a large
+// enough buffer otherwise. A failed call returns -1 minus the length of its
+// error message, which is written like a response.
+const HostModule = "helm"
+
+// The host functions of HostModule.
+const (
+	// HostSettings returns the settings of Helm, like the environment of the
+	// subprocess plugins: {"HELM_NAMESPACE": "default", ...}.
+	HostSettings = "settings"
+	// HostRepositories returns the chart repositories:
+	// [{"name": "stable", "url": "https://charts.example.com"}].
+	HostRepositories = "repositories"
+	// HostRegistryCredentials returns the credentials of the registry of the
+	// request {"registry": "ghcr.io"}: {"username": "...", "password": "..."}.
+	// An empty username with a password is an identity token.
+	HostRegistryCredentials = "registry_credentials"
+)
+
+// Host is the host API of the WebAssembly plugins. It is the only access of
+// the plugins to Helm.
+type Host interface {
+	// Settings returns the settings of Helm, by environment variable.
+	Settings() map[string]string
+	// Repositories returns the chart repositories.
+	Repositories() ([]Repository, error)
+	// RegistryCredentials returns the credentials of the registry.
+	RegistryCredentials(registry string) (Credentials, error)
+}
+
+// Repository is a chart repository of the host API.
+type Repository struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// Credentials are the credentials of a registry of the host API.
+type Credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// CallHost calls the function of the host API with the JSON request, returning
+// its JSON response.
+func CallHost(host Host, function string, request []byte) ([]byte, error) {
+	switch function {
+	case HostSettings:
+		return json.Marshal(host.Settings())
+	case HostRepositories:
+		repos, err := host.Repositories()
+		if err != nil {
+			return nil, err
+		}
+		if repos == nil {
+			repos = []Repository{}
+		}
+		return json.Marshal(repos)
+	case HostRegistryCredentials:
+		var req struct {
+			Registry string `json:"registry"`
+		}
+		if err := json.Unmarshal(request, &req); err != nil {
+			return nil, errors.Wrapf(err, "invalid request of host function %q", function)
+		}
+		if req.Registry == "" {
+			return nil, errors.Errorf("no registry in the request of host function %q", function)
+		}
+		creds, err := host.RegistryCredentials(req.Registry)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(creds)
+	}
+	return nil, errors.Errorf("unknown host function %q", function)
+}
+
+// WasmConfig configures a run of the WebAssembly module of a plugin.
+type WasmConfig struct {
+	// Name is the name of the plugin, the first argument of the module.
+	Name string
+	// Args are the arguments of the module, after the name.
+	Args []string
+	// Stdin, Stdout and Stderr are the standard streams of the module.
+	Stdin          io.Reader
+	Stdout, Stderr io.Writer
+	// Host is the host API of the module.
+	Host Host
+}
+
+// WasmRuntime runs the WebAssembly modules of the plugins.
+//
+// The modules are sandboxed: they must only be given their arguments, their
+// standard streams and the host functions of HostModule, and no access to the
+// filesystem, the network, the environment or the clock of the host.
+type WasmRuntime interface {
+	// Run runs the WASI command module, returning its exit code.
+	Run(ctx context.Context, module []byte, config WasmConfig) (int, error)
+}
+
+var (
+	wasmRuntimeMu sync.Mutex
+	wasmRuntime   WasmRuntime = wazeroRuntime{}
+)
+
+// RegisterWasmRuntime registers the runtime of the plugins of RuntimeWasm,
+// replacing the default runtime based on wazero. Registering nil restores the
+// default runtime.
+func RegisterWasmRuntime(r WasmRuntime) {
+	wasmRuntimeMu.Lock()
+	defer wasmRuntimeMu.Unlock()
+	if r == nil {
+		r = wazeroRuntime{}
+	}
+	wasmRuntime = r
+}
+
+// IsWasm returns whether the plugin is a WebAssembly module.
+func (p *Plugin) IsWasm() bool {
+	return p.Metadata.Runtime == RuntimeWasm
+}
+
+// ModulePath returns the path to the WebAssembly module of the plugin.
+func (p *Plugin) ModulePath() string {
+	return filepath.Join(p.Dir, filepath.FromSlash(p.Metadata.Module))
+}
+
+// RunWasm runs the WebAssembly module of the plugin with the registered
+// runtime, returning its exit code.
+func (p *Plugin) RunWasm(ctx context.Context, config WasmConfig) (int, error) {
+	wasmRuntimeMu.Lock()
+	r := wasmRuntime
+	wasmRuntimeMu.Unlock()
+	module, err := os.ReadFile(p.ModulePath())
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to read the module of plugin %q", p.Metadata.Name)
+	}
+	config.Name = p.Metadata.Name
+	if p.Metadata.IgnoreFlags {
+		config.Args = nil
+	}
+	return r.Run(ctx, module, config)
+}
@@ -0,0 +1,136 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin // import "helm.sh/helm/v3/pkg/plugin"
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+)
+
+type mockHost struct{}
+
+func (mockHost) Settings() map[string]string {
+	return map[string]string{"HELM_NAMESPACE": "default"}
+}
+
+func (mockHost) Repositories() ([]Repository, error) {
+	return []Repository{{Name: "stable", URL: "https://charts.example.com"}}, nil
+}
+
+func (mockHost) RegistryCredentials(registry string) (Credentials, error) {
+	return Credentials{Username: "user", Password: "pass@" + registry}, nil
+}
+
+// mockWasmRuntime records the module and the configuration of its runs.
+type mockWasmRuntime struct {
+	module []byte
+	config WasmConfig
+}
+
+func (r *mockWasmRuntime) Run(_ context.Context, module []byte, config WasmConfig) (int, error) {
+	r.module = module
+	r.config = config
+	response, err := CallHost(config.Host, HostSettings, nil)
+	if err != nil {
+		return 0, err
+	}
+	config.Stdout.Write(response)
+	return 3, nil
+}
+
+func TestCallHost(t *testing.T) {
+	for _, tt := range []struct {
+		function, request, response string
+	}{
+		{HostSettings, "", `{"HELM_NAMESPACE":"default"}`},
+		{HostRepositories, "", `[{"name":"stable","url":"https://charts.example.com"}]`},
+		{HostRegistryCredentials, `{"registry":"ghcr.io"}`, `{"username":"user","password":"pass@ghcr.io"}`},
+	} {
+		response, err := CallHost(mockHost{}, tt.function, []byte(tt.request))
+		if err != nil {
+			t.Errorf("%s: %s", tt.function, err)
+		} else if string(response) != tt.response {
+			t.Errorf("%s: expected %s, got %s", tt.function, tt.response, response)
+		}
+	}
+
+	if _, err := CallHost(mockHost{}, HostRegistryCredentials, []byte(`{}`)); err == nil {
+		t.Error("expected an error without a registry")
+	}
+	if _, err := CallHost(mockHost{}, "exec", nil); err == nil {
+		t.Error("expected an error calling an unknown function")
+	}
+}
+
+func TestRunWasm(t *testing.T) {
+	plug, err := LoadDir("testdata/plugdir/wasm/hello-wasm")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer RegisterWasmRuntime(nil)
+	r := &mockWasmRuntime{}
+	RegisterWasmRuntime(r)
+	var out bytes.Buffer
+	code, err := plug.RunWasm(context.Background(), WasmConfig{Args: []string{"--flag"}, Stdout: &out, Host: mockHost{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != 3 {
+		t.Errorf("expected the exit code 3, got %d", code)
+	}
+	if !bytes.HasPrefix(r.module, []byte("\x00asm")) {
+		t.Errorf("expected the module of the plugin, got %q", r.module)
+	}
+	if r.config.Name != "hello-wasm" || !reflect.DeepEqual(r.config.Args, []string{"--flag"}) {
+		t.Errorf("unexpected name %q and arguments %v", r.config.Name, r.config.Args)
+	}
+	if out.String() != `{"HELM_NAMESPACE":"default"}` {
+		t.Errorf("expected the settings of the host, got %q", out.String())
+	}
+}
+
+func TestRunWasmModule(t *testing.T) {
+	plug, err := LoadDir("testdata/plugdir/wasm/hello-wasm")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The module of the plugin prints the settings of the host with the
+	// default runtime, see plugin.wat.
+	var out bytes.Buffer
+	code, err := plug.RunWasm(context.Background(), WasmConfig{Stdout: &out, Host: mockHost{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != 3 {
+		t.Errorf("expected the exit code 3, got %d", code)
+	}
+	if out.String() != `{"HELM_NAMESPACE":"default"}` {
+		t.Errorf("expected the settings of the host, got %q", out.String())
+	}
+
+	// Without a host API, the host function fails and nothing is printed
+	out.Reset()
+	if _, err := plug.RunWasm(context.Background(), WasmConfig{Stdout: &out}); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("expected no output without a host API, got %q", out.String())
+	}
+}
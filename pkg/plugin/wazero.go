@@ -0,0 +1,100 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin // import "helm.sh/helm/v3/pkg/plugin"
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// wazeroRuntime is the default WasmRuntime, running the modules with wazero,
+// a WebAssembly engine without dependencies on the host.
+//
+// The modules are given no filesystem and no environment, and the clocks and
+// the random source of WASI are not the ones of the host.
+type wazeroRuntime struct{}
+
+func (wazeroRuntime) Run(ctx context.Context, module []byte, config WasmConfig) (int, error) {
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true))
+	defer r.Close(ctx)
+
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, r); err != nil {
+		return 0, err
+	}
+	host := r.NewHostModuleBuilder(HostModule)
+	for _, function := range []string{HostSettings, HostRepositories, HostRegistryCredentials} {
+		host.NewFunctionBuilder().
+			WithGoModuleFunction(hostFunction(config.Host, function),
+				[]api.ValueType{api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32, api.ValueTypeI32},
+				[]api.ValueType{api.ValueTypeI32}).
+			Export(function)
+	}
+	if _, err := host.Instantiate(ctx); err != nil {
+		return 0, err
+	}
+
+	mc := wazero.NewModuleConfig().WithArgs(append([]string{config.Name}, config.Args...)...)
+	if config.Stdin != nil {
+		mc = mc.WithStdin(config.Stdin)
+	}
+	if config.Stdout != nil {
+		mc = mc.WithStdout(config.Stdout)
+	}
+	if config.Stderr != nil {
+		mc = mc.WithStderr(config.Stderr)
+	}
+	_, err := r.InstantiateWithConfig(ctx, module, mc)
+	var exit *sys.ExitError
+	if errors.As(err, &exit) {
+		return int(exit.ExitCode()), nil
+	}
+	return 0, err
+}
+
+// hostFunction returns the host function of HostModule calling function of
+// the host API, with the calling convention documented by HostModule.
+func hostFunction(host Host, function string) api.GoModuleFunc {
+	return func(_ context.Context, mod api.Module, stack []uint64) {
+		reqOffset, reqLen := api.DecodeU32(stack[0]), api.DecodeU32(stack[1])
+		respOffset, respCap := api.DecodeU32(stack[2]), api.DecodeU32(stack[3])
+
+		request, ok := mod.Memory().Read(reqOffset, reqLen)
+		if !ok {
+			panic(errors.Errorf("request of host function %q out of the memory", function))
+		}
+		var response []byte
+		err := errors.New("the host API is not available")
+		if host != nil {
+			response, err = CallHost(host, function, request)
+		}
+		if err != nil {
+			response = []byte(err.Error())
+		}
+		if uint32(len(response)) <= respCap && !mod.Memory().Write(respOffset, response) {
+			panic(errors.Errorf("response of host function %q out of the memory", function))
+		}
+		n := int32(len(response))
+		if err != nil {
+			n = -1 - n
+		}
+		stack[0] = api.EncodeI32(n)
+	}
+}
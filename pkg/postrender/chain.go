@@ -0,0 +1,52 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postrender
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// Chain is a PostRenderer running post-renderers in order, each one on the
+// output of the previous one.
+type Chain []PostRenderer
+
+// Run runs the post-renderers of the chain, and returns the output of the
+// last one. The error of a post-renderer is attributed to its stage, and to
+// its name if it implements fmt.Stringer.
+func (c Chain) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	return c.RunWithMetadata(renderedManifests, Metadata{})
+}
+
+// RunWithMetadata is Run, passing the metadata to the post-renderers
+// implementing MetadataPostRenderer.
+func (c Chain) RunWithMetadata(renderedManifests *bytes.Buffer, metadata Metadata) (*bytes.Buffer, error) {
+	out := renderedManifests
+	for i, pr := range c {
+		var err error
+		if out, err = Run(pr, out, metadata); err != nil {
+			stage := fmt.Sprintf("post-renderer %d of %d", i+1, len(c))
+			if s, ok := pr.(fmt.Stringer); ok {
+				stage += fmt.Sprintf(" (%s)", s)
+			}
+			return nil, errors.Wrap(err, stage)
+		}
+	}
+	return out, nil
+}
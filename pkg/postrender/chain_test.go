@@ -0,0 +1,61 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postrender
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type replaceRender struct {
+	old, new string
+}
+
+func (r *replaceRender) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	return bytes.NewBufferString(strings.ReplaceAll(renderedManifests.String(), r.old, r.new)), nil
+}
+
+type failingRender struct{}
+
+func (failingRender) Run(*bytes.Buffer) (*bytes.Buffer, error) {
+	return nil, errors.New("policy violation")
+}
+
+func (failingRender) String() string {
+	return "policy"
+}
+
+func TestChain(t *testing.T) {
+	chain := Chain{&replaceRender{"FOOTEST", "BARTEST"}, &replaceRender{"BARTEST", "BAZTEST"}}
+	out, err := chain.Run(bytes.NewBufferString("FOOTEST"))
+	require.NoError(t, err)
+	assert.Equal(t, "BAZTEST", out.String())
+
+	chain = Chain{&replaceRender{"FOOTEST", "BARTEST"}, failingRender{}}
+	_, err = chain.Run(bytes.NewBufferString("FOOTEST"))
+	require.Error(t, err)
+	assert.Equal(t, "post-renderer 2 of 2 (policy): policy violation", err.Error())
+
+	out, err = Chain{}.Run(bytes.NewBufferString("FOOTEST"))
+	require.NoError(t, err)
+	assert.Equal(t, "FOOTEST", out.String())
+}
@@ -19,15 +19,20 @@ package postrender
 import (
 	"bytes"
 	"io"
+	"os"
 	"os/exec"
 	"path/filepath"
 
 	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/plugin/sdk"
 )
 
 type execRender struct {
-	binaryPath string
-	args       []string
+	binaryPath      string
+	args            []string
+	request         func() *sdk.Request
+	protocolVersion int
 }
 
 // NewExec returns a PostRenderer implementation that calls the provided binary.
@@ -39,12 +44,67 @@ func NewExec(binaryPath string, args ...string) (PostRenderer, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &execRender{fullPath, args}, nil
+	return &execRender{binaryPath: fullPath, args: args, protocolVersion: sdk.PostRenderProtocolV1}, nil
+}
+
+// NewExecWithRequest returns a PostRenderer like NewExec, passing the request
+// of the plugin API returned by request to each run of the binary, with the
+// arguments of the post-renderer and the metadata of the release.
+func NewExecWithRequest(binaryPath string, request func() *sdk.Request, args ...string) (PostRenderer, error) {
+	return NewExecWithProtocol(binaryPath, sdk.PostRenderProtocolV1, request, args...)
+}
+
+// NewExecWithProtocol returns a PostRenderer like NewExecWithRequest, talking
+// the version of the post-renderer protocol to the binary, like
+// sdk.PostRenderProtocolV2.
+func NewExecWithProtocol(binaryPath string, protocolVersion int, request func() *sdk.Request, args ...string) (PostRenderer, error) {
+	if protocolVersion != sdk.PostRenderProtocolV1 && protocolVersion != sdk.PostRenderProtocolV2 {
+		return nil, errors.Errorf("unsupported post-renderer protocol version %d", protocolVersion)
+	}
+	pr, err := NewExec(binaryPath, args...)
+	if err != nil {
+		return nil, err
+	}
+	pr.(*execRender).request = request
+	pr.(*execRender).protocolVersion = protocolVersion
+	return pr, nil
+}
+
+// String returns the path of the binary
+func (p *execRender) String() string {
+	return p.binaryPath
 }
 
 // Run the configured binary for the post render
 func (p *execRender) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	return p.RunWithMetadata(renderedManifests, Metadata{})
+}
+
+// RunWithMetadata runs the configured binary for the post render, passing
+// the metadata in its request
+func (p *execRender) RunWithMetadata(renderedManifests *bytes.Buffer, metadata Metadata) (*bytes.Buffer, error) {
 	cmd := exec.Command(p.binaryPath, p.args...)
+	if p.request != nil {
+		req := p.request()
+		req.Kind = sdk.KindPostRenderer
+		req.PostRender = &sdk.PostRenderRequest{Args: p.args, ProtocolVersion: p.protocolVersion}
+		if metadata.ReleaseName != "" {
+			req.PostRender.Release = &sdk.Release{Name: metadata.ReleaseName, Namespace: metadata.Namespace, Revision: metadata.Revision}
+			req.PostRender.Chart = &sdk.Chart{Name: metadata.Chart, Version: metadata.ChartVersion}
+		}
+		env, err := req.Env()
+		if err != nil {
+			return nil, err
+		}
+		cmd.Env = append(os.Environ(), env...)
+	}
+	input := renderedManifests
+	if p.protocolVersion == sdk.PostRenderProtocolV2 {
+		var err error
+		if input, err = encodeManifests(renderedManifests); err != nil {
+			return nil, err
+		}
+	}
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
 		return nil, err
@@ -57,13 +117,16 @@ func (p *execRender) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error)
 
 	go func() {
 		defer stdin.Close()
-		io.Copy(stdin, renderedManifests)
+		io.Copy(stdin, input)
 	}()
 	err = cmd.Run()
 	if err != nil {
 		return nil, errors.Wrapf(err, "error while running command %s. error output:\n%s", p.binaryPath, stderr.String())
 	}
 
+	if p.protocolVersion == sdk.PostRenderProtocolV2 {
+		return decodeManifests(postRendered)
+	}
 	return postRendered, nil
 }
 
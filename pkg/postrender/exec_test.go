@@ -25,6 +25,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"helm.sh/helm/v3/pkg/plugin/sdk"
 )
 
 const testingScript = `#!/bin/sh
@@ -153,6 +155,71 @@ func TestNewExecWithTwoArgsRun(t *testing.T) {
 	is.Contains(output.String(), "ARG1 ARG2")
 }
 
+func TestNewExecWithRequestRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		// the actual Run test uses a basic sed example, so skip this test on windows
+		t.Skip("skipping on windows")
+	}
+	is := assert.New(t)
+	testpath := filepath.Join(t.TempDir(), "post-render-request.sh")
+	script := "#!/bin/sh\ncat >/dev/null\necho \"$HELM_PLUGIN_REQUEST\"\n"
+	require.NoError(t, os.WriteFile(testpath, []byte(script), 0755))
+
+	request := func() *sdk.Request {
+		return &sdk.Request{APIVersion: sdk.APIVersionV1}
+	}
+	renderer, err := NewExecWithRequest(testpath, request, "ARG1")
+	require.NoError(t, err)
+
+	output, err := renderer.Run(bytes.NewBufferString("FOOTEST"))
+	is.NoError(err)
+	req, err := sdk.ParseRequest(bytes.TrimSpace(output.Bytes()))
+	require.NoError(t, err)
+	is.Equal(sdk.KindPostRenderer, req.Kind)
+	is.Equal([]string{"ARG1"}, req.PostRender.Args)
+}
+
+func TestNewExecWithProtocolRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		// the actual Run test uses a basic sed example, so skip this test on windows
+		t.Skip("skipping on windows")
+	}
+	is := assert.New(t)
+	dir := t.TempDir()
+	testpath := filepath.Join(dir, "post-render-v2.sh")
+	script := "#!/bin/sh\necho \"$HELM_PLUGIN_REQUEST\" > \"$(dirname \"$0\")/request.json\"\nsed s/FOOTEST/BARTEST/g\n"
+	require.NoError(t, os.WriteFile(testpath, []byte(script), 0755))
+	manifests := "---\n# Source: chart/templates/cm.yaml\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: FOOTEST\n---\n# Source: chart/templates/empty.yaml\n"
+
+	request := func() *sdk.Request {
+		return &sdk.Request{APIVersion: sdk.APIVersionV1}
+	}
+	_, err := NewExecWithProtocol(testpath, 3, request)
+	is.Error(err)
+	renderer, err := NewExecWithProtocol(testpath, sdk.PostRenderProtocolV2, request)
+	require.NoError(t, err)
+
+	metadata := Metadata{ReleaseName: "prod", Namespace: "default", Revision: 2, Chart: "chart", ChartVersion: "1.0.0"}
+	output, err := Run(renderer, bytes.NewBufferString(manifests), metadata)
+	require.NoError(t, err)
+	is.Equal("---\n# Source: chart/templates/cm.yaml\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: BARTEST\n", output.String())
+
+	data, err := os.ReadFile(filepath.Join(dir, "request.json"))
+	require.NoError(t, err)
+	req, err := sdk.ParseRequest(data)
+	require.NoError(t, err)
+	is.Equal(sdk.PostRenderProtocolV2, req.PostRender.ProtocolVersion)
+	is.Equal(&sdk.Release{Name: "prod", Namespace: "default", Revision: 2}, req.PostRender.Release)
+	is.Equal(&sdk.Chart{Name: "chart", Version: "1.0.0"}, req.PostRender.Chart)
+
+	// The manifests rejected by the post-renderer fail the post render
+	script = "#!/bin/sh\nsed 's/\"object\"/\"error\":\"denied\",\"object\"/'\n"
+	require.NoError(t, os.WriteFile(testpath, []byte(script), 0755))
+	_, err = renderer.Run(bytes.NewBufferString(manifests))
+	require.Error(t, err)
+	is.Contains(err.Error(), "ConfigMap/FOOTEST (chart/templates/cm.yaml): denied")
+}
+
 func setupTestingScript(t *testing.T) (filepath string) {
 	t.Helper()
 
@@ -0,0 +1,178 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postrender
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+)
+
+// Selector selects the manifests passed to a post-renderer. A manifest is
+// selected if it matches one of the kinds, one of the names and the labels,
+// each one matching any manifest if unset.
+type Selector struct {
+	// Kinds are the kinds of the selected manifests, like "Deployment".
+	Kinds []string
+	// Names are the patterns of the names of the selected manifests, like
+	// "web-*".
+	Names []string
+	// Labels selects the manifests by their labels.
+	Labels labels.Selector
+}
+
+// ParseSelector parses the filters of a selector, each one of:
+//
+//	kind=<kind>        the kind of the selected manifests
+//	name=<pattern>     a pattern of the names of the selected manifests
+//	label=<selector>   a label selector, like "app=web,tier!=cache"
+//
+// The filters may be repeated: a manifest matches the kinds or the names if
+// it matches one of them, and the labels if it matches all the selectors.
+func ParseSelector(filters ...string) (Selector, error) {
+	var s Selector
+	var labelSelectors []string
+	for _, filter := range filters {
+		key, value, ok := strings.Cut(filter, "=")
+		if !ok || value == "" {
+			return Selector{}, errors.Errorf("invalid post-renderer filter %q: expected key=value", filter)
+		}
+		switch key {
+		case "kind":
+			s.Kinds = append(s.Kinds, value)
+		case "name":
+			if _, err := path.Match(value, ""); err != nil {
+				return Selector{}, errors.Wrapf(err, "invalid post-renderer filter %q", filter)
+			}
+			s.Names = append(s.Names, value)
+		case "label":
+			labelSelectors = append(labelSelectors, value)
+		default:
+			return Selector{}, errors.Errorf("unknown post-renderer filter %q", key)
+		}
+	}
+	if len(labelSelectors) > 0 {
+		sel, err := labels.Parse(strings.Join(labelSelectors, ","))
+		if err != nil {
+			return Selector{}, errors.Wrap(err, "invalid post-renderer label filter")
+		}
+		s.Labels = sel
+	}
+	return s, nil
+}
+
+// selectedManifest is the part of a manifest matched by a Selector.
+type selectedManifest struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name   string            `json:"name"`
+		Labels map[string]string `json:"labels"`
+	} `json:"metadata"`
+}
+
+// Matches returns whether the selector selects the YAML manifest. The
+// manifests which are not objects are not selected.
+func (s Selector) Matches(manifest string) bool {
+	var m selectedManifest
+	if err := yaml.Unmarshal([]byte(manifest), &m); err != nil || m.Kind == "" {
+		return false
+	}
+	if len(s.Kinds) > 0 && !matchAny(s.Kinds, func(kind string) bool { return kind == m.Kind }) {
+		return false
+	}
+	if len(s.Names) > 0 && !matchAny(s.Names, func(pattern string) bool {
+		ok, _ := path.Match(pattern, m.Metadata.Name)
+		return ok
+	}) {
+		return false
+	}
+	return s.Labels == nil || s.Labels.Matches(labels.Set(m.Metadata.Labels))
+}
+
+func matchAny(values []string, match func(string) bool) bool {
+	for _, v := range values {
+		if match(v) {
+			return true
+		}
+	}
+	return false
+}
+
+type filterRender struct {
+	pr       PostRenderer
+	selector Selector
+}
+
+// NewFilter returns a PostRenderer running pr on the rendered manifests
+// selected by the selector only. The other manifests are passed through
+// unchanged, before the post-rendered ones. pr is not run if no manifest is
+// selected.
+func NewFilter(pr PostRenderer, selector Selector) PostRenderer {
+	return &filterRender{pr: pr, selector: selector}
+}
+
+func (f *filterRender) String() string {
+	if s, ok := f.pr.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", f.pr)
+}
+
+// Run runs the post-renderer on the selected manifests
+func (f *filterRender) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	return f.RunWithMetadata(renderedManifests, Metadata{})
+}
+
+// RunWithMetadata runs the post-renderer on the selected manifests, with the
+// metadata if it is a MetadataPostRenderer
+func (f *filterRender) RunWithMetadata(renderedManifests *bytes.Buffer, metadata Metadata) (*bytes.Buffer, error) {
+	selected, passed := &bytes.Buffer{}, &bytes.Buffer{}
+	for _, doc := range documentSeparator.Split(renderedManifests.String(), -1) {
+		if strings.TrimSpace(doc) == "" {
+			continue
+		}
+		out := passed
+		if f.selector.Matches(doc) {
+			out = selected
+		}
+		out.WriteString("---")
+		if !strings.HasPrefix(doc, "\n") {
+			out.WriteString("\n")
+		}
+		out.WriteString(doc)
+		if !strings.HasSuffix(doc, "\n") {
+			out.WriteString("\n")
+		}
+	}
+	if selected.Len() == 0 {
+		return passed, nil
+	}
+	postRendered, err := Run(f.pr, selected, metadata)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(postRendered.Bytes(), []byte("---")) {
+		passed.WriteString("---\n")
+	}
+	passed.Write(postRendered.Bytes())
+	return passed, nil
+}
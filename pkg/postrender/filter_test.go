@@ -0,0 +1,105 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postrender
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const filterManifests = `---
+# Source: chart/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web-FOOTEST
+  labels:
+    app: web
+---
+# Source: chart/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: web-FOOTEST
+  labels:
+    app: web
+---
+# Source: chart/templates/cache.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: cache-FOOTEST
+  labels:
+    app: cache
+`
+
+func TestParseSelector(t *testing.T) {
+	for _, filters := range [][]string{{"kind"}, {"kind="}, {"unknown=value"}, {"name=[web"}, {"label=app in web"}} {
+		_, err := ParseSelector(filters...)
+		assert.Error(t, err, filters)
+	}
+
+	s, err := ParseSelector("kind=Deployment", "kind=StatefulSet", "name=web-*", "label=app=web", "label=tier!=cache")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Deployment", "StatefulSet"}, s.Kinds)
+	assert.Equal(t, []string{"web-*"}, s.Names)
+	assert.Equal(t, "app=web,tier!=cache", s.Labels.String())
+}
+
+func TestFilterRun(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		filters []string
+		expect  []string
+	}{
+		{"kind", []string{"kind=Deployment"}, []string{"name: web-BARTEST", "name: cache-BARTEST"}},
+		{"name", []string{"name=web-*"}, []string{"kind: Deployment\nmetadata:\n  name: web-BARTEST", "kind: Service\nmetadata:\n  name: web-BARTEST"}},
+		{"label", []string{"kind=Deployment", "label=app=web"}, []string{"name: web-BARTEST"}},
+		{"none", []string{"kind=Job"}, nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			selector, err := ParseSelector(tt.filters...)
+			require.NoError(t, err)
+			pr := &countingRender{replaceRender: replaceRender{"FOOTEST", "BARTEST"}}
+			out, err := NewFilter(pr, selector).Run(bytes.NewBufferString(filterManifests))
+			require.NoError(t, err)
+			output := out.String()
+			assert.Equal(t, 3, bytes.Count(out.Bytes(), []byte("---\n")), output)
+			assert.Equal(t, 3-len(tt.expect), bytes.Count(out.Bytes(), []byte("FOOTEST")), output)
+			for _, expect := range tt.expect {
+				assert.Contains(t, output, expect)
+			}
+			if len(tt.expect) == 0 {
+				assert.Equal(t, filterManifests, output)
+				assert.Equal(t, 0, pr.runs, "expected the post-renderer not to run without a selected manifest")
+			}
+		})
+	}
+}
+
+type countingRender struct {
+	replaceRender
+	runs int
+}
+
+func (r *countingRender) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	r.runs++
+	return r.replaceRender.Run(renderedManifests)
+}
@@ -0,0 +1,230 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postrender
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/kustomize/api/konfig"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/yaml"
+)
+
+// BuiltinKustomize is the name of the post-renderer applying a kustomization
+// in-process, given to --post-renderer instead of the path of an executable.
+const BuiltinKustomize = "builtin-kustomize"
+
+// renderedManifestsFile is the name of the rendered manifests in the
+// kustomization, added to its resources.
+const renderedManifestsFile = "helm-rendered-manifests.yaml"
+
+type kustomizeRender struct {
+	dir       string
+	namespace string
+	labels    map[string]string
+	images    []types.Image
+	patches   []string
+}
+
+// NewKustomize returns a PostRenderer applying a kustomization to the
+// rendered manifests, configured by the arguments, each one of:
+//
+//	dir=<path>             the directory of a kustomization, whose resources
+//	                       are the rendered manifests besides its own
+//	namespace=<namespace>  the namespace of all the resources
+//	label=<key>=<value>    a label added to all the resources
+//	image=<name>=<image>   the image replacing the images named name, like
+//	                       image=nginx=registry.example.com/nginx:1.25
+//	patch=<path>           a strategic merge or JSON 6902 patch
+//
+// The arguments but dir may be repeated. Without dir, the kustomization is
+// made of the other arguments only.
+func NewKustomize(args ...string) (PostRenderer, error) {
+	k := &kustomizeRender{labels: map[string]string{}}
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok || value == "" {
+			return nil, errors.Errorf("invalid %s argument %q: expected key=value", BuiltinKustomize, arg)
+		}
+		switch key {
+		case "dir":
+			k.dir = value
+		case "namespace":
+			k.namespace = value
+		case "label":
+			name, v, ok := strings.Cut(value, "=")
+			if !ok || name == "" {
+				return nil, errors.Errorf("invalid label %q: expected label=<key>=<value>", value)
+			}
+			k.labels[name] = v
+		case "image":
+			image, err := parseImage(value)
+			if err != nil {
+				return nil, err
+			}
+			k.images = append(k.images, image)
+		case "patch":
+			k.patches = append(k.patches, value)
+		default:
+			return nil, errors.Errorf("unknown %s argument %q", BuiltinKustomize, key)
+		}
+	}
+	return k, nil
+}
+
+// parseImage parses an image override like
+// "nginx=registry.example.com/nginx:1.25@sha256:...".
+func parseImage(value string) (types.Image, error) {
+	name, ref, ok := strings.Cut(value, "=")
+	if !ok || name == "" || ref == "" {
+		return types.Image{}, errors.Errorf("invalid image %q: expected image=<name>=<image>", value)
+	}
+	image := types.Image{Name: name}
+	ref, image.Digest, _ = strings.Cut(ref, "@")
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		ref, image.NewTag = ref[:i], ref[i+1:]
+	}
+	if ref != name {
+		image.NewName = ref
+	}
+	return image, nil
+}
+
+func (k *kustomizeRender) String() string {
+	return BuiltinKustomize
+}
+
+// Run applies the kustomization to the rendered manifests
+func (k *kustomizeRender) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	fSys, root, kustomization, err := k.load()
+	if err != nil {
+		return nil, err
+	}
+	kustomization.Resources = append([]string{renderedManifestsFile}, kustomization.Resources...)
+	if k.namespace != "" {
+		kustomization.Namespace = k.namespace
+	}
+	if len(k.labels) > 0 {
+		kustomization.Labels = append(kustomization.Labels, types.Label{Pairs: k.labels})
+	}
+	kustomization.Images = append(kustomization.Images, k.images...)
+	for _, p := range k.patches {
+		patch, err := os.ReadFile(p)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to read the patch")
+		}
+		kustomization.Patches = append(kustomization.Patches, types.Patch{Patch: string(patch)})
+	}
+	data, err := yaml.Marshal(kustomization)
+	if err != nil {
+		return nil, err
+	}
+	fSys.files[filepath.Join(root, renderedManifestsFile)] = renderedManifests.Bytes()
+	fSys.files[filepath.Join(root, fSys.kustomizationFile)] = data
+
+	resources, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(fSys, root)
+	if err != nil {
+		return nil, errors.Wrap(err, "error while running the kustomize post-renderer")
+	}
+	out, err := resources.AsYaml()
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewBuffer(out), nil
+}
+
+// load returns the file system of the kustomization, its root and the
+// kustomization read from its directory, empty and in memory without one.
+func (k *kustomizeRender) load() (*overlayFS, string, *types.Kustomization, error) {
+	kustomization := &types.Kustomization{}
+	if k.dir == "" {
+		fSys := &overlayFS{
+			FileSystem:        filesys.MakeFsInMemory(),
+			files:             map[string][]byte{},
+			kustomizationFile: konfig.DefaultKustomizationFileName(),
+		}
+		kustomization.FixKustomization()
+		return fSys, filesys.Separator, kustomization, nil
+	}
+
+	root, err := filepath.Abs(k.dir)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	// kustomize reads the files under the root with the symbolic links
+	// evaluated
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		return nil, "", nil, err
+	}
+	for _, name := range konfig.RecognizedKustomizationFileNames() {
+		data, err := os.ReadFile(filepath.Join(root, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, "", nil, err
+		}
+		if err := kustomization.Unmarshal(data); err != nil {
+			return nil, "", nil, errors.Wrapf(err, "unable to read the kustomization in %s", k.dir)
+		}
+		kustomization.FixKustomization()
+		fSys := &overlayFS{
+			FileSystem:        filesys.MakeFsOnDisk(),
+			files:             map[string][]byte{},
+			kustomizationFile: name,
+		}
+		return fSys, root, kustomization, nil
+	}
+	return nil, "", nil, errors.Errorf("no kustomization found in %s", k.dir)
+}
+
+// overlayFS is a file system with files in memory over another one, so that
+// the rendered manifests and the kustomization completed with the arguments
+// are read instead of the files on disk.
+type overlayFS struct {
+	filesys.FileSystem
+	files             map[string][]byte
+	kustomizationFile string
+}
+
+func (fs *overlayFS) Exists(path string) bool {
+	if _, ok := fs.files[filepath.Clean(path)]; ok {
+		return true
+	}
+	return fs.FileSystem.Exists(path)
+}
+
+func (fs *overlayFS) CleanedAbs(path string) (filesys.ConfirmedDir, string, error) {
+	if _, ok := fs.files[filepath.Clean(path)]; ok {
+		dir, _, err := fs.FileSystem.CleanedAbs(filepath.Dir(path))
+		return dir, filepath.Base(path), err
+	}
+	return fs.FileSystem.CleanedAbs(path)
+}
+
+func (fs *overlayFS) ReadFile(path string) ([]byte, error) {
+	if data, ok := fs.files[filepath.Clean(path)]; ok {
+		return data, nil
+	}
+	return fs.FileSystem.ReadFile(path)
+}
@@ -0,0 +1,133 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postrender
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/kustomize/api/types"
+)
+
+const kustomizeManifests = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.19
+`
+
+const kustomizeReplicasPatch = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 3
+`
+
+func TestKustomizeArguments(t *testing.T) {
+	_, err := NewKustomize("namespace")
+	assert.Error(t, err)
+	_, err = NewKustomize("unknown=value")
+	assert.Error(t, err)
+	_, err = NewKustomize("label=nokey")
+	assert.Error(t, err)
+	_, err = NewKustomize("image=nginx")
+	assert.Error(t, err)
+
+	for value, expect := range map[string]types.Image{
+		"nginx=registry.example.com/nginx:1.25": {Name: "nginx", NewName: "registry.example.com/nginx", NewTag: "1.25"},
+		"nginx=nginx:1.25":                      {Name: "nginx", NewTag: "1.25"},
+		"nginx=localhost:5000/nginx":            {Name: "nginx", NewName: "localhost:5000/nginx"},
+		"nginx=nginx@sha256:abc":                {Name: "nginx", Digest: "sha256:abc"},
+	} {
+		image, err := parseImage(value)
+		require.NoError(t, err)
+		assert.Equal(t, expect, image, value)
+	}
+}
+
+func TestKustomizeRun(t *testing.T) {
+	dir := t.TempDir()
+	patch := filepath.Join(dir, "replicas.yaml")
+	require.NoError(t, os.WriteFile(patch, []byte(kustomizeReplicasPatch), 0644))
+
+	pr, err := NewKustomize("namespace=prod", "label=team=web", "image=nginx=registry.example.com/nginx:1.25", "patch="+patch)
+	require.NoError(t, err)
+	out, err := pr.Run(bytes.NewBufferString(kustomizeManifests))
+	require.NoError(t, err)
+	output := out.String()
+	assert.Contains(t, output, "namespace: prod")
+	assert.Contains(t, output, "team: web")
+	assert.Contains(t, output, "image: registry.example.com/nginx:1.25")
+	assert.Contains(t, output, "replicas: 3")
+	// The labels are not added to the selectors, which are immutable
+	assert.NotContains(t, output, "matchLabels:\n      app: web\n      team: web")
+}
+
+func TestKustomizeRunOverlay(t *testing.T) {
+	dir := t.TempDir()
+	kustomization := `resources:
+- configmap.yaml
+namePrefix: prod-
+patches:
+- path: replicas.yaml
+`
+	configMap := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "kustomization.yaml"), []byte(kustomization), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "configmap.yaml"), []byte(configMap), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "replicas.yaml"), []byte(kustomizeReplicasPatch), 0644))
+
+	pr, err := NewKustomize("dir="+dir, "namespace=prod")
+	require.NoError(t, err)
+	out, err := pr.Run(bytes.NewBufferString(kustomizeManifests))
+	require.NoError(t, err)
+	output := out.String()
+	assert.Contains(t, output, "name: prod-web")
+	assert.Contains(t, output, "name: prod-settings")
+	assert.Contains(t, output, "namespace: prod")
+	assert.Contains(t, output, "replicas: 3")
+
+	// The kustomization on disk is left unchanged
+	data, err := os.ReadFile(filepath.Join(dir, "kustomization.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, kustomization, string(data))
+
+	pr, err = NewKustomize("dir=" + t.TempDir())
+	require.NoError(t, err)
+	_, err = pr.Run(bytes.NewBufferString(kustomizeManifests))
+	assert.Error(t, err)
+}
@@ -27,3 +27,30 @@ type PostRenderer interface {
 	// error if there was an issue or failure while running the post render step
 	Run(renderedManifests *bytes.Buffer) (modifiedManifests *bytes.Buffer, err error)
 }
+
+// Metadata describes the release of the rendered manifests.
+type Metadata struct {
+	ReleaseName  string
+	Namespace    string
+	Revision     int
+	Chart        string
+	ChartVersion string
+}
+
+// MetadataPostRenderer is a PostRenderer making decisions on the release of
+// the rendered manifests.
+type MetadataPostRenderer interface {
+	PostRenderer
+	// RunWithMetadata is Run, with the metadata of the release of the
+	// rendered manifests.
+	RunWithMetadata(renderedManifests *bytes.Buffer, metadata Metadata) (modifiedManifests *bytes.Buffer, err error)
+}
+
+// Run runs pr on the rendered manifests, with the metadata if it is a
+// MetadataPostRenderer.
+func Run(pr PostRenderer, renderedManifests *bytes.Buffer, metadata Metadata) (*bytes.Buffer, error) {
+	if mpr, ok := pr.(MetadataPostRenderer); ok {
+		return mpr.RunWithMetadata(renderedManifests, metadata)
+	}
+	return pr.Run(renderedManifests)
+}
@@ -0,0 +1,105 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postrender
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/plugin/sdk"
+)
+
+var (
+	documentSeparator = regexp.MustCompile(`(?m)^---[ \t]*$`)
+	sourceComment     = regexp.MustCompile(`(?m)^# Source: (.+)$`)
+)
+
+// encodeManifests encodes the YAML stream of the rendered manifests to the
+// manifests of the version 2 of the post-renderer protocol.
+func encodeManifests(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	var manifests []sdk.PostRenderManifest
+	for _, doc := range documentSeparator.Split(renderedManifests.String(), -1) {
+		var object map[string]interface{}
+		if err := yaml.Unmarshal([]byte(doc), &object); err != nil {
+			return nil, errors.Wrap(err, "unable to decode the rendered manifests")
+		}
+		if len(object) == 0 {
+			continue
+		}
+		m := sdk.PostRenderManifest{Object: object}
+		if match := sourceComment.FindStringSubmatch(doc); match != nil {
+			m.Source = strings.TrimSpace(match[1])
+		}
+		manifests = append(manifests, m)
+	}
+	out := &bytes.Buffer{}
+	if err := sdk.WritePostRenderManifests(out, manifests); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// decodeManifests decodes the manifests of the version 2 of the
+// post-renderer protocol to a YAML stream, failing with the errors of the
+// rejected manifests.
+func decodeManifests(postRendered *bytes.Buffer) (*bytes.Buffer, error) {
+	manifests, err := sdk.ReadPostRenderManifests(postRendered)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid output of the post-renderer")
+	}
+	var rejected []string
+	out := &bytes.Buffer{}
+	for _, m := range manifests {
+		if m.Error != "" {
+			rejected = append(rejected, fmt.Sprintf("%s: %s", describeManifest(m), m.Error))
+			continue
+		}
+		data, err := yaml.Marshal(m.Object)
+		if err != nil {
+			return nil, err
+		}
+		out.WriteString("---\n")
+		if m.Source != "" {
+			fmt.Fprintf(out, "# Source: %s\n", m.Source)
+		}
+		out.Write(data)
+	}
+	if len(rejected) > 0 {
+		return nil, errors.Errorf("the post-renderer rejected %d manifests:\n%s", len(rejected), strings.Join(rejected, "\n"))
+	}
+	return out, nil
+}
+
+// describeManifest describes a manifest by its kind, name and source, like
+// "Deployment/web (mychart/templates/deployment.yaml)".
+func describeManifest(m sdk.PostRenderManifest) string {
+	kind, _ := m.Object["kind"].(string)
+	var name string
+	if metadata, ok := m.Object["metadata"].(map[string]interface{}); ok {
+		name, _ = metadata["name"].(string)
+	}
+	desc := kind + "/" + name
+	if m.Source != "" {
+		desc += " (" + m.Source + ")"
+	}
+	return desc
+}
@@ -0,0 +1,112 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postrender
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Factory returns a post-renderer configured by the arguments.
+type Factory func(args ...string) (PostRenderer, error)
+
+var registry = struct {
+	mu        sync.RWMutex
+	factories map[string]Factory
+}{
+	factories: map[string]Factory{BuiltinKustomize: NewKustomize},
+}
+
+// Register registers the factory of an in-process post-renderer, selected by
+// name with New, the PostRendererName of the actions or --post-renderer
+// instead of the path of an executable. It returns an error if the name is
+// empty or already registered.
+func Register(name string, factory Factory) error {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	if name == "" {
+		return errors.New("post-renderer has no name")
+	}
+	if _, ok := registry.factories[name]; ok {
+		return errors.Errorf("post-renderer %q is already registered", name)
+	}
+	registry.factories[name] = factory
+	return nil
+}
+
+// IsRegistered returns whether a post-renderer is registered with the name.
+func IsRegistered(name string) bool {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	_, ok := registry.factories[name]
+	return ok
+}
+
+// Registered returns the sorted names of the registered post-renderers,
+// including the built-in ones.
+func Registered() []string {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+
+	names := make([]string, 0, len(registry.factories))
+	for name := range registry.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New returns the registered post-renderer named name, configured by the
+// arguments.
+func New(name string, args ...string) (PostRenderer, error) {
+	registry.mu.RLock()
+	factory, ok := registry.factories[name]
+	registry.mu.RUnlock()
+
+	if !ok {
+		return nil, errors.Errorf("post-renderer %q is not registered", name)
+	}
+	pr, err := factory(args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "post-renderer %q", name)
+	}
+	if _, ok := pr.(fmt.Stringer); !ok {
+		pr = &namedRender{PostRenderer: pr, name: name}
+	}
+	return pr, nil
+}
+
+// namedRender names a registered post-renderer, so that its errors are
+// attributed to it in a Chain.
+type namedRender struct {
+	PostRenderer
+	name string
+}
+
+func (n *namedRender) RunWithMetadata(renderedManifests *bytes.Buffer, metadata Metadata) (*bytes.Buffer, error) {
+	return Run(n.PostRenderer, renderedManifests, metadata)
+}
+
+func (n *namedRender) String() string {
+	return n.name
+}
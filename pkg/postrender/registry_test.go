@@ -0,0 +1,57 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postrender
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	assert.True(t, IsRegistered(BuiltinKustomize))
+	assert.False(t, IsRegistered("test-replace"))
+
+	require.NoError(t, Register("test-replace", func(args ...string) (PostRenderer, error) {
+		if len(args) != 2 {
+			return nil, errors.New("expected the old and the new strings")
+		}
+		return &replaceRender{args[0], args[1]}, nil
+	}))
+	assert.Error(t, Register("test-replace", NewKustomize))
+	assert.Error(t, Register("", NewKustomize))
+	assert.True(t, IsRegistered("test-replace"))
+	assert.Contains(t, Registered(), "test-replace")
+
+	pr, err := New("test-replace", "FOOTEST", "BARTEST")
+	require.NoError(t, err)
+	out, err := pr.Run(bytes.NewBufferString("FOOTEST"))
+	require.NoError(t, err)
+	assert.Equal(t, "BARTEST", out.String())
+	// The registered post-renderers are named in the errors of the chains
+	_, err = Chain{pr, failingRender{}}.Run(bytes.NewBufferString("FOOTEST"))
+	assert.Error(t, err)
+	assert.Equal(t, "test-replace", pr.(*namedRender).String())
+
+	_, err = New("test-replace")
+	assert.EqualError(t, err, `post-renderer "test-replace": expected the old and the new strings`)
+	_, err = New("unregistered")
+	assert.EqualError(t, err, `post-renderer "unregistered" is not registered`)
+}
@@ -16,6 +16,7 @@ limitations under the License.
 package provenance
 
 import (
+	"bufio"
 	"bytes"
 	"crypto"
 	"encoding/hex"
@@ -63,6 +64,12 @@ type Verification struct {
 	FileHash string
 	// FileName is the name of the file that FileHash verifies.
 	FileName string
+	// Signer describes the signer of a chart verified with a sigstore
+	// signature instead of a provenance file, when SignedBy is nil.
+	Signer string
+	// Keyring is the keyring file holding the key of SignedBy, if the
+	// Signatory was created with NewFromKeyrings.
+	Keyring string
 }
 
 // Signatory signs things.
@@ -77,6 +84,10 @@ type Signatory struct {
 	Entity *openpgp.Entity
 	// The keyring for this instance of Helm. This is used for verification.
 	KeyRing openpgp.EntityList
+
+	// keyrings are the keyring files holding the keys of KeyRing, by
+	// fingerprint.
+	keyrings map[[20]byte]string
 }
 
 // NewFromFiles constructs a new Signatory from the PGP key in the given filename.
@@ -150,6 +161,70 @@ func NewFromKeyring(keyringfile, id string) (*Signatory, error) {
 	return s, nil
 }
 
+// NewFromKeyrings reads the public keys of several keyrings and creates a
+// Signatory verifying the signatures made with any of them, so that keys can
+// be rotated.
+//
+// Each path is either a keyring file or a directory of keyring files, whose
+// files with the extension .gpg, .pgp, .pub, .asc or .key are read in lexical
+// order. Keyrings may be binary or ASCII armored. A key found in several
+// keyrings is attributed to the first one.
+func NewFromKeyrings(paths ...string) (*Signatory, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("no keyring given")
+	}
+	s := &Signatory{keyrings: map[[20]byte]string{}}
+	for _, path := range paths {
+		files, err := keyringFiles(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			ring, err := loadKeyRing(f)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to read keyring %s", f)
+			}
+			for _, e := range ring {
+				if _, ok := s.keyrings[e.PrimaryKey.Fingerprint]; !ok {
+					s.keyrings[e.PrimaryKey.Fingerprint] = f
+				}
+			}
+			s.KeyRing = append(s.KeyRing, ring...)
+		}
+	}
+	if len(s.KeyRing) == 0 {
+		return nil, errors.Errorf("no public keys found in %s", strings.Join(paths, ", "))
+	}
+	return s, nil
+}
+
+// keyringExts are the extensions of the keyring files read in directories.
+var keyringExts = map[string]bool{".gpg": true, ".pgp": true, ".pub": true, ".asc": true, ".key": true}
+
+// keyringFiles returns the path if it is a file, or the keyring files of the
+// directory.
+func keyringFiles(path string) ([]string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return []string{path}, nil
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if e.IsDir() || !keyringExts[filepath.Ext(e.Name())] {
+			continue
+		}
+		files = append(files, filepath.Join(path, e.Name()))
+	}
+	return files, nil
+}
+
 // PassphraseFetcher returns a passphrase for decrypting keys.
 //
 // This is used as a callback to read a passphrase from some other location. The
@@ -269,6 +344,7 @@ func (s *Signatory) Verify(chartpath, sigpath string) (*Verification, error) {
 		return ver, err
 	}
 	ver.SignedBy = by
+	ver.Keyring = s.keyrings[by.PrimaryKey.Fingerprint]
 
 	// Second, verify the hash of the tarball.
 	sum, err := DigestFile(chartpath)
@@ -391,15 +467,25 @@ func loadKey(keypath string) (*openpgp.Entity, error) {
 	return openpgp.ReadEntity(pr)
 }
 
+// loadKeyRing loads a binary or ASCII armored GPG keyring found at a
+// particular path.
 func loadKeyRing(ringpath string) (openpgp.EntityList, error) {
 	f, err := os.Open(ringpath)
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
-	return openpgp.ReadKeyRing(f)
+
+	r := bufio.NewReader(f)
+	if prefix, _ := r.Peek(len(armorPrefix)); string(prefix) == armorPrefix {
+		return openpgp.ReadArmoredKeyRing(r)
+	}
+	return openpgp.ReadKeyRing(r)
 }
 
+// armorPrefix starts the ASCII armored keyrings.
+const armorPrefix = "-----BEGIN PGP"
+
 // DigestFile calculates a SHA256 hash (like Docker) for a given file.
 //
 // It takes the path to the archive file, and returns a string representation of
@@ -24,6 +24,8 @@ import (
 	"strings"
 	"testing"
 
+	"golang.org/x/crypto/openpgp"                  //nolint
+	"golang.org/x/crypto/openpgp/armor"            //nolint
 	pgperrors "golang.org/x/crypto/openpgp/errors" //nolint
 )
 
@@ -136,6 +138,74 @@ func TestLoadKeyRing(t *testing.T) {
 	}
 }
 
+func TestLoadArmoredKeyRing(t *testing.T) {
+	ring, err := loadKeyRing(testPubfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf strings.Builder
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ring[0].Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	armored := filepath.Join(t.TempDir(), "helm-test-key.asc")
+	if err := os.WriteFile(armored, []byte(buf.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	k, err := loadKeyRing(armored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(k) != 1 {
+		t.Fatalf("Expected 1, got %d", len(k))
+	}
+	if _, ok := k[0].Identities[testKeyName]; !ok {
+		t.Errorf("Expected %s in %v", testKeyName, k[0].Identities)
+	}
+}
+
+func TestNewFromKeyrings(t *testing.T) {
+	dir := t.TempDir()
+	pub, err := os.ReadFile(testPubfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring := filepath.Join(dir, "current.gpg")
+	if err := os.WriteFile(keyring, pub, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a key"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := NewFromKeyrings(dir, testPubfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(signer.KeyRing) != 2 {
+		t.Errorf("Expected 2 keys, got %d", len(signer.KeyRing))
+	}
+	ver, err := signer.Verify(testChartfile, testSigBlock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ver.Keyring != keyring {
+		t.Errorf("Expected the key to be found in %s, got %q", keyring, ver.Keyring)
+	}
+
+	if _, err := NewFromKeyrings(t.TempDir()); err == nil {
+		t.Error("Expected a directory without keyrings to fail")
+	}
+	if _, err := NewFromKeyrings(); err == nil {
+		t.Error("Expected no keyring to fail")
+	}
+}
+
 func TestDigest(t *testing.T) {
 	f, err := os.Open(testChartfile)
 	if err != nil {
@@ -92,9 +92,18 @@ func (pusher *OCIPusher) push(chartRef, href string) error {
 
 	chartCreationTime := ctime.Created(stat)
 	pushOpts = append(pushOpts, registry.PushOptCreationTime(chartCreationTime.Format(time.RFC3339)))
+	if pusher.opts.signer != nil {
+		pushOpts = append(pushOpts, registry.PushOptSign(pusher.opts.signer))
+	}
 
-	_, err = client.Push(chartBytes, ref, pushOpts...)
-	return err
+	result, err := client.Push(chartBytes, ref, pushOpts...)
+	if err != nil {
+		return err
+	}
+	if pusher.opts.result != nil {
+		*pusher.opts.result = *result
+	}
+	return nil
 }
 
 // NewOCIPusher constructs a valid OCI client as a Pusher
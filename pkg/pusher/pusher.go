@@ -33,6 +33,8 @@ type options struct {
 	caFile                string
 	insecureSkipTLSverify bool
 	plainHTTP             bool
+	signer                *registry.SignatureSigner
+	result                *registry.PushResult
 }
 
 // Option allows specifying various settings configurable by the user for overriding the defaults
@@ -68,6 +70,21 @@ func WithPlainHTTP(plainHTTP bool) Option {
 	}
 }
 
+// WithSigner sets the signer signing the pushed charts.
+func WithSigner(signer *registry.SignatureSigner) Option {
+	return func(opts *options) {
+		opts.signer = signer
+	}
+}
+
+// WithPushResult sets the result of the pushes to registries, set once a
+// chart is pushed.
+func WithPushResult(result *registry.PushResult) Option {
+	return func(opts *options) {
+		opts.result = result
+	}
+}
+
 // Pusher is an interface to support upload to the specified URL.
 type Pusher interface {
 	// Push file content by url string
@@ -0,0 +1,339 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry // import "helm.sh/helm/v3/pkg/registry"
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// bundleImagesName is the name of the file listing the container images of
+// the charts of a bundle.
+const bundleImagesName = "images.json"
+
+// maxBundleBlobSize bounds the size of a blob read from a bundle.
+const maxBundleBlobSize = 1 << 30
+
+type (
+	// Bundle describes the content of an air-gap bundle.
+	Bundle struct {
+		// Charts are the charts of the bundle
+		Charts []*BundleChart
+		// Images are the references of the container images used by the
+		// charts, if they were collected
+		Images []string
+	}
+
+	// BundleChart is a chart of an air-gap bundle.
+	BundleChart struct {
+		// Ref is the reference of the chart: the reference it was exported
+		// from in a bundle, or it was imported to
+		Ref string
+		// Digest is the digest of the manifest of the chart, which is
+		// kept when the chart is imported
+		Digest string
+	}
+
+	// BundleOption allows specifying various settings on bundle export
+	BundleOption func(*bundleOperation)
+
+	bundleOperation struct {
+		images []string
+	}
+)
+
+// BundleOptImages returns a function that sets the references of the
+// container images listed in the bundle
+func BundleOptImages(images []string) BundleOption {
+	return func(operation *bundleOperation) {
+		operation.images = images
+	}
+}
+
+// ExportBundle writes an air-gap bundle of the charts at refs to w. The
+// bundle is a tarball in the OCI image layout, holding the manifests and the
+// blobs of the charts as they are stored in the registry, so ImportBundle
+// pushes them with the same digests into another registry.
+func (c *Client) ExportBundle(w io.Writer, refs []string, options ...BundleOption) (_ *Bundle, err error) {
+	defer c.wrapError(&err)
+
+	operation := &bundleOperation{}
+	for _, option := range options {
+		option(operation)
+	}
+
+	tw := tar.NewWriter(w)
+	now := time.Now()
+	add := func(name string, data []byte) error {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(data)),
+			ModTime:  now,
+			Typeflag: tar.TypeReg,
+		}); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	layout, err := json.Marshal(ocispec.ImageLayout{Version: ocispec.ImageLayoutVersion})
+	if err != nil {
+		return nil, err
+	}
+	if err := add(ocispec.ImageLayoutFile, layout); err != nil {
+		return nil, err
+	}
+
+	bundle := &Bundle{}
+	index := ocispec.Index{MediaType: ocispec.MediaTypeImageIndex}
+	index.SchemaVersion = 2
+	written := map[digest.Digest]bool{}
+	for _, ref := range refs {
+		desc, blobs, err := c.fetchChartContent(ref)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to export %s", ref)
+		}
+		for _, blob := range blobs {
+			if written[blob.desc.Digest] {
+				continue
+			}
+			if err := add(bundleBlobName(blob.desc.Digest), blob.data); err != nil {
+				return nil, err
+			}
+			written[blob.desc.Digest] = true
+		}
+		desc.Annotations = map[string]string{ocispec.AnnotationRefName: ref}
+		index.Manifests = append(index.Manifests, desc)
+		bundle.Charts = append(bundle.Charts, &BundleChart{Ref: ref, Digest: desc.Digest.String()})
+	}
+
+	data, err := json.Marshal(index)
+	if err != nil {
+		return nil, err
+	}
+	if err := add(ocispec.ImageIndexFile, data); err != nil {
+		return nil, err
+	}
+	if len(operation.images) > 0 {
+		bundle.Images = append([]string{}, operation.images...)
+		sort.Strings(bundle.Images)
+		data, err := json.MarshalIndent(bundle.Images, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := add(bundleImagesName, data); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+// bundleBlob is a blob of a chart with its content.
+type bundleBlob struct {
+	desc ocispec.Descriptor
+	data []byte
+}
+
+// fetchChartContent fetches the manifest of the chart at ref, and returns
+// its descriptor with the manifest and the blobs it references, manifest
+// last.
+func (c *Client) fetchChartContent(ref string) (ocispec.Descriptor, []bundleBlob, error) {
+	parsedRef, err := parseReference(ref)
+	if err != nil {
+		return ocispec.Descriptor{}, nil, err
+	}
+	resolver, err := c.resolver(parsedRef)
+	if err != nil {
+		return ocispec.Descriptor{}, nil, err
+	}
+	rctx := ctx(c.out, c.debug)
+
+	_, desc, err := resolver.Resolve(rctx, parsedRef.String())
+	if err != nil {
+		return desc, nil, err
+	}
+	if desc.MediaType != ocispec.MediaTypeImageManifest {
+		return desc, nil, errors.Errorf("%s is not a chart: unexpected media type %s", ref, desc.MediaType)
+	}
+	fetcher, err := resolver.Fetcher(rctx, parsedRef.String())
+	if err != nil {
+		return desc, nil, err
+	}
+	data, err := fetchBlob(rctx, fetcher, desc)
+	if err != nil {
+		return desc, nil, err
+	}
+	manifest := &ocispec.Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return desc, nil, errors.Wrapf(err, "invalid manifest %s", desc.Digest)
+	}
+	if manifest.Config.MediaType != ConfigMediaType {
+		return desc, nil, errors.Errorf("%s is not a chart: unexpected config media type %s", ref, manifest.Config.MediaType)
+	}
+
+	descs := append([]ocispec.Descriptor{manifest.Config}, manifest.Layers...)
+	blobs := make([]bundleBlob, 0, len(descs)+1)
+	for _, d := range descs {
+		b, err := fetchBlob(rctx, fetcher, d)
+		if err != nil {
+			return desc, nil, err
+		}
+		blobs = append(blobs, bundleBlob{desc: d, data: b})
+	}
+	desc = ocispec.Descriptor{MediaType: desc.MediaType, Digest: desc.Digest, Size: desc.Size}
+	return desc, append(blobs, bundleBlob{desc: desc, data: data}), nil
+}
+
+// ImportBundle pushes the charts of the air-gap bundle read from r into the
+// registry namespace target, such as "registry.example.com/mirror". Charts
+// keep the path of their repository and their tag under the namespace, and
+// the digests of their manifests.
+func (c *Client) ImportBundle(r io.Reader, target string) (_ *Bundle, err error) {
+	defer c.wrapError(&err)
+
+	target = strings.Trim(strings.TrimPrefix(target, fmt.Sprintf("%s://", OCIScheme)), "/")
+	if target == "" {
+		return nil, errors.New("missing the registry to import the bundle into")
+	}
+
+	var index *ocispec.Index
+	var images []string
+	layout := false
+	blobs := map[digest.Digest][]byte{}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid bundle")
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := path.Clean(hdr.Name)
+		switch {
+		case name == ocispec.ImageLayoutFile:
+			layout = true
+		case name == ocispec.ImageIndexFile:
+			index = &ocispec.Index{}
+			if err := json.NewDecoder(tr).Decode(index); err != nil {
+				return nil, errors.Wrap(err, "invalid bundle index")
+			}
+		case name == bundleImagesName:
+			if err := json.NewDecoder(tr).Decode(&images); err != nil {
+				return nil, errors.Wrap(err, "invalid bundle images")
+			}
+		case path.Dir(path.Dir(name)) == "blobs":
+			d := digest.NewDigestFromEncoded(digest.Algorithm(path.Base(path.Dir(name))), path.Base(name))
+			if err := d.Validate(); err != nil {
+				return nil, errors.Wrapf(err, "invalid bundle blob %s", name)
+			}
+			data, err := io.ReadAll(io.LimitReader(tr, maxBundleBlobSize))
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid bundle")
+			}
+			if d.Algorithm().FromBytes(data) != d {
+				return nil, errors.Errorf("content of bundle blob %s does not match its digest", name)
+			}
+			blobs[d] = data
+		}
+	}
+	if !layout || index == nil {
+		return nil, errors.New("invalid bundle: not an OCI image layout")
+	}
+
+	bundle := &Bundle{Images: images}
+	for _, desc := range index.Manifests {
+		ref, err := importReference(target, desc.Annotations[ocispec.AnnotationRefName])
+		if err != nil {
+			return nil, err
+		}
+		if err := c.importChart(ref, desc, blobs); err != nil {
+			return nil, errors.Wrapf(err, "failed to import %s", ref)
+		}
+		bundle.Charts = append(bundle.Charts, &BundleChart{Ref: ref, Digest: desc.Digest.String()})
+	}
+	return bundle, nil
+}
+
+// importReference returns the reference under target of the chart exported
+// from ref.
+func importReference(target, ref string) (string, error) {
+	parsedRef, err := parseReference(ref)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid bundle: invalid chart reference %q", ref)
+	}
+	if parsedRef.Reference == "" {
+		return "", errors.Errorf("invalid bundle: chart reference %q has no tag", ref)
+	}
+	return fmt.Sprintf("%s/%s:%s", target, parsedRef.Repository, parsedRef.Reference), nil
+}
+
+// importChart pushes the blobs of the manifest desc, then the manifest with
+// the tag of ref.
+func (c *Client) importChart(ref string, desc ocispec.Descriptor, blobs map[digest.Digest][]byte) error {
+	parsedRef, err := parseReference(ref)
+	if err != nil {
+		return err
+	}
+	resolver, err := c.resolver(parsedRef)
+	if err != nil {
+		return err
+	}
+	rctx := ctx(c.out, c.debug)
+
+	data, ok := blobs[desc.Digest]
+	if !ok {
+		return errors.Errorf("invalid bundle: manifest %s is missing", desc.Digest)
+	}
+	manifest := &ocispec.Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return errors.Wrapf(err, "invalid manifest %s", desc.Digest)
+	}
+	for _, d := range append([]ocispec.Descriptor{manifest.Config}, manifest.Layers...) {
+		b, ok := blobs[d.Digest]
+		if !ok {
+			return errors.Errorf("invalid bundle: blob %s is missing", d.Digest)
+		}
+		if err := pushContent(rctx, resolver, parsedRef.String(), d, b); err != nil {
+			return err
+		}
+	}
+	return pushContent(rctx, resolver, parsedRef.String(), desc, data)
+}
+
+func bundleBlobName(d digest.Digest) string {
+	return path.Join("blobs", d.Algorithm().String(), d.Encoded())
+}
@@ -0,0 +1,233 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry // import "helm.sh/helm/v3/pkg/registry"
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	registryauth "oras.land/oras-go/pkg/registry/remote/auth"
+
+	"helm.sh/helm/v3/pkg/chart"
+)
+
+// maxCatalogPages bounds the pages of the catalog followed for a namespace.
+const maxCatalogPages = 100
+
+// catalogPageSize is the number of repositories requested in a page of the
+// catalog.
+const catalogPageSize = 1000
+
+type (
+	// CatalogEntry is a chart repository found in a namespace of a registry.
+	CatalogEntry struct {
+		// Repository is the reference of the repository, without tag
+		Repository string
+		// Versions are the versions of the chart, newest first
+		Versions []*CatalogVersion
+	}
+
+	// CatalogVersion is a version of a chart found in a namespace.
+	CatalogVersion struct {
+		// Tag is the tag of the version, with plus (+) signs
+		Tag string
+		// Digest is the digest of the manifest of the version
+		Digest string
+		// Metadata is the content of the Chart.yaml of the version
+		Metadata *chart.Metadata
+	}
+
+	// CatalogOption allows specifying various settings on catalog
+	CatalogOption func(*catalogOperation)
+
+	catalogOperation struct {
+		allVersions bool
+	}
+)
+
+// CatalogOptAllVersions returns a function that sets whether every version of
+// the charts is listed, rather than only the newest one
+func CatalogOptAllVersions(allVersions bool) CatalogOption {
+	return func(operation *catalogOperation) {
+		operation.allVersions = allVersions
+	}
+}
+
+// Catalog lists the charts stored in the repositories under namespace, such
+// as "ghcr.io/helm" or "localhost:5000", with their tags and metadata.
+//
+// Repositories are enumerated with the catalog API of the registry, so the
+// registry must implement it; many public registries do not. Repositories
+// which do not store charts are skipped.
+func (c *Client) Catalog(namespace string, options ...CatalogOption) (_ []*CatalogEntry, err error) {
+	defer c.wrapError(&err)
+
+	operation := &catalogOperation{}
+	for _, option := range options {
+		option(operation)
+	}
+
+	host, prefix, _ := strings.Cut(strings.Trim(strings.TrimPrefix(namespace, fmt.Sprintf("%s://", OCIScheme)), "/"), "/")
+	if host == "" {
+		return nil, errors.Errorf("invalid namespace %q", namespace)
+	}
+	repositories, err := c.Repositories(host)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*CatalogEntry
+	for _, repository := range repositories {
+		if prefix != "" && repository != prefix && !strings.HasPrefix(repository, prefix+"/") {
+			continue
+		}
+		entry, err := c.catalogEntry(fmt.Sprintf("%s/%s", host, repository), operation.allVersions)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list the charts of %s/%s", host, repository)
+		}
+		if entry != nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// catalogEntry lists the versions of the chart of repository, or returns nil
+// if the repository does not store a chart.
+func (c *Client) catalogEntry(repository string, allVersions bool) (*CatalogEntry, error) {
+	tags, err := c.Tags(repository)
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) > 1 && !allVersions {
+		tags = tags[:1]
+	}
+
+	entry := &CatalogEntry{Repository: repository}
+	for _, tag := range tags {
+		version, err := c.catalogVersion(fmt.Sprintf("%s:%s", repository, tag))
+		if err != nil {
+			return nil, err
+		}
+		if version == nil {
+			// not a chart
+			return nil, nil
+		}
+		version.Tag = tag
+		entry.Versions = append(entry.Versions, version)
+	}
+	if len(entry.Versions) == 0 {
+		return nil, nil
+	}
+	return entry, nil
+}
+
+// catalogVersion fetches the metadata of the chart at ref from the config of
+// its manifest, or returns nil if ref is not a chart.
+func (c *Client) catalogVersion(ref string) (*CatalogVersion, error) {
+	parsedRef, err := parseReference(ref)
+	if err != nil {
+		return nil, err
+	}
+	resolver, err := c.resolver(parsedRef)
+	if err != nil {
+		return nil, err
+	}
+	rctx := ctx(c.out, c.debug)
+
+	desc, manifest, err := fetchManifest(rctx, resolver, parsedRef.String())
+	if err != nil {
+		return nil, err
+	}
+	if manifest.Config.MediaType != ConfigMediaType {
+		return nil, nil
+	}
+	fetcher, err := resolver.Fetcher(rctx, parsedRef.String())
+	if err != nil {
+		return nil, err
+	}
+	data, err := fetchBlob(rctx, fetcher, manifest.Config)
+	if err != nil {
+		return nil, err
+	}
+	metadata := &chart.Metadata{}
+	if err := json.Unmarshal(data, metadata); err != nil {
+		return nil, errors.Wrapf(err, "invalid chart config %s", manifest.Config.Digest)
+	}
+	return &CatalogVersion{Digest: desc.Digest.String(), Metadata: metadata}, nil
+}
+
+// Repositories lists the repositories of the registry at host with the
+// catalog API, following the pages of the results.
+func (c *Client) Repositories(host string) ([]string, error) {
+	scheme := "https"
+	if c.plainHTTP {
+		scheme = "http"
+	}
+	u := fmt.Sprintf("%s://%s/v2/_catalog?n=%d", scheme, host, catalogPageSize)
+	rctx := registryauth.WithScopes(ctx(c.out, c.debug), registryauth.ScopeRegistryCatalog)
+
+	var repositories []string
+	for page := 0; u != "" && page < maxCatalogPages; page++ {
+		req, err := http.NewRequestWithContext(rctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.registryAuthorizer.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		names, next, err := readCatalogPage(host, resp)
+		if err != nil {
+			return nil, err
+		}
+		repositories = append(repositories, names...)
+		u = next
+	}
+	return repositories, nil
+}
+
+// readCatalogPage reads a page of the catalog API and returns the URL of the
+// next page, if any.
+func readCatalogPage(host string, resp *http.Response) ([]string, string, error) {
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		return nil, "", errors.Wrapf(newResponseError(resp), "registry %s does not support listing its repositories", host)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, "", errors.Wrapf(newResponseError(resp), "listing the repositories of %s is not allowed", host)
+	default:
+		return nil, "", errors.Wrap(newResponseError(resp), "failed to list repositories")
+	}
+	var catalog struct {
+		Repositories []string `json:"repositories"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 4<<20)).Decode(&catalog); err != nil {
+		return nil, "", errors.Wrap(err, "invalid catalog")
+	}
+
+	next, err := nextPageURL(resp)
+	if err != nil {
+		return nil, "", err
+	}
+	return catalog.Repositories, next, nil
+}
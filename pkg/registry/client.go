@@ -21,12 +21,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
+	"path/filepath"
 	"sort"
 	"strings"
 
 	"github.com/Masterminds/semver/v3"
 	"github.com/containerd/containerd/remotes"
+	"github.com/containerd/containerd/remotes/docker"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/pkg/errors"
 	"oras.land/oras-go/pkg/auth"
@@ -34,7 +37,6 @@ import (
 	"oras.land/oras-go/pkg/content"
 	"oras.land/oras-go/pkg/oras"
 	"oras.land/oras-go/pkg/registry"
-	registryremote "oras.land/oras-go/pkg/registry/remote"
 	registryauth "oras.land/oras-go/pkg/registry/remote/auth"
 
 	"helm.sh/helm/v3/internal/version"
@@ -59,9 +61,18 @@ type (
 		out                io.Writer
 		authorizer         auth.Client
 		registryAuthorizer *registryauth.Client
+		credentials        *credentialStore
+		credentialProvider CredentialProvider
 		resolver           func(ref registry.Reference) (remotes.Resolver, error)
 		httpClient         *http.Client
 		plainHTTP          bool
+		progress           ProgressFunc
+		concurrency        int
+		// path to registries config file e.g. ~/.config/helm/registry/registries.yaml
+		registriesConfig string
+		mirrors          map[string][]*mirrorHost
+		retryPolicy      RetryPolicy
+		failures         *failedRequests
 	}
 
 	// ClientOption allows specifying various settings configurable by the user for overriding the defaults
@@ -72,7 +83,10 @@ type (
 // NewClient returns a new registry client with config
 func NewClient(options ...ClientOption) (*Client, error) {
 	client := &Client{
-		out: io.Discard,
+		out:         io.Discard,
+		concurrency: DefaultConcurrency,
+		retryPolicy: DefaultRetryPolicy,
+		failures:    newFailedRequests(),
 	}
 	for _, option := range options {
 		option(client)
@@ -87,6 +101,33 @@ func NewClient(options ...ClientOption) (*Client, error) {
 		}
 		client.authorizer = authClient
 	}
+	httpClient := client.httpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	httpClient = client.withRetries(httpClient)
+	client.httpClient = httpClient
+	if client.registriesConfig != "" {
+		cfg, err := LoadRegistriesConfig(client.registriesConfig)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		if cfg != nil {
+			mirrors, err := newMirrorHosts(cfg, httpClient, client.withRetries)
+			if err != nil {
+				return nil, err
+			}
+			client.mirrors = mirrors
+		}
+	}
+	client.credentials = newCredentialStore(client.credentialsFile)
+	client.credentials.provider = client.credentialProvider
+	client.credentials.oidc = &oidcStore{
+		path:      filepath.Join(filepath.Dir(client.credentialsFile), OIDCSessionsFileBasename),
+		client:    httpClient,
+		out:       client.out,
+		plainHTTP: client.plainHTTP,
+	}
 
 	resolverFn := client.resolver // copy for avoiding recursive call
 	client.resolver = func(ref registry.Reference) (remotes.Resolver, error) {
@@ -96,20 +137,7 @@ func NewClient(options ...ClientOption) (*Client, error) {
 				return resolver, nil
 			}
 		}
-		headers := http.Header{}
-		headers.Set("User-Agent", version.GetUserAgent())
-		opts := []auth.ResolverOption{auth.WithResolverHeaders(headers)}
-		if client.httpClient != nil {
-			opts = append(opts, auth.WithResolverClient(client.httpClient))
-		}
-		if client.plainHTTP {
-			opts = append(opts, auth.WithResolverPlainHTTP())
-		}
-		resolver, err := client.authorizer.ResolverWithOpts(opts...)
-		if err != nil {
-			return nil, err
-		}
-		return resolver, nil
+		return client.newResolver(ref, false), nil
 	}
 
 	// allocate a cache if option is set
@@ -123,37 +151,66 @@ func NewClient(options ...ClientOption) (*Client, error) {
 			Header: http.Header{
 				"User-Agent": {version.GetUserAgent()},
 			},
-			Cache: cache,
-			Credential: func(_ context.Context, reg string) (registryauth.Credential, error) {
-				dockerClient, ok := client.authorizer.(*dockerauth.Client)
-				if !ok {
-					return registryauth.EmptyCredential, errors.New("unable to obtain docker client")
-				}
-
-				username, password, err := dockerClient.Credential(reg)
-				if err != nil {
-					return registryauth.EmptyCredential, errors.New("unable to retrieve credentials")
-				}
-
-				// A blank returned username and password value is a bearer token
-				if username == "" && password != "" {
-					return registryauth.Credential{
-						RefreshToken: password,
-					}, nil
-				}
-
-				return registryauth.Credential{
-					Username: username,
-					Password: password,
-				}, nil
-
-			},
+			Cache:      cache,
+			Credential: client.registryCredential(""),
 		}
 
 	}
 	return client, nil
 }
 
+// newResolver returns the resolver of ref, authenticating with the stored
+// credentials of its repository unless anonymous is set.
+func (c *Client) newResolver(ref registry.Reference, anonymous bool) remotes.Resolver {
+	headers := http.Header{}
+	headers.Set("User-Agent", version.GetUserAgent())
+	authorizerOpts := []docker.AuthorizerOpt{
+		docker.WithAuthClient(c.httpClient),
+		docker.WithAuthHeader(headers),
+	}
+	if !anonymous {
+		authorizerOpts = append(authorizerOpts, docker.WithAuthCreds(func(host string) (string, string, error) {
+			return c.credentials.RepositoryCredential(host, ref.Repository)
+		}))
+	}
+	opts := []docker.RegistryOpt{docker.WithAuthorizer(docker.NewDockerAuthorizer(authorizerOpts...))}
+	if c.httpClient != nil {
+		opts = append(opts, docker.WithClient(c.httpClient))
+	}
+	if c.plainHTTP {
+		opts = append(opts, docker.WithPlainHTTP(docker.MatchAllHosts))
+	} else {
+		opts = append(opts, docker.WithPlainHTTP(docker.MatchLocalhost))
+	}
+	return docker.NewResolver(docker.ResolverOptions{
+		Hosts:   c.registryHosts(headers, opts...),
+		Headers: headers,
+	})
+}
+
+// registryCredential returns the function resolving the credentials of the
+// registry clients, with the credentials of repository if it is not empty.
+func (c *Client) registryCredential(repository string) func(context.Context, string) (registryauth.Credential, error) {
+	return func(_ context.Context, reg string) (registryauth.Credential, error) {
+		username, password, err := c.credentials.RepositoryCredential(reg, repository)
+		if err != nil {
+			return registryauth.EmptyCredential, err
+		}
+
+		// A blank returned username and password value is a bearer token
+		if username == "" && password != "" {
+			return registryauth.Credential{
+				RefreshToken: password,
+			}, nil
+		}
+
+		return registryauth.Credential{
+			Username: username,
+			Password: password,
+		}, nil
+	}
+}
+
 // ClientOptDebug returns a function that sets the debug setting on client options set
 func ClientOptDebug(debug bool) ClientOption {
 	return func(client *Client) {
@@ -182,6 +239,23 @@ func ClientOptCredentialsFile(credentialsFile string) ClientOption {
 	}
 }
 
+// ClientOptCredentialProvider returns a function that sets the provider of
+// the credentials of the registries without stored credentials on a client
+// options set
+func ClientOptCredentialProvider(provider CredentialProvider) ClientOption {
+	return func(client *Client) {
+		client.credentialProvider = provider
+	}
+}
+
+// ClientOptRegistriesConfig returns a function that sets the registriesConfig setting on a client options set.
+// The mirrors of the config are used if the file exists.
+func ClientOptRegistriesConfig(registriesConfig string) ClientOption {
+	return func(client *Client) {
+		client.registriesConfig = registriesConfig
+	}
+}
+
 // ClientOptHTTPClient returns a function that sets the httpClient setting on a client options set
 func ClientOptHTTPClient(httpClient *http.Client) ClientOption {
 	return func(client *Client) {
@@ -195,6 +269,31 @@ func ClientOptPlainHTTP() ClientOption {
 	}
 }
 
+// ClientOptProgress returns a function that sets the default progress
+// callback of the pushes and the pulls of the client
+func ClientOptProgress(progress ProgressFunc) ClientOption {
+	return func(client *Client) {
+		client.progress = progress
+	}
+}
+
+// ClientOptConcurrency returns a function that sets the maximum number of
+// blobs transferred concurrently by the pushes and the pulls of the client,
+// DefaultConcurrency by default. There is no limit if it is not positive.
+func ClientOptConcurrency(concurrency int) ClientOption {
+	return func(client *Client) {
+		client.concurrency = concurrency
+	}
+}
+
+// ClientOptRetryPolicy returns a function that sets the policy retrying the
+// requests failing with transient errors on client options set
+func ClientOptRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(client *Client) {
+		client.retryPolicy = policy
+	}
+}
+
 // ClientOptResolver returns a function that sets the resolver setting on a client options set
 func ClientOptResolver(resolver remotes.Resolver) ClientOption {
 	return func(client *Client) {
@@ -215,6 +314,7 @@ type (
 		certFile string
 		keyFile  string
 		caFile   string
+		oidc     *OIDCLogin
 	}
 )
 
@@ -224,6 +324,9 @@ func (c *Client) Login(host string, options ...LoginOption) error {
 	for _, option := range options {
 		option(operation)
 	}
+	if operation.oidc != nil {
+		return c.loginOIDC(host, operation)
+	}
 	authorizerLoginOpts := []auth.LoginOption{
 		auth.WithLoginContext(ctx(c.out, c.debug)),
 		auth.WithLoginHostname(host),
@@ -257,6 +360,13 @@ func LoginOptInsecure(insecure bool) LoginOption {
 	}
 }
 
+// LoginOptOIDC returns a function that sets the OIDC login settings on login
+func LoginOptOIDC(login *OIDCLogin) LoginOption {
+	return func(operation *loginOperation) {
+		operation.oidc = login
+	}
+}
+
 // LoginOptTLSClientConfig returns a function that sets the TLS settings on login.
 func LoginOptTLSClientConfig(certFile, keyFile, caFile string) LoginOption {
 	return func(operation *loginOperation) {
@@ -279,9 +389,15 @@ func (c *Client) Logout(host string, opts ...LogoutOption) error {
 	for _, opt := range opts {
 		opt(operation)
 	}
-	if err := c.authorizer.Logout(ctx(c.out, c.debug), host); err != nil {
+	removed, err := c.credentials.oidc.remove(host)
+	if err != nil {
 		return err
 	}
+	if err := c.authorizer.Logout(ctx(c.out, c.debug), host); err != nil {
+		if !removed || !errors.Is(err, auth.ErrNotLoggedIn) {
+			return err
+		}
+	}
 	fmt.Fprintf(c.out, "Removing login credentials for %s\n", host)
 	return nil
 }
@@ -314,11 +430,15 @@ type (
 		withChart         bool
 		withProv          bool
 		ignoreMissingProv bool
+		progress          ProgressFunc
+		digest            string
 	}
 )
 
 // Pull downloads a chart from a registry
-func (c *Client) Pull(ref string, options ...PullOption) (*PullResult, error) {
+func (c *Client) Pull(ref string, options ...PullOption) (_ *PullResult, err error) {
+	defer c.wrapError(&err)
+
 	parsedRef, err := parseReference(ref)
 	if err != nil {
 		return nil, err
@@ -326,6 +446,7 @@ func (c *Client) Pull(ref string, options ...PullOption) (*PullResult, error) {
 
 	operation := &pullOperation{
 		withChart: true, // By default, always download the chart layer
+		progress:  c.progress,
 	}
 	for _, option := range options {
 		option(operation)
@@ -334,7 +455,7 @@ func (c *Client) Pull(ref string, options ...PullOption) (*PullResult, error) {
 		return nil, errors.New(
 			"must specify at least one layer to pull (chart/prov)")
 	}
-	memoryStore := content.NewMemory()
+	var memoryStore *content.Memory
 	allowedMediaTypes := []string{
 		ConfigMediaType,
 	}
@@ -351,20 +472,26 @@ func (c *Client) Pull(ref string, options ...PullOption) (*PullResult, error) {
 	}
 
 	var descriptors, layers []ocispec.Descriptor
-	remotesResolver, err := c.resolver(parsedRef)
+	var manifest ocispec.Descriptor
+	err = c.withAnonymousFallback(parsedRef, func(remotesResolver remotes.Resolver) error {
+		memoryStore = content.NewMemory()
+		registryStore := content.Registry{Resolver: withProgress(withTransfers(remotesResolver, c.concurrency, c.retryPolicy), operation.progress)}
+		var err error
+		manifest, err = oras.Copy(ctx(c.out, c.debug), registryStore, parsedRef.String(), memoryStore, "",
+			oras.WithPullEmptyNameAllowed(),
+			oras.WithAllowedMediaTypes(allowedMediaTypes),
+			oras.WithLayerDescriptors(func(l []ocispec.Descriptor) {
+				layers = l
+			}))
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	registryStore := content.Registry{Resolver: remotesResolver}
 
-	manifest, err := oras.Copy(ctx(c.out, c.debug), registryStore, parsedRef.String(), memoryStore, "",
-		oras.WithPullEmptyNameAllowed(),
-		oras.WithAllowedMediaTypes(allowedMediaTypes),
-		oras.WithLayerDescriptors(func(l []ocispec.Descriptor) {
-			layers = l
-		}))
-	if err != nil {
-		return nil, err
+	if operation.digest != "" && manifest.Digest.String() != operation.digest {
+		return nil, errors.Errorf("manifest digest of %s is %s, expected %s: the tag may have been moved",
+			ref, manifest.Digest, operation.digest)
 	}
 
 	descriptors = append(descriptors, manifest)
@@ -496,6 +623,22 @@ func PullOptWithProv(withProv bool) PullOption {
 	}
 }
 
+// PullOptProgress returns a function that sets the callback reporting the
+// progress of the pull
+func PullOptProgress(progress ProgressFunc) PullOption {
+	return func(operation *pullOperation) {
+		operation.progress = progress
+	}
+}
+
+// PullOptDigest returns a function that sets the digest the manifest of the
+// pulled chart must have
+func PullOptDigest(digest string) PullOption {
+	return func(operation *pullOperation) {
+		operation.digest = digest
+	}
+}
+
 // PullOptIgnoreMissingProv returns a function that sets the ignoreMissingProv setting on pull
 func PullOptIgnoreMissingProv(ignoreMissingProv bool) PullOption {
 	return func(operation *pullOperation) {
@@ -514,6 +657,9 @@ type (
 		Chart    *descriptorPushSummaryWithMeta `json:"chart"`
 		Prov     *descriptorPushSummary         `json:"prov"`
 		Ref      string                         `json:"ref"`
+		// Signature is the reference of the signatures of the chart, if it
+		// was signed.
+		Signature string `json:"signature,omitempty"`
 	}
 
 	descriptorPushSummary struct {
@@ -530,11 +676,15 @@ type (
 		provData     []byte
 		strictMode   bool
 		creationTime string
+		signer       *SignatureSigner
+		progress     ProgressFunc
 	}
 )
 
 // Push uploads a chart to a registry.
-func (c *Client) Push(data []byte, ref string, options ...PushOption) (*PushResult, error) {
+func (c *Client) Push(data []byte, ref string, options ...PushOption) (_ *PushResult, err error) {
+	defer c.wrapError(&err)
+
 	parsedRef, err := parseReference(ref)
 	if err != nil {
 		return nil, err
@@ -542,6 +692,7 @@ func (c *Client) Push(data []byte, ref string, options ...PushOption) (*PushResu
 
 	operation := &pushOperation{
 		strictMode: true, // By default, enable strict mode
+		progress:   c.progress,
 	}
 	for _, option := range options {
 		option(operation)
@@ -598,7 +749,7 @@ func (c *Client) Push(data []byte, ref string, options ...PushOption) (*PushResu
 	if err != nil {
 		return nil, err
 	}
-	registryStore := content.Registry{Resolver: remotesResolver}
+	registryStore := content.Registry{Resolver: withProgress(withTransfers(remotesResolver, c.concurrency, c.retryPolicy), operation.progress)}
 	_, err = oras.Copy(ctx(c.out, c.debug), memoryStore, parsedRef.String(), registryStore, "",
 		oras.WithNameValidation(nil))
 	if err != nil {
@@ -630,6 +781,13 @@ func (c *Client) Push(data []byte, ref string, options ...PushOption) (*PushResu
 	}
 	fmt.Fprintf(c.out, "Pushed: %s\n", result.Ref)
 	fmt.Fprintf(c.out, "Digest: %s\n", result.Manifest.Digest)
+	if operation.signer != nil {
+		result.Signature, err = c.pushSignature(parsedRef, remotesResolver, manifest.Digest, operation.signer)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to sign the chart")
+		}
+		fmt.Fprintf(c.out, "Signed: %s\n", result.Signature)
+	}
 	if strings.Contains(parsedRef.Reference, "_") {
 		fmt.Fprintf(c.out, "%s contains an underscore.\n", result.Ref)
 		fmt.Fprint(c.out, registryUnderscoreMessage+"\n")
@@ -652,6 +810,21 @@ func PushOptStrictMode(strictMode bool) PushOption {
 	}
 }
 
+// PushOptSign returns a function that sets the signer signing the pushed chart
+func PushOptSign(signer *SignatureSigner) PushOption {
+	return func(operation *pushOperation) {
+		operation.signer = signer
+	}
+}
+
+// PushOptProgress returns a function that sets the callback reporting the
+// progress of the push
+func PushOptProgress(progress ProgressFunc) PushOption {
+	return func(operation *pushOperation) {
+		operation.progress = progress
+	}
+}
+
 // PushOptCreationDate returns a function that sets the creation time
 func PushOptCreationTime(creationTime string) PushOption {
 	return func(operation *pushOperation) {
@@ -659,6 +832,26 @@ func PushOptCreationTime(creationTime string) PushOption {
 	}
 }
 
+// Resolve returns the digest of the manifest of the chart at ref
+func (c *Client) Resolve(ref string) (_ string, err error) {
+	defer c.wrapError(&err)
+
+	parsedRef, err := parseReference(ref)
+	if err != nil {
+		return "", err
+	}
+	var desc ocispec.Descriptor
+	err = c.withAnonymousFallback(parsedRef, func(remotesResolver remotes.Resolver) error {
+		var err error
+		_, desc, err = remotesResolver.Resolve(ctx(c.out, c.debug), parsedRef.String())
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return desc.Digest.String(), nil
+}
+
 // Tags provides a sorted list all semver compliant tags for a given repository
 func (c *Client) Tags(ref string) ([]string, error) {
 	parsedReference, err := registry.ParseReference(ref)
@@ -666,17 +859,26 @@ func (c *Client) Tags(ref string) ([]string, error) {
 		return nil, err
 	}
 
-	repository := registryremote.Repository{
-		Reference: parsedReference,
-		Client:    c.registryAuthorizer,
-		PlainHTTP: c.plainHTTP,
-	}
-
 	var registryTags []string
-
-	registryTags, err = registry.Tags(ctx(c.out, c.debug), &repository)
+	var firstErr error
+	for _, repository := range c.tagRepositories(parsedReference) {
+		// Mirrors are tried in order, the first error is reported if none
+		// of them lists the tags
+		registryTags, err = repository.tags(ctx(c.out, c.debug), repository.client)
+		if err != nil && repository.anonymous != nil && credentialsRejected(err) {
+			if anonymousTags, anonErr := repository.tags(ctx(c.out, c.debug), repository.anonymous); anonErr == nil {
+				registryTags, err = anonymousTags, nil
+			}
+		}
+		if err == nil {
+			break
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
 	if err != nil {
-		return nil, err
+		return nil, firstErr
 	}
 
 	var tagVersions []*semver.Version
@@ -63,6 +63,34 @@ func (suite *HTTPRegistryClientTestSuite) Test_4_ManInTheMiddle() {
 	suite.True(errdefs.IsFailedPrecondition(err))
 }
 
+func (suite *HTTPRegistryClientTestSuite) Test_5_Sign() {
+	testSign(&suite.TestSuite)
+}
+
+func (suite *HTTPRegistryClientTestSuite) Test_6_Attach() {
+	testAttach(&suite.TestSuite)
+}
+
+func (suite *HTTPRegistryClientTestSuite) Test_7_Progress() {
+	testProgress(&suite.TestSuite)
+}
+
+func (suite *HTTPRegistryClientTestSuite) Test_8_Mirrors() {
+	testMirrors(&suite.TestSuite)
+}
+
+func (suite *HTTPRegistryClientTestSuite) Test_9_Resolve() {
+	testResolve(&suite.TestSuite)
+}
+
+func (suite *HTTPRegistryClientTestSuite) Test_10_Catalog() {
+	testCatalog(&suite.TestSuite)
+}
+
+func (suite *HTTPRegistryClientTestSuite) Test_11_Bundle() {
+	testBundle(&suite.TestSuite)
+}
+
 func TestHTTPRegistryClientTestSuite(t *testing.T) {
 	suite.Run(t, new(HTTPRegistryClientTestSuite))
 }
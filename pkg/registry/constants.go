@@ -34,4 +34,28 @@ const (
 
 	// LegacyChartLayerMediaType is the legacy reserved media type for Helm chart package content.
 	LegacyChartLayerMediaType = "application/tar+gzip"
+
+	// SignatureLayerMediaType is the media type of the layers of the cosign
+	// signatures of a chart, holding the signed payload
+	SignatureLayerMediaType = "application/vnd.dev.cosign.simplesigning.v1+json"
+
+	// SPDXArtifactType is the artifact type of SPDX software bills of
+	// materials attached to a chart
+	SPDXArtifactType = "application/spdx+json"
+
+	// CycloneDXArtifactType is the artifact type of CycloneDX software bills
+	// of materials attached to a chart
+	CycloneDXArtifactType = "application/vnd.cyclonedx+json"
+
+	// InTotoArtifactType is the artifact type of in-toto attestations, such
+	// as SLSA provenance, attached to a chart
+	InTotoArtifactType = "application/vnd.in-toto+json"
+
+	// PluginArtifactType is the artifact type of the manifests of Helm
+	// plugins
+	PluginArtifactType = "application/vnd.cncf.helm.plugin.v1+json"
+
+	// PluginLayerMediaType is the media type of the layers of Helm plugin
+	// archives
+	PluginLayerMediaType = "application/vnd.cncf.helm.plugin.content.v1.tar+gzip"
 )
@@ -0,0 +1,287 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry // import "helm.sh/helm/v3/pkg/registry"
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/containerd/containerd/remotes"
+	"github.com/containerd/containerd/remotes/docker"
+	remoteserrors "github.com/containerd/containerd/remotes/errors"
+	"github.com/docker/cli/cli/config"
+	"github.com/docker/cli/cli/config/configfile"
+	"github.com/docker/cli/cli/config/credentials"
+	"github.com/pkg/errors"
+	"oras.land/oras-go/pkg/registry"
+)
+
+// dockerHubServer is the key of the credentials of Docker Hub in config.json.
+const dockerHubServer = "https://index.docker.io/v1/"
+
+// credentialStore resolves the credentials of registries from the Helm
+// registry configuration file, falling back to the Docker configuration
+// file.
+//
+// Credentials are read from the "auths" of a file, or obtained from the
+// docker-credential-<helper> program configured for the registry in
+// "credHelpers", or for all registries in "credsStore". This is how cloud
+// registries are usually authenticated, for example with the ecr-login,
+// gcloud or acr-env helpers, without a login. The keys of "credHelpers" may
+// also be patterns such as "*.dkr.ecr.us-east-1.amazonaws.com" matching all
+// the registries of a cloud provider; the longest matching pattern wins.
+//
+// Credentials may be scoped to the repositories under a path of a registry,
+// with keys such as "registry.example.com/team-a" in "auths" or
+// "credHelpers". The credentials of the longest path prefixing a repository
+// are used for it, else the credentials of its registry.
+//
+// The files are read for every lookup, so credentials stored by a login are
+// used right away. The credentials of the registries without stored
+// credentials are obtained from the credential provider, if any.
+type credentialStore struct {
+	paths []string
+	// oidc holds the sessions of the registries logged in with OIDC, which
+	// take precedence over the configuration files
+	oidc *oidcStore
+	// provider provides the credentials of the registries without stored
+	// credentials
+	provider CredentialProvider
+}
+
+// CredentialProvider provides the credentials of hosts, like the
+// credential-provider plugins exchanging cloud identities for short-lived
+// tokens.
+type CredentialProvider interface {
+	// Credential returns the username and the password of host, both empty
+	// if the provider has none for it.
+	Credential(host string) (string, string, error)
+}
+
+func newCredentialStore(paths ...string) *credentialStore {
+	return &credentialStore{paths: paths}
+}
+
+// Credential returns the username and the password of host. An empty
+// username with a password is an identity token.
+func (s *credentialStore) Credential(host string) (string, string, error) {
+	return s.RepositoryCredential(host, "")
+}
+
+// RepositoryCredential returns the username and the password of the
+// repository of host, with the credentials scoped to the longest path
+// prefixing the repository, else the credentials of host.
+func (s *credentialStore) RepositoryCredential(host, repository string) (string, string, error) {
+	if s.oidc != nil {
+		if username, password, ok, err := s.oidc.credential(host); ok || err != nil {
+			return username, password, err
+		}
+	}
+	configs, err := s.configs()
+	if err != nil {
+		return "", "", err
+	}
+
+	// the repository and its parents, longest first
+	for repository != "" && repository != "." {
+		key := host + "/" + repository
+		var scoped []*configfile.ConfigFile
+		for _, cfg := range configs {
+			_, auth := cfg.AuthConfigs[key]
+			_, helper := cfg.CredentialHelpers[key]
+			if auth || helper {
+				scoped = append(scoped, cfg)
+			}
+		}
+		if len(scoped) > 0 {
+			if username, password, err := lookupCredential(scoped, key); username != "" || password != "" || err != nil {
+				return username, password, err
+			}
+		}
+		repository = path.Dir(repository)
+	}
+	username, password, err := lookupCredential(configs, credentialServer(host))
+	if username != "" || password != "" || err != nil || s.provider == nil {
+		return username, password, err
+	}
+	return s.provider.Credential(host)
+}
+
+// lookupCredential returns the credentials stored for key in the first of
+// the configuration files which has some.
+func lookupCredential(configs []*configfile.ConfigFile, key string) (string, string, error) {
+	var problems []string
+	for _, cfg := range configs {
+		store := credentials.NewFileStore(cfg)
+		helper, configured := credentialHelper(cfg, key)
+		if helper != "" {
+			store = credentials.NewNativeStore(cfg, helper)
+		}
+		auth, err := store.Get(key)
+		if err != nil {
+			// a broken default credsStore must not prevent the anonymous
+			// access of public registries
+			if configured {
+				problems = append(problems, errors.Wrapf(err, "credential helper docker-credential-%s", helper).Error())
+			}
+			continue
+		}
+		if auth.IdentityToken != "" {
+			return "", auth.IdentityToken, nil
+		}
+		if auth.Username == "" && auth.Password == "" {
+			continue
+		}
+		return auth.Username, auth.Password, nil
+	}
+	if len(problems) > 0 {
+		return "", "", errors.Errorf("unable to retrieve credentials for %s: %s", key, strings.Join(problems, "; "))
+	}
+	return "", "", nil
+}
+
+// configs loads the configuration files of the store, then the Docker
+// configuration file.
+func (s *credentialStore) configs() ([]*configfile.ConfigFile, error) {
+	var configs []*configfile.ConfigFile
+	for _, p := range s.paths {
+		cfg := configfile.New(p)
+		f, err := os.Open(p)
+		switch {
+		case os.IsNotExist(err):
+		case err != nil:
+			return nil, err
+		default:
+			err = cfg.LoadFromReader(f)
+			f.Close()
+			if err != nil {
+				return nil, errors.Wrap(err, p)
+			}
+		}
+		configs = append(configs, cfg)
+	}
+
+	dockerConfig, err := config.Load(config.Dir())
+	if err != nil {
+		return nil, err
+	}
+	configs = append(configs, dockerConfig)
+
+	for _, cfg := range configs {
+		if !cfg.ContainsAuth() {
+			cfg.CredentialsStore = credentials.DetectDefaultStore(cfg.CredentialsStore)
+		}
+	}
+	return configs, nil
+}
+
+// credentialHelper returns the suffix of the credential helper of host: the
+// helper configured for host in credHelpers, else the helper of the longest
+// pattern of credHelpers matching host, else the default credsStore. It
+// reports whether the helper is configured for host in credHelpers.
+func credentialHelper(cfg *configfile.ConfigFile, host string) (string, bool) {
+	if helper, ok := cfg.CredentialHelpers[host]; ok {
+		return helper, true
+	}
+	var helper, pattern string
+	for p, h := range cfg.CredentialHelpers {
+		if !strings.ContainsAny(p, "*?[") || len(p) < len(pattern) {
+			continue
+		}
+		if ok, _ := path.Match(p, host); ok && (len(p) > len(pattern) || p < pattern) {
+			helper, pattern = h, p
+		}
+	}
+	if helper != "" {
+		return helper, true
+	}
+	return cfg.CredentialsStore, false
+}
+
+// credentialServer returns the key of the credentials of host, mapping the
+// hostnames of Docker Hub to the server Docker stores its credentials for.
+func credentialServer(host string) string {
+	switch host {
+	case "docker.io", "index.docker.io", "registry-1.docker.io":
+		return dockerHubServer
+	}
+	return host
+}
+
+// withAnonymousFallback calls pull with the resolver of ref, then again with
+// an anonymous resolver if the registry rejected the stored credentials of
+// ref, so that public charts are pulled even if the credentials are expired
+// or do not grant access to them.
+func (c *Client) withAnonymousFallback(ref registry.Reference, pull func(remotes.Resolver) error) error {
+	resolver, err := c.resolver(ref)
+	if err != nil {
+		return err
+	}
+	err = pull(resolver)
+	if err == nil || !credentialsRejected(err) || !c.hasCredential(ref) {
+		return err
+	}
+	if c.debug {
+		fmt.Fprintf(c.out, "the credentials of %s were rejected, trying anonymously: %s\n", ref, err)
+	}
+	if anonErr := pull(c.newResolver(ref, true)); anonErr != nil {
+		// the error with the credentials tells why they were rejected
+		return err
+	}
+	return nil
+}
+
+// Credential returns the username and the password stored for the registry
+// host. An empty username with a password is an identity token.
+func (c *Client) Credential(host string) (string, string, error) {
+	return c.credentials.Credential(host)
+}
+
+// hasCredential returns whether credentials are stored for the repository
+// of ref.
+func (c *Client) hasCredential(ref registry.Reference) bool {
+	username, password, err := c.credentials.RepositoryCredential(ref.Registry, ref.Repository)
+	return err == nil && (username != "" || password != "")
+}
+
+// authenticationError is an error of a registry client failing to
+// authenticate a request, such as the rejection of the request of a token.
+type authenticationError struct {
+	err error
+}
+
+func (e *authenticationError) Error() string {
+	return e.err.Error()
+}
+
+func (e *authenticationError) Unwrap() error {
+	return e.err
+}
+
+// credentialsRejected returns whether err is caused by a registry rejecting
+// the credentials of a request.
+func credentialsRejected(err error) bool {
+	var authErr *authenticationError
+	if IsUnauthorized(err) || errors.Is(err, docker.ErrInvalidAuthorization) || errors.As(err, &authErr) {
+		return true
+	}
+	var status remoteserrors.ErrUnexpectedStatus
+	return errors.As(err, &status) && (status.StatusCode == http.StatusUnauthorized || status.StatusCode == http.StatusForbidden)
+}
@@ -0,0 +1,262 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/docker/cli/cli/config"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+const testCredentialHelper = `#!/bin/sh
+read server
+case "$server" in
+fail.example.com)
+	echo "helper failed"
+	exit 1
+	;;
+missing.example.com)
+	echo "credentials not found in native keychain"
+	exit 1
+	;;
+token.example.com)
+	echo '{"ServerURL":"'$server'","Username":"<token>","Secret":"mytoken"}'
+	;;
+*)
+	echo '{"ServerURL":"'$server'","Username":"user@'$server'","Secret":"secret"}'
+	;;
+esac
+`
+
+func TestCredentialStore(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake credential helper is a shell script")
+	}
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "bin")
+	if err := os.Mkdir(bin, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(bin, "docker-credential-helmtest"), []byte(testCredentialHelper), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	auth := func(username, password string) string {
+		return base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	}
+	helmConfig := filepath.Join(dir, "registry", "config.json")
+	if err := os.MkdirAll(filepath.Dir(helmConfig), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(helmConfig, []byte(`{
+	"auths": {
+		"auths.example.com": {"auth": "`+auth("helm", "pass")+`"},
+		"auths.example.com/team-a": {"auth": "`+auth("team-a", "pass")+`"}
+	},
+	"credHelpers": {
+		"exact.example.com": "helmtest",
+		"exact.example.com/team-b/charts": "helmtest",
+		"fail.example.com": "helmtest",
+		"missing.example.com": "helmtest",
+		"token.example.com": "helmtest",
+		"*.amazonaws.com": "missing",
+		"*.dkr.ecr.us-east-1.amazonaws.com": "helmtest"
+	}
+}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dockerDir := filepath.Join(dir, "docker")
+	if err := os.Mkdir(dockerDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dockerDir, "config.json"), []byte(`{
+	"auths": {
+		"docker.example.com": {"auth": "`+auth("docker", "pass")+`"},
+		"https://index.docker.io/v1/": {"auth": "`+auth("hub", "pass")+`"}
+	}
+}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	oldDir := config.Dir()
+	config.SetDir(dockerDir)
+	defer config.SetDir(oldDir)
+
+	store := newCredentialStore(helmConfig)
+	for _, tt := range []struct {
+		host, repository   string
+		username, password string
+		wantErr            bool
+	}{
+		{host: "exact.example.com", username: "user@exact.example.com", password: "secret"},
+		{host: "123456789012.dkr.ecr.us-east-1.amazonaws.com", username: "user@123456789012.dkr.ecr.us-east-1.amazonaws.com", password: "secret"},
+		{host: "token.example.com", password: "mytoken"},
+		{host: "auths.example.com", username: "helm", password: "pass"},
+		{host: "auths.example.com", repository: "team-a", username: "team-a", password: "pass"},
+		{host: "auths.example.com", repository: "team-a/charts/mychart", username: "team-a", password: "pass"},
+		{host: "auths.example.com", repository: "team-ab/mychart", username: "helm", password: "pass"},
+		{host: "exact.example.com", repository: "team-b/charts/mychart", username: "user@exact.example.com/team-b/charts", password: "secret"},
+		{host: "exact.example.com", repository: "team-b/mychart", username: "user@exact.example.com", password: "secret"},
+		{host: "docker.example.com", username: "docker", password: "pass"},
+		{host: "registry-1.docker.io", username: "hub", password: "pass"},
+		{host: "missing.example.com"},
+		{host: "unknown.example.com"},
+		{host: "fail.example.com", wantErr: true},
+		{host: "s3.amazonaws.com", wantErr: true},
+	} {
+		t.Run(path.Join(tt.host, tt.repository), func(t *testing.T) {
+			username, password, err := store.RepositoryCredential(tt.host, tt.repository)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected an error, got %q %q", username, password)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if username != tt.username || password != tt.password {
+				t.Errorf("Expected %q %q, got %q %q", tt.username, tt.password, username, password)
+			}
+		})
+	}
+}
+
+// credentialProviderFunc is a CredentialProvider calling the function.
+type credentialProviderFunc func(host string) (string, string, error)
+
+func (f credentialProviderFunc) Credential(host string) (string, string, error) {
+	return f(host)
+}
+
+func TestCredentialStoreProvider(t *testing.T) {
+	dir := t.TempDir()
+	helmConfig := filepath.Join(dir, "config.json")
+	auth := base64.StdEncoding.EncodeToString([]byte("helm:pass"))
+	if err := os.WriteFile(helmConfig, []byte(`{"auths": {"auths.example.com": {"auth": "`+auth+`"}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	oldDir := config.Dir()
+	config.SetDir(filepath.Join(dir, "docker"))
+	defer config.SetDir(oldDir)
+
+	store := newCredentialStore(helmConfig)
+	store.provider = credentialProviderFunc(func(host string) (string, string, error) {
+		if host == "provided.example.com" {
+			return "", "token", nil
+		}
+		return "", "", nil
+	})
+	for _, tt := range []struct {
+		host               string
+		username, password string
+	}{
+		{host: "auths.example.com", username: "helm", password: "pass"},
+		{host: "provided.example.com", password: "token"},
+		{host: "unknown.example.com"},
+	} {
+		username, password, err := store.Credential(tt.host)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if username != tt.username || password != tt.password {
+			t.Errorf("%s: expected %q %q, got %q %q", tt.host, tt.username, tt.password, username, password)
+		}
+	}
+}
+
+func TestAnonymousFallback(t *testing.T) {
+	manifest := []byte(`{"schemaVersion":2,"mediaType":"application/vnd.oci.image.manifest.v1+json","config":{"mediaType":"` + ConfigMediaType + `","digest":"sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a","size":2},"layers":[]}`)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			// the stored credentials are rejected, anonymous tokens are
+			// granted for the public repository
+			if r.Method != http.MethodGet || r.Header.Get("Authorization") != "" || !strings.Contains(r.URL.Query().Get("scope"), "public/") {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"token":"anonymous"}`)
+		case r.Header.Get("Authorization") != "Bearer anonymous":
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="http://%s/token",service="test"`, r.Host))
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/v2/public/mychart/tags/list":
+			fmt.Fprint(w, `{"tags":["0.1.0","0.2.0"]}`)
+		case r.URL.Path == "/v2/public/mychart/manifests/0.1.0":
+			w.Header().Set("Content-Type", ocispec.MediaTypeImageManifest)
+			w.Header().Set("Docker-Content-Digest", digest.FromBytes(manifest).String())
+			w.Header().Set("Content-Length", fmt.Sprint(len(manifest)))
+			w.Write(manifest)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+
+	dir := t.TempDir()
+	credentialsFile := filepath.Join(dir, "config.json")
+	auth := base64.StdEncoding.EncodeToString([]byte("expired:credentials"))
+	if err := os.WriteFile(credentialsFile, []byte(`{"auths":{"`+u.Host+`":{"auth":"`+auth+`"}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	oldDir := config.Dir()
+	config.SetDir(dir)
+	defer config.SetDir(oldDir)
+
+	client, err := NewClient(ClientOptCredentialsFile(credentialsFile), ClientOptPlainHTTP(), ClientOptRetryPolicy(RetryPolicy{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tags, err := client.Tags(u.Host + "/public/mychart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(tags, ",") != "0.2.0,0.1.0" {
+		t.Errorf("Unexpected tags %v", tags)
+	}
+	d, err := client.Resolve(u.Host + "/public/mychart:0.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d != digest.FromBytes(manifest).String() {
+		t.Errorf("Unexpected digest %s", d)
+	}
+
+	// private charts are not pulled anonymously, and the rejection of the
+	// credentials is reported
+	if _, err := client.Resolve(u.Host + "/private/mychart:0.1.0"); !IsUnauthorized(err) {
+		t.Errorf("Expected the credentials to be rejected, got %v", err)
+	}
+	if _, err := client.Tags(u.Host + "/private/mychart"); err == nil {
+		t.Error("Expected the tags of a private chart not to be listed")
+	}
+}
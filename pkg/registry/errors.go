@@ -0,0 +1,252 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry // import "helm.sh/helm/v3/pkg/registry"
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	remoteserrors "github.com/containerd/containerd/remotes/errors"
+	"github.com/pkg/errors"
+)
+
+// requestIDHeaders are the headers in which registries return the
+// identifier of a request, to be quoted to their support.
+var requestIDHeaders = []string{
+	"X-Request-Id",
+	"X-Github-Request-Id",
+	"X-Amzn-Requestid",
+	"X-Ms-Request-Id",
+	"Request-Id",
+}
+
+// maxErrorBodySize bounds the size of the error responses read.
+const maxErrorBodySize = 64 << 10
+
+// Error is a request to a registry which failed with an error response.
+//
+// The errors of the operations of Client wrap an Error when a registry
+// rejected a request, so they can be told apart with errors.As, IsUnauthorized
+// and IsRateLimited.
+type Error struct {
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+	// Registry is the host of the registry
+	Registry string
+	// Repository is the repository of the request, if any
+	Repository string
+	// RequestID is the identifier the registry gave to the request, if any
+	RequestID string
+	// Method is the method of the request
+	Method string
+	// URL is the URL of the request
+	URL string
+	// Message is the message of the errors returned by the registry, if any
+	Message string
+
+	err error
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s: %s", e.Method, e.URL, statusText(e.StatusCode))
+	if e.Message != "" {
+		fmt.Fprintf(&b, ": %s", e.Message)
+	}
+	if e.RequestID != "" {
+		fmt.Fprintf(&b, " (request ID %s)", e.RequestID)
+	}
+	return b.String()
+}
+
+// Unwrap returns the error the Error was made from, if any.
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// IsUnauthorized returns whether err is caused by a registry rejecting the
+// credentials, or the lack of credentials, of a request.
+func IsUnauthorized(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && (e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden)
+}
+
+// IsRateLimited returns whether err is caused by a registry rate limiting
+// the requests.
+func IsRateLimited(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.StatusCode == http.StatusTooManyRequests
+}
+
+func statusText(code int) string {
+	return fmt.Sprintf("%d %s", code, http.StatusText(code))
+}
+
+// newResponseError returns the Error of the error response resp. The body of
+// resp is read, and replaced so it can be read again.
+func newResponseError(resp *http.Response) *Error {
+	e := &Error{StatusCode: resp.StatusCode}
+	if req := resp.Request; req != nil {
+		e.Method = req.Method
+		e.URL = req.URL.String()
+		e.Registry = req.URL.Host
+		e.Repository = requestRepository(req.URL)
+	}
+	for _, h := range requestIDHeaders {
+		if id := resp.Header.Get(h); id != "" {
+			e.RequestID = id
+			break
+		}
+	}
+	if resp.Body != nil {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		e.Message = errorMessage(body)
+	}
+	return e
+}
+
+// errorMessage returns the messages of the errors of the body of an error
+// response of the distribution API.
+func errorMessage(body []byte) string {
+	var resp struct {
+		Errors []struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return ""
+	}
+	msgs := make([]string, 0, len(resp.Errors))
+	for _, e := range resp.Errors {
+		switch {
+		case e.Message == "":
+			msgs = append(msgs, strings.ToLower(e.Code))
+		case e.Code == "":
+			msgs = append(msgs, e.Message)
+		default:
+			msgs = append(msgs, fmt.Sprintf("%s: %s", strings.ToLower(e.Code), e.Message))
+		}
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// requestRepository returns the repository of a request of the distribution
+// API, such as "/v2/charts/mychart/manifests/1.0.0".
+func requestRepository(u *url.URL) string {
+	p := strings.TrimPrefix(u.Path, "/v2/")
+	if p == u.Path {
+		return ""
+	}
+	parts := strings.Split(p, "/")
+	for i := len(parts) - 2; i > 0; i-- {
+		switch parts[i] {
+		case "manifests", "blobs", "tags", "referrers":
+			return strings.Join(parts[:i], "/")
+		}
+	}
+	return ""
+}
+
+// maxFailedRequests bounds the failed requests remembered by a client.
+const maxFailedRequests = 64
+
+// failedRequests remembers the errors of the last failed requests of a
+// client, so the errors of the containerd resolvers, which only keep the
+// status of the responses, are completed with what the registry returned.
+type failedRequests struct {
+	mu     sync.Mutex
+	errors map[string]*Error
+	order  []string
+}
+
+func newFailedRequests() *failedRequests {
+	return &failedRequests{errors: map[string]*Error{}}
+}
+
+func (f *failedRequests) add(e *Error) {
+	key := e.Method + " " + e.URL
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.errors[key]; !ok {
+		if len(f.order) >= maxFailedRequests {
+			delete(f.errors, f.order[0])
+			f.order = f.order[1:]
+		}
+		f.order = append(f.order, key)
+	}
+	f.errors[key] = e
+}
+
+func (f *failedRequests) get(method, url string) *Error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.errors[method+" "+url]
+}
+
+// wrapError replaces *errp with an error wrapping the Error of the failed
+// request causing it, if any, keeping the message of *errp.
+func (c *Client) wrapError(errp *error) {
+	err := *errp
+	var e *Error
+	if err == nil || errors.As(err, &e) {
+		return
+	}
+	var status remoteserrors.ErrUnexpectedStatus
+	if !errors.As(err, &status) {
+		return
+	}
+	if c.failures != nil {
+		e = c.failures.get(status.RequestMethod, status.RequestURL)
+	}
+	if e == nil || e.StatusCode != status.StatusCode {
+		e = &Error{StatusCode: status.StatusCode, Method: status.RequestMethod, URL: status.RequestURL, Message: errorMessage(status.Body)}
+		if u, perr := url.Parse(status.RequestURL); perr == nil {
+			e.Registry = u.Host
+			e.Repository = requestRepository(u)
+		}
+	}
+	wrapped := *e
+	wrapped.err = err
+	*errp = &wrappedError{msg: err.Error(), err: &wrapped}
+}
+
+// wrappedError is an error with the message of an error which does not wrap
+// the Error causing it.
+type wrappedError struct {
+	msg string
+	err *Error
+}
+
+func (w *wrappedError) Error() string {
+	if w.err.RequestID != "" {
+		return fmt.Sprintf("%s (request ID %s)", w.msg, w.err.RequestID)
+	}
+	return w.msg
+}
+
+func (w *wrappedError) Unwrap() error {
+	return w.err
+}
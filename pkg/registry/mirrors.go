@@ -0,0 +1,380 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry // import "helm.sh/helm/v3/pkg/registry"
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/containerd/containerd/remotes/docker"
+	"github.com/pkg/errors"
+	"oras.land/oras-go/pkg/registry"
+	registryauth "oras.land/oras-go/pkg/registry/remote/auth"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/internal/tlsutil"
+)
+
+// The capabilities of registry mirrors.
+const (
+	// MirrorCapabilityPull allows fetching manifests and blobs by digest.
+	MirrorCapabilityPull = "pull"
+	// MirrorCapabilityResolve allows resolving tags and listing them.
+	MirrorCapabilityResolve = "resolve"
+	// MirrorCapabilityPush allows pushing charts.
+	MirrorCapabilityPush = "push"
+)
+
+// RegistriesConfig maps the hosts of registries to the mirrors serving them,
+// like the hosts.toml files of containerd. It lets charts be pulled from
+// pull-through caches or from air-gapped copies of registries, while they
+// are still referenced by their original registry.
+//
+// The configuration is usually loaded from a YAML file with
+// LoadRegistriesConfig:
+//
+//	registries:
+//	  ghcr.io:
+//	    mirrors:
+//	    - endpoint: https://mirror.example.com
+//	      caFile: mirror-ca.pem
+//	    - endpoint: https://harbor.example.com/v2/ghcr-proxy
+//	      username: robot
+//	      password: secret
+type RegistriesConfig struct {
+	// Registries are the configurations of the registries, by host.
+	Registries map[string]RegistryHostConfig `json:"registries"`
+}
+
+// RegistryHostConfig configures the hosts serving a registry.
+type RegistryHostConfig struct {
+	// Mirrors are tried in order before the registry itself.
+	Mirrors []RegistryMirror `json:"mirrors,omitempty"`
+}
+
+// RegistryMirror is a host serving the content of a registry.
+type RegistryMirror struct {
+	// Endpoint is the URL of the mirror. Plain HTTP is used for http URLs.
+	// Its path is the path of the registry API of the mirror, "/v2" if it is
+	// empty. The path is joined with the repositories, so repositories are
+	// mapped to the project of a Harbor proxy cache with a path such as
+	// "/v2/proxy".
+	Endpoint string `json:"endpoint"`
+	// Capabilities are the operations the mirror is used for, "pull" and
+	// "resolve" if it is empty. Pushes go to the registry unless a mirror
+	// has the "push" capability.
+	Capabilities []string `json:"capabilities,omitempty"`
+	// Username and Password are the credentials of the mirror. The
+	// credentials of the host of the mirror, as stored by
+	// 'helm registry login', are used if they are empty.
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// CertFile and KeyFile are the paths of the client certificate and key
+	// authenticating to the mirror.
+	CertFile string `json:"certFile,omitempty"`
+	KeyFile  string `json:"keyFile,omitempty"`
+	// CAFile is the path of the certificate authorities verifying the
+	// certificate of the mirror.
+	CAFile string `json:"caFile,omitempty"`
+	// InsecureSkipTLSVerify skips the verification of the certificate of the
+	// mirror.
+	InsecureSkipTLSVerify bool `json:"insecureSkipTLSVerify,omitempty"`
+}
+
+// LoadRegistriesConfig reads a registries configuration from a YAML file.
+// The paths of the configuration are relative to the directory of the file.
+func LoadRegistriesConfig(path string) (*RegistriesConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read registries config")
+	}
+	cfg := &RegistriesConfig{}
+	if err := yaml.UnmarshalStrict(b, cfg); err != nil {
+		return nil, errors.Wrapf(err, "invalid registries config %s", path)
+	}
+
+	dir := filepath.Dir(path)
+	resolve := func(name string) string {
+		if name == "" || filepath.IsAbs(name) {
+			return name
+		}
+		return filepath.Join(dir, name)
+	}
+	for host, reg := range cfg.Registries {
+		for i := range reg.Mirrors {
+			m := &reg.Mirrors[i]
+			m.CertFile = resolve(m.CertFile)
+			m.KeyFile = resolve(m.KeyFile)
+			m.CAFile = resolve(m.CAFile)
+			if _, err := m.endpoint(); err != nil {
+				return nil, errors.Wrapf(err, "invalid registries config %s: registry %s", path, host)
+			}
+			if _, err := m.capabilities(); err != nil {
+				return nil, errors.Wrapf(err, "invalid registries config %s: registry %s", path, host)
+			}
+		}
+	}
+	return cfg, nil
+}
+
+func (m *RegistryMirror) endpoint() (*url.URL, error) {
+	u, err := url.Parse(m.Endpoint)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid mirror endpoint %q", m.Endpoint)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, errors.Errorf("invalid mirror endpoint %q: must be an http or https URL", m.Endpoint)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	if u.Path == "" {
+		u.Path = "/v2"
+	}
+	return u, nil
+}
+
+func (m *RegistryMirror) capabilities() (docker.HostCapabilities, error) {
+	if len(m.Capabilities) == 0 {
+		return docker.HostCapabilityPull | docker.HostCapabilityResolve, nil
+	}
+	var caps docker.HostCapabilities
+	for _, c := range m.Capabilities {
+		switch c {
+		case MirrorCapabilityPull:
+			caps |= docker.HostCapabilityPull
+		case MirrorCapabilityResolve:
+			caps |= docker.HostCapabilityResolve
+		case MirrorCapabilityPush:
+			caps |= docker.HostCapabilityPush
+		default:
+			return 0, errors.Errorf("invalid capability %q of mirror %s", c, m.Endpoint)
+		}
+	}
+	return caps, nil
+}
+
+// mirrorHost is a mirror prepared for the requests of a client.
+type mirrorHost struct {
+	url          *url.URL
+	capabilities docker.HostCapabilities
+	client       *http.Client
+	username     string
+	password     string
+}
+
+// newMirrorHosts prepares the mirrors of the registries, by host, using
+// the HTTP client unless a mirror has its own TLS configuration. The clients
+// of the mirrors with their own TLS configuration are made with newClient.
+func newMirrorHosts(cfg *RegistriesConfig, httpClient *http.Client, newClient func(*http.Client) *http.Client) (map[string][]*mirrorHost, error) {
+	hosts := map[string][]*mirrorHost{}
+	for host, reg := range cfg.Registries {
+		for _, m := range reg.Mirrors {
+			u, err := m.endpoint()
+			if err != nil {
+				return nil, err
+			}
+			caps, err := m.capabilities()
+			if err != nil {
+				return nil, err
+			}
+			client := httpClient
+			if m.CertFile != "" || m.KeyFile != "" || m.CAFile != "" || m.InsecureSkipTLSVerify {
+				tlsConf, err := tlsutil.NewClientTLS(m.CertFile, m.KeyFile, m.CAFile, m.InsecureSkipTLSVerify)
+				if err != nil {
+					return nil, errors.Wrapf(err, "can't create TLS config for mirror %s", m.Endpoint)
+				}
+				client = newClient(&http.Client{
+					Transport: &http.Transport{
+						TLSClientConfig: tlsConf,
+						Proxy:           http.ProxyFromEnvironment,
+					},
+				})
+			}
+			hosts[host] = append(hosts[host], &mirrorHost{
+				url:          u,
+				capabilities: caps,
+				client:       client,
+				username:     m.Username,
+				password:     m.Password,
+			})
+		}
+	}
+	return hosts, nil
+}
+
+// registryHosts returns the hosts of the registries for the resolvers of
+// the client: the mirrors of a registry, then the registry itself.
+func (c *Client) registryHosts(headers http.Header, opts ...docker.RegistryOpt) docker.RegistryHosts {
+	upstream := docker.ConfigureDefaultRegistries(opts...)
+	return func(host string) ([]docker.RegistryHost, error) {
+		hosts, err := upstream(host)
+		if err != nil {
+			return nil, err
+		}
+		mirrors := c.mirrors[host]
+		if len(mirrors) == 0 {
+			return hosts, nil
+		}
+		result := make([]docker.RegistryHost, 0, len(mirrors)+len(hosts))
+		for _, m := range mirrors {
+			result = append(result, docker.RegistryHost{
+				Client: m.client,
+				Authorizer: docker.NewDockerAuthorizer(
+					docker.WithAuthClient(m.client),
+					docker.WithAuthHeader(headers),
+					docker.WithAuthCreds(c.mirrorCredential(m))),
+				Host:         m.url.Host,
+				Scheme:       m.url.Scheme,
+				Path:         m.url.Path,
+				Capabilities: m.capabilities,
+			})
+		}
+		return append(result, hosts...), nil
+	}
+}
+
+// mirrorCredential returns the credentials of the mirror, or the stored
+// credentials of its host.
+func (c *Client) mirrorCredential(m *mirrorHost) func(string) (string, string, error) {
+	return func(host string) (string, string, error) {
+		if m.username != "" || m.password != "" {
+			return m.username, m.password, nil
+		}
+		return c.credentials.Credential(host)
+	}
+}
+
+// tagRepository is a repository listing the tags of a chart.
+type tagRepository struct {
+	ref       registry.Reference
+	client    *registryauth.Client
+	plainHTTP bool
+	// anonymous lists the tags if the registry rejects the credentials of
+	// client, if it is set.
+	anonymous *registryauth.Client
+}
+
+// tagRepositories returns the repositories listing the tags of ref: the
+// mirrors of its registry which resolve tags, then the registry itself.
+// Mirrors whose path is not under "/v2" cannot list tags and are skipped.
+func (c *Client) tagRepositories(ref registry.Reference) []*tagRepository {
+	var repositories []*tagRepository
+	for _, m := range c.mirrors[ref.Registry] {
+		if !m.capabilities.Has(docker.HostCapabilityResolve) {
+			continue
+		}
+		prefix := strings.TrimPrefix(m.url.Path, "/v2")
+		if prefix != "" && !strings.HasPrefix(prefix, "/") {
+			continue
+		}
+		authorizer := *c.registryAuthorizer
+		authorizer.Client = m.client
+		if m.username != "" || m.password != "" {
+			cred := registryauth.Credential{Username: m.username, Password: m.password}
+			authorizer.Credential = func(context.Context, string) (registryauth.Credential, error) {
+				return cred, nil
+			}
+		}
+		repositories = append(repositories, &tagRepository{
+			ref: registry.Reference{
+				Registry:   m.url.Host,
+				Repository: strings.TrimPrefix(path.Join(prefix, ref.Repository), "/"),
+			},
+			client:    &authorizer,
+			plainHTTP: m.url.Scheme == "http",
+		})
+	}
+	upstream := &tagRepository{
+		ref:       ref,
+		client:    c.registryAuthorizer,
+		plainHTTP: c.plainHTTP,
+	}
+	if c.hasCredential(ref) {
+		authorizer := *c.registryAuthorizer
+		authorizer.Credential = c.registryCredential(ref.Repository)
+		upstream.client = &authorizer
+		anonymous := *c.registryAuthorizer
+		anonymous.Cache = nil
+		anonymous.Credential = nil
+		upstream.anonymous = &anonymous
+	}
+	return append(repositories, upstream)
+}
+
+// maxTagsPages bounds the pages of tags followed for a repository.
+const maxTagsPages = 100
+
+// tags lists the tags of the repository with the tags API, following the
+// pages of the results.
+func (r *tagRepository) tags(ctx context.Context, client *registryauth.Client) ([]string, error) {
+	scheme := "https"
+	if r.plainHTTP {
+		scheme = "http"
+	}
+	u := fmt.Sprintf("%s://%s/v2/%s/tags/list", scheme, r.ref.Host(), r.ref.Repository)
+	ctx = registryauth.WithScopes(ctx, registryauth.ScopeRepository(r.ref.Repository, registryauth.ActionPull))
+
+	var tags []string
+	for page := 0; u != "" && page < maxTagsPages; page++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			var uerr *url.Error
+			if !errors.As(err, &uerr) {
+				return nil, &authenticationError{err: err}
+			}
+			return nil, err
+		}
+		names, next, err := readTagsPage(resp)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, names...)
+		u = next
+	}
+	return tags, nil
+}
+
+// readTagsPage reads a page of the tags API and returns the URL of the next
+// page, if any.
+func readTagsPage(resp *http.Response) ([]string, string, error) {
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", newResponseError(resp)
+	}
+	var list struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 4<<20)).Decode(&list); err != nil {
+		return nil, "", errors.Wrap(err, "invalid tags list")
+	}
+	next, err := nextPageURL(resp)
+	if err != nil {
+		return nil, "", err
+	}
+	return list.Tags, next, nil
+}
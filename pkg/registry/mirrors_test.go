@@ -0,0 +1,88 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/containerd/containerd/remotes/docker"
+	"github.com/pkg/errors"
+)
+
+func TestLoadRegistriesConfig(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "registries.yaml")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	cfg, err := LoadRegistriesConfig(write(`registries:
+  ghcr.io:
+    mirrors:
+    - endpoint: https://mirror.example.com/
+      caFile: ca.pem
+    - endpoint: http://10.0.0.1:5000/v2/proxy
+      capabilities: [pull]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mirrors := cfg.Registries["ghcr.io"].Mirrors
+	if len(mirrors) != 2 {
+		t.Fatalf("Expected 2 mirrors, got %d", len(mirrors))
+	}
+	if mirrors[0].CAFile != filepath.Join(dir, "ca.pem") {
+		t.Errorf("Expected the CA file to be relative to the config, got %q", mirrors[0].CAFile)
+	}
+
+	u, _ := mirrors[0].endpoint()
+	if u.Path != "/v2" {
+		t.Errorf("Expected the default API path, got %q", u.Path)
+	}
+	caps, _ := mirrors[0].capabilities()
+	if !caps.Has(docker.HostCapabilityPull|docker.HostCapabilityResolve) || caps.Has(docker.HostCapabilityPush) {
+		t.Errorf("Expected mirrors to pull and resolve by default, got %v", caps)
+	}
+	u, _ = mirrors[1].endpoint()
+	if u.Scheme != "http" || u.Path != "/v2/proxy" {
+		t.Errorf("Expected the endpoint to be kept, got %s", u)
+	}
+	caps, _ = mirrors[1].capabilities()
+	if caps != docker.HostCapabilityPull {
+		t.Errorf("Expected the configured capabilities, got %v", caps)
+	}
+
+	for _, invalid := range []string{
+		"registries:\n  ghcr.io:\n    mirrors:\n    - endpoint: mirror.example.com\n",
+		"registries:\n  ghcr.io:\n    mirrors:\n    - endpoint: ftp://mirror.example.com\n",
+		"registries:\n  ghcr.io:\n    mirrors:\n    - endpoint: https://mirror.example.com\n      capabilities: [delete]\n",
+		"registries:\n  ghcr.io:\n    mirror: https://mirror.example.com\n",
+	} {
+		if _, err := LoadRegistriesConfig(write(invalid)); err == nil {
+			t.Errorf("Expected config %q to be invalid", invalid)
+		}
+	}
+
+	if _, err := LoadRegistriesConfig(filepath.Join(dir, "missing.yaml")); !os.IsNotExist(errors.Cause(err)) {
+		t.Errorf("Expected a missing config to fail with a not exist error, got %v", err)
+	}
+}
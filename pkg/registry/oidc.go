@@ -0,0 +1,580 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry // import "helm.sh/helm/v3/pkg/registry"
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	registryauth "oras.land/oras-go/pkg/registry/remote/auth"
+
+	"helm.sh/helm/v3/internal/tlsutil"
+)
+
+const (
+	// OIDCSessionsFileBasename is the filename of the OIDC sessions of the
+	// registries logged in with OIDC, next to the credentials file
+	OIDCSessionsFileBasename = "oidc.json"
+
+	tokenExchangeGrantType = "urn:ietf:params:oauth:grant-type:token-exchange"
+	deviceCodeGrantType    = "urn:ietf:params:oauth:grant-type:device_code"
+	idTokenType            = "urn:ietf:params:oauth:token-type:id_token"
+	refreshTokenType       = "urn:ietf:params:oauth:token-type:refresh_token"
+
+	// accessTokenUsername is the username of the registry access tokens
+	// issued by a token exchange.
+	accessTokenUsername = "oauth2accesstoken"
+
+	// tokenExpiryLeeway is how long before their expiry the tokens of a
+	// session are refreshed.
+	tokenExpiryLeeway = 30 * time.Second
+)
+
+// OIDCLogin configures a registry login with an OIDC identity token, which
+// is exchanged for a registry token with the OAuth 2.0 token exchange of the
+// registry (RFC 8693).
+//
+// The identity token is either a workload identity token read from
+// TokenFile, such as a projected Kubernetes service account token, or it is
+// obtained interactively from Issuer with the device authorization grant
+// (RFC 8628). The registry token is refreshed when it expires, with the
+// token file or the refresh token of the issuer, without another login.
+type OIDCLogin struct {
+	// Issuer is the URL of the OIDC provider
+	Issuer string `json:"issuer,omitempty"`
+	// ClientID is the client ID of Helm at the OIDC provider
+	ClientID string `json:"clientID,omitempty"`
+	// TokenFile is the path of a workload identity token
+	TokenFile string `json:"tokenFile,omitempty"`
+	// ExchangeURL is the token exchange endpoint of the registry. It
+	// defaults to the realm of the bearer challenge of the registry.
+	ExchangeURL string `json:"exchangeURL,omitempty"`
+}
+
+// oidcSession is an OIDC login to a registry, stored in the OIDC sessions
+// file.
+type oidcSession struct {
+	OIDCLogin
+	// Service is the service of the bearer challenge of the registry
+	Service string `json:"service,omitempty"`
+	// RefreshToken is the refresh token of the OIDC provider
+	RefreshToken string `json:"refreshToken,omitempty"`
+	// Username is the username of Token, empty for identity tokens
+	Username string `json:"username,omitempty"`
+	// Token is the registry token
+	Token string `json:"token"`
+	// Expiry is the time Token expires at, if it does
+	Expiry time.Time `json:"expiry,omitempty"`
+}
+
+// oidcSessionsFile is the content of the OIDC sessions file.
+type oidcSessionsFile struct {
+	Sessions map[string]*oidcSession `json:"sessions"`
+}
+
+// oidcStore keeps the OIDC sessions of registries in a file, refreshing
+// their tokens when they expire.
+type oidcStore struct {
+	path      string
+	client    *http.Client
+	out       io.Writer
+	plainHTTP bool
+}
+
+// credential returns the credentials of the OIDC session of host, if any,
+// refreshing its token if it has expired.
+func (s *oidcStore) credential(host string) (username, password string, ok bool, err error) {
+	sessions, err := s.load()
+	if err != nil {
+		return "", "", false, err
+	}
+	session, ok := sessions.Sessions[host]
+	if !ok {
+		return "", "", false, nil
+	}
+	if !session.expired() {
+		return session.Username, session.Token, true, nil
+	}
+
+	idToken, err := s.identityToken(session, false)
+	if err != nil {
+		return "", "", true, errors.Wrapf(err, "failed to refresh the OIDC login of %s", host)
+	}
+	if err := s.exchange(host, session, idToken); err != nil {
+		return "", "", true, errors.Wrapf(err, "failed to refresh the OIDC login of %s", host)
+	}
+	if err := s.save(host, session); err != nil {
+		return "", "", true, err
+	}
+	return session.Username, session.Token, true, nil
+}
+
+// login obtains an identity token for the registry at host, exchanges it
+// and stores the session.
+func (s *oidcStore) login(host string, login *OIDCLogin) error {
+	if login.TokenFile == "" && (login.Issuer == "" || login.ClientID == "") {
+		return errors.New("an OIDC issuer and client ID, or a workload identity token file, are required")
+	}
+	session := &oidcSession{OIDCLogin: *login}
+	if session.TokenFile != "" {
+		// the file is read again on refresh
+		abs, err := filepath.Abs(session.TokenFile)
+		if err != nil {
+			return err
+		}
+		session.TokenFile = abs
+	}
+	idToken, err := s.identityToken(session, true)
+	if err != nil {
+		return err
+	}
+	if err := s.exchange(host, session, idToken); err != nil {
+		return err
+	}
+	return s.save(host, session)
+}
+
+// remove removes the OIDC session of host, reporting whether it existed.
+func (s *oidcStore) remove(host string) (bool, error) {
+	sessions, err := s.load()
+	if err != nil {
+		return false, err
+	}
+	if _, ok := sessions.Sessions[host]; !ok {
+		return false, nil
+	}
+	delete(sessions.Sessions, host)
+	return true, s.write(sessions)
+}
+
+func (s *oidcSession) expired() bool {
+	return !s.Expiry.IsZero() && time.Now().Add(tokenExpiryLeeway).After(s.Expiry)
+}
+
+// identityToken returns an OIDC identity token for the session: the
+// workload identity token, a token refreshed with the refresh token of the
+// session, or, if interactive is set, a token obtained with the device
+// authorization grant.
+func (s *oidcStore) identityToken(session *oidcSession, interactive bool) (string, error) {
+	if session.TokenFile != "" {
+		b, err := os.ReadFile(session.TokenFile)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to read the workload identity token")
+		}
+		return strings.TrimSpace(string(b)), nil
+	}
+
+	provider, err := s.discover(session.Issuer)
+	if err != nil {
+		return "", err
+	}
+	if session.RefreshToken != "" {
+		token, err := s.requestToken(provider.TokenEndpoint, url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {session.RefreshToken},
+			"client_id":     {session.ClientID},
+		})
+		if err == nil {
+			return session.useToken(token)
+		}
+		if !interactive {
+			return "", err
+		}
+	}
+	if !interactive {
+		return "", errors.New("the OIDC login has no refresh token, log in again")
+	}
+	if provider.DeviceAuthorizationEndpoint == "" {
+		return "", errors.Errorf("OIDC issuer %s does not support the device authorization grant", session.Issuer)
+	}
+	token, err := s.deviceAuthorization(provider, session.ClientID)
+	if err != nil {
+		return "", err
+	}
+	return session.useToken(token)
+}
+
+// useToken keeps the refresh token of a token response of the OIDC provider
+// and returns its identity token.
+func (s *oidcSession) useToken(token *tokenResponse) (string, error) {
+	if token.IDToken == "" {
+		return "", errors.New("the OIDC provider returned no identity token")
+	}
+	if token.RefreshToken != "" {
+		s.RefreshToken = token.RefreshToken
+	}
+	return token.IDToken, nil
+}
+
+// oidcProvider is the part of the OpenID provider metadata used by Helm.
+type oidcProvider struct {
+	TokenEndpoint               string `json:"token_endpoint"`
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+}
+
+func (s *oidcStore) discover(issuer string) (*oidcProvider, error) {
+	u := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	resp, err := s.client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to discover the OIDC provider %s: %s", issuer, resp.Status)
+	}
+	provider := &oidcProvider{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(provider); err != nil {
+		return nil, errors.Wrapf(err, "invalid OIDC provider metadata of %s", issuer)
+	}
+	if provider.TokenEndpoint == "" {
+		return nil, errors.Errorf("OIDC provider %s has no token endpoint", issuer)
+	}
+	return provider, nil
+}
+
+// deviceAuthorizationResponse is the response of a device authorization
+// request.
+type deviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// deviceAuthorization obtains a token with the device authorization grant,
+// polling the token endpoint while the user approves the login.
+func (s *oidcStore) deviceAuthorization(provider *oidcProvider, clientID string) (*tokenResponse, error) {
+	var device deviceAuthorizationResponse
+	if err := s.postForm(provider.DeviceAuthorizationEndpoint, url.Values{
+		"client_id": {clientID},
+		"scope":     {"openid offline_access"},
+	}, &device); err != nil {
+		return nil, errors.Wrap(err, "device authorization failed")
+	}
+
+	verificationURI := device.VerificationURIComplete
+	if verificationURI == "" {
+		verificationURI = device.VerificationURI
+	}
+	fmt.Fprintf(s.out, "To log in, open %s and enter the code %s\n", verificationURI, device.UserCode)
+
+	interval := time.Duration(device.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	expiresIn := time.Duration(device.ExpiresIn) * time.Second
+	if expiresIn <= 0 {
+		expiresIn = 10 * time.Minute
+	}
+	deadline := time.Now().Add(expiresIn)
+	for time.Now().Before(deadline) {
+		time.Sleep(interval)
+		token, err := s.requestToken(provider.TokenEndpoint, url.Values{
+			"grant_type":  {deviceCodeGrantType},
+			"device_code": {device.DeviceCode},
+			"client_id":   {clientID},
+		})
+		var oauthErr *oauthError
+		switch {
+		case errors.As(err, &oauthErr) && oauthErr.Code == "authorization_pending":
+		case errors.As(err, &oauthErr) && oauthErr.Code == "slow_down":
+			interval += 5 * time.Second
+		case err != nil:
+			return nil, err
+		default:
+			return token, nil
+		}
+	}
+	return nil, errors.New("the device authorization expired before the login was approved")
+}
+
+// tokenResponse is the response of an OAuth 2.0 token request.
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	IDToken      string `json:"id_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// oauthError is an OAuth 2.0 error response.
+type oauthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (e *oauthError) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Description)
+}
+
+func (s *oidcStore) requestToken(endpoint string, form url.Values) (*tokenResponse, error) {
+	token := &tokenResponse{}
+	if err := s.postForm(endpoint, form, token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// postForm posts form to endpoint and decodes the JSON response into out.
+// OAuth 2.0 error responses are returned as an *oauthError.
+func (s *oidcStore) postForm(endpoint string, form url.Values, out interface{}) error {
+	resp, err := s.client.PostForm(endpoint, form)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		oauthErr := &oauthError{}
+		if json.Unmarshal(body, oauthErr) == nil && oauthErr.Code != "" {
+			return oauthErr
+		}
+		return errors.Errorf("%s returned %s: %s", endpoint, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, out)
+}
+
+// exchange exchanges the identity token for a registry token, stored in the
+// session.
+func (s *oidcStore) exchange(host string, session *oidcSession, idToken string) error {
+	if session.ExchangeURL == "" {
+		realm, service, err := s.challenge(host)
+		if err != nil {
+			return err
+		}
+		session.ExchangeURL = realm
+		session.Service = service
+	}
+
+	form := url.Values{
+		"grant_type":           {tokenExchangeGrantType},
+		"subject_token":        {idToken},
+		"subject_token_type":   {idTokenType},
+		"requested_token_type": {refreshTokenType},
+		"client_id":            {"helm"},
+	}
+	if session.Service != "" {
+		form.Set("service", session.Service)
+	}
+	token, err := s.requestToken(session.ExchangeURL, form)
+	if err != nil {
+		return errors.Wrapf(err, "token exchange with %s failed", host)
+	}
+
+	switch {
+	case token.RefreshToken != "":
+		// an identity token, exchanged for access tokens by the registry
+		// client
+		session.Username = ""
+		session.Token = token.RefreshToken
+	case token.AccessToken != "":
+		session.Username = accessTokenUsername
+		session.Token = token.AccessToken
+	default:
+		return errors.Errorf("token exchange with %s returned no token", host)
+	}
+
+	session.Expiry = time.Time{}
+	if token.ExpiresIn > 0 {
+		session.Expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	} else if exp := tokenExpiry(idToken); !exp.IsZero() {
+		// the registry token is assumed not to outlive the identity token
+		session.Expiry = exp
+	}
+	return nil
+}
+
+// challenge returns the realm and the service of the bearer challenge of
+// the registry at host.
+func (s *oidcStore) challenge(host string) (string, string, error) {
+	scheme := "https"
+	if s.plainHTTP {
+		scheme = "http"
+	}
+	resp, err := s.client.Get(fmt.Sprintf("%s://%s/v2/", scheme, host))
+	if err != nil {
+		return "", "", err
+	}
+	resp.Body.Close()
+	realm, service := parseBearerChallenge(resp.Header.Get("WWW-Authenticate"))
+	if resp.StatusCode != http.StatusUnauthorized || realm == "" {
+		return "", "", errors.Errorf("registry %s does not use token authentication, set the token exchange URL", host)
+	}
+	return realm, service, nil
+}
+
+// parseBearerChallenge returns the realm and the service of a bearer
+// WWW-Authenticate challenge.
+func parseBearerChallenge(header string) (realm, service string) {
+	scheme, params, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "bearer") {
+		return "", ""
+	}
+	for params != "" {
+		var key, value string
+		key, params, _ = strings.Cut(strings.TrimLeft(params, " ,"), "=")
+		if strings.HasPrefix(params, `"`) {
+			value, params, _ = strings.Cut(params[1:], `"`)
+		} else {
+			value, params, _ = strings.Cut(params, ",")
+		}
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "realm":
+			realm = value
+		case "service":
+			service = value
+		}
+	}
+	return realm, service
+}
+
+func (s *oidcStore) load() (*oidcSessionsFile, error) {
+	sessions := &oidcSessionsFile{}
+	b, err := os.ReadFile(s.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, sessions); err != nil {
+			return nil, errors.Wrapf(err, "invalid OIDC sessions file %s", s.path)
+		}
+	}
+	if sessions.Sessions == nil {
+		sessions.Sessions = map[string]*oidcSession{}
+	}
+	return sessions, nil
+}
+
+func (s *oidcStore) save(host string, session *oidcSession) error {
+	sessions, err := s.load()
+	if err != nil {
+		return err
+	}
+	sessions.Sessions[host] = session
+	return s.write(sessions)
+}
+
+func (s *oidcStore) write(sessions *oidcSessionsFile) error {
+	b, err := json.MarshalIndent(sessions, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, b, 0600)
+}
+
+// parseTokenClaims decodes the claims of a JWT into claims, without
+// verifying the token.
+func parseTokenClaims(token string, claims interface{}) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return errors.New("invalid OIDC identity token")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return errors.Wrap(err, "invalid OIDC identity token")
+	}
+	if err := json.Unmarshal(b, claims); err != nil {
+		return errors.Wrap(err, "invalid OIDC identity token")
+	}
+	return nil
+}
+
+// tokenExpiry returns the expiry of a JWT, or the zero time if it is not
+// known.
+func tokenExpiry(token string) time.Time {
+	var claims struct {
+		Expiry int64 `json:"exp"`
+	}
+	if err := parseTokenClaims(token, &claims); err != nil || claims.Expiry == 0 {
+		return time.Time{}
+	}
+	return time.Unix(claims.Expiry, 0)
+}
+
+// ping checks that the credentials of the registry at host are accepted.
+func ping(authorizer *registryauth.Client, host string, plainHTTP bool) error {
+	scheme := "https"
+	if plainHTTP {
+		scheme = "http"
+	}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, fmt.Sprintf("%s://%s/v2/", scheme, host), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := authorizer.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("login to %s failed: %s", host, resp.Status)
+	}
+	return nil
+}
+
+// loginOIDC logs into the registry at host with an OIDC identity token.
+func (c *Client) loginOIDC(host string, operation *loginOperation) error {
+	store := *c.credentials.oidc
+	authorizer := *c.registryAuthorizer
+	if operation.certFile != "" || operation.keyFile != "" || operation.caFile != "" || operation.insecure {
+		tlsConf, err := tlsutil.NewClientTLS(operation.certFile, operation.keyFile, operation.caFile, operation.insecure)
+		if err != nil {
+			return fmt.Errorf("can't create TLS config for client: %s", err)
+		}
+		store.client = &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: tlsConf,
+				Proxy:           http.ProxyFromEnvironment,
+			},
+		}
+		authorizer.Client = store.client
+	}
+	// the credentials of the registry are looked up again
+	authorizer.Cache = nil
+
+	if err := store.login(host, operation.oidc); err != nil {
+		return err
+	}
+	if err := ping(&authorizer, host, c.plainHTTP); err != nil {
+		if _, rerr := store.remove(host); rerr != nil {
+			return rerr
+		}
+		return err
+	}
+	fmt.Fprintln(c.out, "Login Succeeded")
+	return nil
+}
@@ -0,0 +1,256 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// oidcTestServer is an OIDC provider with the device authorization grant,
+// and a registry exchanging its identity tokens for registry tokens.
+type oidcTestServer struct {
+	*httptest.Server
+	// expiresIn is the lifetime of the exchanged registry tokens
+	expiresIn int
+
+	mu                          sync.Mutex
+	exchanges, refreshes, polls int
+}
+
+func testIDToken(subject string) string {
+	enc := base64.RawURLEncoding
+	claims, _ := json.Marshal(map[string]interface{}{
+		"sub": subject,
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	return enc.EncodeToString([]byte(`{"alg":"none"}`)) + "." + enc.EncodeToString(claims) + ".sig"
+}
+
+func newOIDCTestServer(t *testing.T) *oidcTestServer {
+	t.Helper()
+	s := &oidcTestServer{expiresIn: 3600}
+	mux := http.NewServeMux()
+	writeJSON := func(w http.ResponseWriter, status int, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(v)
+	}
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"token_endpoint":                s.URL + "/token",
+			"device_authorization_endpoint": s.URL + "/device",
+		})
+	})
+	mux.HandleFunc("/device", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("client_id") != "helm-test" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_client"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"device_code":      "device",
+			"user_code":        "ABCD-EFGH",
+			"verification_uri": s.URL + "/activate",
+			"interval":         1,
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		switch r.FormValue("grant_type") {
+		case deviceCodeGrantType:
+			s.polls++
+			if s.polls == 1 {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "authorization_pending"})
+				return
+			}
+		case "refresh_token":
+			if r.FormValue("refresh_token") != "provider-refresh" {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+				return
+			}
+			s.refreshes++
+		default:
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{
+			"id_token":      testIDToken("device-user"),
+			"refresh_token": "provider-refresh",
+		})
+	})
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer registry-access" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/exchange",service="test-registry"`, s.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/exchange", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		switch r.FormValue("grant_type") {
+		case tokenExchangeGrantType:
+			if r.FormValue("subject_token_type") != idTokenType || r.FormValue("service") != "test-registry" ||
+				strings.Count(r.FormValue("subject_token"), ".") != 2 {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+				return
+			}
+			s.exchanges++
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"refresh_token": fmt.Sprintf("registry-refresh-%d", s.exchanges),
+				"expires_in":    s.expiresIn,
+			})
+		case "refresh_token":
+			if !strings.HasPrefix(r.FormValue("refresh_token"), "registry-refresh-") {
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_grant"})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]string{"access_token": "registry-access"})
+		default:
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		}
+	})
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+func newOIDCTestClient(t *testing.T, out *bytes.Buffer) *Client {
+	t.Helper()
+	client, err := NewClient(
+		ClientOptPlainHTTP(),
+		ClientOptWriter(out),
+		ClientOptCredentialsFile(filepath.Join(t.TempDir(), "registry", "config.json")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestLoginOIDCWorkloadIdentity(t *testing.T) {
+	srv := newOIDCTestServer(t)
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte(testIDToken("system:serviceaccount:default:helm")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	client := newOIDCTestClient(t, &out)
+	if err := client.Login(host, LoginOptOIDC(&OIDCLogin{TokenFile: tokenFile})); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Login Succeeded") {
+		t.Errorf("Expected the login to succeed, got %q", out.String())
+	}
+
+	username, password, err := client.credentials.Credential(host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if username != "" || password != "registry-refresh-1" {
+		t.Errorf("Expected the exchanged identity token, got %q %q", username, password)
+	}
+	if srv.exchanges != 1 {
+		t.Errorf("Expected a single token exchange, got %d", srv.exchanges)
+	}
+
+	// the token expires within the leeway, so it is exchanged again with the
+	// token file whenever it is used: by the check of the login, then by the
+	// lookup
+	srv.expiresIn = 1
+	if err := client.Login(host, LoginOptOIDC(&OIDCLogin{TokenFile: tokenFile})); err != nil {
+		t.Fatal(err)
+	}
+	if _, password, err = client.credentials.Credential(host); err != nil {
+		t.Fatal(err)
+	}
+	if password != "registry-refresh-4" || srv.exchanges != 4 {
+		t.Errorf("Expected the expired token to be exchanged again, got %q after %d exchanges", password, srv.exchanges)
+	}
+
+	out.Reset()
+	if err := client.Logout(host); err != nil {
+		t.Fatal(err)
+	}
+	if _, password, err = client.credentials.Credential(host); err != nil || password != "" {
+		t.Errorf("Expected no credentials after logout, got %q, %v", password, err)
+	}
+}
+
+func TestLoginOIDCDeviceCode(t *testing.T) {
+	srv := newOIDCTestServer(t)
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+	srv.expiresIn = 1
+
+	var out bytes.Buffer
+	client := newOIDCTestClient(t, &out)
+	if err := client.Login(host, LoginOptOIDC(&OIDCLogin{Issuer: srv.URL, ClientID: "helm-test"})); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "ABCD-EFGH") {
+		t.Errorf("Expected the user code to be shown, got %q", out.String())
+	}
+	if srv.polls != 2 {
+		t.Errorf("Expected the token endpoint to be polled until the login is approved, got %d polls", srv.polls)
+	}
+
+	// the expired token is refreshed with the refresh token of the provider,
+	// by the check of the login, then by the lookup
+	if _, _, err := client.credentials.Credential(host); err != nil {
+		t.Fatal(err)
+	}
+	if srv.refreshes != 2 || srv.exchanges != 3 {
+		t.Errorf("Expected a refresh and an exchange, got %d refreshes and %d exchanges", srv.refreshes, srv.exchanges)
+	}
+
+	err := client.Login(host, LoginOptOIDC(&OIDCLogin{Issuer: srv.URL}))
+	if err == nil {
+		t.Error("Expected a login without client ID to fail")
+	}
+}
+
+func TestParseBearerChallenge(t *testing.T) {
+	for _, tt := range []struct {
+		header, realm, service string
+	}{
+		{`Bearer realm="https://auth.example.com/token",service="registry.example.com"`, "https://auth.example.com/token", "registry.example.com"},
+		{`bearer service="svc", realm="https://auth.example.com/token",scope="repository:a:pull"`, "https://auth.example.com/token", "svc"},
+		{`Basic realm="registry"`, "", ""},
+		{``, "", ""},
+	} {
+		realm, service := parseBearerChallenge(tt.header)
+		if realm != tt.realm || service != tt.service {
+			t.Errorf("%s: expected %q %q, got %q %q", tt.header, tt.realm, tt.service, realm, service)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry // import "helm.sh/helm/v3/pkg/registry"
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/containerd/containerd/remotes"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+type (
+	// PluginArchive is the archive of a plugin, for a platform or for all of
+	// them.
+	PluginArchive struct {
+		// Platform is the platform of the archive, or nil if the archive is
+		// portable.
+		Platform *ocispec.Platform
+		// Data is the gzip compressed tar archive of the plugin
+		Data []byte
+	}
+
+	// PluginPullResult is the result of the pull of a plugin.
+	PluginPullResult struct {
+		// Ref is the reference the plugin was pulled from
+		Ref string
+		// Digest is the digest the reference resolved to: the digest of the
+		// index of the plugin, or of its manifest if it has no index.
+		Digest string
+		// Manifest is the digest of the manifest of the pulled archive
+		Manifest string
+		// Archive is the pulled archive
+		Archive PluginArchive
+	}
+)
+
+// PushPlugin pushes the archives of a plugin to ref. A single portable
+// archive is pushed as a manifest, and the archives of several platforms as
+// an index of their manifests. It returns the digest of the pushed manifest
+// or index.
+func (c *Client) PushPlugin(ref string, archives ...PluginArchive) (_ string, err error) {
+	defer c.wrapError(&err)
+
+	if len(archives) == 0 {
+		return "", errors.New("no plugin archive to push")
+	}
+	parsedRef, err := parseReference(ref)
+	if err != nil {
+		return "", err
+	}
+	resolver, err := c.resolver(parsedRef)
+	if err != nil {
+		return "", err
+	}
+	rctx := ctx(c.out, c.debug)
+	repoRef := fmt.Sprintf("%s/%s", parsedRef.Registry, parsedRef.Repository)
+
+	if err := pushContent(rctx, resolver, repoRef, ocispec.DescriptorEmptyJSON, ocispec.DescriptorEmptyJSON.Data); err != nil {
+		return "", err
+	}
+	var manifests []ocispec.Descriptor
+	for _, archive := range archives {
+		if len(archives) > 1 && archive.Platform == nil {
+			return "", errors.New("the archives of a plugin for several platforms must have a platform")
+		}
+		layer := ocispec.Descriptor{
+			MediaType: PluginLayerMediaType,
+			Digest:    digest.FromBytes(archive.Data),
+			Size:      int64(len(archive.Data)),
+		}
+		if err := pushContent(rctx, resolver, repoRef, layer, archive.Data); err != nil {
+			return "", err
+		}
+		manifest := ocispec.Manifest{
+			MediaType:    ocispec.MediaTypeImageManifest,
+			ArtifactType: PluginArtifactType,
+			Config:       ocispec.DescriptorEmptyJSON,
+			Layers:       []ocispec.Descriptor{layer},
+		}
+		manifest.SchemaVersion = 2
+		data, err := json.Marshal(manifest)
+		if err != nil {
+			return "", err
+		}
+		desc := ocispec.Descriptor{
+			MediaType:    ocispec.MediaTypeImageManifest,
+			ArtifactType: PluginArtifactType,
+			Digest:       digest.FromBytes(data),
+			Size:         int64(len(data)),
+			Platform:     archive.Platform,
+		}
+		if len(archives) == 1 {
+			if err := pushContent(rctx, resolver, parsedRef.String(), desc, data); err != nil {
+				return "", err
+			}
+			fmt.Fprintf(c.out, "Pushed: %s\nDigest: %s\n", parsedRef, desc.Digest)
+			return desc.Digest.String(), nil
+		}
+		if err := pushContent(rctx, resolver, fmt.Sprintf("%s@%s", repoRef, desc.Digest), desc, data); err != nil {
+			return "", err
+		}
+		manifests = append(manifests, desc)
+	}
+
+	index := ocispec.Index{
+		MediaType:    ocispec.MediaTypeImageIndex,
+		ArtifactType: PluginArtifactType,
+		Manifests:    manifests,
+	}
+	index.SchemaVersion = 2
+	data, err := json.Marshal(index)
+	if err != nil {
+		return "", err
+	}
+	desc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Digest:    digest.FromBytes(data),
+		Size:      int64(len(data)),
+	}
+	if err := pushContent(rctx, resolver, parsedRef.String(), desc, data); err != nil {
+		return "", err
+	}
+	fmt.Fprintf(c.out, "Pushed: %s\nDigest: %s\n", parsedRef, desc.Digest)
+	return desc.Digest.String(), nil
+}
+
+// PullPlugin pulls the archive of the plugin at ref for the platform. The
+// archive of the index of a plugin matching the operating system and the
+// architecture of the platform is preferred to a portable archive. A
+// reference with a digest pins the plugin: its content is checked against
+// the digest.
+func (c *Client) PullPlugin(ref string, platform ocispec.Platform) (_ *PluginPullResult, err error) {
+	defer c.wrapError(&err)
+
+	parsedRef, err := parseReference(ref)
+	if err != nil {
+		return nil, err
+	}
+	var result *PluginPullResult
+	err = c.withAnonymousFallback(parsedRef, func(resolver remotes.Resolver) error {
+		var err error
+		result, err = c.pullPlugin(resolver, parsedRef.String(), platform)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Ref = parsedRef.String()
+	return result, nil
+}
+
+func (c *Client) pullPlugin(resolver remotes.Resolver, ref string, platform ocispec.Platform) (*PluginPullResult, error) {
+	rctx := ctx(c.out, c.debug)
+	_, desc, err := resolver.Resolve(rctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	fetcher, err := resolver.Fetcher(rctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	data, err := fetchBlob(rctx, fetcher, desc)
+	if err != nil {
+		return nil, err
+	}
+	result := &PluginPullResult{Digest: desc.Digest.String()}
+
+	if desc.MediaType == ocispec.MediaTypeImageIndex {
+		index := &ocispec.Index{}
+		if err := json.Unmarshal(data, index); err != nil {
+			return nil, errors.Wrapf(err, "invalid index %s", desc.Digest)
+		}
+		m, ok := matchPlatform(index.Manifests, platform)
+		if !ok {
+			return nil, errors.Errorf("plugin %s has no archive for %s/%s", ref, platform.OS, platform.Architecture)
+		}
+		result.Archive.Platform = m.Platform
+		desc = m
+		if data, err = fetchBlob(rctx, fetcher, desc); err != nil {
+			return nil, err
+		}
+	}
+	if desc.MediaType != ocispec.MediaTypeImageManifest {
+		return nil, errors.Errorf("%s is not a plugin: unexpected media type %s", ref, desc.MediaType)
+	}
+	manifest := &ocispec.Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, errors.Wrapf(err, "invalid manifest %s", desc.Digest)
+	}
+	result.Manifest = desc.Digest.String()
+	for _, layer := range manifest.Layers {
+		if layer.MediaType != PluginLayerMediaType {
+			continue
+		}
+		if result.Archive.Data, err = fetchBlob(rctx, fetcher, layer); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+	return nil, errors.Errorf("%s is not a plugin: manifest does not contain a layer with mediatype %s", ref, PluginLayerMediaType)
+}
+
+// matchPlatform returns the manifest of the platform: the manifest of its
+// operating system and architecture, else the manifest of its operating
+// system for any architecture, else the portable manifest.
+func matchPlatform(manifests []ocispec.Descriptor, platform ocispec.Platform) (ocispec.Descriptor, bool) {
+	for _, match := range []func(*ocispec.Platform) bool{
+		func(p *ocispec.Platform) bool {
+			return p != nil && p.OS == platform.OS && p.Architecture == platform.Architecture
+		},
+		func(p *ocispec.Platform) bool {
+			return p != nil && p.OS == platform.OS && p.Architecture == ""
+		},
+		func(p *ocispec.Platform) bool {
+			return p == nil
+		},
+	} {
+		for _, m := range manifests {
+			if match(m.Platform) {
+				return m, true
+			}
+		}
+	}
+	return ocispec.Descriptor{}, false
+}
@@ -0,0 +1,48 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func TestMatchPlatform(t *testing.T) {
+	linuxAMD64 := ocispec.Descriptor{Digest: digest.FromString("linux-amd64"), Platform: &ocispec.Platform{OS: "linux", Architecture: "amd64"}}
+	linux := ocispec.Descriptor{Digest: digest.FromString("linux"), Platform: &ocispec.Platform{OS: "linux"}}
+	portable := ocispec.Descriptor{Digest: digest.FromString("portable")}
+	manifests := []ocispec.Descriptor{portable, linux, linuxAMD64}
+
+	for _, tt := range []struct {
+		platform  ocispec.Platform
+		manifests []ocispec.Descriptor
+		expect    ocispec.Descriptor
+		ok        bool
+	}{
+		{ocispec.Platform{OS: "linux", Architecture: "amd64"}, manifests, linuxAMD64, true},
+		{ocispec.Platform{OS: "linux", Architecture: "arm64"}, manifests, linux, true},
+		{ocispec.Platform{OS: "darwin", Architecture: "arm64"}, manifests, portable, true},
+		{ocispec.Platform{OS: "darwin", Architecture: "arm64"}, []ocispec.Descriptor{linux, linuxAMD64}, ocispec.Descriptor{}, false},
+	} {
+		got, ok := matchPlatform(tt.manifests, tt.platform)
+		if ok != tt.ok || got.Digest != tt.expect.Digest {
+			t.Errorf("%s/%s: expected %s, got %s", tt.platform.OS, tt.platform.Architecture, tt.expect.Digest, got.Digest)
+		}
+	}
+}
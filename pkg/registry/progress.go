@@ -0,0 +1,125 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry // import "helm.sh/helm/v3/pkg/registry"
+
+import (
+	"context"
+	"io"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/remotes"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// ProgressFunc reports the progress of the transfer of the content desc, a
+// manifest or a blob pulled from or pushed to a registry, with the number of
+// bytes transferred so far. Transfers are complete when transferred reaches
+// desc.Size. Content already present in the registry is reported as
+// complete at once.
+type ProgressFunc func(desc ocispec.Descriptor, transferred int64)
+
+// withProgress returns resolver, reporting the progress of its transfers
+// to progress if it is not nil.
+func withProgress(resolver remotes.Resolver, progress ProgressFunc) remotes.Resolver {
+	if progress == nil {
+		return resolver
+	}
+	return &progressResolver{Resolver: resolver, progress: progress}
+}
+
+type progressResolver struct {
+	remotes.Resolver
+	progress ProgressFunc
+}
+
+func (r *progressResolver) Fetcher(ctx context.Context, ref string) (remotes.Fetcher, error) {
+	fetcher, err := r.Resolver.Fetcher(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return &progressFetcher{Fetcher: fetcher, progress: r.progress}, nil
+}
+
+func (r *progressResolver) Pusher(ctx context.Context, ref string) (remotes.Pusher, error) {
+	pusher, err := r.Resolver.Pusher(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return &progressPusher{Pusher: pusher, progress: r.progress}, nil
+}
+
+type progressFetcher struct {
+	remotes.Fetcher
+	progress ProgressFunc
+}
+
+func (f *progressFetcher) Fetch(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+	rc, err := f.Fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+	return &progressReader{ReadCloser: rc, desc: desc, progress: f.progress}, nil
+}
+
+type progressReader struct {
+	io.ReadCloser
+	desc        ocispec.Descriptor
+	progress    ProgressFunc
+	transferred int64
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if n > 0 {
+		r.transferred += int64(n)
+		r.progress(r.desc, r.transferred)
+	}
+	return n, err
+}
+
+type progressPusher struct {
+	remotes.Pusher
+	progress ProgressFunc
+}
+
+func (p *progressPusher) Push(ctx context.Context, desc ocispec.Descriptor) (content.Writer, error) {
+	w, err := p.Pusher.Push(ctx, desc)
+	if errdefs.IsAlreadyExists(err) {
+		p.progress(desc, desc.Size)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &progressWriter{Writer: w, desc: desc, progress: p.progress}, nil
+}
+
+type progressWriter struct {
+	content.Writer
+	desc        ocispec.Descriptor
+	progress    ProgressFunc
+	transferred int64
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	if n > 0 {
+		w.transferred += int64(n)
+		w.progress(w.desc, w.transferred)
+	}
+	return n, err
+}
@@ -0,0 +1,484 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry // import "helm.sh/helm/v3/pkg/registry"
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/remotes"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	"oras.land/oras-go/pkg/registry"
+	registryauth "oras.land/oras-go/pkg/registry/remote/auth"
+)
+
+// maxReferrersPages bounds the pages of referrers followed for a chart.
+const maxReferrersPages = 100
+
+type (
+	// Artifact is an auxiliary artifact, such as a software bill of
+	// materials, a provenance attestation or a test report, attached to a
+	// chart with the OCI referrers API.
+	Artifact struct {
+		// Digest is the digest of the manifest of the artifact
+		Digest string `json:"digest"`
+		// ArtifactType is the media type of the artifact
+		ArtifactType string `json:"artifactType"`
+		// Annotations are the annotations of the manifest of the artifact
+		Annotations map[string]string `json:"annotations,omitempty"`
+	}
+
+	// ArtifactFile is the content of a layer of an artifact.
+	ArtifactFile struct {
+		// Name is the file name of the layer, from its title annotation
+		Name string
+		// MediaType is the media type of the layer
+		MediaType string
+		// Data is the content of the layer
+		Data []byte
+	}
+
+	// AttachOption allows specifying various settings on attach
+	AttachOption func(*attachOperation)
+
+	attachOperation struct {
+		fileName     string
+		annotations  map[string]string
+		creationTime string
+	}
+)
+
+// Title returns the title annotation of the artifact, usually the name of
+// the attached file.
+func (a *Artifact) Title() string {
+	return a.Annotations[ocispec.AnnotationTitle]
+}
+
+// Created returns the creation time annotation of the artifact.
+func (a *Artifact) Created() string {
+	return a.Annotations[ocispec.AnnotationCreated]
+}
+
+// AttachOptFileName returns a function that sets the file name of the attached content
+func AttachOptFileName(fileName string) AttachOption {
+	return func(operation *attachOperation) {
+		operation.fileName = fileName
+	}
+}
+
+// AttachOptAnnotations returns a function that sets the annotations of the attached artifact
+func AttachOptAnnotations(annotations map[string]string) AttachOption {
+	return func(operation *attachOperation) {
+		operation.annotations = annotations
+	}
+}
+
+// AttachOptCreationTime returns a function that sets the creation time of the attached artifact
+func AttachOptCreationTime(creationTime string) AttachOption {
+	return func(operation *attachOperation) {
+		operation.creationTime = creationTime
+	}
+}
+
+// Attach attaches data as an artifact of type artifactType to the chart at
+// ref. The artifact is pushed as a manifest whose subject is the manifest of
+// the chart, so it is listed by the referrers API of the registry. For
+// registries without the referrers API, the artifact is also added to the
+// index tagged with the digest of the chart manifest, as the OCI distribution
+// specification requires.
+func (c *Client) Attach(ref, artifactType string, data []byte, options ...AttachOption) (_ *Artifact, err error) {
+	defer c.wrapError(&err)
+
+	if artifactType == "" {
+		return nil, errors.New("artifact type is required")
+	}
+	operation := &attachOperation{}
+	for _, option := range options {
+		option(operation)
+	}
+	parsedRef, err := parseReference(ref)
+	if err != nil {
+		return nil, err
+	}
+	resolver, err := c.resolver(parsedRef)
+	if err != nil {
+		return nil, err
+	}
+	rctx := ctx(c.out, c.debug)
+
+	_, subject, err := resolver.Resolve(rctx, parsedRef.String())
+	if err != nil {
+		return nil, err
+	}
+
+	layer := ocispec.Descriptor{
+		MediaType: artifactType,
+		Digest:    digest.FromBytes(data),
+		Size:      int64(len(data)),
+	}
+	if operation.fileName != "" {
+		layer.Annotations = map[string]string{ocispec.AnnotationTitle: operation.fileName}
+	}
+	annotations := map[string]string{}
+	for k, v := range operation.annotations {
+		annotations[k] = v
+	}
+	if operation.fileName != "" {
+		annotations[ocispec.AnnotationTitle] = operation.fileName
+	}
+	if operation.creationTime == "" {
+		operation.creationTime = time.Now().UTC().Format(time.RFC3339)
+	}
+	annotations[ocispec.AnnotationCreated] = operation.creationTime
+
+	manifest := ocispec.Manifest{
+		MediaType:    ocispec.MediaTypeImageManifest,
+		ArtifactType: artifactType,
+		Config:       ocispec.DescriptorEmptyJSON,
+		Layers:       []ocispec.Descriptor{layer},
+		Subject: &ocispec.Descriptor{
+			MediaType: subject.MediaType,
+			Digest:    subject.Digest,
+			Size:      subject.Size,
+		},
+		Annotations: annotations,
+	}
+	manifest.SchemaVersion = 2
+	manifestData, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+	manifestDescriptor := ocispec.Descriptor{
+		MediaType:    ocispec.MediaTypeImageManifest,
+		Digest:       digest.FromBytes(manifestData),
+		Size:         int64(len(manifestData)),
+		ArtifactType: artifactType,
+		Annotations:  annotations,
+	}
+
+	artifactRef := fmt.Sprintf("%s/%s@%s", parsedRef.Registry, parsedRef.Repository, manifestDescriptor.Digest)
+	for _, blob := range []struct {
+		desc ocispec.Descriptor
+		data []byte
+	}{
+		{ocispec.DescriptorEmptyJSON, ocispec.DescriptorEmptyJSON.Data},
+		{layer, data},
+		{manifestDescriptor, manifestData},
+	} {
+		if err := pushContent(rctx, resolver, artifactRef, blob.desc, blob.data); err != nil {
+			return nil, errors.Wrap(err, "failed to push the artifact")
+		}
+	}
+
+	_, err = c.fetchReferrers(rctx, parsedRef, subject.Digest, "")
+	switch {
+	case errors.Is(err, errReferrersUnsupported):
+		if err := c.addReferrersTag(rctx, resolver, parsedRef, subject.Digest, manifestDescriptor); err != nil {
+			return nil, errors.Wrap(err, "failed to update the referrers of the chart")
+		}
+	case err != nil:
+		return nil, err
+	}
+
+	artifact := newArtifact(manifestDescriptor)
+	fmt.Fprintf(c.out, "Attached: %s\n", artifactRef)
+	return artifact, nil
+}
+
+// Referrers lists the artifacts attached to the chart at ref, restricted to
+// the artifacts of type artifactType if it is not empty.
+func (c *Client) Referrers(ref, artifactType string) (_ []*Artifact, err error) {
+	defer c.wrapError(&err)
+
+	parsedRef, err := parseReference(ref)
+	if err != nil {
+		return nil, err
+	}
+	resolver, err := c.resolver(parsedRef)
+	if err != nil {
+		return nil, err
+	}
+	rctx := ctx(c.out, c.debug)
+
+	_, subject, err := resolver.Resolve(rctx, parsedRef.String())
+	if err != nil {
+		return nil, err
+	}
+	descriptors, err := c.fetchReferrers(rctx, parsedRef, subject.Digest, artifactType)
+	if errors.Is(err, errReferrersUnsupported) {
+		var index *ocispec.Index
+		index, err = fetchIndex(rctx, resolver, referrersTagReference(parsedRef, subject.Digest))
+		if index != nil {
+			descriptors = index.Manifests
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var artifacts []*Artifact
+	for _, desc := range descriptors {
+		if artifactType != "" && desc.ArtifactType != artifactType {
+			continue
+		}
+		artifacts = append(artifacts, newArtifact(desc))
+	}
+	return artifacts, nil
+}
+
+// FetchArtifact fetches the content of the artifact with the manifest digest
+// dgst attached to the chart at ref.
+func (c *Client) FetchArtifact(ref, dgst string) (_ *Artifact, _ []ArtifactFile, err error) {
+	defer c.wrapError(&err)
+
+	parsedRef, err := parseReference(ref)
+	if err != nil {
+		return nil, nil, err
+	}
+	d, err := digest.Parse(dgst)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "invalid artifact digest %q", dgst)
+	}
+	resolver, err := c.resolver(parsedRef)
+	if err != nil {
+		return nil, nil, err
+	}
+	rctx := ctx(c.out, c.debug)
+
+	_, subject, err := resolver.Resolve(rctx, parsedRef.String())
+	if err != nil {
+		return nil, nil, err
+	}
+	artifactRef := fmt.Sprintf("%s/%s@%s", parsedRef.Registry, parsedRef.Repository, d)
+	desc, manifest, err := fetchManifest(rctx, resolver, artifactRef)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to fetch the artifact %s", d)
+	}
+	if manifest.Subject == nil || manifest.Subject.Digest != subject.Digest {
+		return nil, nil, errors.Errorf("artifact %s is not attached to %s", d, ref)
+	}
+
+	fetcher, err := resolver.Fetcher(rctx, artifactRef)
+	if err != nil {
+		return nil, nil, err
+	}
+	files := make([]ArtifactFile, 0, len(manifest.Layers))
+	for _, layer := range manifest.Layers {
+		data, err := fetchBlob(rctx, fetcher, layer)
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, ArtifactFile{
+			Name:      layer.Annotations[ocispec.AnnotationTitle],
+			MediaType: layer.MediaType,
+			Data:      data,
+		})
+	}
+
+	desc.ArtifactType = manifest.ArtifactType
+	if desc.ArtifactType == "" {
+		desc.ArtifactType = manifest.Config.MediaType
+	}
+	desc.Annotations = manifest.Annotations
+	return newArtifact(desc), files, nil
+}
+
+func newArtifact(desc ocispec.Descriptor) *Artifact {
+	return &Artifact{
+		Digest:       desc.Digest.String(),
+		ArtifactType: desc.ArtifactType,
+		Annotations:  desc.Annotations,
+	}
+}
+
+// errReferrersUnsupported is returned by fetchReferrers when the registry
+// does not implement the referrers API.
+var errReferrersUnsupported = errors.New("referrers API is not supported")
+
+// fetchReferrers lists the referrers of the manifest with the digest d with
+// the referrers API of the registry, following the pages of the results.
+func (c *Client) fetchReferrers(ctx context.Context, ref registry.Reference, d digest.Digest, artifactType string) ([]ocispec.Descriptor, error) {
+	scheme := "https"
+	if c.plainHTTP {
+		scheme = "http"
+	}
+	u := fmt.Sprintf("%s://%s/v2/%s/referrers/%s", scheme, ref.Host(), ref.Repository, d)
+	if artifactType != "" {
+		u += "?artifactType=" + url.QueryEscape(artifactType)
+	}
+	ctx = registryauth.WithScopes(ctx, registryauth.ScopeRepository(ref.Repository, registryauth.ActionPull))
+
+	var descriptors []ocispec.Descriptor
+	for page := 0; u != "" && page < maxReferrersPages; page++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", ocispec.MediaTypeImageIndex)
+		resp, err := c.registryAuthorizer.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		index, next, err := readReferrersPage(resp)
+		if err != nil {
+			return nil, err
+		}
+		descriptors = append(descriptors, index.Manifests...)
+		u = next
+	}
+	return descriptors, nil
+}
+
+// readReferrersPage reads a page of the referrers API and returns the URL of
+// the next page, if any.
+func readReferrersPage(resp *http.Response) (*ocispec.Index, string, error) {
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, "", errReferrersUnsupported
+	default:
+		return nil, "", errors.Wrap(newResponseError(resp), "failed to list referrers")
+	}
+	if mediaType := resp.Header.Get("Content-Type"); !strings.HasPrefix(mediaType, ocispec.MediaTypeImageIndex) {
+		// registries serving an unrelated page for the path do not
+		// implement the API
+		return nil, "", errReferrersUnsupported
+	}
+	index := &ocispec.Index{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 4<<20)).Decode(index); err != nil {
+		return nil, "", errors.Wrap(err, "invalid referrers index")
+	}
+
+	next, err := nextPageURL(resp)
+	if err != nil {
+		return nil, "", err
+	}
+	return index, next, nil
+}
+
+// nextPageURL returns the URL of the next page from the Link header of resp.
+func nextPageURL(resp *http.Response) (string, error) {
+	link := resp.Header.Get("Link")
+	if link == "" {
+		return "", nil
+	}
+	if link[0] != '<' {
+		return "", errors.Errorf("invalid Link header %q", link)
+	}
+	end := strings.IndexByte(link, '>')
+	if end < 0 {
+		return "", errors.Errorf("invalid Link header %q", link)
+	}
+	next, err := resp.Request.URL.Parse(link[1:end])
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid Link header %q", link)
+	}
+	return next.String(), nil
+}
+
+// referrersTagReference returns the reference of the index listing the
+// referrers of the manifest with the digest d, for registries without the
+// referrers API.
+func referrersTagReference(ref registry.Reference, d digest.Digest) string {
+	return fmt.Sprintf("%s/%s:%s-%s", ref.Registry, ref.Repository, d.Algorithm(), d.Encoded())
+}
+
+// addReferrersTag adds desc to the index listing the referrers of the
+// manifest with the digest d.
+func (c *Client) addReferrersTag(ctx context.Context, resolver remotes.Resolver, ref registry.Reference, d digest.Digest, desc ocispec.Descriptor) error {
+	tagRef := referrersTagReference(ref, d)
+	index, err := fetchIndex(ctx, resolver, tagRef)
+	if err != nil {
+		return err
+	}
+	for _, m := range index.Manifests {
+		if m.Digest == desc.Digest {
+			return nil
+		}
+	}
+	index.Manifests = append(index.Manifests, desc)
+
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	return pushContent(ctx, resolver, tagRef, ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Digest:    digest.FromBytes(data),
+		Size:      int64(len(data)),
+	}, data)
+}
+
+// fetchIndex fetches the index at ref. An empty index is returned if it does
+// not exist.
+func fetchIndex(ctx context.Context, resolver remotes.Resolver, ref string) (*ocispec.Index, error) {
+	index := &ocispec.Index{MediaType: ocispec.MediaTypeImageIndex}
+	index.SchemaVersion = 2
+	_, desc, err := resolver.Resolve(ctx, ref)
+	if errdefs.IsNotFound(err) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	fetcher, err := resolver.Fetcher(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	data, err := fetchBlob(ctx, fetcher, desc)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, errors.Wrapf(err, "invalid index %s", desc.Digest)
+	}
+	return index, nil
+}
+
+// pushContent pushes the blob or the manifest desc with its data to the
+// repository of ref. Manifests are tagged with the tag of ref, if any.
+func pushContent(ctx context.Context, resolver remotes.Resolver, ref string, desc ocispec.Descriptor, data []byte) error {
+	pusher, err := resolver.Pusher(ctx, ref)
+	if err != nil {
+		return err
+	}
+	w, err := pusher.Push(ctx, desc)
+	if errdefs.IsAlreadyExists(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Commit(ctx, desc.Size, desc.Digest); err != nil && !errdefs.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// referrersServer serves a chart manifest and its referrers with the
+// referrers API, in two pages.
+func referrersServer(t *testing.T, referrers ...ocispec.Descriptor) *httptest.Server {
+	t.Helper()
+	manifest, err := json.Marshal(ocispec.Manifest{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    ocispec.Descriptor{MediaType: ConfigMediaType, Digest: digest.FromString("{}"), Size: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifestDigest := digest.FromBytes(manifest)
+
+	page := func(w http.ResponseWriter, manifests []ocispec.Descriptor) {
+		w.Header().Set("Content-Type", ocispec.MediaTypeImageIndex)
+		index := ocispec.Index{MediaType: ocispec.MediaTypeImageIndex, Manifests: manifests}
+		index.SchemaVersion = 2
+		if err := json.NewEncoder(w).Encode(index); err != nil {
+			t.Error(err)
+		}
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/":
+			w.WriteHeader(http.StatusOK)
+		case "/v2/charts/mychart/manifests/1.0.0", "/v2/charts/mychart/manifests/" + manifestDigest.String():
+			w.Header().Set("Content-Type", ocispec.MediaTypeImageManifest)
+			w.Header().Set("Docker-Content-Digest", manifestDigest.String())
+			w.Write(manifest)
+		case "/v2/charts/mychart/referrers/" + manifestDigest.String():
+			if r.URL.Query().Get("page") == "" {
+				w.Header().Set("Link", `</v2/charts/mychart/referrers/`+manifestDigest.String()+`?page=2>; rel="next"`)
+				page(w, referrers[:1])
+				return
+			}
+			page(w, referrers[1:])
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestReferrers(t *testing.T) {
+	sbom := ocispec.Descriptor{
+		MediaType:    ocispec.MediaTypeImageManifest,
+		Digest:       digest.FromString("sbom"),
+		Size:         4,
+		ArtifactType: SPDXArtifactType,
+		Annotations:  map[string]string{ocispec.AnnotationTitle: "sbom.spdx.json"},
+	}
+	provenance := ocispec.Descriptor{
+		MediaType:    ocispec.MediaTypeImageManifest,
+		Digest:       digest.FromString("provenance"),
+		Size:         10,
+		ArtifactType: InTotoArtifactType,
+	}
+	srv := referrersServer(t, sbom, provenance)
+	defer srv.Close()
+
+	client, err := NewClient(ClientOptPlainHTTP(), ClientOptCredentialsFile(filepath.Join(t.TempDir(), "config.json")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref := strings.TrimPrefix(srv.URL, "http://") + "/charts/mychart:1.0.0"
+
+	artifacts, err := client.Referrers(ref, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(artifacts) != 2 {
+		t.Fatalf("Expected the artifacts of both pages, got %d", len(artifacts))
+	}
+	if artifacts[0].Digest != sbom.Digest.String() || artifacts[0].Title() != "sbom.spdx.json" {
+		t.Errorf("Unexpected artifact %+v", artifacts[0])
+	}
+
+	artifacts, err = client.Referrers(ref, InTotoArtifactType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(artifacts) != 1 || artifacts[0].Digest != provenance.Digest.String() {
+		t.Errorf("Expected only the provenance artifact, got %+v", artifacts)
+	}
+
+	if _, err := client.Referrers(strings.TrimPrefix(srv.URL, "http://")+"/charts/mychart:2.0.0", ""); err == nil {
+		t.Error("Expected listing the artifacts of a missing chart to fail")
+	}
+}
@@ -0,0 +1,204 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry // import "helm.sh/helm/v3/pkg/registry"
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	remoteserrors "github.com/containerd/containerd/remotes/errors"
+	"github.com/pkg/errors"
+)
+
+// RetryPolicy configures the retries of the requests of a client failing
+// with transient errors: rate limiting (429), server errors (5xx) and
+// interrupted connections. The delay before a retry doubles with every retry,
+// unless the registry asks for another delay with a Retry-After header.
+type RetryPolicy struct {
+	// MaxRetries is the number of times a request is retried. Requests are
+	// not retried if it is zero.
+	MaxRetries int
+	// MinDelay is the delay before the first retry.
+	MinDelay time.Duration
+	// MaxDelay bounds the delay before a retry, including the delays asked
+	// by registries.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy is the retry policy of clients unless another one is
+// set with ClientOptRetryPolicy.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	MinDelay:   time.Second,
+	MaxDelay:   30 * time.Second,
+}
+
+// delay returns the delay before the retry number retries, starting at 1.
+func (p RetryPolicy) delay(retries int) time.Duration {
+	d := p.MinDelay
+	for i := 1; i < retries && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d
+}
+
+// retryAfter returns the delay asked by the Retry-After header of resp, if
+// any, bounded by the maximum delay of the policy.
+func (p RetryPolicy) retryAfter(resp *http.Response) (time.Duration, bool) {
+	h := resp.Header.Get("Retry-After")
+	if h == "" {
+		return 0, false
+	}
+	var d time.Duration
+	if seconds, err := strconv.Atoi(h); err == nil {
+		d = time.Duration(seconds) * time.Second
+	} else if t, err := http.ParseTime(h); err == nil {
+		d = time.Until(t)
+	} else {
+		return 0, false
+	}
+	if d < 0 {
+		d = 0
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d, true
+}
+
+// sleep waits for the delay before the retry number retries.
+func (p RetryPolicy) sleep(ctx context.Context, retries int) error {
+	return sleep(ctx, p.delay(retries))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// retryableStatus returns whether a request failing with the status code may
+// succeed if it is retried.
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError && code != http.StatusNotImplemented
+}
+
+// retryable returns whether a transfer failing with err may succeed if it
+// is retried: network errors, server errors and rate limiting.
+func retryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var status remoteserrors.ErrUnexpectedStatus
+	if errors.As(err, &status) {
+		return retryableStatus(status.StatusCode)
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// retryableRequestError returns whether a request failing with err before
+// getting a response may succeed if it is retried. Requests failing to
+// connect are not retried, so the next host of a registry, such as the
+// registry behind its mirrors, is tried without delay.
+func retryableRequestError(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return false
+	}
+	return retryable(err)
+}
+
+// registryTransport sends the requests of a client, retrying the requests
+// failing with transient errors and remembering the failed requests.
+type registryTransport struct {
+	base     http.RoundTripper
+	policy   RetryPolicy
+	failures *failedRequests
+}
+
+func (t *registryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Requests are only retried if their body can be sent again
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	for retries := 0; ; retries++ {
+		r := req
+		if retries > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r = req.Clone(req.Context())
+			r.Body = body
+		}
+		resp, err := t.base.RoundTrip(r)
+		more := replayable && retries < t.policy.MaxRetries
+		if err != nil {
+			if !more || !retryableRequestError(err) {
+				return nil, err
+			}
+			if err := t.policy.sleep(req.Context(), retries+1); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if resp.StatusCode < http.StatusBadRequest {
+			return resp, nil
+		}
+		if !more || !retryableStatus(resp.StatusCode) {
+			t.failures.add(newResponseError(resp))
+			return resp, nil
+		}
+
+		delay, ok := t.policy.retryAfter(resp)
+		if !ok {
+			delay = t.policy.delay(retries + 1)
+		}
+		io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBodySize))
+		resp.Body.Close()
+		if err := sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// withRetries returns a copy of httpClient sending its requests with a
+// registryTransport.
+func (c *Client) withRetries(httpClient *http.Client) *http.Client {
+	base := httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	withRetries := *httpClient
+	withRetries.Transport = &registryTransport{base: base, policy: c.retryPolicy, failures: c.failures}
+	return &withRetries
+}
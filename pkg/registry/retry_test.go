@@ -0,0 +1,181 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	remoteserrors "github.com/containerd/containerd/remotes/errors"
+	"github.com/pkg/errors"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{MaxRetries: 5, MinDelay: time.Second, MaxDelay: 5 * time.Second}
+	for retries, expect := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 10: 5 * time.Second} {
+		if got := p.delay(retries); got != expect {
+			t.Errorf("retry %d: expected a delay of %s, got %s", retries, expect, got)
+		}
+	}
+
+	resp := &http.Response{Header: http.Header{"Retry-After": {"120"}}}
+	if d, ok := p.retryAfter(resp); !ok || d != 5*time.Second {
+		t.Errorf("Expected Retry-After to be bounded by the maximum delay, got %s", d)
+	}
+	resp.Header.Set("Retry-After", "soon")
+	if _, ok := p.retryAfter(resp); ok {
+		t.Error("Expected an invalid Retry-After to be ignored")
+	}
+}
+
+func TestRegistryTransport(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		switch r.URL.Path {
+		case "/v2/flaky/tags/list":
+			if n == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			fmt.Fprint(w, `{"tags":["1.0.0"]}`)
+		case "/v2/limited/tags/list":
+			w.Header().Set("X-Request-Id", "abc123")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"errors":[{"code":"TOOMANYREQUESTS","message":"slow down"}]}`)
+		case "/v2/private/tags/list":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			io.Copy(io.Discard, r.Body)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	failures := newFailedRequests()
+	client := &http.Client{Transport: &registryTransport{
+		base:     http.DefaultTransport,
+		policy:   RetryPolicy{MaxRetries: 2},
+		failures: failures,
+	}}
+	get := func(path string) *http.Response {
+		resp, err := client.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	if resp := get("/v2/flaky/tags/list"); resp.StatusCode != http.StatusOK || requests != 2 {
+		t.Errorf("Expected the rate limited request to be retried, got %s after %d requests", resp.Status, requests)
+	}
+
+	requests = 0
+	if resp := get("/v2/limited/tags/list"); resp.StatusCode != http.StatusTooManyRequests || requests != 3 {
+		t.Errorf("Expected the request to be retried twice, got %s after %d requests", resp.Status, requests)
+	}
+	e := failures.get(http.MethodGet, srv.URL+"/v2/limited/tags/list")
+	if e == nil {
+		t.Fatal("Expected the failed request to be remembered")
+	}
+	if e.RequestID != "abc123" || e.Repository != "limited" || e.Message != "toomanyrequests: slow down" {
+		t.Errorf("Unexpected error %+v", e)
+	}
+
+	requests = 0
+	if resp := get("/v2/private/tags/list"); resp.StatusCode != http.StatusForbidden || requests != 1 {
+		t.Errorf("Expected the forbidden request not to be retried, got %s after %d requests", resp.Status, requests)
+	}
+
+	// Requests whose body cannot be sent again are not retried
+	requests = 0
+	resp, err := client.Post(srv.URL+"/v2/upload/blobs/uploads/", "application/octet-stream", io.NopCloser(strings.NewReader("data")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if requests != 1 {
+		t.Errorf("Expected the request with a stream body to be sent once, got %d requests", requests)
+	}
+}
+
+func TestWrapError(t *testing.T) {
+	c := &Client{failures: newFailedRequests()}
+	u := "https://registry.example.com/v2/charts/mychart/manifests/1.0.0"
+	c.failures.add(&Error{StatusCode: http.StatusTooManyRequests, Method: http.MethodHead, URL: u, Registry: "registry.example.com", Repository: "charts/mychart", RequestID: "abc123"})
+
+	status := remoteserrors.ErrUnexpectedStatus{StatusCode: http.StatusTooManyRequests, Status: "429 Too Many Requests", RequestMethod: http.MethodHead, RequestURL: u}
+	err := error(errors.Wrap(status, "failed to resolve"))
+	c.wrapError(&err)
+	if !IsRateLimited(err) || IsUnauthorized(err) {
+		t.Errorf("Expected a rate limited error, got %v", err)
+	}
+	if !strings.HasPrefix(err.Error(), "failed to resolve: unexpected status") || !strings.HasSuffix(err.Error(), "(request ID abc123)") {
+		t.Errorf("Unexpected message %q", err)
+	}
+	var e *Error
+	if !errors.As(err, &e) || e.Repository != "charts/mychart" || e.Registry != "registry.example.com" {
+		t.Errorf("Expected the error of the request, got %+v", e)
+	}
+	if !errors.As(err, &status) {
+		t.Error("Expected the error to wrap the original error")
+	}
+
+	// The errors of requests which were not remembered are made from the
+	// original error
+	err = remoteserrors.ErrUnexpectedStatus{StatusCode: http.StatusUnauthorized, RequestMethod: http.MethodGet, RequestURL: "https://registry.example.com/v2/charts/other/blobs/sha256:abc",
+		Body: []byte(`{"errors":[{"code":"UNAUTHORIZED","message":"authentication required"}]}`)}
+	c.wrapError(&err)
+	if !IsUnauthorized(err) {
+		t.Errorf("Expected an unauthorized error, got %v", err)
+	}
+	if !errors.As(err, &e) || e.Repository != "charts/other" || e.Message != "unauthorized: authentication required" {
+		t.Errorf("Unexpected error %+v", e)
+	}
+
+	err = errors.New("other")
+	c.wrapError(&err)
+	if IsUnauthorized(err) || IsRateLimited(err) || err.Error() != "other" {
+		t.Errorf("Expected other errors to be kept, got %v", err)
+	}
+}
+
+func TestRequestRepository(t *testing.T) {
+	for path, expect := range map[string]string{
+		"/v2/charts/mychart/manifests/1.0.0":      "charts/mychart",
+		"/v2/a/b/c/blobs/sha256:abc":              "a/b/c",
+		"/v2/mychart/tags/list":                   "mychart",
+		"/v2/charts/mychart/blobs/uploads/":       "charts/mychart",
+		"/v2/charts/mychart/referrers/sha256:abc": "charts/mychart",
+		"/v2/_catalog":                            "",
+		"/v2/":                                    "",
+		"/token":                                  "",
+	} {
+		u, _ := http.NewRequest(http.MethodGet, "https://registry.example.com"+path, nil)
+		if got := requestRepository(u.URL); got != expect {
+			t.Errorf("%s: expected repository %q, got %q", path, expect, got)
+		}
+	}
+}
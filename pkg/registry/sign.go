@@ -0,0 +1,529 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry // import "helm.sh/helm/v3/pkg/registry"
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/remotes"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+	"oras.land/oras-go/pkg/content"
+	"oras.land/oras-go/pkg/oras"
+	"oras.land/oras-go/pkg/registry"
+
+	"helm.sh/helm/v3/internal/version"
+)
+
+const (
+	// DefaultFulcioURL is the URL of the public Fulcio certificate authority
+	// issuing the certificates of keyless signatures.
+	DefaultFulcioURL = "https://fulcio.sigstore.dev"
+	// DefaultRekorURL is the URL of the public Rekor transparency log
+	// recording keyless signatures.
+	DefaultRekorURL = "https://rekor.sigstore.dev"
+	// SignatureBundleExt is the extension of the cosign bundles holding the
+	// sigstore signatures of chart archives.
+	SignatureBundleExt = ".cosign.bundle"
+)
+
+type (
+	// SignatureSigner makes cosign signatures, either with a private key or
+	// keyless, with a short-lived certificate that Fulcio issues to the
+	// identity of an OIDC token. Keyless signatures are recorded in the Rekor
+	// transparency log.
+	SignatureSigner struct {
+		key           crypto.Signer
+		identityToken string
+		fulcioURL     string
+		rekorURL      string
+		httpClient    *http.Client
+	}
+
+	// SignerOption allows specifying various settings of keyless signers
+	SignerOption func(*SignatureSigner)
+
+	// SignatureBundle is the signature of a file, in the format of the
+	// bundles of "cosign sign-blob --bundle", which "cosign verify-blob
+	// --bundle" verifies.
+	SignatureBundle struct {
+		Base64Signature string `json:"base64Signature"`
+		// Cert is the base64 encoded PEM certificate of keyless signatures.
+		Cert string `json:"cert,omitempty"`
+		// RekorBundle proves that the transparency log recorded keyless
+		// signatures.
+		RekorBundle json.RawMessage `json:"rekorBundle,omitempty"`
+	}
+
+	// signature is a signature made by a SignatureSigner.
+	signature struct {
+		sig    []byte
+		cert   []byte
+		chain  []byte
+		bundle *transparencyLogBundle
+	}
+)
+
+// SignerOptFulcioURL returns a function that sets the URL of the certificate authority of a keyless signer
+func SignerOptFulcioURL(fulcioURL string) SignerOption {
+	return func(s *SignatureSigner) {
+		s.fulcioURL = strings.TrimSuffix(fulcioURL, "/")
+	}
+}
+
+// SignerOptRekorURL returns a function that sets the URL of the transparency log of a keyless signer
+func SignerOptRekorURL(rekorURL string) SignerOption {
+	return func(s *SignatureSigner) {
+		s.rekorURL = strings.TrimSuffix(rekorURL, "/")
+	}
+}
+
+// SignerOptHTTPClient returns a function that sets the HTTP client of a keyless signer
+func SignerOptHTTPClient(httpClient *http.Client) SignerOption {
+	return func(s *SignatureSigner) {
+		s.httpClient = httpClient
+	}
+}
+
+// NewKeySigner returns a signer signing with the PEM encoded private key in
+// the file. Keys generated by "cosign generate-key-pair" are decrypted with
+// the password.
+func NewKeySigner(path string, password []byte) (*SignatureSigner, error) {
+	key, err := loadPrivateKey(path, password)
+	if err != nil {
+		return nil, err
+	}
+	return &SignatureSigner{key: key}, nil
+}
+
+// NewKeylessSigner returns a keyless signer, requesting certificates for the
+// identity of the OIDC identity token.
+func NewKeylessSigner(identityToken string, options ...SignerOption) (*SignatureSigner, error) {
+	if identityToken == "" {
+		return nil, errors.New("keyless signing requires an OIDC identity token")
+	}
+	s := &SignatureSigner{
+		identityToken: identityToken,
+		fulcioURL:     DefaultFulcioURL,
+		rekorURL:      DefaultRekorURL,
+		httpClient:    http.DefaultClient,
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s, nil
+}
+
+// SignBlob signs the content of a file.
+func (s *SignatureSigner) SignBlob(data []byte) (*SignatureBundle, error) {
+	sig, err := s.sign(data)
+	if err != nil {
+		return nil, err
+	}
+	b := &SignatureBundle{Base64Signature: base64.StdEncoding.EncodeToString(sig.sig)}
+	if sig.cert != nil {
+		b.Cert = base64.StdEncoding.EncodeToString(sig.cert)
+	}
+	if sig.bundle != nil {
+		if b.RekorBundle, err = json.Marshal(sig.bundle); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// sign signs data, with a new certificate if the signer is keyless.
+func (s *SignatureSigner) sign(data []byte) (*signature, error) {
+	if s.key != nil {
+		sig, err := signBlob(s.key, data)
+		if err != nil {
+			return nil, err
+		}
+		return &signature{sig: sig}, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	chain, err := s.requestCertificate(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get a signing certificate")
+	}
+	sig, err := signBlob(key, data)
+	if err != nil {
+		return nil, err
+	}
+	block, rest := pem.Decode(chain)
+	if block == nil {
+		return nil, errors.New("no signing certificate issued")
+	}
+	cert := pem.EncodeToMemory(block)
+	bundle, err := s.recordSignature(data, sig, cert)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to record the signature in the transparency log")
+	}
+	return &signature{sig: sig, cert: cert, chain: bytes.TrimSpace(rest), bundle: bundle}, nil
+}
+
+// annotations returns the annotations of the signature layer of sig.
+func (sig *signature) annotations() (map[string]string, error) {
+	a := map[string]string{
+		signatureAnnotation: base64.StdEncoding.EncodeToString(sig.sig),
+	}
+	if sig.cert != nil {
+		a[signatureCertificateAnnotation] = string(sig.cert)
+		if len(sig.chain) > 0 {
+			a[signatureChainAnnotation] = string(sig.chain)
+		}
+	}
+	if sig.bundle != nil {
+		b, err := json.Marshal(sig.bundle)
+		if err != nil {
+			return nil, err
+		}
+		a[signatureBundleAnnotation] = string(b)
+	}
+	return a, nil
+}
+
+// requestCertificate requests a certificate for the public key of key from
+// Fulcio, and returns the PEM encoded certificate chain.
+func (s *SignatureSigner) requestCertificate(key *ecdsa.PrivateKey) ([]byte, error) {
+	subject, err := tokenSubject(s.identityToken)
+	if err != nil {
+		return nil, err
+	}
+	// proves the possession of the key by signing the subject of the token
+	proof, err := signBlob(key, []byte(subject))
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var req struct {
+		Credentials struct {
+			OIDCIdentityToken string `json:"oidcIdentityToken"`
+		} `json:"credentials"`
+		PublicKeyRequest struct {
+			PublicKey struct {
+				Algorithm string `json:"algorithm"`
+				Content   string `json:"content"`
+			} `json:"publicKey"`
+			ProofOfPossession []byte `json:"proofOfPossession"`
+		} `json:"publicKeyRequest"`
+	}
+	req.Credentials.OIDCIdentityToken = s.identityToken
+	req.PublicKeyRequest.PublicKey.Algorithm = "ECDSA"
+	req.PublicKeyRequest.PublicKey.Content = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	req.PublicKeyRequest.ProofOfPossession = proof
+
+	type certificateChain struct {
+		Chain struct {
+			Certificates []string `json:"certificates"`
+		} `json:"chain"`
+	}
+	var resp struct {
+		SignedCertificateEmbeddedSct *certificateChain `json:"signedCertificateEmbeddedSct"`
+		SignedCertificateDetachedSct *certificateChain `json:"signedCertificateDetachedSct"`
+	}
+	if err := s.post(s.fulcioURL+"/api/v2/signingCert", req, &resp); err != nil {
+		return nil, err
+	}
+	chain := resp.SignedCertificateEmbeddedSct
+	if chain == nil {
+		chain = resp.SignedCertificateDetachedSct
+	}
+	if chain == nil || len(chain.Chain.Certificates) == 0 {
+		return nil, errors.New("no certificate in the response")
+	}
+	return []byte(strings.Join(chain.Chain.Certificates, "\n")), nil
+}
+
+// recordSignature records the signature of data in Rekor, and returns the
+// bundle proving that it did.
+func (s *SignatureSigner) recordSignature(data, sig, cert []byte) (*transparencyLogBundle, error) {
+	sum := sha256.Sum256(data)
+	entry := hashedRekord{APIVersion: "0.0.1", Kind: "hashedrekord"}
+	entry.Spec.Data.Hash.Algorithm = "sha256"
+	entry.Spec.Data.Hash.Value = hex.EncodeToString(sum[:])
+	entry.Spec.Signature.Content = sig
+	entry.Spec.Signature.PublicKey.Content = cert
+
+	var resp map[string]struct {
+		Body           string `json:"body"`
+		IntegratedTime int64  `json:"integratedTime"`
+		LogID          string `json:"logID"`
+		LogIndex       int64  `json:"logIndex"`
+		Verification   struct {
+			SignedEntryTimestamp []byte `json:"signedEntryTimestamp"`
+		} `json:"verification"`
+	}
+	if err := s.post(s.rekorURL+"/api/v1/log/entries", entry, &resp); err != nil {
+		return nil, err
+	}
+	for _, e := range resp {
+		return &transparencyLogBundle{
+			SignedEntryTimestamp: e.Verification.SignedEntryTimestamp,
+			Payload: transparencyLogPayload{
+				Body:           e.Body,
+				IntegratedTime: e.IntegratedTime,
+				LogID:          e.LogID,
+				LogIndex:       e.LogIndex,
+			},
+		}, nil
+	}
+	return nil, errors.New("no entry in the response")
+}
+
+func (s *SignatureSigner) post(url string, in, out interface{}) error {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", version.GetUserAgent())
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		return errors.Errorf("%s returned %s: %s", url, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, out)
+}
+
+// tokenSubject returns the email address of the OIDC identity token, or its
+// subject if it has none. The token is verified by Fulcio.
+func tokenSubject(token string) (string, error) {
+	var claims struct {
+		Email   string `json:"email"`
+		Subject string `json:"sub"`
+	}
+	if err := parseTokenClaims(token, &claims); err != nil {
+		return "", err
+	}
+	if claims.Email != "" {
+		return claims.Email, nil
+	}
+	if claims.Subject == "" {
+		return "", errors.New("OIDC identity token has no subject")
+	}
+	return claims.Subject, nil
+}
+
+// pushSignature signs the manifest with the digest d in the repository of
+// ref, and pushes the signature along the existing signatures of the
+// manifest. It returns the reference of the signatures.
+func (c *Client) pushSignature(ref registry.Reference, resolver remotes.Resolver, d digest.Digest, signer *SignatureSigner) (string, error) {
+	var p signaturePayload
+	p.Critical.Identity.DockerReference = fmt.Sprintf("%s/%s", ref.Registry, ref.Repository)
+	p.Critical.Image.DockerManifestDigest = d.String()
+	p.Critical.Type = signaturePayloadType
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	sig, err := signer.sign(payload)
+	if err != nil {
+		return "", err
+	}
+	annotations, err := sig.annotations()
+	if err != nil {
+		return "", err
+	}
+
+	memoryStore := content.NewMemory()
+	layer, err := memoryStore.Add("", SignatureLayerMediaType, payload)
+	if err != nil {
+		return "", err
+	}
+	layer.Annotations = annotations
+	layers := []ocispec.Descriptor{layer}
+
+	sigRef := signatureReference(ref, d)
+	rctx := ctx(c.out, c.debug)
+	_, existing, err := fetchManifest(rctx, resolver, sigRef)
+	switch {
+	case errdefs.IsNotFound(err):
+	case err != nil:
+		return "", err
+	default:
+		fetcher, err := resolver.Fetcher(rctx, sigRef)
+		if err != nil {
+			return "", err
+		}
+		for _, l := range existing.Layers {
+			data, err := fetchBlob(rctx, fetcher, l)
+			if err != nil {
+				return "", err
+			}
+			memoryStore.Set(l, data)
+			layers = append(layers, l)
+		}
+	}
+
+	configDescriptor, err := memoryStore.Add("", ocispec.MediaTypeImageConfig, []byte("{}"))
+	if err != nil {
+		return "", err
+	}
+	manifestData, manifest, err := content.GenerateManifest(&configDescriptor, nil, layers...)
+	if err != nil {
+		return "", err
+	}
+	if err := memoryStore.StoreManifest(sigRef, manifest, manifestData); err != nil {
+		return "", err
+	}
+	registryStore := content.Registry{Resolver: resolver}
+	if _, err := oras.Copy(rctx, memoryStore, sigRef, registryStore, "", oras.WithNameValidation(nil)); err != nil {
+		return "", err
+	}
+	return sigRef, nil
+}
+
+// signBlob signs the SHA-256 digest of data, or data itself with Ed25519
+// keys, as verified by verifyBlob.
+func signBlob(key crypto.Signer, data []byte) ([]byte, error) {
+	if _, ok := key.(ed25519.PrivateKey); ok {
+		return key.Sign(rand.Reader, data, crypto.Hash(0))
+	}
+	sum := sha256.Sum256(data)
+	return key.Sign(rand.Reader, sum[:], crypto.SHA256)
+}
+
+// encryptedKey is a private key encrypted by cosign.
+type encryptedKey struct {
+	KDF struct {
+		Name   string `json:"name"`
+		Params struct {
+			N int `json:"N"`
+			R int `json:"r"`
+			P int `json:"p"`
+		} `json:"params"`
+		Salt []byte `json:"salt"`
+	} `json:"kdf"`
+	Cipher struct {
+		Name  string `json:"name"`
+		Nonce []byte `json:"nonce"`
+	} `json:"cipher"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+func loadPrivateKey(path string, password []byte) (crypto.Signer, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read private key")
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.Errorf("invalid private key %s", path)
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "ENCRYPTED SIGSTORE PRIVATE KEY", "ENCRYPTED COSIGN PRIVATE KEY":
+		der, err := decryptKey(block.Bytes, password)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to decrypt private key %s", path)
+		}
+		key, err = x509.ParsePKCS8PrivateKey(der)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid private key %s", path)
+		}
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, errors.Errorf("unsupported private key type %q in %s", block.Type, path)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid private key %s", path)
+	}
+
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		return k, nil
+	case *rsa.PrivateKey:
+		return k, nil
+	case ed25519.PrivateKey:
+		return k, nil
+	default:
+		return nil, errors.Errorf("unsupported private key type %T in %s", key, path)
+	}
+}
+
+// decryptKey decrypts a private key encrypted by cosign, with the scrypt key
+// derivation function and NaCl secretbox.
+func decryptKey(data, password []byte) ([]byte, error) {
+	var k encryptedKey
+	if err := json.Unmarshal(data, &k); err != nil {
+		return nil, err
+	}
+	if k.KDF.Name != "scrypt" || k.Cipher.Name != "nacl/secretbox" || len(k.Cipher.Nonce) != 24 {
+		return nil, errors.Errorf("unsupported key encryption %s with %s", k.KDF.Name, k.Cipher.Name)
+	}
+	secret, err := scrypt.Key(password, k.KDF.Salt, k.KDF.Params.N, k.KDF.Params.R, k.KDF.Params.P, 32)
+	if err != nil {
+		return nil, err
+	}
+	var nonce [24]byte
+	var secretKey [32]byte
+	copy(nonce[:], k.Cipher.Nonce)
+	copy(secretKey[:], secret)
+	der, ok := secretbox.Open(nil, k.Ciphertext, &nonce, &secretKey)
+	if !ok {
+		return nil, errors.New("wrong password")
+	}
+	return der, nil
+}
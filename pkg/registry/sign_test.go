@@ -0,0 +1,262 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry // import "helm.sh/helm/v3/pkg/registry"
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+// writeEncryptedKey writes key encrypted with password as cosign does.
+func writeEncryptedKey(t *testing.T, path string, key *ecdsa.PrivateKey, password []byte) {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var k encryptedKey
+	k.KDF.Name = "scrypt"
+	k.KDF.Params.N, k.KDF.Params.R, k.KDF.Params.P = 1024, 8, 1
+	k.KDF.Salt = []byte("0123456789abcdef0123456789abcdef")
+	k.Cipher.Name = "nacl/secretbox"
+	k.Cipher.Nonce = []byte("0123456789abcdef01234567")
+	secret, err := scrypt.Key(password, k.KDF.Salt, 1024, 8, 1, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var nonce [24]byte
+	var secretKey [32]byte
+	copy(nonce[:], k.Cipher.Nonce)
+	copy(secretKey[:], secret)
+	k.Ciphertext = secretbox.Seal(nil, der, &nonce, &secretKey)
+	b, err := json.Marshal(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, path, "ENCRYPTED SIGSTORE PRIVATE KEY", b)
+}
+
+func TestKeySigner(t *testing.T) {
+	dir := t.TempDir()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted := filepath.Join(dir, "cosign.key")
+	writeEncryptedKey(t, encrypted, key, []byte("hunter2"))
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := filepath.Join(dir, "plain.key")
+	writePEM(t, plain, "PRIVATE KEY", der)
+
+	for _, path := range []string{encrypted, plain} {
+		signer, err := NewKeySigner(path, []byte("hunter2"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		bundle, err := signer.SignBlob([]byte("chart"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := base64.StdEncoding.DecodeString(bundle.Base64Signature)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := verifyBlob(&key.PublicKey, []byte("chart"), sig); err != nil {
+			t.Errorf("Expected the signature made with %s to be valid: %v", filepath.Base(path), err)
+		}
+		if bundle.Cert != "" || bundle.RekorBundle != nil {
+			t.Error("Expected a signature made with a key to have no certificate or transparency log bundle")
+		}
+	}
+
+	if _, err := NewKeySigner(encrypted, []byte("wrong")); err == nil {
+		t.Error("Expected a wrong password to fail")
+	}
+	if _, err := NewKeySigner(filepath.Join(dir, "missing.key"), nil); err == nil {
+		t.Error("Expected a missing key to fail")
+	}
+}
+
+// testIdentityToken returns an unsigned OIDC identity token for email.
+func testIdentityToken(email string) string {
+	claims, _ := json.Marshal(map[string]string{"email": email, "sub": "12345"})
+	return "eyJhbGciOiJub25lIn0." + base64.RawURLEncoding.EncodeToString(claims) + ".signature"
+}
+
+// newTestSigstore starts fake Fulcio and Rekor servers, issuing certificates
+// signed by the CA and recording entries signed by the transparency log key.
+func newTestSigstore(t *testing.T, ca *x509.Certificate, caKey, tlogKey *ecdsa.PrivateKey) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/signingCert", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Credentials struct {
+				OIDCIdentityToken string `json:"oidcIdentityToken"`
+			} `json:"credentials"`
+			PublicKeyRequest struct {
+				PublicKey struct {
+					Content string `json:"content"`
+				} `json:"publicKey"`
+				ProofOfPossession []byte `json:"proofOfPossession"`
+			} `json:"publicKeyRequest"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		block, _ := pem.Decode([]byte(req.PublicKeyRequest.PublicKey.Content))
+		pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		email, _ := tokenSubject(req.Credentials.OIDCIdentityToken)
+		if verifyBlob(pub, []byte(email), req.PublicKeyRequest.ProofOfPossession) != nil {
+			http.Error(w, "invalid proof of possession", http.StatusBadRequest)
+			return
+		}
+		issuer, _ := asn1.Marshal("https://issuer.example.com")
+		der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+			SerialNumber:    big.NewInt(time.Now().UnixNano()),
+			NotBefore:       time.Now().Add(-time.Minute),
+			NotAfter:        time.Now().Add(10 * time.Minute),
+			EmailAddresses:  []string{email},
+			KeyUsage:        x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+			ExtraExtensions: []pkix.Extension{{Id: oidIssuerV2, Value: issuer}},
+		}, ca, pub, caKey)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var resp struct {
+			SignedCertificateEmbeddedSct struct {
+				Chain struct {
+					Certificates []string `json:"certificates"`
+				} `json:"chain"`
+			} `json:"signedCertificateEmbeddedSct"`
+		}
+		resp.SignedCertificateEmbeddedSct.Chain.Certificates = []string{
+			string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+			string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw})),
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+	mux.HandleFunc("/api/v1/log/entries", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		payload := transparencyLogPayload{
+			Body:           base64.StdEncoding.EncodeToString(body),
+			IntegratedTime: time.Now().Unix(),
+			LogID:          "c0d23d6ad406973f9559f3ba2d1ca01f84147d8ffc5b8445c224f98b9591801d",
+			LogIndex:       7,
+		}
+		canonical, _ := json.Marshal(payload)
+		sum := sha256.Sum256(canonical)
+		set, _ := ecdsa.SignASN1(rand.Reader, tlogKey, sum[:])
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"24296fb24b8ad77a": map[string]interface{}{
+				"body":           payload.Body,
+				"integratedTime": payload.IntegratedTime,
+				"logID":          payload.LogID,
+				"logIndex":       payload.LogIndex,
+				"verification":   map[string]interface{}{"signedEntryTimestamp": set},
+			},
+		})
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestKeylessSigner(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := newTestCA(t, filepath.Join(dir, "fulcio.pem"))
+	tlogKey := newTestKey(t, filepath.Join(dir, "rekor.pub"))
+	srv := newTestSigstore(t, ca, caKey, tlogKey)
+
+	if _, err := NewKeylessSigner(""); err == nil {
+		t.Error("Expected keyless signing without an identity token to fail")
+	}
+	signer, err := NewKeylessSigner(testIdentityToken("release@example.com"),
+		SignerOptFulcioURL(srv.URL+"/"), SignerOptRekorURL(srv.URL), SignerOptHTTPClient(srv.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := digest.FromString("manifest")
+	payload := testPayload(d)
+	sig, err := signer.sign(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	annotations, err := sig.annotations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy := &SignaturePolicy{Keyless: &KeylessPolicy{
+		Roots:              filepath.Join(dir, "fulcio.pem"),
+		TransparencyLogKey: filepath.Join(dir, "rekor.pub"),
+		Identities:         []SignatureIdentity{{Subject: "release@example.com", Issuer: "https://issuer.example.com"}},
+	}}
+	v, err := policy.verifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := v.verify(d, payload, annotations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Signer != "release@example.com" {
+		t.Errorf("Expected the signer to be the identity of the token, got %q", result.Signer)
+	}
+
+	bundle, err := signer.SignBlob([]byte("chart"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bundle.Cert == "" || bundle.RekorBundle == nil {
+		t.Error("Expected a keyless signature to have a certificate and a transparency log bundle")
+	}
+}
@@ -0,0 +1,694 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry // import "helm.sh/helm/v3/pkg/registry"
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/containerd/containerd/remotes"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	"oras.land/oras-go/pkg/registry"
+	"sigs.k8s.io/yaml"
+)
+
+// The annotations of the layers of cosign signatures.
+const (
+	signatureAnnotation            = "dev.cosignproject.cosign/signature"
+	signatureCertificateAnnotation = "dev.sigstore.cosign/certificate"
+	signatureChainAnnotation       = "dev.sigstore.cosign/chain"
+	signatureBundleAnnotation      = "dev.sigstore.cosign/bundle"
+)
+
+// signaturePayloadType is the type of the payloads of cosign signatures.
+const signaturePayloadType = "cosign container image signature"
+
+// The extensions of Fulcio certificates holding the OIDC issuer of the
+// identity of the signer.
+var (
+	oidIssuerV1 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	oidIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// SignaturePolicy configures the verification of the sigstore signatures of
+// charts, as made by cosign: the signatures attached to charts stored in
+// registries, and the cosign bundles alongside chart archives. A chart is
+// trusted if one of its signatures is made with one of the public keys, or is
+// a keyless signature of one of the identities of the keyless policy, and
+// satisfies the requirements of the policy.
+//
+// The policy is usually loaded from a YAML file with LoadSignaturePolicy:
+//
+//	publicKeys:
+//	- cosign.pub
+//	keyless:
+//	  roots: fulcio.pem
+//	  transparencyLogKey: rekor.pub
+//	  identities:
+//	  - subject: release@example.com
+//	    issuer: https://accounts.google.com
+//	annotations:
+//	  org.example/approved: "true"
+//	transparencyLog:
+//	  required: true
+type SignaturePolicy struct {
+	// PublicKeys are the paths of the PEM encoded public keys trusted to sign
+	// charts.
+	PublicKeys []string `json:"publicKeys,omitempty"`
+	// Keyless configures the verification of keyless signatures. They are not
+	// trusted if it is nil.
+	Keyless *KeylessPolicy `json:"keyless,omitempty"`
+	// Annotations are the annotations trusted signatures must have, as added
+	// by "cosign sign -a". The signatures of chart archives have none, so
+	// they are not trusted by policies requiring annotations.
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// TransparencyLog configures the verification of the transparency log
+	// entries of signatures.
+	TransparencyLog *TransparencyLogPolicy `json:"transparencyLog,omitempty"`
+}
+
+// TransparencyLogPolicy configures the verification of the transparency log
+// entries recording signatures. Keyless signatures must always be recorded.
+type TransparencyLogPolicy struct {
+	// Key is the path of the PEM encoded public key of the transparency log.
+	// It defaults to the transparency log key of the keyless policy.
+	Key string `json:"key,omitempty"`
+	// Required requires the signatures made with public keys to be recorded
+	// in the transparency log too.
+	Required bool `json:"required,omitempty"`
+}
+
+// KeylessPolicy configures the verification of keyless signatures, made with
+// short-lived certificates issued by a certificate authority such as Fulcio
+// to an OIDC identity, and recorded in a transparency log such as Rekor.
+type KeylessPolicy struct {
+	// Roots is the path of the PEM encoded certificates of the certificate
+	// authorities issuing signing certificates, with their intermediates.
+	Roots string `json:"roots"`
+	// TransparencyLogKey is the path of the PEM encoded public key of the
+	// transparency log the signatures must be recorded in. The certificates
+	// are verified at the time the log recorded the signature.
+	TransparencyLogKey string `json:"transparencyLogKey"`
+	// Identities are the identities trusted to sign charts.
+	Identities []SignatureIdentity `json:"identities"`
+}
+
+// SignatureIdentity matches the identities of signing certificates. Every
+// field matches if it is empty; the regular expressions match if they match
+// any part of the value, as in cosign.
+type SignatureIdentity struct {
+	// Subject is the email address or URI of the signer.
+	Subject string `json:"subject,omitempty"`
+	// SubjectRegexp is a regular expression matching the subject.
+	SubjectRegexp string `json:"subjectRegexp,omitempty"`
+	// Issuer is the OIDC issuer of the identity of the signer.
+	Issuer string `json:"issuer,omitempty"`
+	// IssuerRegexp is a regular expression matching the issuer.
+	IssuerRegexp string `json:"issuerRegexp,omitempty"`
+}
+
+// SignatureVerification is the result of the verification of the signature
+// of a chart.
+type SignatureVerification struct {
+	// Digest is the digest of the signed manifest.
+	Digest string
+	// ChartDigest is the digest of the chart layer of the manifest.
+	ChartDigest string
+	// Signer is the public key file, or the subject of the certificate of a
+	// keyless signature.
+	Signer string
+	// Issuer is the OIDC issuer of the identity of a keyless signature.
+	Issuer string
+}
+
+// LoadSignaturePolicy reads a signature policy from a YAML file. The paths
+// of the policy are relative to the directory of the file.
+func LoadSignaturePolicy(path string) (*SignaturePolicy, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read signature policy")
+	}
+	p := &SignaturePolicy{}
+	if err := yaml.UnmarshalStrict(b, p); err != nil {
+		return nil, errors.Wrapf(err, "invalid signature policy %s", path)
+	}
+
+	dir := filepath.Dir(path)
+	resolve := func(name string) string {
+		if name == "" || filepath.IsAbs(name) {
+			return name
+		}
+		return filepath.Join(dir, name)
+	}
+	for i, key := range p.PublicKeys {
+		p.PublicKeys[i] = resolve(key)
+	}
+	if p.Keyless != nil {
+		p.Keyless.Roots = resolve(p.Keyless.Roots)
+		p.Keyless.TransparencyLogKey = resolve(p.Keyless.TransparencyLogKey)
+	}
+	if p.TransparencyLog != nil {
+		p.TransparencyLog.Key = resolve(p.TransparencyLog.Key)
+	}
+
+	if _, err := p.verifier(); err != nil {
+		return nil, errors.Wrapf(err, "invalid signature policy %s", path)
+	}
+	return p, nil
+}
+
+// VerifySignature verifies that the chart at ref, with the chart layer data,
+// has a cosign signature trusted by the policy. The plugins at ref, with the
+// plugin archive data, are verified the same way.
+func (c *Client) VerifySignature(ref string, data []byte, policy *SignaturePolicy) (_ *SignatureVerification, err error) {
+	defer c.wrapError(&err)
+
+	if policy == nil {
+		return nil, errors.New("no signature policy")
+	}
+	v, err := policy.verifier()
+	if err != nil {
+		return nil, err
+	}
+	parsedRef, err := parseReference(ref)
+	if err != nil {
+		return nil, err
+	}
+	resolver, err := c.resolver(parsedRef)
+	if err != nil {
+		return nil, err
+	}
+	rctx := ctx(c.out, c.debug)
+
+	desc, manifest, err := fetchManifest(rctx, resolver, parsedRef.String())
+	if err != nil {
+		return nil, err
+	}
+	chartDigest := digest.FromBytes(data)
+	var found bool
+	for _, layer := range manifest.Layers {
+		if (layer.MediaType == ChartLayerMediaType || layer.MediaType == LegacyChartLayerMediaType || layer.MediaType == PluginLayerMediaType) && layer.Digest == chartDigest {
+			found = true
+		}
+	}
+	if !found {
+		return nil, errors.Errorf("chart does not match the manifest %s of %s", desc.Digest, ref)
+	}
+
+	sigRef := signatureReference(parsedRef, desc.Digest)
+	_, sigManifest, err := fetchManifest(rctx, resolver, sigRef)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch the signatures of %s", ref)
+	}
+	fetcher, err := resolver.Fetcher(rctx, sigRef)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	for _, layer := range sigManifest.Layers {
+		if layer.MediaType != SignatureLayerMediaType {
+			continue
+		}
+		payload, err := fetchBlob(rctx, fetcher, layer)
+		if err != nil {
+			return nil, err
+		}
+		result, err := v.verify(desc.Digest, payload, layer.Annotations)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		result.Digest = desc.Digest.String()
+		result.ChartDigest = chartDigest.String()
+		return result, nil
+	}
+	if len(problems) == 0 {
+		return nil, errors.Errorf("no signatures found for %s", ref)
+	}
+	return nil, errors.Errorf("no signature of %s is trusted: %s", ref, strings.Join(problems, "; "))
+}
+
+// VerifyBundle verifies that the cosign bundle of a chart archive, as written
+// by "helm package --sign-cosign" or "cosign sign-blob --bundle", holds a
+// signature of data trusted by the policy.
+func (p *SignaturePolicy) VerifyBundle(data []byte, bundle *SignatureBundle) (*SignatureVerification, error) {
+	v, err := p.verifier()
+	if err != nil {
+		return nil, err
+	}
+	if len(v.annotations) > 0 {
+		return nil, errors.New("signatures of chart archives have no annotations")
+	}
+	sig, err := base64.StdEncoding.DecodeString(bundle.Base64Signature)
+	if err != nil || len(sig) == 0 {
+		return nil, errors.New("invalid signature")
+	}
+	certPEM, err := base64.StdEncoding.DecodeString(bundle.Cert)
+	if err != nil {
+		return nil, errors.New("invalid signing certificate")
+	}
+	result, err := v.verifySignature(data, sig, certPEM, nil, bundle.RekorBundle)
+	if err != nil {
+		return nil, err
+	}
+	result.ChartDigest = digest.FromBytes(data).String()
+	return result, nil
+}
+
+// signatureReference returns the reference of the cosign signatures of the
+// manifest with the digest d in the repository of ref.
+func signatureReference(ref registry.Reference, d digest.Digest) string {
+	return fmt.Sprintf("%s/%s:%s-%s.sig", ref.Registry, ref.Repository, d.Algorithm(), d.Encoded())
+}
+
+func fetchManifest(ctx context.Context, resolver remotes.Resolver, ref string) (ocispec.Descriptor, *ocispec.Manifest, error) {
+	_, desc, err := resolver.Resolve(ctx, ref)
+	if err != nil {
+		return desc, nil, err
+	}
+	fetcher, err := resolver.Fetcher(ctx, ref)
+	if err != nil {
+		return desc, nil, err
+	}
+	data, err := fetchBlob(ctx, fetcher, desc)
+	if err != nil {
+		return desc, nil, err
+	}
+	manifest := &ocispec.Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return desc, nil, errors.Wrapf(err, "invalid manifest %s", desc.Digest)
+	}
+	return desc, manifest, nil
+}
+
+// fetchBlob fetches the content of desc, checking its digest.
+func fetchBlob(ctx context.Context, fetcher remotes.Fetcher, desc ocispec.Descriptor) ([]byte, error) {
+	rc, err := fetcher.Fetch(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, desc.Size+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) != desc.Size || digest.FromBytes(data) != desc.Digest {
+		return nil, errors.Errorf("content of %s does not match its digest", desc.Digest)
+	}
+	return data, nil
+}
+
+// signaturePayload is the simple signing payload of cosign signatures.
+type signaturePayload struct {
+	Critical struct {
+		Identity struct {
+			DockerReference string `json:"docker-reference"`
+		} `json:"identity"`
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+	Optional map[string]interface{} `json:"optional"`
+}
+
+// transparencyLogBundle is the proof that a signature was recorded in the
+// Rekor transparency log.
+type transparencyLogBundle struct {
+	SignedEntryTimestamp []byte                 `json:"SignedEntryTimestamp"`
+	Payload              transparencyLogPayload `json:"Payload"`
+}
+
+// transparencyLogPayload is the payload of the signed entry timestamp. Its
+// fields are sorted, so it marshals to its canonical JSON.
+type transparencyLogPayload struct {
+	Body           string `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogID          string `json:"logID"`
+	LogIndex       int64  `json:"logIndex"`
+}
+
+// hashedRekord is the body of the transparency log entry of a signature.
+type hashedRekord struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Spec       struct {
+		Data struct {
+			Hash struct {
+				Algorithm string `json:"algorithm"`
+				Value     string `json:"value"`
+			} `json:"hash"`
+		} `json:"data"`
+		Signature struct {
+			Content   []byte `json:"content"`
+			PublicKey struct {
+				Content []byte `json:"content"`
+			} `json:"publicKey"`
+		} `json:"signature"`
+	} `json:"spec"`
+}
+
+type namedKey struct {
+	name string
+	key  crypto.PublicKey
+}
+
+type identityMatcher struct {
+	subject, issuer             string
+	subjectRegexp, issuerRegexp *regexp.Regexp
+}
+
+func (m *identityMatcher) match(subjects []string, issuer string) bool {
+	if m.issuer != "" && m.issuer != issuer {
+		return false
+	}
+	if m.issuerRegexp != nil && !m.issuerRegexp.MatchString(issuer) {
+		return false
+	}
+	for _, subject := range subjects {
+		if (m.subject == "" || m.subject == subject) && (m.subjectRegexp == nil || m.subjectRegexp.MatchString(subject)) {
+			return true
+		}
+	}
+	return false
+}
+
+// signatureVerifier verifies signatures with the keys and certificates of a
+// policy.
+type signatureVerifier struct {
+	keys                 []namedKey
+	roots, intermediates *x509.CertPool
+	tlogKey              crypto.PublicKey
+	tlogRequired         bool
+	identities           []identityMatcher
+	annotations          map[string]string
+}
+
+func (p *SignaturePolicy) verifier() (*signatureVerifier, error) {
+	v := &signatureVerifier{annotations: p.Annotations}
+	for _, name := range p.PublicKeys {
+		key, err := loadPublicKey(name)
+		if err != nil {
+			return nil, err
+		}
+		v.keys = append(v.keys, namedKey{name: name, key: key})
+	}
+
+	var tlogKey string
+	if p.Keyless != nil {
+		tlogKey = p.Keyless.TransparencyLogKey
+	}
+	if t := p.TransparencyLog; t != nil {
+		if t.Key != "" {
+			tlogKey = t.Key
+		}
+		v.tlogRequired = t.Required
+	}
+	if tlogKey != "" {
+		var err error
+		if v.tlogKey, err = loadPublicKey(tlogKey); err != nil {
+			return nil, err
+		}
+	} else if v.tlogRequired {
+		return nil, errors.New("the transparency log requirement needs a transparency log key")
+	}
+
+	if k := p.Keyless; k != nil {
+		if len(k.Identities) == 0 {
+			return nil, errors.New("keyless signatures require at least one trusted identity")
+		}
+		if k.Roots == "" || v.tlogKey == nil {
+			return nil, errors.New("keyless signatures require roots and a transparency log key")
+		}
+		b, err := os.ReadFile(k.Roots)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read certificate roots")
+		}
+		v.roots, v.intermediates = x509.NewCertPool(), x509.NewCertPool()
+		for _, cert := range parseCertificates(b) {
+			if bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+				v.roots.AddCert(cert)
+			} else {
+				v.intermediates.AddCert(cert)
+			}
+		}
+		for _, id := range k.Identities {
+			m := identityMatcher{subject: id.Subject, issuer: id.Issuer}
+			if id.SubjectRegexp != "" {
+				if m.subjectRegexp, err = regexp.Compile(id.SubjectRegexp); err != nil {
+					return nil, errors.Wrap(err, "invalid subject regular expression")
+				}
+			}
+			if id.IssuerRegexp != "" {
+				if m.issuerRegexp, err = regexp.Compile(id.IssuerRegexp); err != nil {
+					return nil, errors.Wrap(err, "invalid issuer regular expression")
+				}
+			}
+			v.identities = append(v.identities, m)
+		}
+	}
+
+	if len(v.keys) == 0 && v.roots == nil {
+		return nil, errors.New("no public keys or keyless identities are trusted")
+	}
+	return v, nil
+}
+
+// verify verifies a signature of the manifest with the digest d.
+func (v *signatureVerifier) verify(d digest.Digest, payload []byte, annotations map[string]string) (*SignatureVerification, error) {
+	var p signaturePayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return nil, errors.Wrap(err, "invalid signature payload")
+	}
+	if p.Critical.Type != signaturePayloadType {
+		return nil, errors.Errorf("unsupported signature type %q", p.Critical.Type)
+	}
+	if p.Critical.Image.DockerManifestDigest != d.String() {
+		return nil, errors.Errorf("signature of another manifest %s", p.Critical.Image.DockerManifestDigest)
+	}
+	for k, expected := range v.annotations {
+		if value, ok := p.Optional[k]; !ok || fmt.Sprint(value) != expected {
+			return nil, errors.Errorf("signature without the annotation %s=%s", k, expected)
+		}
+	}
+	sig, err := base64.StdEncoding.DecodeString(annotations[signatureAnnotation])
+	if err != nil || len(sig) == 0 {
+		return nil, errors.New("invalid signature")
+	}
+	return v.verifySignature(payload, sig, []byte(annotations[signatureCertificateAnnotation]),
+		[]byte(annotations[signatureChainAnnotation]), []byte(annotations[signatureBundleAnnotation]))
+}
+
+// verifySignature verifies the signature sig of data, made with a trusted key
+// or keyless with the certificate in certPEM, and the transparency log entry
+// recording it in bundle.
+func (v *signatureVerifier) verifySignature(data, sig, certPEM, chainPEM, bundle []byte) (*SignatureVerification, error) {
+	if len(certPEM) > 0 {
+		if v.roots == nil {
+			return nil, errors.New("keyless signatures are not trusted")
+		}
+		return v.verifyKeyless(data, sig, certPEM, chainPEM, bundle)
+	}
+	for _, k := range v.keys {
+		if verifyBlob(k.key, data, sig) != nil {
+			continue
+		}
+		if v.tlogRequired {
+			if len(bundle) == 0 {
+				return nil, errors.New("signature not recorded in the transparency log")
+			}
+			der, err := x509.MarshalPKIXPublicKey(k.key)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := v.verifyBundle(bundle, data, sig, der); err != nil {
+				return nil, err
+			}
+		}
+		return &SignatureVerification{Signer: k.name}, nil
+	}
+	return nil, errors.New("signature not made with a trusted key")
+}
+
+func (v *signatureVerifier) verifyKeyless(payload, sig, certPEM, chainPEM, bundle []byte) (*SignatureVerification, error) {
+	certs := parseCertificates(certPEM)
+	if len(certs) != 1 {
+		return nil, errors.New("invalid signing certificate")
+	}
+	cert := certs[0]
+
+	if len(bundle) == 0 {
+		return nil, errors.New("signature not recorded in the transparency log")
+	}
+	integrated, err := v.verifyBundle(bundle, payload, sig, cert.Raw)
+	if err != nil {
+		return nil, err
+	}
+
+	intermediates := v.intermediates.Clone()
+	for _, c := range parseCertificates(chainPEM) {
+		intermediates.AddCert(c)
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		CurrentTime:   integrated,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}); err != nil {
+		return nil, errors.Wrap(err, "untrusted signing certificate")
+	}
+	if err := verifyBlob(cert.PublicKey, payload, sig); err != nil {
+		return nil, err
+	}
+
+	var subjects []string
+	subjects = append(subjects, cert.EmailAddresses...)
+	for _, u := range cert.URIs {
+		subjects = append(subjects, u.String())
+	}
+	issuer := certificateIssuer(cert)
+	for _, m := range v.identities {
+		if m.match(subjects, issuer) {
+			return &SignatureVerification{Signer: strings.Join(subjects, ", "), Issuer: issuer}, nil
+		}
+	}
+	return nil, errors.Errorf("signature by untrusted identity %s (issuer %s)", strings.Join(subjects, ", "), issuer)
+}
+
+// verifyBundle verifies that the transparency log recorded the signature
+// made with the DER encoded certificate or public key, and returns the time
+// it did.
+func (v *signatureVerifier) verifyBundle(bundle, payload, sig, signer []byte) (time.Time, error) {
+	var b transparencyLogBundle
+	if err := json.Unmarshal(bundle, &b); err != nil {
+		return time.Time{}, errors.Wrap(err, "invalid transparency log bundle")
+	}
+	canonical, err := json.Marshal(b.Payload)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if err := verifyBlob(v.tlogKey, canonical, b.SignedEntryTimestamp); err != nil {
+		return time.Time{}, errors.Wrap(err, "invalid transparency log entry timestamp")
+	}
+
+	body, err := base64.StdEncoding.DecodeString(b.Payload.Body)
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "invalid transparency log entry")
+	}
+	var entry hashedRekord
+	if err := json.Unmarshal(body, &entry); err != nil {
+		return time.Time{}, errors.Wrap(err, "invalid transparency log entry")
+	}
+	sum := sha256.Sum256(payload)
+	block, _ := pem.Decode(entry.Spec.Signature.PublicKey.Content)
+	if entry.Spec.Data.Hash.Algorithm != "sha256" || entry.Spec.Data.Hash.Value != hex.EncodeToString(sum[:]) ||
+		!bytes.Equal(entry.Spec.Signature.Content, sig) || block == nil || !bytes.Equal(block.Bytes, signer) {
+		return time.Time{}, errors.New("transparency log entry does not match the signature")
+	}
+	return time.Unix(b.Payload.IntegratedTime, 0), nil
+}
+
+func certificateIssuer(cert *x509.Certificate) string {
+	for _, ext := range cert.Extensions {
+		switch {
+		case ext.Id.Equal(oidIssuerV2):
+			var issuer string
+			if _, err := asn1.Unmarshal(ext.Value, &issuer); err == nil {
+				return issuer
+			}
+		case ext.Id.Equal(oidIssuerV1):
+			return string(ext.Value)
+		}
+	}
+	return ""
+}
+
+// verifyBlob verifies the signature of the SHA-256 digest of data, or of
+// data itself for Ed25519 keys.
+func verifyBlob(key crypto.PublicKey, data, sig []byte) error {
+	sum := sha256.Sum256(data)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(k, sum[:], sig) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, sum[:], sig)
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, data, sig) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	default:
+		return errors.Errorf("unsupported public key type %T", key)
+	}
+}
+
+func loadPublicKey(path string) (crypto.PublicKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read public key")
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.Errorf("invalid public key %s", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid public key %s", path)
+	}
+	return key, nil
+}
+
+func parseCertificates(b []byte) []*x509.Certificate {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			return certs
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+			certs = append(certs, cert)
+		}
+	}
+}
@@ -0,0 +1,515 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry // import "helm.sh/helm/v3/pkg/registry"
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/remotes"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// memoryResolver is a remotes.Resolver serving manifests and blobs from
+// memory.
+type memoryResolver struct {
+	refs  map[string]ocispec.Descriptor
+	blobs map[digest.Digest][]byte
+}
+
+func newMemoryResolver() *memoryResolver {
+	return &memoryResolver{refs: map[string]ocispec.Descriptor{}, blobs: map[digest.Digest][]byte{}}
+}
+
+func (r *memoryResolver) add(mediaType string, data []byte, annotations map[string]string) ocispec.Descriptor {
+	d := digest.FromBytes(data)
+	r.blobs[d] = data
+	return ocispec.Descriptor{MediaType: mediaType, Digest: d, Size: int64(len(data)), Annotations: annotations}
+}
+
+func (r *memoryResolver) tag(ref string, layers ...ocispec.Descriptor) ocispec.Descriptor {
+	config := r.add(ConfigMediaType, []byte("{}"), nil)
+	data, _ := json.Marshal(ocispec.Manifest{MediaType: ocispec.MediaTypeImageManifest, Config: config, Layers: layers})
+	desc := r.add(ocispec.MediaTypeImageManifest, data, nil)
+	r.refs[ref] = desc
+	return desc
+}
+
+func (r *memoryResolver) Resolve(_ context.Context, ref string) (string, ocispec.Descriptor, error) {
+	desc, ok := r.refs[ref]
+	if !ok {
+		return "", desc, errdefs.ErrNotFound
+	}
+	return ref, desc, nil
+}
+
+func (r *memoryResolver) Fetcher(_ context.Context, _ string) (remotes.Fetcher, error) {
+	return remotes.FetcherFunc(func(_ context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+		data, ok := r.blobs[desc.Digest]
+		if !ok {
+			return nil, errdefs.ErrNotFound
+		}
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}), nil
+}
+
+func (r *memoryResolver) Pusher(_ context.Context, _ string) (remotes.Pusher, error) {
+	return nil, errdefs.ErrNotImplemented
+}
+
+func signPayload(t *testing.T, key *ecdsa.PrivateKey, data []byte) []byte {
+	t.Helper()
+	sum := sha256.Sum256(data)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sig
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestKey(t *testing.T, path string) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, path, "PUBLIC KEY", der)
+	return key
+}
+
+// newTestCA writes the certificate of a new certificate authority to path.
+func newTestCA(t *testing.T, path string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fulcio"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, path, "CERTIFICATE", der)
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func testPayload(d digest.Digest) []byte {
+	var p signaturePayload
+	p.Critical.Identity.DockerReference = "localhost:5000/charts/hello"
+	p.Critical.Image.DockerManifestDigest = d.String()
+	p.Critical.Type = signaturePayloadType
+	data, _ := json.Marshal(p)
+	return data
+}
+
+// testLogBundle returns the transparency log bundle recording the signature
+// sig of data, made with the PEM encoded certificate or public key signer.
+func testLogBundle(t *testing.T, tlogKey *ecdsa.PrivateKey, data, sig, signer []byte, integrated time.Time) []byte {
+	t.Helper()
+	var entry hashedRekord
+	sum := sha256.Sum256(data)
+	entry.Spec.Data.Hash.Algorithm = "sha256"
+	entry.Spec.Data.Hash.Value = hex.EncodeToString(sum[:])
+	entry.Spec.Signature.Content = sig
+	entry.Spec.Signature.PublicKey.Content = signer
+	body, _ := json.Marshal(entry)
+	bundle := transparencyLogBundle{Payload: transparencyLogPayload{
+		Body:           base64.StdEncoding.EncodeToString(body),
+		IntegratedTime: integrated.Unix(),
+		LogID:          "c0d23d6ad406973f9559f3ba2d1ca01f84147d8ffc5b8445c224f98b9591801d",
+		LogIndex:       42,
+	}}
+	canonical, _ := json.Marshal(bundle.Payload)
+	bundle.SignedEntryTimestamp = signPayload(t, tlogKey, canonical)
+	b, _ := json.Marshal(bundle)
+	return b
+}
+
+func TestVerifySignature(t *testing.T) {
+	dir := t.TempDir()
+	key := newTestKey(t, filepath.Join(dir, "cosign.pub"))
+	other := newTestKey(t, filepath.Join(dir, "other.pub"))
+	policyFile := filepath.Join(dir, "policy.yaml")
+	if err := os.WriteFile(policyFile, []byte("publicKeys:\n- cosign.pub\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := LoadSignaturePolicy(policyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolver := newMemoryResolver()
+	chartData := []byte("chart")
+	manifest := resolver.tag("localhost:5000/charts/hello:0.1.0", resolver.add(ChartLayerMediaType, chartData, nil))
+	unsigned := resolver.tag("localhost:5000/charts/hello:0.2.0", resolver.add(ChartLayerMediaType, []byte("unsigned"), nil))
+
+	payload := testPayload(manifest.Digest)
+	sigTag := "localhost:5000/charts/hello:sha256-" + manifest.Digest.Encoded() + ".sig"
+	resolver.tag(sigTag,
+		resolver.add(SignatureLayerMediaType, payload, map[string]string{
+			signatureAnnotation: base64.StdEncoding.EncodeToString(signPayload(t, other, payload)),
+		}),
+		resolver.add(SignatureLayerMediaType, payload, map[string]string{
+			signatureAnnotation: base64.StdEncoding.EncodeToString(signPayload(t, key, payload)),
+		}),
+	)
+
+	client, err := NewClient(ClientOptResolver(resolver))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := client.VerifySignature("localhost:5000/charts/hello:0.1.0", chartData, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Signer != filepath.Join(dir, "cosign.pub") {
+		t.Errorf("Expected the signer to be the public key, got %q", result.Signer)
+	}
+	if result.Digest != manifest.Digest.String() {
+		t.Errorf("Expected digest %s, got %s", manifest.Digest, result.Digest)
+	}
+
+	if _, err := client.VerifySignature("localhost:5000/charts/hello:0.1.0", []byte("tampered"), policy); err == nil {
+		t.Error("Expected a chart not matching the manifest to fail")
+	}
+	if _, err := client.VerifySignature("localhost:5000/charts/hello:0.2.0", []byte("unsigned"), policy); err == nil {
+		t.Error("Expected an unsigned chart to fail")
+	}
+
+	otherPolicy := &SignaturePolicy{PublicKeys: []string{filepath.Join(dir, "other.pub")}}
+	resolver.tag(sigTag, resolver.add(SignatureLayerMediaType, payload, map[string]string{
+		signatureAnnotation: base64.StdEncoding.EncodeToString(signPayload(t, key, payload)),
+	}))
+	if _, err := client.VerifySignature("localhost:5000/charts/hello:0.1.0", chartData, otherPolicy); err == nil || !strings.Contains(err.Error(), "trusted key") {
+		t.Errorf("Expected a signature by an untrusted key to fail, got %v", err)
+	}
+
+	// a signature of another manifest cannot be replayed
+	replayed := testPayload(unsigned.Digest)
+	resolver.tag(sigTag, resolver.add(SignatureLayerMediaType, replayed, map[string]string{
+		signatureAnnotation: base64.StdEncoding.EncodeToString(signPayload(t, key, replayed)),
+	}))
+	if _, err := client.VerifySignature("localhost:5000/charts/hello:0.1.0", chartData, policy); err == nil {
+		t.Error("Expected a signature of another manifest to fail")
+	}
+}
+
+func TestVerifyKeylessSignature(t *testing.T) {
+	dir := t.TempDir()
+	tlogKey := newTestKey(t, filepath.Join(dir, "rekor.pub"))
+
+	ca, caKey := newTestCA(t, filepath.Join(dir, "fulcio.pem"))
+
+	// the signing certificate expired long before the verification
+	signedAt := time.Now().Add(-30 * time.Minute)
+	signer, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer, _ := asn1.Marshal("https://issuer.example.com")
+	leafDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber:    big.NewInt(2),
+		NotBefore:       signedAt.Add(-time.Minute),
+		NotAfter:        signedAt.Add(9 * time.Minute),
+		EmailAddresses:  []string{"release@example.com"},
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		ExtraExtensions: []pkix.Extension{{Id: oidIssuerV2, Value: issuer}},
+	}, ca, &signer.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER})
+
+	resolver := newMemoryResolver()
+	chartData := []byte("chart")
+	manifest := resolver.tag("localhost:5000/charts/hello:0.1.0", resolver.add(ChartLayerMediaType, chartData, nil))
+	payload := testPayload(manifest.Digest)
+	sig := signPayload(t, signer, payload)
+
+	var entry hashedRekord
+	sum := sha256.Sum256(payload)
+	entry.Spec.Data.Hash.Algorithm = "sha256"
+	entry.Spec.Data.Hash.Value = hex.EncodeToString(sum[:])
+	entry.Spec.Signature.Content = sig
+	entry.Spec.Signature.PublicKey.Content = leafPEM
+	body, _ := json.Marshal(entry)
+	bundle := transparencyLogBundle{Payload: transparencyLogPayload{
+		Body:           base64.StdEncoding.EncodeToString(body),
+		IntegratedTime: signedAt.Unix(),
+		LogID:          "c0d23d6ad406973f9559f3ba2d1ca01f84147d8ffc5b8445c224f98b9591801d",
+		LogIndex:       42,
+	}}
+	canonical, _ := json.Marshal(bundle.Payload)
+	bundle.SignedEntryTimestamp = signPayload(t, tlogKey, canonical)
+	bundleJSON, _ := json.Marshal(bundle)
+
+	resolver.tag("localhost:5000/charts/hello:sha256-"+manifest.Digest.Encoded()+".sig",
+		resolver.add(SignatureLayerMediaType, payload, map[string]string{
+			signatureAnnotation:            base64.StdEncoding.EncodeToString(sig),
+			signatureCertificateAnnotation: string(leafPEM),
+			signatureBundleAnnotation:      string(bundleJSON),
+		}))
+
+	client, err := NewClient(ClientOptResolver(resolver))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyless := func(ids ...SignatureIdentity) *SignaturePolicy {
+		return &SignaturePolicy{Keyless: &KeylessPolicy{
+			Roots:              filepath.Join(dir, "fulcio.pem"),
+			TransparencyLogKey: filepath.Join(dir, "rekor.pub"),
+			Identities:         ids,
+		}}
+	}
+
+	result, err := client.VerifySignature("localhost:5000/charts/hello:0.1.0", chartData,
+		keyless(SignatureIdentity{Subject: "release@example.com", Issuer: "https://issuer.example.com"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Signer != "release@example.com" || result.Issuer != "https://issuer.example.com" {
+		t.Errorf("Unexpected signer %q of issuer %q", result.Signer, result.Issuer)
+	}
+
+	if _, err := client.VerifySignature("localhost:5000/charts/hello:0.1.0", chartData,
+		keyless(SignatureIdentity{SubjectRegexp: "@example\\.com$", IssuerRegexp: "^https://issuer\\."})); err != nil {
+		t.Errorf("Expected the identity to match the regular expressions, got %v", err)
+	}
+	if _, err := client.VerifySignature("localhost:5000/charts/hello:0.1.0", chartData,
+		keyless(SignatureIdentity{Subject: "release@example.com", Issuer: "https://other.example.com"})); err == nil {
+		t.Error("Expected a signature from another issuer to fail")
+	}
+	if _, err := client.VerifySignature("localhost:5000/charts/hello:0.1.0", chartData,
+		keyless(SignatureIdentity{Subject: "mallory@example.com"})); err == nil {
+		t.Error("Expected a signature by another identity to fail")
+	}
+	if _, err := client.VerifySignature("localhost:5000/charts/hello:0.1.0", chartData, keyless()); err == nil {
+		t.Error("Expected a keyless policy without identities to fail")
+	}
+}
+
+func TestLoadSignaturePolicy(t *testing.T) {
+	dir := t.TempDir()
+	newTestKey(t, filepath.Join(dir, "cosign.pub"))
+
+	for name, policy := range map[string]string{
+		"empty":       "{}\n",
+		"missing key": "publicKeys:\n- missing.pub\n",
+		"unknown":     "keys:\n- cosign.pub\n",
+		"bad regexp":  "keyless:\n  roots: cosign.pub\n  transparencyLogKey: cosign.pub\n  identities:\n  - subjectRegexp: \"(\"\n",
+		"no tlog key": "keyless:\n  roots: cosign.pub\n  identities:\n  - subject: me\n",
+		"tlog key":    "publicKeys:\n- cosign.pub\ntransparencyLog:\n  required: true\n",
+	} {
+		path := filepath.Join(dir, "policy.yaml")
+		if err := os.WriteFile(path, []byte(policy), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadSignaturePolicy(path); err == nil {
+			t.Errorf("Expected the %s policy to be invalid", name)
+		}
+	}
+
+	if _, err := LoadSignaturePolicy(filepath.Join(dir, "missing.yaml")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected a missing policy to fail with a not exist error, got %v", err)
+	}
+}
+
+func TestVerifySignatureRequirements(t *testing.T) {
+	dir := t.TempDir()
+	key := newTestKey(t, filepath.Join(dir, "cosign.pub"))
+	tlogKey := newTestKey(t, filepath.Join(dir, "rekor.pub"))
+	der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	resolver := newMemoryResolver()
+	chartData := []byte("chart")
+	recorded := resolver.tag("localhost:5000/charts/hello:0.1.0", resolver.add(ChartLayerMediaType, chartData, nil))
+	unrecorded := resolver.tag("localhost:5000/charts/hello:0.2.0", resolver.add(ChartLayerMediaType, []byte("unrecorded"), nil))
+
+	sign := func(manifest ocispec.Descriptor, record bool) {
+		var p signaturePayload
+		if err := json.Unmarshal(testPayload(manifest.Digest), &p); err != nil {
+			t.Fatal(err)
+		}
+		p.Optional = map[string]interface{}{"org.example/approved": "true"}
+		payload, _ := json.Marshal(p)
+		sig := signPayload(t, key, payload)
+		annotations := map[string]string{signatureAnnotation: base64.StdEncoding.EncodeToString(sig)}
+		if record {
+			annotations[signatureBundleAnnotation] = string(testLogBundle(t, tlogKey, payload, sig, keyPEM, time.Now()))
+		}
+		resolver.tag("localhost:5000/charts/hello:sha256-"+manifest.Digest.Encoded()+".sig",
+			resolver.add(SignatureLayerMediaType, payload, annotations))
+	}
+	sign(recorded, true)
+	sign(unrecorded, false)
+
+	client, err := NewClient(ClientOptResolver(resolver))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy := &SignaturePolicy{
+		PublicKeys:  []string{filepath.Join(dir, "cosign.pub")},
+		Annotations: map[string]string{"org.example/approved": "true"},
+	}
+	if _, err := client.VerifySignature("localhost:5000/charts/hello:0.2.0", []byte("unrecorded"), policy); err != nil {
+		t.Errorf("Expected the annotated signature to be trusted, got %v", err)
+	}
+	policy.Annotations["org.example/team"] = "charts"
+	if _, err := client.VerifySignature("localhost:5000/charts/hello:0.2.0", []byte("unrecorded"), policy); err == nil || !strings.Contains(err.Error(), "org.example/team=charts") {
+		t.Errorf("Expected a signature without a required annotation to fail, got %v", err)
+	}
+
+	policy = &SignaturePolicy{
+		PublicKeys:      []string{filepath.Join(dir, "cosign.pub")},
+		TransparencyLog: &TransparencyLogPolicy{Key: filepath.Join(dir, "rekor.pub"), Required: true},
+	}
+	if _, err := client.VerifySignature("localhost:5000/charts/hello:0.1.0", chartData, policy); err != nil {
+		t.Errorf("Expected the recorded signature to be trusted, got %v", err)
+	}
+	if _, err := client.VerifySignature("localhost:5000/charts/hello:0.2.0", []byte("unrecorded"), policy); err == nil || !strings.Contains(err.Error(), "transparency log") {
+		t.Errorf("Expected a signature not recorded in the transparency log to fail, got %v", err)
+	}
+}
+
+func TestVerifyBundle(t *testing.T) {
+	dir := t.TempDir()
+	key := newTestKey(t, filepath.Join(dir, "cosign.pub"))
+	tlogKey := newTestKey(t, filepath.Join(dir, "rekor.pub"))
+	ca, caKey := newTestCA(t, filepath.Join(dir, "fulcio.pem"))
+	der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	data := []byte("chart archive")
+	sig := signPayload(t, key, data)
+	bundle := &SignatureBundle{Base64Signature: base64.StdEncoding.EncodeToString(sig)}
+
+	policy := &SignaturePolicy{PublicKeys: []string{filepath.Join(dir, "cosign.pub")}}
+	result, err := policy.VerifyBundle(data, bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Signer != filepath.Join(dir, "cosign.pub") || result.ChartDigest != digest.FromBytes(data).String() {
+		t.Errorf("Unexpected verification %+v", result)
+	}
+	if _, err := policy.VerifyBundle([]byte("tampered"), bundle); err == nil {
+		t.Error("Expected a tampered chart archive to fail")
+	}
+
+	policy.TransparencyLog = &TransparencyLogPolicy{Key: filepath.Join(dir, "rekor.pub"), Required: true}
+	if _, err := policy.VerifyBundle(data, bundle); err == nil {
+		t.Error("Expected a signature not recorded in the transparency log to fail")
+	}
+	bundle.RekorBundle = testLogBundle(t, tlogKey, data, sig, keyPEM, time.Now())
+	if _, err := policy.VerifyBundle(data, bundle); err != nil {
+		t.Errorf("Expected the recorded signature to be trusted, got %v", err)
+	}
+
+	policy.Annotations = map[string]string{"org.example/approved": "true"}
+	if _, err := policy.VerifyBundle(data, bundle); err == nil || !strings.Contains(err.Error(), "annotations") {
+		t.Errorf("Expected a policy requiring annotations to reject chart archives, got %v", err)
+	}
+
+	// keyless signatures of chart archives
+	signer, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer, _ := asn1.Marshal("https://issuer.example.com")
+	leafDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber:    big.NewInt(2),
+		NotBefore:       time.Now().Add(-time.Minute),
+		NotAfter:        time.Now().Add(9 * time.Minute),
+		EmailAddresses:  []string{"release@example.com"},
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		ExtraExtensions: []pkix.Extension{{Id: oidIssuerV2, Value: issuer}},
+	}, ca, &signer.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER})
+	sig = signPayload(t, signer, data)
+	bundle = &SignatureBundle{
+		Base64Signature: base64.StdEncoding.EncodeToString(sig),
+		Cert:            base64.StdEncoding.EncodeToString(leafPEM),
+		RekorBundle:     testLogBundle(t, tlogKey, data, sig, leafPEM, time.Now()),
+	}
+	policy = &SignaturePolicy{Keyless: &KeylessPolicy{
+		Roots:              filepath.Join(dir, "fulcio.pem"),
+		TransparencyLogKey: filepath.Join(dir, "rekor.pub"),
+		Identities:         []SignatureIdentity{{Subject: "release@example.com"}},
+	}}
+	if result, err = policy.VerifyBundle(data, bundle); err != nil {
+		t.Fatal(err)
+	}
+	if result.Signer != "release@example.com" || result.Issuer != "https://issuer.example.com" {
+		t.Errorf("Unexpected signer %q of issuer %q", result.Signer, result.Issuer)
+	}
+	policy.Keyless.Identities = []SignatureIdentity{{Subject: "mallory@example.com"}}
+	if _, err := policy.VerifyBundle(data, bundle); err == nil {
+		t.Error("Expected a signature by another identity to fail")
+	}
+}
@@ -0,0 +1,270 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry // import "helm.sh/helm/v3/pkg/registry"
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/remotes"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/semaphore"
+)
+
+// DefaultConcurrency is the default number of blobs a client transfers
+// concurrently.
+const DefaultConcurrency = 3
+
+// withTransfers returns resolver, transferring at most concurrency blobs at
+// the same time if concurrency is positive, and retrying the transfers
+// failing with transient errors with the retry policy. Interrupted pulls
+// resume where they stopped; pushes are restarted from the data written so
+// far. The requests starting the transfers are retried by the transport of
+// the client.
+func withTransfers(resolver remotes.Resolver, concurrency int, policy RetryPolicy) remotes.Resolver {
+	r := &transferResolver{Resolver: resolver, policy: policy}
+	if concurrency > 0 {
+		r.limit = semaphore.NewWeighted(int64(concurrency))
+	}
+	return r
+}
+
+type transferResolver struct {
+	remotes.Resolver
+	limit  *semaphore.Weighted
+	policy RetryPolicy
+}
+
+func (r *transferResolver) Fetcher(ctx context.Context, ref string) (remotes.Fetcher, error) {
+	fetcher, err := r.Resolver.Fetcher(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return &transferFetcher{Fetcher: fetcher, limit: r.limit, policy: r.policy}, nil
+}
+
+func (r *transferResolver) Pusher(ctx context.Context, ref string) (remotes.Pusher, error) {
+	pusher, err := r.Resolver.Pusher(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return &transferPusher{Pusher: pusher, limit: r.limit, policy: r.policy}, nil
+}
+
+// acquire takes a slot of the limit, and returns the function releasing it.
+func acquire(ctx context.Context, limit *semaphore.Weighted) (func(), error) {
+	if limit == nil {
+		return func() {}, nil
+	}
+	if err := limit.Acquire(ctx, 1); err != nil {
+		return nil, err
+	}
+	return func() { limit.Release(1) }, nil
+}
+
+type transferFetcher struct {
+	remotes.Fetcher
+	limit  *semaphore.Weighted
+	policy RetryPolicy
+}
+
+func (f *transferFetcher) Fetch(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+	release, err := acquire(ctx, f.limit)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := f.Fetcher.Fetch(ctx, desc)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return &resumingReader{ctx: ctx, fetcher: f.Fetcher, policy: f.policy, desc: desc, rc: rc, release: release}, nil
+}
+
+// resumingReader reads a blob, fetching it again from the offset it stopped
+// at if reading fails with a transient error.
+type resumingReader struct {
+	ctx     context.Context
+	fetcher remotes.Fetcher
+	policy  RetryPolicy
+	desc    ocispec.Descriptor
+	rc      io.ReadCloser
+	release func()
+	offset  int64
+	retries int
+}
+
+func (r *resumingReader) Read(p []byte) (int, error) {
+	n, err := r.rc.Read(p)
+	r.offset += int64(n)
+	if err == nil || err == io.EOF || !retryable(err) || r.retries >= r.policy.MaxRetries {
+		return n, err
+	}
+	r.retries++
+	if err := r.resume(); err != nil {
+		return n, err
+	}
+	return n, nil
+}
+
+func (r *resumingReader) resume() error {
+	r.rc.Close()
+	if err := r.policy.sleep(r.ctx, r.retries); err != nil {
+		return err
+	}
+	rc, err := r.fetcher.Fetch(r.ctx, r.desc)
+	if err != nil {
+		return err
+	}
+	// The fetchers of registries seek with range requests; other content
+	// is read again up to the offset.
+	if seeker, ok := rc.(io.Seeker); ok {
+		_, err = seeker.Seek(r.offset, io.SeekStart)
+	} else {
+		_, err = io.CopyN(io.Discard, rc, r.offset)
+	}
+	if err != nil {
+		rc.Close()
+		return err
+	}
+	r.rc = rc
+	return nil
+}
+
+func (r *resumingReader) Close() error {
+	if r.release != nil {
+		r.release()
+		r.release = nil
+	}
+	return r.rc.Close()
+}
+
+type transferPusher struct {
+	remotes.Pusher
+	limit  *semaphore.Weighted
+	policy RetryPolicy
+}
+
+func (p *transferPusher) Push(ctx context.Context, desc ocispec.Descriptor) (content.Writer, error) {
+	release, err := acquire(ctx, p.limit)
+	if err != nil {
+		return nil, err
+	}
+	w, err := p.Pusher.Push(ctx, desc)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return &retryingWriter{ctx: ctx, pusher: p.Pusher, policy: p.policy, desc: desc, w: w, release: release}, nil
+}
+
+// retryingWriter pushes a blob, pushing it again with the data written so
+// far if the push fails with a transient error. The data of charts is held
+// in memory anyway, so keeping a copy of the blob is cheap.
+type retryingWriter struct {
+	ctx     context.Context
+	pusher  remotes.Pusher
+	policy  RetryPolicy
+	desc    ocispec.Descriptor
+	w       content.Writer
+	release func()
+	buf     bytes.Buffer
+	retries int
+	// exists is set if the registry got the blob during a retry.
+	exists bool
+}
+
+func (w *retryingWriter) Write(p []byte) (int, error) {
+	w.buf.Write(p)
+	if w.exists {
+		return len(p), nil
+	}
+	if _, err := w.w.Write(p); err != nil {
+		if err := w.retry(err); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (w *retryingWriter) Commit(ctx context.Context, size int64, expected digest.Digest, opts ...content.Opt) error {
+	for !w.exists {
+		err := w.w.Commit(ctx, size, expected, opts...)
+		if err == nil {
+			return nil
+		}
+		if err := w.retry(err); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// retry pushes the blob again with the data written so far, if err is
+// transient and there are retries left.
+func (w *retryingWriter) retry(err error) error {
+	for retryable(err) && w.retries < w.policy.MaxRetries {
+		w.retries++
+		w.w.Close()
+		if err := w.policy.sleep(w.ctx, w.retries); err != nil {
+			return err
+		}
+		var pw content.Writer
+		pw, err = w.pusher.Push(w.ctx, w.desc)
+		if errdefs.IsAlreadyExists(err) {
+			w.exists = true
+			return nil
+		}
+		if err != nil {
+			continue
+		}
+		w.w = pw
+		if _, err = w.w.Write(w.buf.Bytes()); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (w *retryingWriter) Close() error {
+	if w.release != nil {
+		w.release()
+		w.release = nil
+	}
+	return w.w.Close()
+}
+
+func (w *retryingWriter) Digest() digest.Digest {
+	return w.w.Digest()
+}
+
+func (w *retryingWriter) Status() (content.Status, error) {
+	return w.w.Status()
+}
+
+func (w *retryingWriter) Truncate(size int64) error {
+	if size > int64(w.buf.Len()) {
+		return errors.Errorf("cannot truncate %d bytes to %d", w.buf.Len(), size)
+	}
+	w.buf.Truncate(int(size))
+	return w.w.Truncate(size)
+}
@@ -0,0 +1,308 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/remotes"
+	remoteserrors "github.com/containerd/containerd/remotes/errors"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// testRetryPolicy retries transfers without delay.
+var testRetryPolicy = RetryPolicy{MaxRetries: 3}
+
+// fakeFetcher serves data, failing the first read of the first fetches after
+// failAfter bytes.
+type fakeFetcher struct {
+	data      []byte
+	failAfter int
+	failures  int
+	seekable  bool
+
+	mu      sync.Mutex
+	offsets []int64
+	open    int
+	maxOpen int
+}
+
+func (f *fakeFetcher) Fetch(_ context.Context, _ ocispec.Descriptor) (io.ReadCloser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.open++
+	if f.open > f.maxOpen {
+		f.maxOpen = f.open
+	}
+	r := &fakeReader{f: f, r: bytes.NewReader(f.data), fail: f.failures > 0}
+	if f.failures > 0 {
+		f.failures--
+	}
+	return r, nil
+}
+
+type fakeReader struct {
+	f    *fakeFetcher
+	r    *bytes.Reader
+	read int
+	fail bool
+}
+
+func (r *fakeReader) Read(p []byte) (int, error) {
+	if r.fail && r.read >= r.f.failAfter {
+		return 0, syscall.ECONNRESET
+	}
+	if r.fail && len(p) > r.f.failAfter-r.read {
+		p = p[:r.f.failAfter-r.read]
+	}
+	n, err := r.r.Read(p)
+	r.read += n
+	return n, err
+}
+
+func (r *fakeReader) Close() error {
+	r.f.mu.Lock()
+	defer r.f.mu.Unlock()
+	r.f.open--
+	return nil
+}
+
+// seekableFakeReader is a fakeReader seeking like the fetchers of registries.
+type seekableFakeReader struct {
+	*fakeReader
+}
+
+func (r *seekableFakeReader) Seek(offset int64, whence int) (int64, error) {
+	r.f.mu.Lock()
+	r.f.offsets = append(r.f.offsets, offset)
+	r.f.mu.Unlock()
+	return r.r.Seek(offset, whence)
+}
+
+type seekableFakeFetcher struct {
+	*fakeFetcher
+}
+
+func (f *seekableFakeFetcher) Fetch(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+	rc, err := f.fakeFetcher.Fetch(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+	return &seekableFakeReader{rc.(*fakeReader)}, nil
+}
+
+type fakeResolver struct {
+	remotes.Resolver
+	fetcher remotes.Fetcher
+	pusher  remotes.Pusher
+}
+
+func (r *fakeResolver) Fetcher(_ context.Context, _ string) (remotes.Fetcher, error) {
+	return r.fetcher, nil
+}
+
+func (r *fakeResolver) Pusher(_ context.Context, _ string) (remotes.Pusher, error) {
+	return r.pusher, nil
+}
+
+func TestTransferResume(t *testing.T) {
+	data := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+	desc := ocispec.Descriptor{Digest: digest.FromBytes(data), Size: int64(len(data))}
+
+	for _, seekable := range []bool{false, true} {
+		f := &fakeFetcher{data: data, failAfter: 10, failures: 2}
+		var fetcher remotes.Fetcher = f
+		if seekable {
+			fetcher = &seekableFakeFetcher{f}
+		}
+		resolver := withTransfers(&fakeResolver{fetcher: fetcher}, 1, testRetryPolicy)
+		rf, _ := resolver.Fetcher(context.Background(), "")
+		rc, err := rf.Fetch(context.Background(), desc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(rc)
+		if err != nil {
+			t.Fatalf("seekable %t: %v", seekable, err)
+		}
+		rc.Close()
+		if !bytes.Equal(got, data) {
+			t.Errorf("seekable %t: expected %q, got %q", seekable, data, got)
+		}
+		if seekable && fmt.Sprint(f.offsets) != "[10 20]" {
+			t.Errorf("Expected the pull to resume at offsets 10 and 20, got %v", f.offsets)
+		}
+		if f.open != 0 {
+			t.Errorf("seekable %t: expected all readers to be closed, %d are open", seekable, f.open)
+		}
+	}
+
+	// The retries are exhausted
+	f := &fakeFetcher{data: data, failAfter: 0, failures: testRetryPolicy.MaxRetries + 1}
+	rf, _ := withTransfers(&fakeResolver{fetcher: f}, 1, testRetryPolicy).Fetcher(context.Background(), "")
+	rc, err := rf.Fetch(context.Background(), desc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(rc); err == nil {
+		t.Error("Expected the pull to fail once the retries are exhausted")
+	}
+	rc.Close()
+}
+
+func TestTransferConcurrency(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 1024)
+	desc := ocispec.Descriptor{Digest: digest.FromBytes(data), Size: int64(len(data))}
+	f := &fakeFetcher{data: data}
+	rf, _ := withTransfers(&fakeResolver{fetcher: f}, 2, testRetryPolicy).Fetcher(context.Background(), "")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rc, err := rf.Fetch(context.Background(), desc)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer rc.Close()
+			if _, err := io.Copy(io.Discard, rc); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if f.maxOpen > 2 {
+		t.Errorf("Expected at most 2 concurrent transfers, got %d", f.maxOpen)
+	}
+}
+
+// fakePusher pushes blobs to a buffer, failing the writes of the first
+// pushes.
+type fakePusher struct {
+	failures int
+	exists   bool
+	pushes   int
+	pushed   *bytes.Buffer
+}
+
+func (p *fakePusher) Push(_ context.Context, desc ocispec.Descriptor) (content.Writer, error) {
+	p.pushes++
+	if p.exists && p.pushes > 1 {
+		return nil, errdefs.ErrAlreadyExists
+	}
+	w := &fakeWriter{p: p, fail: p.failures > 0}
+	if p.failures > 0 {
+		p.failures--
+	}
+	return w, nil
+}
+
+type fakeWriter struct {
+	content.Writer
+	p    *fakePusher
+	buf  bytes.Buffer
+	fail bool
+}
+
+func (w *fakeWriter) Write(p []byte) (int, error) {
+	if w.fail {
+		return 0, remoteserrors.ErrUnexpectedStatus{StatusCode: 503}
+	}
+	return w.buf.Write(p)
+}
+
+func (w *fakeWriter) Commit(_ context.Context, _ int64, _ digest.Digest, _ ...content.Opt) error {
+	w.p.pushed = &w.buf
+	return nil
+}
+
+func (w *fakeWriter) Close() error {
+	return nil
+}
+
+func TestTransferRetryPush(t *testing.T) {
+	data := []byte("0123456789")
+	desc := ocispec.Descriptor{Digest: digest.FromBytes(data), Size: int64(len(data))}
+	push := func(p *fakePusher) error {
+		rp, _ := withTransfers(&fakeResolver{pusher: p}, 1, testRetryPolicy).Pusher(context.Background(), "")
+		w, err := rp.Push(context.Background(), desc)
+		if err != nil {
+			return err
+		}
+		defer w.Close()
+		for _, chunk := range [][]byte{data[:5], data[5:]} {
+			if _, err := w.Write(chunk); err != nil {
+				return err
+			}
+		}
+		return w.Commit(context.Background(), desc.Size, desc.Digest)
+	}
+
+	p := &fakePusher{failures: 1}
+	if err := push(p); err != nil {
+		t.Fatal(err)
+	}
+	if p.pushes != 2 || p.pushed == nil || !bytes.Equal(p.pushed.Bytes(), data) {
+		t.Errorf("Expected the blob to be pushed again, got %d pushes", p.pushes)
+	}
+
+	// The registry got the blob before the first push failed
+	p = &fakePusher{failures: 1, exists: true}
+	if err := push(p); err != nil {
+		t.Fatal(err)
+	}
+	if p.pushed != nil {
+		t.Error("Expected an existing blob not to be pushed again")
+	}
+
+	p = &fakePusher{failures: testRetryPolicy.MaxRetries + 1}
+	if err := push(p); err == nil {
+		t.Error("Expected the push to fail once the retries are exhausted")
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	for _, tt := range []struct {
+		err    error
+		expect bool
+	}{
+		{nil, false},
+		{io.EOF, false},
+		{context.Canceled, false},
+		{errdefs.ErrNotFound, false},
+		{remoteserrors.ErrUnexpectedStatus{StatusCode: 401}, false},
+		{remoteserrors.ErrUnexpectedStatus{StatusCode: 429}, true},
+		{remoteserrors.ErrUnexpectedStatus{StatusCode: 502}, true},
+		{io.ErrUnexpectedEOF, true},
+		{fmt.Errorf("read: %w", syscall.ECONNRESET), true},
+	} {
+		if got := retryable(tt.err); got != tt.expect {
+			t.Errorf("%v: expected retryable %t, got %t", tt.err, tt.expect, got)
+		}
+	}
+}
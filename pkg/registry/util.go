@@ -59,6 +59,11 @@ func ContainsTag(tags []string, tag string) bool {
 	return false
 }
 
+// GetTagMatchingVersionOrConstraint returns the tag of the highest version
+// matching versionString, which is either an exact version or a semver
+// constraint such as ">=1.2.0 <2.0.0", like the versions of the charts of
+// classic repositories. An empty versionString matches the highest stable
+// version. Tags which are not valid semantic versions are ignored.
 func GetTagMatchingVersionOrConstraint(tags []string, versionString string) (string, error) {
 	var constraint *semver.Constraints
 	if versionString == "" {
@@ -81,19 +86,25 @@ func GetTagMatchingVersionOrConstraint(tags []string, versionString string) (str
 		}
 	}
 
-	// Otherwise try to find the first available version matching the string,
-	// in case it is a constraint
+	// Otherwise find the highest version matching the string, in case it is
+	// a constraint
+	var match string
+	var highest *semver.Version
 	for _, v := range tags {
-		test, err := semver.NewVersion(v)
+		// Tags hold underscores (_) in place of plus (+) signs
+		// See https://github.com/helm/helm/issues/10166
+		test, err := semver.StrictNewVersion(strings.ReplaceAll(v, "_", "+"))
 		if err != nil {
 			continue
 		}
-		if constraint.Check(test) {
-			return v, nil
+		if constraint.Check(test) && (highest == nil || test.GreaterThan(highest)) {
+			match, highest = v, test
 		}
 	}
-
-	return "", errors.Errorf("Could not locate a version matching provided version string %s", versionString)
+	if highest == nil {
+		return "", errors.Errorf("Could not locate a version matching provided version string %s", versionString)
+	}
+	return match, nil
 }
 
 // extractChartMeta is used to extract a chart metadata from a byte array
@@ -142,13 +153,14 @@ func parseReference(raw string) (registry.Reference, error) {
 }
 
 // NewRegistryClientWithTLS is a helper function to create a new registry client with TLS enabled.
-func NewRegistryClientWithTLS(out io.Writer, certFile, keyFile, caFile string, insecureSkipTLSverify bool, registryConfig string, debug bool) (*Client, error) {
+// Additional options are applied after the TLS options.
+func NewRegistryClientWithTLS(out io.Writer, certFile, keyFile, caFile string, insecureSkipTLSverify bool, registryConfig string, debug bool, options ...ClientOption) (*Client, error) {
 	tlsConf, err := tlsutil.NewClientTLS(certFile, keyFile, caFile, insecureSkipTLSverify)
 	if err != nil {
 		return nil, fmt.Errorf("can't create TLS config for client: %s", err)
 	}
 	// Create a new registry client
-	registryClient, err := NewClient(
+	registryClient, err := NewClient(append([]ClientOption{
 		ClientOptDebug(debug),
 		ClientOptEnableCache(true),
 		ClientOptWriter(out),
@@ -159,7 +171,7 @@ func NewRegistryClientWithTLS(out io.Writer, certFile, keyFile, caFile string, i
 				Proxy:           http.ProxyFromEnvironment,
 			},
 		}),
-	)
+	}, options...)...)
 	if err != nil {
 		return nil, err
 	}
@@ -273,3 +273,36 @@ func TestGenerateOCICreatedAnnotations(t *testing.T) {
 	}
 
 }
+
+func TestGetTagMatchingVersionOrConstraint(t *testing.T) {
+	// Unsorted, with a plus (+) sign stored as an underscore (_) and a tag
+	// which is not a version.
+	tags := []string{"1.2.0", "2.0.0", "latest", "1.10.1", "1.3.0-rc.1", "1.9.0_build.1", "0.9.0"}
+
+	tests := []struct {
+		version string
+		expect  string
+		wantErr bool
+	}{
+		{"", "2.0.0", false},
+		{"1.2.0", "1.2.0", false},
+		{"latest", "latest", false},
+		{">=1.2.0 <2.0.0", "1.10.1", false},
+		{"~1.9", "1.9.0_build.1", false},
+		{"1.2", "1.2.0", false},
+		{"~1.3.0-0", "1.3.0-rc.1", false},
+		{">=3.0.0", "", true},
+		{"not a constraint", "", true},
+	}
+
+	for _, tt := range tests {
+		tag, err := GetTagMatchingVersionOrConstraint(tags, tt.version)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: expected error %t, got %v", tt.version, tt.wantErr, err)
+			continue
+		}
+		if tag != tt.expect {
+			t.Errorf("%q: expected tag %q, got %q", tt.version, tt.expect, tag)
+		}
+	}
+}
@@ -19,7 +19,12 @@ package registry
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
 	"fmt"
 	"io"
 	"net"
@@ -36,6 +41,7 @@ import (
 	_ "github.com/distribution/distribution/v3/registry/auth/htpasswd"
 	_ "github.com/distribution/distribution/v3/registry/storage/driver/inmemory"
 	"github.com/foxcpp/go-mockdns"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/phayes/freeport"
 	"github.com/stretchr/testify/suite"
 	"golang.org/x/crypto/bcrypt"
@@ -394,3 +400,261 @@ func testTags(suite *TestSuite) {
 	suite.Nil(err, "no error retrieving tags")
 	suite.Equal(1, len(tags))
 }
+
+func testSign(suite *TestSuite) {
+	chartData, err := os.ReadFile("../downloader/testdata/local-subchart-0.1.0.tgz")
+	suite.Nil(err, "no error loading test chart")
+	meta, err := extractChartMeta(chartData)
+	suite.Nil(err, "no error extracting chart meta")
+	ref := fmt.Sprintf("%s/testsign/%s:%s", suite.DockerRegistryHost, meta.Name, meta.Version)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Nil(err, "no error generating a key")
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	suite.Nil(err, "no error marshaling the private key")
+	keyFile := filepath.Join(suite.WorkspaceDir, "cosign.key")
+	suite.Nil(os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+	der, err = x509.MarshalPKIXPublicKey(&key.PublicKey)
+	suite.Nil(err, "no error marshaling the public key")
+	pubFile := filepath.Join(suite.WorkspaceDir, "cosign.pub")
+	suite.Nil(os.WriteFile(pubFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644))
+
+	policy := &SignaturePolicy{PublicKeys: []string{pubFile}}
+	_, err = suite.RegistryClient.Push(chartData, ref)
+	suite.Nil(err, "no error pushing an unsigned chart")
+	_, err = suite.RegistryClient.VerifySignature(ref, chartData, policy)
+	suite.NotNil(err, "error verifying an unsigned chart")
+
+	signer, err := NewKeySigner(keyFile, nil)
+	suite.Nil(err, "no error loading the signing key")
+	result, err := suite.RegistryClient.Push(chartData, ref, PushOptSign(signer))
+	suite.Nil(err, "no error pushing a signed chart")
+	suite.True(strings.HasSuffix(result.Signature, ".sig"))
+
+	verification, err := suite.RegistryClient.VerifySignature(ref, chartData, policy)
+	suite.Nil(err, "no error verifying a signed chart")
+	suite.Equal(result.Manifest.Digest, verification.Digest)
+	suite.Equal(pubFile, verification.Signer)
+
+	// signing again keeps the existing signatures
+	_, err = suite.RegistryClient.Push(chartData, ref, PushOptSign(signer))
+	suite.Nil(err, "no error signing a chart again")
+	parsedRef, err := parseReference(result.Signature)
+	suite.Nil(err)
+	resolver, err := suite.RegistryClient.resolver(parsedRef)
+	suite.Nil(err)
+	_, manifest, err := fetchManifest(context.Background(), resolver, result.Signature)
+	suite.Nil(err, "no error fetching the signatures")
+	suite.Len(manifest.Layers, 2)
+	for _, layer := range manifest.Layers {
+		suite.Equal(SignatureLayerMediaType, layer.MediaType)
+	}
+}
+
+func testAttach(suite *TestSuite) {
+	chartData, err := os.ReadFile("../downloader/testdata/local-subchart-0.1.0.tgz")
+	suite.Nil(err, "no error loading test chart")
+	meta, err := extractChartMeta(chartData)
+	suite.Nil(err, "no error extracting chart meta")
+	ref := fmt.Sprintf("%s/testattach/%s:%s", suite.DockerRegistryHost, meta.Name, meta.Version)
+
+	_, err = suite.RegistryClient.Push(chartData, ref)
+	suite.Nil(err, "no error pushing the chart")
+	artifacts, err := suite.RegistryClient.Referrers(ref, "")
+	suite.Nil(err, "no error listing the artifacts of a chart without artifacts")
+	suite.Empty(artifacts)
+
+	sbom := []byte(`{"spdxVersion":"SPDX-2.3"}`)
+	attached, err := suite.RegistryClient.Attach(ref, SPDXArtifactType, sbom, AttachOptFileName("sbom.spdx.json"))
+	suite.Nil(err, "no error attaching an SBOM")
+	suite.Equal(SPDXArtifactType, attached.ArtifactType)
+	_, err = suite.RegistryClient.Attach(ref, InTotoArtifactType, []byte(`{"_type":"https://in-toto.io/Statement/v1"}`),
+		AttachOptAnnotations(map[string]string{"dev.helm.test": "true"}))
+	suite.Nil(err, "no error attaching a provenance attestation")
+
+	artifacts, err = suite.RegistryClient.Referrers(ref, "")
+	suite.Nil(err, "no error listing the artifacts")
+	suite.Len(artifacts, 2)
+	artifacts, err = suite.RegistryClient.Referrers(ref, SPDXArtifactType)
+	suite.Nil(err, "no error listing the artifacts of a type")
+	suite.Len(artifacts, 1)
+	suite.Equal(attached.Digest, artifacts[0].Digest)
+	suite.Equal("sbom.spdx.json", artifacts[0].Title())
+	suite.NotEmpty(artifacts[0].Created())
+
+	artifact, files, err := suite.RegistryClient.FetchArtifact(ref, attached.Digest)
+	suite.Nil(err, "no error fetching the SBOM")
+	suite.Equal(SPDXArtifactType, artifact.ArtifactType)
+	suite.Len(files, 1)
+	suite.Equal("sbom.spdx.json", files[0].Name)
+	suite.Equal(sbom, files[0].Data)
+
+	// the chart manifest itself is not attached to the chart
+	result, err := suite.RegistryClient.Push(chartData, ref)
+	suite.Nil(err)
+	_, _, err = suite.RegistryClient.FetchArtifact(ref, result.Manifest.Digest)
+	suite.NotNil(err, "error fetching a manifest that is not attached to the chart")
+}
+
+func testProgress(suite *TestSuite) {
+	chartData, err := os.ReadFile("../downloader/testdata/local-subchart-0.1.0.tgz")
+	suite.Nil(err, "no error loading test chart")
+	meta, err := extractChartMeta(chartData)
+	suite.Nil(err, "no error extracting chart meta")
+	ref := fmt.Sprintf("%s/testprogress/%s:%s", suite.DockerRegistryHost, meta.Name, meta.Version)
+
+	progress := map[string]int64{}
+	record := func(desc ocispec.Descriptor, transferred int64) {
+		suite.LessOrEqual(transferred, desc.Size, "no more bytes than the size of the content")
+		suite.GreaterOrEqual(transferred, progress[desc.Digest.String()], "transferred bytes increase")
+		progress[desc.Digest.String()] = transferred
+	}
+	result, err := suite.RegistryClient.Push(chartData, ref, PushOptProgress(record))
+	suite.Nil(err, "no error pushing the chart")
+	suite.Equal(result.Chart.Size, progress[result.Chart.Digest], "the push of the chart layer is reported")
+	suite.Equal(result.Manifest.Size, progress[result.Manifest.Digest], "the push of the manifest is reported")
+
+	progress = map[string]int64{}
+	pulled, err := suite.RegistryClient.Pull(ref, PullOptProgress(record))
+	suite.Nil(err, "no error pulling the chart")
+	suite.Equal(pulled.Chart.Size, progress[pulled.Chart.Digest], "the pull of the chart layer is reported")
+}
+
+func testMirrors(suite *TestSuite) {
+	chartData, err := os.ReadFile("../downloader/testdata/local-subchart-0.1.0.tgz")
+	suite.Nil(err, "no error loading test chart")
+	meta, err := extractChartMeta(chartData)
+	suite.Nil(err, "no error extracting chart meta")
+
+	// The chart pushed to testrepo is served for another registry by a
+	// mirror mapping its repositories to testrepo, after an unreachable one.
+	configFile := filepath.Join(suite.WorkspaceDir, "registries.yaml")
+	err = os.WriteFile(configFile, []byte(fmt.Sprintf(`registries:
+  charts.invalid:
+    mirrors:
+    - endpoint: http://127.0.0.1:1
+    - endpoint: http://%s/v2/testrepo
+`, suite.DockerRegistryHost)), 0644)
+	suite.Nil(err, "no error writing registries config")
+
+	client, err := NewClient(
+		ClientOptWriter(suite.Out),
+		ClientOptCredentialsFile(filepath.Join(suite.WorkspaceDir, CredentialsFileBasename)),
+		ClientOptRegistriesConfig(configFile),
+	)
+	suite.Nil(err, "no error creating registry client with mirrors")
+
+	tags, err := client.Tags(fmt.Sprintf("charts.invalid/%s", meta.Name))
+	suite.Nil(err, "no error listing tags from the mirror")
+	suite.Equal([]string{meta.Version}, tags)
+
+	result, err := client.Pull(fmt.Sprintf("charts.invalid/%s:%s", meta.Name, meta.Version))
+	suite.Nil(err, "no error pulling the chart from the mirror")
+	suite.Equal(chartData, result.Chart.Data)
+
+	_, err = client.Pull(fmt.Sprintf("charts.invalid/%s:9.9.9", meta.Name))
+	suite.NotNil(err, "error pulling a chart missing from the mirrors")
+}
+
+func testResolve(suite *TestSuite) {
+	chartData, err := os.ReadFile("../downloader/testdata/local-subchart-0.1.0.tgz")
+	suite.Nil(err, "no error loading test chart")
+	meta, err := extractChartMeta(chartData)
+	suite.Nil(err, "no error extracting chart meta")
+	ref := fmt.Sprintf("%s/testresolve/%s:%s", suite.DockerRegistryHost, meta.Name, meta.Version)
+
+	pushed, err := suite.RegistryClient.Push(chartData, ref)
+	suite.Nil(err, "no error pushing the chart")
+
+	digest, err := suite.RegistryClient.Resolve(ref)
+	suite.Nil(err, "no error resolving the chart")
+	suite.Equal(pushed.Manifest.Digest, digest)
+
+	_, err = suite.RegistryClient.Pull(ref, PullOptDigest(digest))
+	suite.Nil(err, "no error pulling the chart with its digest")
+
+	// The tag is moved to a chart with another manifest
+	otherData, err := os.ReadFile("../repo/repotest/testdata/examplechart-0.1.0.tgz")
+	suite.Nil(err, "no error loading other test chart")
+	_, err = suite.RegistryClient.Push(otherData, ref, PushOptStrictMode(false))
+	suite.Nil(err, "no error moving the tag")
+
+	_, err = suite.RegistryClient.Pull(ref, PullOptDigest(digest))
+	suite.NotNil(err, "error pulling a chart whose tag was moved")
+
+	_, err = suite.RegistryClient.Resolve(fmt.Sprintf("%s/testresolve/%s:9.9.9", suite.DockerRegistryHost, meta.Name))
+	suite.NotNil(err, "error resolving a missing chart")
+}
+
+func testCatalog(suite *TestSuite) {
+	chartData, err := os.ReadFile("../downloader/testdata/local-subchart-0.1.0.tgz")
+	suite.Nil(err, "no error loading test chart")
+	meta, err := extractChartMeta(chartData)
+	suite.Nil(err, "no error extracting chart meta")
+	otherData, err := os.ReadFile("../repo/repotest/testdata/examplechart-0.1.0.tgz")
+	suite.Nil(err, "no error loading other test chart")
+
+	_, err = suite.RegistryClient.Push(chartData, fmt.Sprintf("%s/testcatalog/%s:%s", suite.DockerRegistryHost, meta.Name, meta.Version))
+	suite.Nil(err, "no error pushing the chart")
+	_, err = suite.RegistryClient.Push(otherData, fmt.Sprintf("%s/testcatalog/%s:0.2.0", suite.DockerRegistryHost, meta.Name), PushOptStrictMode(false))
+	suite.Nil(err, "no error pushing another version of the chart")
+	_, err = suite.RegistryClient.Push(otherData, fmt.Sprintf("%s/testcatalog-other/examplechart:0.1.0", suite.DockerRegistryHost))
+	suite.Nil(err, "no error pushing a chart outside of the namespace")
+
+	entries, err := suite.RegistryClient.Catalog(fmt.Sprintf("oci://%s/testcatalog", suite.DockerRegistryHost))
+	suite.Nil(err, "no error listing the namespace")
+	suite.Len(entries, 1)
+	suite.Equal(fmt.Sprintf("%s/testcatalog/%s", suite.DockerRegistryHost, meta.Name), entries[0].Repository)
+	suite.Len(entries[0].Versions, 1)
+	suite.Equal("0.2.0", entries[0].Versions[0].Tag)
+	suite.Equal("examplechart", entries[0].Versions[0].Metadata.Name)
+
+	entries, err = suite.RegistryClient.Catalog(fmt.Sprintf("%s/testcatalog", suite.DockerRegistryHost), CatalogOptAllVersions(true))
+	suite.Nil(err, "no error listing every version of the namespace")
+	suite.Len(entries, 1)
+	suite.Len(entries[0].Versions, 2)
+	suite.Equal(meta.Name, entries[0].Versions[1].Metadata.Name)
+	suite.Equal(meta.Version, entries[0].Versions[1].Metadata.Version)
+
+	repositories, err := suite.RegistryClient.Repositories(suite.DockerRegistryHost)
+	suite.Nil(err, "no error listing the repositories")
+	suite.Contains(repositories, "testcatalog-other/examplechart")
+}
+
+func testBundle(suite *TestSuite) {
+	chartData, err := os.ReadFile("../downloader/testdata/local-subchart-0.1.0.tgz")
+	suite.Nil(err, "no error loading test chart")
+	meta, err := extractChartMeta(chartData)
+	suite.Nil(err, "no error extracting chart meta")
+	ref := fmt.Sprintf("%s/testbundle/%s:%s", suite.DockerRegistryHost, meta.Name, meta.Version)
+	pushed, err := suite.RegistryClient.Push(chartData, ref)
+	suite.Nil(err, "no error pushing the chart")
+
+	var buf bytes.Buffer
+	bundle, err := suite.RegistryClient.ExportBundle(&buf, []string{ref}, BundleOptImages([]string{"nginx:1.16.0", "busybox"}))
+	suite.Nil(err, "no error exporting the bundle")
+	suite.Len(bundle.Charts, 1)
+	suite.Equal(pushed.Manifest.Digest, bundle.Charts[0].Digest)
+	suite.Equal([]string{"busybox", "nginx:1.16.0"}, bundle.Images)
+
+	imported, err := suite.RegistryClient.ImportBundle(bytes.NewReader(buf.Bytes()), fmt.Sprintf("oci://%s/testbundle-mirror", suite.DockerRegistryHost))
+	suite.Nil(err, "no error importing the bundle")
+	suite.Len(imported.Charts, 1)
+	suite.Equal(fmt.Sprintf("%s/testbundle-mirror/testbundle/%s:%s", suite.DockerRegistryHost, meta.Name, meta.Version), imported.Charts[0].Ref)
+	suite.Equal([]string{"busybox", "nginx:1.16.0"}, imported.Images)
+
+	digest, err := suite.RegistryClient.Resolve(imported.Charts[0].Ref)
+	suite.Nil(err, "no error resolving the imported chart")
+	suite.Equal(pushed.Manifest.Digest, digest)
+	_, err = suite.RegistryClient.Pull(imported.Charts[0].Ref, PullOptDigest(pushed.Manifest.Digest))
+	suite.Nil(err, "no error pulling the imported chart")
+
+	// The blobs of bundles are checked against their digest
+	tampered := bytes.Replace(buf.Bytes(), chartData[:64], make([]byte, 64), 1)
+	suite.NotEqual(buf.Bytes(), tampered)
+	_, err = suite.RegistryClient.ImportBundle(bytes.NewReader(tampered), fmt.Sprintf("%s/testbundle-tampered", suite.DockerRegistryHost))
+	suite.NotNil(err, "error importing a tampered bundle")
+
+	_, err = suite.RegistryClient.ImportBundle(strings.NewReader("not a bundle"), fmt.Sprintf("%s/testbundle-invalid", suite.DockerRegistryHost))
+	suite.NotNil(err, "error importing an invalid bundle")
+}
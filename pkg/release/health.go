@@ -0,0 +1,44 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+// HealthStatus is the health of a live release resource. The statuses are
+// the ones of kstatus.
+type HealthStatus string
+
+const (
+	// HealthCurrent indicates that the resource is reconciled and ready.
+	HealthCurrent HealthStatus = "Current"
+	// HealthInProgress indicates that the resource is being reconciled.
+	HealthInProgress HealthStatus = "InProgress"
+	// HealthFailed indicates that the resource failed to be reconciled.
+	HealthFailed HealthStatus = "Failed"
+	// HealthNotFound indicates that the resource does not exist in the cluster.
+	HealthNotFound HealthStatus = "NotFound"
+	// HealthUnknown indicates that the health of the resource could not be determined.
+	HealthUnknown HealthStatus = "Unknown"
+)
+
+// ResourceHealth is the health of a live release resource.
+type ResourceHealth struct {
+	APIVersion string       `json:"apiVersion"`
+	Kind       string       `json:"kind"`
+	Namespace  string       `json:"namespace,omitempty"`
+	Name       string       `json:"name"`
+	Status     HealthStatus `json:"status"`
+	// Message is a human-friendly explanation of the status.
+	Message string `json:"message,omitempty"`
+}
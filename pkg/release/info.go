@@ -37,4 +37,19 @@ type Info struct {
 	Notes string `json:"notes,omitempty"`
 	// Contains the deployed resources information
 	Resources map[string][]runtime.Object `json:"resources,omitempty"`
+	// Contains the health of the deployed resources, if requested
+	Health []ResourceHealth `json:"health,omitempty"`
+	// Clusters records the state of the release in each target cluster, keyed
+	// by cluster name. The default cluster is recorded under an empty name.
+	Clusters map[string]*ClusterInfo `json:"clusters,omitempty"`
+}
+
+// ClusterInfo describes the state of a release in one target cluster.
+type ClusterInfo struct {
+	// Status is the state of the release resources in this cluster.
+	Status Status `json:"status,omitempty"`
+	// Description is a human-friendly message about the last operation in this cluster.
+	Description string `json:"description,omitempty"`
+	// Resources is the number of release resources targeting this cluster.
+	Resources int `json:"resources,omitempty"`
 }
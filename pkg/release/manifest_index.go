@@ -0,0 +1,43 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+// ResourceIndex locates the manifest of a resource in the manifest of a
+// release, so that the resource can be addressed without splitting the
+// manifest again.
+type ResourceIndex struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace,omitempty"`
+	// Source is the path to the template of the resource, like
+	// "mychart/templates/deployment.yaml".
+	Source string `json:"source,omitempty"`
+	// Offset is the offset of the manifest of the resource in the manifest
+	// of the release, in bytes.
+	Offset int `json:"offset"`
+	// Length is the length of the manifest of the resource, in bytes.
+	Length int `json:"length"`
+}
+
+// ResourceManifest returns the manifest of the resource located by the index
+// in the manifest of the release, and false if the index does not fit in it.
+func (r *Release) ResourceManifest(i ResourceIndex) (string, bool) {
+	if i.Offset < 0 || i.Length < 0 || i.Offset+i.Length > len(r.Manifest) {
+		return "", false
+	}
+	return r.Manifest[i.Offset : i.Offset+i.Length], true
+}
@@ -29,8 +29,14 @@ type Release struct {
 	// Config is the set of extra Values added to the chart.
 	// These values override the default values inside of the chart.
 	Config map[string]interface{} `json:"config,omitempty"`
+	// ValueSources are the sources of Config, in the order they were merged,
+	// each overriding the previous ones.
+	ValueSources []ValueSource `json:"value_sources,omitempty"`
 	// Manifest is the string representation of the rendered template.
 	Manifest string `json:"manifest,omitempty"`
+	// ManifestIndex locates the resources in Manifest, in order. It is empty
+	// for the releases stored before it was introduced.
+	ManifestIndex []ResourceIndex `json:"manifest_index,omitempty"`
 	// Hooks are all of the hooks declared for this release.
 	Hooks []*Hook `json:"hooks,omitempty"`
 	// Version is an int which represents the revision of the release.
@@ -40,6 +46,11 @@ type Release struct {
 	// Labels of the release.
 	// Disabled encoding into Json cause labels are stored in storage driver metadata field.
 	Labels map[string]string `json:"-"`
+	// Annotations are the user-defined metadata of the release, like the team
+	// owning it or the ticket of its deployment. Unlike the labels, they are
+	// stored with the release and cannot be used to select releases in the
+	// storage backends.
+	Annotations map[string]string `json:"annotations,omitempty"`
 }
 
 // SetStatus is a helper for setting the status on a release.
@@ -0,0 +1,62 @@
+/*
+Copyright The Helm Authors.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import "fmt"
+
+// ValueSourceType is the type of a source of the values of a release, named
+// after the flag giving it.
+type ValueSourceType string
+
+const (
+	// ValueSourceFile is a values file given with -f/--values.
+	ValueSourceFile ValueSourceType = "values"
+	// ValueSourceSetJSON is a value given with --set-json.
+	ValueSourceSetJSON ValueSourceType = "set-json"
+	// ValueSourceSet is a value given with --set.
+	ValueSourceSet ValueSourceType = "set"
+	// ValueSourceSetString is a value given with --set-string.
+	ValueSourceSetString ValueSourceType = "set-string"
+	// ValueSourceSetFile is a value read from a file given with --set-file.
+	ValueSourceSetFile ValueSourceType = "set-file"
+	// ValueSourceSetLiteral is a value given with --set-literal.
+	ValueSourceSetLiteral ValueSourceType = "set-literal"
+	// ValueSourceRevision is the values of a previous revision of the
+	// release, reused by an upgrade.
+	ValueSourceRevision ValueSourceType = "revision"
+)
+
+// ValueSource is a source of the values of a release.
+type ValueSource struct {
+	Type ValueSourceType `json:"type"`
+	// Value is the path or the URL of a values file, or the argument of a
+	// --set flag.
+	Value string `json:"value,omitempty"`
+	// Digest is the SHA256 digest of the content of the file, like
+	// "sha256:...", for the values files and the --set-file flags. The
+	// digests of the files read by a --set-file flag are separated by commas.
+	Digest string `json:"digest,omitempty"`
+	// Revision is the revision whose values were reused.
+	Revision int `json:"revision,omitempty"`
+}
+
+// String returns the source as a flag, like "--values=values.yaml".
+func (s ValueSource) String() string {
+	if s.Type == ValueSourceRevision {
+		return fmt.Sprintf("values of revision %d", s.Revision)
+	}
+	return fmt.Sprintf("--%s=%s", s.Type, s.Value)
+}
@@ -0,0 +1,72 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package releaseutil
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/release"
+)
+
+var manifestSource = regexp.MustCompile(`(?m)^# Source: (.+)$`)
+
+// IndexManifest returns the index of the resources of a release manifest, in
+// order. The documents are split as SplitManifests does, and the documents
+// which are not resources are skipped.
+func IndexManifest(manifest string) []release.ResourceIndex {
+	var index []release.ResourceIndex
+	start := 0
+	bounds := append(sep.FindAllStringIndex(manifest, -1), []int{len(manifest), len(manifest)})
+	for _, b := range bounds {
+		doc := manifest[start:b[0]]
+		offset := start + len(doc) - len(strings.TrimLeftFunc(doc, unicode.IsSpace))
+		start = b[1]
+		doc = strings.TrimSpace(doc)
+		if doc == "" {
+			continue
+		}
+
+		var head struct {
+			APIVersion string `json:"apiVersion"`
+			Kind       string `json:"kind"`
+			Metadata   struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+		}
+		if err := yaml.Unmarshal([]byte(doc), &head); err != nil || head.Kind == "" {
+			continue
+		}
+		r := release.ResourceIndex{
+			APIVersion: head.APIVersion,
+			Kind:       head.Kind,
+			Name:       head.Metadata.Name,
+			Namespace:  head.Metadata.Namespace,
+			Offset:     offset,
+			Length:     len(doc),
+		}
+		if match := manifestSource.FindStringSubmatch(doc); match != nil {
+			r.Source = strings.TrimSpace(match[1])
+		}
+		index = append(index, r)
+	}
+	return index
+}
@@ -0,0 +1,87 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package releaseutil
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/release"
+)
+
+const indexedManifest = `
+---
+# Source: mychart/templates/empty.yaml
+---
+# Source: mychart/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: prod
+---
+# Source: mychart/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+`
+
+func TestIndexManifest(t *testing.T) {
+	index := IndexManifest(indexedManifest)
+
+	expected := []release.ResourceIndex{{
+		APIVersion: "apps/v1",
+		Kind:       "Deployment",
+		Name:       "web",
+		Namespace:  "prod",
+		Source:     "mychart/templates/deployment.yaml",
+	}, {
+		APIVersion: "v1",
+		Kind:       "Service",
+		Name:       "web",
+		Source:     "mychart/templates/service.yaml",
+	}}
+	if len(index) != len(expected) {
+		t.Fatalf("Expected %d resources, got %d: %v", len(expected), len(index), index)
+	}
+
+	// The indexed manifests are the documents split by SplitManifests
+	split := SplitManifests(indexedManifest)
+	rel := &release.Release{Manifest: indexedManifest}
+	for i, r := range index {
+		manifest, ok := rel.ResourceManifest(r)
+		if !ok {
+			t.Fatalf("Expected the index %v to fit in the manifest", r)
+		}
+		if want := split[fmt.Sprintf("manifest-%d", i+1)]; manifest != want {
+			t.Errorf("Expected the manifest %q, got %q", want, manifest)
+		}
+		r.Offset, r.Length = 0, 0
+		if !reflect.DeepEqual(r, expected[i]) {
+			t.Errorf("Expected %v, got %v", expected[i], r)
+		}
+	}
+
+	if _, ok := rel.ResourceManifest(release.ResourceIndex{Offset: len(indexedManifest), Length: 1}); ok {
+		t.Error("Expected an index out of the manifest not to fit in it")
+	}
+	if index := IndexManifest(""); index != nil {
+		t.Errorf("Expected no resources in an empty manifest, got %v", index)
+	}
+}
@@ -19,6 +19,7 @@ package releaseutil
 import (
 	"log"
 	"path"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -37,6 +38,35 @@ type Manifest struct {
 	Head    *SimpleHead
 }
 
+// manifestSourceRegex matches the comment naming the template of a rendered
+// manifest.
+var manifestSourceRegex = regexp.MustCompile("# Source: [^/]+/(.+)")
+
+// ReleaseManifests returns the manifests of the resources of a release, in
+// the order of its manifest and named after the templates rendering them,
+// followed by the manifests of its hooks.
+func ReleaseManifests(rel *release.Release) []Manifest {
+	split := SplitManifests(rel.Manifest)
+	keys := make([]string, 0, len(split))
+	for k := range split {
+		keys = append(keys, k)
+	}
+	sort.Sort(BySplitManifestsOrder(keys))
+
+	manifests := make([]Manifest, 0, len(keys)+len(rel.Hooks))
+	for _, k := range keys {
+		var source string
+		if submatch := manifestSourceRegex.FindStringSubmatch(split[k]); submatch != nil {
+			source = submatch[1]
+		}
+		manifests = append(manifests, Manifest{Name: source, Content: split[k]})
+	}
+	for _, h := range rel.Hooks {
+		manifests = append(manifests, Manifest{Name: h.Path, Content: h.Manifest})
+	}
+	return manifests
+}
+
 // manifestFile represents a file that contains a manifest.
 type manifestFile struct {
 	entries map[string]string
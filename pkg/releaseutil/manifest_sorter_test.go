@@ -225,3 +225,28 @@ metadata:
 		}
 	}
 }
+
+func TestReleaseManifests(t *testing.T) {
+	rel := &release.Release{
+		Manifest: `---
+# Source: mychart/templates/b.yaml
+kind: ConfigMap
+---
+# Source: mychart/templates/a.yaml
+kind: Secret
+---
+kind: Service
+`,
+		Hooks: []*release.Hook{{Path: "templates/hook.yaml", Manifest: "kind: Job"}},
+	}
+
+	expected := []Manifest{
+		{Name: "templates/b.yaml", Content: "# Source: mychart/templates/b.yaml\nkind: ConfigMap"},
+		{Name: "templates/a.yaml", Content: "# Source: mychart/templates/a.yaml\nkind: Secret"},
+		{Name: "", Content: "kind: Service"},
+		{Name: "templates/hook.yaml", Content: "kind: Job"},
+	}
+	if got := ReleaseManifests(rel); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected\n%v\ngot\n%v", expected, got)
+	}
+}
@@ -0,0 +1,101 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package renderhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/plugin"
+)
+
+// pluginRequest is the request read by a render hook plugin on its standard
+// input.
+type pluginRequest struct {
+	Context   Context     `json:"context"`
+	Manifests []*Manifest `json:"manifests"`
+}
+
+// pluginResponse is the response written by a render hook plugin on its
+// standard output: the manifests of the request, mutated, or the reason of a
+// veto.
+type pluginResponse struct {
+	Manifests []*Manifest `json:"manifests"`
+	Veto      string      `json:"veto,omitempty"`
+}
+
+type pluginHook struct {
+	plugin *plugin.Plugin
+	host   plugin.Host
+}
+
+// NewPlugin returns the render hook of the WebAssembly plugin, run in process
+// with the host API.
+//
+// The module of the plugin reads the context and the manifests on its
+// standard input, as a JSON object {"context": {...}, "manifests": [...]},
+// and writes the manifests in the same order on its standard output, as a
+// JSON object {"manifests": [...]}, or vetoes them with {"veto": "reason"}.
+func NewPlugin(p *plugin.Plugin, host plugin.Host) (Hook, error) {
+	if !p.Metadata.RenderHook {
+		return nil, errors.Errorf("plugin %q is not a render hook", p.Metadata.Name)
+	}
+	return &pluginHook{plugin: p, host: host}, nil
+}
+
+func (h *pluginHook) Name() string {
+	return h.plugin.Metadata.Name
+}
+
+func (h *pluginHook) Run(ctx Context, manifests []*Manifest) error {
+	request, err := json.Marshal(&pluginRequest{Context: ctx, Manifests: manifests})
+	if err != nil {
+		return err
+	}
+	var stdout, stderr bytes.Buffer
+	code, err := h.plugin.RunWasm(context.Background(), plugin.WasmConfig{
+		Stdin:  bytes.NewReader(request),
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Host:   h.host,
+	})
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return errors.Errorf("exit code %d: %s", code, strings.TrimSpace(stderr.String()))
+	}
+
+	var response pluginResponse
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return errors.Wrap(err, "invalid response")
+	}
+	if response.Veto != "" {
+		return errors.New(response.Veto)
+	}
+	if len(response.Manifests) != len(manifests) {
+		return errors.Errorf("invalid response with %d manifests instead of %d", len(response.Manifests), len(manifests))
+	}
+	for i, m := range response.Manifests {
+		manifests[i].Object = m.Object
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package renderhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/plugin"
+)
+
+// labelRuntime runs the render hook plugins as if their modules labeled the
+// manifests of the release, and vetoed the Pods.
+type labelRuntime struct{}
+
+func (labelRuntime) Run(_ context.Context, _ []byte, config plugin.WasmConfig) (int, error) {
+	var request pluginRequest
+	if err := json.NewDecoder(config.Stdin).Decode(&request); err != nil {
+		return 1, nil
+	}
+	var response pluginResponse
+	for _, m := range request.Manifests {
+		if m.Object["kind"] == "Pod" {
+			response.Veto = "bare pods are not allowed"
+		}
+		m.Object["release"] = request.Context.ReleaseName
+		response.Manifests = append(response.Manifests, &Manifest{Object: m.Object})
+	}
+	return 0, json.NewEncoder(config.Stdout).Encode(&response)
+}
+
+func TestPluginHook(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "plugin.wasm"), []byte("\x00asm\x01\x00\x00\x00"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := &plugin.Plugin{
+		Dir:      dir,
+		Metadata: &plugin.Metadata{Name: "labels", Runtime: plugin.RuntimeWasm, Module: "plugin.wasm"},
+	}
+	if _, err := NewPlugin(p, nil); err == nil {
+		t.Fatal("expected an error with a plugin which is not a render hook")
+	}
+	p.Metadata.RenderHook = true
+	h, err := NewPlugin(p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plugin.RegisterWasmRuntime(labelRuntime{})
+	defer plugin.RegisterWasmRuntime(nil)
+
+	manifests := []*Manifest{{Source: "mychart/templates/cm.yaml", Object: map[string]interface{}{"kind": "ConfigMap"}}}
+	if err := Run(Context{ReleaseName: "prod"}, []Hook{h}, manifests); err != nil {
+		t.Fatal(err)
+	}
+	if manifests[0].Object["release"] != "prod" {
+		t.Errorf("expected the manifest labeled by the plugin, got %v", manifests[0].Object)
+	}
+
+	manifests = append(manifests, &Manifest{Source: "mychart/templates/pod.yaml", Object: map[string]interface{}{"kind": "Pod"}})
+	err = Run(Context{ReleaseName: "prod"}, []Hook{h}, manifests)
+	var veto *VetoError
+	if !errors.As(err, &veto) || veto.Hook != "labels" || veto.Err.Error() != "bare pods are not allowed" {
+		t.Errorf("expected a veto of the plugin, got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package renderhook contains the render hooks, run in process on the
+// manifests rendered by the engine of an install, an upgrade or a template,
+// before the release hooks and the resources are applied. The render hooks
+// can mutate the manifests, like adding labels or sidecars, or veto the
+// rendering, like enforcing a policy.
+package renderhook
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// The operations rendering the manifests.
+const (
+	OperationInstall  = "install"
+	OperationUpgrade  = "upgrade"
+	OperationTemplate = "template"
+)
+
+// Context describes the rendering of the manifests of a release.
+type Context struct {
+	// Operation is the operation rendering the manifests, like
+	// OperationInstall.
+	Operation string `json:"operation"`
+	// ReleaseName is the name of the release.
+	ReleaseName string `json:"releaseName"`
+	// Namespace is the namespace of the release.
+	Namespace string `json:"namespace"`
+	// Revision is the revision of the release being rendered.
+	Revision int `json:"revision,omitempty"`
+	// Chart is the name of the chart of the release.
+	Chart string `json:"chart"`
+	// ChartVersion is the version of the chart of the release.
+	ChartVersion string `json:"chartVersion"`
+}
+
+// Manifest is a manifest rendered by the engine.
+type Manifest struct {
+	// Source is the path to the template of the manifest, like
+	// "mychart/templates/deployment.yaml".
+	Source string `json:"source"`
+	// Hook is whether the manifest is a release hook.
+	Hook bool `json:"hook,omitempty"`
+	// Object is the decoded manifest, mutated in place by the render hooks.
+	Object map[string]interface{} `json:"object"`
+}
+
+// Hook is a render hook.
+type Hook interface {
+	// Name identifies the render hook.
+	Name() string
+	// Run mutates the manifests in place, or vetoes the rendering with an
+	// error.
+	Run(ctx Context, manifests []*Manifest) error
+}
+
+type hookFunc struct {
+	name string
+	run  func(Context, []*Manifest) error
+}
+
+func (h *hookFunc) Name() string { return h.name }
+
+func (h *hookFunc) Run(ctx Context, manifests []*Manifest) error { return h.run(ctx, manifests) }
+
+// HookFunc returns the render hook of the name running run.
+func HookFunc(name string, run func(ctx Context, manifests []*Manifest) error) Hook {
+	return &hookFunc{name: name, run: run}
+}
+
+// VetoError is the error of a render hook vetoing a rendering.
+type VetoError struct {
+	// Hook is the name of the render hook.
+	Hook string
+	// Err is the reason of the veto.
+	Err error
+}
+
+func (e *VetoError) Error() string {
+	return fmt.Sprintf("render hook %q vetoed the manifests: %s", e.Hook, e.Err)
+}
+
+func (e *VetoError) Unwrap() error {
+	return e.Err
+}
+
+// Run runs the render hooks on the manifests in order, stopping at the first
+// veto.
+func Run(ctx Context, hooks []Hook, manifests []*Manifest) error {
+	for _, h := range hooks {
+		if err := h.Run(ctx, manifests); err != nil {
+			return &VetoError{Hook: h.Name(), Err: err}
+		}
+		for _, m := range manifests {
+			if m.Object == nil {
+				return errors.Errorf("render hook %q removed the manifest of %s", h.Name(), m.Source)
+			}
+		}
+	}
+	return nil
+}
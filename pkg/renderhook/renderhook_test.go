@@ -0,0 +1,72 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package renderhook
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	var order []string
+	label := HookFunc("label", func(_ Context, manifests []*Manifest) error {
+		order = append(order, "label")
+		for _, m := range manifests {
+			m.Object["metadata"] = map[string]interface{}{"labels": map[string]interface{}{"team": "platform"}}
+		}
+		return nil
+	})
+	policy := HookFunc("policy", func(_ Context, manifests []*Manifest) error {
+		order = append(order, "policy")
+		for _, m := range manifests {
+			if m.Object["kind"] == "Pod" {
+				return errors.New("bare pods are not allowed")
+			}
+		}
+		return nil
+	})
+
+	manifests := []*Manifest{{Source: "mychart/templates/cm.yaml", Object: map[string]interface{}{"kind": "ConfigMap"}}}
+	if err := Run(Context{}, []Hook{label, policy}, manifests); err != nil {
+		t.Fatal(err)
+	}
+	if len(order) != 2 || order[0] != "label" || order[1] != "policy" {
+		t.Errorf("expected the hooks run in order, got %v", order)
+	}
+	if _, ok := manifests[0].Object["metadata"]; !ok {
+		t.Error("expected the manifest mutated")
+	}
+
+	order = nil
+	manifests = append(manifests, &Manifest{Source: "mychart/templates/pod.yaml", Object: map[string]interface{}{"kind": "Pod"}})
+	err := Run(Context{}, []Hook{policy, label}, manifests)
+	var veto *VetoError
+	if !errors.As(err, &veto) || veto.Hook != "policy" || veto.Err.Error() != "bare pods are not allowed" {
+		t.Fatalf("expected a veto of the policy hook, got %v", err)
+	}
+	if len(order) != 1 {
+		t.Errorf("expected the hooks stopped at the veto, got %v", order)
+	}
+
+	remove := HookFunc("remove", func(_ Context, manifests []*Manifest) error {
+		manifests[0].Object = nil
+		return nil
+	})
+	if err := Run(Context{}, []Hook{remove}, manifests); err == nil {
+		t.Error("expected an error removing a manifest")
+	}
+}
@@ -0,0 +1,215 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/helmpath"
+)
+
+// Cache is a repository cache directory, holding the indices of the
+// repositories and the chart archives downloaded from them.
+//
+// The files of the cache are evicted by their modification time, which is
+// updated every time an index is downloaded or found not modified by a
+// refresh, and every time a chart is downloaded. An index is evicted together
+// with the files derived from it: its validators, its list of charts and the
+// manifest of its shards.
+type Cache struct {
+	// Path is the path to the cache directory.
+	Path string
+	// MaxAge is the age the files of the cache are evicted at. Zero keeps the
+	// files regardless of their age.
+	MaxAge time.Duration
+	// MaxSize is the total size in bytes the cache is bounded to, the oldest
+	// files being evicted first. Zero does not bound the cache.
+	MaxSize int64
+}
+
+// CacheEntry is a file of a repository cache.
+type CacheEntry struct {
+	// Path is the path to the file, relative to the cache directory.
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// NewCache returns the repository cache at path, without bounds.
+func NewCache(path string) *Cache {
+	return &Cache{Path: path}
+}
+
+// Entries returns the files of the cache, the oldest first.
+func (c *Cache) Entries() ([]CacheEntry, error) {
+	var entries []CacheEntry
+	err := filepath.WalkDir(c.Path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(c.Path, path)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, CacheEntry{Path: rel, Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the repository cache %s", c.Path)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].ModTime.Before(entries[j].ModTime)
+	})
+	return entries, nil
+}
+
+// Size returns the total size in bytes of the files of the cache.
+func (c *Cache) Size() (int64, error) {
+	entries, err := c.Entries()
+	if err != nil {
+		return 0, err
+	}
+	var size int64
+	for _, e := range entries {
+		size += e.Size
+	}
+	return size, nil
+}
+
+// Prune evicts the files older than MaxAge, then the oldest files until the
+// cache fits in MaxSize. It returns the evicted files.
+func (c *Cache) Prune() ([]CacheEntry, error) {
+	entries, err := c.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	var size int64
+	for _, e := range entries {
+		size += e.Size
+	}
+	now := time.Now()
+
+	var evicted []CacheEntry
+	for _, g := range groupCacheEntries(entries) {
+		expired := c.MaxAge > 0 && now.Sub(g.modTime) > c.MaxAge
+		oversized := c.MaxSize > 0 && size > c.MaxSize
+		if !expired && !oversized {
+			// The groups are sorted by age, the next ones are kept too
+			break
+		}
+		for _, e := range g.entries {
+			if err := c.evict(e); err != nil {
+				return evicted, err
+			}
+			size -= e.Size
+			evicted = append(evicted, e)
+		}
+	}
+	return evicted, nil
+}
+
+// cacheGroup is a set of files of the cache evicted together.
+type cacheGroup struct {
+	entries []CacheEntry
+	// modTime is the modification time of the most recent file of the group.
+	modTime time.Time
+}
+
+// groupCacheEntries groups each index with the files derived from it, the
+// other files being alone in their group. The groups are sorted like the
+// entries, the oldest first.
+func groupCacheEntries(entries []CacheEntry) []*cacheGroup {
+	var groups []*cacheGroup
+	indices := map[string]*cacheGroup{}
+	for _, e := range entries {
+		name, ok := cacheIndexName(e.Path)
+		if !ok {
+			groups = append(groups, &cacheGroup{entries: []CacheEntry{e}, modTime: e.ModTime})
+			continue
+		}
+		g, ok := indices[name]
+		if !ok {
+			g = &cacheGroup{}
+			indices[name] = g
+			groups = append(groups, g)
+		}
+		g.entries = append(g.entries, e)
+		if e.ModTime.After(g.modTime) {
+			g.modTime = e.ModTime
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return groups[i].modTime.Before(groups[j].modTime)
+	})
+	return groups
+}
+
+// cacheIndexName returns the name of the repository of path if it is an
+// index of the cache or a file derived from one.
+func cacheIndexName(path string) (string, bool) {
+	dir, base := filepath.Split(path)
+	for _, file := range []func(string) string{
+		helmpath.CacheIndexFile,
+		helmpath.CacheIndexValidatorsFile,
+		helmpath.CacheIndexManifestFile,
+		helmpath.CacheChartsFile,
+	} {
+		if suffix := file(""); strings.HasSuffix(base, suffix) {
+			return dir + strings.TrimSuffix(base, suffix), true
+		}
+	}
+	return "", false
+}
+
+// Clear evicts every file of the cache. It returns the evicted files.
+func (c *Cache) Clear() ([]CacheEntry, error) {
+	entries, err := c.Entries()
+	if err != nil {
+		return nil, err
+	}
+	for i, e := range entries {
+		if err := c.evict(e); err != nil {
+			return entries[:i], err
+		}
+	}
+	return entries, nil
+}
+
+func (c *Cache) evict(e CacheEntry) error {
+	if err := os.Remove(filepath.Join(c.Path, e.Path)); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "failed to evict %s from the repository cache", e.Path)
+	}
+	return nil
+}
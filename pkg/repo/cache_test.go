@@ -0,0 +1,143 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeCacheFile(t *testing.T, dir, name string, size int, age time.Duration) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(strings.Repeat("x", size)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Now().Add(-age)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCachePrune(t *testing.T) {
+	dir := t.TempDir()
+	writeCacheFile(t, dir, "old-1.0.0.tgz", 100, 48*time.Hour)
+	writeCacheFile(t, dir, "stable-index.yaml", 50, 36*time.Hour)
+	writeCacheFile(t, dir, "shards/mariadb.yaml", 30, 12*time.Hour)
+	writeCacheFile(t, dir, "new-1.0.0.tgz", 20, time.Hour)
+
+	c := NewCache(dir)
+	size, err := c.Size()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 200 {
+		t.Errorf("expected a size of 200, got %d", size)
+	}
+
+	// Without bounds, nothing is evicted
+	evicted, err := c.Prune()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evicted) != 0 {
+		t.Errorf("expected no evicted files, got %v", evicted)
+	}
+
+	c.MaxAge = 24 * time.Hour
+	evicted, err = c.Prune()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evicted) != 2 || evicted[0].Path != "old-1.0.0.tgz" || evicted[1].Path != "stable-index.yaml" {
+		t.Errorf("expected the files older than a day to be evicted, got %v", evicted)
+	}
+
+	c.MaxAge = 0
+	c.MaxSize = 25
+	evicted, err = c.Prune()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evicted) != 1 || evicted[0].Path != filepath.Join("shards", "mariadb.yaml") {
+		t.Errorf("expected the oldest file to be evicted, got %v", evicted)
+	}
+
+	entries, err := c.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Path != "new-1.0.0.tgz" {
+		t.Errorf("unexpected cache entries %v", entries)
+	}
+
+	evicted, err = c.Clear()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size, _ := c.Size(); len(evicted) != 1 || size != 0 {
+		t.Errorf("expected the cache to be cleared, got %v", evicted)
+	}
+}
+
+func TestCachePruneIndexGroup(t *testing.T) {
+	dir := t.TempDir()
+	writeCacheFile(t, dir, "stable-index.yaml", 10, 48*time.Hour)
+	writeCacheFile(t, dir, "stable-index-validators.json", 10, 48*time.Hour)
+	writeCacheFile(t, dir, "stable-charts.txt", 10, 48*time.Hour)
+	writeCacheFile(t, dir, "stable-index-manifest.json", 10, 48*time.Hour)
+	// the index was found not modified recently, so its group is kept
+	writeCacheFile(t, dir, "incubator-index.yaml", 10, time.Hour)
+	writeCacheFile(t, dir, "incubator-charts.txt", 10, 48*time.Hour)
+
+	c := &Cache{Path: dir, MaxAge: 24 * time.Hour}
+	evicted, err := c.Prune()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evicted) != 4 {
+		t.Errorf("expected the stable index to be evicted with its files, got %v", evicted)
+	}
+	for _, e := range evicted {
+		if !strings.HasPrefix(e.Path, "stable-") {
+			t.Errorf("unexpected evicted file %s", e.Path)
+		}
+	}
+
+	// evicting the oldest file to fit in the size evicts its whole group
+	c = &Cache{Path: dir, MaxSize: 15}
+	evicted, err = c.Prune()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(evicted) != 2 {
+		t.Errorf("expected the incubator index to be evicted with its files, got %v", evicted)
+	}
+}
+
+func TestCacheMissingDirectory(t *testing.T) {
+	c := &Cache{Path: filepath.Join(t.TempDir(), "missing"), MaxSize: 1}
+	if _, err := c.Prune(); err != nil {
+		t.Errorf("expected a missing cache to be empty, got %v", err)
+	}
+}
@@ -27,6 +27,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"sigs.k8s.io/yaml"
@@ -48,6 +49,17 @@ type Entry struct {
 	CAFile                string `json:"caFile"`
 	InsecureSkipTLSverify bool   `json:"insecure_skip_tls_verify"`
 	PassCredentialsAll    bool   `json:"pass_credentials_all"`
+	// Proxy is the URL of the HTTP(S) proxy of the repository, overriding the
+	// HTTP_PROXY and HTTPS_PROXY environment variables.
+	Proxy string `json:"proxy,omitempty"`
+	// NoProxy is a comma-separated list of the hosts not reached through
+	// Proxy, like the NO_PROXY environment variable.
+	NoProxy string `json:"noProxy,omitempty"`
+	// Headers are static headers sent to the repository, like API tokens.
+	Headers map[string]string `json:"headers,omitempty"`
+	// Priority orders the repositories serving the same chart, the
+	// repositories with a higher priority being preferred.
+	Priority int `json:"priority,omitempty"`
 }
 
 // ChartRepository represents a chart repository
@@ -114,32 +126,51 @@ func (r *ChartRepository) Load() error {
 }
 
 // DownloadIndexFile fetches the index from a repository.
+//
+// The index is requested in JSON and compressed with zstd or gzip, falling
+// back to YAML and to no compression, in the format served by the repository.
+// It is stored decompressed in the cache directory.
+//
+// If the repository serves a sharded index, the cached index is updated by
+// only downloading the shards of the charts that changed since. Otherwise the
+// index is requested conditionally on being modified since the cached index.
 func (r *ChartRepository) DownloadIndexFile() (string, error) {
-	indexURL, err := ResolveReferenceURL(r.Config.URL, "index.yaml")
-	if err != nil {
-		return "", err
-	}
+	fname := filepath.Join(r.CachePath, helmpath.CacheIndexFile(r.Config.Name))
+	manifestFile := filepath.Join(r.CachePath, helmpath.CacheIndexManifestFile(r.Config.Name))
 
-	resp, err := r.Client.Get(indexURL,
-		getter.WithURL(r.Config.URL),
-		getter.WithInsecureSkipVerifyTLS(r.Config.InsecureSkipTLSverify),
-		getter.WithTLSClientConfig(r.Config.CertFile, r.Config.KeyFile, r.Config.CAFile),
-		getter.WithBasicAuth(r.Config.Username, r.Config.Password),
-		getter.WithPassCredentialsAll(r.Config.PassCredentialsAll),
-	)
+	index, manifest, err := r.downloadShardedIndex(fname, manifestFile)
 	if err != nil {
 		return "", err
 	}
-
-	index, err := io.ReadAll(resp)
-	if err != nil {
-		return "", err
+	validatorsFile := filepath.Join(r.CachePath, helmpath.CacheIndexValidatorsFile(r.Config.Name))
+	var validators *getter.CacheValidators
+	if index == nil {
+		validators = cachedIndexValidators(fname, validatorsFile)
+		index, err = r.downloadIndexData("index.yaml", validators)
+		if errors.Is(err, getter.ErrNotModified) {
+			// The cached index is still current, it is touched so it is
+			// not evicted from the cache as stale.
+			now := time.Now()
+			os.Chtimes(fname, now, now)
+			return fname, nil
+		}
+		if err != nil {
+			return "", err
+		}
 	}
 
 	indexFile, err := loadIndex(index, r.Config.URL)
 	if err != nil {
 		return "", err
 	}
+	if manifest == nil && indexFile.Annotations[IndexShardsAnnotation] != "" {
+		// The manifest is only usable to update the cached index if both
+		// were generated together.
+		data, m, err := r.downloadIndexManifest()
+		if err == nil && m.Generated.Equal(indexFile.Generated) {
+			manifest = data
+		}
+	}
 
 	// Create the chart list file in the cache directory
 	var charts strings.Builder
@@ -151,9 +182,78 @@ func (r *ChartRepository) DownloadIndexFile() (string, error) {
 	os.WriteFile(chartsFile, []byte(charts.String()), 0644)
 
 	// Create the index file in the cache directory
-	fname := filepath.Join(r.CachePath, helmpath.CacheIndexFile(r.Config.Name))
 	os.MkdirAll(filepath.Dir(fname), 0755)
-	return fname, os.WriteFile(fname, index, 0644)
+	if err := os.WriteFile(fname, index, 0644); err != nil {
+		return fname, err
+	}
+
+	// The validators of the index are stored to only download it next time
+	// if it is modified.
+	if validators == nil || *validators == (getter.CacheValidators{}) {
+		os.Remove(validatorsFile)
+	} else if b, err := json.Marshal(validators); err == nil {
+		os.WriteFile(validatorsFile, b, 0644)
+	}
+
+	// The manifest of a sharded index is stored to update the index next
+	// time, and removed if the repository stopped serving one.
+	if manifest == nil {
+		os.Remove(manifestFile)
+		return fname, nil
+	}
+	return fname, os.WriteFile(manifestFile, manifest, 0644)
+}
+
+// cachedIndexValidators returns the validators of the cached index stored in
+// validatorsFile, or empty validators if the index is not cached.
+func cachedIndexValidators(indexFile, validatorsFile string) *getter.CacheValidators {
+	v := &getter.CacheValidators{}
+	if _, err := os.Stat(indexFile); err != nil {
+		return v
+	}
+	if b, err := os.ReadFile(validatorsFile); err == nil {
+		if err := json.Unmarshal(b, v); err != nil {
+			return &getter.CacheValidators{}
+		}
+	}
+	return v
+}
+
+// downloadIndexData downloads the file of the index of the repository at ref,
+// relative to the URL of the repository, and decompresses it. The request is
+// conditional if validators is not nil.
+func (r *ChartRepository) downloadIndexData(ref string, validators *getter.CacheValidators) ([]byte, error) {
+	indexURL, err := ResolveReferenceURL(r.Config.URL, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.Client.Get(indexURL, append(r.getterOptions(),
+		getter.WithAcceptHeaders(indexAccept, indexAcceptEncoding),
+		getter.WithCacheValidators(validators),
+	)...)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(resp)
+	if err != nil {
+		return nil, err
+	}
+	return decompressIndex(data)
+}
+
+// getterOptions returns the options of the requests of the repository.
+func (r *ChartRepository) getterOptions() []getter.Option {
+	return []getter.Option{
+		getter.WithURL(r.Config.URL),
+		getter.WithInsecureSkipVerifyTLS(r.Config.InsecureSkipTLSverify),
+		getter.WithTLSClientConfig(r.Config.CertFile, r.Config.KeyFile, r.Config.CAFile),
+		getter.WithBasicAuth(r.Config.Username, r.Config.Password),
+		getter.WithPassCredentialsAll(r.Config.PassCredentialsAll),
+		getter.WithProxy(r.Config.Proxy, r.Config.NoProxy),
+		getter.WithHeaders(r.Config.Headers),
+	}
 }
 
 // Index generates an index for the chart repository and writes an index.yaml file.
@@ -251,6 +351,8 @@ func FindChartInAuthAndTLSAndPassRepoURL(repoURL, username, password, chartName,
 	defer func() {
 		os.RemoveAll(filepath.Join(r.CachePath, helmpath.CacheChartsFile(r.Config.Name)))
 		os.RemoveAll(filepath.Join(r.CachePath, helmpath.CacheIndexFile(r.Config.Name)))
+		os.RemoveAll(filepath.Join(r.CachePath, helmpath.CacheIndexManifestFile(r.Config.Name)))
+		os.RemoveAll(filepath.Join(r.CachePath, helmpath.CacheIndexValidatorsFile(r.Config.Name)))
 	}()
 
 	// Read the index file for the repository to get chart information and return chart URL
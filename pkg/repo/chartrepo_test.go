@@ -33,6 +33,7 @@ import (
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/helmpath"
 )
 
 const (
@@ -260,6 +261,94 @@ func verifyIndex(t *testing.T, actual *IndexFile) {
 	}
 }
 
+func TestDownloadIndexFileNotModified(t *testing.T) {
+	docroot := t.TempDir()
+	index := filepath.Join(docroot, "index.yaml")
+	b, err := os.ReadFile(testfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(index, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var status int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w}
+		http.FileServer(http.Dir(docroot)).ServeHTTP(sw, r)
+		status = sw.status
+	}))
+	defer srv.Close()
+
+	r, err := NewChartRepository(&Entry{Name: testRepo, URL: srv.URL}, getter.All(&cli.EnvSettings{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.CachePath = t.TempDir()
+	idx, err := r.DownloadIndexFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(r.CachePath, helmpath.CacheIndexValidatorsFile(testRepo))); err != nil {
+		t.Fatalf("expected the validators of the index to be cached: %s", err)
+	}
+
+	// The unchanged index is not downloaded again, but it is touched so it
+	// is not evicted from the cache
+	stale := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(idx, stale, stale); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.DownloadIndexFile(); err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusNotModified {
+		t.Errorf("expected a conditional request, got status %d", status)
+	}
+	if info, err := os.Stat(idx); err != nil || time.Since(info.ModTime()) > time.Hour {
+		t.Errorf("expected the cached index to be touched, got %v", err)
+	}
+	i, err := LoadIndexFile(idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyLocalIndex(t, i)
+
+	// The index is downloaded without a cached index
+	if err := os.Remove(idx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.DownloadIndexFile(); err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusOK {
+		t.Errorf("expected the index to be downloaded, got status %d", status)
+	}
+
+	// The modified index is downloaded
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(index, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.DownloadIndexFile(); err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusOK {
+		t.Errorf("expected the modified index to be downloaded, got status %d", status)
+	}
+}
+
+// statusWriter records the status of the responses.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
 // startLocalServerForTests Start the local helm server
 func startLocalServerForTests(handler http.Handler) (*httptest.Server, error) {
 	if handler == nil {
@@ -19,6 +19,7 @@ package repo
 import (
 	"bytes"
 	"encoding/json"
+	"io"
 	"log"
 	"os"
 	"path"
@@ -34,7 +35,6 @@ import (
 	"helm.sh/helm/v3/internal/fileutil"
 	"helm.sh/helm/v3/internal/urlutil"
 	"helm.sh/helm/v3/pkg/chart"
-	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/provenance"
 )
 
@@ -226,11 +226,13 @@ func (i IndexFile) Get(name, version string) (*ChartVersion, error) {
 //
 // The mode on the file is set to 'mode'.
 func (i IndexFile) WriteFile(dest string, mode os.FileMode) error {
-	b, err := yaml.Marshal(i)
-	if err != nil {
-		return err
-	}
-	return fileutil.AtomicWriteFile(dest, bytes.NewReader(b), mode)
+	r, w := io.Pipe()
+	go func() {
+		w.CloseWithError(i.writeYAML(w))
+	}()
+	err := fileutil.AtomicWriteFile(dest, r, mode)
+	r.Close()
+	return err
 }
 
 // WriteJSONFile writes an index file in JSON format to the given destination
@@ -292,10 +294,32 @@ type ChartVersion struct {
 
 // IndexDirectory reads a (flat) directory and generates an index.
 //
-// It indexes only charts that have been packaged (*.tgz).
+// It indexes only charts that have been packaged (*.tgz). Only the metadata of
+// the charts is loaded, the archives are streamed.
 //
 // The index returned will be in an unsorted state
 func IndexDirectory(dir, baseURL string) (*IndexFile, error) {
+	return indexDirectory(dir, baseURL, nil)
+}
+
+// MergeDirectory generates an index of a directory like IndexDirectory, and
+// merges the existing index into it like Merge.
+//
+// The archives with the digest of a version of the existing index are not
+// loaded again, the version of the existing index is reused with the URL of
+// the archive in the directory.
+//
+// The index returned will be in an unsorted state
+func MergeDirectory(dir, baseURL string, existing *IndexFile) (*IndexFile, error) {
+	index, err := indexDirectory(dir, baseURL, existing)
+	if err != nil {
+		return index, err
+	}
+	index.Merge(existing)
+	return index, nil
+}
+
+func indexDirectory(dir, baseURL string, existing *IndexFile) (*IndexFile, error) {
 	archives, err := filepath.Glob(filepath.Join(dir, "*.tgz"))
 	if err != nil {
 		return nil, err
@@ -306,6 +330,17 @@ func IndexDirectory(dir, baseURL string) (*IndexFile, error) {
 	}
 	archives = append(archives, moreArchives...)
 
+	indexed := make(map[string]*ChartVersion)
+	if existing != nil {
+		for _, cvs := range existing.Entries {
+			for _, cv := range cvs {
+				if cv.Digest != "" {
+					indexed[cv.Digest] = cv
+				}
+			}
+		}
+	}
+
 	index := NewIndexFile()
 	for _, arch := range archives {
 		fname, err := filepath.Rel(dir, arch)
@@ -322,16 +357,26 @@ func IndexDirectory(dir, baseURL string) (*IndexFile, error) {
 			parentURL = path.Join(baseURL, parentDir)
 		}
 
-		c, err := loader.Load(arch)
+		if len(indexed) > 0 {
+			hash, err := provenance.DigestFile(arch)
+			if err != nil {
+				return index, err
+			}
+			if cv, ok := indexed[hash]; ok {
+				md := *cv.Metadata
+				if err := index.MustAdd(&md, fname, parentURL, hash); err != nil {
+					return index, errors.Wrapf(err, "failed adding to %s to index", fname)
+				}
+				continue
+			}
+		}
+
+		md, hash, err := loadArchiveMetadata(arch)
 		if err != nil {
 			// Assume this is not a chart.
 			continue
 		}
-		hash, err := provenance.DigestFile(arch)
-		if err != nil {
-			return index, err
-		}
-		if err := index.MustAdd(c.Metadata, fname, parentURL, hash); err != nil {
+		if err := index.MustAdd(md, fname, parentURL, hash); err != nil {
 			return index, errors.Wrapf(err, "failed adding to %s to index", fname)
 		}
 	}
@@ -349,6 +394,10 @@ func loadIndex(data []byte, source string) (*IndexFile, error) {
 		return i, ErrEmptyIndexYaml
 	}
 
+	data, err := decompressIndex(data)
+	if err != nil {
+		return i, err
+	}
 	if err := jsonOrYamlUnmarshal(data, i); err != nil {
 		return i, err
 	}
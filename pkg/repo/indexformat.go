@@ -0,0 +1,322 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/internal/fileutil"
+)
+
+// IndexFormat is the format of a repository index file, named by the
+// extension of the file: "yaml" or "json", optionally compressed with gzip
+// ("yaml.gz", "json.gz") or with zstd ("yaml.zst", "json.zst").
+//
+// Indices are loaded whatever their format, so a repository may serve any of
+// them. JSON is parsed much faster than YAML, and compression reduces the size
+// of the indices of large repositories tenfold.
+type IndexFormat string
+
+const (
+	IndexFormatYAML     IndexFormat = "yaml"
+	IndexFormatJSON     IndexFormat = "json"
+	IndexFormatYAMLGzip IndexFormat = "yaml.gz"
+	IndexFormatJSONGzip IndexFormat = "json.gz"
+	IndexFormatYAMLZstd IndexFormat = "yaml.zst"
+	IndexFormatJSONZstd IndexFormat = "json.zst"
+)
+
+// IndexFormats are the supported formats of the index files.
+var IndexFormats = []IndexFormat{
+	IndexFormatYAML,
+	IndexFormatJSON,
+	IndexFormatYAMLGzip,
+	IndexFormatJSONGzip,
+	IndexFormatYAMLZstd,
+	IndexFormatJSONZstd,
+}
+
+const (
+	// indexAccept is the Accept header of the requests of the indices,
+	// preferring JSON which is faster to parse.
+	indexAccept = "application/json, application/yaml;q=0.9, */*;q=0.8"
+	// indexAcceptEncoding is the Accept-Encoding header of the requests of the
+	// indices.
+	indexAcceptEncoding = "zstd, gzip;q=0.9"
+)
+
+var (
+	magicGzip = []byte{0x1f, 0x8b}
+	magicZstd = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// ParseIndexFormat returns the index format named s.
+func ParseIndexFormat(s string) (IndexFormat, error) {
+	for _, f := range IndexFormats {
+		if string(f) == s {
+			return f, nil
+		}
+	}
+	return "", errors.Errorf("unsupported index format %q", s)
+}
+
+// FileName returns the name of the index file of the format, like
+// index.json.gz.
+func (f IndexFormat) FileName() string {
+	return "index." + string(f)
+}
+
+// ContentType returns the media type of the index files of the format.
+func (f IndexFormat) ContentType() string {
+	if strings.HasPrefix(string(f), string(IndexFormatJSON)) {
+		return "application/json"
+	}
+	return "application/yaml"
+}
+
+// ContentEncoding returns the compression of the index files of the format,
+// "gzip" or "zstd", or an empty string if they are not compressed.
+func (f IndexFormat) ContentEncoding() string {
+	switch filepath.Ext(string(f)) {
+	case ".gz":
+		return "gzip"
+	case ".zst":
+		return "zstd"
+	}
+	return ""
+}
+
+// Encode returns the index encoded in the format.
+func (i IndexFile) Encode(format IndexFormat) ([]byte, error) {
+	var b []byte
+	var err error
+	if format.ContentType() == "application/json" {
+		b, err = json.MarshalIndent(i, "", "  ")
+	} else {
+		b, err = yaml.Marshal(i)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch format.ContentEncoding() {
+	case "gzip":
+		w, err = gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	case "zstd":
+		w, err = zstd.NewWriter(&buf, zstd.WithEncoderLevel(zstd.SpeedBestCompression))
+	default:
+		return b, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteFormatFile writes the index in the format to the given destination
+// path.
+//
+// The mode on the file is set to 'mode'.
+func (i IndexFile) WriteFormatFile(dest string, format IndexFormat, mode os.FileMode) error {
+	b, err := i.Encode(format)
+	if err != nil {
+		return err
+	}
+	return fileutil.AtomicWriteFile(dest, bytes.NewReader(b), mode)
+}
+
+// decompressIndex returns the decompressed data of an index compressed with
+// gzip or zstd, recognized by their magic numbers, or data unchanged.
+func decompressIndex(data []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, magicGzip):
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid gzip compressed index")
+		}
+		defer r.Close()
+		b, err := io.ReadAll(r)
+		return b, errors.Wrap(err, "invalid gzip compressed index")
+	case bytes.HasPrefix(data, magicZstd):
+		r, err := zstd.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid zstd compressed index")
+		}
+		defer r.Close()
+		b, err := io.ReadAll(r)
+		return b, errors.Wrap(err, "invalid zstd compressed index")
+	}
+	return data, nil
+}
+
+// ServeIndex replies to a request of the index of the repository in dir with
+// the index file in the best format accepted by the Accept and Accept-Encoding
+// headers of the request, among the index.yaml, index.json and the compressed
+// index files of dir. It is suited to the handlers of repository servers, to
+// serve compressed JSON indices to the clients supporting them and index.yaml
+// to the others. Conditional requests are answered with the ETag and the
+// modification time of the file.
+func ServeIndex(w http.ResponseWriter, r *http.Request, dir string) {
+	var available []IndexFormat
+	for _, f := range IndexFormats {
+		if fi, err := os.Stat(filepath.Join(dir, f.FileName())); err == nil && fi.Mode().IsRegular() {
+			available = append(available, f)
+		}
+	}
+	w.Header().Add("Vary", "Accept, Accept-Encoding")
+	if len(available) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+	format, ok := negotiateIndexFormat(available, r.Header.Get("Accept"), r.Header.Get("Accept-Encoding"))
+	if !ok {
+		http.Error(w, "no acceptable index format", http.StatusNotAcceptable)
+		return
+	}
+
+	file, err := os.Open(filepath.Join(dir, format.FileName()))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+	fi, err := file.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// The ETag identifies the file of the format, the responses of the
+	// formats being cached separately.
+	w.Header().Set("ETag", fmt.Sprintf(`"%s-%x-%x"`, format, fi.ModTime().UnixNano(), fi.Size()))
+	w.Header().Set("Content-Type", format.ContentType())
+	if encoding := format.ContentEncoding(); encoding != "" {
+		w.Header().Set("Content-Encoding", encoding)
+	}
+	http.ServeContent(w, r, format.FileName(), fi.ModTime(), file)
+}
+
+// negotiateIndexFormat returns the available format with the highest quality
+// for the accept and acceptEncoding headers. The formats are preferred in
+// their order on equal qualities, so clients that do not send the headers get
+// the uncompressed index.yaml.
+func negotiateIndexFormat(available []IndexFormat, accept, acceptEncoding string) (IndexFormat, bool) {
+	types := parseQualities(accept)
+	encodings := parseQualities(acceptEncoding)
+
+	var best IndexFormat
+	bestQ := 0.0
+	for _, f := range available {
+		q := mediaTypeQuality(types, accept == "", f.ContentType()) * encodingQuality(encodings, acceptEncoding == "", f.ContentEncoding())
+		if q > bestQ {
+			best, bestQ = f, q
+		}
+	}
+	return best, bestQ > 0
+}
+
+// parseQualities returns the quality values of the elements of an Accept or
+// Accept-Encoding header, by lowercased element.
+func parseQualities(header string) map[string]float64 {
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			k, v, ok := strings.Cut(param, "=")
+			if !ok || strings.TrimSpace(k) != "q" {
+				continue
+			}
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				q = f
+			}
+		}
+		qualities[name] = q
+	}
+	return qualities
+}
+
+// mediaTypeQuality returns the quality of the media type for the parsed
+// Accept header. YAML is also accepted under its unregistered names.
+func mediaTypeQuality(types map[string]float64, absent bool, mediaType string) float64 {
+	if absent {
+		return 1
+	}
+	names := []string{mediaType}
+	if mediaType == "application/yaml" {
+		names = append(names, "application/x-yaml", "text/yaml", "text/x-yaml")
+	}
+	for _, name := range names {
+		if q, ok := types[name]; ok {
+			return q
+		}
+	}
+	if q, ok := types[mediaType[:strings.Index(mediaType, "/")]+"/*"]; ok {
+		return q
+	}
+	return types["*/*"]
+}
+
+// encodingQuality returns the quality of the content encoding for the parsed
+// Accept-Encoding header. The identity encoding is acceptable unless it is
+// excluded, with the lowest quality when it is not listed so that the
+// compressed indices are preferred by the clients that accept them.
+func encodingQuality(encodings map[string]float64, absent bool, encoding string) float64 {
+	if encoding == "" {
+		if absent {
+			return 1
+		}
+		if q, ok := encodings["identity"]; ok {
+			return q
+		}
+		if q, ok := encodings["*"]; ok && q == 0 {
+			return 0
+		}
+		return 0.001
+	}
+	if absent {
+		return 0
+	}
+	if q, ok := encodings[encoding]; ok {
+		return q
+	}
+	return encodings["*"]
+}
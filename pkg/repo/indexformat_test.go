@@ -0,0 +1,144 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/getter"
+)
+
+func TestIndexFormats(t *testing.T) {
+	i, err := LoadIndexFile(testfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	for _, f := range IndexFormats {
+		file := filepath.Join(dir, f.FileName())
+		if err := i.WriteFormatFile(file, f, 0644); err != nil {
+			t.Fatalf("%s: %s", f, err)
+		}
+		b, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case f.ContentEncoding() == "gzip" && !bytes.HasPrefix(b, magicGzip),
+			f.ContentEncoding() == "zstd" && !bytes.HasPrefix(b, magicZstd),
+			f == IndexFormatJSON && !json.Valid(b),
+			f == IndexFormatYAML && json.Valid(b):
+			t.Errorf("%s: unexpected encoding of the index", f)
+		}
+
+		loaded, err := LoadIndexFile(file)
+		if err != nil {
+			t.Fatalf("%s: %s", f, err)
+		}
+		verifyLocalIndex(t, loaded)
+	}
+
+	if _, err := ParseIndexFormat("xml"); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
+
+func TestNegotiateIndexFormat(t *testing.T) {
+	all := IndexFormats
+	tests := []struct {
+		name                   string
+		available              []IndexFormat
+		accept, acceptEncoding string
+		want                   IndexFormat
+	}{
+		{"no headers", all, "", "", IndexFormatYAML},
+		{"helm", all, indexAccept, indexAcceptEncoding, IndexFormatJSONZstd},
+		{"gzip", all, indexAccept, "gzip", IndexFormatJSONGzip},
+		{"yaml", all, "application/x-yaml", "gzip, zstd", IndexFormatYAMLGzip},
+		{"uncompressed json", all, "application/json", "", IndexFormatJSON},
+		{"identity preferred", all, "*/*", "gzip;q=0.5, identity", IndexFormatYAML},
+		{"only yaml", []IndexFormat{IndexFormatYAML}, indexAccept, indexAcceptEncoding, IndexFormatYAML},
+		{"json.gz only", []IndexFormat{IndexFormatYAML, IndexFormatJSONGzip}, indexAccept, indexAcceptEncoding, IndexFormatJSONGzip},
+	}
+	for _, tt := range tests {
+		got, ok := negotiateIndexFormat(tt.available, tt.accept, tt.acceptEncoding)
+		if !ok || got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+
+	if f, ok := negotiateIndexFormat([]IndexFormat{IndexFormatJSONZstd}, "application/json", "gzip"); ok {
+		t.Errorf("expected no acceptable format, got %s", f)
+	}
+	if f, ok := negotiateIndexFormat([]IndexFormat{IndexFormatYAML}, "", "identity;q=0"); ok {
+		t.Errorf("expected no acceptable format, got %s", f)
+	}
+}
+
+func TestDownloadCompressedIndexFile(t *testing.T) {
+	i, err := LoadIndexFile(testfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	for _, f := range []IndexFormat{IndexFormatYAML, IndexFormatJSONZstd} {
+		if err := i.WriteFormatFile(filepath.Join(dir, f.FileName()), f, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var encoding string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServeIndex(w, r, dir)
+		encoding = w.Header().Get("Content-Encoding")
+	}))
+	defer srv.Close()
+
+	r, err := NewChartRepository(&Entry{Name: testRepo, URL: srv.URL}, getter.All(&cli.EnvSettings{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.CachePath = t.TempDir()
+	idx, err := r.DownloadIndexFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encoding != "zstd" {
+		t.Errorf("expected the zstd compressed index to be served, got %q", encoding)
+	}
+
+	// The index is cached decompressed
+	b, err := os.ReadFile(idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !json.Valid(b) {
+		t.Error("expected the cached index to be JSON")
+	}
+	loaded, err := LoadIndexFile(idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifyLocalIndex(t, loaded)
+}
@@ -0,0 +1,237 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/internal/fileutil"
+)
+
+const (
+	// IndexShardsDir is the directory of the sharded index of a repository,
+	// relative to the URL of the repository.
+	IndexShardsDir = "index.d"
+	// IndexShardsAnnotation is the annotation of the index files of the
+	// repositories serving a sharded index.
+	IndexShardsAnnotation = "helm.sh/index-shards"
+	// indexManifestFile is the name of the manifest in IndexShardsDir.
+	indexManifestFile = "manifest.json"
+)
+
+// IndexManifest is the manifest of a sharded repository index. The versions
+// of each chart of the index are stored in a shard file, and the manifest
+// lists the shards with their digests, so clients holding a cached index only
+// download the shards of the charts that changed since.
+//
+// A sharded index is served alongside the index.yaml of the repository in
+// the index.d directory, as written by IndexFile.WriteShards, and the index
+// files have the IndexShardsAnnotation annotation. Clients download the whole
+// index the first time, and the manifest generated with it.
+type IndexManifest struct {
+	APIVersion string    `json:"apiVersion"`
+	Generated  time.Time `json:"generated"`
+	// Shards are the shards of the index, by chart name.
+	Shards     map[string]IndexShard `json:"shards"`
+	PublicKeys []string              `json:"publicKeys,omitempty"`
+	// Annotations are the annotations of the index.
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// IndexShard is a shard of a sharded index, holding the versions of a chart as
+// a JSON list.
+type IndexShard struct {
+	// Path is the name of the shard file in the index.d directory.
+	Path string `json:"path"`
+	// Digest is the SHA256 digest of the shard file, like sha256:<hex>.
+	Digest string `json:"digest"`
+}
+
+// WriteShards writes the index as a sharded index to the given directory,
+// usually the index.d directory of the repository: a shard file for each chart
+// and the manifest.json listing them. The shard files of charts that are no
+// longer in the index are removed.
+//
+// The IndexShardsAnnotation annotation is to be set on the index files served
+// with the shards, for clients to use them.
+//
+// The mode on the files is set to 'mode'.
+func (i IndexFile) WriteShards(dir string, mode os.FileMode) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	manifest := IndexManifest{
+		APIVersion:  i.APIVersion,
+		Generated:   i.Generated,
+		Shards:      make(map[string]IndexShard, len(i.Entries)),
+		PublicKeys:  i.PublicKeys,
+		Annotations: i.Annotations,
+	}
+	for name, versions := range i.Entries {
+		if !validShardPath(name + ".json") {
+			return errors.Errorf("invalid chart name %q for an index shard", name)
+		}
+		b, err := json.Marshal(versions)
+		if err != nil {
+			return err
+		}
+		shard := IndexShard{Path: name + ".json", Digest: shardDigest(b)}
+		if err := fileutil.AtomicWriteFile(filepath.Join(dir, shard.Path), bytes.NewReader(b), mode); err != nil {
+			return err
+		}
+		manifest.Shards[name] = shard
+	}
+
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := fileutil.AtomicWriteFile(filepath.Join(dir, indexManifestFile), bytes.NewReader(b), mode); err != nil {
+		return err
+	}
+
+	// Remove the shards of the removed charts
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		name := strings.TrimSuffix(f.Name(), ".json")
+		if f.Type().IsRegular() && name != f.Name() && f.Name() != indexManifestFile {
+			if _, ok := manifest.Shards[name]; !ok {
+				if err := os.Remove(filepath.Join(dir, f.Name())); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// downloadShardedIndex updates the index cached in indexFile from the sharded
+// index of the repository, if the manifest cached in manifestFile shows the
+// repository serves one. Only the shards whose digest changed since the cached
+// manifest are downloaded.
+//
+// It returns the data of the updated index and of its manifest, or nil if the
+// whole index is to be downloaded.
+func (r *ChartRepository) downloadShardedIndex(indexFile, manifestFile string) ([]byte, []byte, error) {
+	b, err := os.ReadFile(manifestFile)
+	if err != nil {
+		return nil, nil, nil
+	}
+	previous, err := loadIndexManifest(b)
+	if err != nil {
+		return nil, nil, nil
+	}
+	cached, err := LoadIndexFile(indexFile)
+	if err != nil {
+		return nil, nil, nil
+	}
+	manifestData, manifest, err := r.downloadIndexManifest()
+	if err != nil {
+		return nil, nil, nil
+	}
+
+	i := &IndexFile{
+		APIVersion:  manifest.APIVersion,
+		Generated:   manifest.Generated,
+		Entries:     make(map[string]ChartVersions, len(manifest.Shards)),
+		PublicKeys:  manifest.PublicKeys,
+		Annotations: manifest.Annotations,
+	}
+	for name, shard := range manifest.Shards {
+		if versions, ok := cached.Entries[name]; ok && previous.Shards[name].Digest == shard.Digest {
+			i.Entries[name] = versions
+			continue
+		}
+
+		data, err := r.downloadIndexData(path.Join(IndexShardsDir, shard.Path), nil)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to download the index shard of chart %q", name)
+		}
+		if digest := shardDigest(data); digest != shard.Digest {
+			return nil, nil, errors.Errorf("the index shard of chart %q has digest %s, expected %s", name, digest, shard.Digest)
+		}
+		var versions ChartVersions
+		if err := json.Unmarshal(data, &versions); err != nil {
+			return nil, nil, errors.Wrapf(err, "invalid index shard of chart %q", name)
+		}
+		i.Entries[name] = versions
+	}
+
+	index, err := json.Marshal(i)
+	if err != nil {
+		return nil, nil, err
+	}
+	return index, manifestData, nil
+}
+
+// downloadIndexManifest downloads the manifest of the sharded index of the
+// repository.
+func (r *ChartRepository) downloadIndexManifest() ([]byte, *IndexManifest, error) {
+	data, err := r.downloadIndexData(path.Join(IndexShardsDir, indexManifestFile), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	m, err := loadIndexManifest(data)
+	return data, m, err
+}
+
+// loadIndexManifest loads a manifest of a sharded index, and checks the paths
+// of its shards.
+func loadIndexManifest(data []byte) (*IndexManifest, error) {
+	data, err := decompressIndex(data)
+	if err != nil {
+		return nil, err
+	}
+	m := &IndexManifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, errors.Wrap(err, "invalid index manifest")
+	}
+	if m.APIVersion == "" {
+		return nil, ErrNoAPIVersion
+	}
+	for name, shard := range m.Shards {
+		if !validShardPath(shard.Path) {
+			return nil, errors.Errorf("invalid path %q of the index shard of chart %q", shard.Path, name)
+		}
+	}
+	return m, nil
+}
+
+// validShardPath returns true if p is the name of a file of the index.d
+// directory.
+func validShardPath(p string) bool {
+	return p != "" && path.Base(p) == p && p != "." && p != ".." && !strings.ContainsAny(p, `\:`)
+}
+
+func shardDigest(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
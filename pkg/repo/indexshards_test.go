@@ -0,0 +1,194 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/helmpath"
+)
+
+func TestWriteShards(t *testing.T) {
+	i, err := LoadIndexFile(testfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := i.WriteShards(dir, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, indexManifestFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := loadIndexManifest(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Shards) != 3 {
+		t.Fatalf("expected 3 shards, got %d", len(m.Shards))
+	}
+	for name, shard := range m.Shards {
+		b, err := os.ReadFile(filepath.Join(dir, shard.Path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if shardDigest(b) != shard.Digest {
+			t.Errorf("%s: unexpected digest %s", name, shard.Digest)
+		}
+	}
+
+	// The shards of removed charts are removed
+	delete(i.Entries, "nginx")
+	if err := i.WriteShards(dir, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "nginx.json")); !os.IsNotExist(err) {
+		t.Errorf("expected the shard of nginx to be removed, got %v", err)
+	}
+
+	for _, p := range []string{"../index.json", "a/b.json", ".."} {
+		if _, err := loadIndexManifest([]byte(`{"apiVersion": "v1", "shards": {"a": {"path": "` + p + `"}}}`)); err == nil {
+			t.Errorf("%s: expected an invalid path error", p)
+		}
+	}
+}
+
+func TestDownloadShardedIndexFile(t *testing.T) {
+	docroot := t.TempDir()
+	i, err := LoadIndexFile(testfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.Annotations = map[string]string{IndexShardsAnnotation: "true"}
+	writeIndex := func() {
+		if err := i.WriteFile(filepath.Join(docroot, "index.yaml"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := i.WriteShards(filepath.Join(docroot, IndexShardsDir), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeIndex()
+
+	var mu sync.Mutex
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.URL.Path)
+		mu.Unlock()
+		http.FileServer(http.Dir(docroot)).ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	fetched := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		r := requests
+		requests = nil
+		sort.Strings(r)
+		return r
+	}
+
+	r, err := NewChartRepository(&Entry{Name: testRepo, URL: srv.URL}, getter.All(&cli.EnvSettings{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.CachePath = t.TempDir()
+	manifestFile := filepath.Join(r.CachePath, helmpath.CacheIndexManifestFile(testRepo))
+
+	// Without a cached index, the whole index is downloaded
+	idx, err := r.DownloadIndexFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(fetched(), ","); got != "/index.d/manifest.json,/index.yaml" {
+		t.Errorf("unexpected requests %s", got)
+	}
+	if _, err := os.Stat(manifestFile); err != nil {
+		t.Fatalf("expected the manifest to be cached: %s", err)
+	}
+
+	// Only the changed shards are downloaded
+	i.Entries["alpine"][0].Description = "updated"
+	delete(i.Entries, "chartWithNoURL")
+	writeIndex()
+	if _, err := r.DownloadIndexFile(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(fetched(), ","); got != "/index.d/alpine.json,/index.d/manifest.json" {
+		t.Errorf("unexpected requests %s", got)
+	}
+	cached, err := LoadIndexFile(idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cached.Entries) != 2 || cached.Entries["alpine"][0].Description != "updated" || len(cached.Entries["nginx"]) != 2 {
+		t.Errorf("unexpected updated index %#v", cached.Entries)
+	}
+
+	// Shards are verified
+	manifest := filepath.Join(docroot, IndexShardsDir, indexManifestFile)
+	b, err := os.ReadFile(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := loadIndexManifest(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Shards["nginx"] = IndexShard{Path: "nginx.json", Digest: "sha256:0000"}
+	if b, err = json.Marshal(m); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(manifest, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.DownloadIndexFile(); err == nil || !strings.Contains(err.Error(), "digest") {
+		t.Errorf("expected a digest mismatch, got %v", err)
+	}
+
+	// The whole index is downloaded from repositories that stopped serving a
+	// sharded index
+	delete(i.Annotations, IndexShardsAnnotation)
+	if err := i.WriteFile(filepath.Join(docroot, "index.yaml"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(filepath.Join(docroot, IndexShardsDir)); err != nil {
+		t.Fatal(err)
+	}
+	fetched()
+	if _, err := r.DownloadIndexFile(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(fetched(), ","); got != "/index.d/manifest.json,/index.yaml" {
+		t.Errorf("unexpected requests %s", got)
+	}
+	if _, err := os.Stat(manifestFile); !os.IsNotExist(err) {
+		t.Errorf("expected the cached manifest to be removed, got %v", err)
+	}
+}
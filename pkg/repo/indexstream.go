@@ -0,0 +1,105 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+)
+
+// entriesPlaceholder is the line of the entries in the YAML encoding of an
+// index without entries.
+var entriesPlaceholder = []byte("entries: null\n")
+
+// writeYAML writes the index in YAML to w, encoding the versions of one chart
+// at a time, so the encoding of the whole index is never held in memory.
+func (i IndexFile) writeYAML(w io.Writer) error {
+	entries := i.Entries
+	i.Entries = nil
+	header, err := yaml.Marshal(i)
+	if err != nil {
+		return err
+	}
+	idx := bytes.Index(header, entriesPlaceholder)
+	if idx < 0 || (idx > 0 && header[idx-1] != '\n') {
+		return errors.New("cannot find the entries of the index")
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.Write(header[:idx])
+	if len(entries) == 0 {
+		bw.WriteString("entries: {}\n")
+	} else {
+		bw.WriteString("entries:\n")
+	}
+
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b, err := yaml.Marshal(map[string]ChartVersions{name: entries[name]})
+		if err != nil {
+			return err
+		}
+		// Indent the chart under the entries
+		for _, line := range bytes.SplitAfter(b, []byte("\n")) {
+			if len(line) > 1 {
+				bw.WriteString("  ")
+			}
+			bw.Write(line)
+		}
+	}
+
+	bw.Write(header[idx+len(entriesPlaceholder):])
+	return bw.Flush()
+}
+
+// loadArchiveMetadata loads the metadata of the chart archive at path, and
+// computes its digest, reading the archive once without loading the files of
+// the chart in memory.
+func loadArchiveMetadata(path string) (*chart.Metadata, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	r := io.TeeReader(f, hash)
+	md, err := loader.LoadArchiveMetadata(r)
+	if err != nil {
+		return nil, "", err
+	}
+	// The end of the archive may not be read by the decompression
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return nil, "", err
+	}
+	return md, hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,110 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/provenance"
+)
+
+func TestWriteFileStreams(t *testing.T) {
+	for _, name := range []string{"local-index.yaml", "local-index-annotations.yaml", "chartmuseum-index.yaml"} {
+		i, err := LoadIndexFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		dest := filepath.Join(t.TempDir(), "index.yaml")
+		if err := i.WriteFile(dest, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		// The index is written like it is encoded at once
+		got, err := os.ReadFile(dest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect, err := yaml.Marshal(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, expect) {
+			t.Errorf("expected %s to be written as\n%s\ngot\n%s", name, expect, got)
+		}
+	}
+
+	// An index without entries
+	dest := filepath.Join(t.TempDir(), "index.yaml")
+	if err := NewIndexFile().WriteFile(dest, 0644); err != nil {
+		t.Fatal(err)
+	}
+	i, err := LoadIndexFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(i.Entries) != 0 {
+		t.Errorf("expected no entries, got %d", len(i.Entries))
+	}
+}
+
+func TestMergeDirectory(t *testing.T) {
+	dir := "testdata/repository"
+	existing, err := IndexDirectory(dir, "http://localhost:8080")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The versions of the existing index with the digest of an archive are
+	// reused instead of loading the archive
+	existing.Entries["frobnitz"][0].Description = "indexed before"
+	if err := existing.MustAdd(&chart.Metadata{APIVersion: "v2", Name: "other", Version: "0.1.0"}, "other-0.1.0.tgz", "http://localhost:8080", "aaaa"); err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := MergeDirectory(dir, "http://example.com/charts", existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(index.Entries); l != 4 {
+		t.Fatalf("Expected 4 entries, got %d", l)
+	}
+	frob, err := index.Get("frobnitz", "1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frob.Description != "indexed before" {
+		t.Errorf("expected the metadata of the existing index to be reused, got %q", frob.Description)
+	}
+	if frob.URLs[0] != "http://example.com/charts/frobnitz-1.2.3.tgz" {
+		t.Errorf("Unexpected URLs: %v", frob.URLs)
+	}
+	digest, err := provenance.DigestFile(filepath.Join(dir, "frobnitz-1.2.3.tgz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frob.Digest != digest {
+		t.Errorf("expected the digest %s, got %s", digest, frob.Digest)
+	}
+	if !index.Has("other", "0.1.0") {
+		t.Error("expected the charts of the existing index to be merged")
+	}
+}
@@ -0,0 +1,205 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/internal/fileutil"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/provenance"
+)
+
+// MirrorFilter selects the charts of a repository mirrored by Mirror.
+type MirrorFilter struct {
+	// Charts are the patterns of the names of the mirrored charts, like
+	// path.Match patterns. All the charts are mirrored if it is empty.
+	Charts []string
+	// Version is the version constraint of the mirrored chart versions. All
+	// the versions are mirrored if it is empty.
+	Version string
+}
+
+// Mirror copies the versions of the charts of the repository selected by
+// filter into dir, with their provenance files, and writes the index of the
+// mirrored charts in dir, so dir can be served as a repository or pushed to
+// an OCI registry in disconnected environments.
+//
+// The archives are verified against the digests of the index of the
+// repository, which are preserved in the index of the mirror. The charts
+// already mirrored in dir with the same digests are not downloaded again, and
+// the index of the mirror keeps them. It returns the mirrored chart versions.
+func (r *ChartRepository) Mirror(dir string, filter MirrorFilter, out io.Writer) ([]*ChartVersion, error) {
+	var constraint *semver.Constraints
+	if filter.Version != "" {
+		var err error
+		if constraint, err = semver.NewConstraint(filter.Version); err != nil {
+			return nil, errors.Wrapf(err, "invalid version constraint %q", filter.Version)
+		}
+	}
+	for _, p := range filter.Charts {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, errors.Wrapf(err, "invalid chart pattern %q", p)
+		}
+	}
+
+	fname, err := r.DownloadIndexFile()
+	if err != nil {
+		return nil, errors.Wrapf(err, "looks like %q is not a valid chart repository or cannot be reached", r.Config.URL)
+	}
+	index, err := LoadIndexFile(fname)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	mirror := NewIndexFile()
+	indexFile := filepath.Join(dir, "index.yaml")
+	if _, err := os.Stat(indexFile); err == nil {
+		if mirror, err = LoadIndexFile(indexFile); err != nil {
+			return nil, errors.Wrapf(err, "cannot load the index of the mirror %s", indexFile)
+		}
+	}
+
+	names := make([]string, 0, len(index.Entries))
+	for name := range index.Entries {
+		if matchesAny(name, filter.Charts) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var mirrored []*ChartVersion
+	for _, name := range names {
+		for _, cv := range index.Entries[name] {
+			if constraint != nil {
+				v, err := semver.NewVersion(cv.Version)
+				if err != nil || !constraint.Check(v) {
+					continue
+				}
+			}
+			if len(cv.URLs) == 0 {
+				return mirrored, errors.Errorf("chart %q version %s has no downloadable URLs", name, cv.Version)
+			}
+
+			mcv, err := r.mirrorChartVersion(cv, dir, mirror, out)
+			if err != nil {
+				return mirrored, err
+			}
+			mirror.replace(mcv)
+			mirrored = append(mirrored, mcv)
+		}
+	}
+
+	mirror.SortEntries()
+	mirror.Generated = index.Generated
+	if err := mirror.WriteFile(indexFile, 0644); err != nil {
+		return mirrored, err
+	}
+	return mirrored, nil
+}
+
+// mirrorChartVersion copies the archive and the provenance file of cv into
+// dir, unless the mirror already has it with the same digest. It returns the
+// version of the mirror, with the URL of the archive in dir.
+func (r *ChartRepository) mirrorChartVersion(cv *ChartVersion, dir string, mirror *IndexFile, out io.Writer) (*ChartVersion, error) {
+	u, err := ResolveReferenceURL(r.Config.URL, cv.URLs[0])
+	if err != nil {
+		return nil, err
+	}
+	filename := path.Base(cv.URLs[0])
+	if i := strings.IndexAny(filename, "?#"); i >= 0 {
+		filename = filename[:i]
+	}
+	dest := filepath.Join(dir, filename)
+
+	mcv := *cv
+	mcv.URLs = []string{filename}
+
+	if existing, err := mirror.Get(cv.Name, cv.Version); err == nil && cv.Digest != "" && existing.Digest == cv.Digest {
+		if _, err := os.Stat(dest); err == nil {
+			return &mcv, nil
+		}
+	}
+
+	// The options of the requests of the index persist in the getter
+	opts := append(r.getterOptions(), getter.WithAcceptHeaders("", ""), getter.WithCacheValidators(nil))
+
+	fmt.Fprintf(out, "Mirroring %s version %s\n", cv.Name, cv.Version)
+	data, err := r.Client.Get(u, opts...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to download %s", u)
+	}
+	sum, err := provenance.Digest(bytes.NewReader(data.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	if cv.Digest != "" && sum != cv.Digest {
+		return nil, errors.Errorf("the archive of chart %q version %s has digest %s, but the index of the repository requires %s", cv.Name, cv.Version, sum, cv.Digest)
+	}
+	mcv.Digest = sum
+	if err := fileutil.AtomicWriteFile(dest, data, 0644); err != nil {
+		return nil, err
+	}
+
+	// The provenance files are optional, the charts without one are
+	// mirrored without
+	if prov, err := r.Client.Get(u+".prov", opts...); err == nil {
+		if err := fileutil.AtomicWriteFile(dest+".prov", prov, 0644); err != nil {
+			return nil, err
+		}
+	}
+	return &mcv, nil
+}
+
+// replace adds cv to the index, replacing the entry of the same version.
+func (i *IndexFile) replace(cv *ChartVersion) {
+	versions := i.Entries[cv.Name]
+	for j, v := range versions {
+		if v.Version == cv.Version {
+			versions[j] = cv
+			return
+		}
+	}
+	i.Entries[cv.Name] = append(versions, cv)
+}
+
+// matchesAny returns whether name matches any of patterns, or patterns is
+// empty.
+func matchesAny(name string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/getter"
+)
+
+func TestMirror(t *testing.T) {
+	docroot := t.TempDir()
+	for _, name := range []string{"frobnitz-1.2.3.tgz", "sprocket-1.1.0.tgz", "sprocket-1.2.0.tgz"} {
+		b, err := os.ReadFile(filepath.Join("testdata/repository", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(docroot, name), b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(docroot, "sprocket-1.2.0.tgz.prov"), []byte("provenance"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		http.FileServer(http.Dir(docroot)).ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	index, err := IndexDirectory(docroot, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := index.WriteFile(filepath.Join(docroot, "index.yaml"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewChartRepository(&Entry{Name: testRepo, URL: srv.URL}, getter.All(&cli.EnvSettings{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.CachePath = t.TempDir()
+
+	dir := t.TempDir()
+	filter := MirrorFilter{Charts: []string{"spr*"}, Version: ">=1.2.0"}
+	mirrored, err := r.Mirror(dir, filter, new(bytes.Buffer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mirrored) != 1 || mirrored[0].Name != "sprocket" || mirrored[0].Version != "1.2.0" {
+		t.Fatalf("unexpected mirrored charts %v", mirrored)
+	}
+	for _, name := range []string{"sprocket-1.2.0.tgz", "sprocket-1.2.0.tgz.prov"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected %s to be mirrored: %s", name, err)
+		}
+	}
+
+	// The index of the mirror preserves the digests, with relative URLs
+	mirror, err := LoadIndexFile(filepath.Join(dir, "index.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cv, err := mirror.Get("sprocket", "1.2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	upstream, _ := index.Get("sprocket", "1.2.0")
+	if cv.Digest != upstream.Digest || cv.URLs[0] != "sprocket-1.2.0.tgz" {
+		t.Errorf("unexpected mirrored version %+v", cv)
+	}
+
+	// Mirroring again only downloads the charts not mirrored yet
+	requests = nil
+	filter.Charts = nil
+	if _, err := r.Mirror(dir, filter, new(bytes.Buffer)); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range requests {
+		if strings.HasPrefix(path, "/sprocket-1.2.0") {
+			t.Errorf("expected the mirrored chart not to be downloaded again, got %s", path)
+		}
+	}
+	if mirror, err = LoadIndexFile(filepath.Join(dir, "index.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mirror.Get("frobnitz", "1.2.3"); err != nil {
+		t.Errorf("expected frobnitz to be mirrored: %s", err)
+	}
+
+	// The archives are verified against the digests of the index
+	if err := os.WriteFile(filepath.Join(docroot, "sprocket-1.1.0.tgz"), []byte("tampered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	filter.Version = ""
+	if _, err := r.Mirror(t.TempDir(), filter, new(bytes.Buffer)); err == nil || !strings.Contains(err.Error(), "digest") {
+		t.Errorf("expected a digest mismatch, got %v", err)
+	}
+}
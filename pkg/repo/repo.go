@@ -30,6 +30,9 @@ type File struct {
 	APIVersion   string    `json:"apiVersion"`
 	Generated    time.Time `json:"generated"`
 	Repositories []*Entry  `json:"repositories"`
+	// ResolutionPolicy chooses among the repositories serving the charts of
+	// the dependencies of the AnyRepository repository, FirstMatch if empty.
+	ResolutionPolicy ResolutionPolicy `json:"resolutionPolicy,omitempty"`
 }
 
 // NewFile generates an empty repositories file.
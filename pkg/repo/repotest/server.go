@@ -21,6 +21,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path"
 	"path/filepath"
 	"testing"
 	"time"
@@ -352,10 +353,20 @@ func (s *Server) Start() {
 		if s.middleware != nil {
 			s.middleware.ServeHTTP(w, r)
 		}
-		http.FileServer(http.Dir(s.docroot)).ServeHTTP(w, r)
+		s.serveFile(w, r)
 	}))
 }
 
+// serveFile serves the files of the docroot, negotiating the format of the
+// index files with repo.ServeIndex.
+func (s *Server) serveFile(w http.ResponseWriter, r *http.Request) {
+	if path.Base(r.URL.Path) == "index.yaml" {
+		repo.ServeIndex(w, r, filepath.Join(s.docroot, filepath.FromSlash(path.Dir(path.Clean(r.URL.Path)))))
+		return
+	}
+	http.FileServer(http.Dir(s.docroot)).ServeHTTP(w, r)
+}
+
 func (s *Server) StartTLS() {
 	cd := "../../testdata"
 	ca, pub, priv := filepath.Join(cd, "rootca.crt"), filepath.Join(cd, "crt.pem"), filepath.Join(cd, "key.pem")
@@ -365,7 +376,7 @@ func (s *Server) StartTLS() {
 		if s.middleware != nil {
 			s.middleware.ServeHTTP(w, r)
 		}
-		http.FileServer(http.Dir(s.Root())).ServeHTTP(w, r)
+		s.serveFile(w, r)
 	}))
 	tlsConf, err := tlsutil.NewClientTLS(pub, priv, ca, insecure)
 	if err != nil {
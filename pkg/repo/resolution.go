@@ -0,0 +1,148 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/pkg/errors"
+
+	"helm.sh/helm/v3/pkg/helmpath"
+)
+
+// AnyRepository is the repository of a dependency served by any of the
+// configured repositories, chosen by ResolveChart.
+const AnyRepository = "@*"
+
+// ResolutionPolicy is the policy choosing among the repositories serving a
+// chart.
+type ResolutionPolicy string
+
+const (
+	// FirstMatch chooses the first repository serving a matching version of
+	// the chart, by priority.
+	FirstMatch ResolutionPolicy = "first-match"
+	// HighestVersion chooses the repository serving the highest matching
+	// version of the chart, the priority breaking ties.
+	HighestVersion ResolutionPolicy = "highest-version"
+	// ErrorOnAmbiguity fails if several repositories of the highest priority
+	// serve a matching version of the chart.
+	ErrorOnAmbiguity ResolutionPolicy = "error-on-ambiguity"
+)
+
+// ResolutionPolicies are the supported resolution policies.
+var ResolutionPolicies = []ResolutionPolicy{FirstMatch, HighestVersion, ErrorOnAmbiguity}
+
+// Validate checks the policy is supported. The empty policy is FirstMatch.
+func (p ResolutionPolicy) Validate() error {
+	if p == "" {
+		return nil
+	}
+	for _, s := range ResolutionPolicies {
+		if p == s {
+			return nil
+		}
+	}
+	return errors.Errorf("unsupported resolution policy %q", p)
+}
+
+// ChartSource is a version of a chart served by a repository.
+type ChartSource struct {
+	Repository *Entry
+	Version    *ChartVersion
+}
+
+// ResolveChart finds the version of the chart name matching the version
+// constraint in the indices of the repositories of the file cached in
+// cacheDir, choosing among the repositories serving it by policy, the policy
+// of the file if it is empty.
+//
+// The repositories are considered by descending priority, then in the order
+// of the file. The repositories without a cached index are skipped.
+func (r *File) ResolveChart(name, version, cacheDir string, policy ResolutionPolicy) (*ChartSource, error) {
+	if policy == "" {
+		policy = r.ResolutionPolicy
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	entries := make([]*Entry, 0, len(r.Repositories))
+	for _, e := range r.Repositories {
+		if e != nil {
+			entries = append(entries, e)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Priority > entries[j].Priority
+	})
+
+	var sources []*ChartSource
+	for _, e := range entries {
+		i, err := LoadIndexFile(filepath.Join(cacheDir, helmpath.CacheIndexFile(e.Name)))
+		if err != nil {
+			continue
+		}
+		cv, err := i.Get(name, version)
+		if err != nil {
+			continue
+		}
+		sources = append(sources, &ChartSource{Repository: e, Version: cv})
+	}
+	if len(sources) == 0 {
+		return nil, errors.Errorf("chart %q matching %q not found in the configured repositories. (try 'helm repo update')", name, version)
+	}
+
+	switch policy {
+	case HighestVersion:
+		highest := sources[0]
+		for _, s := range sources[1:] {
+			if higherVersion(s.Version.Version, highest.Version.Version) {
+				highest = s
+			}
+		}
+		return highest, nil
+	case ErrorOnAmbiguity:
+		var names []string
+		for _, s := range sources {
+			if s.Repository.Priority == sources[0].Repository.Priority {
+				names = append(names, s.Repository.Name)
+			}
+		}
+		if len(names) > 1 {
+			return nil, errors.Errorf("chart %q is served by several repositories of the same priority: %s. Set the priorities of the repositories to choose one", name, strings.Join(names, ", "))
+		}
+	}
+	return sources[0], nil
+}
+
+// higherVersion returns whether the version a is higher than the version b,
+// the invalid versions being the lowest.
+func higherVersion(a, b string) bool {
+	va, err := semver.NewVersion(a)
+	if err != nil {
+		return false
+	}
+	vb, err := semver.NewVersion(b)
+	if err != nil {
+		return true
+	}
+	return va.GreaterThan(vb)
+}
@@ -0,0 +1,106 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package repo
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/helmpath"
+)
+
+func TestResolveChart(t *testing.T) {
+	dir := t.TempDir()
+	versions := map[string][]string{
+		"mirror":   {"1.0.0"},
+		"upstream": {"1.0.0", "1.1.0"},
+		"other":    {"2.0.0"},
+	}
+	for name, vs := range versions {
+		i := NewIndexFile()
+		for _, v := range vs {
+			chartName := "nginx"
+			if name == "other" {
+				chartName = "redis"
+			}
+			md := &chart.Metadata{APIVersion: chart.APIVersionV2, Name: chartName, Version: v}
+			if err := i.MustAdd(md, chartName+"-"+v+".tgz", "https://"+name+".example.com", "sha256:1234"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		i.SortEntries()
+		if err := i.WriteFile(filepath.Join(dir, helmpath.CacheIndexFile(name)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f := NewFile()
+	f.Add(
+		&Entry{Name: "other", URL: "https://other.example.com"},
+		&Entry{Name: "mirror", URL: "https://mirror.example.com"},
+		&Entry{Name: "upstream", URL: "https://upstream.example.com"},
+		&Entry{Name: "uncached", URL: "https://uncached.example.com"},
+	)
+
+	for _, tt := range []struct {
+		policy      ResolutionPolicy
+		priority    int
+		version     string
+		repository  string
+		chosen      string
+		expectError string
+	}{
+		{policy: "", version: "^1", repository: "mirror", chosen: "1.0.0"},
+		{policy: FirstMatch, version: "^1", repository: "mirror", chosen: "1.0.0"},
+		{policy: FirstMatch, priority: 10, version: "^1", repository: "upstream", chosen: "1.1.0"},
+		{policy: HighestVersion, version: "^1", repository: "upstream", chosen: "1.1.0"},
+		{policy: FirstMatch, version: "~1.1", repository: "upstream", chosen: "1.1.0"},
+		{policy: ErrorOnAmbiguity, version: "^1", expectError: "mirror, upstream"},
+		{policy: ErrorOnAmbiguity, priority: 10, version: "^1", repository: "upstream", chosen: "1.1.0"},
+		{policy: FirstMatch, version: "^3", expectError: "not found"},
+		{policy: "newest", version: "^1", expectError: "unsupported resolution policy"},
+	} {
+		f.Get("upstream").Priority = tt.priority
+		src, err := f.ResolveChart("nginx", tt.version, dir, tt.policy)
+		if tt.expectError != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+				t.Errorf("%s %s: expected an error containing %q, got %v", tt.policy, tt.version, tt.expectError, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s %s: %s", tt.policy, tt.version, err)
+			continue
+		}
+		if src.Repository.Name != tt.repository || src.Version.Version != tt.chosen {
+			t.Errorf("%s %s: expected %s from %s, got %s from %s", tt.policy, tt.version, tt.chosen, tt.repository, src.Version.Version, src.Repository.Name)
+		}
+	}
+
+	// The policy of the file is the default
+	f.Get("upstream").Priority = 0
+	f.ResolutionPolicy = HighestVersion
+	src, err := f.ResolveChart("nginx", "^1", dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src.Repository.Name != "upstream" {
+		t.Errorf("expected the policy of the file to choose upstream, got %s", src.Repository.Name)
+	}
+}
@@ -0,0 +1,213 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage // import "helm.sh/helm/v3/pkg/storage"
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/pkg/errors"
+
+	rspb "helm.sh/helm/v3/pkg/release"
+	relutil "helm.sh/helm/v3/pkg/releaseutil"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+// ArchiveAPIVersion is the version of the format of release archives.
+const ArchiveAPIVersion = "helm.sh/release-archive/v1"
+
+// archiveIndexName is the name of the index of a release archive.
+const archiveIndexName = "index.json"
+
+// ArchiveIndex describes the content of a release archive.
+type ArchiveIndex struct {
+	APIVersion string `json:"apiVersion"`
+	// Name is the name of the release.
+	Name string `json:"name"`
+	// Namespace is the namespace the release was exported from.
+	Namespace string `json:"namespace"`
+	// Revisions are the versions of the archived revisions, in ascending
+	// order.
+	Revisions []int `json:"revisions"`
+}
+
+// WriteArchive writes a release archive, a gzipped tarball holding an index
+// and every revision of the release in JSON, so it does not depend on the
+// storage driver the release is read from or restored to.
+func WriteArchive(w io.Writer, rels []*rspb.Release) error {
+	if len(rels) == 0 {
+		return errors.New("no release revisions to archive")
+	}
+	rels = append([]*rspb.Release{}, rels...)
+	relutil.SortByRevision(rels)
+
+	index := ArchiveIndex{
+		APIVersion: ArchiveAPIVersion,
+		Name:       rels[0].Name,
+		Namespace:  rels[0].Namespace,
+	}
+	for _, rel := range rels {
+		if rel.Name != index.Name {
+			return errors.Errorf("cannot archive revisions of releases %q and %q together", index.Name, rel.Name)
+		}
+		index.Revisions = append(index.Revisions, rel.Version)
+	}
+
+	zw := gzip.NewWriter(w)
+	tw := tar.NewWriter(zw)
+	now := time.Now()
+
+	add := func(name string, v interface{}) error {
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := tw.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(b)),
+			ModTime:  now,
+			Typeflag: tar.TypeReg,
+		}); err != nil {
+			return err
+		}
+		_, err = tw.Write(b)
+		return err
+	}
+
+	if err := add(archiveIndexName, index); err != nil {
+		return err
+	}
+	for _, rel := range rels {
+		if err := add(archiveRevisionName(rel.Version), rel); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// ReadArchive reads the index and the revisions of a release archive
+// written by WriteArchive.
+func ReadArchive(r io.Reader) (*ArchiveIndex, []*rspb.Release, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "invalid release archive")
+	}
+	defer zr.Close()
+
+	var index *ArchiveIndex
+	revisions := map[string]*rspb.Release{}
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "invalid release archive")
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := path.Clean(hdr.Name)
+		switch {
+		case name == archiveIndexName:
+			index = &ArchiveIndex{}
+			if err := json.NewDecoder(tr).Decode(index); err != nil {
+				return nil, nil, errors.Wrapf(err, "invalid release archive index")
+			}
+		case path.Dir(name) == "revisions":
+			rel := &rspb.Release{}
+			if err := json.NewDecoder(tr).Decode(rel); err != nil {
+				return nil, nil, errors.Wrapf(err, "invalid release revision %s", name)
+			}
+			revisions[name] = rel
+		}
+	}
+
+	if index == nil {
+		return nil, nil, errors.New("invalid release archive: no index")
+	}
+	if index.APIVersion != ArchiveAPIVersion {
+		return nil, nil, errors.Errorf("unsupported release archive version %q", index.APIVersion)
+	}
+
+	rels := make([]*rspb.Release, 0, len(index.Revisions))
+	for _, version := range index.Revisions {
+		rel, ok := revisions[archiveRevisionName(version)]
+		if !ok {
+			return nil, nil, errors.Errorf("invalid release archive: revision %d is missing", version)
+		}
+		if rel.Name != index.Name || rel.Version != version {
+			return nil, nil, errors.Errorf("invalid release archive: revision %d does not match the index", version)
+		}
+		rels = append(rels, rel)
+	}
+	return index, rels, nil
+}
+
+func archiveRevisionName(version int) string {
+	return fmt.Sprintf("revisions/v%d.json", version)
+}
+
+// Export writes the full history of the named release as a release archive.
+func (s *Storage) Export(w io.Writer, name string) error {
+	s.Log("exporting release %s", name)
+	rels, err := s.History(name)
+	if err != nil {
+		return err
+	}
+	return WriteArchive(w, rels)
+}
+
+// Import restores the revisions of a release archive into the storage, in
+// namespace if it is not empty. It fails without storing anything if a
+// revision of the release already exists.
+func (s *Storage) Import(r io.Reader, namespace string) ([]*rspb.Release, error) {
+	index, rels, err := ReadArchive(r)
+	if err != nil {
+		return nil, err
+	}
+	s.Log("importing release %s", index.Name)
+
+	existing, err := s.History(index.Name)
+	if err != nil && !errors.Is(err, driver.ErrReleaseNotFound) {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		return nil, errors.Errorf("release %q already exists", index.Name)
+	}
+
+	for i, rel := range rels {
+		if namespace != "" {
+			rel.Namespace = namespace
+		}
+		if err := s.Create(rel); err != nil {
+			return rels[:i], errors.Wrapf(err, "failed to import release %s revision %d", rel.Name, rel.Version)
+		}
+	}
+	return rels, nil
+}
@@ -0,0 +1,104 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package storage // import "helm.sh/helm/v3/pkg/storage"
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	rspb "helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+func TestStorageExportImport(t *testing.T) {
+	src := Init(driver.NewMemory())
+	for _, rls := range []*rspb.Release{
+		ReleaseTestData{Name: "angry-bird", Version: 1, Namespace: "default", Status: rspb.StatusSuperseded, Manifest: "v1"}.ToRelease(),
+		ReleaseTestData{Name: "angry-bird", Version: 2, Namespace: "default", Status: rspb.StatusDeployed, Manifest: "v2"}.ToRelease(),
+		ReleaseTestData{Name: "smug-pigeon", Version: 1, Namespace: "default", Status: rspb.StatusDeployed}.ToRelease(),
+	} {
+		assertErrNil(t.Fatal, src.Create(rls), "Storing release")
+	}
+
+	var buf bytes.Buffer
+	assertErrNil(t.Fatal, src.Export(&buf, "angry-bird"), "Exporting release 'angry-bird'")
+	archive := buf.Bytes()
+
+	if err := src.Export(&bytes.Buffer{}, "missing"); err == nil {
+		t.Error("Expected exporting a missing release to fail")
+	}
+	if _, err := src.Import(bytes.NewReader(archive), ""); err == nil {
+		t.Error("Expected importing an existing release to fail")
+	}
+
+	mem := driver.NewMemory()
+	mem.SetNamespace("restored")
+	dst := Init(mem)
+	rels, err := dst.Import(bytes.NewReader(archive), "restored")
+	assertErrNil(t.Fatal, err, "Importing release 'angry-bird'")
+	if len(rels) != 2 {
+		t.Fatalf("Expected 2 revisions to be imported, got %d", len(rels))
+	}
+
+	h, err := dst.History("angry-bird")
+	assertErrNil(t.Fatal, err, "Querying the imported release")
+	if len(h) != 2 {
+		t.Fatalf("Expected 2 revisions to be stored, got %d", len(h))
+	}
+	last, err := dst.Last("angry-bird")
+	assertErrNil(t.Fatal, err, "Getting the last revision")
+	if last.Version != 2 || last.Manifest != "v2" || last.Namespace != "restored" {
+		t.Errorf("Expected revision 2 in namespace restored, got revision %d in namespace %q", last.Version, last.Namespace)
+	}
+	if _, err := dst.History("smug-pigeon"); err == nil {
+		t.Error("Expected smug-pigeon not to be imported")
+	}
+}
+
+func TestReadArchiveInvalid(t *testing.T) {
+	archive := func(files map[string]string) []byte {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(zw)
+		for name, content := range files {
+			tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+			tw.Write([]byte(content))
+		}
+		tw.Close()
+		zw.Close()
+		return buf.Bytes()
+	}
+
+	for name, data := range map[string][]byte{
+		"not gzipped":     []byte("release"),
+		"no index":        archive(map[string]string{"revisions/v1.json": `{"name":"angry-bird","version":1}`}),
+		"unknown version": archive(map[string]string{"index.json": `{"apiVersion":"v0","name":"angry-bird"}`}),
+		"missing revision": archive(map[string]string{
+			"index.json": `{"apiVersion":"helm.sh/release-archive/v1","name":"angry-bird","revisions":[1]}`,
+		}),
+		"mismatched revision": archive(map[string]string{
+			"index.json":        `{"apiVersion":"helm.sh/release-archive/v1","name":"angry-bird","revisions":[1]}`,
+			"revisions/v1.json": `{"name":"smug-pigeon","version":1}`,
+		}),
+	} {
+		if _, _, err := ReadArchive(bytes.NewReader(data)); err == nil {
+			t.Errorf("%s: expected the archive to be invalid", name)
+		}
+	}
+}
@@ -34,15 +34,25 @@ import (
 )
 
 var _ Driver = (*ConfigMaps)(nil)
+var _ Pager = (*ConfigMaps)(nil)
 
 // ConfigMapsDriverName is the string name of the driver.
 const ConfigMapsDriverName = "ConfigMap"
 
 // ConfigMaps is a wrapper around an implementation of a kubernetes
 // ConfigMapsInterface.
+//
+// Updates are conditional on the resourceVersion of the configmap when the
+// release was read, and fail with a ConflictError if it was modified since.
 type ConfigMaps struct {
-	impl corev1.ConfigMapInterface
-	Log  func(string, ...interface{})
+	impl     corev1.ConfigMapInterface
+	versions versions
+	Log      func(string, ...interface{})
+
+	// Compression configures the compression of the stored releases.
+	Compression Compression
+	// Encryptor encrypts the stored releases if it is set.
+	Encryptor Encryptor
 }
 
 // NewConfigMaps initializes a new ConfigMaps wrapping an implementation of
@@ -73,12 +83,13 @@ func (cfgmaps *ConfigMaps) Get(key string) (*rspb.Release, error) {
 		return nil, err
 	}
 	// found the configmap, decode the base64 data string
-	r, err := decodeRelease(obj.Data["release"])
+	r, err := cfgmaps.decode(obj)
 	if err != nil {
 		cfgmaps.Log("get: failed to decode data %q: %s", key, err)
 		return nil, err
 	}
-	r.Labels = filterSystemLabels(obj.ObjectMeta.Labels)
+	cfgmaps.versions.set(key, obj.ObjectMeta.ResourceVersion)
+	r.Labels = FilterSystemLabels(obj.ObjectMeta.Labels)
 	// return the release object
 	return r, nil
 }
@@ -100,12 +111,14 @@ func (cfgmaps *ConfigMaps) List(filter func(*rspb.Release) bool) ([]*rspb.Releas
 
 	// iterate over the configmaps object list
 	// and decode each release
-	for _, item := range list.Items {
-		rls, err := decodeRelease(item.Data["release"])
+	for i := range list.Items {
+		item := &list.Items[i]
+		rls, err := cfgmaps.decode(item)
 		if err != nil {
 			cfgmaps.Log("list: failed to decode release: %v: %s", item, err)
 			continue
 		}
+		cfgmaps.versions.set(item.ObjectMeta.Name, item.ObjectMeta.ResourceVersion)
 
 		rls.Labels = item.ObjectMeta.Labels
 
@@ -116,6 +129,40 @@ func (cfgmaps *ConfigMaps) List(filter func(*rspb.Release) bool) ([]*rspb.Releas
 	return results, nil
 }
 
+// ListPage fetches the releases selected by opts, with the label selector,
+// limit and continue token of the ConfigMap list request.
+func (cfgmaps *ConfigMaps) ListPage(opts ListOptions) ([]*rspb.Release, string, error) {
+	sel, err := listSelector(opts)
+	if err != nil {
+		cfgmaps.Log("list: invalid selector: %s", err)
+		return nil, "", err
+	}
+
+	list, err := cfgmaps.impl.List(context.Background(), metav1.ListOptions{
+		LabelSelector: sel.String(),
+		Limit:         opts.Limit,
+		Continue:      opts.Continue,
+	})
+	if err != nil {
+		cfgmaps.Log("list: failed to list: %s", err)
+		return nil, "", err
+	}
+
+	var results []*rspb.Release
+	for i := range list.Items {
+		item := &list.Items[i]
+		rls, err := cfgmaps.decode(item)
+		if err != nil {
+			cfgmaps.Log("list: failed to decode release: %s: %s", item.ObjectMeta.Name, err)
+			continue
+		}
+		cfgmaps.versions.set(item.ObjectMeta.Name, item.ObjectMeta.ResourceVersion)
+		rls.Labels = item.ObjectMeta.Labels
+		results = append(results, rls)
+	}
+	return results, list.Continue, nil
+}
+
 // Query fetches all releases that match the provided map of labels.
 // An error is returned if the configmap fails to retrieve the releases.
 func (cfgmaps *ConfigMaps) Query(labels map[string]string) ([]*rspb.Release, error) {
@@ -140,12 +187,18 @@ func (cfgmaps *ConfigMaps) Query(labels map[string]string) ([]*rspb.Release, err
 	}
 
 	var results []*rspb.Release
-	for _, item := range list.Items {
-		rls, err := decodeRelease(item.Data["release"])
+	for i := range list.Items {
+		item := &list.Items[i]
+		// Spillover configmaps are only ever read through their main configmap.
+		if item.ObjectMeta.Labels["owner"] == spilloverOwner {
+			continue
+		}
+		rls, err := cfgmaps.decode(item)
 		if err != nil {
 			cfgmaps.Log("query: failed to decode release: %s", err)
 			continue
 		}
+		cfgmaps.versions.set(item.ObjectMeta.Name, item.ObjectMeta.ResourceVersion)
 		rls.Labels = item.ObjectMeta.Labels
 		results = append(results, rls)
 	}
@@ -163,13 +216,23 @@ func (cfgmaps *ConfigMaps) Create(key string, rls *rspb.Release) error {
 	lbs.set("createdAt", strconv.Itoa(int(time.Now().Unix())))
 
 	// create a new configmap to hold the release
-	obj, err := newConfigMapsObject(key, rls, lbs)
+	obj, parts, err := newConfigMapsObject(key, rls, lbs, cfgmaps.Compression, cfgmaps.Encryptor)
 	if err != nil {
 		cfgmaps.Log("create: failed to encode release %q: %s", rls.Name, err)
 		return err
 	}
+	// The parts are written first so the main configmap never references
+	// parts that do not exist yet.
+	written, err := cfgmaps.writeParts(parts)
+	if err != nil {
+		cfgmaps.deleteParts(written)
+		cfgmaps.Log("create: failed to create spillover configmaps: %s", err)
+		return err
+	}
 	// push the configmap object out into the kubiverse
-	if _, err := cfgmaps.impl.Create(context.Background(), obj, metav1.CreateOptions{}); err != nil {
+	created, err := cfgmaps.impl.Create(context.Background(), obj, metav1.CreateOptions{})
+	if err != nil {
+		cfgmaps.deleteParts(written)
 		if apierrors.IsAlreadyExists(err) {
 			return ErrReleaseExists
 		}
@@ -177,6 +240,7 @@ func (cfgmaps *ConfigMaps) Create(key string, rls *rspb.Release) error {
 		cfgmaps.Log("create: failed to create: %s", err)
 		return err
 	}
+	cfgmaps.versions.set(key, created.ObjectMeta.ResourceVersion)
 	return nil
 }
 
@@ -191,37 +255,142 @@ func (cfgmaps *ConfigMaps) Update(key string, rls *rspb.Release) error {
 	lbs.set("modifiedAt", strconv.Itoa(int(time.Now().Unix())))
 
 	// create a new configmap object to hold the release
-	obj, err := newConfigMapsObject(key, rls, lbs)
+	obj, parts, err := newConfigMapsObject(key, rls, lbs, cfgmaps.Compression, cfgmaps.Encryptor)
 	if err != nil {
 		cfgmaps.Log("update: failed to encode release %q: %s", rls.Name, err)
 		return err
 	}
+	// the parts of the current configmap, to delete those that are no longer
+	// referenced
+	var currentParts []string
+	current, err := cfgmaps.impl.Get(context.Background(), key, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		// Conflicts are detected before the parts are written, so the parts
+		// of the other client are not overwritten.
+		if err := cfgmaps.versions.check(key, current.ObjectMeta.ResourceVersion); err != nil {
+			return err
+		}
+		currentParts = configMapPartNames(current)
+	case !apierrors.IsNotFound(err):
+		return errors.Wrapf(err, "update: failed to get current configmap")
+	}
+	obj.ObjectMeta.ResourceVersion = cfgmaps.versions.get(key)
+	// The parts are written first so the main configmap never references
+	// parts that do not exist yet. They are named after their content, so
+	// the parts referenced by the current configmap are left untouched.
+	written, err := cfgmaps.writeParts(parts)
+	if err != nil {
+		cfgmaps.deleteParts(written)
+		cfgmaps.Log("update: failed to update spillover configmaps: %s", err)
+		return err
+	}
 	// push the configmap object out into the kubiverse
-	_, err = cfgmaps.impl.Update(context.Background(), obj, metav1.UpdateOptions{})
+	updated, err := cfgmaps.impl.Update(context.Background(), obj, metav1.UpdateOptions{})
 	if err != nil {
+		cfgmaps.deleteParts(written)
+		if apierrors.IsConflict(err) {
+			return &ConflictError{Key: key, Err: err}
+		}
 		cfgmaps.Log("update: failed to update: %s", err)
 		return err
 	}
+	cfgmaps.versions.set(key, updated.ObjectMeta.ResourceVersion)
+	cfgmaps.deleteParts(staleParts(currentParts, configMapPartNames(obj)))
 	return nil
 }
 
-// Delete deletes the ConfigMap holding the release named by key.
+// Delete deletes the ConfigMap holding the release named by key, along with
+// its spillover configmaps.
 func (cfgmaps *ConfigMaps) Delete(key string) (rls *rspb.Release, err error) {
-	// fetch the release to check existence
-	if rls, err = cfgmaps.Get(key); err != nil {
+	// fetch the configmap to check existence
+	obj, err := cfgmaps.impl.Get(context.Background(), key, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, ErrReleaseNotFound
+		}
+
+		cfgmaps.Log("delete: failed to get %q: %s", key, err)
 		return nil, err
 	}
+	if rls, err = cfgmaps.decode(obj); err != nil {
+		cfgmaps.Log("delete: failed to decode data %q: %s", key, err)
+		return nil, err
+	}
+	rls.Labels = FilterSystemLabels(obj.ObjectMeta.Labels)
 	// delete the release
 	if err = cfgmaps.impl.Delete(context.Background(), key, metav1.DeleteOptions{}); err != nil {
 		return rls, err
 	}
+	cfgmaps.versions.set(key, "")
+	cfgmaps.deleteParts(configMapPartNames(obj))
 	return rls, nil
 }
 
+// decode reassembles and decodes the release held by a main configmap and its
+// spillover configmaps.
+func (cfgmaps *ConfigMaps) decode(obj *v1.ConfigMap) (*rspb.Release, error) {
+	data := obj.Data["release"]
+	if names := configMapPartNames(obj); len(names) > 0 {
+		var b strings.Builder
+		b.WriteString(data)
+		for i, name := range names {
+			part, err := cfgmaps.impl.Get(context.Background(), name, metav1.GetOptions{})
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to get spillover configmap %d of %d", i+1, len(names))
+			}
+			b.WriteString(part.Data["release"])
+		}
+		data = b.String()
+	}
+	return openRelease(data, cfgmaps.Encryptor)
+}
+
+// writeParts creates the spillover configmaps, and returns the names of those
+// it created. The spillover configmaps that already exist hold the same
+// content, as they are named after it, and are left untouched.
+func (cfgmaps *ConfigMaps) writeParts(parts []*v1.ConfigMap) ([]string, error) {
+	var written []string
+	for _, part := range parts {
+		_, err := cfgmaps.impl.Create(context.Background(), part, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			continue
+		}
+		if err != nil {
+			return written, err
+		}
+		written = append(written, part.ObjectMeta.Name)
+	}
+	return written, nil
+}
+
+// deleteParts deletes the named spillover configmaps. Failures are only
+// logged: a spillover configmap which is no longer referenced is never read.
+func (cfgmaps *ConfigMaps) deleteParts(names []string) {
+	for _, name := range names {
+		if err := cfgmaps.impl.Delete(context.Background(), name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			cfgmaps.Log("failed to delete spillover configmap %s: %s", name, err)
+		}
+	}
+}
+
+// configMapPartNames returns the names of the spillover configmaps of a main
+// configmap, in order.
+func configMapPartNames(obj *v1.ConfigMap) []string {
+	n, _ := strconv.Atoi(obj.Data["parts"])
+	return partNames(obj.ObjectMeta.Name, obj.Data["partsID"], n)
+}
+
 // newConfigMapsObject constructs a kubernetes ConfigMap object
 // to store a release. Each configmap data entry is the base64
 // encoded gzipped string of a release.
 //
+// If the release does not fit into a single ConfigMap, the data of the
+// returned ConfigMap holds its first part, the number of spillover ConfigMaps
+// under the "parts" key and their identifier under the "partsID" key, and the
+// spillover ConfigMaps holding the rest of the release are returned too. They
+// are named "<key>.p<i>-<id>" and labeled with spilloverLabels.
+//
 // The following labels are used within each configmap:
 //
 //	"modifiedAt"     - timestamp indicating when this configmap was last modified. (set in Update)
@@ -230,14 +399,15 @@ func (cfgmaps *ConfigMaps) Delete(key string) (rls *rspb.Release, err error) {
 //	"status"         - status of the release (see pkg/release/status.go for variants)
 //	"owner"          - owner of the configmap, currently "helm".
 //	"name"           - name of the release.
-func newConfigMapsObject(key string, rls *rspb.Release, lbs labels) (*v1.ConfigMap, error) {
+func newConfigMapsObject(key string, rls *rspb.Release, lbs labels, comp Compression, enc Encryptor) (*v1.ConfigMap, []*v1.ConfigMap, error) {
 	const owner = "helm"
 
 	// encode the release
-	s, err := encodeRelease(rls)
+	s, err := sealRelease(rls, comp, enc)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	chunks := splitReleaseData(s)
 
 	if lbs == nil {
 		lbs.init()
@@ -251,13 +421,34 @@ func newConfigMapsObject(key string, rls *rspb.Release, lbs labels) (*v1.ConfigM
 	lbs.set("owner", owner)
 	lbs.set("status", rls.Info.Status.String())
 	lbs.set("version", strconv.Itoa(rls.Version))
+	if chart := chartNameLabelValue(rls); chart != "" {
+		lbs.set(ChartNameLabel, chart)
+	}
 
 	// create and return configmap object
-	return &v1.ConfigMap{
+	obj := &v1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:   key,
 			Labels: lbs.toMap(),
 		},
-		Data: map[string]string{"release": s},
-	}, nil
+		Data: map[string]string{"release": chunks[0]},
+	}
+	if len(chunks) == 1 {
+		return obj, nil, nil
+	}
+	id := partsID(s)
+	obj.Data["parts"] = strconv.Itoa(len(chunks) - 1)
+	obj.Data["partsID"] = id
+
+	var parts []*v1.ConfigMap
+	for i, chunk := range chunks[1:] {
+		parts = append(parts, &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   partName(key, id, i+1),
+				Labels: spilloverLabels(rls),
+			},
+			Data: map[string]string{"release": chunk},
+		})
+	}
+	return obj, parts, nil
 }
@@ -19,6 +19,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
 
 	rspb "helm.sh/helm/v3/pkg/release"
@@ -59,7 +60,7 @@ func TestUncompressedConfigMapGet(t *testing.T) {
 	rel := releaseStub(name, vers, namespace, rspb.StatusDeployed)
 
 	// Create a test fixture which contains an uncompressed release
-	cfgmap, err := newConfigMapsObject(key, rel, nil)
+	cfgmap, _, err := newConfigMapsObject(key, rel, nil, Compression{}, nil)
 	if err != nil {
 		t.Fatalf("Failed to create configmap: %s", err)
 	}
@@ -219,6 +220,56 @@ func TestConfigMapUpdate(t *testing.T) {
 	}
 }
 
+func TestConfigMapUpdateConflict(t *testing.T) {
+	key := testKey("smug-pigeon", 1)
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+
+	var mock MockConfigMapsInterface
+	mock.Init(t, rel)
+	first, second := NewConfigMaps(&mock), NewConfigMaps(&mock)
+
+	// both clients read the release, then the second one updates it
+	for _, d := range []*ConfigMaps{first, second} {
+		if _, err := d.Get(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rel.Info.Status = rspb.StatusSuperseded
+	if err := second.Update(key, rel); err != nil {
+		t.Fatalf("Failed to update release: %s", err)
+	}
+
+	// the update of the first client is based on a stale read
+	rel.Info.Status = rspb.StatusFailed
+	err := first.Update(key, rel)
+	if !errors.Is(err, ErrReleaseConflict) {
+		t.Fatalf("Expected %v, got %v", ErrReleaseConflict, err)
+	}
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || conflict.Key != key {
+		t.Errorf("Expected a ConflictError for %q, got %v", key, err)
+	}
+	if got, _ := second.Get(key); got.Info.Status != rspb.StatusSuperseded {
+		t.Errorf("Expected the conflicting update not to be applied, got status %s", got.Info.Status)
+	}
+
+	// it succeeds once the release is read again, and the driver follows its
+	// own updates
+	if _, err := first.Get(key); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := first.Update(key, rel); err != nil {
+			t.Fatalf("Failed to update release: %s", err)
+		}
+	}
+
+	// releases that were not read are updated unconditionally
+	if err := NewConfigMaps(&mock).Update(key, rel); err != nil {
+		t.Fatalf("Failed to update release: %s", err)
+	}
+}
+
 func TestConfigMapDelete(t *testing.T) {
 	vers := 1
 	name := "smug-pigeon"
@@ -249,3 +300,113 @@ func TestConfigMapDelete(t *testing.T) {
 		t.Errorf("Expected {%v}, got {%v}", ErrReleaseNotFound, err)
 	}
 }
+
+func TestConfigMapSpillover(t *testing.T) {
+	withMaxRecordDataSize(t, 64)
+
+	key := testKey("smug-pigeon", 1)
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+	rel.Manifest = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: smug-pigeon\n"
+
+	cfgmaps := newTestFixtureCfgMaps(t)
+	mock := cfgmaps.impl.(*MockConfigMapsInterface)
+	if err := cfgmaps.Create(key, rel); err != nil {
+		t.Fatalf("Failed to create release with key %q: %s", key, err)
+	}
+
+	created := len(mock.objects)
+	if created < 3 {
+		t.Fatalf("Expected the release to be split across several configmaps, got %d", created)
+	}
+
+	got, err := cfgmaps.Get(key)
+	if err != nil {
+		t.Fatalf("Failed to get release with key %q: %s", key, err)
+	}
+	if !reflect.DeepEqual(rel, got) {
+		t.Errorf("Expected {%v}, got {%v}", rel, got)
+	}
+
+	// spillover configmaps must not be returned as releases
+	rls, err := cfgmaps.Query(map[string]string{"name": "smug-pigeon"})
+	if err != nil {
+		t.Fatalf("Failed to query: %s", err)
+	}
+	if len(rls) != 1 {
+		t.Errorf("Expected 1 release, got %d", len(rls))
+	}
+
+	// shrinking the release removes the spillover configmaps that are no longer needed
+	rel.Manifest = ""
+	if err := cfgmaps.Update(key, rel); err != nil {
+		t.Fatalf("Failed to update release: %s", err)
+	}
+	if n := len(mock.objects); n >= created {
+		t.Errorf("Expected fewer than %d configmaps after update, got %d", created, n)
+	}
+
+	if _, err := cfgmaps.Delete(key); err != nil {
+		t.Fatalf("Failed to delete release with key %q: %s", key, err)
+	}
+	if n := len(mock.objects); n != 0 {
+		t.Errorf("Expected all configmaps to be deleted, got %d", n)
+	}
+}
+
+func TestConfigMapSpilloverFailedWrites(t *testing.T) {
+	withMaxRecordDataSize(t, 64)
+
+	key := testKey("smug-pigeon", 1)
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+	rel.Manifest = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: smug-pigeon\n"
+
+	cfgmaps := newTestFixtureCfgMaps(t)
+	mock := cfgmaps.impl.(*MockConfigMapsInterface)
+	if err := cfgmaps.Create(key, rel); err != nil {
+		t.Fatalf("Failed to create release with key %q: %s", key, err)
+	}
+	stored := len(mock.objects)
+
+	// A failed update leaves the stored release and its parts untouched, and
+	// removes the parts it wrote.
+	updated := *rel
+	updated.Manifest = "apiVersion: v1\nkind: Secret\nmetadata:\n  name: updated-pigeon\n"
+	mock.updateErr = errors.New("etcd unavailable")
+	if err := cfgmaps.Update(key, &updated); err == nil {
+		t.Fatal("Expected the update to fail")
+	}
+	mock.updateErr = nil
+	if n := len(mock.objects); n != stored {
+		t.Errorf("Expected %d configmaps after the failed update, got %d", stored, n)
+	}
+	got, err := cfgmaps.Get(key)
+	if err != nil {
+		t.Fatalf("Failed to get release with key %q: %s", key, err)
+	}
+	if !reflect.DeepEqual(rel, got) {
+		t.Errorf("Expected {%v}, got {%v}", rel, got)
+	}
+
+	// A failed creation removes the parts it wrote.
+	if err := cfgmaps.Create(key, &updated); err != ErrReleaseExists {
+		t.Errorf("Expected {%v}, got {%v}", ErrReleaseExists, err)
+	}
+	if n := len(mock.objects); n != stored {
+		t.Errorf("Expected %d configmaps after the failed creation, got %d", stored, n)
+	}
+
+	// A successful update removes the parts of the previous release.
+	if err := cfgmaps.Update(key, &updated); err != nil {
+		t.Fatalf("Failed to update release: %s", err)
+	}
+	got, err = cfgmaps.Get(key)
+	if err != nil {
+		t.Fatalf("Failed to get release with key %q: %s", key, err)
+	}
+	if !reflect.DeepEqual(&updated, got) {
+		t.Errorf("Expected {%v}, got {%v}", &updated, got)
+	}
+	if n := len(mock.objects); n != stored {
+		t.Errorf("Expected %d configmaps after the update, got %d", stored, n)
+	}
+}
@@ -18,8 +18,10 @@ package driver // import "helm.sh/helm/v3/pkg/storage/driver"
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/pkg/errors"
+	kblabels "k8s.io/apimachinery/pkg/labels"
 
 	rspb "helm.sh/helm/v3/pkg/release"
 )
@@ -29,6 +31,9 @@ var (
 	ErrReleaseNotFound = errors.New("release: not found")
 	// ErrReleaseExists indicates that a release already exists.
 	ErrReleaseExists = errors.New("release: already exists")
+	// ErrReleaseConflict indicates that a release was modified since it was
+	// read. It is matched by every ConflictError.
+	ErrReleaseConflict = errors.New("release: modified concurrently")
 	// ErrInvalidKey indicates that a release key could not be parsed.
 	ErrInvalidKey = errors.New("release: invalid key")
 	// ErrNoDeployedReleases indicates that there are no releases with the given key in the deployed state
@@ -47,6 +52,23 @@ func (e *StorageDriverError) Error() string {
 
 func (e *StorageDriverError) Unwrap() error { return e.Err }
 
+// ConflictError is returned by Update when the stored release was modified by
+// another client since the driver read it. The update is not applied, so the
+// caller can read the release again and retry.
+type ConflictError struct {
+	Key string
+	Err error
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("release %q was modified concurrently: %s", e.Key, e.Err)
+}
+
+// Is reports whether target is ErrReleaseConflict.
+func (e *ConflictError) Is(target error) bool { return target == ErrReleaseConflict }
+
+func (e *ConflictError) Unwrap() error { return e.Err }
+
 func NewErrNoDeployedReleases(releaseName string) error {
 	return &StorageDriverError{
 		ReleaseName: releaseName,
@@ -92,6 +114,77 @@ type Queryor interface {
 	Query(labels map[string]string) ([]*rspb.Release, error)
 }
 
+// ListOptions selects and paginates the releases listed by a Pager.
+type ListOptions struct {
+	// Selector selects releases by the labels of their records, which
+	// include the system labels such as "name" and "status". All releases
+	// are selected if it is nil.
+	Selector kblabels.Selector
+	// Statuses selects the releases with one of the statuses. All releases
+	// are selected if it is empty.
+	Statuses []rspb.Status
+	// Limit is the maximum number of releases returned by a call. All
+	// releases are returned if it is zero.
+	Limit int64
+	// Continue is the token returned by the previous call, to list the next
+	// page of releases.
+	Continue string
+}
+
+// Pager is the interface that wraps the ListPage method.
+//
+// ListPage returns the releases selected by the options, filtering them in
+// the storage backend rather than decoding every release record, and the
+// token listing the next page. The token is empty on the last page.
+//
+// Pager is implemented by the drivers that can filter releases in their
+// storage backend. ListPage falls back to List for other drivers.
+type Pager interface {
+	ListPage(opts ListOptions) ([]*rspb.Release, string, error)
+}
+
+// ListPage returns the releases selected by opts with d, and the token
+// listing the next page. If d does not implement Pager, all releases are
+// listed and filtered by Helm, and they are returned in a single page.
+func ListPage(d Driver, opts ListOptions) ([]*rspb.Release, string, error) {
+	if p, ok := d.(Pager); ok {
+		return p.ListPage(opts)
+	}
+
+	rls, err := d.List(func(rls *rspb.Release) bool {
+		if len(opts.Statuses) > 0 && !containsStatus(opts.Statuses, rls.Info.Status) {
+			return false
+		}
+		if opts.Selector == nil {
+			return true
+		}
+		// the system labels are not set by every driver
+		lbs := kblabels.Set{
+			"name":    rls.Name,
+			"owner":   "helm",
+			"status":  rls.Info.Status.String(),
+			"version": strconv.Itoa(rls.Version),
+		}
+		if chart := chartNameLabelValue(rls); chart != "" {
+			lbs[ChartNameLabel] = chart
+		}
+		for k, v := range rls.Labels {
+			lbs[k] = v
+		}
+		return opts.Selector.Matches(lbs)
+	})
+	return rls, "", err
+}
+
+func containsStatus(statuses []rspb.Status, status rspb.Status) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
 // Driver is the interface composed of Creator, Updator, Deletor, and Queryor
 // interfaces. It defines the behavior for storing, updating, deleted,
 // and retrieving Helm releases from some underlying storage mechanism,
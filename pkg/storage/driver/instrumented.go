@@ -0,0 +1,169 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver // import "helm.sh/helm/v3/pkg/storage/driver"
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	rspb "helm.sh/helm/v3/pkg/release"
+)
+
+var _ Driver = (*Instrumented)(nil)
+var _ Pager = (*Instrumented)(nil)
+
+// InstrumentationName is the name of the tracer of the storage operations.
+const InstrumentationName = "helm.sh/helm/v3/pkg/storage/driver"
+
+// InstrumentOptions configures the instrumentation of a driver.
+type InstrumentOptions struct {
+	// TracerProvider provides the tracer recording a span for every
+	// operation. No spans are recorded if it is nil.
+	TracerProvider trace.TracerProvider
+	// Registerer registers the histogram of the duration of the operations,
+	// "helm_storage_operation_duration_seconds". No metrics are recorded if
+	// it is nil.
+	Registerer prometheus.Registerer
+}
+
+// Instrumented wraps a Driver, recording the duration of its operations in
+// a Prometheus histogram and tracing them with OpenTelemetry.
+type Instrumented struct {
+	Driver
+
+	tracer   trace.Tracer
+	duration *prometheus.HistogramVec
+}
+
+// NewInstrumented returns an Instrumented driver wrapping d. The histogram is
+// shared by all the drivers instrumented with the same Registerer.
+func NewInstrumented(d Driver, opts InstrumentOptions) (*Instrumented, error) {
+	tp := opts.TracerProvider
+	if tp == nil {
+		tp = noop.NewTracerProvider()
+	}
+	i := &Instrumented{
+		Driver: d,
+		tracer: tp.Tracer(InstrumentationName),
+	}
+
+	if opts.Registerer != nil {
+		duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "helm_storage_operation_duration_seconds",
+			Help:    "Duration of the operations of the Helm release storage driver.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"driver", "operation", "result"})
+		if err := opts.Registerer.Register(duration); err != nil {
+			are := prometheus.AlreadyRegisteredError{}
+			if !errors.As(err, &are) {
+				return nil, errors.Wrap(err, "failed to register storage metrics")
+			}
+			duration = are.ExistingCollector.(*prometheus.HistogramVec)
+		}
+		i.duration = duration
+	}
+	return i, nil
+}
+
+// Unwrap returns the instrumented driver.
+func (i *Instrumented) Unwrap() Driver {
+	return i.Driver
+}
+
+// Get fetches the release named by key.
+func (i *Instrumented) Get(key string) (rls *rspb.Release, err error) {
+	defer i.observe("get", key)(&err)
+	return i.Driver.Get(key)
+}
+
+// List returns the releases such that filter(release) == true.
+func (i *Instrumented) List(filter func(*rspb.Release) bool) (rls []*rspb.Release, err error) {
+	defer i.observe("list", "")(&err)
+	return i.Driver.List(filter)
+}
+
+// ListPage returns the releases selected by opts. See ListPage.
+func (i *Instrumented) ListPage(opts ListOptions) (rls []*rspb.Release, next string, err error) {
+	defer i.observe("list_page", "")(&err)
+	return ListPage(i.Driver, opts)
+}
+
+// Query returns the releases that match the labels.
+func (i *Instrumented) Query(labels map[string]string) (rls []*rspb.Release, err error) {
+	defer i.observe("query", "")(&err)
+	return i.Driver.Query(labels)
+}
+
+// Create stores the release.
+func (i *Instrumented) Create(key string, rls *rspb.Release) (err error) {
+	defer i.observe("create", key)(&err)
+	return i.Driver.Create(key, rls)
+}
+
+// Update updates the stored release.
+func (i *Instrumented) Update(key string, rls *rspb.Release) (err error) {
+	defer i.observe("update", key)(&err)
+	return i.Driver.Update(key, rls)
+}
+
+// Delete deletes the release named by key.
+func (i *Instrumented) Delete(key string) (rls *rspb.Release, err error) {
+	defer i.observe("delete", key)(&err)
+	return i.Driver.Delete(key)
+}
+
+// observe starts the span of an operation. The returned function ends it and
+// records the duration of the operation, with the result of its error.
+func (i *Instrumented) observe(operation, key string) func(*error) {
+	attrs := []attribute.KeyValue{
+		attribute.String("helm.storage.driver", i.Driver.Name()),
+	}
+	if key != "" {
+		attrs = append(attrs, attribute.String("helm.storage.key", key))
+	}
+	_, span := i.tracer.Start(context.Background(), "helm.storage."+operation, trace.WithAttributes(attrs...))
+	start := time.Now()
+
+	return func(errp *error) {
+		result := "success"
+		switch err := *errp; {
+		case errors.Is(err, ErrReleaseNotFound):
+			result = "not_found"
+		case errors.Is(err, ErrReleaseConflict):
+			result = "conflict"
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		case err != nil:
+			result = "error"
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.SetAttributes(attribute.String("helm.storage.result", result))
+		span.End()
+
+		if i.duration != nil {
+			i.duration.WithLabelValues(i.Driver.Name(), operation, result).Observe(time.Since(start).Seconds())
+		}
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	rspb "helm.sh/helm/v3/pkg/release"
+)
+
+// recordingTracerProvider records the names of the spans that are started.
+type recordingTracerProvider struct {
+	noop.TracerProvider
+	spans []string
+}
+
+func (p *recordingTracerProvider) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return &recordingTracer{provider: p}
+}
+
+type recordingTracer struct {
+	noop.Tracer
+	provider *recordingTracerProvider
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	t.provider.spans = append(t.provider.spans, name)
+	return t.Tracer.Start(ctx, name, opts...)
+}
+
+func TestInstrumented(t *testing.T) {
+	tp := &recordingTracerProvider{}
+	reg := prometheus.NewRegistry()
+
+	d, err := NewInstrumented(NewMemory(), InstrumentOptions{TracerProvider: tp, Registerer: reg})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := testKey("smug-pigeon", 1)
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+	if err := d.Create(key, rel); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Get(key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Get(testKey("nonexistent", 1)); err != ErrReleaseNotFound {
+		t.Errorf("Expected {%v}, got {%v}", ErrReleaseNotFound, err)
+	}
+	if _, _, err := d.ListPage(ListOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"helm.storage.create", "helm.storage.get", "helm.storage.get", "helm.storage.list_page"}
+	if !reflect.DeepEqual(tp.spans, expected) {
+		t.Errorf("Expected spans %v, got %v", expected, tp.spans)
+	}
+
+	if n := testutil.CollectAndCount(reg, "helm_storage_operation_duration_seconds"); n != 4 {
+		t.Errorf("Expected 4 series, got %d", n)
+	}
+
+	// drivers instrumented with the same registerer share the histogram
+	if _, err := NewInstrumented(NewMemory(), InstrumentOptions{Registerer: reg}); err != nil {
+		t.Errorf("Expected the histogram to be reused, got %v", err)
+	}
+}
@@ -19,11 +19,14 @@ package driver // import "helm.sh/helm/v3/pkg/storage/driver"
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strconv"
 	"testing"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
 	sq "github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
 
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -80,6 +83,13 @@ func tsFixtureMemory(t *testing.T) *Memory {
 	return mem
 }
 
+// nextResourceVersion returns the resourceVersion of an object after it is
+// written.
+func nextResourceVersion(current string) string {
+	n, _ := strconv.Atoi(current)
+	return strconv.Itoa(n + 1)
+}
+
 // newTestFixture initializes a MockConfigMapsInterface.
 // ConfigMaps are created for each release provided.
 func newTestFixtureCfgMaps(t *testing.T, releases ...*rspb.Release) *ConfigMaps {
@@ -94,6 +104,8 @@ type MockConfigMapsInterface struct {
 	corev1.ConfigMapInterface
 
 	objects map[string]*v1.ConfigMap
+	// updateErr is returned by Update if it is set.
+	updateErr error
 }
 
 // Init initializes the MockConfigMapsInterface with the set of releases.
@@ -103,10 +115,11 @@ func (mock *MockConfigMapsInterface) Init(t *testing.T, releases ...*rspb.Releas
 	for _, rls := range releases {
 		objkey := testKey(rls.Name, rls.Version)
 
-		cfgmap, err := newConfigMapsObject(objkey, rls, nil)
+		cfgmap, _, err := newConfigMapsObject(objkey, rls, nil, Compression{}, nil)
 		if err != nil {
 			t.Fatalf("Failed to create configmap: %s", err)
 		}
+		cfgmap.ObjectMeta.ResourceVersion = nextResourceVersion("")
 		mock.objects[objkey] = cfgmap
 	}
 }
@@ -143,6 +156,8 @@ func (mock *MockConfigMapsInterface) Create(_ context.Context, cfgmap *v1.Config
 	if object, ok := mock.objects[name]; ok {
 		return object, apierrors.NewAlreadyExists(v1.Resource("tests"), name)
 	}
+	cfgmap = cfgmap.DeepCopy()
+	cfgmap.ObjectMeta.ResourceVersion = nextResourceVersion("")
 	mock.objects[name] = cfgmap
 	return cfgmap, nil
 }
@@ -150,9 +165,19 @@ func (mock *MockConfigMapsInterface) Create(_ context.Context, cfgmap *v1.Config
 // Update updates a ConfigMap.
 func (mock *MockConfigMapsInterface) Update(_ context.Context, cfgmap *v1.ConfigMap, _ metav1.UpdateOptions) (*v1.ConfigMap, error) {
 	name := cfgmap.ObjectMeta.Name
-	if _, ok := mock.objects[name]; !ok {
+	if mock.updateErr != nil {
+		return nil, mock.updateErr
+	}
+	current, ok := mock.objects[name]
+	if !ok {
 		return nil, apierrors.NewNotFound(v1.Resource("tests"), name)
 	}
+	// like the API server, updates with a resourceVersion are conditional
+	if cfgmap.ObjectMeta.ResourceVersion != "" && cfgmap.ObjectMeta.ResourceVersion != current.ObjectMeta.ResourceVersion {
+		return nil, apierrors.NewConflict(v1.Resource("tests"), name, errors.New("the object has been modified"))
+	}
+	cfgmap = cfgmap.DeepCopy()
+	cfgmap.ObjectMeta.ResourceVersion = nextResourceVersion(current.ObjectMeta.ResourceVersion)
 	mock.objects[name] = cfgmap
 	return cfgmap, nil
 }
@@ -180,6 +205,10 @@ type MockSecretsInterface struct {
 	corev1.SecretInterface
 
 	objects map[string]*v1.Secret
+	// getErr is returned by Get if it is set.
+	getErr error
+	// updateErr is returned by Update if it is set.
+	updateErr error
 }
 
 // Init initializes the MockSecretsInterface with the set of releases.
@@ -189,16 +218,20 @@ func (mock *MockSecretsInterface) Init(t *testing.T, releases ...*rspb.Release)
 	for _, rls := range releases {
 		objkey := testKey(rls.Name, rls.Version)
 
-		secret, err := newSecretsObject(objkey, rls, nil)
+		secret, _, err := newSecretsObject(objkey, rls, nil, Compression{}, nil)
 		if err != nil {
 			t.Fatalf("Failed to create secret: %s", err)
 		}
+		secret.ObjectMeta.ResourceVersion = nextResourceVersion("")
 		mock.objects[objkey] = secret
 	}
 }
 
 // Get returns the Secret by name.
 func (mock *MockSecretsInterface) Get(_ context.Context, name string, _ metav1.GetOptions) (*v1.Secret, error) {
+	if mock.getErr != nil {
+		return nil, mock.getErr
+	}
 	object, ok := mock.objects[name]
 	if !ok {
 		return nil, apierrors.NewNotFound(v1.Resource("tests"), name)
@@ -215,10 +248,23 @@ func (mock *MockSecretsInterface) List(_ context.Context, opts metav1.ListOption
 		return nil, err
 	}
 
-	for _, secret := range mock.objects {
-		if labelSelector.Matches(kblabels.Set(secret.ObjectMeta.Labels)) {
-			list.Items = append(list.Items, *secret)
+	// paginate in the order of the names, like the API server does
+	names := make([]string, 0, len(mock.objects))
+	for name := range mock.objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		secret := mock.objects[name]
+		if name <= opts.Continue || !labelSelector.Matches(kblabels.Set(secret.ObjectMeta.Labels)) {
+			continue
 		}
+		if opts.Limit > 0 && int64(len(list.Items)) == opts.Limit {
+			list.Continue = list.Items[len(list.Items)-1].ObjectMeta.Name
+			break
+		}
+		list.Items = append(list.Items, *secret)
 	}
 	return &list, nil
 }
@@ -229,6 +275,8 @@ func (mock *MockSecretsInterface) Create(_ context.Context, secret *v1.Secret, _
 	if object, ok := mock.objects[name]; ok {
 		return object, apierrors.NewAlreadyExists(v1.Resource("tests"), name)
 	}
+	secret = secret.DeepCopy()
+	secret.ObjectMeta.ResourceVersion = nextResourceVersion("")
 	mock.objects[name] = secret
 	return secret, nil
 }
@@ -236,9 +284,19 @@ func (mock *MockSecretsInterface) Create(_ context.Context, secret *v1.Secret, _
 // Update updates a Secret.
 func (mock *MockSecretsInterface) Update(_ context.Context, secret *v1.Secret, _ metav1.UpdateOptions) (*v1.Secret, error) {
 	name := secret.ObjectMeta.Name
-	if _, ok := mock.objects[name]; !ok {
+	if mock.updateErr != nil {
+		return nil, mock.updateErr
+	}
+	current, ok := mock.objects[name]
+	if !ok {
 		return nil, apierrors.NewNotFound(v1.Resource("tests"), name)
 	}
+	// like the API server, updates with a resourceVersion are conditional
+	if secret.ObjectMeta.ResourceVersion != "" && secret.ObjectMeta.ResourceVersion != current.ObjectMeta.ResourceVersion {
+		return nil, apierrors.NewConflict(v1.Resource("tests"), name, errors.New("the object has been modified"))
+	}
+	secret = secret.DeepCopy()
+	secret.ObjectMeta.ResourceVersion = nextResourceVersion(current.ObjectMeta.ResourceVersion)
 	mock.objects[name] = secret
 	return secret, nil
 }
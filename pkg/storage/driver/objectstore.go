@@ -0,0 +1,357 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver // import "helm.sh/helm/v3/pkg/storage/driver"
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	rspb "helm.sh/helm/v3/pkg/release"
+)
+
+var _ Driver = (*ObjectStorage)(nil)
+
+// ObjectStorageDriverName is the string name of the driver.
+const ObjectStorageDriverName = "ObjectStorage"
+
+var (
+	// ErrObjectNotFound indicates that an object does not exist in the object store.
+	ErrObjectNotFound = errors.New("object: not found")
+	// ErrObjectPreconditionFailed indicates that a conditional write was
+	// rejected because the object was changed by someone else.
+	ErrObjectPreconditionFailed = errors.New("object: precondition failed")
+)
+
+// ObjectStore is a bucket of an object store, such as Amazon S3, Google Cloud
+// Storage or Azure Blob Storage, that supports conditional writes.
+//
+// Objects are identified by an entity tag (ETag) that changes whenever the
+// object is written. Conditional writes are used to implement optimistic
+// concurrency: a write based on a stale read fails instead of silently
+// overwriting the write of another client. All three providers support these
+// semantics with If-Match and If-None-Match conditional requests, so ObjectStore
+// maps directly onto their SDKs. DirectoryObjectStore is the implementation of
+// a local directory, and the getter package implements it for these providers.
+type ObjectStore interface {
+	// Get returns the content and the ETag of the named object, or
+	// ErrObjectNotFound.
+	Get(ctx context.Context, name string) (data []byte, etag string, err error)
+	// Put writes the named object and returns its new ETag. If ifMatch is
+	// empty the object must not exist, otherwise its ETag must be ifMatch.
+	// ErrObjectPreconditionFailed is returned if the condition is not met.
+	Put(ctx context.Context, name string, data []byte, ifMatch string) (etag string, err error)
+	// Delete deletes the named object if its ETag is ifMatch. It returns
+	// ErrObjectNotFound or ErrObjectPreconditionFailed if the condition is
+	// not met.
+	Delete(ctx context.Context, name, ifMatch string) error
+	// List returns the names of the objects starting with prefix.
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// objectRecord is the content of the object storing a release.
+type objectRecord struct {
+	Labels  map[string]string `json:"labels"`
+	Release string            `json:"release"`
+}
+
+// ObjectStorage is the storage driver storing release records in an object
+// store bucket, outside of the cluster.
+//
+// Each release is stored as the object "<prefix>/<namespace>/<key>". The
+// object holds the labels of the release with the base64 encoded, gzipped
+// release, so releases can be queried without another index.
+//
+// Updates are conditional on the ETag of the object when the release was
+// read, and fail with a ConflictError if it was modified since.
+type ObjectStorage struct {
+	store     ObjectStore
+	prefix    string
+	namespace string
+	versions  versions
+
+	Log func(string, ...interface{})
+	// Compression configures the compression of the stored releases.
+	Compression Compression
+	// Encryptor encrypts the stored releases if it is set.
+	Encryptor Encryptor
+}
+
+// NewObjectStorage initializes a new object storage driver storing releases
+// of namespace under prefix in the store. An empty namespace lists the
+// releases of all namespaces.
+func NewObjectStorage(store ObjectStore, prefix, namespace string) *ObjectStorage {
+	return &ObjectStorage{
+		store:     store,
+		prefix:    strings.Trim(prefix, "/"),
+		namespace: namespace,
+		Log:       func(_ string, _ ...interface{}) {},
+	}
+}
+
+// Name returns the name of the driver.
+func (o *ObjectStorage) Name() string {
+	return ObjectStorageDriverName
+}
+
+// Get fetches the release named by key. The corresponding release is returned
+// or error if not found.
+func (o *ObjectStorage) Get(key string) (*rspb.Release, error) {
+	name := o.objectName(o.namespace, key)
+	rec, etag, err := o.get(name)
+	if err != nil {
+		if errors.Is(err, ErrObjectNotFound) {
+			return nil, ErrReleaseNotFound
+		}
+		return nil, errors.Wrapf(err, "get: failed to get %q", key)
+	}
+	o.versions.set(name, etag)
+	rls, err := openRelease(rec.Release, o.Encryptor)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get: failed to decode data %q", key)
+	}
+	rls.Labels = FilterSystemLabels(rec.Labels)
+	return rls, nil
+}
+
+// List fetches all releases and returns the list releases such
+// that filter(release) == true. An error is returned if the
+// objects fail to be retrieved.
+func (o *ObjectStorage) List(filter func(*rspb.Release) bool) ([]*rspb.Release, error) {
+	recs, err := o.list()
+	if err != nil {
+		return nil, errors.Wrap(err, "list: failed to list")
+	}
+
+	var results []*rspb.Release
+	for name, rec := range recs {
+		rls, err := openRelease(rec.Release, o.Encryptor)
+		if err != nil {
+			o.Log("list: failed to decode release: %s: %s", name, err)
+			continue
+		}
+		rls.Labels = rec.Labels
+		if filter(rls) {
+			results = append(results, rls)
+		}
+	}
+	return results, nil
+}
+
+// Query fetches all releases that match the provided map of labels.
+// An error is returned if the objects fail to be retrieved.
+func (o *ObjectStorage) Query(labels map[string]string) ([]*rspb.Release, error) {
+	recs, err := o.list()
+	if err != nil {
+		return nil, errors.Wrap(err, "query: failed to query with labels")
+	}
+
+	var results []*rspb.Release
+	for name, rec := range recs {
+		if !matchLabels(rec.Labels, labels) {
+			continue
+		}
+		rls, err := openRelease(rec.Release, o.Encryptor)
+		if err != nil {
+			o.Log("query: failed to decode release: %s: %s", name, err)
+			continue
+		}
+		rls.Labels = rec.Labels
+		results = append(results, rls)
+	}
+	if len(results) == 0 {
+		return nil, ErrReleaseNotFound
+	}
+	return results, nil
+}
+
+// Create stores the release in a new object. If the object already exists,
+// ErrReleaseExists is returned.
+func (o *ObjectStorage) Create(key string, rls *rspb.Release) error {
+	data, err := newObjectRecord(rls, map[string]string{
+		"createdAt": strconv.Itoa(int(time.Now().Unix())),
+	}, o.Compression, o.Encryptor)
+	if err != nil {
+		return errors.Wrapf(err, "create: failed to encode release %q", rls.Name)
+	}
+	name := o.objectName(rls.Namespace, key)
+	etag, err := o.store.Put(context.Background(), name, data, "")
+	if err != nil {
+		if errors.Is(err, ErrObjectPreconditionFailed) {
+			return ErrReleaseExists
+		}
+		return errors.Wrap(err, "create: failed to create")
+	}
+	o.versions.set(name, etag)
+	return nil
+}
+
+// Update updates the object storing the release. The write fails with a
+// ConflictError if the object was modified since it was read, so concurrent
+// updates of the same release are never lost.
+func (o *ObjectStorage) Update(key string, rls *rspb.Release) error {
+	name := o.objectName(rls.Namespace, key)
+	current, etag, err := o.get(name)
+	if err != nil {
+		if errors.Is(err, ErrObjectNotFound) {
+			return ErrReleaseNotFound
+		}
+		return errors.Wrap(err, "update: failed to update")
+	}
+	if err := o.versions.check(name, etag); err != nil {
+		return err
+	}
+
+	timestamps := map[string]string{"modifiedAt": strconv.Itoa(int(time.Now().Unix()))}
+	if createdAt, ok := current.Labels["createdAt"]; ok {
+		timestamps["createdAt"] = createdAt
+	}
+	data, err := newObjectRecord(rls, timestamps, o.Compression, o.Encryptor)
+	if err != nil {
+		return errors.Wrapf(err, "update: failed to encode release %q", rls.Name)
+	}
+
+	etag, err = o.store.Put(context.Background(), name, data, etag)
+	if err != nil {
+		if errors.Is(err, ErrObjectPreconditionFailed) {
+			return &ConflictError{Key: key, Err: err}
+		}
+		return errors.Wrap(err, "update: failed to update")
+	}
+	o.versions.set(name, etag)
+	return nil
+}
+
+// Delete deletes the object storing the release named by key.
+func (o *ObjectStorage) Delete(key string) (*rspb.Release, error) {
+	name := o.objectName(o.namespace, key)
+	rec, etag, err := o.get(name)
+	if err != nil {
+		if errors.Is(err, ErrObjectNotFound) {
+			return nil, ErrReleaseNotFound
+		}
+		return nil, errors.Wrapf(err, "delete: failed to get %q", key)
+	}
+	rls, err := openRelease(rec.Release, o.Encryptor)
+	if err != nil {
+		return nil, errors.Wrapf(err, "delete: failed to decode data %q", key)
+	}
+	rls.Labels = FilterSystemLabels(rec.Labels)
+
+	if err := o.store.Delete(context.Background(), name, etag); err != nil {
+		if errors.Is(err, ErrObjectNotFound) {
+			return nil, ErrReleaseNotFound
+		}
+		if errors.Is(err, ErrObjectPreconditionFailed) {
+			return nil, errors.Wrapf(err, "delete: release %q was modified concurrently", key)
+		}
+		return nil, errors.Wrapf(err, "delete: failed to delete %q", key)
+	}
+	o.versions.set(name, "")
+	return rls, nil
+}
+
+func (o *ObjectStorage) objectName(namespace, key string) string {
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+	return path.Join(o.prefix, namespace, key)
+}
+
+func (o *ObjectStorage) get(name string) (*objectRecord, string, error) {
+	data, etag, err := o.store.Get(context.Background(), name)
+	if err != nil {
+		return nil, "", err
+	}
+	var rec objectRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, "", errors.Wrapf(err, "invalid release record %q", name)
+	}
+	return &rec, etag, nil
+}
+
+// list returns the release records of the namespace of the driver, or of all
+// namespaces if it is empty, by object name.
+func (o *ObjectStorage) list() (map[string]*objectRecord, error) {
+	prefix := o.prefix
+	if o.namespace != "" {
+		prefix = path.Join(prefix, o.namespace)
+	}
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	names, err := o.store.List(context.Background(), prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	recs := make(map[string]*objectRecord, len(names))
+	for _, name := range names {
+		rec, etag, err := o.get(name)
+		if err != nil {
+			if errors.Is(err, ErrObjectNotFound) {
+				// deleted since it was listed
+				continue
+			}
+			return nil, err
+		}
+		if rec.Labels["owner"] != "helm" {
+			continue
+		}
+		o.versions.set(name, etag)
+		recs[name] = rec
+	}
+	return recs, nil
+}
+
+// newObjectRecord encodes the record storing the release, with the system
+// labels of the release and the given timestamp labels.
+func newObjectRecord(rls *rspb.Release, timestamps map[string]string, comp Compression, enc Encryptor) ([]byte, error) {
+	s, err := sealRelease(rls, comp, enc)
+	if err != nil {
+		return nil, err
+	}
+
+	var lbs labels
+	lbs.init()
+	lbs.fromMap(rls.Labels)
+	lbs.set("name", rls.Name)
+	lbs.set("owner", "helm")
+	lbs.set("status", rls.Info.Status.String())
+	lbs.set("version", strconv.Itoa(rls.Version))
+	if chart := chartNameLabelValue(rls); chart != "" {
+		lbs.set(ChartNameLabel, chart)
+	}
+	lbs.fromMap(timestamps)
+
+	return json.Marshal(&objectRecord{Labels: lbs.toMap(), Release: s})
+}
+
+func matchLabels(have, want map[string]string) bool {
+	for k, v := range want {
+		if have[k] != v {
+			return false
+		}
+	}
+	return true
+}
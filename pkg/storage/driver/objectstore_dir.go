@@ -0,0 +1,186 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver // import "helm.sh/helm/v3/pkg/storage/driver"
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gofrs/flock"
+	"github.com/pkg/errors"
+)
+
+var _ ObjectStore = (*DirectoryObjectStore)(nil)
+
+// directoryLockFile is the name of the lock file guarding the conditional
+// writes of a DirectoryObjectStore.
+const directoryLockFile = ".helm-objectstore.lock"
+
+// DirectoryObjectStore is an ObjectStore keeping objects as files of a local
+// directory, such as a volume shared by CI jobs. Conditional writes are
+// serialized with a file lock, so the file system must support locks.
+//
+// The ETag of an object is the SHA-256 digest of its content.
+type DirectoryObjectStore struct {
+	root string
+}
+
+// NewDirectoryObjectStore returns an ObjectStore keeping objects in root.
+func NewDirectoryObjectStore(root string) *DirectoryObjectStore {
+	return &DirectoryObjectStore{root: root}
+}
+
+// Get returns the content and the ETag of the named object.
+func (d *DirectoryObjectStore) Get(_ context.Context, name string) ([]byte, string, error) {
+	data, err := os.ReadFile(d.path(name))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, "", ErrObjectNotFound
+		}
+		return nil, "", err
+	}
+	return data, contentETag(data), nil
+}
+
+// Put writes the named object if its ETag is ifMatch, or if it does not exist
+// when ifMatch is empty.
+func (d *DirectoryObjectStore) Put(ctx context.Context, name string, data []byte, ifMatch string) (string, error) {
+	unlock, err := d.lock(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	if err := d.checkETag(name, ifMatch, true); err != nil {
+		return "", err
+	}
+
+	p := d.path(name)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return "", err
+	}
+	// Write to a temporary file first, so readers never see a partial object.
+	tmp, err := os.CreateTemp(filepath.Dir(p), "."+filepath.Base(p)+".tmp-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		return "", err
+	}
+	return contentETag(data), nil
+}
+
+// Delete deletes the named object if its ETag is ifMatch.
+func (d *DirectoryObjectStore) Delete(ctx context.Context, name, ifMatch string) error {
+	unlock, err := d.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := d.checkETag(name, ifMatch, false); err != nil {
+		return err
+	}
+	return os.Remove(d.path(name))
+}
+
+// List returns the names of the objects starting with prefix.
+func (d *DirectoryObjectStore) List(_ context.Context, prefix string) ([]string, error) {
+	var names []string
+	err := filepath.WalkDir(d.root, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		// Skip the lock file and temporary files.
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			return nil
+		}
+		rel, err := filepath.Rel(d.root, p)
+		if err != nil {
+			return err
+		}
+		if name := filepath.ToSlash(rel); strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+		return nil
+	})
+	return names, err
+}
+
+// path returns the file of the named object. Names are cleaned so that they
+// cannot refer to files outside of the root directory.
+func (d *DirectoryObjectStore) path(name string) string {
+	return filepath.Join(d.root, filepath.FromSlash(path.Clean("/"+name)))
+}
+
+// checkETag checks the condition of a conditional write of the named object.
+// If ifMatch is empty and allowCreate is set, the object must not exist.
+func (d *DirectoryObjectStore) checkETag(name, ifMatch string, allowCreate bool) error {
+	_, etag, err := d.Get(context.Background(), name)
+	switch {
+	case errors.Is(err, ErrObjectNotFound):
+		if ifMatch == "" && allowCreate {
+			return nil
+		}
+		return err
+	case err != nil:
+		return err
+	case ifMatch == "" && allowCreate, etag != ifMatch:
+		return ErrObjectPreconditionFailed
+	}
+	return nil
+}
+
+func (d *DirectoryObjectStore) lock(ctx context.Context) (func(), error) {
+	if err := os.MkdirAll(d.root, 0755); err != nil {
+		return nil, err
+	}
+	fileLock := flock.New(filepath.Join(d.root, directoryLockFile))
+	lockCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	locked, err := fileLock.TryLockContext(lockCtx, 100*time.Millisecond)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to lock object store")
+	}
+	if !locked {
+		return nil, errors.New("failed to lock object store")
+	}
+	return func() { fileLock.Unlock() }, nil
+}
+
+func contentETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,319 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	rspb "helm.sh/helm/v3/pkg/release"
+)
+
+func newTestFixtureObjectStorage(t *testing.T, releases ...*rspb.Release) *ObjectStorage {
+	t.Helper()
+	o := NewObjectStorage(NewDirectoryObjectStore(t.TempDir()), "releases", "default")
+	for _, rls := range releases {
+		if err := o.Create(testKey(rls.Name, rls.Version), rls); err != nil {
+			t.Fatalf("failed to create release %s: %s", rls.Name, err)
+		}
+	}
+	return o
+}
+
+// racingObjectStore writes the object once more before the first conditional
+// write, as a concurrent client would.
+type racingObjectStore struct {
+	ObjectStore
+	raced bool
+}
+
+func (r *racingObjectStore) Put(ctx context.Context, name string, data []byte, ifMatch string) (string, error) {
+	if !r.raced && ifMatch != "" {
+		r.raced = true
+		if _, err := r.ObjectStore.Put(ctx, name, append(data, ' '), ifMatch); err != nil {
+			return "", err
+		}
+	}
+	return r.ObjectStore.Put(ctx, name, data, ifMatch)
+}
+
+func TestObjectStorageName(t *testing.T) {
+	o := newTestFixtureObjectStorage(t)
+	if o.Name() != ObjectStorageDriverName {
+		t.Errorf("Expected name to be %q, got %q", ObjectStorageDriverName, o.Name())
+	}
+}
+
+func TestObjectStorageGet(t *testing.T) {
+	key := testKey("smug-pigeon", 1)
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+
+	o := newTestFixtureObjectStorage(t, rel)
+
+	got, err := o.Get(key)
+	if err != nil {
+		t.Fatalf("Failed to get release: %s", err)
+	}
+	if !reflect.DeepEqual(rel, got) {
+		t.Errorf("Expected release {%v}, got {%v}", rel, got)
+	}
+	if _, err := o.Get("nonexistent"); err != ErrReleaseNotFound {
+		t.Errorf("Expected {%v}, got {%v}", ErrReleaseNotFound, err)
+	}
+}
+
+func TestObjectStorageList(t *testing.T) {
+	o := newTestFixtureObjectStorage(t, []*rspb.Release{
+		releaseStub("key-1", 1, "default", rspb.StatusUninstalled),
+		releaseStub("key-2", 1, "default", rspb.StatusUninstalled),
+		releaseStub("key-3", 1, "default", rspb.StatusDeployed),
+		releaseStub("key-4", 1, "other", rspb.StatusDeployed),
+	}...)
+
+	del, err := o.List(func(rel *rspb.Release) bool {
+		return rel.Info.Status == rspb.StatusUninstalled
+	})
+	if err != nil {
+		t.Fatalf("Failed to list deleted: %s", err)
+	}
+	if len(del) != 2 {
+		t.Errorf("Expected 2 deleted, got %d", len(del))
+	}
+
+	// releases of other namespaces are not listed
+	dpl, err := o.List(func(rel *rspb.Release) bool {
+		return rel.Info.Status == rspb.StatusDeployed
+	})
+	if err != nil {
+		t.Fatalf("Failed to list deployed: %s", err)
+	}
+	if len(dpl) != 1 {
+		t.Fatalf("Expected 1 deployed, got %d", len(dpl))
+	}
+	if _, ok := dpl[0].Labels["name"]; !ok {
+		t.Errorf("Expected 'name' label in results, actual %v", dpl[0].Labels)
+	}
+
+	all := NewObjectStorage(o.store, "releases", "")
+	rls, err := all.List(func(_ *rspb.Release) bool { return true })
+	if err != nil {
+		t.Fatalf("Failed to list all namespaces: %s", err)
+	}
+	if len(rls) != 4 {
+		t.Errorf("Expected 4 releases in all namespaces, got %d", len(rls))
+	}
+}
+
+func TestObjectStorageQuery(t *testing.T) {
+	o := newTestFixtureObjectStorage(t, []*rspb.Release{
+		releaseStub("key-1", 1, "default", rspb.StatusUninstalled),
+		releaseStub("key-2", 1, "default", rspb.StatusDeployed),
+		releaseStub("key-3", 1, "default", rspb.StatusDeployed),
+	}...)
+
+	rls, err := o.Query(map[string]string{"status": "deployed"})
+	if err != nil {
+		t.Fatalf("Failed to query: %s", err)
+	}
+	if len(rls) != 2 {
+		t.Fatalf("Expected 2 results, actual %d", len(rls))
+	}
+
+	if _, err := o.Query(map[string]string{"name": "notExist"}); err != ErrReleaseNotFound {
+		t.Errorf("Expected {%v}, got {%v}", ErrReleaseNotFound, err)
+	}
+}
+
+func TestObjectStorageCreate(t *testing.T) {
+	o := newTestFixtureObjectStorage(t)
+
+	key := testKey("smug-pigeon", 1)
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+
+	if err := o.Create(key, rel); err != nil {
+		t.Fatalf("Failed to create release with key %q: %s", key, err)
+	}
+	if err := o.Create(key, rel); err != ErrReleaseExists {
+		t.Errorf("Expected {%v}, got {%v}", ErrReleaseExists, err)
+	}
+
+	got, err := o.Get(key)
+	if err != nil {
+		t.Fatalf("Failed to get release with key %q: %s", key, err)
+	}
+	if !reflect.DeepEqual(rel, got) {
+		t.Errorf("Expected {%v}, got {%v}", rel, got)
+	}
+}
+
+func TestObjectStorageUpdate(t *testing.T) {
+	key := testKey("smug-pigeon", 1)
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+
+	o := newTestFixtureObjectStorage(t, rel)
+
+	rel.Info.Status = rspb.StatusSuperseded
+	if err := o.Update(key, rel); err != nil {
+		t.Fatalf("Failed to update release: %s", err)
+	}
+	got, err := o.Get(key)
+	if err != nil {
+		t.Fatalf("Failed to get release with key %q: %s", key, err)
+	}
+	if rel.Info.Status != got.Info.Status {
+		t.Errorf("Expected status %s, got status %s", rel.Info.Status.String(), got.Info.Status.String())
+	}
+
+	if err := o.Update(testKey("nonexistent", 1), releaseStub("nonexistent", 1, "default", rspb.StatusDeployed)); err != ErrReleaseNotFound {
+		t.Errorf("Expected {%v}, got {%v}", ErrReleaseNotFound, err)
+	}
+}
+
+func TestObjectStorageConcurrentUpdate(t *testing.T) {
+	key := testKey("smug-pigeon", 1)
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+
+	o := newTestFixtureObjectStorage(t, rel)
+	o.store = &racingObjectStore{ObjectStore: o.store}
+
+	rel.Info.Status = rspb.StatusSuperseded
+	err := o.Update(key, rel)
+	if !errors.Is(err, ErrObjectPreconditionFailed) {
+		t.Errorf("Expected a concurrent update to fail with %v, got %v", ErrObjectPreconditionFailed, err)
+	}
+	if !errors.Is(err, ErrReleaseConflict) {
+		t.Errorf("Expected a concurrent update to fail with %v, got %v", ErrReleaseConflict, err)
+	}
+}
+
+func TestObjectStorageStaleUpdate(t *testing.T) {
+	key := testKey("smug-pigeon", 1)
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+
+	first := newTestFixtureObjectStorage(t, rel)
+	second := NewObjectStorage(first.store, first.prefix, "default")
+	if _, err := first.Get(key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := second.Get(key); err != nil {
+		t.Fatal(err)
+	}
+
+	rel.Info.Status = rspb.StatusSuperseded
+	if err := second.Update(key, rel); err != nil {
+		t.Fatalf("Failed to update release: %s", err)
+	}
+	if err := first.Update(key, rel); !errors.Is(err, ErrReleaseConflict) {
+		t.Errorf("Expected an update based on a stale read to fail with %v, got %v", ErrReleaseConflict, err)
+	}
+}
+
+func TestObjectStorageDelete(t *testing.T) {
+	key := testKey("smug-pigeon", 1)
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+
+	o := newTestFixtureObjectStorage(t, rel)
+
+	if _, err := o.Delete("nonexistent"); err != ErrReleaseNotFound {
+		t.Fatalf("Expected ErrReleaseNotFound, got: {%v}", err)
+	}
+
+	rls, err := o.Delete(key)
+	if err != nil {
+		t.Fatalf("Failed to delete release with key %q: %s", key, err)
+	}
+	if !reflect.DeepEqual(rel, rls) {
+		t.Errorf("Expected {%v}, got {%v}", rel, rls)
+	}
+	if _, err := o.Get(key); err != ErrReleaseNotFound {
+		t.Errorf("Expected {%v}, got {%v}", ErrReleaseNotFound, err)
+	}
+}
+
+func TestDirectoryObjectStore(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	d := NewDirectoryObjectStore(root)
+
+	if _, _, err := d.Get(ctx, "a/b"); err != ErrObjectNotFound {
+		t.Errorf("Expected {%v}, got {%v}", ErrObjectNotFound, err)
+	}
+
+	etag, err := d.Put(ctx, "a/b", []byte("one"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Put(ctx, "a/b", []byte("two"), ""); err != ErrObjectPreconditionFailed {
+		t.Errorf("Expected creating an existing object to fail, got %v", err)
+	}
+	if _, err := d.Put(ctx, "a/b", []byte("two"), "stale"); err != ErrObjectPreconditionFailed {
+		t.Errorf("Expected a write with a stale ETag to fail, got %v", err)
+	}
+	newETag, err := d.Put(ctx, "a/b", []byte("two"), etag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newETag == etag {
+		t.Error("Expected the ETag to change when the object is written")
+	}
+
+	data, got, err := d.Get(ctx, "a/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "two" || got != newETag {
+		t.Errorf("Expected content %q with ETag %q, got %q with %q", "two", newETag, data, got)
+	}
+
+	if _, err := d.Put(ctx, "a/c", []byte("three"), ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Put(ctx, "b/d", []byte("four"), ""); err != nil {
+		t.Fatal(err)
+	}
+	names, err := d.List(ctx, "a/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(names)
+	if !reflect.DeepEqual(names, []string{"a/b", "a/c"}) {
+		t.Errorf("Expected objects a/b and a/c, got %v", names)
+	}
+
+	if err := d.Delete(ctx, "a/b", etag); err != ErrObjectPreconditionFailed {
+		t.Errorf("Expected a delete with a stale ETag to fail, got %v", err)
+	}
+	if err := d.Delete(ctx, "a/b", newETag); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Delete(ctx, "a/b", newETag); err != ErrObjectNotFound {
+		t.Errorf("Expected {%v}, got {%v}", ErrObjectNotFound, err)
+	}
+
+	// names cannot escape the root directory
+	if _, err := d.Put(ctx, "../escaped", []byte("five"), ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "escaped")); err != nil {
+		t.Errorf("Expected the object to be stored in the root directory: %s", err)
+	}
+}
@@ -118,6 +118,9 @@ func newRecord(key string, rls *rspb.Release) *record {
 	lbs.set("owner", "helm")
 	lbs.set("status", rls.Info.Status.String())
 	lbs.set("version", strconv.Itoa(rls.Version))
+	if chart := chartNameLabelValue(rls); chart != "" {
+		lbs.set(ChartNameLabel, chart)
+	}
 
 	// return &record{key: key, lbs: lbs, rls: proto.Clone(rls).(*rspb.Release)}
 	return &record{key: key, lbs: lbs, rls: rls}
@@ -0,0 +1,492 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver // import "helm.sh/helm/v3/pkg/storage/driver"
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	kblabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+
+	rspb "helm.sh/helm/v3/pkg/release"
+)
+
+var _ Driver = (*ReleaseRecords)(nil)
+
+// ReleaseRecordsDriverName is the string name of the driver.
+const ReleaseRecordsDriverName = "HelmReleaseRecord"
+
+// ReleaseRecordGVR is the resource of the HelmReleaseRecord custom resource
+// used by the ReleaseRecords driver. The definition is ReleaseRecordCRD.
+var ReleaseRecordGVR = schema.GroupVersionResource{Group: "helm.sh", Version: "v1", Resource: "helmreleaserecords"}
+
+// ReleaseRecordCRD is the CustomResourceDefinition that must be installed in
+// the cluster before the ReleaseRecords driver can be used. It is installed by
+// EnsureReleaseRecordCRD, or can be installed by an administrator with:
+//
+//	helm release crd | kubectl apply -f -
+const ReleaseRecordCRD = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: helmreleaserecords.helm.sh
+spec:
+  group: helm.sh
+  names:
+    kind: HelmReleaseRecord
+    listKind: HelmReleaseRecordList
+    plural: helmreleaserecords
+    singular: helmreleaserecord
+  scope: Namespaced
+  versions:
+  - name: v1
+    served: true
+    storage: true
+    additionalPrinterColumns:
+    - name: Release
+      type: string
+      jsonPath: .metadata.labels.name
+    - name: Revision
+      type: string
+      jsonPath: .metadata.labels.version
+    - name: Status
+      type: string
+      jsonPath: .metadata.labels.status
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              release:
+                description: The base64 encoded, gzipped release, or its first part.
+                type: string
+              parts:
+                description: The number of spillover records holding the rest of the release.
+                type: integer
+              partsID:
+                description: The identifier of the spillover records holding the rest of the release.
+                type: string
+              part:
+                description: The index of a spillover record.
+                type: integer
+`
+
+// crdGVR is the resource of the CustomResourceDefinitions.
+var crdGVR = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+
+// releaseRecordCRDTimeout is how long EnsureReleaseRecordCRD waits for the
+// definition it installed to be established.
+var releaseRecordCRDTimeout = time.Minute
+
+// EnsureReleaseRecordCRD installs ReleaseRecordCRD in the cluster if it is not
+// installed, and waits for it to be established.
+//
+// It is not an error if the definitions cannot be read: the users allowed to
+// access the release records are rarely allowed to read the definitions, and
+// the definition is then assumed to be installed.
+func EnsureReleaseRecordCRD(client dynamic.Interface) error {
+	ctx := context.Background()
+	crds := client.Resource(crdGVR)
+	name := ReleaseRecordGVR.GroupResource().String()
+
+	_, err := crds.Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case err == nil || apierrors.IsForbidden(err):
+		return nil
+	case !apierrors.IsNotFound(err):
+		return errors.Wrap(err, "failed to get the HelmReleaseRecord CRD")
+	}
+
+	crd := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal([]byte(ReleaseRecordCRD), &crd.Object); err != nil {
+		return err
+	}
+	if _, err := crds.Create(ctx, crd, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		if apierrors.IsForbidden(err) {
+			return errors.Wrap(err, "the HelmReleaseRecord CRD is not installed and cannot be created, install it with 'helm release crd | kubectl apply -f -'")
+		}
+		return errors.Wrap(err, "failed to create the HelmReleaseRecord CRD")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, releaseRecordCRDTimeout)
+	defer cancel()
+	err = wait.PollUntilContextCancel(ctx, time.Second, true, func(ctx context.Context) (bool, error) {
+		crd, err := crds.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+		conditions, _, _ := unstructured.NestedSlice(crd.Object, "status", "conditions")
+		for _, c := range conditions {
+			if c, ok := c.(map[string]interface{}); ok && c["type"] == "Established" && c["status"] == "True" {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	return errors.Wrap(err, "the HelmReleaseRecord CRD was not established")
+}
+
+// ReleaseRecords stores releases as HelmReleaseRecord custom resources.
+//
+// Like with Secrets and ConfigMaps, the size of a release is not limited by
+// the size of a single object: releases that are too large are split across
+// spillover records. Because release records have their own resource type,
+// access to them can be granted with RBAC independently of Secrets, and they
+// can be watched by controllers.
+//
+// Updates are conditional on the resourceVersion of the record when the
+// release was read, and fail with a ConflictError if it was modified since.
+type ReleaseRecords struct {
+	impl     dynamic.ResourceInterface
+	versions versions
+	Log      func(string, ...interface{})
+
+	// Compression configures the compression of the stored releases.
+	Compression Compression
+	// Encryptor encrypts the stored releases if it is set.
+	Encryptor Encryptor
+}
+
+// NewReleaseRecords initializes a new ReleaseRecords driver wrapping a
+// dynamic client for the HelmReleaseRecord resource of a namespace.
+func NewReleaseRecords(impl dynamic.ResourceInterface) *ReleaseRecords {
+	return &ReleaseRecords{
+		impl: impl,
+		Log:  func(_ string, _ ...interface{}) {},
+	}
+}
+
+// Name returns the name of the driver.
+func (r *ReleaseRecords) Name() string {
+	return ReleaseRecordsDriverName
+}
+
+// Get fetches the release named by key. The corresponding release is returned
+// or error if not found.
+func (r *ReleaseRecords) Get(key string) (*rspb.Release, error) {
+	obj, err := r.impl.Get(context.Background(), key, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, ErrReleaseNotFound
+		}
+		return nil, errors.Wrapf(err, "get: failed to get %q", key)
+	}
+	rls, err := r.decode(obj)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get: failed to decode data %q", key)
+	}
+	r.versions.set(key, obj.GetResourceVersion())
+	rls.Labels = FilterSystemLabels(obj.GetLabels())
+	return rls, nil
+}
+
+// List fetches all releases and returns the list releases such
+// that filter(release) == true. An error is returned if the
+// records fail to be retrieved.
+func (r *ReleaseRecords) List(filter func(*rspb.Release) bool) ([]*rspb.Release, error) {
+	lsel := kblabels.Set{"owner": "helm"}.AsSelector()
+	list, err := r.impl.List(context.Background(), metav1.ListOptions{LabelSelector: lsel.String()})
+	if err != nil {
+		return nil, errors.Wrap(err, "list: failed to list")
+	}
+
+	var results []*rspb.Release
+	for i := range list.Items {
+		rls, err := r.decode(&list.Items[i])
+		if err != nil {
+			r.Log("list: failed to decode release: %s: %s", list.Items[i].GetName(), err)
+			continue
+		}
+		r.versions.set(list.Items[i].GetName(), list.Items[i].GetResourceVersion())
+		rls.Labels = list.Items[i].GetLabels()
+		if filter(rls) {
+			results = append(results, rls)
+		}
+	}
+	return results, nil
+}
+
+// Query fetches all releases that match the provided map of labels.
+// An error is returned if the records fail to be retrieved.
+func (r *ReleaseRecords) Query(labels map[string]string) ([]*rspb.Release, error) {
+	ls := kblabels.Set{}
+	for k, v := range labels {
+		if errs := validation.IsValidLabelValue(v); len(errs) != 0 {
+			return nil, errors.Errorf("invalid label value: %q: %s", v, strings.Join(errs, "; "))
+		}
+		ls[k] = v
+	}
+
+	list, err := r.impl.List(context.Background(), metav1.ListOptions{LabelSelector: ls.AsSelector().String()})
+	if err != nil {
+		return nil, errors.Wrap(err, "query: failed to query with labels")
+	}
+	if len(list.Items) == 0 {
+		return nil, ErrReleaseNotFound
+	}
+
+	var results []*rspb.Release
+	for i := range list.Items {
+		// Spillover records are only ever read through their main record.
+		if list.Items[i].GetLabels()["owner"] == spilloverOwner {
+			continue
+		}
+		rls, err := r.decode(&list.Items[i])
+		if err != nil {
+			r.Log("query: failed to decode release: %s", err)
+			continue
+		}
+		r.versions.set(list.Items[i].GetName(), list.Items[i].GetResourceVersion())
+		rls.Labels = list.Items[i].GetLabels()
+		results = append(results, rls)
+	}
+	return results, nil
+}
+
+// Create creates a new HelmReleaseRecord holding the release. If the record
+// already exists, ErrReleaseExists is returned.
+func (r *ReleaseRecords) Create(key string, rls *rspb.Release) error {
+	var lbs labels
+
+	lbs.init()
+	lbs.fromMap(rls.Labels)
+	lbs.set("createdAt", strconv.Itoa(int(time.Now().Unix())))
+
+	obj, parts, err := newReleaseRecordObjects(key, rls, lbs, r.Compression, r.Encryptor)
+	if err != nil {
+		return errors.Wrapf(err, "create: failed to encode release %q", rls.Name)
+	}
+	// The parts are written first so the main record never references parts
+	// that do not exist yet.
+	written, err := r.writeParts(parts)
+	if err != nil {
+		r.deleteParts(written)
+		return errors.Wrap(err, "create: failed to create spillover records")
+	}
+	created, err := r.impl.Create(context.Background(), obj, metav1.CreateOptions{})
+	if err != nil {
+		r.deleteParts(written)
+		if apierrors.IsAlreadyExists(err) {
+			return ErrReleaseExists
+		}
+		return errors.Wrap(err, "create: failed to create")
+	}
+	r.versions.set(key, created.GetResourceVersion())
+	return nil
+}
+
+// Update updates the HelmReleaseRecord holding the release.
+func (r *ReleaseRecords) Update(key string, rls *rspb.Release) error {
+	var lbs labels
+
+	lbs.init()
+	lbs.fromMap(rls.Labels)
+	lbs.set("modifiedAt", strconv.Itoa(int(time.Now().Unix())))
+
+	obj, parts, err := newReleaseRecordObjects(key, rls, lbs, r.Compression, r.Encryptor)
+	if err != nil {
+		return errors.Wrapf(err, "update: failed to encode release %q", rls.Name)
+	}
+
+	current, err := r.impl.Get(context.Background(), key, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrap(err, "update: failed to update")
+	}
+	// Conflicts are detected before the parts are written, so the parts of
+	// the other client are not overwritten.
+	if err := r.versions.check(key, current.GetResourceVersion()); err != nil {
+		return err
+	}
+	// Custom resources do not support unconditional updates, so releases
+	// that were not read are updated based on the current record.
+	obj.SetResourceVersion(current.GetResourceVersion())
+
+	// The parts are written first so the main record never references parts
+	// that do not exist yet. They are named after their content, so the parts
+	// referenced by the current record are left untouched.
+	written, err := r.writeParts(parts)
+	if err != nil {
+		r.deleteParts(written)
+		return errors.Wrap(err, "update: failed to update spillover records")
+	}
+	updated, err := r.impl.Update(context.Background(), obj, metav1.UpdateOptions{})
+	if err != nil {
+		r.deleteParts(written)
+		if apierrors.IsConflict(err) {
+			return &ConflictError{Key: key, Err: err}
+		}
+		return errors.Wrap(err, "update: failed to update")
+	}
+	r.versions.set(key, updated.GetResourceVersion())
+	r.deleteParts(staleParts(recordPartNames(current), recordPartNames(obj)))
+	return nil
+}
+
+// Delete deletes the HelmReleaseRecord holding the release named by key,
+// along with its spillover records.
+func (r *ReleaseRecords) Delete(key string) (rls *rspb.Release, err error) {
+	obj, err := r.impl.Get(context.Background(), key, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, ErrReleaseNotFound
+		}
+		return nil, errors.Wrapf(err, "delete: failed to get %q", key)
+	}
+	if rls, err = r.decode(obj); err != nil {
+		return nil, errors.Wrapf(err, "delete: failed to decode data %q", key)
+	}
+	rls.Labels = FilterSystemLabels(obj.GetLabels())
+
+	if err := r.impl.Delete(context.Background(), key, metav1.DeleteOptions{}); err != nil {
+		return rls, err
+	}
+	r.versions.set(key, "")
+	r.deleteParts(recordPartNames(obj))
+	return rls, nil
+}
+
+// decode reassembles and decodes the release held by a main record and its
+// spillover records.
+func (r *ReleaseRecords) decode(obj *unstructured.Unstructured) (*rspb.Release, error) {
+	data, _, err := unstructured.NestedString(obj.Object, "spec", "release")
+	if err != nil {
+		return nil, err
+	}
+	if names := recordPartNames(obj); len(names) > 0 {
+		var b strings.Builder
+		b.WriteString(data)
+		for i, name := range names {
+			part, err := r.impl.Get(context.Background(), name, metav1.GetOptions{})
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to get spillover record %d of %d", i+1, len(names))
+			}
+			s, _, err := unstructured.NestedString(part.Object, "spec", "release")
+			if err != nil {
+				return nil, err
+			}
+			b.WriteString(s)
+		}
+		data = b.String()
+	}
+	return openRelease(data, r.Encryptor)
+}
+
+// writeParts creates the spillover records, and returns the names of those it
+// created. The spillover records that already exist hold the same content,
+// as they are named after it, and are left untouched.
+func (r *ReleaseRecords) writeParts(parts []*unstructured.Unstructured) ([]string, error) {
+	var written []string
+	for _, part := range parts {
+		_, err := r.impl.Create(context.Background(), part, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			continue
+		}
+		if err != nil {
+			return written, err
+		}
+		written = append(written, part.GetName())
+	}
+	return written, nil
+}
+
+// deleteParts deletes the named spillover records. Failures are only logged:
+// a spillover record which is no longer referenced is never read.
+func (r *ReleaseRecords) deleteParts(names []string) {
+	for _, name := range names {
+		if err := r.impl.Delete(context.Background(), name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			r.Log("failed to delete spillover record %s: %s", name, err)
+		}
+	}
+}
+
+// recordPartNames returns the names of the spillover records of a main
+// record, in order.
+func recordPartNames(obj *unstructured.Unstructured) []string {
+	n, _, _ := unstructured.NestedInt64(obj.Object, "spec", "parts")
+	id, _, _ := unstructured.NestedString(obj.Object, "spec", "partsID")
+	return partNames(obj.GetName(), id, int(n))
+}
+
+// newReleaseRecordObjects constructs the HelmReleaseRecord objects that store
+// a release: the main record, and the spillover records holding the data that
+// does not fit into it.
+//
+// The main record uses the same labels as the Secrets driver, and spillover
+// records use spilloverLabels.
+func newReleaseRecordObjects(key string, rls *rspb.Release, lbs labels, comp Compression, enc Encryptor) (*unstructured.Unstructured, []*unstructured.Unstructured, error) {
+	const owner = "helm"
+
+	s, err := sealRelease(rls, comp, enc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	chunks := splitReleaseData(s)
+
+	if lbs == nil {
+		lbs.init()
+	}
+	lbs.fromMap(rls.Labels)
+	lbs.set("name", rls.Name)
+	lbs.set("owner", owner)
+	lbs.set("status", rls.Info.Status.String())
+	lbs.set("version", strconv.Itoa(rls.Version))
+	if chart := chartNameLabelValue(rls); chart != "" {
+		lbs.set(ChartNameLabel, chart)
+	}
+
+	spec := map[string]interface{}{
+		"release": chunks[0],
+		"parts":   int64(len(chunks) - 1),
+	}
+	var id string
+	if len(chunks) > 1 {
+		id = partsID(s)
+		spec["partsID"] = id
+	}
+	main := newReleaseRecord(key, lbs.toMap(), spec)
+
+	var parts []*unstructured.Unstructured
+	for i, chunk := range chunks[1:] {
+		parts = append(parts, newReleaseRecord(partName(key, id, i+1), spilloverLabels(rls), map[string]interface{}{
+			"release": chunk,
+			"part":    int64(i + 1),
+		}))
+	}
+	return main, parts, nil
+}
+
+func newReleaseRecord(name string, labels map[string]string, spec map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	obj.SetAPIVersion(ReleaseRecordGVR.GroupVersion().String())
+	obj.SetKind("HelmReleaseRecord")
+	obj.SetName(name)
+	obj.SetLabels(labels)
+	return obj
+}
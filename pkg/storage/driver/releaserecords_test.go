@@ -0,0 +1,332 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	rspb "helm.sh/helm/v3/pkg/release"
+)
+
+func newTestFixtureReleaseRecords(t *testing.T, releases ...*rspb.Release) (*ReleaseRecords, dynamic.ResourceInterface) {
+	t.Helper()
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		ReleaseRecordGVR: "HelmReleaseRecordList",
+	})
+	impl := client.Resource(ReleaseRecordGVR).Namespace("default")
+	records := NewReleaseRecords(impl)
+	for _, rls := range releases {
+		if err := records.Create(testKey(rls.Name, rls.Version), rls); err != nil {
+			t.Fatalf("failed to create release %s: %s", rls.Name, err)
+		}
+	}
+	return records, impl
+}
+
+// withMaxRecordDataSize lowers the size of the data stored per record so that
+// every release spills over into several records.
+func withMaxRecordDataSize(t *testing.T, size int) {
+	t.Helper()
+	old := maxRecordDataSize
+	maxRecordDataSize = size
+	t.Cleanup(func() { maxRecordDataSize = old })
+}
+
+func countRecords(t *testing.T, impl dynamic.ResourceInterface) int {
+	t.Helper()
+	list, err := impl.List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(list.Items)
+}
+
+func TestReleaseRecordsName(t *testing.T) {
+	records, _ := newTestFixtureReleaseRecords(t)
+	if records.Name() != ReleaseRecordsDriverName {
+		t.Errorf("Expected name to be %q, got %q", ReleaseRecordsDriverName, records.Name())
+	}
+}
+
+func TestReleaseRecordsGet(t *testing.T) {
+	vers := 1
+	name := "smug-pigeon"
+	namespace := "default"
+	key := testKey(name, vers)
+	rel := releaseStub(name, vers, namespace, rspb.StatusDeployed)
+
+	records, _ := newTestFixtureReleaseRecords(t, rel)
+
+	got, err := records.Get(key)
+	if err != nil {
+		t.Fatalf("Failed to get release: %s", err)
+	}
+	if !reflect.DeepEqual(rel, got) {
+		t.Errorf("Expected release {%v}, got {%v}", rel, got)
+	}
+
+	if _, err := records.Get("nonexistent"); err != ErrReleaseNotFound {
+		t.Errorf("Expected {%v}, got {%v}", ErrReleaseNotFound, err)
+	}
+}
+
+func TestReleaseRecordsList(t *testing.T) {
+	withMaxRecordDataSize(t, 16)
+
+	records, _ := newTestFixtureReleaseRecords(t, []*rspb.Release{
+		releaseStub("key-1", 1, "default", rspb.StatusUninstalled),
+		releaseStub("key-2", 1, "default", rspb.StatusUninstalled),
+		releaseStub("key-3", 1, "default", rspb.StatusDeployed),
+		releaseStub("key-4", 1, "default", rspb.StatusSuperseded),
+	}...)
+
+	// spillover records must not be listed as releases
+	all, err := records.List(func(_ *rspb.Release) bool { return true })
+	if err != nil {
+		t.Fatalf("Failed to list: %s", err)
+	}
+	if len(all) != 4 {
+		t.Errorf("Expected 4 releases, got %d", len(all))
+	}
+
+	del, err := records.List(func(rel *rspb.Release) bool {
+		return rel.Info.Status == rspb.StatusUninstalled
+	})
+	if err != nil {
+		t.Fatalf("Failed to list deleted: %s", err)
+	}
+	if len(del) != 2 {
+		t.Errorf("Expected 2 deleted, got %d", len(del))
+	}
+	if _, ok := del[0].Labels["key1"]; !ok {
+		t.Errorf("Expected 'key1' label in results, actual %v", del[0].Labels)
+	}
+}
+
+func TestReleaseRecordsQuery(t *testing.T) {
+	withMaxRecordDataSize(t, 16)
+
+	records, _ := newTestFixtureReleaseRecords(t, []*rspb.Release{
+		releaseStub("key-1", 1, "default", rspb.StatusUninstalled),
+		releaseStub("key-2", 1, "default", rspb.StatusDeployed),
+		releaseStub("key-3", 1, "default", rspb.StatusDeployed),
+	}...)
+
+	rls, err := records.Query(map[string]string{"status": "deployed"})
+	if err != nil {
+		t.Fatalf("Failed to query: %s", err)
+	}
+	if len(rls) != 2 {
+		t.Fatalf("Expected 2 results, actual %d", len(rls))
+	}
+
+	rls, err = records.Query(map[string]string{"name": "key-1"})
+	if err != nil {
+		t.Fatalf("Failed to query: %s", err)
+	}
+	if len(rls) != 1 {
+		t.Fatalf("Expected 1 result, actual %d", len(rls))
+	}
+
+	if _, err := records.Query(map[string]string{"name": "notExist"}); err != ErrReleaseNotFound {
+		t.Errorf("Expected {%v}, got {%v}", ErrReleaseNotFound, err)
+	}
+}
+
+func TestReleaseRecordsCreate(t *testing.T) {
+	records, _ := newTestFixtureReleaseRecords(t)
+
+	key := testKey("smug-pigeon", 1)
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+
+	if err := records.Create(key, rel); err != nil {
+		t.Fatalf("Failed to create release with key %q: %s", key, err)
+	}
+	if err := records.Create(key, rel); err != ErrReleaseExists {
+		t.Errorf("Expected {%v}, got {%v}", ErrReleaseExists, err)
+	}
+
+	got, err := records.Get(key)
+	if err != nil {
+		t.Fatalf("Failed to get release with key %q: %s", key, err)
+	}
+	if !reflect.DeepEqual(rel, got) {
+		t.Errorf("Expected {%v}, got {%v}", rel, got)
+	}
+}
+
+func TestReleaseRecordsSpillover(t *testing.T) {
+	withMaxRecordDataSize(t, 64)
+
+	key := testKey("smug-pigeon", 1)
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+	rel.Manifest = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: smug-pigeon\n"
+
+	records, impl := newTestFixtureReleaseRecords(t, rel)
+
+	created := countRecords(t, impl)
+	if created < 3 {
+		t.Fatalf("Expected the release to be split across several records, got %d", created)
+	}
+
+	got, err := records.Get(key)
+	if err != nil {
+		t.Fatalf("Failed to get release with key %q: %s", key, err)
+	}
+	if !reflect.DeepEqual(rel, got) {
+		t.Errorf("Expected {%v}, got {%v}", rel, got)
+	}
+
+	// shrinking the release removes the spillover records that are no longer needed
+	rel.Manifest = ""
+	rel.Info.Status = rspb.StatusSuperseded
+	if err := records.Update(key, rel); err != nil {
+		t.Fatalf("Failed to update release: %s", err)
+	}
+	if n := countRecords(t, impl); n >= created {
+		t.Errorf("Expected fewer than %d records after update, got %d", created, n)
+	}
+	got, err = records.Get(key)
+	if err != nil {
+		t.Fatalf("Failed to get release with key %q: %s", key, err)
+	}
+	if !reflect.DeepEqual(rel, got) {
+		t.Errorf("Expected {%v}, got {%v}", rel, got)
+	}
+
+	if _, err := records.Delete(key); err != nil {
+		t.Fatalf("Failed to delete release with key %q: %s", key, err)
+	}
+	if n := countRecords(t, impl); n != 0 {
+		t.Errorf("Expected all records to be deleted, got %d", n)
+	}
+}
+
+func TestReleaseRecordsUpdate(t *testing.T) {
+	key := testKey("smug-pigeon", 1)
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+
+	records, _ := newTestFixtureReleaseRecords(t, rel)
+
+	rel.Info.Status = rspb.StatusSuperseded
+	if err := records.Update(key, rel); err != nil {
+		t.Fatalf("Failed to update release: %s", err)
+	}
+
+	got, err := records.Get(key)
+	if err != nil {
+		t.Fatalf("Failed to get release with key %q: %s", key, err)
+	}
+	if rel.Info.Status != got.Info.Status {
+		t.Errorf("Expected status %s, got status %s", rel.Info.Status.String(), got.Info.Status.String())
+	}
+}
+
+func TestReleaseRecordsDelete(t *testing.T) {
+	key := testKey("smug-pigeon", 1)
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+
+	records, _ := newTestFixtureReleaseRecords(t, rel)
+
+	if _, err := records.Delete("nonexistent"); err != ErrReleaseNotFound {
+		t.Fatalf("Expected ErrReleaseNotFound, got: {%v}", err)
+	}
+
+	rls, err := records.Delete(key)
+	if err != nil {
+		t.Fatalf("Failed to delete release with key %q: %s", key, err)
+	}
+	if !reflect.DeepEqual(rel, rls) {
+		t.Errorf("Expected {%v}, got {%v}", rel, rls)
+	}
+
+	if _, err := records.Get(key); err != ErrReleaseNotFound {
+		t.Errorf("Expected {%v}, got {%v}", ErrReleaseNotFound, err)
+	}
+}
+
+func TestEnsureReleaseRecordCRD(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		crdGVR: "CustomResourceDefinitionList",
+	})
+	// The API server establishes the definitions it creates
+	client.PrependReactor("create", "customresourcedefinitions", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		crd := action.(k8stesting.CreateAction).GetObject().(*unstructured.Unstructured)
+		conditions := []interface{}{map[string]interface{}{"type": "Established", "status": "True"}}
+		return false, nil, unstructured.SetNestedSlice(crd.Object, conditions, "status", "conditions")
+	})
+
+	if err := EnsureReleaseRecordCRD(client); err != nil {
+		t.Fatal(err)
+	}
+	crd, err := client.Resource(crdGVR).Get(context.Background(), "helmreleaserecords.helm.sh", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected the CRD to be installed: %s", err)
+	}
+	if group, _, _ := unstructured.NestedString(crd.Object, "spec", "group"); group != ReleaseRecordGVR.Group {
+		t.Errorf("expected the CRD of group %q, got %q", ReleaseRecordGVR.Group, group)
+	}
+
+	// An installed definition is left untouched
+	if err := EnsureReleaseRecordCRD(client); err != nil {
+		t.Fatal(err)
+	}
+	creates := 0
+	for _, a := range client.Actions() {
+		if a.GetVerb() == "create" {
+			creates++
+		}
+	}
+	if creates != 1 {
+		t.Errorf("expected the CRD to be created once, got %d creations", creates)
+	}
+}
+
+func TestEnsureReleaseRecordCRDForbidden(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		crdGVR: "CustomResourceDefinitionList",
+	})
+	forbidden := apierrors.NewForbidden(crdGVR.GroupResource(), "helmreleaserecords.helm.sh", errors.New("no access"))
+	client.PrependReactor("create", "customresourcedefinitions", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, forbidden
+	})
+	err := EnsureReleaseRecordCRD(client)
+	if err == nil || !strings.Contains(err.Error(), "helm release crd") {
+		t.Errorf("expected an error telling how to install the CRD, got %v", err)
+	}
+
+	// The definition is assumed to be installed if it cannot be read
+	client.PrependReactor("get", "customresourcedefinitions", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, forbidden
+	})
+	if err := EnsureReleaseRecordCRD(client); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
@@ -34,15 +34,25 @@ import (
 )
 
 var _ Driver = (*Secrets)(nil)
+var _ Pager = (*Secrets)(nil)
 
 // SecretsDriverName is the string name of the driver.
 const SecretsDriverName = "Secret"
 
 // Secrets is a wrapper around an implementation of a kubernetes
 // SecretsInterface.
+//
+// Updates are conditional on the resourceVersion of the secret when the
+// release was read, and fail with a ConflictError if it was modified since.
 type Secrets struct {
-	impl corev1.SecretInterface
-	Log  func(string, ...interface{})
+	impl     corev1.SecretInterface
+	versions versions
+	Log      func(string, ...interface{})
+
+	// Compression configures the compression of the stored releases.
+	Compression Compression
+	// Encryptor encrypts the stored releases if it is set.
+	Encryptor Encryptor
 }
 
 // NewSecrets initializes a new Secrets wrapping an implementation of
@@ -71,9 +81,13 @@ func (secrets *Secrets) Get(key string) (*rspb.Release, error) {
 		return nil, errors.Wrapf(err, "get: failed to get %q", key)
 	}
 	// found the secret, decode the base64 data string
-	r, err := decodeRelease(string(obj.Data["release"]))
-	r.Labels = filterSystemLabels(obj.ObjectMeta.Labels)
-	return r, errors.Wrapf(err, "get: failed to decode data %q", key)
+	r, err := secrets.decode(obj)
+	if err != nil {
+		return nil, errors.Wrapf(err, "get: failed to decode data %q", key)
+	}
+	secrets.versions.set(key, obj.ObjectMeta.ResourceVersion)
+	r.Labels = FilterSystemLabels(obj.ObjectMeta.Labels)
+	return r, nil
 }
 
 // List fetches all releases and returns the list releases such
@@ -92,12 +106,14 @@ func (secrets *Secrets) List(filter func(*rspb.Release) bool) ([]*rspb.Release,
 
 	// iterate over the secrets object list
 	// and decode each release
-	for _, item := range list.Items {
-		rls, err := decodeRelease(string(item.Data["release"]))
+	for i := range list.Items {
+		item := &list.Items[i]
+		rls, err := secrets.decode(item)
 		if err != nil {
 			secrets.Log("list: failed to decode release: %v: %s", item, err)
 			continue
 		}
+		secrets.versions.set(item.ObjectMeta.Name, item.ObjectMeta.ResourceVersion)
 
 		rls.Labels = item.ObjectMeta.Labels
 
@@ -108,6 +124,38 @@ func (secrets *Secrets) List(filter func(*rspb.Release) bool) ([]*rspb.Release,
 	return results, nil
 }
 
+// ListPage fetches the releases selected by opts, with the label selector,
+// limit and continue token of the Secret list request.
+func (secrets *Secrets) ListPage(opts ListOptions) ([]*rspb.Release, string, error) {
+	sel, err := listSelector(opts)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "list: invalid selector")
+	}
+
+	list, err := secrets.impl.List(context.Background(), metav1.ListOptions{
+		LabelSelector: sel.String(),
+		Limit:         opts.Limit,
+		Continue:      opts.Continue,
+	})
+	if err != nil {
+		return nil, "", errors.Wrap(err, "list: failed to list")
+	}
+
+	var results []*rspb.Release
+	for i := range list.Items {
+		item := &list.Items[i]
+		rls, err := secrets.decode(item)
+		if err != nil {
+			secrets.Log("list: failed to decode release: %s: %s", item.ObjectMeta.Name, err)
+			continue
+		}
+		secrets.versions.set(item.ObjectMeta.Name, item.ObjectMeta.ResourceVersion)
+		rls.Labels = item.ObjectMeta.Labels
+		results = append(results, rls)
+	}
+	return results, list.Continue, nil
+}
+
 // Query fetches all releases that match the provided map of labels.
 // An error is returned if the secret fails to retrieve the releases.
 func (secrets *Secrets) Query(labels map[string]string) ([]*rspb.Release, error) {
@@ -131,12 +179,18 @@ func (secrets *Secrets) Query(labels map[string]string) ([]*rspb.Release, error)
 	}
 
 	var results []*rspb.Release
-	for _, item := range list.Items {
-		rls, err := decodeRelease(string(item.Data["release"]))
+	for i := range list.Items {
+		item := &list.Items[i]
+		// Spillover secrets are only ever read through their main secret.
+		if item.ObjectMeta.Labels["owner"] == spilloverOwner {
+			continue
+		}
+		rls, err := secrets.decode(item)
 		if err != nil {
 			secrets.Log("query: failed to decode release: %s", err)
 			continue
 		}
+		secrets.versions.set(item.ObjectMeta.Name, item.ObjectMeta.ResourceVersion)
 		rls.Labels = item.ObjectMeta.Labels
 		results = append(results, rls)
 	}
@@ -154,18 +208,28 @@ func (secrets *Secrets) Create(key string, rls *rspb.Release) error {
 	lbs.set("createdAt", strconv.Itoa(int(time.Now().Unix())))
 
 	// create a new secret to hold the release
-	obj, err := newSecretsObject(key, rls, lbs)
+	obj, parts, err := newSecretsObject(key, rls, lbs, secrets.Compression, secrets.Encryptor)
 	if err != nil {
 		return errors.Wrapf(err, "create: failed to encode release %q", rls.Name)
 	}
+	// The parts are written first so the main secret never references parts
+	// that do not exist yet.
+	written, err := secrets.writeParts(parts)
+	if err != nil {
+		secrets.deleteParts(written)
+		return errors.Wrap(err, "create: failed to create spillover secrets")
+	}
 	// push the secret object out into the kubiverse
-	if _, err := secrets.impl.Create(context.Background(), obj, metav1.CreateOptions{}); err != nil {
+	created, err := secrets.impl.Create(context.Background(), obj, metav1.CreateOptions{})
+	if err != nil {
+		secrets.deleteParts(written)
 		if apierrors.IsAlreadyExists(err) {
 			return ErrReleaseExists
 		}
 
 		return errors.Wrap(err, "create: failed to create")
 	}
+	secrets.versions.set(key, created.ObjectMeta.ResourceVersion)
 	return nil
 }
 
@@ -180,30 +244,136 @@ func (secrets *Secrets) Update(key string, rls *rspb.Release) error {
 	lbs.set("modifiedAt", strconv.Itoa(int(time.Now().Unix())))
 
 	// create a new secret object to hold the release
-	obj, err := newSecretsObject(key, rls, lbs)
+	obj, parts, err := newSecretsObject(key, rls, lbs, secrets.Compression, secrets.Encryptor)
 	if err != nil {
 		return errors.Wrapf(err, "update: failed to encode release %q", rls.Name)
 	}
+	// the parts of the current secret, to delete those that are no longer
+	// referenced
+	var currentParts []string
+	current, err := secrets.impl.Get(context.Background(), key, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		// Conflicts are detected before the parts are written, so the parts
+		// of the other client are not overwritten.
+		if err := secrets.versions.check(key, current.ObjectMeta.ResourceVersion); err != nil {
+			return err
+		}
+		currentParts = secretPartNames(current)
+	case !apierrors.IsNotFound(err):
+		return errors.Wrapf(err, "update: failed to get current secret")
+	}
+	obj.ObjectMeta.ResourceVersion = secrets.versions.get(key)
+	// The parts are written first so the main secret never references parts
+	// that do not exist yet. They are named after their content, so the
+	// parts referenced by the current secret are left untouched.
+	written, err := secrets.writeParts(parts)
+	if err != nil {
+		secrets.deleteParts(written)
+		return errors.Wrap(err, "update: failed to update spillover secrets")
+	}
 	// push the secret object out into the kubiverse
-	_, err = secrets.impl.Update(context.Background(), obj, metav1.UpdateOptions{})
-	return errors.Wrap(err, "update: failed to update")
+	updated, err := secrets.impl.Update(context.Background(), obj, metav1.UpdateOptions{})
+	if err != nil {
+		secrets.deleteParts(written)
+		if apierrors.IsConflict(err) {
+			return &ConflictError{Key: key, Err: err}
+		}
+		return errors.Wrap(err, "update: failed to update")
+	}
+	secrets.versions.set(key, updated.ObjectMeta.ResourceVersion)
+	secrets.deleteParts(staleParts(currentParts, secretPartNames(obj)))
+	return nil
 }
 
-// Delete deletes the Secret holding the release named by key.
+// Delete deletes the Secret holding the release named by key, along with its
+// spillover secrets.
 func (secrets *Secrets) Delete(key string) (rls *rspb.Release, err error) {
 	// fetch the release to check existence
-	if rls, err = secrets.Get(key); err != nil {
-		return nil, err
+	obj, err := secrets.impl.Get(context.Background(), key, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, ErrReleaseNotFound
+		}
+		return nil, errors.Wrapf(err, "get: failed to get %q", key)
 	}
+	if rls, err = secrets.decode(obj); err != nil {
+		return nil, errors.Wrapf(err, "get: failed to decode data %q", key)
+	}
+	rls.Labels = FilterSystemLabels(obj.ObjectMeta.Labels)
 	// delete the release
-	err = secrets.impl.Delete(context.Background(), key, metav1.DeleteOptions{})
-	return rls, err
+	if err = secrets.impl.Delete(context.Background(), key, metav1.DeleteOptions{}); err != nil {
+		return rls, err
+	}
+	secrets.versions.set(key, "")
+	secrets.deleteParts(secretPartNames(obj))
+	return rls, nil
+}
+
+// decode reassembles and decodes the release held by a main secret and its
+// spillover secrets.
+func (secrets *Secrets) decode(obj *v1.Secret) (*rspb.Release, error) {
+	data := string(obj.Data["release"])
+	if names := secretPartNames(obj); len(names) > 0 {
+		var b strings.Builder
+		b.WriteString(data)
+		for i, name := range names {
+			part, err := secrets.impl.Get(context.Background(), name, metav1.GetOptions{})
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to get spillover secret %d of %d", i+1, len(names))
+			}
+			b.Write(part.Data["release"])
+		}
+		data = b.String()
+	}
+	return openRelease(data, secrets.Encryptor)
+}
+
+// writeParts creates the spillover secrets, and returns the names of those it
+// created. The spillover secrets that already exist hold the same content,
+// as they are named after it, and are left untouched.
+func (secrets *Secrets) writeParts(parts []*v1.Secret) ([]string, error) {
+	var written []string
+	for _, part := range parts {
+		_, err := secrets.impl.Create(context.Background(), part, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			continue
+		}
+		if err != nil {
+			return written, err
+		}
+		written = append(written, part.ObjectMeta.Name)
+	}
+	return written, nil
+}
+
+// deleteParts deletes the named spillover secrets. Failures are only logged:
+// a spillover secret which is no longer referenced is never read.
+func (secrets *Secrets) deleteParts(names []string) {
+	for _, name := range names {
+		if err := secrets.impl.Delete(context.Background(), name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			secrets.Log("failed to delete spillover secret %s: %s", name, err)
+		}
+	}
+}
+
+// secretPartNames returns the names of the spillover secrets of a main
+// secret, in order.
+func secretPartNames(obj *v1.Secret) []string {
+	n, _ := strconv.Atoi(string(obj.Data["parts"]))
+	return partNames(obj.ObjectMeta.Name, string(obj.Data["partsID"]), n)
 }
 
 // newSecretsObject constructs a kubernetes Secret object
 // to store a release. Each secret data entry is the base64
 // encoded gzipped string of a release.
 //
+// If the release does not fit into a single Secret, the data of the returned
+// Secret holds its first part, the number of spillover Secrets under the
+// "parts" key and their identifier under the "partsID" key, and the spillover
+// Secrets holding the rest of the release are returned too. They are named
+// "<key>.p<i>-<id>" and labeled with spilloverLabels.
+//
 // The following labels are used within each secret:
 //
 //	"modifiedAt"    - timestamp indicating when this secret was last modified. (set in Update)
@@ -212,14 +382,15 @@ func (secrets *Secrets) Delete(key string) (rls *rspb.Release, err error) {
 //	"status"         - status of the release (see pkg/release/status.go for variants)
 //	"owner"          - owner of the secret, currently "helm".
 //	"name"           - name of the release.
-func newSecretsObject(key string, rls *rspb.Release, lbs labels) (*v1.Secret, error) {
+func newSecretsObject(key string, rls *rspb.Release, lbs labels, comp Compression, enc Encryptor) (*v1.Secret, []*v1.Secret, error) {
 	const owner = "helm"
 
 	// encode the release
-	s, err := encodeRelease(rls)
+	s, err := sealRelease(rls, comp, enc)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	chunks := splitReleaseData(s)
 
 	if lbs == nil {
 		lbs.init()
@@ -233,6 +404,9 @@ func newSecretsObject(key string, rls *rspb.Release, lbs labels) (*v1.Secret, er
 	lbs.set("owner", owner)
 	lbs.set("status", rls.Info.Status.String())
 	lbs.set("version", strconv.Itoa(rls.Version))
+	if chart := chartNameLabelValue(rls); chart != "" {
+		lbs.set(ChartNameLabel, chart)
+	}
 
 	// create and return secret object.
 	// Helm 3 introduced setting the 'Type' field
@@ -245,12 +419,31 @@ func newSecretsObject(key string, rls *rspb.Release, lbs labels) (*v1.Secret, er
 	// metadata is modified.
 	// This would potentially be a breaking change
 	// and should only happen between major versions.
-	return &v1.Secret{
+	obj := &v1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:   key,
 			Labels: lbs.toMap(),
 		},
 		Type: "helm.sh/release.v1",
-		Data: map[string][]byte{"release": []byte(s)},
-	}, nil
+		Data: map[string][]byte{"release": []byte(chunks[0])},
+	}
+	if len(chunks) == 1 {
+		return obj, nil, nil
+	}
+	id := partsID(s)
+	obj.Data["parts"] = []byte(strconv.Itoa(len(chunks) - 1))
+	obj.Data["partsID"] = []byte(id)
+
+	var parts []*v1.Secret
+	for i, chunk := range chunks[1:] {
+		parts = append(parts, &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   partName(key, id, i+1),
+				Labels: spilloverLabels(rls),
+			},
+			Type: "helm.sh/release.v1",
+			Data: map[string][]byte{"release": []byte(chunk)},
+		})
+	}
+	return obj, parts, nil
 }
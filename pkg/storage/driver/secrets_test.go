@@ -14,12 +14,18 @@ limitations under the License.
 package driver
 
 import (
+	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"reflect"
 	"testing"
 
+	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kblabels "k8s.io/apimachinery/pkg/labels"
 
 	rspb "helm.sh/helm/v3/pkg/release"
 )
@@ -59,7 +65,7 @@ func TestUNcompressedSecretGet(t *testing.T) {
 	rel := releaseStub(name, vers, namespace, rspb.StatusDeployed)
 
 	// Create a test fixture which contains an uncompressed release
-	secret, err := newSecretsObject(key, rel, nil)
+	secret, _, err := newSecretsObject(key, rel, nil, Compression{}, nil)
 	if err != nil {
 		t.Fatalf("Failed to create secret: %s", err)
 	}
@@ -190,6 +196,46 @@ func TestSecretCreate(t *testing.T) {
 	}
 }
 
+func TestSecretCreateWithAnnotations(t *testing.T) {
+	secrets := newTestFixtureSecrets(t)
+
+	key := testKey("smug-pigeon", 1)
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+	rel.Annotations = map[string]string{"example.com/owner": "team-a"}
+
+	if err := secrets.Create(key, rel); err != nil {
+		t.Fatalf("Failed to create release with key %q: %s", key, err)
+	}
+
+	got, err := secrets.Get(key)
+	if err != nil {
+		t.Fatalf("Failed to get release with key %q: %s", key, err)
+	}
+	if !reflect.DeepEqual(rel.Annotations, got.Annotations) {
+		t.Errorf("Expected annotations %v, got %v", rel.Annotations, got.Annotations)
+	}
+}
+
+func TestSecretCreateWithChartNameCustomLabel(t *testing.T) {
+	secrets := newTestFixtureSecrets(t)
+
+	key := testKey("smug-pigeon", 1)
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+	rel.Labels = map[string]string{"chartName": "custom"}
+
+	if err := secrets.Create(key, rel); err != nil {
+		t.Fatalf("Failed to create release with key %q: %s", key, err)
+	}
+
+	got, err := secrets.Get(key)
+	if err != nil {
+		t.Fatalf("Failed to get release with key %q: %s", key, err)
+	}
+	if got.Labels["chartName"] != "custom" {
+		t.Errorf("Expected the custom chartName label to be kept, got %v", got.Labels)
+	}
+}
+
 func TestSecretUpdate(t *testing.T) {
 	vers := 1
 	name := "smug-pigeon"
@@ -219,6 +265,79 @@ func TestSecretUpdate(t *testing.T) {
 	}
 }
 
+func TestSecretUpdateGetError(t *testing.T) {
+	key := testKey("smug-pigeon", 1)
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+
+	var mock MockSecretsInterface
+	mock.Init(t, rel)
+	secrets := NewSecrets(&mock)
+	if _, err := secrets.Get(key); err != nil {
+		t.Fatal(err)
+	}
+
+	// the update cannot be checked for conflicts, so it is not applied
+	mock.getErr = errors.New("etcd unavailable")
+	rel.Info.Status = rspb.StatusSuperseded
+	if err := secrets.Update(key, rel); err == nil {
+		t.Fatal("Expected the update to fail")
+	}
+	mock.getErr = nil
+	if got, _ := secrets.Get(key); got.Info.Status != rspb.StatusDeployed {
+		t.Errorf("Expected the update not to be applied, got status %s", got.Info.Status)
+	}
+}
+
+func TestSecretUpdateConflict(t *testing.T) {
+	key := testKey("smug-pigeon", 1)
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+
+	var mock MockSecretsInterface
+	mock.Init(t, rel)
+	first, second := NewSecrets(&mock), NewSecrets(&mock)
+
+	// both clients read the release, then the second one updates it
+	for _, d := range []*Secrets{first, second} {
+		if _, err := d.Get(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rel.Info.Status = rspb.StatusSuperseded
+	if err := second.Update(key, rel); err != nil {
+		t.Fatalf("Failed to update release: %s", err)
+	}
+
+	// the update of the first client is based on a stale read
+	rel.Info.Status = rspb.StatusFailed
+	err := first.Update(key, rel)
+	if !errors.Is(err, ErrReleaseConflict) {
+		t.Fatalf("Expected %v, got %v", ErrReleaseConflict, err)
+	}
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || conflict.Key != key {
+		t.Errorf("Expected a ConflictError for %q, got %v", key, err)
+	}
+	if got, _ := second.Get(key); got.Info.Status != rspb.StatusSuperseded {
+		t.Errorf("Expected the conflicting update not to be applied, got status %s", got.Info.Status)
+	}
+
+	// it succeeds once the release is read again, and the driver follows its
+	// own updates
+	if _, err := first.Get(key); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := first.Update(key, rel); err != nil {
+			t.Fatalf("Failed to update release: %s", err)
+		}
+	}
+
+	// releases that were not read are updated unconditionally
+	if err := NewSecrets(&mock).Update(key, rel); err != nil {
+		t.Fatalf("Failed to update release: %s", err)
+	}
+}
+
 func TestSecretDelete(t *testing.T) {
 	vers := 1
 	name := "smug-pigeon"
@@ -249,3 +368,203 @@ func TestSecretDelete(t *testing.T) {
 		t.Errorf("Expected {%v}, got {%v}", ErrReleaseNotFound, err)
 	}
 }
+
+func TestSecretEncryption(t *testing.T) {
+	key := testKey("smug-pigeon", 1)
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+	rel.Manifest = "password: hunter2"
+
+	secrets := newTestFixtureSecrets(t)
+	secrets.Encryptor = newTestEncryptor(t, 1)
+
+	if err := secrets.Create(key, rel); err != nil {
+		t.Fatalf("Failed to create release with key %q: %s", key, err)
+	}
+
+	obj, err := secrets.impl.Get(context.Background(), key, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := b64.DecodeString(string(obj.Data["release"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, magicEncrypted) {
+		t.Error("Expected the release to be stored encrypted")
+	}
+
+	got, err := secrets.Get(key)
+	if err != nil {
+		t.Fatalf("Failed to get release with key %q: %s", key, err)
+	}
+	if !reflect.DeepEqual(rel, got) {
+		t.Errorf("Expected {%v}, got {%v}", rel, got)
+	}
+}
+
+func TestSecretSpillover(t *testing.T) {
+	withMaxRecordDataSize(t, 64)
+
+	key := testKey("smug-pigeon", 1)
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+	rel.Manifest = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: smug-pigeon\n"
+
+	secrets := newTestFixtureSecrets(t)
+	mock := secrets.impl.(*MockSecretsInterface)
+	if err := secrets.Create(key, rel); err != nil {
+		t.Fatalf("Failed to create release with key %q: %s", key, err)
+	}
+
+	created := len(mock.objects)
+	if created < 3 {
+		t.Fatalf("Expected the release to be split across several secrets, got %d", created)
+	}
+
+	got, err := secrets.Get(key)
+	if err != nil {
+		t.Fatalf("Failed to get release with key %q: %s", key, err)
+	}
+	if !reflect.DeepEqual(rel, got) {
+		t.Errorf("Expected {%v}, got {%v}", rel, got)
+	}
+
+	// spillover secrets must not be returned as releases
+	all, err := secrets.List(func(_ *rspb.Release) bool { return true })
+	if err != nil {
+		t.Fatalf("Failed to list: %s", err)
+	}
+	if len(all) != 1 {
+		t.Errorf("Expected 1 release, got %d", len(all))
+	}
+	rls, err := secrets.Query(map[string]string{"name": "smug-pigeon"})
+	if err != nil {
+		t.Fatalf("Failed to query: %s", err)
+	}
+	if len(rls) != 1 {
+		t.Errorf("Expected 1 release, got %d", len(rls))
+	}
+
+	// shrinking the release removes the spillover secrets that are no longer needed
+	rel.Manifest = ""
+	rel.Info.Status = rspb.StatusSuperseded
+	if err := secrets.Update(key, rel); err != nil {
+		t.Fatalf("Failed to update release: %s", err)
+	}
+	if n := len(mock.objects); n >= created {
+		t.Errorf("Expected fewer than %d secrets after update, got %d", created, n)
+	}
+	got, err = secrets.Get(key)
+	if err != nil {
+		t.Fatalf("Failed to get release with key %q: %s", key, err)
+	}
+	if !reflect.DeepEqual(rel, got) {
+		t.Errorf("Expected {%v}, got {%v}", rel, got)
+	}
+
+	if _, err := secrets.Delete(key); err != nil {
+		t.Fatalf("Failed to delete release with key %q: %s", key, err)
+	}
+	if n := len(mock.objects); n != 0 {
+		t.Errorf("Expected all secrets to be deleted, got %d", n)
+	}
+}
+
+func TestSecretSpilloverFailedWrites(t *testing.T) {
+	withMaxRecordDataSize(t, 64)
+
+	key := testKey("smug-pigeon", 1)
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+	rel.Manifest = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: smug-pigeon\n"
+
+	secrets := newTestFixtureSecrets(t)
+	mock := secrets.impl.(*MockSecretsInterface)
+	if err := secrets.Create(key, rel); err != nil {
+		t.Fatalf("Failed to create release with key %q: %s", key, err)
+	}
+	stored := len(mock.objects)
+
+	// A failed update leaves the stored release and its parts untouched, and
+	// removes the parts it wrote.
+	updated := *rel
+	updated.Manifest = "apiVersion: v1\nkind: Secret\nmetadata:\n  name: updated-pigeon\n"
+	mock.updateErr = errors.New("etcd unavailable")
+	if err := secrets.Update(key, &updated); err == nil {
+		t.Fatal("Expected the update to fail")
+	}
+	mock.updateErr = nil
+	if n := len(mock.objects); n != stored {
+		t.Errorf("Expected %d secrets after the failed update, got %d", stored, n)
+	}
+	got, err := secrets.Get(key)
+	if err != nil {
+		t.Fatalf("Failed to get release with key %q: %s", key, err)
+	}
+	if !reflect.DeepEqual(rel, got) {
+		t.Errorf("Expected {%v}, got {%v}", rel, got)
+	}
+
+	// A failed creation removes the parts it wrote.
+	if err := secrets.Create(key, &updated); err != ErrReleaseExists {
+		t.Errorf("Expected {%v}, got {%v}", ErrReleaseExists, err)
+	}
+	if n := len(mock.objects); n != stored {
+		t.Errorf("Expected %d secrets after the failed creation, got %d", stored, n)
+	}
+
+	// A successful update removes the parts of the previous release.
+	if err := secrets.Update(key, &updated); err != nil {
+		t.Fatalf("Failed to update release: %s", err)
+	}
+	got, err = secrets.Get(key)
+	if err != nil {
+		t.Fatalf("Failed to get release with key %q: %s", key, err)
+	}
+	if !reflect.DeepEqual(&updated, got) {
+		t.Errorf("Expected {%v}, got {%v}", &updated, got)
+	}
+	if n := len(mock.objects); n != stored {
+		t.Errorf("Expected %d secrets after the update, got %d", stored, n)
+	}
+}
+
+func TestSecretListPage(t *testing.T) {
+	secrets := newTestFixtureSecrets(t, []*rspb.Release{
+		releaseStub("key-1", 1, "default", rspb.StatusSuperseded),
+		releaseStub("key-1", 2, "default", rspb.StatusDeployed),
+		releaseStub("key-2", 1, "default", rspb.StatusFailed),
+		releaseStub("key-3", 1, "default", rspb.StatusDeployed),
+		releaseStub("key-4", 1, "default", rspb.StatusUninstalled),
+	}...)
+
+	sel, err := kblabels.Parse("name!=key-3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := ListOptions{
+		Selector: sel,
+		Statuses: []rspb.Status{rspb.StatusDeployed, rspb.StatusFailed},
+		Limit:    1,
+	}
+
+	var names []string
+	for {
+		rls, next, err := secrets.ListPage(opts)
+		if err != nil {
+			t.Fatalf("Failed to list: %s", err)
+		}
+		if len(rls) > 1 {
+			t.Errorf("Expected at most 1 release per page, got %d", len(rls))
+		}
+		for _, rls := range rls {
+			names = append(names, fmt.Sprintf("%s.v%d", rls.Name, rls.Version))
+		}
+		if next == "" {
+			break
+		}
+		opts.Continue = next
+	}
+
+	if expected := []string{"key-1.v2", "key-2.v1"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected releases %v, got %v", expected, names)
+	}
+}
@@ -20,31 +20,44 @@ import (
 	"fmt"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 	migrate "github.com/rubenv/sql-migrate"
 
 	sq "github.com/Masterminds/squirrel"
-
-	// Import pq for postgres dialect
-	_ "github.com/lib/pq"
+	"github.com/pkg/errors"
+	kblabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
 
 	rspb "helm.sh/helm/v3/pkg/release"
 )
 
 var _ Driver = (*SQL)(nil)
-
-var labelMap = map[string]struct{}{
-	"modifiedAt": {},
-	"createdAt":  {},
-	"version":    {},
-	"status":     {},
-	"owner":      {},
-	"name":       {},
+var _ Pager = (*SQL)(nil)
+
+// labelMap maps the system labels to the columns of the releases table
+// holding them.
+var labelMap = map[string]string{
+	"modifiedAt":   sqlReleaseTableModifiedAtColumn,
+	"createdAt":    sqlReleaseTableCreatedAtColumn,
+	"version":      sqlReleaseTableVersionColumn,
+	"status":       sqlReleaseTableStatusColumn,
+	"owner":        sqlReleaseTableOwnerColumn,
+	"name":         sqlReleaseTableNameColumn,
+	ChartNameLabel: sqlReleaseTableChartNameColumn,
 }
 
-const postgreSQLDialect = "postgres"
+const (
+	// PostgreSQLDialect is the dialect of PostgreSQL databases. It is the
+	// default dialect of the SQL driver.
+	PostgreSQLDialect = "postgres"
+	// MySQLDialect is the dialect of MySQL and MariaDB databases.
+	MySQLDialect = "mysql"
+	// SQLiteDialect is the dialect of SQLite database files.
+	SQLiteDialect = "sqlite"
+)
 
 // SQLDriverName is the string name of this driver.
 const SQLDriverName = "SQL"
@@ -63,6 +76,7 @@ const (
 	sqlReleaseTableOwnerColumn      = "owner"
 	sqlReleaseTableCreatedAtColumn  = "createdAt"
 	sqlReleaseTableModifiedAtColumn = "modifiedAt"
+	sqlReleaseTableChartNameColumn  = "chartName"
 
 	sqlCustomLabelsTableReleaseKeyColumn       = "releaseKey"
 	sqlCustomLabelsTableReleaseNamespaceColumn = "releaseNamespace"
@@ -85,9 +99,14 @@ const (
 type SQL struct {
 	db               *sqlx.DB
 	namespace        string
+	dialect          string
 	statementBuilder sq.StatementBuilderType
 
 	Log func(string, ...interface{})
+	// Compression configures the compression of the stored releases.
+	Compression Compression
+	// Encryptor encrypts the stored releases if it is set.
+	Encryptor Encryptor
 }
 
 // Name returns the name of the driver.
@@ -105,7 +124,7 @@ func (s *SQL) checkAlreadyApplied(migrations []*migrate.Migration) bool {
 
 	// get list of applied migrations
 	migrate.SetDisableCreateTable(true)
-	records, err := migrate.GetMigrationRecords(s.db.DB, postgreSQLDialect)
+	records, err := migrate.GetMigrationRecords(s.db.DB, s.migrationDialect())
 	migrate.SetDisableCreateTable(false)
 	if err != nil {
 		s.Log("checkAlreadyApplied: failed to get migration records: %v", err)
@@ -130,109 +149,8 @@ func (s *SQL) checkAlreadyApplied(migrations []*migrate.Migration) bool {
 }
 
 func (s *SQL) ensureDBSetup() error {
-
 	migrations := &migrate.MemoryMigrationSource{
-		Migrations: []*migrate.Migration{
-			{
-				Id: "init",
-				Up: []string{
-					fmt.Sprintf(`
-						CREATE TABLE %s (
-							%s VARCHAR(90),
-							%s VARCHAR(64) NOT NULL,
-							%s TEXT NOT NULL,
-							%s VARCHAR(64) NOT NULL,
-							%s VARCHAR(64) NOT NULL,
-							%s INTEGER NOT NULL,
-							%s TEXT NOT NULL,
-							%s TEXT NOT NULL,
-							%s INTEGER NOT NULL,
-							%s INTEGER NOT NULL DEFAULT 0,
-							PRIMARY KEY(%s, %s)
-						);
-						CREATE INDEX ON %s (%s, %s);
-						CREATE INDEX ON %s (%s);
-						CREATE INDEX ON %s (%s);
-						CREATE INDEX ON %s (%s);
-						CREATE INDEX ON %s (%s);
-						CREATE INDEX ON %s (%s);
-	
-						GRANT ALL ON %s TO PUBLIC;
-	
-						ALTER TABLE %s ENABLE ROW LEVEL SECURITY;
-					`,
-						sqlReleaseTableName,
-						sqlReleaseTableKeyColumn,
-						sqlReleaseTableTypeColumn,
-						sqlReleaseTableBodyColumn,
-						sqlReleaseTableNameColumn,
-						sqlReleaseTableNamespaceColumn,
-						sqlReleaseTableVersionColumn,
-						sqlReleaseTableStatusColumn,
-						sqlReleaseTableOwnerColumn,
-						sqlReleaseTableCreatedAtColumn,
-						sqlReleaseTableModifiedAtColumn,
-						sqlReleaseTableKeyColumn,
-						sqlReleaseTableNamespaceColumn,
-						sqlReleaseTableName,
-						sqlReleaseTableKeyColumn,
-						sqlReleaseTableNamespaceColumn,
-						sqlReleaseTableName,
-						sqlReleaseTableVersionColumn,
-						sqlReleaseTableName,
-						sqlReleaseTableStatusColumn,
-						sqlReleaseTableName,
-						sqlReleaseTableOwnerColumn,
-						sqlReleaseTableName,
-						sqlReleaseTableCreatedAtColumn,
-						sqlReleaseTableName,
-						sqlReleaseTableModifiedAtColumn,
-						sqlReleaseTableName,
-						sqlReleaseTableName,
-					),
-				},
-				Down: []string{
-					fmt.Sprintf(`
-						DROP TABLE %s;
-					`, sqlReleaseTableName),
-				},
-			},
-			{
-				Id: "custom_labels",
-				Up: []string{
-					fmt.Sprintf(`
-						CREATE TABLE %s (
-							%s VARCHAR(64),
-							%s VARCHAR(67),
-							%s VARCHAR(%d), 
-							%s VARCHAR(%d)
-						);
-						CREATE INDEX ON %s (%s, %s);
-						
-						GRANT ALL ON %s TO PUBLIC;
-						ALTER TABLE %s ENABLE ROW LEVEL SECURITY;
-					`,
-						sqlCustomLabelsTableName,
-						sqlCustomLabelsTableReleaseKeyColumn,
-						sqlCustomLabelsTableReleaseNamespaceColumn,
-						sqlCustomLabelsTableKeyColumn,
-						sqlCustomLabelsTableKeyMaxLenght,
-						sqlCustomLabelsTableValueColumn,
-						sqlCustomLabelsTableValueMaxLenght,
-						sqlCustomLabelsTableName,
-						sqlCustomLabelsTableReleaseKeyColumn,
-						sqlCustomLabelsTableReleaseNamespaceColumn,
-						sqlCustomLabelsTableName,
-						sqlCustomLabelsTableName,
-					),
-				},
-				Down: []string{
-					fmt.Sprintf(`
-						DELETE TABLE %s;
-					`, sqlCustomLabelsTableName),
-				},
-			},
-		},
+		Migrations: sqlMigrations(s.dialect),
 	}
 
 	// Check that init migration already applied
@@ -241,10 +159,329 @@ func (s *SQL) ensureDBSetup() error {
 	}
 
 	// Populate the database with the relations we need if they don't exist yet
-	_, err := migrate.Exec(s.db.DB, postgreSQLDialect, migrations, migrate.Up)
+	_, err := migrate.Exec(s.db.DB, s.migrationDialect(), migrations, migrate.Up)
 	return err
 }
 
+// migrationDialect returns the sql-migrate dialect of the database.
+func (s *SQL) migrationDialect() string {
+	switch s.dialect {
+	case MySQLDialect:
+		return MySQLDialect
+	case SQLiteDialect:
+		return "sqlite3"
+	}
+	return PostgreSQLDialect
+}
+
+// sqlMigrations returns the migrations that create the schema of the SQL
+// driver in a database of the given dialect. Migrations with the same ID
+// create the same schema in every dialect.
+func sqlMigrations(dialect string) []*migrate.Migration {
+	switch dialect {
+	case MySQLDialect:
+		return mySQLMigrations()
+	case SQLiteDialect:
+		return sqliteMigrations()
+	}
+	return postgreSQLMigrations()
+}
+
+func postgreSQLMigrations() []*migrate.Migration {
+	return []*migrate.Migration{
+		{
+			Id: "init",
+			Up: []string{
+				fmt.Sprintf(`
+					CREATE TABLE %s (
+						%s VARCHAR(90),
+						%s VARCHAR(64) NOT NULL,
+						%s TEXT NOT NULL,
+						%s VARCHAR(64) NOT NULL,
+						%s VARCHAR(64) NOT NULL,
+						%s INTEGER NOT NULL,
+						%s TEXT NOT NULL,
+						%s TEXT NOT NULL,
+						%s INTEGER NOT NULL,
+						%s INTEGER NOT NULL DEFAULT 0,
+						PRIMARY KEY(%s, %s)
+					);
+					CREATE INDEX ON %s (%s, %s);
+					CREATE INDEX ON %s (%s);
+					CREATE INDEX ON %s (%s);
+					CREATE INDEX ON %s (%s);
+					CREATE INDEX ON %s (%s);
+					CREATE INDEX ON %s (%s);
+	
+					GRANT ALL ON %s TO PUBLIC;
+	
+					ALTER TABLE %s ENABLE ROW LEVEL SECURITY;
+				`,
+					sqlReleaseTableName,
+					sqlReleaseTableKeyColumn,
+					sqlReleaseTableTypeColumn,
+					sqlReleaseTableBodyColumn,
+					sqlReleaseTableNameColumn,
+					sqlReleaseTableNamespaceColumn,
+					sqlReleaseTableVersionColumn,
+					sqlReleaseTableStatusColumn,
+					sqlReleaseTableOwnerColumn,
+					sqlReleaseTableCreatedAtColumn,
+					sqlReleaseTableModifiedAtColumn,
+					sqlReleaseTableKeyColumn,
+					sqlReleaseTableNamespaceColumn,
+					sqlReleaseTableName,
+					sqlReleaseTableKeyColumn,
+					sqlReleaseTableNamespaceColumn,
+					sqlReleaseTableName,
+					sqlReleaseTableVersionColumn,
+					sqlReleaseTableName,
+					sqlReleaseTableStatusColumn,
+					sqlReleaseTableName,
+					sqlReleaseTableOwnerColumn,
+					sqlReleaseTableName,
+					sqlReleaseTableCreatedAtColumn,
+					sqlReleaseTableName,
+					sqlReleaseTableModifiedAtColumn,
+					sqlReleaseTableName,
+					sqlReleaseTableName,
+				),
+			},
+			Down: []string{
+				fmt.Sprintf(`
+					DROP TABLE %s;
+				`, sqlReleaseTableName),
+			},
+		},
+		{
+			Id: "custom_labels",
+			Up: []string{
+				fmt.Sprintf(`
+					CREATE TABLE %s (
+						%s VARCHAR(64),
+						%s VARCHAR(67),
+						%s VARCHAR(%d), 
+						%s VARCHAR(%d)
+					);
+					CREATE INDEX ON %s (%s, %s);
+					
+					GRANT ALL ON %s TO PUBLIC;
+					ALTER TABLE %s ENABLE ROW LEVEL SECURITY;
+				`,
+					sqlCustomLabelsTableName,
+					sqlCustomLabelsTableReleaseKeyColumn,
+					sqlCustomLabelsTableReleaseNamespaceColumn,
+					sqlCustomLabelsTableKeyColumn,
+					sqlCustomLabelsTableKeyMaxLenght,
+					sqlCustomLabelsTableValueColumn,
+					sqlCustomLabelsTableValueMaxLenght,
+					sqlCustomLabelsTableName,
+					sqlCustomLabelsTableReleaseKeyColumn,
+					sqlCustomLabelsTableReleaseNamespaceColumn,
+					sqlCustomLabelsTableName,
+					sqlCustomLabelsTableName,
+				),
+			},
+			Down: []string{
+				fmt.Sprintf(`
+					DROP TABLE %s;
+				`, sqlCustomLabelsTableName),
+			},
+		},
+		{
+			// Migrations are applied in the order of their IDs, which must
+			// sort after "init".
+			Id: "release_chart_name",
+			Up: []string{
+				fmt.Sprintf(`
+					ALTER TABLE %s ADD COLUMN %s VARCHAR(63) NOT NULL DEFAULT '';
+					CREATE INDEX ON %s (%s);
+				`,
+					sqlReleaseTableName,
+					sqlReleaseTableChartNameColumn,
+					sqlReleaseTableName,
+					sqlReleaseTableChartNameColumn,
+				),
+			},
+			Down: []string{
+				fmt.Sprintf(`
+					ALTER TABLE %s DROP COLUMN %s;
+				`, sqlReleaseTableName, sqlReleaseTableChartNameColumn),
+			},
+		},
+	}
+}
+
+// mySQLMigrations returns the MySQL migrations. MySQL requires index names,
+// cannot index TEXT columns and does not support row level security, and
+// "key" is a reserved word, so the schema is declared separately. Each
+// statement is executed on its own, since multi statements are disabled by
+// default.
+func mySQLMigrations() []*migrate.Migration {
+	return []*migrate.Migration{
+		{
+			Id: "init",
+			Up: []string{
+				fmt.Sprintf(`
+					CREATE TABLE %s (
+						`+"`%s`"+` VARCHAR(90),
+						%s VARCHAR(64) NOT NULL,
+						%s LONGTEXT NOT NULL,
+						%s VARCHAR(64) NOT NULL,
+						%s VARCHAR(64) NOT NULL,
+						%s INTEGER NOT NULL,
+						%s VARCHAR(64) NOT NULL,
+						%s VARCHAR(64) NOT NULL,
+						%s INTEGER NOT NULL,
+						%s INTEGER NOT NULL DEFAULT 0,
+						PRIMARY KEY(`+"`%s`"+`, %s)
+					)`,
+					sqlReleaseTableName,
+					sqlReleaseTableKeyColumn,
+					sqlReleaseTableTypeColumn,
+					sqlReleaseTableBodyColumn,
+					sqlReleaseTableNameColumn,
+					sqlReleaseTableNamespaceColumn,
+					sqlReleaseTableVersionColumn,
+					sqlReleaseTableStatusColumn,
+					sqlReleaseTableOwnerColumn,
+					sqlReleaseTableCreatedAtColumn,
+					sqlReleaseTableModifiedAtColumn,
+					sqlReleaseTableKeyColumn,
+					sqlReleaseTableNamespaceColumn,
+				),
+				sqlCreateIndex(sqlReleaseTableName, sqlReleaseTableVersionColumn),
+				sqlCreateIndex(sqlReleaseTableName, sqlReleaseTableStatusColumn),
+				sqlCreateIndex(sqlReleaseTableName, sqlReleaseTableOwnerColumn),
+				sqlCreateIndex(sqlReleaseTableName, sqlReleaseTableCreatedAtColumn),
+				sqlCreateIndex(sqlReleaseTableName, sqlReleaseTableModifiedAtColumn),
+			},
+			Down: []string{
+				fmt.Sprintf("DROP TABLE %s", sqlReleaseTableName),
+			},
+		},
+		{
+			Id: "custom_labels",
+			Up: []string{
+				fmt.Sprintf(`
+					CREATE TABLE %s (
+						%s VARCHAR(64),
+						%s VARCHAR(67),
+						`+"`%s`"+` VARCHAR(%d),
+						%s VARCHAR(%d)
+					)`,
+					sqlCustomLabelsTableName,
+					sqlCustomLabelsTableReleaseKeyColumn,
+					sqlCustomLabelsTableReleaseNamespaceColumn,
+					sqlCustomLabelsTableKeyColumn,
+					sqlCustomLabelsTableKeyMaxLenght,
+					sqlCustomLabelsTableValueColumn,
+					sqlCustomLabelsTableValueMaxLenght,
+				),
+				sqlCreateIndex(sqlCustomLabelsTableName, sqlCustomLabelsTableReleaseKeyColumn, sqlCustomLabelsTableReleaseNamespaceColumn),
+			},
+			Down: []string{
+				fmt.Sprintf("DROP TABLE %s", sqlCustomLabelsTableName),
+			},
+		},
+		sqlChartNameMigration(),
+	}
+}
+
+// sqliteMigrations returns the SQLite migrations. Like MySQL, SQLite requires
+// index names and has no row level security.
+func sqliteMigrations() []*migrate.Migration {
+	return []*migrate.Migration{
+		{
+			Id: "init",
+			Up: []string{
+				fmt.Sprintf(`
+					CREATE TABLE %s (
+						%s VARCHAR(90),
+						%s VARCHAR(64) NOT NULL,
+						%s TEXT NOT NULL,
+						%s VARCHAR(64) NOT NULL,
+						%s VARCHAR(64) NOT NULL,
+						%s INTEGER NOT NULL,
+						%s TEXT NOT NULL,
+						%s TEXT NOT NULL,
+						%s INTEGER NOT NULL,
+						%s INTEGER NOT NULL DEFAULT 0,
+						PRIMARY KEY(%s, %s)
+					)`,
+					sqlReleaseTableName,
+					sqlReleaseTableKeyColumn,
+					sqlReleaseTableTypeColumn,
+					sqlReleaseTableBodyColumn,
+					sqlReleaseTableNameColumn,
+					sqlReleaseTableNamespaceColumn,
+					sqlReleaseTableVersionColumn,
+					sqlReleaseTableStatusColumn,
+					sqlReleaseTableOwnerColumn,
+					sqlReleaseTableCreatedAtColumn,
+					sqlReleaseTableModifiedAtColumn,
+					sqlReleaseTableKeyColumn,
+					sqlReleaseTableNamespaceColumn,
+				),
+				sqlCreateIndex(sqlReleaseTableName, sqlReleaseTableVersionColumn),
+				sqlCreateIndex(sqlReleaseTableName, sqlReleaseTableStatusColumn),
+				sqlCreateIndex(sqlReleaseTableName, sqlReleaseTableOwnerColumn),
+				sqlCreateIndex(sqlReleaseTableName, sqlReleaseTableCreatedAtColumn),
+				sqlCreateIndex(sqlReleaseTableName, sqlReleaseTableModifiedAtColumn),
+			},
+			Down: []string{
+				fmt.Sprintf("DROP TABLE %s", sqlReleaseTableName),
+			},
+		},
+		{
+			Id: "custom_labels",
+			Up: []string{
+				fmt.Sprintf(`
+					CREATE TABLE %s (
+						%s VARCHAR(64),
+						%s VARCHAR(67),
+						%s VARCHAR(%d),
+						%s VARCHAR(%d)
+					)`,
+					sqlCustomLabelsTableName,
+					sqlCustomLabelsTableReleaseKeyColumn,
+					sqlCustomLabelsTableReleaseNamespaceColumn,
+					sqlCustomLabelsTableKeyColumn,
+					sqlCustomLabelsTableKeyMaxLenght,
+					sqlCustomLabelsTableValueColumn,
+					sqlCustomLabelsTableValueMaxLenght,
+				),
+				sqlCreateIndex(sqlCustomLabelsTableName, sqlCustomLabelsTableReleaseKeyColumn, sqlCustomLabelsTableReleaseNamespaceColumn),
+			},
+			Down: []string{
+				fmt.Sprintf("DROP TABLE %s", sqlCustomLabelsTableName),
+			},
+		},
+		sqlChartNameMigration(),
+	}
+}
+
+// sqlChartNameMigration returns the MySQL and SQLite migration adding the
+// column of the chart name. Releases stored before it have an empty chart name.
+func sqlChartNameMigration() *migrate.Migration {
+	return &migrate.Migration{
+		Id: "release_chart_name",
+		Up: []string{
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s VARCHAR(63) NOT NULL DEFAULT ''", sqlReleaseTableName, sqlReleaseTableChartNameColumn),
+			sqlCreateIndex(sqlReleaseTableName, sqlReleaseTableChartNameColumn),
+		},
+		Down: []string{
+			fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", sqlReleaseTableName, sqlReleaseTableChartNameColumn),
+		},
+	}
+}
+
+// sqlCreateIndex returns the statement creating a named index, for the
+// dialects that require index names.
+func sqlCreateIndex(table string, columns ...string) string {
+	return fmt.Sprintf("CREATE INDEX %s_%s ON %s (%s)", table, strings.Join(columns, "_"), table, strings.Join(columns, ", "))
+}
+
 // SQLReleaseWrapper describes how Helm releases are stored in an SQL database
 type SQLReleaseWrapper struct {
 	// The primary key, made of {release-name}.{release-version}
@@ -275,9 +512,22 @@ type SQLReleaseCustomLabelWrapper struct {
 	Value            string `db:"value"`
 }
 
-// NewSQL initializes a new sql driver.
+// NewSQL initializes a new sql driver connected to a PostgreSQL database.
 func NewSQL(connectionString string, logger func(string, ...interface{}), namespace string) (*SQL, error) {
-	db, err := sqlx.Connect(postgreSQLDialect, connectionString)
+	return NewSQLWithOptions(SQLOptions{ConnectionString: connectionString}, logger, namespace)
+}
+
+// NewSQLite initializes a new sql driver storing releases in the SQLite
+// database file at path. The file is created if it does not exist.
+func NewSQLite(path string, logger func(string, ...interface{}), namespace string) (*SQL, error) {
+	return NewSQLWithOptions(SQLOptions{Dialect: SQLiteDialect, ConnectionString: path}, logger, namespace)
+}
+
+// NewSQLWithOptions initializes a new sql driver with the given options. The
+// schema of the database is created or migrated to the latest version, unless
+// opts.SkipMigrations is set.
+func NewSQLWithOptions(opts SQLOptions, logger func(string, ...interface{}), namespace string) (*SQL, error) {
+	db, err := openSQL(opts)
 	if err != nil {
 		return nil, err
 	}
@@ -285,11 +535,17 @@ func NewSQL(connectionString string, logger func(string, ...interface{}), namesp
 	driver := &SQL{
 		db:               db,
 		Log:              logger,
+		dialect:          db.DriverName(),
 		statementBuilder: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
 	}
+	if driver.dialect == MySQLDialect || driver.dialect == SQLiteDialect {
+		driver.statementBuilder = sq.StatementBuilder.PlaceholderFormat(sq.Question)
+	}
 
-	if err := driver.ensureDBSetup(); err != nil {
-		return nil, err
+	if !opts.SkipMigrations {
+		if err := driver.ensureDBSetup(); err != nil {
+			return nil, err
+		}
 	}
 
 	driver.namespace = namespace
@@ -297,6 +553,15 @@ func NewSQL(connectionString string, logger func(string, ...interface{}), namesp
 	return driver, nil
 }
 
+// column quotes a column name for the dialect of the database. It must be used
+// for the "key" columns, since key is a reserved word in MySQL.
+func (s *SQL) column(name string) string {
+	if s.dialect == MySQLDialect {
+		return "`" + name + "`"
+	}
+	return name
+}
+
 // Get returns the release named by key.
 func (s *SQL) Get(key string) (*rspb.Release, error) {
 	var record SQLReleaseWrapper
@@ -304,7 +569,7 @@ func (s *SQL) Get(key string) (*rspb.Release, error) {
 	qb := s.statementBuilder.
 		Select(sqlReleaseTableBodyColumn).
 		From(sqlReleaseTableName).
-		Where(sq.Eq{sqlReleaseTableKeyColumn: key}).
+		Where(sq.Eq{s.column(sqlReleaseTableKeyColumn): key}).
 		Where(sq.Eq{sqlReleaseTableNamespaceColumn: s.namespace})
 
 	query, args, err := qb.ToSql()
@@ -319,7 +584,7 @@ func (s *SQL) Get(key string) (*rspb.Release, error) {
 		return nil, ErrReleaseNotFound
 	}
 
-	release, err := decodeRelease(record.Body)
+	release, err := openRelease(record.Body, s.Encryptor)
 	if err != nil {
 		s.Log("get: failed to decode data %q: %v", key, err)
 		return nil, err
@@ -336,7 +601,7 @@ func (s *SQL) Get(key string) (*rspb.Release, error) {
 // List returns the list of all releases such that filter(release) == true
 func (s *SQL) List(filter func(*rspb.Release) bool) ([]*rspb.Release, error) {
 	sb := s.statementBuilder.
-		Select(sqlReleaseTableKeyColumn, sqlReleaseTableNamespaceColumn, sqlReleaseTableBodyColumn).
+		Select(s.column(sqlReleaseTableKeyColumn), sqlReleaseTableNamespaceColumn, sqlReleaseTableBodyColumn).
 		From(sqlReleaseTableName).
 		Where(sq.Eq{sqlReleaseTableOwnerColumn: sqlReleaseDefaultOwner})
 
@@ -359,7 +624,7 @@ func (s *SQL) List(filter func(*rspb.Release) bool) ([]*rspb.Release, error) {
 
 	var releases []*rspb.Release
 	for _, record := range records {
-		release, err := decodeRelease(record.Body)
+		release, err := openRelease(record.Body, s.Encryptor)
 		if err != nil {
 			s.Log("list: failed to decode release: %v: %v", record, err)
 			continue
@@ -381,10 +646,164 @@ func (s *SQL) List(filter func(*rspb.Release) bool) ([]*rspb.Release, error) {
 	return releases, nil
 }
 
+// ListPage returns the releases selected by opts. The selector is translated
+// to conditions on the release columns and on the custom labels table, and
+// the releases are paginated in the order of their namespace and key.
+func (s *SQL) ListPage(opts ListOptions) ([]*rspb.Release, string, error) {
+	sb := s.statementBuilder.
+		Select(s.column(sqlReleaseTableKeyColumn), sqlReleaseTableNamespaceColumn, sqlReleaseTableBodyColumn).
+		From(sqlReleaseTableName).
+		Where(sq.Eq{sqlReleaseTableOwnerColumn: sqlReleaseDefaultOwner})
+
+	// If a namespace was specified, we only list releases from that namespace
+	if s.namespace != "" {
+		sb = sb.Where(sq.Eq{sqlReleaseTableNamespaceColumn: s.namespace})
+	}
+
+	if len(opts.Statuses) > 0 {
+		statuses := make([]string, 0, len(opts.Statuses))
+		for _, status := range opts.Statuses {
+			statuses = append(statuses, status.String())
+		}
+		sb = sb.Where(sq.Eq{sqlReleaseTableStatusColumn: statuses})
+	}
+
+	if opts.Selector != nil {
+		reqs, _ := opts.Selector.Requirements()
+		for _, req := range reqs {
+			cond, err := s.labelCondition(req)
+			if err != nil {
+				return nil, "", err
+			}
+			sb = sb.Where(cond)
+		}
+	}
+
+	if opts.Continue != "" {
+		namespace, key, err := parseSQLContinue(opts.Continue)
+		if err != nil {
+			return nil, "", err
+		}
+		sb = sb.Where(sq.Or{
+			sq.Gt{sqlReleaseTableNamespaceColumn: namespace},
+			sq.And{
+				sq.Eq{sqlReleaseTableNamespaceColumn: namespace},
+				sq.Gt{s.column(sqlReleaseTableKeyColumn): key},
+			},
+		})
+	}
+
+	sb = sb.OrderBy(sqlReleaseTableNamespaceColumn, s.column(sqlReleaseTableKeyColumn))
+	if opts.Limit > 0 {
+		sb = sb.Limit(uint64(opts.Limit))
+	}
+
+	query, args, err := sb.ToSql()
+	if err != nil {
+		s.Log("failed to build query: %v", err)
+		return nil, "", err
+	}
+
+	var records = []SQLReleaseWrapper{}
+	if err := s.db.Select(&records, query, args...); err != nil {
+		s.Log("list: failed to list: %v", err)
+		return nil, "", err
+	}
+
+	var releases []*rspb.Release
+	for _, record := range records {
+		release, err := openRelease(record.Body, s.Encryptor)
+		if err != nil {
+			s.Log("list: failed to decode release: %v: %v", record, err)
+			continue
+		}
+
+		if release.Labels, err = s.getReleaseCustomLabels(record.Key, record.Namespace); err != nil {
+			s.Log("failed to get release %s/%s custom labels: %v", record.Namespace, record.Key, err)
+			return nil, "", err
+		}
+		for k, v := range getReleaseSystemLabels(release) {
+			release.Labels[k] = v
+		}
+		releases = append(releases, release)
+	}
+
+	var next string
+	if opts.Limit > 0 && len(records) == int(opts.Limit) {
+		last := records[len(records)-1]
+		next = b64.EncodeToString([]byte(last.Namespace + "/" + last.Key))
+	}
+	return releases, next, nil
+}
+
+// labelCondition translates a label selector requirement to a condition on
+// the column of a system label, or on the custom labels of the release.
+func (s *SQL) labelCondition(req kblabels.Requirement) (sq.Sqlizer, error) {
+	values := req.Values().List()
+
+	if column, ok := labelMap[req.Key()]; ok {
+		switch req.Operator() {
+		case selection.Equals, selection.DoubleEquals, selection.In:
+			return sq.Eq{column: values}, nil
+		case selection.NotEquals, selection.NotIn:
+			return sq.NotEq{column: values}, nil
+		case selection.Exists:
+			if req.Key() == ChartNameLabel {
+				return sq.NotEq{column: ""}, nil
+			}
+			return sq.Expr("1 = 1"), nil
+		case selection.DoesNotExist:
+			// the chart name is empty for releases stored before it was
+			// recorded
+			if req.Key() == ChartNameLabel {
+				return sq.Eq{column: ""}, nil
+			}
+			return sq.Expr("1 = 0"), nil
+		}
+		return nil, errors.Errorf("unsupported operator %q for label %s", req.Operator(), req.Key())
+	}
+
+	// the custom labels of the release, with the key of the requirement
+	exists := fmt.Sprintf("SELECT 1 FROM %s WHERE %s = %s.%s AND %s = %s.%s AND %s = ?",
+		sqlCustomLabelsTableName,
+		sqlCustomLabelsTableReleaseKeyColumn, sqlReleaseTableName, s.column(sqlReleaseTableKeyColumn),
+		sqlCustomLabelsTableReleaseNamespaceColumn, sqlReleaseTableName, sqlReleaseTableNamespaceColumn,
+		s.column(sqlCustomLabelsTableKeyColumn))
+	args := []interface{}{req.Key()}
+	if len(values) > 0 {
+		exists += fmt.Sprintf(" AND %s IN (%s)", sqlCustomLabelsTableValueColumn, sq.Placeholders(len(values)))
+		for _, v := range values {
+			args = append(args, v)
+		}
+	}
+
+	switch req.Operator() {
+	case selection.Equals, selection.DoubleEquals, selection.In, selection.Exists:
+		return sq.Expr("EXISTS ("+exists+")", args...), nil
+	case selection.NotEquals, selection.NotIn, selection.DoesNotExist:
+		return sq.Expr("NOT EXISTS ("+exists+")", args...), nil
+	}
+	return nil, errors.Errorf("unsupported operator %q for label %s", req.Operator(), req.Key())
+}
+
+// parseSQLContinue returns the namespace and the key of the last release of
+// the previous page.
+func parseSQLContinue(token string) (string, string, error) {
+	b, err := b64.DecodeString(token)
+	if err != nil {
+		return "", "", errors.Wrap(err, "invalid continue token")
+	}
+	namespace, key, ok := strings.Cut(string(b), "/")
+	if !ok {
+		return "", "", errors.New("invalid continue token")
+	}
+	return namespace, key, nil
+}
+
 // Query returns the set of releases that match the provided set of labels.
 func (s *SQL) Query(labels map[string]string) ([]*rspb.Release, error) {
 	sb := s.statementBuilder.
-		Select(sqlReleaseTableKeyColumn, sqlReleaseTableNamespaceColumn, sqlReleaseTableBodyColumn).
+		Select(s.column(sqlReleaseTableKeyColumn), sqlReleaseTableNamespaceColumn, sqlReleaseTableBodyColumn).
 		From(sqlReleaseTableName)
 
 	keys := make([]string, 0, len(labels))
@@ -393,8 +812,8 @@ func (s *SQL) Query(labels map[string]string) ([]*rspb.Release, error) {
 	}
 	sort.Strings(keys)
 	for _, key := range keys {
-		if _, ok := labelMap[key]; ok {
-			sb = sb.Where(sq.Eq{key: labels[key]})
+		if column, ok := labelMap[key]; ok {
+			sb = sb.Where(sq.Eq{column: labels[key]})
 		} else {
 			s.Log("unknown label %s", key)
 			return nil, fmt.Errorf("unknown label %s", key)
@@ -425,7 +844,7 @@ func (s *SQL) Query(labels map[string]string) ([]*rspb.Release, error) {
 
 	var releases []*rspb.Release
 	for _, record := range records {
-		release, err := decodeRelease(record.Body)
+		release, err := openRelease(record.Body, s.Encryptor)
 		if err != nil {
 			s.Log("list: failed to decode release: %v: %v", record, err)
 			continue
@@ -454,7 +873,7 @@ func (s *SQL) Create(key string, rls *rspb.Release) error {
 	}
 	s.namespace = namespace
 
-	body, err := encodeRelease(rls)
+	body, err := sealRelease(rls, s.Compression, s.Encryptor)
 	if err != nil {
 		s.Log("failed to encode release: %v", err)
 		return err
@@ -469,7 +888,7 @@ func (s *SQL) Create(key string, rls *rspb.Release) error {
 	insertQuery, args, err := s.statementBuilder.
 		Insert(sqlReleaseTableName).
 		Columns(
-			sqlReleaseTableKeyColumn,
+			s.column(sqlReleaseTableKeyColumn),
 			sqlReleaseTableTypeColumn,
 			sqlReleaseTableBodyColumn,
 			sqlReleaseTableNameColumn,
@@ -478,6 +897,7 @@ func (s *SQL) Create(key string, rls *rspb.Release) error {
 			sqlReleaseTableStatusColumn,
 			sqlReleaseTableOwnerColumn,
 			sqlReleaseTableCreatedAtColumn,
+			sqlReleaseTableChartNameColumn,
 		).
 		Values(
 			key,
@@ -489,6 +909,7 @@ func (s *SQL) Create(key string, rls *rspb.Release) error {
 			rls.Info.Status.String(),
 			sqlReleaseDefaultOwner,
 			int(time.Now().Unix()),
+			chartNameLabelValue(rls),
 		).ToSql()
 	if err != nil {
 		s.Log("failed to build insert query: %v", err)
@@ -499,9 +920,9 @@ func (s *SQL) Create(key string, rls *rspb.Release) error {
 		defer transaction.Rollback()
 
 		selectQuery, args, buildErr := s.statementBuilder.
-			Select(sqlReleaseTableKeyColumn).
+			Select(s.column(sqlReleaseTableKeyColumn)).
 			From(sqlReleaseTableName).
-			Where(sq.Eq{sqlReleaseTableKeyColumn: key}).
+			Where(sq.Eq{s.column(sqlReleaseTableKeyColumn): key}).
 			Where(sq.Eq{sqlReleaseTableNamespaceColumn: s.namespace}).
 			ToSql()
 		if buildErr != nil {
@@ -520,13 +941,13 @@ func (s *SQL) Create(key string, rls *rspb.Release) error {
 	}
 
 	// Filtering labels before insert cause in SQL storage driver system releases are stored in separate columns of release table
-	for k, v := range filterSystemLabels(rls.Labels) {
+	for k, v := range FilterSystemLabels(rls.Labels) {
 		insertLabelsQuery, args, err := s.statementBuilder.
 			Insert(sqlCustomLabelsTableName).
 			Columns(
 				sqlCustomLabelsTableReleaseKeyColumn,
 				sqlCustomLabelsTableReleaseNamespaceColumn,
-				sqlCustomLabelsTableKeyColumn,
+				s.column(sqlCustomLabelsTableKeyColumn),
 				sqlCustomLabelsTableValueColumn,
 			).
 			Values(
@@ -561,7 +982,7 @@ func (s *SQL) Update(key string, rls *rspb.Release) error {
 	}
 	s.namespace = namespace
 
-	body, err := encodeRelease(rls)
+	body, err := sealRelease(rls, s.Compression, s.Encryptor)
 	if err != nil {
 		s.Log("failed to encode release: %v", err)
 		return err
@@ -575,7 +996,8 @@ func (s *SQL) Update(key string, rls *rspb.Release) error {
 		Set(sqlReleaseTableStatusColumn, rls.Info.Status.String()).
 		Set(sqlReleaseTableOwnerColumn, sqlReleaseDefaultOwner).
 		Set(sqlReleaseTableModifiedAtColumn, int(time.Now().Unix())).
-		Where(sq.Eq{sqlReleaseTableKeyColumn: key}).
+		Set(sqlReleaseTableChartNameColumn, chartNameLabelValue(rls)).
+		Where(sq.Eq{s.column(sqlReleaseTableKeyColumn): key}).
 		Where(sq.Eq{sqlReleaseTableNamespaceColumn: namespace}).
 		ToSql()
 
@@ -603,7 +1025,7 @@ func (s *SQL) Delete(key string) (*rspb.Release, error) {
 	selectQuery, args, err := s.statementBuilder.
 		Select(sqlReleaseTableBodyColumn).
 		From(sqlReleaseTableName).
-		Where(sq.Eq{sqlReleaseTableKeyColumn: key}).
+		Where(sq.Eq{s.column(sqlReleaseTableKeyColumn): key}).
 		Where(sq.Eq{sqlReleaseTableNamespaceColumn: s.namespace}).
 		ToSql()
 	if err != nil {
@@ -618,7 +1040,7 @@ func (s *SQL) Delete(key string) (*rspb.Release, error) {
 		return nil, ErrReleaseNotFound
 	}
 
-	release, err := decodeRelease(record.Body)
+	release, err := openRelease(record.Body, s.Encryptor)
 	if err != nil {
 		s.Log("failed to decode release %s: %v", key, err)
 		transaction.Rollback()
@@ -628,7 +1050,7 @@ func (s *SQL) Delete(key string) (*rspb.Release, error) {
 
 	deleteQuery, args, err := s.statementBuilder.
 		Delete(sqlReleaseTableName).
-		Where(sq.Eq{sqlReleaseTableKeyColumn: key}).
+		Where(sq.Eq{s.column(sqlReleaseTableKeyColumn): key}).
 		Where(sq.Eq{sqlReleaseTableNamespaceColumn: s.namespace}).
 		ToSql()
 	if err != nil {
@@ -664,7 +1086,7 @@ func (s *SQL) Delete(key string) (*rspb.Release, error) {
 // Get release custom labels from database
 func (s *SQL) getReleaseCustomLabels(key string, _ string) (map[string]string, error) {
 	query, args, err := s.statementBuilder.
-		Select(sqlCustomLabelsTableKeyColumn, sqlCustomLabelsTableValueColumn).
+		Select(s.column(sqlCustomLabelsTableKeyColumn), sqlCustomLabelsTableValueColumn).
 		From(sqlCustomLabelsTableName).
 		Where(sq.Eq{sqlCustomLabelsTableReleaseKeyColumn: key,
 			sqlCustomLabelsTableReleaseNamespaceColumn: s.namespace}).
@@ -683,15 +1105,19 @@ func (s *SQL) getReleaseCustomLabels(key string, _ string) (map[string]string, e
 		labelsMap[i.Key] = i.Value
 	}
 
-	return filterSystemLabels(labelsMap), nil
+	return FilterSystemLabels(labelsMap), nil
 }
 
 // Rebuild system labels from release object
 func getReleaseSystemLabels(rls *rspb.Release) map[string]string {
-	return map[string]string{
+	lbs := map[string]string{
 		"name":    rls.Name,
 		"owner":   sqlReleaseDefaultOwner,
 		"status":  rls.Info.Status.String(),
 		"version": strconv.Itoa(rls.Version),
 	}
+	if chart := chartNameLabelValue(rls); chart != "" {
+		lbs[ChartNameLabel] = chart
+	}
+	return lbs
 }
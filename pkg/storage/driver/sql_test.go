@@ -17,11 +17,14 @@ import (
 	"fmt"
 	"reflect"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	sq "github.com/Masterminds/squirrel"
 	migrate "github.com/rubenv/sql-migrate"
+	kblabels "k8s.io/apimachinery/pkg/labels"
 
 	rspb "helm.sh/helm/v3/pkg/release"
 )
@@ -181,7 +184,7 @@ func TestSqlCreate(t *testing.T) {
 	body, _ := encodeRelease(rel)
 
 	query := fmt.Sprintf(
-		"INSERT INTO %s (%s,%s,%s,%s,%s,%s,%s,%s,%s) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)",
+		"INSERT INTO %s (%s,%s,%s,%s,%s,%s,%s,%s,%s,%s) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)",
 		sqlReleaseTableName,
 		sqlReleaseTableKeyColumn,
 		sqlReleaseTableTypeColumn,
@@ -192,12 +195,13 @@ func TestSqlCreate(t *testing.T) {
 		sqlReleaseTableStatusColumn,
 		sqlReleaseTableOwnerColumn,
 		sqlReleaseTableCreatedAtColumn,
+		sqlReleaseTableChartNameColumn,
 	)
 
 	mock.ExpectBegin()
 	mock.
 		ExpectExec(regexp.QuoteMeta(query)).
-		WithArgs(key, sqlReleaseDefaultType, body, rel.Name, rel.Namespace, int(rel.Version), rel.Info.Status.String(), sqlReleaseDefaultOwner, int(time.Now().Unix())).
+		WithArgs(key, sqlReleaseDefaultType, body, rel.Name, rel.Namespace, int(rel.Version), rel.Info.Status.String(), sqlReleaseDefaultOwner, int(time.Now().Unix()), "").
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	labelsQuery := fmt.Sprintf(
@@ -210,7 +214,7 @@ func TestSqlCreate(t *testing.T) {
 	)
 
 	mock.MatchExpectationsInOrder(false)
-	for k, v := range filterSystemLabels(rel.Labels) {
+	for k, v := range FilterSystemLabels(rel.Labels) {
 		mock.
 			ExpectExec(regexp.QuoteMeta(labelsQuery)).
 			WithArgs(key, rel.Namespace, k, v).
@@ -238,7 +242,7 @@ func TestSqlCreateAlreadyExists(t *testing.T) {
 	body, _ := encodeRelease(rel)
 
 	insertQuery := fmt.Sprintf(
-		"INSERT INTO %s (%s,%s,%s,%s,%s,%s,%s,%s,%s) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)",
+		"INSERT INTO %s (%s,%s,%s,%s,%s,%s,%s,%s,%s,%s) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)",
 		sqlReleaseTableName,
 		sqlReleaseTableKeyColumn,
 		sqlReleaseTableTypeColumn,
@@ -249,13 +253,14 @@ func TestSqlCreateAlreadyExists(t *testing.T) {
 		sqlReleaseTableStatusColumn,
 		sqlReleaseTableOwnerColumn,
 		sqlReleaseTableCreatedAtColumn,
+		sqlReleaseTableChartNameColumn,
 	)
 
 	// Insert fails (primary key already exists)
 	mock.ExpectBegin()
 	mock.
 		ExpectExec(regexp.QuoteMeta(insertQuery)).
-		WithArgs(key, sqlReleaseDefaultType, body, rel.Name, rel.Namespace, int(rel.Version), rel.Info.Status.String(), sqlReleaseDefaultOwner, int(time.Now().Unix())).
+		WithArgs(key, sqlReleaseDefaultType, body, rel.Name, rel.Namespace, int(rel.Version), rel.Info.Status.String(), sqlReleaseDefaultOwner, int(time.Now().Unix()), "").
 		WillReturnError(fmt.Errorf("dialect dependent SQL error"))
 
 	selectQuery := fmt.Sprintf(
@@ -299,7 +304,7 @@ func TestSqlUpdate(t *testing.T) {
 	body, _ := encodeRelease(rel)
 
 	query := fmt.Sprintf(
-		"UPDATE %s SET %s = $1, %s = $2, %s = $3, %s = $4, %s = $5, %s = $6 WHERE %s = $7 AND %s = $8",
+		"UPDATE %s SET %s = $1, %s = $2, %s = $3, %s = $4, %s = $5, %s = $6, %s = $7 WHERE %s = $8 AND %s = $9",
 		sqlReleaseTableName,
 		sqlReleaseTableBodyColumn,
 		sqlReleaseTableNameColumn,
@@ -307,13 +312,14 @@ func TestSqlUpdate(t *testing.T) {
 		sqlReleaseTableStatusColumn,
 		sqlReleaseTableOwnerColumn,
 		sqlReleaseTableModifiedAtColumn,
+		sqlReleaseTableChartNameColumn,
 		sqlReleaseTableKeyColumn,
 		sqlReleaseTableNamespaceColumn,
 	)
 
 	mock.
 		ExpectExec(regexp.QuoteMeta(query)).
-		WithArgs(body, rel.Name, int(rel.Version), rel.Info.Status.String(), sqlReleaseDefaultOwner, int(time.Now().Unix()), key, namespace).
+		WithArgs(body, rel.Name, int(rel.Version), rel.Info.Status.String(), sqlReleaseDefaultOwner, int(time.Now().Unix()), "", key, namespace).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	if err := sqlDriver.Update(key, rel); err != nil {
@@ -590,3 +596,130 @@ func TestSqlChechkAppliedMigrations(t *testing.T) {
 		}
 	}
 }
+
+func TestSQLMigrations(t *testing.T) {
+	postgres := sqlMigrations(PostgreSQLDialect)
+	mysql := sqlMigrations(MySQLDialect)
+	if len(postgres) != len(mysql) {
+		t.Fatalf("Expected the same number of migrations for every dialect, got %d and %d", len(postgres), len(mysql))
+	}
+	for i := range postgres {
+		if postgres[i].Id != mysql[i].Id {
+			t.Errorf("Expected migration %d to have the same ID in every dialect, got %q and %q", i, postgres[i].Id, mysql[i].Id)
+		}
+	}
+
+	for _, migration := range mysql {
+		for _, statement := range migration.Up {
+			if strings.Contains(statement, ";") {
+				t.Errorf("Expected MySQL migration %q to hold a single statement per query, got %q", migration.Id, statement)
+			}
+			if strings.Contains(statement, "CREATE TABLE") && !strings.Contains(statement, "`key`") {
+				t.Errorf("Expected MySQL migration %q to quote the key column, got %q", migration.Id, statement)
+			}
+		}
+	}
+}
+
+func TestSQLGetMySQL(t *testing.T) {
+	vers := int(1)
+	name := "smug-pigeon"
+	namespace := "default"
+	key := testKey(name, vers)
+	rel := releaseStub(name, vers, namespace, rspb.StatusDeployed)
+
+	body, _ := encodeRelease(rel)
+
+	sqlDriver, mock := newTestFixtureSQL(t)
+	sqlDriver.dialect = MySQLDialect
+	sqlDriver.statementBuilder = sq.StatementBuilder.PlaceholderFormat(sq.Question)
+
+	mock.
+		ExpectQuery(regexp.QuoteMeta("SELECT body FROM releases_v1 WHERE `key` = ? AND namespace = ?")).
+		WithArgs(key, namespace).
+		WillReturnRows(mock.NewRows([]string{sqlReleaseTableBodyColumn}).AddRow(body)).
+		RowsWillBeClosed()
+	mock.
+		ExpectQuery(regexp.QuoteMeta("SELECT `key`, value FROM custom_labels_v1 WHERE releaseKey = ? AND releaseNamespace = ?")).
+		WithArgs(key, namespace).
+		WillReturnRows(mock.NewRows([]string{sqlCustomLabelsTableKeyColumn, sqlCustomLabelsTableValueColumn}).
+			AddRow("key1", "val1").
+			AddRow("key2", "val2")).
+		RowsWillBeClosed()
+
+	got, err := sqlDriver.Get(key)
+	if err != nil {
+		t.Fatalf("Failed to get release: %v", err)
+	}
+	if !reflect.DeepEqual(rel, got) {
+		t.Errorf("Expected release {%v}, got {%v}", rel, got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("sql expectations weren't met: %v", err)
+	}
+}
+
+func TestSQLListPage(t *testing.T) {
+	sqlDriver, mock := newTestFixtureSQL(t)
+
+	rel1 := releaseStub("key-1", 1, "default", rspb.StatusDeployed)
+	rel2 := releaseStub("key-2", 1, "default", rspb.StatusDeployed)
+	key1 := testKey(rel1.Name, rel1.Version)
+	key2 := testKey(rel2.Name, rel2.Version)
+	body1, _ := encodeRelease(rel1)
+	body2, _ := encodeRelease(rel2)
+
+	query := "SELECT key, namespace, body FROM releases_v1 WHERE owner = $1 AND namespace = $2 AND status IN ($3) AND " +
+		"EXISTS (SELECT 1 FROM custom_labels_v1 WHERE releaseKey = releases_v1.key AND releaseNamespace = releases_v1.namespace AND key = $4 AND value IN ($5)) AND " +
+		"NOT EXISTS (SELECT 1 FROM custom_labels_v1 WHERE releaseKey = releases_v1.key AND releaseNamespace = releases_v1.namespace AND key = $6) AND " +
+		"version NOT IN ($7) AND (namespace > $8 OR (namespace = $9 AND key > $10)) ORDER BY namespace, key LIMIT 2"
+	mock.
+		ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs(sqlReleaseDefaultOwner, "default", "deployed", "key1", "val1", "missing", "3", "default", "default", "key-0.v1").
+		WillReturnRows(
+			mock.NewRows([]string{sqlReleaseTableKeyColumn, sqlReleaseTableNamespaceColumn, sqlReleaseTableBodyColumn}).
+				AddRow(key1, "default", body1).
+				AddRow(key2, "default", body2),
+		).RowsWillBeClosed()
+	mockGetReleaseCustomLabels(mock, key1, "default", rel1.Labels)
+	mockGetReleaseCustomLabels(mock, key2, "default", rel2.Labels)
+
+	sel, err := kblabels.Parse("key1=val1,!missing,version!=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rls, next, err := sqlDriver.ListPage(ListOptions{
+		Selector: sel,
+		Statuses: []rspb.Status{rspb.StatusDeployed},
+		Limit:    2,
+		Continue: b64.EncodeToString([]byte("default/key-0.v1")),
+	})
+	if err != nil {
+		t.Fatalf("Failed to list: %v", err)
+	}
+	if len(rls) != 2 {
+		t.Fatalf("Expected 2 releases, got %d", len(rls))
+	}
+	if rls[0].Labels["status"] != "deployed" || rls[0].Labels["key1"] != "val1" {
+		t.Errorf("Expected system and custom labels, got %v", rls[0].Labels)
+	}
+	namespace, key, err := parseSQLContinue(next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if namespace != "default" || key != key2 {
+		t.Errorf("Expected the next page to start after %s/%s, got %s/%s", "default", key2, namespace, key)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("sql expectations weren't met: %v", err)
+	}
+
+	sel, _ = kblabels.Parse("version>3")
+	if _, _, err := sqlDriver.ListPage(ListOptions{Selector: sel}); err == nil {
+		t.Error("Expected an unsupported operator to fail")
+	}
+	if _, _, err := sqlDriver.ListPage(ListOptions{Continue: "invalid"}); err == nil {
+		t.Error("Expected an invalid continue token to fail")
+	}
+}
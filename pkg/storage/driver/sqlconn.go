@@ -0,0 +1,271 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver // import "helm.sh/helm/v3/pkg/storage/driver"
+
+import (
+	"context"
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"modernc.org/sqlite"
+
+	"helm.sh/helm/v3/internal/tlsutil"
+)
+
+// SQLOptions configures the connection of the SQL driver to its database.
+type SQLOptions struct {
+	// Dialect is the dialect of the database, PostgreSQLDialect,
+	// MySQLDialect or SQLiteDialect. Defaults to PostgreSQLDialect.
+	Dialect string
+	// ConnectionString is the data source name of the database, in the format
+	// of the driver of the dialect: a PostgreSQL URL or keyword/value string,
+	// a MySQL DSN, or the path of a SQLite database file.
+	ConnectionString string
+
+	// CAFile, CertFile and KeyFile configure TLS connections to the database.
+	// If CAFile is set, the certificate of the server is verified against it.
+	// CertFile and KeyFile set a client certificate.
+	CAFile   string
+	CertFile string
+	KeyFile  string
+
+	// PasswordFunc returns the password used by every new connection. It is
+	// meant for short-lived credentials, such as the IAM authentication tokens
+	// of managed databases, and takes precedence over the password of the
+	// connection string.
+	PasswordFunc func(ctx context.Context) (string, error)
+
+	// MaxOpenConns is the maximum number of open connections. Zero means no limit.
+	MaxOpenConns int
+	// MaxIdleConns is the maximum number of idle connections. Zero uses the
+	// database/sql default.
+	MaxIdleConns int
+	// ConnMaxLifetime is the maximum amount of time a connection may be reused.
+	ConnMaxLifetime time.Duration
+	// ConnMaxIdleTime is the maximum amount of time a connection may be idle.
+	ConnMaxIdleTime time.Duration
+
+	// SkipMigrations disables the creation and migration of the schema, for
+	// databases whose schema is managed separately.
+	SkipMigrations bool
+}
+
+// PasswordFromFile returns a SQLOptions.PasswordFunc that reads the password
+// from a file each time a connection is opened, so that tokens rotated by an
+// external process are picked up.
+func PasswordFromFile(path string) func(context.Context) (string, error) {
+	return func(context.Context) (string, error) {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to read database password")
+		}
+		return strings.TrimSpace(string(b)), nil
+	}
+}
+
+// openSQL opens and checks the connection to the database described by opts.
+func openSQL(opts SQLOptions) (*sqlx.DB, error) {
+	dialect := opts.Dialect
+	if dialect == "" {
+		dialect = PostgreSQLDialect
+	}
+
+	var connector sqldriver.Connector
+	var err error
+	switch dialect {
+	case PostgreSQLDialect:
+		connector, err = postgreSQLConnector(opts)
+	case MySQLDialect:
+		connector, err = mySQLConnector(opts)
+	case SQLiteDialect:
+		connector, err = sqliteConnector(opts)
+	default:
+		return nil, errors.Errorf("unsupported SQL dialect %q", dialect)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	db := sqlx.NewDb(sql.OpenDB(connector), dialect)
+	db.SetMaxOpenConns(opts.MaxOpenConns)
+	if opts.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(opts.MaxIdleConns)
+	}
+	db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// postgreSQLConnectionString converts the connection string of opts to the
+// keyword/value format and adds the TLS settings to it.
+func postgreSQLConnectionString(opts SQLOptions) (string, error) {
+	dsn := opts.ConnectionString
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		var err error
+		if dsn, err = pq.ParseURL(dsn); err != nil {
+			return "", errors.Wrap(err, "invalid PostgreSQL connection string")
+		}
+	}
+
+	params := []string{dsn}
+	if opts.CAFile != "" {
+		if !strings.Contains(dsn, "sslmode=") {
+			params = append(params, "sslmode=verify-full")
+		}
+		params = append(params, "sslrootcert="+postgreSQLQuote(opts.CAFile))
+	}
+	if opts.CertFile != "" {
+		params = append(params, "sslcert="+postgreSQLQuote(opts.CertFile))
+	}
+	if opts.KeyFile != "" {
+		params = append(params, "sslkey="+postgreSQLQuote(opts.KeyFile))
+	}
+	return strings.TrimSpace(strings.Join(params, " ")), nil
+}
+
+// postgreSQLQuote quotes a value of a keyword/value connection string.
+func postgreSQLQuote(value string) string {
+	r := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	return "'" + r.Replace(value) + "'"
+}
+
+func postgreSQLConnector(opts SQLOptions) (sqldriver.Connector, error) {
+	dsn, err := postgreSQLConnectionString(opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.PasswordFunc == nil {
+		return pq.NewConnector(dsn)
+	}
+	return &passwordConnector{dsn: dsn, password: opts.PasswordFunc}, nil
+}
+
+// passwordConnector opens PostgreSQL connections with a password that is
+// fetched for every connection.
+type passwordConnector struct {
+	dsn      string
+	password func(context.Context) (string, error)
+}
+
+func (c *passwordConnector) Connect(ctx context.Context) (sqldriver.Conn, error) {
+	password, err := c.password(ctx)
+	if err != nil {
+		return nil, err
+	}
+	connector, err := pq.NewConnector(c.dsn + " password=" + postgreSQLQuote(password))
+	if err != nil {
+		return nil, err
+	}
+	return connector.Connect(ctx)
+}
+
+func (c *passwordConnector) Driver() sqldriver.Driver {
+	return &pq.Driver{}
+}
+
+// mySQLConfig parses the connection string of opts and applies the TLS and
+// password settings to it.
+func mySQLConfig(opts SQLOptions) (*mysql.Config, error) {
+	cfg, err := mysql.ParseDSN(opts.ConnectionString)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid MySQL connection string")
+	}
+
+	if opts.CAFile != "" || opts.CertFile != "" {
+		tlsConf, err := tlsutil.NewClientTLS(opts.CertFile, opts.KeyFile, opts.CAFile, false)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to configure TLS")
+		}
+		cfg.TLS = tlsConf
+	}
+
+	if opts.PasswordFunc != nil {
+		// Authentication tokens are sent with the cleartext plugin, which
+		// managed databases require for token authentication.
+		cfg.AllowCleartextPasswords = true
+		err := cfg.Apply(mysql.BeforeConnect(func(ctx context.Context, c *mysql.Config) error {
+			password, err := opts.PasswordFunc(ctx)
+			if err != nil {
+				return err
+			}
+			c.Passwd = password
+			return nil
+		}))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+func mySQLConnector(opts SQLOptions) (sqldriver.Connector, error) {
+	cfg, err := mySQLConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	return mysql.NewConnector(cfg)
+}
+
+// sqliteConnector opens the SQLite database file of opts, creating it and its
+// directory if they do not exist. Writers wait for the lock of the database,
+// held by another Helm process, instead of failing immediately, and
+// transactions take the write lock when they begin so they cannot deadlock.
+func sqliteConnector(opts SQLOptions) (sqldriver.Connector, error) {
+	path := opts.ConnectionString
+	if path == "" {
+		return nil, errors.New("no SQLite database path configured")
+	}
+	if !strings.HasPrefix(path, "file:") && path != ":memory:" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, errors.Wrap(err, "failed to create the directory of the SQLite database")
+		}
+	}
+
+	dsn := path
+	if !strings.Contains(dsn, "?") {
+		dsn += "?_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+	}
+	return &dsnConnector{dsn: dsn, driver: &sqlite.Driver{}}, nil
+}
+
+// dsnConnector opens connections to a data source name with a driver that
+// does not provide connectors.
+type dsnConnector struct {
+	dsn    string
+	driver sqldriver.Driver
+}
+
+func (c *dsnConnector) Connect(_ context.Context) (sqldriver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c *dsnConnector) Driver() sqldriver.Driver {
+	return c.driver
+}
@@ -0,0 +1,157 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const (
+	testCAFile   = "../../../testdata/rootca.crt"
+	testCertFile = "../../../testdata/crt.pem"
+	testKeyFile  = "../../../testdata/key.pem"
+)
+
+func TestPostgreSQLConnectionString(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    SQLOptions
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "keyword/value string is kept",
+			opts: SQLOptions{ConnectionString: "host=db user=helm dbname=helm"},
+			want: "host=db user=helm dbname=helm",
+		},
+		{
+			name: "URL is converted",
+			opts: SQLOptions{ConnectionString: "postgres://helm@db:5432/helm"},
+			want: "dbname='helm' host='db' port='5432' user='helm'",
+		},
+		{
+			name: "TLS settings are added",
+			opts: SQLOptions{ConnectionString: "host=db", CAFile: "/ca.crt", CertFile: "/tls.crt", KeyFile: "/tls.key"},
+			want: "host=db sslmode=verify-full sslrootcert='/ca.crt' sslcert='/tls.crt' sslkey='/tls.key'",
+		},
+		{
+			name: "explicit sslmode is kept",
+			opts: SQLOptions{ConnectionString: "host=db sslmode=verify-ca", CAFile: "/ca.crt"},
+			want: "host=db sslmode=verify-ca sslrootcert='/ca.crt'",
+		},
+		{
+			name:    "invalid URL",
+			opts:    SQLOptions{ConnectionString: "postgres://%zz"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := postgreSQLConnectionString(tt.opts)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected an error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPostgreSQLQuote(t *testing.T) {
+	if got, want := postgreSQLQuote(`it's a\secret`), `'it\'s a\\secret'`; got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestMySQLConfig(t *testing.T) {
+	cfg, err := mySQLConfig(SQLOptions{
+		ConnectionString: "helm@tcp(db:3306)/helm",
+		CAFile:           testCAFile,
+		CertFile:         testCertFile,
+		KeyFile:          testKeyFile,
+		PasswordFunc: func(context.Context) (string, error) {
+			return "token", nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.TLS == nil || cfg.TLS.RootCAs == nil || len(cfg.TLS.Certificates) != 1 {
+		t.Errorf("Expected TLS to be configured with the CA and client certificate, got %+v", cfg.TLS)
+	}
+	if !cfg.AllowCleartextPasswords {
+		t.Error("Expected cleartext passwords to be allowed for token authentication")
+	}
+	if cfg.User != "helm" || cfg.Addr != "db:3306" || cfg.DBName != "helm" {
+		t.Errorf("Expected the connection string to be parsed, got %+v", cfg)
+	}
+
+	if _, err := mySQLConfig(SQLOptions{ConnectionString: "helm@tcp(db:3306"}); err == nil {
+		t.Error("Expected an invalid connection string to fail")
+	}
+
+	plain, err := mySQLConfig(SQLOptions{ConnectionString: "helm:secret@tcp(db:3306)/helm"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain.TLS != nil || plain.AllowCleartextPasswords || plain.Passwd != "secret" {
+		t.Errorf("Expected the connection string to be used as is, got %+v", plain)
+	}
+	if _, err := mysql.NewConnector(plain); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPasswordFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	password := PasswordFromFile(path)
+
+	if _, err := password(context.Background()); err == nil {
+		t.Error("Expected a missing password file to fail")
+	}
+
+	for _, token := range []string{"first", "second"} {
+		if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		got, err := password(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != token {
+			t.Errorf("Expected the rotated password %q, got %q", token, got)
+		}
+	}
+}
+
+func TestOpenSQLUnsupportedDialect(t *testing.T) {
+	if _, err := openSQL(SQLOptions{Dialect: "oracle"}); err == nil {
+		t.Error("Expected an unsupported dialect to fail")
+	}
+}
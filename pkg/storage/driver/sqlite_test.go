@@ -0,0 +1,120 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"path/filepath"
+	"testing"
+
+	kblabels "k8s.io/apimachinery/pkg/labels"
+
+	rspb "helm.sh/helm/v3/pkg/release"
+)
+
+func TestSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "helm", "releases.db")
+	s, err := NewSQLite(path, t.Logf, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, rls := range []*rspb.Release{
+		releaseStub("smug-pigeon", 1, "default", rspb.StatusSuperseded),
+		releaseStub("smug-pigeon", 2, "default", rspb.StatusDeployed),
+		releaseStub("angry-bird", 1, "default", rspb.StatusDeployed),
+		releaseStub("angry-bird", 1, "other", rspb.StatusDeployed),
+	} {
+		if err := s.Create(testKey(rls.Name, rls.Version), rls); err != nil {
+			t.Fatalf("failed to create %s: %v", rls.Name, err)
+		}
+	}
+	s.namespace = "default"
+
+	rls := releaseStub("smug-pigeon", 2, "default", rspb.StatusDeployed)
+	if err := s.Create(testKey("smug-pigeon", 2), rls); err != ErrReleaseExists {
+		t.Errorf("Expected ErrReleaseExists, got %v", err)
+	}
+
+	got, err := s.Get(testKey("smug-pigeon", 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "smug-pigeon" || got.Version != 2 || got.Labels["key1"] != "val1" {
+		t.Errorf("Unexpected release %s v%d with labels %v", got.Name, got.Version, got.Labels)
+	}
+
+	rls.Info.Status = rspb.StatusFailed
+	if err := s.Update(testKey("smug-pigeon", 2), rls); err != nil {
+		t.Fatal(err)
+	}
+	failed, err := s.Query(map[string]string{"name": "smug-pigeon", "status": "failed", "owner": "helm"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 1 || failed[0].Version != 2 {
+		t.Errorf("Expected revision 2 to be failed, got %d releases", len(failed))
+	}
+
+	all, err := s.List(func(*rspb.Release) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 {
+		t.Errorf("Expected 3 releases in namespace default, got %d", len(all))
+	}
+
+	selector, err := kblabels.Parse("key1=val1,name!=angry-bird")
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, next, err := s.ListPage(ListOptions{Selector: selector, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 1 || page[0].Version != 1 || next == "" {
+		t.Fatalf("Expected the first page to hold smug-pigeon v1, got %d releases", len(page))
+	}
+	page, _, err = s.ListPage(ListOptions{Selector: selector, Limit: 1, Continue: next})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 1 || page[0].Version != 2 {
+		t.Errorf("Expected the second page to hold smug-pigeon v2, got %d releases", len(page))
+	}
+
+	if _, err := s.Delete(testKey("angry-bird", 1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(testKey("angry-bird", 1)); err != ErrReleaseNotFound {
+		t.Errorf("Expected ErrReleaseNotFound, got %v", err)
+	}
+
+	// The schema is not migrated again when the database is reopened.
+	reopened, err := NewSQLite(path, t.Logf, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reopened.Get(testKey("angry-bird", 1)); err != nil {
+		t.Errorf("Expected angry-bird to exist in namespace other, got %v", err)
+	}
+}
+
+func TestSQLiteNoPath(t *testing.T) {
+	if _, err := NewSQLite("", t.Logf, "default"); err == nil {
+		t.Error("Expected an empty path to fail")
+	}
+}
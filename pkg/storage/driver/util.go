@@ -19,9 +19,19 @@ package driver // import "helm.sh/helm/v3/pkg/storage/driver"
 import (
 	"bytes"
 	"compress/gzip"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"io"
+	"strconv"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+	kblabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	rspb "helm.sh/helm/v3/pkg/release"
 )
@@ -30,52 +40,191 @@ var b64 = base64.StdEncoding
 
 var magicGzip = []byte{0x1f, 0x8b, 0x08}
 
-var systemLabels = []string{"name", "owner", "status", "version", "createdAt", "modifiedAt"}
+var magicZstd = []byte{0x28, 0xb5, 0x2f, 0xfd}
 
-// encodeRelease encodes a release returning a base64 encoded
-// gzipped string representation, or error.
-func encodeRelease(rls *rspb.Release) (string, error) {
-	b, err := json.Marshal(rls)
-	if err != nil {
-		return "", err
+// magicEncrypted prefixes the payloads encrypted by an Encryptor.
+var magicEncrypted = []byte("helm-encrypted:")
+
+// Encryptor encrypts the compressed release payloads written by the storage
+// drivers, protecting the rendered manifests and values they contain. See the
+// encryption package for an implementation.
+type Encryptor interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+const (
+	// CompressionGzip compresses release payloads with gzip.
+	CompressionGzip = "gzip"
+	// CompressionZstd compresses release payloads with zstd, which produces
+	// smaller payloads than gzip for large releases.
+	CompressionZstd = "zstd"
+)
+
+// Compression configures how the storage drivers compress release payloads.
+// Payloads are decompressed according to their content, so records written
+// with any compression remain readable when it is changed.
+type Compression struct {
+	// Algorithm is CompressionGzip or CompressionZstd. Gzip is used if it
+	// is empty.
+	Algorithm string
+	// Level is the compression level of the algorithm, from 1 to 9 for gzip
+	// and from 1 to 22 for zstd. The best compression of the algorithm is
+	// used if it is zero.
+	Level int
+}
+
+// Validate returns an error if the algorithm or the level is not supported.
+func (c Compression) Validate() error {
+	switch c.Algorithm {
+	case "", CompressionGzip:
+		if c.Level < 0 || c.Level > gzip.BestCompression {
+			return errors.Errorf("invalid gzip compression level %d", c.Level)
+		}
+	case CompressionZstd:
+		if c.Level < 0 || c.Level > 22 {
+			return errors.Errorf("invalid zstd compression level %d", c.Level)
+		}
+	default:
+		return errors.Errorf("unsupported compression %q", c.Algorithm)
+	}
+	return nil
+}
+
+func (c Compression) compress(b []byte) ([]byte, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	if c.Algorithm == CompressionZstd {
+		level := zstd.SpeedBestCompression
+		if c.Level != 0 {
+			level = zstd.EncoderLevelFromZstd(c.Level)
+		}
+		w, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(level), zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		defer w.Close()
+		return w.EncodeAll(b, nil), nil
+	}
+
+	level := gzip.BestCompression
+	if c.Level != 0 {
+		level = c.Level
 	}
 	var buf bytes.Buffer
-	w, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	w, err := gzip.NewWriterLevel(&buf, level)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if _, err = w.Write(b); err != nil {
-		return "", err
+		return nil, err
 	}
 	w.Close()
+	return buf.Bytes(), nil
+}
 
-	return b64.EncodeToString(buf.Bytes()), nil
+// decompress decompresses gzip and zstd payloads. For backwards compatibility
+// with releases that were stored before compression was introduced, other
+// payloads are returned as is.
+func decompress(b []byte) ([]byte, error) {
+	switch {
+	case len(b) > 3 && bytes.Equal(b[0:3], magicGzip):
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return io.ReadAll(r)
+	case bytes.HasPrefix(b, magicZstd):
+		r, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return r.DecodeAll(b, nil)
+	}
+	return b, nil
+}
+
+var systemLabels = []string{"name", "owner", "status", "version", "createdAt", "modifiedAt", ChartNameLabel}
+
+// ChartNameLabel is the system label holding the name of the chart of a
+// release, so the releases of a chart can be selected by the drivers. It is
+// not set on releases stored by older versions of Helm, nor on releases whose
+// chart name is not a valid label value. It is prefixed, so it does not
+// collide with the custom labels of the releases.
+const ChartNameLabel = "helm.sh/chart-name"
+
+// chartNameLabelValue returns the value of the ChartNameLabel of the release,
+// or "" if it is not set.
+func chartNameLabelValue(rls *rspb.Release) string {
+	if rls.Chart == nil || rls.Chart.Metadata == nil {
+		return ""
+	}
+	name := rls.Chart.Metadata.Name
+	if len(validation.IsValidLabelValue(name)) != 0 {
+		return ""
+	}
+	return name
+}
+
+// encodeRelease encodes a release returning a base64 encoded
+// gzipped string representation, or error.
+func encodeRelease(rls *rspb.Release) (string, error) {
+	return sealRelease(rls, Compression{}, nil)
 }
 
 // decodeRelease decodes the bytes of data into a release
 // type. Data must contain a base64 encoded gzipped string of a
 // valid release, otherwise an error is returned.
 func decodeRelease(data string) (*rspb.Release, error) {
+	return openRelease(data, nil)
+}
+
+// sealRelease encodes a release like encodeRelease, compressing it with comp
+// and encrypting the compressed release with enc if it is not nil.
+func sealRelease(rls *rspb.Release, comp Compression, enc Encryptor) (string, error) {
+	b, err := json.Marshal(rls)
+	if err != nil {
+		return "", err
+	}
+	if b, err = comp.compress(b); err != nil {
+		return "", err
+	}
+
+	if enc == nil {
+		return b64.EncodeToString(b), nil
+	}
+	ciphertext, err := enc.Encrypt(b)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to encrypt release")
+	}
+	return b64.EncodeToString(append(append([]byte{}, magicEncrypted...), ciphertext...)), nil
+}
+
+// openRelease decodes data like decodeRelease, decrypting it with enc if it was
+// encrypted. Releases stored without encryption are read as is, so encryption
+// can be enabled on existing storage.
+func openRelease(data string, enc Encryptor) (*rspb.Release, error) {
 	// base64 decode string
 	b, err := b64.DecodeString(data)
 	if err != nil {
 		return nil, err
 	}
 
-	// For backwards compatibility with releases that were stored before
-	// compression was introduced we skip decompression if the
-	// gzip magic header is not found
-	if len(b) > 3 && bytes.Equal(b[0:3], magicGzip) {
-		r, err := gzip.NewReader(bytes.NewReader(b))
-		if err != nil {
-			return nil, err
+	if bytes.HasPrefix(b, magicEncrypted) {
+		if enc == nil {
+			return nil, errors.New("release is encrypted, but no encryption key is configured")
 		}
-		defer r.Close()
-		b2, err := io.ReadAll(r)
-		if err != nil {
+		if b, err = enc.Decrypt(b[len(magicEncrypted):]); err != nil {
 			return nil, err
 		}
-		b = b2
+	}
+
+	if b, err = decompress(b); err != nil {
+		return nil, err
 	}
 
 	var rls rspb.Release
@@ -86,6 +235,110 @@ func decodeRelease(data string) (*rspb.Release, error) {
 	return &rls, nil
 }
 
+// spilloverOwner is the owner label of the records holding the parts of a
+// release that do not fit into its main record.
+const spilloverOwner = "helm-spillover"
+
+// maxRecordDataSize is the maximum size of the release data stored in a
+// single record. It keeps each object below the 1MiB limit of Secrets and
+// ConfigMaps, and well below the 1.5MiB limit of etcd.
+var maxRecordDataSize = 768 * 1024
+
+// splitReleaseData splits an encoded release into the chunks stored by its
+// main record, first, and by its spillover records.
+func splitReleaseData(s string) []string {
+	var chunks []string
+	for len(s) > maxRecordDataSize {
+		chunks = append(chunks, s[:maxRecordDataSize])
+		s = s[maxRecordDataSize:]
+	}
+	return append(chunks, s)
+}
+
+// partsID returns the identifier of the spillover records of an encoded
+// release, derived from its content. Each content of a release is split
+// across its own spillover records, so the spillover records referenced by a
+// main record are never overwritten: the new ones are written before the main
+// record, and the old ones deleted after it.
+func partsID(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:6])
+}
+
+// partName returns the name of the i-th spillover record of key, identified
+// by id. The spillover records stored before they were identified have no id.
+func partName(key, id string, i int) string {
+	if id == "" {
+		return fmt.Sprintf("%s.p%d", key, i)
+	}
+	return fmt.Sprintf("%s.p%d-%s", key, i, id)
+}
+
+// partNames returns the names of the n spillover records of key, identified
+// by id, in order.
+func partNames(key, id string, n int) []string {
+	names := make([]string, 0, n)
+	for i := 1; i <= n; i++ {
+		names = append(names, partName(key, id, i))
+	}
+	return names
+}
+
+// staleParts returns the names of the spillover records which are no longer
+// referenced once the names current are replaced with the names next.
+func staleParts(current, next []string) []string {
+	keep := make(map[string]bool, len(next))
+	for _, name := range next {
+		keep[name] = true
+	}
+	var stale []string
+	for _, name := range current {
+		if !keep[name] {
+			stale = append(stale, name)
+		}
+	}
+	return stale
+}
+
+// spilloverLabels returns the labels of the spillover records of a release.
+// The "owner" label is set to "helm-spillover", so spillover records are not
+// returned when listing releases.
+func spilloverLabels(rls *rspb.Release) map[string]string {
+	return map[string]string{
+		"name":    rls.Name,
+		"owner":   spilloverOwner,
+		"version": strconv.Itoa(rls.Version),
+	}
+}
+
+// listSelector returns the label selector of the release records selected
+// by the options.
+func listSelector(opts ListOptions) (kblabels.Selector, error) {
+	owner, err := kblabels.NewRequirement("owner", selection.Equals, []string{"helm"})
+	if err != nil {
+		return nil, err
+	}
+	sel := kblabels.NewSelector().Add(*owner)
+
+	if len(opts.Statuses) > 0 {
+		statuses := make([]string, 0, len(opts.Statuses))
+		for _, status := range opts.Statuses {
+			statuses = append(statuses, status.String())
+		}
+		status, err := kblabels.NewRequirement("status", selection.In, statuses)
+		if err != nil {
+			return nil, err
+		}
+		sel = sel.Add(*status)
+	}
+
+	if opts.Selector != nil {
+		reqs, _ := opts.Selector.Requirements()
+		sel = sel.Add(reqs...)
+	}
+	return sel, nil
+}
+
 // Checks if label is system
 func isSystemLabel(key string) bool {
 	for _, v := range GetSystemLabels() {
@@ -96,8 +349,9 @@ func isSystemLabel(key string) bool {
 	return false
 }
 
-// Removes system labels from labels map
-func filterSystemLabels(lbs map[string]string) map[string]string {
+// FilterSystemLabels returns the labels without the system labels set by the
+// drivers, the labels of the release set by the users.
+func FilterSystemLabels(lbs map[string]string) map[string]string {
 	result := make(map[string]string)
 	for k, v := range lbs {
 		if !isSystemLabel(k) {
@@ -14,8 +14,13 @@ limitations under the License.
 package driver
 
 import (
+	"bytes"
 	"reflect"
+	"strings"
 	"testing"
+
+	rspb "helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/encryption"
 )
 
 func TestGetSystemLabel(t *testing.T) {
@@ -68,7 +73,7 @@ func TestFilterSystemLabels(t *testing.T) {
 		}},
 	}
 	for _, test := range tests {
-		if output := filterSystemLabels(test[0]); !reflect.DeepEqual(test[1], output) {
+		if output := FilterSystemLabels(test[0]); !reflect.DeepEqual(test[1], output) {
 			t.Errorf("Expected {%v}, got {%v}", test[1], output)
 		}
 	}
@@ -106,3 +111,106 @@ func TestContainsSystemLabels(t *testing.T) {
 		}
 	}
 }
+
+func newTestEncryptor(t *testing.T, b byte) Encryptor {
+	t.Helper()
+	wrapper, err := encryption.NewAESKeyWrapper(bytes.Repeat([]byte{b}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encryption.NewEnvelope(wrapper)
+}
+
+func TestSealRelease(t *testing.T) {
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+	rel.Manifest = "password: hunter2"
+	rel.Labels = nil
+	enc := newTestEncryptor(t, 1)
+
+	sealed, err := sealRelease(rel, Compression{}, enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := b64.DecodeString(sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(b, magicEncrypted) {
+		t.Errorf("Expected the release to be encrypted, got %q", b)
+	}
+
+	got, err := openRelease(sealed, enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rel, got) {
+		t.Errorf("Expected {%v}, got {%v}", rel, got)
+	}
+
+	if _, err := decodeRelease(sealed); err == nil || !strings.Contains(err.Error(), "encrypted") {
+		t.Errorf("Expected reading an encrypted release without a key to fail, got %v", err)
+	}
+	if _, err := openRelease(sealed, newTestEncryptor(t, 2)); err == nil {
+		t.Error("Expected reading an encrypted release with another key to fail")
+	}
+
+	// releases stored before encryption was enabled remain readable
+	plain, err := encodeRelease(rel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err = openRelease(plain, enc); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rel, got) {
+		t.Errorf("Expected {%v}, got {%v}", rel, got)
+	}
+}
+
+func TestCompression(t *testing.T) {
+	rel := releaseStub("smug-pigeon", 1, "default", rspb.StatusDeployed)
+	rel.Manifest = strings.Repeat("kind: ConfigMap\n", 1000)
+	rel.Labels = nil
+
+	for _, comp := range []Compression{
+		{},
+		{Algorithm: CompressionGzip, Level: 1},
+		{Algorithm: CompressionZstd},
+		{Algorithm: CompressionZstd, Level: 3},
+	} {
+		sealed, err := sealRelease(rel, comp, nil)
+		if err != nil {
+			t.Fatalf("%v: %s", comp, err)
+		}
+		b, err := b64.DecodeString(sealed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		magic := magicGzip
+		if comp.Algorithm == CompressionZstd {
+			magic = magicZstd
+		}
+		if !bytes.HasPrefix(b, magic) {
+			t.Errorf("%v: Expected the release to be compressed with %q", comp, comp.Algorithm)
+		}
+
+		// records are read back regardless of the configured compression
+		got, err := decodeRelease(sealed)
+		if err != nil {
+			t.Fatalf("%v: %s", comp, err)
+		}
+		if !reflect.DeepEqual(rel, got) {
+			t.Errorf("%v: Expected {%v}, got {%v}", comp, rel, got)
+		}
+	}
+
+	for _, comp := range []Compression{
+		{Algorithm: "lz4"},
+		{Algorithm: CompressionGzip, Level: 10},
+		{Algorithm: CompressionZstd, Level: 23},
+	} {
+		if _, err := sealRelease(rel, comp, nil); err == nil {
+			t.Errorf("Expected compression %v to be invalid", comp)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver // import "helm.sh/helm/v3/pkg/storage/driver"
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// maxVersions is the number of versions a driver records. Listing the
+// releases of a large namespace records the version of each of them, so the
+// least recently recorded versions are evicted beyond this number.
+const maxVersions = 1024
+
+// versions records the version of the stored object of each release a driver
+// reads or writes: the resourceVersion of a Kubernetes object or the ETag of
+// an object in an object store.
+//
+// Updates are conditional on the recorded version, so an update based on a
+// stale read fails with a ConflictError instead of overwriting the write of
+// another client. Releases that were not read by the driver, or whose version
+// was evicted, are updated unconditionally.
+type versions struct {
+	mu sync.Mutex
+	m  map[string]*list.Element
+	// order holds the recorded versions, the most recently recorded first.
+	order *list.List
+}
+
+type versionEntry struct {
+	key, version string
+}
+
+func (v *versions) get(key string) string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if e, ok := v.m[key]; ok {
+		return e.Value.(*versionEntry).version
+	}
+	return ""
+}
+
+func (v *versions) set(key, version string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if e, ok := v.m[key]; ok {
+		if version == "" {
+			v.order.Remove(e)
+			delete(v.m, key)
+			return
+		}
+		e.Value.(*versionEntry).version = version
+		v.order.MoveToFront(e)
+		return
+	}
+	if version == "" {
+		return
+	}
+	if v.m == nil {
+		v.m = map[string]*list.Element{}
+		v.order = list.New()
+	}
+	v.m[key] = v.order.PushFront(&versionEntry{key: key, version: version})
+	if v.order.Len() > maxVersions {
+		oldest := v.order.Back()
+		v.order.Remove(oldest)
+		delete(v.m, oldest.Value.(*versionEntry).key)
+	}
+}
+
+// check returns a ConflictError if a version of key was recorded and the
+// current version of its object is different.
+func (v *versions) check(key, current string) error {
+	if recorded := v.get(key); recorded != "" && recorded != current {
+		return &ConflictError{
+			Key: key,
+			Err: errors.Errorf("expected version %s, found %s", recorded, current),
+		}
+	}
+	return nil
+}
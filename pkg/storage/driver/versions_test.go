@@ -0,0 +1,52 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package driver
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestVersionsBounded(t *testing.T) {
+	var v versions
+	for i := 0; i < maxVersions+10; i++ {
+		v.set(fmt.Sprintf("key-%d", i), "1")
+	}
+	// the first keys recorded are re-recorded, so they are kept
+	v.set("key-0", "2")
+
+	if n := len(v.m); n != maxVersions {
+		t.Errorf("Expected %d versions, got %d", maxVersions, n)
+	}
+	if got := v.get("key-0"); got != "2" {
+		t.Errorf("Expected version 2 of key-0, got %q", got)
+	}
+	if got := v.get("key-1"); got != "" {
+		t.Errorf("Expected the version of key-1 to be evicted, got %q", got)
+	}
+	if got := v.get(fmt.Sprintf("key-%d", maxVersions+9)); got != "1" {
+		t.Errorf("Expected version 1 of the last key, got %q", got)
+	}
+
+	v.set("key-0", "")
+	if got := v.get("key-0"); got != "" {
+		t.Errorf("Expected the version of key-0 to be removed, got %q", got)
+	}
+	if n := v.order.Len(); n != len(v.m) {
+		t.Errorf("Expected %d ordered versions, got %d", len(v.m), n)
+	}
+}
@@ -0,0 +1,175 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package encryption implements envelope encryption of the release records
+written by the storage drivers.
+
+Each payload is encrypted with AES-256-GCM using a data key. The data key is
+wrapped by a KeyWrapper, such as a key management service or age, and stored
+alongside the payload, so the key-encryption key never leaves the KeyWrapper.
+*/
+package encryption // import "helm.sh/helm/v3/pkg/storage/encryption"
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// envelopeVersion is the first byte of every envelope.
+const envelopeVersion = 1
+
+// dataKeySize is the size of the AES-256 data keys.
+const dataKeySize = 32
+
+// KeyWrapper wraps and unwraps data keys with a key-encryption key.
+type KeyWrapper interface {
+	// WrapKey encrypts a data key.
+	WrapKey(key []byte) ([]byte, error)
+	// UnwrapKey decrypts a data key wrapped by WrapKey.
+	UnwrapKey(wrapped []byte) ([]byte, error)
+}
+
+// Envelope encrypts payloads with data keys wrapped by a KeyWrapper. It
+// implements the driver.Encryptor interface.
+//
+// A single data key is generated for the lifetime of an Envelope, and data
+// keys unwrapped while decrypting are cached, so the KeyWrapper is called once
+// per key rather than once per release record.
+type Envelope struct {
+	wrapper KeyWrapper
+
+	mu         sync.Mutex
+	key        []byte
+	wrappedKey []byte
+	unwrapped  map[string][]byte
+}
+
+// NewEnvelope returns an Envelope wrapping its data keys with wrapper.
+func NewEnvelope(wrapper KeyWrapper) *Envelope {
+	return &Envelope{
+		wrapper:   wrapper,
+		unwrapped: map[string][]byte{},
+	}
+}
+
+// Encrypt encrypts plaintext. The result holds the envelope version, the
+// wrapped data key, the nonce and the ciphertext.
+func (e *Envelope) Encrypt(plaintext []byte) ([]byte, error) {
+	key, wrappedKey, err := e.dataKey()
+	if err != nil {
+		return nil, err
+	}
+	if len(wrappedKey) > 0xffff {
+		return nil, errors.Errorf("wrapped data key is too large (%d bytes)", len(wrappedKey))
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(envelopeVersion)
+	binary.Write(&buf, binary.BigEndian, uint16(len(wrappedKey)))
+	buf.Write(wrappedKey)
+	buf.Write(nonce)
+	return aead.Seal(buf.Bytes(), nonce, plaintext, nil), nil
+}
+
+// Decrypt decrypts data encrypted by Encrypt, with any data key the
+// KeyWrapper can unwrap.
+func (e *Envelope) Decrypt(data []byte) ([]byte, error) {
+	if len(data) < 3 || data[0] != envelopeVersion {
+		return nil, errors.New("unsupported encryption envelope")
+	}
+	n := int(binary.BigEndian.Uint16(data[1:3]))
+	data = data[3:]
+	if len(data) < n {
+		return nil, errors.New("truncated encryption envelope")
+	}
+	wrappedKey, data := data[:n], data[n:]
+
+	key, err := e.unwrap(wrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("truncated encryption envelope")
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	return plaintext, errors.Wrap(err, "failed to decrypt release")
+}
+
+// dataKey returns the data key used for encryption and its wrapped form,
+// generating it on first use.
+func (e *Envelope) dataKey() ([]byte, []byte, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.key == nil {
+		key := make([]byte, dataKeySize)
+		if _, err := io.ReadFull(rand.Reader, key); err != nil {
+			return nil, nil, err
+		}
+		wrapped, err := e.wrapper.WrapKey(key)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to wrap data key")
+		}
+		e.key, e.wrappedKey = key, wrapped
+		e.unwrapped[string(wrapped)] = key
+	}
+	return e.key, e.wrappedKey, nil
+}
+
+func (e *Envelope) unwrap(wrapped []byte) ([]byte, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if key, ok := e.unwrapped[string(wrapped)]; ok {
+		return key, nil
+	}
+	key, err := e.wrapper.UnwrapKey(wrapped)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unwrap data key")
+	}
+	e.unwrapped[string(wrapped)] = key
+	return key, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
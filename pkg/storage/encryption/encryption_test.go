@@ -0,0 +1,178 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// countingWrapper counts the calls to the KeyWrapper it wraps.
+type countingWrapper struct {
+	KeyWrapper
+	wraps, unwraps int
+}
+
+func (c *countingWrapper) WrapKey(key []byte) ([]byte, error) {
+	c.wraps++
+	return c.KeyWrapper.WrapKey(key)
+}
+
+func (c *countingWrapper) UnwrapKey(wrapped []byte) ([]byte, error) {
+	c.unwraps++
+	return c.KeyWrapper.UnwrapKey(wrapped)
+}
+
+func testKEK(b byte) []byte {
+	return bytes.Repeat([]byte{b}, dataKeySize)
+}
+
+func TestEnvelope(t *testing.T) {
+	wrapper, err := NewAESKeyWrapper(testKEK(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	counting := &countingWrapper{KeyWrapper: wrapper}
+	e := NewEnvelope(counting)
+
+	plaintext := []byte("password: hunter2")
+	first, err := e.Encrypt(plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := e.Encrypt(plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(first, plaintext) {
+		t.Error("Expected the plaintext not to appear in the envelope")
+	}
+	if bytes.Equal(first, second) {
+		t.Error("Expected every envelope to use a new nonce")
+	}
+	if counting.wraps != 1 {
+		t.Errorf("Expected the data key to be wrapped once, got %d", counting.wraps)
+	}
+
+	// A new envelope, as in another process, unwraps the key of the record.
+	other := &countingWrapper{KeyWrapper: wrapper}
+	reader := NewEnvelope(other)
+	for _, data := range [][]byte{first, second} {
+		got, err := reader.Decrypt(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, plaintext) {
+			t.Errorf("Expected %q, got %q", plaintext, got)
+		}
+	}
+	if other.unwraps != 1 {
+		t.Errorf("Expected the data key to be unwrapped once, got %d", other.unwraps)
+	}
+
+	tampered := append([]byte{}, first...)
+	tampered[len(tampered)-1] ^= 0xff
+	if _, err := reader.Decrypt(tampered); err == nil {
+		t.Error("Expected a tampered envelope to fail")
+	}
+
+	wrongWrapper, _ := NewAESKeyWrapper(testKEK(2))
+	if _, err := NewEnvelope(wrongWrapper).Decrypt(first); err == nil {
+		t.Error("Expected decryption with another key-encryption key to fail")
+	}
+
+	for _, invalid := range [][]byte{nil, {2, 0, 0}, {envelopeVersion, 0, 10}} {
+		if _, err := reader.Decrypt(invalid); err == nil {
+			t.Errorf("Expected invalid envelope %v to fail", invalid)
+		}
+	}
+}
+
+func TestLoadAESKeyWrapper(t *testing.T) {
+	dir := t.TempDir()
+
+	raw := filepath.Join(dir, "raw")
+	if err := os.WriteFile(raw, testKEK(3), 0600); err != nil {
+		t.Fatal(err)
+	}
+	encoded := filepath.Join(dir, "encoded")
+	if err := os.WriteFile(encoded, []byte(base64.StdEncoding.EncodeToString(testKEK(3))+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	short := filepath.Join(dir, "short")
+	if err := os.WriteFile(short, []byte("too short"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := LoadAESKeyWrapper(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := LoadAESKeyWrapper(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapped, err := a.WrapKey(testKEK(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := b.UnwrapKey(wrapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(key, testKEK(4)) {
+		t.Error("Expected the raw and base64 encoded keys to be the same")
+	}
+
+	if _, err := LoadAESKeyWrapper(short); err == nil {
+		t.Error("Expected a key of the wrong size to fail")
+	}
+	if _, err := LoadAESKeyWrapper(filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected a missing key file to fail")
+	}
+}
+
+func TestExecKeyWrapper(t *testing.T) {
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skip("cat is not available")
+	}
+
+	w := &ExecKeyWrapper{WrapCommand: []string{"cat"}, UnwrapCommand: []string{"cat"}}
+	e := NewEnvelope(w)
+	data, err := e.Encrypt([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := NewEnvelope(w).Decrypt(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "secret" {
+		t.Errorf("Expected %q, got %q", "secret", got)
+	}
+
+	if _, err := (&ExecKeyWrapper{}).WrapKey([]byte("key")); err == nil {
+		t.Error("Expected a missing command to fail")
+	}
+	if _, err := (&ExecKeyWrapper{WrapCommand: []string{"false"}}).WrapKey([]byte("key")); err == nil {
+		t.Error("Expected a failing command to fail")
+	}
+}
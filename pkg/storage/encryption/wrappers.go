@@ -0,0 +1,124 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/pkg/errors"
+)
+
+var _ KeyWrapper = (*AESKeyWrapper)(nil)
+var _ KeyWrapper = (*ExecKeyWrapper)(nil)
+
+// AESKeyWrapper wraps data keys with a local AES-256 key-encryption key.
+type AESKeyWrapper struct {
+	kek []byte
+}
+
+// NewAESKeyWrapper returns a KeyWrapper using the 32 byte key-encryption key.
+func NewAESKeyWrapper(kek []byte) (*AESKeyWrapper, error) {
+	if len(kek) != dataKeySize {
+		return nil, errors.Errorf("key-encryption key must be %d bytes, got %d", dataKeySize, len(kek))
+	}
+	return &AESKeyWrapper{kek: kek}, nil
+}
+
+// LoadAESKeyWrapper reads the key-encryption key of an AESKeyWrapper from a
+// file holding either the raw key or its base64 encoding.
+func LoadAESKeyWrapper(path string) (*AESKeyWrapper, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read key-encryption key")
+	}
+	if len(b) != dataKeySize {
+		decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(b)))
+		if err == nil {
+			b = decoded
+		}
+	}
+	return NewAESKeyWrapper(b)
+}
+
+// WrapKey encrypts key with the key-encryption key.
+func (w *AESKeyWrapper) WrapKey(key []byte) ([]byte, error) {
+	aead, err := newAEAD(w.kek)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, key, nil), nil
+}
+
+// UnwrapKey decrypts a key wrapped by WrapKey.
+func (w *AESKeyWrapper) UnwrapKey(wrapped []byte) ([]byte, error) {
+	aead, err := newAEAD(w.kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, errors.New("invalid wrapped key")
+	}
+	return aead.Open(nil, wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():], nil)
+}
+
+// ExecKeyWrapper wraps data keys by running external commands. The key is
+// written to the standard input of the command, which must write the result
+// to its standard output.
+//
+// It integrates with tools such as age, with the commands
+// "age -e -r <recipient>" and "age -d -i <identity file>", and with the
+// command line clients of key management services.
+type ExecKeyWrapper struct {
+	// WrapCommand is the command, with its arguments, wrapping a key.
+	WrapCommand []string
+	// UnwrapCommand is the command, with its arguments, unwrapping a key.
+	UnwrapCommand []string
+}
+
+// WrapKey runs the wrap command.
+func (w *ExecKeyWrapper) WrapKey(key []byte) ([]byte, error) {
+	return runKeyCommand(w.WrapCommand, key)
+}
+
+// UnwrapKey runs the unwrap command.
+func (w *ExecKeyWrapper) UnwrapKey(wrapped []byte) ([]byte, error) {
+	return runKeyCommand(w.UnwrapCommand, wrapped)
+}
+
+func runKeyCommand(command []string, input []byte) ([]byte, error) {
+	if len(command) == 0 {
+		return nil, errors.New("no key command configured")
+	}
+	cmd := exec.Command(command[0], command[1:]...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, errors.Wrapf(err, "error while running command %s. error output:\n%s", command[0], stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
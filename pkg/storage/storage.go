@@ -92,6 +92,13 @@ func (s *Storage) ListReleases() ([]*rspb.Release, error) {
 	return s.Driver.List(func(_ *rspb.Release) bool { return true })
 }
 
+// ListPage returns the releases selected by opts, and the token listing the
+// next page. See driver.ListPage.
+func (s *Storage) ListPage(opts driver.ListOptions) ([]*rspb.Release, string, error) {
+	s.Log("listing a page of releases in storage")
+	return driver.ListPage(s.Driver, opts)
+}
+
 // ListUninstalled returns all releases with Status == UNINSTALLED. An error is returned
 // if the storage backend fails to retrieve the releases.
 func (s *Storage) ListUninstalled() ([]*rspb.Release, error) {
@@ -22,6 +22,7 @@ import (
 	"testing"
 
 	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/labels"
 
 	rspb "helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/storage/driver"
@@ -167,6 +168,40 @@ func TestStorageList(t *testing.T) {
 	}
 }
 
+func TestStorageListPage(t *testing.T) {
+	storage := Init(driver.NewMemory())
+
+	rls0 := ReleaseTestData{Name: "happy-catdog", Status: rspb.StatusSuperseded}.ToRelease()
+	rls1 := ReleaseTestData{Name: "hungry-hippo", Status: rspb.StatusDeployed}.ToRelease()
+	rls1.Labels = map[string]string{"team": "a"}
+	rls2 := ReleaseTestData{Name: "angry-beaver", Status: rspb.StatusDeployed}.ToRelease()
+	rls2.Labels = map[string]string{"team": "b"}
+	for _, rls := range []*rspb.Release{rls0, rls1, rls2} {
+		assertErrNil(t.Fatal, storage.Create(rls), "Storing release")
+	}
+
+	// drivers without pagination are filtered by Helm, in a single page
+	sel, err := labels.Parse("team=a,name=hungry-hippo")
+	assertErrNil(t.Fatal, err, "Parsing selector")
+	rls, next, err := storage.ListPage(driver.ListOptions{
+		Selector: sel,
+		Statuses: []rspb.Status{rspb.StatusDeployed},
+		Limit:    1,
+	})
+	assertErrNil(t.Fatal, err, "Listing a page of releases")
+	if len(rls) != 1 || rls[0].Name != "hungry-hippo" {
+		t.Errorf("Expected only hungry-hippo, got %v", rls)
+	}
+	if next != "" {
+		t.Errorf("Expected a single page, got continue token %q", next)
+	}
+
+	rls, _, err = storage.ListPage(driver.ListOptions{Statuses: []rspb.Status{rspb.StatusDeployed}})
+	assertErrNil(t.Fatal, err, "Listing a page of releases")
+	if len(rls) != 2 {
+		t.Errorf("Expected 2 deployed releases, got %d", len(rls))
+	}
+}
 func TestStorageDeployed(t *testing.T) {
 	storage := Init(driver.NewMemory())
 